@@ -0,0 +1,10 @@
+// services/user-service/api/api.go
+package api
+
+import _ "embed"
+
+// OpenAPISpec is the OpenAPI 3 document describing the service's HTTP API.
+// Keep it in sync with the handlers; response validation in non-production environments checks it.
+//
+//go:embed openapi.json
+var OpenAPISpec []byte
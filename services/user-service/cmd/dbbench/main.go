@@ -0,0 +1,154 @@
+// services/user-service/cmd/dbbench/main.go
+
+// Command dbbench measures the user lookups on the hot paths, GetUserByEmail on every login and
+// GetUserByID on every token resolution, against a PostgreSQL database, with pgx's statement cache
+// and without it.
+//
+// With the cache, which the service uses, each connection prepares a query the first time it runs
+// it and then only binds and executes it. Without it (default_query_exec_mode=describe_exec), every
+// call has the server parse and plan the query again. Each mode gets its own pool of one connection,
+// so the numbers are per lookup rather than per pool. The lookups are of the oldest user, or of a
+// user that does not exist if the database has none.
+//
+// The pending users migrations are applied first, as the service does at startup, so point it at a
+// development database:
+//
+//	go run ./cmd/dbbench -dsn "$DATABASE_URL" -n 5000
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+
+	"health-tracker-project/services/user-service/internal/repository"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// mode is one way of running the lookups.
+type mode struct {
+	name     string
+	execMode string // pgx default_query_exec_mode, or "" for the service's default
+}
+
+var modes = []mode{
+	{name: "statement cache", execMode: ""},
+	{name: "no statement cache", execMode: "describe_exec"},
+}
+
+func main() {
+	dsn := flag.String("dsn", os.Getenv("DATABASE_URL"), "PostgreSQL data source name (default $DATABASE_URL)")
+	n := flag.Int("n", 2000, "timed lookups of each kind per mode")
+	warmup := flag.Int("warmup", 100, "untimed lookups of each kind per mode before timing")
+	flag.Parse()
+
+	logger.InitLogger("development")
+	defer logger.Logger.Sync()
+	logger.SetLevel("info") // The lookups log at debug level
+
+	if *dsn == "" || *n < 1 || *warmup < 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	fmt.Printf("%-20s %-16s %10s %10s %10s %10s\n", "mode", "lookup", "mean", "p50", "p95", "p99")
+	for _, m := range modes {
+		results, err := run(m, *dsn, *n, *warmup)
+		if err != nil {
+			logger.Logger.Fatalf("Benchmark with %s failed: %v", m.name, err)
+		}
+		for _, r := range results {
+			fmt.Printf("%-20s %-16s %10s %10s %10s %10s\n", m.name, r.lookup, r.mean(), r.percentile(50), r.percentile(95), r.percentile(99))
+		}
+	}
+}
+
+// result holds the latency of each timed call of one lookup.
+type result struct {
+	lookup    string
+	latencies []time.Duration
+}
+
+func (r result) mean() time.Duration {
+	var total time.Duration
+	for _, d := range r.latencies {
+		total += d
+	}
+	return (total / time.Duration(len(r.latencies))).Round(time.Microsecond)
+}
+
+// percentile returns the latency p percent of the calls stayed within; latencies must be sorted.
+func (r result) percentile(p int) time.Duration {
+	i := min(len(r.latencies)*p/100, len(r.latencies)-1)
+	return r.latencies[i].Round(time.Microsecond)
+}
+
+// run times n calls of each lookup on a pool of one connection opened in mode m.
+func run(m mode, dsn string, n, warmup int) ([]result, error) {
+	if m.execMode != "" {
+		dsn = withParam(dsn, "default_query_exec_mode", m.execMode)
+	}
+	db, err := repository.NewPostgresDB(dsn, "pulse-dbbench", repository.PoolConfig{MaxOpenConns: 1})
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	repo, err := repository.NewPostgresUserRepository(db)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	email, id := "dbbench-"+uuid.NewString()+"@example.invalid", uuid.New()
+	users, err := repo.GetAllUsers(ctx, nil, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(users) > 0 {
+		email, id = users[0].Email, users[0].ID
+	}
+
+	lookups := []struct {
+		name string
+		call func() error
+	}{
+		{"GetUserByEmail", func() error { _, err := repo.GetUserByEmail(ctx, email); return err }},
+		{"GetUserByID", func() error { _, err := repo.GetUserByID(ctx, id); return err }},
+	}
+	results := make([]result, 0, len(lookups))
+	for _, lookup := range lookups {
+		for range warmup {
+			if err := lookup.call(); err != nil {
+				return nil, err
+			}
+		}
+		r := result{lookup: lookup.name, latencies: make([]time.Duration, n)}
+		for i := range n {
+			start := time.Now()
+			if err := lookup.call(); err != nil {
+				return nil, err
+			}
+			r.latencies[i] = time.Since(start)
+		}
+		slices.Sort(r.latencies)
+		results = append(results, r)
+	}
+	return results, nil
+}
+
+// withParam sets a connection parameter in a postgres:// URL or a key=value data source name.
+func withParam(dsn, key, value string) string {
+	if u, err := url.Parse(dsn); err == nil && (u.Scheme == "postgres" || u.Scheme == "postgresql") {
+		q := u.Query()
+		q.Set(key, value)
+		u.RawQuery = q.Encode()
+		return u.String()
+	}
+	return dsn + " " + key + "=" + value // A later key=value pair overrides an earlier one
+}
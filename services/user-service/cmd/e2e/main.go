@@ -0,0 +1,141 @@
+// services/user-service/cmd/e2e/main.go
+
+// Command e2e runs the end-to-end scenarios in e2e/scenarios (or -scenarios) against a running stack,
+// such as the one `make compose-up` starts, and exits non-zero if any run failed. See package e2e for
+// the scenario format.
+//
+//	go run ./cmd/e2e -target http://localhost:8080 -parallel 4
+//	go run ./cmd/e2e -target user=http://localhost:8080,sync=http://localhost:8081 -run journey -tenants 8
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"regexp"
+	"strings"
+	"time"
+
+	"health-tracker-project/services/user-service/e2e"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+func main() {
+	dir := flag.String("scenarios", "e2e/scenarios", "directory of scenario files")
+	targets := flag.String("target", "http://localhost:8080", "base URL of the user-service, or name=url pairs, comma separated, for each target the scenarios use")
+	run := flag.String("run", "", "only run scenarios whose name matches this regular expression")
+	tenants := flag.Int("tenants", 0, "runs of each scenario, each as its own tenant (default: the scenario's tenants)")
+	parallel := flag.Int("parallel", 4, "tenants running at once")
+	timeout := flag.Duration("timeout", 10*time.Second, "timeout per request")
+	spreadIPs := flag.Bool("spread-ips", false, "give each tenant its own X-Forwarded-For address, for stacks with TRUST_PROXY_HEADERS=true")
+	report := flag.String("report", "", "also write the results as JSON to this file")
+	flag.Parse()
+
+	logger.InitLogger("development")
+	defer logger.Logger.Sync()
+
+	baseURLs, err := parseTargets(*targets)
+	if err != nil || *tenants < 0 || *parallel < 1 {
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		flag.Usage()
+		os.Exit(2)
+	}
+	filter, err := regexp.Compile(*run)
+	if err != nil {
+		logger.Logger.Fatalf("Invalid -run: %v", err)
+	}
+
+	scenarios, err := e2e.LoadScenarios(*dir)
+	if err != nil {
+		logger.Logger.Fatalf("Failed to load scenarios: %v", err)
+	}
+	selected := scenarios[:0]
+	for _, scenario := range scenarios {
+		if !filter.MatchString(scenario.Name) {
+			continue
+		}
+		if *tenants > 0 {
+			scenario.Tenants = *tenants
+		}
+		selected = append(selected, scenario)
+	}
+	if len(selected) == 0 {
+		logger.Logger.Fatalf("No scenario matches -run %q", *run)
+	}
+
+	// Interrupting stops the requests in flight; cleanup steps then fail, and their accounts are left behind
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	runID := e2e.NewRunID()
+	runner := &e2e.Runner{Targets: baseURLs, Parallel: *parallel, Timeout: *timeout, SpreadClientIPs: *spreadIPs}
+	logger.Logger.Infof("Run %s: %d scenarios against %s, %d tenants at a time", runID, len(selected), *targets, *parallel)
+	start := time.Now()
+	results := runner.Run(ctx, runID, selected)
+
+	for _, result := range results {
+		printResult(result)
+	}
+	if *report != "" {
+		data, _ := json.MarshalIndent(results, "", "  ")
+		if err := os.WriteFile(*report, data, 0o644); err != nil {
+			logger.Logger.Errorf("Failed to write report: %v", err)
+		}
+	}
+	logger.Logger.Infof("Run %s finished in %s: %s", runID, time.Since(start).Round(time.Millisecond), e2e.Summary(results))
+	if !e2e.Passed(results) {
+		os.Exit(1)
+	}
+}
+
+// parseTargets reads -target: a bare URL is the user-service, otherwise name=url pairs.
+func parseTargets(s string) (map[string]string, error) {
+	targets := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		name, url, ok := strings.Cut(pair, "=")
+		if !ok {
+			name, url = e2e.DefaultTarget, pair
+		}
+		if name == "" || !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+			return nil, fmt.Errorf("invalid -target %q: want a URL or name=url pairs", pair)
+		}
+		targets[name] = url
+	}
+	return targets, nil
+}
+
+// printResult writes a run's outcome, with the steps of a failed run, to stdout.
+func printResult(result e2e.Result) {
+	outcome := "PASS"
+	if !result.Passed {
+		outcome = "FAIL"
+	}
+	fmt.Printf("%s %s #%d (%s, %dms)\n", outcome, result.Scenario, result.Tenant, result.Email, result.DurationMS)
+	if !result.Passed {
+		for _, step := range result.Steps {
+			printStep("", step)
+		}
+	}
+	for _, step := range result.Cleanup {
+		if step.Outcome == e2e.OutcomeFail {
+			printStep("cleanup ", step)
+		}
+	}
+}
+
+// printStep writes one step's outcome.
+func printStep(prefix string, step e2e.StepResult) {
+	line := fmt.Sprintf("    %s%-4s %s", prefix, step.Outcome, step.Name)
+	if step.Status != 0 {
+		line += fmt.Sprintf(" [%d]", step.Status)
+	}
+	if step.Error != "" {
+		line += ": " + step.Error
+	}
+	fmt.Println(line)
+}
@@ -2,16 +2,52 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"database/sql"
+	"errors"
 	"fmt"
+	"math"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
-	_ "github.com/lib/pq" // PostgreSQL driver
+	_ "time/tzdata" // Embed the IANA timezone database so timezone validation works in minimal images
 
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+
+	"health-tracker-project/services/user-service/api"
+	"health-tracker-project/services/user-service/internal/auth/oidc"
+	"health-tracker-project/services/user-service/internal/auth/saml"
+	"health-tracker-project/services/user-service/internal/blobstore"
+	"health-tracker-project/services/user-service/internal/captcha"
+	"health-tracker-project/services/user-service/internal/config"
+	"health-tracker-project/services/user-service/internal/datasummary"
+	"health-tracker-project/services/user-service/internal/errreport"
+	"health-tracker-project/services/user-service/internal/eventbus"
+	"health-tracker-project/services/user-service/internal/fieldcrypt"
 	"health-tracker-project/services/user-service/internal/handlers"
+	"health-tracker-project/services/user-service/internal/mailer"
+	"health-tracker-project/services/user-service/internal/metrics"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/push"
+	"health-tracker-project/services/user-service/internal/realtime"
 	"health-tracker-project/services/user-service/internal/repository"
+	"health-tracker-project/services/user-service/internal/repository/inmemory"
 	"health-tracker-project/services/user-service/internal/services"
+	"health-tracker-project/services/user-service/internal/utils/certs"
+	"health-tracker-project/services/user-service/internal/utils/jwt"
 	"health-tracker-project/services/user-service/internal/utils/logger" // Import the new logger package
+	"health-tracker-project/services/user-service/internal/utils/pagination"
+	"health-tracker-project/services/user-service/internal/utils/password"
+	"health-tracker-project/services/user-service/internal/utils/schema"
+	"health-tracker-project/services/user-service/internal/virusscan"
 )
 
 func main() {
@@ -25,60 +61,1079 @@ func main() {
 
 	logger.Logger.Info("Starting User Service...")
 
+	// ctx is the context of startup and of the background jobs. SIGTERM or an interrupt cancels it, which
+	// stops the jobs and shuts the server down gracefully; a second signal ends the process at once.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	context.AfterFunc(ctx, stop)
+
+	// Optional error tracking (Sentry or a compatible service). Every error-level log entry is reported.
+	if dsn := os.Getenv("SENTRY_DSN"); dsn != "" {
+		reporter, err := errreport.NewSentryReporter(dsn)
+		if err != nil {
+			logger.Logger.Fatalf("Failed to configure error reporting: %v", err)
+		}
+		sentryEnv := os.Getenv("SENTRY_ENVIRONMENT")
+		if sentryEnv == "" {
+			sentryEnv = env
+		}
+		errreport.Init(reporter, os.Getenv("SENTRY_RELEASE"), sentryEnv)
+	}
+
 	// 1. Configuration (e.g., from environment variables)
+	// `user-service --dev`, or DATABASE_URL=memory://, runs on in-memory repositories instead of PostgreSQL.
+	args := os.Args[1:]
+	devMode := len(args) > 0 && args[0] == "--dev"
+	if devMode {
+		args = args[1:]
+	}
 	dbURL := os.Getenv("DATABASE_URL")
-	if dbURL == "" {
+	if strings.HasPrefix(dbURL, "memory://") {
+		devMode = true
+	}
+	if dbURL == "" && !devMode {
 		logger.Logger.Fatal("DATABASE_URL environment variable not set")
 	}
 
+	// Privacy mode hides whether an email is already registered (202 + email instead of 409)
+	privacyMode := os.Getenv("REGISTRATION_PRIVACY_MODE") == "true"
+
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080" // Default port
 	}
+	// TLS is terminated by the service itself when TLS_CERT_FILE or TLS_AUTOCERT_DOMAINS is set
+	tlsSettings, err := config.LoadTLS()
+	if err != nil {
+		logger.Logger.Fatalf("Invalid TLS configuration: %v", err)
+	}
 
-	// 2. Initialize Repository (concrete implementation)
-	// NewPostgresUserRepository handles DB connection, ping, and migrations internally.
-	userRepo, err := repository.NewPostgresUserRepository(dbURL)
+	// Configure JWT signing (HS256 by default, RS256/ES256 for JWKS-verifiable tokens)
+	if err := jwt.InitJWT(os.Getenv("JWT_SIGNING_ALG"), os.Getenv("JWT_SECRET"), os.Getenv("JWT_PRIVATE_KEY_PATH")); err != nil {
+		logger.Logger.Fatalf("Failed to configure JWT signing: %v", err)
+	}
+
+	// List cursors are signed so clients cannot forge positions; replicas must share PAGINATION_SECRET
+	if err := pagination.Init(os.Getenv("PAGINATION_SECRET")); err != nil {
+		logger.Logger.Fatalf("Failed to configure pagination: %v", err)
+	}
+
+	// With PII_ENCRYPTION_KEYS, users' health fields are sealed before they reach the database (and any
+	// backup of it). It must be set before any user is read or written, and kept once anything is sealed.
+	if keySpec := os.Getenv("PII_ENCRYPTION_KEYS"); keySpec != "" {
+		keys, err := fieldcrypt.ParseKeys(keySpec)
+		if err != nil {
+			logger.Logger.Fatalf("Invalid PII_ENCRYPTION_KEYS: %v", err)
+		}
+		if repository.HealthSealer, err = fieldcrypt.NewSealer(keys); err != nil {
+			logger.Logger.Fatalf("Failed to initialize field encryption: %v", err)
+		}
+		logger.Logger.Infof("Health fields are sealed with key %s", keys[0].ID)
+	} else if env == "production" {
+		logger.Logger.Warn("PII_ENCRYPTION_KEYS is not set: health fields are stored unencrypted")
+	}
+
+	// Auth cookie attributes; cross-site frontends need COOKIE_SAMESITE=none with COOKIE_SECURE=true
+	cookies, err := config.LoadCookies(env == "production")
 	if err != nil {
-		logger.Logger.Fatalf("Failed to initialize user repository: %v", err)
+		logger.Logger.Fatalf("Invalid cookie configuration: %v", err)
+	}
+	logger.Logger.Infof("Auth cookie %s configured (secure=%t, samesite=%s, domain=%q)", cookies.Name, cookies.Secure, cookies.SameSiteName(), cookies.Domain)
+
+	// Password hashing for new passwords (bcrypt by default, or argon2id). Existing hashes of
+	// either algorithm keep working and are upgraded at the next successful login.
+	if err := password.Init(password.Config{
+		Algorithm:  os.Getenv("PASSWORD_HASH_ALGORITHM"),
+		BcryptCost: envInt("BCRYPT_COST"),
+		Argon2: password.Argon2idHasher{
+			Memory:      uint32(envInt("ARGON2_MEMORY_KIB")),
+			Iterations:  uint32(envInt("ARGON2_ITERATIONS")),
+			Parallelism: uint8(min(envInt("ARGON2_PARALLELISM"), math.MaxUint8)),
+		},
+	}); err != nil {
+		logger.Logger.Fatalf("Failed to configure password hashing: %v", err)
+	}
+
+	// 2. Initialize Repositories (concrete implementations)
+	// Connections are labeled with application_name (DB_APPLICATION_NAME, then the pool) in pg_stat_activity.
+	appName := os.Getenv("DB_APPLICATION_NAME")
+	if appName == "" {
+		appName = "user-service"
+	}
+	// Each pool of the service (home, regions, metering) is sized alike; unset variables keep the defaults.
+	dbPool := repository.PoolConfig{
+		MaxOpenConns:    envInt("DB_MAX_OPEN_CONNS"),
+		MinConns:        envInt("DB_MIN_CONNS"),
+		ConnMaxLifetime: envDuration("DB_CONN_MAX_LIFETIME"),
+	}
+	if os.Getenv("DB_MAX_IDLE_CONNS") != "" {
+		logger.Logger.Warn("DB_MAX_IDLE_CONNS is ignored: idle connections close after 30 minutes; set DB_MIN_CONNS to keep some open")
+	}
+	roleCheck := os.Getenv("DB_ROLE_CHECK")
+	if roleCheck == "" {
+		roleCheck = "warn"
+	}
+	if roleCheck != "warn" && roleCheck != "enforce" && roleCheck != "off" {
+		logger.Logger.Fatalf("Invalid DB_ROLE_CHECK %q: must be warn, enforce, or off", roleCheck)
+	}
+	driftCheck := os.Getenv("SCHEMA_DRIFT_CHECK")
+	if driftCheck == "" {
+		driftCheck = "warn"
+	}
+	if driftCheck != "warn" && driftCheck != "enforce" && driftCheck != "off" {
+		logger.Logger.Fatalf("Invalid SCHEMA_DRIFT_CHECK %q: must be warn, enforce, or off", driftCheck)
+	}
+
+	// `user-service migrate <command>` plans, lists, applies, or rolls back migrations instead of starting the service.
+	if len(args) > 0 {
+		if args[0] != "migrate" {
+			logger.Logger.Fatalf("Unknown command %q; the only command is \"migrate\"", strings.Join(args, " "))
+		}
+		if devMode {
+			logger.Logger.Fatal("Dev mode has no database to migrate")
+		}
+		residency, err := config.LoadResidency(dbURL)
+		if err != nil {
+			logger.Logger.Fatalf("Invalid data residency configuration: %v", err)
+		}
+		runMigrateCommand(args, schemaDatabases(dbURL, residency), appName)
+		return
+	}
+
+	var (
+		userRepo         repository.UserRepository
+		userEventRepo    repository.UserEventRepository
+		loginAttemptRepo repository.LoginAttemptRepository
+		dashboardRepo    repository.DashboardRepository
+		settingsRepo     repository.SettingsRepository
+		identityRepo     repository.IdentityRepository
+		sessionRepo      repository.SessionRepository
+		consentRepo      repository.ConsentRepository
+		messagingRepo    repository.MessagingRepository
+		appointmentRepo  repository.AppointmentRepository
+		workoutRepo      repository.WorkoutAttachmentRepository
+		workoutPlanRepo  repository.WorkoutPlanRepository
+		systemEventRepo  repository.SystemEventRepository
+		auditRepo        repository.AuditRepository
+		developerAppRepo repository.DeveloperAppRepository
+		announcementRepo repository.AnnouncementRepository
+		meteringRepo     repository.MeteringRepository
+		outboxRepo       repository.OutboxRepository
+		eventRelay       repository.EventRelay // Carries WebSocket events between replicas; nil in dev mode
+		regionRouter     *repository.RegionRouter
+		healthDeps       []handlers.HealthDependency // Pinged by GET /health
+	)
+	if devMode {
+		// Dev mode keeps every table in memory: no PostgreSQL, nothing kept across restarts, and no
+		// residency, role, or schema drift checks, which are about real databases.
+		store := inmemory.NewDB(inmemory.Options{Latency: envDuration("DEV_DB_LATENCY")})
+		userRepo = inmemory.NewUserRepository(store)
+		userEventRepo = inmemory.NewUserEventRepository(store)
+		loginAttemptRepo = inmemory.NewLoginAttemptRepository(store)
+		dashboardRepo = inmemory.NewDashboardRepository(store)
+		settingsRepo = inmemory.NewSettingsRepository(store)
+		identityRepo = inmemory.NewIdentityRepository(store)
+		sessionRepo = inmemory.NewSessionRepository(store)
+		consentRepo = inmemory.NewConsentRepository(store)
+		messagingRepo = inmemory.NewMessagingRepository(store)
+		appointmentRepo = inmemory.NewAppointmentRepository(store)
+		workoutRepo = inmemory.NewWorkoutAttachmentRepository(store)
+		workoutPlanRepo = inmemory.NewWorkoutPlanRepository(store)
+		systemEventRepo = inmemory.NewSystemEventRepository(store)
+		auditRepo = inmemory.NewAuditRepository(store)
+		developerAppRepo = inmemory.NewDeveloperAppRepository(store)
+		announcementRepo = inmemory.NewAnnouncementRepository(store)
+		meteringRepo = inmemory.NewMeteringRepository(store)
+		outboxRepo = inmemory.NewOutboxRepository(store)
+		logger.Logger.Warn("Dev mode: data is kept in memory and lost when the service stops")
+	} else {
+		// With DATABASE_ADMIN_URL, the role in DATABASE_URL is created or tightened before the service
+		// connects as it: least-privilege grants, and ownership of the service's own tables.
+		if adminURL := os.Getenv("DATABASE_ADMIN_URL"); adminURL != "" {
+			admin, err := repository.NewPostgresDB(adminURL, appName+"/admin", repository.PoolConfig{})
+			if err != nil {
+				logger.Logger.Fatalf("Failed to connect to database as admin: %v", err)
+			}
+			err = repository.ProvisionServiceRole(admin, dbURL)
+			admin.Close()
+			if err != nil {
+				logger.Logger.Fatalf("Failed to provision the service database role: %v", err)
+			}
+		}
+
+		// The connection pool is shared; each repository applies the pending migrations of its tables.
+		db, err := repository.NewPostgresDB(dbURL, appName, dbPool)
+		if err != nil {
+			logger.Logger.Fatalf("Failed to connect to database: %v", err)
+		}
+		defer db.Close()
+		checkDBRole(db, "home", roleCheck)
+		healthDeps = append(healthDeps, handlers.HealthDependency{Name: "database", Critical: true, Check: db.PingContext})
+		metrics.RegisterDBPool("database", func() metrics.DBPoolStats { return repository.PoolStats(db) })
+		liveDBs := map[string]*sql.DB{"home": db} // By schemaDatabase name, for the schema drift check
+
+		// With data residency, each region has its own database holding the full schema, and every
+		// user-owned repository routes calls to the user's region. Audit and system events stay in the home database.
+		residency, err := config.LoadResidency(dbURL)
+		if err != nil {
+			logger.Logger.Fatalf("Invalid data residency configuration: %v", err)
+		}
+		if residency == nil {
+			if userRepo, err = repository.NewPostgresUserRepository(db); err != nil {
+				logger.Logger.Fatalf("Failed to initialize user repository: %v", err)
+			}
+			if userEventRepo, err = repository.NewPostgresUserEventRepository(db); err != nil {
+				logger.Logger.Fatalf("Failed to initialize user event repository: %v", err)
+			}
+			if loginAttemptRepo, err = repository.NewPostgresLoginAttemptRepository(db); err != nil {
+				logger.Logger.Fatalf("Failed to initialize login attempt repository: %v", err)
+			}
+			if dashboardRepo, err = repository.NewPostgresDashboardRepository(db); err != nil {
+				logger.Logger.Fatalf("Failed to initialize dashboard repository: %v", err)
+			}
+			if settingsRepo, err = repository.NewPostgresSettingsRepository(db); err != nil {
+				logger.Logger.Fatalf("Failed to initialize settings repository: %v", err)
+			}
+			if identityRepo, err = repository.NewPostgresIdentityRepository(db); err != nil {
+				logger.Logger.Fatalf("Failed to initialize identity repository: %v", err)
+			}
+			if sessionRepo, err = repository.NewPostgresSessionRepository(db); err != nil {
+				logger.Logger.Fatalf("Failed to initialize session repository: %v", err)
+			}
+			if consentRepo, err = repository.NewPostgresConsentRepository(db); err != nil {
+				logger.Logger.Fatalf("Failed to initialize consent repository: %v", err)
+			}
+			if messagingRepo, err = repository.NewPostgresMessagingRepository(db); err != nil {
+				logger.Logger.Fatalf("Failed to initialize messaging repository: %v", err)
+			}
+			if appointmentRepo, err = repository.NewPostgresAppointmentRepository(db); err != nil {
+				logger.Logger.Fatalf("Failed to initialize appointment repository: %v", err)
+			}
+			if workoutRepo, err = repository.NewPostgresWorkoutAttachmentRepository(db); err != nil {
+				logger.Logger.Fatalf("Failed to initialize workout attachment repository: %v", err)
+			}
+			if workoutPlanRepo, err = repository.NewPostgresWorkoutPlanRepository(db); err != nil {
+				logger.Logger.Fatalf("Failed to initialize workout plan repository: %v", err)
+			}
+			if outboxRepo, err = repository.NewPostgresOutboxRepository(db); err != nil {
+				logger.Logger.Fatalf("Failed to initialize outbox repository: %v", err)
+			}
+		} else {
+			regionDBs := map[string]*sql.DB{residency.HomeRegion: db}
+			for region, dsn := range residency.DatabaseURLs {
+				if region == residency.HomeRegion {
+					continue
+				}
+				regionDB, err := repository.NewPostgresDB(dsn, appName+"/"+region, dbPool)
+				if err != nil {
+					logger.Logger.Fatalf("Failed to connect to database of region %s: %v", region, err)
+				}
+				defer regionDB.Close()
+				checkDBRole(regionDB, "region "+region, roleCheck)
+				regionDBs[region] = regionDB
+				healthDeps = append(healthDeps, handlers.HealthDependency{Name: "database:" + region, Critical: true, Check: regionDB.PingContext})
+				metrics.RegisterDBPool("database:"+region, func() metrics.DBPoolStats { return repository.PoolStats(regionDB) })
+				liveDBs["region "+region] = regionDB
+			}
+			if regionRouter, err = repository.NewRegionRouter(residency.HomeRegion, regionDBs); err != nil {
+				logger.Logger.Fatalf("Failed to initialize region router: %v", err)
+			}
+			if userRepo, err = repository.NewRoutedUserRepository(regionRouter); err != nil {
+				logger.Logger.Fatalf("Failed to initialize user repository: %v", err)
+			}
+			if userEventRepo, err = repository.NewRoutedUserEventRepository(regionRouter); err != nil {
+				logger.Logger.Fatalf("Failed to initialize user event repository: %v", err)
+			}
+			if loginAttemptRepo, err = repository.NewRoutedLoginAttemptRepository(regionRouter); err != nil {
+				logger.Logger.Fatalf("Failed to initialize login attempt repository: %v", err)
+			}
+			if dashboardRepo, err = repository.NewRoutedDashboardRepository(regionRouter); err != nil {
+				logger.Logger.Fatalf("Failed to initialize dashboard repository: %v", err)
+			}
+			if settingsRepo, err = repository.NewRoutedSettingsRepository(regionRouter); err != nil {
+				logger.Logger.Fatalf("Failed to initialize settings repository: %v", err)
+			}
+			if identityRepo, err = repository.NewRoutedIdentityRepository(regionRouter); err != nil {
+				logger.Logger.Fatalf("Failed to initialize identity repository: %v", err)
+			}
+			if sessionRepo, err = repository.NewRoutedSessionRepository(regionRouter); err != nil {
+				logger.Logger.Fatalf("Failed to initialize session repository: %v", err)
+			}
+			if consentRepo, err = repository.NewRoutedConsentRepository(regionRouter); err != nil {
+				logger.Logger.Fatalf("Failed to initialize consent repository: %v", err)
+			}
+			if messagingRepo, err = repository.NewRoutedMessagingRepository(regionRouter); err != nil {
+				logger.Logger.Fatalf("Failed to initialize messaging repository: %v", err)
+			}
+			if appointmentRepo, err = repository.NewRoutedAppointmentRepository(regionRouter); err != nil {
+				logger.Logger.Fatalf("Failed to initialize appointment repository: %v", err)
+			}
+			if workoutRepo, err = repository.NewRoutedWorkoutAttachmentRepository(regionRouter); err != nil {
+				logger.Logger.Fatalf("Failed to initialize workout attachment repository: %v", err)
+			}
+			if workoutPlanRepo, err = repository.NewRoutedWorkoutPlanRepository(regionRouter); err != nil {
+				logger.Logger.Fatalf("Failed to initialize workout plan repository: %v", err)
+			}
+			if outboxRepo, err = repository.NewRoutedOutboxRepository(regionRouter); err != nil {
+				logger.Logger.Fatalf("Failed to initialize outbox repository: %v", err)
+			}
+			logger.Logger.Infof("Data residency enabled with regions %s (home %s)", strings.Join(regionRouter.Regions(), ", "), residency.HomeRegion)
+		}
+		systemEventRepo, err = repository.NewPostgresSystemEventRepository(db)
+		if err != nil {
+			logger.Logger.Fatalf("Failed to initialize system event repository: %v", err)
+		}
+		auditRepo, err = repository.NewPostgresAuditRepository(db)
+		if err != nil {
+			logger.Logger.Fatalf("Failed to initialize audit repository: %v", err)
+		}
+		// Developer apps are looked up by API key before their owner is known, so they stay in the home database too.
+		developerAppRepo, err = repository.NewPostgresDeveloperAppRepository(db)
+		if err != nil {
+			logger.Logger.Fatalf("Failed to initialize developer app repository: %v", err)
+		}
+		// Announcements go to users of every region, so they are kept once, in the home database.
+		announcementRepo, err = repository.NewPostgresAnnouncementRepository(db)
+		if err != nil {
+			logger.Logger.Fatalf("Failed to initialize announcement repository: %v", err)
+		}
+		// A user's WebSocket connections may be open on any replica, so their events are broadcast
+		// through the home database with LISTEN/NOTIFY.
+		eventRelay, err = repository.NewPostgresEventRelay(db, "pulse_user_events")
+		if err != nil {
+			logger.Logger.Fatalf("Failed to initialize event relay: %v", err)
+		}
+
+		// Usage metering is the source of truth for invoicing; it can live in its own database
+		// (METERING_DATABASE_URL) so it is not restored or purged along with application data.
+		meteringDB := db
+		if meteringURL := os.Getenv("METERING_DATABASE_URL"); meteringURL != "" {
+			if meteringDB, err = repository.NewPostgresDB(meteringURL, appName+"/metering", dbPool); err != nil {
+				logger.Logger.Fatalf("Failed to connect to metering database: %v", err)
+			}
+			defer meteringDB.Close()
+			checkDBRole(meteringDB, "metering", roleCheck)
+			liveDBs["metering"] = meteringDB
+			// Usage events are queued and retried, so requests are still served while it is down.
+			healthDeps = append(healthDeps, handlers.HealthDependency{Name: "database:metering", Check: meteringDB.PingContext})
+			metrics.RegisterDBPool("database:metering", func() metrics.DBPoolStats { return repository.PoolStats(meteringDB) })
+		}
+		meteringRepo, err = repository.NewPostgresMeteringRepository(meteringDB)
+		if err != nil {
+			logger.Logger.Fatalf("Failed to initialize metering repository: %v", err)
+		}
+
+		// Calls that fail for a transient reason (a serialization failure, a deadlock, a lost connection) are
+		// retried with jittered exponential backoff; DB_RETRY_ATTEMPTS=1 turns retrying off.
+		dbRetry := repository.RetryPolicy{
+			Attempts:  envInt("DB_RETRY_ATTEMPTS"),
+			BaseDelay: envDuration("DB_RETRY_BASE_DELAY"),
+			MaxDelay:  envDuration("DB_RETRY_MAX_DELAY"),
+		}
+		userRepo = repository.NewRetryingUserRepository(userRepo, dbRetry)
+		userEventRepo = repository.NewRetryingUserEventRepository(userEventRepo, dbRetry)
+		loginAttemptRepo = repository.NewRetryingLoginAttemptRepository(loginAttemptRepo, dbRetry)
+		dashboardRepo = repository.NewRetryingDashboardRepository(dashboardRepo, dbRetry)
+		settingsRepo = repository.NewRetryingSettingsRepository(settingsRepo, dbRetry)
+		identityRepo = repository.NewRetryingIdentityRepository(identityRepo, dbRetry)
+		sessionRepo = repository.NewRetryingSessionRepository(sessionRepo, dbRetry)
+		consentRepo = repository.NewRetryingConsentRepository(consentRepo, dbRetry)
+		messagingRepo = repository.NewRetryingMessagingRepository(messagingRepo, dbRetry)
+		appointmentRepo = repository.NewRetryingAppointmentRepository(appointmentRepo, dbRetry)
+		workoutRepo = repository.NewRetryingWorkoutAttachmentRepository(workoutRepo, dbRetry)
+		workoutPlanRepo = repository.NewRetryingWorkoutPlanRepository(workoutPlanRepo, dbRetry)
+		systemEventRepo = repository.NewRetryingSystemEventRepository(systemEventRepo, dbRetry)
+		auditRepo = repository.NewRetryingAuditRepository(auditRepo, dbRetry)
+		developerAppRepo = repository.NewRetryingDeveloperAppRepository(developerAppRepo, dbRetry)
+		announcementRepo = repository.NewRetryingAnnouncementRepository(announcementRepo, dbRetry)
+		meteringRepo = repository.NewRetryingMeteringRepository(meteringRepo, dbRetry)
+		outboxRepo = repository.NewRetryingOutboxRepository(outboxRepo, dbRetry)
+
+		// With every migration applied, each schema should match the one the migrations build from nothing.
+		for _, database := range schemaDatabases(dbURL, residency) {
+			checkSchemaDrift(liveDBs[database.name], database, appName, driftCheck)
+		}
 	}
-	// In a complete app, you might add a Close() method to UserRepository interface
-	// and defer userRepo.Close() here for graceful shutdown of the DB connection.
 
 	// 3. Initialize Service Implementations (concretions)
 	// Services depend on repository interfaces.
-	authService := services.NewAuthService(userRepo)
-	userService := services.NewUserService(userRepo)
+	var mail mailer.Mailer = mailer.NewLogMailer() // Swap for a real provider-backed Mailer in production
+	var emailDomains mailer.DomainChecker
+	if os.Getenv("EMAIL_MX_CHECK") == "true" {
+		emailDomains = mailer.NewMXChecker(3 * time.Second) // New emails must be at a domain that accepts mail
+	}
+	// Account erasures and merges are announced to other Pulse services through the event gateway (EVENT_WEBHOOK_URL)
+	var publisher eventbus.Publisher = eventbus.NewLogPublisher()
+	if eventURL := os.Getenv("EVENT_WEBHOOK_URL"); eventURL != "" {
+		publisher = eventbus.NewWebhookPublisher(eventURL, os.Getenv("EVENT_WEBHOOK_TOKEN"))
+		logger.Logger.Infof("Domain events are published to %s", eventURL)
+	} else {
+		logger.Logger.Warn("EVENT_WEBHOOK_URL is not set; deletion and merge events are only logged, and other services keep erased and merged-away users' data")
+	}
+	// Timeline and session events are streamed to the user's open WebSocket connections (GET /ws)
+	realtimeHub := realtime.NewHub(eventRelay)
+	go realtimeHub.Listen()
+	userEventService := services.NewUserEventService(userEventRepo, realtimeHub)
+	// Mail to addresses that bounced or complained is dropped; only re-verification codes still go out
+	emailService := services.NewEmailDeliverabilityService(userRepo, mail, userEventService)
+	mail = mailer.NewSuppressingMailer(mail, emailService)
+	identityService := services.NewIdentityService(userRepo, identityRepo, mail, userEventService)
+	authService := services.NewAuthService(userRepo, mail, emailDomains, privacyMode, userEventService, loginAttemptRepo, identityService, sessionRepo)
+	userService := services.NewUserService(userRepo, userEventService, emailDomains, publisher)
+	systemEventService := services.NewSystemEventService(systemEventRepo)
+	auditService := services.NewAuditService(auditRepo)
+	dashboardService := services.NewDashboardService(dashboardRepo)
+	settingsService := services.NewSettingsService(settingsRepo)
+	aggregationService := services.NewAggregationService(userRepo)
+	developerAppService := services.NewDeveloperAppService(developerAppRepo, userRepo, env != "production") // Debug recording only in the sandbox
+	meteringService := services.NewMeteringService(meteringRepo, userRepo)
+
+	// Mark this startup on the admin timeline
+	systemEventService.Record(ctx, models.SystemEventMigration, "Database migrations applied")
+	systemEventService.Record(ctx, models.SystemEventConfigChange, fmt.Sprintf(
+		"Service started with APP_ENV=%s, JWT_SIGNING_ALG=%s, REGISTRATION_PRIVACY_MODE=%t", env, os.Getenv("JWT_SIGNING_ALG"), privacyMode))
+
+	// Runtime config (log level, feature flags, CORS, rate limits) can be reloaded
+	// via SIGHUP or POST /admin/config/reload; every change is recorded on the timeline.
+	configReloader := config.NewReloader(os.Getenv("RUNTIME_CONFIG_PATH"), func(actor string, changed []string) {
+		_, err := systemEventService.RecordEvent(ctx, models.CreateSystemEventRequest{
+			Type:    models.SystemEventConfigChange,
+			Message: "Runtime config reloaded, changed: " + strings.Join(changed, ", "),
+		}, actor)
+		if err != nil {
+			logger.Logger.Warnf("Failed to record runtime config change: %v", err)
+		}
+	})
+	if _, err := configReloader.Reload("startup"); err != nil {
+		logger.Logger.Fatalf("Failed to load runtime config: %v", err)
+	}
+	go func() {
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		for range hup {
+			if _, err := configReloader.Reload("signal"); err != nil {
+				logger.Logger.Errorw("Runtime config reload on SIGHUP failed, keeping previous config", "error", err, "job", "config_reload")
+			}
+		}
+	}()
+
+	// Onboarding recommendations come from a data file (ONBOARDING_RULES_PATH) or the built-in ruleset.
+	onboardingRules, err := config.LoadOnboardingRules(os.Getenv("ONBOARDING_RULES_PATH"))
+	if err != nil {
+		logger.Logger.Fatalf("Failed to load onboarding rules: %v", err)
+	}
+	logger.Logger.Infof("Onboarding ruleset %s loaded with %d rules", onboardingRules.Version, len(onboardingRules.Rules))
+	onboardingService := services.NewOnboardingService(onboardingRules, userRepo, userEventService)
+	quickLogService := services.NewQuickLogService()
+
+	// Third-party integrations users must consent to before linking, from a data file (INTEGRATIONS_PATH) or the built-in catalog.
+	integrations, err := config.LoadIntegrations(os.Getenv("INTEGRATIONS_PATH"))
+	if err != nil {
+		logger.Logger.Fatalf("Failed to load integration catalog: %v", err)
+	}
+	logger.Logger.Infof("Integration catalog loaded with %d integrations", len(integrations.Integrations))
+	consentService := services.NewConsentService(integrations, consentRepo, userEventService)
+
+	// Coach messaging keeps attachments in a blob store (BLOB_STORE_DIR) and sends push notifications
+	// through a webhook to the push gateway (PUSH_WEBHOOK_URL), or only logs them when none is set.
+	blobDir := os.Getenv("BLOB_STORE_DIR")
+	if blobDir == "" {
+		blobDir = "data/blobs"
+	}
+	hotBlobs, err := blobstore.NewDirStore(blobDir)
+	if err != nil {
+		logger.Logger.Fatalf("Failed to initialize blob store: %v", err)
+	}
+	// Lifecycle rules (blob_storage in the runtime config) move old files to BLOB_COLD_DIR, on cheaper
+	// storage, and delete expired ones; without it files stay where they are until they expire.
+	var coldBlobs *blobstore.DirStore
+	if coldDir := os.Getenv("BLOB_COLD_DIR"); coldDir != "" {
+		if coldBlobs, err = blobstore.NewDirStore(coldDir); err != nil {
+			logger.Logger.Fatalf("Failed to initialize cold blob store: %v", err)
+		}
+	}
+	tieredBlobs := blobstore.NewTieredStore(hotBlobs, coldBlobs)
+	blobMaintenanceService := services.NewBlobMaintenanceService(tieredBlobs)
+	// With BLOB_ENCRYPTION_KEYS, files are encrypted before they reach either tier (and any backup of them).
+	var blobs blobstore.Store = tieredBlobs
+	if keySpec := os.Getenv("BLOB_ENCRYPTION_KEYS"); keySpec != "" {
+		keys, err := blobstore.ParseEncryptionKeys(keySpec)
+		if err != nil {
+			logger.Logger.Fatalf("Invalid BLOB_ENCRYPTION_KEYS: %v", err)
+		}
+		if blobs, err = blobstore.NewEncryptedStore(tieredBlobs, keys); err != nil {
+			logger.Logger.Fatalf("Failed to initialize blob encryption: %v", err)
+		}
+		logger.Logger.Infof("Blob store encrypts new files with key %s", keys[0].ID)
+	} else if env == "production" {
+		logger.Logger.Warn("BLOB_ENCRYPTION_KEYS is not set: attachments are stored unencrypted")
+	}
+	var notifier push.Notifier = push.NewLogNotifier()
+	if pushURL := os.Getenv("PUSH_WEBHOOK_URL"); pushURL != "" {
+		notifier = push.NewWebhookNotifier(pushURL, os.Getenv("PUSH_WEBHOOK_TOKEN"))
+		logger.Logger.Infof("Push notifications are sent to %s", pushURL)
+	}
+	messagingService := services.NewMessagingService(messagingRepo, userRepo, blobs, notifier, userEventService)
+	appointmentService := services.NewAppointmentService(appointmentRepo, userRepo, mail, notifier, userEventService)
+	announcementService := services.NewAnnouncementService(announcementRepo, userRepo, notifier)
+
+	// Workout attachments share the blob store, and are virus scanned by clamd (CLAMD_ADDR) before they can be downloaded
+	var scanner virusscan.Scanner
+	if clamdAddr := os.Getenv("CLAMD_ADDR"); clamdAddr != "" {
+		scanner = virusscan.NewClamdScanner(clamdAddr)
+		logger.Logger.Infof("Workout attachments are virus scanned by clamd at %s", clamdAddr)
+	} else {
+		logger.Logger.Warn("CLAMD_ADDR is not set; workout attachments are not virus scanned")
+	}
+	workoutAttachmentService := services.NewWorkoutAttachmentService(workoutRepo, messagingRepo, blobs, scanner)
+	workoutPlanService := services.NewWorkoutPlanService(workoutPlanRepo, userRepo)
+
+	outboxService := services.NewOutboxService(outboxRepo, publisher)
+	fieldSealingService := services.NewFieldSealingService(userRepo)
+	accountDeletionService := services.NewAccountDeletionService(userRepo, auditRepo, developerAppRepo, blobs, publisher, userEventService)
+
+	// GET /me/data-summary adds what other Pulse services store, asked through their internal APIs (DATA_SUMMARY_SOURCES)
+	dataSummarySources, err := datasummary.ParseSources(os.Getenv("DATA_SUMMARY_SOURCES"), os.Getenv("DATA_SUMMARY_TOKEN"))
+	if err != nil {
+		logger.Logger.Fatalf("Failed to configure data summary sources: %v", err)
+	}
+	if len(dataSummarySources) == 0 {
+		logger.Logger.Warn("DATA_SUMMARY_SOURCES is not set; data summaries only cover what the user-service stores")
+	}
+	dataSummaryService := services.NewDataSummaryService(userRepo, dataSummarySources)
+	rolloutService := services.NewRolloutService(userRepo)
 
 	// 4. Initialize Handler Implementations (concretions)
 	// Handlers depend on service interfaces.
-	authHandlers := handlers.NewAuthHandlers(authService)
-	userHandlers := handlers.NewUserHandler(userService)
+	// X-Forwarded-For is only trusted when the service runs behind proxies that set it, and only the
+	// entries they appended; it decides the client IP for both rate limiting and the audit log.
+	proxyHops := envInt("TRUSTED_PROXY_HOPS")
+	if proxyHops == 0 && os.Getenv("TRUST_PROXY_HEADERS") == "true" {
+		proxyHops = 1
+	}
+	auditor := handlers.NewAuditor(auditService, proxyHops)
+
+	// Optional CAPTCHA on /login and /register; which endpoints require it is set by captcha_required
+	// in the runtime config, so it can be switched on during an attack without a restart.
+	var captchaVerifier captcha.Verifier
+	if provider := os.Getenv("CAPTCHA_PROVIDER"); provider != "" {
+		minScore, _ := strconv.ParseFloat(os.Getenv("CAPTCHA_MIN_SCORE"), 64)
+		captchaVerifier, err = captcha.New(captcha.Config{
+			Provider:  provider,
+			Secret:    os.Getenv("CAPTCHA_SECRET_KEY"),
+			MinScore:  minScore,
+			VerifyURL: os.Getenv("CAPTCHA_VERIFY_URL"),
+		})
+		if err != nil {
+			logger.Logger.Fatalf("Failed to configure CAPTCHA: %v", err)
+		}
+		logger.Logger.Infof("CAPTCHA verification configured with provider %s", provider)
+	} else if len(config.Current().CaptchaRequired) > 0 {
+		logger.Logger.Warn("captcha_required is set but CAPTCHA_PROVIDER is not; CAPTCHA tokens will not be checked.")
+	}
+	authHandlers := handlers.NewAuthHandlers(authService, auditor, captchaVerifier)
+	userHandlers := handlers.NewUserHandler(userService, userEventService, auditor)
+	dashboardHandlers := handlers.NewDashboardHandler(dashboardService)
+	settingsHandlers := handlers.NewSettingsHandler(settingsService)
+	aggregationHandlers := handlers.NewAggregationHandler(aggregationService)
+	onboardingHandlers := handlers.NewOnboardingHandler(onboardingService)
+	quickLogHandlers := handlers.NewQuickLogHandler(quickLogService)
+	identityHandlers := handlers.NewIdentityHandler(identityService, authService, auditor)
+	consentHandlers := handlers.NewConsentHandler(consentService, auditor)
+	messagingHandlers := handlers.NewMessagingHandler(messagingService, auditor)
+	appointmentHandlers := handlers.NewAppointmentHandler(appointmentService)
+	workoutAttachmentHandlers := handlers.NewWorkoutAttachmentHandler(workoutAttachmentService)
+	workoutPlanHandlers := handlers.NewWorkoutPlanHandler(workoutPlanService)
+	accountDeletionHandlers := handlers.NewAccountDeletionHandler(accountDeletionService, auditor)
+	dataSummaryHandlers := handlers.NewDataSummaryHandler(dataSummaryService)
+	realtimeHandlers := handlers.NewRealtimeHandler(realtimeHub, userEventService)
+	graphqlHandlers, err := handlers.NewGraphQLHandler(userService, authService)
+	if err != nil {
+		logger.Logger.Fatalf("Failed to build GraphQL schema: %v", err)
+	}
+	// Soft-launched features are served only to the users their rollout admits; countries come from
+	// a header the edge proxy sets (GEO_COUNTRY_HEADER), which clients must not be able to set themselves.
+	rolloutGate := handlers.NewRolloutGate(rolloutService, os.Getenv("GEO_COUNTRY_HEADER"))
+	adminHandlers := handlers.NewAdminHandler(systemEventService, userService, configReloader, auditor)
+	meteringHandlers := handlers.NewMeteringHandler(meteringService)
+	developerAppHandlers := handlers.NewDeveloperAppHandler(developerAppService, auditor)
+	announcementHandlers := handlers.NewAnnouncementHandler(announcementService, auditor)
+	var residencyHandlers *handlers.ResidencyHandler
+	if regionRouter != nil {
+		residencyService := services.NewResidencyService(userRepo, regionRouter, userEventService)
+		residencyHandlers = handlers.NewResidencyHandler(residencyService, auditor)
+	}
+
+	// Optional enterprise SSO through any OpenID Connect provider (Okta, Keycloak, Azure AD, ...)
+	var oidcHandlers *handlers.OIDCHandlers
+	if issuer := os.Getenv("OIDC_ISSUER_URL"); issuer != "" {
+		provider, err := oidc.NewProvider(ctx, oidc.Config{
+			IssuerURL:    issuer,
+			ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+			ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+			RedirectURL:  os.Getenv("OIDC_REDIRECT_URL"),
+			Scopes:       strings.Fields(os.Getenv("OIDC_SCOPES")),
+		})
+		if err != nil {
+			logger.Logger.Fatalf("Failed to configure OIDC provider: %v", err)
+		}
+		oidcHandlers = handlers.NewOIDCHandlers(provider, authService, auditor, meteringService)
+	}
+
+	// Optional enterprise SSO through a SAML 2.0 identity provider, configured from its metadata
+	var samlHandlers *handlers.SAMLHandlers
+	if metadataURL := os.Getenv("SAML_IDP_METADATA_URL"); metadataURL != "" {
+		sp, err := saml.NewServiceProvider(ctx, saml.Config{
+			EntityID:       os.Getenv("SAML_ENTITY_ID"),
+			ACSURL:         os.Getenv("SAML_ACS_URL"),
+			IdPMetadataURL: metadataURL,
+			EmailAttribute: os.Getenv("SAML_EMAIL_ATTRIBUTE"),
+			NameAttribute:  os.Getenv("SAML_NAME_ATTRIBUTE"),
+		}, identityRepo) // Pending requests are shared by every replica
+		if err != nil {
+			logger.Logger.Fatalf("Failed to configure SAML service provider: %v", err)
+		}
+		samlHandlers = handlers.NewSAMLHandlers(sp, authService, auditor, meteringService)
+	}
 
 	// 5. Setup HTTP Router (using net/http's ServeMux with Go 1.22+ patterns)
 	mux := http.NewServeMux()
 
-	// Public Authentication Routes
-	mux.HandleFunc("POST /register", authHandlers.Register)
-	mux.HandleFunc("POST /login", authHandlers.Login)
+	// Per-IP rate limits (RATE_LIMIT_* env vars, overridable in the runtime config).
+	authRateLimit := handlers.RateLimit("auth", func() config.RateLimit { return config.Current().RateLimits.Auth }, proxyHops)
+	handleRateLimit := handlers.RateLimit("handles", func() config.RateLimit { return config.Current().RateLimits.Handles }, proxyHops)
+
+	// Public Authentication Routes (credential endpoints share one rate limit to slow credential stuffing)
+	mux.Handle("POST /register", authRateLimit(http.HandlerFunc(authHandlers.Register)))
+	mux.Handle("POST /login", authRateLimit(http.HandlerFunc(authHandlers.Login)))
+	mux.Handle("POST /auth/forgot-password", authRateLimit(http.HandlerFunc(authHandlers.ForgotPassword))) // Also stops mailing an address over and over
+	mux.Handle("POST /auth/reset-password", authRateLimit(http.HandlerFunc(authHandlers.ResetPassword)))   // Also slows guessing reset codes
+	mux.Handle("POST /auth/link", authRateLimit(http.HandlerFunc(identityHandlers.Link)))
+	mux.Handle("GET /handles/availability", handleRateLimit(http.HandlerFunc(userHandlers.CheckHandleAvailability)))
+	if oidcHandlers != nil {
+		mux.HandleFunc("GET /auth/oidc/login", oidcHandlers.Login)
+		mux.HandleFunc("GET /auth/oidc/callback", oidcHandlers.Callback)
+	}
+	if samlHandlers != nil {
+		mux.HandleFunc("GET /auth/saml/metadata", samlHandlers.Metadata)
+		mux.HandleFunc("GET /auth/saml/login", samlHandlers.Login)
+		mux.HandleFunc("POST /auth/saml/acs", samlHandlers.ACS)
+	}
 
 	// Protected Authentication Routes (require JWT authentication middleware)
-	mux.Handle("GET /protected", handlers.AuthMiddleware(http.HandlerFunc(authHandlers.ProtectedRoute)))
-	mux.Handle("POST /logout", handlers.AuthMiddleware(http.HandlerFunc(authHandlers.Logout)))
+	mux.Handle("GET /protected", authHandlers.AuthMiddleware(http.HandlerFunc(authHandlers.ProtectedRoute)))
+	mux.Handle("POST /logout", authHandlers.AuthMiddleware(http.HandlerFunc(authHandlers.Logout)))
+	mux.Handle("POST /me/deactivate", authHandlers.AuthMiddleware(http.HandlerFunc(userHandlers.DeactivateAccount)))
+	mux.Handle("GET /me/identities", authHandlers.AuthMiddleware(http.HandlerFunc(identityHandlers.ListIdentities)))
+	mux.Handle("GET /me/integrations/consents", authHandlers.AuthMiddleware(http.HandlerFunc(consentHandlers.ListConsents)))
+	mux.Handle("PUT /me/integrations/{provider}/consent", authHandlers.AuthMiddleware(http.HandlerFunc(consentHandlers.GrantConsent)))
+	mux.Handle("DELETE /me/integrations/{provider}/consent", authHandlers.AuthMiddleware(http.HandlerFunc(consentHandlers.RevokeConsent)))
+	mux.Handle("GET /me/coaches", authHandlers.AuthMiddleware(http.HandlerFunc(messagingHandlers.ListCoaches)))
+	mux.Handle("PUT /me/coaches/{coach_id}", authHandlers.AuthMiddleware(http.HandlerFunc(messagingHandlers.AuthorizeCoach)))
+	mux.Handle("DELETE /me/coaches/{coach_id}", authHandlers.AuthMiddleware(http.HandlerFunc(messagingHandlers.RevokeCoach)))
+	mux.Handle("GET /me/messages/export", authHandlers.AuthMiddleware(http.HandlerFunc(messagingHandlers.Export)))
+	mux.Handle("GET /me/timeline", authHandlers.AuthMiddleware(http.HandlerFunc(userHandlers.GetTimeline)))
+	mux.Handle("GET /ws", authHandlers.AuthMiddleware(http.HandlerFunc(realtimeHandlers.Stream)))
+	mux.Handle("GET /users/me/events", authHandlers.AuthMiddleware(http.HandlerFunc(realtimeHandlers.Events)))
+	mux.Handle("GET /me/features", authHandlers.AuthMiddleware(http.HandlerFunc(rolloutGate.GetFeatures)))
+	mux.Handle("GET /me/data-summary", authHandlers.AuthMiddleware(http.HandlerFunc(dataSummaryHandlers.GetDataSummary)))
+	mux.Handle("GET /me/profile-prompts", authHandlers.AuthMiddleware(http.HandlerFunc(userHandlers.GetProfilePrompts)))
+	mux.Handle("POST /me/profile-prompts/{field}/dismiss", authHandlers.AuthMiddleware(http.HandlerFunc(userHandlers.DismissProfilePrompt)))
+	mux.Handle("POST /onboarding/recommendations", authHandlers.AuthMiddleware(http.HandlerFunc(onboardingHandlers.Recommend)))
+	mux.Handle("POST /quicklog", authHandlers.AuthMiddleware(rolloutGate.Require("quick-log")(http.HandlerFunc(quickLogHandlers.Parse))))
+	mux.Handle("GET /me/dashboard", authHandlers.AuthMiddleware(http.HandlerFunc(dashboardHandlers.GetLayout)))
+	mux.Handle("PUT /me/dashboard", authHandlers.AuthMiddleware(http.HandlerFunc(dashboardHandlers.SaveLayout)))
+	mux.Handle("DELETE /me/dashboard", authHandlers.AuthMiddleware(http.HandlerFunc(dashboardHandlers.ResetLayout)))
+	mux.Handle("GET /me/aggregation-periods", authHandlers.AuthMiddleware(http.HandlerFunc(aggregationHandlers.ListPeriods)))
+	mux.Handle("POST /me/aggregation-periods", authHandlers.AuthMiddleware(http.HandlerFunc(aggregationHandlers.CreatePeriod)))
+	mux.Handle("PUT /me/aggregation-periods/{id}", authHandlers.AuthMiddleware(http.HandlerFunc(aggregationHandlers.UpdatePeriod)))
+	mux.Handle("DELETE /me/aggregation-periods/{id}", authHandlers.AuthMiddleware(http.HandlerFunc(aggregationHandlers.DeletePeriod)))
+
+	// Coach Messaging Routes (Protected; threads are only visible to their user and authorized coach)
+	mux.Handle("GET /coach/clients", authHandlers.AuthMiddleware(http.HandlerFunc(messagingHandlers.ListClients)))
+	mux.Handle("GET /threads", authHandlers.AuthMiddleware(http.HandlerFunc(messagingHandlers.ListThreads)))
+	mux.Handle("POST /threads", authHandlers.AuthMiddleware(http.HandlerFunc(messagingHandlers.CreateThread)))
+	mux.Handle("GET /threads/{id}/messages", authHandlers.AuthMiddleware(http.HandlerFunc(messagingHandlers.ListMessages)))
+	mux.Handle("POST /threads/{id}/messages", authHandlers.AuthMiddleware(http.HandlerFunc(messagingHandlers.SendMessage)))
+	mux.Handle("POST /threads/{id}/read", authHandlers.AuthMiddleware(http.HandlerFunc(messagingHandlers.MarkRead)))
+	mux.Handle("POST /threads/{id}/attachments", authHandlers.AuthMiddleware(http.HandlerFunc(messagingHandlers.UploadAttachment)))
+	mux.Handle("GET /threads/{id}/attachments/{attachment_id}", authHandlers.AuthMiddleware(http.HandlerFunc(messagingHandlers.GetAttachment)))
+
+	// Workout Attachment Routes (Protected; coaches only see what their authorizing clients share)
+	mux.Handle("POST /me/workout-attachments", authHandlers.AuthMiddleware(rolloutGate.Require("workout-attachments")(http.HandlerFunc(workoutAttachmentHandlers.Upload))))
+	mux.Handle("GET /me/workout-attachments", authHandlers.AuthMiddleware(rolloutGate.Require("workout-attachments")(http.HandlerFunc(workoutAttachmentHandlers.List))))
+	mux.Handle("PATCH /me/workout-attachments/{id}", authHandlers.AuthMiddleware(rolloutGate.Require("workout-attachments")(http.HandlerFunc(workoutAttachmentHandlers.Update))))
+	mux.Handle("DELETE /me/workout-attachments/{id}", authHandlers.AuthMiddleware(rolloutGate.Require("workout-attachments")(http.HandlerFunc(workoutAttachmentHandlers.Delete))))
+	mux.Handle("GET /me/workout-attachments/{id}/content", authHandlers.AuthMiddleware(rolloutGate.Require("workout-attachments")(http.HandlerFunc(workoutAttachmentHandlers.Download))))
+	mux.Handle("GET /coach/clients/{id}/workout-attachments", authHandlers.AuthMiddleware(http.HandlerFunc(workoutAttachmentHandlers.ListForCoach)))
+	mux.Handle("GET /coach/clients/{id}/workout-attachments/{attachment_id}/content", authHandlers.AuthMiddleware(http.HandlerFunc(workoutAttachmentHandlers.DownloadForCoach)))
+
+	// Workout Plan Routes (Protected); writing and sharing plans needs the plans:author scope of coaches
+	mux.Handle("POST /coach/plans", authHandlers.AuthMiddleware(handlers.RequireScope(models.ScopePlans)(http.HandlerFunc(workoutPlanHandlers.Create))))
+	mux.Handle("GET /coach/plans", authHandlers.AuthMiddleware(handlers.RequireScope(models.ScopePlans)(http.HandlerFunc(workoutPlanHandlers.List))))
+	mux.Handle("GET /coach/plans/export", authHandlers.AuthMiddleware(handlers.RequireScope(models.ScopePlans)(http.HandlerFunc(workoutPlanHandlers.Export))))
+	mux.Handle("POST /coach/plans/import", authHandlers.AuthMiddleware(handlers.RequireScope(models.ScopePlans)(http.HandlerFunc(workoutPlanHandlers.Import))))
+	mux.Handle("GET /coach/plans/{id}", authHandlers.AuthMiddleware(handlers.RequireScope(models.ScopePlans)(http.HandlerFunc(workoutPlanHandlers.Get))))
+	mux.Handle("DELETE /coach/plans/{id}", authHandlers.AuthMiddleware(handlers.RequireScope(models.ScopePlans)(http.HandlerFunc(workoutPlanHandlers.Delete))))
+
+	// Appointment Routes (Protected); publishing availability needs the appointments:provide scope of coaches and clinicians
+	mux.Handle("POST /provider/availability", authHandlers.AuthMiddleware(handlers.RequireScope(models.ScopeAppointments)(http.HandlerFunc(appointmentHandlers.PublishAvailability))))
+	mux.Handle("GET /provider/slots", authHandlers.AuthMiddleware(handlers.RequireScope(models.ScopeAppointments)(http.HandlerFunc(appointmentHandlers.ListOwnSlots))))
+	mux.Handle("DELETE /provider/slots/{id}", authHandlers.AuthMiddleware(handlers.RequireScope(models.ScopeAppointments)(http.HandlerFunc(appointmentHandlers.DeleteSlot))))
+	mux.Handle("GET /provider/appointments", authHandlers.AuthMiddleware(handlers.RequireScope(models.ScopeAppointments)(http.HandlerFunc(appointmentHandlers.ListProviderAppointments))))
+	mux.Handle("GET /providers/{id}/slots", authHandlers.AuthMiddleware(http.HandlerFunc(appointmentHandlers.ListOpenSlots)))
+	mux.Handle("GET /me/appointments", authHandlers.AuthMiddleware(http.HandlerFunc(appointmentHandlers.ListMyAppointments)))
+	mux.Handle("POST /appointments", authHandlers.AuthMiddleware(http.HandlerFunc(appointmentHandlers.Book)))
+	mux.Handle("GET /appointments/{id}", authHandlers.AuthMiddleware(http.HandlerFunc(appointmentHandlers.GetAppointment)))
+	mux.Handle("POST /appointments/{id}/cancel", authHandlers.AuthMiddleware(http.HandlerFunc(appointmentHandlers.Cancel)))
+	mux.Handle("POST /appointments/{id}/reschedule", authHandlers.AuthMiddleware(http.HandlerFunc(appointmentHandlers.Reschedule)))
+	mux.Handle("GET /appointments/{id}/invite.ics", authHandlers.AuthMiddleware(http.HandlerFunc(appointmentHandlers.Invite)))
 
 	// User Management Routes (Protected)
 	// Using the new Go 1.22+ pattern matching for path parameters
-	mux.Handle("GET /users", handlers.AuthMiddleware(http.HandlerFunc(userHandlers.UsersCollectionHandler)))
-	mux.Handle("POST /users", handlers.AuthMiddleware(http.HandlerFunc(userHandlers.UsersCollectionHandler)))
-	mux.Handle("GET /users/{id}", handlers.AuthMiddleware(http.HandlerFunc(userHandlers.UserItemHandler)))
-	mux.Handle("PUT /users/{id}", handlers.AuthMiddleware(http.HandlerFunc(userHandlers.UserItemHandler)))
-	mux.Handle("DELETE /users/{id}", handlers.AuthMiddleware(http.HandlerFunc(userHandlers.UserItemHandler)))
-	mux.Handle("GET /users/by-email", handlers.AuthMiddleware(http.HandlerFunc(userHandlers.GetUserByEmailHandler)))
+	// Collection routes need users:* scopes; item routes allow self-access and check scopes otherwise.
+	mux.Handle("GET /users", authHandlers.AuthMiddleware(handlers.RequireScope(models.ScopeUsersRead)(http.HandlerFunc(userHandlers.UsersCollectionHandler))))
+	mux.Handle("POST /users", authHandlers.AuthMiddleware(handlers.RequireScope(models.ScopeUsersWrite)(http.HandlerFunc(userHandlers.UsersCollectionHandler))))
+	mux.Handle("POST /users/bulk", authHandlers.AuthMiddleware(handlers.RequireScope(models.ScopeUsersWrite)(http.HandlerFunc(userHandlers.BulkUsers))))
+	mux.Handle("GET /users/{id}", authHandlers.AuthMiddleware(http.HandlerFunc(userHandlers.UserItemHandler)))
+	mux.Handle("PUT /users/{id}", authHandlers.AuthMiddleware(http.HandlerFunc(userHandlers.UserItemHandler)))
+	mux.Handle("DELETE /users/{id}", authHandlers.AuthMiddleware(http.HandlerFunc(userHandlers.UserItemHandler)))
+	mux.Handle("GET /users/{id}/{view}", handlers.UserViews(map[string]http.Handler{
+		"timezone-history":    authHandlers.AuthMiddleware(http.HandlerFunc(userHandlers.GetTimezoneHistory)),
+		"aggregation-windows": authHandlers.AuthMiddleware(http.HandlerFunc(aggregationHandlers.GetWindows)),
+		"metadata":            authHandlers.AuthMiddleware(http.HandlerFunc(userHandlers.GetMetadata)),
+	}))
+	mux.Handle("PATCH /users/{id}/metadata", authHandlers.AuthMiddleware(handlers.RequireScope(models.ScopeUsersWrite)(http.HandlerFunc(userHandlers.PatchMetadata))))
+	mux.Handle("GET /users/me/settings", authHandlers.AuthMiddleware(http.HandlerFunc(settingsHandlers.GetSettings)))
+	mux.Handle("PUT /users/me/settings", authHandlers.AuthMiddleware(http.HandlerFunc(settingsHandlers.UpdateSettings)))
+	mux.Handle("GET /users/me/logins", authHandlers.AuthMiddleware(http.HandlerFunc(authHandlers.GetLoginHistory)))
+	mux.Handle("GET /users/me/onboarding", authHandlers.AuthMiddleware(http.HandlerFunc(onboardingHandlers.GetState)))
+	mux.Handle("POST /users/me/onboarding", authHandlers.AuthMiddleware(http.HandlerFunc(onboardingHandlers.Advance)))
+	mux.Handle("POST /users/me/delete-account", authHandlers.AuthMiddleware(http.HandlerFunc(accountDeletionHandlers.DeleteAccount)))
+	mux.Handle("GET /users/by-email", authHandlers.AuthMiddleware(handlers.RequireScope(models.ScopeUsersRead)(http.HandlerFunc(userHandlers.GetUserByEmailHandler))))
+	mux.Handle("GET /users/search", authHandlers.AuthMiddleware(handlers.RequireScope(models.ScopeUsersRead)(http.HandlerFunc(userHandlers.SearchUsers))))
+	mux.Handle("GET /users/by-username/{handle}", authHandlers.AuthMiddleware(handlers.RequireScope(models.ScopeUsersRead)(http.HandlerFunc(userHandlers.GetUserByUsername))))
+
+	// GraphQL Routes; the query endpoint applies the REST routes' access rules field by field
+	mux.Handle("POST /graphql", authHandlers.AuthMiddleware(http.HandlerFunc(graphqlHandlers.Query)))
+	mux.HandleFunc("GET /graphql/schema", graphqlHandlers.Schema)
+
+	// Developer Portal Routes (Protected); apps registered here call the public API with their key
+	mux.Handle("GET /developer/apps", authHandlers.AuthMiddleware(http.HandlerFunc(developerAppHandlers.ListApps)))
+	mux.Handle("POST /developer/apps", authHandlers.AuthMiddleware(http.HandlerFunc(developerAppHandlers.CreateApp)))
+	mux.Handle("POST /developer/apps/{id}/rotate-key", authHandlers.AuthMiddleware(http.HandlerFunc(developerAppHandlers.RotateKey)))
+	mux.Handle("POST /developer/apps/{id}/revoke", authHandlers.AuthMiddleware(http.HandlerFunc(developerAppHandlers.RevokeApp)))
+	mux.Handle("GET /developer/apps/{id}/usage", authHandlers.AuthMiddleware(http.HandlerFunc(developerAppHandlers.GetUsage)))
+	mux.Handle("POST /developer/apps/{id}/debug", authHandlers.AuthMiddleware(http.HandlerFunc(developerAppHandlers.StartDebug)))
+	mux.Handle("DELETE /developer/apps/{id}/debug", authHandlers.AuthMiddleware(http.HandlerFunc(developerAppHandlers.StopDebug)))
+	mux.Handle("GET /developer/apps/{id}/debug/har", authHandlers.AuthMiddleware(http.HandlerFunc(developerAppHandlers.ExportHAR)))
+
+	// Public API Routes (API key only, read-only, per-app rate limit and daily quota). They reuse the
+	// regular handlers; an app acts as its owner with no scopes. With PUBLIC_API_HOST set they only
+	// match requests for that host.
+	publicAPI := handlers.PublicAPI(developerAppService)
+	publicHost := os.Getenv("PUBLIC_API_HOST")
+	publicRoute := func(method, path string) string { return method + " " + publicHost + handlers.PublicAPIPrefix + path }
+	publicSpec, err := handlers.PublicAPISpec(api.OpenAPISpec)
+	if err != nil {
+		logger.Logger.Fatalf("Failed to load public API spec: %v", err)
+	}
+	mux.HandleFunc(publicRoute("GET", "/openapi.json"), publicSpec)
+	mux.Handle(publicRoute("GET", "/users/{id}"), publicAPI(http.HandlerFunc(userHandlers.UserItemHandler)))
+	mux.Handle(publicRoute("GET", "/users/{id}/timezone-history"), publicAPI(http.HandlerFunc(userHandlers.GetTimezoneHistory)))
+	mux.Handle(publicRoute("GET", "/users/{id}/aggregation-windows"), publicAPI(http.HandlerFunc(aggregationHandlers.GetWindows)))
+	mux.Handle(publicRoute("GET", "/me/aggregation-periods"), publicAPI(http.HandlerFunc(aggregationHandlers.ListPeriods)))
+	mux.Handle(publicRoute("GET", "/integrations"), publicAPI(http.HandlerFunc(consentHandlers.ListIntegrations)))
+
+	// Admin Routes (Protected, admin role required)
+	mux.Handle("GET /admin/timeline", authHandlers.AuthMiddleware(handlers.RequireAdmin(http.HandlerFunc(adminHandlers.GetTimeline))))
+	mux.Handle("POST /admin/timeline", authHandlers.AuthMiddleware(handlers.RequireAdmin(http.HandlerFunc(adminHandlers.CreateTimelineEvent))))
+	mux.Handle("GET /admin/users", authHandlers.AuthMiddleware(handlers.RequireAdmin(http.HandlerFunc(adminHandlers.ListUsers))))
+	mux.Handle("POST /admin/users/merge", authHandlers.AuthMiddleware(handlers.RequireAdmin(http.HandlerFunc(adminHandlers.MergeUsers))))
+	mux.Handle("POST /admin/users/merges/{id}/undo", authHandlers.AuthMiddleware(handlers.RequireAdmin(http.HandlerFunc(adminHandlers.UndoUserMerge))))
+	mux.Handle("POST /admin/users/{id}/suspend", authHandlers.AuthMiddleware(handlers.RequireAdmin(http.HandlerFunc(adminHandlers.SuspendUser))))
+	mux.Handle("POST /admin/users/{id}/reactivate", authHandlers.AuthMiddleware(handlers.RequireAdmin(http.HandlerFunc(adminHandlers.ReactivateUser))))
+	mux.Handle("GET /admin/config", authHandlers.AuthMiddleware(handlers.RequireAdmin(http.HandlerFunc(adminHandlers.GetConfig))))
+	mux.Handle("POST /admin/config/reload", authHandlers.AuthMiddleware(handlers.RequireAdmin(http.HandlerFunc(adminHandlers.ReloadConfig))))
+	mux.Handle("GET /admin/audit-events", authHandlers.AuthMiddleware(handlers.RequireAdmin(http.HandlerFunc(adminHandlers.ListAuditEvents))))
+	mux.Handle("GET /admin/slo", authHandlers.AuthMiddleware(handlers.RequireAdmin(http.HandlerFunc(adminHandlers.GetSLO))))
+	mux.Handle("GET /admin/users/{id}/integrations/{provider}/consent", authHandlers.AuthMiddleware(handlers.RequireAdmin(http.HandlerFunc(consentHandlers.CheckConsent))))
+	mux.Handle("GET /admin/integrations/revocations", authHandlers.AuthMiddleware(handlers.RequireAdmin(http.HandlerFunc(consentHandlers.ListRevocations))))
+	mux.Handle("GET /admin/onboarding/funnel", authHandlers.AuthMiddleware(handlers.RequireAdmin(http.HandlerFunc(onboardingHandlers.GetFunnel))))
+	mux.Handle("GET /admin/metering/reconciliation", authHandlers.AuthMiddleware(handlers.RequireAdmin(http.HandlerFunc(meteringHandlers.Reconciliation))))
+	mux.Handle("POST /admin/announcements/preview", authHandlers.AuthMiddleware(handlers.RequireAdmin(http.HandlerFunc(announcementHandlers.PreviewAnnouncement))))
+	mux.Handle("POST /admin/announcements", authHandlers.AuthMiddleware(handlers.RequireAdmin(http.HandlerFunc(announcementHandlers.CreateAnnouncement))))
+	mux.Handle("GET /admin/announcements", authHandlers.AuthMiddleware(handlers.RequireAdmin(http.HandlerFunc(announcementHandlers.ListAnnouncements))))
+	mux.Handle("GET /admin/announcements/{id}", authHandlers.AuthMiddleware(handlers.RequireAdmin(http.HandlerFunc(announcementHandlers.GetAnnouncement))))
+	mux.Handle("POST /admin/announcements/{id}/cancel", authHandlers.AuthMiddleware(handlers.RequireAdmin(http.HandlerFunc(announcementHandlers.CancelAnnouncement))))
+	if residencyHandlers != nil {
+		mux.Handle("POST /admin/users/{id}/region", authHandlers.AuthMiddleware(handlers.RequireAdmin(http.HandlerFunc(residencyHandlers.MoveUser))))
+		mux.Handle("GET /admin/residency/violations", authHandlers.AuthMiddleware(handlers.RequireAdmin(http.HandlerFunc(residencyHandlers.Violations))))
+	}
+
+	// Email re-verification after a bounce or complaint, and the mail provider's webhooks, authenticated
+	// with EMAIL_WEBHOOK_TOKEN; the webhooks are off when it is unset
+	emailHandlers := handlers.NewEmailHandler(emailService, os.Getenv("EMAIL_WEBHOOK_TOKEN"))
+	mux.Handle("POST /me/email/verification", authRateLimit(authHandlers.AuthMiddleware(http.HandlerFunc(emailHandlers.RequestVerification))))
+	mux.Handle("POST /me/email/verification/confirm", authRateLimit(authHandlers.AuthMiddleware(http.HandlerFunc(emailHandlers.ConfirmVerification))))
+	if os.Getenv("EMAIL_WEBHOOK_TOKEN") != "" {
+		mux.HandleFunc("POST /webhooks/email/{provider}", emailHandlers.Webhook)
+	} else {
+		logger.Logger.Warn("EMAIL_WEBHOOK_TOKEN is not set; bounces and complaints are not received, and mail keeps going to dead addresses")
+	}
+
+	// Synthetic journey for external uptime monitors, authenticated with SYNTHETIC_PROBE_TOKEN; off when unset
+	var syntheticHandlers *handlers.SyntheticHandler
+	if probeToken := os.Getenv("SYNTHETIC_PROBE_TOKEN"); probeToken != "" {
+		syntheticHandlers = handlers.NewSyntheticHandler(userService, probeToken)
+		mux.HandleFunc("GET /synthetic/journey", syntheticHandlers.Journey)
+	}
+
+	// Public key set for verifying tokens issued by this service
+	mux.HandleFunc("GET /.well-known/jwks.json", handlers.JWKS)
+
+	// Public Health Check Route: 503 while a critical dependency is down; each is given HEALTH_CHECK_TIMEOUT to answer
+	healthTimeout := envDuration("HEALTH_CHECK_TIMEOUT")
+	if healthTimeout == 0 {
+		healthTimeout = 2 * time.Second
+	}
+	healthHandlers := handlers.NewHealthHandler(healthTimeout, healthDeps...)
+	mux.HandleFunc("GET /health", healthHandlers.Check)
+
+	// Public catalog of integrations with their terms and data flows, shown before consent
+	mux.HandleFunc("GET /integrations", consentHandlers.ListIntegrations)
+
+	// SLO gauges in the Prometheus text format, on a listener of their own (METRICS_PORT) for scraping
+	// from inside the cluster, so they are never served on the public port
+	metricsPort := os.Getenv("METRICS_PORT")
+	if metricsPort == "" {
+		metricsPort = "9090"
+	}
+	metricsMux := http.NewServeMux()
+	metricsMux.HandleFunc("GET /metrics", metrics.Handler)
+	go func() {
+		if err := serveInternal(ctx, metricsMux, metricsPort); err != nil {
+			logger.Logger.Fatalf("Metrics listener failed: %v", err)
+		}
+	}()
+
+	// Per-route concurrency limits and shedding on latency and CPU use, from load_shedding in the runtime config
+	loadShedder := handlers.NewLoadShedder(mux, func() config.LoadShedding { return config.Current().LoadShedding })
+	go loadShedder.Watch(time.Second)
+
+	// Per-route SLO metrics; this must wrap the mux (through the load shedder, which gives it the route
+	// pattern of the requests it refuses) to see the matched route pattern
+	var handler http.Handler = metrics.Middleware(loadShedder)
+	go metrics.WatchBurnRates(time.Minute)
+	go authService.ReapSessions(ctx, time.Minute) // Removes expired sessions and refreshes the session gauges
+	go outboxService.Dispatch(ctx, time.Second)   // Publishes user.created, user.updated and user.deleted events
+
+	// Authenticated API calls are metered per route, so this must also see the matched pattern
+	handler = handlers.MeterAPICalls(meteringService)(handler)
+	go meteringService.SnapshotStorage(ctx, time.Hour) // One storage_bytes event per user per UTC day
+	go messagingService.PurgeExpired(ctx, time.Hour)   // Applies message_retention_days and drops unsent attachments
+	go appointmentService.SendReminders(ctx, time.Minute)
+	go announcementService.SendDue(ctx, 10*time.Second) // Each announcement is sent by one replica at a time
+	go workoutAttachmentService.ScanPending(ctx, 30*time.Second)
+	go workoutAttachmentService.PurgeExpired(ctx, time.Hour)
+	go accountDeletionService.EraseDue(ctx, time.Hour) // Erases accounts whose account_deletion_grace_days have passed
+	go blobMaintenanceService.RunLifecycle(ctx, time.Hour)
+	go blobMaintenanceService.RunIntegrityChecks(ctx, time.Hour) // Checksums blob_storage.integrity_sample_size random files
+	go fieldSealingService.RunResealing(ctx, 10*time.Minute)     // Seals plaintext health fields and moves them off retired keys
+
+	// Response schema validation against the OpenAPI spec (never in production)
+	validationMode := os.Getenv("RESPONSE_VALIDATION")
+	if validationMode == "" {
+		validationMode = handlers.ResponseValidationLog
+	}
+	if env != "production" && validationMode != handlers.ResponseValidationOff {
+		spec, err := schema.Parse(api.OpenAPISpec)
+		if err != nil {
+			logger.Logger.Fatalf("Failed to load OpenAPI spec: %v", err)
+		}
+		handler = handlers.ResponseValidation(spec, validationMode)(handler)
+		logger.Logger.Infof("Response schema validation enabled in %s mode", validationMode)
+	}
+
+	// The global rate limit applies to every route and is off unless RATE_LIMIT_PER_MINUTE is set.
+	handler = handlers.RateLimit("global", func() config.RateLimit { return config.Current().RateLimits.RateLimit }, proxyHops)(handler)
+
+	// Panics become 500s and are reported; standard context headers are extracted first; feature overrides are only trusted outside production.
+	// Bodies in MessagePack or Protobuf are converted to and from JSON around all of it, recovered panics included.
+	handler = handlers.RequestContext(env != "production")(handlers.ContentNegotiation(handlers.Recover(handlers.CORS(handler))))
 
-	// Public Health Check Route
-	mux.HandleFunc("GET /health", userHandlers.HealthCheck)
+	if syntheticHandlers != nil {
+		syntheticHandlers.Mount(handler) // Journeys run through the same chain as real requests
+	}
 
 	// 6. Start HTTP Server
-	logger.Logger.Infof("User Service listening on port %s", port)
-	logger.Logger.Fatal(http.ListenAndServe(fmt.Sprintf(":%s", port), mux))
-}
\ No newline at end of file
+	if err := serve(ctx, handler, port, tlsSettings); err != nil {
+		logger.Logger.Fatal(err)
+	}
+	logger.Logger.Info("User Service stopped")
+}
+
+// newServer returns a server for handler on addr, with timeouts sized for API requests so slow or idle
+// clients cannot hold connections open. Event streams, WebSockets, and attachment transfers extend
+// their own deadlines.
+func newServer(addr string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      time.Minute,
+		IdleTimeout:       2 * time.Minute,
+	}
+}
+
+// shutdownTimeout bounds how long a server waits for requests in flight once ctx is done; streams
+// still open then are closed.
+const shutdownTimeout = 20 * time.Second
+
+// shutdownOnDone shuts srv down gracefully once ctx is done. The channel it returns is closed when
+// the shutdown is over.
+func shutdownOnDone(ctx context.Context, srv *http.Server) <-chan struct{} {
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			logger.Logger.Warnf("Requests to %s still in flight after %s are cut off: %v", srv.Addr, shutdownTimeout, err)
+			srv.Close()
+		}
+	}()
+	return stopped
+}
+
+// serve serves handler on port until ctx is done, then shuts down gracefully, or until it fails. With
+// TLS it serves HTTPS, negotiating HTTP/2, with the certificate of the TLS settings or those obtained
+// over ACME, and optionally redirects plain HTTP.
+func serve(ctx context.Context, handler http.Handler, port string, settings *config.TLS) error {
+	srv := newServer(":"+port, handler)
+	stopped := shutdownOnDone(ctx, srv)
+	if err := listen(srv, port, settings); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	logger.Logger.Info("Shutting down User Service")
+	<-stopped
+	return nil
+}
+
+// serveInternal serves handler on port in plain HTTP until ctx is done, or until it fails. It is for
+// endpoints meant for the cluster only, such as metrics: the port must not be exposed publicly.
+func serveInternal(ctx context.Context, handler http.Handler, port string) error {
+	srv := newServer(":"+port, handler)
+	stopped := shutdownOnDone(ctx, srv)
+	logger.Logger.Infof("Internal endpoints listening on port %s", port)
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	<-stopped
+	return nil
+}
+
+// listen runs srv on port, over TLS if the settings enable it, until it fails or is shut down.
+func listen(srv *http.Server, port string, settings *config.TLS) error {
+	if !settings.Enabled() {
+		logger.Logger.Infof("User Service listening on port %s", port)
+		return srv.ListenAndServe()
+	}
+
+	srv.Protocols = new(http.Protocols)
+	srv.Protocols.SetHTTP1(true)
+	srv.Protocols.SetHTTP2(true)
+	var redirect http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		}
+		if port != "443" {
+			host = net.JoinHostPort(host, port)
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+	if settings.CertFile != "" {
+		reloader, err := certs.NewReloader(settings.CertFile, settings.KeyFile)
+		if err != nil {
+			return err
+		}
+		go reloader.Watch(10 * time.Second) // Renewed certificates take effect without a restart
+		srv.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12, GetCertificate: reloader.GetCertificate}
+	} else {
+		// Certificates are obtained and renewed over ACME, through TLS-ALPN-01 challenges on this port or
+		// HTTP-01 challenges on TLS_HTTP_PORT
+		manager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(settings.AutocertDomains...),
+			Cache:      autocert.DirCache(settings.AutocertCacheDir),
+			Email:      settings.AutocertEmail,
+		}
+		if settings.AutocertURL != "" {
+			manager.Client = &acme.Client{DirectoryURL: settings.AutocertURL}
+		}
+		srv.TLSConfig = manager.TLSConfig()
+		srv.TLSConfig.MinVersion = tls.VersionTLS12
+		redirect = manager.HTTPHandler(redirect)
+		logger.Logger.Infof("TLS certificates for %s are obtained over ACME and cached in %s", strings.Join(settings.AutocertDomains, ", "), settings.AutocertCacheDir)
+	}
+
+	if settings.HTTPPort != "" {
+		go func() {
+			logger.Logger.Infof("Redirecting plain HTTP on port %s to HTTPS", settings.HTTPPort)
+			logger.Logger.Fatal(newServer(":"+settings.HTTPPort, redirect).ListenAndServe())
+		}()
+	}
+	logger.Logger.Infof("User Service listening on port %s with TLS and HTTP/2", port)
+	return srv.ListenAndServeTLS("", "")
+}
+
+// checkDBRole verifies at startup that a pool's role cannot touch tables it does not own, such as other
+// services' tables. With DB_ROLE_CHECK=enforce any violation stops the service; with warn it is logged.
+func checkDBRole(db *sql.DB, name, mode string) {
+	if mode == "off" {
+		return
+	}
+	violations, err := repository.RoleViolations(db)
+	if err != nil {
+		if mode == "enforce" {
+			logger.Logger.Fatalf("Failed to verify the %s database role: %v", name, err)
+		}
+		logger.Logger.Warnf("Failed to verify the %s database role: %v", name, err)
+		return
+	}
+	for _, v := range violations {
+		logger.Logger.Warnf("Database role check (%s): %s", name, v)
+	}
+	if len(violations) > 0 && mode == "enforce" {
+		logger.Logger.Fatalf("The %s database role is not least-privilege (%d violations); see DATABASE_ADMIN_URL", name, len(violations))
+	}
+}
+
+// envInt reads an optional non-negative integer environment variable; unset means 0.
+func envInt(name string) int {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 0 || n > math.MaxUint32 {
+		logger.Logger.Fatalf("%s must be a non-negative integer, got %q", name, raw)
+	}
+	return n
+}
+
+// envDuration reads an optional positive duration environment variable, such as "30m"; unset means 0.
+func envDuration(name string) time.Duration {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil || d <= 0 {
+		logger.Logger.Fatalf("%s must be a positive duration such as 30m, got %q", name, raw)
+	}
+	return d
+}
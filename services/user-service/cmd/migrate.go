@@ -0,0 +1,229 @@
+// services/user-service/cmd/migrate.go
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"health-tracker-project/services/user-service/internal/config"
+	"health-tracker-project/services/user-service/internal/repository"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// schemaDatabase is a database the service migrates, with the migration components that build its
+// schema: those of the repositories living in it, in the order main constructs them.
+type schemaDatabase struct {
+	name       string // "home", "region <name>", or "metering"
+	dsn        string
+	components []string // Directories of internal/repository/migrations
+}
+
+// userDataComponents build the user-owned tables, which the home database and every region database hold.
+var userDataComponents = []string{
+	"users", "user_events", "login_attempts", "dashboard_layouts", "user_settings", "identities", "sessions",
+	"integration_consents", "messaging", "appointments", "workout_attachments", "workout_plans", "outbox",
+}
+
+// schemaDatabases lists the databases main migrates, and what it builds in each. Keep in sync with the
+// repositories main constructs.
+func schemaDatabases(dbURL string, residency *config.Residency) []schemaDatabase {
+	home := schemaDatabase{name: "home", dsn: dbURL}
+	if residency != nil {
+		home.components = append(home.components, "user_regions")
+	}
+	home.components = append(home.components, userDataComponents...)
+	home.components = append(home.components, "system_events", "audit_events", "developer_apps", "announcements")
+	meteringURL := os.Getenv("METERING_DATABASE_URL")
+	if meteringURL == "" {
+		home.components = append(home.components, "metering_events")
+	}
+
+	databases := []schemaDatabase{home}
+	if residency != nil {
+		regions := make([]string, 0, len(residency.DatabaseURLs))
+		for region := range residency.DatabaseURLs {
+			if region != residency.HomeRegion {
+				regions = append(regions, region)
+			}
+		}
+		sort.Strings(regions)
+		for _, region := range regions {
+			databases = append(databases, schemaDatabase{name: "region " + region, dsn: residency.DatabaseURLs[region], components: userDataComponents})
+		}
+	}
+	if meteringURL != "" {
+		databases = append(databases, schemaDatabase{name: "metering", dsn: meteringURL, components: []string{"metering_events"}})
+	}
+	return databases
+}
+
+// runMigrateCommand runs `user-service migrate <command>` on every database instead of starting the service:
+//
+//	plan              print the SQL the next start would run, without applying it
+//	status            list each migration and when it was applied
+//	up                apply the pending migrations, as a start would
+//	down <component>  roll back the latest applied migration of a component
+func runMigrateCommand(args []string, databases []schemaDatabase, appName string) {
+	switch {
+	case len(args) == 2 && args[1] == "plan":
+		logger.SetLevel("warn") // Keep the plan readable
+		planMigrations(databases, appName)
+	case len(args) == 2 && args[1] == "status":
+		logger.SetLevel("warn")
+		printMigrationStatus(databases, appName)
+	case len(args) == 2 && args[1] == "up":
+		for _, database := range databases {
+			db := openMigrationTarget(database, appName)
+			err := repository.MigrateSchema(db, database.components...)
+			db.Close()
+			if err != nil {
+				logger.Logger.Fatalf("Failed to migrate the %s database: %v", database.name, err)
+			}
+			fmt.Printf("-- %s database: up to date\n", database.name)
+		}
+	case len(args) == 3 && args[1] == "down":
+		rollBackMigration(databases, args[2], appName)
+	default:
+		logger.Logger.Fatalf("Unknown command %q; expected \"migrate plan\", \"migrate status\", \"migrate up\", or \"migrate down <component>\"", strings.Join(args, " "))
+	}
+}
+
+// openMigrationTarget connects to a database to apply or roll back migrations.
+func openMigrationTarget(database schemaDatabase, appName string) *sql.DB {
+	db, err := repository.NewPostgresDB(database.dsn, appName+"/migrate", repository.PoolConfig{})
+	if err != nil {
+		logger.Logger.Fatalf("Failed to connect to the %s database: %v", database.name, err)
+	}
+	return db
+}
+
+// planMigrations prints the migrations pending on each database and the SQL they would run at the next
+// start, without applying any of it. Statements run in a transaction that is rolled back, so a statement
+// that would fail, such as a unique constraint over duplicate rows, fails the plan too. Statements that
+// change nothing, as in a baseline migration recorded on a database built before versioning, are left
+// out. The region directory backfill, which copies rows between databases, is not planned.
+func planMigrations(databases []schemaDatabase, appName string) {
+	for _, database := range databases {
+		plan, err := repository.NewMigrationPlan(database.dsn, appName+"/migrate-plan")
+		if err != nil {
+			logger.Logger.Fatalf("Failed to connect to the %s database: %v", database.name, err)
+		}
+		statuses, err := repository.MigrationStatuses(plan.DB, database.components...)
+		if err == nil {
+			err = repository.MigrateSchema(plan.DB, database.components...)
+		}
+		pending := plan.Pending()
+		plan.Close()
+		if err != nil {
+			logger.Logger.Fatalf("Failed to plan the migrations of the %s database: %v", database.name, err)
+		}
+
+		var migrations []string
+		for _, status := range statuses {
+			if status.AppliedAt == nil {
+				migrations = append(migrations, status.String())
+			}
+		}
+		if len(migrations) == 0 {
+			fmt.Printf("-- %s database: up to date\n\n", database.name)
+			continue
+		}
+		fmt.Printf("-- %s database: %d pending migration(s), %d statement(s) that change something\n", database.name, len(migrations), len(pending))
+		fmt.Printf("-- %s\n", strings.Join(migrations, ", "))
+		for _, statement := range pending {
+			fmt.Println(statement)
+		}
+		fmt.Println()
+	}
+}
+
+// printMigrationStatus lists the migrations of each database and when they were applied. It reads
+// through a planning pool, so a database never migrated by this runner is left without schema_migrations.
+func printMigrationStatus(databases []schemaDatabase, appName string) {
+	for _, database := range databases {
+		plan, err := repository.NewMigrationPlan(database.dsn, appName+"/migrate-status")
+		if err != nil {
+			logger.Logger.Fatalf("Failed to connect to the %s database: %v", database.name, err)
+		}
+		statuses, err := repository.MigrationStatuses(plan.DB, database.components...)
+		plan.Close()
+		if err != nil {
+			logger.Logger.Fatalf("Failed to read the migrations of the %s database: %v", database.name, err)
+		}
+		fmt.Printf("-- %s database\n", database.name)
+		for _, status := range statuses {
+			state := "pending"
+			if status.AppliedAt != nil {
+				state = "applied " + status.AppliedAt.UTC().Format(time.RFC3339)
+			}
+			if !status.Reversible() {
+				state += " (no down migration)"
+			}
+			fmt.Printf("%-48s %s\n", status.String(), state)
+		}
+		fmt.Println()
+	}
+}
+
+// rollBackMigration rolls back the latest applied migration of a component on every database holding it,
+// one after the other. Run it with the release that shipped the migration, which has its down
+// migration, before deploying the previous release; starting this release again reapplies it.
+func rollBackMigration(databases []schemaDatabase, component, appName string) {
+	found := false
+	for _, database := range databases {
+		if !slices.Contains(database.components, component) {
+			continue
+		}
+		found = true
+		db := openMigrationTarget(database, appName)
+		undone, err := repository.RollbackMigration(db, component)
+		db.Close()
+		if err != nil {
+			logger.Logger.Fatalf("Failed to roll back %s on the %s database: %v", component, database.name, err)
+		}
+		if undone == nil {
+			fmt.Printf("-- %s database: no migration of %s is applied\n", database.name, component)
+			continue
+		}
+		fmt.Printf("-- %s database: rolled back %s\n", database.name, undone)
+	}
+	if !found {
+		logger.Logger.Fatalf("No database holds migration component %q", component)
+	}
+}
+
+// checkSchemaDrift compares a database's schema, after its migrations ran, with the schema they build on
+// an empty database, and reports what differs: tables, columns, indexes, constraints, or triggers
+// changed by hand. With SCHEMA_DRIFT_CHECK=enforce any drift stops the service; with warn it is logged
+// as an error, which reaches error tracking.
+func checkSchemaDrift(db *sql.DB, database schemaDatabase, appName, mode string) {
+	if mode == "off" {
+		return
+	}
+	migrate := func(scratch *sql.DB) error {
+		return repository.MigrateSchema(scratch, database.components...)
+	}
+	drift, err := repository.SchemaDrift(db, database.dsn, appName+"/drift-check", migrate)
+	if err != nil {
+		if mode == "enforce" {
+			logger.Logger.Fatalf("Failed to check the %s database for schema drift: %v", database.name, err)
+		}
+		logger.Logger.Warnf("Failed to check the %s database for schema drift: %v", database.name, err)
+		return
+	}
+	for _, d := range drift {
+		logger.Logger.Warnf("Schema drift (%s): %s", database.name, d)
+	}
+	if len(drift) == 0 {
+		return
+	}
+	if mode == "enforce" {
+		logger.Logger.Fatalf("The %s database schema differs from its migrations in %d place(s)", database.name, len(drift))
+	}
+	logger.Logger.Errorf("The %s database schema differs from its migrations in %d place(s); was it changed by hand?", database.name, len(drift))
+}
@@ -0,0 +1,180 @@
+// services/user-service/cmd/replay/main.go
+
+// Command replay sends captured wearable payloads to a local stack, keeping their original spacing
+// (scaled by -speed), so ingest, dedup, and aggregation can be debugged without real hardware.
+//
+// A capture is a JSON Lines file with one device request per line:
+//
+//	{"captured_at": "2026-10-16T07:30:00.120Z", "device": "fitbit-7f3a", "method": "POST",
+//	 "path": "/ingest/heart-rate", "headers": {"X-Device-Id": "fitbit-7f3a"}, "body": {...}}
+//
+// method defaults to POST and headers and body are optional. Requests go out one at a time in
+// captured_at order, authenticated with -token as the session cookie.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// record is one captured device request.
+type record struct {
+	CapturedAt time.Time         `json:"captured_at"`
+	Device     string            `json:"device"`
+	Method     string            `json:"method"`
+	Path       string            `json:"path"`
+	Headers    map[string]string `json:"headers"`
+	Body       json.RawMessage   `json:"body"`
+}
+
+func main() {
+	capture := flag.String("capture", "", "capture file (JSON Lines), or - for stdin")
+	target := flag.String("target", "http://localhost:8080", "base URL of the stack to replay against")
+	speed := flag.Float64("speed", 1, "replay speed: 2 is twice as fast as captured, 0 sends without waiting")
+	repeat := flag.Int("repeat", 1, "times each payload is sent in a row, to exercise dedup")
+	device := flag.String("device", "", "only replay this device")
+	token := flag.String("token", os.Getenv("REPLAY_TOKEN"), "access token sent as the session cookie (default $REPLAY_TOKEN)")
+	cookie := flag.String("cookie", "jwt_token", "session cookie name (COOKIE_NAME of the target)")
+	timeout := flag.Duration("timeout", 10*time.Second, "timeout per request")
+	dryRun := flag.Bool("dry-run", false, "print the schedule without sending anything")
+	flag.Parse()
+
+	logger.InitLogger("development")
+	defer logger.Logger.Sync()
+
+	if *capture == "" || *speed < 0 || *repeat < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	records, err := load(*capture, *device)
+	if err != nil {
+		logger.Logger.Fatalf("Failed to load capture: %v", err)
+	}
+	if len(records) == 0 {
+		logger.Logger.Fatal("Capture has no records to replay")
+	}
+	span := records[len(records)-1].CapturedAt.Sub(records[0].CapturedAt)
+	logger.Logger.Infof("Replaying %d records spanning %s at %gx against %s", len(records), span, *speed, *target)
+
+	client := &http.Client{Timeout: *timeout}
+	statuses := map[string]int{}
+	failed := 0
+	start := time.Now()
+	for _, rec := range records {
+		// Each record is due at its offset in the capture, scaled by the speed; a slow target makes the replay
+		// fall behind rather than skip records.
+		if *speed > 0 {
+			offset := time.Duration(float64(rec.CapturedAt.Sub(records[0].CapturedAt)) / *speed)
+			if wait := time.Until(start.Add(offset)); wait > 0 && !*dryRun {
+				time.Sleep(wait)
+			}
+		}
+		for i := 0; i < *repeat; i++ {
+			if *dryRun {
+				fmt.Printf("%s %s %s%s (%d bytes)\n", rec.CapturedAt.Format(time.RFC3339Nano), rec.Method, *target, rec.Path, len(rec.Body))
+				continue
+			}
+			status, err := send(client, *target, *cookie, *token, rec)
+			if err != nil {
+				logger.Logger.Warnf("%s %s from %s failed: %v", rec.Method, rec.Path, rec.Device, err)
+				statuses["error"]++
+				failed++
+				continue
+			}
+			statuses[fmt.Sprint(status)]++
+			if status >= 300 {
+				failed++
+			}
+		}
+	}
+
+	if *dryRun {
+		return
+	}
+	logger.Logger.Infof("Replay finished in %s: %v", time.Since(start).Round(time.Millisecond), statuses)
+	if failed > 0 {
+		logger.Logger.Errorf("%d requests did not succeed", failed)
+		os.Exit(1)
+	}
+}
+
+// load reads a capture, keeps the records of one device if given, and sorts them by capture time.
+func load(path, device string) ([]record, error) {
+	var in io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	records := []record{}
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64<<10), 16<<20) // Device batches can be large
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var rec record
+		if err := json.Unmarshal([]byte(text), &rec); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if rec.CapturedAt.IsZero() || !strings.HasPrefix(rec.Path, "/") {
+			return nil, fmt.Errorf("line %d: captured_at and a path starting with / are required", line)
+		}
+		if rec.Method == "" {
+			rec.Method = http.MethodPost
+		}
+		if device == "" || rec.Device == device {
+			records = append(records, rec)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(records, func(a, b record) int { return a.CapturedAt.Compare(b.CapturedAt) })
+	return records, nil
+}
+
+// send makes one request and returns its status, draining the response so the connection is reused.
+func send(client *http.Client, target, cookie, token string, rec record) (int, error) {
+	var body io.Reader
+	if len(rec.Body) > 0 && string(rec.Body) != "null" {
+		body = bytes.NewReader(rec.Body)
+	}
+	req, err := http.NewRequest(rec.Method, strings.TrimSuffix(target, "/")+rec.Path, body)
+	if err != nil {
+		return 0, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for name, value := range rec.Headers {
+		req.Header.Set(name, value)
+	}
+	if token != "" {
+		req.AddCookie(&http.Cookie{Name: cookie, Value: token})
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,388 @@
+// services/user-service/e2e/runner.go
+package e2e
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"health-tracker-project/services/user-service/internal/models"
+)
+
+// Step outcomes, as in the synthetic journey's report.
+const (
+	OutcomePass = "pass"
+	OutcomeFail = "fail"
+	OutcomeSkip = "skip"
+)
+
+const (
+	maxResponseBody = 4 << 20 // Responses are read whole to check them; exports can be large
+	maxRateLimited  = 3       // Retries of a request answered 429 before the step fails
+	maxRetryAfter   = 30 * time.Second
+)
+
+// Runner runs scenarios against the targets of a stack.
+type Runner struct {
+	Targets  map[string]string // Base URL of each target, by name; steps default to DefaultTarget
+	Parallel int               // Tenants running at once; default 1
+	Timeout  time.Duration     // Per request; default 10s
+	// SpreadClientIPs gives each tenant its own X-Forwarded-For address, so per-IP rate limits apply
+	// to each tenant rather than to the whole run. The stack must trust proxy headers for it to matter.
+	SpreadClientIPs bool
+}
+
+// StepResult is the outcome of one step of a scenario run.
+type StepResult struct {
+	Name       string `json:"name"`
+	Outcome    string `json:"outcome"`
+	Status     int    `json:"status,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Result is the outcome of one run of a scenario, by one tenant.
+type Result struct {
+	Scenario   string       `json:"scenario"`
+	Tenant     int          `json:"tenant"` // From 1 to the scenario's Tenants
+	Email      string       `json:"email"`  // Of the tenant's account, for finding it in the stack's logs
+	Passed     bool         `json:"passed"`
+	DurationMS int64        `json:"duration_ms"`
+	Steps      []StepResult `json:"steps"`
+	Cleanup    []StepResult `json:"cleanup,omitempty"`
+}
+
+// NewRunID returns a random ID for a run, part of every tenant's account and request IDs.
+func NewRunID() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// tenant is the isolated identity one scenario run acts as: its own account details, variables,
+// cookie jar, and client address.
+type tenant struct {
+	vars     map[string]string
+	client   *http.Client
+	clientIP string
+}
+
+// newTenant creates the tenant of the index-th run of a Run. Its account is at the synthetic email
+// domain, like the synthetic journey's, so it is never mistaken for a real user's and passes the MX check.
+func newTenant(runID string, index int, timeout time.Duration) *tenant {
+	b := make([]byte, 4)
+	rand.Read(b)
+	id := runID + hex.EncodeToString(b)
+	secret := make([]byte, 16)
+	rand.Read(secret)
+	jar, _ := cookiejar.New(nil)
+	return &tenant{
+		vars: map[string]string{
+			"run.id":          runID,
+			"tenant.id":       id,
+			"tenant.name":     "E2E Tenant " + strconv.Itoa(index+1),
+			"tenant.email":    "e2e-" + id + "@" + models.SyntheticEmailDomain,
+			"tenant.username": "e2e_" + id,
+			"tenant.password": hex.EncodeToString(secret),
+		},
+		client:   &http.Client{Jar: jar, Timeout: timeout},
+		clientIP: fmt.Sprintf("198.18.%d.%d", index/250, index%250+1), // Benchmarking range, RFC 2544
+	}
+}
+
+// Run runs every scenario once per tenant, Parallel runs at a time, and returns the results in the
+// order of the scenarios and then their tenants.
+func (r *Runner) Run(ctx context.Context, runID string, scenarios []Scenario) []Result {
+	type job struct {
+		scenario *Scenario
+		tenant   int
+	}
+	var jobs []job
+	for i := range scenarios {
+		for t := 1; t <= scenarios[i].Tenants; t++ {
+			jobs = append(jobs, job{scenario: &scenarios[i], tenant: t})
+		}
+	}
+
+	parallel := max(r.Parallel, 1)
+	timeout := r.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	results := make([]Result, len(jobs))
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i, j := range jobs {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = r.runScenario(ctx, j.scenario, j.tenant, newTenant(runID, i, timeout))
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// runScenario runs a scenario's steps as a tenant, skipping the rest once one fails, then its cleanup.
+func (r *Runner) runScenario(ctx context.Context, scenario *Scenario, number int, t *tenant) Result {
+	start := time.Now()
+	result := Result{Scenario: scenario.Name, Tenant: number, Email: t.vars["tenant.email"], Passed: true}
+	for i := range scenario.Steps {
+		step := &scenario.Steps[i]
+		if !result.Passed {
+			result.Steps = append(result.Steps, StepResult{Name: step.Name, Outcome: OutcomeSkip})
+			continue
+		}
+		stepResult := r.runStep(ctx, t, step, fmt.Sprintf("e2e-%s-%d", t.vars["tenant.id"], i+1), false)
+		result.Passed = stepResult.Outcome == OutcomePass
+		result.Steps = append(result.Steps, stepResult)
+	}
+	for i := range scenario.Cleanup {
+		result.Cleanup = append(result.Cleanup, r.runStep(ctx, t, &scenario.Cleanup[i], fmt.Sprintf("e2e-%s-c%d", t.vars["tenant.id"], i+1), true))
+	}
+	result.DurationMS = time.Since(start).Milliseconds()
+	return result
+}
+
+// errUndefined is returned for a step that uses a variable no earlier step captured.
+var errUndefined = errors.New("undefined variable")
+
+// runStep sends a step's request and checks its response. A cleanup step using an undefined variable
+// is skipped: the step that would have captured it failed, so there is nothing to clean up.
+func (r *Runner) runStep(ctx context.Context, t *tenant, step *Step, requestID string, cleanup bool) StepResult {
+	start := time.Now()
+	result := StepResult{Name: step.Name, Outcome: OutcomePass}
+	status, err := r.do(ctx, t, step, requestID)
+	result.Status = status
+	result.DurationMS = time.Since(start).Milliseconds()
+	switch {
+	case err == nil:
+	case cleanup && errors.Is(err, errUndefined):
+		result.Outcome = OutcomeSkip
+	default:
+		result.Outcome = OutcomeFail
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// do sends a step's request, retrying while it is rate limited, and checks the response against the
+// step's expectation. It returns the status of the last response.
+func (r *Runner) do(ctx context.Context, t *tenant, step *Step, requestID string) (int, error) {
+	base, ok := r.Targets[step.Target]
+	if !ok {
+		return 0, fmt.Errorf("unknown target '%s'", step.Target)
+	}
+	path, err := expand(step.Path, t.vars, false)
+	if err != nil {
+		return 0, err
+	}
+	var body string
+	if len(step.Body) > 0 && string(step.Body) != "null" {
+		if body, err = expand(string(step.Body), t.vars, true); err != nil {
+			return 0, err
+		}
+	}
+	headers := make(map[string]string, len(step.Headers))
+	for name, value := range step.Headers {
+		if headers[name], err = expand(value, t.vars, false); err != nil {
+			return 0, err
+		}
+	}
+
+	var resp *http.Response
+	var respBody []byte
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, step.Method, strings.TrimSuffix(base, "/")+path, strings.NewReader(body))
+		if err != nil {
+			return 0, err
+		}
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		req.Header.Set("X-Request-ID", requestID) // Finds the step's requests in the stack's logs
+		if r.SpreadClientIPs {
+			req.Header.Set("X-Forwarded-For", t.clientIP)
+		}
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+
+		if resp, err = t.client.Do(req); err != nil {
+			return 0, err
+		}
+		respBody, err = io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+		resp.Body.Close()
+		if err != nil {
+			return resp.StatusCode, fmt.Errorf("failed to read response: %w", err)
+		}
+		if resp.StatusCode != http.StatusTooManyRequests || step.Expect.Status.allows(resp.StatusCode) || attempt == maxRateLimited {
+			break
+		}
+		if err := waitRetryAfter(ctx, resp.Header.Get("Retry-After")); err != nil {
+			return resp.StatusCode, err
+		}
+	}
+
+	if !step.Expect.Status.allows(resp.StatusCode) {
+		if len(respBody) == 0 {
+			return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+		}
+		return resp.StatusCode, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, snippet(respBody))
+	}
+	if len(step.Expect.JSON) == 0 && len(step.Expect.Present) == 0 && len(step.Capture) == 0 {
+		return resp.StatusCode, nil
+	}
+
+	var doc any
+	if err := json.Unmarshal(respBody, &doc); err != nil {
+		return resp.StatusCode, fmt.Errorf("response is not JSON: %s", snippet(respBody))
+	}
+	for path, raw := range step.Expect.JSON {
+		wantJSON, err := expand(string(raw), t.vars, true)
+		if err != nil {
+			return resp.StatusCode, err
+		}
+		var want any
+		json.Unmarshal([]byte(wantJSON), &want) // Valid: it was JSON before expanding, and values are escaped
+		got, ok := lookup(doc, path)
+		if !ok {
+			return resp.StatusCode, fmt.Errorf("%s is missing, want %s", path, wantJSON)
+		}
+		if !reflect.DeepEqual(got, want) {
+			gotJSON, _ := json.Marshal(got)
+			return resp.StatusCode, fmt.Errorf("%s is %s, want %s", path, gotJSON, wantJSON)
+		}
+	}
+	for _, path := range step.Expect.Present {
+		if _, ok := lookup(doc, path); !ok {
+			return resp.StatusCode, fmt.Errorf("%s is missing", path)
+		}
+	}
+	for name, path := range step.Capture {
+		value, ok := lookup(doc, path)
+		if !ok {
+			return resp.StatusCode, fmt.Errorf("cannot capture %s: %s is missing", name, path)
+		}
+		if s, ok := value.(string); ok {
+			t.vars[name] = s
+		} else {
+			encoded, _ := json.Marshal(value)
+			t.vars[name] = string(encoded)
+		}
+	}
+	return resp.StatusCode, nil
+}
+
+// waitRetryAfter waits as long as a 429's Retry-After asks, within limits.
+func waitRetryAfter(ctx context.Context, retryAfter string) error {
+	wait := time.Second
+	if seconds, err := strconv.Atoi(retryAfter); err == nil && seconds > 0 {
+		wait = min(time.Duration(seconds)*time.Second, maxRetryAfter)
+	}
+	select {
+	case <-time.After(wait):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// variablePattern matches a {{variable}} in a step.
+var variablePattern = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_.]+)\s*\}\}`)
+
+// expand replaces the {{variables}} in s. Inside JSON, values are escaped for a JSON string, which
+// is where variables can appear in valid JSON.
+func expand(s string, vars map[string]string, inJSON bool) (string, error) {
+	var missing string
+	out := variablePattern.ReplaceAllStringFunc(s, func(match string) string {
+		name := variablePattern.FindStringSubmatch(match)[1]
+		value, ok := vars[name]
+		if !ok {
+			missing = name
+			return match
+		}
+		if inJSON {
+			encoded, _ := json.Marshal(value)
+			return string(encoded[1 : len(encoded)-1])
+		}
+		return value
+	})
+	if missing != "" {
+		return "", fmt.Errorf("%w {{%s}}", errUndefined, missing)
+	}
+	return out, nil
+}
+
+// lookup finds the value at a JSON path in a decoded document.
+func lookup(doc any, path string) (any, bool) {
+	for _, key := range strings.Split(path, ".") {
+		switch v := doc.(type) {
+		case map[string]any:
+			value, ok := v[key]
+			if !ok {
+				return nil, false
+			}
+			doc = value
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, false
+			}
+			doc = v[i]
+		default:
+			return nil, false
+		}
+	}
+	return doc, true
+}
+
+// snippet shortens a response body for an error message.
+func snippet(body []byte) string {
+	s := strings.TrimSpace(string(body))
+	if len(s) > 200 {
+		s = s[:200] + "..."
+	}
+	return s
+}
+
+// Passed reports whether every result passed.
+func Passed(results []Result) bool {
+	for _, result := range results {
+		if !result.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+// Summary counts the results that passed and failed, by scenario, in a line for the console.
+func Summary(results []Result) string {
+	passed := 0
+	var failed []string
+	for _, result := range results {
+		if result.Passed {
+			passed++
+		} else {
+			failed = append(failed, fmt.Sprintf("%s#%d", result.Scenario, result.Tenant))
+		}
+	}
+	if len(failed) == 0 {
+		return fmt.Sprintf("%d runs passed", passed)
+	}
+	return fmt.Sprintf("%d runs passed, %d failed: %s", passed, len(failed), strings.Join(failed, ", "))
+}
@@ -0,0 +1,170 @@
+// services/user-service/e2e/scenario.go
+
+// Package e2e runs end-to-end scenarios: user journeys driven through real HTTP against a running
+// Pulse stack, such as the one docker compose starts. A scenario is a JSON file of steps, each one
+// request and what its response must look like. Every run of a scenario gets its own tenant, a fresh
+// account with its own session, so runs are isolated from each other and from real users and can go
+// in parallel.
+package e2e
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultTarget is the name of the target steps go to when they do not name one: the user-service.
+const DefaultTarget = "user"
+
+// Scenario is one user journey, loaded from a scenario file.
+type Scenario struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Tenants     int    `json:"tenants,omitempty"` // Runs of the scenario, each with its own tenant; default 1
+	Steps       []Step `json:"steps"`
+	// Cleanup runs after Steps whether they passed or not, to remove what a failed run left behind.
+	// Its failures are reported but do not fail the scenario, and steps using a variable that was
+	// never captured are skipped.
+	Cleanup []Step `json:"cleanup,omitempty"`
+	File    string `json:"-"`
+}
+
+// Step is one request of a scenario. Path, header values, Body, and the expected JSON values may use
+// {{variables}}: the tenant's (tenant.id, tenant.name, tenant.email, tenant.password), run.id, and
+// any captured by earlier steps.
+type Step struct {
+	Name    string            `json:"name"`
+	Target  string            `json:"target,omitempty"` // A target of the runner; default DefaultTarget
+	Method  string            `json:"method,omitempty"` // Default GET
+	Path    string            `json:"path"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"` // Sent as JSON
+	Expect  Expectation       `json:"expect"`
+	// Capture names values of the JSON response, by path, as variables for later steps.
+	Capture map[string]string `json:"capture,omitempty"`
+}
+
+// Expectation is what a step's response must look like. JSON paths are dot-separated keys, with
+// numbers indexing into arrays: "user.id", "0.terms_version".
+type Expectation struct {
+	Status  Statuses                   `json:"status,omitempty"`  // Any of these; default any 2xx
+	JSON    map[string]json.RawMessage `json:"json,omitempty"`    // Path → the value it must have
+	Present []string                   `json:"present,omitempty"` // Paths that must exist, with any value
+}
+
+// Statuses is one status code, or a list of them, in a scenario file.
+type Statuses []int
+
+// UnmarshalJSON accepts a status code or a list of them.
+func (s *Statuses) UnmarshalJSON(data []byte) error {
+	var one int
+	if err := json.Unmarshal(data, &one); err == nil {
+		*s = Statuses{one}
+		return nil
+	}
+	var many []int
+	if err := json.Unmarshal(data, &many); err != nil {
+		return fmt.Errorf("status must be a status code or a list of them")
+	}
+	*s = many
+	return nil
+}
+
+// allows reports whether a response status meets the expectation.
+func (s Statuses) allows(status int) bool {
+	if len(s) == 0 {
+		return status >= 200 && status < 300
+	}
+	for _, want := range s {
+		if status == want {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadScenarios reads every *.json scenario file in dir, in file name order.
+func LoadScenarios(dir string) ([]Scenario, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no scenario files in %s", dir)
+	}
+
+	var scenarios []Scenario
+	names := make(map[string]string)
+	for _, file := range files {
+		scenario, err := loadScenario(file)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		if other, ok := names[scenario.Name]; ok {
+			return nil, fmt.Errorf("%s: scenario '%s' is also defined in %s", file, scenario.Name, other)
+		}
+		names[scenario.Name] = file
+		scenarios = append(scenarios, scenario)
+	}
+	return scenarios, nil
+}
+
+// loadScenario reads and checks one scenario file.
+func loadScenario(file string) (Scenario, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return Scenario{}, err
+	}
+	var scenario Scenario
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields() // A misspelled field would silently check nothing
+	if err := dec.Decode(&scenario); err != nil {
+		return Scenario{}, err
+	}
+	scenario.File = file
+
+	if scenario.Name == "" {
+		return Scenario{}, fmt.Errorf("name is required")
+	}
+	if scenario.Tenants < 0 {
+		return Scenario{}, fmt.Errorf("tenants must not be negative")
+	}
+	if scenario.Tenants == 0 {
+		scenario.Tenants = 1
+	}
+	if len(scenario.Steps) == 0 {
+		return Scenario{}, fmt.Errorf("steps are required")
+	}
+	for i := range scenario.Steps {
+		if err := checkStep(&scenario.Steps[i]); err != nil {
+			return Scenario{}, fmt.Errorf("step %d: %w", i+1, err)
+		}
+	}
+	for i := range scenario.Cleanup {
+		if err := checkStep(&scenario.Cleanup[i]); err != nil {
+			return Scenario{}, fmt.Errorf("cleanup step %d: %w", i+1, err)
+		}
+	}
+	return scenario, nil
+}
+
+// checkStep validates a step and fills in its defaults.
+func checkStep(step *Step) error {
+	if step.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if !strings.HasPrefix(step.Path, "/") {
+		return fmt.Errorf("path must start with /")
+	}
+	if step.Method == "" {
+		step.Method = "GET"
+	}
+	step.Method = strings.ToUpper(step.Method)
+	if step.Target == "" {
+		step.Target = DefaultTarget
+	}
+	return nil
+}
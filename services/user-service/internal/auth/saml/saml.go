@@ -0,0 +1,374 @@
+// services/user-service/internal/auth/saml/saml.go
+package saml
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// SAML 2.0 namespaces, bindings, and status codes.
+const (
+	nsAssertion     = "urn:oasis:names:tc:SAML:2.0:assertion"
+	nsProtocol      = "urn:oasis:names:tc:SAML:2.0:protocol"
+	nsMetadata      = "urn:oasis:names:tc:SAML:2.0:metadata"
+	bindingRedirect = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-Redirect"
+	bindingPOST     = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST"
+	nameIDEmail     = "urn:oasis:names:tc:SAML:1.1:nameid-format:emailAddress"
+	statusSuccess   = "urn:oasis:names:tc:SAML:2.0:status:Success"
+	confirmBearer   = "urn:oasis:names:tc:SAML:2.0:cm:bearer"
+)
+
+const (
+	requestTTL = 10 * time.Minute // How long a login started at the IdP may take to come back
+	clockSkew  = 2 * time.Minute  // Tolerance for clock drift between the IdP and this service
+)
+
+// Config holds the settings for one SAML 2.0 identity provider (Okta, Azure AD, ADFS, ...).
+type Config struct {
+	EntityID       string // This service's SP entity ID, usually the metadata URL
+	ACSURL         string // Must point at this service's /auth/saml/acs
+	IdPMetadataURL string
+	EmailAttribute string // Assertion attribute holding the email; the NameID is used when it is absent and an email address
+	NameAttribute  string // Assertion attribute holding the display name
+}
+
+// Identity is the subset of a verified assertion used to map an IdP account to a Pulse user.
+type Identity struct {
+	Issuer  string
+	Subject string // NameID
+	Email   string
+	Name    string
+}
+
+// idpMetadata is the relevant part of the IdP's EntityDescriptor.
+type idpMetadata struct {
+	EntityID string `xml:"entityID,attr"`
+	IDP      struct {
+		Keys []struct {
+			Use          string   `xml:"use,attr"`
+			Certificates []string `xml:"KeyInfo>X509Data>X509Certificate"`
+		} `xml:"KeyDescriptor"`
+		SSO []struct {
+			Binding  string `xml:"Binding,attr"`
+			Location string `xml:"Location,attr"`
+		} `xml:"SingleSignOnService"`
+	} `xml:"IDPSSODescriptor"`
+}
+
+// ServiceProvider is this service acting as a SAML service provider for one IdP.
+type ServiceProvider struct {
+	config      Config
+	idpEntityID string
+	ssoURL      string
+	certs       []*x509.Certificate // IdP signing certificates
+	requests    RequestStore
+}
+
+// ErrRequestStore is returned by ParseResponse when the RequestStore failed, so whether the response
+// answers a pending request is not known: a failure of the service rather than of the response.
+var ErrRequestStore = errors.New("saml: request store failed")
+
+// RequestStore keeps the IDs of outstanding AuthnRequests where every replica of the service sees them,
+// so the IdP's response can be posted to any replica, and can answer its request only once.
+type RequestStore interface {
+	CreateSAMLRequest(ctx context.Context, id string, expiresAt time.Time) error
+	ConsumeSAMLRequest(ctx context.Context, id string) (bool, error) // false if unknown, already answered, or expired
+}
+
+// NewServiceProvider fetches the IdP metadata and returns a ready ServiceProvider, which keeps the
+// requests it issues in requests.
+func NewServiceProvider(ctx context.Context, cfg Config, requests RequestStore) (*ServiceProvider, error) {
+	if cfg.EntityID == "" || cfg.ACSURL == "" || cfg.IdPMetadataURL == "" {
+		return nil, fmt.Errorf("saml: entity ID, ACS URL, and IdP metadata URL are required")
+	}
+	if cfg.EmailAttribute == "" {
+		cfg.EmailAttribute = "email"
+	}
+	if cfg.NameAttribute == "" {
+		cfg.NameAttribute = "name"
+	}
+
+	raw, err := fetch(ctx, cfg.IdPMetadataURL)
+	if err != nil {
+		return nil, fmt.Errorf("saml: failed to fetch IdP metadata: %w", err)
+	}
+	var md idpMetadata
+	if err := xml.Unmarshal(raw, &md); err != nil {
+		return nil, fmt.Errorf("saml: failed to parse IdP metadata: %w", err)
+	}
+
+	sp := &ServiceProvider{config: cfg, idpEntityID: md.EntityID, requests: requests}
+	for _, sso := range md.IDP.SSO {
+		if sso.Binding == bindingRedirect {
+			sp.ssoURL = sso.Location
+			break
+		}
+	}
+	for _, key := range md.IDP.Keys {
+		if key.Use != "" && key.Use != "signing" {
+			continue
+		}
+		for _, encoded := range key.Certificates {
+			der, err := decodeBase64(encoded)
+			if err != nil {
+				return nil, fmt.Errorf("saml: malformed IdP certificate: %w", err)
+			}
+			cert, err := x509.ParseCertificate(der)
+			if err != nil {
+				return nil, fmt.Errorf("saml: malformed IdP certificate: %w", err)
+			}
+			sp.certs = append(sp.certs, cert)
+		}
+	}
+	if sp.idpEntityID == "" || sp.ssoURL == "" || len(sp.certs) == 0 {
+		return nil, fmt.Errorf("saml: IdP metadata needs an entity ID, an HTTP-Redirect SingleSignOnService, and a signing certificate")
+	}
+
+	logger.FromContext(ctx).Infof("SAML identity provider configured: %s", sp.idpEntityID)
+	return sp, nil
+}
+
+// Metadata returns this service provider's SAML metadata document, for registering it with the IdP.
+func (sp *ServiceProvider) Metadata() []byte {
+	var b bytes.Buffer
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	b.WriteString(`<md:EntityDescriptor xmlns:md="` + nsMetadata + `" entityID="`)
+	xml.EscapeText(&b, []byte(sp.config.EntityID))
+	b.WriteString(`">`)
+	b.WriteString(`<md:SPSSODescriptor AuthnRequestsSigned="false" WantAssertionsSigned="true" protocolSupportEnumeration="` + nsProtocol + `">`)
+	b.WriteString(`<md:NameIDFormat>` + nameIDEmail + `</md:NameIDFormat>`)
+	b.WriteString(`<md:AssertionConsumerService Binding="` + bindingPOST + `" Location="`)
+	xml.EscapeText(&b, []byte(sp.config.ACSURL))
+	b.WriteString(`" index="0" isDefault="true"/>`)
+	b.WriteString(`</md:SPSSODescriptor></md:EntityDescriptor>` + "\n")
+	return b.Bytes()
+}
+
+// AuthnRequestURL starts a login: it returns the IdP URL to redirect the browser to (HTTP-Redirect binding)
+// and the ID of the request, which the response must answer. The ID can be used once, within requestTTL.
+func (sp *ServiceProvider) AuthnRequestURL(ctx context.Context, relayState string) (string, string, error) {
+	idBytes := make([]byte, 20)
+	if _, err := rand.Read(idBytes); err != nil {
+		return "", "", fmt.Errorf("saml: failed to generate request ID: %w", err)
+	}
+	id := "_" + hex.EncodeToString(idBytes) // IDs are xs:ID, which must not start with a digit
+
+	var request bytes.Buffer
+	request.WriteString(`<samlp:AuthnRequest xmlns:samlp="` + nsProtocol + `" xmlns:saml="` + nsAssertion + `" ID="` + id + `" Version="2.0"`)
+	request.WriteString(` IssueInstant="` + time.Now().UTC().Format(time.RFC3339) + `" Destination="`)
+	xml.EscapeText(&request, []byte(sp.ssoURL))
+	request.WriteString(`" AssertionConsumerServiceURL="`)
+	xml.EscapeText(&request, []byte(sp.config.ACSURL))
+	request.WriteString(`" ProtocolBinding="` + bindingPOST + `"><saml:Issuer>`)
+	xml.EscapeText(&request, []byte(sp.config.EntityID))
+	request.WriteString(`</saml:Issuer><samlp:NameIDPolicy Format="` + nameIDEmail + `" AllowCreate="true"/></samlp:AuthnRequest>`)
+
+	var deflated bytes.Buffer
+	fw, _ := flate.NewWriter(&deflated, flate.DefaultCompression)
+	fw.Write(request.Bytes())
+	fw.Close()
+
+	v := url.Values{"SAMLRequest": {base64.StdEncoding.EncodeToString(deflated.Bytes())}}
+	if relayState != "" {
+		v.Set("RelayState", relayState)
+	}
+	sep := "?"
+	if strings.Contains(sp.ssoURL, "?") {
+		sep = "&"
+	}
+
+	if err := sp.requests.CreateSAMLRequest(ctx, id, time.Now().Add(requestTTL)); err != nil {
+		return "", "", fmt.Errorf("saml: failed to store request: %w", err)
+	}
+	return sp.ssoURL + sep + v.Encode(), id, nil
+}
+
+// ParseResponse verifies a base64 SAMLResponse posted to the ACS and returns the asserted identity.
+// The response must answer requestID, which must be one this service provider issued and has not seen answered;
+// unsolicited (IdP-initiated) responses are rejected. The response or its assertion must be signed by the IdP,
+// and the assertion must be addressed to this service provider and currently valid.
+func (sp *ServiceProvider) ParseResponse(ctx context.Context, encoded, requestID string) (*Identity, error) {
+	raw, err := decodeBase64(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("saml: malformed SAMLResponse encoding: %w", err)
+	}
+	root, err := parseDocument(raw)
+	if err != nil {
+		return nil, err
+	}
+	if root.space != nsProtocol || root.local != "Response" {
+		return nil, fmt.Errorf("saml: document is not a SAML Response")
+	}
+	ids := map[string]int{}
+	root.collectIDs(ids)
+	for id, n := range ids {
+		if n > 1 {
+			return nil, fmt.Errorf("saml: duplicate ID %q", id)
+		}
+	}
+
+	if requestID == "" || root.attr("InResponseTo") != requestID {
+		return nil, fmt.Errorf("saml: response does not answer a pending request")
+	}
+	if dest := root.attr("Destination"); dest != "" && dest != sp.config.ACSURL {
+		return nil, fmt.Errorf("saml: response destination %q is not this service", dest)
+	}
+	if issuer := root.child(nsAssertion, "Issuer"); issuer != nil && issuer.text() != sp.idpEntityID {
+		return nil, fmt.Errorf("saml: response issuer %q is not the configured IdP", issuer.text())
+	}
+	if status := root.child(nsProtocol, "Status"); status == nil || status.child(nsProtocol, "StatusCode") == nil ||
+		status.child(nsProtocol, "StatusCode").attr("Value") != statusSuccess {
+		return nil, fmt.Errorf("saml: IdP did not report success")
+	}
+
+	if len(root.childElements(nsAssertion, "EncryptedAssertion")) > 0 {
+		return nil, fmt.Errorf("saml: encrypted assertions are not supported")
+	}
+	assertions := root.childElements(nsAssertion, "Assertion")
+	if len(assertions) != 1 {
+		return nil, fmt.Errorf("saml: response must contain exactly one assertion")
+	}
+	assertion := assertions[0]
+
+	responseErr := verifySignature(root, sp.certs)
+	if responseErr != nil && responseErr != errNotSigned {
+		return nil, responseErr
+	}
+	assertionErr := verifySignature(assertion, sp.certs)
+	if assertionErr != nil && assertionErr != errNotSigned {
+		return nil, assertionErr
+	}
+	if responseErr == errNotSigned && assertionErr == errNotSigned {
+		return nil, fmt.Errorf("saml: neither the response nor the assertion is signed")
+	}
+
+	identity, err := sp.readAssertion(assertion, requestID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	// Consumed only once the response is known good, so a forged post cannot cancel a genuine login.
+	pending, err := sp.requests.ConsumeSAMLRequest(ctx, requestID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrRequestStore, err)
+	}
+	if !pending {
+		return nil, fmt.Errorf("saml: response does not answer a pending request")
+	}
+	return identity, nil
+}
+
+// readAssertion checks the issuer, subject confirmation, and conditions of a verified assertion and maps its attributes.
+func (sp *ServiceProvider) readAssertion(assertion *element, requestID string, now time.Time) (*Identity, error) {
+	if issuer := assertion.child(nsAssertion, "Issuer"); issuer == nil || issuer.text() != sp.idpEntityID {
+		return nil, fmt.Errorf("saml: assertion is not issued by the configured IdP")
+	}
+
+	subject := assertion.child(nsAssertion, "Subject")
+	if subject == nil || subject.child(nsAssertion, "NameID") == nil {
+		return nil, fmt.Errorf("saml: assertion has no subject")
+	}
+	confirmed := false
+	for _, sc := range subject.childElements(nsAssertion, "SubjectConfirmation") {
+		data := sc.child(nsAssertion, "SubjectConfirmationData")
+		if sc.attr("Method") != confirmBearer || data == nil {
+			continue
+		}
+		if data.attr("Recipient") != sp.config.ACSURL || !before(now.Add(-clockSkew), data.attr("NotOnOrAfter")) {
+			continue
+		}
+		if irt := data.attr("InResponseTo"); irt != "" && irt != requestID {
+			continue
+		}
+		confirmed = true
+		break
+	}
+	if !confirmed {
+		return nil, fmt.Errorf("saml: assertion has no valid bearer subject confirmation for this service")
+	}
+
+	conditions := assertion.child(nsAssertion, "Conditions")
+	if conditions == nil {
+		return nil, fmt.Errorf("saml: assertion has no conditions")
+	}
+	if nb := conditions.attr("NotBefore"); nb != "" {
+		if notBefore, err := time.Parse(time.RFC3339Nano, nb); err != nil || now.Add(clockSkew).Before(notBefore) {
+			return nil, fmt.Errorf("saml: assertion is not yet valid")
+		}
+	}
+	if nooa := conditions.attr("NotOnOrAfter"); nooa != "" && !before(now.Add(-clockSkew), nooa) {
+		return nil, fmt.Errorf("saml: assertion has expired")
+	}
+	audienceOK := false
+	for _, restriction := range conditions.childElements(nsAssertion, "AudienceRestriction") {
+		for _, audience := range restriction.childElements(nsAssertion, "Audience") {
+			if audience.text() == sp.config.EntityID {
+				audienceOK = true
+			}
+		}
+	}
+	if !audienceOK {
+		return nil, fmt.Errorf("saml: assertion is not addressed to this service provider")
+	}
+
+	nameID := subject.child(nsAssertion, "NameID")
+	identity := &Identity{Issuer: sp.idpEntityID, Subject: nameID.text()}
+	if statement := assertion.child(nsAssertion, "AttributeStatement"); statement != nil {
+		for _, attr := range statement.childElements(nsAssertion, "Attribute") {
+			value := attr.child(nsAssertion, "AttributeValue")
+			if value == nil {
+				continue
+			}
+			switch sp.config.EmailAttribute {
+			case attr.attr("Name"), attr.attr("FriendlyName"):
+				identity.Email = value.text()
+			}
+			switch sp.config.NameAttribute {
+			case attr.attr("Name"), attr.attr("FriendlyName"):
+				identity.Name = value.text()
+			}
+		}
+	}
+	if identity.Email == "" && nameID.attr("Format") == nameIDEmail {
+		identity.Email = identity.Subject
+	}
+	identity.Email = strings.ToLower(identity.Email)
+	return identity, nil
+}
+
+// before reports whether t is strictly before the xs:dateTime value.
+func before(t time.Time, value string) bool {
+	limit, err := time.Parse(time.RFC3339Nano, value)
+	return err == nil && t.Before(limit)
+}
+
+func fetch(ctx context.Context, target string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s returned status %d", target, resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
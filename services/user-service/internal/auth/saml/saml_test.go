@@ -0,0 +1,212 @@
+// services/user-service/internal/auth/saml/saml_test.go
+package saml
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// memoryRequests is a RequestStore holding pending request IDs in a map.
+type memoryRequests map[string]bool
+
+func (m memoryRequests) CreateSAMLRequest(_ context.Context, id string, _ time.Time) error {
+	m[id] = true
+	return nil
+}
+
+func (m memoryRequests) ConsumeSAMLRequest(_ context.Context, id string) (bool, error) {
+	pending := m[id]
+	delete(m, id)
+	return pending, nil
+}
+
+// failingRequests is a RequestStore that is down.
+type failingRequests struct{}
+
+func (failingRequests) CreateSAMLRequest(context.Context, string, time.Time) error {
+	return errors.New("connection refused")
+}
+
+func (failingRequests) ConsumeSAMLRequest(context.Context, string) (bool, error) {
+	return false, errors.New("connection refused")
+}
+
+const (
+	testEntityID  = "https://pulse.example.com/auth/saml/metadata"
+	testACSURL    = "https://pulse.example.com/auth/saml/acs"
+	testIdP       = "https://idp.example.com"
+	testRequestID = "_req1"
+)
+
+func newTestServiceProvider(requests RequestStore, certs ...*x509.Certificate) *ServiceProvider {
+	return &ServiceProvider{
+		config: Config{
+			EntityID:       testEntityID,
+			ACSURL:         testACSURL,
+			EmailAttribute: "email",
+			NameAttribute:  "displayName",
+		},
+		idpEntityID: testIdP,
+		ssoURL:      testIdP + "/sso",
+		certs:       certs,
+		requests:    requests,
+	}
+}
+
+// responseTemplate is a successful response to testRequestID. {response} and {assertion} are where the
+// signature of either goes; the times are filled in relative to now.
+const responseTemplate = `<samlp:Response xmlns:samlp="` + nsProtocol + `" xmlns:saml="` + nsAssertion + `" ID="_resp" Version="2.0" InResponseTo="{request}" Destination="` + testACSURL + `">` +
+	`<saml:Issuer>` + testIdP + `</saml:Issuer>{response}` +
+	`<samlp:Status><samlp:StatusCode Value="` + statusSuccess + `"/></samlp:Status>` +
+	`<saml:Assertion ID="_assert" Version="2.0">` +
+	`<saml:Issuer>` + testIdP + `</saml:Issuer>{assertion}` +
+	`<saml:Subject><saml:NameID Format="` + nameIDEmail + `">Ann@Example.com</saml:NameID>` +
+	`<saml:SubjectConfirmation Method="` + confirmBearer + `">` +
+	`<saml:SubjectConfirmationData Recipient="` + testACSURL + `" NotOnOrAfter="{later}" InResponseTo="{request}"/>` +
+	`</saml:SubjectConfirmation></saml:Subject>` +
+	`<saml:Conditions NotBefore="{earlier}" NotOnOrAfter="{later}">` +
+	`<saml:AudienceRestriction><saml:Audience>{audience}</saml:Audience></saml:AudienceRestriction></saml:Conditions>` +
+	`<saml:AttributeStatement><saml:Attribute Name="displayName"><saml:AttributeValue>Ann Lee</saml:AttributeValue></saml:Attribute></saml:AttributeStatement>` +
+	`</saml:Assertion></samlp:Response>`
+
+// response fills in responseTemplate, with sigMarker where the element named by signed ("response",
+// "assertion", or "" for neither) is signed. edits are applied to the result as old, new pairs.
+func response(signed string, now time.Time, edits ...string) string {
+	doc := strings.NewReplacer(
+		"{request}", testRequestID,
+		"{audience}", testEntityID,
+		"{earlier}", now.Add(-5*time.Minute).UTC().Format(time.RFC3339),
+		"{later}", now.Add(5*time.Minute).UTC().Format(time.RFC3339),
+	).Replace(responseTemplate)
+	for _, place := range []string{"response", "assertion"} {
+		marker := ""
+		if place == signed {
+			marker = sigMarker
+		}
+		doc = strings.Replace(doc, "{"+place+"}", marker, 1)
+	}
+	return strings.NewReplacer(edits...).Replace(doc)
+}
+
+// signedAssertion returns the assertion of a document signed by sign, signature included.
+func signedAssertion(doc string) string {
+	start := strings.Index(doc, "<saml:Assertion ")
+	end := strings.Index(doc, "</saml:Assertion>") + len("</saml:Assertion>")
+	return doc[start:end]
+}
+
+func TestParseResponse(t *testing.T) {
+	idp := newSigner(t, "idp")
+	attacker := newSigner(t, "attacker")
+	now := time.Now()
+	signAssertion := func(s *signer, edits ...string) string {
+		return s.sign(t, response("assertion", now, edits...), "_assert", "")
+	}
+	signResponse := func(s *signer, edits ...string) string {
+		return s.sign(t, response("response", now, edits...), "_resp", "")
+	}
+	want := &Identity{Issuer: testIdP, Subject: "Ann@Example.com", Email: "ann@example.com", Name: "Ann Lee"}
+
+	// The assertion as signed by the IdP, moved aside while a forged copy with the same ID takes its place.
+	wrapped := func(signed string) string {
+		genuine := signedAssertion(signed)
+		forged := strings.Replace(genuine, "Ann@Example.com", "admin@example.com", 1)
+		return strings.Replace(signed, genuine, `<samlp:Extensions>`+genuine+`</samlp:Extensions>`+forged, 1)
+	}
+
+	tests := []struct {
+		name      string
+		doc       string
+		requestID string
+		want      string // "" if the response is accepted
+	}{
+		{"signed assertion", signAssertion(idp), testRequestID, ""},
+		{"signed response", signResponse(idp), testRequestID, ""},
+		{"embedded certificate of the IdP", idp.sign(t, response("assertion", now), "_assert", idp.keyInfo()), testRequestID, ""},
+
+		{"unsigned", response("", now), testRequestID, "neither the response nor the assertion is signed"},
+		{"assertion tampered", strings.Replace(signAssertion(idp), "Ann@Example.com", "admin@example.com", 1), testRequestID, "digest mismatch"},
+		{"assertion tampered under a signed response", strings.Replace(signResponse(idp), "Ann Lee", "Admin", 1), testRequestID, "digest mismatch"},
+		{"signature value tampered", tamperSignatureValue(t, signAssertion(idp)), testRequestID, "does not verify"},
+		{"untrusted certificate", signAssertion(attacker), testRequestID, "does not verify"},
+		{"attacker's embedded certificate", attacker.sign(t, response("assertion", now), "_assert", attacker.keyInfo()), testRequestID, "does not verify"},
+		{"signature moved to the response", strings.Replace(signResponse(idp), `ID="_resp"`, `ID="_other"`, 1), testRequestID, "does not reference the signed element"},
+
+		{"duplicate ID wrapping the signed assertion", wrapped(signAssertion(idp)), testRequestID, `duplicate ID "_assert"`},
+		{"duplicate ID wrapping the signed response", wrapped(signResponse(idp)), testRequestID, `duplicate ID "_assert"`},
+		{"second assertion", strings.Replace(signResponse(idp), "</samlp:Response>", `<saml:Assertion ID="_second"/></samlp:Response>`, 1),
+			testRequestID, "exactly one assertion"},
+		{"encrypted assertion", strings.Replace(signResponse(idp), "</samlp:Response>", `<saml:EncryptedAssertion/></samlp:Response>`, 1),
+			testRequestID, "encrypted assertions are not supported"},
+
+		{"other request", signAssertion(idp), "_req2", "does not answer a pending request"},
+		{"no request", signAssertion(idp), "", "does not answer a pending request"},
+		{"unsolicited", signAssertion(idp, ` InResponseTo="`+testRequestID+`"`, ""), testRequestID, "does not answer a pending request"},
+		{"other destination", signResponse(idp, testACSURL+`">`, `https://evil.example.com/acs">`), testRequestID, "is not this service"},
+		{"other response issuer", signResponse(idp, `<saml:Issuer>`+testIdP+`</saml:Issuer>`+sigMarker, `<saml:Issuer>https://evil.example.com</saml:Issuer>`+sigMarker),
+			testRequestID, "is not the configured IdP"},
+		{"failed status", signResponse(idp, statusSuccess, "urn:oasis:names:tc:SAML:2.0:status:Requester"), testRequestID, "did not report success"},
+		{"other assertion issuer", signAssertion(idp, `<saml:Issuer>`+testIdP+`</saml:Issuer>`+sigMarker, `<saml:Issuer>https://evil.example.com</saml:Issuer>`+sigMarker),
+			testRequestID, "not issued by the configured IdP"},
+		{"other audience", signAssertion(idp, `<saml:Audience>`+testEntityID, `<saml:Audience>https://other.example.com`), testRequestID, "not addressed to this service provider"},
+		{"other recipient", signAssertion(idp, `Recipient="`+testACSURL, `Recipient="https://evil.example.com/acs`), testRequestID, "no valid bearer subject confirmation"},
+		{"expired", idp.sign(t, response("assertion", now.Add(-time.Hour)), "_assert", ""), testRequestID, "no valid bearer subject confirmation"},
+		{"not yet valid", idp.sign(t, response("assertion", now.Add(time.Hour)), "_assert", ""), testRequestID, "not yet valid"},
+		{"not a response", idp.sign(t, `<saml:Assertion xmlns:saml="`+nsAssertion+`" ID="_assert">`+sigMarker+`</saml:Assertion>`, "_assert", ""),
+			testRequestID, "not a SAML Response"},
+		{"DOCTYPE", `<!DOCTYPE r [<!ENTITY e "x">]>` + signAssertion(idp), testRequestID, "directives are not allowed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sp := newTestServiceProvider(memoryRequests{testRequestID: true}, idp.cert)
+			encoded := base64.StdEncoding.EncodeToString([]byte(tt.doc))
+			got, err := sp.ParseResponse(context.Background(), encoded, tt.requestID)
+			if tt.want != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.want) {
+					t.Fatalf("ParseResponse() = %+v, %v; want an error containing %q", got, err, tt.want)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("ParseResponse() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestParseResponseConsumesRequest(t *testing.T) {
+	idp := newSigner(t, "idp")
+	encoded := base64.StdEncoding.EncodeToString([]byte(idp.sign(t, response("assertion", time.Now()), "_assert", "")))
+
+	requests := memoryRequests{testRequestID: true}
+	sp := newTestServiceProvider(requests, idp.cert)
+	if _, err := sp.ParseResponse(context.Background(), encoded, testRequestID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sp.ParseResponse(context.Background(), encoded, testRequestID); err == nil {
+		t.Fatal("ParseResponse() accepted a response to a request that was already answered")
+	}
+
+	// A rejected response leaves the request pending for the genuine one.
+	requests[testRequestID] = true
+	if _, err := sp.ParseResponse(context.Background(), base64.StdEncoding.EncodeToString([]byte(response("", time.Now()))), testRequestID); err == nil {
+		t.Fatal("ParseResponse() accepted an unsigned response")
+	}
+	if !requests[testRequestID] {
+		t.Error("a rejected response consumed the request")
+	}
+
+	down := newTestServiceProvider(failingRequests{}, idp.cert)
+	if _, err := down.ParseResponse(context.Background(), encoded, testRequestID); !errors.Is(err, ErrRequestStore) {
+		t.Errorf("ParseResponse() with the store down = %v, want ErrRequestStore", err)
+	}
+}
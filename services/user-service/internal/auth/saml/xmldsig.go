@@ -0,0 +1,442 @@
+// services/user-service/internal/auth/saml/xmldsig.go
+package saml
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// XML namespaces and XML Signature algorithm identifiers used by SAML.
+const (
+	nsXML       = "http://www.w3.org/XML/1998/namespace"
+	nsDSig      = "http://www.w3.org/2000/09/xmldsig#"
+	nsExcC14N   = "http://www.w3.org/2001/10/xml-exc-c14n#"
+	algExcC14N  = "http://www.w3.org/2001/10/xml-exc-c14n#"
+	algEnvSig   = "http://www.w3.org/2000/09/xmldsig#enveloped-signature"
+	algSHA256   = "http://www.w3.org/2001/04/xmlenc#sha256"
+	algSHA512   = "http://www.w3.org/2001/04/xmlenc#sha512"
+	algRSA256   = "http://www.w3.org/2001/04/xmldsig-more#rsa-sha256"
+	algRSA512   = "http://www.w3.org/2001/04/xmldsig-more#rsa-sha512"
+	maxDocDepth = 64
+)
+
+// element is a parsed XML element that keeps the namespace prefixes as written,
+// which exclusive canonicalization needs and encoding/xml's Unmarshal discards.
+type element struct {
+	prefix, local string
+	space         string            // Resolved namespace URI
+	attrs         []xml.Attr        // As written; Name.Space holds the prefix
+	decls         map[string]string // Namespace declarations on this element, by prefix ("" is the default namespace)
+	children      []interface{}     // *element, xml.CharData, or xml.ProcInst
+	parent        *element
+}
+
+// parseDocument parses raw XML into an element tree. Comments are dropped, and documents
+// with a DOCTYPE are rejected so entity declarations can never take part.
+func parseDocument(data []byte) (*element, error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	var root, cur *element
+	depth := 0
+	for {
+		tok, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("saml: malformed XML: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if root != nil && cur == nil {
+				return nil, fmt.Errorf("saml: malformed XML: more than one root element")
+			}
+			if depth++; depth > maxDocDepth {
+				return nil, fmt.Errorf("saml: malformed XML: nesting too deep")
+			}
+			el := &element{prefix: t.Name.Space, local: t.Name.Local, decls: map[string]string{}, parent: cur}
+			for _, a := range t.Attr {
+				switch {
+				case a.Name.Space == "xmlns":
+					el.decls[a.Name.Local] = a.Value
+				case a.Name.Space == "" && a.Name.Local == "xmlns":
+					el.decls[""] = a.Value
+				default:
+					el.attrs = append(el.attrs, a)
+				}
+			}
+			space, ok := el.lookup(el.prefix)
+			if !ok && el.prefix != "" {
+				return nil, fmt.Errorf("saml: malformed XML: undeclared prefix %q", el.prefix)
+			}
+			el.space = space
+			if cur == nil {
+				root = el
+			} else {
+				cur.children = append(cur.children, el)
+			}
+			cur = el
+		case xml.EndElement:
+			if cur == nil || t.Name.Space != cur.prefix || t.Name.Local != cur.local {
+				return nil, fmt.Errorf("saml: malformed XML: unexpected end element %s", t.Name.Local)
+			}
+			cur = cur.parent
+			depth--
+		case xml.CharData:
+			if cur != nil {
+				cur.children = append(cur.children, t.Copy())
+			}
+		case xml.ProcInst:
+			if cur != nil {
+				cur.children = append(cur.children, t.Copy())
+			}
+		case xml.Directive:
+			return nil, fmt.Errorf("saml: XML directives are not allowed")
+		}
+	}
+	if root == nil || cur != nil {
+		return nil, fmt.Errorf("saml: malformed XML: incomplete document")
+	}
+	return root, nil
+}
+
+// lookup resolves a namespace prefix in the scope of e.
+func (e *element) lookup(prefix string) (string, bool) {
+	if prefix == "xml" {
+		return nsXML, true
+	}
+	for n := e; n != nil; n = n.parent {
+		if uri, ok := n.decls[prefix]; ok {
+			return uri, true
+		}
+	}
+	return "", prefix == ""
+}
+
+// attr returns the value of the unqualified attribute name.
+func (e *element) attr(name string) string {
+	for _, a := range e.attrs {
+		if a.Name.Space == "" && a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// child returns the first child element with the given namespace and local name.
+func (e *element) child(space, local string) *element {
+	for _, c := range e.childElements(space, local) {
+		return c
+	}
+	return nil
+}
+
+// childElements returns the child elements with the given namespace and local name.
+func (e *element) childElements(space, local string) []*element {
+	var out []*element
+	for _, c := range e.children {
+		if el, ok := c.(*element); ok && el.space == space && el.local == local {
+			out = append(out, el)
+		}
+	}
+	return out
+}
+
+// text returns the element's character data, trimmed.
+func (e *element) text() string {
+	var b strings.Builder
+	for _, c := range e.children {
+		if cd, ok := c.(xml.CharData); ok {
+			b.Write(cd)
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// collectIDs counts every ID attribute in the tree; a signed ID that appears twice is a wrapping attack.
+func (e *element) collectIDs(seen map[string]int) {
+	if id := e.attr("ID"); id != "" {
+		seen[id]++
+	}
+	for _, c := range e.children {
+		if el, ok := c.(*element); ok {
+			el.collectIDs(seen)
+		}
+	}
+}
+
+// canonicalize serializes the subtree rooted at e with Exclusive XML Canonicalization (without comments),
+// leaving out skip (the enveloped signature) if it is non-nil.
+func canonicalize(e *element, skip *element, inclusivePrefixes []string) []byte {
+	var buf bytes.Buffer
+	c := &canonicalizer{buf: &buf, skip: skip, inclusive: map[string]bool{}}
+	for _, p := range inclusivePrefixes {
+		if p == "#default" {
+			p = ""
+		}
+		c.inclusive[p] = true
+	}
+	c.write(e, map[string]string{})
+	return buf.Bytes()
+}
+
+type canonicalizer struct {
+	buf       *bytes.Buffer
+	skip      *element
+	inclusive map[string]bool
+}
+
+func (c *canonicalizer) write(e *element, rendered map[string]string) {
+	// Namespaces are output where they are visibly used (or listed as inclusive) and not already in effect in the output.
+	needed := map[string]bool{e.prefix: true}
+	for _, a := range e.attrs {
+		if a.Name.Space != "" && a.Name.Space != "xml" {
+			needed[a.Name.Space] = true
+		}
+	}
+	for p := range c.inclusive {
+		if _, ok := e.lookup(p); ok {
+			needed[p] = true
+		}
+	}
+	var prefixes []string
+	for p := range needed {
+		uri, _ := e.lookup(p)
+		prev, wasRendered := rendered[p]
+		if p == "" && uri == "" && (!wasRendered || prev == "") {
+			continue
+		}
+		if wasRendered && prev == uri {
+			continue
+		}
+		prefixes = append(prefixes, p)
+	}
+	sort.Strings(prefixes)
+
+	scope := rendered
+	if len(prefixes) > 0 {
+		scope = make(map[string]string, len(rendered)+len(prefixes))
+		for p, uri := range rendered {
+			scope[p] = uri
+		}
+	}
+
+	c.buf.WriteByte('<')
+	c.buf.WriteString(qualified(e.prefix, e.local))
+	for _, p := range prefixes {
+		uri, _ := e.lookup(p)
+		scope[p] = uri
+		if p == "" {
+			c.buf.WriteString(` xmlns="`)
+		} else {
+			c.buf.WriteString(` xmlns:` + p + `="`)
+		}
+		escapeAttr(c.buf, uri)
+		c.buf.WriteByte('"')
+	}
+
+	attrs := make([]xml.Attr, len(e.attrs))
+	copy(attrs, e.attrs)
+	attrSpace := func(a xml.Attr) string {
+		if a.Name.Space == "" {
+			return ""
+		}
+		uri, _ := e.lookup(a.Name.Space)
+		return uri
+	}
+	sort.SliceStable(attrs, func(i, j int) bool {
+		si, sj := attrSpace(attrs[i]), attrSpace(attrs[j])
+		if si != sj {
+			return si < sj
+		}
+		return attrs[i].Name.Local < attrs[j].Name.Local
+	})
+	for _, a := range attrs {
+		c.buf.WriteString(" " + qualified(a.Name.Space, a.Name.Local) + `="`)
+		escapeAttr(c.buf, a.Value)
+		c.buf.WriteByte('"')
+	}
+	c.buf.WriteByte('>')
+
+	for _, child := range e.children {
+		switch t := child.(type) {
+		case *element:
+			if t != c.skip {
+				c.write(t, scope)
+			}
+		case xml.CharData:
+			escapeText(c.buf, string(t))
+		case xml.ProcInst:
+			c.buf.WriteString("<?" + t.Target)
+			if len(t.Inst) > 0 {
+				c.buf.WriteByte(' ')
+				c.buf.Write(t.Inst)
+			}
+			c.buf.WriteString("?>")
+		}
+	}
+	c.buf.WriteString("</" + qualified(e.prefix, e.local) + ">")
+}
+
+func qualified(prefix, local string) string {
+	if prefix == "" {
+		return local
+	}
+	return prefix + ":" + local
+}
+
+func escapeText(buf *bytes.Buffer, s string) {
+	for _, r := range s {
+		switch r {
+		case '&':
+			buf.WriteString("&amp;")
+		case '<':
+			buf.WriteString("&lt;")
+		case '>':
+			buf.WriteString("&gt;")
+		case '\r':
+			buf.WriteString("&#xD;")
+		default:
+			buf.WriteRune(r)
+		}
+	}
+}
+
+func escapeAttr(buf *bytes.Buffer, s string) {
+	for _, r := range s {
+		switch r {
+		case '&':
+			buf.WriteString("&amp;")
+		case '<':
+			buf.WriteString("&lt;")
+		case '"':
+			buf.WriteString("&quot;")
+		case '\t':
+			buf.WriteString("&#x9;")
+		case '\n':
+			buf.WriteString("&#xA;")
+		case '\r':
+			buf.WriteString("&#xD;")
+		default:
+			buf.WriteRune(r)
+		}
+	}
+}
+
+// errNotSigned is returned by verifySignature when the element has no enveloped signature.
+var errNotSigned = fmt.Errorf("saml: element is not signed")
+
+// verifySignature checks the enveloped XML signature that is a direct child of e. The signature must cover
+// exactly e (a single same-document reference to its ID) and verify against one of the trusted certificates;
+// any key material embedded in the signature itself is ignored.
+func verifySignature(e *element, certs []*x509.Certificate) error {
+	sig := e.child(nsDSig, "Signature")
+	if sig == nil {
+		return errNotSigned
+	}
+	signedInfo := sig.child(nsDSig, "SignedInfo")
+	if signedInfo == nil {
+		return fmt.Errorf("saml: signature has no SignedInfo")
+	}
+
+	c14n := signedInfo.child(nsDSig, "CanonicalizationMethod")
+	if c14n == nil || c14n.attr("Algorithm") != algExcC14N {
+		return fmt.Errorf("saml: unsupported canonicalization method")
+	}
+	var hash crypto.Hash
+	switch method := signedInfo.child(nsDSig, "SignatureMethod"); {
+	case method == nil:
+		return fmt.Errorf("saml: signature has no SignatureMethod")
+	case method.attr("Algorithm") == algRSA256:
+		hash = crypto.SHA256
+	case method.attr("Algorithm") == algRSA512:
+		hash = crypto.SHA512
+	default:
+		return fmt.Errorf("saml: unsupported signature method %q", method.attr("Algorithm"))
+	}
+
+	refs := signedInfo.childElements(nsDSig, "Reference")
+	if len(refs) != 1 {
+		return fmt.Errorf("saml: signature must have exactly one reference")
+	}
+	ref := refs[0]
+	if id := e.attr("ID"); id == "" || ref.attr("URI") != "#"+id {
+		return fmt.Errorf("saml: signature does not reference the signed element")
+	}
+
+	var prefixes []string
+	if transforms := ref.child(nsDSig, "Transforms"); transforms != nil {
+		for _, t := range transforms.childElements(nsDSig, "Transform") {
+			switch t.attr("Algorithm") {
+			case algEnvSig:
+			case algExcC14N:
+				prefixes = inclusiveNamespaces(t)
+			default:
+				return fmt.Errorf("saml: unsupported transform %q", t.attr("Algorithm"))
+			}
+		}
+	}
+
+	var digest []byte
+	switch method := ref.child(nsDSig, "DigestMethod"); {
+	case method == nil:
+		return fmt.Errorf("saml: reference has no DigestMethod")
+	case method.attr("Algorithm") == algSHA256:
+		sum := sha256.Sum256(canonicalize(e, sig, prefixes))
+		digest = sum[:]
+	case method.attr("Algorithm") == algSHA512:
+		sum := sha512.Sum512(canonicalize(e, sig, prefixes))
+		digest = sum[:]
+	default:
+		return fmt.Errorf("saml: unsupported digest method %q", method.attr("Algorithm"))
+	}
+	digestValue := ref.child(nsDSig, "DigestValue")
+	if digestValue == nil {
+		return fmt.Errorf("saml: reference has no DigestValue")
+	}
+	expected, err := decodeBase64(digestValue.text())
+	if err != nil || subtle.ConstantTimeCompare(digest, expected) != 1 {
+		return fmt.Errorf("saml: digest mismatch")
+	}
+
+	signatureValue := sig.child(nsDSig, "SignatureValue")
+	if signatureValue == nil {
+		return fmt.Errorf("saml: signature has no SignatureValue")
+	}
+	signature, err := decodeBase64(signatureValue.text())
+	if err != nil {
+		return fmt.Errorf("saml: malformed SignatureValue: %w", err)
+	}
+	h := hash.New()
+	h.Write(canonicalize(signedInfo, nil, inclusiveNamespaces(c14n)))
+	hashed := h.Sum(nil)
+	for _, cert := range certs {
+		if key, ok := cert.PublicKey.(*rsa.PublicKey); ok && rsa.VerifyPKCS1v15(key, hash, hashed, signature) == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("saml: signature does not verify with any trusted certificate")
+}
+
+// inclusiveNamespaces returns the PrefixList of an exclusive canonicalization method or transform.
+func inclusiveNamespaces(method *element) []string {
+	if in := method.child(nsExcC14N, "InclusiveNamespaces"); in != nil {
+		return strings.Fields(in.attr("PrefixList"))
+	}
+	return nil
+}
+
+// decodeBase64 decodes standard base64, ignoring the line breaks and indentation XML documents often add.
+func decodeBase64(s string) ([]byte, error) {
+	return base64.StdEncoding.DecodeString(strings.Join(strings.Fields(s), ""))
+}
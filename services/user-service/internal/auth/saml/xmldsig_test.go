@@ -0,0 +1,236 @@
+// services/user-service/internal/auth/saml/xmldsig_test.go
+package saml
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+// signer holds an RSA key and a self-signed certificate for it, as an IdP's signing key.
+type signer struct {
+	key  *rsa.PrivateKey
+	cert *x509.Certificate
+}
+
+func newSigner(t *testing.T, name string) *signer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &signer{key: key, cert: cert}
+}
+
+// sigMarker marks where sign puts the enveloped signature of an element: its first child.
+const sigMarker = "<!--signature-->"
+
+// sign returns doc with an enveloped signature over the element whose ID is id put in place of
+// sigMarker. The signature is computed independently of verifySignature, apart from canonicalization.
+// keyInfo, if set, is embedded in the signature as given.
+func (s *signer) sign(t *testing.T, doc, id, keyInfo string) string {
+	t.Helper()
+	unsigned := strings.Replace(doc, sigMarker, "", 1)
+	root, err := parseDocument([]byte(unsigned))
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := findID(root, id)
+	if target == nil {
+		t.Fatalf("no element with ID %q", id)
+	}
+	digest := sha256.Sum256(canonicalize(target, nil, nil))
+
+	signedInfo := `<ds:SignedInfo>` +
+		`<ds:CanonicalizationMethod Algorithm="` + algExcC14N + `"/>` +
+		`<ds:SignatureMethod Algorithm="` + algRSA256 + `"/>` +
+		`<ds:Reference URI="#` + id + `"><ds:Transforms>` +
+		`<ds:Transform Algorithm="` + algEnvSig + `"/><ds:Transform Algorithm="` + algExcC14N + `"/>` +
+		`</ds:Transforms><ds:DigestMethod Algorithm="` + algSHA256 + `"/>` +
+		`<ds:DigestValue>` + base64.StdEncoding.EncodeToString(digest[:]) + `</ds:DigestValue>` +
+		`</ds:Reference></ds:SignedInfo>`
+	sig, err := parseDocument([]byte(`<ds:Signature xmlns:ds="` + nsDSig + `">` + signedInfo + `</ds:Signature>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashed := sha256.Sum256(canonicalize(sig.child(nsDSig, "SignedInfo"), nil, nil))
+	value, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, hashed[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signature := `<ds:Signature xmlns:ds="` + nsDSig + `">` + signedInfo +
+		"<ds:SignatureValue>\n" + base64.StdEncoding.EncodeToString(value) + "\n</ds:SignatureValue>" + keyInfo + `</ds:Signature>`
+	return strings.Replace(doc, sigMarker, signature, 1)
+}
+
+// keyInfo embeds the certificate of s in a signature, as IdPs often do.
+func (s *signer) keyInfo() string {
+	return `<ds:KeyInfo><ds:X509Data><ds:X509Certificate>` + base64.StdEncoding.EncodeToString(s.cert.Raw) +
+		`</ds:X509Certificate></ds:X509Data></ds:KeyInfo>`
+}
+
+// findID returns the element of the tree with the given ID attribute.
+func findID(e *element, id string) *element {
+	if e.attr("ID") == id {
+		return e
+	}
+	for _, c := range e.children {
+		if el, ok := c.(*element); ok {
+			if found := findID(el, id); found != nil {
+				return found
+			}
+		}
+	}
+	return nil
+}
+
+func TestCanonicalize(t *testing.T) {
+	tests := []struct {
+		name      string
+		doc       string
+		inclusive []string
+		want      string
+	}{
+		{"empty element expanded", `<a/>`, nil, `<a></a>`},
+		{"attributes sorted", `<a z="1" b="2" a="3"/>`, nil, `<a a="3" b="2" z="1"></a>`},
+		{"attribute quotes and whitespace", `<a v='say "hi"&#9;&#10;'/>`, nil, `<a v="say &quot;hi&quot;&#x9;&#xA;"></a>`},
+		{"text escaped", `<a>1 &lt; 2 &amp;&amp; 3 &gt; 2</a>`, nil, `<a>1 &lt; 2 &amp;&amp; 3 &gt; 2</a>`},
+		{"comments dropped", `<a><!-- note -->x</a>`, nil, `<a>x</a>`},
+		{"unused namespace dropped", `<a xmlns:x="urn:x"><b/></a>`, nil, `<a><b></b></a>`},
+		{"namespace pushed down to its use", `<a xmlns:x="urn:x"><x:b/><x:c/></a>`, nil,
+			`<a><x:b xmlns:x="urn:x"></x:b><x:c xmlns:x="urn:x"></x:c></a>`},
+		{"namespace not repeated below its use", `<x:a xmlns:x="urn:x"><x:b/></x:a>`, nil,
+			`<x:a xmlns:x="urn:x"><x:b></x:b></x:a>`},
+		{"attribute namespace", `<a xmlns:x="urn:x" x:k="v"/>`, nil, `<a xmlns:x="urn:x" x:k="v"></a>`},
+		{"attributes sorted by namespace first", `<a xmlns:y="urn:a" xmlns:x="urn:b" x:k="1" y:k="2" k="3"/>`, nil,
+			`<a xmlns:x="urn:b" xmlns:y="urn:a" k="3" y:k="2" x:k="1"></a>`},
+		{"default namespace", `<a xmlns="urn:d"><b/></a>`, nil, `<a xmlns="urn:d"><b></b></a>`},
+		{"inclusive prefix kept", `<a xmlns:x="urn:x"><b/></a>`, []string{"x"}, `<a xmlns:x="urn:x"><b></b></a>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, err := parseDocument([]byte(tt.doc))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := string(canonicalize(root, nil, tt.inclusive)); got != tt.want {
+				t.Errorf("canonicalize() = %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDocumentRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		want string
+	}{
+		{"DOCTYPE", `<!DOCTYPE a [<!ENTITY e "x">]><a>&e;</a>`, "directives are not allowed"},
+		{"two roots", `<a/><b/>`, "more than one root element"},
+		{"unclosed", `<a><b></b>`, "incomplete document"},
+		{"mismatched end", `<a></b>`, "unexpected end element"},
+		{"undeclared prefix", `<x:a/>`, "undeclared prefix"},
+		{"too deep", strings.Repeat("<a>", maxDocDepth+1) + strings.Repeat("</a>", maxDocDepth+1), "nesting too deep"},
+		{"empty", ``, "incomplete document"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseDocument([]byte(tt.doc)); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("parseDocument() error = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifySignature(t *testing.T) {
+	idp := newSigner(t, "idp")
+	attacker := newSigner(t, "attacker")
+	const doc = `<r:Root xmlns:r="urn:root" ID="_root">` + sigMarker + `<r:Name>Ann</r:Name><r:Role>user</r:Role></r:Root>`
+	signed := idp.sign(t, doc, "_root", "")
+
+	tests := []struct {
+		name  string
+		doc   string
+		certs []*x509.Certificate
+		want  string // "" if the signature verifies
+	}{
+		{"valid", signed, []*x509.Certificate{idp.cert}, ""},
+		{"one of several trusted certificates", signed, []*x509.Certificate{attacker.cert, idp.cert}, ""},
+		{"whitespace in signature value", strings.Replace(signed, "<ds:SignatureValue>", "<ds:SignatureValue>\n  \t", 1), []*x509.Certificate{idp.cert}, ""},
+		{"not signed", strings.Replace(doc, sigMarker, "", 1), []*x509.Certificate{idp.cert}, "not signed"},
+		{"content tampered", strings.Replace(signed, "<r:Role>user</r:Role>", "<r:Role>admin</r:Role>", 1),
+			[]*x509.Certificate{idp.cert}, "digest mismatch"},
+		{"element added", strings.Replace(signed, "</r:Root>", "<r:Role>admin</r:Role></r:Root>", 1),
+			[]*x509.Certificate{idp.cert}, "digest mismatch"},
+		{"attribute added", strings.Replace(signed, `ID="_root"`, `ID="_root" Admin="true"`, 1),
+			[]*x509.Certificate{idp.cert}, "digest mismatch"},
+		{"signed info tampered", strings.Replace(signed, `URI="#_root"`, `URI="#_root" Id="x"`, 1),
+			[]*x509.Certificate{idp.cert}, "does not verify"},
+		{"signature value tampered", tamperSignatureValue(t, signed), []*x509.Certificate{idp.cert}, "does not verify"},
+		{"untrusted certificate", attacker.sign(t, doc, "_root", ""), []*x509.Certificate{idp.cert}, "does not verify"},
+		{"embedded certificate ignored", attacker.sign(t, doc, "_root", attacker.keyInfo()), []*x509.Certificate{idp.cert}, "does not verify"},
+		{"no trusted certificates", signed, nil, "does not verify"},
+		{"reference to another element", strings.Replace(signed, `ID="_root"`, `ID="_other"`, 1),
+			[]*x509.Certificate{idp.cert}, "does not reference the signed element"},
+		{"unsupported signature method", strings.Replace(signed, algRSA256, "http://www.w3.org/2000/09/xmldsig#rsa-sha1", 1),
+			[]*x509.Certificate{idp.cert}, "unsupported signature method"},
+		{"unsupported digest method", strings.Replace(signed, algSHA256, "http://www.w3.org/2000/09/xmldsig#sha1", 1),
+			[]*x509.Certificate{idp.cert}, "unsupported digest method"},
+		{"unsupported transform", strings.Replace(signed, `<ds:Transform Algorithm="`+algEnvSig+`"/>`,
+			`<ds:Transform Algorithm="http://www.w3.org/TR/1999/REC-xslt-19991116"/>`, 1), []*x509.Certificate{idp.cert}, "unsupported transform"},
+		{"two references", strings.Replace(signed, "</ds:SignedInfo>", `<ds:Reference URI="#_root"/></ds:SignedInfo>`, 1),
+			[]*x509.Certificate{idp.cert}, "exactly one reference"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, err := parseDocument([]byte(tt.doc))
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = verifySignature(root, tt.certs)
+			switch {
+			case tt.want == "" && err != nil:
+				t.Fatalf("verifySignature() = %v, want it to verify", err)
+			case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+				t.Fatalf("verifySignature() = %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+// tamperSignatureValue flips a bit of the signature value of a signed document.
+func tamperSignatureValue(t *testing.T, doc string) string {
+	t.Helper()
+	start := strings.Index(doc, "<ds:SignatureValue>") + len("<ds:SignatureValue>")
+	end := strings.Index(doc, "</ds:SignatureValue>")
+	value, err := decodeBase64(doc[start:end])
+	if err != nil {
+		t.Fatal(err)
+	}
+	value[len(value)/2] ^= 0x01
+	return doc[:start] + base64.StdEncoding.EncodeToString(value) + doc[end:]
+}
@@ -0,0 +1,318 @@
+// services/user-service/internal/blobstore/blobstore.go
+package blobstore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned by Get for a key that does not exist.
+var ErrNotFound = errors.New("blob not found")
+
+// ErrChecksumMismatch is returned when an object's bytes no longer match the checksum stored with them.
+var ErrChecksumMismatch = errors.New("blob checksum mismatch")
+
+// Store defines the interface for storing binary objects such as message attachments.
+// Implementations can wrap S3, GCS, or a local directory.
+type Store interface {
+	Put(key string, r io.Reader) (int64, error) // Returns the number of bytes stored
+	Get(key string) (io.ReadCloser, error)
+	Delete(key string) error // Deleting a missing key is not an error
+}
+
+// Object describes a stored object.
+type Object struct {
+	Key     string
+	Size    int64
+	ModTime time.Time // When it was stored; kept when it moves between tiers
+}
+
+// validKey keeps keys to slash-separated safe segments, so a key can never escape the store's root.
+var validKey = regexp.MustCompile(`^[A-Za-z0-9_-]+(/[A-Za-z0-9_.-]+)*$`)
+
+// checksumDir holds the SHA-256 of each object under the object's key. Keys cannot start with a
+// dot, so it never collides with an object.
+const checksumDir = ".sha256"
+
+// uploadPattern names the temporary files Put writes before renaming them into place.
+const uploadPattern = ".upload-*"
+
+// DirStore is a Store that keeps each object as a file under a root directory.
+// It suits development and single-instance deployments with a persistent volume.
+type DirStore struct {
+	root string
+}
+
+// NewDirStore creates a DirStore rooted at dir, creating the directory if needed.
+func NewDirStore(dir string) (*DirStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create blob directory: %w", err)
+	}
+	return &DirStore{root: dir}, nil
+}
+
+func (s *DirStore) path(key string) (string, error) {
+	if !validKey.MatchString(key) {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+	return filepath.Join(s.root, filepath.FromSlash(key)), nil
+}
+
+func (s *DirStore) checksumPath(key string) string {
+	return filepath.Join(s.root, checksumDir, filepath.FromSlash(key))
+}
+
+// Put writes the object to a temporary file first and renames it into place, so readers never see a partial object.
+// The object's SHA-256 is stored alongside it for Verify.
+func (s *DirStore) Put(key string, r io.Reader) (int64, error) {
+	n, _, err := s.put(key, r)
+	return n, err
+}
+
+func (s *DirStore) put(key string, r io.Reader) (int64, string, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return 0, "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return 0, "", fmt.Errorf("failed to create blob directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), uploadPattern)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to create blob: %w", err)
+	}
+	hash := sha256.New()
+	n, err := io.Copy(io.MultiWriter(tmp, hash), r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return 0, "", fmt.Errorf("failed to write blob: %w", err)
+	}
+	// Drop the old checksum first: a crash before the new one is written leaves the object without a
+	// checksum, which Verify records again, rather than with a wrong one.
+	if err := os.Remove(s.checksumPath(key)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		os.Remove(tmp.Name())
+		return 0, "", fmt.Errorf("failed to replace blob checksum: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return 0, "", fmt.Errorf("failed to store blob: %w", err)
+	}
+	sum := hex.EncodeToString(hash.Sum(nil))
+	if err := s.writeChecksum(key, sum); err != nil {
+		return 0, "", err
+	}
+	return n, sum, nil
+}
+
+func (s *DirStore) writeChecksum(key, sum string) error {
+	path := s.checksumPath(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create blob checksum directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), uploadPattern)
+	if err != nil {
+		return fmt.Errorf("failed to create blob checksum: %w", err)
+	}
+	_, err = tmp.WriteString(sum)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to store blob checksum: %w", err)
+	}
+	return nil
+}
+
+// Get opens the object for reading. The caller must close it.
+func (s *DirStore) Get(key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open blob: %w", err)
+	}
+	return f, nil
+}
+
+// Delete removes the object and its checksum.
+func (s *DirStore) Delete(key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete blob: %w", err)
+	}
+	if err := os.Remove(s.checksumPath(key)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete blob checksum: %w", err)
+	}
+	return nil
+}
+
+// List returns every stored object. Objects being written are left out.
+func (s *DirStore) List() ([]Object, error) {
+	var objects []Object
+	err := filepath.WalkDir(s.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == checksumDir && filepath.Dir(path) == filepath.Clean(s.root) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".upload-") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil // Deleted since the directory was read
+			}
+			return err
+		}
+		rel, err := filepath.Rel(s.root, path)
+		if err != nil {
+			return err
+		}
+		objects = append(objects, Object{Key: filepath.ToSlash(rel), Size: info.Size(), ModTime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list blobs: %w", err)
+	}
+	return objects, nil
+}
+
+// Verify rereads an object and compares its SHA-256 with the one stored when it was written. Objects
+// stored before checksums were kept get one recorded from their current bytes, and recorded is true.
+func (s *DirStore) Verify(key string) (recorded bool, err error) {
+	path, err := s.path(key)
+	if err != nil {
+		return false, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, ErrNotFound
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to open blob: %w", err)
+	}
+	defer f.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return false, fmt.Errorf("failed to read blob: %w", err)
+	}
+	sum := hex.EncodeToString(hash.Sum(nil))
+
+	want, err := s.checksum(key)
+	if err != nil {
+		return false, err
+	}
+	if want == "" {
+		return true, s.writeChecksum(key, sum)
+	}
+	if sum != want {
+		return false, fmt.Errorf("%w: %s", ErrChecksumMismatch, key)
+	}
+	return false, nil
+}
+
+// checksum returns the stored SHA-256 of an object, or "" if none was stored.
+func (s *DirStore) checksum(key string) (string, error) {
+	data, err := os.ReadFile(s.checksumPath(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read blob checksum: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// moveTo copies an object to dst, keeping its modification time, and then deletes it here. The copy
+// is checked against the stored checksum, so a corrupt object is never moved.
+func (s *DirStore) moveTo(dst *DirStore, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open blob: %w", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat blob: %w", err)
+	}
+	want, err := s.checksum(key)
+	if err != nil {
+		return err
+	}
+	_, sum, err := dst.put(key, f)
+	if err != nil {
+		return err
+	}
+	if want != "" && sum != want {
+		dst.Delete(key)
+		return fmt.Errorf("%w: %s", ErrChecksumMismatch, key)
+	}
+	dstPath, _ := dst.path(key)
+	if err := os.Chtimes(dstPath, info.ModTime(), info.ModTime()); err != nil {
+		return fmt.Errorf("failed to keep blob time: %w", err)
+	}
+	// An object rewritten while it was copied stays here; the stale copy is dropped.
+	if now, err := os.Stat(path); err == nil && !now.ModTime().Equal(info.ModTime()) {
+		return dst.Delete(key)
+	}
+	return s.Delete(key)
+}
+
+// RemoveStaleUploads deletes temporary files left by uploads that were interrupted before before.
+func (s *DirStore) RemoveStaleUploads(before time.Time) (int, error) {
+	removed := 0
+	err := filepath.WalkDir(s.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasPrefix(d.Name(), ".upload-") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil || !info.ModTime().Before(before) {
+			return nil
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		removed++
+		return nil
+	})
+	if err != nil {
+		return removed, fmt.Errorf("failed to remove stale uploads: %w", err)
+	}
+	return removed, nil
+}
@@ -0,0 +1,292 @@
+// services/user-service/internal/blobstore/encrypted.go
+package blobstore
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Encrypted objects start with encryptionMagic, the key ID (one length byte, then the ID), and a random
+// salt. The content follows as AES-256-GCM sealed chunks of chunkSize plaintext bytes; the nonce
+// counts chunks and flags the last one, so chunks cannot be reordered, dropped, or truncated unnoticed.
+const (
+	encryptionMagic = "PBENC1"
+	saltSize        = 32
+	chunkSize       = 64 << 10
+)
+
+// ErrCorrupt is returned while reading an encrypted object that was altered or cut short.
+var ErrCorrupt = errors.New("encrypted blob is corrupt")
+
+var validKeyID = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+// EncryptionKey is a named 256-bit master key. Its ID is stored with every object it encrypts.
+type EncryptionKey struct {
+	ID  string
+	Key []byte
+}
+
+// ParseEncryptionKeys parses "id:base64key,id:base64key". The first key encrypts new objects; the
+// others are kept to read objects written before a rotation.
+func ParseEncryptionKeys(spec string) ([]EncryptionKey, error) {
+	var keys []EncryptionKey
+	seen := map[string]bool{}
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		id, encoded, ok := strings.Cut(pair, ":")
+		if !ok || !validKeyID.MatchString(id) {
+			return nil, fmt.Errorf("encryption key %q must be id:base64key with an ID of up to 32 letters, digits, - or _", pair)
+		}
+		if seen[id] {
+			return nil, fmt.Errorf("encryption key ID %q appears more than once", id)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("encryption key %q must be 32 bytes, base64-encoded", id)
+		}
+		seen[id] = true
+		keys = append(keys, EncryptionKey{ID: id, Key: key})
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no encryption keys given")
+	}
+	return keys, nil
+}
+
+// EncryptedStore is a Store that encrypts objects before they reach the underlying store, so blob
+// files, cold storage, and copies of either hold only ciphertext. Each object gets its own key,
+// derived from the master key and a random salt. Objects stored before encryption was enabled are
+// read as they are.
+type EncryptedStore struct {
+	store   Store
+	keys    map[string][]byte
+	current string
+}
+
+// NewEncryptedStore wraps store. keys[0] encrypts new objects.
+func NewEncryptedStore(store Store, keys []EncryptionKey) (*EncryptedStore, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no encryption keys given")
+	}
+	s := &EncryptedStore{store: store, keys: map[string][]byte{}, current: keys[0].ID}
+	for _, k := range keys {
+		s.keys[k.ID] = k.Key
+	}
+	return s, nil
+}
+
+// Put encrypts the object with the current key. It returns the plaintext size.
+func (s *EncryptedStore) Put(key string, r io.Reader) (int64, error) {
+	header := make([]byte, 0, len(encryptionMagic)+1+len(s.current)+saltSize)
+	header = append(header, encryptionMagic...)
+	header = append(header, byte(len(s.current)))
+	header = append(header, s.current...)
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return 0, fmt.Errorf("failed to generate blob salt: %w", err)
+	}
+	header = append(header, salt...)
+	aead, err := objectCipher(s.keys[s.current], salt, s.current)
+	if err != nil {
+		return 0, err
+	}
+
+	pr, pw := io.Pipe()
+	var plain int64
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var err error
+		plain, err = encryptChunks(pw, r, aead, header)
+		pw.CloseWithError(err)
+	}()
+	_, err = s.store.Put(key, pr)
+	pr.CloseWithError(io.ErrClosedPipe) // Unblocks the encryption if the store gave up early
+	<-done
+	if err != nil {
+		return 0, err
+	}
+	return plain, nil
+}
+
+func encryptChunks(w io.Writer, r io.Reader, aead cipher.AEAD, header []byte) (int64, error) {
+	if _, err := w.Write(header); err != nil {
+		return 0, err
+	}
+	br := bufio.NewReaderSize(r, chunkSize)
+	buf := make([]byte, chunkSize)
+	sealed := make([]byte, 0, chunkSize+aead.Overhead())
+	var total int64
+	for counter := uint32(0); ; counter++ {
+		n, err := io.ReadFull(br, buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return total, err
+		}
+		last := err != nil
+		if !last {
+			if _, err := br.Peek(1); err == io.EOF {
+				last = true
+			} else if err != nil {
+				return total, err
+			}
+		}
+		total += int64(n)
+		sealed = aead.Seal(sealed[:0], chunkNonce(counter, last), buf[:n], header)
+		if _, err := w.Write(sealed); err != nil {
+			return total, err
+		}
+		if last {
+			return total, nil
+		}
+		if counter == ^uint32(0) {
+			return total, fmt.Errorf("blob too large to encrypt")
+		}
+	}
+}
+
+// Get opens and decrypts the object. Reads fail with ErrCorrupt if it was altered.
+func (s *EncryptedStore) Get(key string) (io.ReadCloser, error) {
+	rc, err := s.store.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	br := bufio.NewReaderSize(rc, chunkSize+64)
+	if magic, err := br.Peek(len(encryptionMagic)); err != nil || string(magic) != encryptionMagic {
+		return readCloser{Reader: br, Closer: rc}, nil // Stored before encryption was enabled
+	}
+
+	header := make([]byte, len(encryptionMagic)+1)
+	if _, err := io.ReadFull(br, header); err != nil {
+		rc.Close()
+		return nil, ErrCorrupt
+	}
+	id := make([]byte, int(header[len(encryptionMagic)]))
+	salt := make([]byte, saltSize)
+	if _, err := io.ReadFull(br, id); err != nil {
+		rc.Close()
+		return nil, ErrCorrupt
+	}
+	if _, err := io.ReadFull(br, salt); err != nil {
+		rc.Close()
+		return nil, ErrCorrupt
+	}
+	master, ok := s.keys[string(id)]
+	if !ok {
+		rc.Close()
+		return nil, fmt.Errorf("blob %s is encrypted with unknown key %q", key, id)
+	}
+	aead, err := objectCipher(master, salt, string(id))
+	if err != nil {
+		rc.Close()
+		return nil, err
+	}
+	header = append(append(header, id...), salt...)
+	return &decryptReader{src: br, closer: rc, aead: aead, header: header}, nil
+}
+
+// Delete removes the object.
+func (s *EncryptedStore) Delete(key string) error {
+	return s.store.Delete(key)
+}
+
+// objectCipher derives an object's AES-256-GCM cipher from the master key and the object's salt.
+func objectCipher(master, salt []byte, id string) (cipher.AEAD, error) {
+	key, err := hkdf.Key(sha256.New, master, salt, "pulse blob "+id, 32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive blob key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create blob cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// chunkNonce is the chunk counter and a last-chunk flag. Nonces only repeat across objects, each of
+// which has its own key.
+func chunkNonce(counter uint32, last bool) []byte {
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint32(nonce[7:11], counter)
+	if last {
+		nonce[11] = 1
+	}
+	return nonce
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// decryptReader decrypts an object one chunk at a time.
+type decryptReader struct {
+	src     *bufio.Reader
+	closer  io.Closer
+	aead    cipher.AEAD
+	header  []byte
+	counter uint32
+	plain   []byte
+	sealed  []byte
+	done    bool
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.plain) == 0 {
+		if d.done {
+			return 0, io.EOF
+		}
+		if err := d.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, d.plain)
+	d.plain = d.plain[n:]
+	return n, nil
+}
+
+func (d *decryptReader) next() error {
+	if d.sealed == nil {
+		d.sealed = make([]byte, chunkSize+d.aead.Overhead())
+	}
+	n, err := io.ReadFull(d.src, d.sealed)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		if err == io.EOF {
+			return ErrCorrupt // The last chunk is missing
+		}
+		return err
+	}
+	last := err == io.ErrUnexpectedEOF
+	if !last {
+		if _, err := d.src.Peek(1); err == io.EOF {
+			last = true
+		} else if err != nil {
+			return err
+		}
+	}
+	plain, err := d.aead.Open(d.sealed[:0], chunkNonce(d.counter, last), d.sealed[:n], d.header)
+	if err != nil {
+		return ErrCorrupt
+	}
+	d.plain = plain // Decrypted in place; the buffer is only reused once it is drained
+	d.counter++
+	d.done = last
+	return nil
+}
+
+func (d *decryptReader) Close() error {
+	return d.closer.Close()
+}
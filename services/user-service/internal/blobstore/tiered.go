@@ -0,0 +1,183 @@
+// services/user-service/internal/blobstore/tiered.go
+package blobstore
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"strings"
+	"time"
+)
+
+// staleUploadAge is how long an interrupted upload's temporary file is kept before lifecycle passes remove it.
+const staleUploadAge = 24 * time.Hour
+
+// LifecycleRule moves objects whose key starts with Prefix to cold storage, and later deletes them,
+// by the time since they were stored.
+type LifecycleRule struct {
+	Prefix      string
+	ColdAfter   time.Duration // 0 keeps objects hot
+	DeleteAfter time.Duration // 0 keeps objects
+}
+
+// LifecycleResult counts what one lifecycle pass did.
+type LifecycleResult struct {
+	Transitioned int // Moved from hot to cold storage
+	Expired      int // Deleted by a rule
+	StaleUploads int // Temporary files of interrupted uploads removed
+	Failed       int
+}
+
+// IntegrityResult counts what one integrity pass found.
+type IntegrityResult struct {
+	Checked  int
+	Recorded int      // Objects without a checksum, which now have one
+	Corrupt  []string // Keys whose bytes no longer match their checksum
+	Failed   int      // Objects that could not be read
+}
+
+// TieredStore is a Store over a hot DirStore, where objects are written, and an optional cold DirStore
+// on cheaper, slower storage, where lifecycle rules move them. Reads look in both.
+type TieredStore struct {
+	hot  *DirStore
+	cold *DirStore
+}
+
+// NewTieredStore creates a TieredStore. cold may be nil, in which case objects are never moved.
+func NewTieredStore(hot, cold *DirStore) *TieredStore {
+	return &TieredStore{hot: hot, cold: cold}
+}
+
+// Put stores the object in hot storage.
+func (s *TieredStore) Put(key string, r io.Reader) (int64, error) {
+	return s.hot.Put(key, r)
+}
+
+// Get opens the object from hot storage, or else from cold storage.
+func (s *TieredStore) Get(key string) (io.ReadCloser, error) {
+	rc, err := s.hot.Get(key)
+	if errors.Is(err, ErrNotFound) && s.cold != nil {
+		return s.cold.Get(key)
+	}
+	return rc, err
+}
+
+// Delete removes the object from both tiers.
+func (s *TieredStore) Delete(key string) error {
+	if err := s.hot.Delete(key); err != nil {
+		return err
+	}
+	if s.cold != nil {
+		return s.cold.Delete(key)
+	}
+	return nil
+}
+
+// ApplyLifecycle runs one pass of the rules: the first rule whose prefix matches a key applies to it.
+// Objects past their rule's DeleteAfter are deleted from either tier; hot objects past ColdAfter move
+// to cold storage. Failures are counted and returned together; the pass goes on past them.
+func (s *TieredStore) ApplyLifecycle(rules []LifecycleRule, now time.Time) (LifecycleResult, error) {
+	var result LifecycleResult
+	var errs []error
+	tiers := []*DirStore{s.hot}
+	if s.cold != nil {
+		tiers = append(tiers, s.cold)
+	}
+	for _, tier := range tiers {
+		objects, err := tier.List()
+		if err != nil {
+			return result, err
+		}
+		for _, obj := range objects {
+			rule, ok := matchRule(rules, obj.Key)
+			if !ok {
+				continue
+			}
+			age := now.Sub(obj.ModTime)
+			switch {
+			case rule.DeleteAfter > 0 && age >= rule.DeleteAfter:
+				err = tier.Delete(obj.Key)
+				if err == nil {
+					result.Expired++
+				}
+			case tier == s.hot && s.cold != nil && rule.ColdAfter > 0 && age >= rule.ColdAfter:
+				err = tier.moveTo(s.cold, obj.Key)
+				if err == nil {
+					result.Transitioned++
+				}
+			default:
+				continue
+			}
+			if err != nil {
+				result.Failed++
+				errs = append(errs, err)
+			}
+		}
+		n, err := tier.RemoveStaleUploads(now.Add(-staleUploadAge))
+		result.StaleUploads += n
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return result, errors.Join(errs...)
+}
+
+func matchRule(rules []LifecycleRule, key string) (LifecycleRule, bool) {
+	for _, rule := range rules {
+		if strings.HasPrefix(key, rule.Prefix) {
+			return rule, true
+		}
+	}
+	return LifecycleRule{}, false
+}
+
+// VerifySample checks the stored checksums of up to n objects picked at random across both tiers.
+func (s *TieredStore) VerifySample(n int) (IntegrityResult, error) {
+	var result IntegrityResult
+	type sampled struct {
+		tier *DirStore
+		key  string
+	}
+	var sample []sampled
+	seen := 0
+	tiers := []*DirStore{s.hot}
+	if s.cold != nil {
+		tiers = append(tiers, s.cold)
+	}
+	for _, tier := range tiers {
+		objects, err := tier.List()
+		if err != nil {
+			return result, err
+		}
+		for _, obj := range objects { // Reservoir sampling: every object is equally likely to be picked
+			seen++
+			if len(sample) < n {
+				sample = append(sample, sampled{tier, obj.Key})
+			} else if i := rand.IntN(seen); i < n {
+				sample[i] = sampled{tier, obj.Key}
+			}
+		}
+	}
+
+	var errs []error
+	for _, obj := range sample {
+		recorded, err := obj.tier.Verify(obj.key)
+		switch {
+		case errors.Is(err, ErrNotFound):
+			continue // Deleted since it was listed
+		case errors.Is(err, ErrChecksumMismatch):
+			result.Checked++
+			result.Corrupt = append(result.Corrupt, obj.key)
+		case err != nil:
+			result.Failed++
+			errs = append(errs, fmt.Errorf("%s: %w", obj.key, err))
+		default:
+			result.Checked++
+			if recorded {
+				result.Recorded++
+			}
+		}
+	}
+	return result, errors.Join(errs...)
+}
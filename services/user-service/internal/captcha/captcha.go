@@ -0,0 +1,121 @@
+// services/user-service/internal/captcha/captcha.go
+package captcha
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrRejected is returned when the provider reports the token as invalid, expired, reused, or,
+// for score-based reCAPTCHA, as too likely to come from a bot.
+var ErrRejected = errors.New("captcha: token rejected")
+
+// Verifier checks a CAPTCHA token solved by a client. Any error other than ErrRejected
+// means the provider could not be asked.
+type Verifier interface {
+	Verify(ctx context.Context, token, remoteIP string) error
+}
+
+// Config selects and configures a provider.
+type Config struct {
+	Provider  string  // "recaptcha", "hcaptcha", or "turnstile"
+	Secret    string  // The provider's server-side secret key
+	MinScore  float64 // reCAPTCHA v3 only: reject scores below this; 0 accepts any score
+	VerifyURL string  // Overrides the provider's siteverify endpoint, e.g. for a proxy
+}
+
+// verifyURLs are the siteverify endpoints of the supported providers. All three accept the same
+// form-encoded request and answer with the same core JSON fields.
+var verifyURLs = map[string]string{
+	"recaptcha": "https://www.google.com/recaptcha/api/siteverify",
+	"hcaptcha":  "https://api.hcaptcha.com/siteverify",
+	"turnstile": "https://challenges.cloudflare.com/turnstile/v0/siteverify",
+}
+
+// siteVerifier verifies tokens against a siteverify endpoint.
+type siteVerifier struct {
+	verifyURL string
+	secret    string
+	minScore  float64
+	client    *http.Client
+}
+
+// siteVerifyResponse is the provider's answer. Score is only sent by reCAPTCHA v3.
+type siteVerifyResponse struct {
+	Success    bool     `json:"success"`
+	Score      *float64 `json:"score"`
+	ErrorCodes []string `json:"error-codes"`
+}
+
+// New returns a Verifier for the configured provider.
+func New(cfg Config) (Verifier, error) {
+	endpoint, ok := verifyURLs[cfg.Provider]
+	if !ok {
+		return nil, fmt.Errorf("captcha: unsupported provider %q, expected recaptcha, hcaptcha, or turnstile", cfg.Provider)
+	}
+	if cfg.Secret == "" {
+		return nil, fmt.Errorf("captcha: secret key is required")
+	}
+	if cfg.MinScore < 0 || cfg.MinScore > 1 {
+		return nil, fmt.Errorf("captcha: minimum score must be between 0 and 1")
+	}
+	if cfg.VerifyURL != "" {
+		endpoint = cfg.VerifyURL
+	}
+	return &siteVerifier{
+		verifyURL: endpoint,
+		secret:    cfg.Secret,
+		minScore:  cfg.MinScore,
+		client:    &http.Client{Timeout: 5 * time.Second},
+	}, nil
+}
+
+// Verify asks the provider whether the token was solved. remoteIP is optional.
+func (v *siteVerifier) Verify(ctx context.Context, token, remoteIP string) error {
+	if token == "" {
+		return ErrRejected
+	}
+	form := url.Values{"secret": {v.secret}, "response": {token}}
+	if remoteIP != "" {
+		form.Set("remoteip", remoteIP)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.verifyURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("captcha: failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("captcha: verification request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("captcha: provider returned status %d", resp.StatusCode)
+	}
+
+	var result siteVerifyResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<16)).Decode(&result); err != nil {
+		return fmt.Errorf("captcha: failed to decode provider response: %w", err)
+	}
+	if !result.Success {
+		// A bad secret is a misconfiguration, not a bot.
+		for _, code := range result.ErrorCodes {
+			if code == "invalid-input-secret" || code == "missing-input-secret" {
+				return fmt.Errorf("captcha: provider rejected the secret key (%s)", code)
+			}
+		}
+		return fmt.Errorf("%w: %s", ErrRejected, strings.Join(result.ErrorCodes, ","))
+	}
+	if v.minScore > 0 && result.Score != nil && *result.Score < v.minScore {
+		return fmt.Errorf("%w: score %.2f below %.2f", ErrRejected, *result.Score, v.minScore)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+// services/user-service/internal/config/cookies.go
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Cookies holds the attributes of the cookie carrying the JWT. It is read once at startup.
+type Cookies struct {
+	Name     string        // COOKIE_NAME, default "jwt_token"
+	Domain   string        // COOKIE_DOMAIN; empty keeps the cookie on the exact host
+	Secure   bool          // COOKIE_SECURE, default true in production
+	SameSite http.SameSite // COOKIE_SAMESITE: lax (default), strict, or none
+}
+
+// cookies defaults to the development settings until LoadCookies runs.
+var cookies = Cookies{Name: "jwt_token", SameSite: http.SameSiteLaxMode}
+
+// sameSiteModes maps COOKIE_SAMESITE values to cookie modes.
+var sameSiteModes = map[string]http.SameSite{
+	"lax":    http.SameSiteLaxMode,
+	"strict": http.SameSiteStrictMode,
+	"none":   http.SameSiteNoneMode,
+}
+
+// LoadCookies reads COOKIE_NAME, COOKIE_DOMAIN, COOKIE_SECURE, and COOKIE_SAMESITE. Frontends on
+// another site need COOKIE_SAMESITE=none, which browsers only accept together with Secure.
+func LoadCookies(production bool) (*Cookies, error) {
+	c := Cookies{Name: os.Getenv("COOKIE_NAME"), Domain: os.Getenv("COOKIE_DOMAIN"), Secure: production, SameSite: http.SameSiteLaxMode}
+	if c.Name == "" {
+		c.Name = "jwt_token"
+	}
+	if v := os.Getenv("COOKIE_SECURE"); v != "" {
+		secure, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid COOKIE_SECURE %q, expected true or false", v)
+		}
+		c.Secure = secure
+	}
+	if v := os.Getenv("COOKIE_SAMESITE"); v != "" {
+		mode, ok := sameSiteModes[strings.ToLower(v)]
+		if !ok {
+			return nil, fmt.Errorf("invalid COOKIE_SAMESITE %q, expected lax, strict, or none", v)
+		}
+		c.SameSite = mode
+	}
+
+	if err := (&http.Cookie{Name: c.Name, Domain: c.Domain, Path: "/"}).Valid(); err != nil {
+		return nil, fmt.Errorf("invalid COOKIE_NAME or COOKIE_DOMAIN: %w", err)
+	}
+	if c.SameSite == http.SameSiteNoneMode && !c.Secure {
+		return nil, fmt.Errorf("COOKIE_SAMESITE=none requires COOKIE_SECURE=true")
+	}
+	// Browsers drop prefixed cookies that do not meet the prefix's rules, which would silently break sign-in.
+	if strings.HasPrefix(c.Name, "__Secure-") && !c.Secure {
+		return nil, fmt.Errorf("cookie name %s requires COOKIE_SECURE=true", c.Name)
+	}
+	if strings.HasPrefix(c.Name, "__Host-") && (!c.Secure || c.Domain != "") {
+		return nil, fmt.Errorf("cookie name %s requires COOKIE_SECURE=true and no COOKIE_DOMAIN", c.Name)
+	}
+
+	cookies = c
+	return &c, nil
+}
+
+// CookieSettings returns the cookie attributes loaded at startup.
+func CookieSettings() Cookies {
+	return cookies
+}
+
+// SameSiteName returns the COOKIE_SAMESITE value of c's SameSite mode.
+func (c Cookies) SameSiteName() string {
+	for name, mode := range sameSiteModes {
+		if mode == c.SameSite {
+			return name
+		}
+	}
+	return "default"
+}
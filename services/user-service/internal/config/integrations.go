@@ -0,0 +1,70 @@
+// services/user-service/internal/config/integrations.go
+package config
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+
+	"health-tracker-project/services/user-service/internal/models"
+)
+
+// defaultIntegrations is the catalog used when INTEGRATIONS_PATH is not set.
+//
+//go:embed integrations.json
+var defaultIntegrations []byte
+
+// providerName restricts provider identifiers, since they appear in URLs and consent records.
+var providerName = regexp.MustCompile(`^[a-z0-9][a-z0-9_]{0,31}$`)
+
+// LoadIntegrations reads and validates an integration catalog JSON file. An empty path yields the built-in catalog.
+func LoadIntegrations(path string) (*models.IntegrationCatalog, error) {
+	data := defaultIntegrations
+	if path != "" {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, fmt.Errorf("failed to read integration catalog: %w", err)
+		}
+	}
+	var catalog models.IntegrationCatalog
+	if err := json.Unmarshal(data, &catalog); err != nil {
+		return nil, fmt.Errorf("failed to parse integration catalog: %w", err)
+	}
+	if err := validateIntegrations(&catalog); err != nil {
+		return nil, fmt.Errorf("integration catalog validation failed: %w", err)
+	}
+	return &catalog, nil
+}
+
+// validateIntegrations checks that every integration states its terms and what data flows each way,
+// since users consent to exactly what the catalog describes.
+func validateIntegrations(c *models.IntegrationCatalog) error {
+	seen := map[string]bool{}
+	for i := range c.Integrations {
+		in := &c.Integrations[i]
+		if !providerName.MatchString(in.Provider) || seen[in.Provider] {
+			return fmt.Errorf("every integration needs a unique provider of lowercase letters, digits, and underscores")
+		}
+		seen[in.Provider] = true
+		if in.Name == "" || in.TermsVersion == "" || in.TermsURL == "" {
+			return fmt.Errorf("integration %q: name, terms_version, and terms_url are required", in.Provider)
+		}
+		if len(in.Imports) == 0 && len(in.Exports) == 0 {
+			return fmt.Errorf("integration %q: at least one import or export is required", in.Provider)
+		}
+		if in.Imports == nil {
+			in.Imports = []models.DataFlow{}
+		}
+		if in.Exports == nil {
+			in.Exports = []models.DataFlow{}
+		}
+		for _, flow := range append(append([]models.DataFlow{}, in.Imports...), in.Exports...) {
+			if flow.Data == "" || flow.Purpose == "" {
+				return fmt.Errorf("integration %q: every data flow needs data and a purpose", in.Provider)
+			}
+		}
+	}
+	return nil
+}
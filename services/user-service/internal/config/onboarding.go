@@ -0,0 +1,94 @@
+// services/user-service/internal/config/onboarding.go
+package config
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"health-tracker-project/services/user-service/internal/models"
+)
+
+// defaultOnboardingRules is the ruleset used when ONBOARDING_RULES_PATH is not set.
+//
+//go:embed onboarding_rules.json
+var defaultOnboardingRules []byte
+
+var weekdays = []string{"mon", "tue", "wed", "thu", "fri", "sat", "sun"}
+
+// LoadOnboardingRules reads and validates an onboarding ruleset JSON file. An empty path yields the built-in ruleset.
+func LoadOnboardingRules(path string) (*models.OnboardingRuleset, error) {
+	data := defaultOnboardingRules
+	if path != "" {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, fmt.Errorf("failed to read onboarding rules: %w", err)
+		}
+	}
+	var rules models.OnboardingRuleset
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse onboarding rules: %w", err)
+	}
+	if err := validateOnboardingRules(&rules); err != nil {
+		return nil, fmt.Errorf("onboarding rules validation failed: %w", err)
+	}
+	return &rules, nil
+}
+
+// validateOnboardingRules checks that every rule is usable and that some rule matches any answers.
+func validateOnboardingRules(rs *models.OnboardingRuleset) error {
+	if rs.Version == "" || len(rs.ActivityLevels) == 0 || len(rs.Objectives) == 0 || len(rs.Rules) == 0 {
+		return fmt.Errorf("version, activity_levels, objectives, and rules are required")
+	}
+	names := map[string]bool{}
+	for _, rule := range rs.Rules {
+		if rule.Name == "" || names[rule.Name] {
+			return fmt.Errorf("every rule needs a unique name")
+		}
+		names[rule.Name] = true
+		when := rule.When
+		if when.MinAge < 0 || when.MaxAge < 0 || (when.MaxAge > 0 && when.MaxAge < when.MinAge) {
+			return fmt.Errorf("rule %q: invalid age range", rule.Name)
+		}
+		for _, level := range when.ActivityLevels {
+			if !slices.Contains(rs.ActivityLevels, level) {
+				return fmt.Errorf("rule %q: unknown activity level %q", rule.Name, level)
+			}
+		}
+		for _, objective := range when.Objectives {
+			if !slices.Contains(rs.Objectives, objective) {
+				return fmt.Errorf("rule %q: unknown objective %q", rule.Name, objective)
+			}
+		}
+		if len(rule.Goals) == 0 {
+			return fmt.Errorf("rule %q: at least one goal is required", rule.Name)
+		}
+		for _, goal := range rule.Goals {
+			if goal.Metric == "" || goal.Target <= 0 || (goal.Period != "day" && goal.Period != "week") {
+				return fmt.Errorf("rule %q: goals need a metric, a positive target, and a period of day or week", rule.Name)
+			}
+		}
+		for _, reminder := range rule.Reminders {
+			if _, err := time.Parse("15:04", reminder.Time); err != nil || reminder.Type == "" {
+				return fmt.Errorf("rule %q: reminders need a type and a time as HH:MM", rule.Name)
+			}
+			for _, day := range reminder.Days {
+				if !slices.Contains(weekdays, day) {
+					return fmt.Errorf("rule %q: unknown reminder day %q", rule.Name, day)
+				}
+			}
+		}
+		plan := rule.StarterPlan
+		if plan.Name == "" || plan.Weeks <= 0 || plan.SessionsPerWeek <= 0 || plan.SessionMinutes <= 0 {
+			return fmt.Errorf("rule %q: starter_plan needs a name and positive weeks, sessions_per_week, and session_minutes", rule.Name)
+		}
+	}
+	last := rs.Rules[len(rs.Rules)-1].When
+	if last.MinAge != 0 || last.MaxAge != 0 || len(last.ActivityLevels) != 0 || len(last.Objectives) != 0 {
+		return fmt.Errorf("the last rule must have no conditions, so every answer gets a recommendation")
+	}
+	return nil
+}
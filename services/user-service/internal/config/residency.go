@@ -0,0 +1,92 @@
+// services/user-service/internal/config/residency.go
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// regionName restricts region names to short identifiers, since they are stored and shown to admins.
+var regionName = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,31}$`)
+
+// Residency pins each user's data to the database cluster of one region.
+// It is read once at startup: moving a region's database is a restart, not a reload.
+type Residency struct {
+	HomeRegion   string            // Region of DATABASE_URL, which also holds the region directory
+	DatabaseURLs map[string]string // Region -> database URL, including the home region
+	Countries    map[string]string // ISO 3166-1 alpha-2 country code -> region
+}
+
+// residency is nil when data residency is disabled.
+var residency *Residency
+
+// LoadResidency reads RESIDENCY_REGIONS ("eu=postgres://...,us=postgres://..."), RESIDENCY_HOME_REGION,
+// and RESIDENCY_COUNTRY_REGIONS ("DE=eu,FR=eu,US=us"). It returns nil when RESIDENCY_REGIONS is unset.
+// The home region uses homeDatabaseURL and must not be listed in RESIDENCY_REGIONS.
+func LoadResidency(homeDatabaseURL string) (*Residency, error) {
+	regions := os.Getenv("RESIDENCY_REGIONS")
+	if regions == "" {
+		residency = nil
+		return nil, nil
+	}
+
+	r := &Residency{
+		HomeRegion:   os.Getenv("RESIDENCY_HOME_REGION"),
+		DatabaseURLs: map[string]string{},
+		Countries:    map[string]string{},
+	}
+	if r.HomeRegion == "" {
+		r.HomeRegion = "default"
+	}
+	if !regionName.MatchString(r.HomeRegion) {
+		return nil, fmt.Errorf("invalid RESIDENCY_HOME_REGION %q", r.HomeRegion)
+	}
+	r.DatabaseURLs[r.HomeRegion] = homeDatabaseURL
+
+	for _, entry := range strings.Split(regions, ",") {
+		name, dsn, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || !regionName.MatchString(name) || dsn == "" {
+			return nil, fmt.Errorf("invalid RESIDENCY_REGIONS entry %q, expected region=database-url", entry)
+		}
+		if _, dup := r.DatabaseURLs[name]; dup {
+			return nil, fmt.Errorf("region %q is configured twice (the home region uses DATABASE_URL)", name)
+		}
+		r.DatabaseURLs[name] = dsn
+	}
+
+	if countries := os.Getenv("RESIDENCY_COUNTRY_REGIONS"); countries != "" {
+		for _, entry := range strings.Split(countries, ",") {
+			country, region, ok := strings.Cut(strings.TrimSpace(entry), "=")
+			country = strings.ToUpper(country)
+			if !ok || len(country) != 2 {
+				return nil, fmt.Errorf("invalid RESIDENCY_COUNTRY_REGIONS entry %q, expected CC=region", entry)
+			}
+			if _, known := r.DatabaseURLs[region]; !known {
+				return nil, fmt.Errorf("country %s maps to unconfigured region %q", country, region)
+			}
+			r.Countries[country] = region
+		}
+	}
+
+	residency = r
+	return r, nil
+}
+
+// ResidencyEnabled reports whether user data is split across regions.
+func ResidencyEnabled() bool {
+	return residency != nil
+}
+
+// RegionForCountry returns the region that stores data of users in the given country.
+// Unmapped or empty countries fall back to the home region; without residency it returns "".
+func RegionForCountry(country string) string {
+	if residency == nil {
+		return ""
+	}
+	if region, ok := residency.Countries[strings.ToUpper(country)]; ok {
+		return region
+	}
+	return residency.HomeRegion
+}
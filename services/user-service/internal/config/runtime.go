@@ -0,0 +1,512 @@
+// services/user-service/internal/config/runtime.go
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+	"health-tracker-project/services/user-service/internal/utils/reqctx"
+)
+
+// RuntimeConfig holds non-critical settings that can be changed without a restart.
+// Critical settings (database URL, port, JWT keys) stay in environment variables.
+type RuntimeConfig struct {
+	LogLevel    string                 `json:"log_level"`    // Empty keeps the environment default
+	LogSampling *logger.SamplingConfig `json:"log_sampling"` // Nil restores the environment default
+
+	FeatureFlags       map[string]bool    `json:"feature_flags"`
+	Rollouts           map[string]Rollout `json:"rollouts"`             // Feature -> who gets it while it soft-launches
+	CORSAllowedOrigins []string           `json:"cors_allowed_origins"` // "*" allows any origin
+	RateLimits         RateLimitConfig    `json:"rate_limits"`
+	LoadShedding       LoadShedding       `json:"load_shedding"`
+	SLOs               []SLO              `json:"slos"`
+
+	MaxSessionsPerUser int      `json:"max_sessions_per_user"` // Oldest sessions are signed out beyond this; 0 means unlimited
+	CaptchaRequired    []string `json:"captcha_required"`      // Endpoints that need a solved CAPTCHA: "login", "register"
+
+	MessageRetentionDays int `json:"message_retention_days"` // Coach messages and attachments older than this are deleted; 0 keeps them
+
+	AccountDeletionGraceDays int `json:"account_deletion_grace_days"` // Days between an erasure request and the erasure
+
+	PublicAPIDailyQuota int `json:"public_api_daily_quota"` // Public API requests per developer app per UTC day; 0 means unlimited
+
+	AnnouncementSendsPerSecond int `json:"announcement_sends_per_second"` // Push notifications of announcements each replica sends per second
+
+	AppointmentPolicy AppointmentPolicy `json:"appointment_policy"`
+
+	WorkoutAttachments WorkoutAttachmentLimits `json:"workout_attachments"`
+
+	BlobStorage BlobStoragePolicy `json:"blob_storage"`
+}
+
+// Rollout limits a feature to a subset of users while it soft-launches. Its feature flag must be on;
+// switching it off takes the feature away from everyone. Empty lists do not restrict.
+type Rollout struct {
+	Countries []string `json:"countries"` // ISO 3166-1 alpha-2 codes of where the request comes from
+	Locales   []string `json:"locales"`   // BCP 47 tags; "de" also admits "de-AT"
+	Plans     []string `json:"plans"`     // The user's plan, from the "plan" key of their metadata
+	Percent   int      `json:"percent"`   // Share of the users admitted by the lists, by a stable hash of the user ID; 0 means all
+}
+
+// AppointmentPolicy sets what users may change about their bookings. Providers can always cancel.
+type AppointmentPolicy struct {
+	CancelNoticeHours     int `json:"cancel_notice_hours"`     // Users cannot cancel later than this before the start
+	RescheduleNoticeHours int `json:"reschedule_notice_hours"` // Users cannot reschedule later than this before the start
+	MaxReschedules        int `json:"max_reschedules"`         // Reschedules allowed per booking; 0 means unlimited
+	ReminderHours         int `json:"reminder_hours"`          // Reminders go out this long before the start; 0 disables them
+}
+
+// WorkoutAttachmentLimits bounds the form-check videos and files users attach to workout sets.
+type WorkoutAttachmentLimits struct {
+	MaxVideoMB        int `json:"max_video_mb"`        // Largest accepted video upload
+	MaxFileMB         int `json:"max_file_mb"`         // Largest accepted image or PDF upload
+	DefaultExpiryDays int `json:"default_expiry_days"` // Expiry of uploads that don't set one; 0 keeps them
+	MaxExpiryDays     int `json:"max_expiry_days"`     // Longest expiry a user can choose; 0 allows keeping uploads
+}
+
+// BlobStoragePolicy sets how long stored files stay in hot storage, when they are deleted, and how
+// many are checksummed per integrity pass.
+type BlobStoragePolicy struct {
+	Lifecycle           []BlobLifecycleRule `json:"lifecycle"`             // The first rule whose prefix matches a key applies
+	IntegritySampleSize int                 `json:"integrity_sample_size"` // Objects checked per hourly pass; 0 disables the check
+}
+
+// BlobLifecycleRule moves files whose key starts with Prefix to cold storage, then deletes them.
+type BlobLifecycleRule struct {
+	Prefix          string `json:"prefix"`
+	ColdAfterDays   int    `json:"cold_after_days"`   // 0 keeps them in hot storage
+	DeleteAfterDays int    `json:"delete_after_days"` // 0 keeps them
+}
+
+// trackedBlobPrefixes hold files the database refers to. They are deleted along with their rows, so
+// lifecycle rules may only move them to cold storage.
+var trackedBlobPrefixes = []string{"messages/", "workouts/"}
+
+// Endpoints that can require a CAPTCHA token.
+const (
+	CaptchaLogin    = "login"
+	CaptchaRegister = "register"
+)
+
+// MaxSLOWindowMinutes bounds an SLO's rolling window, since history is kept in memory per minute.
+const MaxSLOWindowMinutes = 24 * 60
+
+// SLO is a service level objective for one route. A request is good when it does not fail
+// with a 5xx status and, if LatencyThresholdMS is set, completes within the threshold.
+type SLO struct {
+	Name               string  `json:"name"`
+	Route              string  `json:"route"`                // ServeMux pattern, e.g. "POST /login"
+	Objective          float64 `json:"objective"`            // Target fraction of good requests, e.g. 0.999
+	LatencyThresholdMS int     `json:"latency_threshold_ms"` // 0 tracks availability only
+	WindowMinutes      int     `json:"window_minutes"`       // Rolling compliance window
+	BurnRateAlert      float64 `json:"burn_rate_alert"`      // Alert when the 5m and 1h burn rates both exceed this; 0 means 14.4
+}
+
+// RateLimit is a per-client token bucket: RequestsPerMinute refill rate and Burst capacity.
+type RateLimit struct {
+	RequestsPerMinute int `json:"requests_per_minute"` // 0 disables limiting
+	Burst             int `json:"burst"`               // 0 means a burst of 1
+}
+
+// RateLimitConfig holds request rate limit tuning. The embedded limit applies to every
+// route; Auth applies additionally to the credential endpoints (/login, /register), Handles
+// to the public username availability check, and PublicAPI to each developer app's API key
+// on the public API.
+type RateLimitConfig struct {
+	RateLimit
+	Auth      RateLimit `json:"auth"`
+	Handles   RateLimit `json:"handles"`
+	PublicAPI RateLimit `json:"public_api"`
+}
+
+// Bounds on load shedding settings.
+const (
+	MaxQueueTimeoutMS        = 60 * 1000
+	MaxRetryAfterSeconds     = 300
+	DefaultMaxShedFraction   = 0.9
+	DefaultRetryAfterSeconds = 1
+)
+
+// ConcurrencyLimit bounds the requests a route handles at once. Requests beyond MaxInFlight wait in
+// a first-in, first-out queue of up to MaxQueue for at most QueueTimeoutMS; the rest are refused.
+type ConcurrencyLimit struct {
+	Route          string `json:"route,omitempty"`  // ServeMux pattern, e.g. "GET /users"; unset in the default limit
+	MaxInFlight    int    `json:"max_in_flight"`    // 0 means unlimited
+	MaxQueue       int    `json:"max_queue"`        // 0 refuses requests as soon as MaxInFlight is reached
+	QueueTimeoutMS int    `json:"queue_timeout_ms"` // 0 means 1000
+}
+
+// LoadShedding protects the service and its database during traffic spikes. Each route has its own
+// concurrency limit, and while the smoothed request latency or the process CPU use is above its
+// target, a share of requests is refused in proportion to the excess.
+type LoadShedding struct {
+	Default           ConcurrencyLimit   `json:"default"` // Applies to every route without its own limit
+	Routes            []ConcurrencyLimit `json:"routes"`
+	TargetLatencyMS   int                `json:"target_latency_ms"`   // 0 disables shedding on latency
+	MaxCPU            float64            `json:"max_cpu"`             // Fraction of GOMAXPROCS, e.g. 0.85; 0 disables shedding on CPU
+	MaxShedFraction   float64            `json:"max_shed_fraction"`   // Most requests shed at once; 0 means 0.9
+	RetryAfterSeconds int                `json:"retry_after_seconds"` // Retry-After of refused requests; 0 means 1
+	ExemptRoutes      []string           `json:"exempt_routes"`       // Never limited or shed
+}
+
+// Limit returns the concurrency limit of a route.
+func (l LoadShedding) Limit(route string) ConcurrencyLimit {
+	for _, limit := range l.Routes {
+		if limit.Route == route {
+			return limit
+		}
+	}
+	return l.Default
+}
+
+// current is swapped atomically on reload so readers never see a partially applied config.
+var current atomic.Pointer[RuntimeConfig]
+
+func init() {
+	current.Store(defaultRuntimeConfig())
+}
+
+// defaultRuntimeConfig is used when no config file is configured. Rate limits, the session
+// limit, CAPTCHA endpoints, message retention, the account deletion grace period, and the public API
+// quota default to their environment variables; the config file can override them.
+func defaultRuntimeConfig() *RuntimeConfig {
+	return &RuntimeConfig{
+		FeatureFlags:               map[string]bool{},
+		Rollouts:                   map[string]Rollout{},
+		MaxSessionsPerUser:         envInt("MAX_SESSIONS_PER_USER", 5),
+		CaptchaRequired:            strings.FieldsFunc(os.Getenv("CAPTCHA_REQUIRED"), func(r rune) bool { return r == ',' || r == ' ' }),
+		MessageRetentionDays:       envInt("MESSAGE_RETENTION_DAYS", 0),
+		AccountDeletionGraceDays:   envInt("ACCOUNT_DELETION_GRACE_DAYS", 30),
+		PublicAPIDailyQuota:        envInt("PUBLIC_API_DAILY_QUOTA", 10000),
+		AnnouncementSendsPerSecond: 50,
+		AppointmentPolicy: AppointmentPolicy{
+			CancelNoticeHours:     24,
+			RescheduleNoticeHours: 24,
+			MaxReschedules:        2,
+			ReminderHours:         24,
+		},
+		WorkoutAttachments: WorkoutAttachmentLimits{
+			MaxVideoMB: 200,
+			MaxFileMB:  10,
+		},
+		BlobStorage: BlobStoragePolicy{
+			Lifecycle: []BlobLifecycleRule{
+				{Prefix: "exports/", ColdAfterDays: 7, DeleteAfterDays: 30},
+				{Prefix: "tmp/", ColdAfterDays: 1, DeleteAfterDays: 7},
+				{Prefix: "messages/", ColdAfterDays: 90},
+				{Prefix: "workouts/", ColdAfterDays: 90},
+			},
+			IntegritySampleSize: 100,
+		},
+		LoadShedding: LoadShedding{
+			ExemptRoutes: []string{"GET /health"},
+		},
+		RateLimits: RateLimitConfig{
+			RateLimit: RateLimit{
+				RequestsPerMinute: envInt("RATE_LIMIT_PER_MINUTE", 0),
+				Burst:             envInt("RATE_LIMIT_BURST", 0),
+			},
+			Auth: RateLimit{
+				RequestsPerMinute: envInt("RATE_LIMIT_AUTH_PER_MINUTE", 10),
+				Burst:             envInt("RATE_LIMIT_AUTH_BURST", 5),
+			},
+			Handles: RateLimit{
+				RequestsPerMinute: envInt("HANDLE_CHECK_RATE_LIMIT_PER_MINUTE", 10),
+				Burst:             envInt("HANDLE_CHECK_RATE_LIMIT_BURST", 5),
+			},
+			PublicAPI: RateLimit{
+				RequestsPerMinute: envInt("PUBLIC_API_RATE_LIMIT_PER_MINUTE", 60),
+				Burst:             envInt("PUBLIC_API_RATE_LIMIT_BURST", 10),
+			},
+		},
+	}
+}
+
+// envInt reads a non-negative integer environment variable, falling back to def when unset or invalid.
+// It must not log: it also runs from init(), before the logger is set up.
+func envInt(name string, def int) int {
+	n, err := strconv.Atoi(os.Getenv(name))
+	if err != nil || n < 0 {
+		return def
+	}
+	return n
+}
+
+// Current returns the active runtime config. The returned value must not be modified.
+func Current() *RuntimeConfig {
+	return current.Load()
+}
+
+// CaptchaRequiredFor reports whether the active config requires a CAPTCHA token on an endpoint.
+func CaptchaRequiredFor(endpoint string) bool {
+	return slices.Contains(Current().CaptchaRequired, endpoint)
+}
+
+// FeatureEnabled reports whether a feature flag is switched on in the active config.
+func FeatureEnabled(name string) bool {
+	return Current().FeatureFlags[name]
+}
+
+// FeatureEnabledFor is like FeatureEnabled but honours per-request overrides
+// propagated in the X-Feature-Flags header.
+func FeatureEnabledFor(ctx context.Context, name string) bool {
+	if enabled, ok := reqctx.FeatureOverride(ctx, name); ok {
+		return enabled
+	}
+	return FeatureEnabled(name)
+}
+
+// LoadRuntimeConfig reads a runtime config JSON file. An empty path yields the defaults.
+func LoadRuntimeConfig(path string) (*RuntimeConfig, error) {
+	cfg := defaultRuntimeConfig()
+	if path == "" {
+		return cfg, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read runtime config: %w", err)
+	}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse runtime config: %w", err)
+	}
+	if cfg.FeatureFlags == nil {
+		cfg.FeatureFlags = map[string]bool{}
+	}
+	if cfg.Rollouts == nil {
+		cfg.Rollouts = map[string]Rollout{}
+	}
+	return cfg, nil
+}
+
+// Validate checks that every field holds a usable value.
+func (c *RuntimeConfig) Validate() error {
+	if c.LogLevel != "" && !logger.ValidLevel(c.LogLevel) {
+		return fmt.Errorf("invalid log_level %q", c.LogLevel)
+	}
+	if c.LogSampling != nil {
+		if err := c.LogSampling.Validate(); err != nil {
+			return fmt.Errorf("invalid log_sampling: %w", err)
+		}
+	}
+	if c.RateLimits.RequestsPerMinute < 0 || c.RateLimits.Burst < 0 ||
+		c.RateLimits.Auth.RequestsPerMinute < 0 || c.RateLimits.Auth.Burst < 0 ||
+		c.RateLimits.Handles.RequestsPerMinute < 0 || c.RateLimits.Handles.Burst < 0 ||
+		c.RateLimits.PublicAPI.RequestsPerMinute < 0 || c.RateLimits.PublicAPI.Burst < 0 {
+		return fmt.Errorf("rate_limits values must not be negative")
+	}
+	if err := c.LoadShedding.validate(); err != nil {
+		return fmt.Errorf("invalid load_shedding: %w", err)
+	}
+	for feature, rollout := range c.Rollouts {
+		if err := rollout.validate(); err != nil {
+			return fmt.Errorf("invalid rollout %q: %w", feature, err)
+		}
+	}
+	if c.MaxSessionsPerUser < 0 {
+		return fmt.Errorf("max_sessions_per_user must not be negative")
+	}
+	if c.MessageRetentionDays < 0 {
+		return fmt.Errorf("message_retention_days must not be negative")
+	}
+	if c.AccountDeletionGraceDays < 0 {
+		return fmt.Errorf("account_deletion_grace_days must not be negative")
+	}
+	if c.PublicAPIDailyQuota < 0 {
+		return fmt.Errorf("public_api_daily_quota must not be negative")
+	}
+	if c.AnnouncementSendsPerSecond < 1 {
+		return fmt.Errorf("announcement_sends_per_second must be at least 1")
+	}
+	if p := c.AppointmentPolicy; p.CancelNoticeHours < 0 || p.RescheduleNoticeHours < 0 || p.MaxReschedules < 0 || p.ReminderHours < 0 {
+		return fmt.Errorf("appointment_policy values must not be negative")
+	}
+	l := c.WorkoutAttachments
+	if l.MaxVideoMB <= 0 || l.MaxFileMB <= 0 {
+		return fmt.Errorf("workout_attachments max_video_mb and max_file_mb must be positive")
+	}
+	if l.DefaultExpiryDays < 0 || l.MaxExpiryDays < 0 {
+		return fmt.Errorf("workout_attachments expiry days must not be negative")
+	}
+	if l.MaxExpiryDays > 0 && (l.DefaultExpiryDays == 0 || l.DefaultExpiryDays > l.MaxExpiryDays) {
+		return fmt.Errorf("workout_attachments default_expiry_days must be between 1 and max_expiry_days")
+	}
+	if c.BlobStorage.IntegritySampleSize < 0 {
+		return fmt.Errorf("blob_storage integrity_sample_size must not be negative")
+	}
+	for _, rule := range c.BlobStorage.Lifecycle {
+		if rule.ColdAfterDays < 0 || rule.DeleteAfterDays < 0 {
+			return fmt.Errorf("blob_storage lifecycle rule %q: days must not be negative", rule.Prefix)
+		}
+		if rule.ColdAfterDays > 0 && rule.DeleteAfterDays > 0 && rule.DeleteAfterDays <= rule.ColdAfterDays {
+			return fmt.Errorf("blob_storage lifecycle rule %q: delete_after_days must be after cold_after_days", rule.Prefix)
+		}
+		for _, tracked := range trackedBlobPrefixes {
+			if rule.DeleteAfterDays > 0 && (strings.HasPrefix(tracked, rule.Prefix) || strings.HasPrefix(rule.Prefix, tracked)) {
+				return fmt.Errorf("blob_storage lifecycle rule %q: %s files are deleted with their records and cannot have delete_after_days", rule.Prefix, tracked)
+			}
+		}
+	}
+	for _, endpoint := range c.CaptchaRequired {
+		if endpoint != CaptchaLogin && endpoint != CaptchaRegister {
+			return fmt.Errorf("invalid captcha_required endpoint %q, expected %q or %q", endpoint, CaptchaLogin, CaptchaRegister)
+		}
+	}
+	names := map[string]bool{}
+	for _, slo := range c.SLOs {
+		if slo.Name == "" || names[slo.Name] {
+			return fmt.Errorf("every SLO needs a unique name")
+		}
+		names[slo.Name] = true
+		if _, _, ok := strings.Cut(slo.Route, " "); !ok {
+			return fmt.Errorf("SLO %q: route must be a method and pattern such as \"POST /login\"", slo.Name)
+		}
+		if slo.Objective <= 0 || slo.Objective >= 1 {
+			return fmt.Errorf("SLO %q: objective must be between 0 and 1 exclusive", slo.Name)
+		}
+		if slo.WindowMinutes <= 0 || slo.WindowMinutes > MaxSLOWindowMinutes {
+			return fmt.Errorf("SLO %q: window_minutes must be between 1 and %d", slo.Name, MaxSLOWindowMinutes)
+		}
+		if slo.LatencyThresholdMS < 0 || slo.BurnRateAlert < 0 {
+			return fmt.Errorf("SLO %q: latency_threshold_ms and burn_rate_alert must not be negative", slo.Name)
+		}
+	}
+	for _, origin := range c.CORSAllowedOrigins {
+		if origin == "*" {
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || u.Scheme == "" || u.Host == "" || u.Path != "" {
+			return fmt.Errorf("invalid CORS origin %q, expected scheme://host[:port]", origin)
+		}
+	}
+	return nil
+}
+
+func (l LoadShedding) validate() error {
+	if l.Default.Route != "" {
+		return fmt.Errorf("the default limit cannot have a route")
+	}
+	routes := map[string]bool{}
+	for _, limit := range append([]ConcurrencyLimit{l.Default}, l.Routes...) {
+		if limit.MaxInFlight < 0 || limit.MaxQueue < 0 || limit.QueueTimeoutMS < 0 {
+			return fmt.Errorf("limit %q: values must not be negative", limit.Route)
+		}
+		if limit.QueueTimeoutMS > MaxQueueTimeoutMS {
+			return fmt.Errorf("limit %q: queue_timeout_ms must be at most %d", limit.Route, MaxQueueTimeoutMS)
+		}
+	}
+	for _, limit := range l.Routes {
+		if _, _, ok := strings.Cut(limit.Route, " "); !ok || routes[limit.Route] {
+			return fmt.Errorf("every route limit needs a unique method and pattern such as \"GET /users\"")
+		}
+		routes[limit.Route] = true
+	}
+	if l.TargetLatencyMS < 0 {
+		return fmt.Errorf("target_latency_ms must not be negative")
+	}
+	if l.MaxCPU < 0 || l.MaxCPU > 1 {
+		return fmt.Errorf("max_cpu must be between 0 and 1")
+	}
+	if l.MaxShedFraction < 0 || l.MaxShedFraction > 1 {
+		return fmt.Errorf("max_shed_fraction must be between 0 and 1")
+	}
+	if l.RetryAfterSeconds < 0 || l.RetryAfterSeconds > MaxRetryAfterSeconds {
+		return fmt.Errorf("retry_after_seconds must be between 0 and %d", MaxRetryAfterSeconds)
+	}
+	return nil
+}
+
+func (r Rollout) validate() error {
+	for _, country := range r.Countries {
+		if len(country) != 2 || strings.ToUpper(country) != country {
+			return fmt.Errorf("country %q must be an uppercase ISO 3166-1 alpha-2 code", country)
+		}
+	}
+	for _, locale := range r.Locales {
+		if locale == "" || strings.ContainsAny(locale, ",; ") {
+			return fmt.Errorf("locale %q must be a BCP 47 tag such as \"de\" or \"pt-BR\"", locale)
+		}
+	}
+	if slices.Contains(r.Plans, "") {
+		return fmt.Errorf("plans must not be empty")
+	}
+	if r.Percent < 0 || r.Percent > 100 {
+		return fmt.Errorf("percent must be between 0 and 100")
+	}
+	return nil
+}
+
+// Diff lists the top-level fields that differ between two configs.
+func Diff(old, updated *RuntimeConfig) []string {
+	var changed []string
+	ov, nv := reflect.ValueOf(*old), reflect.ValueOf(*updated)
+	for i := 0; i < ov.NumField(); i++ {
+		if !reflect.DeepEqual(ov.Field(i).Interface(), nv.Field(i).Interface()) {
+			changed = append(changed, ov.Type().Field(i).Tag.Get("json"))
+		}
+	}
+	return changed
+}
+
+// Reloader re-reads the runtime config file and applies it atomically.
+type Reloader struct {
+	path     string
+	mu       sync.Mutex                           // Serializes concurrent reloads (signal + endpoint)
+	onChange func(actor string, changed []string) // Called after a successful reload that changed something
+}
+
+// NewReloader creates a Reloader for the given file. onChange may be nil.
+func NewReloader(path string, onChange func(actor string, changed []string)) *Reloader {
+	return &Reloader{path: path, onChange: onChange}
+}
+
+// Reload loads, validates, and applies the config file. On any error the active config is left untouched.
+// actor identifies who triggered the reload for auditing (a user ID, or "signal").
+func (r *Reloader) Reload(actor string) (*RuntimeConfig, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cfg, err := LoadRuntimeConfig(r.path)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("runtime config validation failed: %w", err)
+	}
+
+	old := Current()
+	if cfg.LogLevel != "" {
+		if err := logger.SetLevel(cfg.LogLevel); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.LogSampling != nil {
+		if err := logger.SetSampling(*cfg.LogSampling); err != nil {
+			return nil, err
+		}
+	} else {
+		logger.ResetSampling()
+	}
+	current.Store(cfg)
+
+	changed := Diff(old, cfg)
+	if len(changed) > 0 {
+		logger.Logger.Infof("Runtime config reloaded by %s, changed: %s", actor, strings.Join(changed, ", "))
+		if r.onChange != nil {
+			r.onChange(actor, changed)
+		}
+	} else {
+		logger.Logger.Infof("Runtime config reloaded by %s, no changes.", actor)
+	}
+	return cfg, nil
+}
@@ -0,0 +1,70 @@
+// services/user-service/internal/config/tls.go
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// DefaultAutocertCacheDir is where certificates obtained from the ACME CA are kept between restarts.
+const DefaultAutocertCacheDir = "data/autocert"
+
+// TLS holds how the service terminates TLS itself, for environments without a fronting proxy. It is
+// read once at startup; with neither a certificate nor autocert domains the service serves plain HTTP.
+type TLS struct {
+	CertFile string // TLS_CERT_FILE, PEM certificate chain; reloaded when it changes
+	KeyFile  string // TLS_KEY_FILE, PEM private key of TLS_CERT_FILE
+
+	AutocertDomains  []string // TLS_AUTOCERT_DOMAINS, comma-separated hosts to obtain certificates for over ACME
+	AutocertEmail    string   // TLS_AUTOCERT_EMAIL, contact for the ACME account; optional
+	AutocertCacheDir string   // TLS_AUTOCERT_CACHE_DIR, default DefaultAutocertCacheDir
+	AutocertURL      string   // TLS_AUTOCERT_DIRECTORY_URL, ACME directory; default Let's Encrypt production
+
+	// HTTPPort is TLS_HTTP_PORT, a plain HTTP port that redirects to HTTPS and answers ACME HTTP-01
+	// challenges; empty for none.
+	HTTPPort string
+}
+
+// LoadTLS reads TLS_CERT_FILE, TLS_KEY_FILE, TLS_AUTOCERT_DOMAINS, TLS_AUTOCERT_EMAIL,
+// TLS_AUTOCERT_CACHE_DIR, TLS_AUTOCERT_DIRECTORY_URL, and TLS_HTTP_PORT.
+func LoadTLS() (*TLS, error) {
+	t := TLS{
+		CertFile:         os.Getenv("TLS_CERT_FILE"),
+		KeyFile:          os.Getenv("TLS_KEY_FILE"),
+		AutocertEmail:    os.Getenv("TLS_AUTOCERT_EMAIL"),
+		AutocertCacheDir: os.Getenv("TLS_AUTOCERT_CACHE_DIR"),
+		AutocertURL:      os.Getenv("TLS_AUTOCERT_DIRECTORY_URL"),
+		HTTPPort:         os.Getenv("TLS_HTTP_PORT"),
+	}
+	for _, domain := range strings.Split(os.Getenv("TLS_AUTOCERT_DOMAINS"), ",") {
+		if domain = strings.TrimSpace(domain); domain != "" {
+			t.AutocertDomains = append(t.AutocertDomains, strings.ToLower(domain))
+		}
+	}
+	if t.AutocertCacheDir == "" {
+		t.AutocertCacheDir = DefaultAutocertCacheDir
+	}
+
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		return nil, fmt.Errorf("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	if t.CertFile != "" && len(t.AutocertDomains) > 0 {
+		return nil, fmt.Errorf("TLS_CERT_FILE and TLS_AUTOCERT_DOMAINS are mutually exclusive")
+	}
+	if t.HTTPPort != "" {
+		if !t.Enabled() {
+			return nil, fmt.Errorf("TLS_HTTP_PORT requires TLS_CERT_FILE or TLS_AUTOCERT_DOMAINS")
+		}
+		if port, err := strconv.Atoi(t.HTTPPort); err != nil || port <= 0 || port > 65535 {
+			return nil, fmt.Errorf("invalid TLS_HTTP_PORT %q, expected a port number", t.HTTPPort)
+		}
+	}
+	return &t, nil
+}
+
+// Enabled reports whether the service serves HTTPS.
+func (t TLS) Enabled() bool {
+	return t.CertFile != "" || len(t.AutocertDomains) > 0
+}
@@ -0,0 +1,99 @@
+// services/user-service/internal/datasummary/datasummary.go
+package datasummary
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/utils/reqctx"
+)
+
+// serviceName restricts source names to short identifiers, since they are shown to users.
+var serviceName = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,31}$`)
+
+// Source reports what one other Pulse service stores about a user.
+type Source interface {
+	Name() string // The service, as shown in the summary
+	Summarize(ctx context.Context, userID uuid.UUID) ([]models.DataCategory, error)
+}
+
+// HTTPSource asks a Pulse service for its summary through its internal API: GET on a URL template
+// where {user_id} is replaced, answered with {"categories": [...]} in the DataCategory format. The
+// standard context headers, such as the request ID, are forwarded.
+type HTTPSource struct {
+	name   string
+	url    string
+	token  string // Sent as a bearer token when set
+	client *http.Client
+}
+
+// NewHTTPSource creates an HTTPSource for a service's URL template.
+func NewHTTPSource(name, url, token string) *HTTPSource {
+	return &HTTPSource{name: name, url: url, token: token, client: reqctx.NewClient(5 * time.Second)}
+}
+
+// ParseSources reads DATA_SUMMARY_SOURCES ("workout-service=http://.../internal/users/{user_id}/data-summary,...").
+// Every source is sent token as a bearer token.
+func ParseSources(spec, token string) ([]Source, error) {
+	var sources []Source
+	seen := map[string]bool{}
+	for _, entry := range strings.Split(spec, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		name, url, ok := strings.Cut(entry, "=")
+		if !ok || !serviceName.MatchString(name) || !strings.Contains(url, "{user_id}") {
+			return nil, fmt.Errorf("invalid DATA_SUMMARY_SOURCES entry %q, expected service=url with {user_id}", entry)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("service %q is configured twice in DATA_SUMMARY_SOURCES", name)
+		}
+		seen[name] = true
+		sources = append(sources, NewHTTPSource(name, url, token))
+	}
+	return sources, nil
+}
+
+// Name returns the service the source asks.
+func (s *HTTPSource) Name() string {
+	return s.name
+}
+
+// Summarize fetches the service's categories for the user; any non-2xx response is an error.
+// The categories are attributed to the source, whatever service the response names.
+func (s *HTTPSource) Summarize(ctx context.Context, userID uuid.UUID) ([]models.DataCategory, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.ReplaceAll(s.url, "{user_id}", userID.String()), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build data summary request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s unreachable: %w", s.name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("%s returned status %d", s.name, resp.StatusCode)
+	}
+
+	var body struct {
+		Categories []models.DataCategory `json:"categories"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("%s returned an invalid summary: %w", s.name, err)
+	}
+	for i := range body.Categories {
+		body.Categories[i].Service = s.name
+	}
+	return body.Categories, nil
+}
@@ -0,0 +1,124 @@
+// services/user-service/internal/errreport/errreport.go
+package errreport
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// Event is one error sent to the error tracker.
+type Event struct {
+	Message     string
+	Level       string // "error" or "fatal"
+	Timestamp   time.Time
+	Release     string
+	Environment string
+	UserID      string // Only the verified user ID is attached, never an email
+	RequestID   string
+	Tags        map[string]string
+	Extra       map[string]interface{}
+	Stack       string
+}
+
+// Reporter delivers events to an error tracker. Report must not block the caller.
+type Reporter interface {
+	Report(event Event)
+}
+
+var (
+	reporter    atomic.Pointer[Reporter]
+	release     string
+	environment string
+)
+
+// Init installs r as the process-wide reporter and forwards every error-level log entry to it.
+// release and environment are attached to every event.
+func Init(r Reporter, rel, env string) {
+	release, environment = rel, env
+	reporter.Store(&r)
+	logger.SetErrorHook(fromLog)
+	logger.Logger.Infof("Error reporting enabled (release %q, environment %q)", rel, env)
+}
+
+// fromLog converts a redacted error log entry to an event. The well-known fields user_id,
+// request_id, and job become the user, request ID, and a tag; the others are kept as extra data.
+func fromLog(ent zapcore.Entry, fields map[string]interface{}) {
+	event := Event{
+		Message:   ent.Message,
+		Level:     "error",
+		Timestamp: ent.Time,
+		Stack:     ent.Stack,
+		Tags:      map[string]string{},
+		Extra:     map[string]interface{}{},
+	}
+	if ent.Level >= zapcore.DPanicLevel {
+		event.Level = "fatal"
+	}
+	if ent.Caller.Defined {
+		event.Tags["caller"] = ent.Caller.TrimmedPath()
+	}
+	for key, value := range fields {
+		switch key {
+		case "user_id":
+			event.UserID = fmt.Sprint(value)
+		case "request_id":
+			event.RequestID = fmt.Sprint(value)
+		case "job":
+			event.Tags["job"] = fmt.Sprint(value)
+		default:
+			event.Extra[key] = value
+		}
+	}
+	send(event)
+}
+
+func send(event Event) {
+	r := reporter.Load()
+	if r == nil {
+		return
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+	event.Release, event.Environment = release, environment
+	(*r).Report(event)
+}
+
+// scope collects reporting context that is only known deeper in the handler chain,
+// such as the verified user, so middleware further out can attach it to a report.
+type scope struct {
+	mu     sync.Mutex
+	userID string
+}
+
+type scopeKey struct{}
+
+// WithScope returns a copy of ctx with an empty reporting scope.
+func WithScope(ctx context.Context) context.Context {
+	return context.WithValue(ctx, scopeKey{}, &scope{})
+}
+
+// SetUser records the verified user ID on the scope in ctx, if there is one.
+func SetUser(ctx context.Context, userID string) {
+	if s, ok := ctx.Value(scopeKey{}).(*scope); ok {
+		s.mu.Lock()
+		s.userID = userID
+		s.mu.Unlock()
+	}
+}
+
+// User returns the verified user ID recorded on the scope in ctx, or "".
+func User(ctx context.Context) string {
+	if s, ok := ctx.Value(scopeKey{}).(*scope); ok {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.userID
+	}
+	return ""
+}
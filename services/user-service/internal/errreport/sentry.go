@@ -0,0 +1,135 @@
+// services/user-service/internal/errreport/sentry.go
+package errreport
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// sentryQueueSize bounds the events waiting to be sent; further events are dropped.
+const sentryQueueSize = 100
+
+// SentryReporter sends events to Sentry, or any service accepting the Sentry envelope
+// protocol (e.g. GlitchTip), from a background goroutine.
+type SentryReporter struct {
+	endpoint   string
+	authHeader string
+	serverName string
+	client     *http.Client
+	queue      chan Event
+}
+
+// NewSentryReporter parses a DSN of the form https://<key>@<host>[/<path>]/<project-id>
+// and starts the delivery goroutine.
+func NewSentryReporter(dsn string) (*SentryReporter, error) {
+	u, err := url.Parse(dsn)
+	if err != nil || u.User == nil || u.User.Username() == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid Sentry DSN")
+	}
+	path := strings.TrimSuffix(u.Path, "/")
+	slash := strings.LastIndex(path, "/")
+	project := path[slash+1:]
+	if project == "" {
+		return nil, fmt.Errorf("invalid Sentry DSN: missing project ID")
+	}
+	hostname, _ := os.Hostname()
+
+	r := &SentryReporter{
+		endpoint:   fmt.Sprintf("%s://%s%s/api/%s/envelope/", u.Scheme, u.Host, path[:slash], project),
+		authHeader: fmt.Sprintf("Sentry sentry_version=7, sentry_client=pulse-user-service/1.0, sentry_key=%s", u.User.Username()),
+		serverName: hostname,
+		client:     &http.Client{Timeout: 5 * time.Second},
+		queue:      make(chan Event, sentryQueueSize),
+	}
+	go func() {
+		for event := range r.queue {
+			r.deliver(event)
+		}
+	}()
+	return r, nil
+}
+
+// Report queues the event. Fatal events are sent synchronously since the process is about to exit.
+func (r *SentryReporter) Report(event Event) {
+	if event.Level == "fatal" {
+		r.deliver(event)
+		return
+	}
+	select {
+	case r.queue <- event:
+	default:
+		logger.Logger.Warnf("Error report queue full, dropping event: %s", event.Message)
+	}
+}
+
+func (r *SentryReporter) deliver(event Event) {
+	eventID := strings.ReplaceAll(uuid.NewString(), "-", "")
+	payload := map[string]interface{}{
+		"event_id":    eventID,
+		"timestamp":   event.Timestamp.UTC().Format(time.RFC3339Nano),
+		"level":       event.Level,
+		"platform":    "go",
+		"logger":      "user-service",
+		"server_name": r.serverName,
+		"release":     event.Release,
+		"environment": event.Environment,
+		"message":     map[string]string{"formatted": event.Message},
+	}
+	if event.UserID != "" {
+		payload["user"] = map[string]string{"id": event.UserID}
+	}
+	tags := map[string]string{}
+	for k, v := range event.Tags {
+		tags[k] = v
+	}
+	if event.RequestID != "" {
+		tags["request_id"] = event.RequestID
+	}
+	payload["tags"] = tags
+	extra := map[string]interface{}{}
+	for k, v := range event.Extra {
+		extra[k] = v
+	}
+	if event.Stack != "" {
+		extra["stacktrace"] = event.Stack
+	}
+	payload["extra"] = extra
+
+	item, err := json.Marshal(payload)
+	if err != nil {
+		logger.Logger.Warnf("Failed to encode error report: %v", err)
+		return
+	}
+	// An envelope is newline-delimited: envelope header, item header, item payload.
+	var body bytes.Buffer
+	fmt.Fprintf(&body, `{"event_id":%q,"sent_at":%q}`+"\n", eventID, time.Now().UTC().Format(time.RFC3339Nano))
+	fmt.Fprintf(&body, `{"type":"event","length":%d}`+"\n", len(item))
+	body.Write(item)
+	body.WriteByte('\n')
+
+	req, err := http.NewRequest(http.MethodPost, r.endpoint, &body)
+	if err != nil {
+		logger.Logger.Warnf("Failed to build error report request: %v", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("X-Sentry-Auth", r.authHeader)
+	resp, err := r.client.Do(req)
+	if err != nil {
+		logger.Logger.Warnf("Failed to send error report: %v", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		logger.Logger.Warnf("Error tracker rejected report with status %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,115 @@
+// services/user-service/internal/eventbus/eventbus.go
+package eventbus
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+	"health-tracker-project/services/user-service/internal/utils/reqctx"
+)
+
+// Event types published to other Pulse services.
+const (
+	// UserCreated announces a new user. Like UserUpdated, it carries no data: consumers that keep
+	// details of users read them from this service.
+	UserCreated = "user.created"
+	// UserUpdated announces a change to a user's account, such as their name, email, or status. Each
+	// update is its own event, with its own ID.
+	UserUpdated = "user.updated"
+	// UserDeleted tells every service holding data about the user to purge it. It is published once
+	// the grace period of an erasure request has passed, before the user's record is removed here, and
+	// when an admin deletes the user.
+	UserDeleted = "user.deleted"
+	// UserMerged tells every service holding data about data.donor_user_id to re-point it to the user,
+	// whom a duplicate account was merged into. The donor's ID is not used again unless the merge is undone.
+	UserMerged = "user.merged"
+	// UserMergeUndone reverses UserMerged for data.merge_id: the donor account exists again, and services
+	// that recorded what they re-pointed for the merge move it back.
+	UserMergeUndone = "user.merge_undone"
+)
+
+// Event is a domain event for other Pulse services. Consumers must tolerate redelivery: a failed
+// step after publishing publishes the event again, with the same ID.
+type Event struct {
+	ID         uuid.UUID         `json:"id"`
+	Type       string            `json:"type"`
+	UserID     uuid.UUID         `json:"user_id"`
+	OccurredAt time.Time         `json:"occurred_at"`
+	Data       map[string]string `json:"data,omitempty"`
+}
+
+// NewEvent creates an event whose ID is derived from its type and user, so republishing the same
+// fact yields the same ID.
+func NewEvent(eventType string, userID uuid.UUID, data map[string]string) Event {
+	return Event{
+		ID:         uuid.NewSHA1(userID, []byte(eventType)),
+		Type:       eventType,
+		UserID:     userID,
+		OccurredAt: time.Now().UTC(),
+		Data:       data,
+	}
+}
+
+// Publisher defines the interface for delivering events to the services that consume them.
+type Publisher interface {
+	Publish(ctx context.Context, e Event) error
+}
+
+// LogPublisher is a development Publisher that writes events to the log instead of sending them.
+type LogPublisher struct{}
+
+// NewLogPublisher creates a new LogPublisher instance.
+func NewLogPublisher() *LogPublisher {
+	return &LogPublisher{}
+}
+
+// Publish logs the event that would have been delivered.
+func (p *LogPublisher) Publish(ctx context.Context, e Event) error {
+	logger.FromContext(ctx).Infof("Event %s | Type: %s | User: %s", e.ID, e.Type, e.UserID)
+	return nil
+}
+
+// WebhookPublisher posts events as JSON to an event gateway that fans them out to subscribed services.
+type WebhookPublisher struct {
+	url    string
+	token  string // Sent as a bearer token when set
+	client *http.Client
+}
+
+// NewWebhookPublisher creates a WebhookPublisher for a gateway URL. Its requests carry the standard
+// Pulse headers of the context they are published in, such as the request ID.
+func NewWebhookPublisher(url, token string) *WebhookPublisher {
+	return &WebhookPublisher{url: url, token: token, client: reqctx.NewClient(10 * time.Second)}
+}
+
+// Publish posts the event; any non-2xx response is an error. It gives up once ctx is done.
+func (p *WebhookPublisher) Publish(ctx context.Context, e Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build event request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event-Type", e.Type)
+	if p.token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.token)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("event gateway unreachable: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("event gateway returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+// services/user-service/internal/fieldcrypt/fieldcrypt.go
+
+// Package fieldcrypt seals sensitive column values before they are written to the database, so the
+// database, its replicas, and its backups hold only ciphertext. It uses envelope encryption: each value
+// is encrypted with AES-256-GCM under a random data key of its own, and the data key is encrypted under
+// a named 256-bit master key, whose ID is stored with the value. A value is bound to a context, such as
+// its column and row, so it does not open once copied into another row or column.
+//
+// A sealed value is text: "pf1.<key ID>.<sealed data key>.<sealed value>", both sealed parts base64url
+// encoded with their nonce in front. Rotating master keys only re-seals the data keys (see Rewrap).
+package fieldcrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// version starts every sealed value, so the format can change without guessing at old values.
+const version = "pf1"
+
+var (
+	// ErrUnknownKey is returned for a value sealed with a master key the Sealer was not given.
+	ErrUnknownKey = errors.New("fieldcrypt: sealed with an unknown key")
+	// ErrCorrupt is returned for a value that is not a sealed value, was altered, or belongs to another context.
+	ErrCorrupt = errors.New("fieldcrypt: sealed value is corrupt")
+)
+
+var validKeyID = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+// Key is a named 256-bit master key. Its ID is stored with every value it seals.
+type Key struct {
+	ID  string
+	Key []byte
+}
+
+// ParseKeys parses "id:base64key,id:base64key". The first key seals new values; the others are kept
+// to open values sealed before a rotation, until Rewrap has moved them to the first.
+func ParseKeys(spec string) ([]Key, error) {
+	var keys []Key
+	seen := map[string]bool{}
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		id, encoded, ok := strings.Cut(pair, ":")
+		if !ok || !validKeyID.MatchString(id) {
+			return nil, fmt.Errorf("encryption key %q must be id:base64key with an ID of up to 32 letters, digits, - or _", pair)
+		}
+		if seen[id] {
+			return nil, fmt.Errorf("encryption key ID %q appears more than once", id)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("encryption key %q must be 32 bytes, base64-encoded", id)
+		}
+		seen[id] = true
+		keys = append(keys, Key{ID: id, Key: key})
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no encryption keys given")
+	}
+	return keys, nil
+}
+
+// Sealer seals and opens values under a set of master keys. It is safe for concurrent use.
+type Sealer struct {
+	keys    map[string]cipher.AEAD
+	current string
+}
+
+// NewSealer creates a Sealer. keys[0] seals new values.
+func NewSealer(keys []Key) (*Sealer, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no encryption keys given")
+	}
+	s := &Sealer{keys: map[string]cipher.AEAD{}, current: keys[0].ID}
+	for _, k := range keys {
+		if !validKeyID.MatchString(k.ID) {
+			return nil, fmt.Errorf("invalid encryption key ID %q", k.ID)
+		}
+		aead, err := newAEAD(k.Key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid encryption key %q: %w", k.ID, err)
+		}
+		s.keys[k.ID] = aead
+	}
+	return s, nil
+}
+
+// CurrentPrefix is how every value sealed under the current master key starts. A stored value that
+// does not start with it is due for Rewrap.
+func (s *Sealer) CurrentPrefix() string {
+	return version + "." + s.current + "."
+}
+
+// Seal encrypts plaintext under a new data key, sealed with the current master key. context names
+// where the value is stored, such as "users.health_sealed:" and the row's ID; Open needs the same one.
+func (s *Sealer) Seal(plaintext []byte, context string) (string, error) {
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", fmt.Errorf("fieldcrypt: failed to generate a data key: %w", err)
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+	body, err := seal(aead, plaintext, []byte(context))
+	if err != nil {
+		return "", err
+	}
+	return s.wrap(dataKey, body)
+}
+
+// Open decrypts a value sealed with Seal under the same context.
+func (s *Sealer) Open(sealed, context string) ([]byte, error) {
+	dataKey, body, err := s.unwrap(sealed)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return open(aead, body, []byte(context))
+}
+
+// Rewrap re-seals the data key of a sealed value under the current master key, leaving the value's
+// own ciphertext as it is; it needs no context. A value already under the current key is returned as it is.
+func (s *Sealer) Rewrap(sealed string) (string, error) {
+	if strings.HasPrefix(sealed, s.CurrentPrefix()) {
+		return sealed, nil
+	}
+	dataKey, body, err := s.unwrap(sealed)
+	if err != nil {
+		return "", err
+	}
+	return s.wrap(dataKey, body)
+}
+
+// wrap seals dataKey under the current master key and formats it with the sealed value body.
+func (s *Sealer) wrap(dataKey, body []byte) (string, error) {
+	sealedKey, err := seal(s.keys[s.current], dataKey, []byte(version+"."+s.current))
+	if err != nil {
+		return "", err
+	}
+	return s.CurrentPrefix() + base64.RawURLEncoding.EncodeToString(sealedKey) + "." + base64.RawURLEncoding.EncodeToString(body), nil
+}
+
+// unwrap opens the data key of a sealed value and returns it with the sealed value body.
+func (s *Sealer) unwrap(sealed string) (dataKey, body []byte, err error) {
+	parts := strings.Split(sealed, ".")
+	if len(parts) != 4 || parts[0] != version {
+		return nil, nil, ErrCorrupt
+	}
+	master, ok := s.keys[parts[1]]
+	if !ok {
+		return nil, nil, fmt.Errorf("%w %q", ErrUnknownKey, parts[1])
+	}
+	sealedKey, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, nil, ErrCorrupt
+	}
+	if body, err = base64.RawURLEncoding.DecodeString(parts[3]); err != nil {
+		return nil, nil, ErrCorrupt
+	}
+	if dataKey, err = open(master, sealedKey, []byte(version+"."+parts[1])); err != nil {
+		return nil, nil, err
+	}
+	return dataKey, body, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts plaintext with a random nonce, which it puts in front of the ciphertext.
+func seal(aead cipher.AEAD, plaintext, additional []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("fieldcrypt: failed to generate a nonce: %w", err)
+	}
+	return aead.Seal(nonce, nonce, plaintext, additional), nil
+}
+
+func open(aead cipher.AEAD, sealed, additional []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, ErrCorrupt
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], additional)
+	if err != nil {
+		return nil, ErrCorrupt
+	}
+	return plaintext, nil
+}
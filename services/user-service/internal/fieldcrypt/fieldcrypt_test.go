@@ -0,0 +1,228 @@
+// services/user-service/internal/fieldcrypt/fieldcrypt_test.go
+package fieldcrypt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// testKey returns a master key whose bytes are all b, so keys differ by their fill.
+func testKey(id string, b byte) Key {
+	return Key{ID: id, Key: bytes.Repeat([]byte{b}, 32)}
+}
+
+func newTestSealer(t *testing.T, keys ...Key) *Sealer {
+	t.Helper()
+	s, err := NewSealer(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestSealOpenRoundTrip(t *testing.T) {
+	s := newTestSealer(t, testKey("k1", 1))
+	tests := []struct {
+		name      string
+		plaintext []byte
+		context   string
+	}{
+		{"empty", []byte{}, "users.health_sealed:1"},
+		{"text", []byte(`{"height_cm":182.5,"date_of_birth":"1990-04-01"}`), "users.health_sealed:2"},
+		{"binary", []byte{0, 1, 2, 0xfe, 0xff}, "users.health_sealed:3"},
+		{"no context", []byte("value"), ""},
+		{"large", bytes.Repeat([]byte("x"), 1<<16), "users.health_sealed:4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sealed, err := s.Seal(tt.plaintext, tt.context)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(sealed, s.CurrentPrefix()) {
+				t.Errorf("sealed value %q does not start with %q", sealed, s.CurrentPrefix())
+			}
+			if len(tt.plaintext) > 0 && strings.Contains(sealed, string(tt.plaintext)) {
+				t.Error("sealed value holds the plaintext")
+			}
+			opened, err := s.Open(sealed, tt.context)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(opened, tt.plaintext) {
+				t.Errorf("Open() = %q, want %q", opened, tt.plaintext)
+			}
+		})
+	}
+}
+
+func TestSealUsesFreshKeys(t *testing.T) {
+	s := newTestSealer(t, testKey("k1", 1))
+	a, err := s.Seal([]byte("same"), "ctx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := s.Seal([]byte("same"), "ctx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a == b {
+		t.Error("sealing the same value twice gave the same result")
+	}
+}
+
+// replacePart returns sealed with its part i (0 is the version) replaced by part.
+func replacePart(sealed string, i int, part string) string {
+	parts := strings.Split(sealed, ".")
+	parts[i] = part
+	return strings.Join(parts, ".")
+}
+
+// mustDecode returns the base64url part i of sealed, decoded.
+func mustDecode(t *testing.T, sealed string, i int) []byte {
+	t.Helper()
+	raw, err := base64.RawURLEncoding.DecodeString(strings.Split(sealed, ".")[i])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+// flipByte returns sealed with the byte at offset of its base64url part i flipped.
+func flipByte(t *testing.T, sealed string, i, offset int) string {
+	t.Helper()
+	raw := mustDecode(t, sealed, i)
+	raw[offset] ^= 0x01
+	return replacePart(sealed, i, base64.RawURLEncoding.EncodeToString(raw))
+}
+
+func TestOpenDetectsTampering(t *testing.T) {
+	s := newTestSealer(t, testKey("k1", 1), testKey("k0", 2))
+	const context = "users.health_sealed:1"
+	sealed, err := s.Seal([]byte("182.5"), context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := s.Seal([]byte("170.0"), context)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		sealed  string
+		context string
+		want    error
+	}{
+		{"other context", sealed, "users.health_sealed:2", ErrCorrupt},
+		{"no context", sealed, "", ErrCorrupt},
+		{"flipped value nonce", flipByte(t, sealed, 3, 0), context, ErrCorrupt},
+		{"flipped value ciphertext", flipByte(t, sealed, 3, 12), context, ErrCorrupt},
+		{"flipped value tag", flipByte(t, sealed, 3, len(mustDecode(t, sealed, 3))-1), context, ErrCorrupt},
+		{"flipped data key", flipByte(t, sealed, 2, 20), context, ErrCorrupt},
+		{"data key of another value", replacePart(sealed, 2, strings.Split(other, ".")[2]), context, ErrCorrupt},
+		{"value of another data key", replacePart(sealed, 3, strings.Split(other, ".")[3]), context, ErrCorrupt},
+		{"data key relabeled to another master key", replacePart(sealed, 1, "k0"), context, ErrCorrupt},
+		{"truncated value", replacePart(sealed, 3, "AAAA"), context, ErrCorrupt},
+		{"empty value", replacePart(sealed, 3, ""), context, ErrCorrupt},
+		{"not base64", replacePart(sealed, 3, "!!!"), context, ErrCorrupt},
+		{"other version", replacePart(sealed, 0, "pf2"), context, ErrCorrupt},
+		{"missing part", sealed[:strings.LastIndex(sealed, ".")], context, ErrCorrupt},
+		{"plaintext", "182.5", context, ErrCorrupt},
+		{"unknown master key", replacePart(sealed, 1, "k9"), context, ErrUnknownKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opened, err := s.Open(tt.sealed, tt.context)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Open() = %q, %v; want %v", opened, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRewrap(t *testing.T) {
+	const context = "users.health_sealed:1"
+	old := newTestSealer(t, testKey("k1", 1))
+	sealed, err := old.Seal([]byte("182.5"), context)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotated := newTestSealer(t, testKey("k2", 2), testKey("k1", 1))
+	if strings.HasPrefix(sealed, rotated.CurrentPrefix()) {
+		t.Fatal("value sealed under k1 looks current after rotating to k2")
+	}
+	rewrapped, err := rotated.Rewrap(sealed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(rewrapped, rotated.CurrentPrefix()) {
+		t.Errorf("Rewrap() = %q, want it under %q", rewrapped, rotated.CurrentPrefix())
+	}
+	if got, want := strings.Split(rewrapped, ".")[3], strings.Split(sealed, ".")[3]; got != want {
+		t.Error("Rewrap() changed the sealed value itself, not only its data key")
+	}
+	opened, err := rotated.Open(rewrapped, context)
+	if err != nil || string(opened) != "182.5" {
+		t.Fatalf("Open() after Rewrap() = %q, %v", opened, err)
+	}
+	if again, err := rotated.Rewrap(rewrapped); err != nil || again != rewrapped {
+		t.Errorf("Rewrap() of a current value = %q, %v; want it unchanged", again, err)
+	}
+
+	// Once k1 is retired, only the rewrapped value still opens.
+	retired := newTestSealer(t, testKey("k2", 2))
+	if _, err := retired.Open(sealed, context); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Open() of a value under a retired key = %v, want ErrUnknownKey", err)
+	}
+	if _, err := retired.Open(rewrapped, context); err != nil {
+		t.Errorf("Open() of a rewrapped value = %v", err)
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, 32))
+	short := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, 16))
+	tests := []struct {
+		name    string
+		spec    string
+		wantIDs []string
+		wantErr string
+	}{
+		{"one key", "k1:" + key, []string{"k1"}, ""},
+		{"rotation", "k2:" + key + ", k1:" + key, []string{"k2", "k1"}, ""},
+		{"empty entries", ",k1:" + key + ",", []string{"k1"}, ""},
+		{"empty", "", nil, "no encryption keys"},
+		{"no ID", key, nil, "must be id:base64key"},
+		{"bad ID", "k 1:" + key, nil, "must be id:base64key"},
+		{"ID too long", strings.Repeat("k", 33) + ":" + key, nil, "must be id:base64key"},
+		{"duplicate ID", "k1:" + key + ",k1:" + key, nil, "more than once"},
+		{"short key", "k1:" + short, nil, "must be 32 bytes"},
+		{"not base64", "k1:not-base64!", nil, "must be 32 bytes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := ParseKeys(tt.spec)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseKeys() error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ids []string
+			for _, k := range keys {
+				ids = append(ids, k.ID)
+			}
+			if strings.Join(ids, ",") != strings.Join(tt.wantIDs, ",") {
+				t.Errorf("ParseKeys() IDs = %v, want %v", ids, tt.wantIDs)
+			}
+		})
+	}
+}
@@ -0,0 +1,406 @@
+// services/user-service/internal/graphql/execute.go
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// Execute parses, validates, and runs a request. Requests that cannot run get a response with errors
+// and no data; fields that fail while running are null in the data, with an error each.
+func (s *Schema) Execute(ctx context.Context, req Request) *Response {
+	doc, err := parse(req.Query)
+	if err != nil {
+		return &Response{Errors: []*Error{err}}
+	}
+	if errs := s.validate(doc); len(errs) > 0 {
+		return &Response{Errors: errs}
+	}
+	op, err := selectOperation(doc, req.OperationName)
+	if err != nil {
+		return &Response{Errors: []*Error{err}}
+	}
+	vars, errs := s.coerceVariables(op, req.Variables)
+	if len(errs) > 0 {
+		return &Response{Errors: errs}
+	}
+
+	e := &executor{ctx: ctx, schema: s, doc: doc, vars: vars}
+	data, _ := e.selectionSet(s.query, nil, op.selections, []any{})
+	raw, marshalErr := json.Marshal(data)
+	if marshalErr != nil {
+		logger.FromContext(ctx).Errorf("Failed to encode GraphQL result: %v", marshalErr)
+		return &Response{Data: json.RawMessage("null"), Errors: append(e.errors, &Error{Message: "Internal error."})}
+	}
+	return &Response{Data: raw, Errors: e.errors}
+}
+
+// selectOperation picks the operation a request runs: the one named, or the only one.
+func selectOperation(doc *document, name string) (*operation, *Error) {
+	if name == "" {
+		if len(doc.operations) > 1 {
+			return nil, &Error{Message: "Must provide operation name if query contains multiple operations."}
+		}
+		return doc.operations[0], nil
+	}
+	for _, op := range doc.operations {
+		if op.name == name {
+			return op, nil
+		}
+	}
+	return nil, &Error{Message: fmt.Sprintf("Unknown operation named %q.", name)}
+}
+
+// coerceVariables checks the variables of a request against the operation's definitions, applying
+// their defaults. Variables that are absent and have no default are absent from the result.
+func (s *Schema) coerceVariables(op *operation, given map[string]any) (map[string]any, []*Error) {
+	vars := map[string]any{}
+	var errs []*Error
+	for _, def := range op.vars {
+		t, err := s.inputType(def.typ)
+		if err != nil {
+			continue // Reported by validation
+		}
+		raw, ok := given[def.name]
+		if !ok {
+			if def.def != nil {
+				vars[def.name], _ = valueFromLiteral(def.def, t, nil)
+			} else if _, nonNull := t.(*NonNull); nonNull {
+				errs = append(errs, &Error{Message: fmt.Sprintf("Variable \"$%s\" of required type %q was not provided.", def.name, def.typ), Locations: []Location{def.loc}})
+			}
+			continue
+		}
+		v, err := coerceInput(raw, t)
+		if err != nil {
+			errs = append(errs, &Error{Message: fmt.Sprintf("Variable \"$%s\" got invalid value: %v.", def.name, err), Locations: []Location{def.loc}})
+			continue
+		}
+		vars[def.name] = v
+	}
+	return vars, errs
+}
+
+// coerceInput checks a variable's JSON value against type t, converting it as Scalar.Parse does.
+func coerceInput(v any, t Type) (any, error) {
+	if nn, ok := t.(*NonNull); ok {
+		if v == nil {
+			return nil, fmt.Errorf("expected non-nullable type %q not to be null", t)
+		}
+		return coerceInput(v, nn.Of)
+	}
+	if v == nil {
+		return nil, nil
+	}
+	if list, ok := t.(*List); ok {
+		items, ok := v.([]any)
+		if !ok {
+			item, err := coerceInput(v, list.Of)
+			if err != nil {
+				return nil, err
+			}
+			return []any{item}, nil
+		}
+		coerced := make([]any, len(items))
+		for i, item := range items {
+			var err error
+			if coerced[i], err = coerceInput(item, list.Of); err != nil {
+				return nil, fmt.Errorf("at index %d: %w", i, err)
+			}
+		}
+		return coerced, nil
+	}
+	switch n := v.(type) { // Variables decoded without json.Decoder.UseNumber
+	case float64:
+		v = json.Number(strconv.FormatFloat(n, 'f', -1, 64))
+	case int:
+		v = json.Number(strconv.Itoa(n))
+	}
+	return t.(*Scalar).Parse(v)
+}
+
+// literalInput returns a scalar value written in a query as it would arrive in JSON.
+func literalInput(val *value) (any, error) {
+	switch val.kind {
+	case intValue, floatValue:
+		return json.Number(val.raw), nil
+	case stringValue:
+		return val.raw, nil
+	case booleanValue:
+		return val.raw == "true", nil
+	case enumValue:
+		return nil, fmt.Errorf("enum values are not supported, found %s", val.raw)
+	}
+	return nil, fmt.Errorf("expected a scalar, found %s", printValue(val))
+}
+
+// valueFromLiteral returns the value of an argument written in a query for type t, with variables
+// replaced by their values. present is false for a variable with no value and no default.
+func valueFromLiteral(val *value, t Type, vars map[string]any) (v any, present bool) {
+	if val.kind == variableValue {
+		v, present = vars[val.raw]
+		return v, present
+	}
+	if val.kind == nullValue {
+		return nil, true
+	}
+	t = nullable(t)
+	if list, ok := t.(*List); ok {
+		if val.kind != listValue {
+			item, _ := valueFromLiteral(val, list.Of, vars)
+			return []any{item}, true
+		}
+		items := make([]any, len(val.list))
+		for i, item := range val.list {
+			items[i], _ = valueFromLiteral(item, list.Of, vars)
+		}
+		return items, true
+	}
+	raw, _ := literalInput(val)
+	v, _ = t.(*Scalar).Parse(raw) // Validated already
+	return v, true
+}
+
+// executor runs one validated operation, collecting the errors of fields that fail.
+type executor struct {
+	ctx    context.Context
+	schema *Schema
+	doc    *document
+	vars   map[string]any
+	errors []*Error
+}
+
+// result is an object in the response, which keeps its fields in the order they were selected.
+type result struct {
+	keys   []string
+	values map[string]any
+}
+
+func (r *result) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, key := range r.keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		k, _ := json.Marshal(key)
+		v, err := json.Marshal(r.values[key])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(k)
+		b.WriteByte(':')
+		b.Write(v)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+func (e *executor) fail(f *field, path []any, err error) {
+	gqlErr := &Error{Message: err.Error()}
+	var resolverErr *Error
+	if errors.As(err, &resolverErr) {
+		gqlErr.Message, gqlErr.Extensions = resolverErr.Message, resolverErr.Extensions
+	}
+	gqlErr.Locations = []Location{f.loc}
+	gqlErr.Path = append([]any(nil), path...)
+	e.errors = append(e.errors, gqlErr)
+}
+
+// selectionSet resolves the fields selected on obj from source. It returns failed, and no result,
+// if a non-null field could not be resolved, which makes the object null.
+func (e *executor) selectionSet(obj *Object, source any, selections []selection, path []any) (*result, bool) {
+	var keys []string
+	groups := map[string][]*field{}
+	e.collectFields(obj, selections, map[string]bool{}, groups, &keys)
+
+	r := &result{keys: keys, values: make(map[string]any, len(keys))}
+	for _, key := range keys {
+		fields := groups[key]
+		fieldPath := append(path[:len(path):len(path)], key)
+		if fields[0].name == "__typename" {
+			r.values[key] = obj.Name
+			continue
+		}
+		def := obj.field(fields[0].name)
+		v, failed := e.field(def, source, fields, fieldPath)
+		if failed {
+			if _, nonNull := def.Type.(*NonNull); nonNull {
+				return nil, true
+			}
+		}
+		r.values[key] = v
+	}
+	return r, false
+}
+
+// collectFields groups the fields of a selection set by response key, leaving out those that
+// @include and @skip exclude and fragments that do not apply to obj.
+func (e *executor) collectFields(obj *Object, selections []selection, visited map[string]bool, groups map[string][]*field, keys *[]string) {
+	for _, sel := range selections {
+		switch sel := sel.(type) {
+		case *field:
+			if !e.included(sel.directives) {
+				continue
+			}
+			key := sel.responseKey()
+			if _, ok := groups[key]; !ok {
+				*keys = append(*keys, key)
+			}
+			groups[key] = append(groups[key], sel)
+		case *inlineFragment:
+			if e.included(sel.directives) && (sel.typeCondition == "" || sel.typeCondition == obj.Name) {
+				e.collectFields(obj, sel.selections, visited, groups, keys)
+			}
+		case *fragmentSpread:
+			if visited[sel.name] || !e.included(sel.directives) {
+				continue
+			}
+			visited[sel.name] = true
+			if f := e.doc.fragments[sel.name]; f.typeCondition == obj.Name {
+				e.collectFields(obj, f.selections, visited, groups, keys)
+			}
+		}
+	}
+}
+
+// included applies @skip and @include.
+func (e *executor) included(directives []*directive) bool {
+	for _, d := range directives {
+		cond, _ := valueFromLiteral(d.args[0].value, conditionArgs[0].Type, e.vars)
+		if b, _ := cond.(bool); b == (d.name == "skip") {
+			return false
+		}
+	}
+	return true
+}
+
+// field resolves one field, merged from the fields selected under its response key, and completes
+// its value. failed means it is null because of an error already reported.
+func (e *executor) field(def *Field, source any, fields []*field, path []any) (v any, failed bool) {
+	f := fields[0]
+	args, err := e.arguments(def, f)
+	if err != nil {
+		e.fail(f, path, err)
+		return nil, true
+	}
+
+	resolved, err := func() (v any, err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				logger.Logger.Errorf("GraphQL resolver for %s panicked: %v", f.name, p)
+				v, err = nil, errors.New("Internal error.")
+			}
+		}()
+		return def.Resolve(e.ctx, source, args)
+	}()
+	if err != nil {
+		e.fail(f, path, err)
+		return nil, true
+	}
+
+	var selections []selection
+	for _, f := range fields {
+		selections = append(selections, f.selections...)
+	}
+	return e.complete(def.Type, f, selections, resolved, path)
+}
+
+// arguments coerces the arguments of a field, applying defaults and substituting variables.
+func (e *executor) arguments(def *Field, f *field) (map[string]any, error) {
+	args := map[string]any{}
+	for _, argDef := range def.Args {
+		var given *argument
+		for _, a := range f.args {
+			if a.name == argDef.Name {
+				given = a
+			}
+		}
+		v, present := any(nil), false
+		if given != nil {
+			v, present = valueFromLiteral(given.value, argDef.Type, e.vars)
+		}
+		if !present {
+			if argDef.Default == nil {
+				if _, nonNull := argDef.Type.(*NonNull); nonNull && given != nil {
+					return nil, fmt.Errorf("Argument %q of required type %q was provided the variable %s, which was not provided a runtime value.", argDef.Name, argDef.Type, printValue(given.value))
+				}
+				continue
+			}
+			v = argDef.Default
+		}
+		if _, nonNull := argDef.Type.(*NonNull); nonNull && v == nil {
+			return nil, fmt.Errorf("Argument %q of non-null type %q must not be null.", argDef.Name, argDef.Type)
+		}
+		args[argDef.Name] = v
+	}
+	return args, nil
+}
+
+// complete turns a resolved value into its result for type t. failed means it is null because of an
+// error already reported; a NonNull type that completes to null fails, reporting one if there was none.
+func (e *executor) complete(t Type, f *field, selections []selection, v any, path []any) (any, bool) {
+	if nn, ok := t.(*NonNull); ok {
+		completed, failed := e.complete(nn.Of, f, selections, v, path)
+		if completed == nil {
+			if !failed {
+				e.fail(f, path, fmt.Errorf("Cannot return null for non-nullable field %s.", f.name))
+			}
+			return nil, true
+		}
+		return completed, false
+	}
+
+	rv := reflect.ValueOf(v)
+	for rv.IsValid() && (rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface) {
+		if rv.IsNil() {
+			return nil, false
+		}
+		if _, isObject := t.(*Object); isObject {
+			break // Objects resolve from the pointer itself
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return nil, false
+	}
+
+	switch t := t.(type) {
+	case *List:
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			e.fail(f, path, fmt.Errorf("Expected a list for field %s, got %T.", f.name, v))
+			return nil, true
+		}
+		items := make([]any, rv.Len())
+		for i := range items {
+			item, failed := e.complete(t.Of, f, selections, rv.Index(i).Interface(), append(path[:len(path):len(path)], i))
+			if failed {
+				if _, nonNull := t.Of.(*NonNull); nonNull {
+					return nil, true
+				}
+			}
+			items[i] = item
+		}
+		return items, false
+	case *Scalar:
+		serialized, err := t.Serialize(rv.Interface())
+		if err != nil {
+			e.fail(f, path, err)
+			return nil, true
+		}
+		return serialized, false
+	case *Object:
+		r, failed := e.selectionSet(t, rv.Interface(), selections, path)
+		if failed {
+			return nil, true
+		}
+		return r, false
+	}
+	return nil, false
+}
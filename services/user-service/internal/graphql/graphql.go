@@ -0,0 +1,320 @@
+// services/user-service/internal/graphql/graphql.go
+
+// Package graphql executes GraphQL queries against a schema defined in Go. It implements the query
+// language as the frontends use it: operations, variables, aliases, named and inline fragments, and the
+// @include and @skip directives, with the validation and error handling of the GraphQL specification.
+// The schema is made of object types and scalars; interfaces, unions, enums, input objects, and
+// introspection beyond __typename are not supported, and neither are mutations or subscriptions.
+// Tools that need the schema get it in the schema definition language from Schema.SDL.
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+)
+
+// Type is the type of a field or argument: a *Scalar, an *Object, or a List or NonNull of one.
+// Arguments only take scalars, and lists of them.
+type Type interface {
+	String() string
+}
+
+// List is a list of Of.
+type List struct{ Of Type }
+
+// NonNull is Of, never null. A field of this type that resolves to null makes its parent null instead.
+type NonNull struct{ Of Type }
+
+func (t *List) String() string    { return "[" + t.Of.String() + "]" }
+func (t *NonNull) String() string { return t.Of.String() + "!" }
+
+// Scalar is a leaf type. Serialize turns a resolved Go value into its JSON value, and Parse turns an
+// argument or variable into the Go value resolvers receive: a string, json.Number, bool, or []any.
+type Scalar struct {
+	Name        string
+	Description string
+	Serialize   func(v any) (any, error)
+	Parse       func(v any) (any, error)
+}
+
+func (t *Scalar) String() string { return t.Name }
+
+// Object is a type with fields, resolved from a Go value of the parent field.
+type Object struct {
+	Name        string
+	Description string
+	Fields      []*Field
+}
+
+func (t *Object) String() string { return t.Name }
+
+func (t *Object) field(name string) *Field {
+	for _, f := range t.Fields {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// Field is a field of an Object. Resolve gets the value the object was resolved from (nil for Query)
+// and the field's arguments, coerced to their types with defaults applied; absent nullable arguments
+// without a default are absent from args.
+type Field struct {
+	Name        string
+	Description string
+	Args        []*Argument
+	Type        Type
+	Resolve     func(ctx context.Context, source any, args map[string]any) (any, error)
+}
+
+// Argument is an argument of a Field. Default, if set, is used when the argument is absent, and is a
+// value Parse of its type returns.
+type Argument struct {
+	Name        string
+	Description string
+	Type        Type
+	Default     any
+}
+
+// The built-in scalars.
+var (
+	String = &Scalar{
+		Name:        "String",
+		Description: "UTF-8 text.",
+		Serialize: func(v any) (any, error) {
+			switch v := v.(type) {
+			case string:
+				return v, nil
+			case fmt.Stringer:
+				return v.String(), nil
+			}
+			return nil, fmt.Errorf("String cannot represent %T", v)
+		},
+		Parse: func(v any) (any, error) {
+			if s, ok := v.(string); ok {
+				return s, nil
+			}
+			return nil, fmt.Errorf("String cannot represent a non-string value")
+		},
+	}
+	Int = &Scalar{
+		Name:        "Int",
+		Description: "A signed 32-bit integer.",
+		Serialize: func(v any) (any, error) {
+			var n int64
+			switch v := v.(type) {
+			case int:
+				n = int64(v)
+			case int32:
+				n = int64(v)
+			case int64:
+				n = v
+			default:
+				return nil, fmt.Errorf("Int cannot represent %T", v)
+			}
+			if n < math.MinInt32 || n > math.MaxInt32 {
+				return nil, fmt.Errorf("Int cannot represent non 32-bit signed integer value %d", n)
+			}
+			return n, nil
+		},
+		Parse: func(v any) (any, error) {
+			if num, ok := v.(json.Number); ok {
+				if n, err := strconv.ParseInt(string(num), 10, 32); err == nil {
+					return int(n), nil
+				}
+			}
+			return nil, fmt.Errorf("Int cannot represent a non 32-bit integer value")
+		},
+	}
+	Float = &Scalar{
+		Name:        "Float",
+		Description: "A double-precision floating point number.",
+		Serialize: func(v any) (any, error) {
+			switch v := v.(type) {
+			case float64:
+				if !math.IsInf(v, 0) && !math.IsNaN(v) {
+					return v, nil
+				}
+			case float32:
+				return float64(v), nil
+			case int:
+				return float64(v), nil
+			}
+			return nil, fmt.Errorf("Float cannot represent %v", v)
+		},
+		Parse: func(v any) (any, error) {
+			if num, ok := v.(json.Number); ok {
+				if f, err := num.Float64(); err == nil {
+					return f, nil
+				}
+			}
+			return nil, fmt.Errorf("Float cannot represent a non-numeric value")
+		},
+	}
+	Boolean = &Scalar{
+		Name:        "Boolean",
+		Description: "true or false.",
+		Serialize: func(v any) (any, error) {
+			if b, ok := v.(bool); ok {
+				return b, nil
+			}
+			return nil, fmt.Errorf("Boolean cannot represent %T", v)
+		},
+		Parse: func(v any) (any, error) {
+			if b, ok := v.(bool); ok {
+				return b, nil
+			}
+			return nil, fmt.Errorf("Boolean cannot represent a non-boolean value")
+		},
+	}
+	ID = &Scalar{
+		Name:        "ID",
+		Description: "A unique identifier, serialized as a string.",
+		Serialize:   String.Serialize,
+		Parse: func(v any) (any, error) {
+			switch v := v.(type) {
+			case string:
+				return v, nil
+			case json.Number:
+				if _, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+					return string(v), nil
+				}
+			}
+			return nil, fmt.Errorf("ID cannot represent a non-string and non-integer value")
+		},
+	}
+)
+
+// Error is a GraphQL error as it appears in a response. Resolvers may return one made with NewError
+// to give it a code; other errors are reported with their message.
+type Error struct {
+	Message    string         `json:"message"`
+	Locations  []Location     `json:"locations,omitempty"`
+	Path       []any          `json:"path,omitempty"`
+	Extensions map[string]any `json:"extensions,omitempty"`
+}
+
+func (e *Error) Error() string { return e.Message }
+
+// NewError returns an error for a resolver to return, reported with code in its extensions, such as
+// "FORBIDDEN", for clients to tell errors apart by.
+func NewError(code, message string) error {
+	return &Error{Message: message, Extensions: map[string]any{"code": code}}
+}
+
+// Request is a GraphQL request, as POSTed in JSON.
+type Request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+// Response is the result of a request. Data is absent if the request failed before it was executed,
+// and null if execution made the whole result null.
+type Response struct {
+	Data   json.RawMessage `json:"data,omitempty"`
+	Errors []*Error        `json:"errors,omitempty"`
+}
+
+// Executed reports whether the request got as far as being executed, even if fields failed.
+func (r *Response) Executed() bool {
+	return r.Data != nil
+}
+
+// Schema is a validated set of types, starting from Query. It is safe for concurrent use.
+type Schema struct {
+	query    *Object
+	objects  map[string]*Object
+	scalars  map[string]*Scalar
+	maxDepth int
+}
+
+var validName = regexp.MustCompile(`^[_A-Za-z][_0-9A-Za-z]*$`)
+
+// NewSchema checks the types reachable from query and returns a schema serving them. Queries nested
+// deeper than maxDepth fields are refused before they run.
+func NewSchema(query *Object, maxDepth int) (*Schema, error) {
+	s := &Schema{
+		query:    query,
+		objects:  map[string]*Object{},
+		scalars:  map[string]*Scalar{String.Name: String, Int.Name: Int, Float.Name: Float, Boolean.Name: Boolean, ID.Name: ID},
+		maxDepth: maxDepth,
+	}
+	if err := s.addType(query, false); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// addType adds t and the types of its fields to the schema.
+func (s *Schema) addType(t Type, input bool) error {
+	switch t := t.(type) {
+	case *List:
+		return s.addType(t.Of, input)
+	case *NonNull:
+		if _, ok := t.Of.(*NonNull); ok {
+			return fmt.Errorf("graphql: %s is non-null twice", t)
+		}
+		return s.addType(t.Of, input)
+	case *Scalar:
+		if !validName.MatchString(t.Name) || t.Serialize == nil || t.Parse == nil {
+			return fmt.Errorf("graphql: scalar %q needs a valid name, Serialize, and Parse", t.Name)
+		}
+		if existing, ok := s.scalars[t.Name]; ok && existing != t {
+			return fmt.Errorf("graphql: two types are named %s", t.Name)
+		}
+		if _, ok := s.objects[t.Name]; ok {
+			return fmt.Errorf("graphql: two types are named %s", t.Name)
+		}
+		s.scalars[t.Name] = t
+		return nil
+	case *Object:
+		if input {
+			return fmt.Errorf("graphql: object %s cannot be an argument", t.Name)
+		}
+		if existing, ok := s.objects[t.Name]; ok {
+			if existing != t {
+				return fmt.Errorf("graphql: two types are named %s", t.Name)
+			}
+			return nil
+		}
+		if !validName.MatchString(t.Name) || len(t.Fields) == 0 {
+			return fmt.Errorf("graphql: object %q needs a valid name and fields", t.Name)
+		}
+		s.objects[t.Name] = t
+		seen := map[string]bool{}
+		for _, f := range t.Fields {
+			if !validName.MatchString(f.Name) || f.Name[:min(2, len(f.Name))] == "__" || seen[f.Name] {
+				return fmt.Errorf("graphql: %s has an invalid or repeated field name %q", t.Name, f.Name)
+			}
+			seen[f.Name] = true
+			if f.Resolve == nil || f.Type == nil {
+				return fmt.Errorf("graphql: %s.%s needs a type and a resolver", t.Name, f.Name)
+			}
+			for _, a := range f.Args {
+				if !validName.MatchString(a.Name) || a.Type == nil {
+					return fmt.Errorf("graphql: %s.%s has an invalid argument %q", t.Name, f.Name, a.Name)
+				}
+				if err := s.addType(a.Type, true); err != nil {
+					return err
+				}
+			}
+			if err := s.addType(f.Type, false); err != nil {
+				return err
+			}
+		}
+		if _, ok := s.scalars[t.Name]; ok {
+			return fmt.Errorf("graphql: two types are named %s", t.Name)
+		}
+		return nil
+	case nil:
+		return errors.New("graphql: missing type")
+	}
+	return fmt.Errorf("graphql: unsupported type %T", t)
+}
@@ -0,0 +1,766 @@
+// services/user-service/internal/graphql/parse.go
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Location is a position in a query, 1-based, as reported with errors.
+type Location struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// document is a parsed query document: its operations and the fragments they may spread.
+type document struct {
+	operations    []*operation
+	fragments     map[string]*fragment
+	fragmentNames []string // In the order they were defined
+}
+
+type operation struct {
+	kind       string // query, mutation, or subscription
+	name       string
+	vars       []*variableDef
+	directives []*directive
+	selections []selection
+	loc        Location
+}
+
+type variableDef struct {
+	name string
+	typ  *typeRef
+	def  *value // nil without a default
+	loc  Location
+}
+
+// typeRef is a type written in a query: a named type, or a list of one, either of which may be non-null.
+type typeRef struct {
+	name    string
+	elem    *typeRef // Set for a list
+	nonNull bool
+}
+
+func (t *typeRef) String() string {
+	s := t.name
+	if t.elem != nil {
+		s = "[" + t.elem.String() + "]"
+	}
+	if t.nonNull {
+		s += "!"
+	}
+	return s
+}
+
+// selection is a *field, a *fragmentSpread, or an *inlineFragment.
+type selection interface{ location() Location }
+
+type field struct {
+	alias, name string
+	args        []*argument
+	directives  []*directive
+	selections  []selection
+	loc         Location
+}
+
+// responseKey is the name of the field in the response.
+func (f *field) responseKey() string {
+	if f.alias != "" {
+		return f.alias
+	}
+	return f.name
+}
+
+type fragmentSpread struct {
+	name       string
+	directives []*directive
+	loc        Location
+}
+
+type inlineFragment struct {
+	typeCondition string // "" applies to any type
+	directives    []*directive
+	selections    []selection
+	loc           Location
+}
+
+type fragment struct {
+	name          string
+	typeCondition string
+	directives    []*directive
+	selections    []selection
+	loc           Location
+}
+
+func (f *field) location() Location          { return f.loc }
+func (f *fragmentSpread) location() Location { return f.loc }
+func (f *inlineFragment) location() Location { return f.loc }
+
+type argument struct {
+	name  string
+	value *value
+	loc   Location
+}
+
+type directive struct {
+	name string
+	args []*argument
+	loc  Location
+}
+
+type valueKind int
+
+const (
+	variableValue valueKind = iota
+	intValue
+	floatValue
+	stringValue
+	booleanValue
+	nullValue
+	enumValue
+	listValue
+	objectValue
+)
+
+// value is a value written in a query. raw holds the variable name, the number as written, the
+// decoded string, or the enum or boolean name.
+type value struct {
+	kind   valueKind
+	raw    string
+	list   []*value
+	fields []*argument // Of an object value
+	loc    Location
+}
+
+// tokenKind is the kind of a lexical token. Punctuators are their own text.
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokPunct
+	tokName
+	tokInt
+	tokFloat
+	tokString
+)
+
+type token struct {
+	kind tokenKind
+	text string // The punctuator, name, number as written, or decoded string
+	loc  Location
+}
+
+// lexer splits a query into tokens, skipping whitespace, commas, and comments.
+type lexer struct {
+	src       string
+	pos       int
+	line, col int
+}
+
+func (l *lexer) errorf(loc Location, format string, args ...any) *Error {
+	return &Error{Message: "Syntax Error: " + fmt.Sprintf(format, args...), Locations: []Location{loc}}
+}
+
+// advance moves past n bytes of the current line.
+func (l *lexer) advance(n int) {
+	l.pos += n
+	l.col += n
+}
+
+func (l *lexer) next() (token, *Error) {
+	for l.pos < len(l.src) {
+		switch c := l.src[l.pos]; {
+		case c == '\n':
+			l.pos, l.line, l.col = l.pos+1, l.line+1, 1
+		case c == '\r':
+			l.pos, l.line, l.col = l.pos+1, l.line+1, 1
+			if l.pos < len(l.src) && l.src[l.pos] == '\n' {
+				l.pos++
+			}
+		case c == ' ' || c == '\t' || c == ',':
+			l.advance(1)
+		case c == '#':
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' && l.src[l.pos] != '\r' {
+				l.advance(1)
+			}
+		case strings.HasPrefix(l.src[l.pos:], "\uFEFF"):
+			l.advance(len("\uFEFF"))
+		default:
+			return l.read()
+		}
+	}
+	return token{kind: tokEOF, loc: Location{l.line, l.col}}, nil
+}
+
+func (l *lexer) read() (token, *Error) {
+	loc := Location{l.line, l.col}
+	c := l.src[l.pos]
+	switch {
+	case strings.HasPrefix(l.src[l.pos:], "..."):
+		l.advance(3)
+		return token{kind: tokPunct, text: "...", loc: loc}, nil
+	case strings.IndexByte("!$&():=@[]{|}", c) >= 0:
+		l.advance(1)
+		return token{kind: tokPunct, text: string(c), loc: loc}, nil
+	case c == '_' || isLetter(c):
+		start := l.pos
+		for l.pos < len(l.src) && (l.src[l.pos] == '_' || isLetter(l.src[l.pos]) || isDigit(l.src[l.pos])) {
+			l.advance(1)
+		}
+		return token{kind: tokName, text: l.src[start:l.pos], loc: loc}, nil
+	case c == '-' || isDigit(c):
+		return l.readNumber(loc)
+	case c == '"':
+		if strings.HasPrefix(l.src[l.pos:], `"""`) {
+			return l.readBlockString(loc)
+		}
+		return l.readString(loc)
+	}
+	r, _ := utf8.DecodeRuneInString(l.src[l.pos:])
+	return token{}, l.errorf(loc, "unexpected character %q", r)
+}
+
+func (l *lexer) readNumber(loc Location) (token, *Error) {
+	start, kind := l.pos, tokInt
+	digits := func() int {
+		n := 0
+		for l.pos < len(l.src) && isDigit(l.src[l.pos]) {
+			l.advance(1)
+			n++
+		}
+		return n
+	}
+	if l.src[l.pos] == '-' {
+		l.advance(1)
+	}
+	intStart := l.pos
+	if digits() == 0 {
+		return token{}, l.errorf(loc, "invalid number")
+	}
+	if l.src[intStart] == '0' && l.pos-intStart > 1 {
+		return token{}, l.errorf(loc, "invalid number, unexpected digit after 0")
+	}
+	if l.pos < len(l.src) && l.src[l.pos] == '.' {
+		kind = tokFloat
+		l.advance(1)
+		if digits() == 0 {
+			return token{}, l.errorf(loc, "invalid number, expected digit after '.'")
+		}
+	}
+	if l.pos < len(l.src) && (l.src[l.pos] == 'e' || l.src[l.pos] == 'E') {
+		kind = tokFloat
+		l.advance(1)
+		if l.pos < len(l.src) && (l.src[l.pos] == '+' || l.src[l.pos] == '-') {
+			l.advance(1)
+		}
+		if digits() == 0 {
+			return token{}, l.errorf(loc, "invalid number, expected digit in exponent")
+		}
+	}
+	if l.pos < len(l.src) && (l.src[l.pos] == '_' || l.src[l.pos] == '.' || isLetter(l.src[l.pos])) {
+		return token{}, l.errorf(loc, "invalid number, unexpected %q", l.src[l.pos])
+	}
+	return token{kind: kind, text: l.src[start:l.pos], loc: loc}, nil
+}
+
+func (l *lexer) readString(loc Location) (token, *Error) {
+	l.advance(1)
+	var b strings.Builder
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == '"':
+			l.advance(1)
+			return token{kind: tokString, text: b.String(), loc: loc}, nil
+		case c == '\n' || c == '\r':
+			return token{}, l.errorf(loc, "unterminated string")
+		case c == '\\':
+			if l.pos+1 >= len(l.src) {
+				return token{}, l.errorf(loc, "unterminated string")
+			}
+			esc := l.src[l.pos+1]
+			if esc == 'u' {
+				if l.pos+6 > len(l.src) {
+					return token{}, l.errorf(loc, "invalid unicode escape")
+				}
+				r, err := strconv.ParseUint(l.src[l.pos+2:l.pos+6], 16, 32)
+				if err != nil {
+					return token{}, l.errorf(loc, "invalid unicode escape")
+				}
+				b.WriteRune(rune(r))
+				l.advance(6)
+				continue
+			}
+			unescaped, ok := map[byte]byte{'"': '"', '\\': '\\', '/': '/', 'b': '\b', 'f': '\f', 'n': '\n', 'r': '\r', 't': '\t'}[esc]
+			if !ok {
+				return token{}, l.errorf(loc, "invalid escape \\%c", esc)
+			}
+			b.WriteByte(unescaped)
+			l.advance(2)
+		default:
+			r, size := utf8.DecodeRuneInString(l.src[l.pos:])
+			b.WriteRune(r)
+			l.advance(size)
+		}
+	}
+	return token{}, l.errorf(loc, "unterminated string")
+}
+
+// readBlockString reads a """block string""", removing its common indentation and its leading and
+// trailing blank lines.
+func (l *lexer) readBlockString(loc Location) (token, *Error) {
+	l.advance(3)
+	var raw strings.Builder
+	for l.pos < len(l.src) {
+		switch {
+		case strings.HasPrefix(l.src[l.pos:], `"""`):
+			l.advance(3)
+			return token{kind: tokString, text: blockStringValue(raw.String()), loc: loc}, nil
+		case strings.HasPrefix(l.src[l.pos:], `\"""`):
+			raw.WriteString(`"""`)
+			l.advance(4)
+		case l.src[l.pos] == '\n':
+			raw.WriteByte('\n')
+			l.pos, l.line, l.col = l.pos+1, l.line+1, 1
+		default:
+			raw.WriteByte(l.src[l.pos])
+			l.advance(1)
+		}
+	}
+	return token{}, l.errorf(loc, "unterminated block string")
+}
+
+func blockStringValue(raw string) string {
+	lines := strings.Split(strings.ReplaceAll(raw, "\r\n", "\n"), "\n")
+	indent := -1
+	for _, line := range lines[1:] {
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed != "" && (indent < 0 || len(line)-len(trimmed) < indent) {
+			indent = len(line) - len(trimmed)
+		}
+	}
+	for i := 1; i < len(lines) && indent > 0; i++ {
+		if len(lines[i]) >= indent {
+			lines[i] = lines[i][indent:]
+		} else {
+			lines[i] = ""
+		}
+	}
+	for len(lines) > 0 && strings.TrimLeft(lines[0], " \t") == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimLeft(lines[len(lines)-1], " \t") == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+func isLetter(c byte) bool { return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' }
+func isDigit(c byte) bool  { return c >= '0' && c <= '9' }
+
+// parser builds a document from the tokens of a query, one token of lookahead at a time.
+type parser struct {
+	lex *lexer
+	tok token
+}
+
+// parse parses a query document. Only executable definitions, operations and fragments, are accepted.
+func parse(query string) (*document, *Error) {
+	p := &parser{lex: &lexer{src: query, line: 1, col: 1}}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	doc := &document{fragments: map[string]*fragment{}}
+	for p.tok.kind != tokEOF {
+		switch {
+		case p.peek("{") || p.peekName("query", "mutation", "subscription"):
+			op, err := p.parseOperation()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, op)
+		case p.peekName("fragment"):
+			f, err := p.parseFragment()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := doc.fragments[f.name]; ok {
+				return nil, &Error{Message: fmt.Sprintf("There can be only one fragment named %q.", f.name), Locations: []Location{f.loc}}
+			}
+			doc.fragments[f.name] = f
+			doc.fragmentNames = append(doc.fragmentNames, f.name)
+		default:
+			return nil, p.unexpected()
+		}
+	}
+	if len(doc.operations) == 0 {
+		return nil, &Error{Message: "The document holds no operation."}
+	}
+	return doc, nil
+}
+
+func (p *parser) advance() *Error {
+	tok, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+func (p *parser) peek(punct string) bool {
+	return p.tok.kind == tokPunct && p.tok.text == punct
+}
+
+func (p *parser) peekName(names ...string) bool {
+	if p.tok.kind != tokName {
+		return false
+	}
+	for _, name := range names {
+		if p.tok.text == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) unexpected() *Error {
+	if p.tok.kind == tokEOF {
+		return p.lex.errorf(p.tok.loc, "unexpected end of query")
+	}
+	return p.lex.errorf(p.tok.loc, "unexpected %q", p.tok.text)
+}
+
+// skip consumes punct if it is next, and reports whether it was.
+func (p *parser) skip(punct string) (bool, *Error) {
+	if !p.peek(punct) {
+		return false, nil
+	}
+	return true, p.advance()
+}
+
+func (p *parser) expect(punct string) *Error {
+	if !p.peek(punct) {
+		if p.tok.kind == tokEOF {
+			return p.lex.errorf(p.tok.loc, "expected %q, found end of query", punct)
+		}
+		return p.lex.errorf(p.tok.loc, "expected %q, found %q", punct, p.tok.text)
+	}
+	return p.advance()
+}
+
+func (p *parser) name() (string, *Error) {
+	if p.tok.kind != tokName {
+		return "", p.unexpected()
+	}
+	name := p.tok.text
+	return name, p.advance()
+}
+
+func (p *parser) parseOperation() (*operation, *Error) {
+	op := &operation{kind: "query", loc: p.tok.loc}
+	if p.tok.kind == tokName {
+		op.kind = p.tok.text
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		if p.tok.kind == tokName {
+			op.name = p.tok.text
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+		}
+		if ok, err := p.skip("("); err != nil {
+			return nil, err
+		} else if ok {
+			for !p.peek(")") {
+				v, err := p.parseVariableDef()
+				if err != nil {
+					return nil, err
+				}
+				op.vars = append(op.vars, v)
+			}
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+		}
+		var err *Error
+		if op.directives, err = p.parseDirectives(false); err != nil {
+			return nil, err
+		}
+	}
+	var err *Error
+	op.selections, err = p.parseSelectionSet()
+	return op, err
+}
+
+func (p *parser) parseVariableDef() (*variableDef, *Error) {
+	v := &variableDef{loc: p.tok.loc}
+	if err := p.expect("$"); err != nil {
+		return nil, err
+	}
+	var err *Error
+	if v.name, err = p.name(); err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	if v.typ, err = p.parseType(); err != nil {
+		return nil, err
+	}
+	if ok, err := p.skip("="); err != nil {
+		return nil, err
+	} else if ok {
+		if v.def, err = p.parseValue(true); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := p.parseDirectives(true); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func (p *parser) parseType() (*typeRef, *Error) {
+	t := &typeRef{}
+	if ok, err := p.skip("["); err != nil {
+		return nil, err
+	} else if ok {
+		if t.elem, err = p.parseType(); err != nil {
+			return nil, err
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+	} else {
+		var err *Error
+		if t.name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	var err *Error
+	t.nonNull, err = p.skip("!")
+	return t, err
+}
+
+func (p *parser) parseDirectives(isConst bool) ([]*directive, *Error) {
+	var directives []*directive
+	for p.peek("@") {
+		d := &directive{loc: p.tok.loc}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		var err *Error
+		if d.name, err = p.name(); err != nil {
+			return nil, err
+		}
+		if d.args, err = p.parseArguments(isConst); err != nil {
+			return nil, err
+		}
+		directives = append(directives, d)
+	}
+	return directives, nil
+}
+
+func (p *parser) parseArguments(isConst bool) ([]*argument, *Error) {
+	ok, err := p.skip("(")
+	if err != nil || !ok {
+		return nil, err
+	}
+	var args []*argument
+	for !p.peek(")") {
+		a := &argument{loc: p.tok.loc}
+		if a.name, err = p.name(); err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		if a.value, err = p.parseValue(isConst); err != nil {
+			return nil, err
+		}
+		args = append(args, a)
+	}
+	if len(args) == 0 {
+		return nil, p.unexpected()
+	}
+	return args, p.advance()
+}
+
+func (p *parser) parseSelectionSet() ([]selection, *Error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var selections []selection
+	for !p.peek("}") {
+		s, err := p.parseSelection()
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, s)
+	}
+	if len(selections) == 0 {
+		return nil, p.unexpected()
+	}
+	return selections, p.advance()
+}
+
+func (p *parser) parseSelection() (selection, *Error) {
+	loc := p.tok.loc
+	if ok, err := p.skip("..."); err != nil {
+		return nil, err
+	} else if ok {
+		if p.tok.kind == tokName && p.tok.text != "on" {
+			spread := &fragmentSpread{name: p.tok.text, loc: loc}
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			spread.directives, err = p.parseDirectives(false)
+			return spread, err
+		}
+		inline := &inlineFragment{loc: loc}
+		if p.peekName("on") {
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			if inline.typeCondition, err = p.name(); err != nil {
+				return nil, err
+			}
+		}
+		if inline.directives, err = p.parseDirectives(false); err != nil {
+			return nil, err
+		}
+		inline.selections, err = p.parseSelectionSet()
+		return inline, err
+	}
+
+	f := &field{loc: loc}
+	var err *Error
+	if f.name, err = p.name(); err != nil {
+		return nil, err
+	}
+	if ok, err := p.skip(":"); err != nil {
+		return nil, err
+	} else if ok {
+		f.alias = f.name
+		if f.name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	if f.args, err = p.parseArguments(false); err != nil {
+		return nil, err
+	}
+	if f.directives, err = p.parseDirectives(false); err != nil {
+		return nil, err
+	}
+	if p.peek("{") {
+		f.selections, err = p.parseSelectionSet()
+	}
+	return f, err
+}
+
+func (p *parser) parseFragment() (*fragment, *Error) {
+	f := &fragment{loc: p.tok.loc}
+	if err := p.advance(); err != nil { // fragment
+		return nil, err
+	}
+	var err *Error
+	if f.name, err = p.name(); err != nil {
+		return nil, err
+	}
+	if f.name == "on" {
+		return nil, p.lex.errorf(f.loc, "a fragment cannot be named \"on\"")
+	}
+	if !p.peekName("on") {
+		return nil, p.unexpected()
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	if f.typeCondition, err = p.name(); err != nil {
+		return nil, err
+	}
+	if f.directives, err = p.parseDirectives(false); err != nil {
+		return nil, err
+	}
+	f.selections, err = p.parseSelectionSet()
+	return f, err
+}
+
+// parseValue parses a value; in a const context, such as a variable default, variables are not allowed.
+func (p *parser) parseValue(isConst bool) (*value, *Error) {
+	v := &value{loc: p.tok.loc, raw: p.tok.text}
+	switch p.tok.kind {
+	case tokInt:
+		v.kind = intValue
+	case tokFloat:
+		v.kind = floatValue
+	case tokString:
+		v.kind = stringValue
+	case tokName:
+		switch p.tok.text {
+		case "true", "false":
+			v.kind = booleanValue
+		case "null":
+			v.kind = nullValue
+		default:
+			v.kind = enumValue
+		}
+	case tokPunct:
+		switch p.tok.text {
+		case "$":
+			if isConst {
+				return nil, p.unexpected()
+			}
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			var err *Error
+			v.kind = variableValue
+			v.raw, err = p.name()
+			return v, err
+		case "[":
+			v.kind = listValue
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			for !p.peek("]") {
+				item, err := p.parseValue(isConst)
+				if err != nil {
+					return nil, err
+				}
+				v.list = append(v.list, item)
+			}
+			return v, p.advance()
+		case "{":
+			v.kind = objectValue
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			for !p.peek("}") {
+				f := &argument{loc: p.tok.loc}
+				var err *Error
+				if f.name, err = p.name(); err != nil {
+					return nil, err
+				}
+				if err := p.expect(":"); err != nil {
+					return nil, err
+				}
+				if f.value, err = p.parseValue(isConst); err != nil {
+					return nil, err
+				}
+				v.fields = append(v.fields, f)
+			}
+			return v, p.advance()
+		default:
+			return nil, p.unexpected()
+		}
+	default:
+		return nil, p.unexpected()
+	}
+	return v, p.advance()
+}
@@ -0,0 +1,235 @@
+// services/user-service/internal/graphql/parse_test.go
+package graphql
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// printDocument writes a parsed document back as a query on one line, so tests compare what the parser
+// understood rather than its structs. Operations of all kinds print their kind, even a shorthand query.
+func printDocument(doc *document) string {
+	var parts []string
+	for _, op := range doc.operations {
+		s := op.kind
+		if op.name != "" {
+			s += " " + op.name
+		}
+		if len(op.vars) > 0 {
+			vars := make([]string, len(op.vars))
+			for i, v := range op.vars {
+				vars[i] = "$" + v.name + ": " + v.typ.String()
+				if v.def != nil {
+					vars[i] += " = " + printValue(v.def)
+				}
+			}
+			s += "(" + strings.Join(vars, ", ") + ")"
+		}
+		parts = append(parts, s+printDirectives(op.directives)+" "+printSelections(op.selections))
+	}
+	for _, name := range doc.fragmentNames {
+		f := doc.fragments[name]
+		parts = append(parts, "fragment "+f.name+" on "+f.typeCondition+printDirectives(f.directives)+" "+printSelections(f.selections))
+	}
+	return strings.Join(parts, " ")
+}
+
+func printSelections(selections []selection) string {
+	printed := make([]string, len(selections))
+	for i, s := range selections {
+		switch s := s.(type) {
+		case *field:
+			printed[i] = s.name
+			if s.alias != "" {
+				printed[i] = s.alias + ": " + s.name
+			}
+			printed[i] += printArgs(s.args) + printDirectives(s.directives)
+			if s.selections != nil {
+				printed[i] += " " + printSelections(s.selections)
+			}
+		case *fragmentSpread:
+			printed[i] = "..." + s.name + printDirectives(s.directives)
+		case *inlineFragment:
+			printed[i] = "..."
+			if s.typeCondition != "" {
+				printed[i] += " on " + s.typeCondition
+			}
+			printed[i] += printDirectives(s.directives) + " " + printSelections(s.selections)
+		}
+	}
+	return "{ " + strings.Join(printed, " ") + " }"
+}
+
+func printArgs(args []*argument) string {
+	if len(args) == 0 {
+		return ""
+	}
+	printed := make([]string, len(args))
+	for i, a := range args {
+		printed[i] = a.name + ": " + printValue(a.value)
+	}
+	return "(" + strings.Join(printed, ", ") + ")"
+}
+
+func printDirectives(directives []*directive) string {
+	var s string
+	for _, d := range directives {
+		s += " @" + d.name + printArgs(d.args)
+	}
+	return s
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"shorthand query", `{ me { id } }`, `query { me { id } }`},
+		{"named query", `query Profile { me { id name } }`, `query Profile { me { id name } }`},
+		{"mutation", `mutation { logWorkout(type: RUN) { id } }`, `mutation { logWorkout(type: RUN) { id } }`},
+		{"commas and comments are ignored", "{\n  me { # who\n    id,, name\n  }\n}", `query { me { id name } }`},
+		{"byte order mark", "\uFEFF{ me { id } }", `query { me { id } }`},
+		{"alias", `{ self: me { id } }`, `query { self: me { id } }`},
+		{"variables", `query Q($id: ID!, $ids: [ID!]!, $n: Int = 10, $tags: [[String]]) { user(id: $id) { id } }`,
+			`query Q($id: ID!, $ids: [ID!]!, $n: Int = 10, $tags: [[String]]) { user(id: $id) { id } }`},
+		{"argument values", `{ f(i: -12, f: 1.5e3, s: "x", b: true, n: null, e: ASC, l: [1, [2]], o: {a: 1, b: {c: $v}}) }`,
+			`query { f(i: -12, f: 1.5e3, s: "x", b: true, n: null, e: ASC, l: [1, [2]], o: {a: 1, b: {c: $v}}) }`},
+		{"empty list and object", `{ f(l: [], o: {}) }`, `query { f(l: [], o: {}) }`},
+		{"directives", `query Q($on: Boolean!) @live { me @include(if: $on) { id @skip(if: false) } }`,
+			`query Q($on: Boolean!) @live { me @include(if: $on) { id @skip(if: false) } }`},
+		{"fragments", `{ me { ...F ... on User { email } ... @include(if: true) { id } } } fragment F on User @x { name }`,
+			`query { me { ...F ... on User { email } ... @include(if: true) { id } } } fragment F on User @x { name }`},
+		{"fragment before operation", `fragment F on User { id } { me { ...F } }`, `query { me { ...F } } fragment F on User { id }`},
+		{"keywords as names", `{ query fragment: on mutation(null: null) { true } }`, `query { query fragment: on mutation(null: null) { true } }`},
+		{"several operations", `query A { a } query B { b }`, `query A { a } query B { b }`},
+		{"string escapes", `{ f(s: "a\"b\\c\/d\n\té☃") }`, `query { f(s: "a\"b\\c/d\n\té☃") }`},
+		{"unicode in string", `{ f(s: "Zoë ☃") }`, `query { f(s: "Zoë ☃") }`},
+		{"block string", "{ f(s: \"\"\"\n    first\n      indented\n\n    last \\\"\"\" quote\n  \"\"\") }",
+			`query { f(s: "first\n  indented\n\nlast \"\"\" quote") }`},
+		{"block string keeps escapes", `{ f(s: """a\nb""") }`, `query { f(s: "a\\nb") }`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parse(tt.query)
+			if err != nil {
+				t.Fatalf("parse() = %v at %v", err, err.Locations)
+			}
+			if got := printDocument(doc); got != tt.want {
+				t.Errorf("parse() = %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseValueKinds(t *testing.T) {
+	doc, err := parse(`{ f(a: 1, b: 1.0, c: 1e2, d: "1", e: true, f: null, g: ONE, h: $v, i: [], j: {}) }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []valueKind{intValue, floatValue, floatValue, stringValue, booleanValue, nullValue, enumValue, variableValue, listValue, objectValue}
+	var got []valueKind
+	for _, a := range doc.operations[0].selections[0].(*field).args {
+		got = append(got, a.value.kind)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("value kinds = %v, want %v", got, want)
+	}
+}
+
+func TestParseLocations(t *testing.T) {
+	doc, err := parse("query {\n  me {\r\n\tid\r  }\n}\nfragment F on User { name }")
+	if err != nil {
+		t.Fatal(err)
+	}
+	me := doc.operations[0].selections[0].(*field)
+	id := me.selections[0].(*field)
+	got := []Location{doc.operations[0].loc, me.loc, id.loc, doc.fragments["F"].loc}
+	want := []Location{{1, 1}, {2, 3}, {3, 2}, {6, 1}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("locations = %v, want %v", got, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+		loc   Location
+	}{
+		{"empty", ``, "The document holds no operation.", Location{}},
+		{"only fragments", `fragment F on User { id }`, "The document holds no operation.", Location{}},
+		{"type definition", `type User { id: ID }`, `Syntax Error: unexpected "type"`, Location{1, 1}},
+		{"unclosed selection set", `{ me { id }`, "Syntax Error: unexpected end of query", Location{1, 12}},
+		{"empty selection set", `{ me { } }`, `Syntax Error: unexpected "}"`, Location{1, 8}},
+		{"empty arguments", `{ me() }`, `Syntax Error: unexpected ")"`, Location{1, 6}},
+		{"missing colon", `{ f(a 1) }`, `Syntax Error: expected ":", found "1"`, Location{1, 7}},
+		{"missing argument value", `{ f(a: ) }`, `Syntax Error: unexpected ")"`, Location{1, 8}},
+		{"unexpected character", `{ me ? }`, `Syntax Error: unexpected character '?'`, Location{1, 6}},
+		{"unexpected unicode character", "{ me é }", `Syntax Error: unexpected character 'é'`, Location{1, 6}},
+		{"variable in default", `query ($a: Int = $b) { f }`, `Syntax Error: unexpected "$"`, Location{1, 18}},
+		{"variable in variable directive", `query ($a: Int @d(if: $b)) { f }`, `Syntax Error: unexpected "$"`, Location{1, 23}},
+		{"variable without a type", `query ($a) { f }`, `Syntax Error: expected ":", found ")"`, Location{1, 10}},
+		{"unclosed list type", `query ($a: [Int) { f }`, `Syntax Error: expected "]", found ")"`, Location{1, 16}},
+		{"fragment named on", `fragment on on User { id } { f }`, `Syntax Error: a fragment cannot be named "on"`, Location{1, 1}},
+		{"fragment without type condition", `fragment F { id } { f }`, `Syntax Error: unexpected "{"`, Location{1, 12}},
+		{"duplicate fragment", `{ f } fragment F on A { a } fragment F on B { b }`, `There can be only one fragment named "F".`, Location{1, 29}},
+		{"leading zero", `{ f(a: 012) }`, "Syntax Error: invalid number, unexpected digit after 0", Location{1, 8}},
+		{"minus alone", `{ f(a: -) }`, "Syntax Error: invalid number", Location{1, 8}},
+		{"no digit after point", `{ f(a: 1.) }`, "Syntax Error: invalid number, expected digit after '.'", Location{1, 8}},
+		{"no digit in exponent", `{ f(a: 1e+) }`, "Syntax Error: invalid number, expected digit in exponent", Location{1, 8}},
+		{"name after number", `{ f(a: 1x) }`, "Syntax Error: invalid number, unexpected 'x'", Location{1, 8}},
+		{"unterminated string", `{ f(a: "abc) }`, "Syntax Error: unterminated string", Location{1, 8}},
+		{"newline in string", "{ f(a: \"a\nb\") }", "Syntax Error: unterminated string", Location{1, 8}},
+		{"bad escape", `{ f(a: "\x") }`, `Syntax Error: invalid escape \x`, Location{1, 8}},
+		{"bad unicode escape", `{ f(a: "\u12G4") }`, "Syntax Error: invalid unicode escape", Location{1, 8}},
+		{"short unicode escape", `{ f(a: "\u12`, "Syntax Error: invalid unicode escape", Location{1, 8}},
+		{"unterminated block string", `{ f(a: """abc") }`, "Syntax Error: unterminated block string", Location{1, 8}},
+		{"error on a later line", "{\n  me {\n    id(\n  }\n}", `Syntax Error: unexpected "}"`, Location{4, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parse(tt.query)
+			if err == nil {
+				t.Fatalf("parse() = %s, want an error", printDocument(doc))
+			}
+			if err.Message != tt.want {
+				t.Errorf("parse() error = %q, want %q", err.Message, tt.want)
+			}
+			var loc Location
+			if len(err.Locations) > 0 {
+				loc = err.Locations[0]
+			}
+			if loc != tt.loc {
+				t.Errorf("parse() error at %v, want %v", loc, tt.loc)
+			}
+		})
+	}
+}
+
+func TestBlockStringValue(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"single line", "single line"},
+		{"  first line keeps its indent", "  first line keeps its indent"},
+		{"\n    a\n      b\n    c\n", "a\n  b\nc"},
+		{"\n\n  a\n\n  b\n  \n\n", "a\n\nb"},
+		{"\r\n  a\r\n  b", "a\nb"},
+		{"\n\ta\n\t\tb", "a\n\tb"},
+		{"\n   a\n b", "  a\nb"},
+		{"", ""},
+		{"\n   \n", ""},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%q", tt.raw), func(t *testing.T) {
+			if got := blockStringValue(tt.raw); got != tt.want {
+				t.Errorf("blockStringValue(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
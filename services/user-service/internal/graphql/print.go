@@ -0,0 +1,97 @@
+// services/user-service/internal/graphql/print.go
+package graphql
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SDL returns the schema in the GraphQL schema definition language, for clients that generate code or
+// check their queries against it: its custom scalars, then Query, then the other types by name.
+func (s *Schema) SDL() string {
+	var b strings.Builder
+	var scalars []string
+	for name := range s.scalars {
+		switch name {
+		case String.Name, Int.Name, Float.Name, Boolean.Name, ID.Name:
+		default:
+			scalars = append(scalars, name)
+		}
+	}
+	sort.Strings(scalars)
+	for _, name := range scalars {
+		t := s.scalars[name]
+		writeDescription(&b, t.Description, "")
+		fmt.Fprintf(&b, "scalar %s\n\n", t.Name)
+	}
+
+	objects := []*Object{s.query}
+	var names []string
+	for name := range s.objects {
+		if name != s.query.Name {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		objects = append(objects, s.objects[name])
+	}
+	for i, obj := range objects {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		writeDescription(&b, obj.Description, "")
+		fmt.Fprintf(&b, "type %s {\n", obj.Name)
+		for _, f := range obj.Fields {
+			writeDescription(&b, f.Description, "  ")
+			fmt.Fprintf(&b, "  %s%s: %s\n", f.Name, printArguments(f.Args), f.Type)
+		}
+		b.WriteString("}\n")
+	}
+	return b.String()
+}
+
+func printArguments(args []*Argument) string {
+	if len(args) == 0 {
+		return ""
+	}
+	printed := make([]string, len(args))
+	for i, a := range args {
+		printed[i] = a.Name + ": " + a.Type.String()
+		if a.Default != nil {
+			printed[i] += " = " + printDefault(a.Default)
+		}
+	}
+	return "(" + strings.Join(printed, ", ") + ")"
+}
+
+// printDefault writes an argument default, a value Scalar.Parse returned, as a literal.
+func printDefault(v any) string {
+	switch v := v.(type) {
+	case string:
+		return fmt.Sprintf("%q", v)
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = printDefault(item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	}
+	return fmt.Sprint(v)
+}
+
+func writeDescription(b *strings.Builder, description, indent string) {
+	if description == "" {
+		return
+	}
+	if !strings.Contains(description, "\n") && !strings.Contains(description, `"`) {
+		fmt.Fprintf(b, "%s\"%s\"\n", indent, description)
+		return
+	}
+	fmt.Fprintf(b, "%s\"\"\"\n", indent)
+	for _, line := range strings.Split(strings.ReplaceAll(description, `"""`, `\"""`), "\n") {
+		fmt.Fprintf(b, "%s%s\n", indent, line)
+	}
+	fmt.Fprintf(b, "%s\"\"\"\n", indent)
+}
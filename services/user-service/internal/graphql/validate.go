@@ -0,0 +1,613 @@
+// services/user-service/internal/graphql/validate.go
+package graphql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// validator checks a document against the schema before any of it runs, so a request either fails
+// as a whole with the reasons or is executed.
+type validator struct {
+	schema *Schema
+	doc    *document
+	errors []*Error
+	seen   map[string]bool // Errors reported, by message and location, so overlapping checks report each once
+}
+
+// variableUsage is a variable used as an argument, with the type the argument expects.
+type variableUsage struct {
+	name       string
+	typ        Type
+	hasDefault bool // The argument has a default of its own
+	loc        Location
+}
+
+// The arguments of @include and @skip.
+var conditionArgs = []*Argument{{Name: "if", Type: &NonNull{Boolean}}}
+
+func (s *Schema) validate(doc *document) []*Error {
+	v := &validator{schema: s, doc: doc, seen: map[string]bool{}}
+	names := map[string]bool{}
+	for _, op := range doc.operations {
+		if op.name == "" && len(doc.operations) > 1 {
+			v.report(op.loc, "This anonymous operation must be the only defined operation.")
+		}
+		if op.name != "" && names[op.name] {
+			v.report(op.loc, "There can be only one operation named %q.", op.name)
+		}
+		names[op.name] = true
+	}
+
+	for _, name := range doc.fragmentNames {
+		f := doc.fragments[name]
+		v.directivesAllowed(f.directives, "FRAGMENT_DEFINITION")
+		if obj := v.conditionType(f.typeCondition, f.loc); obj != nil {
+			v.selections(obj, f.selections)
+		}
+	}
+	cyclic := v.fragmentCycles()
+
+	used := map[string]bool{}
+	for _, op := range doc.operations {
+		if op.kind != "query" {
+			v.report(op.loc, "Only queries are supported; %s operations are not.", op.kind)
+			continue
+		}
+		v.directivesAllowed(op.directives, "QUERY")
+		v.selections(s.query, op.selections)
+		if cyclic {
+			continue // Walking through the fragments would not end
+		}
+		v.variables(op)
+		markSpreads(doc, op.selections, used)
+		if s.maxDepth > 0 {
+			if depth := v.depth(op.selections); depth > s.maxDepth {
+				v.report(op.loc, "The query is nested %d fields deep, more than the %d allowed.", depth, s.maxDepth)
+			}
+		}
+	}
+	for _, name := range doc.fragmentNames {
+		if !used[name] && !cyclic {
+			v.report(doc.fragments[name].loc, "Fragment %q is never used.", name)
+		}
+	}
+	return v.errors
+}
+
+func (v *validator) report(loc Location, format string, args ...any) {
+	message := fmt.Sprintf(format, args...)
+	key := fmt.Sprintf("%s@%d:%d", message, loc.Line, loc.Column)
+	if v.seen[key] {
+		return
+	}
+	v.seen[key] = true
+	v.errors = append(v.errors, &Error{Message: message, Locations: []Location{loc}})
+}
+
+// conditionType returns the object a fragment's type condition names, or reports why there is none.
+func (v *validator) conditionType(name string, loc Location) *Object {
+	if obj, ok := v.schema.objects[name]; ok {
+		return obj
+	}
+	if _, ok := v.schema.scalars[name]; ok {
+		v.report(loc, "Fragment cannot condition on non composite type %q.", name)
+	} else {
+		v.report(loc, "Unknown type %q.", name)
+	}
+	return nil
+}
+
+// selections checks a selection set made on obj, and the fields it asks for.
+func (v *validator) selections(obj *Object, selections []selection) {
+	for _, sel := range selections {
+		switch sel := sel.(type) {
+		case *field:
+			v.directives(sel.directives)
+			if sel.name == "__typename" {
+				v.arguments(nil, sel.args, "__typename", sel.loc)
+				if sel.selections != nil {
+					v.report(sel.loc, "Field \"__typename\" must not have a selection since type \"String!\" has no subfields.")
+				}
+				continue
+			}
+			def := obj.field(sel.name)
+			if def == nil {
+				v.report(sel.loc, "Cannot query field %q on type %q.", sel.name, obj.Name)
+				continue
+			}
+			v.arguments(def.Args, sel.args, obj.Name+"."+def.Name, sel.loc)
+			switch named := namedType(def.Type).(type) {
+			case *Object:
+				if sel.selections == nil {
+					v.report(sel.loc, "Field %q of type %q must have a selection of subfields.", sel.name, def.Type)
+				} else {
+					v.selections(named, sel.selections)
+				}
+			case *Scalar:
+				if sel.selections != nil {
+					v.report(sel.loc, "Field %q must not have a selection since type %q has no subfields.", sel.name, def.Type)
+				}
+			}
+		case *inlineFragment:
+			v.directives(sel.directives)
+			if sel.typeCondition != "" {
+				if v.conditionType(sel.typeCondition, sel.loc) == nil {
+					continue
+				}
+				if sel.typeCondition != obj.Name {
+					v.report(sel.loc, "Fragment cannot be spread here as objects of type %q can never be of type %q.", obj.Name, sel.typeCondition)
+					continue
+				}
+			}
+			v.selections(obj, sel.selections)
+		case *fragmentSpread:
+			v.directives(sel.directives)
+			f, ok := v.doc.fragments[sel.name]
+			if !ok {
+				v.report(sel.loc, "Unknown fragment %q.", sel.name)
+				continue
+			}
+			if _, known := v.schema.objects[f.typeCondition]; known && f.typeCondition != obj.Name {
+				v.report(sel.loc, "Fragment %q cannot be spread here as objects of type %q can never be of type %q.", sel.name, obj.Name, f.typeCondition)
+			}
+		}
+	}
+	v.mergeable(obj, selections)
+}
+
+// arguments checks the arguments given to a field or directive, written at loc, against those it takes.
+func (v *validator) arguments(defs []*Argument, args []*argument, owner string, loc Location) {
+	given := map[string]bool{}
+	for _, a := range args {
+		if given[a.name] {
+			v.report(a.loc, "There can be only one argument named %q.", a.name)
+		}
+		given[a.name] = true
+		def := argumentDef(defs, a.name)
+		if def == nil {
+			v.report(a.loc, "Unknown argument %q on %q.", a.name, owner)
+			continue
+		}
+		if err := validLiteral(a.value, def.Type); err != nil {
+			v.report(a.value.loc, "Argument %q has an invalid value %s: %v.", a.name, printValue(a.value), err)
+		}
+	}
+	for _, def := range defs {
+		if _, nonNull := def.Type.(*NonNull); nonNull && def.Default == nil && !given[def.Name] {
+			v.report(loc, "Argument %q of type %q on %q is required, but it was not provided.", def.Name, def.Type, owner)
+		}
+	}
+}
+
+func argumentDef(defs []*Argument, name string) *Argument {
+	for _, def := range defs {
+		if def.Name == name {
+			return def
+		}
+	}
+	return nil
+}
+
+// directives checks the directives of a field or fragment, which may be @include and @skip, once each.
+func (v *validator) directives(directives []*directive) {
+	seen := map[string]bool{}
+	for _, d := range directives {
+		if d.name != "include" && d.name != "skip" {
+			v.report(d.loc, "Unknown directive \"@%s\".", d.name)
+			continue
+		}
+		if seen[d.name] {
+			v.report(d.loc, "The directive \"@%s\" can only be used once at this location.", d.name)
+		}
+		seen[d.name] = true
+		v.arguments(conditionArgs, d.args, "@"+d.name, d.loc)
+	}
+}
+
+// directivesAllowed reports the directives on a location that takes none.
+func (v *validator) directivesAllowed(directives []*directive, location string) {
+	for _, d := range directives {
+		if d.name != "include" && d.name != "skip" {
+			v.report(d.loc, "Unknown directive \"@%s\".", d.name)
+		} else {
+			v.report(d.loc, "Directive \"@%s\" may not be used on %s.", d.name, location)
+		}
+	}
+}
+
+// mergeable checks that the fields selected under one response key, directly or through fragments,
+// are the same field with the same arguments, so their results can be merged.
+func (v *validator) mergeable(obj *Object, selections []selection) {
+	var keys []string
+	groups := map[string][]*field{}
+	v.gatherFields(obj, selections, map[string]bool{}, groups, &keys)
+	for _, key := range keys {
+		fields := groups[key]
+		for _, f := range fields[1:] {
+			if f.name != fields[0].name {
+				v.report(f.loc, "Fields %q conflict because %q and %q are different fields. Use different aliases on the fields to fetch both if this was intentional.", key, fields[0].name, f.name)
+			} else if !sameArguments(fields[0].args, f.args) {
+				v.report(f.loc, "Fields %q conflict because they have differing arguments. Use different aliases on the fields to fetch both if this was intentional.", key)
+			}
+		}
+		if len(fields) < 2 || fields[0].name == "__typename" {
+			continue
+		}
+		def := obj.field(fields[0].name)
+		if def == nil {
+			continue
+		}
+		if child, ok := namedType(def.Type).(*Object); ok {
+			var combined []selection
+			for _, f := range fields {
+				if f.name == fields[0].name {
+					combined = append(combined, f.selections...)
+				}
+			}
+			v.mergeable(child, combined)
+		}
+	}
+}
+
+// gatherFields groups the fields of a selection set by response key, following fragments that apply
+// to obj whatever their directives say.
+func (v *validator) gatherFields(obj *Object, selections []selection, visited map[string]bool, groups map[string][]*field, keys *[]string) {
+	for _, sel := range selections {
+		switch sel := sel.(type) {
+		case *field:
+			key := sel.responseKey()
+			if _, ok := groups[key]; !ok {
+				*keys = append(*keys, key)
+			}
+			groups[key] = append(groups[key], sel)
+		case *inlineFragment:
+			if sel.typeCondition == "" || sel.typeCondition == obj.Name {
+				v.gatherFields(obj, sel.selections, visited, groups, keys)
+			}
+		case *fragmentSpread:
+			f, ok := v.doc.fragments[sel.name]
+			if ok && !visited[sel.name] && f.typeCondition == obj.Name {
+				visited[sel.name] = true
+				v.gatherFields(obj, f.selections, visited, groups, keys)
+			}
+		}
+	}
+}
+
+func sameArguments(a, b []*argument) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, x := range a {
+		found := false
+		for _, y := range b {
+			if x.name == y.name {
+				found = printValue(x.value) == printValue(y.value)
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// fragmentCycles reports fragments that spread themselves, directly or through others, and returns
+// whether there were any.
+func (v *validator) fragmentCycles() bool {
+	state := map[string]int{} // 1 while its spreads are walked, 2 once done
+	cyclic := false
+	var visit func(name string)
+	visit = func(name string) {
+		state[name] = 1
+		for _, spread := range spreadsIn(v.doc.fragments[name].selections) {
+			if _, ok := v.doc.fragments[spread.name]; !ok {
+				continue
+			}
+			switch state[spread.name] {
+			case 1:
+				v.report(spread.loc, "Cannot spread fragment %q within itself.", spread.name)
+				cyclic = true
+			case 0:
+				visit(spread.name)
+			}
+		}
+		state[name] = 2
+	}
+	for _, name := range v.doc.fragmentNames {
+		if state[name] == 0 {
+			visit(name)
+		}
+	}
+	return cyclic
+}
+
+// spreadsIn returns the fragment spreads in a selection set, at any depth but not through fragments.
+func spreadsIn(selections []selection) []*fragmentSpread {
+	var spreads []*fragmentSpread
+	for _, sel := range selections {
+		switch sel := sel.(type) {
+		case *field:
+			spreads = append(spreads, spreadsIn(sel.selections)...)
+		case *inlineFragment:
+			spreads = append(spreads, spreadsIn(sel.selections)...)
+		case *fragmentSpread:
+			spreads = append(spreads, sel)
+		}
+	}
+	return spreads
+}
+
+// markSpreads marks the fragments a selection set uses, directly or through other fragments.
+func markSpreads(doc *document, selections []selection, used map[string]bool) {
+	for _, spread := range spreadsIn(selections) {
+		if f, ok := doc.fragments[spread.name]; ok && !used[spread.name] {
+			used[spread.name] = true
+			markSpreads(doc, f.selections, used)
+		}
+	}
+}
+
+// depth returns how many fields deep a selection set goes, through its fragments.
+func (v *validator) depth(selections []selection) int {
+	deepest := 0
+	for _, sel := range selections {
+		switch sel := sel.(type) {
+		case *field:
+			deepest = max(deepest, 1+v.depth(sel.selections))
+		case *inlineFragment:
+			deepest = max(deepest, v.depth(sel.selections))
+		case *fragmentSpread:
+			if f, ok := v.doc.fragments[sel.name]; ok {
+				deepest = max(deepest, v.depth(f.selections))
+			}
+		}
+	}
+	return deepest
+}
+
+// variables checks an operation's variable definitions against the arguments they are used in.
+func (v *validator) variables(op *operation) {
+	defs := map[string]*variableDef{}
+	types := map[string]Type{}
+	for _, def := range op.vars {
+		if _, ok := defs[def.name]; ok {
+			v.report(def.loc, "There can be only one variable named \"$%s\".", def.name)
+		}
+		defs[def.name] = def
+		t, err := v.schema.inputType(def.typ)
+		if err != nil {
+			v.report(def.loc, "Variable \"$%s\" %v.", def.name, err)
+			continue
+		}
+		types[def.name] = t
+		if def.def != nil {
+			if err := validLiteral(def.def, t); err != nil {
+				v.report(def.def.loc, "Variable \"$%s\" has an invalid default value %s: %v.", def.name, printValue(def.def), err)
+			}
+		}
+	}
+
+	used := map[string]bool{}
+	for _, usage := range v.usages(v.schema.query, op.selections, map[string]bool{}) {
+		def, ok := defs[usage.name]
+		if !ok {
+			if op.name != "" {
+				v.report(usage.loc, "Variable \"$%s\" is not defined by operation %q.", usage.name, op.name)
+			} else {
+				v.report(usage.loc, "Variable \"$%s\" is not defined.", usage.name)
+			}
+			continue
+		}
+		used[usage.name] = true
+		if _, ok := types[usage.name]; ok && !variableFits(def, usage) {
+			v.report(usage.loc, "Variable \"$%s\" of type %q used in position expecting type %q.", usage.name, def.typ, usage.typ)
+		}
+	}
+	for _, def := range op.vars {
+		if !used[def.name] {
+			if op.name != "" {
+				v.report(def.loc, "Variable \"$%s\" is never used in operation %q.", def.name, op.name)
+			} else {
+				v.report(def.loc, "Variable \"$%s\" is never used.", def.name)
+			}
+		}
+	}
+}
+
+// usages returns the variables used in a selection set on obj, through its fragments.
+func (v *validator) usages(obj *Object, selections []selection, visited map[string]bool) []variableUsage {
+	var found []variableUsage
+	directiveUsages := func(directives []*directive) {
+		for _, d := range directives {
+			for _, a := range d.args {
+				found = append(found, valueUsages(a.value, conditionArgs[0].Type, false)...)
+			}
+		}
+	}
+	for _, sel := range selections {
+		switch sel := sel.(type) {
+		case *field:
+			directiveUsages(sel.directives)
+			def := obj.field(sel.name)
+			if def == nil {
+				continue
+			}
+			for _, a := range sel.args {
+				if argDef := argumentDef(def.Args, a.name); argDef != nil {
+					found = append(found, valueUsages(a.value, argDef.Type, argDef.Default != nil)...)
+				}
+			}
+			if child, ok := namedType(def.Type).(*Object); ok {
+				found = append(found, v.usages(child, sel.selections, visited)...)
+			}
+		case *inlineFragment:
+			directiveUsages(sel.directives)
+			found = append(found, v.usages(obj, sel.selections, visited)...)
+		case *fragmentSpread:
+			directiveUsages(sel.directives)
+			if f, ok := v.doc.fragments[sel.name]; ok && !visited[sel.name] {
+				visited[sel.name] = true
+				if fobj, ok := v.schema.objects[f.typeCondition]; ok {
+					found = append(found, v.usages(fobj, f.selections, visited)...)
+				}
+			}
+		}
+	}
+	return found
+}
+
+// valueUsages returns the variables in a value given for type t.
+func valueUsages(val *value, t Type, hasDefault bool) []variableUsage {
+	switch val.kind {
+	case variableValue:
+		return []variableUsage{{name: val.raw, typ: t, hasDefault: hasDefault, loc: val.loc}}
+	case listValue:
+		var found []variableUsage
+		if list, ok := nullable(t).(*List); ok {
+			for _, item := range val.list {
+				found = append(found, valueUsages(item, list.Of, false)...)
+			}
+		}
+		return found
+	}
+	return nil
+}
+
+// variableFits reports whether a variable can be used where usage expects its type. A nullable
+// variable fits a non-null position if either has a default.
+func variableFits(def *variableDef, usage variableUsage) bool {
+	expected := usage.typ
+	if nn, ok := expected.(*NonNull); ok && !def.typ.nonNull {
+		if def.def == nil && !usage.hasDefault {
+			return false
+		}
+		expected = nn.Of
+	}
+	return typeFits(def.typ, expected)
+}
+
+func typeFits(given *typeRef, expected Type) bool {
+	if nn, ok := expected.(*NonNull); ok {
+		if !given.nonNull {
+			return false
+		}
+		return typeFits(&typeRef{name: given.name, elem: given.elem}, nn.Of)
+	}
+	if given.nonNull {
+		return typeFits(&typeRef{name: given.name, elem: given.elem}, expected)
+	}
+	if list, ok := expected.(*List); ok {
+		return given.elem != nil && typeFits(given.elem, list.Of)
+	}
+	return given.elem == nil && given.name == expected.String()
+}
+
+// inputType returns the schema type a variable is declared with, which must be made of scalars.
+func (s *Schema) inputType(t *typeRef) (Type, error) {
+	var base Type
+	if t.elem != nil {
+		elem, err := s.inputType(t.elem)
+		if err != nil {
+			return nil, err
+		}
+		base = &List{elem}
+	} else if scalar, ok := s.scalars[t.name]; ok {
+		base = scalar
+	} else if _, ok := s.objects[t.name]; ok {
+		return nil, fmt.Errorf("cannot be non-input type %q", t.name)
+	} else {
+		return nil, fmt.Errorf("has unknown type %q", t.name)
+	}
+	if t.nonNull {
+		return &NonNull{base}, nil
+	}
+	return base, nil
+}
+
+// validLiteral checks a value written in a query against type t. Variables are checked where they
+// are declared.
+func validLiteral(val *value, t Type) error {
+	if val.kind == variableValue {
+		return nil
+	}
+	if nn, ok := t.(*NonNull); ok {
+		if val.kind == nullValue {
+			return fmt.Errorf("expected a value of type %q, found null", t)
+		}
+		return validLiteral(val, nn.Of)
+	}
+	if val.kind == nullValue {
+		return nil
+	}
+	if list, ok := t.(*List); ok {
+		if val.kind != listValue {
+			return validLiteral(val, list.Of)
+		}
+		for _, item := range val.list {
+			if err := validLiteral(item, list.Of); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	scalar, ok := t.(*Scalar)
+	if !ok {
+		return fmt.Errorf("%s is not an input type", t)
+	}
+	raw, err := literalInput(val)
+	if err != nil {
+		return err
+	}
+	_, err = scalar.Parse(raw)
+	return err
+}
+
+// namedType returns t without its List and NonNull wrappers.
+func namedType(t Type) Type {
+	for {
+		switch w := t.(type) {
+		case *List:
+			t = w.Of
+		case *NonNull:
+			t = w.Of
+		default:
+			return t
+		}
+	}
+}
+
+// nullable returns t without a NonNull wrapper.
+func nullable(t Type) Type {
+	if nn, ok := t.(*NonNull); ok {
+		return nn.Of
+	}
+	return t
+}
+
+// printValue writes a value as it would appear in a query, for messages and comparisons.
+func printValue(val *value) string {
+	switch val.kind {
+	case variableValue:
+		return "$" + val.raw
+	case stringValue:
+		return fmt.Sprintf("%q", val.raw)
+	case listValue:
+		items := make([]string, len(val.list))
+		for i, item := range val.list {
+			items[i] = printValue(item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case objectValue:
+		fields := make([]string, len(val.fields))
+		for i, f := range val.fields {
+			fields[i] = f.name + ": " + printValue(f.value)
+		}
+		return "{" + strings.Join(fields, ", ") + "}"
+	case nullValue:
+		return "null"
+	}
+	return val.raw
+}
@@ -0,0 +1,57 @@
+// services/user-service/internal/handlers/account_deletion.go
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/services"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// AccountDeletionHandler holds dependencies for right-to-be-forgotten requests.
+type AccountDeletionHandler struct {
+	deletionService services.AccountDeletionService
+	auditor         *Auditor
+}
+
+// NewAccountDeletionHandler creates a new AccountDeletionHandler instance.
+func NewAccountDeletionHandler(deletionService services.AccountDeletionService, auditor *Auditor) *AccountDeletionHandler {
+	return &AccountDeletionHandler{deletionService: deletionService, auditor: auditor}
+}
+
+// DeleteAccount handles POST /users/me/delete-account requests. The caller's account is locked, its
+// sessions are revoked, and the auth cookie is cleared; the account is erased after the grace period.
+func (h *AccountDeletionHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+
+	deletion, err := h.deletionService.RequestDeletion(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			writeError(w, http.StatusNotFound, models.ErrorCodeUserNotFound, "User not found")
+			return
+		}
+		logger.FromContext(r.Context()).Errorf("Error scheduling deletion of account %s: %v", userID, err)
+		writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to schedule account deletion")
+		return
+	}
+
+	h.auditor.Record(r, models.AuditEvent{
+		Action:   models.AuditUserDeletion,
+		Outcome:  models.AuditSuccess,
+		TargetID: userID.String(),
+		Details:  map[string]string{"due_at": deletion.DueAt.Format(time.RFC3339)},
+	})
+
+	clearAuthCookie(w)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	writeBody(w, deletion)
+}
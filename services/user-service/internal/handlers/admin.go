@@ -0,0 +1,342 @@
+// services/user-service/internal/handlers/admin.go
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/config"
+	"health-tracker-project/services/user-service/internal/metrics"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/services"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// AdminHandler holds dependencies for operator-facing HTTP handlers.
+type AdminHandler struct {
+	eventService   services.SystemEventService
+	userService    services.UserService
+	configReloader *config.Reloader
+	auditor        *Auditor
+}
+
+// NewAdminHandler creates a new AdminHandler instance.
+func NewAdminHandler(eventService services.SystemEventService, userService services.UserService, configReloader *config.Reloader, auditor *Auditor) *AdminHandler {
+	return &AdminHandler{eventService: eventService, userService: userService, configReloader: configReloader, auditor: auditor}
+}
+
+// GetTimeline handles GET /admin/timeline?type=&since=&until=&limit= requests.
+// since/until are RFC 3339 timestamps.
+func (h *AdminHandler) GetTimeline(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := models.SystemEventFilter{Type: q.Get("type")}
+
+	var err error
+	if v := q.Get("since"); v != "" {
+		if filter.Since, err = time.Parse(time.RFC3339, v); err != nil {
+			writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid 'since' timestamp, expected RFC 3339")
+			return
+		}
+	}
+	if v := q.Get("until"); v != "" {
+		if filter.Until, err = time.Parse(time.RFC3339, v); err != nil {
+			writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid 'until' timestamp, expected RFC 3339")
+			return
+		}
+	}
+	if filter.After, err = pageAfter(r); err != nil {
+		writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidCursor, "Invalid 'cursor'")
+		return
+	}
+	if v := q.Get("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil {
+			writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid 'limit', expected an integer")
+			return
+		}
+	}
+
+	events, err := h.eventService.GetTimeline(r.Context(), filter)
+	if err != nil {
+		logger.FromContext(r.Context()).Errorf("Error getting system timeline: %v", err)
+		writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to get timeline")
+		return
+	}
+
+	if len(events) > 0 {
+		last := events[len(events)-1]
+		setNextPage(w, r, models.PageKey{At: last.StartsAt, ID: last.ID})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, events)
+	logger.FromContext(r.Context()).Debugf("Retrieved %d timeline events", len(events))
+}
+
+// ListUsers handles GET /admin/users?role=&status=&verified=&created_since=&created_until=&cursor=&limit= requests.
+// Timestamps are RFC 3339; the Link header holds the URL of the next page.
+func (h *AdminHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := models.UserFilter{Role: q.Get("role"), Status: q.Get("status")}
+
+	if v := q.Get("verified"); v != "" {
+		verified, err := strconv.ParseBool(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid 'verified', expected true or false")
+			return
+		}
+		filter.Verified = &verified
+	}
+	for name, dst := range map[string]*time.Time{"created_since": &filter.CreatedSince, "created_until": &filter.CreatedUntil} {
+		if v := q.Get(name); v != "" {
+			t, err := time.Parse(time.RFC3339Nano, v)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid '"+name+"' timestamp, expected RFC 3339")
+				return
+			}
+			*dst = t
+		}
+	}
+	var err error
+	if filter.After, err = pageAfter(r); err != nil {
+		writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidCursor, "Invalid 'cursor'")
+		return
+	}
+	if v := q.Get("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil {
+			writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid 'limit', expected an integer")
+			return
+		}
+	}
+
+	list, err := h.userService.ListUsers(r.Context(), filter)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidInput) {
+			writeError(w, http.StatusBadRequest, models.ErrorCodeValidationFailed, serviceMessage(err))
+		} else {
+			logger.FromContext(r.Context()).Errorf("Error listing users: %v", err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to list users")
+		}
+		return
+	}
+
+	if len(list.Users) > 0 {
+		last := list.Users[len(list.Users)-1]
+		setNextPage(w, r, models.PageKey{At: last.CreatedAt, ID: last.ID})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, list)
+	logger.FromContext(r.Context()).Debugf("Listed %d of %d users", len(list.Users), list.Counts.Total)
+}
+
+// ListAuditEvents handles GET /admin/audit-events?action=&outcome=&actor_id=&target_id=&ip=&since=&cursor=&limit= requests.
+// since is an RFC 3339 timestamp; the Link header holds the URL of the next page.
+func (h *AdminHandler) ListAuditEvents(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := models.AuditEventFilter{
+		Action:   q.Get("action"),
+		Outcome:  q.Get("outcome"),
+		ActorID:  q.Get("actor_id"),
+		TargetID: q.Get("target_id"),
+		IP:       q.Get("ip"),
+	}
+
+	var err error
+	if v := q.Get("since"); v != "" {
+		if filter.Since, err = time.Parse(time.RFC3339Nano, v); err != nil {
+			writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid 'since' timestamp, expected RFC 3339")
+			return
+		}
+	}
+	if filter.After, err = pageAfter(r); err != nil {
+		writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidCursor, "Invalid 'cursor'")
+		return
+	}
+	if v := q.Get("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil {
+			writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid 'limit', expected an integer")
+			return
+		}
+	}
+
+	events, err := h.auditor.service.ListEvents(r.Context(), filter)
+	if err != nil {
+		logger.FromContext(r.Context()).Errorf("Error listing audit events: %v", err)
+		writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to list audit events")
+		return
+	}
+
+	if len(events) > 0 {
+		last := events[len(events)-1]
+		setNextPage(w, r, models.PageKey{At: last.CreatedAt, ID: last.ID})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, events)
+	logger.FromContext(r.Context()).Debugf("Retrieved %d audit events", len(events))
+}
+
+// CreateTimelineEvent handles POST /admin/timeline requests (deploy markers, maintenance windows, ...).
+func (h *AdminHandler) CreateTimelineEvent(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateSystemEventRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	actor, _ := r.Context().Value(UserContextKey).(string)
+	event, err := h.eventService.RecordEvent(r.Context(), req, actor)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidInput) {
+			logger.FromContext(r.Context()).Warnf("Timeline event rejected: %v", err)
+			writeError(w, http.StatusBadRequest, models.ErrorCodeValidationFailed, serviceMessage(err))
+		} else {
+			logger.FromContext(r.Context()).Errorf("Error recording timeline event: %v", err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to record event")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	writeBody(w, event)
+}
+
+// GetConfig handles GET /admin/config requests, returning the active runtime config.
+func (h *AdminHandler) GetConfig(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, config.Current())
+}
+
+// GetSLO handles GET /admin/slo requests.
+// It summarizes compliance, remaining error budget, and burn rates of every configured SLO.
+func (h *AdminHandler) GetSLO(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, metrics.SLOSummary())
+}
+
+// ReloadConfig handles POST /admin/config/reload requests.
+// The config file is re-read and applied atomically; an invalid file leaves the active config untouched.
+func (h *AdminHandler) ReloadConfig(w http.ResponseWriter, r *http.Request) {
+	actor, _ := r.Context().Value(UserContextKey).(string)
+	cfg, err := h.configReloader.Reload(actor)
+	if err != nil {
+		logger.FromContext(r.Context()).Warnf("Runtime config reload by %s rejected: %v", actor, err)
+		writeError(w, http.StatusUnprocessableEntity, models.ErrorCodeInvalidConfig, serviceMessage(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, cfg)
+}
+
+// MergeUsers handles POST /admin/users/merge requests to fold a duplicate account into another.
+func (h *AdminHandler) MergeUsers(w http.ResponseWriter, r *http.Request) {
+	var req models.MergeUsersRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	actor, _ := r.Context().Value(UserContextKey).(string)
+	merge, err := h.userService.MergeUsers(r.Context(), req, actor)
+	if err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			writeError(w, http.StatusNotFound, models.ErrorCodeUserNotFound, serviceMessage(err))
+		} else if errors.Is(err, services.ErrInvalidInput) {
+			writeError(w, http.StatusBadRequest, models.ErrorCodeValidationFailed, serviceMessage(err))
+		} else {
+			logger.FromContext(r.Context()).Errorf("Error merging users: %v", err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to merge users")
+		}
+		return
+	}
+	h.auditor.Record(r, models.AuditEvent{
+		Action:   models.AuditUserMerge,
+		Outcome:  models.AuditSuccess,
+		TargetID: merge.PrimaryUserID.String(),
+		Details: map[string]string{"merge_id": merge.ID.String(), "donor_user_id": merge.DonorUserID.String(),
+			"identities_moved": strconv.Itoa(len(merge.MovedIdentities))},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	writeBody(w, merge)
+}
+
+// UndoUserMerge handles POST /admin/users/merges/{id}/undo requests.
+func (h *AdminHandler) UndoUserMerge(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid merge ID format")
+		return
+	}
+
+	actor, _ := r.Context().Value(UserContextKey).(string)
+	merge, err := h.userService.UndoUserMerge(r.Context(), id, actor)
+	if err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			writeError(w, http.StatusNotFound, models.ErrorCodeNotFound, serviceMessage(err))
+		} else if errors.Is(err, services.ErrConflict) || errors.Is(err, services.ErrDuplicateEmail) {
+			writeError(w, http.StatusConflict, conflictCode(err), serviceMessage(err))
+		} else {
+			logger.FromContext(r.Context()).Errorf("Error undoing merge %s: %v", id, err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to undo merge")
+		}
+		return
+	}
+	h.auditor.Record(r, models.AuditEvent{
+		Action:   models.AuditUserMergeUndo,
+		Outcome:  models.AuditSuccess,
+		TargetID: merge.PrimaryUserID.String(),
+		Details:  map[string]string{"merge_id": merge.ID.String(), "donor_user_id": merge.DonorUserID.String()},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, merge)
+}
+
+// SuspendUser handles POST /admin/users/{id}/suspend requests.
+func (h *AdminHandler) SuspendUser(w http.ResponseWriter, r *http.Request) {
+	h.changeUserStatus(w, r, models.AuditUserSuspend, h.userService.SuspendUser)
+}
+
+// ReactivateUser handles POST /admin/users/{id}/reactivate requests.
+func (h *AdminHandler) ReactivateUser(w http.ResponseWriter, r *http.Request) {
+	h.changeUserStatus(w, r, models.AuditUserReactivate, h.userService.ReactivateUser)
+}
+
+func (h *AdminHandler) changeUserStatus(w http.ResponseWriter, r *http.Request, action string, change func(context.Context, uuid.UUID, string) (*models.UserResponse, error)) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid user ID format")
+		return
+	}
+
+	actor, _ := r.Context().Value(UserContextKey).(string)
+	user, err := change(r.Context(), id, actor)
+	if err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			writeError(w, http.StatusNotFound, models.ErrorCodeUserNotFound, serviceMessage(err))
+		} else if errors.Is(err, services.ErrInvalidInput) {
+			writeError(w, http.StatusBadRequest, models.ErrorCodeValidationFailed, serviceMessage(err))
+		} else if errors.Is(err, services.ErrConflict) {
+			writeError(w, http.StatusConflict, conflictCode(err), serviceMessage(err))
+		} else {
+			logger.FromContext(r.Context()).Errorf("Error changing status of user %s: %v", id, err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to change user status")
+		}
+		return
+	}
+	h.auditor.Record(r, models.AuditEvent{Action: action, Outcome: models.AuditSuccess, TargetID: id.String()})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, user)
+}
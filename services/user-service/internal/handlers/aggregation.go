@@ -0,0 +1,170 @@
+// services/user-service/internal/handlers/aggregation.go
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/services"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// AggregationHandler holds dependencies for the custom aggregation period and window handlers.
+type AggregationHandler struct {
+	aggregationService services.AggregationService
+}
+
+// NewAggregationHandler creates a new AggregationHandler instance.
+func NewAggregationHandler(aggregationService services.AggregationService) *AggregationHandler {
+	return &AggregationHandler{aggregationService: aggregationService}
+}
+
+// writeAggregationError maps the aggregation service's client errors to responses, reporting whether it did.
+func writeAggregationError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, services.ErrNotFound): // The period, or its user
+		writeError(w, http.StatusNotFound, models.ErrorCodeNotFound, serviceMessage(err))
+	case errors.Is(err, services.ErrConflict):
+		writeError(w, http.StatusConflict, models.ErrorCodeConflict, serviceMessage(err))
+	case errors.Is(err, services.ErrInvalidInput):
+		writeError(w, http.StatusBadRequest, models.ErrorCodeValidationFailed, serviceMessage(err))
+	default:
+		return false
+	}
+	return true
+}
+
+// ListPeriods handles GET /me/aggregation-periods.
+func (h *AggregationHandler) ListPeriods(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+
+	periods, err := h.aggregationService.ListPeriods(r.Context(), userID)
+	if err != nil {
+		logger.FromContext(r.Context()).Errorf("Error listing aggregation periods for user %s: %v", userID, err)
+		writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to list aggregation periods")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, periods)
+}
+
+// CreatePeriod handles POST /me/aggregation-periods.
+func (h *AggregationHandler) CreatePeriod(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+	var req models.AggregationPeriodRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	period, err := h.aggregationService.CreatePeriod(r.Context(), userID, req)
+	if err != nil {
+		if !writeAggregationError(w, err) {
+			logger.FromContext(r.Context()).Errorf("Error creating aggregation period for user %s: %v", userID, err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to create aggregation period")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	writeBody(w, period)
+}
+
+// UpdatePeriod handles PUT /me/aggregation-periods/{id}, replacing the period's name, kind, and dates.
+func (h *AggregationHandler) UpdatePeriod(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid aggregation period ID format")
+		return
+	}
+	var req models.AggregationPeriodRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	period, err := h.aggregationService.UpdatePeriod(r.Context(), userID, id, req)
+	if err != nil {
+		if !writeAggregationError(w, err) {
+			logger.FromContext(r.Context()).Errorf("Error updating aggregation period %s: %v", id, err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to update aggregation period")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, period)
+}
+
+// DeletePeriod handles DELETE /me/aggregation-periods/{id}.
+func (h *AggregationHandler) DeletePeriod(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid aggregation period ID format")
+		return
+	}
+
+	if err := h.aggregationService.DeletePeriod(r.Context(), userID, id); err != nil {
+		if !writeAggregationError(w, err) {
+			logger.FromContext(r.Context()).Errorf("Error deleting aggregation period %s: %v", id, err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to delete aggregation period")
+		}
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetWindows handles GET /users/{id}/aggregation-windows?from=YYYY-MM-DD&to=YYYY-MM-DD, with an
+// optional comma-separated kind filter (week, period). Analytics services call it with the users:read
+// scope to bucket a user's data.
+func (h *AggregationHandler) GetWindows(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid user ID format")
+		return
+	}
+	if !authorizeUserAccess(w, r, userID) {
+		return
+	}
+
+	q := r.URL.Query()
+	var kinds []string
+	if kind := q.Get("kind"); kind != "" {
+		kinds = strings.Split(kind, ",")
+	}
+	windows, err := h.aggregationService.Windows(r.Context(), userID, q.Get("from"), q.Get("to"), kinds)
+	if err != nil {
+		if !writeAggregationError(w, err) {
+			logger.FromContext(r.Context()).Errorf("Error resolving aggregation windows for user %s: %v", userID, err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to resolve aggregation windows")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, windows)
+}
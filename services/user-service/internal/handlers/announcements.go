@@ -0,0 +1,178 @@
+// services/user-service/internal/handlers/announcements.go
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/services"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// AnnouncementHandler holds dependencies for the admin announcement endpoints.
+type AnnouncementHandler struct {
+	announcementService services.AnnouncementService
+	auditor             *Auditor
+}
+
+// NewAnnouncementHandler creates a new AnnouncementHandler instance.
+func NewAnnouncementHandler(announcementService services.AnnouncementService, auditor *Auditor) *AnnouncementHandler {
+	return &AnnouncementHandler{announcementService: announcementService, auditor: auditor}
+}
+
+// writeAnnouncementError maps the announcement service's client errors to responses, reporting whether it did.
+func writeAnnouncementError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, services.ErrNotFound):
+		writeError(w, http.StatusNotFound, models.ErrorCodeAnnouncementNotFound, "Announcement not found")
+	case errors.Is(err, services.ErrConflict):
+		writeError(w, http.StatusConflict, models.ErrorCodeConflict, serviceMessage(err))
+	case errors.Is(err, services.ErrInvalidInput):
+		writeError(w, http.StatusBadRequest, models.ErrorCodeValidationFailed, serviceMessage(err))
+	default:
+		return false
+	}
+	return true
+}
+
+// PreviewAnnouncement handles POST /admin/announcements/preview requests: the announcement as it would
+// be sent and the size of its segment, without scheduling it.
+func (h *AnnouncementHandler) PreviewAnnouncement(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateAnnouncementRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	preview, err := h.announcementService.Preview(r.Context(), req)
+	if err != nil {
+		if !writeAnnouncementError(w, err) {
+			logger.FromContext(r.Context()).Errorf("Error previewing announcement: %v", err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to preview announcement")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, preview)
+}
+
+// CreateAnnouncement handles POST /admin/announcements requests, scheduling an announcement for its
+// send_at, or for as soon as possible.
+func (h *AnnouncementHandler) CreateAnnouncement(w http.ResponseWriter, r *http.Request) {
+	actor, _ := r.Context().Value(UserContextKey).(string)
+	var req models.CreateAnnouncementRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	announcement, err := h.announcementService.Create(r.Context(), req, actor)
+	if err != nil {
+		if !writeAnnouncementError(w, err) {
+			logger.FromContext(r.Context()).Errorf("Error creating announcement: %v", err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to create announcement")
+		}
+		return
+	}
+	h.auditor.Record(r, models.AuditEvent{
+		Action:   models.AuditAnnouncementCreate,
+		Outcome:  models.AuditSuccess,
+		TargetID: announcement.ID.String(),
+		Details:  map[string]string{"segment": announcement.Segment.Type},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	writeBody(w, announcement)
+}
+
+// ListAnnouncements handles GET /admin/announcements?status=&cursor=&limit= requests, newest first; the
+// Link header holds the URL of the next page.
+func (h *AnnouncementHandler) ListAnnouncements(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := models.AnnouncementFilter{Status: q.Get("status")}
+
+	var err error
+	if filter.After, err = pageAfter(r); err != nil {
+		writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidCursor, "Invalid 'cursor'")
+		return
+	}
+	if v := q.Get("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil {
+			writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid 'limit', expected an integer")
+			return
+		}
+	}
+
+	announcements, err := h.announcementService.List(r.Context(), filter)
+	if err != nil {
+		if !writeAnnouncementError(w, err) {
+			logger.FromContext(r.Context()).Errorf("Error listing announcements: %v", err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to list announcements")
+		}
+		return
+	}
+
+	if len(announcements) > 0 {
+		last := announcements[len(announcements)-1]
+		setNextPage(w, r, models.PageKey{At: last.CreatedAt, ID: last.ID})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, announcements)
+}
+
+// GetAnnouncement handles GET /admin/announcements/{id} requests, including delivery stats.
+func (h *AnnouncementHandler) GetAnnouncement(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid announcement ID format")
+		return
+	}
+
+	announcement, err := h.announcementService.Get(r.Context(), id)
+	if err != nil {
+		if !writeAnnouncementError(w, err) {
+			logger.FromContext(r.Context()).Errorf("Error getting announcement %s: %v", id, err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to get announcement")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, announcement)
+}
+
+// CancelAnnouncement handles POST /admin/announcements/{id}/cancel requests. A send in progress stops
+// after the page of recipients it is on.
+func (h *AnnouncementHandler) CancelAnnouncement(w http.ResponseWriter, r *http.Request) {
+	actor, _ := r.Context().Value(UserContextKey).(string)
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid announcement ID format")
+		return
+	}
+
+	announcement, err := h.announcementService.Cancel(r.Context(), id, actor)
+	if err != nil {
+		if !writeAnnouncementError(w, err) {
+			logger.FromContext(r.Context()).Errorf("Error cancelling announcement %s: %v", id, err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to cancel announcement")
+		}
+		return
+	}
+	h.auditor.Record(r, models.AuditEvent{
+		Action:   models.AuditAnnouncementCancel,
+		Outcome:  models.AuditSuccess,
+		TargetID: id.String(),
+		Details:  map[string]string{"sent": strconv.Itoa(announcement.Stats.Sent)},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, announcement)
+}
@@ -0,0 +1,370 @@
+// services/user-service/internal/handlers/appointments.go
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/ics"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/services"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+	"health-tracker-project/services/user-service/internal/utils/measure"
+	"health-tracker-project/services/user-service/internal/utils/reqctx"
+)
+
+// AppointmentHandler holds dependencies for appointment scheduling handlers.
+type AppointmentHandler struct {
+	appointmentService services.AppointmentService
+}
+
+// NewAppointmentHandler creates a new AppointmentHandler instance.
+func NewAppointmentHandler(appointmentService services.AppointmentService) *AppointmentHandler {
+	return &AppointmentHandler{appointmentService: appointmentService}
+}
+
+// parseRange reads the optional RFC 3339 'from' and 'to' query parameters, answering 400 when one is malformed.
+func parseRange(w http.ResponseWriter, r *http.Request) (from, to time.Time, ok bool) {
+	q := r.URL.Query()
+	var err error
+	if v := q.Get("from"); v != "" {
+		if from, err = time.Parse(time.RFC3339, v); err != nil {
+			writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid 'from' timestamp, expected RFC 3339")
+			return from, to, false
+		}
+	}
+	if v := q.Get("to"); v != "" {
+		if to, err = time.Parse(time.RFC3339, v); err != nil {
+			writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid 'to' timestamp, expected RFC 3339")
+			return from, to, false
+		}
+	}
+	return from, to, true
+}
+
+// writeAppointmentError answers the errors shared by the booking endpoints, reporting whether it did.
+func writeAppointmentError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, services.ErrNotFound):
+		writeError(w, http.StatusNotFound, models.ErrorCodeNotFound, serviceMessage(err))
+	case errors.Is(err, services.ErrConflict):
+		writeError(w, http.StatusConflict, models.ErrorCodeConflict, serviceMessage(err))
+	case errors.Is(err, services.ErrForbidden):
+		writeError(w, http.StatusForbidden, models.ErrorCodeForbidden, serviceMessage(err))
+	case errors.Is(err, services.ErrInvalidInput):
+		writeError(w, http.StatusBadRequest, models.ErrorCodeValidationFailed, serviceMessage(err))
+	default:
+		return false
+	}
+	return true
+}
+
+// PublishAvailability handles POST /provider/availability, publishing the caller's bookable slots.
+func (h *AppointmentHandler) PublishAvailability(w http.ResponseWriter, r *http.Request) {
+	providerID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+	var req models.PublishAvailabilityRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.SlotLength != "" {
+		length, err := measure.ParseDuration(req.SlotLength, reqctx.FromContext(r.Context()).Locale, time.Minute)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid slot_length: "+err.Error())
+			return
+		}
+		if length%time.Minute != 0 || (req.SlotMinutes != 0 && req.SlotMinutes != int(length/time.Minute)) {
+			writeError(w, http.StatusBadRequest, models.ErrorCodeValidationFailed, "slot_length must be whole minutes and agree with slot_minutes")
+			return
+		}
+		req.SlotMinutes = int(length / time.Minute)
+	}
+
+	slots, err := h.appointmentService.PublishAvailability(r.Context(), providerID, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrConflict):
+			writeError(w, http.StatusConflict, models.ErrorCodeConflict, "Slots overlap existing availability")
+		case errors.Is(err, services.ErrInvalidInput):
+			writeError(w, http.StatusBadRequest, models.ErrorCodeValidationFailed, serviceMessage(err))
+		default:
+			logger.FromContext(r.Context()).Errorf("Error publishing availability for provider %s: %v", providerID, err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to publish availability")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	writeBody(w, slots)
+}
+
+// listSlots answers a slot listing of one provider for the requested range.
+func (h *AppointmentHandler) listSlots(w http.ResponseWriter, r *http.Request, providerID uuid.UUID, openOnly bool) {
+	from, to, ok := parseRange(w, r)
+	if !ok {
+		return
+	}
+
+	slots, err := h.appointmentService.ListSlots(r.Context(), models.SlotFilter{ProviderID: providerID, From: from, To: to, OpenOnly: openOnly})
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrNotFound):
+			writeError(w, http.StatusNotFound, models.ErrorCodeProviderNotFound, "Provider not found")
+		case errors.Is(err, services.ErrInvalidInput):
+			writeError(w, http.StatusBadRequest, models.ErrorCodeValidationFailed, serviceMessage(err))
+		default:
+			logger.FromContext(r.Context()).Errorf("Error listing slots of provider %s: %v", providerID, err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to list slots")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, slots)
+}
+
+// ListOwnSlots handles GET /provider/slots?from=&to= requests, the caller's slots, booked or not.
+func (h *AppointmentHandler) ListOwnSlots(w http.ResponseWriter, r *http.Request) {
+	providerID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+	h.listSlots(w, r, providerID, false)
+}
+
+// ListOpenSlots handles GET /providers/{id}/slots?from=&to= requests, a provider's open slots.
+func (h *AppointmentHandler) ListOpenSlots(w http.ResponseWriter, r *http.Request) {
+	providerID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid provider ID format")
+		return
+	}
+	h.listSlots(w, r, providerID, true)
+}
+
+// DeleteSlot handles DELETE /provider/slots/{id}, withdrawing one of the caller's open slots.
+func (h *AppointmentHandler) DeleteSlot(w http.ResponseWriter, r *http.Request) {
+	providerID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid slot ID format")
+		return
+	}
+
+	if err := h.appointmentService.DeleteSlot(r.Context(), providerID, id); err != nil {
+		switch {
+		case errors.Is(err, services.ErrNotFound):
+			writeError(w, http.StatusNotFound, models.ErrorCodeSlotNotFound, "Slot not found")
+		case errors.Is(err, services.ErrConflict):
+			writeError(w, http.StatusConflict, models.ErrorCodeConflict, "Slot is booked; cancel the appointment first")
+		default:
+			logger.FromContext(r.Context()).Errorf("Error deleting slot %s: %v", id, err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to delete slot")
+		}
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// listAppointments answers an appointment listing narrowed to the caller by setFilter.
+func (h *AppointmentHandler) listAppointments(w http.ResponseWriter, r *http.Request, setFilter func(*models.AppointmentFilter, uuid.UUID)) {
+	callerID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+	from, to, ok := parseRange(w, r)
+	if !ok {
+		return
+	}
+	filter := models.AppointmentFilter{From: from, To: to, Status: r.URL.Query().Get("status")}
+	setFilter(&filter, callerID)
+
+	appointments, err := h.appointmentService.ListAppointments(r.Context(), filter)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidInput) {
+			writeError(w, http.StatusBadRequest, models.ErrorCodeValidationFailed, serviceMessage(err))
+		} else {
+			logger.FromContext(r.Context()).Errorf("Error listing appointments for %s: %v", callerID, err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to list appointments")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, appointments)
+}
+
+// ListProviderAppointments handles GET /provider/appointments?from=&to=&status= requests.
+func (h *AppointmentHandler) ListProviderAppointments(w http.ResponseWriter, r *http.Request) {
+	h.listAppointments(w, r, func(f *models.AppointmentFilter, id uuid.UUID) { f.ProviderID = id })
+}
+
+// ListMyAppointments handles GET /me/appointments?from=&to=&status= requests.
+func (h *AppointmentHandler) ListMyAppointments(w http.ResponseWriter, r *http.Request) {
+	h.listAppointments(w, r, func(f *models.AppointmentFilter, id uuid.UUID) { f.UserID = id })
+}
+
+// Book handles POST /appointments, booking an open slot.
+func (h *AppointmentHandler) Book(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+	var req models.BookAppointmentRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	appointment, err := h.appointmentService.Book(r.Context(), userID, req)
+	if err != nil {
+		if !writeAppointmentError(w, err) {
+			logger.FromContext(r.Context()).Errorf("Error booking slot %s for user %s: %v", req.SlotID, userID, err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to book appointment")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	writeBody(w, appointment)
+}
+
+// GetAppointment handles GET /appointments/{id} for the booking user and the provider.
+func (h *AppointmentHandler) GetAppointment(w http.ResponseWriter, r *http.Request) {
+	callerID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid appointment ID format")
+		return
+	}
+
+	appointment, err := h.appointmentService.GetAppointment(r.Context(), callerID, id)
+	if err != nil {
+		if !writeAppointmentError(w, err) {
+			logger.FromContext(r.Context()).Errorf("Error getting appointment %s: %v", id, err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to get appointment")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, appointment)
+}
+
+// Cancel handles POST /appointments/{id}/cancel by either participant.
+func (h *AppointmentHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	callerID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid appointment ID format")
+		return
+	}
+	var req models.CancelAppointmentRequest
+	if r.ContentLength != 0 { // The reason is optional, so an empty body is fine
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.FromContext(r.Context()).Debugf("Invalid request payload for cancellation: %v", err)
+			writeInvalidBody(w, err)
+			return
+		}
+		if !validateRequest(w, r, &req) {
+			return
+		}
+	}
+
+	appointment, err := h.appointmentService.Cancel(r.Context(), callerID, id, req)
+	if err != nil {
+		if !writeAppointmentError(w, err) {
+			logger.FromContext(r.Context()).Errorf("Error cancelling appointment %s: %v", id, err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to cancel appointment")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, appointment)
+}
+
+// Reschedule handles POST /appointments/{id}/reschedule, answering with the replacement appointment.
+func (h *AppointmentHandler) Reschedule(w http.ResponseWriter, r *http.Request) {
+	callerID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid appointment ID format")
+		return
+	}
+	var req models.RescheduleAppointmentRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	appointment, err := h.appointmentService.Reschedule(r.Context(), callerID, id, req)
+	if err != nil {
+		if !writeAppointmentError(w, err) {
+			logger.FromContext(r.Context()).Errorf("Error rescheduling appointment %s: %v", id, err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to reschedule appointment")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	writeBody(w, appointment)
+}
+
+// Invite handles GET /appointments/{id}/invite.ics, the appointment as an iCalendar file.
+func (h *AppointmentHandler) Invite(w http.ResponseWriter, r *http.Request) {
+	callerID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid appointment ID format")
+		return
+	}
+
+	invite, method, err := h.appointmentService.Invite(r.Context(), callerID, id)
+	if err != nil {
+		if !writeAppointmentError(w, err) {
+			logger.FromContext(r.Context()).Errorf("Error rendering invite of appointment %s: %v", id, err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to render invite")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", ics.ContentType(method))
+	w.Header().Set("Content-Disposition", `attachment; filename="appointment.ics"`)
+	w.WriteHeader(http.StatusOK)
+	w.Write(invite)
+}
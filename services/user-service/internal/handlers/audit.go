@@ -0,0 +1,44 @@
+// services/user-service/internal/handlers/audit.go
+package handlers
+
+import (
+	"net/http"
+
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/services"
+)
+
+// maxAuditUserAgent bounds the user agent stored per audit event or login attempt.
+const maxAuditUserAgent = 512
+
+// Auditor records security-relevant actions together with the caller's network origin.
+type Auditor struct {
+	service   services.AuditService
+	proxyHops int // Trusted proxies to read the client IP behind in X-Forwarded-For, as rate limiting does
+}
+
+// NewAuditor creates a new Auditor instance.
+func NewAuditor(service services.AuditService, proxyHops int) *Auditor {
+	return &Auditor{service: service, proxyHops: proxyHops}
+}
+
+// Record audits event for request r. The IP and user agent are taken from the request, and
+// ActorID defaults to the authenticated caller, so it only needs to be set for sign-ins.
+func (a *Auditor) Record(r *http.Request, event models.AuditEvent) {
+	if event.ActorID == "" {
+		event.ActorID, _ = r.Context().Value(UserContextKey).(string)
+	}
+	client := a.client(r)
+	event.IP = client.IP
+	event.UserAgent = client.UserAgent
+	a.service.Record(r.Context(), event)
+}
+
+// client returns the network origin of r, as recorded in the audit log and login history.
+func (a *Auditor) client(r *http.Request) models.ClientInfo {
+	userAgent := r.UserAgent()
+	if len(userAgent) > maxAuditUserAgent {
+		userAgent = userAgent[:maxAuditUserAgent]
+	}
+	return models.ClientInfo{IP: clientIP(r, a.proxyHops), UserAgent: userAgent}
+}
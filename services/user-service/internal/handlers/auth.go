@@ -3,120 +3,327 @@ package handlers
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/captcha"
+	"health-tracker-project/services/user-service/internal/config"
+	"health-tracker-project/services/user-service/internal/errreport"
 	"health-tracker-project/services/user-service/internal/models"
 	"health-tracker-project/services/user-service/internal/services"
 	"health-tracker-project/services/user-service/internal/utils/jwt"
 	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+	"health-tracker-project/services/user-service/internal/utils/reqctx"
 )
 
 // ContextKey type for storing values in request context.
 type ContextKey string
 
-const UserContextKey ContextKey = "user" // Key to store user ID in context
+const (
+	UserContextKey    ContextKey = "user"    // Key to store user ID in context
+	RoleContextKey    ContextKey = "role"    // Key to store the user's role in context
+	ScopesContextKey  ContextKey = "scopes"  // Key to store the user's permission scopes in context
+	SessionContextKey ContextKey = "session" // Key to store the token's session ID in context
+	ExpiresContextKey ContextKey = "expires" // Key to store the token's expiry time in context
+	AppContextKey     ContextKey = "app"     // Key to store the developer app ID of public API requests in context
+)
 
 // AuthHandlers holds dependencies for authentication HTTP handlers.
 type AuthHandlers struct {
 	authService services.AuthService // Depends on the AuthService interface
+	auditor     *Auditor             // Records sign-ins, sign-outs, and password changes
+	captcha     captcha.Verifier     // Nil when no CAPTCHA provider is configured
 }
 
-// NewAuthHandlers creates a new AuthHandlers instance.
-func NewAuthHandlers(authService services.AuthService) *AuthHandlers {
-	return &AuthHandlers{authService: authService}
+// NewAuthHandlers creates a new AuthHandlers instance. verifier may be nil.
+func NewAuthHandlers(authService services.AuthService, auditor *Auditor, verifier captcha.Verifier) *AuthHandlers {
+	return &AuthHandlers{authService: authService, auditor: auditor, captcha: verifier}
 }
 
 // Register handles HTTP requests for new user registration.
 func (h *AuthHandlers) Register(w http.ResponseWriter, r *http.Request) {
 	var req models.RegisterRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		logger.Logger.Debugf("Invalid request payload for register: %v", err)
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if !h.checkCaptcha(w, r, config.CaptchaRegister, req.CaptchaToken) {
 		return
 	}
 
-	userResponse, err := h.authService.RegisterUser(req) // Call the service layer
+	userResponse, err := h.authService.RegisterUser(r.Context(), req) // Call the service layer
 	if err != nil {
 		// Map service-level errors to appropriate HTTP status codes
-		if err.Error() == "service: user with this email already exists" {
-			logger.Logger.Warnf("Registration failed: %v", err)
-			http.Error(w, err.Error(), http.StatusConflict) // 409 Conflict
-		} else if err.Error() == "service: name, email, and password are required" {
-			logger.Logger.Warnf("Registration failed: %v", err)
-			http.Error(w, err.Error(), http.StatusBadRequest) // 400 Bad Request
+		if errors.Is(err, services.ErrDuplicateEmail) {
+			logger.FromContext(r.Context()).Warnf("Registration failed: %v", err)
+			writeError(w, http.StatusConflict, conflictCode(err), serviceMessage(err)) // 409 Conflict
+		} else if errors.Is(err, services.ErrInvalidInput) {
+			logger.FromContext(r.Context()).Warnf("Registration failed: %v", err)
+			writeError(w, http.StatusBadRequest, models.ErrorCodeValidationFailed, serviceMessage(err)) // 400 Bad Request
+		} else if errors.Is(err, services.ErrConflict) {
+			logger.FromContext(r.Context()).Warnf("Registration failed: %v", err)
+			writeError(w, http.StatusConflict, conflictCode(err), serviceMessage(err))
 		} else {
-			logger.Logger.Errorf("Error registering user: %v", err)
-			http.Error(w, "Failed to register user", http.StatusInternalServerError)
+			logger.FromContext(r.Context()).Errorf("Error registering user: %v", err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to register user")
 		}
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	if userResponse == nil {
+		// Privacy mode: identical response whether or not the email was already registered.
+		w.WriteHeader(http.StatusAccepted)
+		writeBody(w, map[string]string{"message": "Registration received. Check your email to continue."})
+		logger.FromContext(r.Context()).Info("Registration accepted in privacy mode.")
+		return
+	}
+	h.auditor.Record(r, models.AuditEvent{
+		Action:   models.AuditUserCreate,
+		Outcome:  models.AuditSuccess,
+		ActorID:  userResponse.ID.String(),
+		TargetID: userResponse.ID.String(),
+		Details:  map[string]string{"method": "register"},
+	})
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(userResponse)
-	logger.Logger.Infof("User registered successfully: %s", userResponse.ID)
+	writeBody(w, userResponse)
+	logger.FromContext(r.Context()).Infof("User registered successfully: %s", userResponse.ID)
 }
 
 // Login handles HTTP requests for user login.
 func (h *AuthHandlers) Login(w http.ResponseWriter, r *http.Request) {
 	var req models.LoginRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		logger.Logger.Debugf("Invalid request payload for login: %v", err)
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if !h.checkCaptcha(w, r, config.CaptchaLogin, req.CaptchaToken) {
 		return
 	}
 
-	authResponse, err := h.authService.AuthenticateUser(req) // Call the service layer
+	req.Client = h.auditor.client(r)
+	authResponse, err := h.authService.AuthenticateUser(r.Context(), req) // Call the service layer
 	if err != nil {
-		if err.Error() == "service: invalid credentials" {
-			logger.Logger.Warnf("Authentication failed for email '%s': %v", req.Email, err)
-			http.Error(w, err.Error(), http.StatusUnauthorized) // 401 Unauthorized
-		} else if err.Error() == "service: email and password are required" {
-			logger.Logger.Warnf("Authentication failed (missing fields): %v", err)
-			http.Error(w, err.Error(), http.StatusBadRequest) // 400 Bad Request
+		if errors.Is(err, services.ErrInvalidCredentials) {
+			logger.FromContext(r.Context()).Warnf("Authentication failed for '%s': %v", loginIdentifier(req), err)
+			h.auditLoginFailure(r, req, "invalid credentials")
+			writeError(w, http.StatusUnauthorized, models.ErrorCodeInvalidCredentials, serviceMessage(err)) // 401 Unauthorized
+		} else if errors.Is(err, services.ErrInvalidInput) {
+			logger.FromContext(r.Context()).Warnf("Authentication failed (missing fields): %v", err)
+			writeError(w, http.StatusBadRequest, models.ErrorCodeValidationFailed, serviceMessage(err)) // 400 Bad Request
+		} else if errors.Is(err, services.ErrAccountInactive) {
+			h.auditLoginFailure(r, req, strings.TrimPrefix(err.Error(), "service: "))
+			writeError(w, http.StatusForbidden, models.ErrorCodeAccountInactive, serviceMessage(err)) // 403 Forbidden: suspended or deactivated
 		} else {
-			logger.Logger.Errorf("Error during login for email '%s': %v", req.Email, err)
-			http.Error(w, "Failed to authenticate", http.StatusInternalServerError)
+			logger.FromContext(r.Context()).Errorf("Error during login for '%s': %v", loginIdentifier(req), err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to authenticate")
 		}
 		return
 	}
 
-	// Set HttpOnly cookie for the JWT token
+	h.auditor.Record(r, models.AuditEvent{
+		Action:   models.AuditLogin,
+		Outcome:  models.AuditSuccess,
+		ActorID:  authResponse.User.ID.String(),
+		TargetID: authResponse.User.ID.String(),
+		Details:  map[string]string{"method": "password"},
+	})
+	setAuthCookie(w, authResponse)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, authResponse)
+	logger.FromContext(r.Context()).Infof("User logged in successfully: %s", authResponse.User.ID)
+}
+
+// auditLoginFailure records a rejected password sign-in. The account is identified only by the
+// submitted email or username, since a failed attempt may not match any user.
+func (h *AuthHandlers) auditLoginFailure(r *http.Request, req models.LoginRequest, reason string) {
+	details := map[string]string{"method": "password", "reason": reason}
+	if login := loginIdentifier(req); strings.Contains(login, "@") {
+		details["email"] = login
+	} else {
+		details["username"] = login
+	}
+	h.auditor.Record(r, models.AuditEvent{
+		Action:  models.AuditLogin,
+		Outcome: models.AuditFailure,
+		Details: details,
+	})
+}
+
+// loginIdentifier returns what a login request names its account by: the username if one was sent,
+// otherwise the email field, which may also hold a username.
+func loginIdentifier(req models.LoginRequest) string {
+	if req.Username != "" {
+		return req.Username
+	}
+	return req.Email
+}
+
+// setAuthCookie sets the HttpOnly cookie carrying the JWT for a successful sign-in.
+// Its name, domain, Secure, and SameSite attributes come from the environment (see config.LoadCookies).
+func setAuthCookie(w http.ResponseWriter, authResponse *models.AuthResponse) {
+	c := config.CookieSettings()
 	http.SetCookie(w, &http.Cookie{
-		Name:     "jwt_token",
+		Name:     c.Name,
 		Value:    authResponse.Token,
 		Expires:  time.Now().Add(time.Duration(authResponse.ExpiresInSec) * time.Second),
-		HttpOnly: true,                 // Crucial for security (prevents JS access)
-		Secure:   false,                // Set to 'true' in production with HTTPS (e.g., in a Dockerfile or deployment config)
-		SameSite: http.SameSiteLaxMode, // Adjust as needed (Strict, Lax, None). Use http.SameSiteNone and Secure:true for cross-origin if frontend is on different domain/port.
-		Path:     "/",                  // Available to all paths
+		HttpOnly: true, // Crucial for security (prevents JS access)
+		Secure:   c.Secure,
+		SameSite: c.SameSite,
+		Domain:   c.Domain,
+		Path:     "/", // Available to all paths
 	})
+}
+
+// Logout handles HTTP requests for user logout by ending the session and clearing the JWT cookie.
+func (h *AuthHandlers) Logout(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value(UserContextKey).(string)
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		logger.FromContext(r.Context()).Errorf("Invalid user ID in context: %v", err)
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeInvalidToken, "Unauthorized: Invalid token")
+		return
+	}
+	sessionID, err := uuid.Parse(r.Context().Value(SessionContextKey).(string))
+	if err != nil {
+		logger.FromContext(r.Context()).Errorf("Invalid session ID in context: %v", err)
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeInvalidToken, "Unauthorized: Invalid token")
+		return
+	}
+	if err := h.authService.Logout(r.Context(), uid, sessionID); err != nil {
+		writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to log out")
+		return
+	}
+
+	clearAuthCookie(w)
+	h.auditor.Record(r, models.AuditEvent{Action: models.AuditLogout, Outcome: models.AuditSuccess, TargetID: userID})
 
-	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(authResponse)
-	logger.Logger.Infof("User logged in successfully: %s", authResponse.User.ID)
+	writeBody(w, map[string]string{"message": "Logged out successfully"})
+	logger.FromContext(r.Context()).Info("User logged out successfully.")
 }
 
-// Logout handles HTTP requests for user logout by clearing the JWT cookie.
-func (h *AuthHandlers) Logout(w http.ResponseWriter, r *http.Request) {
-	// Invalidate the JWT cookie by setting an expired cookie
+// clearAuthCookie invalidates the JWT cookie by setting an expired cookie.
+// The attributes must match setAuthCookie, or browsers keep the original cookie.
+func clearAuthCookie(w http.ResponseWriter) {
+	c := config.CookieSettings()
 	http.SetCookie(w, &http.Cookie{
-		Name:     "jwt_token",
+		Name:     c.Name,
 		Value:    "",
 		Expires:  time.Unix(0, 0), // Set expiry to past
 		HttpOnly: true,
-		Secure:   false, // Set to 'true' in production with HTTPS
-		SameSite: http.SameSiteLaxMode,
+		Secure:   c.Secure,
+		SameSite: c.SameSite,
+		Domain:   c.Domain,
 		Path:     "/",
 	})
+}
+
+// ForgotPassword handles HTTP requests to start a password reset.
+// It always responds 202 so callers cannot tell whether the email is registered.
+func (h *AuthHandlers) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req models.ForgotPasswordRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	if err := h.authService.RequestPasswordReset(r.Context(), req); err != nil {
+		if errors.Is(err, services.ErrInvalidInput) {
+			logger.FromContext(r.Context()).Warnf("Forgot password failed: %v", err)
+			writeError(w, http.StatusBadRequest, models.ErrorCodeValidationFailed, serviceMessage(err))
+			return
+		}
+		// Log but don't reveal the failure; the response must look the same for every email.
+		logger.FromContext(r.Context()).Errorf("Error requesting password reset: %v", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	writeBody(w, map[string]string{"message": "If an account exists for this email, a reset code has been sent."})
+}
+
+// ResetPassword handles HTTP requests to complete a password reset with a token.
+func (h *AuthHandlers) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req models.ResetPasswordRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	userID, err := h.authService.ResetPassword(r.Context(), req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidToken) || errors.Is(err, services.ErrInvalidInput) {
+			logger.FromContext(r.Context()).Warnf("Password reset failed: %v", err)
+			h.auditor.Record(r, models.AuditEvent{
+				Action:  models.AuditPasswordChange,
+				Outcome: models.AuditFailure,
+				Details: map[string]string{"method": "reset_token", "reason": strings.TrimPrefix(err.Error(), "service: ")},
+			})
+			code := models.ErrorCodeValidationFailed
+			if errors.Is(err, services.ErrInvalidToken) {
+				code = models.ErrorCodeInvalidToken
+			}
+			writeError(w, http.StatusBadRequest, code, serviceMessage(err))
+		} else {
+			logger.FromContext(r.Context()).Errorf("Error resetting password: %v", err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to reset password")
+		}
+		return
+	}
+	h.auditor.Record(r, models.AuditEvent{
+		Action:   models.AuditPasswordChange,
+		Outcome:  models.AuditSuccess,
+		TargetID: userID.String(),
+		Details:  map[string]string{"method": "reset_token"},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, map[string]string{"message": "Password has been reset. Please log in again."})
+	logger.FromContext(r.Context()).Info("Password reset completed.")
+}
+
+// GetLoginHistory handles GET /users/me/logins?cursor=&limit= requests, listing the caller's
+// recent sign-in attempts newest first. The Link header holds the URL of the next page.
+func (h *AuthHandlers) GetLoginHistory(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+
+	q := r.URL.Query()
+	filter := models.LoginAttemptFilter{UserID: userID}
+	if filter.After, err = pageAfter(r); err != nil {
+		writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidCursor, "Invalid 'cursor'")
+		return
+	}
+	if v := q.Get("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil {
+			writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid 'limit', expected an integer")
+			return
+		}
+	}
+
+	attempts, err := h.authService.GetLoginHistory(r.Context(), filter)
+	if err != nil {
+		logger.FromContext(r.Context()).Errorf("Error getting login history for user %s: %v", userID, err)
+		writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to get login history")
+		return
+	}
 
+	if len(attempts) > 0 {
+		last := attempts[len(attempts)-1]
+		setNextPage(w, r, models.PageKey{At: last.CreatedAt, ID: last.ID})
+	}
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{"message": "Logged out successfully"})
-	logger.Logger.Info("User logged out successfully.")
+	writeBody(w, attempts)
 }
 
 // ProtectedRoute is an example handler that demonstrates JWT authentication.
@@ -125,45 +332,89 @@ func (h *AuthHandlers) ProtectedRoute(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value(UserContextKey).(string)
 	if !ok {
 		// This case should ideally not be reached if AuthMiddleware is correctly applied
-		logger.Logger.Error("User ID not found in context for protected route, middleware error?")
-		http.Error(w, "Internal server error: User ID not found in context", http.StatusInternalServerError)
+		logger.FromContext(r.Context()).Error("User ID not found in context for protected route, middleware error?")
+		writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Internal server error: User ID not found in context")
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{"message": fmt.Sprintf("Welcome to the protected area, User ID: %s!", userID)})
-	logger.Logger.Debugf("Accessed protected route by User ID: %s", userID)
+	writeBody(w, map[string]string{"message": fmt.Sprintf("Welcome to the protected area, User ID: %s!", userID)})
+	logger.FromContext(r.Context()).Debugf("Accessed protected route by User ID: %s", userID)
+}
+
+// JWKS serves the public signing keys so other services can verify tokens locally.
+func JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, jwt.JWKS())
+	logger.FromContext(r.Context()).Debug("JWKS requested.")
 }
 
 // AuthMiddleware is an HTTP middleware for JWT authentication.
-func AuthMiddleware(next http.Handler) http.Handler {
+// Tokens are validated through the AuthService so revoked sessions are rejected.
+func (h *AuthHandlers) AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		cookie, err := r.Cookie("jwt_token")
+		cookie, err := r.Cookie(config.CookieSettings().Name)
 		if err != nil {
 			if err == http.ErrNoCookie {
-				logger.Logger.Debug("Unauthorized: No JWT token cookie found.")
-				http.Error(w, "Unauthorized: No token provided", http.StatusUnauthorized)
+				logger.FromContext(r.Context()).Debug("Unauthorized: No JWT token cookie found.")
+				writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized: No token provided")
 				return
 			}
-			logger.Logger.Warnf("Bad request: error reading JWT cookie: %v", err)
-			http.Error(w, "Bad request", http.StatusBadRequest) // Malformed cookie header
+			logger.FromContext(r.Context()).Warnf("Bad request: error reading JWT cookie: %v", err)
+			writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidToken, "Bad request") // Malformed cookie header
 			return
 		}
 
 		tokenString := cookie.Value
-		claims, err := jwt.ParseJWT(tokenString) // Validate token using JWT utility
+		claims, err := h.authService.ValidateToken(r.Context(), tokenString) // Validate signature, expiry, and revocation
 		if err != nil {
-			logger.Logger.Warnf("Unauthorized: Invalid JWT token: %v", err)
-			http.Error(w, "Unauthorized: Invalid token", http.StatusUnauthorized)
+			logger.FromContext(r.Context()).Warnf("Unauthorized: Invalid JWT token: %v", err)
+			writeError(w, http.StatusUnauthorized, models.ErrorCodeInvalidToken, "Unauthorized: Invalid token")
 			return
 		}
 
 		// Add user ID (from JWT claims) to the request context for downstream handlers.
 		ctx := r.Context()
 		ctx = context.WithValue(ctx, UserContextKey, claims.UserID)
+		ctx = context.WithValue(ctx, RoleContextKey, claims.Role)
+		ctx = context.WithValue(ctx, ScopesContextKey, claims.Scopes)
+		ctx = context.WithValue(ctx, SessionContextKey, claims.ID)
+		if claims.ExpiresAt != nil {
+			ctx = context.WithValue(ctx, ExpiresContextKey, claims.ExpiresAt.Time)
+		}
+		ctx = reqctx.WithUserID(ctx, claims.UserID) // Propagate the verified ID, not the client-supplied header
+		errreport.SetUser(ctx, claims.UserID)       // Attach the verified ID to any error report for this request
 		r = r.WithContext(ctx)
 
-		logger.Logger.Debugf("JWT authentication successful for User ID: %s", claims.UserID)
+		logger.FromContext(ctx).Debugf("JWT authentication successful for User ID: %s", claims.UserID)
 		next.ServeHTTP(w, r)
 	})
-}
\ No newline at end of file
+}
+
+// hasScope reports whether the authenticated caller was granted scope.
+func hasScope(r *http.Request, scope string) bool {
+	scopes, _ := r.Context().Value(ScopesContextKey).([]string)
+	return slices.Contains(scopes, scope)
+}
+
+// RequireScope is an HTTP middleware that only allows callers holding scope.
+// It must be chained after AuthMiddleware, which places the scopes in the context.
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !hasScope(r, scope) {
+				logger.FromContext(r.Context()).Warnf("Forbidden: missing scope %s for %s %s", scope, r.Method, r.URL.Path)
+				writeError(w, http.StatusForbidden, models.ErrorCodeMissingScope, "Forbidden: missing required scope "+scope)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireAdmin is an HTTP middleware that only allows callers with the admin scope.
+func RequireAdmin(next http.Handler) http.Handler {
+	return RequireScope(models.ScopeAdmin)(next)
+}
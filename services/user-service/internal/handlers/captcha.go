@@ -0,0 +1,40 @@
+// services/user-service/internal/handlers/captcha.go
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"health-tracker-project/services/user-service/internal/captcha"
+	"health-tracker-project/services/user-service/internal/config"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// checkCaptcha verifies the request's CAPTCHA token when the runtime config requires one on the endpoint.
+// It writes the error response and returns false when the request must not proceed.
+// A provider that cannot be reached fails closed, so an outage cannot be used to bypass the check.
+// Synthetic journeys are made in-process and never need one.
+func (h *AuthHandlers) checkCaptcha(w http.ResponseWriter, r *http.Request, endpoint, token string) bool {
+	if h.captcha == nil || !config.CaptchaRequiredFor(endpoint) || isSynthetic(r) {
+		return true
+	}
+	if token == "" {
+		logger.FromContext(r.Context()).Debugf("Rejected %s request without a CAPTCHA token.", endpoint)
+		writeError(w, http.StatusBadRequest, models.ErrorCodeCaptchaRequired, "captcha_token is required")
+		return false
+	}
+
+	err := h.captcha.Verify(r.Context(), token, h.auditor.client(r).IP)
+	if errors.Is(err, captcha.ErrRejected) {
+		logger.FromContext(r.Context()).Warnf("CAPTCHA rejected on %s: %v", endpoint, err)
+		writeError(w, http.StatusForbidden, models.ErrorCodeCaptchaFailed, "CAPTCHA verification failed")
+		return false
+	}
+	if err != nil {
+		logger.FromContext(r.Context()).Errorf("CAPTCHA verification unavailable on %s: %v", endpoint, err)
+		writeError(w, http.StatusServiceUnavailable, models.ErrorCodeUnavailable, "CAPTCHA verification is temporarily unavailable")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,203 @@
+// services/user-service/internal/handlers/consent.go
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/services"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// ConsentHandler holds dependencies for third-party integration consent handlers.
+type ConsentHandler struct {
+	consentService services.ConsentService
+	auditor        *Auditor
+}
+
+// NewConsentHandler creates a new ConsentHandler instance.
+func NewConsentHandler(consentService services.ConsentService, auditor *Auditor) *ConsentHandler {
+	return &ConsentHandler{consentService: consentService, auditor: auditor}
+}
+
+// ListIntegrations handles GET /integrations, listing each integration's terms and data flows.
+func (h *ConsentHandler) ListIntegrations(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, h.consentService.ListIntegrations())
+}
+
+// ListConsents handles GET /me/integrations/consents, the caller's consent history.
+func (h *ConsentHandler) ListConsents(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+
+	consents, err := h.consentService.ListConsents(r.Context(), userID)
+	if err != nil {
+		logger.FromContext(r.Context()).Errorf("Error listing consents for user %s: %v", userID, err)
+		writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to list consents")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, consents)
+}
+
+// GrantConsent handles PUT /me/integrations/{provider}/consent, accepting the integration's current terms.
+func (h *ConsentHandler) GrantConsent(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+	var req models.GrantConsentRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	provider := r.PathValue("provider")
+	consent, err := h.consentService.GrantConsent(r.Context(), userID, provider, req, h.auditor.client(r))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrNotFound):
+			writeError(w, http.StatusNotFound, models.ErrorCodeIntegrationNotFound, "Unknown integration")
+		case errors.Is(err, services.ErrInvalidInput):
+			writeError(w, http.StatusBadRequest, models.ErrorCodeValidationFailed, "Consent must be explicitly accepted")
+		case errors.Is(err, services.ErrConflict): // The terms changed, or consent was already granted
+			writeError(w, http.StatusConflict, models.ErrorCodeConflict, serviceMessage(err))
+		default:
+			logger.FromContext(r.Context()).Errorf("Error recording consent for user %s: %v", userID, err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to record consent")
+		}
+		return
+	}
+
+	h.auditor.Record(r, models.AuditEvent{
+		Action:   models.AuditConsentGrant,
+		Outcome:  models.AuditSuccess,
+		ActorID:  userID.String(),
+		TargetID: userID.String(),
+		Details:  map[string]string{"provider": provider, "terms_version": consent.TermsVersion},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	writeBody(w, consent)
+}
+
+// RevokeConsent handles DELETE /me/integrations/{provider}/consent?delete_data=true|false.
+// Syncing stops; with delete_data=true the data imported from the provider is deleted too.
+func (h *ConsentHandler) RevokeConsent(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+	deleteData := false
+	if v := r.URL.Query().Get("delete_data"); v != "" {
+		if deleteData, err = strconv.ParseBool(v); err != nil {
+			writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid 'delete_data', expected true or false")
+			return
+		}
+	}
+
+	provider := r.PathValue("provider")
+	consent, err := h.consentService.RevokeConsent(r.Context(), userID, provider, deleteData)
+	if err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			writeError(w, http.StatusNotFound, models.ErrorCodeConsentNotFound, "No active consent for this integration")
+		} else {
+			logger.FromContext(r.Context()).Errorf("Error revoking consent for user %s: %v", userID, err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to revoke consent")
+		}
+		return
+	}
+
+	h.auditor.Record(r, models.AuditEvent{
+		Action:   models.AuditConsentRevoke,
+		Outcome:  models.AuditSuccess,
+		ActorID:  userID.String(),
+		TargetID: userID.String(),
+		Details:  map[string]string{"provider": provider, "delete_data": strconv.FormatBool(deleteData)},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, consent)
+}
+
+// CheckConsent handles GET /admin/users/{id}/integrations/{provider}/consent. The sync-service calls
+// it before linking a provider and before each sync; anything but 200 means no data may flow.
+func (h *ConsentHandler) CheckConsent(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid user ID format")
+		return
+	}
+
+	consent, err := h.consentService.CheckConsent(r.Context(), id, r.PathValue("provider"))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrNotFound): // Unknown integration, or no active consent
+			writeError(w, http.StatusNotFound, models.ErrorCodeNotFound, serviceMessage(err))
+		case errors.Is(err, services.ErrConflict):
+			writeError(w, http.StatusConflict, models.ErrorCodeConflict, "Consent is for outdated terms")
+		default:
+			logger.FromContext(r.Context()).Errorf("Error checking consent for user %s: %v", id, err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to check consent")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, consent)
+}
+
+// ListRevocations handles GET /admin/integrations/revocations?since=&cursor=&limit= requests. since is
+// an RFC 3339 timestamp where the list starts; the Link header holds the URL of the next page.
+func (h *ConsentHandler) ListRevocations(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	var filter models.ConsentRevocationFilter
+
+	var err error
+	if v := q.Get("since"); v != "" {
+		if filter.Since, err = time.Parse(time.RFC3339Nano, v); err != nil {
+			writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid 'since' timestamp, expected RFC 3339")
+			return
+		}
+	}
+	if filter.After, err = pageAfter(r); err != nil {
+		writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidCursor, "Invalid 'cursor'")
+		return
+	}
+	if v := q.Get("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil {
+			writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid 'limit', expected an integer")
+			return
+		}
+	}
+
+	revocations, err := h.consentService.ListRevocations(r.Context(), filter)
+	if err != nil {
+		logger.FromContext(r.Context()).Errorf("Error listing consent revocations: %v", err)
+		writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to list revocations")
+		return
+	}
+
+	if len(revocations) > 0 {
+		last := revocations[len(revocations)-1]
+		setNextPage(w, r, models.PageKey{At: *last.RevokedAt, ID: last.ID})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, revocations)
+}
@@ -0,0 +1,168 @@
+// services/user-service/internal/handlers/content_negotiation.go
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/utils/codec"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// maxTranscodedRequestBytes bounds request bodies sent in a format other than JSON, which are
+// read whole to be converted.
+const maxTranscodedRequestBytes = 4 << 20
+
+// ContentNegotiation is an HTTP middleware that lets callers exchange the formats registered in
+// package codec, such as MessagePack and Protobuf, instead of JSON on every route. A request body
+// in one of them, by its Content-Type, reaches the handlers converted to JSON. JSON responses are
+// sent in the format Accept prefers: handlers writing their DTO with writeBody have it encoded in
+// that format directly, and JSON written any other way is converted. Other responses, such as file
+// downloads and event streams, pass through. JSON stays the default.
+func ContentNegotiation(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept")
+		if c := codec.Negotiate(r.Header.Get("Accept")); c != codec.JSON {
+			tw := &transcodingWriter{ResponseWriter: w, codec: c, r: r}
+			defer tw.finish()
+			w = tw
+		}
+
+		if c, ok := codec.Lookup(r.Header.Get("Content-Type")); ok && c != codec.JSON {
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxTranscodedRequestBytes))
+			if err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					writeError(w, http.StatusRequestEntityTooLarge, models.ErrorCodePayloadTooLarge, "Request body too large")
+				} else {
+					writeInvalidBody(w, err)
+				}
+				return
+			}
+			data, err := codec.ToJSON(c, body)
+			if err != nil {
+				logger.FromContext(r.Context()).Debugf("Invalid %s request payload for %s %s: %v", c.ContentType(), r.Method, r.URL.Path, err)
+				writeInvalidBody(w, err)
+				return
+			}
+			r = r.Clone(r.Context())
+			r.Body = io.NopCloser(bytes.NewReader(data))
+			r.ContentLength = int64(len(data))
+			r.Header.Set("Content-Type", "application/json")
+			r.Header.Del("Content-Length")
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// writeBody writes v, the DTO of a JSON response whose header was written, as its body. When
+// ContentNegotiation picked another format for the request, v is encoded in it straight away, without
+// going through JSON; otherwise, or if v cannot be encoded in it, it is written as JSON.
+func writeBody(w http.ResponseWriter, v any) {
+	if t := transcoderOf(w); t != nil && t.buffering && !t.encoded && t.body.Len() == 0 {
+		data, err := codec.Marshal(t.codec, v)
+		if err == nil {
+			t.encoded = true
+			w.Header().Set("Content-Type", t.codec.ContentType())
+			w.Write(data)
+			return
+		}
+		logger.FromContext(t.r.Context()).Errorf("Failed to encode the response to %s %s as %s: %v", t.r.Method, t.r.URL.Path, t.codec.ContentType(), err)
+	}
+	json.NewEncoder(w).Encode(v)
+}
+
+// transcoderOf finds the transcodingWriter among the writers w wraps, if the request has one.
+func transcoderOf(w http.ResponseWriter) *transcodingWriter {
+	for {
+		switch rw := w.(type) {
+		case *transcodingWriter:
+			return rw
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = rw.Unwrap()
+		default:
+			return nil
+		}
+	}
+}
+
+// transcodingWriter holds back a JSON response to write it in codec's format once the handler is
+// done. Responses of any other type are written through as they come.
+type transcodingWriter struct {
+	http.ResponseWriter
+	codec       codec.Codec
+	r           *http.Request
+	status      int
+	buffering   bool
+	encoded     bool // The body held back was encoded in codec's format by writeBody
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (t *transcodingWriter) WriteHeader(status int) {
+	if t.wroteHeader {
+		return
+	}
+	if status >= 100 && status < 200 { // Informational responses come before the real one
+		t.ResponseWriter.WriteHeader(status)
+		return
+	}
+	t.wroteHeader = true
+	mt, _, _ := mime.ParseMediaType(t.Header().Get("Content-Type"))
+	if mt == "application/json" {
+		t.status, t.buffering = status, true
+		return
+	}
+	t.ResponseWriter.WriteHeader(status)
+}
+
+func (t *transcodingWriter) Write(p []byte) (int, error) {
+	if !t.wroteHeader {
+		t.WriteHeader(http.StatusOK)
+	}
+	if t.buffering {
+		return t.body.Write(p)
+	}
+	return t.ResponseWriter.Write(p)
+}
+
+// Flush sends what was written so far, unless the response is being held back.
+func (t *transcodingWriter) Flush() {
+	if f, ok := t.ResponseWriter.(http.Flusher); ok && !t.buffering {
+		f.Flush()
+	}
+}
+
+func (t *transcodingWriter) Unwrap() http.ResponseWriter {
+	return t.ResponseWriter
+}
+
+// finish writes a held-back response in the negotiated format, converting it from JSON unless
+// writeBody encoded it. If it cannot be converted, which only a handler writing invalid JSON causes,
+// it is sent as JSON.
+func (t *transcodingWriter) finish() {
+	if !t.buffering {
+		return
+	}
+	h := t.Header()
+	if t.body.Len() > 0 {
+		if t.encoded {
+			h.Set("Content-Type", t.codec.ContentType())
+		} else if data, err := codec.FromJSON(t.codec, t.body.Bytes()); err != nil {
+			logger.FromContext(t.r.Context()).Errorf("Failed to encode the response to %s %s as %s: %v", t.r.Method, t.r.URL.Path, t.codec.ContentType(), err)
+		} else {
+			h.Set("Content-Type", t.codec.ContentType())
+			t.body.Reset()
+			t.body.Write(data)
+		}
+		h.Set("Content-Length", strconv.Itoa(t.body.Len()))
+	}
+	t.ResponseWriter.WriteHeader(t.status)
+	t.ResponseWriter.Write(t.body.Bytes())
+}
@@ -0,0 +1,37 @@
+// services/user-service/internal/handlers/cors.go
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"health-tracker-project/services/user-service/internal/config"
+	"health-tracker-project/services/user-service/internal/utils/reqctx"
+)
+
+// CORS is an HTTP middleware that applies the allowed origins from the runtime config.
+// Origins are re-read on every request so config reloads take effect immediately.
+func CORS(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		allowed := config.Current().CORSAllowedOrigins
+		if origin != "" && (slices.Contains(allowed, origin) || slices.Contains(allowed, "*")) {
+			// Echo the origin rather than "*", since the JWT cookie requires credentialed requests.
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+			w.Header().Add("Vary", "Origin")
+			w.Header().Set("Access-Control-Expose-Headers", reqctx.HeaderRequestID+", Link")
+
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+				w.Header().Set("Access-Control-Allow-Headers", strings.Join([]string{
+					"Content-Type", reqctx.HeaderRequestID, reqctx.HeaderLocale, reqctx.HeaderFeatureFlags,
+				}, ", "))
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
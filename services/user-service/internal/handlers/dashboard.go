@@ -0,0 +1,96 @@
+// services/user-service/internal/handlers/dashboard.go
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/services"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// DashboardHandler holds dependencies for the caller's dashboard layout handlers.
+type DashboardHandler struct {
+	dashboardService services.DashboardService
+}
+
+// NewDashboardHandler creates a new DashboardHandler instance.
+func NewDashboardHandler(dashboardService services.DashboardService) *DashboardHandler {
+	return &DashboardHandler{dashboardService: dashboardService}
+}
+
+// GetLayout handles GET /me/dashboard requests.
+func (h *DashboardHandler) GetLayout(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+
+	layout, err := h.dashboardService.GetLayout(r.Context(), userID)
+	if err != nil {
+		logger.FromContext(r.Context()).Errorf("Error getting dashboard layout for user %s: %v", userID, err)
+		writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to get dashboard layout")
+		return
+	}
+	writeDashboardLayout(w, layout)
+}
+
+// SaveLayout handles PUT /me/dashboard requests. The whole document is replaced; unknown fields are rejected.
+func (h *DashboardHandler) SaveLayout(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+
+	var req models.DashboardLayout
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		logger.FromContext(r.Context()).Debugf("Invalid request payload for dashboard layout: %v", err)
+		writeInvalidBody(w, err)
+		return
+	}
+	if !validateRequest(w, r, &req) {
+		return
+	}
+
+	layout, err := h.dashboardService.SaveLayout(r.Context(), userID, req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidInput) {
+			writeError(w, http.StatusBadRequest, models.ErrorCodeValidationFailed, serviceMessage(err))
+		} else {
+			logger.FromContext(r.Context()).Errorf("Error saving dashboard layout for user %s: %v", userID, err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to save dashboard layout")
+		}
+		return
+	}
+	writeDashboardLayout(w, layout)
+}
+
+// ResetLayout handles DELETE /me/dashboard requests, restoring the default layout.
+func (h *DashboardHandler) ResetLayout(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+
+	layout, err := h.dashboardService.ResetLayout(r.Context(), userID)
+	if err != nil {
+		logger.FromContext(r.Context()).Errorf("Error resetting dashboard layout for user %s: %v", userID, err)
+		writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to reset dashboard layout")
+		return
+	}
+	writeDashboardLayout(w, layout)
+}
+
+func writeDashboardLayout(w http.ResponseWriter, layout *models.DashboardLayout) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, layout)
+}
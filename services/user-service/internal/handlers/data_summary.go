@@ -0,0 +1,47 @@
+// services/user-service/internal/handlers/data_summary.go
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/services"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// DataSummaryHandler holds dependencies for the data summary HTTP handler.
+type DataSummaryHandler struct {
+	dataSummaryService services.DataSummaryService
+}
+
+// NewDataSummaryHandler creates a new DataSummaryHandler instance.
+func NewDataSummaryHandler(dataSummaryService services.DataSummaryService) *DataSummaryHandler {
+	return &DataSummaryHandler{dataSummaryService: dataSummaryService}
+}
+
+// GetDataSummary handles GET /me/data-summary requests: counts, storage, and date ranges of what
+// Pulse stores about the caller, by category, for the settings screen.
+func (h *DataSummaryHandler) GetDataSummary(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+
+	summary, err := h.dataSummaryService.GetDataSummary(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			writeError(w, http.StatusNotFound, models.ErrorCodeUserNotFound, "User not found")
+		} else {
+			logger.FromContext(r.Context()).Errorf("Error summarizing data of user %s: %v", userID, err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to summarize data")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, summary)
+}
@@ -0,0 +1,24 @@
+// services/user-service/internal/handlers/deadlines.go
+package handlers
+
+import (
+	"net/http"
+	"time"
+)
+
+// transferTimeout is how long an attachment upload or download may take. The server's read and write
+// timeouts are sized for API requests, and would cut off a large file on a slow connection.
+const transferTimeout = 10 * time.Minute
+
+// extendDeadlines moves the read and write deadlines of the connection serving w to d from now, or
+// lifts them with d zero, for requests that outlast the server's timeouts: streams and file transfers.
+// Writers that cannot set deadlines, such as test recorders, are left as they are.
+func extendDeadlines(w http.ResponseWriter, d time.Duration) {
+	var deadline time.Time
+	if d > 0 {
+		deadline = time.Now().Add(d)
+	}
+	rc := http.NewResponseController(w)
+	rc.SetReadDeadline(deadline)
+	rc.SetWriteDeadline(deadline)
+}
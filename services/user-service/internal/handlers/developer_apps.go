@@ -0,0 +1,278 @@
+// services/user-service/internal/handlers/developer_apps.go
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/services"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// defaultDeveloperAppUsageDays is the usage history returned when ?days is not given.
+const defaultDeveloperAppUsageDays = 30
+
+// DeveloperAppHandler holds dependencies for the developer portal: registering apps for the public
+// API, managing their keys, and reading their usage.
+type DeveloperAppHandler struct {
+	appService services.DeveloperAppService
+	auditor    *Auditor
+}
+
+// NewDeveloperAppHandler creates a new DeveloperAppHandler instance.
+func NewDeveloperAppHandler(appService services.DeveloperAppService, auditor *Auditor) *DeveloperAppHandler {
+	return &DeveloperAppHandler{appService: appService, auditor: auditor}
+}
+
+// writeDeveloperAppError maps the developer app service's client errors to responses, reporting whether it did.
+func writeDeveloperAppError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, services.ErrNotFound):
+		writeError(w, http.StatusNotFound, models.ErrorCodeDeveloperAppNotFound, "Developer app not found")
+	case errors.Is(err, services.ErrConflict):
+		writeError(w, http.StatusConflict, models.ErrorCodeConflict, serviceMessage(err))
+	case errors.Is(err, services.ErrInvalidInput):
+		writeError(w, http.StatusBadRequest, models.ErrorCodeValidationFailed, serviceMessage(err))
+	case errors.Is(err, services.ErrForbidden):
+		writeError(w, http.StatusForbidden, models.ErrorCodeForbidden, serviceMessage(err))
+	default:
+		return false
+	}
+	return true
+}
+
+// ListApps handles GET /developer/apps.
+func (h *DeveloperAppHandler) ListApps(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+
+	apps, err := h.appService.ListApps(r.Context(), userID)
+	if err != nil {
+		logger.FromContext(r.Context()).Errorf("Error listing developer apps for user %s: %v", userID, err)
+		writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to list developer apps")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, apps)
+}
+
+// CreateApp handles POST /developer/apps. The response holds the API key, which is not shown again.
+func (h *DeveloperAppHandler) CreateApp(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+	var req models.CreateDeveloperAppRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	creds, err := h.appService.CreateApp(r.Context(), userID, req)
+	if err != nil {
+		if !writeDeveloperAppError(w, err) {
+			logger.FromContext(r.Context()).Errorf("Error creating developer app for user %s: %v", userID, err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to create developer app")
+		}
+		return
+	}
+	h.auditor.Record(r, models.AuditEvent{
+		Action:   models.AuditAPIKeyCreate,
+		Outcome:  models.AuditSuccess,
+		TargetID: creds.App.ID.String(),
+		Details:  map[string]string{"key_prefix": creds.App.KeyPrefix},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	writeBody(w, creds)
+}
+
+// RotateKey handles POST /developer/apps/{id}/rotate-key. The old key stops working at once.
+func (h *DeveloperAppHandler) RotateKey(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid developer app ID format")
+		return
+	}
+
+	creds, err := h.appService.RotateKey(r.Context(), userID, id)
+	if err != nil {
+		if !writeDeveloperAppError(w, err) {
+			logger.FromContext(r.Context()).Errorf("Error rotating key of developer app %s: %v", id, err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to rotate developer app key")
+		}
+		return
+	}
+	h.auditor.Record(r, models.AuditEvent{
+		Action:   models.AuditAPIKeyRotate,
+		Outcome:  models.AuditSuccess,
+		TargetID: id.String(),
+		Details:  map[string]string{"key_prefix": creds.App.KeyPrefix},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, creds)
+}
+
+// RevokeApp handles POST /developer/apps/{id}/revoke. Its key is rejected from then on.
+func (h *DeveloperAppHandler) RevokeApp(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid developer app ID format")
+		return
+	}
+
+	app, err := h.appService.RevokeApp(r.Context(), userID, id)
+	if err != nil {
+		if !writeDeveloperAppError(w, err) {
+			logger.FromContext(r.Context()).Errorf("Error revoking developer app %s: %v", id, err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to revoke developer app")
+		}
+		return
+	}
+	h.auditor.Record(r, models.AuditEvent{
+		Action:   models.AuditAPIKeyRevoke,
+		Outcome:  models.AuditSuccess,
+		TargetID: id.String(),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, app)
+}
+
+// GetUsage handles GET /developer/apps/{id}/usage?days=N, the app's public API requests per UTC
+// day and route over the last N days (default 30, at most 90).
+func (h *DeveloperAppHandler) GetUsage(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid developer app ID format")
+		return
+	}
+	days := defaultDeveloperAppUsageDays
+	if v := r.URL.Query().Get("days"); v != "" {
+		if days, err = strconv.Atoi(v); err != nil {
+			writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid days parameter")
+			return
+		}
+	}
+
+	usage, err := h.appService.GetUsage(r.Context(), userID, id, days)
+	if err != nil {
+		if !writeDeveloperAppError(w, err) {
+			logger.FromContext(r.Context()).Errorf("Error getting usage of developer app %s: %v", id, err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to get developer app usage")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, usage)
+}
+
+// StartDebug handles POST /developer/apps/{id}/debug. The app's public API requests and responses are
+// recorded for an hour, in the sandbox only; earlier recordings are discarded.
+func (h *DeveloperAppHandler) StartDebug(w http.ResponseWriter, r *http.Request) {
+	h.setDebug(w, r, true)
+}
+
+// StopDebug handles DELETE /developer/apps/{id}/debug. Recording stops and the recordings are deleted.
+func (h *DeveloperAppHandler) StopDebug(w http.ResponseWriter, r *http.Request) {
+	h.setDebug(w, r, false)
+}
+
+func (h *DeveloperAppHandler) setDebug(w http.ResponseWriter, r *http.Request, on bool) {
+	userID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid developer app ID format")
+		return
+	}
+
+	var app *models.DeveloperApp
+	if on {
+		app, err = h.appService.StartDebug(r.Context(), userID, id)
+	} else {
+		app, err = h.appService.StopDebug(r.Context(), userID, id)
+	}
+	if err != nil {
+		if !writeDeveloperAppError(w, err) {
+			logger.FromContext(r.Context()).Errorf("Error setting debug recording of developer app %s to %t: %v", id, on, err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to update debug recording")
+		}
+		return
+	}
+	h.auditor.Record(r, models.AuditEvent{
+		Action:   models.AuditAPIKeyDebug,
+		Outcome:  models.AuditSuccess,
+		TargetID: id.String(),
+		Details:  map[string]string{"recording": strconv.FormatBool(on)},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, app)
+}
+
+// ExportHAR handles GET /developer/apps/{id}/debug/har, a download of the app's recorded requests as
+// an HTTP Archive that browser developer tools and HTTP debuggers can open.
+func (h *DeveloperAppHandler) ExportHAR(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid developer app ID format")
+		return
+	}
+
+	har, err := h.appService.ExportHAR(r.Context(), userID, id)
+	if err != nil {
+		if !writeDeveloperAppError(w, err) {
+			logger.FromContext(r.Context()).Errorf("Error exporting recordings of developer app %s: %v", id, err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to export developer app recordings")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="pulse-%s.har"`, id))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, har)
+}
@@ -0,0 +1,227 @@
+// services/user-service/internal/handlers/email_webhooks.go
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/services"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+const maxEmailWebhookBody = 1 << 20 // Providers batch events; 1 MiB is far more than one batch
+
+// EmailHandler holds dependencies for the mail provider webhooks and for verifying a suppressed email again.
+type EmailHandler struct {
+	emailService services.EmailDeliverabilityService
+	token        string // Shared with the mail provider, as a bearer token or the basic-auth password
+}
+
+// NewEmailHandler creates a new EmailHandler instance.
+func NewEmailHandler(emailService services.EmailDeliverabilityService, token string) *EmailHandler {
+	return &EmailHandler{emailService: emailService, token: token}
+}
+
+// emailWebhookParsers turn the body of each mail provider's webhook into the bounces and complaints
+// it reports. Other events in the body, such as deliveries and opens, are left out.
+var emailWebhookParsers = map[string]func([]byte) ([]models.EmailDeliveryEvent, error){
+	"sendgrid": parseSendGridEvents,
+	"postmark": parsePostmarkEvents,
+	"generic":  parseGenericEmailEvents,
+}
+
+// parseSendGridEvents parses a SendGrid Event Webhook batch.
+func parseSendGridEvents(body []byte) ([]models.EmailDeliveryEvent, error) {
+	var batch []struct {
+		Email     string `json:"email"`
+		Event     string `json:"event"`
+		Type      string `json:"type"` // "bounce" or "blocked" for bounce events
+		Reason    string `json:"reason"`
+		Timestamp int64  `json:"timestamp"`
+	}
+	if err := json.Unmarshal(body, &batch); err != nil {
+		return nil, err
+	}
+	var events []models.EmailDeliveryEvent
+	for _, e := range batch {
+		event := models.EmailDeliveryEvent{Email: e.Email, Detail: e.Reason}
+		switch {
+		case e.Event == "bounce" && e.Type == "blocked":
+			event.Kind = models.EmailSoftBounce
+		case e.Event == "bounce":
+			event.Kind = models.EmailHardBounce
+		case e.Event == "spamreport":
+			event.Kind = models.EmailComplaint
+		default:
+			continue
+		}
+		if e.Timestamp > 0 {
+			event.OccurredAt = time.Unix(e.Timestamp, 0).UTC()
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// parsePostmarkEvents parses a Postmark bounce or spam complaint webhook, which posts one event at a time.
+func parsePostmarkEvents(body []byte) ([]models.EmailDeliveryEvent, error) {
+	var e struct {
+		RecordType  string    `json:"RecordType"`
+		Type        string    `json:"Type"`
+		Email       string    `json:"Email"`
+		Description string    `json:"Description"`
+		BouncedAt   time.Time `json:"BouncedAt"`
+	}
+	if err := json.Unmarshal(body, &e); err != nil {
+		return nil, err
+	}
+	event := models.EmailDeliveryEvent{Email: e.Email, Detail: e.Description, OccurredAt: e.BouncedAt.UTC()}
+	switch {
+	case e.RecordType == "SpamComplaint":
+		event.Kind = models.EmailComplaint
+	case e.RecordType != "Bounce":
+		return nil, nil
+	case e.Type == "HardBounce" || e.Type == "BadEmailAddress" || e.Type == "ManuallyDeactivated":
+		event.Kind = models.EmailHardBounce
+	case e.Type == "SoftBounce" || e.Type == "Transient" || e.Type == "DnsError":
+		event.Kind = models.EmailSoftBounce
+	default:
+		return nil, nil // Auto-replies, subscription changes, and the like
+	}
+	return []models.EmailDeliveryEvent{event}, nil
+}
+
+// parseGenericEmailEvents parses Pulse's own format, {"events": [EmailDeliveryEvent, ...]}, for
+// providers without a parser of their own, behind a relay that translates their webhooks.
+func parseGenericEmailEvents(body []byte) ([]models.EmailDeliveryEvent, error) {
+	var batch struct {
+		Events []models.EmailDeliveryEvent `json:"events"`
+	}
+	if err := json.Unmarshal(body, &batch); err != nil {
+		return nil, err
+	}
+	events := batch.Events[:0]
+	for _, e := range batch.Events {
+		switch e.Kind {
+		case models.EmailHardBounce, models.EmailSoftBounce, models.EmailComplaint:
+			events = append(events, e)
+		default:
+			return nil, fmt.Errorf("unknown event type '%s'", e.Kind)
+		}
+	}
+	return events, nil
+}
+
+// authorized reports whether the webhook request carries the shared token, as a bearer token or as
+// the basic-auth password, which is all some providers can send.
+func (h *EmailHandler) authorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		_, token, ok = r.BasicAuth()
+	}
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) == 1
+}
+
+// Webhook handles POST /webhooks/email/{provider}: bounces and complaints reported by the mail
+// provider. Events that are not bounces or complaints are acknowledged and ignored.
+func (h *EmailHandler) Webhook(w http.ResponseWriter, r *http.Request) {
+	if !h.authorized(r) {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+	provider := r.PathValue("provider")
+	parse, ok := emailWebhookParsers[provider]
+	if !ok {
+		writeError(w, http.StatusNotFound, models.ErrorCodeNotFound, "Unknown email provider")
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxEmailWebhookBody))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidBody, "Invalid request body")
+		return
+	}
+	events, err := parse(body)
+	if err != nil {
+		logger.FromContext(r.Context()).Warnf("Invalid %s email webhook: %v", provider, err)
+		writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidBody, "Invalid request body")
+		return
+	}
+	for i := range events {
+		events[i].Provider = provider
+	}
+
+	applied, err := h.emailService.HandleEvents(r.Context(), events)
+	if err != nil {
+		// The provider retries the whole batch; events already applied count again, which only
+		// matters for soft bounces and is no worse than the provider reporting them twice.
+		logger.FromContext(r.Context()).Errorf("Error handling %s email webhook: %v", provider, err)
+		writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to handle email events")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, models.EmailWebhookResult{Received: len(events), Applied: applied})
+}
+
+// RequestVerification handles POST /me/email/verification, emailing the caller a code that verifies
+// their email, even when mail to it is suppressed.
+func (h *EmailHandler) RequestVerification(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+
+	if err := h.emailService.RequestVerification(r.Context(), userID); err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			writeError(w, http.StatusNotFound, models.ErrorCodeUserNotFound, "User not found")
+			return
+		}
+		logger.FromContext(r.Context()).Errorf("Error requesting email verification for user %s: %v", userID, err)
+		writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to send verification email")
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// ConfirmVerification handles POST /me/email/verification/confirm, verifying the caller's email with
+// the emailed code and lifting its suppression.
+func (h *EmailHandler) ConfirmVerification(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+	var req models.ConfirmEmailRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	user, err := h.emailService.ConfirmVerification(r.Context(), userID, req.Token)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidInput):
+			writeError(w, http.StatusBadRequest, models.ErrorCodeValidationFailed, serviceMessage(err))
+		case errors.Is(err, services.ErrInvalidToken):
+			writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidToken, "Invalid or expired verification token")
+		default:
+			logger.FromContext(r.Context()).Errorf("Error confirming email verification for user %s: %v", userID, err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to verify email")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, user)
+}
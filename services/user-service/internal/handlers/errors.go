@@ -0,0 +1,189 @@
+// services/user-service/internal/handlers/errors.go
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/services"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// writeError writes an error response in the envelope every route uses, models.ErrorResponse:
+// {"error": {"code": "USER_NOT_FOUND", "message": "User not found"}}, with details if any are given.
+func writeError(w http.ResponseWriter, status int, code, message string, details ...models.ErrorDetail) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	writeBody(w, models.ErrorResponse{Error: models.APIError{Code: code, Message: message, Details: details}})
+}
+
+// writeInvalidBody answers a request whose body could not be decoded as the JSON the route takes, with
+// the decoding error as the detail, naming the field when one is at fault.
+func writeInvalidBody(w http.ResponseWriter, err error) {
+	writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidBody, "Invalid request payload", invalidBodyDetail(err))
+}
+
+// invalidBodyDetail describes a JSON decoding error, naming the field when one is at fault.
+func invalidBodyDetail(err error) models.ErrorDetail {
+	detail := models.ErrorDetail{Message: strings.TrimPrefix(err.Error(), "json: ")}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		detail = models.ErrorDetail{Field: typeErr.Field, Message: "unexpected " + typeErr.Value} // Field is empty for the body itself
+	} else if field, ok := strings.CutPrefix(detail.Message, "unknown field "); ok {
+		detail = models.ErrorDetail{Field: strings.Trim(field, `"`), Message: "unknown field"}
+	}
+	return detail
+}
+
+// maxRequestBytes bounds the JSON bodies read by decodeJSON. Routes that take larger documents, such
+// as bulk requests and workout plans, read their bodies themselves under a limit of their own.
+const maxRequestBytes = 1 << 20
+
+// decodeJSON decodes the JSON body of r into v, a pointer to a request struct, and checks it against
+// the struct's validate tags. If either fails it answers 400 Bad Request, INVALID_BODY or
+// VALIDATION_FAILED with a detail per field at fault, and returns false. A body over maxRequestBytes
+// is answered with 413 Payload Too Large.
+func decodeJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBytes))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeError(w, http.StatusRequestEntityTooLarge, models.ErrorCodePayloadTooLarge, "Request body too large")
+		return false
+	}
+	if err == nil {
+		err = json.NewDecoder(bytes.NewReader(body)).Decode(v)
+	}
+	if err != nil {
+		logger.FromContext(r.Context()).Debugf("Invalid request payload for %s %s: %v", r.Method, r.URL.Path, err)
+		if detail, ok := textFieldError(body, v, err); ok {
+			writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidBody, "Invalid request payload", detail)
+		} else {
+			writeInvalidBody(w, err)
+		}
+		return false
+	}
+	return validateRequest(w, r, v)
+}
+
+// textFieldError names the field behind err when the value's own UnmarshalText rejected it, as
+// uuid.UUID does for a malformed UUID: encoding/json reports those errors without the field. It looks
+// at the top-level fields of v, a pointer to a struct, which is where requests take IDs.
+func textFieldError(body []byte, v any, err error) (models.ErrorDetail, bool) {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var fields map[string]json.RawMessage
+	if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) || json.Unmarshal(body, &fields) != nil {
+		return models.ErrorDetail{}, false
+	}
+	rt := reflect.TypeOf(v).Elem()
+	for i := range rt.NumField() {
+		sf := rt.Field(i)
+		name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		raw, ok := fields[name]
+		if !ok {
+			continue
+		}
+		if err := json.Unmarshal(raw, reflect.New(sf.Type).Interface()); err != nil {
+			if t := sf.Type; t == reflect.TypeFor[uuid.UUID]() || t.Kind() == reflect.Slice && t.Elem() == reflect.TypeFor[uuid.UUID]() {
+				return models.ErrorDetail{Field: name, Message: "must be a UUID"}, true
+			}
+			return models.ErrorDetail{Field: name, Message: err.Error()}, true
+		}
+	}
+	return models.ErrorDetail{}, false
+}
+
+// validateRequest checks a decoded request against its validate tags, as decodeJSON does, for handlers
+// that decode the body themselves.
+func validateRequest(w http.ResponseWriter, r *http.Request, v any) bool {
+	problems, err := models.Validate(v)
+	if err != nil {
+		logger.FromContext(r.Context()).Errorf("Error validating request for %s %s: %v", r.Method, r.URL.Path, err)
+		writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to validate request")
+		return false
+	}
+	if len(problems) == 0 {
+		return true
+	}
+	logger.FromContext(r.Context()).Debugf("Invalid fields in request for %s %s: %v", r.Method, r.URL.Path, problems)
+	writeError(w, http.StatusBadRequest, models.ErrorCodeValidationFailed, "Request has invalid fields", problems...)
+	return false
+}
+
+// serviceMessage is the message of a service error as responses show it, without the "service: " prefix
+// and capitalized: "service: user not found" reads "User not found".
+func serviceMessage(err error) string {
+	msg := strings.TrimPrefix(err.Error(), "service: ")
+	first, size := utf8.DecodeRuneInString(msg)
+	return string(unicode.ToUpper(first)) + msg[size:]
+}
+
+// conflictCode is the code of a 409 Conflict caused by a service error: taken emails and usernames have
+// their own, for sign-up and profile forms to point at the field.
+func conflictCode(err error) string {
+	switch {
+	case errors.Is(err, services.ErrDuplicateEmail):
+		return models.ErrorCodeEmailTaken
+	case errors.Is(err, services.ErrDuplicateUsername):
+		return models.ErrorCodeUsernameTaken
+	}
+	return models.ErrorCodeConflict
+}
+
+// userChangeError is the status and error a failed change to a user answers with, the same for POST
+// /users, PUT /users/{id}, DELETE /users/{id}, and each operation of POST /users/bulk; nil if err is not
+// one the client caused, which the caller reports as a failure of its own.
+func userChangeError(err error) (int, *models.APIError) {
+	switch {
+	case errors.Is(err, services.ErrNotFound):
+		return http.StatusNotFound, &models.APIError{Code: models.ErrorCodeUserNotFound, Message: serviceMessage(err)}
+	case errors.Is(err, services.ErrDuplicateEmail) || errors.Is(err, services.ErrConflict):
+		return http.StatusConflict, &models.APIError{Code: conflictCode(err), Message: serviceMessage(err)}
+	case errors.Is(err, services.ErrInvalidInput):
+		return http.StatusBadRequest, &models.APIError{Code: models.ErrorCodeValidationFailed, Message: serviceMessage(err)}
+	}
+	return 0, nil
+}
+
+// writeNoRoute answers a request as if no route matched it. Routes that are hidden from some callers,
+// such as features not rolled out to them, use it so their answer cannot be told apart.
+func writeNoRoute(w http.ResponseWriter) {
+	writeError(w, http.StatusNotFound, models.ErrorCodeNotFound, "No route matches this path")
+}
+
+// envelopeWriter gives the mux's own 404 Not Found and 405 Method Not Allowed responses, for requests
+// no route matches, the error envelope instead of their plain text.
+type envelopeWriter struct {
+	http.ResponseWriter
+	replaced bool
+}
+
+func (e *envelopeWriter) WriteHeader(status int) {
+	switch status {
+	case http.StatusNotFound:
+		e.replaced = true
+		writeNoRoute(e.ResponseWriter)
+	case http.StatusMethodNotAllowed:
+		e.replaced = true
+		writeError(e.ResponseWriter, status, models.ErrorCodeMethodNotAllowed, "Method not allowed")
+	default:
+		e.ResponseWriter.WriteHeader(status)
+	}
+}
+
+func (e *envelopeWriter) Write(p []byte) (int, error) {
+	if e.replaced {
+		return len(p), nil
+	}
+	return e.ResponseWriter.Write(p)
+}
@@ -0,0 +1,221 @@
+// services/user-service/internal/handlers/golden_test.go
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"health-tracker-project/services/user-service/internal/eventbus"
+	"health-tracker-project/services/user-service/internal/mailer"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/realtime"
+	"health-tracker-project/services/user-service/internal/repository/inmemory"
+	"health-tracker-project/services/user-service/internal/services"
+	"health-tracker-project/services/user-service/internal/utils/codec"
+	"health-tracker-project/services/user-service/internal/utils/jwt"
+	"health-tracker-project/services/user-service/internal/utils/logger"
+)
+
+// update rewrites the golden files with the responses of this run: go test ./internal/handlers -update
+var update = flag.Bool("update", false, "rewrite testdata/*.golden with the current responses")
+
+// goldenMux builds the routes under test as cmd/main.go does in dev mode, on in-memory repositories,
+// behind the same request context, content negotiation, and recovery middleware.
+func goldenMux(t *testing.T) http.Handler {
+	t.Helper()
+	logger.Logger = zap.NewNop().Sugar()
+	if err := jwt.InitJWT("HS256", "golden-test-secret", ""); err != nil {
+		t.Fatalf("InitJWT: %v", err)
+	}
+
+	store := inmemory.NewDB(inmemory.Options{})
+	userRepo := inmemory.NewUserRepository(store)
+	events := services.NewUserEventService(inmemory.NewUserEventRepository(store), realtime.NewHub(nil))
+	mail := mailer.NewLogMailer()
+	identities := services.NewIdentityService(userRepo, inmemory.NewIdentityRepository(store), mail, events)
+	authService := services.NewAuthService(userRepo, mail, nil, false, events, inmemory.NewLoginAttemptRepository(store), identities, inmemory.NewSessionRepository(store))
+	userService := services.NewUserService(userRepo, events, nil, eventbus.NewLogPublisher())
+	auditor := NewAuditor(services.NewAuditService(inmemory.NewAuditRepository(store)), 0)
+
+	authHandlers := NewAuthHandlers(authService, auditor, nil)
+	userHandlers := NewUserHandler(userService, events, auditor)
+	settingsHandlers := NewSettingsHandler(services.NewSettingsService(inmemory.NewSettingsRepository(store)))
+	dashboardHandlers := NewDashboardHandler(services.NewDashboardService(inmemory.NewDashboardRepository(store)))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /register", authHandlers.Register)
+	mux.HandleFunc("POST /login", authHandlers.Login)
+	mux.Handle("GET /protected", authHandlers.AuthMiddleware(http.HandlerFunc(authHandlers.ProtectedRoute)))
+	mux.Handle("POST /logout", authHandlers.AuthMiddleware(http.HandlerFunc(authHandlers.Logout)))
+	mux.Handle("GET /users", authHandlers.AuthMiddleware(RequireScope(models.ScopeUsersRead)(http.HandlerFunc(userHandlers.UsersCollectionHandler))))
+	mux.Handle("GET /users/{id}", authHandlers.AuthMiddleware(http.HandlerFunc(userHandlers.UserItemHandler)))
+	mux.Handle("PUT /users/{id}", authHandlers.AuthMiddleware(http.HandlerFunc(userHandlers.UserItemHandler)))
+	mux.Handle("GET /users/me/settings", authHandlers.AuthMiddleware(http.HandlerFunc(settingsHandlers.GetSettings)))
+	mux.Handle("PUT /users/me/settings", authHandlers.AuthMiddleware(http.HandlerFunc(settingsHandlers.UpdateSettings)))
+	mux.Handle("GET /me/dashboard", authHandlers.AuthMiddleware(http.HandlerFunc(dashboardHandlers.GetLayout)))
+	mux.HandleFunc("GET /.well-known/jwks.json", JWKS)
+	mux.HandleFunc("GET /health", NewHealthHandler(0).Check)
+	return RequestContext(false)(ContentNegotiation(Recover(mux)))
+}
+
+// goldenSteps run in order against one server, so later steps see what earlier ones created. In a
+// path, {user} is the ID of the user registered first; with auth set, the step sends the cookie of
+// the last successful login.
+var goldenSteps = []struct {
+	name   string
+	method string
+	path   string
+	body   string
+	header map[string]string
+	auth   bool
+}{
+	{name: "health", method: "GET", path: "/health"},
+	{name: "jwks", method: "GET", path: "/.well-known/jwks.json"},
+	{name: "register", method: "POST", path: "/register", body: `{"name":"Ada Lovelace","email":"ada@example.com","password":"correct-horse-battery"}`},
+	{name: "register_duplicate_email", method: "POST", path: "/register", body: `{"name":"Ada Again","email":"ada@example.com","password":"correct-horse-battery"}`},
+	{name: "register_invalid_json", method: "POST", path: "/register", body: `{"name":`},
+	{name: "register_unknown_field", method: "POST", path: "/register", body: `{"name":"Bob","email":"bob@example.com","password":"correct-horse-battery","admin":true}`},
+	{name: "register_missing_fields", method: "POST", path: "/register", body: `{"name":"","email":"not-an-email","password":"short"}`},
+	{name: "register_too_large", method: "POST", path: "/register", body: `{"name":"` + strings.Repeat("a", maxRequestBytes) + `"}`},
+	{name: "login_wrong_password", method: "POST", path: "/login", body: `{"email":"ada@example.com","password":"wrong-password"}`},
+	{name: "login", method: "POST", path: "/login", body: `{"email":"ada@example.com","password":"correct-horse-battery"}`},
+	{name: "protected_without_cookie", method: "GET", path: "/protected"},
+	{name: "protected", method: "GET", path: "/protected", auth: true},
+	{name: "get_user", method: "GET", path: "/users/{user}", auth: true},
+	{name: "get_other_user", method: "GET", path: "/users/00000000-0000-0000-0000-000000000000", auth: true},
+	{name: "get_user_invalid_id", method: "GET", path: "/users/not-a-uuid", auth: true},
+	{name: "list_users_missing_scope", method: "GET", path: "/users", auth: true},
+	{name: "update_user", method: "PUT", path: "/users/{user}", body: `{"name":"Ada King","email":"ada@example.com","units":"imperial","height_cm":170}`, auth: true},
+	{name: "get_settings", method: "GET", path: "/users/me/settings", auth: true},
+	{name: "update_settings", method: "PUT", path: "/users/me/settings", body: `{"goals.daily_steps":12000,"privacy.research_opt_in":true}`, auth: true},
+	{name: "update_settings_invalid", method: "PUT", path: "/users/me/settings", body: `{"goals.daily_steps":"lots"}`, auth: true},
+	{name: "get_dashboard", method: "GET", path: "/me/dashboard", auth: true},
+	{name: "get_user_msgpack", method: "GET", path: "/users/{user}", header: map[string]string{"Accept": "application/msgpack"}, auth: true},
+	{name: "logout", method: "POST", path: "/logout", auth: true},
+	{name: "protected_after_logout", method: "GET", path: "/protected", auth: true},
+}
+
+func TestHandlersGolden(t *testing.T) {
+	handler := goldenMux(t)
+	var userID string
+	var cookie *http.Cookie
+
+	for _, step := range goldenSteps {
+		t.Run(step.name, func(t *testing.T) {
+			path := strings.ReplaceAll(step.path, "{user}", userID)
+			req := httptest.NewRequest(step.method, path, strings.NewReader(step.body))
+			if step.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			for name, value := range step.header {
+				req.Header.Set(name, value)
+			}
+			if step.auth && cookie != nil {
+				req.AddCookie(cookie)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			res := rec.Result()
+
+			switch {
+			case step.name == "register" && res.StatusCode == http.StatusCreated:
+				var user models.UserResponse
+				if err := json.Unmarshal(rec.Body.Bytes(), &user); err != nil {
+					t.Fatalf("decoding registered user: %v", err)
+				}
+				userID = user.ID.String()
+			case step.name == "login" && res.StatusCode == http.StatusOK:
+				for _, c := range res.Cookies() {
+					if c.Name == "jwt_token" {
+						cookie = &http.Cookie{Name: c.Name, Value: c.Value}
+					}
+				}
+			}
+
+			got := goldenResponse(res.StatusCode, res.Header, rec.Body.Bytes())
+			file := filepath.Join("testdata", step.name+".golden")
+			if *update {
+				if err := os.WriteFile(file, got, 0o644); err != nil {
+					t.Fatalf("writing %s: %v", file, err)
+				}
+				return
+			}
+			want, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatalf("reading %s (run with -update to create it): %v", file, err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("%s %s does not match %s (run with -update if the change is intended)\ngot:\n%s\nwant:\n%s", step.method, path, file, got, want)
+			}
+		})
+	}
+}
+
+var (
+	goldenUUID      = regexp.MustCompile(`[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`)
+	goldenJWT       = regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`)
+	goldenTimestamp = regexp.MustCompile(`\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})`)
+	goldenHTTPDate  = regexp.MustCompile(`[A-Z][a-z]{2}, \d{2} [A-Z][a-z]{2} \d{4} \d{2}:\d{2}:\d{2} GMT`)
+)
+
+// goldenResponse renders a response as it is kept in a golden file: the status, the headers sorted by
+// name, and the body, indented if it is JSON. Bodies in another registered format are converted to
+// JSON first, so Content-Length, which then depends on the length of the times in them, is left out.
+// Values that differ from run to run (IDs, tokens, and times) are replaced by placeholders; a UUID
+// keeps the same placeholder wherever it appears.
+func goldenResponse(status int, header http.Header, body []byte) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d %s\n", status, http.StatusText(status))
+	if c, ok := codec.Lookup(header.Get("Content-Type")); ok && c != codec.JSON {
+		if converted, err := codec.ToJSON(c, body); err == nil {
+			body = converted
+		}
+	}
+	names := make([]string, 0, len(header))
+	for name := range header {
+		if name != "Content-Length" {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		for _, value := range header[name] {
+			fmt.Fprintf(&b, "%s: %s\n", name, value)
+		}
+	}
+	b.WriteString("\n")
+	var indented bytes.Buffer
+	if json.Valid(body) && json.Indent(&indented, body, "", "  ") == nil {
+		b.Write(indented.Bytes())
+		b.WriteString("\n")
+	} else if len(body) > 0 {
+		fmt.Fprintf(&b, "%q\n", body)
+	}
+
+	out := goldenJWT.ReplaceAllString(b.String(), "<jwt>")
+	out = goldenTimestamp.ReplaceAllString(out, "<time>")
+	out = goldenHTTPDate.ReplaceAllString(out, "<http-date>")
+	seen := map[string]string{}
+	out = goldenUUID.ReplaceAllStringFunc(out, func(id string) string {
+		if id == "00000000-0000-0000-0000-000000000000" {
+			return id
+		}
+		if _, ok := seen[id]; !ok {
+			seen[id] = fmt.Sprintf("<uuid-%d>", len(seen)+1)
+		}
+		return seen[id]
+	})
+	return []byte(out)
+}
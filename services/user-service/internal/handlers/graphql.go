@@ -0,0 +1,496 @@
+// services/user-service/internal/handlers/graphql.go
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/graphql"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/services"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+	"health-tracker-project/services/user-service/internal/utils/pagination"
+)
+
+const (
+	maxGraphQLRequestBytes = 64 << 10 // Queries are text; variables are a few IDs and strings
+	maxGraphQLDepth        = 8        // Fields nested deeper than this are refused before anything runs
+)
+
+// GraphQLHandler serves the GraphQL API, whose resolvers call the same services as the REST routes and
+// apply the same access rules: callers see their own account, and other accounts with users:read.
+type GraphQLHandler struct {
+	schema      *graphql.Schema
+	userService services.UserService
+	authService services.AuthService
+}
+
+// NewGraphQLHandler creates a new GraphQLHandler instance.
+func NewGraphQLHandler(userService services.UserService, authService services.AuthService) (*GraphQLHandler, error) {
+	h := &GraphQLHandler{userService: userService, authService: authService}
+	schema, err := graphql.NewSchema(h.queryType(), maxGraphQLDepth)
+	if err != nil {
+		return nil, err
+	}
+	h.schema = schema
+	return h, nil
+}
+
+// Query handles POST /graphql requests: a JSON body with query, and optionally operationName and
+// variables. Requests that could not run get 400 Bad Request; those that ran get 200 OK, with errors
+// for any fields that failed.
+func (h *GraphQLHandler) Query(w http.ResponseWriter, r *http.Request) {
+	var req graphql.Request
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxGraphQLRequestBytes))
+	decoder.UseNumber() // Int and Float variables keep their literal form until coerced
+	if err := decoder.Decode(&req); err != nil {
+		writeGraphQL(w, http.StatusBadRequest, &graphql.Response{Errors: []*graphql.Error{{Message: "Request body must be JSON with a query."}}})
+		return
+	}
+	if strings.TrimSpace(req.Query) == "" {
+		writeGraphQL(w, http.StatusBadRequest, &graphql.Response{Errors: []*graphql.Error{{Message: "Request body must be JSON with a query."}}})
+		return
+	}
+
+	resp := h.schema.Execute(r.Context(), req)
+	status := http.StatusOK
+	if !resp.Executed() {
+		status = http.StatusBadRequest
+	}
+	writeGraphQL(w, status, resp)
+}
+
+// Schema handles GET /graphql/schema requests, returning the schema in the GraphQL schema definition
+// language for clients that generate types or check their queries.
+func (h *GraphQLHandler) Schema(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(h.schema.SDL()))
+}
+
+func writeGraphQL(w http.ResponseWriter, status int, resp *graphql.Response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	writeBody(w, resp)
+}
+
+// dateTime is an instant, as in the REST API's JSON.
+var dateTime = &graphql.Scalar{
+	Name:        "DateTime",
+	Description: "An instant in RFC 3339 format, in UTC.",
+	Serialize: func(v any) (any, error) {
+		t, ok := v.(time.Time)
+		if !ok {
+			return nil, fmt.Errorf("DateTime cannot represent %T", v)
+		}
+		return t.UTC().Format(time.RFC3339Nano), nil
+	},
+	Parse: func(v any) (any, error) {
+		if s, ok := v.(string); ok {
+			if t, err := time.Parse(time.RFC3339, s); err == nil {
+				return t, nil
+			}
+		}
+		return nil, fmt.Errorf("DateTime must be in RFC 3339 format")
+	},
+}
+
+// graphQLSession is what the Session type resolves from: the caller as AuthMiddleware verified them.
+type graphQLSession struct {
+	id, userID, role string
+	scopes           []string
+}
+
+// graphQLPage is what the page types resolve from: the items of a page and the cursor after its last.
+type graphQLPage struct {
+	nodes     any
+	endCursor *string
+}
+
+// Cursor scopes of the GraphQL lists, so a cursor only resumes the list it was issued for.
+const (
+	usersCursorScope        = "graphql:Query.users"
+	loginHistoryCursorScope = "graphql:User.loginHistory"
+)
+
+func (h *GraphQLHandler) queryType() *graphql.Object {
+	quantity := &graphql.Object{
+		Name:        "Quantity",
+		Description: "A measurement in a unit.",
+		Fields: []*graphql.Field{
+			{Name: "value", Type: &graphql.NonNull{Of: graphql.Float}, Resolve: resolveQuantity(func(q *models.UserResponse) any { return q.HeightInUnits.Value })},
+			{Name: "unit", Type: &graphql.NonNull{Of: graphql.String}, Resolve: resolveQuantity(func(q *models.UserResponse) any { return q.HeightInUnits.Unit })},
+		},
+	}
+	loginAttempt := &graphql.Object{
+		Name:        "LoginAttempt",
+		Description: "A sign-in attempt on an account.",
+		Fields: []*graphql.Field{
+			{Name: "id", Type: &graphql.NonNull{Of: graphql.ID}, Resolve: resolveLoginAttempt(func(a *models.LoginAttempt) any { return a.ID })},
+			{Name: "success", Type: &graphql.NonNull{Of: graphql.Boolean}, Resolve: resolveLoginAttempt(func(a *models.LoginAttempt) any { return a.Success })},
+			{Name: "method", Type: &graphql.NonNull{Of: graphql.String}, Resolve: resolveLoginAttempt(func(a *models.LoginAttempt) any { return a.Method })},
+			{Name: "failureReason", Description: "invalid_credentials, account_suspended, or account_deactivated; null for a success.", Type: graphql.String,
+				Resolve: resolveLoginAttempt(func(a *models.LoginAttempt) any { return optionalString(a.FailureReason) })},
+			{Name: "ip", Type: &graphql.NonNull{Of: graphql.String}, Resolve: resolveLoginAttempt(func(a *models.LoginAttempt) any { return a.IP })},
+			{Name: "userAgent", Type: graphql.String, Resolve: resolveLoginAttempt(func(a *models.LoginAttempt) any { return optionalString(a.UserAgent) })},
+			{Name: "createdAt", Type: &graphql.NonNull{Of: dateTime}, Resolve: resolveLoginAttempt(func(a *models.LoginAttempt) any { return a.CreatedAt })},
+		},
+	}
+	user := &graphql.Object{
+		Name:        "User",
+		Description: "A user account.",
+		Fields: []*graphql.Field{
+			{Name: "id", Type: &graphql.NonNull{Of: graphql.ID}, Resolve: resolveUser(func(u *models.UserResponse) any { return u.ID })},
+			{Name: "name", Type: &graphql.NonNull{Of: graphql.String}, Resolve: resolveUser(func(u *models.UserResponse) any { return u.Name })},
+			{Name: "email", Type: &graphql.NonNull{Of: graphql.String}, Resolve: resolveUser(func(u *models.UserResponse) any { return u.Email })},
+			{Name: "username", Type: graphql.String, Resolve: resolveUser(func(u *models.UserResponse) any { return optionalString(u.Username) })},
+			{Name: "role", Type: &graphql.NonNull{Of: graphql.String}, Resolve: resolveUser(func(u *models.UserResponse) any { return u.Role })},
+			{Name: "timezone", Description: "IANA time zone name.", Type: &graphql.NonNull{Of: graphql.String}, Resolve: resolveUser(func(u *models.UserResponse) any { return u.Timezone })},
+			{Name: "weekStart", Type: &graphql.NonNull{Of: graphql.String}, Resolve: resolveUser(func(u *models.UserResponse) any { return u.WeekStart })},
+			{Name: "units", Description: "metric or imperial.", Type: &graphql.NonNull{Of: graphql.String}, Resolve: resolveUser(func(u *models.UserResponse) any { return u.Units })},
+			{Name: "status", Type: &graphql.NonNull{Of: graphql.String}, Resolve: resolveUser(func(u *models.UserResponse) any { return u.Status })},
+			{Name: "emailVerified", Type: &graphql.NonNull{Of: graphql.Boolean}, Resolve: resolveUser(func(u *models.UserResponse) any { return u.EmailVerified })},
+			{Name: "emailStatus", Description: "ok, soft_bouncing, bounced, or complained.", Type: &graphql.NonNull{Of: graphql.String},
+				Resolve: resolveUser(func(u *models.UserResponse) any { return u.EmailStatus })},
+			{Name: "heightCm", Type: graphql.Float, Resolve: resolveUser(func(u *models.UserResponse) any { return u.HeightCM })},
+			{Name: "height", Description: "heightCm in the user's units, for display.", Type: quantity, Resolve: resolveUser(func(u *models.UserResponse) any {
+				if u.HeightInUnits == nil {
+					return nil
+				}
+				return u
+			})},
+			{Name: "dateOfBirth", Description: "YYYY-MM-DD.", Type: graphql.String, Resolve: resolveUser(func(u *models.UserResponse) any { return optionalString(u.DateOfBirth) })},
+			{Name: "region", Description: "Data residency region; null when residency is disabled.", Type: graphql.String,
+				Resolve: resolveUser(func(u *models.UserResponse) any { return optionalString(u.Region) })},
+			{Name: "deletionDueAt", Description: "Only for pending_deletion accounts.", Type: dateTime, Resolve: resolveUser(func(u *models.UserResponse) any { return u.DeletionDueAt })},
+			{Name: "createdAt", Type: &graphql.NonNull{Of: dateTime}, Resolve: resolveUser(func(u *models.UserResponse) any { return u.CreatedAt })},
+			{
+				Name:        "loginHistory",
+				Description: "The user's sign-in attempts, newest first. Only for your own account.",
+				Args:        pageArgs(20),
+				Type:        &graphql.NonNull{Of: pageType("LoginAttemptPage", loginAttempt)},
+				Resolve:     h.resolveLoginHistory,
+			},
+		},
+	}
+	userMatch := &graphql.Object{
+		Name:        "UserMatch",
+		Description: "A user found by a search, with how well they matched.",
+		Fields: []*graphql.Field{
+			{Name: "user", Type: &graphql.NonNull{Of: user}, Resolve: func(ctx context.Context, source any, args map[string]any) (any, error) {
+				return &source.(*models.UserSearchResult).User, nil
+			}},
+			{Name: "score", Description: "From 0 to 1; higher is a closer match.", Type: &graphql.NonNull{Of: graphql.Float}, Resolve: func(ctx context.Context, source any, args map[string]any) (any, error) {
+				return source.(*models.UserSearchResult).Score, nil
+			}},
+		},
+	}
+	session := &graphql.Object{
+		Name:        "Session",
+		Description: "The session the request was made in.",
+		Fields: []*graphql.Field{
+			{Name: "id", Type: &graphql.NonNull{Of: graphql.ID}, Resolve: resolveSession(func(s *graphQLSession) any { return s.id })},
+			{Name: "userId", Type: &graphql.NonNull{Of: graphql.ID}, Resolve: resolveSession(func(s *graphQLSession) any { return s.userID })},
+			{Name: "role", Type: &graphql.NonNull{Of: graphql.String}, Resolve: resolveSession(func(s *graphQLSession) any { return s.role })},
+			{Name: "scopes", Description: "What the session may do beyond its own account.", Type: &graphql.NonNull{Of: &graphql.List{Of: &graphql.NonNull{Of: graphql.String}}},
+				Resolve: resolveSession(func(s *graphQLSession) any { return s.scopes })},
+		},
+	}
+
+	nonNullString := &graphql.NonNull{Of: graphql.String}
+	return &graphql.Object{
+		Name: "Query",
+		Fields: []*graphql.Field{
+			{Name: "me", Description: "The signed-in user.", Type: &graphql.NonNull{Of: user}, Resolve: h.resolveMe},
+			{Name: "session", Description: "The session the request was made in.", Type: &graphql.NonNull{Of: session}, Resolve: resolveCurrentSession},
+			{
+				Name:        "user",
+				Description: "A user by ID: your own account, or any with the users:read scope. Null if there is none.",
+				Args:        []*graphql.Argument{{Name: "id", Type: &graphql.NonNull{Of: graphql.ID}}},
+				Type:        user,
+				Resolve:     h.resolveUser,
+			},
+			{
+				Name:        "userByEmail",
+				Description: "A user by email address; needs the users:read scope. Null if there is none.",
+				Args:        []*graphql.Argument{{Name: "email", Type: nonNullString}},
+				Type:        user,
+				Resolve:     h.resolveUserByEmail,
+			},
+			{
+				Name:        "userByUsername",
+				Description: "A user by username, ignoring case; needs the users:read scope. Null if there is none.",
+				Args:        []*graphql.Argument{{Name: "username", Type: nonNullString}},
+				Type:        user,
+				Resolve:     h.resolveUserByUsername,
+			},
+			{
+				Name:        "users",
+				Description: "Users oldest first, a page at a time; needs the users:read scope.",
+				Args:        pageArgs(20),
+				Type:        &graphql.NonNull{Of: pageType("UserPage", user)},
+				Resolve:     h.resolveUsers,
+			},
+			{
+				Name:        "searchUsers",
+				Description: "Users whose name, username, or email resembles query, best match first; needs the users:read scope.",
+				Args:        []*graphql.Argument{{Name: "query", Type: nonNullString}, {Name: "first", Type: graphql.Int, Default: 20}},
+				Type:        &graphql.NonNull{Of: &graphql.List{Of: &graphql.NonNull{Of: userMatch}}},
+				Resolve:     h.resolveSearchUsers,
+			},
+		},
+	}
+}
+
+// pageArgs are the arguments of a paged list: its size, and the endCursor of the page before.
+func pageArgs(first int) []*graphql.Argument {
+	return []*graphql.Argument{
+		{Name: "first", Description: "Items per page; capped by the service.", Type: graphql.Int, Default: first},
+		{Name: "after", Description: "The endCursor of the previous page.", Type: graphql.String},
+	}
+}
+
+// pageType is a page of a list of item. Pages continue as long as they hold items; the list ends with
+// an empty page, whose endCursor is null.
+func pageType(name string, item *graphql.Object) *graphql.Object {
+	return &graphql.Object{
+		Name:        name,
+		Description: "A page of a list.",
+		Fields: []*graphql.Field{
+			{Name: "nodes", Type: &graphql.NonNull{Of: &graphql.List{Of: &graphql.NonNull{Of: item}}}, Resolve: func(ctx context.Context, source any, args map[string]any) (any, error) {
+				return source.(*graphQLPage).nodes, nil
+			}},
+			{Name: "endCursor", Description: "Pass as after for the next page; null on an empty page.", Type: graphql.String,
+				Resolve: func(ctx context.Context, source any, args map[string]any) (any, error) {
+					return source.(*graphQLPage).endCursor, nil
+				}},
+		},
+	}
+}
+
+type graphQLResolver = func(ctx context.Context, source any, args map[string]any) (any, error)
+
+func resolveUser(get func(*models.UserResponse) any) graphQLResolver {
+	return func(ctx context.Context, source any, args map[string]any) (any, error) {
+		return get(source.(*models.UserResponse)), nil
+	}
+}
+
+func resolveQuantity(get func(*models.UserResponse) any) graphQLResolver {
+	return resolveUser(get) // Quantity resolves from its user, so it can read HeightInUnits
+}
+
+func resolveLoginAttempt(get func(*models.LoginAttempt) any) graphQLResolver {
+	return func(ctx context.Context, source any, args map[string]any) (any, error) {
+		return get(source.(*models.LoginAttempt)), nil
+	}
+}
+
+func resolveSession(get func(*graphQLSession) any) graphQLResolver {
+	return func(ctx context.Context, source any, args map[string]any) (any, error) {
+		return get(source.(*graphQLSession)), nil
+	}
+}
+
+// optionalString returns nil for an empty string, which the REST API leaves out.
+func optionalString(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// graphQLCaller returns the ID of the user AuthMiddleware authenticated.
+func graphQLCaller(ctx context.Context) (uuid.UUID, error) {
+	caller, _ := ctx.Value(UserContextKey).(string)
+	id, err := uuid.Parse(caller)
+	if err != nil {
+		return uuid.Nil, graphql.NewError("UNAUTHENTICATED", "Unauthorized")
+	}
+	return id, nil
+}
+
+// requireGraphQLScope fails unless the caller was granted scope, as RequireScope does for REST routes.
+func requireGraphQLScope(ctx context.Context, scope string) error {
+	scopes, _ := ctx.Value(ScopesContextKey).([]string)
+	if !slices.Contains(scopes, scope) {
+		return graphql.NewError("FORBIDDEN", "Forbidden: missing required scope "+scope)
+	}
+	return nil
+}
+
+// graphQLUserError maps an error from the user service as the REST handlers do: a missing user is
+// null, invalid input is shown, and anything else is logged and reported as a failure to action.
+func graphQLUserError(ctx context.Context, err error, action string) error {
+	switch {
+	case errors.Is(err, services.ErrNotFound):
+		return nil
+	case errors.Is(err, services.ErrInvalidInput):
+		return graphql.NewError("BAD_USER_INPUT", err.Error())
+	}
+	logger.FromContext(ctx).Errorf("GraphQL: failed to %s: %v", action, err)
+	return graphql.NewError("INTERNAL", "Failed to "+action)
+}
+
+func (h *GraphQLHandler) resolveMe(ctx context.Context, _ any, _ map[string]any) (any, error) {
+	callerID, err := graphQLCaller(ctx)
+	if err != nil {
+		return nil, err
+	}
+	user, err := h.userService.GetUserByID(ctx, callerID)
+	if err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			return nil, graphql.NewError("NOT_FOUND", "User not found")
+		}
+		return nil, graphQLUserError(ctx, err, "get user")
+	}
+	return user, nil
+}
+
+func resolveCurrentSession(ctx context.Context, _ any, _ map[string]any) (any, error) {
+	callerID, err := graphQLCaller(ctx)
+	if err != nil {
+		return nil, err
+	}
+	session := &graphQLSession{userID: callerID.String(), scopes: []string{}}
+	session.id, _ = ctx.Value(SessionContextKey).(string)
+	session.role, _ = ctx.Value(RoleContextKey).(string)
+	if scopes, ok := ctx.Value(ScopesContextKey).([]string); ok {
+		session.scopes = scopes
+	}
+	return session, nil
+}
+
+func (h *GraphQLHandler) resolveUser(ctx context.Context, _ any, args map[string]any) (any, error) {
+	callerID, err := graphQLCaller(ctx)
+	if err != nil {
+		return nil, err
+	}
+	id, err := uuid.Parse(args["id"].(string))
+	if err != nil {
+		return nil, graphql.NewError("BAD_USER_INPUT", "Invalid user ID format")
+	}
+	if id != callerID {
+		if err := requireGraphQLScope(ctx, models.ScopeUsersRead); err != nil {
+			return nil, err
+		}
+	}
+	user, err := h.userService.GetUserByID(ctx, id)
+	if err != nil {
+		return nil, graphQLUserError(ctx, err, "get user")
+	}
+	return user, nil
+}
+
+func (h *GraphQLHandler) resolveUserByEmail(ctx context.Context, _ any, args map[string]any) (any, error) {
+	if err := requireGraphQLScope(ctx, models.ScopeUsersRead); err != nil {
+		return nil, err
+	}
+	user, err := h.userService.GetUserByEmail(ctx, args["email"].(string))
+	if err != nil {
+		return nil, graphQLUserError(ctx, err, "get user")
+	}
+	return user, nil
+}
+
+func (h *GraphQLHandler) resolveUserByUsername(ctx context.Context, _ any, args map[string]any) (any, error) {
+	if err := requireGraphQLScope(ctx, models.ScopeUsersRead); err != nil {
+		return nil, err
+	}
+	user, err := h.userService.GetUserByUsername(ctx, args["username"].(string))
+	if err != nil {
+		return nil, graphQLUserError(ctx, err, "get user")
+	}
+	return user, nil
+}
+
+func (h *GraphQLHandler) resolveUsers(ctx context.Context, _ any, args map[string]any) (any, error) {
+	if err := requireGraphQLScope(ctx, models.ScopeUsersRead); err != nil {
+		return nil, err
+	}
+	after, err := graphQLPageAfter(usersCursorScope, args)
+	if err != nil {
+		return nil, err
+	}
+	users, err := h.userService.GetAllUsers(ctx, after, args["first"].(int))
+	if err != nil {
+		return nil, graphQLUserError(ctx, err, "get users")
+	}
+	page := &graphQLPage{nodes: pointersTo(users)}
+	if len(users) > 0 {
+		last := users[len(users)-1]
+		page.endCursor = graphQLCursor(usersCursorScope, models.PageKey{At: last.CreatedAt, ID: last.ID})
+	}
+	return page, nil
+}
+
+func (h *GraphQLHandler) resolveSearchUsers(ctx context.Context, _ any, args map[string]any) (any, error) {
+	if err := requireGraphQLScope(ctx, models.ScopeUsersRead); err != nil {
+		return nil, err
+	}
+	results, err := h.userService.SearchUsers(ctx, args["query"].(string), args["first"].(int))
+	if err != nil {
+		return nil, graphQLUserError(ctx, err, "search users")
+	}
+	return pointersTo(results), nil
+}
+
+func (h *GraphQLHandler) resolveLoginHistory(ctx context.Context, source any, args map[string]any) (any, error) {
+	callerID, err := graphQLCaller(ctx)
+	if err != nil {
+		return nil, err
+	}
+	user := source.(*models.UserResponse)
+	if user.ID != callerID {
+		return nil, graphql.NewError("FORBIDDEN", "Forbidden: login history is only available for your own account")
+	}
+	filter := models.LoginAttemptFilter{UserID: user.ID, Limit: args["first"].(int)}
+	if filter.After, err = graphQLPageAfter(loginHistoryCursorScope, args); err != nil {
+		return nil, err
+	}
+	attempts, err := h.authService.GetLoginHistory(ctx, filter)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("GraphQL: failed to get login history for user %s: %v", user.ID, err)
+		return nil, graphql.NewError("INTERNAL", "Failed to get login history")
+	}
+	page := &graphQLPage{nodes: pointersTo(attempts)}
+	if len(attempts) > 0 {
+		last := attempts[len(attempts)-1]
+		page.endCursor = graphQLCursor(loginHistoryCursorScope, models.PageKey{At: last.CreatedAt, ID: last.ID})
+	}
+	return page, nil
+}
+
+// graphQLPageAfter returns the position the after argument resumes after, or nil for the first page.
+func graphQLPageAfter(scope string, args map[string]any) (*models.PageKey, error) {
+	cursor, _ := args["after"].(string)
+	if cursor == "" {
+		return nil, nil
+	}
+	pos, err := pagination.Decode(scope, cursor)
+	if err != nil {
+		return nil, graphql.NewError("BAD_USER_INPUT", "Invalid 'after' cursor")
+	}
+	return &models.PageKey{At: pos.At, ID: pos.ID}, nil
+}
+
+func graphQLCursor(scope string, last models.PageKey) *string {
+	cursor := pagination.Encode(scope, pagination.Position{At: last.At, ID: last.ID})
+	return &cursor
+}
+
+// pointersTo returns pointers to the items of a slice, which the object types resolve from.
+func pointersTo[T any](items []T) []*T {
+	pointers := make([]*T, len(items))
+	for i := range items {
+		pointers[i] = &items[i]
+	}
+	return pointers
+}
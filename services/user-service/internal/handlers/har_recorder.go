@@ -0,0 +1,153 @@
+// services/user-service/internal/handlers/har_recorder.go
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	"health-tracker-project/services/user-service/internal/models"
+)
+
+// redactedHeaders are replaced by "[redacted]" in recordings, so a HAR file never holds credentials.
+var redactedHeaders = map[string]bool{
+	http.CanonicalHeaderKey(APIKeyHeader): true,
+	"Authorization":                       true,
+	"Cookie":                              true,
+	"Set-Cookie":                          true,
+}
+
+// harRecorder captures a public API exchange for debug recording: the request as received and the
+// status, headers, and body of the response, each body up to models.MaxRecordedBodyBytes.
+type harRecorder struct {
+	http.ResponseWriter
+	start       time.Time
+	status      int
+	reqBody     []byte
+	reqComplete bool // Whether reqBody holds the whole request body
+	respBody    bytes.Buffer
+	respSize    int
+}
+
+// newHARRecorder starts recording r. It reads up to models.MaxRecordedBodyBytes of the request body
+// ahead of the handler and puts it back, so the handler still sees the full body.
+func newHARRecorder(w http.ResponseWriter, r *http.Request) *harRecorder {
+	h := &harRecorder{ResponseWriter: w, start: time.Now(), reqComplete: true}
+	if r.Body != nil && r.Body != http.NoBody {
+		buf, err := io.ReadAll(io.LimitReader(r.Body, models.MaxRecordedBodyBytes+1))
+		h.reqComplete = err == nil && len(buf) <= models.MaxRecordedBodyBytes
+		h.reqBody = buf[:min(len(buf), models.MaxRecordedBodyBytes)]
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(buf), r.Body), r.Body}
+	}
+	return h
+}
+
+func (h *harRecorder) WriteHeader(status int) {
+	if h.status == 0 {
+		h.status = status
+	}
+	h.ResponseWriter.WriteHeader(status)
+}
+
+func (h *harRecorder) Write(p []byte) (int, error) {
+	if h.status == 0 {
+		h.status = http.StatusOK
+	}
+	h.respSize += len(p)
+	if room := models.MaxRecordedBodyBytes - h.respBody.Len(); room > 0 {
+		h.respBody.Write(p[:min(len(p), room)])
+	}
+	return h.ResponseWriter.Write(p)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (h *harRecorder) Unwrap() http.ResponseWriter {
+	return h.ResponseWriter
+}
+
+// entry returns the recorded exchange as a HAR entry. Call it once the handler has returned.
+func (h *harRecorder) entry(r *http.Request) models.HAREntry {
+	elapsed := float64(time.Since(h.start).Microseconds()) / 1000
+	status := h.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	req := models.HARRequest{
+		Method:      r.Method,
+		URL:         scheme + "://" + r.Host + r.URL.RequestURI(),
+		HTTPVersion: r.Proto,
+		Cookies:     []models.HARNameValue{},
+		Headers:     harHeaders(r.Header),
+		QueryString: []models.HARNameValue{},
+		HeadersSize: -1,
+		BodySize:    int(r.ContentLength),
+	}
+	for name, values := range r.URL.Query() {
+		for _, v := range values {
+			req.QueryString = append(req.QueryString, models.HARNameValue{Name: name, Value: v})
+		}
+	}
+	sort.SliceStable(req.QueryString, func(i, j int) bool { return req.QueryString[i].Name < req.QueryString[j].Name })
+	if h.reqBody != nil {
+		req.PostData = &models.HARPostData{MimeType: r.Header.Get("Content-Type"), Text: string(h.reqBody)}
+		if !h.reqComplete {
+			req.PostData.Comment = fmt.Sprintf("truncated to the first %d bytes", models.MaxRecordedBodyBytes)
+		}
+	} else if req.BodySize < 0 {
+		req.BodySize = 0
+	}
+
+	content := models.HARContent{
+		Size:     h.respSize,
+		MimeType: h.Header().Get("Content-Type"),
+		Text:     h.respBody.String(),
+	}
+	if h.respSize > h.respBody.Len() {
+		content.Comment = fmt.Sprintf("truncated to the first %d bytes", models.MaxRecordedBodyBytes)
+	}
+	return models.HAREntry{
+		StartedDateTime: h.start.UTC(),
+		Time:            elapsed,
+		Request:         req,
+		Response: models.HARResponse{
+			Status:      status,
+			StatusText:  http.StatusText(status),
+			HTTPVersion: r.Proto,
+			Cookies:     []models.HARNameValue{},
+			Headers:     harHeaders(h.Header()),
+			Content:     content,
+			HeadersSize: -1,
+			BodySize:    h.respSize,
+		},
+		Timings: models.HARTimings{Wait: elapsed},
+	}
+}
+
+// harHeaders lists headers sorted by name, with credentials redacted.
+func harHeaders(header http.Header) []models.HARNameValue {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	out := []models.HARNameValue{}
+	for _, name := range names {
+		for _, v := range header[name] {
+			if redactedHeaders[http.CanonicalHeaderKey(name)] {
+				v = "[redacted]"
+			}
+			out = append(out, models.HARNameValue{Name: name, Value: v})
+		}
+	}
+	return out
+}
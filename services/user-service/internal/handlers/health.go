@@ -0,0 +1,100 @@
+// services/user-service/internal/handlers/health.go
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// HealthDependency is something the service relies on, checked on every GET /health.
+type HealthDependency struct {
+	Name     string                          // Shown in the response, such as "database" or "database:eu"
+	Critical bool                            // The service cannot serve requests while it fails
+	Check    func(ctx context.Context) error // Returns nil if the dependency is reachable
+}
+
+// HealthHandler reports whether the service and its dependencies are up.
+type HealthHandler struct {
+	dependencies []HealthDependency
+	timeout      time.Duration
+}
+
+// NewHealthHandler creates a HealthHandler checking each dependency, each given timeout to answer.
+func NewHealthHandler(timeout time.Duration, dependencies ...HealthDependency) *HealthHandler {
+	return &HealthHandler{dependencies: dependencies, timeout: timeout}
+}
+
+// dependencyHealth is the result of checking one dependency.
+type dependencyHealth struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"` // "ok" or "unavailable"
+	Critical  bool    `json:"critical"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"` // "timeout" or "unreachable"; the cause is logged, not shown
+}
+
+// healthResponse is the body of GET /health.
+type healthResponse struct {
+	Status       string             `json:"status"` // "ok", "degraded" if only non-critical dependencies fail, or "unavailable"
+	Dependencies []dependencyHealth `json:"dependencies"`
+}
+
+// Check handles GET /health requests. It answers 503 Service Unavailable when a critical dependency
+// fails, so load balancers and orchestrators stop sending traffic, and 200 OK otherwise.
+func (h *HealthHandler) Check(w http.ResponseWriter, r *http.Request) {
+	resp := healthResponse{Status: "ok", Dependencies: make([]dependencyHealth, len(h.dependencies))}
+	var wg sync.WaitGroup
+	for i, dep := range h.dependencies {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp.Dependencies[i] = h.check(r.Context(), dep)
+		}()
+	}
+	wg.Wait()
+
+	status := http.StatusOK
+	for _, dep := range resp.Dependencies {
+		if dep.Status == "ok" {
+			continue
+		}
+		if dep.Critical {
+			resp.Status = "unavailable"
+			status = http.StatusServiceUnavailable
+		} else if resp.Status == "ok" {
+			resp.Status = "degraded"
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	writeBody(w, resp)
+}
+
+// check runs one dependency's check within the timeout.
+func (h *HealthHandler) check(ctx context.Context, dep HealthDependency) dependencyHealth {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+	start := time.Now()
+	err := dep.Check(ctx)
+	result := dependencyHealth{
+		Name:      dep.Name,
+		Status:    "ok",
+		Critical:  dep.Critical,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = "unavailable"
+		result.Error = "unreachable"
+		if ctx.Err() == context.DeadlineExceeded {
+			result.Error = "timeout"
+		}
+		logger.FromContext(ctx).Warnf("Health check of %s failed: %v", dep.Name, err)
+	}
+	return result
+}
@@ -0,0 +1,105 @@
+// services/user-service/internal/handlers/identity.go
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/services"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// IdentityHandler holds dependencies for linking SSO identities to accounts.
+type IdentityHandler struct {
+	identityService services.IdentityService
+	authService     services.AuthService
+	auditor         *Auditor
+}
+
+// NewIdentityHandler creates a new IdentityHandler instance.
+func NewIdentityHandler(identityService services.IdentityService, authService services.AuthService, auditor *Auditor) *IdentityHandler {
+	return &IdentityHandler{identityService: identityService, authService: authService, auditor: auditor}
+}
+
+// writeLinkChallenge answers an SSO sign-in whose email belongs to an account the identity is not linked to yet.
+func writeLinkChallenge(w http.ResponseWriter, challenge *models.IdentityLinkChallenge) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusConflict)
+	writeBody(w, challenge)
+}
+
+// Link handles POST /auth/link, completing a link challenge with the account's password or the emailed code.
+// On success the identity is linked and the user is signed in.
+func (h *IdentityHandler) Link(w http.ResponseWriter, r *http.Request) {
+	var req models.LinkIdentityRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	req.Client = h.auditor.client(r)
+
+	authResponse, err := h.authService.CompleteIdentityLink(r.Context(), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidInput):
+			writeError(w, http.StatusBadRequest, models.ErrorCodeValidationFailed, serviceMessage(err))
+		case errors.Is(err, services.ErrInvalidToken) || errors.Is(err, services.ErrInvalidCredentials):
+			h.auditor.Record(r, models.AuditEvent{
+				Action:  models.AuditIdentityLink,
+				Outcome: models.AuditFailure,
+				Details: map[string]string{"reason": strings.TrimPrefix(err.Error(), "service: ")},
+			})
+			code := models.ErrorCodeInvalidCredentials
+			if errors.Is(err, services.ErrInvalidToken) {
+				code = models.ErrorCodeInvalidToken
+			}
+			writeError(w, http.StatusUnauthorized, code, serviceMessage(err))
+		case errors.Is(err, services.ErrAccountInactive):
+			writeError(w, http.StatusForbidden, models.ErrorCodeAccountInactive, serviceMessage(err))
+		case errors.Is(err, services.ErrConflict): // A concurrent request linked the identity first
+			writeError(w, http.StatusConflict, models.ErrorCodeConflict, "Identity is already linked")
+		default:
+			logger.FromContext(r.Context()).Errorf("Error completing identity link: %v", err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to link identity")
+		}
+		return
+	}
+
+	userID := authResponse.User.ID.String()
+	h.auditor.Record(r, models.AuditEvent{Action: models.AuditIdentityLink, Outcome: models.AuditSuccess, ActorID: userID, TargetID: userID})
+	h.auditor.Record(r, models.AuditEvent{
+		Action:   models.AuditLogin,
+		Outcome:  models.AuditSuccess,
+		ActorID:  userID,
+		TargetID: userID,
+		Details:  map[string]string{"method": "identity_link"},
+	})
+
+	setAuthCookie(w, authResponse)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, authResponse)
+	logger.FromContext(r.Context()).Infof("Identity linked and user logged in: %s", userID)
+}
+
+// ListIdentities handles GET /me/identities, listing the SSO identities linked to the caller's account.
+func (h *IdentityHandler) ListIdentities(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+
+	identities, err := h.identityService.ListIdentities(r.Context(), userID)
+	if err != nil {
+		logger.FromContext(r.Context()).Errorf("Error listing identities for user %s: %v", userID, err)
+		writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to list identities")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, identities)
+}
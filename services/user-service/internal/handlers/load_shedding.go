@@ -0,0 +1,237 @@
+// services/user-service/internal/handlers/load_shedding.go
+package handlers
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"mime"
+	"net/http"
+	"runtime"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"health-tracker-project/services/user-service/internal/config"
+	"health-tracker-project/services/user-service/internal/metrics"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+	"health-tracker-project/services/user-service/internal/utils/websocket"
+)
+
+// defaultQueueTimeout is how long a queued request waits for its route when the limit sets no timeout.
+const defaultQueueTimeout = time.Second
+
+// latencySmoothing is the weight of the latest sample in the smoothed latency; lower values react slower
+// to spikes but shed less on a single slow interval.
+const latencySmoothing = 0.3
+
+// paddedRoutes are padded by the auth service to a minimum response time, so unknown emails, wrong
+// passwords, and existing accounts look alike. Their latency is mostly that floor, whatever the load,
+// so they are left out of the latency signal.
+var paddedRoutes = []string{"POST /register", "POST /login", "POST /auth/forgot-password"}
+
+// routeLimiter lets at most a route's max_in_flight requests through at once and queues the next
+// max_queue in arrival order.
+type routeLimiter struct {
+	mu       sync.Mutex
+	inFlight int
+	waiters  []chan struct{} // Closed when the waiter is let through
+}
+
+// acquire waits for a slot on the route. It returns "" once the request may proceed, or the reason it
+// was refused. Every successful acquire must be paired with a release.
+func (l *routeLimiter) acquire(ctx context.Context, limit config.ConcurrencyLimit) string {
+	l.mu.Lock()
+	if limit.MaxInFlight <= 0 || l.inFlight < limit.MaxInFlight {
+		l.inFlight++
+		l.mu.Unlock()
+		return ""
+	}
+	if len(l.waiters) >= limit.MaxQueue {
+		l.mu.Unlock()
+		return metrics.ShedQueueFull
+	}
+	ready := make(chan struct{})
+	l.waiters = append(l.waiters, ready)
+	l.mu.Unlock()
+
+	metrics.RequestsInFlight(0, 1)
+	defer metrics.RequestsInFlight(0, -1)
+	timeout := time.Duration(limit.QueueTimeoutMS) * time.Millisecond
+	if timeout == 0 {
+		timeout = defaultQueueTimeout
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-ready:
+		return ""
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if i := slices.Index(l.waiters, ready); i >= 0 {
+		l.waiters = slices.Delete(l.waiters, i, i+1)
+		return metrics.ShedQueueTimeout
+	}
+	return "" // Let through as the wait ended
+}
+
+// release frees a slot and lets queued requests through while the route is under its limit, which
+// may have changed on a config reload since they queued.
+func (l *routeLimiter) release(limit config.ConcurrencyLimit) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.inFlight--
+	for len(l.waiters) > 0 && (limit.MaxInFlight <= 0 || l.inFlight < limit.MaxInFlight) {
+		close(l.waiters[0])
+		l.waiters = l.waiters[1:]
+		l.inFlight++
+	}
+}
+
+// LoadShedder is an HTTP middleware that protects the service and its database during traffic spikes
+// (see config.LoadShedding). Requests over their route's concurrency limit queue or are refused, and
+// while latency or CPU use is over target a share of all requests is refused. Refused requests get
+// 503 Service Unavailable with a Retry-After header. Settings are read on every request so a runtime
+// config reload takes effect immediately.
+type LoadShedder struct {
+	mux      *http.ServeMux
+	settings func() config.LoadShedding
+
+	mu     sync.Mutex
+	routes map[string]*routeLimiter
+
+	latencySum   atomic.Int64  // Nanoseconds handled since the last sample
+	latencyCount atomic.Int64  // Requests handled since the last sample
+	fraction     atomic.Uint64 // float64 bits; share of requests shed on latency or CPU
+}
+
+// NewLoadShedder creates a LoadShedder in front of mux, which it also asks for the route of each request.
+// Watch must run for shedding on latency or CPU to take effect.
+func NewLoadShedder(mux *http.ServeMux, settings func() config.LoadShedding) *LoadShedder {
+	return &LoadShedder{mux: mux, settings: settings, routes: make(map[string]*routeLimiter)}
+}
+
+func (s *LoadShedder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	settings := s.settings()
+	_, route := s.mux.Handler(r)
+	if route == "" {
+		s.mux.ServeHTTP(&envelopeWriter{ResponseWriter: w}, r) // No route matches: 404, 405, or a redirect
+		return
+	}
+	if slices.Contains(settings.ExemptRoutes, route) {
+		s.mux.ServeHTTP(w, r)
+		return
+	}
+
+	if fraction := math.Float64frombits(s.fraction.Load()); fraction > 0 && rand.Float64() < fraction {
+		s.refuse(w, r, route, metrics.ShedOverload, settings)
+		return
+	}
+	limit := settings.Limit(route)
+	limiter := s.limiter(route)
+	if reason := limiter.acquire(r.Context(), limit); reason != "" {
+		s.refuse(w, r, route, reason, settings)
+		return
+	}
+	defer func() { limiter.release(s.settings().Limit(route)) }()
+
+	metrics.RequestsInFlight(1, 0)
+	defer metrics.RequestsInFlight(-1, 0)
+	if websocket.IsUpgrade(r) || slices.Contains(paddedRoutes, route) {
+		// A WebSocket connection holds its slot while open, so a route limit caps open connections,
+		// but its lifetime says nothing about latency; nor does the time of a padded route.
+		s.mux.ServeHTTP(w, r)
+		return
+	}
+	start := time.Now()
+	defer func() {
+		if mediaType, _, _ := mime.ParseMediaType(w.Header().Get("Content-Type")); mediaType == "text/event-stream" {
+			return // Like a WebSocket connection, an event stream's lifetime is not its latency
+		}
+		s.latencySum.Add(int64(time.Since(start)))
+		s.latencyCount.Add(1)
+	}()
+	s.mux.ServeHTTP(w, r)
+}
+
+// limiter returns the limiter of a route, creating it on first use.
+func (s *LoadShedder) limiter(route string) *routeLimiter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l := s.routes[route]
+	if l == nil {
+		l = &routeLimiter{}
+		s.routes[route] = l
+	}
+	return l
+}
+
+func (s *LoadShedder) refuse(w http.ResponseWriter, r *http.Request, route, reason string, settings config.LoadShedding) {
+	// The mux never saw the request, so give its route to the SLO metrics wrapping this middleware.
+	metrics.SetRoute(w, route)
+	metrics.RequestShed(reason)
+	logger.FromContext(r.Context()).Debugf("Load shedding refused %s (%s)", route, reason)
+	retryAfter := settings.RetryAfterSeconds
+	if retryAfter == 0 {
+		retryAfter = config.DefaultRetryAfterSeconds
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	writeError(w, http.StatusServiceUnavailable, models.ErrorCodeOverloaded, "Service overloaded, retry later")
+}
+
+// Watch samples latency and CPU use every interval and sets the share of requests to shed. The share is
+// 1 - 1/overload, where overload is the largest ratio of a signal to its target, so at twice the target
+// latency half the requests are shed; it is capped at max_shed_fraction so some requests always get
+// through to measure recovery.
+func (s *LoadShedder) Watch(interval time.Duration) {
+	var smoothed float64 // Nanoseconds
+	lastCPU, cpuKnown := metrics.ProcessCPUTime()
+	last := time.Now()
+	for now := range time.Tick(interval) {
+		var latency float64
+		if n := s.latencyCount.Swap(0); n > 0 {
+			latency = float64(s.latencySum.Swap(0)) / float64(n)
+		}
+		smoothed = latencySmoothing*latency + (1-latencySmoothing)*smoothed
+
+		var cpu float64
+		if used, ok := metrics.ProcessCPUTime(); ok && cpuKnown {
+			cpu = float64(used-lastCPU) / (float64(now.Sub(last)) * float64(runtime.GOMAXPROCS(0)))
+			lastCPU = used
+		}
+		last = now
+
+		settings := s.settings()
+		overload := 0.0
+		if settings.TargetLatencyMS > 0 {
+			overload = smoothed / float64(time.Duration(settings.TargetLatencyMS)*time.Millisecond)
+		}
+		if settings.MaxCPU > 0 && cpuKnown {
+			overload = max(overload, cpu/settings.MaxCPU)
+		}
+		fraction := 0.0
+		if overload > 1 {
+			maxFraction := settings.MaxShedFraction
+			if maxFraction == 0 {
+				maxFraction = config.DefaultMaxShedFraction
+			}
+			fraction = min(1-1/overload, maxFraction)
+		}
+
+		previous := math.Float64frombits(s.fraction.Swap(math.Float64bits(fraction)))
+		metrics.SetLoadSignals(time.Duration(smoothed), cpu, fraction)
+		if fraction > 0 && previous == 0 {
+			logger.Logger.Warnf("Load shedding started: latency %s, CPU %.0f%%, shedding %.0f%% of requests",
+				time.Duration(smoothed).Round(time.Millisecond), cpu*100, fraction*100)
+		} else if fraction == 0 && previous > 0 {
+			logger.Logger.Infof("Load shedding stopped: latency %s, CPU %.0f%%", time.Duration(smoothed).Round(time.Millisecond), cpu*100)
+		}
+	}
+}
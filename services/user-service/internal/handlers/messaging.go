@@ -0,0 +1,414 @@
+// services/user-service/internal/handlers/messaging.go
+package handlers
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/services"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// MessagingHandler holds dependencies for coach messaging handlers.
+type MessagingHandler struct {
+	messagingService services.MessagingService
+	auditor          *Auditor
+}
+
+// NewMessagingHandler creates a new MessagingHandler instance.
+func NewMessagingHandler(messagingService services.MessagingService, auditor *Auditor) *MessagingHandler {
+	return &MessagingHandler{messagingService: messagingService, auditor: auditor}
+}
+
+// writeThreadError answers the errors shared by every thread endpoint, reporting whether it did.
+// Threads of other users are reported as not found, so their IDs cannot be probed.
+func writeThreadError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, services.ErrNotFound):
+		writeError(w, http.StatusNotFound, models.ErrorCodeThreadNotFound, "Thread not found")
+	case errors.Is(err, services.ErrForbidden):
+		writeError(w, http.StatusForbidden, models.ErrorCodeForbidden, "You are no longer authorized as this user's coach")
+	default:
+		return false
+	}
+	return true
+}
+
+// ListCoaches handles GET /me/coaches, the coaches the caller has authorized.
+func (h *MessagingHandler) ListCoaches(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+
+	coaches, err := h.messagingService.ListCoaches(r.Context(), userID)
+	if err != nil {
+		logger.FromContext(r.Context()).Errorf("Error listing coaches for user %s: %v", userID, err)
+		writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to list coaches")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, coaches)
+}
+
+// AuthorizeCoach handles PUT /me/coaches/{coach_id}, letting a coach message the caller.
+func (h *MessagingHandler) AuthorizeCoach(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+	coachID, err := uuid.Parse(r.PathValue("coach_id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid coach ID format")
+		return
+	}
+
+	auth, err := h.messagingService.AuthorizeCoach(r.Context(), userID, coachID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrNotFound):
+			writeError(w, http.StatusNotFound, models.ErrorCodeCoachNotFound, "Coach not found")
+		case errors.Is(err, services.ErrInvalidInput):
+			writeError(w, http.StatusBadRequest, models.ErrorCodeValidationFailed, "Cannot authorize yourself as a coach")
+		default:
+			logger.FromContext(r.Context()).Errorf("Error authorizing coach %s for user %s: %v", coachID, userID, err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to authorize coach")
+		}
+		return
+	}
+
+	h.auditor.Record(r, models.AuditEvent{
+		Action:   models.AuditCoachAuthorize,
+		Outcome:  models.AuditSuccess,
+		ActorID:  userID.String(),
+		TargetID: userID.String(),
+		Details:  map[string]string{"coach_id": coachID.String()},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, auth)
+}
+
+// RevokeCoach handles DELETE /me/coaches/{coach_id}. The coach can no longer read or post in the caller's threads.
+func (h *MessagingHandler) RevokeCoach(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+	coachID, err := uuid.Parse(r.PathValue("coach_id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid coach ID format")
+		return
+	}
+
+	if err := h.messagingService.RevokeCoach(r.Context(), userID, coachID); err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			writeError(w, http.StatusNotFound, models.ErrorCodeNotFound, "Coach is not authorized")
+		} else {
+			logger.FromContext(r.Context()).Errorf("Error revoking coach %s for user %s: %v", coachID, userID, err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to revoke coach")
+		}
+		return
+	}
+
+	h.auditor.Record(r, models.AuditEvent{
+		Action:   models.AuditCoachRevoke,
+		Outcome:  models.AuditSuccess,
+		ActorID:  userID.String(),
+		TargetID: userID.String(),
+		Details:  map[string]string{"coach_id": coachID.String()},
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListClients handles GET /coach/clients, the users who currently authorize the calling coach.
+func (h *MessagingHandler) ListClients(w http.ResponseWriter, r *http.Request) {
+	coachID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+
+	clients, err := h.messagingService.ListClients(r.Context(), coachID)
+	if err != nil {
+		logger.FromContext(r.Context()).Errorf("Error listing clients for coach %s: %v", coachID, err)
+		writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to list clients")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, clients)
+}
+
+// ListThreads handles GET /threads, the caller's threads with unread counts, most recently active first.
+func (h *MessagingHandler) ListThreads(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+
+	threads, err := h.messagingService.ListThreads(r.Context(), userID)
+	if err != nil {
+		logger.FromContext(r.Context()).Errorf("Error listing threads for %s: %v", userID, err)
+		writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to list threads")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, threads)
+}
+
+// CreateThread handles POST /threads, starting a thread with the caller's coach or client.
+func (h *MessagingHandler) CreateThread(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+	var req models.CreateThreadRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	thread, err := h.messagingService.CreateThread(r.Context(), userID, req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidInput) {
+			writeError(w, http.StatusBadRequest, models.ErrorCodeValidationFailed, serviceMessage(err))
+		} else {
+			logger.FromContext(r.Context()).Errorf("Error creating thread for %s: %v", userID, err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to create thread")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	writeBody(w, thread)
+}
+
+// ListMessages handles GET /threads/{id}/messages?cursor=&limit= requests, newest first. The Link
+// header holds the URL of the next page.
+func (h *MessagingHandler) ListMessages(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+	threadID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid thread ID format")
+		return
+	}
+
+	q := r.URL.Query()
+	filter := models.MessageFilter{ThreadID: threadID}
+	if filter.After, err = pageAfter(r); err != nil {
+		writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidCursor, "Invalid 'cursor'")
+		return
+	}
+	if v := q.Get("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil {
+			writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid 'limit', expected an integer")
+			return
+		}
+	}
+
+	messages, err := h.messagingService.ListMessages(r.Context(), userID, filter)
+	if err != nil {
+		if !writeThreadError(w, err) {
+			logger.FromContext(r.Context()).Errorf("Error listing messages of thread %s: %v", threadID, err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to list messages")
+		}
+		return
+	}
+
+	if len(messages) > 0 {
+		last := messages[len(messages)-1]
+		setNextPage(w, r, models.PageKey{At: last.CreatedAt, ID: last.ID})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, messages)
+}
+
+// SendMessage handles POST /threads/{id}/messages. Attachments are uploaded first and referenced by ID.
+func (h *MessagingHandler) SendMessage(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+	threadID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid thread ID format")
+		return
+	}
+	var req models.SendMessageRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	msg, err := h.messagingService.SendMessage(r.Context(), userID, threadID, req)
+	if err != nil {
+		switch {
+		case writeThreadError(w, err):
+		case errors.Is(err, services.ErrInvalidInput):
+			writeError(w, http.StatusBadRequest, models.ErrorCodeValidationFailed, serviceMessage(err))
+		case errors.Is(err, services.ErrConflict):
+			writeError(w, http.StatusConflict, models.ErrorCodeConflict, "Attachment was already sent or removed")
+		default:
+			logger.FromContext(r.Context()).Errorf("Error sending message in thread %s: %v", threadID, err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to send message")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	writeBody(w, msg)
+}
+
+// MarkRead handles POST /threads/{id}/read, setting read receipts on the other participant's messages.
+func (h *MessagingHandler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+	threadID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid thread ID format")
+		return
+	}
+
+	n, err := h.messagingService.MarkRead(r.Context(), userID, threadID)
+	if err != nil {
+		if !writeThreadError(w, err) {
+			logger.FromContext(r.Context()).Errorf("Error marking thread %s read: %v", threadID, err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to mark messages read")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, map[string]int64{"marked_read": n})
+}
+
+// UploadAttachment handles POST /threads/{id}/attachments?filename= requests. The request body is
+// the raw file; it must be sent in a message within a day or it is deleted.
+func (h *MessagingHandler) UploadAttachment(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+	threadID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid thread ID format")
+		return
+	}
+
+	extendDeadlines(w, transferTimeout)
+	body := http.MaxBytesReader(w, r.Body, services.MaxAttachmentBytes)
+	attachment, err := h.messagingService.UploadAttachment(r.Context(), userID, threadID, r.URL.Query().Get("filename"), body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		switch {
+		case writeThreadError(w, err):
+		case errors.Is(err, services.ErrTooLarge) || errors.As(err, &tooLarge):
+			writeError(w, http.StatusRequestEntityTooLarge, models.ErrorCodePayloadTooLarge, "Attachment is too large")
+		case errors.Is(err, services.ErrUnsupportedType):
+			writeError(w, http.StatusUnsupportedMediaType, models.ErrorCodeUnsupportedMediaType, serviceMessage(err))
+		case errors.Is(err, services.ErrInvalidInput):
+			writeError(w, http.StatusBadRequest, models.ErrorCodeValidationFailed, "Attachment is empty")
+		default:
+			logger.FromContext(r.Context()).Errorf("Error uploading attachment to thread %s: %v", threadID, err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to upload attachment")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	writeBody(w, attachment)
+}
+
+// GetAttachment handles GET /threads/{id}/attachments/{attachment_id}, streaming the file as a download.
+func (h *MessagingHandler) GetAttachment(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+	threadID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid thread ID format")
+		return
+	}
+	attachmentID, err := uuid.Parse(r.PathValue("attachment_id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid attachment ID format")
+		return
+	}
+
+	attachment, content, err := h.messagingService.GetAttachment(r.Context(), userID, threadID, attachmentID)
+	if err != nil {
+		switch {
+		case writeThreadError(w, err):
+		case errors.Is(err, services.ErrNotFound):
+			writeError(w, http.StatusNotFound, models.ErrorCodeAttachmentNotFound, "Attachment not found")
+		default:
+			logger.FromContext(r.Context()).Errorf("Error reading attachment %s: %v", attachmentID, err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to read attachment")
+		}
+		return
+	}
+	defer content.Close()
+
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(attachment.Size, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	extendDeadlines(w, transferTimeout)
+	if _, err := io.Copy(w, content); err != nil {
+		logger.FromContext(r.Context()).Warnf("Error streaming attachment %s: %v", attachmentID, err)
+	}
+}
+
+// Export handles GET /me/messages/export, downloading all of the caller's threads and messages as JSON.
+func (h *MessagingHandler) Export(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+
+	export, err := h.messagingService.Export(r.Context(), userID)
+	if err != nil {
+		logger.FromContext(r.Context()).Errorf("Error exporting messages for %s: %v", userID, err)
+		writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to export messages")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", `attachment; filename="messages.json"`)
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, export)
+}
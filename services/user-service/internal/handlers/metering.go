@@ -0,0 +1,134 @@
+// services/user-service/internal/handlers/metering.go
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/errreport"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/services"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// meteringRecorder remembers the status code written by a handler.
+type meteringRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (m *meteringRecorder) WriteHeader(status int) {
+	if m.status == 0 {
+		m.status = status
+	}
+	m.ResponseWriter.WriteHeader(status)
+}
+
+func (m *meteringRecorder) Write(p []byte) (int, error) {
+	if m.status == 0 {
+		m.status = http.StatusOK
+	}
+	return m.ResponseWriter.Write(p)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (m *meteringRecorder) Unwrap() http.ResponseWriter {
+	return m.ResponseWriter
+}
+
+// MeterAPICalls records an api_calls event, dimensioned by route pattern, for every authenticated
+// request that did not fail on the server side, except synthetic journeys. Like metrics.Middleware it must wrap the ServeMux
+// (or metrics.Middleware) directly to see the matched pattern.
+func MeterAPICalls(metering services.MeteringService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rec := &meteringRecorder{ResponseWriter: w}
+			next.ServeHTTP(rec, r)
+
+			if rec.status >= http.StatusInternalServerError || r.Pattern == "" || isSynthetic(r) {
+				return // Our failures and our own probes are not billable
+			}
+			userID, err := uuid.Parse(errreport.User(r.Context())) // Set by AuthMiddleware once the token is verified
+			if err != nil {
+				return
+			}
+			id := uuid.New()
+			metering.Record(r.Context(), models.MeteringEvent{
+				ID:             id,
+				IdempotencyKey: models.MeterAPICalls + ":" + id.String(), // Each request is counted once
+				UserID:         userID,
+				Meter:          models.MeterAPICalls,
+				Dimension:      r.Pattern,
+				Quantity:       1,
+			})
+		})
+	}
+}
+
+// recordPremiumUsage records one use of a premium feature by a user. key must identify the use,
+// e.g. the session it started, so a retried write is not billed twice.
+func recordPremiumUsage(ctx context.Context, metering services.MeteringService, userID uuid.UUID, feature, key string) {
+	metering.Record(ctx, models.MeteringEvent{
+		IdempotencyKey: models.MeterPremiumFeature + ":" + feature + ":" + key,
+		UserID:         userID,
+		Meter:          models.MeterPremiumFeature,
+		Dimension:      feature,
+		Quantity:       1,
+	})
+}
+
+// MeteringHandler holds dependencies for usage metering admin handlers.
+type MeteringHandler struct {
+	meteringService services.MeteringService
+}
+
+// NewMeteringHandler creates a new MeteringHandler instance.
+func NewMeteringHandler(meteringService services.MeteringService) *MeteringHandler {
+	return &MeteringHandler{meteringService: meteringService}
+}
+
+// Reconciliation handles GET /admin/metering/reconciliation?since=&until=&user_id= requests.
+// since/until are RFC 3339 timestamps; the period defaults to the last month.
+func (h *MeteringHandler) Reconciliation(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	var filter models.MeteringFilter
+
+	var err error
+	if v := q.Get("since"); v != "" {
+		if filter.Since, err = time.Parse(time.RFC3339, v); err != nil {
+			writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid 'since' timestamp, expected RFC 3339")
+			return
+		}
+	}
+	if v := q.Get("until"); v != "" {
+		if filter.Until, err = time.Parse(time.RFC3339, v); err != nil {
+			writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid 'until' timestamp, expected RFC 3339")
+			return
+		}
+	}
+	if v := q.Get("user_id"); v != "" {
+		if filter.UserID, err = uuid.Parse(v); err != nil {
+			writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid user ID format")
+			return
+		}
+	}
+
+	report, err := h.meteringService.Reconcile(r.Context(), filter)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidInput) {
+			writeError(w, http.StatusBadRequest, models.ErrorCodeValidationFailed, serviceMessage(err))
+		} else {
+			logger.FromContext(r.Context()).Errorf("Error building metering reconciliation report: %v", err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to build reconciliation report")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, report)
+	logger.FromContext(r.Context()).Debugf("Built metering reconciliation report for %d meters", len(report.Meters))
+}
@@ -0,0 +1,135 @@
+// services/user-service/internal/handlers/oidc.go
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"health-tracker-project/services/user-service/internal/auth/oidc"
+	"health-tracker-project/services/user-service/internal/config"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/services"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// oidcStateCookie carries the state and nonce between the login redirect and the callback.
+const oidcStateCookie = "oidc_state"
+
+// OIDCHandlers holds dependencies for OpenID Connect sign-in handlers.
+type OIDCHandlers struct {
+	provider    *oidc.Provider
+	authService services.AuthService
+	auditor     *Auditor
+	metering    services.MeteringService // SSO sign-ins are billed as a premium feature
+}
+
+// NewOIDCHandlers creates a new OIDCHandlers instance.
+func NewOIDCHandlers(provider *oidc.Provider, authService services.AuthService, auditor *Auditor, metering services.MeteringService) *OIDCHandlers {
+	return &OIDCHandlers{provider: provider, authService: authService, auditor: auditor, metering: metering}
+}
+
+// Login handles GET /auth/oidc/login by redirecting the browser to the identity provider.
+func (h *OIDCHandlers) Login(w http.ResponseWriter, r *http.Request) {
+	state, errState := randomString()
+	nonce, errNonce := randomString()
+	if errState != nil || errNonce != nil {
+		logger.FromContext(r.Context()).Error("Failed to generate OIDC state/nonce")
+		writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to start sign-in")
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state + "." + nonce,
+		Expires:  time.Now().Add(10 * time.Minute),
+		HttpOnly: true,
+		Secure:   config.CookieSettings().Secure,
+		SameSite: http.SameSiteLaxMode, // Always Lax so the cookie survives the top-level redirect back from the provider
+		Path:     "/auth/oidc",
+	})
+	http.Redirect(w, r, h.provider.AuthCodeURL(state, nonce), http.StatusFound)
+}
+
+// Callback handles GET /auth/oidc/callback, completing the code exchange and signing the user in.
+func (h *OIDCHandlers) Callback(w http.ResponseWriter, r *http.Request) {
+	cookie, err := r.Cookie(oidcStateCookie)
+	if err != nil {
+		logger.FromContext(r.Context()).Debug("OIDC callback without state cookie.")
+		writeError(w, http.StatusBadRequest, models.ErrorCodeSignInFailed, "Sign-in session expired, please try again")
+		return
+	}
+	// The state cookie is single-use.
+	http.SetCookie(w, &http.Cookie{Name: oidcStateCookie, Value: "", Expires: time.Unix(0, 0), HttpOnly: true, Secure: config.CookieSettings().Secure, Path: "/auth/oidc"})
+
+	state, nonce, ok := strings.Cut(cookie.Value, ".")
+	if !ok || state == "" || r.URL.Query().Get("state") != state {
+		logger.FromContext(r.Context()).Warn("OIDC callback state mismatch.")
+		writeError(w, http.StatusBadRequest, models.ErrorCodeSignInFailed, "Invalid sign-in state")
+		return
+	}
+	if providerErr := r.URL.Query().Get("error"); providerErr != "" {
+		logger.FromContext(r.Context()).Warnf("OIDC provider returned error: %s", providerErr)
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeSignInFailed, "Identity provider rejected the sign-in: "+providerErr)
+		return
+	}
+	code := r.URL.Query().Get("code")
+	if code == "" {
+		writeError(w, http.StatusBadRequest, models.ErrorCodeSignInFailed, "Authorization code is required")
+		return
+	}
+
+	identity, err := h.provider.Exchange(r.Context(), code, nonce)
+	if err != nil {
+		logger.FromContext(r.Context()).Warnf("OIDC code exchange failed: %v", err)
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeSignInFailed, "Failed to verify sign-in with identity provider")
+		return
+	}
+
+	authResponse, challenge, err := h.authService.AuthenticateOIDC(r.Context(), identity, h.auditor.client(r))
+	if err != nil {
+		if errors.Is(err, services.ErrForbidden) {
+			h.auditor.Record(r, models.AuditEvent{
+				Action:  models.AuditLogin,
+				Outcome: models.AuditFailure,
+				Details: map[string]string{"method": "oidc", "issuer": identity.Issuer, "reason": strings.TrimPrefix(err.Error(), "service: ")},
+			})
+			writeError(w, http.StatusForbidden, models.ErrorCodeAccountInactive, serviceMessage(err))
+		} else {
+			logger.FromContext(r.Context()).Errorf("Error completing OIDC sign-in: %v", err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to authenticate")
+		}
+		return
+	}
+	if challenge != nil {
+		writeLinkChallenge(w, challenge)
+		return
+	}
+
+	h.auditor.Record(r, models.AuditEvent{
+		Action:   models.AuditLogin,
+		Outcome:  models.AuditSuccess,
+		ActorID:  authResponse.User.ID.String(),
+		TargetID: authResponse.User.ID.String(),
+		Details:  map[string]string{"method": "oidc", "issuer": identity.Issuer},
+	})
+	recordPremiumUsage(r.Context(), h.metering, authResponse.User.ID, "sso_oidc", state) // The state is single-use
+
+	setAuthCookie(w, authResponse)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, authResponse)
+	logger.FromContext(r.Context()).Infof("User logged in via OIDC: %s", authResponse.User.ID)
+}
+
+// randomString returns a URL-safe random value suitable for OIDC state and nonce.
+func randomString() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,130 @@
+// services/user-service/internal/handlers/onboarding.go
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/services"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// OnboardingHandler holds dependencies for onboarding HTTP handlers.
+type OnboardingHandler struct {
+	onboardingService services.OnboardingService
+}
+
+// NewOnboardingHandler creates a new OnboardingHandler instance.
+func NewOnboardingHandler(onboardingService services.OnboardingService) *OnboardingHandler {
+	return &OnboardingHandler{onboardingService: onboardingService}
+}
+
+// Recommend handles POST /onboarding/recommendations requests.
+func (h *OnboardingHandler) Recommend(w http.ResponseWriter, r *http.Request) {
+	var answers models.OnboardingAnswers
+	if !decodeJSON(w, r, &answers) {
+		return
+	}
+
+	recommendation, err := h.onboardingService.Recommend(r.Context(), answers)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidInput) {
+			writeError(w, http.StatusBadRequest, models.ErrorCodeValidationFailed, serviceMessage(err))
+		} else {
+			logger.FromContext(r.Context()).Errorf("Error building onboarding recommendations: %v", err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to build recommendations")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, recommendation)
+	logger.FromContext(r.Context()).Debugf("Onboarding recommendation served from rule %s", recommendation.Rule)
+}
+
+// GetState handles GET /users/me/onboarding requests.
+func (h *OnboardingHandler) GetState(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+
+	state, err := h.onboardingService.GetState(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			writeError(w, http.StatusNotFound, models.ErrorCodeUserNotFound, "User not found")
+		} else {
+			logger.FromContext(r.Context()).Errorf("Error retrieving onboarding state for %s: %v", userID, err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to retrieve onboarding state")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, state)
+}
+
+// Advance handles POST /users/me/onboarding requests.
+// The step must follow the caller's current one, or be done to skip the rest of the flow.
+func (h *OnboardingHandler) Advance(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+
+	var req models.OnboardingStepRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	state, err := h.onboardingService.Advance(r.Context(), userID, req.Step)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidInput):
+			writeError(w, http.StatusBadRequest, models.ErrorCodeValidationFailed, serviceMessage(err))
+		case errors.Is(err, services.ErrConflict):
+			writeError(w, http.StatusConflict, models.ErrorCodeConflict, serviceMessage(err))
+		case errors.Is(err, services.ErrNotFound):
+			writeError(w, http.StatusNotFound, models.ErrorCodeUserNotFound, "User not found")
+		default:
+			logger.FromContext(r.Context()).Errorf("Error advancing onboarding for %s: %v", userID, err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to update onboarding")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, state)
+}
+
+// GetFunnel handles GET /admin/onboarding/funnel?since= requests.
+// since is an RFC 3339 timestamp; only users registered since then are counted.
+func (h *OnboardingHandler) GetFunnel(w http.ResponseWriter, r *http.Request) {
+	var since time.Time
+	if v := r.URL.Query().Get("since"); v != "" {
+		var err error
+		if since, err = time.Parse(time.RFC3339, v); err != nil {
+			writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid 'since' timestamp, expected RFC 3339")
+			return
+		}
+	}
+
+	funnel, err := h.onboardingService.Funnel(r.Context(), since)
+	if err != nil {
+		logger.FromContext(r.Context()).Errorf("Error building onboarding funnel: %v", err)
+		writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to build onboarding funnel")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, funnel)
+}
@@ -0,0 +1,39 @@
+// services/user-service/internal/handlers/pagination.go
+package handlers
+
+import (
+	"net/http"
+
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/utils/pagination"
+)
+
+// pageScope names the list a request reads: its path and filters, that is every query parameter but
+// cursor and limit. A cursor is only accepted by requests with the scope it was issued for.
+func pageScope(r *http.Request) string {
+	q := r.URL.Query()
+	q.Del("cursor")
+	q.Del("limit")
+	return r.URL.Path + "?" + q.Encode() // Encode sorts by key
+}
+
+// pageAfter returns the position the cursor query parameter resumes after, or nil for the first page.
+func pageAfter(r *http.Request) (*models.PageKey, error) {
+	cursor := r.URL.Query().Get("cursor")
+	if cursor == "" {
+		return nil, nil
+	}
+	pos, err := pagination.Decode(pageScope(r), cursor)
+	if err != nil {
+		return nil, err
+	}
+	return &models.PageKey{At: pos.At, ID: pos.ID}, nil
+}
+
+// setNextPage links the page after last in the Link header (RFC 8288). Pages are linked as long as they
+// hold items; the list ends with an empty page.
+func setNextPage(w http.ResponseWriter, r *http.Request, last models.PageKey) {
+	q := r.URL.Query()
+	q.Set("cursor", pagination.Encode(pageScope(r), pagination.Position{At: last.At, ID: last.ID}))
+	w.Header().Set("Link", "<"+r.URL.Path+"?"+q.Encode()+`>; rel="next"`)
+}
@@ -0,0 +1,130 @@
+// services/user-service/internal/handlers/public_api.go
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"health-tracker-project/services/user-service/internal/config"
+	"health-tracker-project/services/user-service/internal/errreport"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/services"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+	"health-tracker-project/services/user-service/internal/utils/reqctx"
+)
+
+// PublicAPIPrefix is the path prefix of the public API for third-party developer apps.
+const PublicAPIPrefix = "/public/v1"
+
+// APIKeyHeader carries a developer app's API key on public API requests.
+const APIKeyHeader = "X-API-Key"
+
+// PublicAPI is an HTTP middleware for the public API. Requests must carry a developer app's key in
+// X-API-Key; session cookies are ignored. The app acts as its owner without any of the owner's
+// scopes, so only the owner's own data is reachable. Each app has its own token bucket
+// (rate_limits.public_api) and daily quota (public_api_daily_quota); requests over either get
+// 429 Too Many Requests with a Retry-After header. Every request is counted in the app's usage.
+// While the app's debug recording is on, each authenticated exchange is also recorded for its HAR
+// export, credentials redacted. Every handler wrapped by the same returned middleware shares one set of buckets.
+func PublicAPI(apps services.DeveloperAppService) func(http.Handler) http.Handler {
+	limiter := &ipRateLimiter{
+		limit:   func() config.RateLimit { return config.Current().RateLimits.PublicAPI },
+		buckets: make(map[string]*bucket),
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(APIKeyHeader)
+			if key == "" {
+				writeError(w, http.StatusUnauthorized, models.ErrorCodeInvalidAPIKey, "Unauthorized: API key required in "+APIKeyHeader)
+				return
+			}
+			app, err := apps.Authenticate(r.Context(), key)
+			if err != nil {
+				if errors.Is(err, services.ErrInvalidCredentials) {
+					logger.FromContext(r.Context()).Warnf("Unauthorized: invalid API key on %s %s", r.Method, r.URL.Path)
+					writeError(w, http.StatusUnauthorized, models.ErrorCodeInvalidAPIKey, "Unauthorized: Invalid API key")
+				} else {
+					logger.FromContext(r.Context()).Errorf("Error authenticating API key: %v", err)
+					writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to authenticate API key")
+				}
+				return
+			}
+			if apps.Recording(app) {
+				har := newHARRecorder(w, r)
+				defer func() { apps.RecordExchange(r.Context(), app.ID, har.entry(r)) }()
+				w = har
+			}
+
+			now := time.Now()
+			if ok, wait := limiter.allow(app.ID.String(), now); !ok {
+				logger.FromContext(r.Context()).Warnf("Public API rate limit exceeded by app %s on %s %s", app.ID, r.Method, r.URL.Path)
+				apps.RecordRequest(r.Context(), app.ID, r.Pattern, http.StatusTooManyRequests, true)
+				w.Header().Set("Retry-After", strconv.Itoa(max(int(math.Ceil(wait.Seconds())), 1)))
+				writeError(w, http.StatusTooManyRequests, models.ErrorCodeRateLimited, "Too many requests")
+				return
+			}
+			exceeded, err := apps.QuotaExceeded(r.Context(), app.ID)
+			if err != nil {
+				logger.FromContext(r.Context()).Errorf("Error checking the daily quota of app %s: %v", app.ID, err) // Fail open
+			}
+			if exceeded {
+				logger.FromContext(r.Context()).Warnf("Public API daily quota exceeded by app %s", app.ID)
+				apps.RecordRequest(r.Context(), app.ID, r.Pattern, http.StatusTooManyRequests, true)
+				midnight := now.UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(midnight.Sub(now).Seconds()))))
+				writeError(w, http.StatusTooManyRequests, models.ErrorCodeQuotaExceeded, "Daily quota exceeded")
+				return
+			}
+
+			owner := app.OwnerID.String()
+			ctx := r.Context()
+			ctx = context.WithValue(ctx, UserContextKey, owner)
+			ctx = context.WithValue(ctx, AppContextKey, app.ID.String())
+			ctx = reqctx.WithUserID(ctx, owner)
+			errreport.SetUser(ctx, owner)
+			rec := &meteringRecorder{ResponseWriter: w}
+			next.ServeHTTP(rec, r.WithContext(ctx))
+			apps.RecordRequest(r.Context(), app.ID, r.Pattern, rec.status, false)
+		})
+	}
+}
+
+// PublicAPISpec returns a handler serving the part of the OpenAPI document that covers the public
+// API: its paths and every component schema. It is computed once, at startup.
+func PublicAPISpec(spec []byte) (http.HandlerFunc, error) {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document: %w", err)
+	}
+	var paths map[string]json.RawMessage
+	if err := json.Unmarshal(doc["paths"], &paths); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI paths: %w", err)
+	}
+	public := map[string]json.RawMessage{}
+	for path, item := range paths {
+		if strings.HasPrefix(path, PublicAPIPrefix+"/") {
+			public[path] = item
+		}
+	}
+	var err error
+	if doc["paths"], err = json.Marshal(public); err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		w.WriteHeader(http.StatusOK)
+		w.Write(body)
+	}, nil
+}
@@ -0,0 +1,46 @@
+// services/user-service/internal/handlers/quicklog.go
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/services"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+	"health-tracker-project/services/user-service/internal/utils/reqctx"
+)
+
+// QuickLogHandler holds dependencies for quick-log HTTP handlers.
+type QuickLogHandler struct {
+	quickLogService services.QuickLogService
+}
+
+// NewQuickLogHandler creates a new QuickLogHandler instance.
+func NewQuickLogHandler(quickLogService services.QuickLogService) *QuickLogHandler {
+	return &QuickLogHandler{quickLogService: quickLogService}
+}
+
+// Parse handles POST /quicklog requests.
+func (h *QuickLogHandler) Parse(w http.ResponseWriter, r *http.Request) {
+	var req models.QuickLogRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	result, err := h.quickLogService.Parse(r.Context(), req.Text, reqctx.FromContext(r.Context()).Locale)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidInput) {
+			writeError(w, http.StatusBadRequest, models.ErrorCodeValidationFailed, serviceMessage(err))
+		} else {
+			logger.FromContext(r.Context()).Errorf("Error parsing quick log: %v", err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to parse quick log")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, result)
+	logger.FromContext(r.Context()).Debugf("Quick log read %d entries, rejected %d phrases", len(result.Entries), len(result.Rejected))
+}
@@ -0,0 +1,120 @@
+// services/user-service/internal/handlers/rate_limit.go
+package handlers
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"health-tracker-project/services/user-service/internal/config"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// rateLimitSweepInterval is how often idle client buckets are dropped.
+const rateLimitSweepInterval = time.Minute
+
+// bucket is one client's token bucket.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// ipRateLimiter keeps a token bucket per client IP. Limits are read on every
+// request so a runtime config reload takes effect immediately.
+type ipRateLimiter struct {
+	limit func() config.RateLimit
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// allow takes a token from the client's bucket. When the bucket is empty it
+// returns false and how long until the next token is available.
+func (l *ipRateLimiter) allow(client string, now time.Time) (bool, time.Duration) {
+	limit := l.limit()
+	if limit.RequestsPerMinute <= 0 {
+		return true, 0
+	}
+	rate := float64(limit.RequestsPerMinute) / 60 // Tokens per second
+	capacity := float64(max(limit.Burst, 1))
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) > rateLimitSweepInterval {
+		// A bucket idle long enough to refill completely is equivalent to no bucket.
+		refill := time.Duration(capacity / rate * float64(time.Second))
+		for key, b := range l.buckets {
+			if now.Sub(b.last) > refill {
+				delete(l.buckets, key)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &bucket{tokens: capacity, last: now}
+		l.buckets[client] = b
+	}
+	b.tokens = math.Min(capacity, b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / rate * float64(time.Second))
+}
+
+// RateLimit is an HTTP middleware that limits requests per client IP with a token bucket.
+// Requests over the limit get 429 Too Many Requests with a Retry-After header.
+// Every handler wrapped by the same returned middleware shares one set of buckets.
+// proxyHops is the number of trusted proxies in front of the service, which the client IP is read
+// behind in X-Forwarded-For (see clientIP); 0 uses the socket address.
+func RateLimit(name string, limit func() config.RateLimit, proxyHops int) func(http.Handler) http.Handler {
+	limiter := &ipRateLimiter{limit: limit, buckets: make(map[string]*bucket)}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			client := clientIP(r, proxyHops)
+			ok, wait := limiter.allow(client, time.Now())
+			if !ok {
+				retryAfter := int(math.Ceil(wait.Seconds()))
+				logger.FromContext(r.Context()).Warnf("Rate limit '%s' exceeded by %s on %s %s", name, client, r.Method, r.URL.Path)
+				w.Header().Set("Retry-After", strconv.Itoa(max(retryAfter, 1)))
+				writeError(w, http.StatusTooManyRequests, models.ErrorCodeRateLimited, "Too many requests")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// clientIP returns the IP address of the client that sent r. Behind proxyHops trusted proxies, each of
+// which appends the address it received the request from to X-Forwarded-For, the client is the entry
+// proxyHops from the right. Entries to its left were sent by the client, which can forge them, so they
+// are never used. A header with fewer entries than proxyHops came through fewer proxies, and its
+// left-most entry is the client.
+func clientIP(r *http.Request, proxyHops int) string {
+	if proxyHops > 0 {
+		var hops []string
+		for _, header := range r.Header.Values("X-Forwarded-For") {
+			hops = append(hops, strings.Split(header, ",")...)
+		}
+		if len(hops) > 0 {
+			if ip := net.ParseIP(strings.TrimSpace(hops[max(len(hops)-proxyHops, 0)])); ip != nil {
+				return ip.String()
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,152 @@
+// services/user-service/internal/handlers/realtime.go
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/config"
+	"health-tracker-project/services/user-service/internal/metrics"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/realtime"
+	"health-tracker-project/services/user-service/internal/services"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+	"health-tracker-project/services/user-service/internal/utils/pagination"
+	"health-tracker-project/services/user-service/internal/utils/websocket"
+)
+
+// eventStreamScope signs the IDs of streamed events, which are pagination cursors into the timeline.
+// It leaves out the type filter, so a client can resume with other types than it started with.
+const eventStreamScope = "GET /users/me/events"
+
+// RealtimeHandler holds dependencies for the WebSocket and Server-Sent Events streams.
+type RealtimeHandler struct {
+	hub          *realtime.Hub
+	eventService services.UserEventService
+}
+
+// NewRealtimeHandler creates a new RealtimeHandler instance.
+func NewRealtimeHandler(hub *realtime.Hub, eventService services.UserEventService) *RealtimeHandler {
+	return &RealtimeHandler{hub: hub, eventService: eventService}
+}
+
+// Stream handles GET /ws, upgrading the request to a WebSocket connection that receives the caller's
+// events, one JSON text message each, until the session ends or its token expires.
+func (h *RealtimeHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+	sessionID, _ := uuid.Parse(r.Context().Value(SessionContextKey).(string))
+	expiresAt, _ := r.Context().Value(ExpiresContextKey).(time.Time)
+
+	if !websocket.IsUpgrade(r) {
+		w.Header().Set("Upgrade", "websocket")
+		writeError(w, http.StatusUpgradeRequired, models.ErrorCodeUpgradeRequired, "This route only speaks WebSocket")
+		return
+	}
+	// Browsers send the session cookie with cross-site handshakes too, and CORS does not apply to them.
+	if !allowedWebSocketOrigin(r) {
+		logger.FromContext(r.Context()).Warnf("Refused WebSocket connection of user %s from origin %s", userID, r.Header.Get("Origin"))
+		writeError(w, http.StatusForbidden, models.ErrorCodeForbidden, "Origin not allowed")
+		return
+	}
+
+	client, err := h.hub.Register(metrics.TransportWebSocket, userID, sessionID, nil)
+	if errors.Is(err, realtime.ErrTooManyConnections) {
+		writeError(w, http.StatusTooManyRequests, models.ErrorCodeRateLimited, "Too many open connections")
+		return
+	}
+	// The connection stays open past the server's timeouts; the hub keeps it alive with pings and
+	// times out its reads and writes itself.
+	extendDeadlines(w, 0)
+	conn, err := websocket.Upgrade(w, r)
+	if err != nil {
+		h.hub.Unregister(client)
+		var handshake *websocket.HandshakeError
+		if errors.As(err, &handshake) {
+			code := models.ErrorCodeValidationFailed
+			if handshake.Status == http.StatusUpgradeRequired {
+				code = models.ErrorCodeUpgradeRequired
+			}
+			writeError(w, handshake.Status, code, "Invalid WebSocket handshake: "+handshake.Message)
+			return
+		}
+		logger.FromContext(r.Context()).Errorf("Failed to open WebSocket connection for user %s: %v", userID, err)
+		return // The connection is gone, so there is no one to answer
+	}
+
+	logger.FromContext(r.Context()).Debugf("WebSocket connection opened for user %s", userID)
+	h.hub.Serve(r.Context(), conn, client, expiresAt)
+}
+
+// Events handles GET /users/me/events?type= requests, streaming the caller's events as Server-Sent
+// Events for clients that cannot use WebSockets. type is a comma-separated list of event types to
+// receive. A Last-Event-ID header, sent by EventSource when it reconnects, resumes the stream after
+// that event with the ones stored on the timeline since.
+func (h *RealtimeHandler) Events(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+	sessionID, _ := uuid.Parse(r.Context().Value(SessionContextKey).(string))
+	expiresAt, _ := r.Context().Value(ExpiresContextKey).(time.Time)
+
+	var types []string
+	if v := r.URL.Query().Get("type"); v != "" {
+		for _, t := range strings.Split(v, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				types = append(types, t)
+			}
+		}
+	}
+	var since *models.PageKey
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		pos, err := pagination.Decode(eventStreamScope, id)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidCursor, "Invalid 'Last-Event-ID'")
+			return
+		}
+		since = &models.PageKey{At: pos.At, ID: pos.ID}
+	}
+
+	client, err := h.hub.Register(metrics.TransportSSE, userID, sessionID, types)
+	if errors.Is(err, realtime.ErrTooManyConnections) {
+		writeError(w, http.StatusTooManyRequests, models.ErrorCodeRateLimited, "Too many open connections")
+		return
+	}
+	// The stream stays open past the server's timeouts; ServeEvents times out each write of its own.
+	extendDeadlines(w, 0)
+	logger.FromContext(r.Context()).Debugf("Event stream opened for user %s", userID)
+	h.hub.ServeEvents(r.Context(), w, client, realtime.EventStream{
+		ExpiresAt: expiresAt,
+		EventID: func(e models.UserEvent) string {
+			return pagination.Encode(eventStreamScope, pagination.Position{At: e.OccurredAt, ID: e.ID})
+		},
+		Since: since,
+		Missed: func(since models.PageKey) ([]models.UserEvent, error) {
+			return h.eventService.GetTimeline(r.Context(), models.UserEventFilter{UserID: userID, Types: types, Since: &since})
+		},
+	})
+}
+
+// allowedWebSocketOrigin reports whether a handshake comes from the service's own origin, one of the
+// CORS allowed origins, or a client that is not a browser and sends none.
+func allowedWebSocketOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if u, err := url.Parse(origin); err == nil && u.Host == r.Host {
+		return true
+	}
+	allowed := config.Current().CORSAllowedOrigins
+	return slices.Contains(allowed, origin) || slices.Contains(allowed, "*")
+}
@@ -0,0 +1,38 @@
+// services/user-service/internal/handlers/recover.go
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"health-tracker-project/services/user-service/internal/errreport"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// Recover is an HTTP middleware that turns a panicking handler into a 500 response.
+// The panic is logged at error level, which also sends it to the error reporter, together with
+// the request ID (added by the request's logger) and the verified user ID (recorded on the reporting
+// scope by AuthMiddleware).
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := errreport.WithScope(r.Context())
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			if rec == http.ErrAbortHandler {
+				panic(rec) // Deliberate abort, let net/http handle it
+			}
+			logger.FromContext(ctx).Errorw("Panic while serving request",
+				"panic", fmt.Sprint(rec),
+				"method", r.Method,
+				"path", r.URL.Path,
+				"user_id", errreport.User(ctx),
+			)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Internal server error")
+		}()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
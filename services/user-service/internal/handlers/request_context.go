@@ -0,0 +1,27 @@
+// services/user-service/internal/handlers/request_context.go
+package handlers
+
+import (
+	"net/http"
+
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+	"health-tracker-project/services/user-service/internal/utils/reqctx"
+)
+
+// RequestContext is an HTTP middleware that extracts the standard Pulse headers
+// (request ID, feature-flag overrides, locale) into the request context and echoes the request ID
+// on the response. The user and org ID headers are stripped, as clients can set them to anything;
+// AuthMiddleware adds the verified user ID. The context also carries a logger that adds the
+// request ID to every line, for logger.FromContext.
+func RequestContext(trustFeatureOverrides bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r = reqctx.Strip(r)
+			values := reqctx.Extract(r, trustFeatureOverrides)
+			w.Header().Set(reqctx.HeaderRequestID, values.RequestID)
+			ctx := reqctx.WithValues(r.Context(), values)
+			ctx = logger.NewContext(ctx, logger.Logger.With("request_id", values.RequestID))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
@@ -0,0 +1,74 @@
+// services/user-service/internal/handlers/residency.go
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/services"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// ResidencyHandler holds dependencies for data residency admin handlers.
+type ResidencyHandler struct {
+	residencyService services.ResidencyService
+	auditor          *Auditor
+}
+
+// NewResidencyHandler creates a new ResidencyHandler instance.
+func NewResidencyHandler(residencyService services.ResidencyService, auditor *Auditor) *ResidencyHandler {
+	return &ResidencyHandler{residencyService: residencyService, auditor: auditor}
+}
+
+// MoveUser handles POST /admin/users/{id}/region requests to move a user's data to another region.
+func (h *ResidencyHandler) MoveUser(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid user ID format")
+		return
+	}
+	var req models.ChangeRegionRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	user, err := h.residencyService.MoveUser(r.Context(), id, req)
+	if err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			writeError(w, http.StatusNotFound, models.ErrorCodeUserNotFound, serviceMessage(err))
+		} else if errors.Is(err, services.ErrInvalidInput) {
+			writeError(w, http.StatusBadRequest, models.ErrorCodeValidationFailed, serviceMessage(err))
+		} else if errors.Is(err, services.ErrConflict) {
+			writeError(w, http.StatusConflict, models.ErrorCodeConflict, serviceMessage(err))
+		} else {
+			logger.FromContext(r.Context()).Errorf("Error moving user %s to region %s: %v", id, req.Region, err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to move user")
+		}
+		return
+	}
+	h.auditor.Record(r, models.AuditEvent{
+		Action:   models.AuditUserRegion,
+		Outcome:  models.AuditSuccess,
+		TargetID: id.String(),
+		Details:  map[string]string{"region": req.Region},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, user)
+}
+
+// Violations handles GET /admin/residency/violations requests, listing users stored outside their region.
+func (h *ResidencyHandler) Violations(w http.ResponseWriter, r *http.Request) {
+	violations, err := h.residencyService.FindViolations(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to check data residency")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, violations)
+}
@@ -0,0 +1,115 @@
+// services/user-service/internal/handlers/response_validation.go
+package handlers
+
+import (
+	"bytes"
+	"mime"
+	"net/http"
+	"strings"
+
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+	"health-tracker-project/services/user-service/internal/utils/schema"
+	"health-tracker-project/services/user-service/internal/utils/websocket"
+)
+
+// Response validation modes.
+const (
+	ResponseValidationOff  = "off"
+	ResponseValidationLog  = "log"  // Log drift but send the response unchanged
+	ResponseValidationFail = "fail" // Replace drifting responses with a 500 so tests and developers notice
+)
+
+// bufferedResponse captures a handler's response so it can be inspected before being sent. Event
+// streams, which never end, are written through to w instead.
+type bufferedResponse struct {
+	w         http.ResponseWriter
+	header    http.Header
+	status    int
+	body      bytes.Buffer
+	streaming bool
+}
+
+func (b *bufferedResponse) Header() http.Header { return b.header }
+
+func (b *bufferedResponse) WriteHeader(status int) {
+	if b.status != 0 {
+		return
+	}
+	b.status = status
+	if mediaType, _, _ := mime.ParseMediaType(b.header.Get("Content-Type")); mediaType == "text/event-stream" {
+		b.streaming = true
+		b.w.WriteHeader(status)
+	}
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	if b.status == 0 {
+		b.WriteHeader(http.StatusOK)
+	}
+	if b.streaming {
+		return b.w.Write(p)
+	}
+	return b.body.Write(p)
+}
+
+// Flush sends what an event stream wrote so far; buffered responses are held back.
+func (b *bufferedResponse) Flush() {
+	if b.streaming {
+		http.NewResponseController(b.w).Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, to set write deadlines.
+func (b *bufferedResponse) Unwrap() http.ResponseWriter {
+	return b.w
+}
+
+// ResponseValidation is an HTTP middleware that checks outgoing JSON responses against the OpenAPI spec.
+// It is meant for development and staging only: every response is buffered before being sent. WebSocket
+// handshakes and event streams pass through, since they are not answered with a document.
+func ResponseValidation(spec *schema.Document, mode string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if mode != ResponseValidationLog && mode != ResponseValidationFail {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if websocket.IsUpgrade(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			buf := &bufferedResponse{w: w, header: w.Header()}
+			next.ServeHTTP(buf, r)
+			if buf.streaming {
+				return
+			}
+			if buf.status == 0 {
+				buf.status = http.StatusOK
+			}
+
+			// Successful responses are checked against their operation, and errors against it or the
+			// shared error envelope. Other media types, such as the metrics text, are skipped.
+			mediaType, _, _ := mime.ParseMediaType(buf.header.Get("Content-Type"))
+			if mediaType == "application/json" || (buf.status < 400 && buf.body.Len() == 0) {
+				if problems := spec.ValidateResponse(r.Method, r.URL.Path, buf.status, buf.body.Bytes()); len(problems) > 0 {
+					logger.FromContext(r.Context()).Errorf("Response schema drift on %s %s (%d): %s", r.Method, r.URL.Path, buf.status, strings.Join(problems, "; "))
+					if mode == ResponseValidationFail {
+						w.Header().Del("Content-Length")
+						details := make([]models.ErrorDetail, len(problems))
+						for i, problem := range problems {
+							details[i] = models.ErrorDetail{Message: problem}
+							if at, msg, ok := strings.Cut(problem, ": "); ok && strings.HasPrefix(at, "$") {
+								details[i] = models.ErrorDetail{Field: at, Message: msg}
+							}
+						}
+						writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Response schema validation failed", details...)
+						return
+					}
+				}
+			}
+
+			w.WriteHeader(buf.status)
+			_, _ = w.Write(buf.body.Bytes())
+		})
+	}
+}
@@ -0,0 +1,124 @@
+// services/user-service/internal/handlers/saml.go
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"health-tracker-project/services/user-service/internal/auth/saml"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/services"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// samlRequestCookie binds the AuthnRequest ID to the browser that started the login.
+const samlRequestCookie = "saml_request"
+
+// SAMLHandlers holds dependencies for SAML 2.0 sign-in handlers.
+type SAMLHandlers struct {
+	sp          *saml.ServiceProvider
+	authService services.AuthService
+	auditor     *Auditor
+	metering    services.MeteringService // SSO sign-ins are billed as a premium feature
+}
+
+// NewSAMLHandlers creates a new SAMLHandlers instance.
+func NewSAMLHandlers(sp *saml.ServiceProvider, authService services.AuthService, auditor *Auditor, metering services.MeteringService) *SAMLHandlers {
+	return &SAMLHandlers{sp: sp, authService: authService, auditor: auditor, metering: metering}
+}
+
+// Metadata handles GET /auth/saml/metadata, serving the SP metadata to register with the IdP.
+func (h *SAMLHandlers) Metadata(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/samlmetadata+xml")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(h.sp.Metadata())
+}
+
+// Login handles GET /auth/saml/login by redirecting the browser to the IdP with an AuthnRequest.
+func (h *SAMLHandlers) Login(w http.ResponseWriter, r *http.Request) {
+	target, requestID, err := h.sp.AuthnRequestURL(r.Context(), "")
+	if err != nil {
+		logger.FromContext(r.Context()).Errorf("Failed to build SAML AuthnRequest: %v", err)
+		writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to start sign-in")
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     samlRequestCookie,
+		Value:    requestID,
+		Expires:  time.Now().Add(10 * time.Minute),
+		HttpOnly: true,
+		Secure:   true,                  // Required by browsers for SameSite=None; localhost counts as secure
+		SameSite: http.SameSiteNoneMode, // The IdP posts back cross-site, which Lax cookies would not survive
+		Path:     "/auth/saml",
+	})
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+// ACS handles POST /auth/saml/acs, the assertion consumer service the IdP posts its response to.
+func (h *SAMLHandlers) ACS(w http.ResponseWriter, r *http.Request) {
+	cookie, err := r.Cookie(samlRequestCookie)
+	if err != nil {
+		logger.FromContext(r.Context()).Debug("SAML response without request cookie.")
+		writeError(w, http.StatusBadRequest, models.ErrorCodeSignInFailed, "Sign-in session expired, please try again")
+		return
+	}
+	// The request cookie is single-use.
+	http.SetCookie(w, &http.Cookie{Name: samlRequestCookie, Value: "", Expires: time.Unix(0, 0), HttpOnly: true, Secure: true, SameSite: http.SameSiteNoneMode, Path: "/auth/saml"})
+
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20)
+	encoded := r.PostFormValue("SAMLResponse")
+	if encoded == "" {
+		writeError(w, http.StatusBadRequest, models.ErrorCodeSignInFailed, "SAMLResponse is required")
+		return
+	}
+
+	identity, err := h.sp.ParseResponse(r.Context(), encoded, cookie.Value)
+	if errors.Is(err, saml.ErrRequestStore) {
+		logger.FromContext(r.Context()).Errorf("Error checking SAML response: %v", err)
+		writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to authenticate")
+		return
+	}
+	if err != nil {
+		logger.FromContext(r.Context()).Warnf("SAML response rejected: %v", err)
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeSignInFailed, "Failed to verify sign-in with identity provider")
+		return
+	}
+
+	authResponse, challenge, err := h.authService.AuthenticateSAML(r.Context(), identity, h.auditor.client(r))
+	if err != nil {
+		if errors.Is(err, services.ErrForbidden) {
+			h.auditor.Record(r, models.AuditEvent{
+				Action:  models.AuditLogin,
+				Outcome: models.AuditFailure,
+				Details: map[string]string{"method": "saml", "issuer": identity.Issuer, "reason": strings.TrimPrefix(err.Error(), "service: ")},
+			})
+			writeError(w, http.StatusForbidden, models.ErrorCodeAccountInactive, serviceMessage(err))
+		} else {
+			logger.FromContext(r.Context()).Errorf("Error completing SAML sign-in: %v", err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to authenticate")
+		}
+		return
+	}
+	if challenge != nil {
+		writeLinkChallenge(w, challenge)
+		return
+	}
+
+	h.auditor.Record(r, models.AuditEvent{
+		Action:   models.AuditLogin,
+		Outcome:  models.AuditSuccess,
+		ActorID:  authResponse.User.ID.String(),
+		TargetID: authResponse.User.ID.String(),
+		Details:  map[string]string{"method": "saml", "issuer": identity.Issuer},
+	})
+	recordPremiumUsage(r.Context(), h.metering, authResponse.User.ID, "sso_saml", cookie.Value) // The AuthnRequest ID is single-use
+
+	setAuthCookie(w, authResponse)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, authResponse)
+	logger.FromContext(r.Context()).Infof("User logged in via SAML: %s", authResponse.User.ID)
+}
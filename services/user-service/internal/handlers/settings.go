@@ -0,0 +1,75 @@
+// services/user-service/internal/handlers/settings.go
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/services"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// SettingsHandler holds dependencies for the caller's settings handlers.
+type SettingsHandler struct {
+	settingsService services.SettingsService
+}
+
+// NewSettingsHandler creates a new SettingsHandler instance.
+func NewSettingsHandler(settingsService services.SettingsService) *SettingsHandler {
+	return &SettingsHandler{settingsService: settingsService}
+}
+
+// GetSettings handles GET /users/me/settings requests.
+func (h *SettingsHandler) GetSettings(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+
+	settings, err := h.settingsService.GetSettings(r.Context(), userID)
+	if err != nil {
+		logger.FromContext(r.Context()).Errorf("Error getting settings for user %s: %v", userID, err)
+		writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to get settings")
+		return
+	}
+	writeUserSettings(w, settings)
+}
+
+// UpdateSettings handles PUT /users/me/settings requests. The body is an object of the keys to change;
+// keys left out keep their values, and null resets a key to its default.
+func (h *SettingsHandler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+
+	var changes map[string]json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&changes); err != nil {
+		logger.FromContext(r.Context()).Debugf("Invalid request payload for settings: %v", err)
+		writeInvalidBody(w, err)
+		return
+	}
+
+	settings, err := h.settingsService.UpdateSettings(r.Context(), userID, changes)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidInput) {
+			writeError(w, http.StatusBadRequest, models.ErrorCodeValidationFailed, serviceMessage(err))
+		} else {
+			logger.FromContext(r.Context()).Errorf("Error saving settings for user %s: %v", userID, err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to save settings")
+		}
+		return
+	}
+	writeUserSettings(w, settings)
+}
+
+func writeUserSettings(w http.ResponseWriter, settings *models.UserSettings) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, settings)
+}
@@ -0,0 +1,240 @@
+// services/user-service/internal/handlers/synthetic.go
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/config"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/services"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+	"health-tracker-project/services/user-service/internal/utils/reqctx"
+)
+
+// syntheticContextKey marks requests made in-process by the synthetic journey. Nothing from the network
+// can set it, so the CAPTCHA check and usage metering can trust it to skip probe traffic.
+const syntheticContextKey ContextKey = "synthetic"
+
+// isSynthetic reports whether the request is part of a synthetic journey.
+func isSynthetic(r *http.Request) bool {
+	synthetic, _ := r.Context().Value(syntheticContextKey).(bool)
+	return synthetic
+}
+
+// SyntheticHandler runs a user journey through the service's own handler chain for external uptime monitors.
+type SyntheticHandler struct {
+	userService services.UserService
+	token       string
+	app         http.Handler
+}
+
+// NewSyntheticHandler creates a SyntheticHandler. Monitors authenticate with token as a bearer token.
+// Mount must be called with the full handler chain before the first journey.
+func NewSyntheticHandler(userService services.UserService, token string) *SyntheticHandler {
+	return &SyntheticHandler{userService: userService, token: token}
+}
+
+// Mount sets the handler the journey's requests are served by: the same chain the server listens with,
+// so middleware, routing, and authentication are all exercised.
+func (h *SyntheticHandler) Mount(app http.Handler) {
+	h.app = app
+}
+
+// syntheticJourney holds the state passed between steps.
+type syntheticJourney struct {
+	app       http.Handler
+	requestID string // The probe's, sent on every step so the journey's logs share it
+	email     string
+	password  string
+	cookie    *http.Cookie
+	userID    uuid.UUID
+	layout    *models.DashboardLayout
+}
+
+// do serves one in-process request and returns the response.
+func (j *syntheticJourney) do(method, path string, body interface{}) (*http.Response, error) {
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			return nil, err
+		}
+	}
+	req := httptest.NewRequest(method, path, &payload)
+	req = req.WithContext(context.WithValue(req.Context(), syntheticContextKey, true))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(reqctx.HeaderRequestID, j.requestID)
+	if j.cookie != nil {
+		req.AddCookie(j.cookie)
+	}
+	rec := httptest.NewRecorder()
+	j.app.ServeHTTP(rec, req)
+	return rec.Result(), nil
+}
+
+// expect checks a response's status, returning the body in the error when it is unexpected.
+func expect(resp *http.Response, statuses ...int) error {
+	for _, status := range statuses {
+		if resp.StatusCode == status {
+			return nil
+		}
+	}
+	var body bytes.Buffer
+	body.ReadFrom(resp.Body)
+	return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(body.String()))
+}
+
+// register creates the throwaway account. A 202 means registration privacy mode is on.
+func (j *syntheticJourney) register() (*http.Response, error) {
+	resp, err := j.do(http.MethodPost, "/register", models.RegisterRequest{Name: "Synthetic Probe", Email: j.email, Password: j.password})
+	if err != nil {
+		return nil, err
+	}
+	return resp, expect(resp, http.StatusCreated, http.StatusAccepted)
+}
+
+// login signs in and keeps the session cookie for the remaining steps.
+func (j *syntheticJourney) login() (*http.Response, error) {
+	resp, err := j.do(http.MethodPost, "/login", models.LoginRequest{Email: j.email, Password: j.password})
+	if err != nil {
+		return nil, err
+	}
+	if err := expect(resp, http.StatusOK); err != nil {
+		return resp, err
+	}
+	var auth models.AuthResponse
+	if err := json.NewDecoder(resp.Body).Decode(&auth); err != nil {
+		return resp, fmt.Errorf("invalid login response: %w", err)
+	}
+	j.userID = auth.User.ID
+	for _, c := range resp.Cookies() {
+		if c.Name == config.CookieSettings().Name {
+			j.cookie = c
+		}
+	}
+	if j.cookie == nil {
+		return resp, fmt.Errorf("login did not set the session cookie")
+	}
+	return resp, nil
+}
+
+// write saves a dashboard layout with the first widget hidden, so the read can tell it from the default.
+func (j *syntheticJourney) write() (*http.Response, error) {
+	j.layout = models.DefaultDashboardLayout()
+	j.layout.Default = false
+	j.layout.Widgets[0].Visible = false
+	resp, err := j.do(http.MethodPut, "/me/dashboard", j.layout)
+	if err != nil {
+		return nil, err
+	}
+	return resp, expect(resp, http.StatusOK)
+}
+
+// read fetches the dashboard and checks that it is the layout just written.
+func (j *syntheticJourney) read() (*http.Response, error) {
+	resp, err := j.do(http.MethodGet, "/me/dashboard", nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := expect(resp, http.StatusOK); err != nil {
+		return resp, err
+	}
+	var layout models.DashboardLayout
+	if err := json.NewDecoder(resp.Body).Decode(&layout); err != nil {
+		return resp, fmt.Errorf("invalid dashboard response: %w", err)
+	}
+	if layout.Default || len(layout.Widgets) != len(j.layout.Widgets) || layout.Widgets[0].Visible {
+		return resp, fmt.Errorf("dashboard does not match the layout just saved")
+	}
+	return resp, nil
+}
+
+// cleanUp deletes the journey's account, looking it up by email when login did not get as far as its ID.
+// It keeps the probe's request values but not its cancellation, so the account is removed even if the
+// probe hung up.
+func (h *SyntheticHandler) cleanUp(ctx context.Context, journey *syntheticJourney) {
+	ctx = context.WithoutCancel(ctx)
+	if journey.userID == uuid.Nil {
+		user, err := h.userService.GetUserByEmail(ctx, journey.email)
+		if err != nil || user == nil {
+			return // Registration failed, or the lookup did and the account is left behind
+		}
+		journey.userID = user.ID
+	}
+	if err := h.userService.DeleteUser(ctx, journey.userID); err != nil {
+		logger.FromContext(ctx).Errorf("Failed to delete synthetic account %s: %v", journey.userID, err)
+	}
+}
+
+// Journey handles GET /synthetic/journey: register → login → write dashboard → read dashboard, with a
+// throwaway account that is deleted afterwards. It answers 200 when every step passed and 503 otherwise,
+// with the outcome of each step.
+func (h *SyntheticHandler) Journey(w http.ResponseWriter, r *http.Request) {
+	bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(bearer), []byte(h.token)) != 1 {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+
+	secret := make([]byte, 16)
+	rand.Read(secret)
+	id := uuid.New()
+	journey := &syntheticJourney{
+		app:       h.app,
+		requestID: reqctx.RequestID(r.Context()),
+		email:     "probe-" + id.String() + "@" + models.SyntheticEmailDomain,
+		password:  hex.EncodeToString(secret),
+	}
+	steps := []struct {
+		name string
+		run  func() (*http.Response, error)
+	}{
+		{"register", journey.register},
+		{"login", journey.login},
+		{"write", journey.write},
+		{"read", journey.read},
+	}
+
+	result := models.SyntheticResult{Passed: true, StartedAt: time.Now().UTC(), Steps: []models.SyntheticStep{}}
+	for _, s := range steps {
+		step := models.SyntheticStep{Name: s.name, Outcome: models.SyntheticSkip}
+		if result.Passed {
+			began := time.Now()
+			resp, err := s.run()
+			step.DurationMs = time.Since(began).Milliseconds()
+			if resp != nil {
+				step.Status = resp.StatusCode
+			}
+			step.Outcome = models.SyntheticPass
+			if err != nil {
+				step.Outcome = models.SyntheticFail
+				step.Error = err.Error()
+				result.Passed = false
+				logger.FromContext(r.Context()).Warnf("Synthetic journey step %s failed: %v", s.name, err)
+			}
+		}
+		result.Steps = append(result.Steps, step)
+	}
+	result.DurationMs = time.Since(result.StartedAt).Milliseconds()
+
+	h.cleanUp(r.Context(), journey)
+
+	status := http.StatusOK
+	if !result.Passed {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	writeBody(w, result)
+}
@@ -2,24 +2,33 @@
 package handlers
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/google/uuid"
 	"health-tracker-project/services/user-service/internal/models"
 	"health-tracker-project/services/user-service/internal/services"
 	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+	"health-tracker-project/services/user-service/internal/utils/measure"
+	"health-tracker-project/services/user-service/internal/utils/reqctx"
 )
 
 // UserHandler holds dependencies for user-related HTTP handlers.
 type UserHandler struct {
-	userService services.UserService // Depends on the UserService interface
+	userService  services.UserService      // Depends on the UserService interface
+	eventService services.UserEventService // Serves the caller's own activity timeline
+	auditor      *Auditor                  // Records account creation, changes, and deletion
 }
 
 // NewUserHandler creates a new UserHandler instance.
-func NewUserHandler(userService services.UserService) *UserHandler {
-	return &UserHandler{userService: userService}
+func NewUserHandler(userService services.UserService, eventService services.UserEventService, auditor *Auditor) *UserHandler {
+	return &UserHandler{userService: userService, eventService: eventService, auditor: auditor}
 }
 
 // UsersCollectionHandler routes requests to /users (GET all, POST create).
@@ -30,8 +39,8 @@ func (h *UserHandler) UsersCollectionHandler(w http.ResponseWriter, r *http.Requ
 	case http.MethodPost:
 		h.CreateUser(w, r)
 	default:
-		logger.Logger.Warnf("Method not allowed for /users: %s", r.Method)
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		logger.FromContext(r.Context()).Warnf("Method not allowed for /users: %s", r.Method)
+		writeError(w, http.StatusMethodNotAllowed, models.ErrorCodeMethodNotAllowed, "Method not allowed")
 	}
 }
 
@@ -48,16 +57,20 @@ func (h *UserHandler) UserItemHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if idParam == "" {
-		logger.Logger.Debug("User ID is missing from path for item handler.")
-		http.Error(w, "User ID is required in path", http.StatusBadRequest)
+		logger.FromContext(r.Context()).Debug("User ID is missing from path for item handler.")
+		writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "User ID is required in path")
 		return
 	}
 
 	// Convert string ID from URL to uuid.UUID for service layer
 	userID, err := uuid.Parse(idParam)
 	if err != nil {
-		logger.Logger.Warnf("Invalid user ID format '%s': %v", idParam, err)
-		http.Error(w, "Invalid user ID format", http.StatusBadRequest)
+		logger.FromContext(r.Context()).Warnf("Invalid user ID format '%s': %v", idParam, err)
+		writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid user ID format")
+		return
+	}
+
+	if !authorizeUserAccess(w, r, userID) {
 		return
 	}
 
@@ -69,16 +82,78 @@ func (h *UserHandler) UserItemHandler(w http.ResponseWriter, r *http.Request) {
 	case http.MethodDelete:
 		h.DeleteUser(w, r, userID)
 	default:
-		logger.Logger.Warnf("Method not allowed for /users/{id}: %s", r.Method)
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		logger.FromContext(r.Context()).Warnf("Method not allowed for /users/{id}: %s", r.Method)
+		writeError(w, http.StatusMethodNotAllowed, models.ErrorCodeMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// authorizeUserAccess lets callers access their own account; other accounts need the users:* scopes.
+// It writes a 403 and returns false if access is denied.
+func authorizeUserAccess(w http.ResponseWriter, r *http.Request, userID uuid.UUID) bool {
+	callerID, _ := r.Context().Value(UserContextKey).(string)
+	if callerID == userID.String() {
+		return true
+	}
+	required := models.ScopeUsersWrite
+	if r.Method == http.MethodGet {
+		required = models.ScopeUsersRead
+	}
+	if !hasScope(r, required) {
+		logger.FromContext(r.Context()).Warnf("Forbidden: user %s lacks %s for user %s", callerID, required, userID)
+		writeError(w, http.StatusForbidden, models.ErrorCodeMissingScope, "Forbidden: missing required scope "+required)
+		return false
+	}
+	return true
+}
+
+// UserViews serves GET /users/{id}/{view} with one handler per view, picked by the view path value.
+// The views share one ServeMux pattern so that GET /users/by-username/{handle}, which is more specific
+// than it, can be registered too; with a pattern per view the two would conflict. Metrics, metering,
+// and load shedding therefore count the views as the one route GET /users/{id}/{view}. Unknown views
+// are not found.
+func UserViews(views map[string]http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		view, ok := views[r.PathValue("view")]
+		if !ok {
+			writeNoRoute(w)
+			return
+		}
+		view.ServeHTTP(w, r)
+	}
+}
+
+// GetTimezoneHistory handles GET /users/{id}/timezone-history requests.
+func (h *UserHandler) GetTimezoneHistory(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid user ID format")
+		return
+	}
+	if !authorizeUserAccess(w, r, userID) {
+		return
+	}
+
+	history, err := h.userService.GetTimezoneHistory(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			writeError(w, http.StatusNotFound, models.ErrorCodeUserNotFound, serviceMessage(err))
+		} else {
+			logger.FromContext(r.Context()).Errorf("Error getting timezone history for user %s: %v", userID, err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to get timezone history")
+		}
+		return
 	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, history)
 }
 
 // GetUserByEmailHandler routes GET requests to /users/by-email?email=...
 func (h *UserHandler) GetUserByEmailHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		logger.Logger.Warnf("Method not allowed for /users/by-email: %s", r.Method)
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		logger.FromContext(r.Context()).Warnf("Method not allowed for /users/by-email: %s", r.Method)
+		writeError(w, http.StatusMethodNotAllowed, models.ErrorCodeMethodNotAllowed, "Method not allowed")
 		return
 	}
 	h.GetUserByEmail(w, r)
@@ -87,150 +162,640 @@ func (h *UserHandler) GetUserByEmailHandler(w http.ResponseWriter, r *http.Reque
 // CreateUser handles POST /users requests to create a new user.
 func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	var req models.CreateUserRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		logger.Logger.Debugf("Invalid request payload for create user: %v", err)
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+	if !decodeJSON(w, r, &req) {
 		return
 	}
 
-	userResp, err := h.userService.CreateUser(req) // Call the service layer
+	userResp, err := h.userService.CreateUser(r.Context(), req) // Call the service layer
 	if err != nil {
-		// Map service-level errors to HTTP status codes (simplified with string checks)
-		if strings.Contains(err.Error(), "already exists") {
-			logger.Logger.Warnf("User creation failed (conflict): %v", err)
-			http.Error(w, err.Error(), http.StatusConflict) // 409 Conflict
-		} else if strings.Contains(err.Error(), "required") {
-			logger.Logger.Warnf("User creation failed (missing fields): %v", err)
-			http.Error(w, err.Error(), http.StatusBadRequest) // 400 Bad Request
+		if status, apiErr := userChangeError(err); apiErr != nil {
+			logger.FromContext(r.Context()).Warnf("User creation failed: %v", err)
+			writeError(w, status, apiErr.Code, apiErr.Message)
 		} else {
-			logger.Logger.Errorf("Error creating user: %v", err)
-			http.Error(w, "Failed to create user", http.StatusInternalServerError)
+			logger.FromContext(r.Context()).Errorf("Error creating user: %v", err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to create user")
 		}
 		return
 	}
 
+	h.auditor.Record(r, models.AuditEvent{
+		Action:   models.AuditUserCreate,
+		Outcome:  models.AuditSuccess,
+		TargetID: userResp.ID.String(),
+		Details:  map[string]string{"method": "admin"},
+	})
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(userResp)
-	logger.Logger.Infof("User created: %s", userResp.ID)
+	writeBody(w, userResp)
+	logger.FromContext(r.Context()).Infof("User created: %s", userResp.ID)
 }
 
 // GetUserByID handles GET /users/{id} requests to retrieve a user by ID.
 func (h *UserHandler) GetUserByID(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
-	userResp, err := h.userService.GetUserByID(id) // Call the service layer
+	userResp, err := h.userService.GetUserByID(r.Context(), id) // Call the service layer
 	if err != nil {
-		if strings.Contains(err.Error(), "not found") {
-			logger.Logger.Warnf("User not found by ID: %s", id)
-			http.Error(w, err.Error(), http.StatusNotFound) // 404 Not Found
+		if errors.Is(err, services.ErrNotFound) {
+			logger.FromContext(r.Context()).Warnf("User not found by ID: %s", id)
+			writeError(w, http.StatusNotFound, models.ErrorCodeUserNotFound, serviceMessage(err)) // 404 Not Found
 		} else {
-			logger.Logger.Errorf("Error getting user by ID %s: %v", id, err)
-			http.Error(w, "Failed to get user", http.StatusInternalServerError)
+			logger.FromContext(r.Context()).Errorf("Error getting user by ID %s: %v", id, err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to get user")
 		}
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(userResp)
-	logger.Logger.Infof("User retrieved by ID: %s", userResp.ID)
+	writeBody(w, userResp)
+	logger.FromContext(r.Context()).Infof("User retrieved by ID: %s", userResp.ID)
 }
 
-// GetAllUsers handles GET /users requests to retrieve all users.
+// GetAllUsers handles GET /users requests to retrieve all users, oldest first, a page at a time.
 func (h *UserHandler) GetAllUsers(w http.ResponseWriter, r *http.Request) {
-	usersResp, err := h.userService.GetAllUsers() // Call the service layer
+	after, err := pageAfter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidCursor, "Invalid 'cursor'")
+		return
+	}
+	var limit int
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil {
+			writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid 'limit', expected an integer")
+			return
+		}
+	}
+
+	usersResp, err := h.userService.GetAllUsers(r.Context(), after, limit) // Call the service layer
+	if err != nil {
+		logger.FromContext(r.Context()).Errorf("Error getting all users: %v", err)
+		writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to get users")
+		return
+	}
+
+	if len(usersResp) > 0 {
+		last := usersResp[len(usersResp)-1]
+		setNextPage(w, r, models.PageKey{At: last.CreatedAt, ID: last.ID})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, usersResp)
+	logger.FromContext(r.Context()).Infof("Retrieved %d users", len(usersResp))
+}
+
+// GetMetadata handles GET /users/{id}/metadata requests.
+func (h *UserHandler) GetMetadata(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid user ID format")
+		return
+	}
+	if !authorizeUserAccess(w, r, userID) {
+		return
+	}
+
+	metadata, err := h.userService.GetMetadata(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			writeError(w, http.StatusNotFound, models.ErrorCodeUserNotFound, serviceMessage(err))
+		} else {
+			logger.FromContext(r.Context()).Errorf("Error getting metadata for user %s: %v", userID, err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to get user metadata")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, metadata)
+}
+
+// PatchMetadata handles PATCH /users/{id}/metadata requests. Metadata is written by integrators, so
+// the route needs users:write even for the caller's own account; users can only read theirs.
+func (h *UserHandler) PatchMetadata(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid user ID format")
+		return
+	}
+	var patch map[string]json.RawMessage
+	body := http.MaxBytesReader(w, r.Body, 2*models.MaxUserMetadataBytes)
+	if err := json.NewDecoder(body).Decode(&patch); err != nil || patch == nil {
+		writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidBody, "Invalid request payload: expected a JSON object")
+		return
+	}
+
+	metadata, err := h.userService.UpdateMetadata(r.Context(), userID, patch)
+	if err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			writeError(w, http.StatusNotFound, models.ErrorCodeUserNotFound, serviceMessage(err))
+		} else if errors.Is(err, services.ErrInvalidInput) {
+			writeError(w, http.StatusBadRequest, models.ErrorCodeValidationFailed, serviceMessage(err))
+		} else {
+			logger.FromContext(r.Context()).Errorf("Error updating metadata for user %s: %v", userID, err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to update user metadata")
+		}
+		return
+	}
+	keys := make([]string, 0, len(patch))
+	for key := range patch {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	h.auditor.Record(r, models.AuditEvent{
+		Action:   models.AuditUserUpdate,
+		Outcome:  models.AuditSuccess,
+		TargetID: userID.String(),
+		Details:  map[string]string{"fields": "metadata", "keys": strings.Join(keys, ",")},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, metadata)
+}
+
+// GetUserByUsername handles GET /users/by-username/{handle} requests. Usernames are matched ignoring case.
+func (h *UserHandler) GetUserByUsername(w http.ResponseWriter, r *http.Request) {
+	handle := r.PathValue("handle")
+	userResp, err := h.userService.GetUserByUsername(r.Context(), handle) // Call the service layer
+	if err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			logger.FromContext(r.Context()).Warnf("User not found by username: %s", handle)
+			writeError(w, http.StatusNotFound, models.ErrorCodeUserNotFound, serviceMessage(err))
+		} else {
+			logger.FromContext(r.Context()).Errorf("Error getting user by username %s: %v", handle, err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to get user")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, userResp)
+}
+
+// SearchUsers handles GET /users/search?q=... requests, finding users by name, username, or email even
+// when the query has a typo.
+func (h *UserHandler) SearchUsers(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	var limit int
+	if v := q.Get("limit"); v != "" {
+		var err error
+		if limit, err = strconv.Atoi(v); err != nil {
+			writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid 'limit', expected an integer")
+			return
+		}
+	}
+
+	results, err := h.userService.SearchUsers(r.Context(), q.Get("q"), limit) // Call the service layer
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidInput) {
+			writeError(w, http.StatusBadRequest, models.ErrorCodeValidationFailed, serviceMessage(err))
+		} else {
+			logger.FromContext(r.Context()).Errorf("Error searching users: %v", err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to search users")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, results)
+}
+
+// CheckHandleAvailability handles GET /handles/availability?name=... requests from the signup form. It is
+// public, so it is rate limited per client (rate_limits.handles) and says no more than the form needs:
+// whether the handle can be taken, why not if it is malformed, and a few available alternatives.
+func (h *UserHandler) CheckHandleAvailability(w http.ResponseWriter, r *http.Request) {
+	availability, err := h.userService.CheckHandleAvailability(r.Context(), r.URL.Query().Get("name"))
 	if err != nil {
-		logger.Logger.Errorf("Error getting all users: %v", err)
-		http.Error(w, "Failed to get users", http.StatusInternalServerError)
+		if errors.Is(err, services.ErrInvalidInput) {
+			writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Name query parameter is required")
+		} else {
+			logger.FromContext(r.Context()).Errorf("Error checking handle availability: %v", err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to check handle availability")
+		}
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(usersResp)
-	logger.Logger.Infof("Retrieved %d users", len(usersResp))
+	writeBody(w, availability)
 }
 
 // GetUserByEmail handles GET /users/by-email?email=... requests.
 func (h *UserHandler) GetUserByEmail(w http.ResponseWriter, r *http.Request) {
 	email := r.URL.Query().Get("email")
 	if email == "" {
-		logger.Logger.Debug("Email query parameter is missing for GetUserByEmail.")
-		http.Error(w, "Email query parameter is required", http.StatusBadRequest)
+		logger.FromContext(r.Context()).Debug("Email query parameter is missing for GetUserByEmail.")
+		writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Email query parameter is required")
 		return
 	}
 
-	userResp, err := h.userService.GetUserByEmail(email) // Call the service layer
+	userResp, err := h.userService.GetUserByEmail(r.Context(), email) // Call the service layer
 	if err != nil {
-		if strings.Contains(err.Error(), "not found") {
-			logger.Logger.Warnf("User not found by email: %s", email)
-			http.Error(w, err.Error(), http.StatusNotFound)
-		} else if strings.Contains(err.Error(), "required") {
-			logger.Logger.Warnf("User retrieval by email failed (missing fields): %v", err)
-			http.Error(w, err.Error(), http.StatusBadRequest)
+		if errors.Is(err, services.ErrNotFound) {
+			logger.FromContext(r.Context()).Warnf("User not found by email: %s", email)
+			writeError(w, http.StatusNotFound, models.ErrorCodeUserNotFound, serviceMessage(err))
+		} else if errors.Is(err, services.ErrInvalidInput) {
+			logger.FromContext(r.Context()).Warnf("User retrieval by email failed (invalid fields): %v", err)
+			writeError(w, http.StatusBadRequest, models.ErrorCodeValidationFailed, serviceMessage(err))
 		} else {
-			logger.Logger.Errorf("Error getting user by email %s: %v", email, err)
-			http.Error(w, "Failed to get user", http.StatusInternalServerError)
+			logger.FromContext(r.Context()).Errorf("Error getting user by email %s: %v", email, err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to get user")
 		}
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(userResp)
-	logger.Logger.Infof("User retrieved by email: %s", userResp.Email)
+	writeBody(w, userResp)
+	logger.FromContext(r.Context()).Infof("User retrieved by email: %s", userResp.Email)
 }
 
 // UpdateUser handles PUT /users/{id} requests to update user details.
 func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
 	var req models.UpdateUserRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		logger.Logger.Debugf("Invalid request payload for update user %s: %v", id, err)
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if msg := resolveHeight(r, &req); msg != "" {
+		writeError(w, http.StatusBadRequest, models.ErrorCodeValidationFailed, msg)
 		return
 	}
 
-	userResp, err := h.userService.UpdateUser(id, req) // Call the service layer
+	userResp, err := h.userService.UpdateUser(r.Context(), id, req) // Call the service layer
 	if err != nil {
-		if strings.Contains(err.Error(), "not found") {
-			logger.Logger.Warnf("User not found for update: %s", id)
-			http.Error(w, err.Error(), http.StatusNotFound)
-		} else if strings.Contains(err.Error(), "already in use") || strings.Contains(err.Error(), "required") {
-			logger.Logger.Warnf("User update failed (validation/conflict): %v", err)
-			http.Error(w, err.Error(), http.StatusBadRequest)
+		if status, apiErr := userChangeError(err); apiErr != nil {
+			logger.FromContext(r.Context()).Warnf("Update of user %s failed: %v", id, err)
+			writeError(w, status, apiErr.Code, apiErr.Message)
 		} else {
-			logger.Logger.Errorf("Error updating user %s: %v", id, err)
-			http.Error(w, "Failed to update user", http.StatusInternalServerError)
+			logger.FromContext(r.Context()).Errorf("Error updating user %s: %v", id, err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to update user")
 		}
 		return
 	}
 
+	h.auditUpdate(r, id, req)
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(userResp)
-	logger.Logger.Infof("User updated: %s", userResp.ID)
+	writeBody(w, userResp)
+	logger.FromContext(r.Context()).Infof("User updated: %s", userResp.ID)
+}
+
+// resolveHeight sets the height_cm of an update from its human-style height, if it has one, read in the
+// caller's locale. It returns why the height is not acceptable, or "".
+func resolveHeight(r *http.Request, req *models.UpdateUserRequest) string {
+	if req.Height == nil {
+		return ""
+	}
+	cm, err := measure.ParseHeight(*req.Height, reqctx.FromContext(r.Context()).Locale)
+	if err != nil {
+		return serviceMessage(err)
+	}
+	if req.HeightCM != nil && *req.HeightCM != cm {
+		return "height and height_cm disagree; send one of them"
+	}
+	req.HeightCM = &cm
+	return ""
+}
+
+// auditUpdate records a successful profile update, and a password change if the update set one.
+func (h *UserHandler) auditUpdate(r *http.Request, id uuid.UUID, req models.UpdateUserRequest) {
+	var fields []string
+	if req.Name != "" {
+		fields = append(fields, "name")
+	}
+	if req.Email != "" {
+		fields = append(fields, "email")
+	}
+	if req.Username != nil {
+		fields = append(fields, "username")
+	}
+	if req.Timezone != nil {
+		fields = append(fields, "timezone")
+	}
+	if req.WeekStart != nil {
+		fields = append(fields, "week_start")
+	}
+	if req.Units != nil {
+		fields = append(fields, "units")
+	}
+	if req.HeightCM != nil {
+		fields = append(fields, "height_cm")
+	}
+	if req.DateOfBirth != nil {
+		fields = append(fields, "date_of_birth")
+	}
+	if len(fields) > 0 {
+		h.auditor.Record(r, models.AuditEvent{
+			Action:   models.AuditUserUpdate,
+			Outcome:  models.AuditSuccess,
+			TargetID: id.String(),
+			Details:  map[string]string{"fields": strings.Join(fields, ",")},
+		})
+	}
+	if req.Password != nil && *req.Password != "" {
+		h.auditor.Record(r, models.AuditEvent{
+			Action:   models.AuditPasswordChange,
+			Outcome:  models.AuditSuccess,
+			TargetID: id.String(),
+			Details:  map[string]string{"method": "profile_update"},
+		})
+	}
 }
 
 // DeleteUser handles DELETE /users/{id} requests to delete a user.
 func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
-	err := h.userService.DeleteUser(id) // Call the service layer
+	err := h.userService.DeleteUser(r.Context(), id) // Call the service layer
 	if err != nil {
-		if strings.Contains(err.Error(), "not found") { // If service checks for existence
-			logger.Logger.Warnf("User not found for deletion: %s", id)
-			http.Error(w, err.Error(), http.StatusNotFound)
+		if status, apiErr := userChangeError(err); apiErr != nil {
+			logger.FromContext(r.Context()).Warnf("Deletion of user %s failed: %v", id, err)
+			writeError(w, status, apiErr.Code, apiErr.Message)
 		} else {
-			logger.Logger.Errorf("Error deleting user %s: %v", id, err)
-			http.Error(w, "Failed to delete user", http.StatusInternalServerError)
+			logger.FromContext(r.Context()).Errorf("Error deleting user %s: %v", id, err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to delete user")
 		}
 		return
 	}
+	h.auditor.Record(r, models.AuditEvent{Action: models.AuditUserDelete, Outcome: models.AuditSuccess, TargetID: id.String()})
 
 	w.WriteHeader(http.StatusNoContent)
-	logger.Logger.Infof("User deleted: %s", id)
+	logger.FromContext(r.Context()).Infof("User deleted: %s", id)
 }
 
-// HealthCheck provides a simple health check endpoint.
-func (h *UserHandler) HealthCheck(w http.ResponseWriter, r *http.Request) {
+// maxBulkRequestBytes bounds the body of a bulk request, which is read whole.
+const maxBulkRequestBytes = 4 << 20
+
+// BulkUsers handles POST /users/bulk requests: create, update, and delete operations applied in one
+// transaction, all or none. Whether or not they were applied, the response is 200 OK with the outcome of
+// each operation, which has the status and error it would have had on its own route; operations rolled
+// back because another failed have 424 Failed Dependency. A body that is not a list of operations is
+// refused as a whole, as on other routes.
+func (h *UserHandler) BulkUsers(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBulkRequestBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, models.ErrorCodePayloadTooLarge, "Request body too large")
+		} else {
+			writeInvalidBody(w, err)
+		}
+		return
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	var req models.BulkUserRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	items := make([]models.BulkUserItem, len(req.Operations))
+	results := make([]models.BulkUserResult, len(req.Operations))
+	outcomes := make([]models.BulkUserOutcome, len(req.Operations))
+	invalid := false
+	for i, op := range req.Operations {
+		results[i] = models.BulkUserResult{Index: i, Op: op.Op}
+		item, apiErr, err := decodeBulkOperation(r, op)
+		if err != nil {
+			logger.FromContext(r.Context()).Errorf("Error validating bulk %s operation: %v", op.Op, err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to validate request")
+			return
+		}
+		if apiErr != nil {
+			results[i].Status, results[i].Error = http.StatusBadRequest, apiErr
+			invalid = true
+		}
+		items[i] = item
+	}
+	if invalid {
+		for i := range outcomes {
+			outcomes[i].Err = services.ErrNotApplied
+		}
+	} else if outcomes, err = h.userService.ApplyBulk(r.Context(), items); err != nil {
+		logger.FromContext(r.Context()).Errorf("Error applying bulk request of %d operations: %v", len(items), err)
+		writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to apply bulk operations")
+		return
+	}
+
+	resp := models.BulkUserResponse{Applied: true, Results: results}
+	for i, outcome := range outcomes {
+		if results[i].Error != nil {
+			resp.Applied = false
+			continue
+		}
+		if outcome.Err != nil {
+			resp.Applied = false
+			results[i].Status, results[i].Error = bulkError(r, items[i], outcome.Err)
+			continue
+		}
+		results[i].User = outcome.User
+		switch items[i].Op {
+		case models.BulkCreate:
+			results[i].Status = http.StatusCreated
+		case models.BulkUpdate:
+			results[i].Status = http.StatusOK
+		case models.BulkDelete:
+			results[i].Status = http.StatusNoContent
+		}
+	}
+	if resp.Applied {
+		for i, item := range items {
+			switch item.Op {
+			case models.BulkCreate:
+				h.auditor.Record(r, models.AuditEvent{
+					Action:   models.AuditUserCreate,
+					Outcome:  models.AuditSuccess,
+					TargetID: outcomes[i].User.ID.String(),
+					Details:  map[string]string{"method": "bulk"},
+				})
+			case models.BulkUpdate:
+				h.auditUpdate(r, item.ID, item.Update)
+			case models.BulkDelete:
+				h.auditor.Record(r, models.AuditEvent{Action: models.AuditUserDelete, Outcome: models.AuditSuccess, TargetID: item.ID.String()})
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	_, _ = w.Write([]byte("User Service is healthy"))
-	logger.Logger.Debug("Health check requested and passed.")
-}
\ No newline at end of file
+	writeBody(w, resp)
+	logger.FromContext(r.Context()).Infof("Bulk request of %d operations answered, applied: %t", len(items), resp.Applied)
+}
+
+// decodeBulkOperation decodes the request of one bulk operation and checks it as its own route would,
+// returning the error it would answer with if it is not acceptable. Field names in the details are
+// those of the operation, such as user.email. The error is returned if the request could not be checked.
+func decodeBulkOperation(r *http.Request, op models.BulkUserOperation) (models.BulkUserItem, *models.APIError, error) {
+	item := models.BulkUserItem{Op: op.Op, ID: op.ID}
+	invalidFields := func(problems ...models.ErrorDetail) *models.APIError {
+		return &models.APIError{Code: models.ErrorCodeValidationFailed, Message: "Request has invalid fields", Details: problems}
+	}
+	if op.Op != models.BulkCreate && op.ID == uuid.Nil {
+		return item, invalidFields(models.ErrorDetail{Field: "id", Message: "is required"}), nil
+	}
+	var req any
+	switch op.Op {
+	case models.BulkCreate:
+		req = &item.Create
+	case models.BulkUpdate:
+		req = &item.Update
+	default:
+		return item, nil, nil
+	}
+	if len(op.User) == 0 {
+		return item, invalidFields(models.ErrorDetail{Field: "user", Message: "is required"}), nil
+	}
+	if err := json.Unmarshal(op.User, req); err != nil {
+		detail := invalidBodyDetail(err)
+		detail.Field = strings.TrimSuffix("user."+detail.Field, ".")
+		return item, &models.APIError{Code: models.ErrorCodeInvalidBody, Message: "Invalid request payload", Details: []models.ErrorDetail{detail}}, nil
+	}
+	problems, err := models.Validate(req)
+	if err != nil {
+		return item, nil, err
+	}
+	if len(problems) > 0 {
+		for i := range problems {
+			problems[i].Field = "user." + problems[i].Field
+		}
+		return item, invalidFields(problems...), nil
+	}
+	if op.Op == models.BulkUpdate {
+		if msg := resolveHeight(r, &item.Update); msg != "" {
+			return item, &models.APIError{Code: models.ErrorCodeValidationFailed, Message: msg}, nil
+		}
+	}
+	return item, nil, nil
+}
+
+// bulkError is the status and error a failed bulk operation answers with: those of its own route, or
+// 424 Failed Dependency if it was rolled back because another operation failed.
+func bulkError(r *http.Request, item models.BulkUserItem, err error) (int, *models.APIError) {
+	if errors.Is(err, services.ErrNotApplied) {
+		return http.StatusFailedDependency, &models.APIError{Code: models.ErrorCodeNotApplied, Message: serviceMessage(err)}
+	}
+	if status, apiErr := userChangeError(err); apiErr != nil {
+		return status, apiErr
+	}
+	logger.FromContext(r.Context()).Errorf("Error in bulk %s operation: %v", item.Op, err)
+	return http.StatusInternalServerError, &models.APIError{Code: models.ErrorCodeInternal, Message: "Failed to " + item.Op + " user"}
+}
+
+// DeactivateAccount handles POST /me/deactivate requests.
+// The caller's account is deactivated, all of its sessions are revoked, and the auth cookie is cleared.
+func (h *UserHandler) DeactivateAccount(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+
+	if err := h.userService.DeactivateUser(r.Context(), userID); err != nil {
+		logger.FromContext(r.Context()).Errorf("Error deactivating account %s: %v", userID, err)
+		writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to deactivate account")
+		return
+	}
+
+	h.auditor.Record(r, models.AuditEvent{Action: models.AuditUserDeactivate, Outcome: models.AuditSuccess, TargetID: userID.String()})
+
+	clearAuthCookie(w)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, map[string]string{"message": "Account deactivated"})
+}
+
+// GetTimeline handles GET /me/timeline?type=&cursor=&limit= requests.
+// type is a comma-separated list of event types.
+// Events are returned newest first; the Link header holds the URL of the next page.
+func (h *UserHandler) GetTimeline(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+
+	q := r.URL.Query()
+	filter := models.UserEventFilter{UserID: userID}
+	if v := q.Get("type"); v != "" {
+		for _, t := range strings.Split(v, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				filter.Types = append(filter.Types, t)
+			}
+		}
+	}
+	if filter.After, err = pageAfter(r); err != nil {
+		writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidCursor, "Invalid 'cursor'")
+		return
+	}
+	if v := q.Get("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil {
+			writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid 'limit', expected an integer")
+			return
+		}
+	}
+
+	events, err := h.eventService.GetTimeline(r.Context(), filter)
+	if err != nil {
+		logger.FromContext(r.Context()).Errorf("Error getting timeline for user %s: %v", userID, err)
+		writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to get timeline")
+		return
+	}
+
+	if len(events) > 0 {
+		last := events[len(events)-1]
+		setNextPage(w, r, models.PageKey{At: last.OccurredAt, ID: last.ID})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, events)
+}
+
+// GetProfilePrompts handles GET /me/profile-prompts requests.
+// It lists the optional profile fields still worth asking the caller for, most valuable first.
+func (h *UserHandler) GetProfilePrompts(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+
+	prompts, err := h.userService.GetProfilePrompts(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			writeError(w, http.StatusNotFound, models.ErrorCodeUserNotFound, serviceMessage(err))
+			return
+		}
+		logger.FromContext(r.Context()).Errorf("Error getting profile prompts for user %s: %v", userID, err)
+		writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to get profile prompts")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, prompts)
+}
+
+// DismissProfilePrompt handles POST /me/profile-prompts/{field}/dismiss requests.
+func (h *UserHandler) DismissProfilePrompt(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+
+	if err := h.userService.DismissProfilePrompt(r.Context(), userID, r.PathValue("field")); err != nil {
+		if errors.Is(err, services.ErrInvalidInput) {
+			writeError(w, http.StatusBadRequest, models.ErrorCodeValidationFailed, serviceMessage(err))
+		} else if errors.Is(err, services.ErrNotFound) {
+			writeError(w, http.StatusNotFound, models.ErrorCodeNotFound, serviceMessage(err))
+		} else {
+			logger.FromContext(r.Context()).Errorf("Error dismissing profile prompt for user %s: %v", userID, err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to dismiss profile prompt")
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,256 @@
+// services/user-service/internal/handlers/workout_attachments.go
+package handlers
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/services"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// WorkoutAttachmentHandler holds dependencies for workout attachment handlers.
+type WorkoutAttachmentHandler struct {
+	attachmentService services.WorkoutAttachmentService
+}
+
+// NewWorkoutAttachmentHandler creates a new WorkoutAttachmentHandler instance.
+func NewWorkoutAttachmentHandler(attachmentService services.WorkoutAttachmentService) *WorkoutAttachmentHandler {
+	return &WorkoutAttachmentHandler{attachmentService: attachmentService}
+}
+
+// writeWorkoutAttachmentError answers the errors shared by the workout attachment endpoints, reporting
+// whether it did. Attachments of other users are reported as not found.
+func writeWorkoutAttachmentError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, services.ErrNotFound): // The attachment, or the client
+		writeError(w, http.StatusNotFound, models.ErrorCodeNotFound, serviceMessage(err))
+	case errors.Is(err, services.ErrConflict): // Awaiting or failed a virus scan
+		writeError(w, http.StatusConflict, models.ErrorCodeConflict, "The "+strings.TrimPrefix(err.Error(), "service: "))
+	case errors.Is(err, services.ErrInvalidInput):
+		writeError(w, http.StatusBadRequest, models.ErrorCodeValidationFailed, serviceMessage(err))
+	default:
+		return false
+	}
+	return true
+}
+
+// Upload handles POST /me/workout-attachments, storing the raw request body as an attachment of the
+// workout set named by the set_ref query parameter.
+func (h *WorkoutAttachmentHandler) Upload(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+
+	query := r.URL.Query()
+	req := models.UploadWorkoutAttachmentRequest{SetRef: query.Get("set_ref"), Filename: query.Get("filename")}
+	if v := query.Get("shared_with_coaches"); v != "" {
+		if req.SharedWithCoaches, err = strconv.ParseBool(v); err != nil {
+			writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid shared_with_coaches, expected true or false")
+			return
+		}
+	}
+	if v := query.Get("expires_in_days"); v != "" {
+		days, err := strconv.Atoi(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid expires_in_days, expected a number of days")
+			return
+		}
+		req.ExpiresInDays = &days
+	}
+
+	extendDeadlines(w, transferTimeout)
+	body := http.MaxBytesReader(w, r.Body, services.WorkoutAttachmentUploadLimit())
+	attachment, err := h.attachmentService.Upload(r.Context(), userID, req, body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		switch {
+		case writeWorkoutAttachmentError(w, err):
+		case errors.Is(err, services.ErrTooLarge):
+			writeError(w, http.StatusRequestEntityTooLarge, models.ErrorCodePayloadTooLarge, serviceMessage(err))
+		case errors.As(err, &tooLarge):
+			writeError(w, http.StatusRequestEntityTooLarge, models.ErrorCodePayloadTooLarge, "Attachment is too large")
+		case errors.Is(err, services.ErrUnsupportedType):
+			writeError(w, http.StatusUnsupportedMediaType, models.ErrorCodeUnsupportedMediaType, serviceMessage(err))
+		default:
+			logger.FromContext(r.Context()).Errorf("Error uploading workout attachment for user %s: %v", userID, err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to upload attachment")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	writeBody(w, attachment)
+}
+
+// List handles GET /me/workout-attachments, optionally filtered by the set_ref query parameter.
+func (h *WorkoutAttachmentHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+
+	attachments, err := h.attachmentService.List(r.Context(), userID, r.URL.Query().Get("set_ref"))
+	if err != nil {
+		logger.FromContext(r.Context()).Errorf("Error listing workout attachments for user %s: %v", userID, err)
+		writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to list attachments")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, attachments)
+}
+
+// Update handles PATCH /me/workout-attachments/{id}, changing coach sharing or expiry.
+func (h *WorkoutAttachmentHandler) Update(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid attachment ID format")
+		return
+	}
+	var req models.UpdateWorkoutAttachmentRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	attachment, err := h.attachmentService.Update(r.Context(), userID, id, req)
+	if err != nil {
+		if !writeWorkoutAttachmentError(w, err) {
+			logger.FromContext(r.Context()).Errorf("Error updating workout attachment %s: %v", id, err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to update attachment")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, attachment)
+}
+
+// Delete handles DELETE /me/workout-attachments/{id}.
+func (h *WorkoutAttachmentHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid attachment ID format")
+		return
+	}
+
+	if err := h.attachmentService.Delete(r.Context(), userID, id); err != nil {
+		if !writeWorkoutAttachmentError(w, err) {
+			logger.FromContext(r.Context()).Errorf("Error deleting workout attachment %s: %v", id, err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to delete attachment")
+		}
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Download handles GET /me/workout-attachments/{id}/content.
+func (h *WorkoutAttachmentHandler) Download(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid attachment ID format")
+		return
+	}
+
+	attachment, content, err := h.attachmentService.Open(r.Context(), userID, id)
+	h.stream(w, r, id, attachment, content, err)
+}
+
+// ListForCoach handles GET /coach/clients/{id}/workout-attachments, the attachments a client shares with
+// their coaches, optionally filtered by the set_ref query parameter.
+func (h *WorkoutAttachmentHandler) ListForCoach(w http.ResponseWriter, r *http.Request) {
+	coachID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+	clientID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid client ID format")
+		return
+	}
+
+	attachments, err := h.attachmentService.ListForCoach(r.Context(), coachID, clientID, r.URL.Query().Get("set_ref"))
+	if err != nil {
+		if !writeWorkoutAttachmentError(w, err) {
+			logger.FromContext(r.Context()).Errorf("Error listing workout attachments of user %s for coach %s: %v", clientID, coachID, err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to list attachments")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, attachments)
+}
+
+// DownloadForCoach handles GET /coach/clients/{id}/workout-attachments/{attachment_id}/content.
+func (h *WorkoutAttachmentHandler) DownloadForCoach(w http.ResponseWriter, r *http.Request) {
+	coachID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+	clientID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid client ID format")
+		return
+	}
+	id, err := uuid.Parse(r.PathValue("attachment_id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid attachment ID format")
+		return
+	}
+
+	attachment, content, err := h.attachmentService.OpenForCoach(r.Context(), coachID, clientID, id)
+	h.stream(w, r, id, attachment, content, err)
+}
+
+// stream answers a download with the attachment's content, or with the error from opening it.
+func (h *WorkoutAttachmentHandler) stream(w http.ResponseWriter, r *http.Request, id uuid.UUID, attachment *models.WorkoutAttachment, content io.ReadCloser, err error) {
+	if err != nil {
+		if !writeWorkoutAttachmentError(w, err) {
+			logger.FromContext(r.Context()).Errorf("Error reading workout attachment %s: %v", id, err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to read attachment")
+		}
+		return
+	}
+	defer content.Close()
+
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(attachment.Size, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	extendDeadlines(w, transferTimeout)
+	if _, err := io.Copy(w, content); err != nil {
+		logger.FromContext(r.Context()).Warnf("Error streaming workout attachment %s: %v", id, err)
+	}
+}
@@ -0,0 +1,221 @@
+// services/user-service/internal/handlers/workout_plans.go
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/services"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// maxPlanDocumentBytes bounds the body of a plan import, which is read whole.
+const maxPlanDocumentBytes = 4 << 20
+
+// WorkoutPlanHandler holds dependencies for workout plan handlers.
+type WorkoutPlanHandler struct {
+	planService services.WorkoutPlanService
+}
+
+// NewWorkoutPlanHandler creates a new WorkoutPlanHandler instance.
+func NewWorkoutPlanHandler(planService services.WorkoutPlanService) *WorkoutPlanHandler {
+	return &WorkoutPlanHandler{planService: planService}
+}
+
+// writeWorkoutPlanError answers the errors shared by the workout plan endpoints, reporting whether it
+// did. Plans of other coaches are reported as not found.
+func writeWorkoutPlanError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, services.ErrNotFound):
+		writeError(w, http.StatusNotFound, models.ErrorCodeNotFound, serviceMessage(err))
+	case errors.Is(err, services.ErrConflict): // A name already taken
+		writeError(w, http.StatusConflict, models.ErrorCodeConflict, serviceMessage(err))
+	case errors.Is(err, services.ErrInvalidInput):
+		writeError(w, http.StatusBadRequest, models.ErrorCodeValidationFailed, serviceMessage(err))
+	default:
+		return false
+	}
+	return true
+}
+
+// Create handles POST /coach/plans, writing a plan credited to the caller.
+func (h *WorkoutPlanHandler) Create(w http.ResponseWriter, r *http.Request) {
+	coachID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+	var req models.CreateWorkoutPlanRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	plan, err := h.planService.Create(r.Context(), coachID, req)
+	if err != nil {
+		if !writeWorkoutPlanError(w, err) {
+			logger.FromContext(r.Context()).Errorf("Error creating workout plan for coach %s: %v", coachID, err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to create plan")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	writeBody(w, plan)
+}
+
+// List handles GET /coach/plans.
+func (h *WorkoutPlanHandler) List(w http.ResponseWriter, r *http.Request) {
+	coachID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+
+	plans, err := h.planService.List(r.Context(), coachID)
+	if err != nil {
+		logger.FromContext(r.Context()).Errorf("Error listing workout plans for coach %s: %v", coachID, err)
+		writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to list plans")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, plans)
+}
+
+// Get handles GET /coach/plans/{id}.
+func (h *WorkoutPlanHandler) Get(w http.ResponseWriter, r *http.Request) {
+	coachID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid plan ID format")
+		return
+	}
+
+	plan, err := h.planService.Get(r.Context(), coachID, id)
+	if err != nil {
+		if !writeWorkoutPlanError(w, err) {
+			logger.FromContext(r.Context()).Errorf("Error getting workout plan %s: %v", id, err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to get plan")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, plan)
+}
+
+// Delete handles DELETE /coach/plans/{id}.
+func (h *WorkoutPlanHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	coachID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid plan ID format")
+		return
+	}
+
+	if err := h.planService.Delete(r.Context(), coachID, id); err != nil {
+		if !writeWorkoutPlanError(w, err) {
+			logger.FromContext(r.Context()).Errorf("Error deleting workout plan %s: %v", id, err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to delete plan")
+		}
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Export handles GET /coach/plans/export, downloading the caller's plans as a plan document: those
+// named by the repeatable id query parameter, or all of them.
+func (h *WorkoutPlanHandler) Export(w http.ResponseWriter, r *http.Request) {
+	coachID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+	var ids []uuid.UUID
+	for _, v := range r.URL.Query()["id"] {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid plan ID format")
+			return
+		}
+		ids = append(ids, id)
+	}
+
+	doc, err := h.planService.Export(r.Context(), coachID, ids)
+	if err != nil {
+		if !writeWorkoutPlanError(w, err) {
+			logger.FromContext(r.Context()).Errorf("Error exporting workout plans for coach %s: %v", coachID, err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to export plans")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", `attachment; filename="workout-plans.json"`)
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, doc)
+}
+
+// Import handles POST /coach/plans/import, adding the plans of a plan document to the caller's. The
+// on_conflict query parameter is skip (the default) or replace; with dry_run=true the response tells
+// what the import would do, conflicts included, and nothing is saved.
+func (h *WorkoutPlanHandler) Import(w http.ResponseWriter, r *http.Request) {
+	coachID, err := uuid.Parse(r.Context().Value(UserContextKey).(string))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, "Unauthorized")
+		return
+	}
+	query := r.URL.Query()
+	opts := models.WorkoutPlanImportOptions{OnConflict: query.Get("on_conflict")}
+	if v := query.Get("dry_run"); v != "" {
+		if opts.DryRun, err = strconv.ParseBool(v); err != nil {
+			writeError(w, http.StatusBadRequest, models.ErrorCodeInvalidParameter, "Invalid dry_run, expected true or false")
+			return
+		}
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPlanDocumentBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, models.ErrorCodePayloadTooLarge, "Request body too large")
+		} else {
+			writeInvalidBody(w, err)
+		}
+		return
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	var doc models.WorkoutPlanDocument
+	if !decodeJSON(w, r, &doc) {
+		return
+	}
+
+	result, err := h.planService.Import(r.Context(), coachID, doc, opts)
+	if err != nil {
+		if !writeWorkoutPlanError(w, err) {
+			logger.FromContext(r.Context()).Errorf("Error importing workout plans for coach %s: %v", coachID, err)
+			writeError(w, http.StatusInternalServerError, models.ErrorCodeInternal, "Failed to import plans")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	writeBody(w, result)
+}
@@ -0,0 +1,115 @@
+// services/user-service/internal/ics/ics.go
+package ics
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Calendar methods (RFC 5546). A REQUEST adds or updates the event in the recipient's calendar;
+// a CANCEL with the same UID removes it.
+const (
+	MethodRequest = "REQUEST"
+	MethodCancel  = "CANCEL"
+)
+
+// ContentType is the MIME type of an invite, with the method parameter mail clients expect.
+func ContentType(method string) string {
+	return "text/calendar; charset=utf-8; method=" + method
+}
+
+// Attendee is a participant of an event.
+type Attendee struct {
+	Name  string
+	Email string
+}
+
+// Event is a single calendar event. Sequence must increase each time an event with the same UID
+// is sent again, or calendars ignore the update.
+type Event struct {
+	UID         string
+	Sequence    int
+	Start       time.Time
+	End         time.Time
+	Summary     string
+	Description string
+	Location    string
+	Organizer   Attendee
+	Attendees   []Attendee
+	Stamp       time.Time
+}
+
+// Invite renders an iCalendar (RFC 5545) object holding the event, for sending with the given method.
+func Invite(e Event, method string) []byte {
+	status := "CONFIRMED"
+	if method == MethodCancel {
+		status = "CANCELLED"
+	}
+	var b strings.Builder
+	line := func(s string) { writeFolded(&b, s) }
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//Pulse//User Service//EN")
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:" + method)
+	line("BEGIN:VEVENT")
+	line("UID:" + escape(e.UID))
+	line(fmt.Sprintf("SEQUENCE:%d", e.Sequence))
+	line("DTSTAMP:" + stamp(e.Stamp))
+	line("DTSTART:" + stamp(e.Start))
+	line("DTEND:" + stamp(e.End))
+	line("SUMMARY:" + escape(e.Summary))
+	if e.Description != "" {
+		line("DESCRIPTION:" + escape(e.Description))
+	}
+	if e.Location != "" {
+		line("LOCATION:" + escape(e.Location))
+	}
+	line("ORGANIZER" + cn(e.Organizer.Name) + ":mailto:" + e.Organizer.Email)
+	for _, a := range e.Attendees {
+		line("ATTENDEE;ROLE=REQ-PARTICIPANT" + cn(a.Name) + ":mailto:" + a.Email)
+	}
+	line("STATUS:" + status)
+	line("END:VEVENT")
+	line("END:VCALENDAR")
+	return []byte(b.String())
+}
+
+// stamp formats a time as a UTC date-time, e.g. 20261016T090000Z.
+func stamp(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// cn renders the common name parameter, quoted since names may contain separators.
+func cn(name string) string {
+	name = strings.NewReplacer(`"`, "'", "\r", "", "\n", " ").Replace(name)
+	if name == "" {
+		return ""
+	}
+	return `;CN="` + name + `"`
+}
+
+// escape escapes a TEXT value: backslashes, separators, and newlines.
+func escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`).Replace(s)
+}
+
+// writeFolded writes a content line, folding it into lines of at most 75 octets without splitting
+// a UTF-8 sequence, and ends it with CRLF.
+func writeFolded(b *strings.Builder, s string) {
+	limit := 75
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && s[cut]&0xC0 == 0x80 { // Don't start the next line inside a multi-byte rune
+			cut--
+		}
+		b.WriteString(s[:cut])
+		b.WriteString("\r\n ")
+		s = s[cut:]
+		limit = 74 // Continuation lines start with a space
+	}
+	b.WriteString(s)
+	b.WriteString("\r\n")
+}
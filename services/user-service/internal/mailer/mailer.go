@@ -0,0 +1,53 @@
+// services/user-service/internal/mailer/mailer.go
+package mailer
+
+import (
+	"fmt"
+	"strings"
+
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// Mailer defines the interface for delivering transactional emails.
+// Implementations can wrap SMTP, a third-party API, or a local stub.
+type Mailer interface {
+	Send(to, subject, body string) error
+}
+
+// LogMailer is a development Mailer that writes emails to the log instead of sending them.
+type LogMailer struct{}
+
+// NewLogMailer creates a new LogMailer instance.
+func NewLogMailer() *LogMailer {
+	return &LogMailer{}
+}
+
+// Send logs the email that would have been delivered.
+func (m *LogMailer) Send(to, subject, body string) error {
+	logger.Logger.Infof("Email to %s | Subject: %s | Body: %s", to, subject, body)
+	return nil
+}
+
+// Attachment is a file sent with an email, such as a calendar invite.
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Content     []byte
+}
+
+// AttachmentMailer is implemented by Mailers that can send attachments. Callers fall back to
+// Send, without the attachments, for Mailers that cannot.
+type AttachmentMailer interface {
+	Mailer
+	SendWithAttachments(to, subject, body string, attachments ...Attachment) error
+}
+
+// SendWithAttachments logs the email and the names and sizes of its attachments.
+func (m *LogMailer) SendWithAttachments(to, subject, body string, attachments ...Attachment) error {
+	names := make([]string, len(attachments))
+	for i, a := range attachments {
+		names[i] = fmt.Sprintf("%s (%s, %d bytes)", a.Filename, a.ContentType, len(a.Content))
+	}
+	logger.Logger.Infof("Email to %s | Subject: %s | Body: %s | Attachments: %s", to, subject, body, strings.Join(names, ", "))
+	return nil
+}
@@ -0,0 +1,61 @@
+// services/user-service/internal/mailer/mx.go
+package mailer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// DomainChecker reports whether a domain can receive email.
+type DomainChecker interface {
+	CheckDomain(domain string) error
+}
+
+// MXChecker is a DomainChecker that looks the domain up in DNS. A domain receives email if it has MX
+// records other than a null MX (RFC 7505), or, lacking MX records, an address record (the implicit MX of
+// RFC 5321). Lookups that fail for any reason other than the domain not existing pass, so a DNS outage
+// never blocks sign-ups.
+type MXChecker struct {
+	resolver *net.Resolver
+	timeout  time.Duration
+}
+
+// NewMXChecker creates an MXChecker whose lookups give up after timeout.
+func NewMXChecker(timeout time.Duration) *MXChecker {
+	return &MXChecker{resolver: net.DefaultResolver, timeout: timeout}
+}
+
+// CheckDomain returns an error if the domain does not exist or does not accept email.
+func (c *MXChecker) CheckDomain(domain string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	mxs, err := c.resolver.LookupMX(ctx, domain)
+	if len(mxs) > 0 {
+		if len(mxs) == 1 && (mxs[0].Host == "." || mxs[0].Host == "") {
+			return fmt.Errorf("domain %s does not accept email", domain)
+		}
+		return nil
+	}
+	if err != nil && !notFound(err) {
+		logger.FromContext(ctx).Warnf("MX lookup for %s failed, accepting the domain: %v", domain, err)
+		return nil
+	}
+	if _, err := c.resolver.LookupHost(ctx, domain); err != nil {
+		if notFound(err) {
+			return fmt.Errorf("domain %s does not accept email", domain)
+		}
+		logger.FromContext(ctx).Warnf("Address lookup for %s failed, accepting the domain: %v", domain, err)
+	}
+	return nil
+}
+
+func notFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
@@ -0,0 +1,58 @@
+// services/user-service/internal/mailer/suppression.go
+package mailer
+
+import (
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// Suppressions tells whether mail to an address must not be sent, because it bounced or its
+// owner complained about mail from Pulse.
+type Suppressions interface {
+	Suppressed(to string) (bool, error)
+}
+
+// SuppressingMailer drops mail to suppressed addresses and sends the rest with the wrapped Mailer.
+// Dropped mail is reported as sent, so callers, such as the forgot-password flow, behave the same
+// for every address. If suppressions cannot be checked, the mail is sent.
+type SuppressingMailer struct {
+	next         Mailer
+	suppressions Suppressions
+}
+
+// NewSuppressingMailer creates a new SuppressingMailer instance.
+func NewSuppressingMailer(next Mailer, suppressions Suppressions) *SuppressingMailer {
+	return &SuppressingMailer{next: next, suppressions: suppressions}
+}
+
+// suppressed reports whether mail to an address must be dropped.
+func (m *SuppressingMailer) suppressed(to, subject string) bool {
+	suppressed, err := m.suppressions.Suppressed(to)
+	if err != nil {
+		logger.Logger.Warnf("Failed to check email suppression of %s, sending anyway: %v", to, err)
+		return false
+	}
+	if suppressed {
+		logger.Logger.Infof("Email to %s suppressed after a bounce or complaint | Subject: %s", to, subject)
+	}
+	return suppressed
+}
+
+// Send sends the email unless its address is suppressed.
+func (m *SuppressingMailer) Send(to, subject, body string) error {
+	if m.suppressed(to, subject) {
+		return nil
+	}
+	return m.next.Send(to, subject, body)
+}
+
+// SendWithAttachments sends the email and its attachments unless its address is suppressed. The
+// attachments are left out if the wrapped Mailer cannot send them.
+func (m *SuppressingMailer) SendWithAttachments(to, subject, body string, attachments ...Attachment) error {
+	if m.suppressed(to, subject) {
+		return nil
+	}
+	if am, ok := m.next.(AttachmentMailer); ok {
+		return am.SendWithAttachments(to, subject, body, attachments...)
+	}
+	return m.next.Send(to, subject, body)
+}
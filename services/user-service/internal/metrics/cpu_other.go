@@ -0,0 +1,12 @@
+// services/user-service/internal/metrics/cpu_other.go
+
+//go:build !unix
+
+package metrics
+
+import "time"
+
+// ProcessCPUTime is not available on this platform; shedding on CPU use is then disabled.
+func ProcessCPUTime() (time.Duration, bool) {
+	return 0, false
+}
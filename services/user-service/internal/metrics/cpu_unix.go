@@ -0,0 +1,20 @@
+// services/user-service/internal/metrics/cpu_unix.go
+
+//go:build unix
+
+package metrics
+
+import (
+	"syscall"
+	"time"
+)
+
+// ProcessCPUTime returns the user and system CPU time the process has used, and whether it is known.
+// The runtime's own CPU metrics are only updated at garbage collections, too rarely to act on.
+func ProcessCPUTime() (time.Duration, bool) {
+	var usage syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &usage); err != nil {
+		return 0, false
+	}
+	return time.Duration(usage.Utime.Nano() + usage.Stime.Nano()), true
+}
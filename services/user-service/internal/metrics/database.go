@@ -0,0 +1,162 @@
+// services/user-service/internal/metrics/database.go
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Reasons a database operation is retried.
+const (
+	DBRetrySerialization = "serialization_failure" // The database aborted a transaction that conflicted with a concurrent one
+	DBRetryDeadlock      = "deadlock"              // The database aborted a transaction to break a deadlock
+	DBRetryConnection    = "connection"            // The connection could not be made or was lost
+)
+
+// Database retry counters grow for the life of the process.
+var (
+	dbRetriesSerialization atomic.Int64
+	dbRetriesDeadlock      atomic.Int64
+	dbRetriesConnection    atomic.Int64
+	dbRetriesSucceeded     atomic.Int64
+	dbRetriesExhausted     atomic.Int64
+)
+
+// DBRetried counts a retry of a database operation that failed for one of the DBRetry reasons.
+func DBRetried(reason string) {
+	switch reason {
+	case DBRetrySerialization:
+		dbRetriesSerialization.Add(1)
+	case DBRetryDeadlock:
+		dbRetriesDeadlock.Add(1)
+	case DBRetryConnection:
+		dbRetriesConnection.Add(1)
+	}
+}
+
+// DBRetrySucceeded counts a database operation that succeeded after being retried.
+func DBRetrySucceeded() {
+	dbRetriesSucceeded.Add(1)
+}
+
+// DBRetriesExhausted counts a database operation that still failed for a transient reason on its last attempt.
+func DBRetriesExhausted() {
+	dbRetriesExhausted.Add(1)
+}
+
+// DBPoolStats is a snapshot of a database connection pool.
+type DBPoolStats struct {
+	MaxConns     int           // Connections the pool may open
+	OpenConns    int           // Connections open, in use or idle
+	InUse        int           // Connections held by a query or transaction
+	Idle         int           // Connections open and free
+	WaitCount    int64         // Connection requests that had to wait for one, since the pool opened
+	WaitDuration time.Duration // Time those requests waited, in total
+}
+
+// dbPool is a pool registered with RegisterDBPool.
+type dbPool struct {
+	name  string
+	stats func() DBPoolStats
+}
+
+var (
+	dbPoolsMu sync.Mutex
+	dbPools   []dbPool
+)
+
+// RegisterDBPool reports the pool stats returns under name, such as "database" or "database:eu", on
+// every scrape. stats is called on the scraping goroutine, so it must not block.
+func RegisterDBPool(name string, stats func() DBPoolStats) {
+	dbPoolsMu.Lock()
+	defer dbPoolsMu.Unlock()
+	dbPools = append(dbPools, dbPool{name: name, stats: stats})
+}
+
+// dbLatencyBuckets are the upper bounds, in seconds, of the database operation latency histogram.
+var dbLatencyBuckets = [...]float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5}
+
+// latencyHistogram counts observed latencies by bucket; the last count is for those above every bound.
+type latencyHistogram struct {
+	counts [len(dbLatencyBuckets) + 1]atomic.Int64
+	sumNS  atomic.Int64
+}
+
+// dbOperations holds a *latencyHistogram per operation name; the names are those of the repository
+// methods, a fixed set, so the labels stay bounded.
+var dbOperations sync.Map
+
+// ObserveDBOperation records the latency of one attempt of a database operation, named like
+// "User.GetUserByEmail".
+func ObserveDBOperation(op string, latency time.Duration) {
+	h, ok := dbOperations.Load(op)
+	if !ok {
+		h, _ = dbOperations.LoadOrStore(op, &latencyHistogram{})
+	}
+	hist := h.(*latencyHistogram)
+	i, _ := slices.BinarySearch(dbLatencyBuckets[:], latency.Seconds())
+	hist.counts[i].Add(1)
+	hist.sumNS.Add(int64(latency))
+}
+
+func writeDatabaseMetrics(w io.Writer) {
+	fmt.Fprintf(w, "# HELP pulse_db_retries_total Retries of database operations that failed for a transient reason, by reason.\n# TYPE pulse_db_retries_total counter\n")
+	fmt.Fprintf(w, "pulse_db_retries_total{reason=%q} %d\n", DBRetrySerialization, dbRetriesSerialization.Load())
+	fmt.Fprintf(w, "pulse_db_retries_total{reason=%q} %d\n", DBRetryDeadlock, dbRetriesDeadlock.Load())
+	fmt.Fprintf(w, "pulse_db_retries_total{reason=%q} %d\n", DBRetryConnection, dbRetriesConnection.Load())
+	fmt.Fprintf(w, "# HELP pulse_db_retry_successes_total Database operations that succeeded after being retried.\n# TYPE pulse_db_retry_successes_total counter\npulse_db_retry_successes_total %d\n", dbRetriesSucceeded.Load())
+	fmt.Fprintf(w, "# HELP pulse_db_retries_exhausted_total Database operations that failed for a transient reason on every attempt.\n# TYPE pulse_db_retries_exhausted_total counter\npulse_db_retries_exhausted_total %d\n", dbRetriesExhausted.Load())
+
+	writeDBPoolMetrics(w)
+	writeDBOperationMetrics(w)
+}
+
+func writeDBPoolMetrics(w io.Writer) {
+	dbPoolsMu.Lock()
+	pools := slices.Clone(dbPools)
+	dbPoolsMu.Unlock()
+	stats := make([]DBPoolStats, len(pools))
+	for i, pool := range pools {
+		stats[i] = pool.stats()
+	}
+	gauge := func(name, kind, help string, value func(DBPoolStats) string) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+		for i, pool := range pools {
+			fmt.Fprintf(w, "%s{pool=%q} %s\n", name, pool.name, value(stats[i]))
+		}
+	}
+	gauge("pulse_db_pool_max_connections", "gauge", "Connections the pool may open.", func(s DBPoolStats) string { return fmt.Sprint(s.MaxConns) })
+	gauge("pulse_db_pool_open_connections", "gauge", "Connections open, in use or idle.", func(s DBPoolStats) string { return fmt.Sprint(s.OpenConns) })
+	gauge("pulse_db_pool_in_use_connections", "gauge", "Connections held by a query or transaction.", func(s DBPoolStats) string { return fmt.Sprint(s.InUse) })
+	gauge("pulse_db_pool_idle_connections", "gauge", "Connections open and free.", func(s DBPoolStats) string { return fmt.Sprint(s.Idle) })
+	gauge("pulse_db_pool_waits_total", "counter", "Connection requests that waited for a free connection.", func(s DBPoolStats) string { return fmt.Sprint(s.WaitCount) })
+	gauge("pulse_db_pool_wait_seconds_total", "counter", "Time connection requests waited for a free connection.", func(s DBPoolStats) string { return fmt.Sprintf("%g", s.WaitDuration.Seconds()) })
+}
+
+func writeDBOperationMetrics(w io.Writer) {
+	var ops []string
+	dbOperations.Range(func(op, _ any) bool {
+		ops = append(ops, op.(string))
+		return true
+	})
+	slices.Sort(ops)
+	name := "pulse_db_operation_duration_seconds"
+	fmt.Fprintf(w, "# HELP %s Latency of each attempt of a repository operation, by operation.\n# TYPE %s histogram\n", name, name)
+	for _, op := range ops {
+		h, _ := dbOperations.Load(op)
+		hist := h.(*latencyHistogram)
+		var count int64
+		for i, bound := range dbLatencyBuckets {
+			count += hist.counts[i].Load()
+			fmt.Fprintf(w, "%s_bucket{operation=%q,le=\"%g\"} %d\n", name, op, bound, count)
+		}
+		count += hist.counts[len(dbLatencyBuckets)].Load()
+		fmt.Fprintf(w, "%s_bucket{operation=%q,le=\"+Inf\"} %d\n", name, op, count)
+		fmt.Fprintf(w, "%s_sum{operation=%q} %g\n", name, op, time.Duration(hist.sumNS.Load()).Seconds())
+		fmt.Fprintf(w, "%s_count{operation=%q} %d\n", name, op, count)
+	}
+}
@@ -0,0 +1,29 @@
+// services/user-service/internal/metrics/field_sealing.go
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sync/atomic"
+)
+
+// Field sealing counters are updated by the resealing job and grow for the life of the process.
+var (
+	healthFieldsResealed atomic.Int64
+	resealFailure        atomic.Int64
+)
+
+// HealthFieldsResealed counts users whose health fields a resealing pass sealed under the current key.
+func HealthFieldsResealed(n int) {
+	healthFieldsResealed.Add(int64(n))
+}
+
+// ResealFailed counts a resealing pass that stopped on an error.
+func ResealFailed() {
+	resealFailure.Add(1)
+}
+
+func writeFieldSealingMetrics(w io.Writer) {
+	fmt.Fprintf(w, "# HELP pulse_health_fields_resealed_total Users whose health fields were sealed under the current key by the resealing job.\n# TYPE pulse_health_fields_resealed_total counter\npulse_health_fields_resealed_total %d\n", healthFieldsResealed.Load())
+	fmt.Fprintf(w, "# HELP pulse_reseal_failures_total Resealing passes that stopped on an error.\n# TYPE pulse_reseal_failures_total counter\npulse_reseal_failures_total %d\n", resealFailure.Load())
+}
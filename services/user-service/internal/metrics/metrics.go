@@ -0,0 +1,90 @@
+// services/user-service/internal/metrics/metrics.go
+package metrics
+
+import (
+	"net/http"
+	"time"
+)
+
+// statusRecorder remembers the status code written by a handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	route  string // Set by SetRoute for requests the mux never routed
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	if s.status == 0 {
+		s.status = status
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusRecorder) Write(p []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	return s.ResponseWriter.Write(p)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+// Middleware records the status and latency of every request under its ServeMux pattern, or the route
+// given with SetRoute. It must wrap the ServeMux directly: the pattern is only known once the mux has
+// routed the request, and is set on the request value the mux receives.
+func Middleware(mux http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		defer func() {
+			status := rec.status
+			if p := recover(); p != nil {
+				status = http.StatusInternalServerError
+				defer panic(p) // Record the failure, then let the recovery middleware handle it
+			}
+			if status == 0 {
+				status = http.StatusOK
+			}
+			route := r.Pattern
+			if rec.route != "" {
+				route = rec.route
+			}
+			if route != "" {
+				observe(route, status, time.Since(start), start)
+			}
+		}()
+		mux.ServeHTTP(rec, r)
+	})
+}
+
+// SetRoute records the route of a request answered before the ServeMux routed it, such as one refused
+// by a middleware in between, for the Middleware w was passed through. It does nothing outside one.
+func SetRoute(w http.ResponseWriter, route string) {
+	for {
+		switch rw := w.(type) {
+		case *statusRecorder:
+			rw.route = route
+			return
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = rw.Unwrap()
+		default:
+			return
+		}
+	}
+}
+
+// Handler serves all gauges and counters in the Prometheus text exposition format.
+func Handler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	writeSLOGauges(w)
+	writeSessionMetrics(w)
+	writeBlobMetrics(w)
+	writeLoadSheddingMetrics(w)
+	writeDatabaseMetrics(w)
+	writeOutboxMetrics(w)
+	writeFieldSealingMetrics(w)
+	writeRealtimeMetrics(w)
+}
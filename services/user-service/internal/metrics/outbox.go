@@ -0,0 +1,29 @@
+// services/user-service/internal/metrics/outbox.go
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sync/atomic"
+)
+
+// Outbox counters are updated by the outbox dispatcher and grow for the life of the process.
+var (
+	outboxPublished      atomic.Int64
+	outboxPublishFailure atomic.Int64
+)
+
+// OutboxPublished counts domain events published from the outbox.
+func OutboxPublished(n int) {
+	outboxPublished.Add(int64(n))
+}
+
+// OutboxPublishFailed counts a failed attempt to publish an event from the outbox.
+func OutboxPublishFailed() {
+	outboxPublishFailure.Add(1)
+}
+
+func writeOutboxMetrics(w io.Writer) {
+	fmt.Fprintf(w, "# HELP pulse_outbox_published_total Domain events published from the outbox.\n# TYPE pulse_outbox_published_total counter\npulse_outbox_published_total %d\n", outboxPublished.Load())
+	fmt.Fprintf(w, "# HELP pulse_outbox_publish_failures_total Failed attempts to publish a domain event from the outbox.\n# TYPE pulse_outbox_publish_failures_total counter\npulse_outbox_publish_failures_total %d\n", outboxPublishFailure.Load())
+}
@@ -0,0 +1,65 @@
+// services/user-service/internal/metrics/realtime.go
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sync/atomic"
+)
+
+// Transports that stream events to clients.
+const (
+	TransportWebSocket = "websocket" // GET /ws
+	TransportSSE       = "sse"       // GET /users/me/events, Server-Sent Events
+)
+
+// streamMetrics are the event stream metrics of one transport.
+type streamMetrics struct {
+	connections   atomic.Int64
+	eventsSent    atomic.Int64
+	slowConsumers atomic.Int64
+}
+
+// Event stream metrics are updated by the realtime hub; the counters grow for the life of the process.
+var (
+	websocketStreams streamMetrics
+	sseStreams       streamMetrics
+)
+
+func streamsOf(transport string) *streamMetrics {
+	if transport == TransportSSE {
+		return &sseStreams
+	}
+	return &websocketStreams
+}
+
+// StreamConnections adjusts the number of open event streams of a transport.
+func StreamConnections(transport string, delta int) {
+	streamsOf(transport).connections.Add(int64(delta))
+}
+
+// StreamEventSent counts an event queued for an event stream.
+func StreamEventSent(transport string) {
+	streamsOf(transport).eventsSent.Add(1)
+}
+
+// StreamSlowConsumer counts an event stream closed because its queue of unsent events was full.
+func StreamSlowConsumer(transport string) {
+	streamsOf(transport).slowConsumers.Add(1)
+}
+
+func writeRealtimeMetrics(w io.Writer) {
+	transports := []string{TransportWebSocket, TransportSSE}
+	fmt.Fprintf(w, "# HELP pulse_event_streams Open event streams on this replica, by transport.\n# TYPE pulse_event_streams gauge\n")
+	for _, t := range transports {
+		fmt.Fprintf(w, "pulse_event_streams{transport=%q} %d\n", t, streamsOf(t).connections.Load())
+	}
+	fmt.Fprintf(w, "# HELP pulse_event_stream_events_sent_total Events queued for event streams, by transport.\n# TYPE pulse_event_stream_events_sent_total counter\n")
+	for _, t := range transports {
+		fmt.Fprintf(w, "pulse_event_stream_events_sent_total{transport=%q} %d\n", t, streamsOf(t).eventsSent.Load())
+	}
+	fmt.Fprintf(w, "# HELP pulse_event_stream_slow_consumers_total Event streams closed for falling too far behind, by transport.\n# TYPE pulse_event_stream_slow_consumers_total counter\n")
+	for _, t := range transports {
+		fmt.Fprintf(w, "pulse_event_stream_slow_consumers_total{transport=%q} %d\n", t, streamsOf(t).slowConsumers.Load())
+	}
+}
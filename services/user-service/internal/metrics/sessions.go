@@ -0,0 +1,43 @@
+// services/user-service/internal/metrics/sessions.go
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sync/atomic"
+)
+
+// Session gauges are refreshed by the session reaper; the counters grow for the life of the process.
+var (
+	activeSessions      atomic.Int64
+	usersWithSessions   atomic.Int64
+	sessionsEvicted     atomic.Int64
+	sessionsReaped      atomic.Int64
+	sessionGaugesLoaded atomic.Bool
+)
+
+// SetActiveSessions records the current number of unexpired sessions and of users holding one.
+func SetActiveSessions(sessions, users int64) {
+	activeSessions.Store(sessions)
+	usersWithSessions.Store(users)
+	sessionGaugesLoaded.Store(true)
+}
+
+// SessionsEvicted counts sessions ended because their user went over the concurrent session limit.
+func SessionsEvicted(n int) {
+	sessionsEvicted.Add(int64(n))
+}
+
+// SessionsReaped counts expired sessions removed by the reaper.
+func SessionsReaped(n int64) {
+	sessionsReaped.Add(n)
+}
+
+func writeSessionMetrics(w io.Writer) {
+	if sessionGaugesLoaded.Load() {
+		fmt.Fprintf(w, "# HELP pulse_active_sessions Unexpired sessions, as of the last reaper run.\n# TYPE pulse_active_sessions gauge\npulse_active_sessions %d\n", activeSessions.Load())
+		fmt.Fprintf(w, "# HELP pulse_users_with_sessions Users with at least one unexpired session, as of the last reaper run.\n# TYPE pulse_users_with_sessions gauge\npulse_users_with_sessions %d\n", usersWithSessions.Load())
+	}
+	fmt.Fprintf(w, "# HELP pulse_sessions_evicted_total Sessions ended by the concurrent session limit.\n# TYPE pulse_sessions_evicted_total counter\npulse_sessions_evicted_total %d\n", sessionsEvicted.Load())
+	fmt.Fprintf(w, "# HELP pulse_sessions_reaped_total Expired sessions removed by the reaper.\n# TYPE pulse_sessions_reaped_total counter\npulse_sessions_reaped_total %d\n", sessionsReaped.Load())
+}
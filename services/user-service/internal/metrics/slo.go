@@ -0,0 +1,229 @@
+// services/user-service/internal/metrics/slo.go
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"health-tracker-project/services/user-service/internal/config"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// defaultBurnRateAlert is the burn rate at which a 30-day error budget would be gone in about two days,
+// the usual threshold for a fast-burn page.
+const defaultBurnRateAlert = 14.4
+
+// Burn rate windows, besides the SLO's own window.
+const (
+	shortBurnWindow = 5
+	longBurnWindow  = 60
+)
+
+// bucket counts the requests of one minute.
+type bucket struct {
+	minute int64 // Unix minute the counts belong to; stale buckets are ignored
+	total  uint64
+	good   uint64
+}
+
+// series is the per-minute history of one SLO, as a ring buffer covering the longest allowed window.
+type series struct {
+	route     string
+	threshold time.Duration
+	buckets   [config.MaxSLOWindowMinutes]bucket
+}
+
+func (s *series) add(minute int64, good bool) {
+	b := &s.buckets[minute%config.MaxSLOWindowMinutes]
+	if b.minute != minute {
+		*b = bucket{minute: minute}
+	}
+	b.total++
+	if good {
+		b.good++
+	}
+}
+
+// sum totals the last n minutes up to and including minute.
+func (s *series) sum(minute int64, n int) (total, good uint64) {
+	for m := minute - int64(n) + 1; m <= minute; m++ {
+		if b := s.buckets[m%config.MaxSLOWindowMinutes]; b.minute == m {
+			total += b.total
+			good += b.good
+		}
+	}
+	return total, good
+}
+
+// SLOStatus is the current state of one SLO.
+type SLOStatus struct {
+	Name                 string             `json:"name"`
+	Route                string             `json:"route"`
+	Objective            float64            `json:"objective"`
+	LatencyThresholdMS   int                `json:"latency_threshold_ms"`
+	WindowMinutes        int                `json:"window_minutes"`
+	Requests             uint64             `json:"requests"`   // Requests in the window
+	Compliance           float64            `json:"compliance"` // Fraction of good requests in the window; 1 without traffic
+	ErrorBudgetRemaining float64            `json:"error_budget_remaining"`
+	BurnRates            map[string]float64 `json:"burn_rates"` // By window: "5m", "1h", and "window"
+	Alerting             bool               `json:"alerting"`
+}
+
+var (
+	mu       sync.Mutex
+	history  = map[string]*series{} // By SLO name
+	alerting = map[string]bool{}
+)
+
+// observe records one request against every SLO defined for its route.
+func observe(route string, status int, latency time.Duration, at time.Time) {
+	slos := config.Current().SLOs
+	if len(slos) == 0 {
+		return
+	}
+	minute := at.Unix() / 60
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, slo := range slos {
+		if slo.Route != route {
+			continue
+		}
+		threshold := time.Duration(slo.LatencyThresholdMS) * time.Millisecond
+		s := history[slo.Name]
+		if s == nil || s.route != slo.Route || s.threshold != threshold {
+			// New SLO, or its definition changed on reload: old counts no longer apply.
+			s = &series{route: slo.Route, threshold: threshold}
+			history[slo.Name] = s
+		}
+		s.add(minute, status < 500 && (threshold == 0 || latency <= threshold))
+	}
+}
+
+// SLOSummary returns the status of every configured SLO, ordered by name.
+func SLOSummary() []SLOStatus {
+	return summarize(time.Now())
+}
+
+func summarize(now time.Time) []SLOStatus {
+	slos := config.Current().SLOs
+	minute := now.Unix() / 60
+
+	mu.Lock()
+	defer mu.Unlock()
+	statuses := make([]SLOStatus, 0, len(slos))
+	for _, slo := range slos {
+		status := SLOStatus{
+			Name:               slo.Name,
+			Route:              slo.Route,
+			Objective:          slo.Objective,
+			LatencyThresholdMS: slo.LatencyThresholdMS,
+			WindowMinutes:      slo.WindowMinutes,
+			Compliance:         1,
+			BurnRates:          map[string]float64{"5m": 0, "1h": 0, "window": 0},
+			Alerting:           alerting[slo.Name],
+		}
+		budget := 1 - slo.Objective
+		burn := func(n int) (rate float64, total, good uint64) {
+			if s := history[slo.Name]; s != nil {
+				total, good = s.sum(minute, min(n, slo.WindowMinutes))
+			}
+			if total == 0 {
+				return 0, 0, 0
+			}
+			return float64(total-good) / float64(total) / budget, total, good
+		}
+		status.BurnRates["5m"], _, _ = burn(shortBurnWindow)
+		status.BurnRates["1h"], _, _ = burn(longBurnWindow)
+		rate, total, good := burn(slo.WindowMinutes)
+		status.BurnRates["window"] = rate
+		status.Requests = total
+		if total > 0 {
+			status.Compliance = float64(good) / float64(total)
+		}
+		status.ErrorBudgetRemaining = 1 - rate
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// WatchBurnRates evaluates burn-rate alerts every interval and never returns.
+// An alert fires when both the 5-minute and 1-hour burn rates exceed the SLO's threshold;
+// it is logged at error level, which also sends it to the error reporter.
+func WatchBurnRates(interval time.Duration) {
+	for range time.Tick(interval) {
+		evaluateAlerts(time.Now())
+	}
+}
+
+func evaluateAlerts(now time.Time) {
+	thresholds := map[string]float64{}
+	for _, slo := range config.Current().SLOs {
+		thresholds[slo.Name] = slo.BurnRateAlert
+		if slo.BurnRateAlert == 0 {
+			thresholds[slo.Name] = defaultBurnRateAlert
+		}
+	}
+
+	for _, status := range summarize(now) {
+		threshold := thresholds[status.Name]
+		firing := status.BurnRates["5m"] > threshold && status.BurnRates["1h"] > threshold
+		if firing == status.Alerting {
+			continue
+		}
+		mu.Lock()
+		alerting[status.Name] = firing
+		mu.Unlock()
+		if firing {
+			logger.Logger.Errorw(fmt.Sprintf("SLO %s is burning its error budget too fast", status.Name),
+				"route", status.Route,
+				"burn_rate_5m", status.BurnRates["5m"],
+				"burn_rate_1h", status.BurnRates["1h"],
+				"threshold", threshold,
+				"job", "slo_alerting",
+			)
+		} else {
+			logger.Logger.Infof("SLO %s burn rate back below %.1f", status.Name, threshold)
+		}
+	}
+}
+
+// writeSLOGauges writes the SLO gauges in the Prometheus text exposition format.
+func writeSLOGauges(w io.Writer) {
+	statuses := SLOSummary()
+	gauge := func(name, help string, value func(SLOStatus) []string) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+		for _, s := range statuses {
+			for _, line := range value(s) {
+				fmt.Fprintf(w, "%s%s\n", name, line)
+			}
+		}
+	}
+	gauge("pulse_slo_compliance", "Fraction of good requests over the SLO window.", func(s SLOStatus) []string {
+		return []string{fmt.Sprintf("{slo=%q} %g", s.Name, s.Compliance)}
+	})
+	gauge("pulse_slo_error_budget_remaining", "Fraction of the error budget left in the SLO window.", func(s SLOStatus) []string {
+		return []string{fmt.Sprintf("{slo=%q} %g", s.Name, s.ErrorBudgetRemaining)}
+	})
+	gauge("pulse_slo_burn_rate", "Error budget burn rate; 1 spends exactly the budget over the window.", func(s SLOStatus) []string {
+		var lines []string
+		for _, window := range []string{"5m", "1h", "window"} {
+			lines = append(lines, fmt.Sprintf("{slo=%q,window=%q} %g", s.Name, window, s.BurnRates[window]))
+		}
+		return lines
+	})
+	gauge("pulse_slo_window_requests", "Requests counted in the SLO window.", func(s SLOStatus) []string {
+		return []string{fmt.Sprintf("{slo=%q} %d", s.Name, s.Requests)}
+	})
+	gauge("pulse_slo_alerting", "1 while a burn-rate alert is firing.", func(s SLOStatus) []string {
+		v := 0
+		if s.Alerting {
+			v = 1
+		}
+		return []string{fmt.Sprintf("{slo=%q} %d", s.Name, v)}
+	})
+}
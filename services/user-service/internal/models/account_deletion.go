@@ -0,0 +1,16 @@
+// services/user-service/internal/models/account_deletion.go
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AccountDeletion answers a right-to-be-forgotten request: the account is locked straight away and
+// erased, with its data in other Pulse services, once DueAt has passed.
+type AccountDeletion struct {
+	UserID uuid.UUID `json:"user_id"`
+	Status string    `json:"status"` // Always pending_deletion
+	DueAt  time.Time `json:"due_at"`
+}
@@ -0,0 +1,67 @@
+// services/user-service/internal/models/aggregation.go
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Kinds of custom aggregation period. They only label the period; all kinds aggregate the same way.
+const (
+	AggregationPeriodTrainingBlock = "training_block"
+	AggregationPeriodChallenge     = "challenge"
+	AggregationPeriodCustom        = "custom"
+)
+
+// AggregationPeriodKinds lists the valid kinds of custom period.
+var AggregationPeriodKinds = []string{AggregationPeriodTrainingBlock, AggregationPeriodChallenge, AggregationPeriodCustom}
+
+// Limits on custom periods and on the range GET /users/{id}/aggregation-windows resolves at once.
+const (
+	MaxAggregationPeriods       = 200
+	MaxAggregationPeriodDays    = 366
+	MaxAggregationWindowsRange  = 400 // Days
+	MaxAggregationPeriodNameLen = 100
+)
+
+// AggregationPeriod is a user-defined date range, such as a training block or a challenge, that
+// analytics aggregate over alongside days and weeks. Both dates are inclusive local dates.
+type AggregationPeriod struct {
+	ID        uuid.UUID `json:"id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Name      string    `json:"name"`
+	Kind      string    `json:"kind"`
+	StartsOn  string    `json:"starts_on"` // YYYY-MM-DD
+	EndsOn    string    `json:"ends_on"`   // YYYY-MM-DD, inclusive
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// AggregationPeriodRequest is the body of POST /me/aggregation-periods and PUT /me/aggregation-periods/{id}.
+type AggregationPeriodRequest struct {
+	Name     string `json:"name" validate:"required,max=100"`
+	Kind     string `json:"kind"`                          // Defaults to custom
+	StartsOn string `json:"starts_on" validate:"required"` // YYYY-MM-DD
+	EndsOn   string `json:"ends_on" validate:"required"`
+}
+
+// Kinds of aggregation window.
+const (
+	AggregationWindowWeek   = "week"
+	AggregationWindowPeriod = "period"
+)
+
+// AggregationWindow is one bucket an analytics query aggregates over: a week starting on the user's
+// WeekStart, or one of their custom periods. StartsOn and EndsOn are the local dates it covers;
+// Start and End are the matching instants (End exclusive), resolved with the timezone in effect on
+// each boundary date so that windows spanning a move still cover whole local days.
+type AggregationWindow struct {
+	Kind     string     `json:"kind"`
+	PeriodID *uuid.UUID `json:"period_id,omitempty"`
+	Name     string     `json:"name,omitempty"`
+	StartsOn string     `json:"starts_on"`
+	EndsOn   string     `json:"ends_on"`
+	Start    time.Time  `json:"start"`
+	End      time.Time  `json:"end"`
+}
@@ -0,0 +1,93 @@
+// services/user-service/internal/models/announcement.go
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Announcement segments: who an announcement is sent to.
+const (
+	SegmentAll      = "all"
+	SegmentOrg      = "org"      // Users whose org metadata is Org
+	SegmentPlan     = "plan"     // Users whose plan metadata is Plan
+	SegmentInactive = "inactive" // Users without a successful sign-in for InactiveDays
+)
+
+// UserOrgMetadataKey is the user metadata key holding the user's organization, such as a clinic or an
+// employer, set by the integrator that enrolled them.
+const UserOrgMetadataKey = "org"
+
+// DefaultInactiveDays is how long users of the inactive segment have not signed in, unless set.
+const DefaultInactiveDays = 30
+
+// Announcement statuses.
+const (
+	AnnouncementScheduled = "scheduled"
+	AnnouncementSending   = "sending"
+	AnnouncementSent      = "sent"
+	AnnouncementCancelled = "cancelled"
+)
+
+// AnnouncementSegment selects the recipients of an announcement. Only active users who have not
+// turned off notifications.push are ever sent one.
+type AnnouncementSegment struct {
+	Type         string `json:"type"`
+	Org          string `json:"org,omitempty"`           // For the org segment
+	Plan         string `json:"plan,omitempty"`          // For the plan segment
+	InactiveDays int    `json:"inactive_days,omitempty"` // For the inactive segment
+}
+
+// AnnouncementStats counts the deliveries of an announcement. Recipients is the size of the segment
+// when sending started; users joining or leaving it during the send make Sent + Failed differ.
+type AnnouncementStats struct {
+	Recipients int `json:"recipients"`
+	Sent       int `json:"sent"`   // Accepted by the push gateway
+	Failed     int `json:"failed"` // Refused by the push gateway, or unreachable
+}
+
+// Announcement is a push notification an admin sends to a segment of users.
+type Announcement struct {
+	ID         uuid.UUID           `json:"id"`
+	Title      string              `json:"title"`
+	Body       string              `json:"body"`
+	Segment    AnnouncementSegment `json:"segment"`
+	Status     string              `json:"status"`
+	SendAt     time.Time           `json:"send_at"`
+	CreatedBy  string              `json:"created_by"`
+	CreatedAt  time.Time           `json:"created_at"`
+	StartedAt  *time.Time          `json:"started_at,omitempty"`
+	FinishedAt *time.Time          `json:"finished_at,omitempty"` // When it was sent in full, or cancelled
+	Stats      AnnouncementStats   `json:"stats"`
+	Cursor     uuid.UUID           `json:"-"` // The last recipient handled, so a send resumes after a restart
+}
+
+// CreateAnnouncementRequest is the payload of POST /admin/announcements and its preview.
+type CreateAnnouncementRequest struct {
+	Title   string              `json:"title" validate:"required"`
+	Body    string              `json:"body" validate:"required"`
+	Segment AnnouncementSegment `json:"segment"`
+	SendAt  *time.Time          `json:"send_at,omitempty"` // Unset or past: as soon as possible
+}
+
+// AnnouncementAudience is the size of a segment.
+type AnnouncementAudience struct {
+	Recipients int `json:"recipients"`
+	OptedOut   int `json:"opted_out"` // In the segment, but with notifications.push turned off
+}
+
+// AnnouncementPreview shows what an announcement would send, and to how many users, without sending it.
+type AnnouncementPreview struct {
+	Title    string               `json:"title"`
+	Body     string               `json:"body"`
+	Audience AnnouncementAudience `json:"audience"`
+	Sample   []uuid.UUID          `json:"sample"` // A few recipients, to check the segment
+}
+
+// AnnouncementFilter narrows a listing of announcements, newest first. Zero values mean "no constraint".
+type AnnouncementFilter struct {
+	Status string
+	After  *PageKey // Key (created_at, ID) of the last announcement of the previous page
+	Limit  int
+}
@@ -0,0 +1,92 @@
+// services/user-service/internal/models/appointment.go
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Appointment statuses. A rescheduled appointment is replaced by a new one pointing back to it.
+const (
+	AppointmentBooked      = "booked"
+	AppointmentCancelled   = "cancelled"
+	AppointmentRescheduled = "rescheduled"
+)
+
+// AppointmentSlot is a bookable time published by a provider (a coach or clinician). Slots and the
+// appointments booked in them are stored with the provider's data.
+type AppointmentSlot struct {
+	ID         uuid.UUID `json:"id"`
+	ProviderID uuid.UUID `json:"provider_id"`
+	StartsAt   time.Time `json:"starts_at"`
+	EndsAt     time.Time `json:"ends_at"`
+	Location   string    `json:"location"` // Address or video call link; may be empty
+	Booked     bool      `json:"booked"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// PublishAvailabilityRequest publishes the window from StartsAt to EndsAt, cut into back-to-back
+// slots of SlotMinutes. SlotLength is a human-style alternative to SlotMinutes, e.g. "1:30" or "45 min".
+type PublishAvailabilityRequest struct {
+	StartsAt    time.Time `json:"starts_at" validate:"required"`
+	EndsAt      time.Time `json:"ends_at" validate:"required"`
+	SlotMinutes int       `json:"slot_minutes"`
+	SlotLength  string    `json:"slot_length,omitempty"`
+	Location    string    `json:"location"`
+}
+
+// Appointment is a user's booking of a provider's slot. The time and location are copied from the
+// slot, so the booking keeps them if the slot is later removed.
+type Appointment struct {
+	ID              uuid.UUID  `json:"id"`
+	SlotID          *uuid.UUID `json:"slot_id,omitempty"`
+	ProviderID      uuid.UUID  `json:"provider_id"`
+	UserID          uuid.UUID  `json:"user_id"`
+	StartsAt        time.Time  `json:"starts_at"`
+	EndsAt          time.Time  `json:"ends_at"`
+	Location        string     `json:"location"`
+	Reason          string     `json:"reason"`
+	Status          string     `json:"status"`
+	RescheduledFrom *uuid.UUID `json:"rescheduled_from,omitempty"` // The appointment this one replaced
+	RescheduleCount int        `json:"reschedule_count"`           // Reschedules so far, carried over from RescheduledFrom
+	CreatedAt       time.Time  `json:"created_at"`
+	CancelledAt     *time.Time `json:"cancelled_at,omitempty"` // Also set when rescheduled
+	CancelledBy     *uuid.UUID `json:"cancelled_by,omitempty"`
+	CancelReason    string     `json:"cancel_reason,omitempty"`
+	ReminderSentAt  *time.Time `json:"reminder_sent_at,omitempty"`
+}
+
+// BookAppointmentRequest books an open slot.
+type BookAppointmentRequest struct {
+	SlotID uuid.UUID `json:"slot_id" validate:"required"`
+	Reason string    `json:"reason"`
+}
+
+// CancelAppointmentRequest cancels a booked appointment.
+type CancelAppointmentRequest struct {
+	Reason string `json:"reason"`
+}
+
+// RescheduleAppointmentRequest moves a booked appointment to another open slot of the same provider.
+type RescheduleAppointmentRequest struct {
+	SlotID uuid.UUID `json:"slot_id" validate:"required"`
+}
+
+// SlotFilter narrows a provider's slots to those starting in [From, To). OpenOnly leaves out booked slots.
+type SlotFilter struct {
+	ProviderID uuid.UUID
+	From       time.Time
+	To         time.Time
+	OpenOnly   bool
+}
+
+// AppointmentFilter narrows an appointment query to one provider's or one user's appointments
+// starting in [From, To). Zero values mean "no constraint".
+type AppointmentFilter struct {
+	ProviderID uuid.UUID
+	UserID     uuid.UUID
+	From       time.Time
+	To         time.Time
+	Status     string
+}
@@ -0,0 +1,70 @@
+// services/user-service/internal/models/audit_event.go
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Audited security-relevant actions.
+const (
+	AuditLogin              = "login"
+	AuditLogout             = "logout"
+	AuditPasswordChange     = "password_change"
+	AuditUserCreate         = "user_create"
+	AuditUserUpdate         = "user_update"
+	AuditUserDelete         = "user_delete"
+	AuditUserSuspend        = "user_suspend"
+	AuditUserReactivate     = "user_reactivate"
+	AuditUserDeactivate     = "user_deactivate"
+	AuditUserDeletion       = "user_deletion_request" // Erasure scheduled by the user
+	AuditUserErase          = "user_erase"            // Erasure carried out; the target is a pseudonym
+	AuditUserMerge          = "user_merge"
+	AuditUserMergeUndo      = "user_merge_undo"
+	AuditIdentityLink       = "identity_link"
+	AuditUserRegion         = "user_region_change"
+	AuditConsentGrant       = "integration_consent_grant"
+	AuditConsentRevoke      = "integration_consent_revoke"
+	AuditCoachAuthorize     = "coach_authorize"
+	AuditCoachRevoke        = "coach_revoke"
+	AuditAPIKeyCreate       = "api_key_create" // Developer app registered; the target is the app
+	AuditAPIKeyRotate       = "api_key_rotate"
+	AuditAPIKeyRevoke       = "api_key_revoke"
+	AuditAPIKeyDebug        = "api_key_debug" // Debug recording turned on or off; details.recording says which
+	AuditAnnouncementCreate = "announcement_create"
+	AuditAnnouncementCancel = "announcement_cancel"
+)
+
+// Audit outcomes.
+const (
+	AuditSuccess = "success"
+	AuditFailure = "failure"
+)
+
+// AuditEvent records who did what to whom, from where. Rows outlive the users they mention,
+// so actor and target are plain IDs rather than foreign keys.
+type AuditEvent struct {
+	ID        uuid.UUID         `json:"id"`
+	Action    string            `json:"action"`
+	Outcome   string            `json:"outcome"`
+	ActorID   string            `json:"actor_id,omitempty"`  // Empty for unauthenticated requests, e.g. a failed login
+	TargetID  string            `json:"target_id,omitempty"` // The user acted upon
+	IP        string            `json:"ip"`
+	UserAgent string            `json:"user_agent,omitempty"`
+	Details   map[string]string `json:"details,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+// AuditEventFilter narrows an audit log query. Zero values mean "no constraint".
+// Pages are fetched newest first (by created_at, then ID) by passing the key of the last event seen as After.
+type AuditEventFilter struct {
+	Action   string
+	Outcome  string
+	ActorID  string
+	TargetID string
+	IP       string
+	Since    time.Time
+	After    *PageKey
+	Limit    int
+}
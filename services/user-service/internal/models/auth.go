@@ -2,17 +2,24 @@
 package models
 
 // LoginRequest defines the structure for a login request from the client.
-// It uses 'email' as the primary identifier for consistency with GetUserByEmail.
+// It uses 'email' as the primary identifier for consistency with GetUserByEmail; users who chose a
+// username can send it in 'email' or 'username' instead.
 type LoginRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email        string     `json:"email"`
+	Username     string     `json:"username,omitempty"`
+	Password     string     `json:"password" validate:"required"`
+	CaptchaToken string     `json:"captcha_token"` // Checked by the handler when the runtime config requires it
+	Client       ClientInfo `json:"-"`             // Set by the handler for the login history, never read from the body
 }
 
 // RegisterRequest defines the structure for a user registration request from the client.
 type RegisterRequest struct {
-	Name     string `json:"name"`
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Name         string `json:"name" validate:"required"`
+	Email        string `json:"email" validate:"required,email"`
+	Username     string `json:"username,omitempty"` // Optional public handle; can be chosen or changed later
+	Password     string `json:"password" validate:"required,min=8"`
+	Country      string `json:"country"`       // Optional ISO 3166-1 alpha-2 code; picks the data residency region
+	CaptchaToken string `json:"captcha_token"` // Checked by the handler when the runtime config requires it
 }
 
 // AuthResponse defines the structure for a successful authentication response to the client.
@@ -20,4 +27,18 @@ type AuthResponse struct {
 	Token        string       `json:"token"`
 	User         UserResponse `json:"user"` // Uses the UserResponse DTO from models/user.go
 	ExpiresInSec int64        `json:"expires_in_sec"`
-}
\ No newline at end of file
+	// ReverifyEmail is set when mail to the user's email is suppressed after a bounce or complaint, so the
+	// client asks them to verify it again (POST /me/email/verification) or change it.
+	ReverifyEmail bool `json:"reverify_email,omitempty"`
+}
+
+// ForgotPasswordRequest defines the structure for requesting a password reset email.
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// ResetPasswordRequest defines the structure for completing a password reset.
+type ResetPasswordRequest struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required,min=8"`
+}
@@ -0,0 +1,72 @@
+// services/user-service/internal/models/bulk.go
+package models
+
+import (
+	"encoding/json"
+
+	"github.com/google/uuid"
+)
+
+// Operations of a bulk user request.
+const (
+	BulkCreate = "create"
+	BulkUpdate = "update"
+	BulkDelete = "delete"
+)
+
+// MaxBulkOperations caps the operations of one bulk request, which all run in one transaction.
+const MaxBulkOperations = 1000
+
+// BulkUserRequest is the payload for POST /users/bulk: operations applied together, all or none.
+type BulkUserRequest struct {
+	Operations []BulkUserOperation `json:"operations" validate:"required,max=1000"`
+}
+
+// BulkUserOperation is one operation of a bulk request. User is a CreateUserRequest for a create and an
+// UpdateUserRequest for an update; ID names the user to update or delete.
+type BulkUserOperation struct {
+	Op   string          `json:"op" validate:"required,oneof=create update delete"`
+	ID   uuid.UUID       `json:"id,omitempty"`
+	User json.RawMessage `json:"user,omitempty"`
+}
+
+// BulkUserItem is a bulk operation as decoded by the handler, for UserService.ApplyBulk. Create or
+// Update holds the request of its kind of operation.
+type BulkUserItem struct {
+	Op     string
+	ID     uuid.UUID
+	Create CreateUserRequest
+	Update UpdateUserRequest
+}
+
+// BulkUserOutcome is what became of a BulkUserItem: the created or updated user, or why it failed or
+// was not applied.
+type BulkUserOutcome struct {
+	User *UserResponse
+	Err  error
+}
+
+// BulkUserResponse is the response of POST /users/bulk. If any operation failed, none was applied.
+type BulkUserResponse struct {
+	Applied bool             `json:"applied"`
+	Results []BulkUserResult `json:"results"` // One per operation, in request order
+}
+
+// BulkUserResult is the outcome of one operation of a bulk request. Status is the one the operation
+// would have had on its own route, such as 201 for a create or 404 for an update of a missing user; an
+// operation that was fine but rolled back because another failed has 424 Failed Dependency.
+type BulkUserResult struct {
+	Index  int           `json:"index"`
+	Op     string        `json:"op"`
+	Status int           `json:"status"`
+	User   *UserResponse `json:"user,omitempty"`  // The created or updated user
+	Error  *APIError     `json:"error,omitempty"` // Why the operation failed or was not applied
+}
+
+// UserChange is a change to a user that UserRepository.ApplyUserChanges applies along with others.
+// User is the user to create, the updated user, or the user to delete.
+type UserChange struct {
+	Op              string // BulkCreate, BulkUpdate, or BulkDelete
+	User            *User
+	TimezoneChanged bool // An update changed the timezone, so it is added to the timezone history
+}
@@ -0,0 +1,72 @@
+// services/user-service/internal/models/consent.go
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DataFlow is one kind of data exchanged with a third-party provider, and why.
+type DataFlow struct {
+	Data    string `json:"data"`    // e.g. "steps", "heart_rate", "profile"
+	Purpose string `json:"purpose"` // Shown to the user before they consent
+}
+
+// Integration describes a third-party provider users can link, and the terms they must accept first.
+type Integration struct {
+	Provider     string     `json:"provider"` // Stable identifier, e.g. "fitbit"
+	Name         string     `json:"name"`
+	TermsVersion string     `json:"terms_version"` // Bumped whenever the terms or data flows change
+	TermsURL     string     `json:"terms_url"`
+	Imports      []DataFlow `json:"imports"` // Data flowing from the provider into Pulse
+	Exports      []DataFlow `json:"exports"` // Data flowing from Pulse to the provider
+}
+
+// IntegrationCatalog lists the linkable integrations. It is maintained as data (see config.LoadIntegrations).
+type IntegrationCatalog struct {
+	Integrations []Integration `json:"integrations"`
+}
+
+// Find returns the integration of a provider, or nil.
+func (c *IntegrationCatalog) Find(provider string) *Integration {
+	for i := range c.Integrations {
+		if c.Integrations[i].Provider == provider {
+			return &c.Integrations[i]
+		}
+	}
+	return nil
+}
+
+// IntegrationConsent records a user accepting an integration's terms. The data flows are copied from
+// the catalog at the time, so the record shows exactly what was agreed to. A consent is never
+// updated except to revoke it; consenting again, e.g. to new terms, adds a new record.
+type IntegrationConsent struct {
+	ID           uuid.UUID  `json:"id"`
+	UserID       uuid.UUID  `json:"user_id"`
+	Provider     string     `json:"provider"`
+	TermsVersion string     `json:"terms_version"`
+	Imports      []DataFlow `json:"imports"`
+	Exports      []DataFlow `json:"exports"`
+	IP           string     `json:"ip"`
+	UserAgent    string     `json:"user_agent"`
+	AcceptedAt   time.Time  `json:"accepted_at"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
+	DeleteData   bool       `json:"delete_data"` // Set on revocation when the user asked for imported data to be deleted
+}
+
+// GrantConsentRequest accepts the terms of an integration. TermsVersion must be the version the user
+// was shown, so consent to outdated terms is refused.
+type GrantConsentRequest struct {
+	TermsVersion string `json:"terms_version" validate:"required"`
+	Accept       bool   `json:"accept"`
+}
+
+// ConsentRevocationFilter selects revocations for the sync-service to act on, oldest first.
+// Pages are fetched (by revoked_at, then ID) by passing the key of the last revocation seen as After;
+// Since only starts the first page.
+type ConsentRevocationFilter struct {
+	Since time.Time
+	After *PageKey
+	Limit int
+}
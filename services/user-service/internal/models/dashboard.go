@@ -0,0 +1,41 @@
+// services/user-service/internal/models/dashboard.go
+package models
+
+import "time"
+
+// DashboardSchemaVersion is the version of the dashboard layout document accepted by PUT /me/dashboard.
+// Bump it, and convert stored documents on read, when the document shape changes.
+const DashboardSchemaVersion = 1
+
+// DashboardWidgetTypes lists the widgets a dashboard can show, in their default order.
+var DashboardWidgetTypes = []string{"steps", "heart_rate", "sleep", "workouts", "calories", "weight", "hydration"}
+
+// DashboardDateRanges lists the date ranges a widget can cover.
+var DashboardDateRanges = []string{"1d", "7d", "30d", "90d", "365d"}
+
+// DefaultDashboardDateRange applies to widgets saved without a date range.
+const DefaultDashboardDateRange = "7d"
+
+// DashboardWidget holds the settings of one widget. Its position in DashboardLayout.Widgets is its order.
+type DashboardWidget struct {
+	Type      string `json:"type"`
+	Visible   bool   `json:"visible"`
+	DateRange string `json:"date_range"`
+}
+
+// DashboardLayout is a user's dashboard as a versioned JSON document.
+type DashboardLayout struct {
+	SchemaVersion int               `json:"schema_version"`
+	Widgets       []DashboardWidget `json:"widgets"`
+	Default       bool              `json:"default"`              // True until the user saves a layout
+	UpdatedAt     *time.Time        `json:"updated_at,omitempty"` // When the layout was last saved
+}
+
+// DefaultDashboardLayout returns the layout shown to users who have not saved one: every widget visible, in catalogue order.
+func DefaultDashboardLayout() *DashboardLayout {
+	layout := &DashboardLayout{SchemaVersion: DashboardSchemaVersion, Default: true}
+	for _, t := range DashboardWidgetTypes {
+		layout.Widgets = append(layout.Widgets, DashboardWidget{Type: t, Visible: true, DateRange: DefaultDashboardDateRange})
+	}
+	return layout
+}
@@ -0,0 +1,39 @@
+// services/user-service/internal/models/data_summary.go
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Data categories the user-service stores. Other Pulse services report their own (activities, vitals, ...).
+const (
+	DataCategoryProfile            = "profile"
+	DataCategoryTimeline           = "timeline"
+	DataCategoryLoginHistory       = "login_history"
+	DataCategoryMessages           = "messages"
+	DataCategoryMessageAttachments = "message_attachments"
+	DataCategoryWorkoutAttachments = "workout_attachments"
+	DataCategoryWorkoutPlans       = "workout_plans" // Plans the user wrote or imported as a coach
+	DataCategoryConsents           = "integration_consents"
+)
+
+// DataCategory summarizes what one Pulse service stores about a user in one category.
+type DataCategory struct {
+	Category string     `json:"category"`
+	Service  string     `json:"service"`          // The Pulse service holding the data
+	Count    int64      `json:"count"`            // Records, such as activities or messages
+	Bytes    int64      `json:"bytes"`            // Storage used, including uploaded files
+	Oldest   *time.Time `json:"oldest,omitempty"` // Unset when Count is 0
+	Newest   *time.Time `json:"newest,omitempty"`
+}
+
+// DataSummary is what Pulse knows about a user, by category, for the settings screen.
+type DataSummary struct {
+	UserID      uuid.UUID      `json:"user_id"`
+	GeneratedAt time.Time      `json:"generated_at"`
+	Categories  []DataCategory `json:"categories"`
+	TotalBytes  int64          `json:"total_bytes"`
+	Unavailable []string       `json:"unavailable,omitempty"` // Services that did not answer; their categories are missing
+}
@@ -0,0 +1,66 @@
+// services/user-service/internal/models/developer_app.go
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// APIKeyPrefix starts every developer app API key, so leaked keys are easy to spot in code and logs.
+const APIKeyPrefix = "pk_"
+
+// MaxDeveloperApps bounds the apps one user can register.
+const MaxDeveloperApps = 10
+
+// Debug recording keeps the most recent MaxDebugRecordings public API exchanges of an app, each body cut
+// to MaxRecordedBodyBytes. It switches itself off DebugRecordingDuration after being turned on.
+const (
+	DebugRecordingDuration = time.Hour
+	MaxDebugRecordings     = 100
+	MaxRecordedBodyBytes   = 64 << 10
+)
+
+// DeveloperApp is a third-party application registered for the public API. It calls the API with
+// its key and acts as its owner, with none of the owner's scopes. Only a hash of the key is stored.
+type DeveloperApp struct {
+	ID           uuid.UUID  `json:"id"`
+	OwnerID      uuid.UUID  `json:"owner_id"`
+	Name         string     `json:"name"`
+	Description  string     `json:"description,omitempty"`
+	KeyPrefix    string     `json:"key_prefix"` // The key's first characters, to tell keys apart
+	KeyHash      string     `json:"-"`          // SHA-256 of the key
+	CreatedAt    time.Time  `json:"created_at"`
+	KeyRotatedAt *time.Time `json:"key_rotated_at,omitempty"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"` // Revoked apps' keys are rejected
+	// DebugUntil is when debug recording of the app's requests stops; nil when it is off.
+	DebugUntil *time.Time `json:"debug_until,omitempty"`
+}
+
+// Debugging reports whether the app's public API requests are being recorded at now.
+func (a *DeveloperApp) Debugging(now time.Time) bool {
+	return a.DebugUntil != nil && now.Before(*a.DebugUntil)
+}
+
+// CreateDeveloperAppRequest is the body of POST /developer/apps.
+type CreateDeveloperAppRequest struct {
+	Name        string `json:"name" validate:"required,max=100"`
+	Description string `json:"description,omitempty"`
+}
+
+// DeveloperAppCredentials returns an app with its API key, which is only shown on creation and rotation.
+type DeveloperAppCredentials struct {
+	App    DeveloperApp `json:"app"`
+	APIKey string       `json:"api_key"`
+}
+
+// DeveloperAppUsage counts an app's public API requests to one route on one UTC day.
+// Throttled requests were refused by the rate limit or the daily quota and are not in Requests.
+type DeveloperAppUsage struct {
+	Date         string `json:"date"`  // YYYY-MM-DD
+	Route        string `json:"route"` // ServeMux pattern, e.g. "GET /public/v1/me"
+	Requests     int64  `json:"requests"`
+	ClientErrors int64  `json:"client_errors"` // 4xx responses other than throttling
+	ServerErrors int64  `json:"server_errors"`
+	Throttled    int64  `json:"throttled"`
+}
@@ -0,0 +1,196 @@
+// services/user-service/internal/models/email.go
+package models
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Limits on email addresses, from RFC 5321.
+const (
+	MaxEmailLength      = 254
+	MaxEmailLocalLength = 64
+)
+
+// emailLocalSymbols are the characters other than letters and digits allowed in a dot-atom local part.
+const emailLocalSymbols = "!#$%&'*+/=?^_`{|}~-."
+
+// NormalizeEmail trims and lowercases an address and checks its syntax: a dot-atom local part of
+// letters, digits, and the RFC 5322 symbols, and a domain of at least two labels. Non-ASCII letters are
+// accepted (RFC 6531); internationalized domains are stored in their ASCII (punycode) form, so both
+// spellings are one address. Quoted local parts, IP literals, and combining characters are refused;
+// the latter means only precomposed spellings of accented letters are accepted, as the standard
+// library has no Unicode normalization to fold the two.
+func NormalizeEmail(addr string) (string, error) {
+	addr = strings.TrimSpace(addr)
+	if !utf8.ValidString(addr) {
+		return "", fmt.Errorf("must be valid UTF-8")
+	}
+	at := strings.LastIndex(addr, "@")
+	if at <= 0 || at == len(addr)-1 {
+		return "", fmt.Errorf("must be of the form name@domain")
+	}
+	local, domain := strings.ToLower(addr[:at]), strings.ToLower(addr[at+1:])
+
+	if len(local) > MaxEmailLocalLength {
+		return "", fmt.Errorf("the part before @ must be at most %d bytes", MaxEmailLocalLength)
+	}
+	if strings.HasPrefix(local, ".") || strings.HasSuffix(local, ".") || strings.Contains(local, "..") {
+		return "", fmt.Errorf("the part before @ cannot start or end with a dot or contain consecutive dots")
+	}
+	for _, c := range local {
+		switch {
+		case unicode.Is(unicode.M, c):
+			return "", fmt.Errorf("must not contain combining characters; use precomposed letters")
+		case !unicode.IsLetter(c) && !unicode.IsDigit(c) && !strings.ContainsRune(emailLocalSymbols, c):
+			return "", fmt.Errorf("the part before @ cannot contain %q", c)
+		}
+	}
+
+	domain = strings.TrimSuffix(domain, ".")
+	labels := strings.Split(domain, ".")
+	if len(labels) < 2 {
+		return "", fmt.Errorf("the domain must have at least two labels")
+	}
+	for i, label := range labels {
+		ascii, err := domainLabelToASCII(label)
+		if err != nil {
+			return "", err
+		}
+		labels[i] = ascii
+	}
+	if strings.Trim(labels[len(labels)-1], "0123456789") == "" {
+		return "", fmt.Errorf("the domain cannot be an IP address")
+	}
+
+	email := local + "@" + strings.Join(labels, ".")
+	if len(email) > MaxEmailLength {
+		return "", fmt.Errorf("must be at most %d bytes", MaxEmailLength)
+	}
+	return email, nil
+}
+
+// EmailKey returns the key two addresses share when they reach the same mailbox: a normalized address
+// without the "+tag" subaddress (RFC 5233) of its local part. Emails are unique by key, and looked up by it.
+func EmailKey(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return email
+	}
+	local := email[:at]
+	if plus := strings.Index(local, "+"); plus > 0 {
+		local = local[:plus]
+	}
+	return local + email[at:]
+}
+
+// domainLabelToASCII checks a lowercased domain label and returns its ASCII form, punycoding
+// labels with non-ASCII letters.
+func domainLabelToASCII(label string) (string, error) {
+	if label == "" {
+		return "", fmt.Errorf("the domain cannot contain empty labels")
+	}
+	ascii := true
+	for _, c := range label {
+		switch {
+		case c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-':
+		case c >= utf8.RuneSelf && unicode.IsLetter(c) || c >= utf8.RuneSelf && unicode.IsDigit(c):
+			ascii = false
+		default:
+			return "", fmt.Errorf("the domain cannot contain %q", c)
+		}
+	}
+	if !ascii {
+		label = "xn--" + punycode(label)
+	}
+	if len(label) > 63 {
+		return "", fmt.Errorf("domain labels must be at most 63 bytes")
+	}
+	if strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+		return "", fmt.Errorf("domain labels cannot start or end with a hyphen")
+	}
+	return label, nil
+}
+
+// punycode encodes a label with the Bootstring parameters of RFC 3492.
+func punycode(label string) string {
+	const (
+		base        = 36
+		tMin        = 1
+		tMax        = 26
+		skew        = 38
+		damp        = 700
+		initialBias = 72
+		initialN    = 128
+	)
+	adapt := func(delta, points int, first bool) int {
+		if first {
+			delta /= damp
+		} else {
+			delta /= 2
+		}
+		delta += delta / points
+		k := 0
+		for delta > (base-tMin)*tMax/2 {
+			delta /= base - tMin
+			k += base
+		}
+		return k + (base-tMin+1)*delta/(delta+skew)
+	}
+	digit := func(d int) byte {
+		if d < 26 {
+			return byte('a' + d)
+		}
+		return byte('0' + d - 26)
+	}
+
+	runes := []rune(label)
+	var out []byte
+	for _, c := range runes {
+		if c < utf8.RuneSelf {
+			out = append(out, byte(c))
+		}
+	}
+	basic := len(out)
+	if basic > 0 {
+		out = append(out, '-')
+	}
+	n, delta, bias := initialN, 0, initialBias
+	for handled := basic; handled < len(runes); {
+		m := int(utf8.MaxRune) + 1
+		for _, c := range runes {
+			if int(c) >= n && int(c) < m {
+				m = int(c)
+			}
+		}
+		delta += (m - n) * (handled + 1)
+		n = m
+		for _, c := range runes {
+			if int(c) < n {
+				delta++
+				continue
+			}
+			if int(c) > n {
+				continue
+			}
+			q := delta
+			for k := base; ; k += base {
+				t := min(max(k-bias, tMin), tMax)
+				if q < t {
+					break
+				}
+				out = append(out, digit(t+(q-t)%(base-t)))
+				q = (q - t) / (base - t)
+			}
+			out = append(out, digit(q))
+			bias = adapt(delta, handled+1, handled == basic)
+			delta = 0
+			handled++
+		}
+		delta++
+		n++
+	}
+	return string(out)
+}
@@ -0,0 +1,75 @@
+// services/user-service/internal/models/email_deliverability.go
+package models
+
+import "time"
+
+// Deliverability of a user's email, from the bounces and complaints the mail provider reports.
+// Mail to a bounced or complained address is suppressed until the user verifies it again or changes it.
+const (
+	EmailOK           = "ok"
+	EmailSoftBouncing = "soft_bouncing" // Temporarily refused, e.g. a full mailbox; mail is still sent
+	EmailBounced      = "bounced"       // Refused for good, or MaxSoftBounces times in a row
+	EmailComplained   = "complained"    // The recipient marked mail from Pulse as spam
+)
+
+// MaxSoftBounces is how many soft bounces in a row, each within SoftBounceWindow of the last,
+// suppress an address like a hard bounce.
+const (
+	MaxSoftBounces   = 3
+	SoftBounceWindow = 7 * 24 * time.Hour
+)
+
+// Kinds of email delivery events reported by mail providers.
+const (
+	EmailHardBounce = "hard_bounce"
+	EmailSoftBounce = "soft_bounce"
+	EmailComplaint  = "complaint"
+)
+
+// EmailDeliveryEvent is a bounce or complaint reported by a mail provider, in a form common to providers.
+type EmailDeliveryEvent struct {
+	Email      string    `json:"email"`
+	Kind       string    `json:"type"`             // EmailHardBounce, EmailSoftBounce, or EmailComplaint
+	Detail     string    `json:"detail,omitempty"` // The provider's description, such as the SMTP reply
+	Provider   string    `json:"-"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// EmailWebhookResult is the response to a mail provider's webhook.
+type EmailWebhookResult struct {
+	Received int `json:"received"` // Bounces and complaints in the request
+	Applied  int `json:"applied"`  // Of those, the ones about a user's current email
+}
+
+// ConfirmEmailRequest is the payload of POST /me/email/verification/confirm.
+type ConfirmEmailRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// EmailSuppressed reports whether mail to the user's email is suppressed.
+func (u *User) EmailSuppressed() bool {
+	return u.EmailStatus == EmailBounced || u.EmailStatus == EmailComplained
+}
+
+// NextEmailStatus returns the deliverability of an email after a delivery event of kind at the given
+// time, and its count of soft bounces in a row, from its current status, count, and when the status
+// last changed. Soft bounces do not change the status of an address already suppressed.
+func NextEmailStatus(status string, softBounces int, statusAt *time.Time, kind string, at time.Time) (string, int) {
+	switch kind {
+	case EmailHardBounce:
+		return EmailBounced, softBounces
+	case EmailComplaint:
+		return EmailComplained, softBounces
+	}
+	if status == EmailBounced || status == EmailComplained {
+		return status, softBounces
+	}
+	if status != EmailSoftBouncing || statusAt == nil || at.Sub(*statusAt) > SoftBounceWindow {
+		softBounces = 0
+	}
+	softBounces++
+	if softBounces >= MaxSoftBounces {
+		return EmailBounced, softBounces
+	}
+	return EmailSoftBouncing, softBounces
+}
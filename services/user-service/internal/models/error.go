@@ -0,0 +1,87 @@
+// services/user-service/internal/models/error.go
+package models
+
+// ErrorResponse is the body of every error response: {"error": {"code": ..., "message": ...}}.
+type ErrorResponse struct {
+	Error APIError `json:"error"`
+}
+
+// APIError describes what went wrong. Code is stable for clients to switch on; Message is for people
+// and may change. Details, when present, list the individual problems, such as each invalid field.
+type APIError struct {
+	Code    string        `json:"code"`
+	Message string        `json:"message"`
+	Details []ErrorDetail `json:"details,omitempty"`
+}
+
+// ErrorDetail is one problem behind an error, at Field (a body field, parameter, or JSON path) if it
+// concerns one.
+type ErrorDetail struct {
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// Error codes, grouped by the status they usually come with. The generic code of a status is used where
+// no more specific one applies, so clients can fall back on it.
+const (
+	// 400 Bad Request
+	ErrorCodeInvalidBody      = "INVALID_BODY"      // The body is not the JSON the route takes
+	ErrorCodeInvalidParameter = "INVALID_PARAMETER" // A path or query parameter is missing or malformed
+	ErrorCodeInvalidCursor    = "INVALID_CURSOR"    // A pagination cursor was altered or sent with other filters
+	ErrorCodeValidationFailed = "VALIDATION_FAILED" // The request is well-formed but a value is not acceptable
+	ErrorCodeCaptchaRequired  = "CAPTCHA_REQUIRED"  // The route needs a captcha_token
+
+	// 401 Unauthorized
+	ErrorCodeUnauthenticated    = "UNAUTHENTICATED"     // No valid session
+	ErrorCodeInvalidToken       = "INVALID_TOKEN"       // A session, reset, link, or verification token is invalid or expired (or 400 Bad Request)
+	ErrorCodeInvalidCredentials = "INVALID_CREDENTIALS" // Wrong email, username, or password
+	ErrorCodeInvalidAPIKey      = "INVALID_API_KEY"     // The public API key is missing, unknown, or revoked
+	ErrorCodeSignInFailed       = "SIGN_IN_FAILED"      // An SSO sign-in was refused, expired, or unconfirmed (or 400 Bad Request)
+
+	// 403 Forbidden
+	ErrorCodeForbidden       = "FORBIDDEN"
+	ErrorCodeMissingScope    = "MISSING_SCOPE"    // The session lacks the scope the route needs
+	ErrorCodeAccountInactive = "ACCOUNT_INACTIVE" // The account is suspended or deactivated
+	ErrorCodeCaptchaFailed   = "CAPTCHA_FAILED"
+
+	// 404 Not Found
+	ErrorCodeNotFound             = "NOT_FOUND"
+	ErrorCodeUserNotFound         = "USER_NOT_FOUND"
+	ErrorCodeThreadNotFound       = "THREAD_NOT_FOUND"
+	ErrorCodeAttachmentNotFound   = "ATTACHMENT_NOT_FOUND"
+	ErrorCodeAnnouncementNotFound = "ANNOUNCEMENT_NOT_FOUND"
+	ErrorCodeDeveloperAppNotFound = "DEVELOPER_APP_NOT_FOUND"
+	ErrorCodeSlotNotFound         = "SLOT_NOT_FOUND"
+	ErrorCodeProviderNotFound     = "PROVIDER_NOT_FOUND"
+	ErrorCodeCoachNotFound        = "COACH_NOT_FOUND"
+	ErrorCodeConsentNotFound      = "CONSENT_NOT_FOUND"
+	ErrorCodeIntegrationNotFound  = "INTEGRATION_NOT_FOUND"
+
+	// 405 Method Not Allowed
+	ErrorCodeMethodNotAllowed = "METHOD_NOT_ALLOWED"
+
+	// 409 Conflict
+	ErrorCodeConflict      = "CONFLICT"
+	ErrorCodeEmailTaken    = "EMAIL_TAKEN"
+	ErrorCodeUsernameTaken = "USERNAME_TAKEN"
+
+	// 413, 415, and 422
+	ErrorCodePayloadTooLarge      = "PAYLOAD_TOO_LARGE"
+	ErrorCodeUnsupportedMediaType = "UNSUPPORTED_MEDIA_TYPE"
+	ErrorCodeInvalidConfig        = "INVALID_CONFIG" // A runtime config that does not validate
+
+	// 426 Upgrade Required
+	ErrorCodeUpgradeRequired = "UPGRADE_REQUIRED" // GET /ws without a WebSocket handshake
+
+	// 424 Failed Dependency
+	ErrorCodeNotApplied = "NOT_APPLIED" // A bulk operation rolled back because another one failed
+
+	// 429 Too Many Requests
+	ErrorCodeRateLimited   = "RATE_LIMITED"
+	ErrorCodeQuotaExceeded = "QUOTA_EXCEEDED" // The public API key's daily quota
+
+	// 500 Internal Server Error and 503 Service Unavailable
+	ErrorCodeInternal    = "INTERNAL_ERROR"
+	ErrorCodeOverloaded  = "OVERLOADED"  // Shed under load; retry after Retry-After
+	ErrorCodeUnavailable = "UNAVAILABLE" // A dependency, such as the CAPTCHA provider, is down
+)
@@ -0,0 +1,63 @@
+// services/user-service/internal/models/identity.go
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Ways to prove ownership of an existing account when linking an SSO identity to it.
+const (
+	LinkMethodPassword  = "password"
+	LinkMethodEmailCode = "email_code"
+)
+
+// ExternalIdentity is an account at an SSO provider, as verified by the oidc or saml package.
+type ExternalIdentity struct {
+	Method  string // LoginMethodOIDC or LoginMethodSAML
+	Issuer  string
+	Subject string
+	Email   string
+	Name    string
+}
+
+// UserIdentity is an SSO identity linked to a Pulse user. An identity belongs to at most one user.
+type UserIdentity struct {
+	UserID   uuid.UUID `json:"-"`
+	Method   string    `json:"method"`
+	Issuer   string    `json:"issuer"`
+	Subject  string    `json:"subject"`
+	Email    string    `json:"email"` // As asserted by the provider when the identity was linked
+	LinkedAt time.Time `json:"linked_at"`
+}
+
+// IdentityLinkRequest is a pending link between an SSO identity and the existing account with the same email.
+// Only hashes of the link token and email code are stored.
+type IdentityLinkRequest struct {
+	ID        uuid.UUID
+	TokenHash string
+	CodeHash  string
+	UserID    uuid.UUID
+	Identity  ExternalIdentity
+	Attempts  int // Failed proofs so far
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+// IdentityLinkChallenge is returned instead of a session when an SSO sign-in matches an existing account
+// that the identity is not linked to yet. The client completes it with POST /auth/link.
+type IdentityLinkChallenge struct {
+	LinkToken string    `json:"link_token"`
+	Email     string    `json:"email"`
+	Methods   []string  `json:"methods"` // Proofs accepted: password, email_code
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// LinkIdentityRequest completes an identity link with the account's password or the code emailed to it.
+type LinkIdentityRequest struct {
+	LinkToken string     `json:"link_token" validate:"required"`
+	Password  string     `json:"password,omitempty"`
+	Code      string     `json:"code,omitempty"`
+	Client    ClientInfo `json:"-"`
+}
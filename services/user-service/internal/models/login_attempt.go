@@ -0,0 +1,41 @@
+// services/user-service/internal/models/login_attempt.go
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Sign-in methods recorded in the login history.
+const (
+	LoginMethodPassword = "password"
+	LoginMethodOIDC     = "oidc"
+	LoginMethodSAML     = "saml"
+)
+
+// ClientInfo is the network origin of a request, as determined by the HTTP layer.
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+// LoginAttempt is one sign-in attempt on an existing account. Attempts for unknown emails are not recorded.
+type LoginAttempt struct {
+	ID            uuid.UUID `json:"id"`
+	UserID        uuid.UUID `json:"-"`
+	Success       bool      `json:"success"`
+	Method        string    `json:"method"`
+	FailureReason string    `json:"failure_reason,omitempty"` // invalid_credentials, account_suspended, or account_deactivated
+	IP            string    `json:"ip"`
+	UserAgent     string    `json:"user_agent,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// LoginAttemptFilter narrows a login history query. Zero values mean "no constraint".
+// Pages are fetched newest first (by created_at, then ID) by passing the key of the last attempt seen as After.
+type LoginAttemptFilter struct {
+	UserID uuid.UUID
+	After  *PageKey
+	Limit  int
+}
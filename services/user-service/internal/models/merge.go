@@ -0,0 +1,35 @@
+// services/user-service/internal/models/merge.go
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserMerge records that a duplicate (donor) account was folded into a primary account.
+// The donor row is kept as a snapshot so the merge can be undone.
+type UserMerge struct {
+	ID              uuid.UUID        `json:"id"`
+	PrimaryUserID   uuid.UUID        `json:"primary_user_id"`
+	DonorUserID     uuid.UUID        `json:"donor_user_id"`
+	DonorEmail      string           `json:"donor_email"` // Kept as an alias of the primary user until undone
+	DonorSnapshot   User             `json:"-"`           // Full donor row, including the password hash, for undo
+	MovedIdentities []MergedIdentity `json:"moved_identities"`
+	MergedBy        string           `json:"merged_by"`
+	CreatedAt       time.Time        `json:"created_at"`
+	UndoneAt        *time.Time       `json:"undone_at,omitempty"`
+	UndoneBy        string           `json:"undone_by,omitempty"`
+}
+
+// MergedIdentity is an external identity that a merge moved from the donor to the primary user.
+type MergedIdentity struct {
+	Issuer  string `json:"issuer"`
+	Subject string `json:"subject"`
+}
+
+// MergeUsersRequest is the payload for POST /admin/users/merge.
+type MergeUsersRequest struct {
+	PrimaryUserID uuid.UUID `json:"primary_user_id" validate:"required"`
+	DonorUserID   uuid.UUID `json:"donor_user_id" validate:"required"`
+}
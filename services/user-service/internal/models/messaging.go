@@ -0,0 +1,86 @@
+// services/user-service/internal/models/messaging.go
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CoachAuthorization lets a coach message a user. Only the user can grant or revoke it.
+type CoachAuthorization struct {
+	UserID       uuid.UUID  `json:"user_id"`
+	CoachID      uuid.UUID  `json:"coach_id"`
+	AuthorizedAt time.Time  `json:"authorized_at"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
+}
+
+// MessageThread is a conversation between a user and one of their coaches. It is stored with the
+// user's data, so it lives in the user's data residency region.
+type MessageThread struct {
+	ID            uuid.UUID `json:"id"`
+	UserID        uuid.UUID `json:"user_id"`
+	CoachID       uuid.UUID `json:"coach_id"`
+	Subject       string    `json:"subject"`
+	CreatedAt     time.Time `json:"created_at"`
+	LastMessageAt time.Time `json:"last_message_at"`
+	Unread        int       `json:"unread"` // Messages from the other participant the caller has not read
+}
+
+// Message is one message in a thread. ReadAt is the read receipt, set when the other participant reads it.
+type Message struct {
+	ID          uuid.UUID           `json:"id"`
+	ThreadID    uuid.UUID           `json:"thread_id"`
+	SenderID    uuid.UUID           `json:"sender_id"`
+	Body        string              `json:"body"`
+	Attachments []MessageAttachment `json:"attachments"`
+	CreatedAt   time.Time           `json:"created_at"`
+	ReadAt      *time.Time          `json:"read_at,omitempty"`
+}
+
+// MessageAttachment is a file uploaded to a thread. Its content is kept in the blob store under
+// BlobKey; it belongs to a message once sent, and unsent uploads are cleaned up.
+type MessageAttachment struct {
+	ID          uuid.UUID  `json:"id"`
+	ThreadID    uuid.UUID  `json:"thread_id"`
+	MessageID   *uuid.UUID `json:"message_id,omitempty"`
+	UploaderID  uuid.UUID  `json:"uploader_id"`
+	Filename    string     `json:"filename"`
+	ContentType string     `json:"content_type"`
+	Size        int64      `json:"size"`
+	BlobKey     string     `json:"-"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// CreateThreadRequest starts a thread. A user names one of their coaches, and a coach names a user
+// who authorized them, in ParticipantID.
+type CreateThreadRequest struct {
+	ParticipantID uuid.UUID `json:"participant_id" validate:"required"`
+	Subject       string    `json:"subject"`
+}
+
+// SendMessageRequest posts a message. AttachmentIDs are uploads to the same thread by the sender.
+type SendMessageRequest struct {
+	Body          string      `json:"body"`
+	AttachmentIDs []uuid.UUID `json:"attachment_ids"`
+}
+
+// MessageFilter pages through a thread, newest first (by created_at, then ID), by passing the key of the last message seen as After.
+type MessageFilter struct {
+	ThreadID uuid.UUID
+	After    *PageKey
+	Limit    int
+}
+
+// MessagingExport is everything a user can see in their threads, for download.
+type MessagingExport struct {
+	UserID     uuid.UUID             `json:"user_id"`
+	ExportedAt time.Time             `json:"exported_at"`
+	Threads    []MessageThreadExport `json:"threads"`
+}
+
+// MessageThreadExport is one thread with all of its messages, oldest first.
+type MessageThreadExport struct {
+	MessageThread
+	Messages []Message `json:"messages"`
+}
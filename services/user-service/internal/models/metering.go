@@ -0,0 +1,73 @@
+// services/user-service/internal/models/metering.go
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Meters. Quantities are summed over a period, so storage_bytes, recorded as one daily snapshot
+// per user, adds up to byte-days.
+const (
+	MeterAPICalls       = "api_calls"       // One per authenticated request; dimension is the route
+	MeterStorageBytes   = "storage_bytes"   // Daily snapshot of the bytes a user's rows take up
+	MeterPremiumFeature = "premium_feature" // One per use; dimension is the feature, e.g. "sso_saml"
+)
+
+// MeteringEvent is one billable usage record. Events are never updated or deleted; an event whose
+// IdempotencyKey was already recorded is a duplicate and is dropped.
+type MeteringEvent struct {
+	ID             uuid.UUID `json:"id"`
+	IdempotencyKey string    `json:"idempotency_key"`
+	UserID         uuid.UUID `json:"user_id"`
+	Meter          string    `json:"meter"`
+	Dimension      string    `json:"dimension"`
+	Quantity       int64     `json:"quantity"`
+	OccurredAt     time.Time `json:"occurred_at"`
+	RecordedAt     time.Time `json:"recorded_at"`
+}
+
+// MeteringFilter selects events by occurrence time, [Since, Until), and optionally one user.
+type MeteringFilter struct {
+	Since  time.Time
+	Until  time.Time
+	UserID uuid.UUID
+}
+
+// MeterTotal aggregates one meter and dimension over a period.
+type MeterTotal struct {
+	Meter     string `json:"meter"`
+	Dimension string `json:"dimension"`
+	Events    int64  `json:"events"`
+	Quantity  int64  `json:"quantity"`
+	Users     int64  `json:"users"`
+}
+
+// UserMeterTotal aggregates one user's usage of a meter over a period.
+type UserMeterTotal struct {
+	UserID   uuid.UUID `json:"user_id"`
+	Meter    string    `json:"meter"`
+	Events   int64     `json:"events"`
+	Quantity int64     `json:"quantity"`
+}
+
+// MeteringPipelineStats counts events handled by this instance since it started. The pipeline is
+// balanced when every emitted event was written, dropped as a duplicate, failed, or is still pending.
+type MeteringPipelineStats struct {
+	Emitted    int64 `json:"emitted"`
+	Written    int64 `json:"written"`
+	Duplicates int64 `json:"duplicates"`
+	Failed     int64 `json:"failed"`
+	Pending    int64 `json:"pending"`
+	Balanced   bool  `json:"balanced"`
+}
+
+// MeteringReport reconciles stored usage for a period with this instance's pipeline.
+type MeteringReport struct {
+	Since    time.Time             `json:"since"`
+	Until    time.Time             `json:"until"`
+	Meters   []MeterTotal          `json:"meters"`
+	Users    []UserMeterTotal      `json:"users"`
+	Pipeline MeteringPipelineStats `json:"pipeline"`
+}
@@ -0,0 +1,118 @@
+// services/user-service/internal/models/onboarding.go
+package models
+
+import "time"
+
+// OnboardingAnswers are the profile answers a client collects during onboarding.
+type OnboardingAnswers struct {
+	Age           int    `json:"age"`
+	ActivityLevel string `json:"activity_level"`
+	Objective     string `json:"objective"`
+}
+
+// RecommendedGoal is a suggested target, e.g. 7000 steps per day.
+type RecommendedGoal struct {
+	Metric string  `json:"metric"`
+	Target float64 `json:"target"`
+	Unit   string  `json:"unit"`
+	Period string  `json:"period"` // day or week
+}
+
+// ReminderDefault is a suggested reminder at a local time of day.
+type ReminderDefault struct {
+	Type string   `json:"type"`
+	Time string   `json:"time"` // HH:MM in the user's timezone
+	Days []string `json:"days"` // mon..sun
+}
+
+// StarterPlan is a suggested first training plan.
+type StarterPlan struct {
+	Name            string   `json:"name"`
+	Weeks           int      `json:"weeks"`
+	SessionsPerWeek int      `json:"sessions_per_week"`
+	SessionMinutes  int      `json:"session_minutes"`
+	Activities      []string `json:"activities"`
+}
+
+// OnboardingRecommendation is the output of the rule matching the answers.
+type OnboardingRecommendation struct {
+	Rule           string            `json:"rule"`
+	RulesetVersion string            `json:"ruleset_version"`
+	Goals          []RecommendedGoal `json:"goals"`
+	Reminders      []ReminderDefault `json:"reminders"`
+	StarterPlan    StarterPlan       `json:"starter_plan"`
+}
+
+// OnboardingRuleset maps onboarding answers to recommendations. It is maintained as data
+// (see config.LoadOnboardingRules); rules are tried in order and the first match wins.
+type OnboardingRuleset struct {
+	Version        string           `json:"version"`
+	ActivityLevels []string         `json:"activity_levels"` // Accepted values of OnboardingAnswers.ActivityLevel
+	Objectives     []string         `json:"objectives"`      // Accepted values of OnboardingAnswers.Objective
+	Rules          []OnboardingRule `json:"rules"`
+}
+
+// OnboardingRule recommends goals, reminders, and a starter plan when its conditions hold.
+type OnboardingRule struct {
+	Name        string              `json:"name"`
+	When        OnboardingCondition `json:"when"`
+	Goals       []RecommendedGoal   `json:"goals"`
+	Reminders   []ReminderDefault   `json:"reminders"`
+	StarterPlan StarterPlan         `json:"starter_plan"`
+}
+
+// OnboardingCondition holds when every set field matches; empty fields match anything.
+type OnboardingCondition struct {
+	MinAge         int      `json:"min_age,omitempty"`
+	MaxAge         int      `json:"max_age,omitempty"`
+	ActivityLevels []string `json:"activity_levels,omitempty"`
+	Objectives     []string `json:"objectives,omitempty"`
+}
+
+// Onboarding steps, in order. Each is reached by completing the one before it; a user can also skip
+// straight to done from any step.
+const (
+	OnboardingRegistered       = "registered"
+	OnboardingProfileCompleted = "profile_completed"
+	OnboardingGoalsSet         = "goals_set"
+	OnboardingDeviceLinked     = "device_linked"
+	OnboardingDone             = "done"
+)
+
+// OnboardingSteps lists the onboarding steps in order.
+var OnboardingSteps = []string{OnboardingRegistered, OnboardingProfileCompleted, OnboardingGoalsSet, OnboardingDeviceLinked, OnboardingDone}
+
+// OnboardingState is where a user is in the first-run flow.
+type OnboardingState struct {
+	Step      string    `json:"step"`
+	Next      string    `json:"next,omitempty"` // The step to post on completing this one; empty once done
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// OnboardingStepRequest is the payload for POST /users/me/onboarding: the step the user has just reached.
+type OnboardingStepRequest struct {
+	Step string `json:"step" validate:"required"`
+}
+
+// OnboardingStepCount is how many users are at an onboarding step, having skipped from SkippedFrom if set.
+type OnboardingStepCount struct {
+	Step        string
+	SkippedFrom string
+	Users       int
+}
+
+// OnboardingFunnelStep counts the users at one onboarding step. Reached includes those who went on
+// to later steps, but not those who skipped past it.
+type OnboardingFunnelStep struct {
+	Step    string `json:"step"`
+	Current int    `json:"current"`
+	Reached int    `json:"reached"`
+}
+
+// OnboardingFunnel is the onboarding drop-off of users who registered since Since.
+type OnboardingFunnel struct {
+	Since   *time.Time             `json:"since,omitempty"`
+	Users   int                    `json:"users"`
+	Skipped int                    `json:"skipped"` // Users who skipped to done
+	Steps   []OnboardingFunnelStep `json:"steps"`
+}
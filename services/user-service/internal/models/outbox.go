@@ -0,0 +1,21 @@
+// services/user-service/internal/models/outbox.go
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OutboxEvent is a domain event waiting to be published to other Pulse services. The repositories
+// write it in the transaction of the change it announces, so it exists exactly when the change does,
+// and it is kept in the outbox until it has been published.
+type OutboxEvent struct {
+	ID         uuid.UUID // The published event's ID, the same on every delivery
+	Type       string    // An eventbus event type
+	UserID     uuid.UUID
+	OccurredAt time.Time
+	Data       map[string]string
+	Attempts   int    // Failed attempts to publish it
+	LastError  string // Of the latest failed attempt
+}
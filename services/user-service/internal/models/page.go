@@ -0,0 +1,16 @@
+// services/user-service/internal/models/page.go
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PageKey is the position of the last item of a page in a list ordered by a timestamp, with the ID
+// ordering items that share a timestamp. Lists resume strictly after it, so items inserted meanwhile
+// neither shift nor repeat the next page.
+type PageKey struct {
+	At time.Time
+	ID uuid.UUID
+}
@@ -0,0 +1,32 @@
+// services/user-service/internal/models/permissions.go
+package models
+
+import "slices"
+
+// Permission scopes carried in access tokens. Pulse services authorize requests by scope,
+// never by role, so new roles only need a new entry in roleScopes.
+const (
+	ScopeProfileRead  = "profile:read"         // Read your own profile
+	ScopeProfileWrite = "profile:write"        // Update your own profile
+	ScopeHealthRead   = "health:read"          // Read your own health data
+	ScopeHealthWrite  = "health:write"         // Record your own health data
+	ScopeUsersRead    = "users:read"           // Read any user
+	ScopeUsersWrite   = "users:write"          // Create, update, and delete any user
+	ScopeAdmin        = "admin"                // Operator endpoints under /admin
+	ScopeAppointments = "appointments:provide" // Publish availability and manage bookings as a provider
+	ScopePlans        = "plans:author"         // Write, export, and import workout plans as a coach
+)
+
+// roleScopes lists the scopes granted to each role.
+var roleScopes = map[string][]string{
+	RoleUser:      {ScopeProfileRead, ScopeProfileWrite, ScopeHealthRead, ScopeHealthWrite},
+	RoleCoach:     {ScopeProfileRead, ScopeProfileWrite, ScopeHealthRead, ScopeHealthWrite, ScopeAppointments, ScopePlans},
+	RoleClinician: {ScopeProfileRead, ScopeProfileWrite, ScopeHealthRead, ScopeHealthWrite, ScopeAppointments},
+	RoleAdmin: {ScopeProfileRead, ScopeProfileWrite, ScopeHealthRead, ScopeHealthWrite,
+		ScopeUsersRead, ScopeUsersWrite, ScopeAdmin},
+}
+
+// ScopesForRole returns the scopes granted to a role. Unknown roles get none.
+func ScopesForRole(role string) []string {
+	return slices.Clone(roleScopes[role])
+}
@@ -0,0 +1,30 @@
+// services/user-service/internal/models/profile_prompt.go
+package models
+
+import "time"
+
+// ProfilePrompt suggests asking the user for an optional profile field they have not filled in.
+type ProfilePrompt struct {
+	Field  string `json:"field"`
+	Reason string `json:"reason"` // Why the field is worth providing, for the client to show
+}
+
+// ProfilePromptFields lists the optional profile fields in the order they are most valuable to ask for.
+var ProfilePromptFields = []ProfilePrompt{
+	{Field: "timezone", Reason: "Reminders and daily totals follow your local day."},
+	{Field: "date_of_birth", Reason: "Goals and heart-rate zones are tuned to your age."},
+	{Field: "height_cm", Reason: "Needed for BMI and calorie estimates."},
+}
+
+// A dismissed prompt returns after ProfilePromptCooldown, until it has been dismissed MaxProfilePromptDismissals times.
+const (
+	ProfilePromptCooldown      = 30 * 24 * time.Hour
+	MaxProfilePromptDismissals = 3
+)
+
+// ProfilePromptDismissal tracks how often, and when last, a user dismissed the prompt for a field.
+type ProfilePromptDismissal struct {
+	Field           string
+	Count           int
+	LastDismissedAt time.Time
+}
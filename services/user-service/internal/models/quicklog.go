@@ -0,0 +1,48 @@
+// services/user-service/internal/models/quicklog.go
+package models
+
+// MaxQuickLogLength is the longest text POST /quicklog accepts, in bytes.
+const MaxQuickLogLength = 1000
+
+// Quick-log entry types.
+const (
+	QuickLogWorkout   = "workout"
+	QuickLogWeight    = "weight"
+	QuickLogSteps     = "steps"
+	QuickLogSleep     = "sleep"
+	QuickLogHydration = "hydration"
+	QuickLogHeartRate = "heart_rate"
+)
+
+// QuickLogRequest is the payload for POST /quicklog: one or more phrases, such as "ran 5k in 28 minutes;
+// weight 82.4", typed or dictated by the user.
+type QuickLogRequest struct {
+	Text string `json:"text" validate:"required"`
+}
+
+// QuickLogEntry is one phrase understood as a structured entry, in canonical units. Workouts have an
+// activity and a distance, a duration, or both; sleep has a duration; the other types have a value in unit.
+type QuickLogEntry struct {
+	Type      string  `json:"type"`
+	Activity  string  `json:"activity,omitempty"`   // Workouts: running, walking, cycling, swimming, hiking, rowing, yoga, strength_training
+	DistanceM float64 `json:"distance_m,omitempty"` // Metres, to 0.1
+	DurationS int     `json:"duration_s,omitempty"`
+	Value     float64 `json:"value,omitempty"`
+	Unit      string  `json:"unit,omitempty"` // kg, steps, ml, or bpm
+	Phrase    string  `json:"phrase"`         // The text this entry was read from
+	Summary   string  `json:"summary"`        // How the entry was understood, for the user to confirm
+}
+
+// QuickLogRejection is a phrase that could not be turned into an entry, and why.
+type QuickLogRejection struct {
+	Phrase string `json:"phrase"`
+	Reason string `json:"reason"`
+}
+
+// QuickLogResult is what POST /quicklog understood. Nothing is logged: the client shows Confirmation, and
+// once the user confirms, logs the entries with the services that store them.
+type QuickLogResult struct {
+	Entries      []QuickLogEntry     `json:"entries"`
+	Rejected     []QuickLogRejection `json:"rejected"`
+	Confirmation string              `json:"confirmation"` // The entries' summaries as one question
+}
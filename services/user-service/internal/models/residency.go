@@ -0,0 +1,17 @@
+// services/user-service/internal/models/residency.go
+package models
+
+import "github.com/google/uuid"
+
+// ChangeRegionRequest moves a user's data to another region's database.
+type ChangeRegionRequest struct {
+	Region string `json:"region" validate:"required"`
+}
+
+// ResidencyViolation is a user whose rows were found in a region other than the one the
+// region directory assigns them to. Expected is empty when the user has no directory entry.
+type ResidencyViolation struct {
+	UserID   uuid.UUID `json:"user_id"`
+	FoundIn  string    `json:"found_in"`
+	Expected string    `json:"expected"`
+}
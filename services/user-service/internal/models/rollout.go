@@ -0,0 +1,14 @@
+// services/user-service/internal/models/rollout.go
+package models
+
+import "github.com/google/uuid"
+
+// RolloutAudience is who a request comes from, as far as rollouts are concerned.
+type RolloutAudience struct {
+	UserID  uuid.UUID
+	Country string // ISO 3166-1 alpha-2, from the edge proxy; empty if unknown
+	Locale  string // BCP 47 tag from X-Locale or Accept-Language; empty if unknown
+}
+
+// UserPlanMetadataKey is the user metadata key holding the user's plan, set by the billing integration.
+const UserPlanMetadataKey = "plan"
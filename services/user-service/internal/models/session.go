@@ -0,0 +1,19 @@
+// services/user-service/internal/models/session.go
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Session is a signed-in device. Every access token carries the ID of its session (the JWT "jti"),
+// and a token is only accepted while its session exists.
+type Session struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	IP        string
+	UserAgent string
+	CreatedAt time.Time
+	ExpiresAt time.Time // Same as the access token's expiry
+}
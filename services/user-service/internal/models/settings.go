@@ -0,0 +1,48 @@
+// services/user-service/internal/models/settings.go
+package models
+
+import "time"
+
+// Setting value types.
+const (
+	SettingBool   = "bool"
+	SettingInt    = "int"
+	SettingString = "string"
+)
+
+// SettingDefinition describes one user setting: its key, the JSON type of its value, and the value
+// users who never set it get.
+type SettingDefinition struct {
+	Key     string
+	Type    string
+	Default interface{}
+	Min     int      // Smallest value of an int setting
+	Max     int      // Largest value of an int setting
+	Values  []string // Allowed values of a string setting
+}
+
+// SettingDefinitions lists the settings users can change at /users/me/settings. Only the values users
+// set are stored; defaults are applied when settings are read, so changing a default here changes it
+// for everyone who never set it. Keys removed from this list are ignored in stored settings.
+var SettingDefinitions = []SettingDefinition{
+	{Key: "notifications.email", Type: SettingBool, Default: true},
+	{Key: "notifications.push", Type: SettingBool, Default: true},
+	{Key: "notifications.weekly_summary", Type: SettingBool, Default: true},
+	{Key: "notifications.appointment_reminders", Type: SettingBool, Default: true},
+	{Key: "notifications.coach_messages", Type: SettingBool, Default: true},
+	{Key: "goals.daily_steps", Type: SettingInt, Default: 10000, Min: 0, Max: 100000},
+	{Key: "goals.weekly_workouts", Type: SettingInt, Default: 3, Min: 0, Max: 21},
+	{Key: "goals.weekly_active_minutes", Type: SettingInt, Default: 150, Min: 0, Max: 10080},
+	{Key: "goals.daily_water_ml", Type: SettingInt, Default: 2000, Min: 0, Max: 10000},
+	{Key: "privacy.profile_visibility", Type: SettingString, Default: "coaches", Values: []string{"private", "coaches"}},
+	{Key: "privacy.share_activity_with_coaches", Type: SettingBool, Default: true},
+	{Key: "privacy.research_opt_in", Type: SettingBool, Default: false},
+}
+
+// UserSettings holds a user's settings. Returned by the API, Settings has every known key, with the
+// user's value or the default; stored, it has only the values the user set.
+type UserSettings struct {
+	Settings   map[string]interface{} `json:"settings"`
+	Customized []string               `json:"customized"`           // Keys the user has set, sorted
+	UpdatedAt  *time.Time             `json:"updated_at,omitempty"` // When settings were last saved
+}
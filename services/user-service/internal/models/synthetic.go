@@ -0,0 +1,31 @@
+// services/user-service/internal/models/synthetic.go
+package models
+
+import "time"
+
+// Outcomes of a synthetic journey step.
+const (
+	SyntheticPass = "pass"
+	SyntheticFail = "fail"
+	SyntheticSkip = "skip" // An earlier step failed
+)
+
+// SyntheticEmailDomain is the reserved domain of the throwaway accounts synthetic journeys create.
+const SyntheticEmailDomain = "synthetic.pulse.invalid"
+
+// SyntheticStep is the result of one step of a synthetic journey.
+type SyntheticStep struct {
+	Name       string `json:"name"`
+	Outcome    string `json:"outcome"`
+	Status     int    `json:"status,omitempty"` // HTTP status of the step's request
+	DurationMs int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+// SyntheticResult is the result of a synthetic journey, for external uptime monitors.
+type SyntheticResult struct {
+	Passed     bool            `json:"passed"`
+	StartedAt  time.Time       `json:"started_at"`
+	DurationMs int64           `json:"duration_ms"`
+	Steps      []SyntheticStep `json:"steps"`
+}
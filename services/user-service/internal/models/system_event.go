@@ -0,0 +1,46 @@
+// services/user-service/internal/models/system_event.go
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// System event types shown on the admin timeline.
+const (
+	SystemEventDeploy       = "deploy"
+	SystemEventMigration    = "migration"
+	SystemEventConfigChange = "config_change"
+	SystemEventMaintenance  = "maintenance"
+)
+
+// SystemEvent is an operational change (deploy, migration, config change, maintenance window)
+// recorded so operators can correlate incidents with what changed.
+type SystemEvent struct {
+	ID        uuid.UUID  `json:"id"`
+	Type      string     `json:"type"`
+	Message   string     `json:"message"`
+	Actor     string     `json:"actor"`             // User ID for API-created events, "system" otherwise
+	StartsAt  time.Time  `json:"starts_at"`         // When the change happened or the window begins
+	EndsAt    *time.Time `json:"ends_at,omitempty"` // Only set for windows such as maintenance
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// CreateSystemEventRequest is the payload for POST /admin/timeline.
+type CreateSystemEventRequest struct {
+	Type     string     `json:"type" validate:"required,oneof=deploy migration config_change maintenance"`
+	Message  string     `json:"message" validate:"required"`
+	StartsAt *time.Time `json:"starts_at,omitempty"` // Defaults to now
+	EndsAt   *time.Time `json:"ends_at,omitempty"`
+}
+
+// SystemEventFilter narrows a timeline query. Zero values mean "no constraint".
+// Pages are fetched latest first (by starts_at, then ID) by passing the key of the last event seen as After.
+type SystemEventFilter struct {
+	Type  string
+	Since time.Time
+	Until time.Time
+	After *PageKey
+	Limit int
+}
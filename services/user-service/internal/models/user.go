@@ -2,24 +2,75 @@
 package models
 
 import (
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
+	"health-tracker-project/services/user-service/internal/utils/measure"
+	"health-tracker-project/services/user-service/internal/utils/password"
+)
+
+// DefaultTimezone is assigned to users who have not chosen one.
+const DefaultTimezone = "UTC"
+
+// DefaultWeekStart is the first day of the week for users who have not chosen one.
+const DefaultWeekStart = "mon"
+
+// DefaultUnits is the unit system for users who have not chosen one.
+const DefaultUnits = measure.Metric
+
+// WeekStarts maps the week_start values to weekdays.
+var WeekStarts = map[string]time.Weekday{
+	"mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday, "thu": time.Thursday,
+	"fri": time.Friday, "sat": time.Saturday, "sun": time.Sunday,
+}
+
+// User roles. Admins can access /admin endpoints; coaches can message users who authorized them.
+// Coaches and clinicians can publish appointment availability.
+const (
+	RoleUser      = "user"
+	RoleAdmin     = "admin"
+	RoleCoach     = "coach"
+	RoleClinician = "clinician"
+)
+
+// Account statuses. Only active accounts can log in or use their tokens.
+const (
+	StatusActive          = "active"
+	StatusSuspended       = "suspended"        // Blocked by an admin
+	StatusDeactivated     = "deactivated"      // Closed by the user; an admin can reactivate it
+	StatusPendingDeletion = "pending_deletion" // Erasure requested by the user; reactivating it before DeletionDueAt cancels it
 )
 
 type User struct {
 	ID           uuid.UUID  `json:"id,omitempty"`
 	Name         string     `json:"name"`
 	Email        string     `json:"email"`
-	PasswordHash string     `json:"-"` // Omit from JSON output for security
-	CreatedAt    time.Time  `json:"created_at,omitempty"`
-	UpdatedAt    time.Time  `json:"updated_at,omitempty"`
+	Username     string     `json:"username,omitempty"` // Optional public handle, lowercased; empty until chosen
+	PasswordHash string     `json:"-"`                  // Omit from JSON output for security
+	Role         string     `json:"role"`
+	Timezone     string     `json:"timezone"`   // IANA name, e.g. "Europe/Berlin"
+	WeekStart    string     `json:"week_start"` // First day of weekly aggregates: mon through sun
+	Units        string     `json:"units"`      // Preferred unit system for display: metric or imperial
+	Status       string     `json:"status"`
+	HeightCM     *float64   `json:"height_cm,omitempty"`     // Optional; nil until the user provides it
+	DateOfBirth  *time.Time `json:"date_of_birth,omitempty"` // Optional; nil until the user provides it
+	Region       string     `json:"region,omitempty"`        // Data residency region; empty when residency is disabled
+	// DeletionDueAt is when a pending_deletion account is erased; nil otherwise.
+	DeletionDueAt *time.Time `json:"deletion_due_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at,omitempty"`
+	UpdatedAt     time.Time  `json:"updated_at,omitempty"`
+	// SessionsRevokedAt invalidates every token issued before it (e.g. after a password reset).
+	SessionsRevokedAt *time.Time `json:"-"`
+	// EmailVerifiedAt is when the user proved they receive mail at Email; nil if never, or since it changed.
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+	// EmailStatus is the deliverability of Email, from bounces and complaints; see EmailOK.
+	EmailStatus string `json:"email_status"`
 }
 
-// NewUser creates a new User instance with a hashed password.
-func NewUser(name, email, password string) (*User, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+// NewUser creates a new User instance with a password hashed by the configured hasher.
+func NewUser(name, email, plaintext string) (*User, error) {
+	hashedPassword, err := password.Hash(plaintext)
 	if err != nil {
 		return nil, err
 	}
@@ -28,51 +79,184 @@ func NewUser(name, email, password string) (*User, error) {
 		ID:           uuid.New(),
 		Name:         name,
 		Email:        email,
-		PasswordHash: string(hashedPassword),
+		PasswordHash: hashedPassword,
+		Role:         RoleUser,
+		Timezone:     DefaultTimezone,
+		WeekStart:    DefaultWeekStart,
+		Units:        DefaultUnits,
+		Status:       StatusActive,
+		EmailStatus:  EmailOK,
 		CreatedAt:    time.Now(),
 		UpdatedAt:    time.Now(),
 	}, nil
 }
 
+// SetPassword hashes and stores a new plaintext password.
+func (u *User) SetPassword(plaintext string) error {
+	hashedPassword, err := password.Hash(plaintext)
+	if err != nil {
+		return err
+	}
+	u.PasswordHash = hashedPassword
+	return nil
+}
+
 // CheckPassword compares a plaintext password with the stored hashed password.
-func (u *User) CheckPassword(password string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password))
-	return err == nil
+func (u *User) CheckPassword(plaintext string) bool {
+	return password.Verify(u.PasswordHash, plaintext)
+}
+
+// PasswordNeedsRehash reports whether the stored hash uses an older algorithm or weaker
+// parameters than the configured hasher, so it should be replaced at the next successful login.
+func (u *User) PasswordNeedsRehash() bool {
+	return password.NeedsRehash(u.PasswordHash)
+}
+
+// dummyPasswordHash is a hash of a throwaway password, computed once on first use.
+var (
+	dummyPasswordHash     string
+	dummyPasswordHashOnce sync.Once
+)
+
+// SimulatePasswordCheck performs a password comparison against a dummy hash from the configured hasher.
+// It is used when no user matches a login attempt so that unknown emails cost
+// the same amount of work as wrong passwords, preventing timing-based enumeration.
+func SimulatePasswordCheck(plaintext string) {
+	dummyPasswordHashOnce.Do(func() {
+		dummyPasswordHash, _ = password.Hash("pulse-timing-dummy-password")
+	})
+	_ = password.Verify(dummyPasswordHash, plaintext)
 }
 
 // UserResponse is a Data Transfer Object (DTO) for sending user data to the client,
 // excluding sensitive information like password hash.
 type UserResponse struct {
-	ID        uuid.UUID `json:"id"`
-	Name      string    `json:"name"`
-	Email     string    `json:"email"`
-	CreatedAt time.Time `json:"created_at"`
+	ID            uuid.UUID         `json:"id"`
+	Name          string            `json:"name"`
+	Email         string            `json:"email"`
+	Username      string            `json:"username,omitempty"`
+	Role          string            `json:"role"`
+	Timezone      string            `json:"timezone"`
+	WeekStart     string            `json:"week_start"`
+	Units         string            `json:"units"`
+	Status        string            `json:"status"`
+	EmailVerified bool              `json:"email_verified"`
+	EmailStatus   string            `json:"email_status"` // ok, soft_bouncing, bounced, or complained; mail is not sent when bounced or complained
+	HeightCM      *float64          `json:"height_cm,omitempty"`
+	HeightInUnits *measure.Quantity `json:"height_in_units,omitempty"` // HeightCM in the user's units, for display
+	DateOfBirth   string            `json:"date_of_birth,omitempty"`   // YYYY-MM-DD
+	Region        string            `json:"region,omitempty"`          // Data residency region; empty when residency is disabled
+	DeletionDueAt *time.Time        `json:"deletion_due_at,omitempty"` // Only for pending_deletion accounts
+	CreatedAt     time.Time         `json:"created_at"`
 }
 
 // ToUserResponse converts a User model to a UserResponse DTO.
 func (u *User) ToUserResponse() UserResponse {
-	return UserResponse{
-		ID:        u.ID,
-		Name:      u.Name,
-		Email:     u.Email,
-		CreatedAt: u.CreatedAt,
+	resp := UserResponse{
+		ID:            u.ID,
+		Name:          u.Name,
+		Email:         u.Email,
+		Username:      u.Username,
+		Role:          u.Role,
+		Timezone:      u.Timezone,
+		WeekStart:     u.WeekStart,
+		Units:         u.Units,
+		Status:        u.Status,
+		EmailVerified: u.EmailVerifiedAt != nil,
+		EmailStatus:   u.EmailStatus,
+		HeightCM:      u.HeightCM,
+		Region:        u.Region,
+		CreatedAt:     u.CreatedAt,
+		DeletionDueAt: u.DeletionDueAt,
 	}
-}
-
-// ErrorResponse for API error messages
-type ErrorResponse struct {
-	Message string `json:"message"`
-	Code    int    `json:"code"`
+	if u.HeightCM != nil {
+		height := measure.Height(*u.HeightCM, u.Units)
+		resp.HeightInUnits = &height
+	}
+	if u.DateOfBirth != nil {
+		resp.DateOfBirth = u.DateOfBirth.Format(time.DateOnly)
+	}
+	return resp
 }
 
 type CreateUserRequest struct {
-	Name     string `json:"name"`
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Name     string `json:"name" validate:"required"`
+	Email    string `json:"email" validate:"required,email"`
+	Username string `json:"username,omitempty"` // Optional
+	Password string `json:"password" validate:"required,min=8"`
 }
 
 type UpdateUserRequest struct {
-	Name     string  `json:"name"`
-	Email    string  `json:"email"`
-	Password *string `json:"password,omitempty"` // Password is a pointer for optionality
-}
\ No newline at end of file
+	Name        string   `json:"name"`
+	Email       string   `json:"email" validate:"email"`
+	Username    *string  `json:"username,omitempty"`                  // An empty string removes the username
+	Password    *string  `json:"password,omitempty" validate:"min=8"` // Password is a pointer for optionality
+	Timezone    *string  `json:"timezone,omitempty"`                  // IANA name; changes are recorded in the timezone history
+	WeekStart   *string  `json:"week_start,omitempty"`
+	Units       *string  `json:"units,omitempty" validate:"oneof=metric imperial"` // metric or imperial
+	HeightCM    *float64 `json:"height_cm,omitempty"`
+	Height      *string  `json:"height,omitempty"`        // Human-style alternative to height_cm, e.g. 5'11" or 1,80 m
+	DateOfBirth *string  `json:"date_of_birth,omitempty"` // YYYY-MM-DD
+}
+
+// TimezonePeriod is one entry of a user's timezone history: Timezone applied from EffectiveFrom
+// until the next entry. Aggregations use it to bucket each sample in the zone in effect at the time.
+type TimezonePeriod struct {
+	Timezone      string    `json:"timezone"`
+	EffectiveFrom time.Time `json:"effective_from"`
+}
+
+// TimezoneAt resolves the zone in effect at instant at from a history sorted oldest first.
+// Instants before the first entry use the first entry's zone.
+func TimezoneAt(history []TimezonePeriod, at time.Time) (*time.Location, error) {
+	name := DefaultTimezone
+	for i, p := range history {
+		if i == 0 || !p.EffectiveFrom.After(at) {
+			name = p.Timezone
+		} else {
+			break
+		}
+	}
+	return time.LoadLocation(name)
+}
+
+// ActiveUserWindow is how recently a user must have signed in to count as active.
+const ActiveUserWindow = 30 * 24 * time.Hour
+
+// UserFilter narrows an admin user listing. Zero values mean "no constraint".
+// Pages are fetched newest first (by created_at, then ID) by passing the key of the last user seen as After.
+type UserFilter struct {
+	Role         string
+	Status       string
+	Verified     *bool     // Whether the email is verified
+	CreatedSince time.Time // Signed up at or after
+	CreatedUntil time.Time // Signed up before
+	After        *PageKey
+	Limit        int
+}
+
+// UserMatch is a user found by a search, with how closely it matched: the share of the query's
+// trigrams found in the user's name, username, or email, from 0 to 1.
+type UserMatch struct {
+	User  User
+	Score float64
+}
+
+// UserSearchResult is a user returned by GET /users/search.
+type UserSearchResult struct {
+	User  UserResponse `json:"user"`
+	Score float64      `json:"score"`
+}
+
+// UserCounts aggregates every user matching a UserFilter, regardless of paging.
+type UserCounts struct {
+	Total            int `json:"total"`
+	ActiveLast30Days int `json:"active_last_30_days"` // Signed in successfully within ActiveUserWindow
+	Unverified       int `json:"unverified"`
+}
+
+// UserList is a page of users with the counts of all users matching the same filter.
+type UserList struct {
+	Users  []UserResponse `json:"users"`
+	Counts UserCounts     `json:"counts"`
+}
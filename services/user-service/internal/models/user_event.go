@@ -0,0 +1,59 @@
+// services/user-service/internal/models/user_event.go
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// User event types shown on a user's own timeline.
+const (
+	UserEventRegistered             = "registered"
+	UserEventPasswordChanged        = "password_changed"
+	UserEventProfileUpdated         = "profile_updated"
+	UserEventTimezoneChanged        = "timezone_changed"
+	UserEventStatusChanged          = "status_changed"
+	UserEventAccountMerged          = "account_merged"
+	UserEventIdentityLinked         = "identity_linked"
+	UserEventRegionChanged          = "region_changed"
+	UserEventConsentGranted         = "integration_consent_granted"
+	UserEventConsentRevoked         = "integration_consent_revoked"
+	UserEventCoachAuthorized        = "coach_authorized"
+	UserEventCoachRevoked           = "coach_revoked"
+	UserEventAppointmentBooked      = "appointment_booked"
+	UserEventAppointmentCancelled   = "appointment_cancelled"
+	UserEventAppointmentRescheduled = "appointment_rescheduled"
+	UserEventOnboardingAdvanced     = "onboarding_advanced"
+	UserEventEmailUndeliverable     = "email_undeliverable" // A bounce or complaint about the user's email
+	UserEventEmailReverified        = "email_reverified"
+)
+
+// User event types that are only streamed to the user's open connections (GET /ws), not kept on
+// their timeline. Both carry the session in details.session_id; a session_revoked event without it
+// ends every session of the user, as a password reset does.
+const (
+	UserEventSessionStarted = "session_started" // A sign-in, on a new device or again on a known one
+	UserEventSessionRevoked = "session_revoked" // A sign-out, or an eviction by the session limit
+)
+
+// UserEvent is a domain event in a user's account history, e.g. registration or a timezone change.
+type UserEvent struct {
+	ID         uuid.UUID         `json:"id"`
+	UserID     uuid.UUID         `json:"-"`
+	Type       string            `json:"type"`
+	Summary    string            `json:"summary"`
+	Details    map[string]string `json:"details,omitempty"`
+	OccurredAt time.Time         `json:"occurred_at"`
+}
+
+// UserEventFilter narrows a user timeline query. Zero values mean "no constraint".
+// Pages are fetched newest first (by occurred_at, then ID) by passing the key of the last event seen as After.
+// Since instead fetches the events newer than its key, oldest first, to catch up a stream that was cut off.
+type UserEventFilter struct {
+	UserID uuid.UUID
+	Types  []string
+	After  *PageKey
+	Since  *PageKey
+	Limit  int
+}
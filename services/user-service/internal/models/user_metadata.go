@@ -0,0 +1,14 @@
+// services/user-service/internal/models/user_metadata.go
+package models
+
+import "encoding/json"
+
+// UserMetadata holds custom attributes integrators attach to a user, such as an employee or clinic ID.
+// Keys are theirs to choose; values are any JSON and are kept exactly as sent.
+type UserMetadata map[string]json.RawMessage
+
+// Limits on a user's metadata. The size is that of the stored JSON text, after merging an update.
+const (
+	MaxUserMetadataBytes     = 16 * 1024
+	MaxUserMetadataKeyLength = 64
+)
@@ -0,0 +1,75 @@
+// services/user-service/internal/models/username.go
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Usernames are optional public handles. They are stored lowercased, so lookups and uniqueness
+// ignore case, and can never contain "@", so a login identifier is unambiguously one or the other.
+const (
+	MinUsernameLength = 3
+	MaxUsernameLength = 30
+)
+
+// ReservedUsernames cannot be taken: they name routes, roles, or could pass for staff.
+var ReservedUsernames = map[string]bool{
+	"abuse": true, "admin": true, "administrator": true, "anonymous": true, "api": true, "auth": true,
+	"billing": true, "clinician": true, "coach": true, "developer": true, "help": true, "login": true,
+	"logout": true, "me": true, "moderator": true, "noreply": true, "null": true, "postmaster": true,
+	"public": true, "pulse": true, "register": true, "root": true, "security": true, "settings": true,
+	"staff": true, "support": true, "system": true, "undefined": true, "user": true, "users": true,
+	"www": true,
+}
+
+// LooksReserved reports whether a handle passes for a reserved one once its dots, underscores, and
+// trailing digits are dropped, like "ad.min", "support_", or "pulse1". It is checked on handles being
+// taken, not on lookups, so that handles taken before the check existed can still be found.
+func LooksReserved(username string) bool {
+	skeleton := strings.TrimRight(strings.NewReplacer(".", "", "_", "").Replace(username), "0123456789")
+	return ReservedUsernames[skeleton]
+}
+
+// ErrReservedUsername is the error of NormalizeUsername for a reserved handle, after the handle.
+var ErrReservedUsername = errors.New("is reserved")
+
+// NormalizeUsername lowercases a handle and checks its format: 3 to 30 letters, digits, underscores, and
+// dots, starting with a letter, with no trailing or doubled dots. Reserved handles are rejected.
+func NormalizeUsername(handle string) (string, error) {
+	username := strings.ToLower(strings.TrimSpace(handle))
+	if len(username) < MinUsernameLength || len(username) > MaxUsernameLength {
+		return "", fmt.Errorf("must be %d to %d characters", MinUsernameLength, MaxUsernameLength)
+	}
+	if username[0] < 'a' || username[0] > 'z' {
+		return "", fmt.Errorf("must start with a letter")
+	}
+	for _, c := range username {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '_' || c == '.') {
+			return "", fmt.Errorf("may only contain letters, digits, underscores, and dots")
+		}
+	}
+	if strings.HasSuffix(username, ".") || strings.Contains(username, "..") {
+		return "", fmt.Errorf("cannot end with a dot or contain consecutive dots")
+	}
+	if ReservedUsernames[username] {
+		return "", fmt.Errorf("%q %w", username, ErrReservedUsername)
+	}
+	return username, nil
+}
+
+// Reasons a handle is not available.
+const (
+	HandleInvalid     = "invalid"     // Malformed; Message says why
+	HandleUnavailable = "unavailable" // Taken or reserved, which are not told apart
+)
+
+// HandleAvailability is the response of GET /handles/availability.
+type HandleAvailability struct {
+	Name        string   `json:"name"` // Normalized, as it would be stored; the request's name if invalid
+	Available   bool     `json:"available"`
+	Reason      string   `json:"reason,omitempty"`
+	Message     string   `json:"message,omitempty"`
+	Suggestions []string `json:"suggestions"` // Available handles close to the name; empty when it is available
+}
@@ -0,0 +1,302 @@
+// services/user-service/internal/models/validate.go
+package models
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// Validate checks a request decoded from JSON against the validate tags of its fields, and returns one
+// detail per field that fails, named by its JSON path (such as segment.org). v is a pointer to a struct.
+// The rules of a tag are separated by commas:
+//
+//	required   the field must be sent: not empty, blank, zero, or nil
+//	email      an email address NormalizeEmail accepts
+//	uuid       a string holding a UUID
+//	min=N      a string of at least N characters, a number of at least N, or a list of at least N items
+//	max=N      likewise, at most N
+//	oneof=a b  one of the listed words
+//
+// Rules other than required skip fields left empty, so optional fields need only be valid when sent; a
+// pointer field that is set is checked even if it points to an empty value. Structs, pointers to them,
+// and lists of them are checked field by field. These checks are the handlers' first line; services
+// still validate what they are given, as not every caller goes through a handler.
+//
+// The tags of a type are parsed on its first use and kept. A tag with an unknown rule, or a rule that
+// does not apply to its field's type, is reported as the error, with no details; CheckValidateTags
+// finds those before any request does.
+func Validate(v any) ([]ErrorDetail, error) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	rules, err := structRulesOf(rv.Type())
+	if err != nil {
+		return nil, err
+	}
+	var problems []ErrorDetail
+	validateStruct(rv, rules, "", &problems)
+	return problems, nil
+}
+
+// CheckValidateTags parses the validate tags of the given structs, or pointers to them, and of the
+// structs they hold, and returns the first that is malformed.
+func CheckValidateTags(values ...any) error {
+	for _, v := range values {
+		rt := reflect.TypeOf(v)
+		if rt.Kind() == reflect.Pointer {
+			rt = rt.Elem()
+		}
+		if _, err := structRulesOf(rt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rule is one parsed rule of a validate tag.
+type rule struct {
+	name    string
+	arg     string
+	limit   float64  // min and max
+	options []string // oneof
+}
+
+// fieldRules are the parsed validate tag of a struct field, along with the rules of the struct or list
+// of structs it holds, if any.
+type fieldRules struct {
+	index  int
+	name   string // JSON name
+	rules  []rule
+	nested *structRules
+}
+
+// structRules are the parsed validate tags of a struct type.
+type structRules struct {
+	fields []fieldRules
+}
+
+// parsedRules caches the structRules of each struct type Validate has seen.
+var parsedRules sync.Map // reflect.Type → *structRules
+
+// structRulesOf returns the parsed validate tags of rt, a struct type.
+func structRulesOf(rt reflect.Type) (*structRules, error) {
+	if rules, ok := parsedRules.Load(rt); ok {
+		return rules.(*structRules), nil
+	}
+	rules, err := parseStructRules(rt, map[reflect.Type]*structRules{})
+	if err != nil {
+		return nil, err
+	}
+	parsedRules.Store(rt, rules)
+	return rules, nil
+}
+
+// parseStructRules parses the validate tags of rt and the structs it holds. parsing holds the types
+// being parsed further up, so that a type holding itself is parsed once.
+func parseStructRules(rt reflect.Type, parsing map[reflect.Type]*structRules) (*structRules, error) {
+	if rt.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("models: cannot validate %s, only structs", rt)
+	}
+	if rules, ok := parsing[rt]; ok {
+		return rules, nil
+	}
+	rules := &structRules{}
+	parsing[rt] = rules
+	for i := range rt.NumField() {
+		sf := rt.Field(i)
+		name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if !sf.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		field := fieldRules{index: i, name: name}
+		ft := sf.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		for _, text := range strings.Split(sf.Tag.Get("validate"), ",") {
+			if text == "" {
+				continue
+			}
+			r, err := parseRule(text, ft)
+			if err != nil {
+				return nil, fmt.Errorf("models: field %s of %s: %w", sf.Name, rt, err)
+			}
+			field.rules = append(field.rules, r)
+		}
+		if nested := nestedStruct(ft); nested != nil {
+			var err error
+			if field.nested, err = parseStructRules(nested, parsing); err != nil {
+				return nil, err
+			}
+		}
+		if len(field.rules) > 0 || field.nested != nil {
+			rules.fields = append(rules.fields, field)
+		}
+	}
+	return rules, nil
+}
+
+// nestedStruct returns the struct type whose fields are checked within a field of type ft: ft itself,
+// or the element of a list, either possibly behind a pointer. It is nil for other types and for times.
+func nestedStruct(ft reflect.Type) reflect.Type {
+	if ft.Kind() == reflect.Slice {
+		ft = ft.Elem()
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+	}
+	if ft.Kind() != reflect.Struct || ft == reflect.TypeFor[time.Time]() {
+		return nil
+	}
+	return ft
+}
+
+// parseRule parses one rule of a validate tag on a field of type ft, pointers followed.
+func parseRule(text string, ft reflect.Type) (rule, error) {
+	name, arg, _ := strings.Cut(text, "=")
+	r := rule{name: name, arg: arg}
+	switch name {
+	case "required":
+		return r, nil
+	case "email", "uuid", "oneof":
+		if ft.Kind() != reflect.String {
+			return r, fmt.Errorf("validate rule %q does not apply to %s", text, ft)
+		}
+		if r.options = strings.Fields(arg); name == "oneof" && len(r.options) == 0 {
+			return r, fmt.Errorf("validate rule %q lists no words", text)
+		}
+		return r, nil
+	case "min", "max":
+		limit, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return r, fmt.Errorf("validate rule %q needs a number", text)
+		}
+		r.limit = limit
+		if sizeOf(reflect.Zero(ft)) == nil {
+			return r, fmt.Errorf("validate rule %q does not apply to %s", text, ft)
+		}
+		return r, nil
+	}
+	return r, fmt.Errorf("unknown validate rule %q", text)
+}
+
+func validateStruct(rv reflect.Value, rules *structRules, prefix string, problems *[]ErrorDetail) {
+	for _, field := range rules.fields {
+		validateField(rv.Field(field.index), prefix+field.name, field, problems)
+	}
+}
+
+func validateField(fv reflect.Value, path string, field fieldRules, problems *[]ErrorDetail) {
+	set := !fv.IsZero()
+	if fv.Kind() == reflect.Pointer {
+		if fv.IsNil() {
+			set = false
+		} else {
+			fv = fv.Elem()
+		}
+	}
+
+	for _, r := range field.rules {
+		if r.name == "required" {
+			if !set || isBlank(fv) {
+				*problems = append(*problems, ErrorDetail{Field: path, Message: "is required"})
+				return
+			}
+			continue
+		}
+		if !set {
+			return
+		}
+		if msg := checkRule(fv, r); msg != "" {
+			*problems = append(*problems, ErrorDetail{Field: path, Message: msg})
+			return
+		}
+	}
+
+	if !set || field.nested == nil {
+		return
+	}
+	switch fv.Kind() {
+	case reflect.Struct:
+		validateStruct(fv, field.nested, path+".", problems)
+	case reflect.Slice:
+		// An item of a list was sent even if it is empty, so its own required fields are checked.
+		for i := range fv.Len() {
+			if item := reflect.Indirect(fv.Index(i)); item.IsValid() {
+				validateStruct(item, field.nested, fmt.Sprintf("%s[%d].", path, i), problems)
+			}
+		}
+	}
+}
+
+// isBlank reports whether a value of a required field counts as not sent.
+func isBlank(fv reflect.Value) bool {
+	switch fv.Kind() {
+	case reflect.String:
+		return strings.TrimSpace(fv.String()) == ""
+	case reflect.Slice, reflect.Map:
+		return fv.Len() == 0
+	}
+	return fv.IsZero()
+}
+
+// ruleSize is the size min and max compare with, and the unit it is counted in.
+type ruleSize struct {
+	n    float64
+	unit string
+}
+
+// sizeOf returns what min and max compare for a value: the characters of a string, the items of a list
+// or map, or a number itself. It is nil for other kinds, which min and max do not apply to.
+func sizeOf(fv reflect.Value) *ruleSize {
+	switch fv.Kind() {
+	case reflect.String:
+		return &ruleSize{float64(utf8.RuneCountInString(fv.String())), " characters"}
+	case reflect.Slice, reflect.Map:
+		return &ruleSize{float64(fv.Len()), " items"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &ruleSize{n: float64(fv.Int())}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &ruleSize{n: float64(fv.Uint())}
+	case reflect.Float32, reflect.Float64:
+		return &ruleSize{n: fv.Float()}
+	}
+	return nil
+}
+
+// checkRule returns why a sent value breaks r, or "" if it does not. The rule was checked to apply to
+// the value's type when it was parsed.
+func checkRule(fv reflect.Value, r rule) string {
+	switch r.name {
+	case "email":
+		if _, err := NormalizeEmail(fv.String()); err != nil {
+			return err.Error()
+		}
+	case "uuid":
+		if _, err := uuid.Parse(fv.String()); err != nil {
+			return "must be a UUID"
+		}
+	case "oneof":
+		if !slices.Contains(r.options, fv.String()) {
+			return "must be one of " + strings.Join(r.options, ", ")
+		}
+	case "min", "max":
+		size := sizeOf(fv)
+		if r.name == "min" && size.n < r.limit {
+			return "must be at least " + r.arg + size.unit
+		}
+		if r.name == "max" && size.n > r.limit {
+			return "must be at most " + r.arg + size.unit
+		}
+	}
+	return ""
+}
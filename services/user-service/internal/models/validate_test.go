@@ -0,0 +1,220 @@
+// services/user-service/internal/models/validate_test.go
+package models
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// validatedRequests holds one of each request type with validate tags, so their tags are checked here
+// rather than by the first request that uses them.
+var validatedRequests = []any{
+	AggregationPeriodRequest{},
+	CreateAnnouncementRequest{},
+	BookAppointmentRequest{},
+	PublishAvailabilityRequest{},
+	RescheduleAppointmentRequest{},
+	ForgotPasswordRequest{},
+	LoginRequest{},
+	RegisterRequest{},
+	ResetPasswordRequest{},
+	BulkUserOperation{},
+	BulkUserRequest{},
+	GrantConsentRequest{},
+	CreateDeveloperAppRequest{},
+	ConfirmEmailRequest{},
+	LinkIdentityRequest{},
+	MergeUsersRequest{},
+	CreateThreadRequest{},
+	OnboardingStepRequest{},
+	QuickLogRequest{},
+	ChangeRegionRequest{},
+	CreateSystemEventRequest{},
+	CreateUserRequest{},
+	UpdateUserRequest{},
+	UpdateWorkoutAttachmentRequest{},
+	CreateWorkoutPlanRequest{},
+	ExportedWorkoutPlan{},
+	PlanAuthor{},
+	WorkoutPlanDocument{},
+	WorkoutPlanExercise{},
+	WorkoutPlanSession{},
+}
+
+func TestValidateTags(t *testing.T) {
+	if err := CheckValidateTags(validatedRequests...); err != nil {
+		t.Fatal(err)
+	}
+
+	listed := map[string]bool{}
+	for _, v := range validatedRequests {
+		listed[reflect.TypeOf(v).Name()] = true
+	}
+	for _, name := range taggedStructs(t) {
+		if !listed[name] {
+			t.Errorf("%s has validate tags but is not in validatedRequests", name)
+		}
+	}
+}
+
+// taggedStructs returns the names of the struct types of this package with a validate tag.
+func taggedStructs(t *testing.T) []string {
+	t.Helper()
+	pkgs, err := parser.ParseDir(token.NewFileSet(), ".", func(info fs.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, pkg := range pkgs {
+		ast.Inspect(pkg, func(n ast.Node) bool {
+			spec, ok := n.(*ast.TypeSpec)
+			if !ok {
+				return true
+			}
+			st, ok := spec.Type.(*ast.StructType)
+			if !ok {
+				return false
+			}
+			for _, field := range st.Fields.List {
+				if field.Tag != nil && strings.Contains(field.Tag.Value, `validate:"`) {
+					names = append(names, spec.Name.Name)
+					break
+				}
+			}
+			return false
+		})
+	}
+	return names
+}
+
+func TestCheckValidateTagsRejects(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+		want  string
+	}{
+		{"unknown rule", struct {
+			Name string `json:"name" validate:"requird"`
+		}{}, `unknown validate rule "requird"`},
+		{"email on a number", struct {
+			Age int `json:"age" validate:"email"`
+		}{}, `validate rule "email" does not apply to int`},
+		{"oneof without words", struct {
+			Kind string `json:"kind" validate:"oneof="`
+		}{}, `validate rule "oneof=" lists no words`},
+		{"min without a number", struct {
+			Name string `json:"name" validate:"min=three"`
+		}{}, `validate rule "min=three" needs a number`},
+		{"max on a bool", struct {
+			On bool `json:"on" validate:"max=1"`
+		}{}, `validate rule "max=1" does not apply to bool`},
+		{"nested struct", struct {
+			Items []struct {
+				Name string `json:"name" validate:"uuid,bogus"`
+			} `json:"items"`
+		}{}, `unknown validate rule "bogus"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckValidateTags(tt.value)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("CheckValidateTags() = %v, want an error containing %q", err, tt.want)
+			}
+			if _, err := Validate(tt.value); err == nil {
+				t.Fatal("Validate() returned no error for a malformed tag")
+			}
+		})
+	}
+}
+
+// ruleRequest exercises every rule of a validate tag, on the field types requests use.
+type ruleRequest struct {
+	Name     string         `json:"name" validate:"required,min=2,max=5"`
+	Email    string         `json:"email" validate:"email"`
+	ID       string         `json:"id" validate:"uuid"`
+	Kind     string         `json:"kind" validate:"oneof=run swim"`
+	Count    int            `json:"count" validate:"min=1,max=10"`
+	Weight   float64        `json:"weight" validate:"max=99.5"`
+	Tags     []string       `json:"tags" validate:"max=2"`
+	Note     *string        `json:"note" validate:"required,max=3"`
+	Limit    *int           `json:"limit" validate:"min=5"`
+	Owner    ruleOwner      `json:"owner"`
+	Members  []ruleOwner    `json:"members" validate:"max=3"`
+	Extra    *ruleOwner     `json:"extra"`
+	Pointers []*ruleOwner   `json:"pointers"`
+	Skipped  string         `json:"-" validate:"required"`
+	Labels   map[string]int `json:"labels" validate:"min=1"`
+}
+
+type ruleOwner struct {
+	Handle string `json:"handle" validate:"required"`
+	Role   string `json:"role"`
+}
+
+func TestValidateRules(t *testing.T) {
+	note, empty, zero, four := "ok", "", 0, 4
+	valid := func() ruleRequest {
+		return ruleRequest{Name: "Ann", Note: &note, Owner: ruleOwner{Handle: "ann"}}
+	}
+	tests := []struct {
+		name   string
+		modify func(r *ruleRequest)
+		want   []ErrorDetail
+	}{
+		{"valid", func(r *ruleRequest) {}, nil},
+		{"optional fields sent and valid", func(r *ruleRequest) {
+			r.Email, r.ID, r.Kind, r.Count, r.Weight = "Ann@Example.com", "6f1c2d5e-8a4b-4c3d-9e2f-1a2b3c4d5e6f", "swim", 10, 99.5
+			r.Tags, r.Labels, r.Members = []string{"a", "b"}, map[string]int{"a": 1}, []ruleOwner{{Handle: "bo"}}
+		}, nil},
+		{"required missing", func(r *ruleRequest) { r.Name = "" }, []ErrorDetail{{"name", "is required"}}},
+		{"required blank", func(r *ruleRequest) { r.Name = "   " }, []ErrorDetail{{"name", "is required"}}},
+		{"required pointer nil", func(r *ruleRequest) { r.Note = nil }, []ErrorDetail{{"note", "is required"}}},
+		{"required pointer to empty", func(r *ruleRequest) { r.Note = &empty }, []ErrorDetail{{"note", "is required"}}},
+		{"min characters", func(r *ruleRequest) { r.Name = "A" }, []ErrorDetail{{"name", "must be at least 2 characters"}}},
+		{"max counts characters, not bytes", func(r *ruleRequest) { r.Name = "Zoë Ö" }, nil},
+		{"max characters", func(r *ruleRequest) { r.Name = "Annabel" }, []ErrorDetail{{"name", "must be at most 5 characters"}}},
+		{"email", func(r *ruleRequest) { r.Email = "ann.example.com" }, []ErrorDetail{{"email", "must be of the form name@domain"}}},
+		{"uuid", func(r *ruleRequest) { r.ID = "not-a-uuid" }, []ErrorDetail{{"id", "must be a UUID"}}},
+		{"oneof", func(r *ruleRequest) { r.Kind = "Run" }, []ErrorDetail{{"kind", "must be one of run, swim"}}},
+		{"min number", func(r *ruleRequest) { r.Count = -1 }, []ErrorDetail{{"count", "must be at least 1"}}},
+		{"max number", func(r *ruleRequest) { r.Count = 11 }, []ErrorDetail{{"count", "must be at most 10"}}},
+		{"max float", func(r *ruleRequest) { r.Weight = 99.6 }, []ErrorDetail{{"weight", "must be at most 99.5"}}},
+		{"max items", func(r *ruleRequest) { r.Tags = []string{"a", "b", "c"} }, []ErrorDetail{{"tags", "must be at most 2 items"}}},
+		{"empty map is sent", func(r *ruleRequest) { r.Labels = map[string]int{} }, []ErrorDetail{{"labels", "must be at least 1 items"}}},
+		{"max pointer target", func(r *ruleRequest) { long := "long"; r.Note = &long }, []ErrorDetail{{"note", "must be at most 3 characters"}}},
+		{"pointer to zero is checked", func(r *ruleRequest) { r.Limit = &zero }, []ErrorDetail{{"limit", "must be at least 5"}}},
+		{"pointer min", func(r *ruleRequest) { r.Limit = &four }, []ErrorDetail{{"limit", "must be at least 5"}}},
+		{"nested struct", func(r *ruleRequest) { r.Owner = ruleOwner{Role: "admin"} }, []ErrorDetail{{"owner.handle", "is required"}}},
+		{"nested struct left empty", func(r *ruleRequest) { r.Owner = ruleOwner{} }, nil},
+		{"list of structs", func(r *ruleRequest) { r.Members = []ruleOwner{{Handle: "a"}, {Role: "admin"}, {}} }, []ErrorDetail{
+			{"members[1].handle", "is required"}, {"members[2].handle", "is required"},
+		}},
+		{"list of pointers", func(r *ruleRequest) { r.Pointers = []*ruleOwner{nil, {}} }, []ErrorDetail{{"pointers[1].handle", "is required"}}},
+		{"list too long skips its items", func(r *ruleRequest) { r.Members = make([]ruleOwner, 4) }, []ErrorDetail{{"members", "must be at most 3 items"}}},
+		{"nil pointer to struct", func(r *ruleRequest) { r.Extra = nil }, nil},
+		{"pointer to struct", func(r *ruleRequest) { r.Extra = &ruleOwner{} }, []ErrorDetail{{"extra.handle", "is required"}}},
+		{"every field reported in order", func(r *ruleRequest) { r.Name, r.ID, r.Count = "", "x", 20 }, []ErrorDetail{
+			{"name", "is required"}, {"id", "must be a UUID"}, {"count", "must be at most 10"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := valid()
+			tt.modify(&req)
+			got, err := Validate(&req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
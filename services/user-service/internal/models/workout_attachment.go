@@ -0,0 +1,50 @@
+// services/user-service/internal/models/workout_attachment.go
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Virus scan statuses of a workout attachment. Only clean (or, without a scanner, not_scanned)
+// attachments can be downloaded.
+const (
+	ScanPending    = "pending"
+	ScanClean      = "clean"
+	ScanInfected   = "infected"
+	ScanNotScanned = "not_scanned" // No scanner is configured
+)
+
+// WorkoutAttachment is a form-check video or file attached to a workout set. Workout sets live in
+// the workout service; SetRef is that service's ID for the set, which this service does not check.
+type WorkoutAttachment struct {
+	ID                uuid.UUID  `json:"id"`
+	UserID            uuid.UUID  `json:"user_id"`
+	SetRef            string     `json:"set_ref"`
+	Filename          string     `json:"filename"`
+	ContentType       string     `json:"content_type"`
+	Size              int64      `json:"size"`
+	ScanStatus        string     `json:"scan_status"`
+	ScannedAt         *time.Time `json:"scanned_at,omitempty"`
+	SharedWithCoaches bool       `json:"shared_with_coaches"` // Visible to the user's authorized coaches
+	ExpiresAt         *time.Time `json:"expires_at,omitempty"`
+	BlobKey           string     `json:"-"`
+	CreatedAt         time.Time  `json:"created_at"`
+}
+
+// UploadWorkoutAttachmentRequest holds the upload options passed as query parameters.
+// ExpiresInDays of nil uses the configured default; 0 keeps the attachment.
+type UploadWorkoutAttachmentRequest struct {
+	SetRef            string
+	Filename          string
+	SharedWithCoaches bool
+	ExpiresInDays     *int
+}
+
+// UpdateWorkoutAttachmentRequest changes the sharing flag or expiry. ExpiresInDays counts from now; 0 keeps
+// the attachment. Nil fields are left unchanged.
+type UpdateWorkoutAttachmentRequest struct {
+	SharedWithCoaches *bool `json:"shared_with_coaches"`
+	ExpiresInDays     *int  `json:"expires_in_days" validate:"min=0"`
+}
@@ -0,0 +1,134 @@
+// services/user-service/internal/models/workout_plan.go
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Format and version of a workout plan document, the JSON coaches export plans as and import them
+// from. A document of another format or a newer version is rejected, not guessed at.
+const (
+	WorkoutPlanFormat  = "pulse.workout-plans"
+	WorkoutPlanVersion = 1
+)
+
+// PlanPlatformPulse is the platform recorded for the authors of plans written in Pulse.
+const PlanPlatformPulse = "pulse"
+
+// Outcomes of importing a plan. A plan conflicts with a plan of the coach imported from the same
+// source, or exported from it (same_source), or else with one of the same name (name_taken).
+const (
+	PlanImportCreate  = "create"
+	PlanImportReplace = "replace"
+	PlanImportSkip    = "skip"
+
+	PlanConflictSameSource = "same_source"
+	PlanConflictNameTaken  = "name_taken"
+)
+
+// WorkoutPlan is a multi-week training plan a coach writes, or imports from another coach. Author is
+// who wrote it, kept through exports and imports; SourceID is the plan's ID where it was written.
+type WorkoutPlan struct {
+	ID          uuid.UUID            `json:"id"`
+	CoachID     uuid.UUID            `json:"coach_id"`
+	Name        string               `json:"name"`
+	Description string               `json:"description,omitempty"`
+	Weeks       int                  `json:"weeks"`
+	Sessions    []WorkoutPlanSession `json:"sessions"`
+	Author      PlanAuthor           `json:"author"`
+	SourceID    *uuid.UUID           `json:"source_id,omitempty"`   // Set on imported plans
+	ImportedAt  *time.Time           `json:"imported_at,omitempty"` // Set on imported plans
+	CreatedAt   time.Time            `json:"created_at"`
+	UpdatedAt   time.Time            `json:"updated_at"`
+}
+
+// OriginID is the ID a plan is exported under, and recognized by when imported again: where it was
+// written, for imported plans, or its own ID.
+func (p *WorkoutPlan) OriginID() uuid.UUID {
+	if p.SourceID != nil {
+		return *p.SourceID
+	}
+	return p.ID
+}
+
+// WorkoutPlanSession is the training of one day of a plan.
+type WorkoutPlanSession struct {
+	Week      int                   `json:"week" validate:"required,min=1,max=52"`
+	Day       int                   `json:"day" validate:"required,min=1,max=7"` // 1 is the first day of the week
+	Name      string                `json:"name,omitempty" validate:"max=100"`
+	Exercises []WorkoutPlanExercise `json:"exercises" validate:"required,max=30"`
+}
+
+// WorkoutPlanExercise is one exercise of a session, with either Reps or DurationSeconds per set.
+type WorkoutPlanExercise struct {
+	Name            string `json:"name" validate:"required,max=100"`
+	Sets            int    `json:"sets" validate:"required,min=1,max=20"`
+	Reps            int    `json:"reps,omitempty" validate:"min=1,max=100"`
+	DurationSeconds int    `json:"duration_seconds,omitempty" validate:"min=1,max=7200"`
+	RestSeconds     int    `json:"rest_seconds,omitempty" validate:"min=0,max=3600"`
+	Load            string `json:"load,omitempty" validate:"max=50"` // Free text, such as "70% 1RM" or "bodyweight"
+	Notes           string `json:"notes,omitempty" validate:"max=500"`
+}
+
+// PlanAuthor credits who wrote a plan. Username is their Pulse username when Platform is pulse.
+type PlanAuthor struct {
+	Name     string `json:"name" validate:"required,max=100"`
+	Username string `json:"username,omitempty" validate:"max=100"`
+	Platform string `json:"platform,omitempty" validate:"max=50"`
+}
+
+// CreateWorkoutPlanRequest writes a new plan, credited to the coach.
+type CreateWorkoutPlanRequest struct {
+	Name        string               `json:"name" validate:"required,max=100"`
+	Description string               `json:"description" validate:"max=2000"`
+	Weeks       int                  `json:"weeks" validate:"required,min=1,max=52"`
+	Sessions    []WorkoutPlanSession `json:"sessions" validate:"required,max=364"`
+}
+
+// WorkoutPlanDocument is an export of plans, and what an import takes. See the WorkoutPlanDocument
+// schema of api/openapi.json.
+type WorkoutPlanDocument struct {
+	Format     string                `json:"format" validate:"required,oneof=pulse.workout-plans"`
+	Version    int                   `json:"version" validate:"required,min=1"`
+	ExportedAt *time.Time            `json:"exported_at,omitempty"`
+	Plans      []ExportedWorkoutPlan `json:"plans" validate:"required,max=50"`
+}
+
+// ExportedWorkoutPlan is a plan in a document. ID is its origin ID, which recognizes the plan when it
+// is imported again.
+type ExportedWorkoutPlan struct {
+	ID          uuid.UUID            `json:"id" validate:"required"`
+	Name        string               `json:"name" validate:"required,max=100"`
+	Description string               `json:"description,omitempty" validate:"max=2000"`
+	Weeks       int                  `json:"weeks" validate:"required,min=1,max=52"`
+	Sessions    []WorkoutPlanSession `json:"sessions" validate:"required,max=364"`
+	Author      PlanAuthor           `json:"author" validate:"required"`
+}
+
+// WorkoutPlanImportOptions holds the import options passed as query parameters. OnConflict is skip
+// (the default) or replace; a dry run reports what the import would do and changes nothing.
+type WorkoutPlanImportOptions struct {
+	OnConflict string
+	DryRun     bool
+}
+
+// WorkoutPlanImport is the result of an import, or with DryRun, what it would do.
+type WorkoutPlanImport struct {
+	DryRun   bool                `json:"dry_run"`
+	Plans    []PlanImportOutcome `json:"plans"` // In the order of the document
+	Created  int                 `json:"created"`
+	Replaced int                 `json:"replaced"`
+	Skipped  int                 `json:"skipped"`
+}
+
+// PlanImportOutcome is what an import does with one plan of the document.
+type PlanImportOutcome struct {
+	SourceID   uuid.UUID  `json:"source_id"` // The plan's ID in the document
+	Name       string     `json:"name"`
+	Action     string     `json:"action"`                // create, replace, or skip
+	Conflict   string     `json:"conflict,omitempty"`    // same_source or name_taken
+	ExistingID *uuid.UUID `json:"existing_id,omitempty"` // The coach's conflicting plan
+	PlanID     *uuid.UUID `json:"plan_id,omitempty"`     // The plan created or replaced; unset on dry runs and skips
+}
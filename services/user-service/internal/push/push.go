@@ -0,0 +1,82 @@
+// services/user-service/internal/push/push.go
+package push
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+	"health-tracker-project/services/user-service/internal/utils/reqctx"
+)
+
+// Notification is a push notification for one user. It must not carry health data or message
+// contents, since push payloads pass through third-party delivery services.
+type Notification struct {
+	UserID uuid.UUID         `json:"user_id"`
+	Title  string            `json:"title"`
+	Body   string            `json:"body"`
+	Data   map[string]string `json:"data,omitempty"` // For the client to route the tap, e.g. a thread ID
+}
+
+// Notifier defines the interface for delivering push notifications to a user's devices.
+// Device tokens are owned by the push gateway, which fans a notification out to them.
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// LogNotifier is a development Notifier that writes notifications to the log instead of sending them.
+type LogNotifier struct{}
+
+// NewLogNotifier creates a new LogNotifier instance.
+func NewLogNotifier() *LogNotifier {
+	return &LogNotifier{}
+}
+
+// Notify logs the notification that would have been delivered.
+func (n *LogNotifier) Notify(ctx context.Context, notification Notification) error {
+	logger.FromContext(ctx).Infof("Push to %s | Title: %s | Body: %s", notification.UserID, notification.Title, notification.Body)
+	return nil
+}
+
+// WebhookNotifier posts notifications as JSON to a push gateway (e.g. one relaying to APNs and FCM).
+type WebhookNotifier struct {
+	url    string
+	token  string // Sent as a bearer token when set
+	client *http.Client
+}
+
+// NewWebhookNotifier creates a WebhookNotifier for a gateway URL. Its requests carry the standard
+// Pulse headers of the context they are sent in, such as the request ID.
+func NewWebhookNotifier(url, token string) *WebhookNotifier {
+	return &WebhookNotifier{url: url, token: token, client: reqctx.NewClient(5 * time.Second)}
+}
+
+// Notify posts the notification; any non-2xx response is an error. It gives up once ctx is done.
+func (n *WebhookNotifier) Notify(ctx context.Context, notification Notification) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build push request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if n.token != "" {
+		req.Header.Set("Authorization", "Bearer "+n.token)
+	}
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("push gateway unreachable: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("push gateway returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,267 @@
+// services/user-service/internal/realtime/hub.go
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/metrics"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/repository"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+	"health-tracker-project/services/user-service/internal/utils/websocket"
+)
+
+const (
+	// MaxConnectionsPerUser caps the open connections of a user on each replica.
+	MaxConnectionsPerUser = 10
+	// sendQueueSize is how many events may wait for a connection. A client that falls further behind
+	// is disconnected rather than slowing down the others or growing memory without bound.
+	sendQueueSize = 32
+	// pingInterval is how often connections are pinged, and pongWait how long a client may go without
+	// answering, or sending anything, before it is considered gone.
+	pingInterval = 30 * time.Second
+	pongWait     = 2 * pingInterval
+	// maxClientMessage caps what a client may send. Nothing is expected but pongs.
+	maxClientMessage = 4 << 10
+	// closeWait is how long a closing connection waits for the client's close frame.
+	closeWait = time.Second
+)
+
+// ErrTooManyConnections is returned by Register when the user already has MaxConnectionsPerUser open.
+var ErrTooManyConnections = errors.New("realtime: too many open connections")
+
+// Publisher delivers events to a user's open connections.
+type Publisher interface {
+	Publish(userID uuid.UUID, e models.UserEvent)
+}
+
+// Hub tracks the open event streams of each user, over WebSocket or Server-Sent Events, and delivers
+// events to them. With a relay,
+// events are broadcast to the hubs of every replica, so they reach connections wherever they are
+// open; without one, only this replica's.
+type Hub struct {
+	relay repository.EventRelay // Nil on a single replica
+
+	mu      sync.Mutex
+	clients map[uuid.UUID]map[*Client]struct{}
+}
+
+// NewHub creates a Hub. relay may be nil; otherwise Listen must run for events to be delivered.
+func NewHub(relay repository.EventRelay) *Hub {
+	return &Hub{relay: relay, clients: make(map[uuid.UUID]map[*Client]struct{})}
+}
+
+// Client is a connection registered with a hub, open for a session of a user.
+type Client struct {
+	transport string // metrics.TransportWebSocket or metrics.TransportSSE
+	userID    uuid.UUID
+	sessionID uuid.UUID
+	types     []string    // Event types the client wants; nil for all
+	send      chan queued // Events waiting to be written
+
+	ended     chan struct{} // Closed when the hub ends the connection
+	endOnce   sync.Once
+	endCode   int
+	endReason string
+}
+
+// queued is an event waiting for a connection, with its JSON encoding.
+type queued struct {
+	event models.UserEvent
+	data  []byte
+}
+
+// wants reports whether the client asked for events of a type.
+func (c *Client) wants(eventType string) bool {
+	return c.types == nil || slices.Contains(c.types, eventType)
+}
+
+// end makes the connection close with code, after the events already queued are written.
+func (c *Client) end(code int, reason string) {
+	c.endOnce.Do(func() {
+		c.endCode, c.endReason = code, reason
+		close(c.ended)
+	})
+}
+
+// relayedEvent is an event as broadcast between replicas.
+type relayedEvent struct {
+	UserID uuid.UUID        `json:"user_id"`
+	Event  models.UserEvent `json:"event"`
+}
+
+// Publish delivers an event to every open connection of the user. A session_revoked event then closes
+// the connections of the session it names, or of every session when it names none. If the relay
+// fails, the event still reaches this replica's connections.
+func (h *Hub) Publish(userID uuid.UUID, e models.UserEvent) {
+	if h.relay != nil {
+		payload, err := json.Marshal(relayedEvent{UserID: userID, Event: e})
+		if err == nil {
+			if err = h.relay.Send(payload); err == nil {
+				return
+			}
+		}
+		logger.Logger.Warnf("Failed to relay %s event for user %s, delivering it on this replica only: %v", e.Type, userID, err)
+	}
+	h.deliver(userID, e)
+}
+
+// Listen delivers the events relayed from every replica. It never returns; without a relay it
+// returns at once.
+func (h *Hub) Listen() {
+	if h.relay == nil {
+		return
+	}
+	h.relay.Listen(func(payload []byte) {
+		var relayed relayedEvent
+		if err := json.Unmarshal(payload, &relayed); err != nil {
+			logger.Logger.Warnf("Ignoring malformed relayed event: %v", err)
+			return
+		}
+		h.deliver(relayed.UserID, relayed.Event)
+	})
+}
+
+// deliver queues an event for this replica's connections of the user that want it, without waiting
+// for any of them. A connection whose queue is full is closed: the client reconnects and catches up
+// from its timeline instead. Revocations end connections whether or not they want the event.
+func (h *Hub) deliver(userID uuid.UUID, e models.UserEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	clients := h.clients[userID]
+	if len(clients) == 0 {
+		return
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		logger.Logger.Errorf("Failed to encode %s event for user %s: %v", e.Type, userID, err)
+		return
+	}
+	revokes := e.Type == models.UserEventSessionRevoked
+	for c := range clients {
+		select {
+		case <-c.ended:
+			continue // Closing already; it gets nothing more
+		default:
+		}
+		if c.wants(e.Type) {
+			select {
+			case c.send <- queued{e, data}:
+				metrics.StreamEventSent(c.transport)
+			default:
+				metrics.StreamSlowConsumer(c.transport)
+				logger.Logger.Infof("Closing a %s event stream of user %s that fell %d events behind", c.transport, userID, sendQueueSize)
+				c.end(websocket.CloseTryAgainLater, "too far behind")
+				continue
+			}
+		}
+		if session := e.Details["session_id"]; revokes && (session == "" || session == c.sessionID.String()) {
+			c.end(websocket.ClosePolicyViolation, "session ended")
+		}
+	}
+}
+
+// Register adds a connection over transport for a session of a user, before it is upgraded or
+// answered, so a user with too many open connections can be refused with an HTTP error. The
+// connection receives the events of types, or all of them if types is nil. Serve, ServeEvents, or
+// Unregister must follow.
+func (h *Hub) Register(transport string, userID, sessionID uuid.UUID, types []string) (*Client, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.clients[userID]) >= MaxConnectionsPerUser {
+		return nil, ErrTooManyConnections
+	}
+	c := &Client{transport: transport, userID: userID, sessionID: sessionID, types: types, send: make(chan queued, sendQueueSize), ended: make(chan struct{})}
+	if h.clients[userID] == nil {
+		h.clients[userID] = make(map[*Client]struct{})
+	}
+	h.clients[userID][c] = struct{}{}
+	metrics.StreamConnections(transport, 1)
+	return c, nil
+}
+
+// Unregister removes a connection; events are no longer queued for it.
+func (h *Hub) Unregister(c *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.clients[c.userID][c]; !ok {
+		return
+	}
+	delete(h.clients[c.userID], c)
+	if len(h.clients[c.userID]) == 0 {
+		delete(h.clients, c.userID)
+	}
+	metrics.StreamConnections(c.transport, -1)
+}
+
+// Serve writes a client's events to its connection until either side closes it, the hub ends it, or
+// the token it was opened with expires at expiresAt, if set. Messages from the client are read only
+// to answer pings and to notice it is gone. Serve unregisters the client and closes the connection.
+func (h *Hub) Serve(ctx context.Context, conn *websocket.Conn, c *Client, expiresAt time.Time) {
+	defer h.Unregister(c)
+	defer conn.Close()
+	log := logger.FromContext(ctx)
+
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		conn.SetReadLimit(maxClientMessage)
+		for {
+			conn.SetReadDeadline(time.Now().Add(pongWait))
+			if _, _, err := conn.ReadMessage(); err != nil {
+				log.Debugf("WebSocket connection of user %s closed: %v", c.userID, err)
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(pingInterval)
+	defer ping.Stop()
+	var expired <-chan time.Time
+	if !expiresAt.IsZero() {
+		expiry := time.NewTimer(time.Until(expiresAt))
+		defer expiry.Stop()
+		expired = expiry.C
+	}
+	for {
+		select {
+		case q := <-c.send:
+			if err := conn.WriteMessage(websocket.TextMessage, q.data); err != nil {
+				log.Debugf("Failed to write to WebSocket connection of user %s: %v", c.userID, err)
+				return
+			}
+		case <-ping.C:
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		case <-expired:
+			c.end(websocket.ClosePolicyViolation, "token expired")
+		case <-c.ended:
+			h.Unregister(c) // Nothing more is queued, so the queue can be drained
+			for drained := false; !drained; {
+				select {
+				case q := <-c.send:
+					if conn.WriteMessage(websocket.TextMessage, q.data) != nil {
+						return
+					}
+				default:
+					drained = true
+				}
+			}
+			conn.WriteClose(c.endCode, c.endReason)
+			select {
+			case <-gone:
+			case <-time.After(closeWait):
+			}
+			return
+		case <-gone:
+			return
+		}
+	}
+}
@@ -0,0 +1,150 @@
+// services/user-service/internal/realtime/sse.go
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+	"health-tracker-project/services/user-service/internal/utils/websocket"
+)
+
+const (
+	// heartbeatInterval is how often an idle event stream gets a comment, so proxies do not time it out
+	// and the server notices clients that are gone.
+	heartbeatInterval = 15 * time.Second
+	// retryAfter is how long EventSource clients wait before reconnecting, sent as the retry field.
+	retryAfter = 3 * time.Second
+	// sseWriteTimeout bounds each write, so a client that stops reading cannot hold the stream forever.
+	sseWriteTimeout = 10 * time.Second
+)
+
+// EventStream describes a Server-Sent Events connection for ServeEvents.
+type EventStream struct {
+	// ExpiresAt is when the token the stream was opened with expires; zero for never.
+	ExpiresAt time.Time
+	// EventID returns the id field of an event, which the client sends back as Last-Event-ID to resume.
+	EventID func(models.UserEvent) string
+	// Since is where a resumed stream left off, and Missed returns a page of the stored events after a
+	// key, oldest first. The pages are sent before live events, until one comes back empty.
+	Since  *models.PageKey
+	Missed func(since models.PageKey) ([]models.UserEvent, error)
+}
+
+// ServeEvents writes a client's events to w as Server-Sent Events until the client goes away, the hub
+// ends the stream, or its token expires. A resumed stream first catches up on the events it missed.
+// Each event is sent with its type as the event field and its JSON as the data; an ending stream
+// sends a last "end" event with the reason. ServeEvents unregisters the client.
+func (h *Hub) ServeEvents(ctx context.Context, w http.ResponseWriter, c *Client, s EventStream) {
+	defer h.Unregister(c)
+	log := logger.FromContext(ctx)
+	rc := http.NewResponseController(w)
+	write := func(frame string) error {
+		if err := rc.SetWriteDeadline(time.Now().Add(sseWriteTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return err
+		}
+		if _, err := fmt.Fprint(w, frame); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // Keeps nginx from buffering the stream
+	w.WriteHeader(http.StatusOK)
+	if err := write(fmt.Sprintf("retry: %d\n\n", retryAfter.Milliseconds())); err != nil {
+		log.Debugf("Failed to start event stream of user %s: %v", c.userID, err)
+		return
+	}
+
+	// Live events are queued while the missed ones are sent, so those recorded meanwhile may come both
+	// ways; the queued copies are skipped.
+	replayed := map[uuid.UUID]bool{}
+	if s.Since != nil && s.Missed != nil {
+		for since := *s.Since; ; {
+			events, err := s.Missed(since)
+			if err != nil {
+				log.Errorf("Failed to replay missed events to user %s: %v", c.userID, err)
+				write(endFrame("failed to replay missed events"))
+				return
+			}
+			if len(events) == 0 {
+				break
+			}
+			for _, e := range events {
+				data, err := json.Marshal(e)
+				if err != nil {
+					continue
+				}
+				if err := write(eventFrame(s.EventID(e), e.Type, data)); err != nil {
+					return
+				}
+				replayed[e.ID] = true
+			}
+			last := events[len(events)-1]
+			since = models.PageKey{At: last.OccurredAt, ID: last.ID}
+		}
+	}
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	var expired <-chan time.Time
+	if !s.ExpiresAt.IsZero() {
+		expiry := time.NewTimer(time.Until(s.ExpiresAt))
+		defer expiry.Stop()
+		expired = expiry.C
+	}
+	for {
+		select {
+		case q := <-c.send:
+			if replayed[q.event.ID] {
+				continue
+			}
+			if err := write(eventFrame(s.EventID(q.event), q.event.Type, q.data)); err != nil {
+				log.Debugf("Failed to write to event stream of user %s: %v", c.userID, err)
+				return
+			}
+		case <-heartbeat.C:
+			if err := write(": heartbeat\n\n"); err != nil {
+				return
+			}
+		case <-expired:
+			c.end(websocket.ClosePolicyViolation, "token expired")
+		case <-c.ended:
+			h.Unregister(c) // Nothing more is queued, so the queue can be drained
+			for drained := false; !drained; {
+				select {
+				case q := <-c.send:
+					if !replayed[q.event.ID] && write(eventFrame(s.EventID(q.event), q.event.Type, q.data)) != nil {
+						return
+					}
+				default:
+					drained = true
+				}
+			}
+			write(endFrame(c.endReason))
+			return
+		case <-ctx.Done():
+			log.Debugf("Event stream of user %s closed: %v", c.userID, ctx.Err())
+			return
+		}
+	}
+}
+
+// eventFrame formats an event for the stream. data is JSON, which holds no newlines.
+func eventFrame(id, eventType string, data []byte) string {
+	return fmt.Sprintf("id: %s\nevent: %s\ndata: %s\n\n", id, eventType, data)
+}
+
+// endFrame formats the last event of a stream the server ends.
+func endFrame(reason string) string {
+	data, _ := json.Marshal(map[string]string{"reason": reason})
+	return fmt.Sprintf("event: end\ndata: %s\n\n", data)
+}
@@ -0,0 +1,80 @@
+// services/user-service/internal/repository/account_deletion.go
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// ListDueDeletions returns accounts pending deletion whose grace period ended by now, oldest first.
+func (r *postgresUserRepository) ListDueDeletions(ctx context.Context, now time.Time, limit int) ([]models.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE status = $1 AND deletion_due_at <= $2 ORDER BY deletion_due_at LIMIT $3`
+	rows, err := r.db.QueryContext(ctx, query, models.StatusPendingDeletion, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to list due deletions: %w", err)
+	}
+	defer rows.Close()
+
+	users := []models.User{}
+	for rows.Next() {
+		var user models.User
+		if err := scanUser(rows, &user); err != nil {
+			return nil, fmt.Errorf("repository: failed to scan user row: %w", err)
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: rows iteration error: %w", err)
+	}
+	return users, nil
+}
+
+// EraseUser removes a user with every row about them in this database and returns the blob keys of
+// their message and workout attachments, which the caller must delete from the blob store. Tables
+// owned by the user cascade from users; merge records, which keep a snapshot of the merged-in
+// account, do not reference it and are deleted first.
+func (r *postgresUserRepository) EraseUser(ctx context.Context, id uuid.UUID) ([]string, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to begin erasure: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT blob_key FROM message_attachments WHERE user_id = $1
+		UNION ALL
+		SELECT blob_key FROM workout_attachments WHERE user_id = $1 AND blob_key <> ''`, id)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to list attachment blobs: %w", err)
+	}
+	keys := []string{}
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("repository: failed to scan attachment blob: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: failed to list attachment blobs: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_merges WHERE primary_user_id = $1 OR donor_user_id = $1`, id); err != nil {
+		return nil, fmt.Errorf("repository: failed to delete merge records: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM users WHERE id = $1`, id); err != nil {
+		return nil, fmt.Errorf("repository: failed to delete user: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("repository: failed to commit erasure: %w", err)
+	}
+	logger.FromContext(ctx).Infof("User erased: %s (%d attachment blobs to delete)", id, len(keys))
+	return keys, nil
+}
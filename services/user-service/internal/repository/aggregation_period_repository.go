@@ -0,0 +1,71 @@
+// services/user-service/internal/repository/aggregation_period_repository.go
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+)
+
+const aggregationPeriodColumns = `id, user_id, name, kind, to_char(starts_on, 'YYYY-MM-DD'), to_char(ends_on, 'YYYY-MM-DD'), created_at, updated_at`
+
+// ListAggregationPeriods returns a user's custom periods, earliest first.
+func (r *postgresUserRepository) ListAggregationPeriods(ctx context.Context, userID uuid.UUID) ([]models.AggregationPeriod, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+aggregationPeriodColumns+` FROM aggregation_periods WHERE user_id = $1 ORDER BY starts_on, ends_on, id`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to list aggregation periods: %w", err)
+	}
+	defer rows.Close()
+
+	periods := []models.AggregationPeriod{}
+	for rows.Next() {
+		var p models.AggregationPeriod
+		if err := rows.Scan(&p.ID, &p.UserID, &p.Name, &p.Kind, &p.StartsOn, &p.EndsOn, &p.CreatedAt, &p.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("repository: failed to scan aggregation period row: %w", err)
+		}
+		periods = append(periods, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: rows iteration error: %w", err)
+	}
+	return periods, nil
+}
+
+// CreateAggregationPeriod inserts a custom period.
+func (r *postgresUserRepository) CreateAggregationPeriod(ctx context.Context, p *models.AggregationPeriod) error {
+	query := `INSERT INTO aggregation_periods (id, user_id, name, kind, starts_on, ends_on, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	if _, err := r.db.ExecContext(ctx, query, p.ID, p.UserID, p.Name, p.Kind, p.StartsOn, p.EndsOn, p.CreatedAt, p.UpdatedAt); err != nil {
+		return fmt.Errorf("repository: failed to create aggregation period: %w", err)
+	}
+	return nil
+}
+
+// UpdateAggregationPeriod saves a custom period's name, kind, and dates, reporting whether it exists.
+func (r *postgresUserRepository) UpdateAggregationPeriod(ctx context.Context, p *models.AggregationPeriod) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `UPDATE aggregation_periods SET name = $3, kind = $4, starts_on = $5, ends_on = $6, updated_at = $7
+		WHERE user_id = $1 AND id = $2`, p.UserID, p.ID, p.Name, p.Kind, p.StartsOn, p.EndsOn, p.UpdatedAt)
+	if err != nil {
+		return false, fmt.Errorf("repository: failed to update aggregation period: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("repository: failed to update aggregation period: %w", err)
+	}
+	return n == 1, nil
+}
+
+// DeleteAggregationPeriod deletes one of the user's custom periods, reporting whether it existed.
+func (r *postgresUserRepository) DeleteAggregationPeriod(ctx context.Context, userID, id uuid.UUID) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM aggregation_periods WHERE user_id = $1 AND id = $2`, userID, id)
+	if err != nil {
+		return false, fmt.Errorf("repository: failed to delete aggregation period: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("repository: failed to delete aggregation period: %w", err)
+	}
+	return n == 1, nil
+}
@@ -0,0 +1,72 @@
+// services/user-service/internal/repository/announcement_audience.go
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+)
+
+// segmentClauses returns the conditions selecting the active users of an announcement segment, and
+// their arguments, numbered after those already in args.
+func segmentClauses(segment models.AnnouncementSegment, args []interface{}) (string, []interface{}) {
+	clauses := ` AND u.status = 'active'`
+	metadataIs := func(key, value string) {
+		args = append(args, key, value)
+		clauses += fmt.Sprintf(` AND u.metadata->>$%d = $%d`, len(args)-1, len(args))
+	}
+	switch segment.Type {
+	case models.SegmentOrg:
+		metadataIs(models.UserOrgMetadataKey, segment.Org)
+	case models.SegmentPlan:
+		metadataIs(models.UserPlanMetadataKey, segment.Plan)
+	case models.SegmentInactive:
+		args = append(args, segment.InactiveDays)
+		clauses += fmt.Sprintf(` AND u.created_at < NOW() - make_interval(days => $%[1]d) AND NOT EXISTS (
+			SELECT 1 FROM login_attempts a WHERE a.user_id = u.id AND a.success AND a.created_at >= NOW() - make_interval(days => $%[1]d))`, len(args))
+	}
+	return clauses, args
+}
+
+// optedOutOfPush is true for users who turned notifications.push off.
+const optedOutOfPush = `EXISTS (SELECT 1 FROM user_settings s WHERE s.user_id = u.id AND s.settings->>'notifications.push' = 'false')`
+
+// CountAnnouncementRecipients counts the users of a segment an announcement would reach, and those
+// of it who turned push notifications off.
+func (r *postgresUserRepository) CountAnnouncementRecipients(ctx context.Context, segment models.AnnouncementSegment) (*models.AnnouncementAudience, error) {
+	clauses, args := segmentClauses(segment, nil)
+	query := `SELECT COUNT(*) FILTER (WHERE NOT opted_out), COUNT(*) FILTER (WHERE opted_out)
+		FROM (SELECT ` + optedOutOfPush + ` AS opted_out FROM users u WHERE TRUE` + clauses + `) segment`
+	audience := &models.AnnouncementAudience{}
+	if err := r.db.QueryRowContext(ctx, query, args...).Scan(&audience.Recipients, &audience.OptedOut); err != nil {
+		return nil, fmt.Errorf("repository: failed to count announcement recipients: %w", err)
+	}
+	return audience, nil
+}
+
+// ListAnnouncementRecipients returns up to limit recipients of a segment with IDs after the given
+// one, in ID order, leaving out users who turned push notifications off.
+func (r *postgresUserRepository) ListAnnouncementRecipients(ctx context.Context, segment models.AnnouncementSegment, after uuid.UUID, limit int) ([]uuid.UUID, error) {
+	clauses, args := segmentClauses(segment, []interface{}{after, limit})
+	query := `SELECT u.id FROM users u WHERE u.id > $1 AND NOT ` + optedOutOfPush + clauses + ` ORDER BY u.id LIMIT $2`
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to list announcement recipients: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("repository: failed to scan announcement recipient: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: rows iteration error: %w", err)
+	}
+	return ids, nil
+}
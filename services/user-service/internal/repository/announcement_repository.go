@@ -0,0 +1,178 @@
+// services/user-service/internal/repository/announcement_repository.go
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+)
+
+// postgresAnnouncementRepository is the PostgreSQL implementation of AnnouncementRepository.
+type postgresAnnouncementRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresAnnouncementRepository creates an AnnouncementRepository on an open pool and runs its migrations.
+func NewPostgresAnnouncementRepository(db *sql.DB) (AnnouncementRepository, error) {
+	repo := &postgresAnnouncementRepository{db: db}
+	if err := repo.Migrate(); err != nil {
+		return nil, fmt.Errorf("failed to run announcement migrations: %w", err)
+	}
+	return repo, nil
+}
+
+// Migrate applies the pending migrations in migrations/announcements.
+func (r *postgresAnnouncementRepository) Migrate() error {
+	return MigrateSchema(r.db, "announcements")
+}
+
+const announcementColumns = `id, title, body, segment, status, send_at, created_by, created_at, started_at, finished_at, cursor_id, recipients, sent, failed`
+
+func scanAnnouncement(row rowScanner) (*models.Announcement, error) {
+	var a models.Announcement
+	var segment []byte
+	err := row.Scan(&a.ID, &a.Title, &a.Body, &segment, &a.Status, &a.SendAt, &a.CreatedBy, &a.CreatedAt, &a.StartedAt, &a.FinishedAt,
+		&a.Cursor, &a.Stats.Recipients, &a.Stats.Sent, &a.Stats.Failed)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(segment, &a.Segment); err != nil {
+		return nil, fmt.Errorf("repository: failed to decode announcement segment: %w", err)
+	}
+	return &a, nil
+}
+
+// CreateAnnouncement inserts a new announcement.
+func (r *postgresAnnouncementRepository) CreateAnnouncement(ctx context.Context, a *models.Announcement) error {
+	segment, err := json.Marshal(a.Segment)
+	if err != nil {
+		return fmt.Errorf("repository: failed to encode announcement segment: %w", err)
+	}
+	_, err = r.db.ExecContext(ctx, `INSERT INTO announcements (id, title, body, segment, status, send_at, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`, a.ID, a.Title, a.Body, segment, a.Status, a.SendAt, a.CreatedBy, a.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("repository: failed to create announcement: %w", err)
+	}
+	return nil
+}
+
+// GetAnnouncement returns an announcement, or nil if it does not exist.
+func (r *postgresAnnouncementRepository) GetAnnouncement(ctx context.Context, id uuid.UUID) (*models.Announcement, error) {
+	a, err := scanAnnouncement(r.db.QueryRowContext(ctx, `SELECT `+announcementColumns+` FROM announcements WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to get announcement: %w", err)
+	}
+	return a, nil
+}
+
+// ListAnnouncements returns announcements matching the filter, newest first.
+func (r *postgresAnnouncementRepository) ListAnnouncements(ctx context.Context, filter models.AnnouncementFilter) ([]models.Announcement, error) {
+	var conditions []string
+	var args []interface{}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		conditions = append(conditions, fmt.Sprintf("status = $%d", len(args)))
+	}
+	if filter.After != nil {
+		args = append(args, filter.After.At, filter.After.ID)
+		conditions = append(conditions, fmt.Sprintf("(created_at, id) < ($%d, $%d)", len(args)-1, len(args)))
+	}
+
+	query := `SELECT ` + announcementColumns + ` FROM announcements`
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(` ORDER BY created_at DESC, id DESC LIMIT $%d`, len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to list announcements: %w", err)
+	}
+	defer rows.Close()
+
+	announcements := []models.Announcement{}
+	for rows.Next() {
+		a, err := scanAnnouncement(rows)
+		if err != nil {
+			return nil, fmt.Errorf("repository: failed to scan announcement row: %w", err)
+		}
+		announcements = append(announcements, *a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: rows iteration error: %w", err)
+	}
+	return announcements, nil
+}
+
+// ClaimDueAnnouncement marks the earliest due announcement as sending, leased to the caller until
+// leaseUntil, and returns it. Due announcements are scheduled ones whose send time has come, and
+// sending ones whose lease ran out because their sender stopped. It returns nil if none is due.
+func (r *postgresAnnouncementRepository) ClaimDueAnnouncement(ctx context.Context, now, leaseUntil time.Time) (*models.Announcement, error) {
+	a, err := scanAnnouncement(r.db.QueryRowContext(ctx, `
+	UPDATE announcements SET status = 'sending', lease_until = $2, started_at = COALESCE(started_at, $1)
+	WHERE id = (
+		SELECT id FROM announcements
+		WHERE send_at <= $1 AND (status = 'scheduled' OR (status = 'sending' AND lease_until < $1))
+		ORDER BY send_at LIMIT 1 FOR UPDATE SKIP LOCKED
+	)
+	RETURNING `+announcementColumns, now, leaseUntil))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to claim due announcement: %w", err)
+	}
+	return a, nil
+}
+
+// SetAnnouncementRecipients records the size of the segment when sending started.
+func (r *postgresAnnouncementRepository) SetAnnouncementRecipients(ctx context.Context, id uuid.UUID, recipients int) error {
+	if _, err := r.db.ExecContext(ctx, `UPDATE announcements SET recipients = $2 WHERE id = $1`, id, recipients); err != nil {
+		return fmt.Errorf("repository: failed to set announcement recipients: %w", err)
+	}
+	return nil
+}
+
+// RecordAnnouncementProgress adds deliveries to a sending announcement, moves its cursor, and extends
+// its lease. It returns false if the announcement is no longer sending, such as after a cancellation.
+func (r *postgresAnnouncementRepository) RecordAnnouncementProgress(ctx context.Context, id, cursor uuid.UUID, sent, failed int, leaseUntil time.Time) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `UPDATE announcements SET cursor_id = $2, sent = sent + $3, failed = failed + $4, lease_until = $5
+		WHERE id = $1 AND status = 'sending'`, id, cursor, sent, failed, leaseUntil)
+	if err != nil {
+		return false, fmt.Errorf("repository: failed to record announcement progress: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// FinishAnnouncement marks a sending announcement as sent.
+func (r *postgresAnnouncementRepository) FinishAnnouncement(ctx context.Context, id uuid.UUID, at time.Time) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE announcements SET status = 'sent', finished_at = $2, lease_until = NULL
+		WHERE id = $1 AND status = 'sending'`, id, at)
+	if err != nil {
+		return fmt.Errorf("repository: failed to finish announcement: %w", err)
+	}
+	return nil
+}
+
+// CancelAnnouncement stops a scheduled or sending announcement. It returns false if the announcement
+// is missing or already finished. A send in progress stops at its next progress record.
+func (r *postgresAnnouncementRepository) CancelAnnouncement(ctx context.Context, id uuid.UUID, at time.Time) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `UPDATE announcements SET status = 'cancelled', finished_at = $2, lease_until = NULL
+		WHERE id = $1 AND status IN ('scheduled', 'sending')`, id, at)
+	if err != nil {
+		return false, fmt.Errorf("repository: failed to cancel announcement: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
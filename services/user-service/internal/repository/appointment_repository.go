@@ -0,0 +1,345 @@
+// services/user-service/internal/repository/appointment_repository.go
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+)
+
+// postgresAppointmentRepository is the PostgreSQL implementation of AppointmentRepository.
+// Slots and appointments are the provider's data; the booking user's ID has no foreign key,
+// since the user may be stored in another region.
+type postgresAppointmentRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresAppointmentRepository creates an AppointmentRepository on an open pool and runs its migrations.
+// The users table must already exist.
+func NewPostgresAppointmentRepository(db *sql.DB) (AppointmentRepository, error) {
+	repo := &postgresAppointmentRepository{db: db}
+	if err := repo.Migrate(); err != nil {
+		return nil, fmt.Errorf("failed to run appointment migrations: %w", err)
+	}
+	return repo, nil
+}
+
+// Migrate applies the pending migrations in migrations/appointments.
+func (r *postgresAppointmentRepository) Migrate() error {
+	return MigrateSchema(r.db, "appointments")
+}
+
+// CreateSlots publishes slots for a provider. It fails without storing any of them if one overlaps
+// a slot the provider already has.
+func (r *postgresAppointmentRepository) CreateSlots(ctx context.Context, providerID uuid.UUID, slots []models.AppointmentSlot) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("repository: failed to begin slot transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Serializes publishing per provider, so two overlapping requests cannot both pass the check.
+	if _, err := tx.ExecContext(ctx, `SELECT id FROM users WHERE id = $1 FOR UPDATE`, providerID); err != nil {
+		return fmt.Errorf("repository: failed to lock provider: %w", err)
+	}
+	for _, s := range slots {
+		var overlaps bool
+		err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM appointment_slots
+			WHERE provider_id = $1 AND starts_at < $3 AND ends_at > $2)`, providerID, s.StartsAt, s.EndsAt).Scan(&overlaps)
+		if err != nil {
+			return fmt.Errorf("repository: failed to check slot overlap: %w", err)
+		}
+		if overlaps {
+			return Errorf(ErrConflict, "repository: slots overlap existing availability")
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO appointment_slots (id, provider_id, starts_at, ends_at, location, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6)`, s.ID, providerID, s.StartsAt, s.EndsAt, s.Location, s.CreatedAt); err != nil {
+			return fmt.Errorf("repository: failed to create slot: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("repository: failed to commit slots: %w", err)
+	}
+	return nil
+}
+
+// slotColumns selects a slot with whether it currently holds a booking.
+const slotColumns = `s.id, s.provider_id, s.starts_at, s.ends_at, s.location, s.created_at,
+	EXISTS (SELECT 1 FROM appointments a WHERE a.slot_id = s.id AND a.status = 'booked')`
+
+func scanSlot(row rowScanner, s *models.AppointmentSlot) error {
+	return row.Scan(&s.ID, &s.ProviderID, &s.StartsAt, &s.EndsAt, &s.Location, &s.CreatedAt, &s.Booked)
+}
+
+// ListSlots returns a provider's slots in the filter's range, earliest first.
+func (r *postgresAppointmentRepository) ListSlots(ctx context.Context, filter models.SlotFilter) ([]models.AppointmentSlot, error) {
+	args := []interface{}{filter.ProviderID}
+	query := `SELECT ` + slotColumns + ` FROM appointment_slots s WHERE s.provider_id = $1`
+	if !filter.From.IsZero() {
+		args = append(args, filter.From)
+		query += fmt.Sprintf(` AND s.starts_at >= $%d`, len(args))
+	}
+	if !filter.To.IsZero() {
+		args = append(args, filter.To)
+		query += fmt.Sprintf(` AND s.starts_at < $%d`, len(args))
+	}
+	if filter.OpenOnly {
+		query += ` AND NOT EXISTS (SELECT 1 FROM appointments a WHERE a.slot_id = s.id AND a.status = 'booked')`
+	}
+	query += ` ORDER BY s.starts_at`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to list slots: %w", err)
+	}
+	defer rows.Close()
+
+	slots := []models.AppointmentSlot{}
+	for rows.Next() {
+		var s models.AppointmentSlot
+		if err := scanSlot(rows, &s); err != nil {
+			return nil, fmt.Errorf("repository: failed to scan slot: %w", err)
+		}
+		slots = append(slots, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: failed to list slots: %w", err)
+	}
+	return slots, nil
+}
+
+// GetSlot returns a slot, or nil if it does not exist.
+func (r *postgresAppointmentRepository) GetSlot(ctx context.Context, id uuid.UUID) (*models.AppointmentSlot, error) {
+	var s models.AppointmentSlot
+	err := scanSlot(r.db.QueryRowContext(ctx, `SELECT `+slotColumns+` FROM appointment_slots s WHERE s.id = $1`, id), &s)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to get slot: %w", err)
+	}
+	return &s, nil
+}
+
+// DeleteSlot removes one of the provider's slots unless it is booked, and reports whether it did.
+func (r *postgresAppointmentRepository) DeleteSlot(ctx context.Context, providerID, id uuid.UUID) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM appointment_slots s WHERE s.id = $1 AND s.provider_id = $2
+		AND NOT EXISTS (SELECT 1 FROM appointments a WHERE a.slot_id = s.id AND a.status = 'booked')`, id, providerID)
+	if err != nil {
+		return false, fmt.Errorf("repository: failed to delete slot: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("repository: failed to delete slot: %w", err)
+	}
+	return n > 0, nil
+}
+
+// bookSlot books appointment.SlotID inside tx, copying the slot's time and location into the appointment.
+// The slot row is locked, so concurrent bookings of the same slot are serialized.
+func bookSlot(ctx context.Context, tx *sql.Tx, a *models.Appointment) error {
+	err := tx.QueryRowContext(ctx, `SELECT starts_at, ends_at, location FROM appointment_slots WHERE id = $1 AND provider_id = $2 FOR UPDATE`,
+		a.SlotID, a.ProviderID).Scan(&a.StartsAt, &a.EndsAt, &a.Location)
+	if err == sql.ErrNoRows {
+		return Errorf(ErrNotFound, "repository: slot not found")
+	}
+	if err != nil {
+		return fmt.Errorf("repository: failed to lock slot: %w", err)
+	}
+	var booked bool
+	if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM appointments WHERE slot_id = $1 AND status = 'booked')`, a.SlotID).Scan(&booked); err != nil {
+		return fmt.Errorf("repository: failed to check slot: %w", err)
+	}
+	if booked {
+		return Errorf(ErrConflict, "repository: slot is already booked")
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO appointments (id, slot_id, provider_id, user_id, starts_at, ends_at, location, reason, status,
+			rescheduled_from, reschedule_count, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
+		a.ID, a.SlotID, a.ProviderID, a.UserID, a.StartsAt, a.EndsAt, a.Location, a.Reason, a.Status,
+		a.RescheduledFrom, a.RescheduleCount, a.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("repository: failed to create appointment: %w", err)
+	}
+	return nil
+}
+
+// BookSlot stores a booked appointment for an open slot, filling in its time and location.
+func (r *postgresAppointmentRepository) BookSlot(ctx context.Context, appointment *models.Appointment) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("repository: failed to begin booking transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := bookSlot(ctx, tx, appointment); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("repository: failed to commit booking: %w", err)
+	}
+	return nil
+}
+
+// appointmentColumns is the column list shared by every query that loads an appointment.
+const appointmentColumns = `id, slot_id, provider_id, user_id, starts_at, ends_at, location, reason, status,
+	rescheduled_from, reschedule_count, created_at, cancelled_at, cancelled_by, cancel_reason, reminder_sent_at`
+
+func scanAppointment(row rowScanner, a *models.Appointment) error {
+	var slotID, rescheduledFrom, cancelledBy uuid.NullUUID
+	var cancelledAt, reminderSentAt sql.NullTime
+	err := row.Scan(&a.ID, &slotID, &a.ProviderID, &a.UserID, &a.StartsAt, &a.EndsAt, &a.Location, &a.Reason, &a.Status,
+		&rescheduledFrom, &a.RescheduleCount, &a.CreatedAt, &cancelledAt, &cancelledBy, &a.CancelReason, &reminderSentAt)
+	if err != nil {
+		return err
+	}
+	if slotID.Valid {
+		a.SlotID = &slotID.UUID
+	}
+	if rescheduledFrom.Valid {
+		a.RescheduledFrom = &rescheduledFrom.UUID
+	}
+	if cancelledBy.Valid {
+		a.CancelledBy = &cancelledBy.UUID
+	}
+	if cancelledAt.Valid {
+		a.CancelledAt = &cancelledAt.Time
+	}
+	if reminderSentAt.Valid {
+		a.ReminderSentAt = &reminderSentAt.Time
+	}
+	return nil
+}
+
+func (r *postgresAppointmentRepository) queryAppointments(ctx context.Context, query string, args ...interface{}) ([]models.Appointment, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to list appointments: %w", err)
+	}
+	defer rows.Close()
+
+	appointments := []models.Appointment{}
+	for rows.Next() {
+		var a models.Appointment
+		if err := scanAppointment(rows, &a); err != nil {
+			return nil, fmt.Errorf("repository: failed to scan appointment: %w", err)
+		}
+		appointments = append(appointments, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: failed to list appointments: %w", err)
+	}
+	return appointments, nil
+}
+
+// GetAppointment returns an appointment, or nil if it does not exist.
+func (r *postgresAppointmentRepository) GetAppointment(ctx context.Context, id uuid.UUID) (*models.Appointment, error) {
+	var a models.Appointment
+	err := scanAppointment(r.db.QueryRowContext(ctx, `SELECT `+appointmentColumns+` FROM appointments WHERE id = $1`, id), &a)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to get appointment: %w", err)
+	}
+	return &a, nil
+}
+
+// ListAppointments returns the appointments matching the filter, earliest first.
+func (r *postgresAppointmentRepository) ListAppointments(ctx context.Context, filter models.AppointmentFilter) ([]models.Appointment, error) {
+	var conditions []string
+	var args []interface{}
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(cond, len(args)))
+	}
+	if filter.ProviderID != uuid.Nil {
+		add("provider_id = $%d", filter.ProviderID)
+	}
+	if filter.UserID != uuid.Nil {
+		add("user_id = $%d", filter.UserID)
+	}
+	if !filter.From.IsZero() {
+		add("starts_at >= $%d", filter.From)
+	}
+	if !filter.To.IsZero() {
+		add("starts_at < $%d", filter.To)
+	}
+	if filter.Status != "" {
+		add("status = $%d", filter.Status)
+	}
+
+	query := `SELECT ` + appointmentColumns + ` FROM appointments`
+	for i, cond := range conditions {
+		if i == 0 {
+			query += ` WHERE ` + cond
+		} else {
+			query += ` AND ` + cond
+		}
+	}
+	return r.queryAppointments(ctx, query+` ORDER BY starts_at`, args...)
+}
+
+// CancelAppointment marks a booked appointment cancelled with a.CancelledAt, CancelledBy, and CancelReason,
+// and reports whether it was still booked. The slot opens up again.
+func (r *postgresAppointmentRepository) CancelAppointment(ctx context.Context, a *models.Appointment) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `UPDATE appointments SET status = $2, cancelled_at = $3, cancelled_by = $4, cancel_reason = $5
+		WHERE id = $1 AND status = $6`,
+		a.ID, models.AppointmentCancelled, a.CancelledAt, a.CancelledBy, a.CancelReason, models.AppointmentBooked)
+	if err != nil {
+		return false, fmt.Errorf("repository: failed to cancel appointment: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("repository: failed to cancel appointment: %w", err)
+	}
+	return n > 0, nil
+}
+
+// RescheduleAppointment marks old rescheduled and books replacement in one transaction, so the
+// booking is never lost or doubled. Both must belong to the same provider.
+func (r *postgresAppointmentRepository) RescheduleAppointment(ctx context.Context, old, replacement *models.Appointment) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("repository: failed to begin reschedule transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `UPDATE appointments SET status = $2, cancelled_at = $3, cancelled_by = $4 WHERE id = $1 AND status = $5`,
+		old.ID, models.AppointmentRescheduled, old.CancelledAt, old.CancelledBy, models.AppointmentBooked)
+	if err != nil {
+		return fmt.Errorf("repository: failed to reschedule appointment: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("repository: failed to reschedule appointment: %w", err)
+	} else if n == 0 {
+		return Errorf(ErrConflict, "repository: appointment is no longer booked")
+	}
+	if err := bookSlot(ctx, tx, replacement); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("repository: failed to commit reschedule: %w", err)
+	}
+	return nil
+}
+
+// ListDueReminders returns booked appointments starting between now and before that have not had
+// a reminder yet, earliest first.
+func (r *postgresAppointmentRepository) ListDueReminders(ctx context.Context, before time.Time) ([]models.Appointment, error) {
+	return r.queryAppointments(ctx, `SELECT `+appointmentColumns+` FROM appointments
+		WHERE status = $1 AND reminder_sent_at IS NULL AND starts_at > NOW() AND starts_at <= $2
+		ORDER BY starts_at`, models.AppointmentBooked, before)
+}
+
+// MarkReminderSent records that an appointment's reminder went out.
+func (r *postgresAppointmentRepository) MarkReminderSent(ctx context.Context, providerID, id uuid.UUID, at time.Time) error {
+	if _, err := r.db.ExecContext(ctx, `UPDATE appointments SET reminder_sent_at = $3 WHERE id = $1 AND provider_id = $2`, id, providerID, at); err != nil {
+		return fmt.Errorf("repository: failed to mark reminder sent: %w", err)
+	}
+	return nil
+}
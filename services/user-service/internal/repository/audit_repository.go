@@ -0,0 +1,145 @@
+// services/user-service/internal/repository/audit_repository.go
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// postgresAuditRepository is the PostgreSQL implementation of AuditRepository.
+type postgresAuditRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresAuditRepository creates an AuditRepository on an open pool and runs its migrations.
+func NewPostgresAuditRepository(db *sql.DB) (AuditRepository, error) {
+	repo := &postgresAuditRepository{db: db}
+	if err := repo.Migrate(); err != nil {
+		return nil, fmt.Errorf("failed to run audit migrations: %w", err)
+	}
+	return repo, nil
+}
+
+// Migrate applies the pending migrations in migrations/audit_events.
+func (r *postgresAuditRepository) Migrate() error {
+	return MigrateSchema(r.db, "audit_events")
+}
+
+// CreateEvent inserts a new audit event.
+func (r *postgresAuditRepository) CreateEvent(ctx context.Context, event *models.AuditEvent) error {
+	var details []byte
+	if len(event.Details) > 0 {
+		var err error
+		if details, err = json.Marshal(event.Details); err != nil {
+			return fmt.Errorf("repository: failed to encode audit event details: %w", err)
+		}
+	}
+	query := `INSERT INTO audit_events (id, action, outcome, actor_id, target_id, ip, user_agent, details, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+	_, err := r.db.ExecContext(ctx, query, event.ID, event.Action, event.Outcome, event.ActorID, event.TargetID, event.IP, event.UserAgent, details, event.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("repository: failed to create audit event: %w", err)
+	}
+	logger.FromContext(ctx).Debugf("Audit event recorded: %s (%s, %s)", event.ID, event.Action, event.Outcome)
+	return nil
+}
+
+// ListEvents returns audit events matching the filter, newest first.
+func (r *postgresAuditRepository) ListEvents(ctx context.Context, filter models.AuditEventFilter) ([]models.AuditEvent, error) {
+	var args []interface{}
+	query := `SELECT id, action, outcome, actor_id, target_id, ip, user_agent, details, created_at FROM audit_events WHERE TRUE`
+	where := func(clause string, value interface{}) {
+		args = append(args, value)
+		query += fmt.Sprintf(clause, len(args))
+	}
+	if filter.Action != "" {
+		where(` AND action = $%d`, filter.Action)
+	}
+	if filter.Outcome != "" {
+		where(` AND outcome = $%d`, filter.Outcome)
+	}
+	if filter.ActorID != "" {
+		where(` AND actor_id = $%d`, filter.ActorID)
+	}
+	if filter.TargetID != "" {
+		where(` AND target_id = $%d`, filter.TargetID)
+	}
+	if filter.IP != "" {
+		where(` AND ip = $%d`, filter.IP)
+	}
+	if !filter.Since.IsZero() {
+		where(` AND created_at >= $%d`, filter.Since)
+	}
+	if filter.After != nil {
+		where(` AND (created_at, id) < ($%d`, filter.After.At)
+		where(`, $%d)`, filter.After.ID)
+	}
+	where(` ORDER BY created_at DESC, id DESC LIMIT $%d`, filter.Limit)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to list audit events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []models.AuditEvent{}
+	for rows.Next() {
+		var e models.AuditEvent
+		var details []byte
+		if err := rows.Scan(&e.ID, &e.Action, &e.Outcome, &e.ActorID, &e.TargetID, &e.IP, &e.UserAgent, &details, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("repository: failed to scan audit event row: %w", err)
+		}
+		if len(details) > 0 {
+			if err := json.Unmarshal(details, &e.Details); err != nil {
+				return nil, fmt.Errorf("repository: failed to decode audit event details: %w", err)
+			}
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: rows iteration error: %w", err)
+	}
+	logger.FromContext(ctx).Debugf("Retrieved %d audit events from DB.", len(events))
+	return events, nil
+}
+
+// AnonymizeSubject replaces a user's ID with a pseudonym in every audit event naming them, as actor,
+// target, or a detail, and drops their email from details. Events the user took part in also lose
+// the IP and user agent; events of other actors that only mention the user keep theirs. The events
+// themselves are kept, so the log stays complete.
+func (r *postgresAuditRepository) AnonymizeSubject(ctx context.Context, userID, email, pseudonym string) (int64, error) {
+	query := `
+	WITH subject AS (
+		SELECT id, (actor_id = $1 OR target_id = $1 OR LOWER(details->>'email') = LOWER($2)) AS own
+		FROM audit_events
+		WHERE actor_id = $1 OR target_id = $1
+			OR EXISTS (SELECT 1 FROM jsonb_each_text(details) d WHERE d.value = $1 OR LOWER(d.value) = LOWER($2))
+	)
+	UPDATE audit_events a SET
+		actor_id = CASE WHEN a.actor_id = $1 THEN $3 ELSE a.actor_id END,
+		target_id = CASE WHEN a.target_id = $1 THEN $3 ELSE a.target_id END,
+		ip = CASE WHEN s.own THEN '' ELSE a.ip END,
+		user_agent = CASE WHEN s.own THEN '' ELSE a.user_agent END,
+		details = (
+			SELECT jsonb_object_agg(d.key, CASE WHEN d.value = to_jsonb($1::text) THEN to_jsonb($3::text) ELSE d.value END)
+			FROM jsonb_each(a.details) d
+			WHERE LOWER(d.value #>> '{}') IS DISTINCT FROM LOWER($2)
+		)
+	FROM subject s
+	WHERE a.id = s.id`
+	res, err := r.db.ExecContext(ctx, query, userID, email, pseudonym)
+	if err != nil {
+		return 0, fmt.Errorf("repository: failed to anonymize audit events: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("repository: failed to anonymize audit events: %w", err)
+	}
+	logger.FromContext(ctx).Debugf("Anonymized %d audit events", n)
+	return n, nil
+}
@@ -0,0 +1,164 @@
+// services/user-service/internal/repository/consent_repository.go
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+)
+
+// postgresConsentRepository is the PostgreSQL implementation of ConsentRepository.
+type postgresConsentRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresConsentRepository creates a ConsentRepository on an open pool and runs its migrations.
+// The users table must already exist.
+func NewPostgresConsentRepository(db *sql.DB) (ConsentRepository, error) {
+	repo := &postgresConsentRepository{db: db}
+	if err := repo.Migrate(); err != nil {
+		return nil, fmt.Errorf("failed to run consent migrations: %w", err)
+	}
+	return repo, nil
+}
+
+// Migrate applies the pending migrations in migrations/integration_consents.
+func (r *postgresConsentRepository) Migrate() error {
+	return MigrateSchema(r.db, "integration_consents")
+}
+
+const consentColumns = `id, user_id, provider, terms_version, imports, exports, ip, user_agent, accepted_at, revoked_at, delete_data`
+
+// scanConsent reads one row selected with consentColumns.
+func scanConsent(row interface{ Scan(...interface{}) error }) (*models.IntegrationConsent, error) {
+	var c models.IntegrationConsent
+	var imports, exports []byte
+	var revokedAt sql.NullTime
+	if err := row.Scan(&c.ID, &c.UserID, &c.Provider, &c.TermsVersion, &imports, &exports, &c.IP, &c.UserAgent, &c.AcceptedAt, &revokedAt, &c.DeleteData); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(imports, &c.Imports); err != nil {
+		return nil, fmt.Errorf("failed to decode imports: %w", err)
+	}
+	if err := json.Unmarshal(exports, &c.Exports); err != nil {
+		return nil, fmt.Errorf("failed to decode exports: %w", err)
+	}
+	if revokedAt.Valid {
+		c.RevokedAt = &revokedAt.Time
+	}
+	return &c, nil
+}
+
+// GrantConsent stores a new active consent, superseding the user's active consent for the provider
+// (e.g. to older terms) without asking for data deletion.
+func (r *postgresConsentRepository) GrantConsent(ctx context.Context, consent *models.IntegrationConsent) error {
+	imports, err := json.Marshal(consent.Imports)
+	if err != nil {
+		return fmt.Errorf("repository: failed to encode imports: %w", err)
+	}
+	exports, err := json.Marshal(consent.Exports)
+	if err != nil {
+		return fmt.Errorf("repository: failed to encode exports: %w", err)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("repository: failed to begin consent transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Lock the user so concurrent grants for the same provider are serialized.
+	var locked uuid.UUID
+	if err := tx.QueryRowContext(ctx, `SELECT id FROM users WHERE id = $1 FOR UPDATE`, consent.UserID).Scan(&locked); err != nil {
+		return fmt.Errorf("repository: failed to lock user for consent: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE integration_consents SET revoked_at = $3 WHERE user_id = $1 AND provider = $2 AND revoked_at IS NULL`,
+		consent.UserID, consent.Provider, consent.AcceptedAt); err != nil {
+		return fmt.Errorf("repository: failed to supersede consent: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO integration_consents (id, user_id, provider, terms_version, imports, exports, ip, user_agent, accepted_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		consent.ID, consent.UserID, consent.Provider, consent.TermsVersion, imports, exports, consent.IP, consent.UserAgent, consent.AcceptedAt); err != nil {
+		return fmt.Errorf("repository: failed to create consent: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("repository: failed to commit consent: %w", err)
+	}
+	return nil
+}
+
+// GetActiveConsent returns the user's unrevoked consent for a provider, or nil if there is none.
+func (r *postgresConsentRepository) GetActiveConsent(ctx context.Context, userID uuid.UUID, provider string) (*models.IntegrationConsent, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+consentColumns+` FROM integration_consents WHERE user_id = $1 AND provider = $2 AND revoked_at IS NULL`, userID, provider)
+	consent, err := scanConsent(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to get consent: %w", err)
+	}
+	return consent, nil
+}
+
+// ListConsents returns all of a user's consents, including revoked ones, newest first.
+func (r *postgresConsentRepository) ListConsents(ctx context.Context, userID uuid.UUID) ([]models.IntegrationConsent, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+consentColumns+` FROM integration_consents WHERE user_id = $1 ORDER BY accepted_at DESC`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to list consents: %w", err)
+	}
+	defer rows.Close()
+	return collectConsents(rows)
+}
+
+// RevokeConsent revokes the user's active consent for a provider and returns it, or nil if there was none.
+func (r *postgresConsentRepository) RevokeConsent(ctx context.Context, userID uuid.UUID, provider string, deleteData bool, at time.Time) (*models.IntegrationConsent, error) {
+	row := r.db.QueryRowContext(ctx, `UPDATE integration_consents SET revoked_at = $3, delete_data = $4
+		WHERE user_id = $1 AND provider = $2 AND revoked_at IS NULL RETURNING `+consentColumns, userID, provider, at, deleteData)
+	consent, err := scanConsent(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to revoke consent: %w", err)
+	}
+	return consent, nil
+}
+
+// ListRevocations returns consents revoked after filter.Since, oldest first, resuming after filter.After.
+func (r *postgresConsentRepository) ListRevocations(ctx context.Context, filter models.ConsentRevocationFilter) ([]models.IntegrationConsent, error) {
+	args := []interface{}{filter.Since}
+	query := `SELECT ` + consentColumns + ` FROM integration_consents WHERE revoked_at > $1`
+	if filter.After != nil {
+		args = append(args, filter.After.At, filter.After.ID)
+		query += fmt.Sprintf(` AND (revoked_at, id) > ($%d, $%d)`, len(args)-1, len(args))
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(` ORDER BY revoked_at, id LIMIT $%d`, len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to list consent revocations: %w", err)
+	}
+	defer rows.Close()
+	return collectConsents(rows)
+}
+
+func collectConsents(rows *sql.Rows) ([]models.IntegrationConsent, error) {
+	consents := []models.IntegrationConsent{}
+	for rows.Next() {
+		consent, err := scanConsent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("repository: failed to scan consent: %w", err)
+		}
+		consents = append(consents, *consent)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: failed to read consents: %w", err)
+	}
+	return consents, nil
+}
@@ -0,0 +1,77 @@
+// services/user-service/internal/repository/dashboard_repository.go
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// postgresDashboardRepository is the PostgreSQL implementation of DashboardRepository.
+type postgresDashboardRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresDashboardRepository creates a DashboardRepository on an open pool and runs its migrations.
+// The users table must already exist.
+func NewPostgresDashboardRepository(db *sql.DB) (DashboardRepository, error) {
+	repo := &postgresDashboardRepository{db: db}
+	if err := repo.Migrate(); err != nil {
+		return nil, fmt.Errorf("failed to run dashboard migrations: %w", err)
+	}
+	return repo, nil
+}
+
+// Migrate applies the pending migrations in migrations/dashboard_layouts.
+func (r *postgresDashboardRepository) Migrate() error {
+	return MigrateSchema(r.db, "dashboard_layouts")
+}
+
+// GetLayout retrieves a user's saved layout. It returns nil, nil if the user has not saved one.
+func (r *postgresDashboardRepository) GetLayout(ctx context.Context, userID uuid.UUID) (*models.DashboardLayout, error) {
+	var layout models.DashboardLayout
+	var widgets []byte
+	var updatedAt time.Time
+	err := r.db.QueryRowContext(ctx, `SELECT schema_version, widgets, updated_at FROM dashboard_layouts WHERE user_id = $1`, userID).
+		Scan(&layout.SchemaVersion, &widgets, &updatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("repository: failed to get dashboard layout: %w", err)
+	}
+	if err := json.Unmarshal(widgets, &layout.Widgets); err != nil {
+		return nil, fmt.Errorf("repository: failed to decode dashboard layout: %w", err)
+	}
+	layout.UpdatedAt = &updatedAt
+	return &layout, nil
+}
+
+// SaveLayout creates or replaces a user's layout.
+func (r *postgresDashboardRepository) SaveLayout(ctx context.Context, userID uuid.UUID, layout *models.DashboardLayout) error {
+	widgets, err := json.Marshal(layout.Widgets)
+	if err != nil {
+		return fmt.Errorf("repository: failed to encode dashboard layout: %w", err)
+	}
+	query := `INSERT INTO dashboard_layouts (user_id, schema_version, widgets, updated_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id) DO UPDATE SET schema_version = EXCLUDED.schema_version, widgets = EXCLUDED.widgets, updated_at = EXCLUDED.updated_at`
+	if _, err := r.db.ExecContext(ctx, query, userID, layout.SchemaVersion, widgets, layout.UpdatedAt); err != nil {
+		return fmt.Errorf("repository: failed to save dashboard layout: %w", err)
+	}
+	logger.FromContext(ctx).Debugf("Dashboard layout saved for user %s", userID)
+	return nil
+}
+
+// DeleteLayout removes a user's saved layout, if any.
+func (r *postgresDashboardRepository) DeleteLayout(ctx context.Context, userID uuid.UUID) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM dashboard_layouts WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("repository: failed to delete dashboard layout: %w", err)
+	}
+	return nil
+}
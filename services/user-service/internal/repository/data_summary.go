@@ -0,0 +1,79 @@
+// services/user-service/internal/repository/data_summary.go
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+)
+
+// SummarizeUserData returns what this database stores about a user, by category, with the same
+// tables as StorageUsage plus messaging, attachments, workout plans, and consents. Row sizes are
+// Postgres datum sizes; attachments also count the size of their uploaded content. It returns nil if
+// the user is missing.
+func (r *postgresUserRepository) SummarizeUserData(ctx context.Context, userID uuid.UUID) ([]models.DataCategory, error) {
+	query := `
+	SELECT $2::text, 1::bigint,
+		pg_column_size(u.*)::bigint
+		+ COALESCE((SELECT SUM(pg_column_size(t.*)) FROM user_timezone_history t WHERE t.user_id = u.id), 0)::bigint
+		+ COALESCE((SELECT SUM(pg_column_size(p.*)) FROM profile_prompt_dismissals p WHERE p.user_id = u.id), 0)::bigint
+		+ COALESCE((SELECT SUM(pg_column_size(a.*)) FROM aggregation_periods a WHERE a.user_id = u.id), 0)::bigint
+		+ COALESCE((SELECT SUM(pg_column_size(d.*)) FROM dashboard_layouts d WHERE d.user_id = u.id), 0)::bigint
+		+ COALESCE((SELECT SUM(pg_column_size(s.*)) FROM user_settings s WHERE s.user_id = u.id), 0)::bigint,
+		u.created_at, COALESCE(u.updated_at, u.created_at)
+	FROM users u WHERE u.id = $1
+	UNION ALL
+	SELECT $3::text, COUNT(*), COALESCE(SUM(pg_column_size(e.*)), 0)::bigint, MIN(occurred_at), MAX(occurred_at)
+	FROM user_events e WHERE user_id = $1
+	UNION ALL
+	SELECT $4::text, COUNT(*), COALESCE(SUM(pg_column_size(l.*)), 0)::bigint, MIN(created_at), MAX(created_at)
+	FROM login_attempts l WHERE user_id = $1
+	UNION ALL
+	SELECT $5::text, COUNT(*), COALESCE(SUM(pg_column_size(m.*)), 0)::bigint, MIN(created_at), MAX(created_at)
+	FROM messages m WHERE user_id = $1
+	UNION ALL
+	SELECT $6::text, COUNT(*), COALESCE(SUM(pg_column_size(a.*) + size), 0)::bigint, MIN(created_at), MAX(created_at)
+	FROM message_attachments a WHERE user_id = $1
+	UNION ALL
+	SELECT $7::text, COUNT(*), COALESCE(SUM(pg_column_size(w.*) + size), 0)::bigint, MIN(created_at), MAX(created_at)
+	FROM workout_attachments w WHERE user_id = $1
+	UNION ALL
+	SELECT $8::text, COUNT(*), COALESCE(SUM(pg_column_size(p.*)), 0)::bigint, MIN(created_at), MAX(created_at)
+	FROM workout_plans p WHERE coach_id = $1
+	UNION ALL
+	SELECT $9::text, COUNT(*), COALESCE(SUM(pg_column_size(c.*)), 0)::bigint, MIN(accepted_at), MAX(accepted_at)
+	FROM integration_consents c WHERE user_id = $1`
+	rows, err := r.db.QueryContext(ctx, query, userID,
+		models.DataCategoryProfile, models.DataCategoryTimeline, models.DataCategoryLoginHistory, models.DataCategoryMessages,
+		models.DataCategoryMessageAttachments, models.DataCategoryWorkoutAttachments, models.DataCategoryWorkoutPlans,
+		models.DataCategoryConsents)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to summarize user data: %w", err)
+	}
+	defer rows.Close()
+
+	var categories []models.DataCategory
+	found := false
+	for rows.Next() {
+		var c models.DataCategory
+		var oldest, newest sql.NullTime
+		if err := rows.Scan(&c.Category, &c.Count, &c.Bytes, &oldest, &newest); err != nil {
+			return nil, fmt.Errorf("repository: failed to scan user data summary: %w", err)
+		}
+		if oldest.Valid {
+			c.Oldest, c.Newest = &oldest.Time, &newest.Time
+		}
+		found = found || c.Category == models.DataCategoryProfile
+		categories = append(categories, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: failed to summarize user data: %w", err)
+	}
+	if !found {
+		return nil, nil
+	}
+	return categories, nil
+}
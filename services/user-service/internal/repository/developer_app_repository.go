@@ -0,0 +1,225 @@
+// services/user-service/internal/repository/developer_app_repository.go
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+)
+
+// postgresDeveloperAppRepository is the PostgreSQL implementation of DeveloperAppRepository.
+type postgresDeveloperAppRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresDeveloperAppRepository creates a DeveloperAppRepository on an open pool and runs its migrations.
+func NewPostgresDeveloperAppRepository(db *sql.DB) (DeveloperAppRepository, error) {
+	repo := &postgresDeveloperAppRepository{db: db}
+	if err := repo.Migrate(); err != nil {
+		return nil, fmt.Errorf("failed to run developer app migrations: %w", err)
+	}
+	return repo, nil
+}
+
+// Migrate applies the pending migrations in migrations/developer_apps.
+func (r *postgresDeveloperAppRepository) Migrate() error {
+	return MigrateSchema(r.db, "developer_apps")
+}
+
+const developerAppColumns = `id, owner_id, name, description, key_prefix, key_hash, created_at, key_rotated_at, revoked_at, debug_until`
+
+func scanDeveloperApp(row rowScanner, app *models.DeveloperApp) error {
+	return row.Scan(&app.ID, &app.OwnerID, &app.Name, &app.Description, &app.KeyPrefix, &app.KeyHash, &app.CreatedAt, &app.KeyRotatedAt, &app.RevokedAt, &app.DebugUntil)
+}
+
+// CreateApp inserts a developer app.
+func (r *postgresDeveloperAppRepository) CreateApp(ctx context.Context, app *models.DeveloperApp) error {
+	query := `INSERT INTO developer_apps (` + developerAppColumns + `) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+	_, err := r.db.ExecContext(ctx, query, app.ID, app.OwnerID, app.Name, app.Description, app.KeyPrefix, app.KeyHash, app.CreatedAt, app.KeyRotatedAt, app.RevokedAt, app.DebugUntil)
+	if err != nil {
+		return fmt.Errorf("repository: failed to create developer app: %w", err)
+	}
+	return nil
+}
+
+// GetApp returns a developer app by ID, or nil if it does not exist.
+func (r *postgresDeveloperAppRepository) GetApp(ctx context.Context, id uuid.UUID) (*models.DeveloperApp, error) {
+	return r.getApp(ctx, `SELECT `+developerAppColumns+` FROM developer_apps WHERE id = $1`, id)
+}
+
+// GetAppByKeyHash returns the developer app whose API key hashes to keyHash, or nil if none does.
+func (r *postgresDeveloperAppRepository) GetAppByKeyHash(ctx context.Context, keyHash string) (*models.DeveloperApp, error) {
+	return r.getApp(ctx, `SELECT `+developerAppColumns+` FROM developer_apps WHERE key_hash = $1`, keyHash)
+}
+
+func (r *postgresDeveloperAppRepository) getApp(ctx context.Context, query string, arg interface{}) (*models.DeveloperApp, error) {
+	var app models.DeveloperApp
+	if err := scanDeveloperApp(r.db.QueryRowContext(ctx, query, arg), &app); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("repository: failed to get developer app: %w", err)
+	}
+	return &app, nil
+}
+
+// ListApps returns a user's developer apps, oldest first, including revoked ones.
+func (r *postgresDeveloperAppRepository) ListApps(ctx context.Context, ownerID uuid.UUID) ([]models.DeveloperApp, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+developerAppColumns+` FROM developer_apps WHERE owner_id = $1 ORDER BY created_at, id`, ownerID)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to list developer apps: %w", err)
+	}
+	defer rows.Close()
+
+	apps := []models.DeveloperApp{}
+	for rows.Next() {
+		var app models.DeveloperApp
+		if err := scanDeveloperApp(rows, &app); err != nil {
+			return nil, fmt.Errorf("repository: failed to scan developer app row: %w", err)
+		}
+		apps = append(apps, app)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: rows iteration error: %w", err)
+	}
+	return apps, nil
+}
+
+// UpdateApp saves an app's key, revocation, and debug recording window.
+func (r *postgresDeveloperAppRepository) UpdateApp(ctx context.Context, app *models.DeveloperApp) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE developer_apps SET key_prefix = $2, key_hash = $3, key_rotated_at = $4, revoked_at = $5, debug_until = $6 WHERE id = $1`,
+		app.ID, app.KeyPrefix, app.KeyHash, app.KeyRotatedAt, app.RevokedAt, app.DebugUntil)
+	if err != nil {
+		return fmt.Errorf("repository: failed to update developer app: %w", err)
+	}
+	return nil
+}
+
+// DeleteOwnerApps deletes every app a user owns, with its usage, and returns how many there were.
+func (r *postgresDeveloperAppRepository) DeleteOwnerApps(ctx context.Context, ownerID uuid.UUID) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM developer_apps WHERE owner_id = $1`, ownerID)
+	if err != nil {
+		return 0, fmt.Errorf("repository: failed to delete developer apps: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("repository: failed to delete developer apps: %w", err)
+	}
+	return n, nil
+}
+
+// AddUsage adds the counts in usage to the app's totals for usage.Date and usage.Route.
+func (r *postgresDeveloperAppRepository) AddUsage(ctx context.Context, appID uuid.UUID, usage models.DeveloperAppUsage) error {
+	query := `INSERT INTO developer_app_usage (app_id, day, route, requests, client_errors, server_errors, throttled)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (app_id, day, route) DO UPDATE SET
+			requests = developer_app_usage.requests + EXCLUDED.requests,
+			client_errors = developer_app_usage.client_errors + EXCLUDED.client_errors,
+			server_errors = developer_app_usage.server_errors + EXCLUDED.server_errors,
+			throttled = developer_app_usage.throttled + EXCLUDED.throttled`
+	_, err := r.db.ExecContext(ctx, query, appID, usage.Date, usage.Route, usage.Requests, usage.ClientErrors, usage.ServerErrors, usage.Throttled)
+	if err != nil {
+		return fmt.Errorf("repository: failed to record developer app usage: %w", err)
+	}
+	return nil
+}
+
+// CountRequests returns the requests an app made on a UTC day, across routes.
+func (r *postgresDeveloperAppRepository) CountRequests(ctx context.Context, appID uuid.UUID, day time.Time) (int64, error) {
+	var n int64
+	err := r.db.QueryRowContext(ctx, `SELECT COALESCE(SUM(requests), 0) FROM developer_app_usage WHERE app_id = $1 AND day = $2`,
+		appID, day.Format(time.DateOnly)).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("repository: failed to count developer app requests: %w", err)
+	}
+	return n, nil
+}
+
+// ListUsage returns an app's usage on the UTC days since through until, inclusive, newest day first.
+func (r *postgresDeveloperAppRepository) ListUsage(ctx context.Context, appID uuid.UUID, since, until time.Time) ([]models.DeveloperAppUsage, error) {
+	query := `SELECT to_char(day, 'YYYY-MM-DD'), route, requests, client_errors, server_errors, throttled FROM developer_app_usage
+		WHERE app_id = $1 AND day BETWEEN $2 AND $3 ORDER BY day DESC, route`
+	rows, err := r.db.QueryContext(ctx, query, appID, since.Format(time.DateOnly), until.Format(time.DateOnly))
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to list developer app usage: %w", err)
+	}
+	defer rows.Close()
+
+	usage := []models.DeveloperAppUsage{}
+	for rows.Next() {
+		var u models.DeveloperAppUsage
+		if err := rows.Scan(&u.Date, &u.Route, &u.Requests, &u.ClientErrors, &u.ServerErrors, &u.Throttled); err != nil {
+			return nil, fmt.Errorf("repository: failed to scan developer app usage row: %w", err)
+		}
+		usage = append(usage, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: rows iteration error: %w", err)
+	}
+	return usage, nil
+}
+
+// AddRecording stores a recorded exchange of an app and drops its recordings beyond the newest keep.
+func (r *postgresDeveloperAppRepository) AddRecording(ctx context.Context, appID uuid.UUID, entry models.HAREntry, keep int) error {
+	raw, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("repository: failed to encode developer app recording: %w", err)
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("repository: failed to begin recording transaction: %w", err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `INSERT INTO developer_app_recordings (app_id, recorded_at, entry) VALUES ($1, $2, $3)`,
+		appID, entry.StartedDateTime, raw); err != nil {
+		return fmt.Errorf("repository: failed to store developer app recording: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, `DELETE FROM developer_app_recordings WHERE app_id = $1 AND id NOT IN (
+		SELECT id FROM developer_app_recordings WHERE app_id = $1 ORDER BY id DESC LIMIT $2)`, appID, keep)
+	if err != nil {
+		return fmt.Errorf("repository: failed to trim developer app recordings: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("repository: failed to commit developer app recording: %w", err)
+	}
+	return nil
+}
+
+// ListRecordings returns an app's recorded exchanges, oldest first.
+func (r *postgresDeveloperAppRepository) ListRecordings(ctx context.Context, appID uuid.UUID) ([]models.HAREntry, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT entry FROM developer_app_recordings WHERE app_id = $1 ORDER BY id`, appID)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to list developer app recordings: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []models.HAREntry{}
+	for rows.Next() {
+		var raw []byte
+		if err := rows.Scan(&raw); err != nil {
+			return nil, fmt.Errorf("repository: failed to scan developer app recording row: %w", err)
+		}
+		var entry models.HAREntry
+		if err := json.Unmarshal(raw, &entry); err != nil {
+			return nil, fmt.Errorf("repository: failed to decode developer app recording: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: rows iteration error: %w", err)
+	}
+	return entries, nil
+}
+
+// DeleteRecordings deletes every recorded exchange of an app.
+func (r *postgresDeveloperAppRepository) DeleteRecordings(ctx context.Context, appID uuid.UUID) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM developer_app_recordings WHERE app_id = $1`, appID); err != nil {
+		return fmt.Errorf("repository: failed to delete developer app recordings: %w", err)
+	}
+	return nil
+}
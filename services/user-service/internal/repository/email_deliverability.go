@@ -0,0 +1,86 @@
+// services/user-service/internal/repository/email_deliverability.go
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+)
+
+// RecordEmailDeliveryEvent applies a bounce or complaint about email to the user's email deliverability,
+// and returns the resulting status. It returns "" and changes nothing if email is no longer the user's,
+// so a late report about an old address does not suppress the new one. Suppressing the email also
+// clears its verification.
+func (r *postgresUserRepository) RecordEmailDeliveryEvent(ctx context.Context, userID uuid.UUID, email, kind string, at time.Time) (string, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("repository: failed to begin email delivery event: %w", err)
+	}
+	defer tx.Rollback()
+
+	var status string
+	var softBounces int
+	var statusAt *time.Time
+	err = tx.QueryRowContext(ctx, `SELECT email_status, email_soft_bounces, email_status_at FROM users WHERE id = $1 AND email = $2 FOR UPDATE`,
+		userID, email).Scan(&status, &softBounces, &statusAt)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("repository: failed to get email status: %w", err)
+	}
+
+	next, softBounces := models.NextEmailStatus(status, softBounces, statusAt, kind, at)
+	suppressed := next == models.EmailBounced || next == models.EmailComplained
+	_, err = tx.ExecContext(ctx, `UPDATE users SET email_status = $2, email_soft_bounces = $3, email_status_at = $4,
+		email_verified_at = CASE WHEN $5 THEN NULL ELSE email_verified_at END WHERE id = $1`, userID, next, softBounces, at, suppressed)
+	if err != nil {
+		return "", fmt.Errorf("repository: failed to update email status: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("repository: failed to commit email delivery event: %w", err)
+	}
+	return next, nil
+}
+
+// CreateEmailVerificationToken stores the hash of a token emailed to a user's current email.
+func (r *postgresUserRepository) CreateEmailVerificationToken(ctx context.Context, userID uuid.UUID, email, tokenHash string, expiresAt time.Time) error {
+	_, err := r.db.ExecContext(ctx, `INSERT INTO email_verification_tokens (token_hash, user_id, email, expires_at) VALUES ($1, $2, $3, $4)`,
+		tokenHash, userID, email, expiresAt.UTC())
+	if err != nil {
+		return fmt.Errorf("repository: failed to create email verification token: %w", err)
+	}
+	return nil
+}
+
+// ConsumeEmailVerificationToken marks an unused, unexpired token of the user as used and returns the
+// email it was sent to. It returns "" (and no error) if the token is invalid.
+func (r *postgresUserRepository) ConsumeEmailVerificationToken(ctx context.Context, userID uuid.UUID, tokenHash string) (string, error) {
+	var email string
+	err := r.db.QueryRowContext(ctx, `UPDATE email_verification_tokens SET used_at = NOW()
+		WHERE token_hash = $1 AND user_id = $2 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING email`, tokenHash, userID).Scan(&email)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("repository: failed to consume email verification token: %w", err)
+	}
+	return email, nil
+}
+
+// RestoreEmailDeliverability marks the user's email verified and deliverable again, lifting its
+// suppression. It returns false if email is no longer the user's.
+func (r *postgresUserRepository) RestoreEmailDeliverability(ctx context.Context, userID uuid.UUID, email string, at time.Time) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `UPDATE users SET email_status = 'ok', email_soft_bounces = 0, email_status_at = $3,
+		email_verified_at = COALESCE(email_verified_at, $3) WHERE id = $1 AND email = $2`, userID, email, at)
+	if err != nil {
+		return false, fmt.Errorf("repository: failed to restore email deliverability: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
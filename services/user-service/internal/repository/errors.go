@@ -0,0 +1,113 @@
+// services/user-service/internal/repository/errors.go
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// The outcomes callers act on are reported with these errors, wrapped in a message of the layer that
+// found them; callers compare with errors.Is rather than matching the message. Lookups by key still
+// return nil, nil for a missing row, which the services turn into ErrNotFound when they need the row.
+var (
+	ErrNotFound       = errors.New("not found")                    // The row an operation needs does not exist
+	ErrDuplicateEmail = errors.New("email already in use")         // The email, or another address of its mailbox, belongs to another user
+	ErrConflict       = errors.New("conflicts with current state") // Another row holds the value, or the row moved on, such as a booked slot
+
+	// ErrDuplicateUsername is the username belonging to another user. It is a kind of ErrConflict, which
+	// errors.Is matches it to as well, so callers that treat every conflict alike need not name it.
+	ErrDuplicateUsername error = &kindError{message: "username already taken", kind: ErrConflict}
+)
+
+// kindError is an error with its own message, which errors.Is matches to one of the errors above, or to
+// one of a service's own, and to the error its message wraps, if any.
+type kindError struct {
+	message string
+	kind    error
+	cause   error
+}
+
+func (e *kindError) Error() string { return e.message }
+
+func (e *kindError) Unwrap() []error {
+	if e.cause == nil {
+		return []error{e.kind}
+	}
+	return []error{e.kind, e.cause}
+}
+
+// Errorf returns an error with the formatted message that errors.Is matches to kind, ErrNotFound,
+// ErrDuplicateEmail, ErrDuplicateUsername, or ErrConflict. The message is kept as it is, so responses
+// that show it are unchanged. As with fmt.Errorf, a %w verb wraps its operand, which errors.Is and
+// errors.As then find too.
+func Errorf(kind error, format string, args ...any) error {
+	err := fmt.Errorf(format, args...)
+	return &kindError{message: err.Error(), kind: kind, cause: errors.Unwrap(err)}
+}
+
+// ChangeError is returned by UserRepository.ApplyUserChanges when one of the changes failed, so none
+// was applied. errors.Is matches it to the kind of Err.
+type ChangeError struct {
+	Index int // Of the change that failed
+	Err   error
+}
+
+func (e *ChangeError) Error() string { return fmt.Sprintf("change %d: %v", e.Index, e.Err) }
+func (e *ChangeError) Unwrap() error { return e.Err }
+
+// SQLSTATE codes of the PostgreSQL integrity constraint violations mapped to the errors above.
+const (
+	pgUniqueViolation     = "23505"
+	pgForeignKeyViolation = "23503"
+)
+
+// pgError returns the PostgreSQL error in err's chain, or nil if the server did not report one.
+func pgError(err error) *pgconn.PgError {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr
+	}
+	return nil
+}
+
+// uniqueViolation returns the unique constraint or index a PostgreSQL error refused a duplicate row for,
+// or "" if err is not a unique violation.
+func uniqueViolation(err error) string {
+	if pgErr := pgError(err); pgErr != nil && pgErr.Code == pgUniqueViolation {
+		return pgErr.ConstraintName
+	}
+	return ""
+}
+
+// constraintError returns ErrConflict if err is PostgreSQL refusing a row because another row holds one
+// of its unique values, and ErrNotFound if a row it refers to does not exist, with a message saying what
+// failed; otherwise it returns nil. These are the races of a write with another one the service checked for.
+func constraintError(err error, failed string) error {
+	pgErr := pgError(err)
+	if pgErr == nil {
+		return nil
+	}
+	switch pgErr.Code {
+	case pgUniqueViolation:
+		return Errorf(ErrConflict, "repository: failed to %s: already exists", failed)
+	case pgForeignKeyViolation:
+		return Errorf(ErrNotFound, "repository: failed to %s: a row it refers to does not exist", failed)
+	}
+	return nil
+}
+
+// duplicateUserError returns ErrDuplicateEmail or ErrDuplicateUsername, with a message saying what failed, if err
+// is PostgreSQL refusing a user's email or username because another user holds it, in the users table or
+// in the region directory; otherwise it returns nil. The services check both before writing, so this is
+// the race of two requests taking the same one.
+func duplicateUserError(err error, failed string) error {
+	switch uniqueViolation(err) {
+	case "users_email_key", "idx_users_email_key", "user_regions_email_hash_key", "user_regions_email_key_hash_key":
+		return Errorf(ErrDuplicateEmail, "repository: failed to %s: email already in use", failed)
+	case "idx_users_username", "user_regions_username_hash_key":
+		return Errorf(ErrDuplicateUsername, "repository: failed to %s: username already taken", failed)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+// services/user-service/internal/repository/event_relay.go
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// maxRelayBackoff caps the wait between attempts to reconnect the listening connection.
+const maxRelayBackoff = 30 * time.Second
+
+// postgresEventRelay sends payloads with NOTIFY on a channel and receives them with LISTEN, on a
+// connection of its own outside the pool, since it is held for as long as the service runs.
+type postgresEventRelay struct {
+	db      *sql.DB
+	config  *pgx.ConnConfig
+	channel string
+}
+
+// NewPostgresEventRelay creates an EventRelay over the PostgreSQL database of db, which must come from
+// NewPostgresDB, on the given notification channel. Payloads are limited to 8000 bytes by PostgreSQL.
+func NewPostgresEventRelay(db *sql.DB, channel string) (EventRelay, error) {
+	pool, ok := pgxPools.Load(db)
+	if !ok {
+		return nil, errors.New("repository: the event relay needs a database opened by NewPostgresDB")
+	}
+	return &postgresEventRelay{db: db, config: pool.(*pgxpool.Pool).Config().ConnConfig.Copy(), channel: channel}, nil
+}
+
+func (r *postgresEventRelay) Send(payload []byte) error {
+	if _, err := r.db.Exec(`SELECT pg_notify($1, $2)`, r.channel, string(payload)); err != nil {
+		return fmt.Errorf("repository: failed to send notification: %w", err)
+	}
+	return nil
+}
+
+func (r *postgresEventRelay) Listen(deliver func(payload []byte)) {
+	backoff := time.Second
+	for {
+		err := r.listen(deliver, func() { backoff = time.Second })
+		logger.Logger.Warnf("Event relay lost its connection to channel %s, reconnecting in %s: %v", r.channel, backoff, err)
+		time.Sleep(backoff)
+		backoff = min(2*backoff, maxRelayBackoff)
+	}
+}
+
+// listen connects, listens on the channel, and delivers notifications until the connection fails.
+// connected is called once listening has started.
+func (r *postgresEventRelay) listen(deliver func(payload []byte), connected func()) error {
+	ctx := context.Background()
+	conn, err := pgx.ConnectConfig(ctx, r.config)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(ctx)
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{r.channel}.Sanitize()); err != nil {
+		return err
+	}
+	connected()
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		deliver([]byte(n.Payload))
+	}
+}
@@ -0,0 +1,143 @@
+// services/user-service/internal/repository/health_sealing.go
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"health-tracker-project/services/user-service/internal/fieldcrypt"
+	"health-tracker-project/services/user-service/internal/models"
+)
+
+// HealthSealer seals users' health fields into users.health_sealed when set, so they are stored
+// encrypted; with nil they are stored in height_cm and date_of_birth as they are. Set it before any
+// repository reads or writes users. Once fields are sealed, the service needs it to read them back.
+var HealthSealer *fieldcrypt.Sealer
+
+// sealedHealth is the document sealed into users.health_sealed. Health fields added to users belong
+// here rather than in columns of their own, so they are never stored in plaintext.
+type sealedHealth struct {
+	HeightCM    *float64   `json:"height_cm,omitempty"`
+	DateOfBirth *time.Time `json:"date_of_birth,omitempty"`
+}
+
+// healthContext binds a user's sealed health fields to their row.
+func healthContext(userID uuid.UUID) string {
+	return "users.health_sealed:" + userID.String()
+}
+
+// sealHealth returns the health fields of user as they are written: with HealthSealer set, sealed,
+// and height_cm and date_of_birth nil; otherwise in plaintext, with nothing sealed.
+func sealHealth(user *models.User) (heightCM *float64, dateOfBirth *time.Time, sealed sql.NullString, err error) {
+	if HealthSealer == nil {
+		return user.HeightCM, user.DateOfBirth, sql.NullString{}, nil
+	}
+	doc, err := json.Marshal(sealedHealth{HeightCM: user.HeightCM, DateOfBirth: user.DateOfBirth})
+	if err != nil {
+		return nil, nil, sql.NullString{}, fmt.Errorf("repository: failed to encode health fields: %w", err)
+	}
+	value, err := HealthSealer.Seal(doc, healthContext(user.ID))
+	if err != nil {
+		return nil, nil, sql.NullString{}, fmt.Errorf("repository: failed to seal health fields: %w", err)
+	}
+	return nil, nil, sql.NullString{String: value, Valid: true}, nil
+}
+
+// openHealth fills in the health fields of a user read with userColumns from their sealed value, if
+// they have one; the fields of users not sealed yet were read from their own columns.
+func openHealth(user *models.User, sealed sql.NullString) error {
+	if !sealed.Valid {
+		return nil
+	}
+	if HealthSealer == nil {
+		return fmt.Errorf("repository: health fields of user %s are sealed, and no keys are configured to open them", user.ID)
+	}
+	doc, err := HealthSealer.Open(sealed.String, healthContext(user.ID))
+	if err != nil {
+		return fmt.Errorf("repository: failed to open health fields of user %s: %w", user.ID, err)
+	}
+	var health sealedHealth
+	if err := json.Unmarshal(doc, &health); err != nil {
+		return fmt.Errorf("repository: failed to decode health fields of user %s: %w", user.ID, err)
+	}
+	user.HeightCM, user.DateOfBirth = health.HeightCM, health.DateOfBirth
+	return nil
+}
+
+// resealedHealth returns the value a user's health_sealed is moved to by ResealHealthFields: their
+// sealed value under the current key, or their plaintext fields sealed for the first time.
+func resealedHealth(userID uuid.UUID, heightCM *float64, dateOfBirth *time.Time, sealed sql.NullString) (string, error) {
+	if sealed.Valid {
+		value, err := HealthSealer.Rewrap(sealed.String)
+		if err != nil {
+			return "", fmt.Errorf("repository: failed to reseal health fields of user %s: %w", userID, err)
+		}
+		return value, nil
+	}
+	_, _, value, err := sealHealth(&models.User{ID: userID, HeightCM: heightCM, DateOfBirth: dateOfBirth})
+	return value.String, err
+}
+
+// ResealHealthFields seals the health fields of up to limit users that are still in plaintext or sealed
+// under a previous key, and returns how many it changed. Users are read first and then updated one at
+// a time, each only if its sealed value is still the one read, so a user updated meanwhile is left as
+// written.
+func (r *postgresUserRepository) ResealHealthFields(ctx context.Context, limit int) (int, error) {
+	if HealthSealer == nil {
+		return 0, nil
+	}
+	// length is overloaded, so $1 needs a type.
+	query := `SELECT id, height_cm, date_of_birth, health_sealed FROM users
+		WHERE (health_sealed IS NULL AND (height_cm IS NOT NULL OR date_of_birth IS NOT NULL))
+			OR substr(health_sealed, 1, length($1::text)) <> $1::text
+		ORDER BY id LIMIT $2`
+	update := `UPDATE users SET health_sealed = $2, height_cm = NULL, date_of_birth = NULL
+		WHERE id = $1 AND health_sealed IS NOT DISTINCT FROM $3`
+	args := []any{HealthSealer.CurrentPrefix(), limit}
+
+	type pending struct {
+		id          uuid.UUID
+		was, sealed sql.NullString
+	}
+	var batch []pending
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("repository: failed to find health fields to reseal: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p pending
+		var heightCM *float64
+		var dateOfBirth *time.Time
+		if err := rows.Scan(&p.id, &heightCM, &dateOfBirth, &p.was); err != nil {
+			return 0, fmt.Errorf("repository: failed to scan health fields: %w", err)
+		}
+		value, err := resealedHealth(p.id, heightCM, dateOfBirth, p.was)
+		if err != nil {
+			return 0, err
+		}
+		p.sealed = sql.NullString{String: value, Valid: true}
+		batch = append(batch, p)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("repository: failed to find health fields to reseal: %w", err)
+	}
+	rows.Close()
+
+	resealed := 0
+	for _, p := range batch {
+		res, err := r.db.ExecContext(ctx, update, p.id, p.sealed, p.was)
+		if err != nil {
+			return resealed, fmt.Errorf("repository: failed to reseal health fields: %w", err)
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			resealed++
+		}
+	}
+	return resealed, nil
+}
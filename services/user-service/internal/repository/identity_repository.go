@@ -0,0 +1,160 @@
+// services/user-service/internal/repository/identity_repository.go
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+)
+
+// postgresIdentityRepository is the PostgreSQL implementation of IdentityRepository.
+type postgresIdentityRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresIdentityRepository creates an IdentityRepository on an open pool and runs its migrations.
+// The users table must already exist.
+func NewPostgresIdentityRepository(db *sql.DB) (IdentityRepository, error) {
+	repo := &postgresIdentityRepository{db: db}
+	if err := repo.Migrate(); err != nil {
+		return nil, fmt.Errorf("failed to run identity migrations: %w", err)
+	}
+	return repo, nil
+}
+
+// Migrate applies the pending migrations in migrations/identities.
+func (r *postgresIdentityRepository) Migrate() error {
+	return MigrateSchema(r.db, "identities")
+}
+
+// GetIdentity returns the linked identity for an issuer and subject, or nil if it is not linked.
+func (r *postgresIdentityRepository) GetIdentity(ctx context.Context, issuer, subject string) (*models.UserIdentity, error) {
+	query := `SELECT user_id, method, issuer, subject, email, linked_at FROM user_identities WHERE issuer = $1 AND subject = $2`
+	var identity models.UserIdentity
+	err := r.db.QueryRowContext(ctx, query, issuer, subject).Scan(&identity.UserID, &identity.Method, &identity.Issuer, &identity.Subject, &identity.Email, &identity.LinkedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to get identity: %w", err)
+	}
+	return &identity, nil
+}
+
+// ListIdentities returns the identities linked to a user, oldest first.
+func (r *postgresIdentityRepository) ListIdentities(ctx context.Context, userID uuid.UUID) ([]models.UserIdentity, error) {
+	query := `SELECT user_id, method, issuer, subject, email, linked_at FROM user_identities WHERE user_id = $1 ORDER BY linked_at`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to list identities: %w", err)
+	}
+	defer rows.Close()
+
+	identities := []models.UserIdentity{}
+	for rows.Next() {
+		var identity models.UserIdentity
+		if err := rows.Scan(&identity.UserID, &identity.Method, &identity.Issuer, &identity.Subject, &identity.Email, &identity.LinkedAt); err != nil {
+			return nil, fmt.Errorf("repository: failed to scan identity row: %w", err)
+		}
+		identities = append(identities, identity)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: rows iteration error: %w", err)
+	}
+	return identities, nil
+}
+
+// CreateIdentity links an identity to a user. It fails with ErrConflict if the identity is already linked,
+// and with ErrNotFound if the user does not exist.
+func (r *postgresIdentityRepository) CreateIdentity(ctx context.Context, identity *models.UserIdentity) error {
+	query := `INSERT INTO user_identities (issuer, subject, user_id, method, email, linked_at) VALUES ($1, $2, $3, $4, $5, $6)`
+	if _, err := r.db.ExecContext(ctx, query, identity.Issuer, identity.Subject, identity.UserID, identity.Method, identity.Email, identity.LinkedAt); err != nil {
+		if typed := constraintError(err, "create identity"); typed != nil {
+			return typed
+		}
+		return fmt.Errorf("repository: failed to create identity: %w", err)
+	}
+	return nil
+}
+
+// CreateLinkRequest stores a pending identity link.
+func (r *postgresIdentityRepository) CreateLinkRequest(ctx context.Context, req *models.IdentityLinkRequest) error {
+	query := `INSERT INTO identity_link_requests (id, token_hash, code_hash, user_id, method, issuer, subject, email, name, attempts, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
+	_, err := r.db.ExecContext(ctx, query, req.ID, req.TokenHash, req.CodeHash, req.UserID, req.Identity.Method, req.Identity.Issuer, req.Identity.Subject,
+		req.Identity.Email, req.Identity.Name, req.Attempts, req.ExpiresAt, req.CreatedAt)
+	if err != nil {
+		if typed := constraintError(err, "create identity link request"); typed != nil {
+			return typed
+		}
+		return fmt.Errorf("repository: failed to create identity link request: %w", err)
+	}
+	return nil
+}
+
+// GetLinkRequest returns the unexpired link request with the given token hash, or nil if there is none.
+func (r *postgresIdentityRepository) GetLinkRequest(ctx context.Context, tokenHash string) (*models.IdentityLinkRequest, error) {
+	query := `SELECT id, token_hash, code_hash, user_id, method, issuer, subject, email, name, attempts, expires_at, created_at
+		FROM identity_link_requests WHERE token_hash = $1 AND expires_at > NOW()`
+	var req models.IdentityLinkRequest
+	err := r.db.QueryRowContext(ctx, query, tokenHash).Scan(&req.ID, &req.TokenHash, &req.CodeHash, &req.UserID, &req.Identity.Method, &req.Identity.Issuer,
+		&req.Identity.Subject, &req.Identity.Email, &req.Identity.Name, &req.Attempts, &req.ExpiresAt, &req.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to get identity link request: %w", err)
+	}
+	return &req, nil
+}
+
+// IncrementLinkAttempts counts a failed proof against a link request.
+func (r *postgresIdentityRepository) IncrementLinkAttempts(ctx context.Context, id uuid.UUID) error {
+	if _, err := r.db.ExecContext(ctx, `UPDATE identity_link_requests SET attempts = attempts + 1 WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("repository: failed to update identity link request: %w", err)
+	}
+	return nil
+}
+
+// DeleteLinkRequest removes a link request, reporting whether it still existed.
+// Completing a link deletes its request first, so the same request cannot be completed twice.
+func (r *postgresIdentityRepository) DeleteLinkRequest(ctx context.Context, id uuid.UUID) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM identity_link_requests WHERE id = $1`, id)
+	if err != nil {
+		return false, fmt.Errorf("repository: failed to delete identity link request: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("repository: failed to delete identity link request: %w", err)
+	}
+	return n > 0, nil
+}
+
+// CreateSAMLRequest stores the ID of an AuthnRequest until expiresAt, and removes those already expired.
+func (r *postgresIdentityRepository) CreateSAMLRequest(ctx context.Context, id string, expiresAt time.Time) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM saml_requests WHERE expires_at <= NOW()`); err != nil {
+		return fmt.Errorf("repository: failed to delete expired SAML requests: %w", err)
+	}
+	if _, err := r.db.ExecContext(ctx, `INSERT INTO saml_requests (id, expires_at) VALUES ($1, $2)`, id, expiresAt); err != nil {
+		return fmt.Errorf("repository: failed to create SAML request: %w", err)
+	}
+	return nil
+}
+
+// ConsumeSAMLRequest deletes the ID of an AuthnRequest, reporting whether it was stored and unexpired.
+// Deleting it is what makes it single-use: of two responses to the same request, only one sees it.
+func (r *postgresIdentityRepository) ConsumeSAMLRequest(ctx context.Context, id string) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM saml_requests WHERE id = $1 AND expires_at > NOW()`, id)
+	if err != nil {
+		return false, fmt.Errorf("repository: failed to consume SAML request: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("repository: failed to consume SAML request: %w", err)
+	}
+	return n > 0, nil
+}
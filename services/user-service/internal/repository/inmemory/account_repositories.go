@@ -0,0 +1,407 @@
+// services/user-service/internal/repository/inmemory/account_repositories.go
+package inmemory
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/repository"
+)
+
+// identityKey is the primary key of a linked identity.
+type identityKey struct {
+	issuer, subject string
+}
+
+// DashboardRepository is the in-memory implementation of repository.DashboardRepository.
+type DashboardRepository struct {
+	db *DB
+}
+
+var _ repository.DashboardRepository = (*DashboardRepository)(nil)
+
+// NewDashboardRepository creates a DashboardRepository on db.
+func NewDashboardRepository(db *DB) *DashboardRepository {
+	return &DashboardRepository{db: db}
+}
+
+// Migrate does nothing; the tables exist as soon as the DB does.
+func (r *DashboardRepository) Migrate() error {
+	return nil
+}
+
+// GetLayout retrieves a user's saved layout. It returns nil, nil if the user has not saved one.
+func (r *DashboardRepository) GetLayout(ctx context.Context, userID uuid.UUID) (*models.DashboardLayout, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, fmt.Errorf("repository: failed to get layout: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	stored, ok := r.db.layouts[userID]
+	if !ok {
+		return nil, nil
+	}
+	return &models.DashboardLayout{
+		SchemaVersion: stored.SchemaVersion,
+		Widgets:       slices.Clone(stored.Widgets),
+		UpdatedAt:     copyTime(stored.UpdatedAt),
+	}, nil
+}
+
+// SaveLayout creates or replaces a user's layout.
+func (r *DashboardRepository) SaveLayout(ctx context.Context, userID uuid.UUID, layout *models.DashboardLayout) error {
+	if err := r.db.lock(ctx); err != nil {
+		return fmt.Errorf("repository: failed to save layout: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	if r.db.users[userID] == nil {
+		return fmt.Errorf("repository: failed to save dashboard layout: user %s does not exist", userID)
+	}
+	r.db.layouts[userID] = models.DashboardLayout{
+		SchemaVersion: layout.SchemaVersion,
+		Widgets:       slices.Clone(layout.Widgets),
+		UpdatedAt:     copyTime(layout.UpdatedAt),
+	}
+	return nil
+}
+
+// DeleteLayout removes a user's saved layout, if any.
+func (r *DashboardRepository) DeleteLayout(ctx context.Context, userID uuid.UUID) error {
+	if err := r.db.lock(ctx); err != nil {
+		return fmt.Errorf("repository: failed to delete layout: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	delete(r.db.layouts, userID)
+	return nil
+}
+
+// SettingsRepository is the in-memory implementation of repository.SettingsRepository.
+type SettingsRepository struct {
+	db *DB
+}
+
+var _ repository.SettingsRepository = (*SettingsRepository)(nil)
+
+// NewSettingsRepository creates a SettingsRepository on db.
+func NewSettingsRepository(db *DB) *SettingsRepository {
+	return &SettingsRepository{db: db}
+}
+
+// Migrate does nothing; the tables exist as soon as the DB does.
+func (r *SettingsRepository) Migrate() error {
+	return nil
+}
+
+// GetSettings retrieves the values a user has set. It returns nil, nil if the user has never saved settings.
+func (r *SettingsRepository) GetSettings(ctx context.Context, userID uuid.UUID) (*models.UserSettings, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, fmt.Errorf("repository: failed to get settings: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	stored, ok := r.db.settings[userID]
+	if !ok {
+		return nil, nil
+	}
+	return &models.UserSettings{Settings: maps.Clone(stored.Settings), UpdatedAt: copyTime(stored.UpdatedAt)}, nil
+}
+
+// SaveSettings creates or replaces the values a user has set.
+func (r *SettingsRepository) SaveSettings(ctx context.Context, userID uuid.UUID, settings *models.UserSettings) error {
+	if err := r.db.lock(ctx); err != nil {
+		return fmt.Errorf("repository: failed to save settings: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	if r.db.users[userID] == nil {
+		return fmt.Errorf("repository: failed to save user settings: user %s does not exist", userID)
+	}
+	r.db.settings[userID] = models.UserSettings{Settings: maps.Clone(settings.Settings), UpdatedAt: copyTime(settings.UpdatedAt)}
+	return nil
+}
+
+// IdentityRepository is the in-memory implementation of repository.IdentityRepository.
+type IdentityRepository struct {
+	db *DB
+}
+
+var _ repository.IdentityRepository = (*IdentityRepository)(nil)
+
+// NewIdentityRepository creates an IdentityRepository on db.
+func NewIdentityRepository(db *DB) *IdentityRepository {
+	return &IdentityRepository{db: db}
+}
+
+// Migrate does nothing; the tables exist as soon as the DB does.
+func (r *IdentityRepository) Migrate() error {
+	return nil
+}
+
+// GetIdentity returns the identity with the given issuer and subject, or nil if none is linked.
+func (r *IdentityRepository) GetIdentity(ctx context.Context, issuer, subject string) (*models.UserIdentity, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, fmt.Errorf("repository: failed to get identity: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	identity, ok := r.db.identities[identityKey{issuer, subject}]
+	if !ok {
+		return nil, nil
+	}
+	return &identity, nil
+}
+
+// ListIdentities returns the identities linked to a user, oldest first.
+func (r *IdentityRepository) ListIdentities(ctx context.Context, userID uuid.UUID) ([]models.UserIdentity, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, fmt.Errorf("repository: failed to list identities: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	identities := []models.UserIdentity{}
+	for _, identity := range r.db.identities {
+		if identity.UserID == userID {
+			identities = append(identities, identity)
+		}
+	}
+	sort.Slice(identities, func(i, j int) bool { return identities[i].LinkedAt.Before(identities[j].LinkedAt) })
+	return identities, nil
+}
+
+// CreateIdentity links an identity to a user.
+func (r *IdentityRepository) CreateIdentity(ctx context.Context, identity *models.UserIdentity) error {
+	if err := r.db.lock(ctx); err != nil {
+		return fmt.Errorf("repository: failed to create identity: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	key := identityKey{identity.Issuer, identity.Subject}
+	if r.db.users[identity.UserID] == nil {
+		return repository.Errorf(repository.ErrNotFound, "repository: failed to create identity: user %s does not exist", identity.UserID)
+	}
+	if _, ok := r.db.identities[key]; ok {
+		return repository.Errorf(repository.ErrConflict, "repository: failed to create identity: %s %s is already linked", identity.Issuer, identity.Subject)
+	}
+	r.db.identities[key] = *identity
+	return nil
+}
+
+// CreateLinkRequest stores a pending link of an identity to an existing user.
+func (r *IdentityRepository) CreateLinkRequest(ctx context.Context, req *models.IdentityLinkRequest) error {
+	if err := r.db.lock(ctx); err != nil {
+		return fmt.Errorf("repository: failed to create link request: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	if r.db.users[req.UserID] == nil {
+		return repository.Errorf(repository.ErrNotFound, "repository: failed to create identity link request: user %s does not exist", req.UserID)
+	}
+	for _, stored := range r.db.linkRequests {
+		if stored.ID == req.ID || stored.TokenHash == req.TokenHash {
+			return repository.Errorf(repository.ErrConflict, "repository: failed to create identity link request: already exists")
+		}
+	}
+	r.db.linkRequests[req.ID] = *req
+	return nil
+}
+
+// GetLinkRequest returns the unexpired link request with the given token hash, or nil if there is none.
+func (r *IdentityRepository) GetLinkRequest(ctx context.Context, tokenHash string) (*models.IdentityLinkRequest, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, fmt.Errorf("repository: failed to get link request: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	now := time.Now()
+	for _, req := range r.db.linkRequests {
+		if req.TokenHash == tokenHash && req.ExpiresAt.After(now) {
+			return &req, nil
+		}
+	}
+	return nil, nil
+}
+
+// IncrementLinkAttempts records a failed proof against a link request.
+func (r *IdentityRepository) IncrementLinkAttempts(ctx context.Context, id uuid.UUID) error {
+	if err := r.db.lock(ctx); err != nil {
+		return fmt.Errorf("repository: failed to increment link attempts: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	if req, ok := r.db.linkRequests[id]; ok {
+		req.Attempts++
+		r.db.linkRequests[id] = req
+	}
+	return nil
+}
+
+// DeleteLinkRequest removes a link request. It returns false if the request was already gone, so that
+// concurrent confirmations cannot both succeed.
+func (r *IdentityRepository) DeleteLinkRequest(ctx context.Context, id uuid.UUID) (bool, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return false, fmt.Errorf("repository: failed to delete link request: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	_, ok := r.db.linkRequests[id]
+	delete(r.db.linkRequests, id)
+	return ok, nil
+}
+
+// CreateSAMLRequest stores the ID of an AuthnRequest until expiresAt, and removes those already expired.
+func (r *IdentityRepository) CreateSAMLRequest(ctx context.Context, id string, expiresAt time.Time) error {
+	if err := r.db.lock(ctx); err != nil {
+		return fmt.Errorf("repository: failed to create SAML request: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	now := time.Now()
+	deleteWhere(r.db.samlRequests, func(expires time.Time) bool { return !expires.After(now) })
+	r.db.samlRequests[id] = expiresAt
+	return nil
+}
+
+// ConsumeSAMLRequest deletes the ID of an AuthnRequest, reporting whether it was stored and unexpired.
+func (r *IdentityRepository) ConsumeSAMLRequest(ctx context.Context, id string) (bool, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return false, fmt.Errorf("repository: failed to consume SAML request: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	expires, ok := r.db.samlRequests[id]
+	delete(r.db.samlRequests, id)
+	return ok && expires.After(time.Now()), nil
+}
+
+// SessionRepository is the in-memory implementation of repository.SessionRepository.
+type SessionRepository struct {
+	db *DB
+}
+
+var _ repository.SessionRepository = (*SessionRepository)(nil)
+
+// NewSessionRepository creates a SessionRepository on db.
+func NewSessionRepository(db *DB) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+// Migrate does nothing; the tables exist as soon as the DB does.
+func (r *SessionRepository) Migrate() error {
+	return nil
+}
+
+// CreateSession stores a session and, if maxPerUser is positive, evicts the user's oldest unexpired
+// sessions beyond that many. It returns the IDs of the sessions evicted.
+func (r *SessionRepository) CreateSession(ctx context.Context, session *models.Session, maxPerUser int) ([]uuid.UUID, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, fmt.Errorf("repository: failed to create session: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	if r.db.users[session.UserID] == nil {
+		return nil, fmt.Errorf("repository: failed to create session: user %s does not exist", session.UserID)
+	}
+	if _, ok := r.db.sessions[session.ID]; ok {
+		return nil, fmt.Errorf("repository: failed to create session: duplicate id %s", session.ID)
+	}
+	r.db.sessions[session.ID] = *session
+	if maxPerUser <= 0 {
+		return nil, nil
+	}
+
+	now := time.Now()
+	var active []models.Session
+	for _, s := range r.db.sessions {
+		if s.UserID == session.UserID && s.ExpiresAt.After(now) {
+			active = append(active, s)
+		}
+	}
+	sort.Slice(active, func(i, j int) bool {
+		if c := active[j].CreatedAt.Compare(active[i].CreatedAt); c != 0 {
+			return c < 0
+		}
+		return compareIDs(active[i].ID, active[j].ID) < 0
+	})
+	var evicted []uuid.UUID
+	for _, s := range active[min(maxPerUser, len(active)):] {
+		delete(r.db.sessions, s.ID)
+		evicted = append(evicted, s.ID)
+	}
+	return evicted, nil
+}
+
+// GetSession returns an unexpired session of a user by ID, or nil if it does not exist or has expired.
+func (r *SessionRepository) GetSession(ctx context.Context, userID, id uuid.UUID) (*models.Session, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, fmt.Errorf("repository: failed to get session: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	s, ok := r.db.sessions[id]
+	if !ok || s.UserID != userID || !s.ExpiresAt.After(time.Now()) {
+		return nil, nil
+	}
+	return &s, nil
+}
+
+// DeleteSession removes one session of a user.
+func (r *SessionRepository) DeleteSession(ctx context.Context, userID, id uuid.UUID) error {
+	if err := r.db.lock(ctx); err != nil {
+		return fmt.Errorf("repository: failed to delete session: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	if s, ok := r.db.sessions[id]; ok && s.UserID == userID {
+		delete(r.db.sessions, id)
+	}
+	return nil
+}
+
+// DeleteUserSessions removes every session of a user.
+func (r *SessionRepository) DeleteUserSessions(ctx context.Context, userID uuid.UUID) error {
+	if err := r.db.lock(ctx); err != nil {
+		return fmt.Errorf("repository: failed to delete user sessions: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	deleteWhere(r.db.sessions, func(s models.Session) bool { return s.UserID == userID })
+	return nil
+}
+
+// DeleteExpiredSessions removes sessions that expired before the given time and returns how many were removed.
+func (r *SessionRepository) DeleteExpiredSessions(ctx context.Context, before time.Time) (int64, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return 0, fmt.Errorf("repository: failed to delete expired sessions: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	n := deleteWhere(r.db.sessions, func(s models.Session) bool { return !s.ExpiresAt.After(before) })
+	return int64(n), nil
+}
+
+// CountActiveSessions returns the number of unexpired sessions and of users holding at least one.
+func (r *SessionRepository) CountActiveSessions(ctx context.Context) (int64, int64, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return 0, 0, fmt.Errorf("repository: failed to count active sessions: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	now := time.Now()
+	var sessions int64
+	users := make(map[uuid.UUID]bool)
+	for _, s := range r.db.sessions {
+		if s.ExpiresAt.After(now) {
+			sessions++
+			users[s.UserID] = true
+		}
+	}
+	return sessions, int64(len(users)), nil
+}
@@ -0,0 +1,244 @@
+// services/user-service/internal/repository/inmemory/db.go
+
+// Package inmemory implements the repository interfaces in process memory, for unit tests and for
+// running the service with --dev without PostgreSQL. Every repository of a DB shares its tables, so
+// what crosses tables in Postgres (cascading deletes, storage usage, the login history behind user
+// counts) behaves the same here. Nothing survives a restart, and migrations are no-ops.
+package inmemory
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+)
+
+// Options configures a DB.
+type Options struct {
+	// Latency delays every call, as a round trip to a real database would, so timeouts, load
+	// shedding, and loading states can be exercised in development. Zero answers at once.
+	Latency time.Duration
+}
+
+// DB holds the tables of the in-memory repositories. It is safe for concurrent use: each call holds
+// a single lock for its whole duration, so what is one transaction in Postgres is atomic here too.
+type DB struct {
+	mu      sync.Mutex
+	latency time.Duration
+
+	users              map[uuid.UUID]*userRow
+	resetTokens        map[string]*tokenRow // By token hash
+	emailTokens        map[string]*tokenRow // By token hash
+	timezones          map[uuid.UUID][]models.TimezonePeriod
+	merges             map[uuid.UUID]*models.UserMerge
+	aliases            map[string]*emailAlias // By email key
+	promptDismissals   map[uuid.UUID]map[string]models.ProfilePromptDismissal
+	periods            map[uuid.UUID]*models.AggregationPeriod
+	systemEvents       []models.SystemEvent
+	userEvents         []models.UserEvent
+	auditEvents        []models.AuditEvent
+	loginAttempts      []models.LoginAttempt
+	layouts            map[uuid.UUID]models.DashboardLayout
+	settings           map[uuid.UUID]models.UserSettings
+	identities         map[identityKey]models.UserIdentity
+	linkRequests       map[uuid.UUID]models.IdentityLinkRequest
+	samlRequests       map[string]time.Time // AuthnRequest ID → when it expires
+	sessions           map[uuid.UUID]models.Session
+	apps               map[uuid.UUID]models.DeveloperApp
+	appUsage           map[appUsageKey]models.DeveloperAppUsage
+	recordings         map[uuid.UUID][]models.HAREntry
+	meteringEvents     []models.MeteringEvent
+	meteringKeys       map[string]bool // Idempotency keys recorded
+	consents           map[uuid.UUID]*models.IntegrationConsent
+	coachAuths         map[coachKey]*models.CoachAuthorization
+	threads            map[uuid.UUID]*models.MessageThread
+	messages           map[uuid.UUID]*messageRow
+	messageAttachments map[uuid.UUID]*messageAttachmentRow
+	slots              map[uuid.UUID]*models.AppointmentSlot
+	appointments       map[uuid.UUID]*models.Appointment
+	workoutAttachments map[uuid.UUID]*models.WorkoutAttachment
+	workoutPlans       map[uuid.UUID]*models.WorkoutPlan
+	announcements      map[uuid.UUID]*announcementRow
+	outbox             map[uuid.UUID]*outboxRow
+}
+
+// NewDB creates an empty DB.
+func NewDB(opts Options) *DB {
+	return &DB{
+		latency:            opts.Latency,
+		users:              make(map[uuid.UUID]*userRow),
+		resetTokens:        make(map[string]*tokenRow),
+		emailTokens:        make(map[string]*tokenRow),
+		timezones:          make(map[uuid.UUID][]models.TimezonePeriod),
+		merges:             make(map[uuid.UUID]*models.UserMerge),
+		aliases:            make(map[string]*emailAlias),
+		promptDismissals:   make(map[uuid.UUID]map[string]models.ProfilePromptDismissal),
+		periods:            make(map[uuid.UUID]*models.AggregationPeriod),
+		layouts:            make(map[uuid.UUID]models.DashboardLayout),
+		settings:           make(map[uuid.UUID]models.UserSettings),
+		identities:         make(map[identityKey]models.UserIdentity),
+		linkRequests:       make(map[uuid.UUID]models.IdentityLinkRequest),
+		samlRequests:       make(map[string]time.Time),
+		sessions:           make(map[uuid.UUID]models.Session),
+		apps:               make(map[uuid.UUID]models.DeveloperApp),
+		appUsage:           make(map[appUsageKey]models.DeveloperAppUsage),
+		recordings:         make(map[uuid.UUID][]models.HAREntry),
+		meteringKeys:       make(map[string]bool),
+		consents:           make(map[uuid.UUID]*models.IntegrationConsent),
+		coachAuths:         make(map[coachKey]*models.CoachAuthorization),
+		threads:            make(map[uuid.UUID]*models.MessageThread),
+		messages:           make(map[uuid.UUID]*messageRow),
+		messageAttachments: make(map[uuid.UUID]*messageAttachmentRow),
+		slots:              make(map[uuid.UUID]*models.AppointmentSlot),
+		appointments:       make(map[uuid.UUID]*models.Appointment),
+		workoutAttachments: make(map[uuid.UUID]*models.WorkoutAttachment),
+		workoutPlans:       make(map[uuid.UUID]*models.WorkoutPlan),
+		announcements:      make(map[uuid.UUID]*announcementRow),
+		outbox:             make(map[uuid.UUID]*outboxRow),
+	}
+}
+
+// lock waits out the fake latency and takes the DB's lock, which the caller must release. It fails
+// without locking if ctx ends first, as a query would be cancelled.
+func (db *DB) lock(ctx context.Context) error {
+	if db.latency > 0 {
+		timer := time.NewTimer(db.latency)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	} else if err := ctx.Err(); err != nil {
+		return err
+	}
+	db.mu.Lock()
+	return nil
+}
+
+// deleteUser removes a user and, as the foreign keys of the Postgres schema cascade, every row
+// referencing them. Merge records, which reference no user, are left for the caller. The lock must be held.
+func (db *DB) deleteUser(id uuid.UUID) {
+	delete(db.users, id)
+	delete(db.timezones, id)
+	delete(db.promptDismissals, id)
+	delete(db.layouts, id)
+	delete(db.settings, id)
+	deleteWhere(db.resetTokens, func(t *tokenRow) bool { return t.userID == id })
+	deleteWhere(db.emailTokens, func(t *tokenRow) bool { return t.userID == id })
+	deleteWhere(db.aliases, func(a *emailAlias) bool { return a.userID == id })
+	deleteWhere(db.periods, func(p *models.AggregationPeriod) bool { return p.UserID == id })
+	db.userEvents = filter(db.userEvents, func(e models.UserEvent) bool { return e.UserID != id })
+	db.loginAttempts = filter(db.loginAttempts, func(a models.LoginAttempt) bool { return a.UserID != id })
+	deleteWhere(db.identities, func(i models.UserIdentity) bool { return i.UserID == id })
+	deleteWhere(db.linkRequests, func(r models.IdentityLinkRequest) bool { return r.UserID == id })
+	deleteWhere(db.sessions, func(s models.Session) bool { return s.UserID == id })
+	deleteWhere(db.consents, func(c *models.IntegrationConsent) bool { return c.UserID == id })
+	deleteWhere(db.coachAuths, func(a *models.CoachAuthorization) bool { return a.UserID == id })
+	for threadID, t := range db.threads {
+		if t.UserID == id {
+			db.deleteThread(threadID)
+		}
+	}
+	deleteWhere(db.messages, func(m *messageRow) bool { return m.userID == id })
+	deleteWhere(db.messageAttachments, func(a *messageAttachmentRow) bool { return a.userID == id })
+	for slotID, s := range db.slots {
+		if s.ProviderID == id {
+			db.deleteSlot(slotID)
+		}
+	}
+	deleteWhere(db.appointments, func(a *models.Appointment) bool { return a.ProviderID == id })
+	deleteWhere(db.workoutAttachments, func(a *models.WorkoutAttachment) bool { return a.UserID == id })
+	deleteWhere(db.workoutPlans, func(p *models.WorkoutPlan) bool { return p.CoachID == id })
+}
+
+// deleteWhere deletes the entries of m whose values match.
+func deleteWhere[K comparable, V any](m map[K]V, match func(V) bool) int {
+	n := 0
+	for k, v := range m {
+		if match(v) {
+			delete(m, k)
+			n++
+		}
+	}
+	return n
+}
+
+// filter returns the elements of s to keep, reusing its storage.
+func filter[T any](s []T, keep func(T) bool) []T {
+	kept := s[:0]
+	for _, v := range s {
+		if keep(v) {
+			kept = append(kept, v)
+		}
+	}
+	clear(s[len(kept):])
+	return kept
+}
+
+// limit cuts a list at n items, as SQL's LIMIT does.
+func limit[T any](s []T, n int) []T {
+	if n >= 0 && len(s) > n {
+		return s[:n]
+	}
+	return s
+}
+
+// compareIDs orders UUIDs as Postgres does, bytewise.
+func compareIDs(a, b uuid.UUID) int {
+	return bytes.Compare(a[:], b[:])
+}
+
+// newerFirst orders rows by time, then ID, both descending, as the ORDER BY of keyset-paginated lists.
+func newerFirst(atA time.Time, idA uuid.UUID, atB time.Time, idB uuid.UUID) int {
+	if c := atB.Compare(atA); c != 0 {
+		return c
+	}
+	return compareIDs(idB, idA)
+}
+
+// beforeKey reports whether a row comes after the page key in a newest-first list, that is whether
+// (at, id) < (key.At, key.ID). Every row does without a key.
+func beforeKey(at time.Time, id uuid.UUID, key *models.PageKey) bool {
+	if key == nil {
+		return true
+	}
+	if c := at.Compare(key.At); c != 0 {
+		return c < 0
+	}
+	return compareIDs(id, key.ID) < 0
+}
+
+// afterKey reports whether a row comes after the page key in an oldest-first list, that is whether
+// (at, id) > (key.At, key.ID). Every row does without a key.
+func afterKey(at time.Time, id uuid.UUID, key *models.PageKey) bool {
+	if key == nil {
+		return true
+	}
+	if c := at.Compare(key.At); c != 0 {
+		return c > 0
+	}
+	return compareIDs(id, key.ID) > 0
+}
+
+// rowSize stands in for the Postgres datum size of a row: the length of its JSON encoding.
+func rowSize(v interface{}) int64 {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return 0
+	}
+	return int64(len(data))
+}
+
+// copyTime returns a copy of t, so a stored row does not share it with the caller.
+func copyTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	c := *t
+	return &c
+}
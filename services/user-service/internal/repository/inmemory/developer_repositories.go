@@ -0,0 +1,346 @@
+// services/user-service/internal/repository/inmemory/developer_repositories.go
+package inmemory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/repository"
+)
+
+// appUsageKey is the primary key of a developer app's usage counts.
+type appUsageKey struct {
+	appID uuid.UUID
+	date  string // YYYY-MM-DD
+	route string
+}
+
+// DeveloperAppRepository is the in-memory implementation of repository.DeveloperAppRepository.
+type DeveloperAppRepository struct {
+	db *DB
+}
+
+var _ repository.DeveloperAppRepository = (*DeveloperAppRepository)(nil)
+
+// NewDeveloperAppRepository creates a DeveloperAppRepository on db.
+func NewDeveloperAppRepository(db *DB) *DeveloperAppRepository {
+	return &DeveloperAppRepository{db: db}
+}
+
+// Migrate does nothing; the tables exist as soon as the DB does.
+func (r *DeveloperAppRepository) Migrate() error {
+	return nil
+}
+
+// CreateApp stores a new developer app.
+func (r *DeveloperAppRepository) CreateApp(ctx context.Context, app *models.DeveloperApp) error {
+	if err := r.db.lock(ctx); err != nil {
+		return fmt.Errorf("repository: failed to create app: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	for _, stored := range r.db.apps {
+		if stored.ID == app.ID || stored.KeyHash == app.KeyHash {
+			return fmt.Errorf("repository: failed to create developer app: duplicate key")
+		}
+	}
+	r.db.apps[app.ID] = *app
+	return nil
+}
+
+// GetApp returns an app by ID, or nil if it does not exist.
+func (r *DeveloperAppRepository) GetApp(ctx context.Context, id uuid.UUID) (*models.DeveloperApp, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, fmt.Errorf("repository: failed to get app: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	app, ok := r.db.apps[id]
+	if !ok {
+		return nil, nil
+	}
+	return &app, nil
+}
+
+// GetAppByKeyHash returns the app holding a key, or nil if no app does.
+func (r *DeveloperAppRepository) GetAppByKeyHash(ctx context.Context, keyHash string) (*models.DeveloperApp, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, fmt.Errorf("repository: failed to get app by key hash: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	for _, app := range r.db.apps {
+		if app.KeyHash == keyHash {
+			return &app, nil
+		}
+	}
+	return nil, nil
+}
+
+// ListApps returns a user's apps, oldest first.
+func (r *DeveloperAppRepository) ListApps(ctx context.Context, ownerID uuid.UUID) ([]models.DeveloperApp, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, fmt.Errorf("repository: failed to list apps: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	apps := []models.DeveloperApp{}
+	for _, app := range r.db.apps {
+		if app.OwnerID == ownerID {
+			apps = append(apps, app)
+		}
+	}
+	sort.Slice(apps, func(i, j int) bool {
+		if c := apps[i].CreatedAt.Compare(apps[j].CreatedAt); c != 0 {
+			return c < 0
+		}
+		return compareIDs(apps[i].ID, apps[j].ID) < 0
+	})
+	return apps, nil
+}
+
+// UpdateApp saves an app's key, revocation, and debug window.
+func (r *DeveloperAppRepository) UpdateApp(ctx context.Context, app *models.DeveloperApp) error {
+	if err := r.db.lock(ctx); err != nil {
+		return fmt.Errorf("repository: failed to update app: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	stored, ok := r.db.apps[app.ID]
+	if !ok {
+		return nil
+	}
+	for _, other := range r.db.apps {
+		if other.ID != app.ID && other.KeyHash == app.KeyHash {
+			return fmt.Errorf("repository: failed to update developer app: duplicate key")
+		}
+	}
+	stored.KeyPrefix, stored.KeyHash = app.KeyPrefix, app.KeyHash
+	stored.KeyRotatedAt = copyTime(app.KeyRotatedAt)
+	stored.RevokedAt = copyTime(app.RevokedAt)
+	stored.DebugUntil = copyTime(app.DebugUntil)
+	r.db.apps[app.ID] = stored
+	return nil
+}
+
+// DeleteOwnerApps removes a user's apps with their usage and recordings, and returns how many apps were removed.
+func (r *DeveloperAppRepository) DeleteOwnerApps(ctx context.Context, ownerID uuid.UUID) (int64, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return 0, fmt.Errorf("repository: failed to delete owner apps: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	var n int64
+	for id, app := range r.db.apps {
+		if app.OwnerID != ownerID {
+			continue
+		}
+		delete(r.db.apps, id)
+		delete(r.db.recordings, id)
+		for key := range r.db.appUsage {
+			if key.appID == id {
+				delete(r.db.appUsage, key)
+			}
+		}
+		n++
+	}
+	return n, nil
+}
+
+// AddUsage adds to an app's counts for the usage's day and route.
+func (r *DeveloperAppRepository) AddUsage(ctx context.Context, appID uuid.UUID, usage models.DeveloperAppUsage) error {
+	if err := r.db.lock(ctx); err != nil {
+		return fmt.Errorf("repository: failed to add usage: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	if _, ok := r.db.apps[appID]; !ok {
+		return fmt.Errorf("repository: failed to record developer app usage: app %s does not exist", appID)
+	}
+	key := appUsageKey{appID, usage.Date, usage.Route}
+	stored, ok := r.db.appUsage[key]
+	if !ok {
+		stored = models.DeveloperAppUsage{Date: usage.Date, Route: usage.Route}
+	}
+	stored.Requests += usage.Requests
+	stored.ClientErrors += usage.ClientErrors
+	stored.ServerErrors += usage.ServerErrors
+	stored.Throttled += usage.Throttled
+	r.db.appUsage[key] = stored
+	return nil
+}
+
+// CountRequests returns how many requests an app made on a day.
+func (r *DeveloperAppRepository) CountRequests(ctx context.Context, appID uuid.UUID, day time.Time) (int64, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return 0, fmt.Errorf("repository: failed to count requests: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	date := day.Format(time.DateOnly)
+	var n int64
+	for key, usage := range r.db.appUsage {
+		if key.appID == appID && key.date == date {
+			n += usage.Requests
+		}
+	}
+	return n, nil
+}
+
+// ListUsage returns an app's usage for the days from since to until, latest day first.
+func (r *DeveloperAppRepository) ListUsage(ctx context.Context, appID uuid.UUID, since, until time.Time) ([]models.DeveloperAppUsage, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, fmt.Errorf("repository: failed to list usage: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	from, to := since.Format(time.DateOnly), until.Format(time.DateOnly)
+	usage := []models.DeveloperAppUsage{}
+	for key, u := range r.db.appUsage {
+		if key.appID == appID && key.date >= from && key.date <= to {
+			usage = append(usage, u)
+		}
+	}
+	sort.Slice(usage, func(i, j int) bool {
+		if usage[i].Date != usage[j].Date {
+			return usage[i].Date > usage[j].Date
+		}
+		return usage[i].Route < usage[j].Route
+	})
+	return usage, nil
+}
+
+// AddRecording stores a recorded exchange of an app and drops its recordings beyond the newest keep.
+func (r *DeveloperAppRepository) AddRecording(ctx context.Context, appID uuid.UUID, entry models.HAREntry, keep int) error {
+	if err := r.db.lock(ctx); err != nil {
+		return fmt.Errorf("repository: failed to add recording: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	if _, ok := r.db.apps[appID]; !ok {
+		return fmt.Errorf("repository: failed to store developer app recording: app %s does not exist", appID)
+	}
+	recordings := append(r.db.recordings[appID], entry)
+	r.db.recordings[appID] = slices.Clone(recordings[len(recordings)-min(max(keep, 0), len(recordings)):])
+	return nil
+}
+
+// ListRecordings returns an app's recorded exchanges, oldest first.
+func (r *DeveloperAppRepository) ListRecordings(ctx context.Context, appID uuid.UUID) ([]models.HAREntry, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, fmt.Errorf("repository: failed to list recordings: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	return append([]models.HAREntry{}, r.db.recordings[appID]...), nil
+}
+
+// DeleteRecordings removes all of an app's recorded exchanges.
+func (r *DeveloperAppRepository) DeleteRecordings(ctx context.Context, appID uuid.UUID) error {
+	if err := r.db.lock(ctx); err != nil {
+		return fmt.Errorf("repository: failed to delete recordings: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	delete(r.db.recordings, appID)
+	return nil
+}
+
+// MeteringRepository is the in-memory implementation of repository.MeteringRepository.
+type MeteringRepository struct {
+	db *DB
+}
+
+var _ repository.MeteringRepository = (*MeteringRepository)(nil)
+
+// NewMeteringRepository creates a MeteringRepository on db.
+func NewMeteringRepository(db *DB) *MeteringRepository {
+	return &MeteringRepository{db: db}
+}
+
+// Migrate does nothing; the tables exist as soon as the DB does.
+func (r *MeteringRepository) Migrate() error {
+	return nil
+}
+
+// RecordEvent appends a usage event. It returns false without storing anything when an event with
+// the same idempotency key was already recorded.
+func (r *MeteringRepository) RecordEvent(ctx context.Context, event *models.MeteringEvent) (bool, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return false, fmt.Errorf("repository: failed to record metering event: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	if r.db.meteringKeys[event.IdempotencyKey] {
+		return false, nil
+	}
+	r.db.meteringKeys[event.IdempotencyKey] = true
+	r.db.meteringEvents = append(r.db.meteringEvents, *event)
+	return true, nil
+}
+
+// Summarize totals the events that occurred in [filter.Since, filter.Until), per meter and dimension
+// and per user and meter.
+func (r *MeteringRepository) Summarize(ctx context.Context, filter models.MeteringFilter) ([]models.MeterTotal, []models.UserMeterTotal, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, nil, fmt.Errorf("repository: failed to summarize metering events: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	type meterKey struct{ meter, dimension string }
+	type userKey struct {
+		userID uuid.UUID
+		meter  string
+	}
+	meters := make(map[meterKey]*models.MeterTotal)
+	meterUsers := make(map[meterKey]map[uuid.UUID]bool)
+	users := make(map[userKey]*models.UserMeterTotal)
+	for _, e := range r.db.meteringEvents {
+		if e.OccurredAt.Before(filter.Since) || !e.OccurredAt.Before(filter.Until) || filter.UserID != uuid.Nil && e.UserID != filter.UserID {
+			continue
+		}
+		mk := meterKey{e.Meter, e.Dimension}
+		if meters[mk] == nil {
+			meters[mk] = &models.MeterTotal{Meter: e.Meter, Dimension: e.Dimension}
+			meterUsers[mk] = make(map[uuid.UUID]bool)
+		}
+		meters[mk].Events++
+		meters[mk].Quantity += e.Quantity
+		meterUsers[mk][e.UserID] = true
+
+		uk := userKey{e.UserID, e.Meter}
+		if users[uk] == nil {
+			users[uk] = &models.UserMeterTotal{UserID: e.UserID, Meter: e.Meter}
+		}
+		users[uk].Events++
+		users[uk].Quantity += e.Quantity
+	}
+
+	meterTotals := []models.MeterTotal{}
+	for mk, total := range meters {
+		total.Users = int64(len(meterUsers[mk]))
+		meterTotals = append(meterTotals, *total)
+	}
+	sort.Slice(meterTotals, func(i, j int) bool {
+		if meterTotals[i].Meter != meterTotals[j].Meter {
+			return meterTotals[i].Meter < meterTotals[j].Meter
+		}
+		return meterTotals[i].Dimension < meterTotals[j].Dimension
+	})
+	userTotals := []models.UserMeterTotal{}
+	for _, total := range users {
+		userTotals = append(userTotals, *total)
+	}
+	sort.Slice(userTotals, func(i, j int) bool {
+		if c := compareIDs(userTotals[i].UserID, userTotals[j].UserID); c != 0 {
+			return c < 0
+		}
+		return userTotals[i].Meter < userTotals[j].Meter
+	})
+	return meterTotals, userTotals, nil
+}
@@ -0,0 +1,399 @@
+// services/user-service/internal/repository/inmemory/event_repositories.go
+package inmemory
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/repository"
+)
+
+// SystemEventRepository is the in-memory implementation of repository.SystemEventRepository.
+type SystemEventRepository struct {
+	db *DB
+}
+
+var _ repository.SystemEventRepository = (*SystemEventRepository)(nil)
+
+// NewSystemEventRepository creates a SystemEventRepository on db.
+func NewSystemEventRepository(db *DB) *SystemEventRepository {
+	return &SystemEventRepository{db: db}
+}
+
+// Migrate does nothing; the tables exist as soon as the DB does.
+func (r *SystemEventRepository) Migrate() error {
+	return nil
+}
+
+// CreateEvent stores an operational event.
+func (r *SystemEventRepository) CreateEvent(ctx context.Context, event *models.SystemEvent) error {
+	if err := r.db.lock(ctx); err != nil {
+		return fmt.Errorf("repository: failed to create event: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	r.db.systemEvents = append(r.db.systemEvents, *event)
+	return nil
+}
+
+// ListEvents returns the events matching the filter, latest start first.
+func (r *SystemEventRepository) ListEvents(ctx context.Context, filter models.SystemEventFilter) ([]models.SystemEvent, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, fmt.Errorf("repository: failed to list events: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	events := []models.SystemEvent{}
+	for _, e := range r.db.systemEvents {
+		switch {
+		case filter.Type != "" && e.Type != filter.Type:
+		case !filter.Since.IsZero() && e.StartsAt.Before(filter.Since):
+		case !filter.Until.IsZero() && e.StartsAt.After(filter.Until):
+		case !beforeKey(e.StartsAt, e.ID, filter.After):
+		default:
+			events = append(events, e)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return newerFirst(events[i].StartsAt, events[i].ID, events[j].StartsAt, events[j].ID) < 0
+	})
+	return limit(events, filter.Limit), nil
+}
+
+// UserEventRepository is the in-memory implementation of repository.UserEventRepository.
+type UserEventRepository struct {
+	db *DB
+}
+
+var _ repository.UserEventRepository = (*UserEventRepository)(nil)
+
+// NewUserEventRepository creates a UserEventRepository on db.
+func NewUserEventRepository(db *DB) *UserEventRepository {
+	return &UserEventRepository{db: db}
+}
+
+// Migrate does nothing; the tables exist as soon as the DB does.
+func (r *UserEventRepository) Migrate() error {
+	return nil
+}
+
+// CreateEvent stores an event on a user's timeline.
+func (r *UserEventRepository) CreateEvent(ctx context.Context, event *models.UserEvent) error {
+	if err := r.db.lock(ctx); err != nil {
+		return fmt.Errorf("repository: failed to create event: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	if r.db.users[event.UserID] == nil {
+		return fmt.Errorf("repository: failed to create user event: user %s does not exist", event.UserID)
+	}
+	e := *event
+	e.Details = maps.Clone(event.Details)
+	r.db.userEvents = append(r.db.userEvents, e)
+	return nil
+}
+
+// ListEvents returns a page of a user's timeline, newest first, or oldest first with Since.
+func (r *UserEventRepository) ListEvents(ctx context.Context, filter models.UserEventFilter) ([]models.UserEvent, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, fmt.Errorf("repository: failed to list events: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	events := []models.UserEvent{}
+	for _, e := range r.db.userEvents {
+		if e.UserID != filter.UserID || len(filter.Types) > 0 && !slices.Contains(filter.Types, e.Type) ||
+			!beforeKey(e.OccurredAt, e.ID, filter.After) || !afterKey(e.OccurredAt, e.ID, filter.Since) {
+			continue
+		}
+		e.Details = maps.Clone(e.Details)
+		events = append(events, e)
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return newerFirst(events[i].OccurredAt, events[i].ID, events[j].OccurredAt, events[j].ID) < 0
+	})
+	if filter.Since != nil {
+		slices.Reverse(events)
+	}
+	return limit(events, filter.Limit), nil
+}
+
+// AuditRepository is the in-memory implementation of repository.AuditRepository.
+type AuditRepository struct {
+	db *DB
+}
+
+var _ repository.AuditRepository = (*AuditRepository)(nil)
+
+// NewAuditRepository creates an AuditRepository on db.
+func NewAuditRepository(db *DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+// Migrate does nothing; the tables exist as soon as the DB does.
+func (r *AuditRepository) Migrate() error {
+	return nil
+}
+
+// CreateEvent stores a security audit event.
+func (r *AuditRepository) CreateEvent(ctx context.Context, event *models.AuditEvent) error {
+	if err := r.db.lock(ctx); err != nil {
+		return fmt.Errorf("repository: failed to create event: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	e := *event
+	e.Details = maps.Clone(event.Details)
+	r.db.auditEvents = append(r.db.auditEvents, e)
+	return nil
+}
+
+// ListEvents returns the audit events matching the filter, newest first.
+func (r *AuditRepository) ListEvents(ctx context.Context, filter models.AuditEventFilter) ([]models.AuditEvent, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, fmt.Errorf("repository: failed to list events: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	events := []models.AuditEvent{}
+	for _, e := range r.db.auditEvents {
+		switch {
+		case filter.Action != "" && e.Action != filter.Action:
+		case filter.Outcome != "" && e.Outcome != filter.Outcome:
+		case filter.ActorID != "" && e.ActorID != filter.ActorID:
+		case filter.TargetID != "" && e.TargetID != filter.TargetID:
+		case filter.IP != "" && e.IP != filter.IP:
+		case !filter.Since.IsZero() && e.CreatedAt.Before(filter.Since):
+		case !beforeKey(e.CreatedAt, e.ID, filter.After):
+		default:
+			e.Details = maps.Clone(e.Details)
+			events = append(events, e)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return newerFirst(events[i].CreatedAt, events[i].ID, events[j].CreatedAt, events[j].ID) < 0
+	})
+	return limit(events, filter.Limit), nil
+}
+
+// AnonymizeSubject replaces a user's ID with pseudonym in the events about them, and drops their email,
+// and the IP and user agent of the events they are the actor or target of. It returns how many events
+// it changed.
+func (r *AuditRepository) AnonymizeSubject(ctx context.Context, userID, email, pseudonym string) (int64, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return 0, fmt.Errorf("repository: failed to anonymize subject: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	isEmail := func(value string) bool { return strings.EqualFold(value, email) }
+	var n int64
+	for i := range r.db.auditEvents {
+		e := &r.db.auditEvents[i]
+		mentioned := false
+		for _, value := range e.Details {
+			mentioned = mentioned || value == userID || isEmail(value)
+		}
+		if e.ActorID != userID && e.TargetID != userID && !mentioned {
+			continue
+		}
+		if e.ActorID == userID || e.TargetID == userID || isEmail(e.Details["email"]) {
+			e.IP, e.UserAgent = "", ""
+		}
+		if e.ActorID == userID {
+			e.ActorID = pseudonym
+		}
+		if e.TargetID == userID {
+			e.TargetID = pseudonym
+		}
+		var details map[string]string
+		for key, value := range e.Details {
+			if isEmail(value) {
+				continue
+			}
+			if value == userID {
+				value = pseudonym
+			}
+			if details == nil {
+				details = make(map[string]string)
+			}
+			details[key] = value
+		}
+		e.Details = details
+		n++
+	}
+	return n, nil
+}
+
+// LoginAttemptRepository is the in-memory implementation of repository.LoginAttemptRepository.
+type LoginAttemptRepository struct {
+	db *DB
+}
+
+var _ repository.LoginAttemptRepository = (*LoginAttemptRepository)(nil)
+
+// NewLoginAttemptRepository creates a LoginAttemptRepository on db.
+func NewLoginAttemptRepository(db *DB) *LoginAttemptRepository {
+	return &LoginAttemptRepository{db: db}
+}
+
+// Migrate does nothing; the tables exist as soon as the DB does.
+func (r *LoginAttemptRepository) Migrate() error {
+	return nil
+}
+
+// CreateAttempt stores a login attempt on a user's login history.
+func (r *LoginAttemptRepository) CreateAttempt(ctx context.Context, attempt *models.LoginAttempt) error {
+	if err := r.db.lock(ctx); err != nil {
+		return fmt.Errorf("repository: failed to create attempt: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	if r.db.users[attempt.UserID] == nil {
+		return fmt.Errorf("repository: failed to create login attempt: user %s does not exist", attempt.UserID)
+	}
+	r.db.loginAttempts = append(r.db.loginAttempts, *attempt)
+	return nil
+}
+
+// ListAttempts returns a page of a user's login history, newest first.
+func (r *LoginAttemptRepository) ListAttempts(ctx context.Context, filter models.LoginAttemptFilter) ([]models.LoginAttempt, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, fmt.Errorf("repository: failed to list attempts: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	attempts := []models.LoginAttempt{}
+	for _, a := range r.db.loginAttempts {
+		if a.UserID == filter.UserID && beforeKey(a.CreatedAt, a.ID, filter.After) {
+			attempts = append(attempts, a)
+		}
+	}
+	sort.Slice(attempts, func(i, j int) bool {
+		return newerFirst(attempts[i].CreatedAt, attempts[i].ID, attempts[j].CreatedAt, attempts[j].ID) < 0
+	})
+	return limit(attempts, filter.Limit), nil
+}
+
+// outboxRow is an event in the outbox, with the columns models.OutboxEvent does not carry.
+type outboxRow struct {
+	event       models.OutboxEvent
+	availableAt time.Time
+	sentAt      *time.Time
+}
+
+// announce writes an event about a user to the outbox, as the Postgres user repository does in the
+// transaction of the change. An event whose ID is already there is left as it is. The lock must be held.
+func (db *DB) announce(eventType string, userID uuid.UUID) {
+	event := repository.UserOutboxEvent(eventType, userID)
+	if db.outbox[event.ID] == nil {
+		db.outbox[event.ID] = &outboxRow{event: event, availableAt: event.OccurredAt}
+	}
+}
+
+// OutboxRepository is the in-memory implementation of repository.OutboxRepository.
+type OutboxRepository struct {
+	db *DB
+}
+
+var _ repository.OutboxRepository = (*OutboxRepository)(nil)
+
+// NewOutboxRepository creates an OutboxRepository on db.
+func NewOutboxRepository(db *DB) *OutboxRepository {
+	return &OutboxRepository{db: db}
+}
+
+// Migrate does nothing; the tables exist as soon as the DB does.
+func (r *OutboxRepository) Migrate() error {
+	return nil
+}
+
+// ClaimOutboxEvents leases up to limit pending events available at now until leaseUntil, and returns
+// them oldest first.
+func (r *OutboxRepository) ClaimOutboxEvents(ctx context.Context, now, leaseUntil time.Time, n int) ([]models.OutboxEvent, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, fmt.Errorf("repository: failed to claim outbox events: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	var pending []*outboxRow
+	for _, row := range r.db.outbox {
+		if row.sentAt == nil && !row.availableAt.After(now) {
+			pending = append(pending, row)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		a, b := pending[i].event, pending[j].event
+		if !a.OccurredAt.Equal(b.OccurredAt) {
+			return a.OccurredAt.Before(b.OccurredAt)
+		}
+		return compareIDs(a.ID, b.ID) < 0
+	})
+	events := []models.OutboxEvent{}
+	for _, row := range limit(pending, n) {
+		row.availableAt = leaseUntil
+		events = append(events, row.event)
+	}
+	return events, nil
+}
+
+// MarkOutboxEventSent records that an event was published.
+func (r *OutboxRepository) MarkOutboxEventSent(ctx context.Context, id uuid.UUID, at time.Time) error {
+	if err := r.db.lock(ctx); err != nil {
+		return fmt.Errorf("repository: failed to mark outbox event sent: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	if row := r.db.outbox[id]; row != nil {
+		row.sentAt = &at
+		row.event.LastError = ""
+	}
+	return nil
+}
+
+// FailOutboxEvent records a failed attempt to publish an event, which is claimable again from retryAt.
+func (r *OutboxRepository) FailOutboxEvent(ctx context.Context, id uuid.UUID, retryAt time.Time, lastError string) error {
+	if err := r.db.lock(ctx); err != nil {
+		return fmt.Errorf("repository: failed to record outbox event failure: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	if row := r.db.outbox[id]; row != nil && row.sentAt == nil {
+		row.availableAt = retryAt
+		row.event.Attempts++
+		row.event.LastError = lastError
+	}
+	return nil
+}
+
+// ReleaseOutboxEvents hands claimed events back, claimable again from at.
+func (r *OutboxRepository) ReleaseOutboxEvents(ctx context.Context, ids []uuid.UUID, at time.Time) error {
+	if err := r.db.lock(ctx); err != nil {
+		return fmt.Errorf("repository: failed to release outbox events: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	for _, id := range ids {
+		if row := r.db.outbox[id]; row != nil && row.sentAt == nil {
+			row.availableAt = at
+		}
+	}
+	return nil
+}
+
+// PurgeSentOutboxEvents deletes the events sent before a time and returns how many it deleted.
+func (r *OutboxRepository) PurgeSentOutboxEvents(ctx context.Context, before time.Time) (int64, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return 0, fmt.Errorf("repository: failed to purge sent outbox events: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	n := deleteWhere(r.db.outbox, func(row *outboxRow) bool { return row.sentAt != nil && row.sentAt.Before(before) })
+	return int64(n), nil
+}
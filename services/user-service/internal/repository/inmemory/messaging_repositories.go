@@ -0,0 +1,478 @@
+// services/user-service/internal/repository/inmemory/messaging_repositories.go
+package inmemory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/repository"
+)
+
+// coachKey is the primary key of a coach authorization.
+type coachKey struct {
+	userID, coachID uuid.UUID
+}
+
+// messageRow is a stored message and the user whose data it is stored with.
+type messageRow struct {
+	msg    models.Message // Without attachments; those are in DB.messageAttachments
+	userID uuid.UUID
+}
+
+// messageAttachmentRow is a stored attachment and the user whose data it is stored with.
+type messageAttachmentRow struct {
+	attachment models.MessageAttachment
+	userID     uuid.UUID
+}
+
+// deleteThread removes a thread with its messages and their attachments, as the foreign keys of the
+// Postgres schema cascade. The lock must be held.
+func (db *DB) deleteThread(id uuid.UUID) {
+	delete(db.threads, id)
+	deleteWhere(db.messages, func(m *messageRow) bool { return m.msg.ThreadID == id })
+	deleteWhere(db.messageAttachments, func(a *messageAttachmentRow) bool { return a.attachment.ThreadID == id })
+}
+
+// ConsentRepository is the in-memory implementation of repository.ConsentRepository.
+type ConsentRepository struct {
+	db *DB
+}
+
+var _ repository.ConsentRepository = (*ConsentRepository)(nil)
+
+// NewConsentRepository creates a ConsentRepository on db.
+func NewConsentRepository(db *DB) *ConsentRepository {
+	return &ConsentRepository{db: db}
+}
+
+// Migrate does nothing; the tables exist as soon as the DB does.
+func (r *ConsentRepository) Migrate() error {
+	return nil
+}
+
+// copyConsent returns a copy of c that shares nothing with it.
+func copyConsent(c *models.IntegrationConsent) *models.IntegrationConsent {
+	consent := *c
+	consent.Imports = slices.Clone(c.Imports)
+	consent.Exports = slices.Clone(c.Exports)
+	consent.RevokedAt = copyTime(c.RevokedAt)
+	return &consent
+}
+
+// activeConsent returns the user's unrevoked consent for a provider, or nil. The lock must be held.
+func (db *DB) activeConsent(userID uuid.UUID, provider string) *models.IntegrationConsent {
+	for _, c := range db.consents {
+		if c.UserID == userID && c.Provider == provider && c.RevokedAt == nil {
+			return c
+		}
+	}
+	return nil
+}
+
+// GrantConsent stores a new active consent, superseding the user's active consent for the provider
+// (e.g. to older terms) without asking for data deletion.
+func (r *ConsentRepository) GrantConsent(ctx context.Context, consent *models.IntegrationConsent) error {
+	if err := r.db.lock(ctx); err != nil {
+		return fmt.Errorf("repository: failed to grant consent: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	if r.db.users[consent.UserID] == nil {
+		return fmt.Errorf("repository: failed to lock user for consent: user %s does not exist", consent.UserID)
+	}
+	if _, ok := r.db.consents[consent.ID]; ok {
+		return fmt.Errorf("repository: failed to create consent: duplicate id %s", consent.ID)
+	}
+	if active := r.db.activeConsent(consent.UserID, consent.Provider); active != nil {
+		active.RevokedAt = copyTime(&consent.AcceptedAt)
+	}
+	stored := copyConsent(consent)
+	stored.RevokedAt, stored.DeleteData = nil, false
+	r.db.consents[consent.ID] = stored
+	return nil
+}
+
+// GetActiveConsent returns the user's unrevoked consent for a provider, or nil if there is none.
+func (r *ConsentRepository) GetActiveConsent(ctx context.Context, userID uuid.UUID, provider string) (*models.IntegrationConsent, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, fmt.Errorf("repository: failed to get active consent: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	if active := r.db.activeConsent(userID, provider); active != nil {
+		return copyConsent(active), nil
+	}
+	return nil, nil
+}
+
+// ListConsents returns every consent a user has given, newest first.
+func (r *ConsentRepository) ListConsents(ctx context.Context, userID uuid.UUID) ([]models.IntegrationConsent, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, fmt.Errorf("repository: failed to list consents: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	consents := []models.IntegrationConsent{}
+	for _, c := range r.db.consents {
+		if c.UserID == userID {
+			consents = append(consents, *copyConsent(c))
+		}
+	}
+	sort.Slice(consents, func(i, j int) bool { return consents[i].AcceptedAt.After(consents[j].AcceptedAt) })
+	return consents, nil
+}
+
+// RevokeConsent revokes the user's active consent for a provider and returns it, or nil if there was none.
+func (r *ConsentRepository) RevokeConsent(ctx context.Context, userID uuid.UUID, provider string, deleteData bool, at time.Time) (*models.IntegrationConsent, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, fmt.Errorf("repository: failed to revoke consent: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	active := r.db.activeConsent(userID, provider)
+	if active == nil {
+		return nil, nil
+	}
+	active.RevokedAt = &at
+	active.DeleteData = deleteData
+	return copyConsent(active), nil
+}
+
+// ListRevocations returns consents revoked after filter.Since, oldest first, resuming after filter.After.
+func (r *ConsentRepository) ListRevocations(ctx context.Context, filter models.ConsentRevocationFilter) ([]models.IntegrationConsent, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, fmt.Errorf("repository: failed to list revocations: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	consents := []models.IntegrationConsent{}
+	for _, c := range r.db.consents {
+		if c.RevokedAt == nil || !c.RevokedAt.After(filter.Since) {
+			continue
+		}
+		if filter.After != nil && (c.RevokedAt.Before(filter.After.At) ||
+			c.RevokedAt.Equal(filter.After.At) && compareIDs(c.ID, filter.After.ID) <= 0) {
+			continue
+		}
+		consents = append(consents, *copyConsent(c))
+	}
+	sort.Slice(consents, func(i, j int) bool {
+		return newerFirst(*consents[i].RevokedAt, consents[i].ID, *consents[j].RevokedAt, consents[j].ID) > 0
+	})
+	return limit(consents, filter.Limit), nil
+}
+
+// MessagingRepository is the in-memory implementation of repository.MessagingRepository.
+type MessagingRepository struct {
+	db *DB
+}
+
+var _ repository.MessagingRepository = (*MessagingRepository)(nil)
+
+// NewMessagingRepository creates a MessagingRepository on db.
+func NewMessagingRepository(db *DB) *MessagingRepository {
+	return &MessagingRepository{db: db}
+}
+
+// Migrate does nothing; the tables exist as soon as the DB does.
+func (r *MessagingRepository) Migrate() error {
+	return nil
+}
+
+// AuthorizeCoach grants a coach access to a user's data, restoring a revoked authorization.
+func (r *MessagingRepository) AuthorizeCoach(ctx context.Context, auth *models.CoachAuthorization) error {
+	if err := r.db.lock(ctx); err != nil {
+		return fmt.Errorf("repository: failed to authorize coach: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	if r.db.users[auth.UserID] == nil {
+		return fmt.Errorf("repository: failed to authorize coach: user %s does not exist", auth.UserID)
+	}
+	r.db.coachAuths[coachKey{auth.UserID, auth.CoachID}] = &models.CoachAuthorization{
+		UserID:       auth.UserID,
+		CoachID:      auth.CoachID,
+		AuthorizedAt: auth.AuthorizedAt,
+	}
+	return nil
+}
+
+// RevokeCoach revokes a coach's access. It returns false if the coach was not authorized.
+func (r *MessagingRepository) RevokeCoach(ctx context.Context, userID, coachID uuid.UUID, at time.Time) (bool, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return false, fmt.Errorf("repository: failed to revoke coach: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	auth := r.db.coachAuths[coachKey{userID, coachID}]
+	if auth == nil || auth.RevokedAt != nil {
+		return false, nil
+	}
+	auth.RevokedAt = &at
+	return true, nil
+}
+
+// IsCoachAuthorized reports whether a user currently authorizes a coach.
+func (r *MessagingRepository) IsCoachAuthorized(ctx context.Context, userID, coachID uuid.UUID) (bool, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return false, fmt.Errorf("repository: failed to check coach authorization: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	auth := r.db.coachAuths[coachKey{userID, coachID}]
+	return auth != nil && auth.RevokedAt == nil, nil
+}
+
+// listAuthorizations returns the authorizations matching, most recently authorized first.
+func (r *MessagingRepository) listAuthorizations(ctx context.Context, match func(*models.CoachAuthorization) bool) ([]models.CoachAuthorization, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, fmt.Errorf("repository: failed to list coach authorizations: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	auths := []models.CoachAuthorization{}
+	for _, auth := range r.db.coachAuths {
+		if match(auth) {
+			a := *auth
+			a.RevokedAt = copyTime(auth.RevokedAt)
+			auths = append(auths, a)
+		}
+	}
+	sort.Slice(auths, func(i, j int) bool { return auths[i].AuthorizedAt.After(auths[j].AuthorizedAt) })
+	return auths, nil
+}
+
+// ListCoaches returns the coaches a user has authorized, including revoked ones.
+func (r *MessagingRepository) ListCoaches(ctx context.Context, userID uuid.UUID) ([]models.CoachAuthorization, error) {
+	return r.listAuthorizations(ctx, func(a *models.CoachAuthorization) bool { return a.UserID == userID })
+}
+
+// ListClients returns the users who have authorized a coach, including revoked authorizations.
+func (r *MessagingRepository) ListClients(ctx context.Context, coachID uuid.UUID) ([]models.CoachAuthorization, error) {
+	return r.listAuthorizations(ctx, func(a *models.CoachAuthorization) bool { return a.CoachID == coachID })
+}
+
+// CreateThread stores a new thread.
+func (r *MessagingRepository) CreateThread(ctx context.Context, thread *models.MessageThread) error {
+	if err := r.db.lock(ctx); err != nil {
+		return fmt.Errorf("repository: failed to create thread: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	if r.db.users[thread.UserID] == nil {
+		return fmt.Errorf("repository: failed to create thread: user %s does not exist", thread.UserID)
+	}
+	if _, ok := r.db.threads[thread.ID]; ok {
+		return fmt.Errorf("repository: failed to create thread: duplicate id %s", thread.ID)
+	}
+	t := *thread
+	t.Unread = 0
+	r.db.threads[thread.ID] = &t
+	return nil
+}
+
+// GetThread returns a thread by ID, or nil if it does not exist.
+func (r *MessagingRepository) GetThread(ctx context.Context, id uuid.UUID) (*models.MessageThread, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, fmt.Errorf("repository: failed to get thread: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	t := r.db.threads[id]
+	if t == nil {
+		return nil, nil
+	}
+	thread := *t
+	return &thread, nil
+}
+
+// ListThreads returns the threads a user or coach takes part in, most recently active first, with
+// the number of messages from the other participant they have not read.
+func (r *MessagingRepository) ListThreads(ctx context.Context, participantID uuid.UUID) ([]models.MessageThread, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, fmt.Errorf("repository: failed to list threads: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	threads := []models.MessageThread{}
+	index := map[uuid.UUID]int{}
+	for _, t := range r.db.threads {
+		if t.UserID == participantID || t.CoachID == participantID {
+			index[t.ID] = len(threads)
+			threads = append(threads, *t)
+		}
+	}
+	for _, m := range r.db.messages {
+		if i, ok := index[m.msg.ThreadID]; ok && m.msg.SenderID != participantID && m.msg.ReadAt == nil {
+			threads[i].Unread++
+		}
+	}
+	sort.Slice(threads, func(i, j int) bool { return threads[i].LastMessageAt.After(threads[j].LastMessageAt) })
+	return threads, nil
+}
+
+// CreateMessage stores a message, attaches the sender's unsent uploads to it, and bumps the thread.
+// It fails, storing nothing, if any upload was already sent or removed.
+func (r *MessagingRepository) CreateMessage(ctx context.Context, userID uuid.UUID, msg *models.Message, attachmentIDs []uuid.UUID) error {
+	if err := r.db.lock(ctx); err != nil {
+		return fmt.Errorf("repository: failed to create message: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	thread := r.db.threads[msg.ThreadID]
+	switch {
+	case thread == nil:
+		return fmt.Errorf("repository: failed to create message: thread %s does not exist", msg.ThreadID)
+	case r.db.users[userID] == nil:
+		return fmt.Errorf("repository: failed to create message: user %s does not exist", userID)
+	case r.db.messages[msg.ID] != nil:
+		return fmt.Errorf("repository: failed to create message: duplicate id %s", msg.ID)
+	}
+
+	var attached []*messageAttachmentRow
+	for _, id := range attachmentIDs {
+		a := r.db.messageAttachments[id]
+		if a != nil && a.attachment.ThreadID == msg.ThreadID && a.attachment.UploaderID == msg.SenderID &&
+			a.attachment.MessageID == nil && !slices.Contains(attached, a) {
+			attached = append(attached, a)
+		}
+	}
+	if len(attached) != len(attachmentIDs) {
+		return repository.Errorf(repository.ErrConflict, "repository: attachment was already sent or removed")
+	}
+
+	stored := *msg
+	stored.Attachments, stored.ReadAt = nil, nil
+	r.db.messages[msg.ID] = &messageRow{msg: stored, userID: userID}
+	msg.Attachments = []models.MessageAttachment{}
+	for _, a := range attached {
+		messageID := msg.ID
+		a.attachment.MessageID = &messageID
+		msg.Attachments = append(msg.Attachments, a.attachment)
+	}
+	thread.LastMessageAt = msg.CreatedAt
+	return nil
+}
+
+// ListMessages returns a page of a thread's messages with their attachments, newest first.
+func (r *MessagingRepository) ListMessages(ctx context.Context, userID uuid.UUID, filter models.MessageFilter) ([]models.Message, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, fmt.Errorf("repository: failed to list messages: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	messages := []models.Message{}
+	for _, m := range r.db.messages {
+		if m.msg.ThreadID == filter.ThreadID && beforeKey(m.msg.CreatedAt, m.msg.ID, filter.After) {
+			msg := m.msg
+			msg.ReadAt = copyTime(m.msg.ReadAt)
+			msg.Attachments = []models.MessageAttachment{}
+			messages = append(messages, msg)
+		}
+	}
+	sort.Slice(messages, func(i, j int) bool {
+		return newerFirst(messages[i].CreatedAt, messages[i].ID, messages[j].CreatedAt, messages[j].ID) < 0
+	})
+	messages = limit(messages, filter.Limit)
+
+	index := map[uuid.UUID]int{}
+	for i, m := range messages {
+		index[m.ID] = i
+	}
+	var attachments []models.MessageAttachment
+	for _, a := range r.db.messageAttachments {
+		if a.attachment.MessageID != nil {
+			if _, ok := index[*a.attachment.MessageID]; ok {
+				attachments = append(attachments, a.attachment)
+			}
+		}
+	}
+	sort.Slice(attachments, func(i, j int) bool { return attachments[i].CreatedAt.Before(attachments[j].CreatedAt) })
+	for _, a := range attachments {
+		i := index[*a.MessageID]
+		messages[i].Attachments = append(messages[i].Attachments, a)
+	}
+	return messages, nil
+}
+
+// MarkRead sets the read receipt on every unread message in the thread not sent by the reader.
+func (r *MessagingRepository) MarkRead(ctx context.Context, userID, threadID, readerID uuid.UUID, at time.Time) (int64, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return 0, fmt.Errorf("repository: failed to mark read: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	var n int64
+	for _, m := range r.db.messages {
+		if m.msg.ThreadID == threadID && m.msg.SenderID != readerID && m.msg.ReadAt == nil {
+			readAt := at
+			m.msg.ReadAt = &readAt
+			n++
+		}
+	}
+	return n, nil
+}
+
+// CreateAttachment stores an upload to a thread, not yet part of a message.
+func (r *MessagingRepository) CreateAttachment(ctx context.Context, userID uuid.UUID, a *models.MessageAttachment) error {
+	if err := r.db.lock(ctx); err != nil {
+		return fmt.Errorf("repository: failed to create attachment: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	switch {
+	case r.db.threads[a.ThreadID] == nil:
+		return fmt.Errorf("repository: failed to create attachment: thread %s does not exist", a.ThreadID)
+	case r.db.users[userID] == nil:
+		return fmt.Errorf("repository: failed to create attachment: user %s does not exist", userID)
+	case r.db.messageAttachments[a.ID] != nil:
+		return fmt.Errorf("repository: failed to create attachment: duplicate id %s", a.ID)
+	}
+	attachment := *a
+	attachment.MessageID = nil
+	r.db.messageAttachments[a.ID] = &messageAttachmentRow{attachment: attachment, userID: userID}
+	return nil
+}
+
+// GetAttachment returns an attachment of a thread, or nil if it does not exist.
+func (r *MessagingRepository) GetAttachment(ctx context.Context, userID, threadID, id uuid.UUID) (*models.MessageAttachment, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, fmt.Errorf("repository: failed to get attachment: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	a := r.db.messageAttachments[id]
+	if a == nil || a.attachment.ThreadID != threadID {
+		return nil, nil
+	}
+	attachment := a.attachment
+	return &attachment, nil
+}
+
+// PurgeMessages deletes messages created before a cutoff, and uploads never sent that were made before
+// unsentBefore. It returns the blob keys of the deleted attachments, for removal from the blob store.
+func (r *MessagingRepository) PurgeMessages(ctx context.Context, before, unsentBefore time.Time) ([]string, int64, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, 0, fmt.Errorf("repository: failed to purge messages: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	keys := []string{}
+	for id, a := range r.db.messageAttachments {
+		expired := a.attachment.MessageID == nil && a.attachment.CreatedAt.Before(unsentBefore)
+		if m := a.attachment.MessageID; m != nil && r.db.messages[*m] != nil {
+			expired = r.db.messages[*m].msg.CreatedAt.Before(before)
+		}
+		if expired {
+			delete(r.db.messageAttachments, id)
+			keys = append(keys, a.attachment.BlobKey)
+		}
+	}
+	n := deleteWhere(r.db.messages, func(m *messageRow) bool { return m.msg.CreatedAt.Before(before) })
+	return keys, int64(n), nil
+}
@@ -0,0 +1,769 @@
+// services/user-service/internal/repository/inmemory/scheduling_repositories.go
+package inmemory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/repository"
+)
+
+// announcementRow is a stored announcement and the lease of the worker sending it.
+type announcementRow struct {
+	a          models.Announcement
+	leaseUntil *time.Time
+}
+
+// deleteSlot removes a slot; its appointments keep their time and location but lose the slot, as
+// the foreign key of the Postgres schema sets them null. The lock must be held.
+func (db *DB) deleteSlot(id uuid.UUID) {
+	delete(db.slots, id)
+	for _, a := range db.appointments {
+		if a.SlotID != nil && *a.SlotID == id {
+			a.SlotID = nil
+		}
+	}
+}
+
+// AppointmentRepository is the in-memory implementation of repository.AppointmentRepository.
+type AppointmentRepository struct {
+	db *DB
+}
+
+var _ repository.AppointmentRepository = (*AppointmentRepository)(nil)
+
+// NewAppointmentRepository creates an AppointmentRepository on db.
+func NewAppointmentRepository(db *DB) *AppointmentRepository {
+	return &AppointmentRepository{db: db}
+}
+
+// Migrate does nothing; the tables exist as soon as the DB does.
+func (r *AppointmentRepository) Migrate() error {
+	return nil
+}
+
+// CreateSlots publishes a provider's availability. It fails, storing nothing, if any slot overlaps
+// one the provider already has.
+func (r *AppointmentRepository) CreateSlots(ctx context.Context, providerID uuid.UUID, slots []models.AppointmentSlot) error {
+	if err := r.db.lock(ctx); err != nil {
+		return fmt.Errorf("repository: failed to create slots: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	if r.db.users[providerID] == nil {
+		return fmt.Errorf("repository: failed to lock provider: user %s does not exist", providerID)
+	}
+	overlaps := func(s models.AppointmentSlot, existing *models.AppointmentSlot) bool {
+		return existing.ProviderID == providerID && existing.StartsAt.Before(s.EndsAt) && existing.EndsAt.After(s.StartsAt)
+	}
+	for i, s := range slots {
+		if r.db.slots[s.ID] != nil {
+			return fmt.Errorf("repository: failed to create slot: duplicate id %s", s.ID)
+		}
+		for _, existing := range r.db.slots {
+			if overlaps(s, existing) {
+				return repository.Errorf(repository.ErrConflict, "repository: slots overlap existing availability")
+			}
+		}
+		for _, earlier := range slots[:i] {
+			if overlaps(s, &earlier) {
+				return repository.Errorf(repository.ErrConflict, "repository: slots overlap existing availability")
+			}
+		}
+	}
+	for _, s := range slots {
+		slot := s
+		slot.ProviderID, slot.Booked = providerID, false
+		r.db.slots[s.ID] = &slot
+	}
+	return nil
+}
+
+// slotBooked reports whether a booked appointment holds a slot. The lock must be held.
+func (db *DB) slotBooked(id uuid.UUID) bool {
+	for _, a := range db.appointments {
+		if a.SlotID != nil && *a.SlotID == id && a.Status == models.AppointmentBooked {
+			return true
+		}
+	}
+	return false
+}
+
+// ListSlots returns a provider's slots in the filter's range, earliest first.
+func (r *AppointmentRepository) ListSlots(ctx context.Context, filter models.SlotFilter) ([]models.AppointmentSlot, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, fmt.Errorf("repository: failed to list slots: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	slots := []models.AppointmentSlot{}
+	for _, s := range r.db.slots {
+		switch {
+		case s.ProviderID != filter.ProviderID:
+		case !filter.From.IsZero() && s.StartsAt.Before(filter.From):
+		case !filter.To.IsZero() && !s.StartsAt.Before(filter.To):
+		default:
+			slot := *s
+			slot.Booked = r.db.slotBooked(s.ID)
+			if !filter.OpenOnly || !slot.Booked {
+				slots = append(slots, slot)
+			}
+		}
+	}
+	sort.Slice(slots, func(i, j int) bool { return slots[i].StartsAt.Before(slots[j].StartsAt) })
+	return slots, nil
+}
+
+// GetSlot returns a slot, or nil if it does not exist.
+func (r *AppointmentRepository) GetSlot(ctx context.Context, id uuid.UUID) (*models.AppointmentSlot, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, fmt.Errorf("repository: failed to get slot: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	s := r.db.slots[id]
+	if s == nil {
+		return nil, nil
+	}
+	slot := *s
+	slot.Booked = r.db.slotBooked(id)
+	return &slot, nil
+}
+
+// DeleteSlot removes one of the provider's slots unless it is booked, and reports whether it did.
+func (r *AppointmentRepository) DeleteSlot(ctx context.Context, providerID, id uuid.UUID) (bool, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return false, fmt.Errorf("repository: failed to delete slot: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	s := r.db.slots[id]
+	if s == nil || s.ProviderID != providerID || r.db.slotBooked(id) {
+		return false, nil
+	}
+	r.db.deleteSlot(id)
+	return true, nil
+}
+
+// bookSlot books appointment.SlotID, copying the slot's time and location into the appointment.
+// The lock must be held.
+func (db *DB) bookSlot(a *models.Appointment) error {
+	var s *models.AppointmentSlot
+	if a.SlotID != nil {
+		s = db.slots[*a.SlotID]
+	}
+	if s == nil || s.ProviderID != a.ProviderID {
+		return repository.Errorf(repository.ErrNotFound, "repository: slot not found")
+	}
+	if db.slotBooked(s.ID) {
+		return repository.Errorf(repository.ErrConflict, "repository: slot is already booked")
+	}
+	if db.appointments[a.ID] != nil {
+		return fmt.Errorf("repository: failed to create appointment: duplicate id %s", a.ID)
+	}
+	a.StartsAt, a.EndsAt, a.Location = s.StartsAt, s.EndsAt, s.Location
+	db.appointments[a.ID] = copyAppointment(&models.Appointment{
+		ID:              a.ID,
+		SlotID:          a.SlotID,
+		ProviderID:      a.ProviderID,
+		UserID:          a.UserID,
+		StartsAt:        a.StartsAt,
+		EndsAt:          a.EndsAt,
+		Location:        a.Location,
+		Reason:          a.Reason,
+		Status:          a.Status,
+		RescheduledFrom: a.RescheduledFrom,
+		RescheduleCount: a.RescheduleCount,
+		CreatedAt:       a.CreatedAt,
+	})
+	return nil
+}
+
+// copyAppointment returns a copy of a that shares nothing with it.
+func copyAppointment(a *models.Appointment) *models.Appointment {
+	c := *a
+	c.SlotID = copyID(a.SlotID)
+	c.RescheduledFrom = copyID(a.RescheduledFrom)
+	c.CancelledAt = copyTime(a.CancelledAt)
+	c.CancelledBy = copyID(a.CancelledBy)
+	c.ReminderSentAt = copyTime(a.ReminderSentAt)
+	return &c
+}
+
+// copyID returns a copy of id, so a stored row does not share it with the caller.
+func copyID(id *uuid.UUID) *uuid.UUID {
+	if id == nil {
+		return nil
+	}
+	c := *id
+	return &c
+}
+
+// BookSlot stores a booked appointment for an open slot, filling in its time and location.
+func (r *AppointmentRepository) BookSlot(ctx context.Context, appointment *models.Appointment) error {
+	if err := r.db.lock(ctx); err != nil {
+		return fmt.Errorf("repository: failed to book slot: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	return r.db.bookSlot(appointment)
+}
+
+// GetAppointment returns an appointment, or nil if it does not exist.
+func (r *AppointmentRepository) GetAppointment(ctx context.Context, id uuid.UUID) (*models.Appointment, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, fmt.Errorf("repository: failed to get appointment: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	a := r.db.appointments[id]
+	if a == nil {
+		return nil, nil
+	}
+	return copyAppointment(a), nil
+}
+
+// ListAppointments returns the appointments matching the filter, earliest first.
+func (r *AppointmentRepository) ListAppointments(ctx context.Context, filter models.AppointmentFilter) ([]models.Appointment, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, fmt.Errorf("repository: failed to list appointments: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	appointments := []models.Appointment{}
+	for _, a := range r.db.appointments {
+		switch {
+		case filter.ProviderID != uuid.Nil && a.ProviderID != filter.ProviderID:
+		case filter.UserID != uuid.Nil && a.UserID != filter.UserID:
+		case !filter.From.IsZero() && a.StartsAt.Before(filter.From):
+		case !filter.To.IsZero() && !a.StartsAt.Before(filter.To):
+		case filter.Status != "" && a.Status != filter.Status:
+		default:
+			appointments = append(appointments, *copyAppointment(a))
+		}
+	}
+	sort.Slice(appointments, func(i, j int) bool { return appointments[i].StartsAt.Before(appointments[j].StartsAt) })
+	return appointments, nil
+}
+
+// CancelAppointment marks a booked appointment cancelled with a.CancelledAt, CancelledBy, and CancelReason,
+// and reports whether it was still booked. The slot opens up again.
+func (r *AppointmentRepository) CancelAppointment(ctx context.Context, a *models.Appointment) (bool, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return false, fmt.Errorf("repository: failed to cancel appointment: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	stored := r.db.appointments[a.ID]
+	if stored == nil || stored.Status != models.AppointmentBooked {
+		return false, nil
+	}
+	stored.Status = models.AppointmentCancelled
+	stored.CancelledAt = copyTime(a.CancelledAt)
+	stored.CancelledBy = copyID(a.CancelledBy)
+	stored.CancelReason = a.CancelReason
+	return true, nil
+}
+
+// RescheduleAppointment marks old rescheduled and books replacement in one step, so the booking is
+// never lost or doubled. Both must belong to the same provider.
+func (r *AppointmentRepository) RescheduleAppointment(ctx context.Context, old, replacement *models.Appointment) error {
+	if err := r.db.lock(ctx); err != nil {
+		return fmt.Errorf("repository: failed to reschedule appointment: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	stored := r.db.appointments[old.ID]
+	if stored == nil || stored.Status != models.AppointmentBooked {
+		return repository.Errorf(repository.ErrConflict, "repository: appointment is no longer booked")
+	}
+	before := *copyAppointment(stored)
+	stored.Status = models.AppointmentRescheduled
+	stored.CancelledAt = copyTime(old.CancelledAt)
+	stored.CancelledBy = copyID(old.CancelledBy)
+	if err := r.db.bookSlot(replacement); err != nil {
+		*stored = before
+		return err
+	}
+	return nil
+}
+
+// ListDueReminders returns booked appointments starting between now and before that have not had
+// a reminder yet, earliest first.
+func (r *AppointmentRepository) ListDueReminders(ctx context.Context, before time.Time) ([]models.Appointment, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, fmt.Errorf("repository: failed to list due reminders: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	now := time.Now()
+	appointments := []models.Appointment{}
+	for _, a := range r.db.appointments {
+		if a.Status == models.AppointmentBooked && a.ReminderSentAt == nil && a.StartsAt.After(now) && !a.StartsAt.After(before) {
+			appointments = append(appointments, *copyAppointment(a))
+		}
+	}
+	sort.Slice(appointments, func(i, j int) bool { return appointments[i].StartsAt.Before(appointments[j].StartsAt) })
+	return appointments, nil
+}
+
+// MarkReminderSent records that an appointment's reminder went out.
+func (r *AppointmentRepository) MarkReminderSent(ctx context.Context, providerID, id uuid.UUID, at time.Time) error {
+	if err := r.db.lock(ctx); err != nil {
+		return fmt.Errorf("repository: failed to mark reminder sent: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	if a := r.db.appointments[id]; a != nil && a.ProviderID == providerID {
+		a.ReminderSentAt = &at
+	}
+	return nil
+}
+
+// WorkoutAttachmentRepository is the in-memory implementation of repository.WorkoutAttachmentRepository.
+type WorkoutAttachmentRepository struct {
+	db *DB
+}
+
+var _ repository.WorkoutAttachmentRepository = (*WorkoutAttachmentRepository)(nil)
+
+// NewWorkoutAttachmentRepository creates a WorkoutAttachmentRepository on db.
+func NewWorkoutAttachmentRepository(db *DB) *WorkoutAttachmentRepository {
+	return &WorkoutAttachmentRepository{db: db}
+}
+
+// Migrate does nothing; the tables exist as soon as the DB does.
+func (r *WorkoutAttachmentRepository) Migrate() error {
+	return nil
+}
+
+// copyWorkoutAttachment returns a copy of a that shares nothing with it.
+func copyWorkoutAttachment(a *models.WorkoutAttachment) *models.WorkoutAttachment {
+	c := *a
+	c.ScannedAt = copyTime(a.ScannedAt)
+	c.ExpiresAt = copyTime(a.ExpiresAt)
+	return &c
+}
+
+// CreateAttachment records an upload whose content is already in the blob store.
+func (r *WorkoutAttachmentRepository) CreateAttachment(ctx context.Context, a *models.WorkoutAttachment) error {
+	if err := r.db.lock(ctx); err != nil {
+		return fmt.Errorf("repository: failed to create attachment: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	switch {
+	case r.db.users[a.UserID] == nil:
+		return fmt.Errorf("repository: failed to create workout attachment: user %s does not exist", a.UserID)
+	case r.db.workoutAttachments[a.ID] != nil:
+		return fmt.Errorf("repository: failed to create workout attachment: duplicate id %s", a.ID)
+	}
+	r.db.workoutAttachments[a.ID] = copyWorkoutAttachment(a)
+	return nil
+}
+
+// GetAttachment returns one of the user's attachments, or nil if it does not exist.
+func (r *WorkoutAttachmentRepository) GetAttachment(ctx context.Context, userID, id uuid.UUID) (*models.WorkoutAttachment, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, fmt.Errorf("repository: failed to get attachment: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	a := r.db.workoutAttachments[id]
+	if a == nil || a.UserID != userID {
+		return nil, nil
+	}
+	return copyWorkoutAttachment(a), nil
+}
+
+// ListAttachments returns the user's attachments, newest first, optionally for one set and only those
+// shared with coaches.
+func (r *WorkoutAttachmentRepository) ListAttachments(ctx context.Context, userID uuid.UUID, setRef string, sharedOnly bool) ([]models.WorkoutAttachment, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, fmt.Errorf("repository: failed to list attachments: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	attachments := []models.WorkoutAttachment{}
+	for _, a := range r.db.workoutAttachments {
+		if a.UserID == userID && (setRef == "" || a.SetRef == setRef) && (!sharedOnly || a.SharedWithCoaches) {
+			attachments = append(attachments, *copyWorkoutAttachment(a))
+		}
+	}
+	sort.Slice(attachments, func(i, j int) bool { return attachments[i].CreatedAt.After(attachments[j].CreatedAt) })
+	return attachments, nil
+}
+
+// UpdateAttachment saves the sharing flag and expiry, reporting whether the attachment exists.
+func (r *WorkoutAttachmentRepository) UpdateAttachment(ctx context.Context, a *models.WorkoutAttachment) (bool, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return false, fmt.Errorf("repository: failed to update attachment: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	stored := r.db.workoutAttachments[a.ID]
+	if stored == nil || stored.UserID != a.UserID {
+		return false, nil
+	}
+	stored.SharedWithCoaches = a.SharedWithCoaches
+	stored.ExpiresAt = copyTime(a.ExpiresAt)
+	return true, nil
+}
+
+// DeleteAttachment deletes one of the user's attachments and returns its blob key, or "" if it did not exist.
+func (r *WorkoutAttachmentRepository) DeleteAttachment(ctx context.Context, userID, id uuid.UUID) (string, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return "", fmt.Errorf("repository: failed to delete attachment: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	a := r.db.workoutAttachments[id]
+	if a == nil || a.UserID != userID {
+		return "", nil
+	}
+	delete(r.db.workoutAttachments, id)
+	return a.BlobKey, nil
+}
+
+// ListPendingScans returns up to limit attachments awaiting a virus scan, oldest first.
+func (r *WorkoutAttachmentRepository) ListPendingScans(ctx context.Context, n int) ([]models.WorkoutAttachment, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, fmt.Errorf("repository: failed to list pending scans: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	attachments := []models.WorkoutAttachment{}
+	for _, a := range r.db.workoutAttachments {
+		if a.ScanStatus == models.ScanPending {
+			attachments = append(attachments, *copyWorkoutAttachment(a))
+		}
+	}
+	sort.Slice(attachments, func(i, j int) bool { return attachments[i].CreatedAt.Before(attachments[j].CreatedAt) })
+	return limit(attachments, n), nil
+}
+
+// SetScanStatus records the outcome of a virus scan.
+func (r *WorkoutAttachmentRepository) SetScanStatus(ctx context.Context, userID, id uuid.UUID, status string, at time.Time) error {
+	if err := r.db.lock(ctx); err != nil {
+		return fmt.Errorf("repository: failed to set scan status: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	if a := r.db.workoutAttachments[id]; a != nil && a.UserID == userID {
+		a.ScanStatus = status
+		a.ScannedAt = &at
+	}
+	return nil
+}
+
+// PurgeExpired deletes attachments that expired before the cutoff and returns their blob keys.
+func (r *WorkoutAttachmentRepository) PurgeExpired(ctx context.Context, before time.Time) ([]string, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, fmt.Errorf("repository: failed to purge expired attachments: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	keys := []string{}
+	for id, a := range r.db.workoutAttachments {
+		if a.ExpiresAt != nil && a.ExpiresAt.Before(before) {
+			delete(r.db.workoutAttachments, id)
+			keys = append(keys, a.BlobKey)
+		}
+	}
+	return keys, nil
+}
+
+// WorkoutPlanRepository is the in-memory implementation of repository.WorkoutPlanRepository.
+type WorkoutPlanRepository struct {
+	db *DB
+}
+
+var _ repository.WorkoutPlanRepository = (*WorkoutPlanRepository)(nil)
+
+// NewWorkoutPlanRepository creates a WorkoutPlanRepository on db.
+func NewWorkoutPlanRepository(db *DB) *WorkoutPlanRepository {
+	return &WorkoutPlanRepository{db: db}
+}
+
+// Migrate does nothing; the tables exist as soon as the DB does.
+func (r *WorkoutPlanRepository) Migrate() error {
+	return nil
+}
+
+// copyWorkoutPlan returns a copy of p that shares nothing with it.
+func copyWorkoutPlan(p *models.WorkoutPlan) *models.WorkoutPlan {
+	c := *p
+	c.Sessions = make([]models.WorkoutPlanSession, len(p.Sessions))
+	for i, s := range p.Sessions {
+		s.Exercises = slices.Clone(s.Exercises)
+		c.Sessions[i] = s
+	}
+	if p.SourceID != nil {
+		id := *p.SourceID
+		c.SourceID = &id
+	}
+	c.ImportedAt = copyTime(p.ImportedAt)
+	return &c
+}
+
+// SavePlans inserts the coach's plans, replacing those whose ID exists, all or none.
+func (r *WorkoutPlanRepository) SavePlans(ctx context.Context, coachID uuid.UUID, plans []models.WorkoutPlan) error {
+	if err := r.db.lock(ctx); err != nil {
+		return fmt.Errorf("repository: failed to save workout plans: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	if r.db.users[coachID] == nil {
+		return fmt.Errorf("repository: failed to save workout plans: user %s does not exist", coachID)
+	}
+	for _, p := range plans {
+		if p.CoachID != coachID {
+			return fmt.Errorf("repository: workout plan %s belongs to another coach", p.ID)
+		}
+		if stored := r.db.workoutPlans[p.ID]; stored != nil && stored.CoachID != coachID {
+			return fmt.Errorf("repository: failed to save workout plan %s: id taken by another coach", p.ID)
+		}
+	}
+	for _, p := range plans {
+		c := copyWorkoutPlan(&p)
+		if stored := r.db.workoutPlans[p.ID]; stored != nil {
+			c.CreatedAt = stored.CreatedAt
+		}
+		r.db.workoutPlans[p.ID] = c
+	}
+	return nil
+}
+
+// GetPlan returns one of the coach's plans, or nil if it does not exist.
+func (r *WorkoutPlanRepository) GetPlan(ctx context.Context, coachID, id uuid.UUID) (*models.WorkoutPlan, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, fmt.Errorf("repository: failed to get workout plan: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	p := r.db.workoutPlans[id]
+	if p == nil || p.CoachID != coachID {
+		return nil, nil
+	}
+	return copyWorkoutPlan(p), nil
+}
+
+// ListPlans returns the coach's plans, oldest first.
+func (r *WorkoutPlanRepository) ListPlans(ctx context.Context, coachID uuid.UUID) ([]models.WorkoutPlan, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, fmt.Errorf("repository: failed to list workout plans: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	plans := []models.WorkoutPlan{}
+	for _, p := range r.db.workoutPlans {
+		if p.CoachID == coachID {
+			plans = append(plans, *copyWorkoutPlan(p))
+		}
+	}
+	sort.Slice(plans, func(i, j int) bool {
+		if !plans[i].CreatedAt.Equal(plans[j].CreatedAt) {
+			return plans[i].CreatedAt.Before(plans[j].CreatedAt)
+		}
+		return compareIDs(plans[i].ID, plans[j].ID) < 0
+	})
+	return plans, nil
+}
+
+// DeletePlan deletes one of the coach's plans, reporting whether it existed.
+func (r *WorkoutPlanRepository) DeletePlan(ctx context.Context, coachID, id uuid.UUID) (bool, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return false, fmt.Errorf("repository: failed to delete workout plan: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	p := r.db.workoutPlans[id]
+	if p == nil || p.CoachID != coachID {
+		return false, nil
+	}
+	delete(r.db.workoutPlans, id)
+	return true, nil
+}
+
+// AnnouncementRepository is the in-memory implementation of repository.AnnouncementRepository.
+type AnnouncementRepository struct {
+	db *DB
+}
+
+var _ repository.AnnouncementRepository = (*AnnouncementRepository)(nil)
+
+// NewAnnouncementRepository creates an AnnouncementRepository on db.
+func NewAnnouncementRepository(db *DB) *AnnouncementRepository {
+	return &AnnouncementRepository{db: db}
+}
+
+// Migrate does nothing; the tables exist as soon as the DB does.
+func (r *AnnouncementRepository) Migrate() error {
+	return nil
+}
+
+// copyAnnouncement returns a copy of a that shares nothing with it.
+func copyAnnouncement(a *models.Announcement) *models.Announcement {
+	c := *a
+	c.StartedAt = copyTime(a.StartedAt)
+	c.FinishedAt = copyTime(a.FinishedAt)
+	return &c
+}
+
+// CreateAnnouncement stores a scheduled announcement.
+func (r *AnnouncementRepository) CreateAnnouncement(ctx context.Context, a *models.Announcement) error {
+	if err := r.db.lock(ctx); err != nil {
+		return fmt.Errorf("repository: failed to create announcement: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	if r.db.announcements[a.ID] != nil {
+		return fmt.Errorf("repository: failed to create announcement: duplicate id %s", a.ID)
+	}
+	stored := models.Announcement{
+		ID:        a.ID,
+		Title:     a.Title,
+		Body:      a.Body,
+		Segment:   a.Segment,
+		Status:    a.Status,
+		SendAt:    a.SendAt,
+		CreatedBy: a.CreatedBy,
+		CreatedAt: a.CreatedAt,
+	}
+	r.db.announcements[a.ID] = &announcementRow{a: stored}
+	return nil
+}
+
+// GetAnnouncement returns an announcement, or nil if it does not exist.
+func (r *AnnouncementRepository) GetAnnouncement(ctx context.Context, id uuid.UUID) (*models.Announcement, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, fmt.Errorf("repository: failed to get announcement: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	row := r.db.announcements[id]
+	if row == nil {
+		return nil, nil
+	}
+	return copyAnnouncement(&row.a), nil
+}
+
+// ListAnnouncements returns a page of announcements, newest first.
+func (r *AnnouncementRepository) ListAnnouncements(ctx context.Context, filter models.AnnouncementFilter) ([]models.Announcement, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, fmt.Errorf("repository: failed to list announcements: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	announcements := []models.Announcement{}
+	for _, row := range r.db.announcements {
+		if (filter.Status == "" || row.a.Status == filter.Status) && beforeKey(row.a.CreatedAt, row.a.ID, filter.After) {
+			announcements = append(announcements, *copyAnnouncement(&row.a))
+		}
+	}
+	sort.Slice(announcements, func(i, j int) bool {
+		return newerFirst(announcements[i].CreatedAt, announcements[i].ID, announcements[j].CreatedAt, announcements[j].ID) < 0
+	})
+	return limit(announcements, filter.Limit), nil
+}
+
+// ClaimDueAnnouncement takes the earliest due announcement that is scheduled, or whose sender's lease
+// ran out, marks it sending under a lease until leaseUntil, and returns it. It returns nil if none is due.
+func (r *AnnouncementRepository) ClaimDueAnnouncement(ctx context.Context, now, leaseUntil time.Time) (*models.Announcement, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, fmt.Errorf("repository: failed to claim due announcement: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	var due *announcementRow
+	for _, row := range r.db.announcements {
+		if row.a.SendAt.After(now) {
+			continue
+		}
+		expired := row.a.Status == models.AnnouncementSending && row.leaseUntil != nil && row.leaseUntil.Before(now)
+		if row.a.Status != models.AnnouncementScheduled && !expired {
+			continue
+		}
+		if due == nil || row.a.SendAt.Before(due.a.SendAt) {
+			due = row
+		}
+	}
+	if due == nil {
+		return nil, nil
+	}
+	due.a.Status = models.AnnouncementSending
+	due.leaseUntil = &leaseUntil
+	if due.a.StartedAt == nil {
+		due.a.StartedAt = &now
+	}
+	return copyAnnouncement(&due.a), nil
+}
+
+// SetAnnouncementRecipients records the size of the segment when sending started.
+func (r *AnnouncementRepository) SetAnnouncementRecipients(ctx context.Context, id uuid.UUID, recipients int) error {
+	if err := r.db.lock(ctx); err != nil {
+		return fmt.Errorf("repository: failed to set announcement recipients: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	if row := r.db.announcements[id]; row != nil {
+		row.a.Stats.Recipients = recipients
+	}
+	return nil
+}
+
+// RecordAnnouncementProgress adds a batch's deliveries, moves the cursor past it, and extends the lease.
+// It returns false once the announcement is no longer sending, e.g. because it was cancelled.
+func (r *AnnouncementRepository) RecordAnnouncementProgress(ctx context.Context, id, cursor uuid.UUID, sent, failed int, leaseUntil time.Time) (bool, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return false, fmt.Errorf("repository: failed to record announcement progress: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	row := r.db.announcements[id]
+	if row == nil || row.a.Status != models.AnnouncementSending {
+		return false, nil
+	}
+	row.a.Cursor = cursor
+	row.a.Stats.Sent += sent
+	row.a.Stats.Failed += failed
+	row.leaseUntil = &leaseUntil
+	return true, nil
+}
+
+// FinishAnnouncement marks a sending announcement sent.
+func (r *AnnouncementRepository) FinishAnnouncement(ctx context.Context, id uuid.UUID, at time.Time) error {
+	if err := r.db.lock(ctx); err != nil {
+		return fmt.Errorf("repository: failed to finish announcement: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	if row := r.db.announcements[id]; row != nil && row.a.Status == models.AnnouncementSending {
+		row.a.Status = models.AnnouncementSent
+		row.a.FinishedAt = &at
+		row.leaseUntil = nil
+	}
+	return nil
+}
+
+// CancelAnnouncement stops a scheduled or sending announcement, and reports whether it was either.
+func (r *AnnouncementRepository) CancelAnnouncement(ctx context.Context, id uuid.UUID, at time.Time) (bool, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return false, fmt.Errorf("repository: failed to cancel announcement: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	row := r.db.announcements[id]
+	if row == nil || row.a.Status != models.AnnouncementScheduled && row.a.Status != models.AnnouncementSending {
+		return false, nil
+	}
+	row.a.Status = models.AnnouncementCancelled
+	row.a.FinishedAt = &at
+	row.leaseUntil = nil
+	return true, nil
+}
@@ -0,0 +1,351 @@
+// services/user-service/internal/repository/inmemory/user_records.go
+package inmemory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/repository"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// MergeUsers records the merge with a snapshot of the donor and removes the donor account. The donor's
+// external identities and email aliases move to the primary user, and the donor's email becomes one of
+// its aliases; everything else of the donor, sessions included, is removed with it.
+func (r *UserRepository) MergeUsers(ctx context.Context, merge *models.UserMerge) error {
+	if err := r.db.lock(ctx); err != nil {
+		return fmt.Errorf("repository: failed to record user merge: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	d := merge.DonorSnapshot
+	if r.db.merges[merge.ID] != nil {
+		return fmt.Errorf("repository: failed to record user merge: duplicate ID %s", merge.ID)
+	}
+	moved := []models.MergedIdentity{}
+	for key, identity := range r.db.identities {
+		if identity.UserID == d.ID {
+			identity.UserID = merge.PrimaryUserID
+			r.db.identities[key] = identity
+			moved = append(moved, models.MergedIdentity{Issuer: identity.Issuer, Subject: identity.Subject})
+		}
+	}
+	sort.Slice(moved, func(i, j int) bool {
+		if moved[i].Issuer != moved[j].Issuer {
+			return moved[i].Issuer < moved[j].Issuer
+		}
+		return moved[i].Subject < moved[j].Subject
+	})
+
+	record := &models.UserMerge{
+		ID:              merge.ID,
+		PrimaryUserID:   merge.PrimaryUserID,
+		DonorUserID:     d.ID,
+		DonorEmail:      d.Email,
+		DonorSnapshot:   models.User{ID: d.ID, Name: d.Name, Email: d.Email, PasswordHash: d.PasswordHash, Role: d.Role, CreatedAt: d.CreatedAt},
+		MovedIdentities: append([]models.MergedIdentity{}, moved...),
+		MergedBy:        merge.MergedBy,
+		CreatedAt:       merge.CreatedAt,
+	}
+	r.db.merges[merge.ID] = record
+	for _, alias := range r.db.aliases {
+		if alias.userID == d.ID {
+			alias.userID = merge.PrimaryUserID
+		}
+	}
+	// A donor sharing its email key with another alias gets no alias of its own; the other one has the key.
+	if key := models.EmailKey(d.Email); r.db.aliases[key] == nil {
+		r.db.aliases[key] = &emailAlias{email: d.Email, userID: merge.PrimaryUserID, donorID: d.ID, mergeID: merge.ID, createdAt: merge.CreatedAt}
+	}
+	r.db.deleteUser(d.ID)
+
+	merge.MovedIdentities = moved
+	logger.FromContext(ctx).Infof("Merged user %s into %s (merge %s), moving %d identities", d.ID, merge.PrimaryUserID, merge.ID, len(moved))
+	return nil
+}
+
+// GetUserMerge retrieves a merge record by ID. It returns nil, nil if not found.
+func (r *UserRepository) GetUserMerge(ctx context.Context, id uuid.UUID) (*models.UserMerge, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, fmt.Errorf("repository: failed to get user merge: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	record := r.db.merges[id]
+	if record == nil {
+		return nil, nil
+	}
+	m := *record
+	m.MovedIdentities = append([]models.MergedIdentity{}, record.MovedIdentities...)
+	return &m, nil
+}
+
+// UndoUserMerge restores the donor account from its snapshot and marks the merge undone. The donor's
+// email alias is dropped and the identities the merge moved go back to the donor, unless unlinked since;
+// aliases the donor had from earlier merges stay with the primary user. Sessions issued before the undo
+// stay invalid.
+func (r *UserRepository) UndoUserMerge(ctx context.Context, merge *models.UserMerge, undoneBy string) error {
+	if err := r.db.lock(ctx); err != nil {
+		return fmt.Errorf("repository: failed to undo user merge: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	now := time.Now().UTC()
+	d := merge.DonorSnapshot
+	record := r.db.merges[merge.ID]
+	if record == nil || record.UndoneAt != nil {
+		return repository.Errorf(repository.ErrConflict, "repository: merge already undone")
+	}
+	key := models.EmailKey(d.Email)
+	if r.db.users[d.ID] != nil || r.userByEmailKey(key) != nil {
+		return repository.Errorf(repository.ErrDuplicateEmail, "repository: failed to restore donor user: email or ID already in use")
+	}
+
+	revoked := now.Truncate(time.Second)
+	restored := models.User{
+		ID: d.ID, Name: d.Name, Email: d.Email, PasswordHash: d.PasswordHash, Role: d.Role,
+		Timezone: models.DefaultTimezone, WeekStart: models.DefaultWeekStart, Units: models.DefaultUnits, Status: models.StatusActive,
+		CreatedAt: d.CreatedAt, UpdatedAt: now, SessionsRevokedAt: &revoked, EmailStatus: models.EmailOK,
+	}
+	r.db.users[d.ID] = &userRow{user: restored, emailKey: key, metadata: models.UserMetadata{}, onboardingStep: models.OnboardingRegistered}
+	deleteWhere(r.db.aliases, func(a *emailAlias) bool { return a.mergeID == merge.ID })
+	for _, id := range merge.MovedIdentities {
+		k := identityKey{issuer: id.Issuer, subject: id.Subject}
+		if identity, ok := r.db.identities[k]; ok && identity.UserID == merge.PrimaryUserID {
+			identity.UserID = d.ID
+			r.db.identities[k] = identity
+		}
+	}
+	record.UndoneAt, record.UndoneBy = &now, undoneBy
+
+	merge.UndoneAt = &now
+	merge.UndoneBy = undoneBy
+	logger.FromContext(ctx).Infof("Undid merge %s, restored user %s", merge.ID, d.ID)
+	return nil
+}
+
+// GetProfilePromptDismissals returns a user's prompt dismissals, keyed by field.
+func (r *UserRepository) GetProfilePromptDismissals(ctx context.Context, userID uuid.UUID) (map[string]models.ProfilePromptDismissal, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, fmt.Errorf("repository: failed to get profile prompt dismissals: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	dismissals := map[string]models.ProfilePromptDismissal{}
+	for field, d := range r.db.promptDismissals[userID] {
+		dismissals[field] = d
+	}
+	return dismissals, nil
+}
+
+// DismissProfilePrompt records one more dismissal of the prompt for field.
+func (r *UserRepository) DismissProfilePrompt(ctx context.Context, userID uuid.UUID, field string, at time.Time) error {
+	if err := r.db.lock(ctx); err != nil {
+		return fmt.Errorf("repository: failed to dismiss profile prompt: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	if r.db.users[userID] == nil {
+		return fmt.Errorf("repository: failed to dismiss profile prompt: user %s does not exist", userID)
+	}
+	dismissals := r.db.promptDismissals[userID]
+	if dismissals == nil {
+		dismissals = make(map[string]models.ProfilePromptDismissal)
+		r.db.promptDismissals[userID] = dismissals
+	}
+	d := dismissals[field]
+	dismissals[field] = models.ProfilePromptDismissal{Field: field, Count: d.Count + 1, LastDismissedAt: at}
+	return nil
+}
+
+// ListAggregationPeriods returns a user's custom periods, earliest first.
+func (r *UserRepository) ListAggregationPeriods(ctx context.Context, userID uuid.UUID) ([]models.AggregationPeriod, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, fmt.Errorf("repository: failed to list aggregation periods: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	periods := []models.AggregationPeriod{}
+	for _, p := range r.db.periods {
+		if p.UserID == userID {
+			periods = append(periods, *p)
+		}
+	}
+	sort.Slice(periods, func(i, j int) bool {
+		a, b := periods[i], periods[j]
+		if a.StartsOn != b.StartsOn {
+			return a.StartsOn < b.StartsOn
+		}
+		if a.EndsOn != b.EndsOn {
+			return a.EndsOn < b.EndsOn
+		}
+		return compareIDs(a.ID, b.ID) < 0
+	})
+	return periods, nil
+}
+
+// CreateAggregationPeriod stores a custom period.
+func (r *UserRepository) CreateAggregationPeriod(ctx context.Context, p *models.AggregationPeriod) error {
+	if err := r.db.lock(ctx); err != nil {
+		return fmt.Errorf("repository: failed to create aggregation period: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	if r.db.periods[p.ID] != nil {
+		return fmt.Errorf("repository: failed to create aggregation period: duplicate ID %s", p.ID)
+	}
+	if r.db.users[p.UserID] == nil {
+		return fmt.Errorf("repository: failed to create aggregation period: user %s does not exist", p.UserID)
+	}
+	period := *p
+	r.db.periods[p.ID] = &period
+	return nil
+}
+
+// UpdateAggregationPeriod saves a custom period's name, kind, and dates, reporting whether it exists.
+func (r *UserRepository) UpdateAggregationPeriod(ctx context.Context, p *models.AggregationPeriod) (bool, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return false, fmt.Errorf("repository: failed to update aggregation period: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	stored := r.db.periods[p.ID]
+	if stored == nil || stored.UserID != p.UserID {
+		return false, nil
+	}
+	stored.Name, stored.Kind, stored.StartsOn, stored.EndsOn, stored.UpdatedAt = p.Name, p.Kind, p.StartsOn, p.EndsOn, p.UpdatedAt
+	return true, nil
+}
+
+// DeleteAggregationPeriod deletes one of the user's custom periods, reporting whether it existed.
+func (r *UserRepository) DeleteAggregationPeriod(ctx context.Context, userID, id uuid.UUID) (bool, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return false, fmt.Errorf("repository: failed to delete aggregation period: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	if p := r.db.periods[id]; p == nil || p.UserID != userID {
+		return false, nil
+	}
+	delete(r.db.periods, id)
+	return true, nil
+}
+
+// GetUserMetadata returns a user's metadata, or nil if the user does not exist.
+func (r *UserRepository) GetUserMetadata(ctx context.Context, userID uuid.UUID) (models.UserMetadata, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, fmt.Errorf("repository: failed to get user metadata: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	row := r.db.users[userID]
+	if row == nil {
+		return nil, nil
+	}
+	return copyMetadata(row.metadata), nil
+}
+
+// MergeUserMetadata sets the keys in set, removes the keys in remove, and leaves the others as they
+// are. The update is only made if the merged metadata stays within maxBytes of JSON text. It returns
+// the merged metadata, or nil if the user does not exist or the limit would be exceeded.
+func (r *UserRepository) MergeUserMetadata(ctx context.Context, userID uuid.UUID, set models.UserMetadata, remove []string, maxBytes int) (models.UserMetadata, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, fmt.Errorf("repository: failed to update user metadata: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	row := r.db.users[userID]
+	if row == nil {
+		return nil, nil
+	}
+	merged := copyMetadata(row.metadata)
+	for key, value := range set {
+		merged[key] = append(json.RawMessage{}, value...)
+	}
+	for _, key := range remove {
+		delete(merged, key)
+	}
+	encoded, err := json.Marshal(merged)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to encode user metadata: %w", err)
+	}
+	if len(encoded) > maxBytes {
+		return nil, nil
+	}
+	row.metadata = merged
+	return copyMetadata(merged), nil
+}
+
+// copyMetadata returns a copy of metadata that shares none of its values.
+func copyMetadata(metadata models.UserMetadata) models.UserMetadata {
+	c := make(models.UserMetadata, len(metadata))
+	for key, value := range metadata {
+		c[key] = append(json.RawMessage{}, value...)
+	}
+	return c
+}
+
+// GetOnboarding returns a user's onboarding state without its Next step, or nil if the user does not exist.
+// A user who never moved keeps the step they were created at, as of their registration.
+func (r *UserRepository) GetOnboarding(ctx context.Context, userID uuid.UUID) (*models.OnboardingState, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, fmt.Errorf("repository: failed to get onboarding state: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	row := r.db.users[userID]
+	if row == nil {
+		return nil, nil
+	}
+	state := &models.OnboardingState{Step: row.onboardingStep, UpdatedAt: row.user.CreatedAt}
+	if row.onboardingUpdatedAt != nil {
+		state.UpdatedAt = *row.onboardingUpdatedAt
+	}
+	return state, nil
+}
+
+// AdvanceOnboarding moves a user from one onboarding step to another, only if they are still at from.
+// A skip records from as the step skipped from. It returns false if nothing was moved.
+func (r *UserRepository) AdvanceOnboarding(ctx context.Context, userID uuid.UUID, from, to string, skip bool, at time.Time) (bool, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return false, fmt.Errorf("repository: failed to advance onboarding: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	row := r.db.users[userID]
+	if row == nil || row.onboardingStep != from {
+		return false, nil
+	}
+	row.onboardingStep, row.skippedFrom, row.onboardingUpdatedAt = to, "", &at
+	if skip {
+		row.skippedFrom = from
+	}
+	return true, nil
+}
+
+// CountOnboardingSteps counts the users created since since (all users if zero) by onboarding step and,
+// for users who skipped, the step they skipped from.
+func (r *UserRepository) CountOnboardingSteps(ctx context.Context, since time.Time) ([]models.OnboardingStepCount, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, fmt.Errorf("repository: failed to count onboarding steps: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	type group struct{ step, skippedFrom string }
+	users := map[group]int{}
+	for _, row := range r.db.users {
+		if !row.user.CreatedAt.Before(since) {
+			users[group{row.onboardingStep, row.skippedFrom}]++
+		}
+	}
+	var counts []models.OnboardingStepCount
+	for g, n := range users {
+		counts = append(counts, models.OnboardingStepCount{Step: g.step, SkippedFrom: g.skippedFrom, Users: n})
+	}
+	return counts, nil
+}
@@ -0,0 +1,397 @@
+// services/user-service/internal/repository/inmemory/user_reports.go
+package inmemory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// matchesUserFilter reports whether a user meets the conditions of a filter, except paging.
+func matchesUserFilter(u models.User, filter models.UserFilter) bool {
+	switch {
+	case filter.Role != "" && u.Role != filter.Role:
+		return false
+	case filter.Status != "" && u.Status != filter.Status:
+		return false
+	case filter.Verified != nil && (u.EmailVerifiedAt != nil) != *filter.Verified:
+		return false
+	case !filter.CreatedSince.IsZero() && u.CreatedAt.Before(filter.CreatedSince):
+		return false
+	case !filter.CreatedUntil.IsZero() && !u.CreatedAt.Before(filter.CreatedUntil):
+		return false
+	}
+	return true
+}
+
+// ListUsers returns the users matching filter, newest first, up to filter.Limit.
+func (r *UserRepository) ListUsers(ctx context.Context, filter models.UserFilter) ([]models.User, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, fmt.Errorf("repository: failed to list users: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	users := []models.User{}
+	for _, row := range r.db.users {
+		if matchesUserFilter(row.user, filter) && beforeKey(row.user.CreatedAt, row.user.ID, filter.After) {
+			users = append(users, row.user)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return newerFirst(users[i].CreatedAt, users[i].ID, users[j].CreatedAt, users[j].ID) < 0
+	})
+	return limit(users, filter.Limit), nil
+}
+
+// searchThreshold is the default pg_trgm.word_similarity_threshold, below which SearchUsers leaves users out.
+const searchThreshold = 0.6
+
+// SearchUsers returns up to limit users whose name, username, or email is similar to the query, best
+// match first. A user scores the share of the query's trigrams found anywhere in the text, which is at
+// least the word similarity Postgres ranks by: the same users match, give or take a few near the threshold.
+func (r *UserRepository) SearchUsers(ctx context.Context, query string, n int) ([]models.UserMatch, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, fmt.Errorf("repository: failed to search users: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	want := trigrams(query)
+	matches := []models.UserMatch{}
+	for _, row := range r.db.users {
+		u := row.user
+		have := trigrams(u.Name + " " + u.Username + " " + u.Email)
+		found := 0
+		for t := range want {
+			if have[t] {
+				found++
+			}
+		}
+		if len(want) > 0 && float64(found)/float64(len(want)) >= searchThreshold {
+			matches = append(matches, models.UserMatch{User: u, Score: float64(found) / float64(len(want))})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return newerFirst(matches[j].User.CreatedAt, matches[j].User.ID, matches[i].User.CreatedAt, matches[i].User.ID) < 0
+	})
+	return limit(matches, n), nil
+}
+
+// trigrams returns the trigrams of s as pg_trgm extracts them: each run of letters and digits is
+// lowercased and padded with two spaces before and one after.
+func trigrams(s string) map[string]bool {
+	set := map[string]bool{}
+	for _, word := range strings.FieldsFunc(strings.ToLower(s), func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+		padded := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			set[string(padded[i:i+3])] = true
+		}
+	}
+	return set
+}
+
+// signedInSince reports whether a user signed in successfully at or after since. The lock must be held.
+func (db *DB) signedInSince(userID uuid.UUID, since time.Time) bool {
+	for _, a := range db.loginAttempts {
+		if a.UserID == userID && a.Success && !a.CreatedAt.Before(since) {
+			return true
+		}
+	}
+	return false
+}
+
+// CountUsers counts the users matching filter, ignoring its paging: all of them, those with a
+// successful sign-in since activeSince, and those whose email is not verified.
+func (r *UserRepository) CountUsers(ctx context.Context, filter models.UserFilter, activeSince time.Time) (*models.UserCounts, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, fmt.Errorf("repository: failed to count users: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	counts := &models.UserCounts{}
+	for _, row := range r.db.users {
+		if !matchesUserFilter(row.user, filter) {
+			continue
+		}
+		counts.Total++
+		if r.db.signedInSince(row.user.ID, activeSince) {
+			counts.ActiveLast30Days++
+		}
+		if row.user.EmailVerifiedAt == nil {
+			counts.Unverified++
+		}
+	}
+	return counts, nil
+}
+
+// profileSize is the size of a user's row with the rows of the tables only the users table has.
+// The lock must be held.
+func (db *DB) profileSize(row *userRow) int64 {
+	id := row.user.ID
+	size := rowSize(row.user) + rowSize(row.metadata) + rowSize(db.timezones[id])
+	for _, d := range db.promptDismissals[id] {
+		size += rowSize(d)
+	}
+	for _, p := range db.periods {
+		if p.UserID == id {
+			size += rowSize(p)
+		}
+	}
+	if layout, ok := db.layouts[id]; ok {
+		size += rowSize(layout)
+	}
+	if settings, ok := db.settings[id]; ok {
+		size += rowSize(settings)
+	}
+	return size
+}
+
+// StorageUsage returns the bytes each user's rows take up, across the users table and the tables of the
+// user-owned repositories sharing it. Sizes are the lengths of the rows' JSON encodings, which stand in
+// for the Postgres datum sizes.
+func (r *UserRepository) StorageUsage(ctx context.Context) (map[uuid.UUID]int64, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, fmt.Errorf("repository: failed to measure storage usage: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	usage := map[uuid.UUID]int64{}
+	for id, row := range r.db.users {
+		usage[id] = r.db.profileSize(row)
+	}
+	for _, e := range r.db.userEvents {
+		if _, ok := usage[e.UserID]; ok {
+			usage[e.UserID] += rowSize(e)
+		}
+	}
+	for _, a := range r.db.loginAttempts {
+		if _, ok := usage[a.UserID]; ok {
+			usage[a.UserID] += rowSize(a)
+		}
+	}
+	return usage, nil
+}
+
+// dataCategory accumulates the rows of a data category.
+type dataCategory struct {
+	models.DataCategory
+}
+
+// add counts a row of the category, made at at.
+func (c *dataCategory) add(bytes int64, at time.Time) {
+	c.Count++
+	c.Bytes += bytes
+	if c.Oldest == nil || at.Before(*c.Oldest) {
+		oldest := at
+		c.Oldest = &oldest
+	}
+	if c.Newest == nil || at.After(*c.Newest) {
+		newest := at
+		c.Newest = &newest
+	}
+}
+
+// SummarizeUserData returns what this DB stores about a user, by category, with the same tables as
+// StorageUsage plus messaging, attachments, workout plans, and consents. Attachments also count the size of their
+// uploaded content. It returns nil if the user is missing.
+func (r *UserRepository) SummarizeUserData(ctx context.Context, userID uuid.UUID) ([]models.DataCategory, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, fmt.Errorf("repository: failed to summarize user data: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	row := r.db.users[userID]
+	if row == nil {
+		return nil, nil
+	}
+	profile := models.DataCategory{Category: models.DataCategoryProfile, Count: 1, Bytes: r.db.profileSize(row)}
+	created, updated := row.user.CreatedAt, row.user.UpdatedAt
+	if updated.IsZero() {
+		updated = created
+	}
+	profile.Oldest, profile.Newest = &created, &updated
+
+	timeline := dataCategory{models.DataCategory{Category: models.DataCategoryTimeline}}
+	for _, e := range r.db.userEvents {
+		if e.UserID == userID {
+			timeline.add(rowSize(e), e.OccurredAt)
+		}
+	}
+	logins := dataCategory{models.DataCategory{Category: models.DataCategoryLoginHistory}}
+	for _, a := range r.db.loginAttempts {
+		if a.UserID == userID {
+			logins.add(rowSize(a), a.CreatedAt)
+		}
+	}
+	messages := dataCategory{models.DataCategory{Category: models.DataCategoryMessages}}
+	for _, m := range r.db.messages {
+		if m.userID == userID {
+			messages.add(rowSize(m.msg), m.msg.CreatedAt)
+		}
+	}
+	messageAttachments := dataCategory{models.DataCategory{Category: models.DataCategoryMessageAttachments}}
+	for _, a := range r.db.messageAttachments {
+		if a.userID == userID {
+			messageAttachments.add(rowSize(a.attachment)+a.attachment.Size, a.attachment.CreatedAt)
+		}
+	}
+	workoutAttachments := dataCategory{models.DataCategory{Category: models.DataCategoryWorkoutAttachments}}
+	for _, a := range r.db.workoutAttachments {
+		if a.UserID == userID {
+			workoutAttachments.add(rowSize(a)+a.Size, a.CreatedAt)
+		}
+	}
+	workoutPlans := dataCategory{models.DataCategory{Category: models.DataCategoryWorkoutPlans}}
+	for _, p := range r.db.workoutPlans {
+		if p.CoachID == userID {
+			workoutPlans.add(rowSize(p), p.CreatedAt)
+		}
+	}
+	consents := dataCategory{models.DataCategory{Category: models.DataCategoryConsents}}
+	for _, c := range r.db.consents {
+		if c.UserID == userID {
+			consents.add(rowSize(c), c.AcceptedAt)
+		}
+	}
+	return []models.DataCategory{profile, timeline.DataCategory, logins.DataCategory, messages.DataCategory,
+		messageAttachments.DataCategory, workoutAttachments.DataCategory, workoutPlans.DataCategory, consents.DataCategory}, nil
+}
+
+// metadataText returns a metadata value as Postgres' ->> operator does: strings unquoted, other
+// values as JSON text.
+func metadataText(value json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(value, &s); err == nil {
+		return s
+	}
+	return string(value)
+}
+
+// inSegment reports whether an active user belongs to an announcement segment. The lock must be held.
+func (db *DB) inSegment(row *userRow, segment models.AnnouncementSegment, now time.Time) bool {
+	if row.user.Status != models.StatusActive {
+		return false
+	}
+	metadataIs := func(key, value string) bool {
+		raw, ok := row.metadata[key]
+		return ok && metadataText(raw) == value
+	}
+	switch segment.Type {
+	case models.SegmentOrg:
+		return metadataIs(models.UserOrgMetadataKey, segment.Org)
+	case models.SegmentPlan:
+		return metadataIs(models.UserPlanMetadataKey, segment.Plan)
+	case models.SegmentInactive:
+		since := now.AddDate(0, 0, -segment.InactiveDays)
+		return row.user.CreatedAt.Before(since) && !db.signedInSince(row.user.ID, since)
+	}
+	return true
+}
+
+// optedOutOfPush reports whether a user turned notifications.push off. The lock must be held.
+func (db *DB) optedOutOfPush(userID uuid.UUID) bool {
+	push, ok := db.settings[userID].Settings["notifications.push"]
+	return ok && push == false
+}
+
+// CountAnnouncementRecipients counts the users of a segment an announcement would reach, and those
+// of it who turned push notifications off.
+func (r *UserRepository) CountAnnouncementRecipients(ctx context.Context, segment models.AnnouncementSegment) (*models.AnnouncementAudience, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, fmt.Errorf("repository: failed to count announcement recipients: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	now := time.Now()
+	audience := &models.AnnouncementAudience{}
+	for _, row := range r.db.users {
+		if !r.db.inSegment(row, segment, now) {
+			continue
+		}
+		if r.db.optedOutOfPush(row.user.ID) {
+			audience.OptedOut++
+		} else {
+			audience.Recipients++
+		}
+	}
+	return audience, nil
+}
+
+// ListAnnouncementRecipients returns up to limit recipients of a segment with IDs after the given
+// one, in ID order, leaving out users who turned push notifications off.
+func (r *UserRepository) ListAnnouncementRecipients(ctx context.Context, segment models.AnnouncementSegment, after uuid.UUID, n int) ([]uuid.UUID, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, fmt.Errorf("repository: failed to list announcement recipients: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	now := time.Now()
+	var ids []uuid.UUID
+	for id, row := range r.db.users {
+		if compareIDs(id, after) > 0 && !r.db.optedOutOfPush(id) && r.db.inSegment(row, segment, now) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return compareIDs(ids[i], ids[j]) < 0 })
+	return limit(ids, n), nil
+}
+
+// ListDueDeletions returns accounts pending deletion whose grace period ended by now, oldest first.
+func (r *UserRepository) ListDueDeletions(ctx context.Context, now time.Time, n int) ([]models.User, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, fmt.Errorf("repository: failed to list due deletions: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	users := []models.User{}
+	for _, row := range r.db.users {
+		due := row.user.DeletionDueAt
+		if row.user.Status == models.StatusPendingDeletion && due != nil && !due.After(now) {
+			users = append(users, row.user)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].DeletionDueAt.Before(*users[j].DeletionDueAt) })
+	return limit(users, n), nil
+}
+
+// EraseUser removes a user with every row about them and returns the blob keys of their message and
+// workout attachments, which the caller must delete from the blob store. Merge records, which keep a
+// snapshot of the merged-in account, are deleted too.
+func (r *UserRepository) EraseUser(ctx context.Context, id uuid.UUID) ([]string, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, fmt.Errorf("repository: failed to erase user: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	keys := []string{}
+	for _, a := range r.db.messageAttachments {
+		if a.userID == id {
+			keys = append(keys, a.attachment.BlobKey)
+		}
+	}
+	for _, a := range r.db.workoutAttachments {
+		if a.UserID == id && a.BlobKey != "" {
+			keys = append(keys, a.BlobKey)
+		}
+	}
+	deleteWhere(r.db.merges, func(m *models.UserMerge) bool { return m.PrimaryUserID == id || m.DonorUserID == id })
+	r.db.deleteUser(id)
+	logger.FromContext(ctx).Infof("User erased: %s (%d attachment blobs to delete)", id, len(keys))
+	return keys, nil
+}
+
+// ResealHealthFields has nothing to do: the in-memory store never writes anything to disk, so health
+// fields are kept as they are.
+func (r *UserRepository) ResealHealthFields(ctx context.Context, limit int) (int, error) {
+	return 0, nil
+}
@@ -0,0 +1,548 @@
+// services/user-service/internal/repository/inmemory/user_repository.go
+package inmemory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/eventbus"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/repository"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// userRow is a user with the columns models.User does not carry.
+type userRow struct {
+	user                models.User
+	emailKey            string
+	softBounces         int
+	statusAt            *time.Time
+	metadata            models.UserMetadata
+	onboardingStep      string
+	skippedFrom         string
+	onboardingUpdatedAt *time.Time
+}
+
+// tokenRow is a password reset or email verification token.
+type tokenRow struct {
+	userID    uuid.UUID
+	email     string // Email verification tokens only
+	expiresAt time.Time
+	used      bool
+}
+
+// emailAlias is an email of a merged-away donor that still signs in to the primary user.
+type emailAlias struct {
+	email     string
+	userID    uuid.UUID
+	donorID   uuid.UUID
+	mergeID   uuid.UUID
+	createdAt time.Time
+}
+
+// UserRepository is the in-memory implementation of repository.UserRepository.
+type UserRepository struct {
+	db *DB
+}
+
+var _ repository.UserRepository = (*UserRepository)(nil)
+
+// NewUserRepository creates a UserRepository on db.
+func NewUserRepository(db *DB) *UserRepository {
+	return &UserRepository{db: db}
+}
+
+// Migrate does nothing; the tables exist as soon as the DB does.
+func (r *UserRepository) Migrate() error {
+	return nil
+}
+
+// userByEmailKey returns the user with an email key, or nil. The lock must be held.
+func (r *UserRepository) userByEmailKey(key string) *userRow {
+	for _, row := range r.db.users {
+		if row.emailKey == key {
+			return row
+		}
+	}
+	return nil
+}
+
+// userByUsername returns the user with a username, or nil. The lock must be held.
+func (r *UserRepository) userByUsername(username string) *userRow {
+	if username == "" {
+		return nil
+	}
+	for _, row := range r.db.users {
+		if row.user.Username == username {
+			return row
+		}
+	}
+	return nil
+}
+
+// CreateUser stores a new user, filling in the defaults the users table has, and seeds their timezone history.
+// Emails are unique by models.EmailKey, and usernames are unique.
+func (r *UserRepository) CreateUser(ctx context.Context, user *models.User) error {
+	if err := r.db.lock(ctx); err != nil {
+		return fmt.Errorf("repository: failed to create user: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	if err := r.insertUser(user); err != nil {
+		return err
+	}
+	logger.FromContext(ctx).Infof("User created successfully: %s", user.ID)
+	return nil
+}
+
+// insertUser is CreateUser with the lock held.
+func (r *UserRepository) insertUser(user *models.User) error {
+	if user.ID == uuid.Nil {
+		user.ID = uuid.New()
+	}
+	if user.Role == "" {
+		user.Role = models.RoleUser
+	}
+	if user.Timezone == "" {
+		user.Timezone = models.DefaultTimezone
+	}
+	if user.WeekStart == "" {
+		user.WeekStart = models.DefaultWeekStart
+	}
+	if user.Units == "" {
+		user.Units = models.DefaultUnits
+	}
+	if user.Status == "" {
+		user.Status = models.StatusActive
+	}
+	user.CreatedAt = time.Now().UTC()
+	user.UpdatedAt = user.CreatedAt
+
+	key := models.EmailKey(user.Email)
+	switch {
+	case r.db.users[user.ID] != nil:
+		return fmt.Errorf("repository: failed to create user: duplicate ID %s", user.ID)
+	case r.userByEmailKey(key) != nil:
+		return repository.Errorf(repository.ErrDuplicateEmail, "repository: failed to create user: email already in use")
+	case r.userByUsername(user.Username) != nil:
+		return repository.Errorf(repository.ErrDuplicateUsername, "repository: failed to create user: username already taken")
+	}
+	// Only the columns Postgres inserts are kept; the others start at their defaults.
+	row := &userRow{user: *user, emailKey: key, metadata: models.UserMetadata{}, onboardingStep: models.OnboardingRegistered}
+	row.user.HeightCM, row.user.DateOfBirth, row.user.Region = nil, nil, ""
+	row.user.DeletionDueAt, row.user.SessionsRevokedAt = nil, nil
+	row.user.EmailStatus = models.EmailOK
+	r.db.users[user.ID] = row
+	r.db.timezones[user.ID] = []models.TimezonePeriod{{Timezone: user.Timezone, EffectiveFrom: user.CreatedAt}}
+	r.db.announce(eventbus.UserCreated, user.ID)
+	return nil
+}
+
+// GetUserByEmail retrieves the user whose email reaches the same mailbox as the given normalized email,
+// by models.EmailKey, or through an email alias left by a merge. An account's own email wins over an alias.
+func (r *UserRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, fmt.Errorf("repository: failed to get user by email: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	key := models.EmailKey(email)
+	found := r.userByEmailKey(key)
+	if alias := r.db.aliases[key]; alias != nil && (found == nil || found.user.Email != email) {
+		if aliased := r.db.users[alias.userID]; aliased != nil && (found == nil || aliased.user.Email == email) {
+			found = aliased
+		}
+	}
+	if found == nil {
+		logger.FromContext(ctx).Debugf("User with email '%s' not found in memory.", email)
+		return nil, nil
+	}
+	user := found.user
+	return &user, nil
+}
+
+// GetUserByUsername retrieves a user by their lowercased username, or nil if no user has it.
+func (r *UserRepository) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, fmt.Errorf("repository: failed to get user by username: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	row := r.userByUsername(username)
+	if row == nil {
+		return nil, nil
+	}
+	user := row.user
+	return &user, nil
+}
+
+// GetUserByID retrieves a user by their UUID, or nil if there is none.
+func (r *UserRepository) GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, fmt.Errorf("repository: failed to get user by ID: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	row := r.db.users[id]
+	if row == nil {
+		return nil, nil
+	}
+	user := row.user
+	return &user, nil
+}
+
+// GetAllUsers retrieves a page of all users, oldest first, resuming after the page key.
+func (r *UserRepository) GetAllUsers(ctx context.Context, after *models.PageKey, n int) ([]models.User, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, fmt.Errorf("repository: failed to get all users: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	users := []models.User{}
+	for _, row := range r.db.users {
+		if afterKey(row.user.CreatedAt, row.user.ID, after) {
+			users = append(users, row.user)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return newerFirst(users[j].CreatedAt, users[j].ID, users[i].CreatedAt, users[i].ID) < 0
+	})
+	return limit(users, n), nil
+}
+
+// UpdateUser saves a user's details. A new email gets a new email key, and clears the verification
+// and deliverability of the old one; those are never written from user otherwise.
+func (r *UserRepository) UpdateUser(ctx context.Context, user *models.User) error {
+	if err := r.db.lock(ctx); err != nil {
+		return fmt.Errorf("repository: failed to update user: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	if err := r.updateUser(user); err != nil {
+		return err
+	}
+	logger.FromContext(ctx).Infof("User updated successfully: %s", user.ID)
+	return nil
+}
+
+// updateUser is UpdateUser with the lock held.
+func (r *UserRepository) updateUser(user *models.User) error {
+	user.UpdatedAt = time.Now().UTC()
+	row := r.db.users[user.ID]
+	if row == nil {
+		return nil // Like an UPDATE matching no row
+	}
+	if other := r.userByUsername(user.Username); other != nil && other != row {
+		return repository.Errorf(repository.ErrDuplicateUsername, "repository: failed to update user: username already taken")
+	}
+	if user.Email != row.user.Email {
+		key := models.EmailKey(user.Email)
+		if other := r.userByEmailKey(key); other != nil && other != row {
+			return repository.Errorf(repository.ErrDuplicateEmail, "repository: failed to update user: email already in use")
+		}
+		row.emailKey = key
+		row.user.EmailVerifiedAt = nil
+		row.user.EmailStatus = models.EmailOK
+		row.softBounces = 0
+		row.statusAt = nil
+	}
+
+	u := &row.user
+	u.Name, u.Email, u.Username, u.PasswordHash = user.Name, user.Email, user.Username, user.PasswordHash
+	u.Timezone, u.WeekStart, u.Units, u.Status = user.Timezone, user.WeekStart, user.Units, user.Status
+	u.HeightCM, u.DateOfBirth, u.UpdatedAt = user.HeightCM, user.DateOfBirth, user.UpdatedAt
+	u.SessionsRevokedAt, u.DeletionDueAt = user.SessionsRevokedAt, user.DeletionDueAt
+	r.db.announce(eventbus.UserUpdated, user.ID)
+	return nil
+}
+
+// DeleteUser deletes a user with every row about them.
+func (r *UserRepository) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	if err := r.db.lock(ctx); err != nil {
+		return fmt.Errorf("repository: failed to delete user: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	r.removeUser(id)
+	logger.FromContext(ctx).Infof("User deleted successfully: %s", id)
+	return nil
+}
+
+// removeUser is DeleteUser with the lock held.
+func (r *UserRepository) removeUser(id uuid.UUID) {
+	if r.db.users[id] != nil {
+		r.db.announce(eventbus.UserDeleted, id)
+	}
+	r.db.deleteUser(id)
+}
+
+// ApplyUserChanges applies the changes as CreateUser, UpdateUser, DeleteUser, and RecordTimezoneChange
+// would, all under one hold of the lock. Each change is checked against the users as the changes before
+// it leave them, and nothing is applied unless all of them pass.
+func (r *UserRepository) ApplyUserChanges(ctx context.Context, changes []models.UserChange) error {
+	if err := r.db.lock(ctx); err != nil {
+		return fmt.Errorf("repository: failed to apply user changes: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	pending := &pendingUsers{r: r, exists: map[uuid.UUID]bool{}, emails: map[string]uuid.UUID{}, usernames: map[string]uuid.UUID{}}
+	for i, change := range changes {
+		if err := pending.check(change); err != nil {
+			return &repository.ChangeError{Index: i, Err: err}
+		}
+		pending.add(change)
+	}
+	for _, change := range changes {
+		user := change.User
+		switch change.Op {
+		case models.BulkCreate:
+			r.insertUser(user)
+		case models.BulkUpdate:
+			r.updateUser(user)
+			if change.TimezoneChanged {
+				r.recordTimezone(user.ID, user.Timezone, user.UpdatedAt)
+			}
+		case models.BulkDelete:
+			r.removeUser(user.ID)
+		}
+	}
+	logger.FromContext(ctx).Infof("Applied %d user changes", len(changes))
+	return nil
+}
+
+// pendingUsers is what the changes of a batch checked so far do to users, so that each change is checked
+// against the users as the changes before it leave them. The lock must be held while it is used.
+type pendingUsers struct {
+	r         *UserRepository
+	exists    map[uuid.UUID]bool   // Users created or deleted
+	emails    map[string]uuid.UUID // Owners by email key; uuid.Nil for keys freed
+	usernames map[string]uuid.UUID // Likewise by username
+}
+
+// check returns why a change would fail after the changes added so far, or nil.
+func (p *pendingUsers) check(change models.UserChange) error {
+	user := change.User
+	found, ok := p.exists[user.ID]
+	if !ok {
+		found = p.r.db.users[user.ID] != nil
+	}
+	switch change.Op {
+	case models.BulkCreate:
+		if found {
+			return fmt.Errorf("repository: failed to create user: duplicate ID %s", user.ID)
+		}
+	case models.BulkUpdate, models.BulkDelete:
+		if !found {
+			return repository.Errorf(repository.ErrNotFound, "repository: user %s not found", user.ID)
+		}
+		if change.Op == models.BulkDelete {
+			return nil
+		}
+	default:
+		return fmt.Errorf("repository: unknown user change %q", change.Op)
+	}
+	if owner := p.emailOwner(models.EmailKey(user.Email)); owner != uuid.Nil && owner != user.ID {
+		return repository.Errorf(repository.ErrDuplicateEmail, "repository: failed to %s user: email already in use", change.Op)
+	}
+	if owner := p.usernameOwner(user.Username); owner != uuid.Nil && owner != user.ID {
+		return repository.Errorf(repository.ErrDuplicateUsername, "repository: failed to %s user: username already taken", change.Op)
+	}
+	return nil
+}
+
+// add records a change that passed check.
+func (p *pendingUsers) add(change models.UserChange) {
+	user := change.User
+	if row := p.r.db.users[user.ID]; row != nil {
+		p.emails[row.emailKey] = uuid.Nil
+		if row.user.Username != "" {
+			p.usernames[row.user.Username] = uuid.Nil
+		}
+	}
+	p.exists[user.ID] = change.Op != models.BulkDelete
+	if change.Op != models.BulkDelete {
+		p.emails[models.EmailKey(user.Email)] = user.ID
+		if user.Username != "" {
+			p.usernames[user.Username] = user.ID
+		}
+	}
+}
+
+func (p *pendingUsers) emailOwner(key string) uuid.UUID {
+	if id, ok := p.emails[key]; ok {
+		return id
+	}
+	if row := p.r.userByEmailKey(key); row != nil {
+		return row.user.ID
+	}
+	return uuid.Nil
+}
+
+func (p *pendingUsers) usernameOwner(username string) uuid.UUID {
+	if id, ok := p.usernames[username]; ok {
+		return id
+	}
+	if row := p.r.userByUsername(username); row != nil {
+		return row.user.ID
+	}
+	return uuid.Nil
+}
+
+// MarkEmailVerified records that a user proved they receive mail at their current email. An earlier
+// verification of the same email is kept.
+func (r *UserRepository) MarkEmailVerified(ctx context.Context, userID uuid.UUID, at time.Time) error {
+	if err := r.db.lock(ctx); err != nil {
+		return fmt.Errorf("repository: failed to mark email verified: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	if row := r.db.users[userID]; row != nil && row.user.EmailVerifiedAt == nil {
+		row.user.EmailVerifiedAt = &at
+	}
+	return nil
+}
+
+// RecordEmailDeliveryEvent applies a bounce or complaint about email to the user's email deliverability,
+// and returns the resulting status. It returns "" and changes nothing if email is no longer the user's.
+// Suppressing the email also clears its verification.
+func (r *UserRepository) RecordEmailDeliveryEvent(ctx context.Context, userID uuid.UUID, email, kind string, at time.Time) (string, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return "", fmt.Errorf("repository: failed to record email delivery event: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	row := r.db.users[userID]
+	if row == nil || row.user.Email != email {
+		return "", nil
+	}
+	next, softBounces := models.NextEmailStatus(row.user.EmailStatus, row.softBounces, row.statusAt, kind, at)
+	row.user.EmailStatus, row.softBounces, row.statusAt = next, softBounces, &at
+	if next == models.EmailBounced || next == models.EmailComplained {
+		row.user.EmailVerifiedAt = nil
+	}
+	return next, nil
+}
+
+// CreateEmailVerificationToken stores the hash of a token emailed to a user's current email.
+func (r *UserRepository) CreateEmailVerificationToken(ctx context.Context, userID uuid.UUID, email, tokenHash string, expiresAt time.Time) error {
+	if err := r.db.lock(ctx); err != nil {
+		return fmt.Errorf("repository: failed to create email verification token: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	if r.db.emailTokens[tokenHash] != nil {
+		return fmt.Errorf("repository: failed to create email verification token: duplicate token")
+	}
+	r.db.emailTokens[tokenHash] = &tokenRow{userID: userID, email: email, expiresAt: expiresAt.UTC()}
+	return nil
+}
+
+// ConsumeEmailVerificationToken marks an unused, unexpired token of the user as used and returns the
+// email it was sent to. It returns "" (and no error) if the token is invalid.
+func (r *UserRepository) ConsumeEmailVerificationToken(ctx context.Context, userID uuid.UUID, tokenHash string) (string, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return "", fmt.Errorf("repository: failed to consume email verification token: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	token := r.db.emailTokens[tokenHash]
+	if token == nil || token.userID != userID || token.used || !token.expiresAt.After(time.Now()) {
+		return "", nil
+	}
+	token.used = true
+	return token.email, nil
+}
+
+// RestoreEmailDeliverability marks the user's email verified and deliverable again, lifting its
+// suppression. It returns false if email is no longer the user's.
+func (r *UserRepository) RestoreEmailDeliverability(ctx context.Context, userID uuid.UUID, email string, at time.Time) (bool, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return false, fmt.Errorf("repository: failed to restore email deliverability: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	row := r.db.users[userID]
+	if row == nil || row.user.Email != email {
+		return false, nil
+	}
+	row.user.EmailStatus, row.softBounces, row.statusAt = models.EmailOK, 0, &at
+	if row.user.EmailVerifiedAt == nil {
+		row.user.EmailVerifiedAt = &at
+	}
+	return true, nil
+}
+
+// CreatePasswordResetToken stores the hash of a newly issued password reset token.
+func (r *UserRepository) CreatePasswordResetToken(ctx context.Context, userID uuid.UUID, tokenHash string, expiresAt time.Time) error {
+	if err := r.db.lock(ctx); err != nil {
+		return fmt.Errorf("repository: failed to create password reset token: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	if r.db.resetTokens[tokenHash] != nil {
+		return fmt.Errorf("repository: failed to create password reset token: duplicate token")
+	}
+	r.db.resetTokens[tokenHash] = &tokenRow{userID: userID, expiresAt: expiresAt.UTC()}
+	logger.FromContext(ctx).Debugf("Password reset token stored for user: %s", userID)
+	return nil
+}
+
+// ConsumePasswordResetToken marks an unused, unexpired token as used and returns its owner. It
+// returns uuid.Nil (and no error) if the token is invalid.
+func (r *UserRepository) ConsumePasswordResetToken(ctx context.Context, tokenHash string) (uuid.UUID, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return uuid.Nil, fmt.Errorf("repository: failed to consume password reset token: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	token := r.db.resetTokens[tokenHash]
+	if token == nil || token.used || !token.expiresAt.After(time.Now()) {
+		logger.FromContext(ctx).Debug("Password reset token is unknown, used, or expired.")
+		return uuid.Nil, nil
+	}
+	token.used = true
+	return token.userID, nil
+}
+
+// RecordTimezoneChange adds an entry to the user's timezone history, replacing one with the same start.
+func (r *UserRepository) RecordTimezoneChange(ctx context.Context, userID uuid.UUID, timezone string, effectiveFrom time.Time) error {
+	if err := r.db.lock(ctx); err != nil {
+		return fmt.Errorf("repository: failed to record timezone change: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	if r.db.users[userID] == nil {
+		return fmt.Errorf("repository: failed to record timezone change: user %s does not exist", userID)
+	}
+	r.recordTimezone(userID, timezone, effectiveFrom)
+	logger.FromContext(ctx).Debugf("Timezone for user %s set to %s from %s", userID, timezone, effectiveFrom)
+	return nil
+}
+
+// recordTimezone is RecordTimezoneChange with the lock held, for a user that exists.
+func (r *UserRepository) recordTimezone(userID uuid.UUID, timezone string, effectiveFrom time.Time) {
+	effectiveFrom = effectiveFrom.UTC()
+	history := r.db.timezones[userID]
+	for i := range history {
+		if history[i].EffectiveFrom.Equal(effectiveFrom) {
+			history[i].Timezone = timezone
+			return
+		}
+	}
+	history = append(history, models.TimezonePeriod{Timezone: timezone, EffectiveFrom: effectiveFrom})
+	sort.Slice(history, func(i, j int) bool { return history[i].EffectiveFrom.Before(history[j].EffectiveFrom) })
+	r.db.timezones[userID] = history
+}
+
+// GetTimezoneHistory returns a user's timezone history, oldest first.
+func (r *UserRepository) GetTimezoneHistory(ctx context.Context, userID uuid.UUID) ([]models.TimezonePeriod, error) {
+	if err := r.db.lock(ctx); err != nil {
+		return nil, fmt.Errorf("repository: failed to get timezone history: %w", err)
+	}
+	defer r.db.mu.Unlock()
+
+	return append([]models.TimezonePeriod{}, r.db.timezones[userID]...), nil
+}
@@ -2,17 +2,262 @@
 package repository
 
 import (
+	"context"
+	"time"
+
 	"github.com/google/uuid"
 	"health-tracker-project/services/user-service/internal/models"
 )
 
-// UserRepository defines the interface for user data operations.
+// UserRepository defines the interface for user data operations. Queries are cancelled with ctx,
+// such as when the client of the request making them disconnects.
 type UserRepository interface {
-	CreateUser(user *models.User) error
-	GetUserByEmail(email string) (*models.User, error)
-	GetUserByID(id uuid.UUID) (*models.User, error)
-	GetAllUsers() ([]models.User, error)
-	UpdateUser(user *models.User) error
-	DeleteUser(id uuid.UUID) error
+	CreateUser(ctx context.Context, user *models.User) error
+	GetUserByEmail(ctx context.Context, email string) (*models.User, error)       // email must be normalized; matches by models.EmailKey, then email aliases
+	GetUserByUsername(ctx context.Context, username string) (*models.User, error) // username must be lowercased
+	GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error)
+	GetAllUsers(ctx context.Context, after *models.PageKey, limit int) ([]models.User, error)                    // Oldest first, from after
+	ListUsers(ctx context.Context, filter models.UserFilter) ([]models.User, error)                              // Newest first
+	CountUsers(ctx context.Context, filter models.UserFilter, activeSince time.Time) (*models.UserCounts, error) // Ignores the filter's paging
+	SearchUsers(ctx context.Context, query string, limit int) ([]models.UserMatch, error)                        // Best match first; query must be lowercased
+	MarkEmailVerified(ctx context.Context, userID uuid.UUID, at time.Time) error
+	RecordEmailDeliveryEvent(ctx context.Context, userID uuid.UUID, email, kind string, at time.Time) (string, error) // "" if email is no longer the user's
+	CreateEmailVerificationToken(ctx context.Context, userID uuid.UUID, email, tokenHash string, expiresAt time.Time) error
+	ConsumeEmailVerificationToken(ctx context.Context, userID uuid.UUID, tokenHash string) (string, error)      // The email it was sent to; "" if invalid
+	RestoreEmailDeliverability(ctx context.Context, userID uuid.UUID, email string, at time.Time) (bool, error) // false if email is no longer the user's
+	UpdateUser(ctx context.Context, user *models.User) error
+	DeleteUser(ctx context.Context, id uuid.UUID) error
+	// ApplyUserChanges applies creates, updates, and deletions of users in one transaction: all of them,
+	// or none if one fails, which it reports as a *ChangeError. No two changes may be to the same user.
+	ApplyUserChanges(ctx context.Context, changes []models.UserChange) error
+	CreatePasswordResetToken(ctx context.Context, userID uuid.UUID, tokenHash string, expiresAt time.Time) error
+	ConsumePasswordResetToken(ctx context.Context, tokenHash string) (uuid.UUID, error)
+	RecordTimezoneChange(ctx context.Context, userID uuid.UUID, timezone string, effectiveFrom time.Time) error
+	GetTimezoneHistory(ctx context.Context, userID uuid.UUID) ([]models.TimezonePeriod, error)
+	MergeUsers(ctx context.Context, merge *models.UserMerge) error
+	GetUserMerge(ctx context.Context, id uuid.UUID) (*models.UserMerge, error)
+	UndoUserMerge(ctx context.Context, merge *models.UserMerge, undoneBy string) error
+	GetProfilePromptDismissals(ctx context.Context, userID uuid.UUID) (map[string]models.ProfilePromptDismissal, error)
+	DismissProfilePrompt(ctx context.Context, userID uuid.UUID, field string, at time.Time) error
+	ListAggregationPeriods(ctx context.Context, userID uuid.UUID) ([]models.AggregationPeriod, error)
+	CreateAggregationPeriod(ctx context.Context, period *models.AggregationPeriod) error
+	UpdateAggregationPeriod(ctx context.Context, period *models.AggregationPeriod) (bool, error) // false if the user has no such period
+	DeleteAggregationPeriod(ctx context.Context, userID, id uuid.UUID) (bool, error)
+	GetUserMetadata(ctx context.Context, userID uuid.UUID) (models.UserMetadata, error)
+	// MergeUserMetadata returns nil if the user is missing or the merged metadata exceeds maxBytes.
+	MergeUserMetadata(ctx context.Context, userID uuid.UUID, set models.UserMetadata, remove []string, maxBytes int) (models.UserMetadata, error)
+	GetOnboarding(ctx context.Context, userID uuid.UUID) (*models.OnboardingState, error)
+	AdvanceOnboarding(ctx context.Context, userID uuid.UUID, from, to string, skip bool, at time.Time) (bool, error) // false if the user is no longer at from
+	CountOnboardingSteps(ctx context.Context, since time.Time) ([]models.OnboardingStepCount, error)
+	StorageUsage(ctx context.Context) (map[uuid.UUID]int64, error)                          // Bytes stored per user, for metering
+	SummarizeUserData(ctx context.Context, userID uuid.UUID) ([]models.DataCategory, error) // nil if the user is missing
+	CountAnnouncementRecipients(ctx context.Context, segment models.AnnouncementSegment) (*models.AnnouncementAudience, error)
+	ListAnnouncementRecipients(ctx context.Context, segment models.AnnouncementSegment, after uuid.UUID, limit int) ([]uuid.UUID, error) // In ID order
+	ListDueDeletions(ctx context.Context, now time.Time, limit int) ([]models.User, error)
+	EraseUser(ctx context.Context, id uuid.UUID) (blobKeys []string, err error) // Removes the user and every row about them
+	// ResealHealthFields seals the health fields of up to limit users still in plaintext or under a
+	// previous key with HealthSealer's current key, and returns how many it changed.
+	ResealHealthFields(ctx context.Context, limit int) (int, error)
 	Migrate() error // Method to run database migrations
-}
\ No newline at end of file
+}
+
+// SystemEventRepository defines the interface for the operational event timeline.
+type SystemEventRepository interface {
+	CreateEvent(ctx context.Context, event *models.SystemEvent) error
+	ListEvents(ctx context.Context, filter models.SystemEventFilter) ([]models.SystemEvent, error)
+	Migrate() error
+}
+
+// AnnouncementRepository defines the interface for announcements admins send to segments of users.
+// They are stored in the home database; their recipients may live in any region.
+type AnnouncementRepository interface {
+	CreateAnnouncement(ctx context.Context, a *models.Announcement) error
+	GetAnnouncement(ctx context.Context, id uuid.UUID) (*models.Announcement, error)                        // nil if missing
+	ListAnnouncements(ctx context.Context, filter models.AnnouncementFilter) ([]models.Announcement, error) // Newest first
+	ClaimDueAnnouncement(ctx context.Context, now, leaseUntil time.Time) (*models.Announcement, error)      // nil if none is due
+	SetAnnouncementRecipients(ctx context.Context, id uuid.UUID, recipients int) error
+	RecordAnnouncementProgress(ctx context.Context, id, cursor uuid.UUID, sent, failed int, leaseUntil time.Time) (bool, error) // false once no longer sending
+	FinishAnnouncement(ctx context.Context, id uuid.UUID, at time.Time) error
+	CancelAnnouncement(ctx context.Context, id uuid.UUID, at time.Time) (bool, error) // false if missing or finished
+	Migrate() error
+}
+
+// UserEventRepository defines the interface for per-user domain event timelines.
+type UserEventRepository interface {
+	CreateEvent(ctx context.Context, event *models.UserEvent) error
+	ListEvents(ctx context.Context, filter models.UserEventFilter) ([]models.UserEvent, error)
+	Migrate() error
+}
+
+// AuditRepository defines the interface for the security audit log.
+type AuditRepository interface {
+	CreateEvent(ctx context.Context, event *models.AuditEvent) error
+	ListEvents(ctx context.Context, filter models.AuditEventFilter) ([]models.AuditEvent, error)
+	AnonymizeSubject(ctx context.Context, userID, email, pseudonym string) (int64, error) // Replaces the user's ID and drops their email, IP, and user agent
+	Migrate() error
+}
+
+// DashboardRepository defines the interface for per-user dashboard layouts.
+type DashboardRepository interface {
+	GetLayout(ctx context.Context, userID uuid.UUID) (*models.DashboardLayout, error)
+	SaveLayout(ctx context.Context, userID uuid.UUID, layout *models.DashboardLayout) error
+	DeleteLayout(ctx context.Context, userID uuid.UUID) error
+	Migrate() error
+}
+
+// SettingsRepository defines the interface for per-user settings. Only the values users set are stored.
+type SettingsRepository interface {
+	GetSettings(ctx context.Context, userID uuid.UUID) (*models.UserSettings, error)
+	SaveSettings(ctx context.Context, userID uuid.UUID, settings *models.UserSettings) error
+	Migrate() error
+}
+
+// LoginAttemptRepository defines the interface for per-user login history.
+type LoginAttemptRepository interface {
+	CreateAttempt(ctx context.Context, attempt *models.LoginAttempt) error
+	ListAttempts(ctx context.Context, filter models.LoginAttemptFilter) ([]models.LoginAttempt, error)
+	Migrate() error
+}
+
+// IdentityRepository defines the interface for SSO identities linked to users and pending links.
+type IdentityRepository interface {
+	GetIdentity(ctx context.Context, issuer, subject string) (*models.UserIdentity, error)
+	ListIdentities(ctx context.Context, userID uuid.UUID) ([]models.UserIdentity, error)
+	CreateIdentity(ctx context.Context, identity *models.UserIdentity) error
+	CreateLinkRequest(ctx context.Context, req *models.IdentityLinkRequest) error
+	GetLinkRequest(ctx context.Context, tokenHash string) (*models.IdentityLinkRequest, error)
+	IncrementLinkAttempts(ctx context.Context, id uuid.UUID) error
+	DeleteLinkRequest(ctx context.Context, id uuid.UUID) (bool, error)
+	CreateSAMLRequest(ctx context.Context, id string, expiresAt time.Time) error
+	ConsumeSAMLRequest(ctx context.Context, id string) (bool, error) // false if unknown, already consumed, or expired
+	Migrate() error
+}
+
+// SessionRepository defines the interface for signed-in sessions.
+type SessionRepository interface {
+	CreateSession(ctx context.Context, session *models.Session, maxPerUser int) ([]uuid.UUID, error) // Returns the IDs of the older sessions evicted
+	GetSession(ctx context.Context, userID, id uuid.UUID) (*models.Session, error)
+	DeleteSession(ctx context.Context, userID, id uuid.UUID) error
+	DeleteUserSessions(ctx context.Context, userID uuid.UUID) error
+	DeleteExpiredSessions(ctx context.Context, before time.Time) (int64, error)
+	CountActiveSessions(ctx context.Context) (sessions int64, users int64, err error)
+	Migrate() error
+}
+
+// EventRelay broadcasts small payloads to every replica of the service, the sending one included.
+// Delivery is best effort: payloads sent while a replica is reconnecting do not reach it.
+type EventRelay interface {
+	Send(payload []byte) error
+	Listen(deliver func(payload []byte)) // Never returns; reconnects after failures
+}
+
+// ResidencyRepository defines the interface for moving users between data residency regions.
+type ResidencyRepository interface {
+	Regions() []string
+	MigrateSubject(ctx context.Context, userID uuid.UUID, region string) error
+	FindViolations(ctx context.Context) ([]models.ResidencyViolation, error)
+}
+
+// DeveloperAppRepository defines the interface for public API developer apps and their usage.
+type DeveloperAppRepository interface {
+	CreateApp(ctx context.Context, app *models.DeveloperApp) error
+	GetApp(ctx context.Context, id uuid.UUID) (*models.DeveloperApp, error)
+	GetAppByKeyHash(ctx context.Context, keyHash string) (*models.DeveloperApp, error)
+	ListApps(ctx context.Context, ownerID uuid.UUID) ([]models.DeveloperApp, error)
+	UpdateApp(ctx context.Context, app *models.DeveloperApp) error // Saves the key, revocation, and debug window
+	DeleteOwnerApps(ctx context.Context, ownerID uuid.UUID) (int64, error)
+	AddUsage(ctx context.Context, appID uuid.UUID, usage models.DeveloperAppUsage) error // Adds to the counts for the day and route
+	CountRequests(ctx context.Context, appID uuid.UUID, day time.Time) (int64, error)
+	ListUsage(ctx context.Context, appID uuid.UUID, since, until time.Time) ([]models.DeveloperAppUsage, error)
+	AddRecording(ctx context.Context, appID uuid.UUID, entry models.HAREntry, keep int) error // Keeps only the newest keep recordings
+	ListRecordings(ctx context.Context, appID uuid.UUID) ([]models.HAREntry, error)
+	DeleteRecordings(ctx context.Context, appID uuid.UUID) error
+	Migrate() error
+}
+
+// MeteringRepository defines the interface for the append-only usage metering store.
+type MeteringRepository interface {
+	RecordEvent(ctx context.Context, event *models.MeteringEvent) (bool, error) // False when the idempotency key was already recorded
+	Summarize(ctx context.Context, filter models.MeteringFilter) ([]models.MeterTotal, []models.UserMeterTotal, error)
+	Migrate() error
+}
+
+// ConsentRepository defines the interface for users' consents to third-party integrations.
+type ConsentRepository interface {
+	GrantConsent(ctx context.Context, consent *models.IntegrationConsent) error // Supersedes the active consent for the same provider
+	GetActiveConsent(ctx context.Context, userID uuid.UUID, provider string) (*models.IntegrationConsent, error)
+	ListConsents(ctx context.Context, userID uuid.UUID) ([]models.IntegrationConsent, error)
+	RevokeConsent(ctx context.Context, userID uuid.UUID, provider string, deleteData bool, at time.Time) (*models.IntegrationConsent, error)
+	ListRevocations(ctx context.Context, filter models.ConsentRevocationFilter) ([]models.IntegrationConsent, error)
+	Migrate() error
+}
+
+// MessagingRepository defines the interface for coach authorizations and coach messaging.
+// Methods taking a userID store or find the rows in that user's data (the thread's non-coach user).
+type MessagingRepository interface {
+	AuthorizeCoach(ctx context.Context, auth *models.CoachAuthorization) error
+	RevokeCoach(ctx context.Context, userID, coachID uuid.UUID, at time.Time) (bool, error)
+	IsCoachAuthorized(ctx context.Context, userID, coachID uuid.UUID) (bool, error)
+	ListCoaches(ctx context.Context, userID uuid.UUID) ([]models.CoachAuthorization, error)
+	ListClients(ctx context.Context, coachID uuid.UUID) ([]models.CoachAuthorization, error)
+	CreateThread(ctx context.Context, thread *models.MessageThread) error
+	GetThread(ctx context.Context, id uuid.UUID) (*models.MessageThread, error)
+	ListThreads(ctx context.Context, participantID uuid.UUID) ([]models.MessageThread, error)
+	CreateMessage(ctx context.Context, userID uuid.UUID, msg *models.Message, attachmentIDs []uuid.UUID) error
+	ListMessages(ctx context.Context, userID uuid.UUID, filter models.MessageFilter) ([]models.Message, error)
+	MarkRead(ctx context.Context, userID, threadID, readerID uuid.UUID, at time.Time) (int64, error)
+	CreateAttachment(ctx context.Context, userID uuid.UUID, attachment *models.MessageAttachment) error
+	GetAttachment(ctx context.Context, userID, threadID, id uuid.UUID) (*models.MessageAttachment, error)
+	PurgeMessages(ctx context.Context, before, unsentBefore time.Time) (blobKeys []string, messages int64, err error)
+	Migrate() error
+}
+
+// AppointmentRepository defines the interface for provider availability and appointments.
+// Slots and appointments are stored with the provider's data.
+type AppointmentRepository interface {
+	CreateSlots(ctx context.Context, providerID uuid.UUID, slots []models.AppointmentSlot) error
+	ListSlots(ctx context.Context, filter models.SlotFilter) ([]models.AppointmentSlot, error)
+	GetSlot(ctx context.Context, id uuid.UUID) (*models.AppointmentSlot, error)
+	DeleteSlot(ctx context.Context, providerID, id uuid.UUID) (bool, error)
+	BookSlot(ctx context.Context, appointment *models.Appointment) error // Fills in the slot's time and location
+	GetAppointment(ctx context.Context, id uuid.UUID) (*models.Appointment, error)
+	ListAppointments(ctx context.Context, filter models.AppointmentFilter) ([]models.Appointment, error)
+	CancelAppointment(ctx context.Context, appointment *models.Appointment) (bool, error)
+	RescheduleAppointment(ctx context.Context, old, replacement *models.Appointment) error
+	ListDueReminders(ctx context.Context, before time.Time) ([]models.Appointment, error)
+	MarkReminderSent(ctx context.Context, providerID, id uuid.UUID, at time.Time) error
+	Migrate() error
+}
+
+// WorkoutAttachmentRepository defines the interface for files attached to a user's workout sets.
+type WorkoutAttachmentRepository interface {
+	CreateAttachment(ctx context.Context, attachment *models.WorkoutAttachment) error
+	GetAttachment(ctx context.Context, userID, id uuid.UUID) (*models.WorkoutAttachment, error)
+	ListAttachments(ctx context.Context, userID uuid.UUID, setRef string, sharedOnly bool) ([]models.WorkoutAttachment, error)
+	UpdateAttachment(ctx context.Context, attachment *models.WorkoutAttachment) (bool, error) // Saves the sharing flag and expiry
+	DeleteAttachment(ctx context.Context, userID, id uuid.UUID) (blobKey string, err error)
+	ListPendingScans(ctx context.Context, limit int) ([]models.WorkoutAttachment, error)
+	SetScanStatus(ctx context.Context, userID, id uuid.UUID, status string, at time.Time) error
+	PurgeExpired(ctx context.Context, before time.Time) (blobKeys []string, err error)
+	Migrate() error
+}
+
+// WorkoutPlanRepository defines the interface for the training plans coaches write and share.
+type WorkoutPlanRepository interface {
+	SavePlans(ctx context.Context, coachID uuid.UUID, plans []models.WorkoutPlan) error // Inserts or replaces by ID, all or none
+	GetPlan(ctx context.Context, coachID, id uuid.UUID) (*models.WorkoutPlan, error)
+	ListPlans(ctx context.Context, coachID uuid.UUID) ([]models.WorkoutPlan, error)
+	DeletePlan(ctx context.Context, coachID, id uuid.UUID) (bool, error)
+	Migrate() error
+}
+
+// OutboxRepository defines the interface for the outbox of domain events waiting to be published. The
+// user repository writes them, in the transaction of the change each one announces.
+type OutboxRepository interface {
+	ClaimOutboxEvents(ctx context.Context, now, leaseUntil time.Time, limit int) ([]models.OutboxEvent, error) // Pending events available at now, oldest first, leased until leaseUntil
+	MarkOutboxEventSent(ctx context.Context, id uuid.UUID, at time.Time) error
+	FailOutboxEvent(ctx context.Context, id uuid.UUID, retryAt time.Time, lastError string) error // Counts a failed attempt; the event is claimable again from retryAt
+	ReleaseOutboxEvents(ctx context.Context, ids []uuid.UUID, at time.Time) error                 // Makes claimed events claimable again from at, without counting an attempt
+	PurgeSentOutboxEvents(ctx context.Context, before time.Time) (int64, error)
+	Migrate() error
+}
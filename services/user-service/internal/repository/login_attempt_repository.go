@@ -0,0 +1,74 @@
+// services/user-service/internal/repository/login_attempt_repository.go
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// postgresLoginAttemptRepository is the PostgreSQL implementation of LoginAttemptRepository.
+type postgresLoginAttemptRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresLoginAttemptRepository creates a LoginAttemptRepository on an open pool and runs its migrations.
+// The users table must already exist.
+func NewPostgresLoginAttemptRepository(db *sql.DB) (LoginAttemptRepository, error) {
+	repo := &postgresLoginAttemptRepository{db: db}
+	if err := repo.Migrate(); err != nil {
+		return nil, fmt.Errorf("failed to run login attempt migrations: %w", err)
+	}
+	return repo, nil
+}
+
+// Migrate applies the pending migrations in migrations/login_attempts.
+func (r *postgresLoginAttemptRepository) Migrate() error {
+	return MigrateSchema(r.db, "login_attempts")
+}
+
+// CreateAttempt inserts a new login attempt.
+func (r *postgresLoginAttemptRepository) CreateAttempt(ctx context.Context, attempt *models.LoginAttempt) error {
+	query := `INSERT INTO login_attempts (id, user_id, success, method, failure_reason, ip, user_agent, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	_, err := r.db.ExecContext(ctx, query, attempt.ID, attempt.UserID, attempt.Success, attempt.Method, attempt.FailureReason, attempt.IP, attempt.UserAgent, attempt.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("repository: failed to create login attempt: %w", err)
+	}
+	return nil
+}
+
+// ListAttempts returns one user's login attempts matching the filter, newest first.
+func (r *postgresLoginAttemptRepository) ListAttempts(ctx context.Context, filter models.LoginAttemptFilter) ([]models.LoginAttempt, error) {
+	args := []interface{}{filter.UserID}
+	query := `SELECT id, user_id, success, method, failure_reason, ip, user_agent, created_at FROM login_attempts WHERE user_id = $1`
+	if filter.After != nil {
+		args = append(args, filter.After.At, filter.After.ID)
+		query += fmt.Sprintf(` AND (created_at, id) < ($%d, $%d)`, len(args)-1, len(args))
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(` ORDER BY created_at DESC, id DESC LIMIT $%d`, len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to list login attempts: %w", err)
+	}
+	defer rows.Close()
+
+	attempts := []models.LoginAttempt{}
+	for rows.Next() {
+		var a models.LoginAttempt
+		if err := rows.Scan(&a.ID, &a.UserID, &a.Success, &a.Method, &a.FailureReason, &a.IP, &a.UserAgent, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("repository: failed to scan login attempt row: %w", err)
+		}
+		attempts = append(attempts, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: rows iteration error: %w", err)
+	}
+	logger.FromContext(ctx).Debugf("Retrieved %d login attempts for user %s from DB.", len(attempts), filter.UserID)
+	return attempts, nil
+}
@@ -0,0 +1,145 @@
+// services/user-service/internal/repository/merge_repository.go
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// MergeUsers records the merge with a snapshot of the donor and removes the donor account, in one transaction.
+// The donor's external identities and email aliases move to the primary user, and the donor's email becomes
+// one of its aliases; everything else of the donor, sessions included, is removed with it.
+func (r *postgresUserRepository) MergeUsers(ctx context.Context, merge *models.UserMerge) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("repository: failed to begin merge transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	d := merge.DonorSnapshot
+	rows, err := tx.QueryContext(ctx, `UPDATE user_identities SET user_id = $1 WHERE user_id = $2 RETURNING issuer, subject`, merge.PrimaryUserID, d.ID)
+	if err != nil {
+		return fmt.Errorf("repository: failed to move donor identities: %w", err)
+	}
+	moved := []models.MergedIdentity{}
+	for rows.Next() {
+		var id models.MergedIdentity
+		if err := rows.Scan(&id.Issuer, &id.Subject); err != nil {
+			rows.Close()
+			return fmt.Errorf("repository: failed to scan moved identity: %w", err)
+		}
+		moved = append(moved, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("repository: failed to move donor identities: %w", err)
+	}
+	movedJSON, err := json.Marshal(moved)
+	if err != nil {
+		return fmt.Errorf("repository: failed to encode moved identities: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO user_merges (id, primary_user_id, donor_user_id, donor_name, donor_email, donor_password_hash, donor_role, donor_created_at, moved_identities, merged_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		merge.ID, merge.PrimaryUserID, d.ID, d.Name, d.Email, d.PasswordHash, d.Role, d.CreatedAt, movedJSON, merge.MergedBy, merge.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("repository: failed to record user merge: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE user_email_aliases SET user_id = $1 WHERE user_id = $2`, merge.PrimaryUserID, d.ID); err != nil {
+		return fmt.Errorf("repository: failed to move donor email aliases: %w", err)
+	}
+	// A legacy donor sharing its email key with another account gets no alias; the other account has the key.
+	_, err = tx.ExecContext(ctx, `INSERT INTO user_email_aliases (email_key, email, user_id, donor_user_id, merge_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT DO NOTHING`,
+		models.EmailKey(d.Email), d.Email, merge.PrimaryUserID, d.ID, merge.ID, merge.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("repository: failed to add donor email alias: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM users WHERE id = $1`, d.ID); err != nil {
+		return fmt.Errorf("repository: failed to remove donor user: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("repository: failed to commit user merge: %w", err)
+	}
+	merge.MovedIdentities = moved
+	logger.FromContext(ctx).Infof("Merged user %s into %s (merge %s), moving %d identities", d.ID, merge.PrimaryUserID, merge.ID, len(moved))
+	return nil
+}
+
+// GetUserMerge retrieves a merge record by ID. It returns nil, nil if not found.
+func (r *postgresUserRepository) GetUserMerge(ctx context.Context, id uuid.UUID) (*models.UserMerge, error) {
+	query := `SELECT id, primary_user_id, donor_user_id, donor_name, donor_email, donor_password_hash, donor_role, donor_created_at,
+		moved_identities, merged_by, created_at, undone_at, COALESCE(undone_by, '') FROM user_merges WHERE id = $1`
+	var m models.UserMerge
+	var movedJSON []byte
+	d := &m.DonorSnapshot
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&m.ID, &m.PrimaryUserID, &m.DonorUserID, &d.Name, &d.Email, &d.PasswordHash, &d.Role, &d.CreatedAt,
+		&movedJSON, &m.MergedBy, &m.CreatedAt, &m.UndoneAt, &m.UndoneBy)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("repository: failed to get user merge: %w", err)
+	}
+	if err := json.Unmarshal(movedJSON, &m.MovedIdentities); err != nil {
+		return nil, fmt.Errorf("repository: failed to decode moved identities: %w", err)
+	}
+	d.ID = m.DonorUserID
+	m.DonorEmail = d.Email
+	return &m, nil
+}
+
+// UndoUserMerge restores the donor account from its snapshot and marks the merge undone, in one transaction.
+// The donor's email alias is dropped and the identities the merge moved go back to the donor, unless unlinked
+// since; aliases the donor had from earlier merges stay with the primary user. Sessions issued before the undo
+// stay invalid.
+func (r *postgresUserRepository) UndoUserMerge(ctx context.Context, merge *models.UserMerge, undoneBy string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("repository: failed to begin undo transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	d := merge.DonorSnapshot
+	_, err = tx.ExecContext(ctx, `INSERT INTO users (id, name, email, email_key, password_hash, role, created_at, updated_at, sessions_revoked_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		d.ID, d.Name, d.Email, models.EmailKey(d.Email), d.PasswordHash, d.Role, d.CreatedAt, now, now.Truncate(time.Second))
+	if err != nil {
+		if dup := duplicateUserError(err, "restore donor user"); dup != nil {
+			return dup
+		}
+		return fmt.Errorf("repository: failed to restore donor user: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_email_aliases WHERE merge_id = $1`, merge.ID); err != nil {
+		return fmt.Errorf("repository: failed to remove donor email alias: %w", err)
+	}
+	for _, id := range merge.MovedIdentities {
+		_, err := tx.ExecContext(ctx, `UPDATE user_identities SET user_id = $1 WHERE issuer = $2 AND subject = $3 AND user_id = $4`,
+			d.ID, id.Issuer, id.Subject, merge.PrimaryUserID)
+		if err != nil {
+			return fmt.Errorf("repository: failed to move back donor identity: %w", err)
+		}
+	}
+	res, err := tx.ExecContext(ctx, `UPDATE user_merges SET undone_at = $1, undone_by = $2 WHERE id = $3 AND undone_at IS NULL`, now, undoneBy, merge.ID)
+	if err != nil {
+		return fmt.Errorf("repository: failed to mark merge undone: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return Errorf(ErrConflict, "repository: merge already undone")
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("repository: failed to commit merge undo: %w", err)
+	}
+	merge.UndoneAt = &now
+	merge.UndoneBy = undoneBy
+	logger.FromContext(ctx).Infof("Undid merge %s, restored user %s", merge.ID, d.ID)
+	return nil
+}
@@ -0,0 +1,356 @@
+// services/user-service/internal/repository/messaging_repository.go
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+)
+
+// postgresMessagingRepository is the PostgreSQL implementation of MessagingRepository.
+// Every row carries the user_id of the thread's (non-coach) user, whose data it is.
+type postgresMessagingRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresMessagingRepository creates a MessagingRepository on an open pool and runs its migrations.
+// The users table must already exist.
+func NewPostgresMessagingRepository(db *sql.DB) (MessagingRepository, error) {
+	repo := &postgresMessagingRepository{db: db}
+	if err := repo.Migrate(); err != nil {
+		return nil, fmt.Errorf("failed to run messaging migrations: %w", err)
+	}
+	return repo, nil
+}
+
+// Migrate applies the pending migrations in migrations/messaging.
+func (r *postgresMessagingRepository) Migrate() error {
+	return MigrateSchema(r.db, "messaging")
+}
+
+// AuthorizeCoach grants a coach access to the user, renewing a revoked authorization.
+func (r *postgresMessagingRepository) AuthorizeCoach(ctx context.Context, auth *models.CoachAuthorization) error {
+	query := `INSERT INTO coach_authorizations (user_id, coach_id, authorized_at) VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, coach_id) DO UPDATE SET authorized_at = EXCLUDED.authorized_at, revoked_at = NULL`
+	if _, err := r.db.ExecContext(ctx, query, auth.UserID, auth.CoachID, auth.AuthorizedAt); err != nil {
+		return fmt.Errorf("repository: failed to authorize coach: %w", err)
+	}
+	return nil
+}
+
+// RevokeCoach revokes an active authorization and reports whether there was one.
+func (r *postgresMessagingRepository) RevokeCoach(ctx context.Context, userID, coachID uuid.UUID, at time.Time) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `UPDATE coach_authorizations SET revoked_at = $3 WHERE user_id = $1 AND coach_id = $2 AND revoked_at IS NULL`, userID, coachID, at)
+	if err != nil {
+		return false, fmt.Errorf("repository: failed to revoke coach: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("repository: failed to revoke coach: %w", err)
+	}
+	return n == 1, nil
+}
+
+// IsCoachAuthorized reports whether the user currently authorizes the coach.
+func (r *postgresMessagingRepository) IsCoachAuthorized(ctx context.Context, userID, coachID uuid.UUID) (bool, error) {
+	var authorized bool
+	err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM coach_authorizations WHERE user_id = $1 AND coach_id = $2 AND revoked_at IS NULL)`,
+		userID, coachID).Scan(&authorized)
+	if err != nil {
+		return false, fmt.Errorf("repository: failed to check coach authorization: %w", err)
+	}
+	return authorized, nil
+}
+
+// listAuthorizations returns the authorizations given by a user (column "user_id") or to a coach
+// (column "coach_id"), including revoked ones, newest first.
+func (r *postgresMessagingRepository) listAuthorizations(ctx context.Context, column string, id uuid.UUID) ([]models.CoachAuthorization, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT user_id, coach_id, authorized_at, revoked_at FROM coach_authorizations
+		WHERE `+column+` = $1 ORDER BY authorized_at DESC`, id)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to list coach authorizations: %w", err)
+	}
+	defer rows.Close()
+
+	auths := []models.CoachAuthorization{}
+	for rows.Next() {
+		var a models.CoachAuthorization
+		var revokedAt sql.NullTime
+		if err := rows.Scan(&a.UserID, &a.CoachID, &a.AuthorizedAt, &revokedAt); err != nil {
+			return nil, fmt.Errorf("repository: failed to scan coach authorization: %w", err)
+		}
+		if revokedAt.Valid {
+			a.RevokedAt = &revokedAt.Time
+		}
+		auths = append(auths, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: failed to list coach authorizations: %w", err)
+	}
+	return auths, nil
+}
+
+// ListCoaches returns the coaches a user has authorized, including revoked ones, newest first.
+func (r *postgresMessagingRepository) ListCoaches(ctx context.Context, userID uuid.UUID) ([]models.CoachAuthorization, error) {
+	return r.listAuthorizations(ctx, "user_id", userID)
+}
+
+// ListClients returns the users who have authorized a coach, including revoked ones, newest first.
+func (r *postgresMessagingRepository) ListClients(ctx context.Context, coachID uuid.UUID) ([]models.CoachAuthorization, error) {
+	return r.listAuthorizations(ctx, "coach_id", coachID)
+}
+
+// CreateThread stores a new thread.
+func (r *postgresMessagingRepository) CreateThread(ctx context.Context, thread *models.MessageThread) error {
+	query := `INSERT INTO message_threads (id, user_id, coach_id, subject, created_at, last_message_at) VALUES ($1, $2, $3, $4, $5, $6)`
+	if _, err := r.db.ExecContext(ctx, query, thread.ID, thread.UserID, thread.CoachID, thread.Subject, thread.CreatedAt, thread.LastMessageAt); err != nil {
+		return fmt.Errorf("repository: failed to create thread: %w", err)
+	}
+	return nil
+}
+
+// GetThread returns a thread by ID, or nil if it does not exist.
+func (r *postgresMessagingRepository) GetThread(ctx context.Context, id uuid.UUID) (*models.MessageThread, error) {
+	var t models.MessageThread
+	err := r.db.QueryRowContext(ctx, `SELECT id, user_id, coach_id, subject, created_at, last_message_at FROM message_threads WHERE id = $1`, id).
+		Scan(&t.ID, &t.UserID, &t.CoachID, &t.Subject, &t.CreatedAt, &t.LastMessageAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to get thread: %w", err)
+	}
+	return &t, nil
+}
+
+// ListThreads returns the threads a user or coach takes part in, most recently active first,
+// with the number of messages from the other participant they have not read.
+func (r *postgresMessagingRepository) ListThreads(ctx context.Context, participantID uuid.UUID) ([]models.MessageThread, error) {
+	query := `
+	SELECT t.id, t.user_id, t.coach_id, t.subject, t.created_at, t.last_message_at,
+		(SELECT COUNT(*) FROM messages m WHERE m.thread_id = t.id AND m.sender_id <> $1 AND m.read_at IS NULL)
+	FROM message_threads t
+	WHERE t.user_id = $1 OR t.coach_id = $1
+	ORDER BY t.last_message_at DESC`
+	rows, err := r.db.QueryContext(ctx, query, participantID)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to list threads: %w", err)
+	}
+	defer rows.Close()
+
+	threads := []models.MessageThread{}
+	for rows.Next() {
+		var t models.MessageThread
+		if err := rows.Scan(&t.ID, &t.UserID, &t.CoachID, &t.Subject, &t.CreatedAt, &t.LastMessageAt, &t.Unread); err != nil {
+			return nil, fmt.Errorf("repository: failed to scan thread: %w", err)
+		}
+		threads = append(threads, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: failed to list threads: %w", err)
+	}
+	return threads, nil
+}
+
+// CreateMessage stores a message, attaches the sender's unsent uploads listed in attachmentIDs, and
+// bumps the thread's activity time, all in one transaction. msg.Attachments is filled in.
+func (r *postgresMessagingRepository) CreateMessage(ctx context.Context, userID uuid.UUID, msg *models.Message, attachmentIDs []uuid.UUID) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("repository: failed to begin message transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `INSERT INTO messages (id, thread_id, user_id, sender_id, body, created_at) VALUES ($1, $2, $3, $4, $5, $6)`,
+		msg.ID, msg.ThreadID, userID, msg.SenderID, msg.Body, msg.CreatedAt); err != nil {
+		return fmt.Errorf("repository: failed to create message: %w", err)
+	}
+
+	msg.Attachments = []models.MessageAttachment{}
+	if len(attachmentIDs) > 0 {
+		rows, err := tx.QueryContext(ctx, `UPDATE message_attachments SET message_id = $1
+			WHERE id = ANY($2::uuid[]) AND thread_id = $3 AND uploader_id = $4 AND message_id IS NULL
+			RETURNING `+attachmentColumns, msg.ID, attachmentIDs, msg.ThreadID, msg.SenderID)
+		if err != nil {
+			return fmt.Errorf("repository: failed to attach uploads: %w", err)
+		}
+		msg.Attachments, err = collectAttachments(rows)
+		if err != nil {
+			return err
+		}
+		if len(msg.Attachments) != len(attachmentIDs) {
+			return Errorf(ErrConflict, "repository: attachment was already sent or removed")
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE message_threads SET last_message_at = $2 WHERE id = $1`, msg.ThreadID, msg.CreatedAt); err != nil {
+		return fmt.Errorf("repository: failed to update thread: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("repository: failed to commit message: %w", err)
+	}
+	return nil
+}
+
+// ListMessages returns a page of a thread's messages with their attachments, newest first.
+func (r *postgresMessagingRepository) ListMessages(ctx context.Context, userID uuid.UUID, filter models.MessageFilter) ([]models.Message, error) {
+	args := []interface{}{filter.ThreadID}
+	query := `SELECT id, thread_id, sender_id, body, created_at, read_at FROM messages WHERE thread_id = $1`
+	if filter.After != nil {
+		args = append(args, filter.After.At, filter.After.ID)
+		query += fmt.Sprintf(` AND (created_at, id) < ($%d, $%d)`, len(args)-1, len(args))
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(` ORDER BY created_at DESC, id DESC LIMIT $%d`, len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to list messages: %w", err)
+	}
+	defer rows.Close()
+
+	messages := []models.Message{}
+	index := map[uuid.UUID]int{}
+	ids := []uuid.UUID{}
+	for rows.Next() {
+		var m models.Message
+		var readAt sql.NullTime
+		if err := rows.Scan(&m.ID, &m.ThreadID, &m.SenderID, &m.Body, &m.CreatedAt, &readAt); err != nil {
+			return nil, fmt.Errorf("repository: failed to scan message: %w", err)
+		}
+		if readAt.Valid {
+			m.ReadAt = &readAt.Time
+		}
+		m.Attachments = []models.MessageAttachment{}
+		index[m.ID] = len(messages)
+		ids = append(ids, m.ID)
+		messages = append(messages, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: failed to list messages: %w", err)
+	}
+	if len(messages) == 0 {
+		return messages, nil
+	}
+
+	attRows, err := r.db.QueryContext(ctx, `SELECT `+attachmentColumns+` FROM message_attachments
+		WHERE message_id = ANY($1::uuid[]) ORDER BY created_at`, ids)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to list attachments: %w", err)
+	}
+	attachments, err := collectAttachments(attRows)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range attachments {
+		i := index[*a.MessageID]
+		messages[i].Attachments = append(messages[i].Attachments, a)
+	}
+	return messages, nil
+}
+
+// MarkRead sets the read receipt on every unread message in the thread not sent by the reader.
+func (r *postgresMessagingRepository) MarkRead(ctx context.Context, userID, threadID, readerID uuid.UUID, at time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `UPDATE messages SET read_at = $3 WHERE thread_id = $1 AND sender_id <> $2 AND read_at IS NULL`, threadID, readerID, at)
+	if err != nil {
+		return 0, fmt.Errorf("repository: failed to mark messages read: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("repository: failed to mark messages read: %w", err)
+	}
+	return n, nil
+}
+
+const attachmentColumns = `id, thread_id, message_id, uploader_id, filename, content_type, size, blob_key, created_at`
+
+func collectAttachments(rows *sql.Rows) ([]models.MessageAttachment, error) {
+	defer rows.Close()
+	attachments := []models.MessageAttachment{}
+	for rows.Next() {
+		var a models.MessageAttachment
+		var messageID uuid.NullUUID
+		if err := rows.Scan(&a.ID, &a.ThreadID, &messageID, &a.UploaderID, &a.Filename, &a.ContentType, &a.Size, &a.BlobKey, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("repository: failed to scan attachment: %w", err)
+		}
+		if messageID.Valid {
+			a.MessageID = &messageID.UUID
+		}
+		attachments = append(attachments, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: failed to read attachments: %w", err)
+	}
+	return attachments, nil
+}
+
+// CreateAttachment records an upload whose content is already in the blob store.
+func (r *postgresMessagingRepository) CreateAttachment(ctx context.Context, userID uuid.UUID, a *models.MessageAttachment) error {
+	query := `INSERT INTO message_attachments (id, thread_id, user_id, uploader_id, filename, content_type, size, blob_key, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+	if _, err := r.db.ExecContext(ctx, query, a.ID, a.ThreadID, userID, a.UploaderID, a.Filename, a.ContentType, a.Size, a.BlobKey, a.CreatedAt); err != nil {
+		return fmt.Errorf("repository: failed to create attachment: %w", err)
+	}
+	return nil
+}
+
+// GetAttachment returns an attachment of a thread, or nil if it does not exist.
+func (r *postgresMessagingRepository) GetAttachment(ctx context.Context, userID, threadID, id uuid.UUID) (*models.MessageAttachment, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+attachmentColumns+` FROM message_attachments WHERE id = $1 AND thread_id = $2`, id, threadID)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to get attachment: %w", err)
+	}
+	attachments, err := collectAttachments(rows)
+	if err != nil || len(attachments) == 0 {
+		return nil, err
+	}
+	return &attachments[0], nil
+}
+
+// PurgeMessages deletes messages created before a cutoff, and uploads never sent that were made before
+// unsentBefore. It returns the blob keys of the deleted attachments, for removal from the blob store.
+func (r *postgresMessagingRepository) PurgeMessages(ctx context.Context, before, unsentBefore time.Time) ([]string, int64, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("repository: failed to begin purge: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `DELETE FROM message_attachments a
+		WHERE (a.message_id IS NULL AND a.created_at < $2)
+			OR a.message_id IN (SELECT id FROM messages WHERE created_at < $1)
+		RETURNING blob_key`, before, unsentBefore)
+	if err != nil {
+		return nil, 0, fmt.Errorf("repository: failed to purge attachments: %w", err)
+	}
+	keys := []string{}
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			rows.Close()
+			return nil, 0, fmt.Errorf("repository: failed to scan purged attachment: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("repository: failed to purge attachments: %w", err)
+	}
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM messages WHERE created_at < $1`, before)
+	if err != nil {
+		return nil, 0, fmt.Errorf("repository: failed to purge messages: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return nil, 0, fmt.Errorf("repository: failed to purge messages: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, 0, fmt.Errorf("repository: failed to commit purge: %w", err)
+	}
+	return keys, n, nil
+}
@@ -0,0 +1,94 @@
+// services/user-service/internal/repository/metering_repository.go
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+)
+
+// postgresMeteringRepository is the PostgreSQL implementation of MeteringRepository.
+type postgresMeteringRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresMeteringRepository creates a MeteringRepository on an open pool and runs its migrations.
+// The pool may belong to a database dedicated to metering.
+func NewPostgresMeteringRepository(db *sql.DB) (MeteringRepository, error) {
+	repo := &postgresMeteringRepository{db: db}
+	if err := repo.Migrate(); err != nil {
+		return nil, fmt.Errorf("failed to run metering migrations: %w", err)
+	}
+	return repo, nil
+}
+
+// Migrate applies the pending migrations in migrations/metering_events.
+func (r *postgresMeteringRepository) Migrate() error {
+	return MigrateSchema(r.db, "metering_events")
+}
+
+// RecordEvent appends an event. It returns false without error when an event with the same
+// idempotency key was already recorded.
+func (r *postgresMeteringRepository) RecordEvent(ctx context.Context, event *models.MeteringEvent) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `INSERT INTO metering_events (id, idempotency_key, user_id, meter, dimension, quantity, occurred_at, recorded_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) ON CONFLICT (idempotency_key) DO NOTHING`,
+		event.ID, event.IdempotencyKey, event.UserID, event.Meter, event.Dimension, event.Quantity, event.OccurredAt, event.RecordedAt)
+	if err != nil {
+		return false, fmt.Errorf("repository: failed to record metering event: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("repository: failed to record metering event: %w", err)
+	}
+	return n == 1, nil
+}
+
+// Summarize totals the events matching the filter per meter and dimension, and per user and meter.
+func (r *postgresMeteringRepository) Summarize(ctx context.Context, filter models.MeteringFilter) ([]models.MeterTotal, []models.UserMeterTotal, error) {
+	where := `WHERE occurred_at >= $1 AND occurred_at < $2 AND ($3::uuid IS NULL OR user_id = $3)`
+	var userID interface{}
+	if filter.UserID != uuid.Nil {
+		userID = filter.UserID
+	}
+
+	rows, err := r.db.QueryContext(ctx, `SELECT meter, dimension, COUNT(*), COALESCE(SUM(quantity), 0), COUNT(DISTINCT user_id)
+		FROM metering_events `+where+` GROUP BY meter, dimension ORDER BY meter, dimension`, filter.Since, filter.Until, userID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("repository: failed to summarize metering events: %w", err)
+	}
+	meters := []models.MeterTotal{}
+	for rows.Next() {
+		var t models.MeterTotal
+		if err := rows.Scan(&t.Meter, &t.Dimension, &t.Events, &t.Quantity, &t.Users); err != nil {
+			rows.Close()
+			return nil, nil, fmt.Errorf("repository: failed to scan meter total: %w", err)
+		}
+		meters = append(meters, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("repository: failed to summarize metering events: %w", err)
+	}
+
+	rows, err = r.db.QueryContext(ctx, `SELECT user_id, meter, COUNT(*), COALESCE(SUM(quantity), 0)
+		FROM metering_events `+where+` GROUP BY user_id, meter ORDER BY user_id, meter`, filter.Since, filter.Until, userID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("repository: failed to summarize metering events: %w", err)
+	}
+	defer rows.Close()
+	users := []models.UserMeterTotal{}
+	for rows.Next() {
+		var t models.UserMeterTotal
+		if err := rows.Scan(&t.UserID, &t.Meter, &t.Events, &t.Quantity); err != nil {
+			return nil, nil, fmt.Errorf("repository: failed to scan user meter total: %w", err)
+		}
+		users = append(users, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("repository: failed to summarize metering events: %w", err)
+	}
+	return meters, users, nil
+}
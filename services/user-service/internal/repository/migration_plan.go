@@ -0,0 +1,340 @@
+// services/user-service/internal/repository/migration_plan.go
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// errMigrationTx is returned to code that begins a transaction on a planning pool, whose connection is
+// already inside the transaction that is rolled back.
+var errMigrationTx = errors.New("repository: transactions cannot be nested in a migration plan")
+
+// MigrationPlan is a pool of one connection on which migrations are planned instead of applied. Its
+// connection runs everything inside a transaction that is rolled back when the pool is closed, and runs
+// each migration one statement at a time, noting the statements that change the schema or rows. Run
+// MigrateSchema on DB to plan the pending migrations, then read Pending.
+type MigrationPlan struct {
+	DB        *sql.DB
+	connector *migrationConnector
+}
+
+// NewMigrationPlan connects to the database like NewPostgresDB, for planning migrations.
+func NewMigrationPlan(dataSourceName, applicationName string) (*MigrationPlan, error) {
+	connector, db, err := openMigrationPool(dataSourceName, applicationName, false)
+	if err != nil {
+		return nil, err
+	}
+	return &MigrationPlan{DB: db, connector: connector}, nil
+}
+
+// Pending returns the statements the migrations run so far would apply, in order. Statements that
+// change nothing, such as adding a column that exists, are left out, as are statements whose changes a
+// later statement of the same migration undoes, such as a trigger that is dropped and created again.
+func (p *MigrationPlan) Pending() []string {
+	p.connector.mu.Lock()
+	defer p.connector.mu.Unlock()
+	return slices.Clone(p.connector.pending)
+}
+
+// Close rolls back everything the migrations did and closes the pool.
+func (p *MigrationPlan) Close() error {
+	return p.DB.Close()
+}
+
+// openMigrationPool opens a pool of one connection whose session is a transaction. With scratch, tables
+// and functions the migrations create go to the session's temporary schema instead of the live one.
+func openMigrationPool(dataSourceName, applicationName string, scratch bool) (*migrationConnector, *sql.DB, error) {
+	config, err := pgx.ParseConfig(withApplicationName(dataSourceName, applicationName))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse database URL: %w", err)
+	}
+	connector := &migrationConnector{base: stdlib.GetConnector(*config), scratch: scratch}
+	db := sql.OpenDB(connector)
+	// A second connection would be a second transaction, which sees none of the first one's changes.
+	db.SetMaxOpenConns(1)
+	db.SetMaxIdleConns(1)
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	return connector, db, nil
+}
+
+// migrationConnector opens the connection of a planning or scratch pool and collects the pending
+// statements its connection finds.
+type migrationConnector struct {
+	base    driver.Connector
+	scratch bool
+
+	mu      sync.Mutex
+	pending []string
+}
+
+func (c *migrationConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.base.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	mc := &migrationConn{Conn: conn, connector: c}
+	setup := "BEGIN"
+	if c.scratch {
+		// Unqualified tables and functions are created in the temporary schema first on the path. The live
+		// schema stays on it because functions are never looked up in the temporary schema.
+		setup = "BEGIN; SELECT set_config('search_path', 'pg_temp, ' || current_setting('search_path'), true)"
+	}
+	if _, err := mc.base().ExecContext(ctx, setup, nil); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to begin the migration transaction: %w", err)
+	}
+	return mc, nil
+}
+
+func (c *migrationConnector) Driver() driver.Driver {
+	return c.base.Driver()
+}
+
+// migrationConn is the connection of a planning or scratch pool.
+type migrationConn struct {
+	driver.Conn
+	connector *migrationConnector
+}
+
+// base returns the wrapped connection; pgx connections run queries and statements directly.
+func (c *migrationConn) base() interface {
+	driver.ExecerContext
+	driver.QueryerContext
+} {
+	return c.Conn.(interface {
+		driver.ExecerContext
+		driver.QueryerContext
+	})
+}
+
+func (c *migrationConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.base().QueryContext(ctx, query, args)
+}
+
+// ExecContext runs a migration. On a planning pool, each of its statements runs on its own between
+// snapshots of the schema, so the pending ones can be told apart. The runner's own bookkeeping in
+// schema_migrations is not part of the plan.
+func (c *migrationConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if c.connector.scratch || migrationBookkeeping[query] {
+		return c.base().ExecContext(ctx, query, args)
+	}
+	statements := []string{query}
+	if len(args) == 0 { // Statements with arguments are single statements
+		statements = splitStatements(query)
+	}
+
+	_, start, err := snapshotSchema(ctx, c.base())
+	if err != nil {
+		return nil, err
+	}
+	before := start
+	changed := make([][]string, len(statements))
+	touched := make([]bool, len(statements))
+	var result driver.Result = driver.RowsAffected(0)
+	for i, statement := range statements {
+		if result, err = c.base().ExecContext(ctx, statement, args); err != nil {
+			return nil, err
+		}
+		if n, err := result.RowsAffected(); err == nil && n > 0 {
+			touched[i] = true
+		}
+		_, after, err := snapshotSchema(ctx, c.base())
+		if err != nil {
+			return nil, err
+		}
+		changed[i] = schemaDiff(before, after)
+		before = after
+	}
+
+	net := make(map[string]bool)
+	for _, key := range schemaDiff(start, before) {
+		net[key] = true
+	}
+	c.connector.mu.Lock()
+	defer c.connector.mu.Unlock()
+	for i, statement := range statements {
+		pending := touched[i]
+		for _, key := range changed[i] {
+			pending = pending || net[key]
+		}
+		if pending {
+			c.connector.pending = append(c.connector.pending, statement)
+		}
+	}
+	return result, nil
+}
+
+func (c *migrationConn) Begin() (driver.Tx, error) {
+	return nil, errMigrationTx
+}
+
+func (c *migrationConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	return nil, errMigrationTx
+}
+
+// Close rolls back the session's transaction before closing the connection.
+func (c *migrationConn) Close() error {
+	c.base().ExecContext(context.Background(), "ROLLBACK", nil)
+	return c.Conn.Close()
+}
+
+// schemaSnapshotQuery lists the objects of the current schema with their definitions: tables, columns,
+// indexes not backing a constraint, constraints, triggers, and functions.
+const schemaSnapshotQuery = `
+	WITH ns AS (SELECT oid FROM pg_namespace WHERE nspname = current_schema())
+	SELECT 'schema', current_schema()
+	UNION ALL
+	SELECT 'table ' || c.relname, ''
+	FROM pg_class c
+	WHERE c.relnamespace = (SELECT oid FROM ns) AND c.relkind IN ('r', 'p')
+	UNION ALL
+	SELECT 'column ' || c.relname || '.' || a.attname,
+		format_type(a.atttypid, a.atttypmod) || CASE WHEN a.attnotnull THEN ' NOT NULL' ELSE '' END ||
+		COALESCE(' DEFAULT ' || pg_get_expr(d.adbin, d.adrelid), '')
+	FROM pg_attribute a
+	JOIN pg_class c ON c.oid = a.attrelid
+	LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+	WHERE c.relnamespace = (SELECT oid FROM ns) AND c.relkind IN ('r', 'p') AND a.attnum > 0 AND NOT a.attisdropped
+	UNION ALL
+	SELECT 'index ' || c.relname || '.' || i.relname, pg_get_indexdef(i.oid)
+	FROM pg_index x
+	JOIN pg_class i ON i.oid = x.indexrelid
+	JOIN pg_class c ON c.oid = x.indrelid
+	WHERE i.relnamespace = (SELECT oid FROM ns)
+		AND NOT EXISTS (SELECT 1 FROM pg_constraint con WHERE con.conindid = x.indexrelid AND con.conrelid = x.indrelid)
+	UNION ALL
+	SELECT 'constraint ' || c.relname || '.' || con.conname, pg_get_constraintdef(con.oid)
+	FROM pg_constraint con
+	JOIN pg_class c ON c.oid = con.conrelid
+	WHERE c.relnamespace = (SELECT oid FROM ns)
+	UNION ALL
+	SELECT 'trigger ' || c.relname || '.' || t.tgname, pg_get_triggerdef(t.oid)
+	FROM pg_trigger t
+	JOIN pg_class c ON c.oid = t.tgrelid
+	WHERE c.relnamespace = (SELECT oid FROM ns) AND NOT t.tgisinternal
+	UNION ALL
+	SELECT 'function ' || p.proname || '(' || pg_get_function_identity_arguments(p.oid) || ')', pg_get_functiondef(p.oid)
+	FROM pg_proc p
+	WHERE p.pronamespace = (SELECT oid FROM ns) AND p.prokind = 'f'`
+
+// snapshotSchema returns the name of the current schema and its objects, keyed like "column users.email",
+// with their definitions.
+func snapshotSchema(ctx context.Context, q driver.QueryerContext) (string, map[string]string, error) {
+	rows, err := q.QueryContext(ctx, schemaSnapshotQuery, nil)
+	if err != nil {
+		return "", nil, fmt.Errorf("repository: failed to read the schema: %w", err)
+	}
+	defer rows.Close()
+	var schema string
+	objects := make(map[string]string)
+	values := make([]driver.Value, 2)
+	for {
+		if err := rows.Next(values); err == io.EOF {
+			break
+		} else if err != nil {
+			return "", nil, fmt.Errorf("repository: failed to read the schema: %w", err)
+		}
+		key, definition := driverString(values[0]), driverString(values[1])
+		if key == "schema" {
+			schema = definition
+			continue
+		}
+		objects[key] = definition
+	}
+	return schema, objects, nil
+}
+
+func driverString(v driver.Value) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	}
+	return ""
+}
+
+// schemaDiff returns the keys of the objects added, removed, or changed between two snapshots, sorted.
+func schemaDiff(before, after map[string]string) []string {
+	var keys []string
+	for key, definition := range after {
+		if previous, ok := before[key]; !ok || previous != definition {
+			keys = append(keys, key)
+		}
+	}
+	for key := range before {
+		if _, ok := after[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// dollarTag matches the opening tag of a dollar-quoted string, such as $$ or $body$.
+var dollarTag = regexp.MustCompile(`^\$([A-Za-z_][A-Za-z0-9_]*)?\$`)
+
+// splitStatements splits a migration into statements at the semicolons outside quotes, comments, and
+// dollar-quoted function bodies. A comment on the rest of a statement's last line stays with it.
+func splitStatements(query string) []string {
+	var statements []string
+	add := func(statement string) {
+		statement = strings.TrimSpace(statement)
+		for _, line := range strings.Split(statement, "\n") {
+			if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "--") {
+				statements = append(statements, statement)
+				return
+			}
+		}
+	}
+	skipTo := func(i int, end string) int { // Index of the last byte of end after i, or the last byte of the query
+		if j := strings.Index(query[i:], end); j >= 0 {
+			return i + j + len(end) - 1
+		}
+		return len(query) - 1
+	}
+
+	start := 0
+	for i := 0; i < len(query); i++ {
+		switch rest := query[i:]; {
+		case rest[0] == '\'' || rest[0] == '"':
+			i = skipTo(i+1, rest[:1])
+		case strings.HasPrefix(rest, "--"):
+			i = skipTo(i, "\n")
+		case strings.HasPrefix(rest, "/*"):
+			i = skipTo(i+2, "*/")
+		case rest[0] == '$':
+			if tag := dollarTag.FindString(rest); tag != "" {
+				i = skipTo(i+len(tag), tag)
+			}
+		case rest[0] == ';':
+			end := i + 1
+			if trailing := strings.TrimLeft(query[end:], " \t"); strings.HasPrefix(trailing, "--") {
+				end = skipTo(len(query)-len(trailing), "\n") + 1
+			}
+			add(query[start:end])
+			start, i = end, end-1
+		}
+	}
+	if last := strings.TrimSpace(query[start:]); last != "" && !strings.Contains(last[strings.LastIndex(last, "\n")+1:], "--") {
+		add(last + ";") // Printed plans end every statement
+	} else {
+		add(last)
+	}
+	return statements
+}
@@ -0,0 +1,21 @@
+-- Announcements sent by admins to a segment of users. The sender holds a lease while fanning out, so
+-- another replica resumes the send from cursor if it stops.
+CREATE TABLE announcements (
+	id UUID PRIMARY KEY,
+	title VARCHAR(100) NOT NULL,
+	body VARCHAR(500) NOT NULL,
+	segment JSONB NOT NULL, -- models.AnnouncementSegment
+	status VARCHAR(16) NOT NULL, -- 'scheduled', 'sending', 'sent', or 'cancelled'
+	send_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	created_by VARCHAR(64) NOT NULL,
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	started_at TIMESTAMP WITH TIME ZONE,
+	finished_at TIMESTAMP WITH TIME ZONE,
+	lease_until TIMESTAMP WITH TIME ZONE, -- Set while a replica is sending
+	cursor_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000', -- Last recipient handled
+	recipients INTEGER NOT NULL DEFAULT 0,
+	sent INTEGER NOT NULL DEFAULT 0,
+	failed INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX idx_announcements_created_at ON announcements (created_at DESC, id DESC);
+CREATE INDEX idx_announcements_due ON announcements (send_at) WHERE status IN ('scheduled', 'sending');
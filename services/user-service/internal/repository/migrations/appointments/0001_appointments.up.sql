@@ -0,0 +1,34 @@
+-- A slot can hold at most one booked appointment; removing a slot keeps its appointment history.
+CREATE TABLE IF NOT EXISTS appointment_slots (
+	id UUID PRIMARY KEY,
+	provider_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	starts_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	ends_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	location VARCHAR(500) NOT NULL DEFAULT '',
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	CHECK (ends_at > starts_at)
+);
+CREATE INDEX IF NOT EXISTS idx_appointment_slots_provider_starts ON appointment_slots (provider_id, starts_at);
+
+CREATE TABLE IF NOT EXISTS appointments (
+	id UUID PRIMARY KEY,
+	slot_id UUID REFERENCES appointment_slots(id) ON DELETE SET NULL,
+	provider_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	user_id UUID NOT NULL,
+	starts_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	ends_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	location VARCHAR(500) NOT NULL DEFAULT '',
+	reason VARCHAR(1000) NOT NULL DEFAULT '',
+	status VARCHAR(16) NOT NULL,
+	rescheduled_from UUID,
+	reschedule_count INTEGER NOT NULL DEFAULT 0,
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	cancelled_at TIMESTAMP WITH TIME ZONE,
+	cancelled_by UUID,
+	cancel_reason VARCHAR(1000) NOT NULL DEFAULT '',
+	reminder_sent_at TIMESTAMP WITH TIME ZONE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_appointments_booked_slot ON appointments (slot_id) WHERE status = 'booked';
+CREATE INDEX IF NOT EXISTS idx_appointments_provider_starts ON appointments (provider_id, starts_at);
+CREATE INDEX IF NOT EXISTS idx_appointments_user_starts ON appointments (user_id, starts_at);
+CREATE INDEX IF NOT EXISTS idx_appointments_reminders ON appointments (starts_at) WHERE status = 'booked' AND reminder_sent_at IS NULL;
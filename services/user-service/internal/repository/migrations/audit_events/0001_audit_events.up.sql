@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS audit_events (
+	id UUID PRIMARY KEY,
+	action VARCHAR(64) NOT NULL,
+	outcome VARCHAR(16) NOT NULL,
+	actor_id VARCHAR(64) NOT NULL DEFAULT '',
+	target_id VARCHAR(64) NOT NULL DEFAULT '',
+	ip VARCHAR(64) NOT NULL,
+	user_agent TEXT NOT NULL DEFAULT '',
+	details JSONB,
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_audit_events_created_at ON audit_events (created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_events_actor ON audit_events (actor_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_events_target ON audit_events (target_id, created_at DESC);
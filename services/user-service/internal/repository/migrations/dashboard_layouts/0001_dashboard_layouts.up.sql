@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS dashboard_layouts (
+	user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+	schema_version INT NOT NULL,
+	widgets JSONB NOT NULL,
+	updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
@@ -0,0 +1,31 @@
+-- owner_id has no foreign key: the owner may be stored in another region's database.
+CREATE TABLE IF NOT EXISTS developer_apps (
+	id UUID PRIMARY KEY,
+	owner_id UUID NOT NULL,
+	name VARCHAR(100) NOT NULL,
+	description TEXT NOT NULL DEFAULT '',
+	key_prefix VARCHAR(16) NOT NULL,
+	key_hash VARCHAR(64) NOT NULL UNIQUE, -- SHA-256 of the API key; the raw key is never stored
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	key_rotated_at TIMESTAMP WITH TIME ZONE,
+	revoked_at TIMESTAMP WITH TIME ZONE
+);
+CREATE INDEX IF NOT EXISTS idx_developer_apps_owner_id ON developer_apps (owner_id);
+CREATE TABLE IF NOT EXISTS developer_app_usage (
+	app_id UUID NOT NULL REFERENCES developer_apps(id) ON DELETE CASCADE,
+	day DATE NOT NULL, -- UTC
+	route VARCHAR(255) NOT NULL,
+	requests BIGINT NOT NULL DEFAULT 0,
+	client_errors BIGINT NOT NULL DEFAULT 0,
+	server_errors BIGINT NOT NULL DEFAULT 0,
+	throttled BIGINT NOT NULL DEFAULT 0,
+	PRIMARY KEY (app_id, day, route)
+);
+ALTER TABLE developer_apps ADD COLUMN IF NOT EXISTS debug_until TIMESTAMP WITH TIME ZONE; -- Requests are recorded until then
+CREATE TABLE IF NOT EXISTS developer_app_recordings (
+	id BIGSERIAL PRIMARY KEY, -- Insertion order
+	app_id UUID NOT NULL REFERENCES developer_apps(id) ON DELETE CASCADE,
+	recorded_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	entry JSONB NOT NULL -- A HAR entry
+);
+CREATE INDEX IF NOT EXISTS idx_developer_app_recordings_app_id ON developer_app_recordings (app_id, id);
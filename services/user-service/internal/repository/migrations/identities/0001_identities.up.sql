@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS user_identities (
+	issuer TEXT NOT NULL,
+	subject TEXT NOT NULL,
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	method VARCHAR(16) NOT NULL,
+	email VARCHAR(255) NOT NULL,
+	linked_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	PRIMARY KEY (issuer, subject)
+);
+CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON user_identities (user_id);
+
+CREATE TABLE IF NOT EXISTS identity_link_requests (
+	id UUID PRIMARY KEY,
+	token_hash CHAR(64) NOT NULL UNIQUE,
+	code_hash CHAR(64) NOT NULL,
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	method VARCHAR(16) NOT NULL,
+	issuer TEXT NOT NULL,
+	subject TEXT NOT NULL,
+	email VARCHAR(255) NOT NULL,
+	name TEXT NOT NULL DEFAULT '',
+	attempts INT NOT NULL DEFAULT 0,
+	expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
@@ -0,0 +1,7 @@
+-- IDs of the SAML AuthnRequests this service issued and has not seen answered, kept in the database so
+-- the IdP's response can be posted to any replica. Expired rows are removed as new requests are made.
+CREATE TABLE saml_requests (
+	id TEXT PRIMARY KEY,
+	expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+CREATE INDEX idx_saml_requests_expires_at ON saml_requests (expires_at);
@@ -0,0 +1,16 @@
+-- A user has at most one active (unrevoked) consent per provider.
+CREATE TABLE IF NOT EXISTS integration_consents (
+	id UUID PRIMARY KEY,
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	provider VARCHAR(32) NOT NULL,
+	terms_version VARCHAR(64) NOT NULL,
+	imports JSONB NOT NULL,
+	exports JSONB NOT NULL,
+	ip VARCHAR(45) NOT NULL DEFAULT '',
+	user_agent TEXT NOT NULL DEFAULT '',
+	accepted_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	revoked_at TIMESTAMP WITH TIME ZONE,
+	delete_data BOOLEAN NOT NULL DEFAULT FALSE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_integration_consents_active ON integration_consents (user_id, provider) WHERE revoked_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_integration_consents_revoked_at ON integration_consents (revoked_at) WHERE revoked_at IS NOT NULL;
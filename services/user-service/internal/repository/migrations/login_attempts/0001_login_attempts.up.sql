@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS login_attempts (
+	id UUID PRIMARY KEY,
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	success BOOLEAN NOT NULL,
+	method VARCHAR(16) NOT NULL,
+	failure_reason VARCHAR(64) NOT NULL DEFAULT '',
+	ip VARCHAR(64) NOT NULL,
+	user_agent TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_login_attempts_user_created_at ON login_attempts (user_id, created_at DESC);
@@ -0,0 +1,46 @@
+-- Coach and sender IDs have no foreign key, since a coach may be stored in another region.
+CREATE TABLE IF NOT EXISTS coach_authorizations (
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	coach_id UUID NOT NULL,
+	authorized_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	revoked_at TIMESTAMP WITH TIME ZONE,
+	PRIMARY KEY (user_id, coach_id)
+);
+CREATE INDEX IF NOT EXISTS idx_coach_authorizations_coach_id ON coach_authorizations (coach_id);
+
+CREATE TABLE IF NOT EXISTS message_threads (
+	id UUID PRIMARY KEY,
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	coach_id UUID NOT NULL,
+	subject VARCHAR(200) NOT NULL DEFAULT '',
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	last_message_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_message_threads_user_id ON message_threads (user_id);
+CREATE INDEX IF NOT EXISTS idx_message_threads_coach_id ON message_threads (coach_id);
+
+CREATE TABLE IF NOT EXISTS messages (
+	id UUID PRIMARY KEY,
+	thread_id UUID NOT NULL REFERENCES message_threads(id) ON DELETE CASCADE,
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	sender_id UUID NOT NULL,
+	body TEXT NOT NULL,
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	read_at TIMESTAMP WITH TIME ZONE
+);
+CREATE INDEX IF NOT EXISTS idx_messages_thread_created ON messages (thread_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_messages_created_at ON messages (created_at);
+
+CREATE TABLE IF NOT EXISTS message_attachments (
+	id UUID PRIMARY KEY,
+	thread_id UUID NOT NULL REFERENCES message_threads(id) ON DELETE CASCADE,
+	message_id UUID REFERENCES messages(id) ON DELETE CASCADE,
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	uploader_id UUID NOT NULL,
+	filename VARCHAR(255) NOT NULL,
+	content_type VARCHAR(100) NOT NULL,
+	size BIGINT NOT NULL,
+	blob_key TEXT NOT NULL,
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_message_attachments_message_id ON message_attachments (message_id);
@@ -0,0 +1,23 @@
+-- Append-only: a trigger rejects every UPDATE and DELETE, so recorded usage can only be corrected by
+-- recording more usage. user_id has no foreign key: usage stays billable after the user is deleted,
+-- and the table may live in its own database.
+CREATE TABLE IF NOT EXISTS metering_events (
+	id UUID PRIMARY KEY,
+	idempotency_key VARCHAR(255) NOT NULL UNIQUE,
+	user_id UUID NOT NULL,
+	meter VARCHAR(64) NOT NULL,
+	dimension VARCHAR(255) NOT NULL DEFAULT '',
+	quantity BIGINT NOT NULL,
+	occurred_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	recorded_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_metering_events_occurred_at ON metering_events (occurred_at);
+CREATE INDEX IF NOT EXISTS idx_metering_events_user_occurred_at ON metering_events (user_id, occurred_at);
+CREATE OR REPLACE FUNCTION metering_events_append_only() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'metering_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+DROP TRIGGER IF EXISTS metering_events_append_only ON metering_events;
+CREATE TRIGGER metering_events_append_only BEFORE UPDATE OR DELETE ON metering_events
+	FOR EACH ROW EXECUTE FUNCTION metering_events_append_only();
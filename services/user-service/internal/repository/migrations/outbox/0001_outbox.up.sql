@@ -0,0 +1,16 @@
+-- Domain events written in the transaction of the change they announce, waiting to be published.
+-- A dispatcher claims pending events by moving available_at to the end of its lease, and sets sent_at
+-- once the event gateway has taken them.
+CREATE TABLE outbox (
+	id UUID PRIMARY KEY, -- The event's ID, kept across redeliveries
+	event_type VARCHAR(64) NOT NULL,
+	user_id UUID NOT NULL, -- No foreign key: deletions are announced too
+	occurred_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	data JSONB,
+	available_at TIMESTAMP WITH TIME ZONE NOT NULL, -- When it may next be claimed
+	attempts INTEGER NOT NULL DEFAULT 0,
+	last_error TEXT,
+	sent_at TIMESTAMP WITH TIME ZONE
+);
+CREATE INDEX idx_outbox_pending ON outbox (available_at) WHERE sent_at IS NULL;
+CREATE INDEX idx_outbox_sent_at ON outbox (sent_at) WHERE sent_at IS NOT NULL;
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS sessions (
+	id UUID PRIMARY KEY,
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	ip VARCHAR(64) NOT NULL,
+	user_agent TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_sessions_user_created_at ON sessions (user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_sessions_expires_at ON sessions (expires_at);
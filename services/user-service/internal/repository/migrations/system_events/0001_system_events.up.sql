@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS system_events (
+	id UUID PRIMARY KEY,
+	type VARCHAR(32) NOT NULL,
+	message TEXT NOT NULL,
+	actor VARCHAR(255) NOT NULL,
+	starts_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	ends_at TIMESTAMP WITH TIME ZONE,
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_system_events_starts_at ON system_events (starts_at DESC);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS user_events (
+	id UUID PRIMARY KEY,
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	type VARCHAR(64) NOT NULL,
+	summary TEXT NOT NULL,
+	details JSONB,
+	occurred_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_user_events_user_occurred_at ON user_events (user_id, occurred_at DESC);
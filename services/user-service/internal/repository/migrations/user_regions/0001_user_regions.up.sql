@@ -0,0 +1,10 @@
+-- The region directory, in the home region's database.
+CREATE TABLE IF NOT EXISTS user_regions (
+	user_id UUID PRIMARY KEY,
+	email_hash CHAR(64) NOT NULL UNIQUE, -- SHA-256 of the email; the address itself stays in its region
+	region VARCHAR(32) NOT NULL,
+	updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+ALTER TABLE user_regions ADD COLUMN IF NOT EXISTS username_hash CHAR(64) UNIQUE; -- SHA-256 of the username; NULL without one
+ALTER TABLE user_regions ADD COLUMN IF NOT EXISTS email_key_hash CHAR(64) UNIQUE; -- SHA-256 of users.email_key; NULL without one
+ALTER TABLE user_regions ADD COLUMN IF NOT EXISTS alias_of UUID; -- Set on entries of merged-away users, whose email is an alias of this user
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS user_settings (
+	user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+	settings JSONB NOT NULL, -- Only the values the user set, by key
+	updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
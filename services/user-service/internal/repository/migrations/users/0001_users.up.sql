@@ -0,0 +1,37 @@
+CREATE TABLE IF NOT EXISTS users (
+	id UUID PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	email VARCHAR(255) UNIQUE NOT NULL, -- Email is unique and used for login
+	password_hash VARCHAR(255) NOT NULL, -- Storing the bcrypt hashed password
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(32) NOT NULL DEFAULT 'user'; -- 'user' or 'admin'
+ALTER TABLE users ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT 'UTC';
+CREATE TABLE IF NOT EXISTS user_timezone_history (
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	timezone VARCHAR(64) NOT NULL,
+	effective_from TIMESTAMP WITH TIME ZONE NOT NULL,
+	PRIMARY KEY (user_id, effective_from)
+);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS sessions_revoked_at TIMESTAMP WITH TIME ZONE; -- Tokens issued before this are rejected
+CREATE TABLE IF NOT EXISTS password_reset_tokens (
+	token_hash VARCHAR(64) PRIMARY KEY, -- SHA-256 of the token; the raw token is never stored
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	used_at TIMESTAMP WITH TIME ZONE,
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS status VARCHAR(16) NOT NULL DEFAULT 'active'; -- 'active', 'suspended', 'deactivated', or 'pending_deletion'
+ALTER TABLE users ADD COLUMN IF NOT EXISTS height_cm DOUBLE PRECISION;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS date_of_birth DATE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deletion_due_at TIMESTAMP WITH TIME ZONE; -- Set while status is 'pending_deletion'
+ALTER TABLE users ADD COLUMN IF NOT EXISTS week_start VARCHAR(3) NOT NULL DEFAULT 'mon'; -- First day of weekly aggregates
+ALTER TABLE users ADD COLUMN IF NOT EXISTS units VARCHAR(8) NOT NULL DEFAULT 'metric'; -- Display unit system; values are stored metric
+CREATE INDEX IF NOT EXISTS idx_users_deletion_due_at ON users (deletion_due_at) WHERE deletion_due_at IS NOT NULL;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS username VARCHAR(30); -- Optional public handle, stored lowercased; NULL until chosen
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username ON users (username);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_key VARCHAR(255); -- models.EmailKey of the email; NULL only for older emails sharing a key
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_key ON users (email_key);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP WITH TIME ZONE; -- Cleared when the email changes
+CREATE INDEX IF NOT EXISTS idx_users_created_at ON users (created_at DESC);
@@ -0,0 +1,9 @@
+-- Sets the email key of users stored before it existed, lowercasing and dropping the +tag the way
+-- models.EmailKey does for normalized emails. Emails that share a key with another user keep none: they
+-- still work, matched exactly, but only one account per mailbox can be created from now on.
+UPDATE users SET email_key = k.key FROM (
+	SELECT id, key, COUNT(*) OVER (PARTITION BY key) AS n FROM (
+		SELECT id, regexp_replace(lower(btrim(email)), '^([^@+]+)\+[^@]*@', '\1@') AS key FROM users WHERE email_key IS NULL
+	) keyed
+) k
+WHERE users.id = k.id AND k.n = 1 AND NOT EXISTS (SELECT 1 FROM users o WHERE o.email_key = k.key);
@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS user_merges (
+	id UUID PRIMARY KEY,
+	primary_user_id UUID NOT NULL,
+	donor_user_id UUID NOT NULL,
+	donor_name VARCHAR(255) NOT NULL,
+	donor_email VARCHAR(255) NOT NULL,
+	donor_password_hash VARCHAR(255) NOT NULL,
+	donor_role VARCHAR(32) NOT NULL,
+	donor_created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	merged_by VARCHAR(255) NOT NULL,
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+	undone_at TIMESTAMP WITH TIME ZONE,
+	undone_by VARCHAR(255)
+);
+ALTER TABLE user_merges ADD COLUMN IF NOT EXISTS moved_identities JSONB NOT NULL DEFAULT '[]'; -- [{issuer, subject}] moved to the primary user
+
+-- Emails of merged-away accounts that still find the account they were merged into
+CREATE TABLE IF NOT EXISTS user_email_aliases (
+	email_key VARCHAR(255) PRIMARY KEY, -- models.EmailKey of email
+	email VARCHAR(255) NOT NULL,
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	donor_user_id UUID NOT NULL,
+	merge_id UUID NOT NULL,
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_user_email_aliases_user_id ON user_email_aliases (user_id);
+CREATE INDEX IF NOT EXISTS idx_user_email_aliases_merge_id ON user_email_aliases (merge_id);
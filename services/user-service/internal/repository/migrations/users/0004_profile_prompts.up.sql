@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS profile_prompt_dismissals (
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	field VARCHAR(64) NOT NULL,
+	count INT NOT NULL,
+	last_dismissed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	PRIMARY KEY (user_id, field)
+);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS aggregation_periods (
+	id UUID PRIMARY KEY,
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	name VARCHAR(100) NOT NULL,
+	kind VARCHAR(32) NOT NULL, -- 'training_block', 'challenge', or 'custom'
+	starts_on DATE NOT NULL,
+	ends_on DATE NOT NULL, -- Inclusive
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	updated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	CHECK (ends_on >= starts_on)
+);
+CREATE INDEX IF NOT EXISTS idx_aggregation_periods_user_id ON aggregation_periods (user_id, starts_on);
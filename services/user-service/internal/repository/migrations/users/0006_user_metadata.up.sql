@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS metadata JSONB NOT NULL DEFAULT '{}'; -- Integrator-defined attributes
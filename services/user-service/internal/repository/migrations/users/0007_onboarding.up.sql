@@ -0,0 +1,5 @@
+-- Users who existed before the onboarding flow are done; new rows then default to the first step.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS onboarding_step VARCHAR(32) NOT NULL DEFAULT 'done';
+ALTER TABLE users ALTER COLUMN onboarding_step SET DEFAULT 'registered';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS onboarding_skipped_from VARCHAR(32); -- Set when done was reached by skipping
+ALTER TABLE users ADD COLUMN IF NOT EXISTS onboarding_updated_at TIMESTAMP WITH TIME ZONE;
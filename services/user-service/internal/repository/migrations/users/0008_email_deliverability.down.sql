@@ -0,0 +1,4 @@
+DROP TABLE email_verification_tokens;
+ALTER TABLE users DROP COLUMN email_status_at;
+ALTER TABLE users DROP COLUMN email_soft_bounces;
+ALTER TABLE users DROP COLUMN email_status;
@@ -0,0 +1,14 @@
+-- Bounces and complaints reported by the mail provider. Mail to a bounced or complained address is suppressed.
+ALTER TABLE users ADD COLUMN email_status VARCHAR(16) NOT NULL DEFAULT 'ok'; -- 'ok', 'soft_bouncing', 'bounced', or 'complained'
+ALTER TABLE users ADD COLUMN email_soft_bounces INT NOT NULL DEFAULT 0; -- In a row, each within a week of the last
+ALTER TABLE users ADD COLUMN email_status_at TIMESTAMP WITH TIME ZONE;
+
+CREATE TABLE email_verification_tokens (
+	token_hash VARCHAR(64) PRIMARY KEY, -- SHA-256 of the token; the raw token is never stored
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	email VARCHAR(255) NOT NULL, -- The address it was sent to; it verifies nothing once the email changes
+	expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	used_at TIMESTAMP WITH TIME ZONE,
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_email_verification_tokens_user_id ON email_verification_tokens (user_id);
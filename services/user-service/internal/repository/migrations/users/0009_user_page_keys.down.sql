@@ -0,0 +1,2 @@
+CREATE INDEX idx_users_created_at ON users (created_at DESC);
+DROP INDEX idx_users_created_at_id;
@@ -0,0 +1,4 @@
+-- User lists page by (created_at, id), oldest or newest first, so one index finds where each page starts
+-- however deep it is.
+CREATE INDEX idx_users_created_at_id ON users (created_at, id);
+DROP INDEX idx_users_created_at;
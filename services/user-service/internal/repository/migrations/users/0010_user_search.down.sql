@@ -0,0 +1,2 @@
+-- pg_trgm stays, as other schemas may use it.
+DROP INDEX idx_users_search;
@@ -0,0 +1,6 @@
+-- Typo-tolerant user search (GET /users/search) matches trigrams of the query against the name, username,
+-- and email. The expression must stay repository.userSearchText. pg_trgm is a trusted extension: the
+-- service role can create it with CREATE on the database, or an admin creates it beforehand (see
+-- DATABASE_ADMIN_URL).
+CREATE EXTENSION IF NOT EXISTS pg_trgm WITH SCHEMA public;
+CREATE INDEX idx_users_search ON users USING gin ((lower(name || ' ' || COALESCE(username, '') || ' ' || email)) gin_trgm_ops);
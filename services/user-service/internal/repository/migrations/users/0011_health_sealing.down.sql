@@ -0,0 +1,11 @@
+-- Refuses while any user's health fields are sealed: their height_cm and date_of_birth are NULL, and
+-- the values would be lost with the column. SQL cannot open them, so this rolls back only databases
+-- that never ran with PII_ENCRYPTION_KEYS, or whose sealed users were restored from a backup first.
+DO $$
+BEGIN
+	IF EXISTS (SELECT 1 FROM users WHERE health_sealed IS NOT NULL) THEN
+		RAISE EXCEPTION 'users.health_sealed holds sealed health fields, which dropping it would lose';
+	END IF;
+END;
+$$;
+ALTER TABLE users DROP COLUMN health_sealed;
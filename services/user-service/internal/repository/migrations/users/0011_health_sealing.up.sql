@@ -0,0 +1,3 @@
+-- Users' health fields sealed by fieldcrypt, as one JSON document. height_cm and date_of_birth keep
+-- the values of users not sealed yet, and are NULL once a user's fields are sealed.
+ALTER TABLE users ADD COLUMN health_sealed TEXT;
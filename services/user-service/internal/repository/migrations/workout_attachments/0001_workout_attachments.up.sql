@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS workout_attachments (
+	id UUID PRIMARY KEY,
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	set_ref VARCHAR(64) NOT NULL,
+	filename VARCHAR(255) NOT NULL,
+	content_type VARCHAR(100) NOT NULL,
+	size BIGINT NOT NULL,
+	scan_status VARCHAR(20) NOT NULL,
+	scanned_at TIMESTAMP WITH TIME ZONE,
+	shared_with_coaches BOOLEAN NOT NULL DEFAULT FALSE,
+	expires_at TIMESTAMP WITH TIME ZONE,
+	blob_key TEXT NOT NULL,
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_workout_attachments_user_set ON workout_attachments (user_id, set_ref);
+CREATE INDEX IF NOT EXISTS idx_workout_attachments_pending ON workout_attachments (created_at) WHERE scan_status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_workout_attachments_expires_at ON workout_attachments (expires_at) WHERE expires_at IS NOT NULL;
@@ -0,0 +1,18 @@
+-- Training plans coaches write or import. Sessions are stored as the JSON of the plan document, which
+-- the service validates; the author columns credit who wrote the plan, kept through imports.
+CREATE TABLE workout_plans (
+	id UUID PRIMARY KEY,
+	coach_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	name VARCHAR(100) NOT NULL,
+	description TEXT NOT NULL DEFAULT '',
+	weeks INTEGER NOT NULL,
+	sessions JSONB NOT NULL,
+	author_name VARCHAR(100) NOT NULL,
+	author_username VARCHAR(100) NOT NULL DEFAULT '',
+	author_platform VARCHAR(50) NOT NULL DEFAULT '',
+	source_id UUID, -- The plan's ID where it was written, for imported plans
+	imported_at TIMESTAMP WITH TIME ZONE,
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+CREATE INDEX idx_workout_plans_coach ON workout_plans (coach_id, created_at);
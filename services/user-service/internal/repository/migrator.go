@@ -0,0 +1,308 @@
+// services/user-service/internal/repository/migrator.go
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// migrationFiles holds the versioned migrations, one directory per component (a table or a group of
+// tables migrated together): migrations/<component>/<version>_<name>.up.sql, and optionally a
+// .down.sql that undoes it. Versions count up from 0001 within a component. A released migration is
+// never edited; a schema change is a new version with both files.
+//
+// The migrations that existed before versioning only create what is missing, so databases built by
+// the old migrations take them as applied. They have no down migration: undoing them would drop tables.
+//
+//go:embed migrations
+var migrationFiles embed.FS
+
+// migrationFileName matches migration files, such as 0003_user_merges.up.sql.
+var migrationFileName = regexp.MustCompile(`^(\d{4})_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// migrationLockKey is the advisory lock held while migrating, so replicas starting together apply
+// each migration once.
+const migrationLockKey = 7301962555284619776
+
+// Bookkeeping statements of the runner, which a migration plan leaves out.
+const (
+	createMigrationsTableQuery = `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		component VARCHAR(64) NOT NULL,
+		version INT NOT NULL,
+		name VARCHAR(255) NOT NULL,
+		applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (component, version)
+	);`
+	recordMigrationQuery   = `INSERT INTO schema_migrations (component, version, name) VALUES ($1, $2, $3)`
+	unrecordMigrationQuery = `DELETE FROM schema_migrations WHERE component = $1 AND version = $2`
+)
+
+var migrationBookkeeping = map[string]bool{
+	createMigrationsTableQuery: true,
+	recordMigrationQuery:       true,
+	unrecordMigrationQuery:     true,
+}
+
+// Migration is one versioned change to the schema of a component.
+type Migration struct {
+	Component string
+	Version   int
+	Name      string
+	up        string
+	down      string // Empty when the migration cannot be rolled back
+}
+
+// String names the migration like its files, such as users/0003_user_merges.
+func (m Migration) String() string {
+	return fmt.Sprintf("%s/%04d_%s", m.Component, m.Version, m.Name)
+}
+
+// Reversible reports whether the migration has a down migration.
+func (m Migration) Reversible() bool {
+	return m.down != ""
+}
+
+// MigrationStatus is a migration and when it was applied to a database, if it was.
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// loadMigrations returns the migrations of a component, in version order.
+func loadMigrations(component string) ([]Migration, error) {
+	dir := path.Join("migrations", component)
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, fmt.Errorf("repository: unknown migration component %q", component)
+	}
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := migrationFileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("repository: unexpected migration file %s/%s", component, entry.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Component: component, Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("repository: migration %s/%04d has files named %s and %s", component, version, m.Name, match[2])
+		}
+		body, err := migrationFiles.ReadFile(path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("repository: failed to read migration %s: %w", entry.Name(), err)
+		}
+		if match[3] == "up" {
+			m.up = string(body)
+		} else {
+			m.down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.up == "" {
+			return nil, fmt.Errorf("repository: migration %s has no up file", m)
+		}
+		migrations = append(migrations, *m)
+	}
+	slices.SortFunc(migrations, func(a, b Migration) int { return a.Version - b.Version })
+	return migrations, nil
+}
+
+// MigrateSchema applies the pending migrations of each component to db, in order, and records them in
+// schema_migrations. Each migration runs in its own transaction with its record, so a failed one leaves
+// nothing behind and is retried at the next start. Migrations recorded in db that this build does not
+// have, applied by a newer release, are logged and left alone.
+func MigrateSchema(db *sql.DB, components ...string) error {
+	return withMigrationLock(db, func(ctx context.Context, conn *sql.Conn, rehearsal bool) error {
+		for _, component := range components {
+			statuses, unknown, err := migrationStatuses(ctx, conn, component)
+			if err != nil {
+				return err
+			}
+			for _, status := range statuses {
+				if status.AppliedAt != nil {
+					continue
+				}
+				err := inMigrationTx(ctx, conn, rehearsal, func(q migrationExecer) error {
+					if _, err := q.ExecContext(ctx, status.up); err != nil {
+						return err
+					}
+					_, err := q.ExecContext(ctx, recordMigrationQuery, status.Component, status.Version, status.Name)
+					return err
+				})
+				if err != nil {
+					return fmt.Errorf("repository: migration %s failed: %w", status.Migration, err)
+				}
+				if !rehearsal {
+					logger.Logger.Infof("Applied migration %s", status.Migration)
+				}
+			}
+			for _, newer := range unknown {
+				logger.Logger.Warnf("Migration %s was applied by a newer release; roll it back with that release's `migrate down` if this one needs the old schema", newer)
+			}
+		}
+		return nil
+	})
+}
+
+// RollbackMigration undoes the latest migration of a component applied to db with its down migration,
+// and removes its record, in one transaction. It returns the migration undone, or nil if none was applied.
+func RollbackMigration(db *sql.DB, component string) (*Migration, error) {
+	var undone *Migration
+	err := withMigrationLock(db, func(ctx context.Context, conn *sql.Conn, rehearsal bool) error {
+		statuses, unknown, err := migrationStatuses(ctx, conn, component)
+		if err != nil {
+			return err
+		}
+		if len(unknown) > 0 {
+			return fmt.Errorf("repository: migration %s was applied by a newer release; roll it back with that release", unknown[len(unknown)-1])
+		}
+		for i := len(statuses) - 1; i >= 0; i-- {
+			if statuses[i].AppliedAt != nil {
+				undone = &statuses[i].Migration
+				break
+			}
+		}
+		if undone == nil {
+			return nil
+		}
+		if !undone.Reversible() {
+			return fmt.Errorf("repository: migration %s has no down migration", undone)
+		}
+		err = inMigrationTx(ctx, conn, rehearsal, func(q migrationExecer) error {
+			if _, err := q.ExecContext(ctx, undone.down); err != nil {
+				return err
+			}
+			_, err := q.ExecContext(ctx, unrecordMigrationQuery, undone.Component, undone.Version)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("repository: rolling back migration %s failed: %w", undone, err)
+		}
+		logger.Logger.Infof("Rolled back migration %s", undone)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return undone, nil
+}
+
+// MigrationStatuses lists the migrations of each component, in order, with when they were applied to db.
+func MigrationStatuses(db *sql.DB, components ...string) ([]MigrationStatus, error) {
+	var all []MigrationStatus
+	err := withMigrationLock(db, func(ctx context.Context, conn *sql.Conn, _ bool) error {
+		for _, component := range components {
+			statuses, _, err := migrationStatuses(ctx, conn, component)
+			if err != nil {
+				return err
+			}
+			all = append(all, statuses...)
+		}
+		return nil
+	})
+	return all, err
+}
+
+// migrationStatuses returns the migrations of a component with when they were applied, and the names of
+// applied versions that this build does not have.
+func migrationStatuses(ctx context.Context, conn *sql.Conn, component string) ([]MigrationStatus, []string, error) {
+	migrations, err := loadMigrations(component)
+	if err != nil {
+		return nil, nil, err
+	}
+	rows, err := conn.QueryContext(ctx, `SELECT version, name, applied_at FROM schema_migrations WHERE component = $1`, component)
+	if err != nil {
+		return nil, nil, fmt.Errorf("repository: failed to read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+	applied := make(map[int]time.Time)
+	var unknown []string
+	for rows.Next() {
+		var version int
+		var name string
+		var at time.Time
+		if err := rows.Scan(&version, &name, &at); err != nil {
+			return nil, nil, fmt.Errorf("repository: failed to scan schema_migrations: %w", err)
+		}
+		applied[version] = at
+		if !slices.ContainsFunc(migrations, func(m Migration) bool { return m.Version == version }) {
+			unknown = append(unknown, Migration{Component: component, Version: version, Name: name}.String())
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("repository: failed to read schema_migrations: %w", err)
+	}
+	slices.Sort(unknown)
+
+	statuses := make([]MigrationStatus, len(migrations))
+	for i, m := range migrations {
+		statuses[i].Migration = m
+		if at, ok := applied[m.Version]; ok {
+			statuses[i].AppliedAt = &at
+		}
+	}
+	return statuses, unknown, nil
+}
+
+// withMigrationLock runs fn on one connection of db holding the migration lock, with schema_migrations
+// created. rehearsal is set on planning and scratch pools, whose connection is a transaction already.
+func withMigrationLock(db *sql.DB, fn func(ctx context.Context, conn *sql.Conn, rehearsal bool) error) error {
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("repository: failed to get a connection: %w", err)
+	}
+	defer conn.Close()
+	rehearsal := false
+	conn.Raw(func(driverConn any) error {
+		_, rehearsal = driverConn.(*migrationConn)
+		return nil
+	})
+
+	var locked bool
+	if err := conn.QueryRowContext(ctx, `SELECT TRUE FROM pg_advisory_lock($1)`, int64(migrationLockKey)).Scan(&locked); err != nil {
+		return fmt.Errorf("repository: failed to take the migration lock: %w", err)
+	}
+	defer conn.QueryRowContext(ctx, `SELECT pg_advisory_unlock($1)`, int64(migrationLockKey)).Scan(&locked)
+	if _, err := conn.ExecContext(ctx, createMigrationsTableQuery); err != nil {
+		return fmt.Errorf("repository: failed to create schema_migrations: %w", err)
+	}
+	return fn(ctx, conn, rehearsal)
+}
+
+// migrationExecer runs statements in a transaction or directly on a connection.
+type migrationExecer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// inMigrationTx runs fn in a transaction on conn. In a rehearsal fn runs directly: the connection's
+// transaction is rolled back at the end anyway, and cannot be nested.
+func inMigrationTx(ctx context.Context, conn *sql.Conn, rehearsal bool, fn func(migrationExecer) error) error {
+	if rehearsal {
+		return fn(conn)
+	}
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
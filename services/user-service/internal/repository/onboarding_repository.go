@@ -0,0 +1,71 @@
+// services/user-service/internal/repository/onboarding_repository.go
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+)
+
+// GetOnboarding returns a user's onboarding state without its Next step, or nil if the user does not exist.
+// A user who never moved keeps the step they were created at, as of their registration.
+func (r *postgresUserRepository) GetOnboarding(ctx context.Context, userID uuid.UUID) (*models.OnboardingState, error) {
+	state := &models.OnboardingState{}
+	err := r.db.QueryRowContext(ctx, `SELECT onboarding_step, COALESCE(onboarding_updated_at, created_at) FROM users WHERE id = $1`, userID).
+		Scan(&state.Step, &state.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("repository: failed to get onboarding state: %w", err)
+	}
+	return state, nil
+}
+
+// AdvanceOnboarding moves a user from one onboarding step to another, only if they are still at from,
+// so concurrent moves cannot both succeed. A skip records from as the step skipped from. It returns
+// false if nothing was moved.
+func (r *postgresUserRepository) AdvanceOnboarding(ctx context.Context, userID uuid.UUID, from, to string, skip bool, at time.Time) (bool, error) {
+	var skippedFrom sql.NullString
+	if skip {
+		skippedFrom = sql.NullString{String: from, Valid: true}
+	}
+	res, err := r.db.ExecContext(ctx, `UPDATE users SET onboarding_step = $3, onboarding_skipped_from = $4, onboarding_updated_at = $5
+		WHERE id = $1 AND onboarding_step = $2`, userID, from, to, skippedFrom, at)
+	if err != nil {
+		return false, fmt.Errorf("repository: failed to advance onboarding: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("repository: failed to advance onboarding: %w", err)
+	}
+	return n > 0, nil
+}
+
+// CountOnboardingSteps counts the users created since since (all users if zero) by onboarding step and,
+// for users who skipped, the step they skipped from.
+func (r *postgresUserRepository) CountOnboardingSteps(ctx context.Context, since time.Time) ([]models.OnboardingStepCount, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT onboarding_step, COALESCE(onboarding_skipped_from, ''), COUNT(*)
+		FROM users WHERE created_at >= $1 GROUP BY 1, 2`, since)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to count onboarding steps: %w", err)
+	}
+	defer rows.Close()
+
+	var counts []models.OnboardingStepCount
+	for rows.Next() {
+		var c models.OnboardingStepCount
+		if err := rows.Scan(&c.Step, &c.SkippedFrom, &c.Users); err != nil {
+			return nil, fmt.Errorf("repository: failed to scan onboarding step count: %w", err)
+		}
+		counts = append(counts, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: rows iteration error: %w", err)
+	}
+	return counts, nil
+}
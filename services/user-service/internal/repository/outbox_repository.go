@@ -0,0 +1,151 @@
+// services/user-service/internal/repository/outbox_repository.go
+package repository
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/eventbus"
+	"health-tracker-project/services/user-service/internal/models"
+)
+
+// postgresOutboxRepository is the PostgreSQL implementation of OutboxRepository.
+type postgresOutboxRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresOutboxRepository creates an OutboxRepository on an open pool and runs its migrations.
+func NewPostgresOutboxRepository(db *sql.DB) (OutboxRepository, error) {
+	repo := &postgresOutboxRepository{db: db}
+	if err := repo.Migrate(); err != nil {
+		return nil, fmt.Errorf("failed to run outbox migrations: %w", err)
+	}
+	return repo, nil
+}
+
+// Migrate applies the pending migrations in migrations/outbox.
+func (r *postgresOutboxRepository) Migrate() error {
+	return MigrateSchema(r.db, "outbox")
+}
+
+// UserOutboxEvent returns the event announcing that a user was created, updated, or deleted (eventbus.UserCreated,
+// UserUpdated, or UserDeleted). A user is created and deleted once, so those events have the ID eventbus.NewEvent
+// derives, and writing one again changes nothing; every update has an ID of its own.
+func UserOutboxEvent(eventType string, userID uuid.UUID) models.OutboxEvent {
+	e := eventbus.NewEvent(eventType, userID, nil)
+	if eventType == eventbus.UserUpdated {
+		e.ID = uuid.New()
+	}
+	return models.OutboxEvent{ID: e.ID, Type: e.Type, UserID: e.UserID, OccurredAt: e.OccurredAt, Data: e.Data}
+}
+
+// insertOutboxEvent writes an event to the outbox in tx, available at once. An event whose ID is
+// already there is left as it is.
+func insertOutboxEvent(ctx context.Context, tx *sql.Tx, event models.OutboxEvent) error {
+	var data []byte
+	if event.Data != nil {
+		var err error
+		if data, err = json.Marshal(event.Data); err != nil {
+			return fmt.Errorf("repository: failed to encode %s event: %w", event.Type, err)
+		}
+	}
+	_, err := tx.ExecContext(ctx, `INSERT INTO outbox (id, event_type, user_id, occurred_at, data, available_at)
+		VALUES ($1, $2, $3, $4, $5, $4) ON CONFLICT (id) DO NOTHING`, event.ID, event.Type, event.UserID, event.OccurredAt, data)
+	if err != nil {
+		return fmt.Errorf("repository: failed to write %s event to the outbox: %w", event.Type, err)
+	}
+	return nil
+}
+
+// ClaimOutboxEvents leases up to limit pending events to the caller until leaseUntil, so other
+// dispatchers skip them, and returns them oldest first. Events whose lease ran out without being sent
+// are pending again.
+func (r *postgresOutboxRepository) ClaimOutboxEvents(ctx context.Context, now, leaseUntil time.Time, limit int) ([]models.OutboxEvent, error) {
+	rows, err := r.db.QueryContext(ctx, `UPDATE outbox SET available_at = $2
+		WHERE id IN (
+			SELECT id FROM outbox WHERE sent_at IS NULL AND available_at <= $1
+			ORDER BY occurred_at, id LIMIT $3 FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, event_type, user_id, occurred_at, data, attempts, COALESCE(last_error, '')`, now, leaseUntil, limit)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to claim outbox events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []models.OutboxEvent{}
+	for rows.Next() {
+		var e models.OutboxEvent
+		var data []byte
+		if err := rows.Scan(&e.ID, &e.Type, &e.UserID, &e.OccurredAt, &data, &e.Attempts, &e.LastError); err != nil {
+			return nil, fmt.Errorf("repository: failed to scan outbox event: %w", err)
+		}
+		if data != nil {
+			if err := json.Unmarshal(data, &e.Data); err != nil {
+				return nil, fmt.Errorf("repository: failed to decode outbox event %s: %w", e.ID, err)
+			}
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: failed to claim outbox events: %w", err)
+	}
+	// RETURNING keeps no order.
+	slices.SortFunc(events, compareOutboxEvents)
+	return events, nil
+}
+
+// compareOutboxEvents orders events as they are claimed: oldest first, then by ID.
+func compareOutboxEvents(a, b models.OutboxEvent) int {
+	if c := a.OccurredAt.Compare(b.OccurredAt); c != 0 {
+		return c
+	}
+	return bytes.Compare(a.ID[:], b.ID[:])
+}
+
+// MarkOutboxEventSent records that an event was published, which ends its stay in the outbox.
+func (r *postgresOutboxRepository) MarkOutboxEventSent(ctx context.Context, id uuid.UUID, at time.Time) error {
+	if _, err := r.db.ExecContext(ctx, `UPDATE outbox SET sent_at = $2, last_error = NULL WHERE id = $1`, id, at); err != nil {
+		return fmt.Errorf("repository: failed to mark outbox event sent: %w", err)
+	}
+	return nil
+}
+
+// FailOutboxEvent records a failed attempt to publish an event, which is claimable again from retryAt.
+func (r *postgresOutboxRepository) FailOutboxEvent(ctx context.Context, id uuid.UUID, retryAt time.Time, lastError string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE outbox SET available_at = $2, attempts = attempts + 1, last_error = $3 WHERE id = $1 AND sent_at IS NULL`,
+		id, retryAt, lastError)
+	if err != nil {
+		return fmt.Errorf("repository: failed to record outbox event failure: %w", err)
+	}
+	return nil
+}
+
+// ReleaseOutboxEvents hands claimed events back before their lease ends, claimable again from at.
+func (r *postgresOutboxRepository) ReleaseOutboxEvents(ctx context.Context, ids []uuid.UUID, at time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if _, err := r.db.ExecContext(ctx, `UPDATE outbox SET available_at = $2 WHERE id = ANY($1::uuid[]) AND sent_at IS NULL`, ids, at); err != nil {
+		return fmt.Errorf("repository: failed to release outbox events: %w", err)
+	}
+	return nil
+}
+
+// PurgeSentOutboxEvents deletes the events sent before a time and returns how many it deleted.
+func (r *postgresOutboxRepository) PurgeSentOutboxEvents(ctx context.Context, before time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM outbox WHERE sent_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("repository: failed to purge sent outbox events: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("repository: failed to purge sent outbox events: %w", err)
+	}
+	return n, nil
+}
@@ -0,0 +1,155 @@
+// services/user-service/internal/repository/postgres.go
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+	"health-tracker-project/services/user-service/internal/metrics"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// PoolConfig sizes a connection pool. Zero fields take their value from DefaultPoolConfig.
+type PoolConfig struct {
+	MaxOpenConns    int           // Connections open at once, in use or idle
+	MinConns        int           // Connections kept open even while idle; capped at MaxOpenConns
+	ConnMaxLifetime time.Duration // Connections are closed after this long, so they rebalance across poolers and replicas
+}
+
+// DefaultPoolConfig is used for the fields a PoolConfig leaves unset. pgxpool alone would open only four
+// connections, or one per CPU on larger hosts. No connection is kept open while idle: pgxpool closes
+// idle ones after half an hour, so a quiet replica holds none of the server's connections.
+var DefaultPoolConfig = PoolConfig{
+	MaxOpenConns:    25,
+	ConnMaxLifetime: 30 * time.Minute,
+}
+
+// withDefaults fills the unset fields of the config from DefaultPoolConfig.
+func (c PoolConfig) withDefaults() PoolConfig {
+	if c.MaxOpenConns == 0 {
+		c.MaxOpenConns = DefaultPoolConfig.MaxOpenConns
+	}
+	if c.ConnMaxLifetime == 0 {
+		c.ConnMaxLifetime = DefaultPoolConfig.ConnMaxLifetime
+	}
+	c.MinConns = min(c.MinConns, c.MaxOpenConns)
+	return c
+}
+
+// NewPostgresDB opens a PostgreSQL connection pool sized by pool and pings it.
+// The returned pool is shared by all Postgres-backed repositories; the caller owns closing it.
+// Its connections report applicationName as application_name (in pg_stat_activity and server logs),
+// unless the data source name already sets one.
+//
+// The connections are pgx ones, pooled by pgxpool and served through database/sql, which keeps none
+// of them idle itself. pgx speaks the binary protocol and caches each query's prepared statement per
+// connection.
+func NewPostgresDB(dataSourceName, applicationName string, pool PoolConfig) (*sql.DB, error) {
+	config, err := pgxpool.ParseConfig(withApplicationName(dataSourceName, applicationName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse database URL: %w", err)
+	}
+	pool = pool.withDefaults()
+	config.MaxConns = int32(pool.MaxOpenConns)
+	config.MinConns = int32(pool.MinConns)
+	config.MaxConnLifetime = pool.ConnMaxLifetime
+	config.AfterConnect = scanTimesInUTC
+	pgxPool, err := pgxpool.NewWithConfig(context.Background(), config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	connector := &poolConnector{Connector: stdlib.GetPoolConnector(pgxPool), pool: pgxPool}
+	db := sql.OpenDB(connector)
+	connector.db = db
+	pgxPools.Store(db, pgxPool)
+	db.SetMaxIdleConns(0) // Idle connections go straight back to pgxpool, which keeps MinConns of them open
+
+	// Ping the database to ensure connection is established
+	if err = db.Ping(); err != nil {
+		db.Close() // Close the connection if ping fails
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	logger.Logger.Infof("Connected to PostgreSQL database successfully as %s! (pool: %d open, %d minimum, %s lifetime)",
+		applicationName, pool.MaxOpenConns, pool.MinConns, pool.ConnMaxLifetime)
+	return db, nil
+}
+
+// poolConnector serves database/sql connections from a pgxpool, and closes the pool with the sql.DB.
+type poolConnector struct {
+	driver.Connector
+	pool *pgxpool.Pool
+	db   *sql.DB
+}
+
+func (c *poolConnector) Close() error {
+	pgxPools.Delete(c.db)
+	c.pool.Close()
+	return nil
+}
+
+// pgxPools maps each *sql.DB opened by NewPostgresDB to the pgxpool it draws its connections from.
+var pgxPools sync.Map
+
+// PoolStats returns a snapshot of db's connection pool, for metrics.RegisterDBPool. For a pool from
+// NewPostgresDB it reports pgxpool, which holds the connections; for any other, database/sql.
+func PoolStats(db *sql.DB) metrics.DBPoolStats {
+	if pool, ok := pgxPools.Load(db); ok {
+		stat := pool.(*pgxpool.Pool).Stat()
+		return metrics.DBPoolStats{
+			MaxConns:     int(stat.MaxConns()),
+			OpenConns:    int(stat.TotalConns()),
+			InUse:        int(stat.AcquiredConns()),
+			Idle:         int(stat.IdleConns()),
+			WaitCount:    stat.EmptyAcquireCount(),
+			WaitDuration: stat.EmptyAcquireWaitTime(),
+		}
+	}
+	stats := db.Stats()
+	return metrics.DBPoolStats{
+		MaxConns:     stats.MaxOpenConnections,
+		OpenConns:    stats.OpenConnections,
+		InUse:        stats.InUse,
+		Idle:         stats.Idle,
+		WaitCount:    stats.WaitCount,
+		WaitDuration: stats.WaitDuration,
+	}
+}
+
+// scanTimesInUTC makes a connection read timestamptz values in UTC rather than in the process's local
+// time zone, so the times repositories return are the same on every host.
+func scanTimesInUTC(ctx context.Context, conn *pgx.Conn) error {
+	conn.TypeMap().RegisterType(&pgtype.Type{Name: "timestamptz", OID: pgtype.TimestamptzOID, Codec: &pgtype.TimestamptzCodec{ScanLocation: time.UTC}})
+	return nil
+}
+
+// withApplicationName adds application_name to a postgres:// URL or a key=value data source name
+// that does not set it already.
+func withApplicationName(dataSourceName, applicationName string) string {
+	if applicationName == "" {
+		return dataSourceName
+	}
+	if u, err := url.Parse(dataSourceName); err == nil && (u.Scheme == "postgres" || u.Scheme == "postgresql") {
+		q := u.Query()
+		if q.Get("application_name") == "" {
+			q.Set("application_name", applicationName)
+			u.RawQuery = q.Encode()
+		}
+		return u.String()
+	}
+	if strings.Contains(dataSourceName, "application_name=") {
+		return dataSourceName
+	}
+	value := strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(applicationName)
+	return strings.TrimSpace(dataSourceName + " application_name='" + value + "'")
+}
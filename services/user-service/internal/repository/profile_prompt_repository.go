@@ -0,0 +1,43 @@
+// services/user-service/internal/repository/profile_prompt_repository.go
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+)
+
+// GetProfilePromptDismissals returns a user's prompt dismissals, keyed by field.
+func (r *postgresUserRepository) GetProfilePromptDismissals(ctx context.Context, userID uuid.UUID) (map[string]models.ProfilePromptDismissal, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT field, count, last_dismissed_at FROM profile_prompt_dismissals WHERE user_id = $1`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to get profile prompt dismissals: %w", err)
+	}
+	defer rows.Close()
+
+	dismissals := map[string]models.ProfilePromptDismissal{}
+	for rows.Next() {
+		var d models.ProfilePromptDismissal
+		if err := rows.Scan(&d.Field, &d.Count, &d.LastDismissedAt); err != nil {
+			return nil, fmt.Errorf("repository: failed to scan profile prompt dismissal row: %w", err)
+		}
+		dismissals[d.Field] = d
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: rows iteration error: %w", err)
+	}
+	return dismissals, nil
+}
+
+// DismissProfilePrompt records one more dismissal of the prompt for field.
+func (r *postgresUserRepository) DismissProfilePrompt(ctx context.Context, userID uuid.UUID, field string, at time.Time) error {
+	query := `INSERT INTO profile_prompt_dismissals (user_id, field, count, last_dismissed_at) VALUES ($1, $2, 1, $3)
+		ON CONFLICT (user_id, field) DO UPDATE SET count = profile_prompt_dismissals.count + 1, last_dismissed_at = EXCLUDED.last_dismissed_at`
+	if _, err := r.db.ExecContext(ctx, query, userID, field, at); err != nil {
+		return fmt.Errorf("repository: failed to dismiss profile prompt: %w", err)
+	}
+	return nil
+}
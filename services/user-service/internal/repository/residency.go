@@ -0,0 +1,448 @@
+// services/user-service/internal/repository/residency.go
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// RegionRouter pins each user's data to the database of one region. Every region database holds the
+// full schema; the region directory in the home database maps user IDs, email and email key hashes, and
+// username hashes to regions, so lookups by email or username never read another region's users table and no
+// email leaves its region.
+type RegionRouter struct {
+	home    string
+	dbs     map[string]*sql.DB
+	regions []string // Home region first, then alphabetical
+}
+
+// subjectTables are the tables holding a user's rows, parents first, with the column naming the user.
+// MigrateSubject copies them in this order.
+var subjectTables = []struct{ table, column string }{
+	{"users", "id"},
+	{"user_timezone_history", "user_id"},
+	{"password_reset_tokens", "user_id"},
+	{"email_verification_tokens", "user_id"},
+	{"profile_prompt_dismissals", "user_id"},
+	{"aggregation_periods", "user_id"},
+	{"user_merges", "primary_user_id"},
+	{"user_email_aliases", "user_id"},
+	{"user_events", "user_id"},
+	{"dashboard_layouts", "user_id"},
+	{"user_settings", "user_id"},
+	{"login_attempts", "user_id"},
+	{"sessions", "user_id"},
+	{"user_identities", "user_id"},
+	{"identity_link_requests", "user_id"},
+	{"integration_consents", "user_id"},
+	{"coach_authorizations", "user_id"},
+	{"message_threads", "user_id"},
+	{"messages", "user_id"},
+	{"message_attachments", "user_id"},
+	{"appointment_slots", "provider_id"},
+	{"appointments", "provider_id"},
+	{"workout_attachments", "user_id"},
+	{"workout_plans", "coach_id"},
+}
+
+// NewRegionRouter creates a router over open pools, one per region, and migrates the region directory
+// in the home region's database.
+func NewRegionRouter(home string, dbs map[string]*sql.DB) (*RegionRouter, error) {
+	if _, ok := dbs[home]; !ok {
+		return nil, fmt.Errorf("home region %q has no database", home)
+	}
+	r := &RegionRouter{home: home, dbs: dbs, regions: []string{home}}
+	for region := range dbs {
+		if region != home {
+			r.regions = append(r.regions, region)
+		}
+	}
+	sort.Strings(r.regions[1:])
+
+	if err := MigrateSchema(dbs[home], "user_regions"); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Regions lists the configured regions, home region first.
+func (r *RegionRouter) Regions() []string {
+	return r.regions
+}
+
+// backfill assigns users created before residency was enabled, who all live in the home database,
+// to the home region. It must run after the users table exists.
+func (r *RegionRouter) backfill() error {
+	res, err := r.dbs[r.home].Exec(`INSERT INTO user_regions (user_id, email_hash, username_hash, email_key_hash, region)
+		SELECT id, encode(sha256(convert_to(email, 'UTF8')), 'hex'), encode(sha256(convert_to(username, 'UTF8')), 'hex'),
+			encode(sha256(convert_to(email_key, 'UTF8')), 'hex'), $1 FROM users
+		ON CONFLICT DO NOTHING`, r.home)
+	if err != nil {
+		return fmt.Errorf("failed to backfill user_regions: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		logger.Logger.Infof("Assigned %d existing user(s) to home region %s", n, r.home)
+	}
+	return r.backfillEmailKeys()
+}
+
+// backfillEmailKeys fills in the email key hash of directory entries made before it existed, from the
+// users table of each entry's region. Keys taken by another entry are left out, like users.email_key
+// leaves out shared keys.
+func (r *RegionRouter) backfillEmailKeys() error {
+	filled := 0
+	for _, region := range r.regions {
+		rows, err := r.dbs[r.home].Query(`SELECT user_id FROM user_regions WHERE region = $1 AND email_key_hash IS NULL`, region)
+		if err != nil {
+			return fmt.Errorf("failed to list user_regions without email keys: %w", err)
+		}
+		var ids []uuid.UUID
+		for rows.Next() {
+			var id uuid.UUID
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan user_regions: %w", err)
+			}
+			ids = append(ids, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to list user_regions without email keys: %w", err)
+		}
+		if len(ids) == 0 {
+			continue
+		}
+
+		keys := map[string]string{}
+		rows, err = r.dbs[region].Query(`SELECT id, email_key FROM users WHERE id = ANY($1::uuid[]) AND email_key IS NOT NULL`, ids)
+		if err != nil {
+			return fmt.Errorf("failed to read email keys in region %s: %w", region, err)
+		}
+		for rows.Next() {
+			var id, key string
+			if err := rows.Scan(&id, &key); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan email keys in region %s: %w", region, err)
+			}
+			keys[id] = key
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to read email keys in region %s: %w", region, err)
+		}
+
+		for id, key := range keys {
+			res, err := r.dbs[r.home].Exec(`UPDATE user_regions SET email_key_hash = $1 WHERE user_id = $2
+				AND NOT EXISTS (SELECT 1 FROM user_regions WHERE email_key_hash = $1)`, hashEmail(key), id)
+			if err != nil {
+				return fmt.Errorf("failed to backfill user_regions email keys: %w", err)
+			}
+			if n, _ := res.RowsAffected(); n > 0 {
+				filled++
+			}
+		}
+	}
+	if filled > 0 {
+		logger.Logger.Infof("Set the email key hash of %d region directory entries", filled)
+	}
+	return nil
+}
+
+// hashEmail matches the email_hash computed by backfill. Email key hashes are hashEmail of the key.
+func hashEmail(email string) string {
+	sum := sha256.Sum256([]byte(email))
+	return hex.EncodeToString(sum[:])
+}
+
+// hashUsername matches the username_hash computed by backfill; users without a username have none.
+func hashUsername(username string) sql.NullString {
+	if username == "" {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: hashEmail(username), Valid: true}
+}
+
+// regionOf returns the region a user is assigned to, or the home region for unknown users,
+// whose lookups then find nothing there.
+func (r *RegionRouter) regionOf(ctx context.Context, userID uuid.UUID) (string, error) {
+	var region string
+	err := r.dbs[r.home].QueryRowContext(ctx, `SELECT region FROM user_regions WHERE user_id = $1`, userID).Scan(&region)
+	if err == sql.ErrNoRows {
+		return r.home, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("repository: failed to look up user region: %w", err)
+	}
+	return region, nil
+}
+
+// regionOfEmail returns the region of the user GetUserByEmail finds for the given normalized email, or ""
+// if there is none: the user with the same email key, or, for entries without one, the same email.
+func (r *RegionRouter) regionOfEmail(ctx context.Context, email string) (string, error) {
+	return r.regionOfHash(ctx, `SELECT region FROM user_regions WHERE email_key_hash = $1 OR email_hash = $2
+		ORDER BY email_hash = $2 DESC LIMIT 1`, hashEmail(models.EmailKey(email)), hashEmail(email))
+}
+
+// regionOfUsername returns the region of the user with the given username, or "" if no user has it.
+func (r *RegionRouter) regionOfUsername(ctx context.Context, username string) (string, error) {
+	return r.regionOfHash(ctx, `SELECT region FROM user_regions WHERE username_hash = $1`, hashEmail(username))
+}
+
+func (r *RegionRouter) regionOfHash(ctx context.Context, query string, hashes ...interface{}) (string, error) {
+	var region string
+	err := r.dbs[r.home].QueryRowContext(ctx, query, hashes...).Scan(&region)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("repository: failed to look up user region: %w", err)
+	}
+	return region, nil
+}
+
+// checkRegion enforces that data is only ever routed to a configured region. A directory entry naming
+// any other region fails the operation instead of falling back to the home database.
+func (r *RegionRouter) checkRegion(region string) error {
+	if _, ok := r.dbs[region]; !ok {
+		return fmt.Errorf("repository: region %q is not configured", region)
+	}
+	return nil
+}
+
+// assign records a new user's region in the directory.
+func (r *RegionRouter) assign(ctx context.Context, userID uuid.UUID, email, username, region string) error {
+	_, err := r.dbs[r.home].ExecContext(ctx, `INSERT INTO user_regions (user_id, email_hash, username_hash, email_key_hash, region) VALUES ($1, $2, $3, $4, $5)`,
+		userID, hashEmail(email), hashUsername(username), hashEmail(models.EmailKey(email)), region)
+	if err != nil {
+		if dup := duplicateUserError(err, "assign user region"); dup != nil {
+			return dup
+		}
+		return fmt.Errorf("repository: failed to assign user region: %w", err)
+	}
+	return nil
+}
+
+// updateEmail keeps the directory's email and email key hashes in step with an email change. A user
+// taking an email aliased to them takes over the alias's entry.
+func (r *RegionRouter) updateEmail(ctx context.Context, userID uuid.UUID, email string) error {
+	_, err := r.dbs[r.home].ExecContext(ctx, `DELETE FROM user_regions WHERE alias_of = $1 AND (email_hash = $2 OR email_key_hash = $3)`,
+		userID, hashEmail(email), hashEmail(models.EmailKey(email)))
+	if err != nil {
+		return fmt.Errorf("repository: failed to update user region email: %w", err)
+	}
+	_, err = r.dbs[r.home].ExecContext(ctx, `UPDATE user_regions SET email_hash = $1, email_key_hash = $2, updated_at = NOW() WHERE user_id = $3`,
+		hashEmail(email), hashEmail(models.EmailKey(email)), userID)
+	if err != nil {
+		if dup := duplicateUserError(err, "update user region email"); dup != nil {
+			return dup
+		}
+		return fmt.Errorf("repository: failed to update user region email: %w", err)
+	}
+	return nil
+}
+
+// updateUsername keeps the directory's username hash in step with a username change. The hash is
+// unique, so a username taken in another region is refused here.
+func (r *RegionRouter) updateUsername(ctx context.Context, userID uuid.UUID, username string) error {
+	_, err := r.dbs[r.home].ExecContext(ctx, `UPDATE user_regions SET username_hash = $1, updated_at = NOW() WHERE user_id = $2`,
+		hashUsername(username), userID)
+	if err != nil {
+		if dup := duplicateUserError(err, "update user region username"); dup != nil {
+			return dup
+		}
+		return fmt.Errorf("repository: failed to update user region username: %w", err)
+	}
+	return nil
+}
+
+// alias keeps a merged-away user's directory entry, without its username, so its email still routes to
+// the region of the user it was merged into; so do the entries of users merged into it before.
+func (r *RegionRouter) alias(ctx context.Context, userID, into uuid.UUID) error {
+	_, err := r.dbs[r.home].ExecContext(ctx, `UPDATE user_regions SET alias_of = $1, username_hash = CASE WHEN user_id = $2 THEN NULL ELSE username_hash END,
+		updated_at = NOW() WHERE user_id = $2 OR alias_of = $2`, into, userID)
+	if err != nil {
+		return fmt.Errorf("repository: failed to alias user region: %w", err)
+	}
+	return nil
+}
+
+// unalias turns an alias entry back into the entry of a user restored by undoing a merge. Entries of users
+// merged into it before stay aliases of the user it had been merged into, like their email aliases.
+func (r *RegionRouter) unalias(ctx context.Context, userID uuid.UUID) error {
+	if _, err := r.dbs[r.home].ExecContext(ctx, `UPDATE user_regions SET alias_of = NULL, updated_at = NOW() WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("repository: failed to unalias user region: %w", err)
+	}
+	return nil
+}
+
+// unassign removes a deleted user from the directory, along with the entries aliasing it.
+func (r *RegionRouter) unassign(ctx context.Context, userID uuid.UUID) error {
+	if _, err := r.dbs[r.home].ExecContext(ctx, `DELETE FROM user_regions WHERE user_id = $1 OR alias_of = $1`, userID); err != nil {
+		return fmt.Errorf("repository: failed to remove user region: %w", err)
+	}
+	return nil
+}
+
+// MigrateSubject moves every row of a user to another region: it copies them into the target database,
+// repoints the directory, and deletes them from the source. The user's row in the source is locked
+// throughout, so writes routed there wait and fail once the row is gone instead of being lost silently.
+func (r *RegionRouter) MigrateSubject(ctx context.Context, userID uuid.UUID, to string) error {
+	if err := r.checkRegion(to); err != nil {
+		return err
+	}
+	from, err := r.regionOf(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := r.checkRegion(from); err != nil {
+		return err
+	}
+	if from == to {
+		return fmt.Errorf("repository: user is already in region %s", to)
+	}
+
+	src, err := r.dbs[from].BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("repository: failed to begin source transaction: %w", err)
+	}
+	defer src.Rollback()
+	var exists bool
+	if err := src.QueryRowContext(ctx, `SELECT TRUE FROM users WHERE id = $1 FOR UPDATE`, userID).Scan(&exists); err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("repository: user not found in region %s", from)
+		}
+		return fmt.Errorf("repository: failed to lock user: %w", err)
+	}
+
+	dst, err := r.dbs[to].BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("repository: failed to begin target transaction: %w", err)
+	}
+	defer dst.Rollback()
+	for _, t := range subjectTables {
+		if err := copyRows(ctx, src, dst, t.table, t.column, userID); err != nil {
+			return err
+		}
+	}
+	if err := dst.Commit(); err != nil {
+		return fmt.Errorf("repository: failed to commit target region: %w", err)
+	}
+
+	if _, err := r.dbs[r.home].ExecContext(ctx, `UPDATE user_regions SET region = $1, updated_at = NOW() WHERE user_id = $2 OR alias_of = $2`, to, userID); err != nil {
+		// Undo the copy so the user is not left in two regions.
+		if cleanupErr := deleteSubject(ctx, r.dbs[to], userID); cleanupErr != nil {
+			logger.FromContext(ctx).Errorf("Failed to remove copy of user %s from region %s: %v", userID, to, cleanupErr)
+		}
+		return fmt.Errorf("repository: failed to update user region: %w", err)
+	}
+
+	err = deleteSubject(ctx, src, userID)
+	if err == nil {
+		err = src.Commit()
+	}
+	if err != nil {
+		// The user is served from the target already; the stale copy shows up in FindViolations.
+		logger.FromContext(ctx).Errorf("Moved user %s to %s but failed to delete it from %s: %v", userID, to, from, err)
+		return fmt.Errorf("repository: user moved but not deleted from region %s: %w", from, err)
+	}
+	logger.FromContext(ctx).Infof("Moved user %s from region %s to %s", userID, from, to)
+	return nil
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx.
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// deleteSubject removes a user's rows. Other tables cascade from users; merge records do not reference it.
+func deleteSubject(ctx context.Context, db execer, userID uuid.UUID) error {
+	if _, err := db.ExecContext(ctx, `DELETE FROM user_merges WHERE primary_user_id = $1`, userID); err != nil {
+		return err
+	}
+	_, err := db.ExecContext(ctx, `DELETE FROM users WHERE id = $1`, userID)
+	return err
+}
+
+// copyRows copies a user's rows of one table between databases with the same schema.
+// Rows travel as JSON so every column type round-trips without listing columns.
+func copyRows(ctx context.Context, src, dst *sql.Tx, table, column string, userID uuid.UUID) error {
+	rows, err := src.QueryContext(ctx, `SELECT row_to_json(t)::text FROM `+table+` t WHERE `+column+` = $1`, userID)
+	if err != nil {
+		return fmt.Errorf("repository: failed to read %s: %w", table, err)
+	}
+	var records []string
+	for rows.Next() {
+		var record string
+		if err := rows.Scan(&record); err != nil {
+			rows.Close()
+			return fmt.Errorf("repository: failed to read %s: %w", table, err)
+		}
+		records = append(records, record)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("repository: failed to read %s: %w", table, err)
+	}
+
+	for _, record := range records {
+		if _, err := dst.ExecContext(ctx, `INSERT INTO `+table+` SELECT * FROM json_populate_record(NULL::`+table+`, $1::json)`, record); err != nil {
+			return fmt.Errorf("repository: failed to copy %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+// FindViolations lists users stored in a region the directory does not assign them to,
+// for example after an interrupted move or a write that bypassed the router.
+func (r *RegionRouter) FindViolations(ctx context.Context) ([]models.ResidencyViolation, error) {
+	assigned := map[uuid.UUID]string{}
+	rows, err := r.dbs[r.home].QueryContext(ctx, `SELECT user_id, region FROM user_regions`)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to read user regions: %w", err)
+	}
+	for rows.Next() {
+		var id uuid.UUID
+		var region string
+		if err := rows.Scan(&id, &region); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("repository: failed to scan user region: %w", err)
+		}
+		assigned[id] = region
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: failed to read user regions: %w", err)
+	}
+
+	violations := []models.ResidencyViolation{}
+	for _, region := range r.regions {
+		rows, err := r.dbs[region].QueryContext(ctx, `SELECT id FROM users`)
+		if err != nil {
+			return nil, fmt.Errorf("repository: failed to list users in region %s: %w", region, err)
+		}
+		for rows.Next() {
+			var id uuid.UUID
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("repository: failed to scan user in region %s: %w", region, err)
+			}
+			if assigned[id] != region {
+				violations = append(violations, models.ResidencyViolation{UserID: id, FoundIn: region, Expected: assigned[id]})
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("repository: failed to list users in region %s: %w", region, err)
+		}
+	}
+	return violations, nil
+}
@@ -0,0 +1,138 @@
+// services/user-service/internal/repository/retry.go
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"syscall"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"health-tracker-project/services/user-service/internal/metrics"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// RetryPolicy configures how the retrying repositories retry operations that failed for a transient
+// reason. Zero fields keep the defaults.
+type RetryPolicy struct {
+	Attempts  int           // Tries per operation, including the first; 1 turns retrying off (default 3)
+	BaseDelay time.Duration // Backoff before the first retry, doubled before each further one (default 50ms)
+	MaxDelay  time.Duration // Longest backoff before a retry (default 2s)
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.Attempts <= 0 {
+		p.Attempts = 3
+	}
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = 50 * time.Millisecond
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = 2 * time.Second
+	}
+	p.MaxDelay = max(p.MaxDelay, p.BaseDelay)
+	return p
+}
+
+// backoff returns how long to wait before retry n (from 1): a random duration up to BaseDelay*2^(n-1),
+// capped at MaxDelay, so operations that failed together do not retry in step.
+func (p RetryPolicy) backoff(n int) time.Duration {
+	ceiling := p.MaxDelay
+	if d := p.BaseDelay << min(n-1, 32); d > 0 && d < p.MaxDelay { // d overflows for large bases
+		ceiling = d
+	}
+	return time.Duration(rand.Int64N(int64(ceiling)) + 1)
+}
+
+// transientReason returns the metrics.DBRetry reason err is worth retrying for, or "" if it is not.
+// Serialization failures and deadlocks roll the transaction back, and a connection that could not be
+// made never ran anything, so those are safe to retry for any operation. A connection lost while a
+// statement was running may have committed it, so mayHaveRun is true and only reads retry it.
+//
+// Errors are classified as the pgx driver reports them, by SQLSTATE, since every repository the retrying
+// ones wrap is PostgreSQL or in memory.
+func transientReason(err error) (reason string, mayHaveRun bool) {
+	if pgErr := pgError(err); pgErr != nil {
+		switch code := pgErr.Code; {
+		case code == "40001":
+			return metrics.DBRetrySerialization, false
+		case code == "40P01":
+			return metrics.DBRetryDeadlock, false
+		case code == "08001", code == "08004", code == "57P03", code == "53300":
+			// Unable to connect, connection rejected, the server starting up, or out of connection slots
+			return metrics.DBRetryConnection, false
+		case code[:2] == "08", code == "57P01", code == "57P02":
+			// Connection failures and the server terminating the connection
+			return metrics.DBRetryConnection, true
+		}
+		return "", false
+	}
+	var connectErr *pgconn.ConnectError
+	if errors.As(err, &connectErr) || pgconn.SafeToRetry(err) {
+		// No connection was made, or pgx knows nothing was sent on it
+		return metrics.DBRetryConnection, false
+	}
+	switch {
+	case errors.Is(err, driver.ErrBadConn), errors.Is(err, syscall.ECONNREFUSED):
+		return metrics.DBRetryConnection, false
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.EPIPE), errors.Is(err, io.ErrUnexpectedEOF):
+		return metrics.DBRetryConnection, true
+	}
+	return "", false
+}
+
+// retrier runs repository operations under a RetryPolicy.
+type retrier struct {
+	policy RetryPolicy
+}
+
+func newRetrier(policy RetryPolicy) retrier {
+	return retrier{policy: policy.withDefaults()}
+}
+
+// read runs an operation that changes nothing, retrying every transient failure.
+func (r retrier) read(ctx context.Context, op string, fn func() error) error {
+	return r.run(ctx, op, true, fn)
+}
+
+// write runs an operation that may change data, retrying only the transient failures that leave
+// nothing changed.
+func (r retrier) write(ctx context.Context, op string, fn func() error) error {
+	return r.run(ctx, op, false, fn)
+}
+
+func (r retrier) run(ctx context.Context, op string, idempotent bool, fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		start := time.Now()
+		err := fn()
+		metrics.ObserveDBOperation(op, time.Since(start))
+		if err == nil {
+			if attempt > 1 {
+				metrics.DBRetrySucceeded()
+			}
+			return nil
+		}
+		reason, mayHaveRun := transientReason(err)
+		if reason == "" || (mayHaveRun && !idempotent) || ctx.Err() != nil {
+			return err
+		}
+		if attempt >= r.policy.Attempts {
+			if attempt > 1 {
+				metrics.DBRetriesExhausted()
+				logger.FromContext(ctx).Warnf("Database operation %s failed after %d attempts: %v", op, attempt, err)
+			}
+			return err
+		}
+		metrics.DBRetried(reason)
+		timer := time.NewTimer(r.policy.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
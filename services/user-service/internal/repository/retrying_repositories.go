@@ -0,0 +1,1389 @@
+// services/user-service/internal/repository/retrying_repositories.go
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+)
+
+// The retrying repositories wrap a repository and retry each call that failed for a transient reason,
+// under a RetryPolicy. A call is retried as a whole, so an operation running in a transaction starts it
+// again. Reads are retried for every transient failure; calls that change data only for those that left
+// nothing changed, as a lost connection may have committed them. Migrate is not retried. Failures are
+// recognized by PostgreSQL's error codes and pgx's connection errors (see transientReason).
+
+// retryingUserRepository retries UserRepository calls that fail for a transient reason.
+type retryingUserRepository struct {
+	next  UserRepository
+	retry retrier
+}
+
+// NewRetryingUserRepository wraps next in a UserRepository that retries under policy.
+func NewRetryingUserRepository(next UserRepository, policy RetryPolicy) UserRepository {
+	return &retryingUserRepository{next: next, retry: newRetrier(policy)}
+}
+
+func (r *retryingUserRepository) CreateUser(ctx context.Context, user *models.User) error {
+	return r.retry.write(ctx, "User.CreateUser", func() error {
+		return r.next.CreateUser(ctx, user)
+	})
+}
+
+func (r *retryingUserRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	var user *models.User
+	err := r.retry.read(ctx, "User.GetUserByEmail", func() (err error) {
+		user, err = r.next.GetUserByEmail(ctx, email)
+		return err
+	})
+	return user, err
+}
+
+func (r *retryingUserRepository) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	var user *models.User
+	err := r.retry.read(ctx, "User.GetUserByUsername", func() (err error) {
+		user, err = r.next.GetUserByUsername(ctx, username)
+		return err
+	})
+	return user, err
+}
+
+func (r *retryingUserRepository) GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	var user *models.User
+	err := r.retry.read(ctx, "User.GetUserByID", func() (err error) {
+		user, err = r.next.GetUserByID(ctx, id)
+		return err
+	})
+	return user, err
+}
+
+func (r *retryingUserRepository) GetAllUsers(ctx context.Context, after *models.PageKey, limit int) ([]models.User, error) {
+	var users []models.User
+	err := r.retry.read(ctx, "User.GetAllUsers", func() (err error) {
+		users, err = r.next.GetAllUsers(ctx, after, limit)
+		return err
+	})
+	return users, err
+}
+
+func (r *retryingUserRepository) ListUsers(ctx context.Context, filter models.UserFilter) ([]models.User, error) {
+	var users []models.User
+	err := r.retry.read(ctx, "User.ListUsers", func() (err error) {
+		users, err = r.next.ListUsers(ctx, filter)
+		return err
+	})
+	return users, err
+}
+
+func (r *retryingUserRepository) CountUsers(ctx context.Context, filter models.UserFilter, activeSince time.Time) (*models.UserCounts, error) {
+	var counts *models.UserCounts
+	err := r.retry.read(ctx, "User.CountUsers", func() (err error) {
+		counts, err = r.next.CountUsers(ctx, filter, activeSince)
+		return err
+	})
+	return counts, err
+}
+
+func (r *retryingUserRepository) SearchUsers(ctx context.Context, query string, limit int) ([]models.UserMatch, error) {
+	var matches []models.UserMatch
+	err := r.retry.read(ctx, "User.SearchUsers", func() (err error) {
+		matches, err = r.next.SearchUsers(ctx, query, limit)
+		return err
+	})
+	return matches, err
+}
+
+func (r *retryingUserRepository) MarkEmailVerified(ctx context.Context, userID uuid.UUID, at time.Time) error {
+	return r.retry.write(ctx, "User.MarkEmailVerified", func() error {
+		return r.next.MarkEmailVerified(ctx, userID, at)
+	})
+}
+
+func (r *retryingUserRepository) RecordEmailDeliveryEvent(ctx context.Context, userID uuid.UUID, email, kind string, at time.Time) (string, error) {
+	var status string
+	err := r.retry.write(ctx, "User.RecordEmailDeliveryEvent", func() (err error) {
+		status, err = r.next.RecordEmailDeliveryEvent(ctx, userID, email, kind, at)
+		return err
+	})
+	return status, err
+}
+
+func (r *retryingUserRepository) CreateEmailVerificationToken(ctx context.Context, userID uuid.UUID, email, tokenHash string, expiresAt time.Time) error {
+	return r.retry.write(ctx, "User.CreateEmailVerificationToken", func() error {
+		return r.next.CreateEmailVerificationToken(ctx, userID, email, tokenHash, expiresAt)
+	})
+}
+
+func (r *retryingUserRepository) ConsumeEmailVerificationToken(ctx context.Context, userID uuid.UUID, tokenHash string) (string, error) {
+	var email string
+	err := r.retry.write(ctx, "User.ConsumeEmailVerificationToken", func() (err error) {
+		email, err = r.next.ConsumeEmailVerificationToken(ctx, userID, tokenHash)
+		return err
+	})
+	return email, err
+}
+
+func (r *retryingUserRepository) RestoreEmailDeliverability(ctx context.Context, userID uuid.UUID, email string, at time.Time) (bool, error) {
+	var ok bool
+	err := r.retry.write(ctx, "User.RestoreEmailDeliverability", func() (err error) {
+		ok, err = r.next.RestoreEmailDeliverability(ctx, userID, email, at)
+		return err
+	})
+	return ok, err
+}
+
+func (r *retryingUserRepository) UpdateUser(ctx context.Context, user *models.User) error {
+	return r.retry.write(ctx, "User.UpdateUser", func() error {
+		return r.next.UpdateUser(ctx, user)
+	})
+}
+
+func (r *retryingUserRepository) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	return r.retry.write(ctx, "User.DeleteUser", func() error {
+		return r.next.DeleteUser(ctx, id)
+	})
+}
+
+func (r *retryingUserRepository) ApplyUserChanges(ctx context.Context, changes []models.UserChange) error {
+	return r.retry.write(ctx, "User.ApplyUserChanges", func() error {
+		return r.next.ApplyUserChanges(ctx, changes)
+	})
+}
+
+func (r *retryingUserRepository) CreatePasswordResetToken(ctx context.Context, userID uuid.UUID, tokenHash string, expiresAt time.Time) error {
+	return r.retry.write(ctx, "User.CreatePasswordResetToken", func() error {
+		return r.next.CreatePasswordResetToken(ctx, userID, tokenHash, expiresAt)
+	})
+}
+
+func (r *retryingUserRepository) ConsumePasswordResetToken(ctx context.Context, tokenHash string) (uuid.UUID, error) {
+	var userID uuid.UUID
+	err := r.retry.write(ctx, "User.ConsumePasswordResetToken", func() (err error) {
+		userID, err = r.next.ConsumePasswordResetToken(ctx, tokenHash)
+		return err
+	})
+	return userID, err
+}
+
+func (r *retryingUserRepository) RecordTimezoneChange(ctx context.Context, userID uuid.UUID, timezone string, effectiveFrom time.Time) error {
+	return r.retry.write(ctx, "User.RecordTimezoneChange", func() error {
+		return r.next.RecordTimezoneChange(ctx, userID, timezone, effectiveFrom)
+	})
+}
+
+func (r *retryingUserRepository) GetTimezoneHistory(ctx context.Context, userID uuid.UUID) ([]models.TimezonePeriod, error) {
+	var periods []models.TimezonePeriod
+	err := r.retry.read(ctx, "User.GetTimezoneHistory", func() (err error) {
+		periods, err = r.next.GetTimezoneHistory(ctx, userID)
+		return err
+	})
+	return periods, err
+}
+
+func (r *retryingUserRepository) MergeUsers(ctx context.Context, merge *models.UserMerge) error {
+	return r.retry.write(ctx, "User.MergeUsers", func() error {
+		return r.next.MergeUsers(ctx, merge)
+	})
+}
+
+func (r *retryingUserRepository) GetUserMerge(ctx context.Context, id uuid.UUID) (*models.UserMerge, error) {
+	var merge *models.UserMerge
+	err := r.retry.read(ctx, "User.GetUserMerge", func() (err error) {
+		merge, err = r.next.GetUserMerge(ctx, id)
+		return err
+	})
+	return merge, err
+}
+
+func (r *retryingUserRepository) UndoUserMerge(ctx context.Context, merge *models.UserMerge, undoneBy string) error {
+	return r.retry.write(ctx, "User.UndoUserMerge", func() error {
+		return r.next.UndoUserMerge(ctx, merge, undoneBy)
+	})
+}
+
+func (r *retryingUserRepository) GetProfilePromptDismissals(ctx context.Context, userID uuid.UUID) (map[string]models.ProfilePromptDismissal, error) {
+	var dismissals map[string]models.ProfilePromptDismissal
+	err := r.retry.read(ctx, "User.GetProfilePromptDismissals", func() (err error) {
+		dismissals, err = r.next.GetProfilePromptDismissals(ctx, userID)
+		return err
+	})
+	return dismissals, err
+}
+
+func (r *retryingUserRepository) DismissProfilePrompt(ctx context.Context, userID uuid.UUID, field string, at time.Time) error {
+	return r.retry.write(ctx, "User.DismissProfilePrompt", func() error {
+		return r.next.DismissProfilePrompt(ctx, userID, field, at)
+	})
+}
+
+func (r *retryingUserRepository) ListAggregationPeriods(ctx context.Context, userID uuid.UUID) ([]models.AggregationPeriod, error) {
+	var periods []models.AggregationPeriod
+	err := r.retry.read(ctx, "User.ListAggregationPeriods", func() (err error) {
+		periods, err = r.next.ListAggregationPeriods(ctx, userID)
+		return err
+	})
+	return periods, err
+}
+
+func (r *retryingUserRepository) CreateAggregationPeriod(ctx context.Context, period *models.AggregationPeriod) error {
+	return r.retry.write(ctx, "User.CreateAggregationPeriod", func() error {
+		return r.next.CreateAggregationPeriod(ctx, period)
+	})
+}
+
+func (r *retryingUserRepository) UpdateAggregationPeriod(ctx context.Context, period *models.AggregationPeriod) (bool, error) {
+	var ok bool
+	err := r.retry.write(ctx, "User.UpdateAggregationPeriod", func() (err error) {
+		ok, err = r.next.UpdateAggregationPeriod(ctx, period)
+		return err
+	})
+	return ok, err
+}
+
+func (r *retryingUserRepository) DeleteAggregationPeriod(ctx context.Context, userID, id uuid.UUID) (bool, error) {
+	var ok bool
+	err := r.retry.write(ctx, "User.DeleteAggregationPeriod", func() (err error) {
+		ok, err = r.next.DeleteAggregationPeriod(ctx, userID, id)
+		return err
+	})
+	return ok, err
+}
+
+func (r *retryingUserRepository) GetUserMetadata(ctx context.Context, userID uuid.UUID) (models.UserMetadata, error) {
+	var metadata models.UserMetadata
+	err := r.retry.read(ctx, "User.GetUserMetadata", func() (err error) {
+		metadata, err = r.next.GetUserMetadata(ctx, userID)
+		return err
+	})
+	return metadata, err
+}
+
+func (r *retryingUserRepository) MergeUserMetadata(ctx context.Context, userID uuid.UUID, set models.UserMetadata, remove []string, maxBytes int) (models.UserMetadata, error) {
+	var metadata models.UserMetadata
+	err := r.retry.write(ctx, "User.MergeUserMetadata", func() (err error) {
+		metadata, err = r.next.MergeUserMetadata(ctx, userID, set, remove, maxBytes)
+		return err
+	})
+	return metadata, err
+}
+
+func (r *retryingUserRepository) GetOnboarding(ctx context.Context, userID uuid.UUID) (*models.OnboardingState, error) {
+	var state *models.OnboardingState
+	err := r.retry.read(ctx, "User.GetOnboarding", func() (err error) {
+		state, err = r.next.GetOnboarding(ctx, userID)
+		return err
+	})
+	return state, err
+}
+
+func (r *retryingUserRepository) AdvanceOnboarding(ctx context.Context, userID uuid.UUID, from, to string, skip bool, at time.Time) (bool, error) {
+	var ok bool
+	err := r.retry.write(ctx, "User.AdvanceOnboarding", func() (err error) {
+		ok, err = r.next.AdvanceOnboarding(ctx, userID, from, to, skip, at)
+		return err
+	})
+	return ok, err
+}
+
+func (r *retryingUserRepository) CountOnboardingSteps(ctx context.Context, since time.Time) ([]models.OnboardingStepCount, error) {
+	var counts []models.OnboardingStepCount
+	err := r.retry.read(ctx, "User.CountOnboardingSteps", func() (err error) {
+		counts, err = r.next.CountOnboardingSteps(ctx, since)
+		return err
+	})
+	return counts, err
+}
+
+func (r *retryingUserRepository) StorageUsage(ctx context.Context) (map[uuid.UUID]int64, error) {
+	var usage map[uuid.UUID]int64
+	err := r.retry.read(ctx, "User.StorageUsage", func() (err error) {
+		usage, err = r.next.StorageUsage(ctx)
+		return err
+	})
+	return usage, err
+}
+
+func (r *retryingUserRepository) SummarizeUserData(ctx context.Context, userID uuid.UUID) ([]models.DataCategory, error) {
+	var categories []models.DataCategory
+	err := r.retry.read(ctx, "User.SummarizeUserData", func() (err error) {
+		categories, err = r.next.SummarizeUserData(ctx, userID)
+		return err
+	})
+	return categories, err
+}
+
+func (r *retryingUserRepository) CountAnnouncementRecipients(ctx context.Context, segment models.AnnouncementSegment) (*models.AnnouncementAudience, error) {
+	var audience *models.AnnouncementAudience
+	err := r.retry.read(ctx, "User.CountAnnouncementRecipients", func() (err error) {
+		audience, err = r.next.CountAnnouncementRecipients(ctx, segment)
+		return err
+	})
+	return audience, err
+}
+
+func (r *retryingUserRepository) ListAnnouncementRecipients(ctx context.Context, segment models.AnnouncementSegment, after uuid.UUID, limit int) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := r.retry.read(ctx, "User.ListAnnouncementRecipients", func() (err error) {
+		ids, err = r.next.ListAnnouncementRecipients(ctx, segment, after, limit)
+		return err
+	})
+	return ids, err
+}
+
+func (r *retryingUserRepository) ListDueDeletions(ctx context.Context, now time.Time, limit int) ([]models.User, error) {
+	var users []models.User
+	err := r.retry.read(ctx, "User.ListDueDeletions", func() (err error) {
+		users, err = r.next.ListDueDeletions(ctx, now, limit)
+		return err
+	})
+	return users, err
+}
+
+func (r *retryingUserRepository) EraseUser(ctx context.Context, id uuid.UUID) (blobKeys []string, err error) {
+	err = r.retry.write(ctx, "User.EraseUser", func() (err error) {
+		blobKeys, err = r.next.EraseUser(ctx, id)
+		return err
+	})
+	return blobKeys, err
+}
+
+func (r *retryingUserRepository) ResealHealthFields(ctx context.Context, limit int) (int, error) {
+	var resealed int
+	err := r.retry.write(ctx, "User.ResealHealthFields", func() (err error) {
+		resealed, err = r.next.ResealHealthFields(ctx, limit)
+		return err
+	})
+	return resealed, err
+}
+
+func (r *retryingUserRepository) Migrate() error {
+	return r.next.Migrate()
+}
+
+// retryingSystemEventRepository retries SystemEventRepository calls that fail for a transient reason.
+type retryingSystemEventRepository struct {
+	next  SystemEventRepository
+	retry retrier
+}
+
+// NewRetryingSystemEventRepository wraps next in a SystemEventRepository that retries under policy.
+func NewRetryingSystemEventRepository(next SystemEventRepository, policy RetryPolicy) SystemEventRepository {
+	return &retryingSystemEventRepository{next: next, retry: newRetrier(policy)}
+}
+
+func (r *retryingSystemEventRepository) CreateEvent(ctx context.Context, event *models.SystemEvent) error {
+	return r.retry.write(ctx, "SystemEvent.CreateEvent", func() error {
+		return r.next.CreateEvent(ctx, event)
+	})
+}
+
+func (r *retryingSystemEventRepository) ListEvents(ctx context.Context, filter models.SystemEventFilter) ([]models.SystemEvent, error) {
+	var events []models.SystemEvent
+	err := r.retry.read(ctx, "SystemEvent.ListEvents", func() (err error) {
+		events, err = r.next.ListEvents(ctx, filter)
+		return err
+	})
+	return events, err
+}
+
+func (r *retryingSystemEventRepository) Migrate() error {
+	return r.next.Migrate()
+}
+
+// retryingAnnouncementRepository retries AnnouncementRepository calls that fail for a transient reason.
+type retryingAnnouncementRepository struct {
+	next  AnnouncementRepository
+	retry retrier
+}
+
+// NewRetryingAnnouncementRepository wraps next in a AnnouncementRepository that retries under policy.
+func NewRetryingAnnouncementRepository(next AnnouncementRepository, policy RetryPolicy) AnnouncementRepository {
+	return &retryingAnnouncementRepository{next: next, retry: newRetrier(policy)}
+}
+
+func (r *retryingAnnouncementRepository) CreateAnnouncement(ctx context.Context, a *models.Announcement) error {
+	return r.retry.write(ctx, "Announcement.CreateAnnouncement", func() error {
+		return r.next.CreateAnnouncement(ctx, a)
+	})
+}
+
+func (r *retryingAnnouncementRepository) GetAnnouncement(ctx context.Context, id uuid.UUID) (*models.Announcement, error) {
+	var announcement *models.Announcement
+	err := r.retry.read(ctx, "Announcement.GetAnnouncement", func() (err error) {
+		announcement, err = r.next.GetAnnouncement(ctx, id)
+		return err
+	})
+	return announcement, err
+}
+
+func (r *retryingAnnouncementRepository) ListAnnouncements(ctx context.Context, filter models.AnnouncementFilter) ([]models.Announcement, error) {
+	var announcements []models.Announcement
+	err := r.retry.read(ctx, "Announcement.ListAnnouncements", func() (err error) {
+		announcements, err = r.next.ListAnnouncements(ctx, filter)
+		return err
+	})
+	return announcements, err
+}
+
+func (r *retryingAnnouncementRepository) ClaimDueAnnouncement(ctx context.Context, now, leaseUntil time.Time) (*models.Announcement, error) {
+	var announcement *models.Announcement
+	err := r.retry.write(ctx, "Announcement.ClaimDueAnnouncement", func() (err error) {
+		announcement, err = r.next.ClaimDueAnnouncement(ctx, now, leaseUntil)
+		return err
+	})
+	return announcement, err
+}
+
+func (r *retryingAnnouncementRepository) SetAnnouncementRecipients(ctx context.Context, id uuid.UUID, recipients int) error {
+	return r.retry.write(ctx, "Announcement.SetAnnouncementRecipients", func() error {
+		return r.next.SetAnnouncementRecipients(ctx, id, recipients)
+	})
+}
+
+func (r *retryingAnnouncementRepository) RecordAnnouncementProgress(ctx context.Context, id, cursor uuid.UUID, sent, failed int, leaseUntil time.Time) (bool, error) {
+	var ok bool
+	err := r.retry.write(ctx, "Announcement.RecordAnnouncementProgress", func() (err error) {
+		ok, err = r.next.RecordAnnouncementProgress(ctx, id, cursor, sent, failed, leaseUntil)
+		return err
+	})
+	return ok, err
+}
+
+func (r *retryingAnnouncementRepository) FinishAnnouncement(ctx context.Context, id uuid.UUID, at time.Time) error {
+	return r.retry.write(ctx, "Announcement.FinishAnnouncement", func() error {
+		return r.next.FinishAnnouncement(ctx, id, at)
+	})
+}
+
+func (r *retryingAnnouncementRepository) CancelAnnouncement(ctx context.Context, id uuid.UUID, at time.Time) (bool, error) {
+	var ok bool
+	err := r.retry.write(ctx, "Announcement.CancelAnnouncement", func() (err error) {
+		ok, err = r.next.CancelAnnouncement(ctx, id, at)
+		return err
+	})
+	return ok, err
+}
+
+func (r *retryingAnnouncementRepository) Migrate() error {
+	return r.next.Migrate()
+}
+
+// retryingUserEventRepository retries UserEventRepository calls that fail for a transient reason.
+type retryingUserEventRepository struct {
+	next  UserEventRepository
+	retry retrier
+}
+
+// NewRetryingUserEventRepository wraps next in a UserEventRepository that retries under policy.
+func NewRetryingUserEventRepository(next UserEventRepository, policy RetryPolicy) UserEventRepository {
+	return &retryingUserEventRepository{next: next, retry: newRetrier(policy)}
+}
+
+func (r *retryingUserEventRepository) CreateEvent(ctx context.Context, event *models.UserEvent) error {
+	return r.retry.write(ctx, "UserEvent.CreateEvent", func() error {
+		return r.next.CreateEvent(ctx, event)
+	})
+}
+
+func (r *retryingUserEventRepository) ListEvents(ctx context.Context, filter models.UserEventFilter) ([]models.UserEvent, error) {
+	var events []models.UserEvent
+	err := r.retry.read(ctx, "UserEvent.ListEvents", func() (err error) {
+		events, err = r.next.ListEvents(ctx, filter)
+		return err
+	})
+	return events, err
+}
+
+func (r *retryingUserEventRepository) Migrate() error {
+	return r.next.Migrate()
+}
+
+// retryingAuditRepository retries AuditRepository calls that fail for a transient reason.
+type retryingAuditRepository struct {
+	next  AuditRepository
+	retry retrier
+}
+
+// NewRetryingAuditRepository wraps next in a AuditRepository that retries under policy.
+func NewRetryingAuditRepository(next AuditRepository, policy RetryPolicy) AuditRepository {
+	return &retryingAuditRepository{next: next, retry: newRetrier(policy)}
+}
+
+func (r *retryingAuditRepository) CreateEvent(ctx context.Context, event *models.AuditEvent) error {
+	return r.retry.write(ctx, "Audit.CreateEvent", func() error {
+		return r.next.CreateEvent(ctx, event)
+	})
+}
+
+func (r *retryingAuditRepository) ListEvents(ctx context.Context, filter models.AuditEventFilter) ([]models.AuditEvent, error) {
+	var events []models.AuditEvent
+	err := r.retry.read(ctx, "Audit.ListEvents", func() (err error) {
+		events, err = r.next.ListEvents(ctx, filter)
+		return err
+	})
+	return events, err
+}
+
+func (r *retryingAuditRepository) AnonymizeSubject(ctx context.Context, userID, email, pseudonym string) (int64, error) {
+	var n int64
+	err := r.retry.write(ctx, "Audit.AnonymizeSubject", func() (err error) {
+		n, err = r.next.AnonymizeSubject(ctx, userID, email, pseudonym)
+		return err
+	})
+	return n, err
+}
+
+func (r *retryingAuditRepository) Migrate() error {
+	return r.next.Migrate()
+}
+
+// retryingDashboardRepository retries DashboardRepository calls that fail for a transient reason.
+type retryingDashboardRepository struct {
+	next  DashboardRepository
+	retry retrier
+}
+
+// NewRetryingDashboardRepository wraps next in a DashboardRepository that retries under policy.
+func NewRetryingDashboardRepository(next DashboardRepository, policy RetryPolicy) DashboardRepository {
+	return &retryingDashboardRepository{next: next, retry: newRetrier(policy)}
+}
+
+func (r *retryingDashboardRepository) GetLayout(ctx context.Context, userID uuid.UUID) (*models.DashboardLayout, error) {
+	var layout *models.DashboardLayout
+	err := r.retry.read(ctx, "Dashboard.GetLayout", func() (err error) {
+		layout, err = r.next.GetLayout(ctx, userID)
+		return err
+	})
+	return layout, err
+}
+
+func (r *retryingDashboardRepository) SaveLayout(ctx context.Context, userID uuid.UUID, layout *models.DashboardLayout) error {
+	return r.retry.write(ctx, "Dashboard.SaveLayout", func() error {
+		return r.next.SaveLayout(ctx, userID, layout)
+	})
+}
+
+func (r *retryingDashboardRepository) DeleteLayout(ctx context.Context, userID uuid.UUID) error {
+	return r.retry.write(ctx, "Dashboard.DeleteLayout", func() error {
+		return r.next.DeleteLayout(ctx, userID)
+	})
+}
+
+func (r *retryingDashboardRepository) Migrate() error {
+	return r.next.Migrate()
+}
+
+// retryingSettingsRepository retries SettingsRepository calls that fail for a transient reason.
+type retryingSettingsRepository struct {
+	next  SettingsRepository
+	retry retrier
+}
+
+// NewRetryingSettingsRepository wraps next in a SettingsRepository that retries under policy.
+func NewRetryingSettingsRepository(next SettingsRepository, policy RetryPolicy) SettingsRepository {
+	return &retryingSettingsRepository{next: next, retry: newRetrier(policy)}
+}
+
+func (r *retryingSettingsRepository) GetSettings(ctx context.Context, userID uuid.UUID) (*models.UserSettings, error) {
+	var settings *models.UserSettings
+	err := r.retry.read(ctx, "Settings.GetSettings", func() (err error) {
+		settings, err = r.next.GetSettings(ctx, userID)
+		return err
+	})
+	return settings, err
+}
+
+func (r *retryingSettingsRepository) SaveSettings(ctx context.Context, userID uuid.UUID, settings *models.UserSettings) error {
+	return r.retry.write(ctx, "Settings.SaveSettings", func() error {
+		return r.next.SaveSettings(ctx, userID, settings)
+	})
+}
+
+func (r *retryingSettingsRepository) Migrate() error {
+	return r.next.Migrate()
+}
+
+// retryingLoginAttemptRepository retries LoginAttemptRepository calls that fail for a transient reason.
+type retryingLoginAttemptRepository struct {
+	next  LoginAttemptRepository
+	retry retrier
+}
+
+// NewRetryingLoginAttemptRepository wraps next in a LoginAttemptRepository that retries under policy.
+func NewRetryingLoginAttemptRepository(next LoginAttemptRepository, policy RetryPolicy) LoginAttemptRepository {
+	return &retryingLoginAttemptRepository{next: next, retry: newRetrier(policy)}
+}
+
+func (r *retryingLoginAttemptRepository) CreateAttempt(ctx context.Context, attempt *models.LoginAttempt) error {
+	return r.retry.write(ctx, "LoginAttempt.CreateAttempt", func() error {
+		return r.next.CreateAttempt(ctx, attempt)
+	})
+}
+
+func (r *retryingLoginAttemptRepository) ListAttempts(ctx context.Context, filter models.LoginAttemptFilter) ([]models.LoginAttempt, error) {
+	var attempts []models.LoginAttempt
+	err := r.retry.read(ctx, "LoginAttempt.ListAttempts", func() (err error) {
+		attempts, err = r.next.ListAttempts(ctx, filter)
+		return err
+	})
+	return attempts, err
+}
+
+func (r *retryingLoginAttemptRepository) Migrate() error {
+	return r.next.Migrate()
+}
+
+// retryingIdentityRepository retries IdentityRepository calls that fail for a transient reason.
+type retryingIdentityRepository struct {
+	next  IdentityRepository
+	retry retrier
+}
+
+// NewRetryingIdentityRepository wraps next in a IdentityRepository that retries under policy.
+func NewRetryingIdentityRepository(next IdentityRepository, policy RetryPolicy) IdentityRepository {
+	return &retryingIdentityRepository{next: next, retry: newRetrier(policy)}
+}
+
+func (r *retryingIdentityRepository) GetIdentity(ctx context.Context, issuer, subject string) (*models.UserIdentity, error) {
+	var identity *models.UserIdentity
+	err := r.retry.read(ctx, "Identity.GetIdentity", func() (err error) {
+		identity, err = r.next.GetIdentity(ctx, issuer, subject)
+		return err
+	})
+	return identity, err
+}
+
+func (r *retryingIdentityRepository) ListIdentities(ctx context.Context, userID uuid.UUID) ([]models.UserIdentity, error) {
+	var identities []models.UserIdentity
+	err := r.retry.read(ctx, "Identity.ListIdentities", func() (err error) {
+		identities, err = r.next.ListIdentities(ctx, userID)
+		return err
+	})
+	return identities, err
+}
+
+func (r *retryingIdentityRepository) CreateIdentity(ctx context.Context, identity *models.UserIdentity) error {
+	return r.retry.write(ctx, "Identity.CreateIdentity", func() error {
+		return r.next.CreateIdentity(ctx, identity)
+	})
+}
+
+func (r *retryingIdentityRepository) CreateLinkRequest(ctx context.Context, req *models.IdentityLinkRequest) error {
+	return r.retry.write(ctx, "Identity.CreateLinkRequest", func() error {
+		return r.next.CreateLinkRequest(ctx, req)
+	})
+}
+
+func (r *retryingIdentityRepository) GetLinkRequest(ctx context.Context, tokenHash string) (*models.IdentityLinkRequest, error) {
+	var req *models.IdentityLinkRequest
+	err := r.retry.read(ctx, "Identity.GetLinkRequest", func() (err error) {
+		req, err = r.next.GetLinkRequest(ctx, tokenHash)
+		return err
+	})
+	return req, err
+}
+
+func (r *retryingIdentityRepository) IncrementLinkAttempts(ctx context.Context, id uuid.UUID) error {
+	return r.retry.write(ctx, "Identity.IncrementLinkAttempts", func() error {
+		return r.next.IncrementLinkAttempts(ctx, id)
+	})
+}
+
+func (r *retryingIdentityRepository) DeleteLinkRequest(ctx context.Context, id uuid.UUID) (bool, error) {
+	var ok bool
+	err := r.retry.write(ctx, "Identity.DeleteLinkRequest", func() (err error) {
+		ok, err = r.next.DeleteLinkRequest(ctx, id)
+		return err
+	})
+	return ok, err
+}
+
+func (r *retryingIdentityRepository) CreateSAMLRequest(ctx context.Context, id string, expiresAt time.Time) error {
+	return r.retry.write(ctx, "Identity.CreateSAMLRequest", func() error {
+		return r.next.CreateSAMLRequest(ctx, id, expiresAt)
+	})
+}
+
+func (r *retryingIdentityRepository) ConsumeSAMLRequest(ctx context.Context, id string) (bool, error) {
+	var consumed bool
+	err := r.retry.write(ctx, "Identity.ConsumeSAMLRequest", func() (err error) {
+		consumed, err = r.next.ConsumeSAMLRequest(ctx, id)
+		return err
+	})
+	return consumed, err
+}
+
+func (r *retryingIdentityRepository) Migrate() error {
+	return r.next.Migrate()
+}
+
+// retryingSessionRepository retries SessionRepository calls that fail for a transient reason.
+type retryingSessionRepository struct {
+	next  SessionRepository
+	retry retrier
+}
+
+// NewRetryingSessionRepository wraps next in a SessionRepository that retries under policy.
+func NewRetryingSessionRepository(next SessionRepository, policy RetryPolicy) SessionRepository {
+	return &retryingSessionRepository{next: next, retry: newRetrier(policy)}
+}
+
+func (r *retryingSessionRepository) CreateSession(ctx context.Context, session *models.Session, maxPerUser int) ([]uuid.UUID, error) {
+	var evicted []uuid.UUID
+	err := r.retry.write(ctx, "Session.CreateSession", func() (err error) {
+		evicted, err = r.next.CreateSession(ctx, session, maxPerUser)
+		return err
+	})
+	return evicted, err
+}
+
+func (r *retryingSessionRepository) GetSession(ctx context.Context, userID, id uuid.UUID) (*models.Session, error) {
+	var session *models.Session
+	err := r.retry.read(ctx, "Session.GetSession", func() (err error) {
+		session, err = r.next.GetSession(ctx, userID, id)
+		return err
+	})
+	return session, err
+}
+
+func (r *retryingSessionRepository) DeleteSession(ctx context.Context, userID, id uuid.UUID) error {
+	return r.retry.write(ctx, "Session.DeleteSession", func() error {
+		return r.next.DeleteSession(ctx, userID, id)
+	})
+}
+
+func (r *retryingSessionRepository) DeleteUserSessions(ctx context.Context, userID uuid.UUID) error {
+	return r.retry.write(ctx, "Session.DeleteUserSessions", func() error {
+		return r.next.DeleteUserSessions(ctx, userID)
+	})
+}
+
+func (r *retryingSessionRepository) DeleteExpiredSessions(ctx context.Context, before time.Time) (int64, error) {
+	var n int64
+	err := r.retry.write(ctx, "Session.DeleteExpiredSessions", func() (err error) {
+		n, err = r.next.DeleteExpiredSessions(ctx, before)
+		return err
+	})
+	return n, err
+}
+
+func (r *retryingSessionRepository) CountActiveSessions(ctx context.Context) (sessions int64, users int64, err error) {
+	err = r.retry.read(ctx, "Session.CountActiveSessions", func() (err error) {
+		sessions, users, err = r.next.CountActiveSessions(ctx)
+		return err
+	})
+	return sessions, users, err
+}
+
+func (r *retryingSessionRepository) Migrate() error {
+	return r.next.Migrate()
+}
+
+// retryingDeveloperAppRepository retries DeveloperAppRepository calls that fail for a transient reason.
+type retryingDeveloperAppRepository struct {
+	next  DeveloperAppRepository
+	retry retrier
+}
+
+// NewRetryingDeveloperAppRepository wraps next in a DeveloperAppRepository that retries under policy.
+func NewRetryingDeveloperAppRepository(next DeveloperAppRepository, policy RetryPolicy) DeveloperAppRepository {
+	return &retryingDeveloperAppRepository{next: next, retry: newRetrier(policy)}
+}
+
+func (r *retryingDeveloperAppRepository) CreateApp(ctx context.Context, app *models.DeveloperApp) error {
+	return r.retry.write(ctx, "DeveloperApp.CreateApp", func() error {
+		return r.next.CreateApp(ctx, app)
+	})
+}
+
+func (r *retryingDeveloperAppRepository) GetApp(ctx context.Context, id uuid.UUID) (*models.DeveloperApp, error) {
+	var app *models.DeveloperApp
+	err := r.retry.read(ctx, "DeveloperApp.GetApp", func() (err error) {
+		app, err = r.next.GetApp(ctx, id)
+		return err
+	})
+	return app, err
+}
+
+func (r *retryingDeveloperAppRepository) GetAppByKeyHash(ctx context.Context, keyHash string) (*models.DeveloperApp, error) {
+	var app *models.DeveloperApp
+	err := r.retry.read(ctx, "DeveloperApp.GetAppByKeyHash", func() (err error) {
+		app, err = r.next.GetAppByKeyHash(ctx, keyHash)
+		return err
+	})
+	return app, err
+}
+
+func (r *retryingDeveloperAppRepository) ListApps(ctx context.Context, ownerID uuid.UUID) ([]models.DeveloperApp, error) {
+	var apps []models.DeveloperApp
+	err := r.retry.read(ctx, "DeveloperApp.ListApps", func() (err error) {
+		apps, err = r.next.ListApps(ctx, ownerID)
+		return err
+	})
+	return apps, err
+}
+
+func (r *retryingDeveloperAppRepository) UpdateApp(ctx context.Context, app *models.DeveloperApp) error {
+	return r.retry.write(ctx, "DeveloperApp.UpdateApp", func() error {
+		return r.next.UpdateApp(ctx, app)
+	})
+}
+
+func (r *retryingDeveloperAppRepository) DeleteOwnerApps(ctx context.Context, ownerID uuid.UUID) (int64, error) {
+	var n int64
+	err := r.retry.write(ctx, "DeveloperApp.DeleteOwnerApps", func() (err error) {
+		n, err = r.next.DeleteOwnerApps(ctx, ownerID)
+		return err
+	})
+	return n, err
+}
+
+func (r *retryingDeveloperAppRepository) AddUsage(ctx context.Context, appID uuid.UUID, usage models.DeveloperAppUsage) error {
+	return r.retry.write(ctx, "DeveloperApp.AddUsage", func() error {
+		return r.next.AddUsage(ctx, appID, usage)
+	})
+}
+
+func (r *retryingDeveloperAppRepository) CountRequests(ctx context.Context, appID uuid.UUID, day time.Time) (int64, error) {
+	var n int64
+	err := r.retry.read(ctx, "DeveloperApp.CountRequests", func() (err error) {
+		n, err = r.next.CountRequests(ctx, appID, day)
+		return err
+	})
+	return n, err
+}
+
+func (r *retryingDeveloperAppRepository) ListUsage(ctx context.Context, appID uuid.UUID, since, until time.Time) ([]models.DeveloperAppUsage, error) {
+	var usage []models.DeveloperAppUsage
+	err := r.retry.read(ctx, "DeveloperApp.ListUsage", func() (err error) {
+		usage, err = r.next.ListUsage(ctx, appID, since, until)
+		return err
+	})
+	return usage, err
+}
+
+func (r *retryingDeveloperAppRepository) AddRecording(ctx context.Context, appID uuid.UUID, entry models.HAREntry, keep int) error {
+	return r.retry.write(ctx, "DeveloperApp.AddRecording", func() error {
+		return r.next.AddRecording(ctx, appID, entry, keep)
+	})
+}
+
+func (r *retryingDeveloperAppRepository) ListRecordings(ctx context.Context, appID uuid.UUID) ([]models.HAREntry, error) {
+	var entries []models.HAREntry
+	err := r.retry.read(ctx, "DeveloperApp.ListRecordings", func() (err error) {
+		entries, err = r.next.ListRecordings(ctx, appID)
+		return err
+	})
+	return entries, err
+}
+
+func (r *retryingDeveloperAppRepository) DeleteRecordings(ctx context.Context, appID uuid.UUID) error {
+	return r.retry.write(ctx, "DeveloperApp.DeleteRecordings", func() error {
+		return r.next.DeleteRecordings(ctx, appID)
+	})
+}
+
+func (r *retryingDeveloperAppRepository) Migrate() error {
+	return r.next.Migrate()
+}
+
+// retryingMeteringRepository retries MeteringRepository calls that fail for a transient reason.
+type retryingMeteringRepository struct {
+	next  MeteringRepository
+	retry retrier
+}
+
+// NewRetryingMeteringRepository wraps next in a MeteringRepository that retries under policy.
+func NewRetryingMeteringRepository(next MeteringRepository, policy RetryPolicy) MeteringRepository {
+	return &retryingMeteringRepository{next: next, retry: newRetrier(policy)}
+}
+
+func (r *retryingMeteringRepository) RecordEvent(ctx context.Context, event *models.MeteringEvent) (bool, error) {
+	var ok bool
+	err := r.retry.write(ctx, "Metering.RecordEvent", func() (err error) {
+		ok, err = r.next.RecordEvent(ctx, event)
+		return err
+	})
+	return ok, err
+}
+
+func (r *retryingMeteringRepository) Summarize(ctx context.Context, filter models.MeteringFilter) ([]models.MeterTotal, []models.UserMeterTotal, error) {
+	var totals []models.MeterTotal
+	var userTotals []models.UserMeterTotal
+	err := r.retry.read(ctx, "Metering.Summarize", func() (err error) {
+		totals, userTotals, err = r.next.Summarize(ctx, filter)
+		return err
+	})
+	return totals, userTotals, err
+}
+
+func (r *retryingMeteringRepository) Migrate() error {
+	return r.next.Migrate()
+}
+
+// retryingConsentRepository retries ConsentRepository calls that fail for a transient reason.
+type retryingConsentRepository struct {
+	next  ConsentRepository
+	retry retrier
+}
+
+// NewRetryingConsentRepository wraps next in a ConsentRepository that retries under policy.
+func NewRetryingConsentRepository(next ConsentRepository, policy RetryPolicy) ConsentRepository {
+	return &retryingConsentRepository{next: next, retry: newRetrier(policy)}
+}
+
+func (r *retryingConsentRepository) GrantConsent(ctx context.Context, consent *models.IntegrationConsent) error {
+	return r.retry.write(ctx, "Consent.GrantConsent", func() error {
+		return r.next.GrantConsent(ctx, consent)
+	})
+}
+
+func (r *retryingConsentRepository) GetActiveConsent(ctx context.Context, userID uuid.UUID, provider string) (*models.IntegrationConsent, error) {
+	var consent *models.IntegrationConsent
+	err := r.retry.read(ctx, "Consent.GetActiveConsent", func() (err error) {
+		consent, err = r.next.GetActiveConsent(ctx, userID, provider)
+		return err
+	})
+	return consent, err
+}
+
+func (r *retryingConsentRepository) ListConsents(ctx context.Context, userID uuid.UUID) ([]models.IntegrationConsent, error) {
+	var consents []models.IntegrationConsent
+	err := r.retry.read(ctx, "Consent.ListConsents", func() (err error) {
+		consents, err = r.next.ListConsents(ctx, userID)
+		return err
+	})
+	return consents, err
+}
+
+func (r *retryingConsentRepository) RevokeConsent(ctx context.Context, userID uuid.UUID, provider string, deleteData bool, at time.Time) (*models.IntegrationConsent, error) {
+	var consent *models.IntegrationConsent
+	err := r.retry.write(ctx, "Consent.RevokeConsent", func() (err error) {
+		consent, err = r.next.RevokeConsent(ctx, userID, provider, deleteData, at)
+		return err
+	})
+	return consent, err
+}
+
+func (r *retryingConsentRepository) ListRevocations(ctx context.Context, filter models.ConsentRevocationFilter) ([]models.IntegrationConsent, error) {
+	var consents []models.IntegrationConsent
+	err := r.retry.read(ctx, "Consent.ListRevocations", func() (err error) {
+		consents, err = r.next.ListRevocations(ctx, filter)
+		return err
+	})
+	return consents, err
+}
+
+func (r *retryingConsentRepository) Migrate() error {
+	return r.next.Migrate()
+}
+
+// retryingMessagingRepository retries MessagingRepository calls that fail for a transient reason.
+type retryingMessagingRepository struct {
+	next  MessagingRepository
+	retry retrier
+}
+
+// NewRetryingMessagingRepository wraps next in a MessagingRepository that retries under policy.
+func NewRetryingMessagingRepository(next MessagingRepository, policy RetryPolicy) MessagingRepository {
+	return &retryingMessagingRepository{next: next, retry: newRetrier(policy)}
+}
+
+func (r *retryingMessagingRepository) AuthorizeCoach(ctx context.Context, auth *models.CoachAuthorization) error {
+	return r.retry.write(ctx, "Messaging.AuthorizeCoach", func() error {
+		return r.next.AuthorizeCoach(ctx, auth)
+	})
+}
+
+func (r *retryingMessagingRepository) RevokeCoach(ctx context.Context, userID, coachID uuid.UUID, at time.Time) (bool, error) {
+	var ok bool
+	err := r.retry.write(ctx, "Messaging.RevokeCoach", func() (err error) {
+		ok, err = r.next.RevokeCoach(ctx, userID, coachID, at)
+		return err
+	})
+	return ok, err
+}
+
+func (r *retryingMessagingRepository) IsCoachAuthorized(ctx context.Context, userID, coachID uuid.UUID) (bool, error) {
+	var ok bool
+	err := r.retry.read(ctx, "Messaging.IsCoachAuthorized", func() (err error) {
+		ok, err = r.next.IsCoachAuthorized(ctx, userID, coachID)
+		return err
+	})
+	return ok, err
+}
+
+func (r *retryingMessagingRepository) ListCoaches(ctx context.Context, userID uuid.UUID) ([]models.CoachAuthorization, error) {
+	var authorizations []models.CoachAuthorization
+	err := r.retry.read(ctx, "Messaging.ListCoaches", func() (err error) {
+		authorizations, err = r.next.ListCoaches(ctx, userID)
+		return err
+	})
+	return authorizations, err
+}
+
+func (r *retryingMessagingRepository) ListClients(ctx context.Context, coachID uuid.UUID) ([]models.CoachAuthorization, error) {
+	var authorizations []models.CoachAuthorization
+	err := r.retry.read(ctx, "Messaging.ListClients", func() (err error) {
+		authorizations, err = r.next.ListClients(ctx, coachID)
+		return err
+	})
+	return authorizations, err
+}
+
+func (r *retryingMessagingRepository) CreateThread(ctx context.Context, thread *models.MessageThread) error {
+	return r.retry.write(ctx, "Messaging.CreateThread", func() error {
+		return r.next.CreateThread(ctx, thread)
+	})
+}
+
+func (r *retryingMessagingRepository) GetThread(ctx context.Context, id uuid.UUID) (*models.MessageThread, error) {
+	var thread *models.MessageThread
+	err := r.retry.read(ctx, "Messaging.GetThread", func() (err error) {
+		thread, err = r.next.GetThread(ctx, id)
+		return err
+	})
+	return thread, err
+}
+
+func (r *retryingMessagingRepository) ListThreads(ctx context.Context, participantID uuid.UUID) ([]models.MessageThread, error) {
+	var threads []models.MessageThread
+	err := r.retry.read(ctx, "Messaging.ListThreads", func() (err error) {
+		threads, err = r.next.ListThreads(ctx, participantID)
+		return err
+	})
+	return threads, err
+}
+
+func (r *retryingMessagingRepository) CreateMessage(ctx context.Context, userID uuid.UUID, msg *models.Message, attachmentIDs []uuid.UUID) error {
+	return r.retry.write(ctx, "Messaging.CreateMessage", func() error {
+		return r.next.CreateMessage(ctx, userID, msg, attachmentIDs)
+	})
+}
+
+func (r *retryingMessagingRepository) ListMessages(ctx context.Context, userID uuid.UUID, filter models.MessageFilter) ([]models.Message, error) {
+	var messages []models.Message
+	err := r.retry.read(ctx, "Messaging.ListMessages", func() (err error) {
+		messages, err = r.next.ListMessages(ctx, userID, filter)
+		return err
+	})
+	return messages, err
+}
+
+func (r *retryingMessagingRepository) MarkRead(ctx context.Context, userID, threadID, readerID uuid.UUID, at time.Time) (int64, error) {
+	var n int64
+	err := r.retry.write(ctx, "Messaging.MarkRead", func() (err error) {
+		n, err = r.next.MarkRead(ctx, userID, threadID, readerID, at)
+		return err
+	})
+	return n, err
+}
+
+func (r *retryingMessagingRepository) CreateAttachment(ctx context.Context, userID uuid.UUID, attachment *models.MessageAttachment) error {
+	return r.retry.write(ctx, "Messaging.CreateAttachment", func() error {
+		return r.next.CreateAttachment(ctx, userID, attachment)
+	})
+}
+
+func (r *retryingMessagingRepository) GetAttachment(ctx context.Context, userID, threadID, id uuid.UUID) (*models.MessageAttachment, error) {
+	var attachment *models.MessageAttachment
+	err := r.retry.read(ctx, "Messaging.GetAttachment", func() (err error) {
+		attachment, err = r.next.GetAttachment(ctx, userID, threadID, id)
+		return err
+	})
+	return attachment, err
+}
+
+func (r *retryingMessagingRepository) PurgeMessages(ctx context.Context, before, unsentBefore time.Time) (blobKeys []string, messages int64, err error) {
+	err = r.retry.write(ctx, "Messaging.PurgeMessages", func() (err error) {
+		blobKeys, messages, err = r.next.PurgeMessages(ctx, before, unsentBefore)
+		return err
+	})
+	return blobKeys, messages, err
+}
+
+func (r *retryingMessagingRepository) Migrate() error {
+	return r.next.Migrate()
+}
+
+// retryingAppointmentRepository retries AppointmentRepository calls that fail for a transient reason.
+type retryingAppointmentRepository struct {
+	next  AppointmentRepository
+	retry retrier
+}
+
+// NewRetryingAppointmentRepository wraps next in a AppointmentRepository that retries under policy.
+func NewRetryingAppointmentRepository(next AppointmentRepository, policy RetryPolicy) AppointmentRepository {
+	return &retryingAppointmentRepository{next: next, retry: newRetrier(policy)}
+}
+
+func (r *retryingAppointmentRepository) CreateSlots(ctx context.Context, providerID uuid.UUID, slots []models.AppointmentSlot) error {
+	return r.retry.write(ctx, "Appointment.CreateSlots", func() error {
+		return r.next.CreateSlots(ctx, providerID, slots)
+	})
+}
+
+func (r *retryingAppointmentRepository) ListSlots(ctx context.Context, filter models.SlotFilter) ([]models.AppointmentSlot, error) {
+	var slots []models.AppointmentSlot
+	err := r.retry.read(ctx, "Appointment.ListSlots", func() (err error) {
+		slots, err = r.next.ListSlots(ctx, filter)
+		return err
+	})
+	return slots, err
+}
+
+func (r *retryingAppointmentRepository) GetSlot(ctx context.Context, id uuid.UUID) (*models.AppointmentSlot, error) {
+	var slot *models.AppointmentSlot
+	err := r.retry.read(ctx, "Appointment.GetSlot", func() (err error) {
+		slot, err = r.next.GetSlot(ctx, id)
+		return err
+	})
+	return slot, err
+}
+
+func (r *retryingAppointmentRepository) DeleteSlot(ctx context.Context, providerID, id uuid.UUID) (bool, error) {
+	var ok bool
+	err := r.retry.write(ctx, "Appointment.DeleteSlot", func() (err error) {
+		ok, err = r.next.DeleteSlot(ctx, providerID, id)
+		return err
+	})
+	return ok, err
+}
+
+func (r *retryingAppointmentRepository) BookSlot(ctx context.Context, appointment *models.Appointment) error {
+	return r.retry.write(ctx, "Appointment.BookSlot", func() error {
+		return r.next.BookSlot(ctx, appointment)
+	})
+}
+
+func (r *retryingAppointmentRepository) GetAppointment(ctx context.Context, id uuid.UUID) (*models.Appointment, error) {
+	var appointment *models.Appointment
+	err := r.retry.read(ctx, "Appointment.GetAppointment", func() (err error) {
+		appointment, err = r.next.GetAppointment(ctx, id)
+		return err
+	})
+	return appointment, err
+}
+
+func (r *retryingAppointmentRepository) ListAppointments(ctx context.Context, filter models.AppointmentFilter) ([]models.Appointment, error) {
+	var appointments []models.Appointment
+	err := r.retry.read(ctx, "Appointment.ListAppointments", func() (err error) {
+		appointments, err = r.next.ListAppointments(ctx, filter)
+		return err
+	})
+	return appointments, err
+}
+
+func (r *retryingAppointmentRepository) CancelAppointment(ctx context.Context, appointment *models.Appointment) (bool, error) {
+	var ok bool
+	err := r.retry.write(ctx, "Appointment.CancelAppointment", func() (err error) {
+		ok, err = r.next.CancelAppointment(ctx, appointment)
+		return err
+	})
+	return ok, err
+}
+
+func (r *retryingAppointmentRepository) RescheduleAppointment(ctx context.Context, old, replacement *models.Appointment) error {
+	return r.retry.write(ctx, "Appointment.RescheduleAppointment", func() error {
+		return r.next.RescheduleAppointment(ctx, old, replacement)
+	})
+}
+
+func (r *retryingAppointmentRepository) ListDueReminders(ctx context.Context, before time.Time) ([]models.Appointment, error) {
+	var appointments []models.Appointment
+	err := r.retry.read(ctx, "Appointment.ListDueReminders", func() (err error) {
+		appointments, err = r.next.ListDueReminders(ctx, before)
+		return err
+	})
+	return appointments, err
+}
+
+func (r *retryingAppointmentRepository) MarkReminderSent(ctx context.Context, providerID, id uuid.UUID, at time.Time) error {
+	return r.retry.write(ctx, "Appointment.MarkReminderSent", func() error {
+		return r.next.MarkReminderSent(ctx, providerID, id, at)
+	})
+}
+
+func (r *retryingAppointmentRepository) Migrate() error {
+	return r.next.Migrate()
+}
+
+// retryingWorkoutAttachmentRepository retries WorkoutAttachmentRepository calls that fail for a transient reason.
+type retryingWorkoutAttachmentRepository struct {
+	next  WorkoutAttachmentRepository
+	retry retrier
+}
+
+// NewRetryingWorkoutAttachmentRepository wraps next in a WorkoutAttachmentRepository that retries under policy.
+func NewRetryingWorkoutAttachmentRepository(next WorkoutAttachmentRepository, policy RetryPolicy) WorkoutAttachmentRepository {
+	return &retryingWorkoutAttachmentRepository{next: next, retry: newRetrier(policy)}
+}
+
+func (r *retryingWorkoutAttachmentRepository) CreateAttachment(ctx context.Context, attachment *models.WorkoutAttachment) error {
+	return r.retry.write(ctx, "WorkoutAttachment.CreateAttachment", func() error {
+		return r.next.CreateAttachment(ctx, attachment)
+	})
+}
+
+func (r *retryingWorkoutAttachmentRepository) GetAttachment(ctx context.Context, userID, id uuid.UUID) (*models.WorkoutAttachment, error) {
+	var attachment *models.WorkoutAttachment
+	err := r.retry.read(ctx, "WorkoutAttachment.GetAttachment", func() (err error) {
+		attachment, err = r.next.GetAttachment(ctx, userID, id)
+		return err
+	})
+	return attachment, err
+}
+
+func (r *retryingWorkoutAttachmentRepository) ListAttachments(ctx context.Context, userID uuid.UUID, setRef string, sharedOnly bool) ([]models.WorkoutAttachment, error) {
+	var attachments []models.WorkoutAttachment
+	err := r.retry.read(ctx, "WorkoutAttachment.ListAttachments", func() (err error) {
+		attachments, err = r.next.ListAttachments(ctx, userID, setRef, sharedOnly)
+		return err
+	})
+	return attachments, err
+}
+
+func (r *retryingWorkoutAttachmentRepository) UpdateAttachment(ctx context.Context, attachment *models.WorkoutAttachment) (bool, error) {
+	var ok bool
+	err := r.retry.write(ctx, "WorkoutAttachment.UpdateAttachment", func() (err error) {
+		ok, err = r.next.UpdateAttachment(ctx, attachment)
+		return err
+	})
+	return ok, err
+}
+
+func (r *retryingWorkoutAttachmentRepository) DeleteAttachment(ctx context.Context, userID, id uuid.UUID) (blobKey string, err error) {
+	err = r.retry.write(ctx, "WorkoutAttachment.DeleteAttachment", func() (err error) {
+		blobKey, err = r.next.DeleteAttachment(ctx, userID, id)
+		return err
+	})
+	return blobKey, err
+}
+
+func (r *retryingWorkoutAttachmentRepository) ListPendingScans(ctx context.Context, limit int) ([]models.WorkoutAttachment, error) {
+	var attachments []models.WorkoutAttachment
+	err := r.retry.read(ctx, "WorkoutAttachment.ListPendingScans", func() (err error) {
+		attachments, err = r.next.ListPendingScans(ctx, limit)
+		return err
+	})
+	return attachments, err
+}
+
+func (r *retryingWorkoutAttachmentRepository) SetScanStatus(ctx context.Context, userID, id uuid.UUID, status string, at time.Time) error {
+	return r.retry.write(ctx, "WorkoutAttachment.SetScanStatus", func() error {
+		return r.next.SetScanStatus(ctx, userID, id, status, at)
+	})
+}
+
+func (r *retryingWorkoutAttachmentRepository) PurgeExpired(ctx context.Context, before time.Time) (blobKeys []string, err error) {
+	err = r.retry.write(ctx, "WorkoutAttachment.PurgeExpired", func() (err error) {
+		blobKeys, err = r.next.PurgeExpired(ctx, before)
+		return err
+	})
+	return blobKeys, err
+}
+
+func (r *retryingWorkoutAttachmentRepository) Migrate() error {
+	return r.next.Migrate()
+}
+
+// retryingWorkoutPlanRepository retries WorkoutPlanRepository calls that fail for a transient reason.
+type retryingWorkoutPlanRepository struct {
+	next  WorkoutPlanRepository
+	retry retrier
+}
+
+// NewRetryingWorkoutPlanRepository wraps next in a WorkoutPlanRepository that retries under policy.
+func NewRetryingWorkoutPlanRepository(next WorkoutPlanRepository, policy RetryPolicy) WorkoutPlanRepository {
+	return &retryingWorkoutPlanRepository{next: next, retry: newRetrier(policy)}
+}
+
+func (r *retryingWorkoutPlanRepository) SavePlans(ctx context.Context, coachID uuid.UUID, plans []models.WorkoutPlan) error {
+	return r.retry.write(ctx, "WorkoutPlan.SavePlans", func() error {
+		return r.next.SavePlans(ctx, coachID, plans)
+	})
+}
+
+func (r *retryingWorkoutPlanRepository) GetPlan(ctx context.Context, coachID, id uuid.UUID) (*models.WorkoutPlan, error) {
+	var plan *models.WorkoutPlan
+	err := r.retry.read(ctx, "WorkoutPlan.GetPlan", func() (err error) {
+		plan, err = r.next.GetPlan(ctx, coachID, id)
+		return err
+	})
+	return plan, err
+}
+
+func (r *retryingWorkoutPlanRepository) ListPlans(ctx context.Context, coachID uuid.UUID) ([]models.WorkoutPlan, error) {
+	var plans []models.WorkoutPlan
+	err := r.retry.read(ctx, "WorkoutPlan.ListPlans", func() (err error) {
+		plans, err = r.next.ListPlans(ctx, coachID)
+		return err
+	})
+	return plans, err
+}
+
+func (r *retryingWorkoutPlanRepository) DeletePlan(ctx context.Context, coachID, id uuid.UUID) (bool, error) {
+	var deleted bool
+	err := r.retry.write(ctx, "WorkoutPlan.DeletePlan", func() (err error) {
+		deleted, err = r.next.DeletePlan(ctx, coachID, id)
+		return err
+	})
+	return deleted, err
+}
+
+func (r *retryingWorkoutPlanRepository) Migrate() error {
+	return r.next.Migrate()
+}
+
+// retryingOutboxRepository retries OutboxRepository calls that fail for a transient reason.
+type retryingOutboxRepository struct {
+	next  OutboxRepository
+	retry retrier
+}
+
+// NewRetryingOutboxRepository wraps next in a OutboxRepository that retries under policy.
+func NewRetryingOutboxRepository(next OutboxRepository, policy RetryPolicy) OutboxRepository {
+	return &retryingOutboxRepository{next: next, retry: newRetrier(policy)}
+}
+
+func (r *retryingOutboxRepository) ClaimOutboxEvents(ctx context.Context, now, leaseUntil time.Time, limit int) ([]models.OutboxEvent, error) {
+	var events []models.OutboxEvent
+	err := r.retry.write(ctx, "Outbox.ClaimOutboxEvents", func() (err error) {
+		events, err = r.next.ClaimOutboxEvents(ctx, now, leaseUntil, limit)
+		return err
+	})
+	return events, err
+}
+
+func (r *retryingOutboxRepository) MarkOutboxEventSent(ctx context.Context, id uuid.UUID, at time.Time) error {
+	return r.retry.write(ctx, "Outbox.MarkOutboxEventSent", func() error {
+		return r.next.MarkOutboxEventSent(ctx, id, at)
+	})
+}
+
+func (r *retryingOutboxRepository) FailOutboxEvent(ctx context.Context, id uuid.UUID, retryAt time.Time, lastError string) error {
+	return r.retry.write(ctx, "Outbox.FailOutboxEvent", func() error {
+		return r.next.FailOutboxEvent(ctx, id, retryAt, lastError)
+	})
+}
+
+func (r *retryingOutboxRepository) ReleaseOutboxEvents(ctx context.Context, ids []uuid.UUID, at time.Time) error {
+	return r.retry.write(ctx, "Outbox.ReleaseOutboxEvents", func() error {
+		return r.next.ReleaseOutboxEvents(ctx, ids, at)
+	})
+}
+
+func (r *retryingOutboxRepository) PurgeSentOutboxEvents(ctx context.Context, before time.Time) (int64, error) {
+	var n int64
+	err := r.retry.write(ctx, "Outbox.PurgeSentOutboxEvents", func() (err error) {
+		n, err = r.next.PurgeSentOutboxEvents(ctx, before)
+		return err
+	})
+	return n, err
+}
+
+func (r *retryingOutboxRepository) Migrate() error {
+	return r.next.Migrate()
+}
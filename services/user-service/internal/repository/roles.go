@@ -0,0 +1,166 @@
+// services/user-service/internal/repository/roles.go
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// serviceTables are the tables this service's migrations create. Keep in sync with the migrations directory.
+// ProvisionServiceRole hands them to the service's role, so tables created before the service had its
+// own role stop belonging to whoever created them.
+var serviceTables = []string{
+	"users", "user_timezone_history", "password_reset_tokens", "email_verification_tokens", "profile_prompt_dismissals",
+	"aggregation_periods", "user_merges", "user_email_aliases", "user_events", "dashboard_layouts", "user_settings",
+	"login_attempts", "sessions", "user_identities", "identity_link_requests", "saml_requests", "integration_consents",
+	"coach_authorizations", "message_threads", "messages", "message_attachments", "appointment_slots",
+	"appointments", "workout_attachments", "workout_plans", "user_regions", "audit_events", "system_events",
+	"developer_apps", "developer_app_usage", "developer_app_recordings", "metering_events", "announcements",
+	"outbox", "schema_migrations",
+}
+
+// serviceFunctions are the functions this service's migrations create, which only their owner may
+// replace in a later migration.
+var serviceFunctions = []string{"metering_events_append_only()"}
+
+// ProvisionServiceRole creates or updates the Postgres role named in the service's data source name,
+// using admin, a pool on the same database with a role allowed to manage roles. The service role
+// gets only what its migrations need: to log in, connect, and create tables in the public schema. The
+// extensions they use are created here, as the service role cannot create them.
+// It is stripped of superuser, CREATEDB, CREATEROLE, BYPASSRLS, and REPLICATION, and takes
+// ownership of the service's existing tables and functions. It is granted nothing on tables it does not own.
+func ProvisionServiceRole(admin *sql.DB, serviceDSN string) error {
+	u, err := url.Parse(serviceDSN)
+	if err != nil || (u.Scheme != "postgres" && u.Scheme != "postgresql") || u.User == nil || u.User.Username() == "" {
+		return fmt.Errorf("repository: role provisioning needs a postgres:// URL with a user name")
+	}
+	role := u.User.Username()
+	var adminRole, database string
+	if err := admin.QueryRow(`SELECT current_user, current_database()`).Scan(&adminRole, &database); err != nil {
+		return fmt.Errorf("repository: failed to identify the admin role: %w", err)
+	}
+	if role == adminRole {
+		return fmt.Errorf("repository: the service and admin connections both use role %q; the service needs its own role", role)
+	}
+	var exists bool
+	if err := admin.QueryRow(`SELECT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = $1)`, role).Scan(&exists); err != nil {
+		return fmt.Errorf("repository: failed to look up role %q: %w", role, err)
+	}
+
+	// Identifiers and passwords cannot be bound as parameters in DDL, so they are quoted instead.
+	quoted := quoteIdentifier(role)
+	verb := "CREATE"
+	if exists {
+		verb = "ALTER"
+	}
+	roleStmt := fmt.Sprintf("%s ROLE %s LOGIN NOSUPERUSER NOCREATEDB NOCREATEROLE NOBYPASSRLS NOREPLICATION", verb, quoted)
+	if password, ok := u.User.Password(); ok {
+		roleStmt += " PASSWORD " + quoteLiteral(password)
+	}
+	stmts := []string{
+		roleStmt,
+		fmt.Sprintf("GRANT CONNECT ON DATABASE %s TO %s", quoteIdentifier(database), quoted),
+		fmt.Sprintf("GRANT USAGE, CREATE ON SCHEMA public TO %s", quoted),
+		// The users migrations need pg_trgm, which only a role with CREATE on the database may add
+		"CREATE EXTENSION IF NOT EXISTS pg_trgm WITH SCHEMA public",
+	}
+	for _, table := range serviceTables {
+		stmts = append(stmts, fmt.Sprintf("ALTER TABLE IF EXISTS public.%s OWNER TO %s", quoteIdentifier(table), quoted))
+	}
+	for _, function := range serviceFunctions {
+		var found bool
+		if err := admin.QueryRow(`SELECT to_regprocedure($1) IS NOT NULL`, "public."+function).Scan(&found); err != nil {
+			return fmt.Errorf("repository: failed to look up function %s: %w", function, err)
+		}
+		if found {
+			stmts = append(stmts, fmt.Sprintf("ALTER FUNCTION public.%s OWNER TO %s", function, quoted))
+		}
+	}
+
+	tx, err := admin.Begin()
+	if err != nil {
+		return fmt.Errorf("repository: failed to begin role provisioning: %w", err)
+	}
+	defer tx.Rollback()
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return fmt.Errorf("repository: failed to provision role %q: %w", role, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("repository: failed to commit role provisioning: %w", err)
+	}
+	logger.Logger.Infof("Provisioned database role %s on %s", role, database)
+	return nil
+}
+
+// RoleViolations lists the ways the pool's role reaches beyond its own tables: role attributes that
+// bypass privileges, and privileges on tables owned by roles it is not a member of, such as other
+// services' tables. An empty list means the role is confined to its own tables.
+func RoleViolations(db *sql.DB) ([]string, error) {
+	var role string
+	var super, createRole, bypassRLS bool
+	err := db.QueryRow(`SELECT rolname, rolsuper, rolcreaterole, rolbypassrls FROM pg_roles WHERE rolname = current_user`).
+		Scan(&role, &super, &createRole, &bypassRLS)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to read role attributes: %w", err)
+	}
+	var violations []string
+	if super {
+		violations = append(violations, fmt.Sprintf("role %s is a superuser and can read and write every table", role))
+	}
+	if createRole {
+		violations = append(violations, fmt.Sprintf("role %s has CREATEROLE and can grant itself other roles", role))
+	}
+	if bypassRLS {
+		violations = append(violations, fmt.Sprintf("role %s has BYPASSRLS", role))
+	}
+	if super {
+		return violations, nil // Every table would be listed below
+	}
+
+	query := `
+	SELECT n.nspname || '.' || c.relname, pg_get_userbyid(c.relowner)
+	FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+	WHERE c.relkind IN ('r', 'p', 'v', 'm', 'f')
+		AND n.nspname NOT IN ('pg_catalog', 'information_schema') AND n.nspname NOT LIKE 'pg\_toast%'
+		AND NOT pg_has_role(c.relowner, 'USAGE')
+		AND has_table_privilege(c.oid, 'SELECT, INSERT, UPDATE, DELETE, TRUNCATE')
+	ORDER BY 1`
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to check table privileges: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var table, owner string
+		if err := rows.Scan(&table, &owner); err != nil {
+			return nil, fmt.Errorf("repository: failed to scan table privilege row: %w", err)
+		}
+		violations = append(violations, fmt.Sprintf("role %s has privileges on %s, owned by %s", role, table, owner))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: rows iteration error: %w", err)
+	}
+	return violations, nil
+}
+
+// quoteIdentifier quotes name for use as an identifier in SQL text.
+func quoteIdentifier(name string) string {
+	return pgx.Identifier{name}.Sanitize()
+}
+
+// quoteLiteral quotes s for use as a string literal in SQL text. Backslashes are doubled in an escape
+// string, so the literal means the same whatever standard_conforming_strings is set to.
+func quoteLiteral(s string) string {
+	quoted := "'" + strings.ReplaceAll(s, "'", "''") + "'"
+	if strings.Contains(s, `\`) {
+		quoted = "E" + strings.ReplaceAll(quoted, `\`, `\\`)
+	}
+	return quoted
+}
@@ -0,0 +1,1647 @@
+// services/user-service/internal/repository/routed_repositories.go
+package repository
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// The routed repositories wrap one Postgres repository per region and send every call to the region
+// of the user it concerns. Calls that are not about a single user (token lookups, reaping) visit
+// every region.
+
+// forUser returns the repository of the region a user is assigned to.
+func forUser[T any](ctx context.Context, r *RegionRouter, repos map[string]T, userID uuid.UUID) (T, string, error) {
+	var zero T
+	region, err := r.regionOf(ctx, userID)
+	if err != nil {
+		return zero, "", err
+	}
+	if err := r.checkRegion(region); err != nil {
+		return zero, "", err
+	}
+	return repos[region], region, nil
+}
+
+// perRegion builds one repository per region with the given Postgres constructor.
+func perRegion[T any](r *RegionRouter, newRepo func(db *sql.DB) (T, error)) (map[string]T, error) {
+	repos := make(map[string]T, len(r.regions))
+	for _, region := range r.regions {
+		repo, err := newRepo(r.dbs[region])
+		if err != nil {
+			return nil, fmt.Errorf("region %s: %w", region, err)
+		}
+		repos[region] = repo
+	}
+	return repos, nil
+}
+
+// routedUserRepository routes UserRepository calls and keeps the region directory in step.
+type routedUserRepository struct {
+	router *RegionRouter
+	repos  map[string]UserRepository
+}
+
+// NewRoutedUserRepository creates a UserRepository over every region. Users created before
+// residency was enabled are assigned to the home region.
+func NewRoutedUserRepository(router *RegionRouter) (UserRepository, error) {
+	repos, err := perRegion(router, NewPostgresUserRepository)
+	if err != nil {
+		return nil, err
+	}
+	if err := router.backfill(); err != nil {
+		return nil, err
+	}
+	return &routedUserRepository{router: router, repos: repos}, nil
+}
+
+// CreateUser stores the user in user.Region, or the home region when it is empty.
+func (r *routedUserRepository) CreateUser(ctx context.Context, user *models.User) error {
+	if user.Region == "" {
+		user.Region = r.router.home
+	}
+	if err := r.router.checkRegion(user.Region); err != nil {
+		return err
+	}
+	// The directory's unique email and username hashes also keep them unique across regions.
+	if err := r.router.assign(ctx, user.ID, user.Email, user.Username, user.Region); err != nil {
+		return err
+	}
+	if err := r.repos[user.Region].CreateUser(ctx, user); err != nil {
+		r.router.unassign(ctx, user.ID)
+		return err
+	}
+	return nil
+}
+
+func (r *routedUserRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	region, err := r.router.regionOfEmail(ctx, email)
+	if err != nil || region == "" {
+		return nil, err
+	}
+	if err := r.router.checkRegion(region); err != nil {
+		return nil, err
+	}
+	user, err := r.repos[region].GetUserByEmail(ctx, email)
+	if user != nil {
+		user.Region = region
+	}
+	return user, err
+}
+
+func (r *routedUserRepository) GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	repo, region, err := forUser(ctx, r.router, r.repos, id)
+	if err != nil {
+		return nil, err
+	}
+	user, err := repo.GetUserByID(ctx, id)
+	if user != nil {
+		user.Region = region
+	}
+	return user, err
+}
+
+// GetAllUsers merges the oldest users after the page key of every region into one page.
+func (r *routedUserRepository) GetAllUsers(ctx context.Context, after *models.PageKey, limit int) ([]models.User, error) {
+	all := []models.User{}
+	for _, region := range r.router.regions {
+		users, err := r.repos[region].GetAllUsers(ctx, after, limit)
+		if err != nil {
+			return nil, err
+		}
+		for i := range users {
+			users[i].Region = region
+		}
+		all = append(all, users...)
+	}
+	slices.SortFunc(all, func(a, b models.User) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID.String(), b.ID.String())
+	})
+	if len(all) > limit {
+		all = all[:limit]
+	}
+	return all, nil
+}
+
+func (r *routedUserRepository) UpdateUser(ctx context.Context, user *models.User) (err error) {
+	repo, _, err := forUser(ctx, r.router, r.repos, user.ID)
+	if err != nil {
+		return err
+	}
+	current, err := repo.GetUserByID(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		return repo.UpdateUser(ctx, user)
+	}
+	// Repoint the directory first so its unique hashes refuse an email or username taken in
+	// another region, and restore it if the update fails.
+	if current.Email != user.Email {
+		if err := r.router.updateEmail(ctx, user.ID, user.Email); err != nil {
+			return err
+		}
+		defer func() {
+			if err != nil {
+				r.router.updateEmail(ctx, user.ID, current.Email)
+			}
+		}()
+	}
+	if current.Username != user.Username {
+		if err = r.router.updateUsername(ctx, user.ID, user.Username); err != nil {
+			return err
+		}
+		defer func() {
+			if err != nil {
+				r.router.updateUsername(ctx, user.ID, current.Username)
+			}
+		}()
+	}
+	err = repo.UpdateUser(ctx, user)
+	return err
+}
+
+// GetUserByUsername reads the user from the region the directory has the username in.
+func (r *routedUserRepository) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	region, err := r.router.regionOfUsername(ctx, username)
+	if err != nil || region == "" {
+		return nil, err
+	}
+	if err := r.router.checkRegion(region); err != nil {
+		return nil, err
+	}
+	user, err := r.repos[region].GetUserByUsername(ctx, username)
+	if user != nil {
+		user.Region = region
+	}
+	return user, err
+}
+
+func (r *routedUserRepository) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	repo, _, err := forUser(ctx, r.router, r.repos, id)
+	if err != nil {
+		return err
+	}
+	if err := repo.DeleteUser(ctx, id); err != nil {
+		return err
+	}
+	return r.router.unassign(ctx, id)
+}
+
+// ApplyUserChanges applies the changes in one transaction in the region of their users, which must all
+// be stored in the same one: a change to a user in another region than the changes before it fails with
+// ErrConflict. New users go to user.Region, or the home region when it is empty. As with CreateUser and
+// UpdateUser, the directory is updated first, and restored if the changes fail; deleted users leave it
+// once they are gone.
+func (r *routedUserRepository) ApplyUserChanges(ctx context.Context, changes []models.UserChange) (err error) {
+	region := ""
+	for i, change := range changes {
+		changeRegion := change.User.Region
+		if change.Op != models.BulkCreate {
+			if changeRegion, err = r.router.regionOf(ctx, change.User.ID); err != nil {
+				return err
+			}
+		} else if changeRegion == "" {
+			changeRegion = r.router.home
+		}
+		if region == "" {
+			region = changeRegion
+		}
+		if changeRegion != region {
+			return &ChangeError{Index: i, Err: Errorf(ErrConflict, "repository: cannot change users stored in different regions (%s, %s) together", region, changeRegion)}
+		}
+	}
+	if err := r.router.checkRegion(region); err != nil {
+		return err
+	}
+
+	var undo []func()
+	defer func() {
+		if err != nil {
+			for _, restore := range slices.Backward(undo) {
+				restore()
+			}
+		}
+	}()
+	for i, change := range changes {
+		user := change.User
+		switch change.Op {
+		case models.BulkCreate:
+			user.Region = region
+			if err := r.router.assign(ctx, user.ID, user.Email, user.Username, region); err != nil {
+				return &ChangeError{Index: i, Err: err}
+			}
+			undo = append(undo, func() { r.router.unassign(ctx, user.ID) })
+		case models.BulkUpdate:
+			current, err := r.repos[region].GetUserByID(ctx, user.ID)
+			if err != nil {
+				return err
+			}
+			if current == nil {
+				continue // The region's transaction reports the user missing
+			}
+			if current.Email != user.Email {
+				if err := r.router.updateEmail(ctx, user.ID, user.Email); err != nil {
+					return &ChangeError{Index: i, Err: err}
+				}
+				undo = append(undo, func() { r.router.updateEmail(ctx, user.ID, current.Email) })
+			}
+			if current.Username != user.Username {
+				if err := r.router.updateUsername(ctx, user.ID, user.Username); err != nil {
+					return &ChangeError{Index: i, Err: err}
+				}
+				undo = append(undo, func() { r.router.updateUsername(ctx, user.ID, current.Username) })
+			}
+		}
+	}
+	if err := r.repos[region].ApplyUserChanges(ctx, changes); err != nil {
+		return err
+	}
+	undo = nil // Applied; the directory stays as it is now
+	for _, change := range changes {
+		if change.Op == models.BulkDelete {
+			if err := r.router.unassign(ctx, change.User.ID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *routedUserRepository) CreatePasswordResetToken(ctx context.Context, userID uuid.UUID, tokenHash string, expiresAt time.Time) error {
+	repo, _, err := forUser(ctx, r.router, r.repos, userID)
+	if err != nil {
+		return err
+	}
+	return repo.CreatePasswordResetToken(ctx, userID, tokenHash, expiresAt)
+}
+
+// ConsumePasswordResetToken tries every region, since the token does not say whose it is.
+func (r *routedUserRepository) ConsumePasswordResetToken(ctx context.Context, tokenHash string) (uuid.UUID, error) {
+	for _, region := range r.router.regions {
+		userID, err := r.repos[region].ConsumePasswordResetToken(ctx, tokenHash)
+		if err != nil || userID != uuid.Nil {
+			return userID, err
+		}
+	}
+	return uuid.Nil, nil
+}
+
+func (r *routedUserRepository) RecordTimezoneChange(ctx context.Context, userID uuid.UUID, timezone string, effectiveFrom time.Time) error {
+	repo, _, err := forUser(ctx, r.router, r.repos, userID)
+	if err != nil {
+		return err
+	}
+	return repo.RecordTimezoneChange(ctx, userID, timezone, effectiveFrom)
+}
+
+func (r *routedUserRepository) GetTimezoneHistory(ctx context.Context, userID uuid.UUID) ([]models.TimezonePeriod, error) {
+	repo, _, err := forUser(ctx, r.router, r.repos, userID)
+	if err != nil {
+		return nil, err
+	}
+	return repo.GetTimezoneHistory(ctx, userID)
+}
+
+// MergeUsers only merges users of the same region; move one of them first otherwise.
+func (r *routedUserRepository) MergeUsers(ctx context.Context, merge *models.UserMerge) error {
+	repo, region, err := forUser(ctx, r.router, r.repos, merge.PrimaryUserID)
+	if err != nil {
+		return err
+	}
+	donorRegion, err := r.router.regionOf(ctx, merge.DonorSnapshot.ID)
+	if err != nil {
+		return err
+	}
+	if donorRegion != region {
+		return fmt.Errorf("repository: cannot merge users stored in different regions (%s, %s)", region, donorRegion)
+	}
+	if err := repo.MergeUsers(ctx, merge); err != nil {
+		return err
+	}
+	return r.router.alias(ctx, merge.DonorSnapshot.ID, merge.PrimaryUserID)
+}
+
+// GetUserMerge tries every region, since the merge ID does not say whose it is.
+func (r *routedUserRepository) GetUserMerge(ctx context.Context, id uuid.UUID) (*models.UserMerge, error) {
+	for _, region := range r.router.regions {
+		merge, err := r.repos[region].GetUserMerge(ctx, id)
+		if err != nil || merge != nil {
+			return merge, err
+		}
+	}
+	return nil, nil
+}
+
+// UndoUserMerge restores the donor into the region of the user it was merged into, where its alias entry points.
+func (r *routedUserRepository) UndoUserMerge(ctx context.Context, merge *models.UserMerge, undoneBy string) error {
+	repo, region, err := forUser(ctx, r.router, r.repos, merge.PrimaryUserID)
+	if err != nil {
+		return err
+	}
+	// The donor's entry was kept as an alias, without its username. Merges from before aliases existed
+	// removed it, so those donors are assigned afresh.
+	aliasRegion, err := r.router.regionOfHash(ctx, `SELECT region FROM user_regions WHERE user_id = $1 AND alias_of = $2`,
+		merge.DonorSnapshot.ID, merge.PrimaryUserID)
+	if err != nil {
+		return err
+	}
+	if aliasRegion == "" {
+		if err := r.router.assign(ctx, merge.DonorSnapshot.ID, merge.DonorSnapshot.Email, "", region); err != nil { // Restored without a username
+			return err
+		}
+	}
+	if err := repo.UndoUserMerge(ctx, merge, undoneBy); err != nil {
+		if aliasRegion == "" {
+			r.router.unassign(ctx, merge.DonorSnapshot.ID)
+		}
+		return err
+	}
+	return r.router.unalias(ctx, merge.DonorSnapshot.ID)
+}
+
+func (r *routedUserRepository) GetUserMetadata(ctx context.Context, userID uuid.UUID) (models.UserMetadata, error) {
+	repo, _, err := forUser(ctx, r.router, r.repos, userID)
+	if err != nil {
+		return nil, err
+	}
+	return repo.GetUserMetadata(ctx, userID)
+}
+
+func (r *routedUserRepository) MergeUserMetadata(ctx context.Context, userID uuid.UUID, set models.UserMetadata, remove []string, maxBytes int) (models.UserMetadata, error) {
+	repo, _, err := forUser(ctx, r.router, r.repos, userID)
+	if err != nil {
+		return nil, err
+	}
+	return repo.MergeUserMetadata(ctx, userID, set, remove, maxBytes)
+}
+
+func (r *routedUserRepository) GetProfilePromptDismissals(ctx context.Context, userID uuid.UUID) (map[string]models.ProfilePromptDismissal, error) {
+	repo, _, err := forUser(ctx, r.router, r.repos, userID)
+	if err != nil {
+		return nil, err
+	}
+	return repo.GetProfilePromptDismissals(ctx, userID)
+}
+
+func (r *routedUserRepository) DismissProfilePrompt(ctx context.Context, userID uuid.UUID, field string, at time.Time) error {
+	repo, _, err := forUser(ctx, r.router, r.repos, userID)
+	if err != nil {
+		return err
+	}
+	return repo.DismissProfilePrompt(ctx, userID, field, at)
+}
+
+func (r *routedUserRepository) ListAggregationPeriods(ctx context.Context, userID uuid.UUID) ([]models.AggregationPeriod, error) {
+	repo, _, err := forUser(ctx, r.router, r.repos, userID)
+	if err != nil {
+		return nil, err
+	}
+	return repo.ListAggregationPeriods(ctx, userID)
+}
+
+func (r *routedUserRepository) CreateAggregationPeriod(ctx context.Context, period *models.AggregationPeriod) error {
+	repo, _, err := forUser(ctx, r.router, r.repos, period.UserID)
+	if err != nil {
+		return err
+	}
+	return repo.CreateAggregationPeriod(ctx, period)
+}
+
+func (r *routedUserRepository) UpdateAggregationPeriod(ctx context.Context, period *models.AggregationPeriod) (bool, error) {
+	repo, _, err := forUser(ctx, r.router, r.repos, period.UserID)
+	if err != nil {
+		return false, err
+	}
+	return repo.UpdateAggregationPeriod(ctx, period)
+}
+
+func (r *routedUserRepository) DeleteAggregationPeriod(ctx context.Context, userID, id uuid.UUID) (bool, error) {
+	repo, _, err := forUser(ctx, r.router, r.repos, userID)
+	if err != nil {
+		return false, err
+	}
+	return repo.DeleteAggregationPeriod(ctx, userID, id)
+}
+
+// ListUsers merges the newest users of every region into one page.
+func (r *routedUserRepository) ListUsers(ctx context.Context, filter models.UserFilter) ([]models.User, error) {
+	all := []models.User{}
+	for _, region := range r.router.regions {
+		users, err := r.repos[region].ListUsers(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		for i := range users {
+			users[i].Region = region
+		}
+		all = append(all, users...)
+	}
+	slices.SortFunc(all, func(a, b models.User) int {
+		if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(b.ID.String(), a.ID.String())
+	})
+	if len(all) > filter.Limit {
+		all = all[:filter.Limit]
+	}
+	return all, nil
+}
+
+// SearchUsers merges the best matches of every region into one list.
+func (r *routedUserRepository) SearchUsers(ctx context.Context, query string, limit int) ([]models.UserMatch, error) {
+	all := []models.UserMatch{}
+	for _, region := range r.router.regions {
+		matches, err := r.repos[region].SearchUsers(ctx, query, limit)
+		if err != nil {
+			return nil, err
+		}
+		for i := range matches {
+			matches[i].User.Region = region
+		}
+		all = append(all, matches...)
+	}
+	slices.SortFunc(all, func(a, b models.UserMatch) int {
+		if c := cmp.Compare(b.Score, a.Score); c != 0 {
+			return c
+		}
+		if c := a.User.CreatedAt.Compare(b.User.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.User.ID.String(), b.User.ID.String())
+	})
+	if len(all) > limit {
+		all = all[:limit]
+	}
+	return all, nil
+}
+
+func (r *routedUserRepository) CountUsers(ctx context.Context, filter models.UserFilter, activeSince time.Time) (*models.UserCounts, error) {
+	total := &models.UserCounts{}
+	for _, region := range r.router.regions {
+		counts, err := r.repos[region].CountUsers(ctx, filter, activeSince)
+		if err != nil {
+			return nil, err
+		}
+		total.Total += counts.Total
+		total.ActiveLast30Days += counts.ActiveLast30Days
+		total.Unverified += counts.Unverified
+	}
+	return total, nil
+}
+
+func (r *routedUserRepository) MarkEmailVerified(ctx context.Context, userID uuid.UUID, at time.Time) error {
+	repo, _, err := forUser(ctx, r.router, r.repos, userID)
+	if err != nil {
+		return err
+	}
+	return repo.MarkEmailVerified(ctx, userID, at)
+}
+
+func (r *routedUserRepository) RecordEmailDeliveryEvent(ctx context.Context, userID uuid.UUID, email, kind string, at time.Time) (string, error) {
+	repo, _, err := forUser(ctx, r.router, r.repos, userID)
+	if err != nil {
+		return "", err
+	}
+	return repo.RecordEmailDeliveryEvent(ctx, userID, email, kind, at)
+}
+
+func (r *routedUserRepository) CreateEmailVerificationToken(ctx context.Context, userID uuid.UUID, email, tokenHash string, expiresAt time.Time) error {
+	repo, _, err := forUser(ctx, r.router, r.repos, userID)
+	if err != nil {
+		return err
+	}
+	return repo.CreateEmailVerificationToken(ctx, userID, email, tokenHash, expiresAt)
+}
+
+func (r *routedUserRepository) ConsumeEmailVerificationToken(ctx context.Context, userID uuid.UUID, tokenHash string) (string, error) {
+	repo, _, err := forUser(ctx, r.router, r.repos, userID)
+	if err != nil {
+		return "", err
+	}
+	return repo.ConsumeEmailVerificationToken(ctx, userID, tokenHash)
+}
+
+func (r *routedUserRepository) RestoreEmailDeliverability(ctx context.Context, userID uuid.UUID, email string, at time.Time) (bool, error) {
+	repo, _, err := forUser(ctx, r.router, r.repos, userID)
+	if err != nil {
+		return false, err
+	}
+	return repo.RestoreEmailDeliverability(ctx, userID, email, at)
+}
+
+func (r *routedUserRepository) GetOnboarding(ctx context.Context, userID uuid.UUID) (*models.OnboardingState, error) {
+	repo, _, err := forUser(ctx, r.router, r.repos, userID)
+	if err != nil {
+		return nil, err
+	}
+	return repo.GetOnboarding(ctx, userID)
+}
+
+func (r *routedUserRepository) AdvanceOnboarding(ctx context.Context, userID uuid.UUID, from, to string, skip bool, at time.Time) (bool, error) {
+	repo, _, err := forUser(ctx, r.router, r.repos, userID)
+	if err != nil {
+		return false, err
+	}
+	return repo.AdvanceOnboarding(ctx, userID, from, to, skip, at)
+}
+
+func (r *routedUserRepository) CountOnboardingSteps(ctx context.Context, since time.Time) ([]models.OnboardingStepCount, error) {
+	var all []models.OnboardingStepCount
+	for _, region := range r.router.regions {
+		counts, err := r.repos[region].CountOnboardingSteps(ctx, since)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, counts...)
+	}
+	return all, nil
+}
+
+func (r *routedUserRepository) StorageUsage(ctx context.Context) (map[uuid.UUID]int64, error) {
+	all := map[uuid.UUID]int64{}
+	for _, region := range r.router.regions {
+		usage, err := r.repos[region].StorageUsage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for id, bytes := range usage {
+			all[id] += bytes
+		}
+	}
+	return all, nil
+}
+
+func (r *routedUserRepository) CountAnnouncementRecipients(ctx context.Context, segment models.AnnouncementSegment) (*models.AnnouncementAudience, error) {
+	total := &models.AnnouncementAudience{}
+	for _, region := range r.router.regions {
+		audience, err := r.repos[region].CountAnnouncementRecipients(ctx, segment)
+		if err != nil {
+			return nil, err
+		}
+		total.Recipients += audience.Recipients
+		total.OptedOut += audience.OptedOut
+	}
+	return total, nil
+}
+
+// ListAnnouncementRecipients merges the next recipients of every region into one page, in ID order.
+func (r *routedUserRepository) ListAnnouncementRecipients(ctx context.Context, segment models.AnnouncementSegment, after uuid.UUID, limit int) ([]uuid.UUID, error) {
+	var all []uuid.UUID
+	for _, region := range r.router.regions {
+		ids, err := r.repos[region].ListAnnouncementRecipients(ctx, segment, after, limit)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, ids...)
+	}
+	slices.SortFunc(all, func(a, b uuid.UUID) int { return bytes.Compare(a[:], b[:]) })
+	if len(all) > limit {
+		all = all[:limit]
+	}
+	return all, nil
+}
+
+func (r *routedUserRepository) SummarizeUserData(ctx context.Context, userID uuid.UUID) ([]models.DataCategory, error) {
+	repo, _, err := forUser(ctx, r.router, r.repos, userID)
+	if err != nil {
+		return nil, err
+	}
+	return repo.SummarizeUserData(ctx, userID)
+}
+
+func (r *routedUserRepository) ListDueDeletions(ctx context.Context, now time.Time, limit int) ([]models.User, error) {
+	var all []models.User
+	for _, region := range r.router.regions {
+		users, err := r.repos[region].ListDueDeletions(ctx, now, limit)
+		if err != nil {
+			return nil, err
+		}
+		for i := range users {
+			users[i].Region = region
+		}
+		all = append(all, users...)
+	}
+	return all, nil
+}
+
+func (r *routedUserRepository) EraseUser(ctx context.Context, id uuid.UUID) ([]string, error) {
+	repo, _, err := forUser(ctx, r.router, r.repos, id)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := repo.EraseUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	// The user is gone either way; a leftover directory entry holds nothing but the ID.
+	if err := r.router.unassign(ctx, id); err != nil {
+		logger.FromContext(ctx).Errorf("Erased user %s but failed to remove their region assignment: %v", id, err)
+	}
+	return keys, nil
+}
+
+// ResealHealthFields reseals up to limit users in each region, so one call may change more than limit.
+func (r *routedUserRepository) ResealHealthFields(ctx context.Context, limit int) (int, error) {
+	total := 0
+	for _, region := range r.router.regions {
+		n, err := r.repos[region].ResealHealthFields(ctx, limit)
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+func (r *routedUserRepository) Migrate() error {
+	for _, repo := range r.repos {
+		if err := repo.Migrate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// routedUserEventRepository routes UserEventRepository calls.
+type routedUserEventRepository struct {
+	router *RegionRouter
+	repos  map[string]UserEventRepository
+}
+
+// NewRoutedUserEventRepository creates a UserEventRepository over every region.
+func NewRoutedUserEventRepository(router *RegionRouter) (UserEventRepository, error) {
+	repos, err := perRegion(router, NewPostgresUserEventRepository)
+	if err != nil {
+		return nil, err
+	}
+	return &routedUserEventRepository{router: router, repos: repos}, nil
+}
+
+func (r *routedUserEventRepository) CreateEvent(ctx context.Context, event *models.UserEvent) error {
+	repo, _, err := forUser(ctx, r.router, r.repos, event.UserID)
+	if err != nil {
+		return err
+	}
+	return repo.CreateEvent(ctx, event)
+}
+
+func (r *routedUserEventRepository) ListEvents(ctx context.Context, filter models.UserEventFilter) ([]models.UserEvent, error) {
+	repo, _, err := forUser(ctx, r.router, r.repos, filter.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return repo.ListEvents(ctx, filter)
+}
+
+func (r *routedUserEventRepository) Migrate() error {
+	for _, repo := range r.repos {
+		if err := repo.Migrate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// routedDashboardRepository routes DashboardRepository calls.
+type routedDashboardRepository struct {
+	router *RegionRouter
+	repos  map[string]DashboardRepository
+}
+
+// NewRoutedDashboardRepository creates a DashboardRepository over every region.
+func NewRoutedDashboardRepository(router *RegionRouter) (DashboardRepository, error) {
+	repos, err := perRegion(router, NewPostgresDashboardRepository)
+	if err != nil {
+		return nil, err
+	}
+	return &routedDashboardRepository{router: router, repos: repos}, nil
+}
+
+func (r *routedDashboardRepository) GetLayout(ctx context.Context, userID uuid.UUID) (*models.DashboardLayout, error) {
+	repo, _, err := forUser(ctx, r.router, r.repos, userID)
+	if err != nil {
+		return nil, err
+	}
+	return repo.GetLayout(ctx, userID)
+}
+
+func (r *routedDashboardRepository) SaveLayout(ctx context.Context, userID uuid.UUID, layout *models.DashboardLayout) error {
+	repo, _, err := forUser(ctx, r.router, r.repos, userID)
+	if err != nil {
+		return err
+	}
+	return repo.SaveLayout(ctx, userID, layout)
+}
+
+func (r *routedDashboardRepository) DeleteLayout(ctx context.Context, userID uuid.UUID) error {
+	repo, _, err := forUser(ctx, r.router, r.repos, userID)
+	if err != nil {
+		return err
+	}
+	return repo.DeleteLayout(ctx, userID)
+}
+
+func (r *routedDashboardRepository) Migrate() error {
+	for _, repo := range r.repos {
+		if err := repo.Migrate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// routedSettingsRepository routes SettingsRepository calls.
+type routedSettingsRepository struct {
+	router *RegionRouter
+	repos  map[string]SettingsRepository
+}
+
+// NewRoutedSettingsRepository creates a SettingsRepository over every region.
+func NewRoutedSettingsRepository(router *RegionRouter) (SettingsRepository, error) {
+	repos, err := perRegion(router, NewPostgresSettingsRepository)
+	if err != nil {
+		return nil, err
+	}
+	return &routedSettingsRepository{router: router, repos: repos}, nil
+}
+
+func (r *routedSettingsRepository) GetSettings(ctx context.Context, userID uuid.UUID) (*models.UserSettings, error) {
+	repo, _, err := forUser(ctx, r.router, r.repos, userID)
+	if err != nil {
+		return nil, err
+	}
+	return repo.GetSettings(ctx, userID)
+}
+
+func (r *routedSettingsRepository) SaveSettings(ctx context.Context, userID uuid.UUID, settings *models.UserSettings) error {
+	repo, _, err := forUser(ctx, r.router, r.repos, userID)
+	if err != nil {
+		return err
+	}
+	return repo.SaveSettings(ctx, userID, settings)
+}
+
+func (r *routedSettingsRepository) Migrate() error {
+	for _, repo := range r.repos {
+		if err := repo.Migrate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// routedLoginAttemptRepository routes LoginAttemptRepository calls.
+type routedLoginAttemptRepository struct {
+	router *RegionRouter
+	repos  map[string]LoginAttemptRepository
+}
+
+// NewRoutedLoginAttemptRepository creates a LoginAttemptRepository over every region.
+func NewRoutedLoginAttemptRepository(router *RegionRouter) (LoginAttemptRepository, error) {
+	repos, err := perRegion(router, NewPostgresLoginAttemptRepository)
+	if err != nil {
+		return nil, err
+	}
+	return &routedLoginAttemptRepository{router: router, repos: repos}, nil
+}
+
+func (r *routedLoginAttemptRepository) CreateAttempt(ctx context.Context, attempt *models.LoginAttempt) error {
+	repo, _, err := forUser(ctx, r.router, r.repos, attempt.UserID)
+	if err != nil {
+		return err
+	}
+	return repo.CreateAttempt(ctx, attempt)
+}
+
+func (r *routedLoginAttemptRepository) ListAttempts(ctx context.Context, filter models.LoginAttemptFilter) ([]models.LoginAttempt, error) {
+	repo, _, err := forUser(ctx, r.router, r.repos, filter.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return repo.ListAttempts(ctx, filter)
+}
+
+func (r *routedLoginAttemptRepository) Migrate() error {
+	for _, repo := range r.repos {
+		if err := repo.Migrate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// routedSessionRepository routes SessionRepository calls.
+type routedSessionRepository struct {
+	router *RegionRouter
+	repos  map[string]SessionRepository
+}
+
+// NewRoutedSessionRepository creates a SessionRepository over every region.
+func NewRoutedSessionRepository(router *RegionRouter) (SessionRepository, error) {
+	repos, err := perRegion(router, NewPostgresSessionRepository)
+	if err != nil {
+		return nil, err
+	}
+	return &routedSessionRepository{router: router, repos: repos}, nil
+}
+
+func (r *routedSessionRepository) CreateSession(ctx context.Context, session *models.Session, maxPerUser int) ([]uuid.UUID, error) {
+	repo, _, err := forUser(ctx, r.router, r.repos, session.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return repo.CreateSession(ctx, session, maxPerUser)
+}
+
+func (r *routedSessionRepository) GetSession(ctx context.Context, userID, id uuid.UUID) (*models.Session, error) {
+	repo, _, err := forUser(ctx, r.router, r.repos, userID)
+	if err != nil {
+		return nil, err
+	}
+	return repo.GetSession(ctx, userID, id)
+}
+
+func (r *routedSessionRepository) DeleteSession(ctx context.Context, userID, id uuid.UUID) error {
+	repo, _, err := forUser(ctx, r.router, r.repos, userID)
+	if err != nil {
+		return err
+	}
+	return repo.DeleteSession(ctx, userID, id)
+}
+
+func (r *routedSessionRepository) DeleteUserSessions(ctx context.Context, userID uuid.UUID) error {
+	repo, _, err := forUser(ctx, r.router, r.repos, userID)
+	if err != nil {
+		return err
+	}
+	return repo.DeleteUserSessions(ctx, userID)
+}
+
+func (r *routedSessionRepository) DeleteExpiredSessions(ctx context.Context, before time.Time) (int64, error) {
+	var total int64
+	for _, region := range r.router.regions {
+		n, err := r.repos[region].DeleteExpiredSessions(ctx, before)
+		if err != nil {
+			return total, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+func (r *routedSessionRepository) CountActiveSessions(ctx context.Context) (int64, int64, error) {
+	var sessions, users int64
+	for _, region := range r.router.regions {
+		s, u, err := r.repos[region].CountActiveSessions(ctx)
+		if err != nil {
+			return 0, 0, err
+		}
+		sessions += s
+		users += u // A user's sessions all live in one region, so per-region counts add up
+	}
+	return sessions, users, nil
+}
+
+func (r *routedSessionRepository) Migrate() error {
+	for _, repo := range r.repos {
+		if err := repo.Migrate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// routedIdentityRepository routes IdentityRepository calls.
+type routedIdentityRepository struct {
+	router *RegionRouter
+	repos  map[string]IdentityRepository
+}
+
+// NewRoutedIdentityRepository creates an IdentityRepository over every region.
+func NewRoutedIdentityRepository(router *RegionRouter) (IdentityRepository, error) {
+	repos, err := perRegion(router, NewPostgresIdentityRepository)
+	if err != nil {
+		return nil, err
+	}
+	return &routedIdentityRepository{router: router, repos: repos}, nil
+}
+
+// GetIdentity tries every region, since the provider's subject does not say which user it belongs to.
+func (r *routedIdentityRepository) GetIdentity(ctx context.Context, issuer, subject string) (*models.UserIdentity, error) {
+	for _, region := range r.router.regions {
+		identity, err := r.repos[region].GetIdentity(ctx, issuer, subject)
+		if err != nil || identity != nil {
+			return identity, err
+		}
+	}
+	return nil, nil
+}
+
+func (r *routedIdentityRepository) ListIdentities(ctx context.Context, userID uuid.UUID) ([]models.UserIdentity, error) {
+	repo, _, err := forUser(ctx, r.router, r.repos, userID)
+	if err != nil {
+		return nil, err
+	}
+	return repo.ListIdentities(ctx, userID)
+}
+
+func (r *routedIdentityRepository) CreateIdentity(ctx context.Context, identity *models.UserIdentity) error {
+	repo, _, err := forUser(ctx, r.router, r.repos, identity.UserID)
+	if err != nil {
+		return err
+	}
+	return repo.CreateIdentity(ctx, identity)
+}
+
+func (r *routedIdentityRepository) CreateLinkRequest(ctx context.Context, req *models.IdentityLinkRequest) error {
+	repo, _, err := forUser(ctx, r.router, r.repos, req.UserID)
+	if err != nil {
+		return err
+	}
+	return repo.CreateLinkRequest(ctx, req)
+}
+
+// GetLinkRequest tries every region, since the link token does not say whose it is.
+func (r *routedIdentityRepository) GetLinkRequest(ctx context.Context, tokenHash string) (*models.IdentityLinkRequest, error) {
+	for _, region := range r.router.regions {
+		req, err := r.repos[region].GetLinkRequest(ctx, tokenHash)
+		if err != nil || req != nil {
+			return req, err
+		}
+	}
+	return nil, nil
+}
+
+// IncrementLinkAttempts updates the request in whichever region holds it.
+func (r *routedIdentityRepository) IncrementLinkAttempts(ctx context.Context, id uuid.UUID) error {
+	for _, region := range r.router.regions {
+		if err := r.repos[region].IncrementLinkAttempts(ctx, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteLinkRequest deletes the request from whichever region holds it.
+func (r *routedIdentityRepository) DeleteLinkRequest(ctx context.Context, id uuid.UUID) (bool, error) {
+	for _, region := range r.router.regions {
+		deleted, err := r.repos[region].DeleteLinkRequest(ctx, id)
+		if err != nil || deleted {
+			return deleted, err
+		}
+	}
+	return false, nil
+}
+
+// CreateSAMLRequest stores the request in the home region: it belongs to no user yet.
+func (r *routedIdentityRepository) CreateSAMLRequest(ctx context.Context, id string, expiresAt time.Time) error {
+	return r.repos[r.router.home].CreateSAMLRequest(ctx, id, expiresAt)
+}
+
+func (r *routedIdentityRepository) ConsumeSAMLRequest(ctx context.Context, id string) (bool, error) {
+	return r.repos[r.router.home].ConsumeSAMLRequest(ctx, id)
+}
+
+func (r *routedIdentityRepository) Migrate() error {
+	for _, repo := range r.repos {
+		if err := repo.Migrate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// routedConsentRepository routes ConsentRepository calls.
+type routedConsentRepository struct {
+	router *RegionRouter
+	repos  map[string]ConsentRepository
+}
+
+// NewRoutedConsentRepository creates a ConsentRepository over every region.
+func NewRoutedConsentRepository(router *RegionRouter) (ConsentRepository, error) {
+	repos, err := perRegion(router, NewPostgresConsentRepository)
+	if err != nil {
+		return nil, err
+	}
+	return &routedConsentRepository{router: router, repos: repos}, nil
+}
+
+func (r *routedConsentRepository) GrantConsent(ctx context.Context, consent *models.IntegrationConsent) error {
+	repo, _, err := forUser(ctx, r.router, r.repos, consent.UserID)
+	if err != nil {
+		return err
+	}
+	return repo.GrantConsent(ctx, consent)
+}
+
+func (r *routedConsentRepository) GetActiveConsent(ctx context.Context, userID uuid.UUID, provider string) (*models.IntegrationConsent, error) {
+	repo, _, err := forUser(ctx, r.router, r.repos, userID)
+	if err != nil {
+		return nil, err
+	}
+	return repo.GetActiveConsent(ctx, userID, provider)
+}
+
+func (r *routedConsentRepository) ListConsents(ctx context.Context, userID uuid.UUID) ([]models.IntegrationConsent, error) {
+	repo, _, err := forUser(ctx, r.router, r.repos, userID)
+	if err != nil {
+		return nil, err
+	}
+	return repo.ListConsents(ctx, userID)
+}
+
+func (r *routedConsentRepository) RevokeConsent(ctx context.Context, userID uuid.UUID, provider string, deleteData bool, at time.Time) (*models.IntegrationConsent, error) {
+	repo, _, err := forUser(ctx, r.router, r.repos, userID)
+	if err != nil {
+		return nil, err
+	}
+	return repo.RevokeConsent(ctx, userID, provider, deleteData, at)
+}
+
+// ListRevocations merges the oldest revocations of every region into one page.
+func (r *routedConsentRepository) ListRevocations(ctx context.Context, filter models.ConsentRevocationFilter) ([]models.IntegrationConsent, error) {
+	merged := []models.IntegrationConsent{}
+	for _, region := range r.router.regions {
+		page, err := r.repos[region].ListRevocations(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		merged = append(merged, page...)
+	}
+	slices.SortFunc(merged, func(a, b models.IntegrationConsent) int {
+		if c := a.RevokedAt.Compare(*b.RevokedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID.String(), b.ID.String())
+	})
+	if len(merged) > filter.Limit {
+		merged = merged[:filter.Limit]
+	}
+	return merged, nil
+}
+
+func (r *routedConsentRepository) Migrate() error {
+	for _, repo := range r.repos {
+		if err := repo.Migrate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// routedMessagingRepository routes MessagingRepository calls to the region of the thread's user.
+// A coach's threads and clients can be in any region, so lookups by coach visit every region.
+type routedMessagingRepository struct {
+	router *RegionRouter
+	repos  map[string]MessagingRepository
+}
+
+// NewRoutedMessagingRepository creates a MessagingRepository over every region.
+func NewRoutedMessagingRepository(router *RegionRouter) (MessagingRepository, error) {
+	repos, err := perRegion(router, NewPostgresMessagingRepository)
+	if err != nil {
+		return nil, err
+	}
+	return &routedMessagingRepository{router: router, repos: repos}, nil
+}
+
+func (r *routedMessagingRepository) AuthorizeCoach(ctx context.Context, auth *models.CoachAuthorization) error {
+	repo, _, err := forUser(ctx, r.router, r.repos, auth.UserID)
+	if err != nil {
+		return err
+	}
+	return repo.AuthorizeCoach(ctx, auth)
+}
+
+func (r *routedMessagingRepository) RevokeCoach(ctx context.Context, userID, coachID uuid.UUID, at time.Time) (bool, error) {
+	repo, _, err := forUser(ctx, r.router, r.repos, userID)
+	if err != nil {
+		return false, err
+	}
+	return repo.RevokeCoach(ctx, userID, coachID, at)
+}
+
+func (r *routedMessagingRepository) IsCoachAuthorized(ctx context.Context, userID, coachID uuid.UUID) (bool, error) {
+	repo, _, err := forUser(ctx, r.router, r.repos, userID)
+	if err != nil {
+		return false, err
+	}
+	return repo.IsCoachAuthorized(ctx, userID, coachID)
+}
+
+func (r *routedMessagingRepository) ListCoaches(ctx context.Context, userID uuid.UUID) ([]models.CoachAuthorization, error) {
+	repo, _, err := forUser(ctx, r.router, r.repos, userID)
+	if err != nil {
+		return nil, err
+	}
+	return repo.ListCoaches(ctx, userID)
+}
+
+// ListClients collects the coach's clients from every region, newest first.
+func (r *routedMessagingRepository) ListClients(ctx context.Context, coachID uuid.UUID) ([]models.CoachAuthorization, error) {
+	all := []models.CoachAuthorization{}
+	for _, region := range r.router.regions {
+		auths, err := r.repos[region].ListClients(ctx, coachID)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, auths...)
+	}
+	slices.SortFunc(all, func(a, b models.CoachAuthorization) int { return b.AuthorizedAt.Compare(a.AuthorizedAt) })
+	return all, nil
+}
+
+func (r *routedMessagingRepository) CreateThread(ctx context.Context, thread *models.MessageThread) error {
+	repo, _, err := forUser(ctx, r.router, r.repos, thread.UserID)
+	if err != nil {
+		return err
+	}
+	return repo.CreateThread(ctx, thread)
+}
+
+// GetThread tries every region, since the thread ID does not say whose it is.
+func (r *routedMessagingRepository) GetThread(ctx context.Context, id uuid.UUID) (*models.MessageThread, error) {
+	for _, region := range r.router.regions {
+		thread, err := r.repos[region].GetThread(ctx, id)
+		if err != nil || thread != nil {
+			return thread, err
+		}
+	}
+	return nil, nil
+}
+
+// ListThreads collects the participant's threads from every region, most recently active first.
+func (r *routedMessagingRepository) ListThreads(ctx context.Context, participantID uuid.UUID) ([]models.MessageThread, error) {
+	all := []models.MessageThread{}
+	for _, region := range r.router.regions {
+		threads, err := r.repos[region].ListThreads(ctx, participantID)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, threads...)
+	}
+	slices.SortFunc(all, func(a, b models.MessageThread) int { return b.LastMessageAt.Compare(a.LastMessageAt) })
+	return all, nil
+}
+
+func (r *routedMessagingRepository) CreateMessage(ctx context.Context, userID uuid.UUID, msg *models.Message, attachmentIDs []uuid.UUID) error {
+	repo, _, err := forUser(ctx, r.router, r.repos, userID)
+	if err != nil {
+		return err
+	}
+	return repo.CreateMessage(ctx, userID, msg, attachmentIDs)
+}
+
+func (r *routedMessagingRepository) ListMessages(ctx context.Context, userID uuid.UUID, filter models.MessageFilter) ([]models.Message, error) {
+	repo, _, err := forUser(ctx, r.router, r.repos, userID)
+	if err != nil {
+		return nil, err
+	}
+	return repo.ListMessages(ctx, userID, filter)
+}
+
+func (r *routedMessagingRepository) MarkRead(ctx context.Context, userID, threadID, readerID uuid.UUID, at time.Time) (int64, error) {
+	repo, _, err := forUser(ctx, r.router, r.repos, userID)
+	if err != nil {
+		return 0, err
+	}
+	return repo.MarkRead(ctx, userID, threadID, readerID, at)
+}
+
+func (r *routedMessagingRepository) CreateAttachment(ctx context.Context, userID uuid.UUID, attachment *models.MessageAttachment) error {
+	repo, _, err := forUser(ctx, r.router, r.repos, userID)
+	if err != nil {
+		return err
+	}
+	return repo.CreateAttachment(ctx, userID, attachment)
+}
+
+func (r *routedMessagingRepository) GetAttachment(ctx context.Context, userID, threadID, id uuid.UUID) (*models.MessageAttachment, error) {
+	repo, _, err := forUser(ctx, r.router, r.repos, userID)
+	if err != nil {
+		return nil, err
+	}
+	return repo.GetAttachment(ctx, userID, threadID, id)
+}
+
+// PurgeMessages purges every region, returning the blob keys deleted so far if one fails.
+func (r *routedMessagingRepository) PurgeMessages(ctx context.Context, before, unsentBefore time.Time) ([]string, int64, error) {
+	keys := []string{}
+	var total int64
+	for _, region := range r.router.regions {
+		regionKeys, n, err := r.repos[region].PurgeMessages(ctx, before, unsentBefore)
+		if err != nil {
+			return keys, total, err
+		}
+		keys = append(keys, regionKeys...)
+		total += n
+	}
+	return keys, total, nil
+}
+
+func (r *routedMessagingRepository) Migrate() error {
+	for _, repo := range r.repos {
+		if err := repo.Migrate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// routedAppointmentRepository routes AppointmentRepository calls to the region of the provider.
+// A user's appointments can be with providers in any region, so lookups by user or ID visit every region.
+type routedAppointmentRepository struct {
+	router *RegionRouter
+	repos  map[string]AppointmentRepository
+}
+
+// NewRoutedAppointmentRepository creates an AppointmentRepository over every region.
+func NewRoutedAppointmentRepository(router *RegionRouter) (AppointmentRepository, error) {
+	repos, err := perRegion(router, NewPostgresAppointmentRepository)
+	if err != nil {
+		return nil, err
+	}
+	return &routedAppointmentRepository{router: router, repos: repos}, nil
+}
+
+func (r *routedAppointmentRepository) CreateSlots(ctx context.Context, providerID uuid.UUID, slots []models.AppointmentSlot) error {
+	repo, _, err := forUser(ctx, r.router, r.repos, providerID)
+	if err != nil {
+		return err
+	}
+	return repo.CreateSlots(ctx, providerID, slots)
+}
+
+func (r *routedAppointmentRepository) ListSlots(ctx context.Context, filter models.SlotFilter) ([]models.AppointmentSlot, error) {
+	repo, _, err := forUser(ctx, r.router, r.repos, filter.ProviderID)
+	if err != nil {
+		return nil, err
+	}
+	return repo.ListSlots(ctx, filter)
+}
+
+// GetSlot tries every region, since the slot ID does not say whose it is.
+func (r *routedAppointmentRepository) GetSlot(ctx context.Context, id uuid.UUID) (*models.AppointmentSlot, error) {
+	for _, region := range r.router.regions {
+		slot, err := r.repos[region].GetSlot(ctx, id)
+		if err != nil || slot != nil {
+			return slot, err
+		}
+	}
+	return nil, nil
+}
+
+func (r *routedAppointmentRepository) DeleteSlot(ctx context.Context, providerID, id uuid.UUID) (bool, error) {
+	repo, _, err := forUser(ctx, r.router, r.repos, providerID)
+	if err != nil {
+		return false, err
+	}
+	return repo.DeleteSlot(ctx, providerID, id)
+}
+
+func (r *routedAppointmentRepository) BookSlot(ctx context.Context, appointment *models.Appointment) error {
+	repo, _, err := forUser(ctx, r.router, r.repos, appointment.ProviderID)
+	if err != nil {
+		return err
+	}
+	return repo.BookSlot(ctx, appointment)
+}
+
+// GetAppointment tries every region, since the appointment ID does not say whose it is.
+func (r *routedAppointmentRepository) GetAppointment(ctx context.Context, id uuid.UUID) (*models.Appointment, error) {
+	for _, region := range r.router.regions {
+		appointment, err := r.repos[region].GetAppointment(ctx, id)
+		if err != nil || appointment != nil {
+			return appointment, err
+		}
+	}
+	return nil, nil
+}
+
+// ListAppointments queries the provider's region when the filter names one, and otherwise
+// collects matches from every region, earliest first.
+func (r *routedAppointmentRepository) ListAppointments(ctx context.Context, filter models.AppointmentFilter) ([]models.Appointment, error) {
+	if filter.ProviderID != uuid.Nil {
+		repo, _, err := forUser(ctx, r.router, r.repos, filter.ProviderID)
+		if err != nil {
+			return nil, err
+		}
+		return repo.ListAppointments(ctx, filter)
+	}
+	all := []models.Appointment{}
+	for _, region := range r.router.regions {
+		appointments, err := r.repos[region].ListAppointments(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, appointments...)
+	}
+	slices.SortFunc(all, func(a, b models.Appointment) int { return a.StartsAt.Compare(b.StartsAt) })
+	return all, nil
+}
+
+func (r *routedAppointmentRepository) CancelAppointment(ctx context.Context, appointment *models.Appointment) (bool, error) {
+	repo, _, err := forUser(ctx, r.router, r.repos, appointment.ProviderID)
+	if err != nil {
+		return false, err
+	}
+	return repo.CancelAppointment(ctx, appointment)
+}
+
+func (r *routedAppointmentRepository) RescheduleAppointment(ctx context.Context, old, replacement *models.Appointment) error {
+	repo, _, err := forUser(ctx, r.router, r.repos, old.ProviderID)
+	if err != nil {
+		return err
+	}
+	return repo.RescheduleAppointment(ctx, old, replacement)
+}
+
+// ListDueReminders collects due reminders from every region, earliest first.
+func (r *routedAppointmentRepository) ListDueReminders(ctx context.Context, before time.Time) ([]models.Appointment, error) {
+	all := []models.Appointment{}
+	for _, region := range r.router.regions {
+		appointments, err := r.repos[region].ListDueReminders(ctx, before)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, appointments...)
+	}
+	slices.SortFunc(all, func(a, b models.Appointment) int { return a.StartsAt.Compare(b.StartsAt) })
+	return all, nil
+}
+
+func (r *routedAppointmentRepository) MarkReminderSent(ctx context.Context, providerID, id uuid.UUID, at time.Time) error {
+	repo, _, err := forUser(ctx, r.router, r.repos, providerID)
+	if err != nil {
+		return err
+	}
+	return repo.MarkReminderSent(ctx, providerID, id, at)
+}
+
+func (r *routedAppointmentRepository) Migrate() error {
+	for _, repo := range r.repos {
+		if err := repo.Migrate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// routedWorkoutAttachmentRepository routes WorkoutAttachmentRepository calls to the region of the user.
+type routedWorkoutAttachmentRepository struct {
+	router *RegionRouter
+	repos  map[string]WorkoutAttachmentRepository
+}
+
+// NewRoutedWorkoutAttachmentRepository creates a WorkoutAttachmentRepository over every region.
+func NewRoutedWorkoutAttachmentRepository(router *RegionRouter) (WorkoutAttachmentRepository, error) {
+	repos, err := perRegion(router, NewPostgresWorkoutAttachmentRepository)
+	if err != nil {
+		return nil, err
+	}
+	return &routedWorkoutAttachmentRepository{router: router, repos: repos}, nil
+}
+
+func (r *routedWorkoutAttachmentRepository) CreateAttachment(ctx context.Context, attachment *models.WorkoutAttachment) error {
+	repo, _, err := forUser(ctx, r.router, r.repos, attachment.UserID)
+	if err != nil {
+		return err
+	}
+	return repo.CreateAttachment(ctx, attachment)
+}
+
+func (r *routedWorkoutAttachmentRepository) GetAttachment(ctx context.Context, userID, id uuid.UUID) (*models.WorkoutAttachment, error) {
+	repo, _, err := forUser(ctx, r.router, r.repos, userID)
+	if err != nil {
+		return nil, err
+	}
+	return repo.GetAttachment(ctx, userID, id)
+}
+
+func (r *routedWorkoutAttachmentRepository) ListAttachments(ctx context.Context, userID uuid.UUID, setRef string, sharedOnly bool) ([]models.WorkoutAttachment, error) {
+	repo, _, err := forUser(ctx, r.router, r.repos, userID)
+	if err != nil {
+		return nil, err
+	}
+	return repo.ListAttachments(ctx, userID, setRef, sharedOnly)
+}
+
+func (r *routedWorkoutAttachmentRepository) UpdateAttachment(ctx context.Context, attachment *models.WorkoutAttachment) (bool, error) {
+	repo, _, err := forUser(ctx, r.router, r.repos, attachment.UserID)
+	if err != nil {
+		return false, err
+	}
+	return repo.UpdateAttachment(ctx, attachment)
+}
+
+func (r *routedWorkoutAttachmentRepository) DeleteAttachment(ctx context.Context, userID, id uuid.UUID) (string, error) {
+	repo, _, err := forUser(ctx, r.router, r.repos, userID)
+	if err != nil {
+		return "", err
+	}
+	return repo.DeleteAttachment(ctx, userID, id)
+}
+
+// ListPendingScans collects pending attachments from every region, oldest first, up to limit.
+func (r *routedWorkoutAttachmentRepository) ListPendingScans(ctx context.Context, limit int) ([]models.WorkoutAttachment, error) {
+	all := []models.WorkoutAttachment{}
+	for _, region := range r.router.regions {
+		attachments, err := r.repos[region].ListPendingScans(ctx, limit)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, attachments...)
+	}
+	slices.SortFunc(all, func(a, b models.WorkoutAttachment) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	if len(all) > limit {
+		all = all[:limit]
+	}
+	return all, nil
+}
+
+func (r *routedWorkoutAttachmentRepository) SetScanStatus(ctx context.Context, userID, id uuid.UUID, status string, at time.Time) error {
+	repo, _, err := forUser(ctx, r.router, r.repos, userID)
+	if err != nil {
+		return err
+	}
+	return repo.SetScanStatus(ctx, userID, id, status, at)
+}
+
+// PurgeExpired purges every region, returning the blob keys deleted so far if one fails.
+func (r *routedWorkoutAttachmentRepository) PurgeExpired(ctx context.Context, before time.Time) ([]string, error) {
+	keys := []string{}
+	for _, region := range r.router.regions {
+		regionKeys, err := r.repos[region].PurgeExpired(ctx, before)
+		if err != nil {
+			return keys, err
+		}
+		keys = append(keys, regionKeys...)
+	}
+	return keys, nil
+}
+
+func (r *routedWorkoutAttachmentRepository) Migrate() error {
+	for _, repo := range r.repos {
+		if err := repo.Migrate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// routedWorkoutPlanRepository routes WorkoutPlanRepository calls to the region of the coach.
+type routedWorkoutPlanRepository struct {
+	router *RegionRouter
+	repos  map[string]WorkoutPlanRepository
+}
+
+// NewRoutedWorkoutPlanRepository creates a WorkoutPlanRepository over every region.
+func NewRoutedWorkoutPlanRepository(router *RegionRouter) (WorkoutPlanRepository, error) {
+	repos, err := perRegion(router, NewPostgresWorkoutPlanRepository)
+	if err != nil {
+		return nil, err
+	}
+	return &routedWorkoutPlanRepository{router: router, repos: repos}, nil
+}
+
+func (r *routedWorkoutPlanRepository) SavePlans(ctx context.Context, coachID uuid.UUID, plans []models.WorkoutPlan) error {
+	repo, _, err := forUser(ctx, r.router, r.repos, coachID)
+	if err != nil {
+		return err
+	}
+	return repo.SavePlans(ctx, coachID, plans)
+}
+
+func (r *routedWorkoutPlanRepository) GetPlan(ctx context.Context, coachID, id uuid.UUID) (*models.WorkoutPlan, error) {
+	repo, _, err := forUser(ctx, r.router, r.repos, coachID)
+	if err != nil {
+		return nil, err
+	}
+	return repo.GetPlan(ctx, coachID, id)
+}
+
+func (r *routedWorkoutPlanRepository) ListPlans(ctx context.Context, coachID uuid.UUID) ([]models.WorkoutPlan, error) {
+	repo, _, err := forUser(ctx, r.router, r.repos, coachID)
+	if err != nil {
+		return nil, err
+	}
+	return repo.ListPlans(ctx, coachID)
+}
+
+func (r *routedWorkoutPlanRepository) DeletePlan(ctx context.Context, coachID, id uuid.UUID) (bool, error) {
+	repo, _, err := forUser(ctx, r.router, r.repos, coachID)
+	if err != nil {
+		return false, err
+	}
+	return repo.DeletePlan(ctx, coachID, id)
+}
+
+func (r *routedWorkoutPlanRepository) Migrate() error {
+	for _, repo := range r.repos {
+		if err := repo.Migrate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// routedOutboxRepository gathers the outboxes of every region. Each region's user repository writes
+// its events to its own outbox, so they are claimed from every region, and marked in each of them:
+// an event's ID is only found in the region that holds it.
+type routedOutboxRepository struct {
+	router *RegionRouter
+	repos  map[string]OutboxRepository
+}
+
+// NewRoutedOutboxRepository creates an OutboxRepository over every region.
+func NewRoutedOutboxRepository(router *RegionRouter) (OutboxRepository, error) {
+	repos, err := perRegion(router, NewPostgresOutboxRepository)
+	if err != nil {
+		return nil, err
+	}
+	return &routedOutboxRepository{router: router, repos: repos}, nil
+}
+
+// ClaimOutboxEvents claims up to limit events in all, from the home region first.
+func (r *routedOutboxRepository) ClaimOutboxEvents(ctx context.Context, now, leaseUntil time.Time, limit int) ([]models.OutboxEvent, error) {
+	claimed := []models.OutboxEvent{}
+	for _, region := range r.router.regions {
+		if len(claimed) >= limit {
+			break
+		}
+		events, err := r.repos[region].ClaimOutboxEvents(ctx, now, leaseUntil, limit-len(claimed))
+		if err != nil {
+			// What was claimed so far is published; the rest waits for the next pass.
+			if len(claimed) > 0 {
+				logger.FromContext(ctx).Warnf("Failed to claim outbox events in region %s: %v", region, err)
+				break
+			}
+			return nil, err
+		}
+		claimed = append(claimed, events...)
+	}
+	return claimed, nil
+}
+
+func (r *routedOutboxRepository) MarkOutboxEventSent(ctx context.Context, id uuid.UUID, at time.Time) error {
+	for _, region := range r.router.regions {
+		if err := r.repos[region].MarkOutboxEventSent(ctx, id, at); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *routedOutboxRepository) FailOutboxEvent(ctx context.Context, id uuid.UUID, retryAt time.Time, lastError string) error {
+	for _, region := range r.router.regions {
+		if err := r.repos[region].FailOutboxEvent(ctx, id, retryAt, lastError); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *routedOutboxRepository) ReleaseOutboxEvents(ctx context.Context, ids []uuid.UUID, at time.Time) error {
+	for _, region := range r.router.regions {
+		if err := r.repos[region].ReleaseOutboxEvents(ctx, ids, at); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *routedOutboxRepository) PurgeSentOutboxEvents(ctx context.Context, before time.Time) (int64, error) {
+	var total int64
+	for _, region := range r.router.regions {
+		n, err := r.repos[region].PurgeSentOutboxEvents(ctx, before)
+		if err != nil {
+			return total, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+func (r *routedOutboxRepository) Migrate() error {
+	for _, repo := range r.repos {
+		if err := repo.Migrate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
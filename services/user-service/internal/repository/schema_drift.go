@@ -0,0 +1,93 @@
+// services/user-service/internal/repository/schema_drift.go
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// SchemaDrift compares the live schema of db with the schema its migrations build, to find changes made
+// by hand. migrate runs the migrations on the pool it is given, like MigrateSchema; it gets
+// a pool on the same database (dataSourceName) whose session builds the schema from nothing in temporary
+// tables and rolls it back. SchemaDrift returns one line per table, column, index, constraint, trigger, or
+// function that differs. Tables the migrations do not build are not compared, as they may belong to
+// other services.
+func SchemaDrift(db *sql.DB, dataSourceName, applicationName string, migrate func(*sql.DB) error) ([]string, error) {
+	_, scratch, err := openMigrationPool(dataSourceName, applicationName, true)
+	if err != nil {
+		return nil, err
+	}
+	defer scratch.Close()
+	if err := migrate(scratch); err != nil {
+		return nil, fmt.Errorf("repository: failed to build the expected schema: %w", err)
+	}
+
+	expectedSchema, expected, err := snapshotPool(scratch)
+	if err != nil {
+		return nil, err
+	}
+	liveSchema, live, err := snapshotPool(db)
+	if err != nil {
+		return nil, err
+	}
+	// Definitions name objects with their schema; functions the expected schema uses are the live ones.
+	normalize := strings.NewReplacer(expectedSchema+".", "", liveSchema+".", "")
+	for key, definition := range expected {
+		expected[key] = normalize.Replace(definition)
+	}
+	normalize = strings.NewReplacer(liveSchema+".", "")
+	for key, definition := range live {
+		live[key] = normalize.Replace(definition)
+	}
+
+	var drift []string
+	for key, want := range expected {
+		got, ok := live[key]
+		switch {
+		case !ok && want == "":
+			drift = append(drift, key+" is missing")
+		case !ok:
+			drift = append(drift, fmt.Sprintf("%s is missing; migrations define %s", key, want))
+		case got != want:
+			drift = append(drift, fmt.Sprintf("%s is %s; migrations define %s", key, got, want))
+		}
+	}
+	for key, got := range live {
+		if _, ok := expected[key]; ok {
+			continue
+		}
+		kind, name, _ := strings.Cut(key, " ")
+		table, _, _ := strings.Cut(name, ".")
+		if _, ours := expected["table "+table]; kind == "table" || kind == "function" || !ours {
+			continue // Possibly another service's
+		}
+		drift = append(drift, fmt.Sprintf("%s is not defined by any migration: %s", key, got))
+	}
+	slices.Sort(drift)
+	return drift, nil
+}
+
+// snapshotPool takes a snapshot of the current schema on one of the pool's connections.
+func snapshotPool(db *sql.DB) (string, map[string]string, error) {
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return "", nil, fmt.Errorf("repository: failed to get a connection: %w", err)
+	}
+	defer conn.Close()
+	var schema string
+	var objects map[string]string
+	err = conn.Raw(func(driverConn any) error {
+		q, ok := driverConn.(driver.QueryerContext)
+		if !ok {
+			return fmt.Errorf("repository: the database driver cannot read the schema")
+		}
+		schema, objects, err = snapshotSchema(ctx, q)
+		return err
+	})
+	return schema, objects, err
+}
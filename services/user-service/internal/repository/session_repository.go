@@ -0,0 +1,132 @@
+// services/user-service/internal/repository/session_repository.go
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+)
+
+// postgresSessionRepository is the PostgreSQL implementation of SessionRepository.
+type postgresSessionRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresSessionRepository creates a SessionRepository on an open pool and runs its migrations.
+// The users table must already exist.
+func NewPostgresSessionRepository(db *sql.DB) (SessionRepository, error) {
+	repo := &postgresSessionRepository{db: db}
+	if err := repo.Migrate(); err != nil {
+		return nil, fmt.Errorf("failed to run session migrations: %w", err)
+	}
+	return repo, nil
+}
+
+// Migrate applies the pending migrations in migrations/sessions.
+func (r *postgresSessionRepository) Migrate() error {
+	return MigrateSchema(r.db, "sessions")
+}
+
+// CreateSession inserts a session and, when maxPerUser is positive, deletes the user's oldest unexpired
+// sessions beyond that many. It returns the IDs of the sessions evicted.
+func (r *postgresSessionRepository) CreateSession(ctx context.Context, session *models.Session, maxPerUser int) ([]uuid.UUID, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Serializes concurrent sign-ins of the same user, so the limit holds under races.
+	if _, err := tx.ExecContext(ctx, `SELECT 1 FROM users WHERE id = $1 FOR UPDATE`, session.UserID); err != nil {
+		return nil, fmt.Errorf("repository: failed to lock user for session: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO sessions (id, user_id, ip, user_agent, created_at, expires_at) VALUES ($1, $2, $3, $4, $5, $6)`,
+		session.ID, session.UserID, session.IP, session.UserAgent, session.CreatedAt, session.ExpiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to create session: %w", err)
+	}
+
+	var evicted []uuid.UUID
+	if maxPerUser > 0 {
+		rows, err := tx.QueryContext(ctx, `DELETE FROM sessions WHERE id IN (
+			SELECT id FROM sessions WHERE user_id = $1 AND expires_at > NOW() ORDER BY created_at DESC, id OFFSET $2)
+			RETURNING id`,
+			session.UserID, maxPerUser)
+		if err != nil {
+			return nil, fmt.Errorf("repository: failed to evict sessions: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var id uuid.UUID
+			if err := rows.Scan(&id); err != nil {
+				return nil, fmt.Errorf("repository: failed to evict sessions: %w", err)
+			}
+			evicted = append(evicted, id)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("repository: failed to evict sessions: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("repository: failed to commit session: %w", err)
+	}
+	return evicted, nil
+}
+
+// GetSession returns an unexpired session of a user by ID, or nil if it does not exist or has expired.
+func (r *postgresSessionRepository) GetSession(ctx context.Context, userID, id uuid.UUID) (*models.Session, error) {
+	query := `SELECT id, user_id, ip, user_agent, created_at, expires_at FROM sessions WHERE id = $1 AND user_id = $2 AND expires_at > NOW()`
+	var s models.Session
+	err := r.db.QueryRowContext(ctx, query, id, userID).Scan(&s.ID, &s.UserID, &s.IP, &s.UserAgent, &s.CreatedAt, &s.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to get session: %w", err)
+	}
+	return &s, nil
+}
+
+// DeleteSession removes one session of a user, e.g. on logout.
+func (r *postgresSessionRepository) DeleteSession(ctx context.Context, userID, id uuid.UUID) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM sessions WHERE id = $1 AND user_id = $2`, id, userID); err != nil {
+		return fmt.Errorf("repository: failed to delete session: %w", err)
+	}
+	return nil
+}
+
+// DeleteUserSessions removes every session of a user.
+func (r *postgresSessionRepository) DeleteUserSessions(ctx context.Context, userID uuid.UUID) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM sessions WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("repository: failed to delete user sessions: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpiredSessions removes sessions that expired before the given time and returns how many were removed.
+func (r *postgresSessionRepository) DeleteExpiredSessions(ctx context.Context, before time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM sessions WHERE expires_at <= $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("repository: failed to delete expired sessions: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("repository: failed to delete expired sessions: %w", err)
+	}
+	return n, nil
+}
+
+// CountActiveSessions returns the number of unexpired sessions and of users holding at least one.
+func (r *postgresSessionRepository) CountActiveSessions(ctx context.Context) (int64, int64, error) {
+	var sessions, users int64
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*), COUNT(DISTINCT user_id) FROM sessions WHERE expires_at > NOW()`).Scan(&sessions, &users)
+	if err != nil {
+		return 0, 0, fmt.Errorf("repository: failed to count sessions: %w", err)
+	}
+	return sessions, users, nil
+}
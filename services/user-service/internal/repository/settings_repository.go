@@ -0,0 +1,67 @@
+// services/user-service/internal/repository/settings_repository.go
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// postgresSettingsRepository is the PostgreSQL implementation of SettingsRepository.
+type postgresSettingsRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresSettingsRepository creates a SettingsRepository on an open pool and runs its migrations.
+// The users table must already exist.
+func NewPostgresSettingsRepository(db *sql.DB) (SettingsRepository, error) {
+	repo := &postgresSettingsRepository{db: db}
+	if err := repo.Migrate(); err != nil {
+		return nil, fmt.Errorf("failed to run settings migrations: %w", err)
+	}
+	return repo, nil
+}
+
+// Migrate applies the pending migrations in migrations/user_settings.
+func (r *postgresSettingsRepository) Migrate() error {
+	return MigrateSchema(r.db, "user_settings")
+}
+
+// GetSettings retrieves the values a user has set. It returns nil, nil if the user has never saved settings.
+func (r *postgresSettingsRepository) GetSettings(ctx context.Context, userID uuid.UUID) (*models.UserSettings, error) {
+	var settings []byte
+	var updatedAt time.Time
+	err := r.db.QueryRowContext(ctx, `SELECT settings, updated_at FROM user_settings WHERE user_id = $1`, userID).Scan(&settings, &updatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("repository: failed to get user settings: %w", err)
+	}
+	stored := &models.UserSettings{UpdatedAt: &updatedAt}
+	if err := json.Unmarshal(settings, &stored.Settings); err != nil {
+		return nil, fmt.Errorf("repository: failed to decode user settings: %w", err)
+	}
+	return stored, nil
+}
+
+// SaveSettings creates or replaces the values a user has set.
+func (r *postgresSettingsRepository) SaveSettings(ctx context.Context, userID uuid.UUID, settings *models.UserSettings) error {
+	values, err := json.Marshal(settings.Settings)
+	if err != nil {
+		return fmt.Errorf("repository: failed to encode user settings: %w", err)
+	}
+	query := `INSERT INTO user_settings (user_id, settings, updated_at) VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE SET settings = EXCLUDED.settings, updated_at = EXCLUDED.updated_at`
+	if _, err := r.db.ExecContext(ctx, query, userID, values, settings.UpdatedAt); err != nil {
+		return fmt.Errorf("repository: failed to save user settings: %w", err)
+	}
+	logger.FromContext(ctx).Debugf("Settings saved for user %s", userID)
+	return nil
+}
@@ -0,0 +1,44 @@
+// services/user-service/internal/repository/storage_usage.go
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// StorageUsage returns the bytes each user's rows take up in this database, across the users table
+// and the tables of the user-owned repositories sharing it. Sizes are Postgres datum sizes, without
+// index or page overhead, so they are stable for billing.
+func (r *postgresUserRepository) StorageUsage(ctx context.Context) (map[uuid.UUID]int64, error) {
+	query := `
+	SELECT u.id, pg_column_size(u.*)::bigint
+		+ COALESCE((SELECT SUM(pg_column_size(t.*)) FROM user_timezone_history t WHERE t.user_id = u.id), 0)::bigint
+		+ COALESCE((SELECT SUM(pg_column_size(p.*)) FROM profile_prompt_dismissals p WHERE p.user_id = u.id), 0)::bigint
+		+ COALESCE((SELECT SUM(pg_column_size(a.*)) FROM aggregation_periods a WHERE a.user_id = u.id), 0)::bigint
+		+ COALESCE((SELECT SUM(pg_column_size(e.*)) FROM user_events e WHERE e.user_id = u.id), 0)::bigint
+		+ COALESCE((SELECT SUM(pg_column_size(d.*)) FROM dashboard_layouts d WHERE d.user_id = u.id), 0)::bigint
+		+ COALESCE((SELECT SUM(pg_column_size(s.*)) FROM user_settings s WHERE s.user_id = u.id), 0)::bigint
+		+ COALESCE((SELECT SUM(pg_column_size(l.*)) FROM login_attempts l WHERE l.user_id = u.id), 0)::bigint
+	FROM users u`
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to measure storage usage: %w", err)
+	}
+	defer rows.Close()
+
+	usage := map[uuid.UUID]int64{}
+	for rows.Next() {
+		var id uuid.UUID
+		var bytes int64
+		if err := rows.Scan(&id, &bytes); err != nil {
+			return nil, fmt.Errorf("repository: failed to scan storage usage: %w", err)
+		}
+		usage[id] = bytes
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: failed to measure storage usage: %w", err)
+	}
+	return usage, nil
+}
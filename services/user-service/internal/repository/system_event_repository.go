@@ -0,0 +1,91 @@
+// services/user-service/internal/repository/system_event_repository.go
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// postgresSystemEventRepository is the PostgreSQL implementation of SystemEventRepository.
+type postgresSystemEventRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresSystemEventRepository creates a SystemEventRepository on an open pool and runs its migrations.
+func NewPostgresSystemEventRepository(db *sql.DB) (SystemEventRepository, error) {
+	repo := &postgresSystemEventRepository{db: db}
+	if err := repo.Migrate(); err != nil {
+		return nil, fmt.Errorf("failed to run system event migrations: %w", err)
+	}
+	return repo, nil
+}
+
+// Migrate applies the pending migrations in migrations/system_events.
+func (r *postgresSystemEventRepository) Migrate() error {
+	return MigrateSchema(r.db, "system_events")
+}
+
+// CreateEvent inserts a new system event.
+func (r *postgresSystemEventRepository) CreateEvent(ctx context.Context, event *models.SystemEvent) error {
+	query := `INSERT INTO system_events (id, type, message, actor, starts_at, ends_at, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	_, err := r.db.ExecContext(ctx, query, event.ID, event.Type, event.Message, event.Actor, event.StartsAt, event.EndsAt, event.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("repository: failed to create system event: %w", err)
+	}
+	logger.FromContext(ctx).Debugf("System event recorded: %s (%s)", event.ID, event.Type)
+	return nil
+}
+
+// ListEvents returns events matching the filter, newest first.
+func (r *postgresSystemEventRepository) ListEvents(ctx context.Context, filter models.SystemEventFilter) ([]models.SystemEvent, error) {
+	var conditions []string
+	var args []interface{}
+	if filter.Type != "" {
+		args = append(args, filter.Type)
+		conditions = append(conditions, fmt.Sprintf("type = $%d", len(args)))
+	}
+	if !filter.Since.IsZero() {
+		args = append(args, filter.Since)
+		conditions = append(conditions, fmt.Sprintf("starts_at >= $%d", len(args)))
+	}
+	if !filter.Until.IsZero() {
+		args = append(args, filter.Until)
+		conditions = append(conditions, fmt.Sprintf("starts_at <= $%d", len(args)))
+	}
+	if filter.After != nil {
+		args = append(args, filter.After.At, filter.After.ID)
+		conditions = append(conditions, fmt.Sprintf("(starts_at, id) < ($%d, $%d)", len(args)-1, len(args)))
+	}
+
+	query := `SELECT id, type, message, actor, starts_at, ends_at, created_at FROM system_events`
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(` ORDER BY starts_at DESC, id DESC LIMIT $%d`, len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to list system events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []models.SystemEvent{}
+	for rows.Next() {
+		var e models.SystemEvent
+		if err := rows.Scan(&e.ID, &e.Type, &e.Message, &e.Actor, &e.StartsAt, &e.EndsAt, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("repository: failed to scan system event row: %w", err)
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: rows iteration error: %w", err)
+	}
+	logger.FromContext(ctx).Debugf("Retrieved %d system events from DB.", len(events))
+	return events, nil
+}
@@ -0,0 +1,97 @@
+// services/user-service/internal/repository/user_event_repository.go
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// postgresUserEventRepository is the PostgreSQL implementation of UserEventRepository.
+type postgresUserEventRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresUserEventRepository creates a UserEventRepository on an open pool and runs its migrations.
+// The users table must already exist.
+func NewPostgresUserEventRepository(db *sql.DB) (UserEventRepository, error) {
+	repo := &postgresUserEventRepository{db: db}
+	if err := repo.Migrate(); err != nil {
+		return nil, fmt.Errorf("failed to run user event migrations: %w", err)
+	}
+	return repo, nil
+}
+
+// Migrate applies the pending migrations in migrations/user_events.
+func (r *postgresUserEventRepository) Migrate() error {
+	return MigrateSchema(r.db, "user_events")
+}
+
+// CreateEvent inserts a new user event.
+func (r *postgresUserEventRepository) CreateEvent(ctx context.Context, event *models.UserEvent) error {
+	var details []byte
+	if len(event.Details) > 0 {
+		var err error
+		if details, err = json.Marshal(event.Details); err != nil {
+			return fmt.Errorf("repository: failed to encode user event details: %w", err)
+		}
+	}
+	query := `INSERT INTO user_events (id, user_id, type, summary, details, occurred_at) VALUES ($1, $2, $3, $4, $5, $6)`
+	if _, err := r.db.ExecContext(ctx, query, event.ID, event.UserID, event.Type, event.Summary, details, event.OccurredAt); err != nil {
+		return fmt.Errorf("repository: failed to create user event: %w", err)
+	}
+	logger.FromContext(ctx).Debugf("User event recorded: %s (%s) for user %s", event.ID, event.Type, event.UserID)
+	return nil
+}
+
+// ListEvents returns one user's events matching the filter, newest first, or oldest first with Since.
+func (r *postgresUserEventRepository) ListEvents(ctx context.Context, filter models.UserEventFilter) ([]models.UserEvent, error) {
+	args := []interface{}{filter.UserID}
+	query := `SELECT id, user_id, type, summary, details, occurred_at FROM user_events WHERE user_id = $1`
+	if len(filter.Types) > 0 {
+		args = append(args, filter.Types)
+		query += fmt.Sprintf(` AND type = ANY($%d)`, len(args))
+	}
+	if filter.After != nil {
+		args = append(args, filter.After.At, filter.After.ID)
+		query += fmt.Sprintf(` AND (occurred_at, id) < ($%d, $%d)`, len(args)-1, len(args))
+	}
+	order := `DESC`
+	if filter.Since != nil {
+		args = append(args, filter.Since.At, filter.Since.ID)
+		query += fmt.Sprintf(` AND (occurred_at, id) > ($%d, $%d)`, len(args)-1, len(args))
+		order = `ASC`
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(` ORDER BY occurred_at %s, id %s LIMIT $%d`, order, order, len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to list user events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []models.UserEvent{}
+	for rows.Next() {
+		var e models.UserEvent
+		var details []byte
+		if err := rows.Scan(&e.ID, &e.UserID, &e.Type, &e.Summary, &details, &e.OccurredAt); err != nil {
+			return nil, fmt.Errorf("repository: failed to scan user event row: %w", err)
+		}
+		if len(details) > 0 {
+			if err := json.Unmarshal(details, &e.Details); err != nil {
+				return nil, fmt.Errorf("repository: failed to decode user event details: %w", err)
+			}
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: rows iteration error: %w", err)
+	}
+	logger.FromContext(ctx).Debugf("Retrieved %d user events for user %s from DB.", len(events), filter.UserID)
+	return events, nil
+}
@@ -0,0 +1,155 @@
+// services/user-service/internal/repository/user_listing_repository.go
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+)
+
+// userFilterClauses returns the conditions of a user filter, except paging, and their arguments.
+func userFilterClauses(filter models.UserFilter) (string, []interface{}) {
+	var args []interface{}
+	clauses := ""
+	where := func(clause string, value interface{}) {
+		args = append(args, value)
+		clauses += fmt.Sprintf(clause, len(args))
+	}
+	if filter.Role != "" {
+		where(` AND role = $%d`, filter.Role)
+	}
+	if filter.Status != "" {
+		where(` AND status = $%d`, filter.Status)
+	}
+	if filter.Verified != nil {
+		where(` AND (email_verified_at IS NOT NULL) = $%d`, *filter.Verified)
+	}
+	if !filter.CreatedSince.IsZero() {
+		where(` AND created_at >= $%d`, filter.CreatedSince)
+	}
+	if !filter.CreatedUntil.IsZero() {
+		where(` AND created_at < $%d`, filter.CreatedUntil)
+	}
+	return clauses, args
+}
+
+// allUsersQuery returns the query, with PostgreSQL placeholders, and arguments of a page of all users,
+// oldest first, after the page key.
+func allUsersQuery(after *models.PageKey, limit int) (string, []interface{}) {
+	var args []interface{}
+	query := `SELECT ` + userColumns + ` FROM users`
+	if after != nil {
+		args = append(args, after.At, after.ID)
+		query += ` WHERE (created_at, id) > ($1, $2)`
+	}
+	args = append(args, limit)
+	query += fmt.Sprintf(` ORDER BY created_at, id LIMIT $%d`, len(args))
+	return query, args
+}
+
+// ListUsers returns the users matching filter, newest first, up to filter.Limit.
+func (r *postgresUserRepository) ListUsers(ctx context.Context, filter models.UserFilter) ([]models.User, error) {
+	clauses, args := userFilterClauses(filter)
+	query := `SELECT ` + userColumns + ` FROM users WHERE TRUE` + clauses
+	if filter.After != nil {
+		args = append(args, filter.After.At, filter.After.ID)
+		query += fmt.Sprintf(` AND (created_at, id) < ($%d, $%d)`, len(args)-1, len(args))
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(` ORDER BY created_at DESC, id DESC LIMIT $%d`, len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to list users: %w", err)
+	}
+	defer rows.Close()
+
+	users := []models.User{}
+	for rows.Next() {
+		var user models.User
+		if err := scanUser(rows, &user); err != nil {
+			return nil, fmt.Errorf("repository: failed to scan user row: %w", err)
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: rows iteration error: %w", err)
+	}
+	return users, nil
+}
+
+// userSearchText is the text SearchUsers matches a query against. It must stay the expression of the
+// idx_users_search index (migrations/users/0010_user_search), or searches scan the table.
+const userSearchText = `lower(name || ' ' || COALESCE(username, '') || ' ' || email)`
+
+// SearchUsers returns up to limit users whose name, username, or email is similar to the query, best
+// match first. It uses pg_trgm's word similarity: the share of the query's trigrams found in one stretch
+// of the text, so a query matches part of a name, and a typo or two still match. Users below the
+// pg_trgm.word_similarity_threshold setting (0.6 by default) are left out.
+func (r *postgresUserRepository) SearchUsers(ctx context.Context, query string, limit int) ([]models.UserMatch, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+userColumns+`, word_similarity($1, `+userSearchText+`) AS score
+		FROM users WHERE $1 <% `+userSearchText+`
+		ORDER BY score DESC, created_at, id LIMIT $2`, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to search users: %w", err)
+	}
+	defer rows.Close()
+
+	matches := []models.UserMatch{}
+	for rows.Next() {
+		var m models.UserMatch
+		if err := scanUser(scoredRow{rows, &m.Score}, &m.User); err != nil {
+			return nil, fmt.Errorf("repository: failed to scan user row: %w", err)
+		}
+		matches = append(matches, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: rows iteration error: %w", err)
+	}
+	return matches, nil
+}
+
+// scoredRow scans a row selected with userColumns followed by a score.
+type scoredRow struct {
+	rowScanner
+	score *float64
+}
+
+func (r scoredRow) Scan(dest ...interface{}) error {
+	return r.rowScanner.Scan(append(dest, r.score)...)
+}
+
+// likePattern returns a LIKE pattern, with ! as the escape character, matching text that contains s.
+func likePattern(s string) string {
+	return "%" + strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(s) + "%"
+}
+
+// CountUsers counts the users matching filter, ignoring its paging: all of them, those with a
+// successful sign-in since activeSince, and those whose email is not verified.
+func (r *postgresUserRepository) CountUsers(ctx context.Context, filter models.UserFilter, activeSince time.Time) (*models.UserCounts, error) {
+	clauses, args := userFilterClauses(filter)
+	args = append(args, activeSince)
+	query := fmt.Sprintf(`SELECT COUNT(*),
+		COUNT(*) FILTER (WHERE EXISTS (SELECT 1 FROM login_attempts a WHERE a.user_id = users.id AND a.success AND a.created_at >= $%d)),
+		COUNT(*) FILTER (WHERE email_verified_at IS NULL)
+		FROM users WHERE TRUE`, len(args)) + clauses
+
+	counts := &models.UserCounts{}
+	if err := r.db.QueryRowContext(ctx, query, args...).Scan(&counts.Total, &counts.ActiveLast30Days, &counts.Unverified); err != nil {
+		return nil, fmt.Errorf("repository: failed to count users: %w", err)
+	}
+	return counts, nil
+}
+
+// MarkEmailVerified records that a user proved they receive mail at their current email. An earlier
+// verification of the same email is kept.
+func (r *postgresUserRepository) MarkEmailVerified(ctx context.Context, userID uuid.UUID, at time.Time) error {
+	if _, err := r.db.ExecContext(ctx, `UPDATE users SET email_verified_at = $2 WHERE id = $1 AND email_verified_at IS NULL`, userID, at); err != nil {
+		return fmt.Errorf("repository: failed to mark email verified: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+// services/user-service/internal/repository/user_metadata_repository.go
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+)
+
+// GetUserMetadata returns a user's metadata, or nil if the user does not exist.
+func (r *postgresUserRepository) GetUserMetadata(ctx context.Context, userID uuid.UUID) (models.UserMetadata, error) {
+	var raw []byte
+	if err := r.db.QueryRowContext(ctx, `SELECT metadata FROM users WHERE id = $1`, userID).Scan(&raw); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("repository: failed to get user metadata: %w", err)
+	}
+	return decodeUserMetadata(raw)
+}
+
+// MergeUserMetadata sets the keys in set, removes the keys in remove, and leaves the others as they
+// are, in one statement, so concurrent updates of different keys are all kept. The update is only
+// made if the merged metadata stays within maxBytes of JSON text. It returns the merged metadata, or
+// nil if the user does not exist or the limit would be exceeded.
+func (r *postgresUserRepository) MergeUserMetadata(ctx context.Context, userID uuid.UUID, set models.UserMetadata, remove []string, maxBytes int) (models.UserMetadata, error) {
+	patch, err := json.Marshal(set)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to encode user metadata: %w", err)
+	}
+	query := `UPDATE users SET metadata = (metadata || $2::jsonb) - $3::text[]
+		WHERE id = $1 AND octet_length(((metadata || $2::jsonb) - $3::text[])::text) <= $4
+		RETURNING metadata`
+	var raw []byte
+	if err := r.db.QueryRowContext(ctx, query, userID, patch, remove, maxBytes).Scan(&raw); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("repository: failed to update user metadata: %w", err)
+	}
+	return decodeUserMetadata(raw)
+}
+
+func decodeUserMetadata(raw []byte) (models.UserMetadata, error) {
+	metadata := models.UserMetadata{}
+	if err := json.Unmarshal(raw, &metadata); err != nil {
+		return nil, fmt.Errorf("repository: failed to decode user metadata: %w", err)
+	}
+	return metadata, nil
+}
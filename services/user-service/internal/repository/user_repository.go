@@ -2,13 +2,14 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
-	_ "github.com/lib/pq" // PostgreSQL driver
 
+	"health-tracker-project/services/user-service/internal/eventbus"
 	"health-tracker-project/services/user-service/internal/models"
 	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
 )
@@ -18,104 +19,180 @@ type postgresUserRepository struct {
 	db *sql.DB // The standard Go SQL database connection pool
 }
 
-// NewPostgresUserRepository creates a new instance of PostgresUserRepository,
-// connects to the database, pings it, and runs migrations.
+// NewPostgresUserRepository creates a new instance of PostgresUserRepository
+// on an open connection pool and runs its migrations.
 // It returns the UserRepository interface, adhering to Dependency Inversion Principle.
-func NewPostgresUserRepository(dataSourceName string) (UserRepository, error) {
-	db, err := sql.Open("postgres", dataSourceName)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
-	}
-
-	// Ping the database to ensure connection is established
-	if err = db.Ping(); err != nil {
-		db.Close() // Close the connection if ping fails
-		return nil, fmt.Errorf("failed to connect to database: %w", err)
-	}
-
+func NewPostgresUserRepository(db *sql.DB) (UserRepository, error) {
 	repo := &postgresUserRepository{db: db}
 
 	// Run migrations (e.g., create tables if they don't exist)
 	if err := repo.Migrate(); err != nil {
-		db.Close() // Close the connection if migration fails
 		return nil, fmt.Errorf("failed to run database migrations: %w", err)
 	}
-
-	logger.Logger.Info("Connected to PostgreSQL database successfully!")
 	return repo, nil
 }
 
-// Migrate creates the 'users' table if it doesn't exist.
-// Column names use standard snake_case for PostgreSQL.
+// userColumns is the column list shared by every query that loads a full user row. Queries built from it
+// stay constant text, with values passed as parameters, so pgx prepares each once per connection.
+const userColumns = `id, name, email, COALESCE(username, ''), password_hash, role, timezone, week_start, units, status, height_cm, date_of_birth, created_at, updated_at, sessions_revoked_at, deletion_due_at, email_verified_at, email_status, health_sealed`
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanUser reads a row selected with userColumns into a User, opening their sealed health fields.
+func scanUser(row rowScanner, user *models.User) error {
+	var sealed sql.NullString
+	if err := row.Scan(&user.ID, &user.Name, &user.Email, &user.Username, &user.PasswordHash, &user.Role, &user.Timezone, &user.WeekStart, &user.Units, &user.Status, &user.HeightCM, &user.DateOfBirth, &user.CreatedAt, &user.UpdatedAt, &user.SessionsRevokedAt, &user.DeletionDueAt, &user.EmailVerifiedAt, &user.EmailStatus, &sealed); err != nil {
+		return err
+	}
+	return openHealth(user, sealed)
+}
+
+// Migrate applies the pending migrations in migrations/users, which hold the users table and the
+// tables that only exist alongside it, and in migrations/outbox, where user changes are announced.
 func (r *postgresUserRepository) Migrate() error {
-	query := `
-	CREATE TABLE IF NOT EXISTS users (
-		id UUID PRIMARY KEY,
-		name VARCHAR(255) NOT NULL,
-		email VARCHAR(255) UNIQUE NOT NULL, -- Email is unique and used for login
-		password_hash VARCHAR(255) NOT NULL, -- Storing the bcrypt hashed password
-		created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
-	);`
-	_, err := r.db.Exec(query)
-	if err != nil {
-		return fmt.Errorf("failed to migrate database: %w", err)
+	if err := MigrateSchema(r.db, "users"); err != nil {
+		return err
+	}
+	if err := MigrateSchema(r.db, "outbox"); err != nil {
+		return err
+	}
+	return r.warnSharedEmailKeys()
+}
+
+// warnSharedEmailKeys logs how many users have no email key, because their email shared a key with
+// another user's when keys were introduced (migrations/users/0002_email_keys). They still work, matched exactly.
+func (r *postgresUserRepository) warnSharedEmailKeys() error {
+	var shared int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM users WHERE email_key IS NULL`).Scan(&shared); err != nil {
+		return fmt.Errorf("failed to count users without an email key: %w", err)
+	}
+	if shared > 0 {
+		logger.Logger.Warnf("%d existing user(s) share a mailbox with another user and have no email key", shared)
 	}
-	logger.Logger.Info("Database migration completed successfully!")
 	return nil
 }
 
-// CreateUser inserts a new user into the database.
+// CreateUser inserts a new user into the database, and a user.created event into the outbox.
 // It assumes the user ID and timestamps are set by the models.NewUser constructor.
-func (r *postgresUserRepository) CreateUser(user *models.User) error {
+func (r *postgresUserRepository) CreateUser(ctx context.Context, user *models.User) error {
+	prepareNewUser(user)
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("repository: failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := insertUser(ctx, tx, user); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("repository: failed to commit user: %w", err)
+	}
+	logger.FromContext(ctx).Infof("User created successfully: %s", user.ID)
+	return nil
+}
+
+// insertUser inserts a user prepared by prepareNewUser in tx, seeds their timezone history, and writes a
+// user.created event to the outbox.
+func insertUser(ctx context.Context, tx *sql.Tx, user *models.User) error {
+	query := `INSERT INTO users (id, name, email, email_key, username, password_hash, role, timezone, week_start, units, status, created_at, updated_at, email_verified_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7, $8, $9, $10, $11, $12, $13, $14)`
+	_, err := tx.ExecContext(ctx, query, user.ID, user.Name, user.Email, models.EmailKey(user.Email), user.Username, user.PasswordHash, user.Role, user.Timezone, user.WeekStart, user.Units, user.Status, user.CreatedAt, user.UpdatedAt, user.EmailVerifiedAt)
+	if err != nil {
+		if dup := duplicateUserError(err, "create user"); dup != nil {
+			return dup
+		}
+		return fmt.Errorf("repository: failed to create user: %w", err)
+	}
+	// Seed the timezone history so lookups before any change resolve to the initial zone.
+	if _, err := tx.ExecContext(ctx, recordTimezoneQuery, user.ID, user.Timezone, user.CreatedAt); err != nil {
+		return fmt.Errorf("repository: failed to record timezone change: %w", err)
+	}
+	return insertOutboxEvent(ctx, tx, UserOutboxEvent(eventbus.UserCreated, user.ID))
+}
+
+// prepareNewUser fills in the defaults of a user about to be created, and its creation time.
+func prepareNewUser(user *models.User) {
 	// Defensive check, user.ID should be set by models.NewUser
 	if user.ID == uuid.Nil {
 		user.ID = uuid.New()
 	}
+	if user.Role == "" {
+		user.Role = models.RoleUser
+	}
+	if user.Timezone == "" {
+		user.Timezone = models.DefaultTimezone
+	}
+	if user.WeekStart == "" {
+		user.WeekStart = models.DefaultWeekStart
+	}
+	if user.Units == "" {
+		user.Units = models.DefaultUnits
+	}
+	if user.Status == "" {
+		user.Status = models.StatusActive
+	}
 	// Ensure timestamps are UTC for consistency
 	user.CreatedAt = time.Now().UTC()
 	user.UpdatedAt = user.CreatedAt
-
-	query := `INSERT INTO users (id, name, email, password_hash, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6)`
-	_, err := r.db.Exec(query, user.ID, user.Name, user.Email, user.PasswordHash, user.CreatedAt, user.UpdatedAt)
-	if err != nil {
-		return fmt.Errorf("repository: failed to create user: %w", err)
-	}
-	logger.Logger.Infof("User created successfully: %s", user.ID)
-	return nil
 }
 
-// GetUserByEmail retrieves a user by their email address.
+// GetUserByEmail retrieves the user whose email reaches the same mailbox as the given normalized email:
+// same models.EmailKey, or, for older emails without a key, the same address. An exact match wins.
 // This is intended to be the primary lookup for authentication.
-func (r *postgresUserRepository) GetUserByEmail(email string) (*models.User, error) {
-	query := `SELECT id, name, email, password_hash, created_at, updated_at FROM users WHERE email = $1`
-	row := r.db.QueryRow(query, email)
+func (r *postgresUserRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	// An account's own email wins over an alias left by a merge into another account.
+	query := `SELECT ` + userColumns + ` FROM users WHERE email_key = $1 OR (email_key IS NULL AND email = $2)
+		OR id = (SELECT user_id FROM user_email_aliases WHERE email_key = $1)
+		ORDER BY email = $2 DESC, email_key IS NOT DISTINCT FROM $1 DESC LIMIT 1`
+	row := r.db.QueryRowContext(ctx, query, models.EmailKey(email), email)
 
 	var user models.User
-	if err := row.Scan(&user.ID, &user.Name, &user.Email, &user.PasswordHash, &user.CreatedAt, &user.UpdatedAt); err != nil {
+	if err := scanUser(row, &user); err != nil {
 		if err == sql.ErrNoRows {
-			logger.Logger.Debugf("User with email '%s' not found in DB.", email)
+			logger.FromContext(ctx).Debugf("User with email '%s' not found in DB.", email)
 			return nil, nil // Return nil, nil when user is not found (idiomatic Go)
 		}
 		return nil, fmt.Errorf("repository: failed to get user by email: %w", err)
 	}
-	logger.Logger.Debugf("Retrieved user by email '%s': %s", email, user.ID)
+	logger.FromContext(ctx).Debugf("Retrieved user by email '%s': %s", email, user.ID)
 	return &user, nil
 }
 
-// GetAllUsers retrieves all users from the database.
-func (r *postgresUserRepository) GetAllUsers() ([]models.User, error) {
-	query := `SELECT id, name, email, password_hash, created_at, updated_at FROM users`
-	rows, err := r.db.Query(query)
+// GetUserByUsername retrieves a user by their lowercased username, or nil if no user has it.
+func (r *postgresUserRepository) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE username = $1`
+	row := r.db.QueryRowContext(ctx, query, username)
+
+	var user models.User
+	if err := scanUser(row, &user); err != nil {
+		if err == sql.ErrNoRows {
+			logger.FromContext(ctx).Debugf("User with username '%s' not found in DB.", username)
+			return nil, nil
+		}
+		return nil, fmt.Errorf("repository: failed to get user by username: %w", err)
+	}
+	return &user, nil
+}
+
+// GetAllUsers retrieves a page of all users, oldest first, resuming after the page key. The
+// (created_at, id) index serves each page directly, however deep it is.
+func (r *postgresUserRepository) GetAllUsers(ctx context.Context, after *models.PageKey, limit int) ([]models.User, error) {
+	query, args := allUsersQuery(after, limit)
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("repository: failed to get all users: %w", err)
 	}
 	defer rows.Close()
 
-	var users []models.User
+	users := []models.User{}
 	for rows.Next() {
 		var user models.User
-		if err := rows.Scan(&user.ID, &user.Name, &user.Email, &user.PasswordHash, &user.CreatedAt, &user.UpdatedAt); err != nil {
+		if err := scanUser(rows, &user); err != nil {
 			return nil, fmt.Errorf("repository: failed to scan user row: %w", err)
 		}
 		users = append(users, user)
@@ -123,47 +200,260 @@ func (r *postgresUserRepository) GetAllUsers() ([]models.User, error) {
 	if err = rows.Err(); err != nil {
 		return nil, fmt.Errorf("repository: rows iteration error: %w", err)
 	}
-	logger.Logger.Debugf("Retrieved %d users from DB.", len(users))
+	logger.FromContext(ctx).Debugf("Retrieved %d users from DB.", len(users))
 	return users, nil
 }
 
 // GetUserByID retrieves a user by their UUID.
-func (r *postgresUserRepository) GetUserByID(id uuid.UUID) (*models.User, error) {
-	query := `SELECT id, name, email, password_hash, created_at, updated_at FROM users WHERE id = $1`
-	row := r.db.QueryRow(query, id)
+func (r *postgresUserRepository) GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = $1`
+	row := r.db.QueryRowContext(ctx, query, id)
 
 	var user models.User
-	if err := row.Scan(&user.ID, &user.Name, &user.Email, &user.PasswordHash, &user.CreatedAt, &user.UpdatedAt); err != nil {
+	if err := scanUser(row, &user); err != nil {
 		if err == sql.ErrNoRows {
-			logger.Logger.Debugf("User with ID '%s' not found in DB.", id)
+			logger.FromContext(ctx).Debugf("User with ID '%s' not found in DB.", id)
 			return nil, nil // Return nil, nil when user is not found
 		}
 		return nil, fmt.Errorf("repository: failed to get user by ID: %w", err)
 	}
-	logger.Logger.Debugf("Retrieved user by ID '%s': %s", id, user.Name)
+	logger.FromContext(ctx).Debugf("Retrieved user by ID '%s': %s", id, user.Name)
 	return &user, nil
 }
 
-// UpdateUser updates an existing user's details in the database.
-func (r *postgresUserRepository) UpdateUser(user *models.User) error {
+// UpdateUser updates an existing user's details in the database, and writes a user.updated event to
+// the outbox if the user exists.
+func (r *postgresUserRepository) UpdateUser(ctx context.Context, user *models.User) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("repository: failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := updateUserRow(ctx, tx, user); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("repository: failed to commit user update: %w", err)
+	}
+	logger.FromContext(ctx).Infof("User updated successfully: %s", user.ID)
+	return nil
+}
+
+// updateUserRow writes a user's details in tx, and a user.updated event to the outbox if the user
+// exists. It reports whether they do.
+func updateUserRow(ctx context.Context, tx *sql.Tx, user *models.User) (bool, error) {
 	user.UpdatedAt = time.Now().UTC() // Update timestamp on modification
+	heightCM, dateOfBirth, sealed, err := sealHealth(user)
+	if err != nil {
+		return false, err
+	}
 
-	query := `UPDATE users SET name = $1, email = $2, password_hash = $3, updated_at = $4 WHERE id = $5`
-	_, err := r.db.Exec(query, user.Name, user.Email, user.PasswordHash, user.UpdatedAt, user.ID)
+	// The email key only follows email changes, so users without one keep none until they change their email.
+	// Verification and deliverability are never written from user, so concurrent updates of them are kept;
+	// a new email clears them.
+	query := `UPDATE users SET name = $1, email = $2, password_hash = $3, timezone = $4, week_start = $5, status = $6, height_cm = $7,
+		date_of_birth = $8, updated_at = $9, sessions_revoked_at = $10, deletion_due_at = $11, username = NULLIF($12, ''),
+		email_key = CASE WHEN email = $2 THEN email_key ELSE $14 END, units = $15, health_sealed = $16,
+		email_verified_at = CASE WHEN email = $2 THEN email_verified_at END,
+		email_status = CASE WHEN email = $2 THEN email_status ELSE 'ok' END,
+		email_soft_bounces = CASE WHEN email = $2 THEN email_soft_bounces ELSE 0 END,
+		email_status_at = CASE WHEN email = $2 THEN email_status_at END WHERE id = $13`
+	res, err := tx.ExecContext(ctx, query, user.Name, user.Email, user.PasswordHash, user.Timezone, user.WeekStart, user.Status, heightCM,
+		dateOfBirth, user.UpdatedAt, user.SessionsRevokedAt, user.DeletionDueAt, user.Username, user.ID, models.EmailKey(user.Email), user.Units, sealed)
 	if err != nil {
-		return fmt.Errorf("repository: failed to update user: %w", err)
+		if dup := duplicateUserError(err, "update user"); dup != nil {
+			return false, dup
+		}
+		return false, fmt.Errorf("repository: failed to update user: %w", err)
+	}
+	return announceUserChange(ctx, tx, res, eventbus.UserUpdated, user.ID)
+}
+
+// DeleteUser deletes a user from the database by their UUID, and writes a user.deleted event to the
+// outbox if the user existed.
+func (r *postgresUserRepository) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("repository: failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := deleteUserRow(ctx, tx, id); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("repository: failed to commit user deletion: %w", err)
 	}
-	logger.Logger.Infof("User updated successfully: %s", user.ID)
+	logger.FromContext(ctx).Infof("User deleted successfully: %s", id)
 	return nil
 }
 
-// DeleteUser deletes a user from the database by their UUID.
-func (r *postgresUserRepository) DeleteUser(id uuid.UUID) error {
-	query := `DELETE FROM users WHERE id = $1`
-	_, err := r.db.Exec(query, id)
+// deleteUserRow deletes a user in tx, and writes a user.deleted event to the outbox if the user
+// existed. It reports whether they did.
+func deleteUserRow(ctx context.Context, tx *sql.Tx, id uuid.UUID) (bool, error) {
+	res, err := tx.ExecContext(ctx, `DELETE FROM users WHERE id = $1`, id)
 	if err != nil {
-		return fmt.Errorf("repository: failed to delete user: %w", err)
+		return false, fmt.Errorf("repository: failed to delete user: %w", err)
 	}
-	logger.Logger.Infof("User deleted successfully: %s", id)
+	return announceUserChange(ctx, tx, res, eventbus.UserDeleted, id)
+}
+
+// announceUserChange writes an event of eventType about a user to the outbox in tx, if the statement
+// that changed the user (res) found them. It reports whether it did.
+func announceUserChange(ctx context.Context, tx *sql.Tx, res sql.Result, eventType string, userID uuid.UUID) (bool, error) {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("repository: failed to check the change to user %s: %w", userID, err)
+	}
+	if n == 0 {
+		return false, nil
+	}
+	return true, insertOutboxEvent(ctx, tx, UserOutboxEvent(eventType, userID))
+}
+
+// ApplyUserChanges applies the changes in one transaction, each with the outbox event CreateUser,
+// UpdateUser, or DeleteUser would write, and adds the timezone changes of updates to the history.
+func (r *postgresUserRepository) ApplyUserChanges(ctx context.Context, changes []models.UserChange) error {
+	return applyUserChanges(ctx, r.db, changes, func(tx *sql.Tx) userChangeWriter {
+		return userChangeWriter{
+			create: func(user *models.User) error { return insertUser(ctx, tx, user) },
+			update: func(user *models.User) (bool, error) { return updateUserRow(ctx, tx, user) },
+			delete: func(id uuid.UUID) (bool, error) { return deleteUserRow(ctx, tx, id) },
+			recordTimezone: func(user *models.User) error {
+				if _, err := tx.ExecContext(ctx, recordTimezoneQuery, user.ID, user.Timezone, user.UpdatedAt); err != nil {
+					return fmt.Errorf("repository: failed to record timezone change: %w", err)
+				}
+				return nil
+			},
+		}
+	})
+}
+
+// userChangeWriter writes the changes of ApplyUserChanges in a transaction. update and delete report whether the user exists; recordTimezone adds an updated user's
+// timezone to their history, effective from the update.
+type userChangeWriter struct {
+	create         func(user *models.User) error
+	update         func(user *models.User) (bool, error)
+	delete         func(id uuid.UUID) (bool, error)
+	recordTimezone func(user *models.User) error
+}
+
+// applyUserChanges runs ApplyUserChanges on db with the given writer: one transaction, rolled
+// back on the first change that fails, which is returned as a *ChangeError. Updates and deletions of
+// users that no longer exist fail with ErrNotFound.
+func applyUserChanges(ctx context.Context, db *sql.DB, changes []models.UserChange, writer func(tx *sql.Tx) userChangeWriter) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("repository: failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	w := writer(tx)
+	for i, change := range changes {
+		if err := w.apply(change); err != nil {
+			return &ChangeError{Index: i, Err: err}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("repository: failed to commit user changes: %w", err)
+	}
+	logger.FromContext(ctx).Infof("Applied %d user changes", len(changes))
 	return nil
-}
\ No newline at end of file
+}
+
+func (w userChangeWriter) apply(change models.UserChange) error {
+	user := change.User
+	switch change.Op {
+	case models.BulkCreate:
+		prepareNewUser(user)
+		return w.create(user)
+	case models.BulkUpdate:
+		found, err := w.update(user)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return Errorf(ErrNotFound, "repository: user %s not found", user.ID)
+		}
+		if change.TimezoneChanged {
+			return w.recordTimezone(user)
+		}
+		return nil
+	case models.BulkDelete:
+		found, err := w.delete(user.ID)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return Errorf(ErrNotFound, "repository: user %s not found", user.ID)
+		}
+		return nil
+	}
+	return fmt.Errorf("repository: unknown user change %q", change.Op)
+}
+
+// CreatePasswordResetToken stores the hash of a newly issued password reset token.
+func (r *postgresUserRepository) CreatePasswordResetToken(ctx context.Context, userID uuid.UUID, tokenHash string, expiresAt time.Time) error {
+	query := `INSERT INTO password_reset_tokens (token_hash, user_id, expires_at) VALUES ($1, $2, $3)`
+	_, err := r.db.ExecContext(ctx, query, tokenHash, userID, expiresAt.UTC())
+	if err != nil {
+		return fmt.Errorf("repository: failed to create password reset token: %w", err)
+	}
+	logger.FromContext(ctx).Debugf("Password reset token stored for user: %s", userID)
+	return nil
+}
+
+// ConsumePasswordResetToken atomically marks an unused, unexpired token as used and
+// returns its owner. It returns uuid.Nil (and no error) if the token is invalid.
+func (r *postgresUserRepository) ConsumePasswordResetToken(ctx context.Context, tokenHash string) (uuid.UUID, error) {
+	query := `UPDATE password_reset_tokens SET used_at = NOW()
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING user_id`
+	var userID uuid.UUID
+	if err := r.db.QueryRowContext(ctx, query, tokenHash).Scan(&userID); err != nil {
+		if err == sql.ErrNoRows {
+			logger.FromContext(ctx).Debug("Password reset token is unknown, used, or expired.")
+			return uuid.Nil, nil
+		}
+		return uuid.Nil, fmt.Errorf("repository: failed to consume password reset token: %w", err)
+	}
+	logger.FromContext(ctx).Debugf("Password reset token consumed for user: %s", userID)
+	return userID, nil
+}
+
+// recordTimezoneQuery appends an entry to a user's timezone history, replacing one effective from the same time.
+const recordTimezoneQuery = `INSERT INTO user_timezone_history (user_id, timezone, effective_from) VALUES ($1, $2, $3)
+	ON CONFLICT (user_id, effective_from) DO UPDATE SET timezone = EXCLUDED.timezone`
+
+// RecordTimezoneChange appends an entry to the user's timezone history.
+func (r *postgresUserRepository) RecordTimezoneChange(ctx context.Context, userID uuid.UUID, timezone string, effectiveFrom time.Time) error {
+	if _, err := r.db.ExecContext(ctx, recordTimezoneQuery, userID, timezone, effectiveFrom.UTC()); err != nil {
+		return fmt.Errorf("repository: failed to record timezone change: %w", err)
+	}
+	logger.FromContext(ctx).Debugf("Timezone for user %s set to %s from %s", userID, timezone, effectiveFrom)
+	return nil
+}
+
+// GetTimezoneHistory returns a user's timezone history, oldest first.
+func (r *postgresUserRepository) GetTimezoneHistory(ctx context.Context, userID uuid.UUID) ([]models.TimezonePeriod, error) {
+	query := `SELECT timezone, effective_from FROM user_timezone_history WHERE user_id = $1 ORDER BY effective_from`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to get timezone history: %w", err)
+	}
+	defer rows.Close()
+
+	history := []models.TimezonePeriod{}
+	for rows.Next() {
+		var p models.TimezonePeriod
+		if err := rows.Scan(&p.Timezone, &p.EffectiveFrom); err != nil {
+			return nil, fmt.Errorf("repository: failed to scan timezone history row: %w", err)
+		}
+		history = append(history, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: rows iteration error: %w", err)
+	}
+	return history, nil
+}
@@ -0,0 +1,173 @@
+// services/user-service/internal/repository/workout_attachment_repository.go
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+)
+
+// postgresWorkoutAttachmentRepository is the PostgreSQL implementation of WorkoutAttachmentRepository.
+type postgresWorkoutAttachmentRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresWorkoutAttachmentRepository creates a WorkoutAttachmentRepository on an open pool and runs its
+// migrations. The users table must already exist.
+func NewPostgresWorkoutAttachmentRepository(db *sql.DB) (WorkoutAttachmentRepository, error) {
+	repo := &postgresWorkoutAttachmentRepository{db: db}
+	if err := repo.Migrate(); err != nil {
+		return nil, fmt.Errorf("failed to run workout attachment migrations: %w", err)
+	}
+	return repo, nil
+}
+
+// Migrate applies the pending migrations in migrations/workout_attachments.
+func (r *postgresWorkoutAttachmentRepository) Migrate() error {
+	return MigrateSchema(r.db, "workout_attachments")
+}
+
+const workoutAttachmentColumns = `id, user_id, set_ref, filename, content_type, size, scan_status, scanned_at,
+	shared_with_coaches, expires_at, blob_key, created_at`
+
+func scanWorkoutAttachment(row rowScanner) (*models.WorkoutAttachment, error) {
+	var a models.WorkoutAttachment
+	var scannedAt, expiresAt sql.NullTime
+	err := row.Scan(&a.ID, &a.UserID, &a.SetRef, &a.Filename, &a.ContentType, &a.Size, &a.ScanStatus, &scannedAt,
+		&a.SharedWithCoaches, &expiresAt, &a.BlobKey, &a.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if scannedAt.Valid {
+		a.ScannedAt = &scannedAt.Time
+	}
+	if expiresAt.Valid {
+		a.ExpiresAt = &expiresAt.Time
+	}
+	return &a, nil
+}
+
+func (r *postgresWorkoutAttachmentRepository) queryAttachments(ctx context.Context, query string, args ...interface{}) ([]models.WorkoutAttachment, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to list workout attachments: %w", err)
+	}
+	defer rows.Close()
+
+	attachments := []models.WorkoutAttachment{}
+	for rows.Next() {
+		a, err := scanWorkoutAttachment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("repository: failed to scan workout attachment: %w", err)
+		}
+		attachments = append(attachments, *a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: failed to list workout attachments: %w", err)
+	}
+	return attachments, nil
+}
+
+// CreateAttachment records an upload whose content is already in the blob store.
+func (r *postgresWorkoutAttachmentRepository) CreateAttachment(ctx context.Context, a *models.WorkoutAttachment) error {
+	query := `INSERT INTO workout_attachments (` + workoutAttachmentColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
+	_, err := r.db.ExecContext(ctx, query, a.ID, a.UserID, a.SetRef, a.Filename, a.ContentType, a.Size, a.ScanStatus, a.ScannedAt,
+		a.SharedWithCoaches, a.ExpiresAt, a.BlobKey, a.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("repository: failed to create workout attachment: %w", err)
+	}
+	return nil
+}
+
+// GetAttachment returns one of the user's attachments, or nil if it does not exist.
+func (r *postgresWorkoutAttachmentRepository) GetAttachment(ctx context.Context, userID, id uuid.UUID) (*models.WorkoutAttachment, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+workoutAttachmentColumns+` FROM workout_attachments WHERE user_id = $1 AND id = $2`, userID, id)
+	a, err := scanWorkoutAttachment(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to get workout attachment: %w", err)
+	}
+	return a, nil
+}
+
+// ListAttachments returns the user's attachments, newest first, optionally for one set and only those
+// shared with coaches.
+func (r *postgresWorkoutAttachmentRepository) ListAttachments(ctx context.Context, userID uuid.UUID, setRef string, sharedOnly bool) ([]models.WorkoutAttachment, error) {
+	query := `SELECT ` + workoutAttachmentColumns + ` FROM workout_attachments
+		WHERE user_id = $1 AND ($2 = '' OR set_ref = $2) AND (NOT $3 OR shared_with_coaches)
+		ORDER BY created_at DESC`
+	return r.queryAttachments(ctx, query, userID, setRef, sharedOnly)
+}
+
+// UpdateAttachment saves the sharing flag and expiry, reporting whether the attachment exists.
+func (r *postgresWorkoutAttachmentRepository) UpdateAttachment(ctx context.Context, a *models.WorkoutAttachment) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `UPDATE workout_attachments SET shared_with_coaches = $3, expires_at = $4 WHERE user_id = $1 AND id = $2`,
+		a.UserID, a.ID, a.SharedWithCoaches, a.ExpiresAt)
+	if err != nil {
+		return false, fmt.Errorf("repository: failed to update workout attachment: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("repository: failed to update workout attachment: %w", err)
+	}
+	return n == 1, nil
+}
+
+// DeleteAttachment deletes one of the user's attachments and returns its blob key, or "" if it did not exist.
+func (r *postgresWorkoutAttachmentRepository) DeleteAttachment(ctx context.Context, userID, id uuid.UUID) (string, error) {
+	var key string
+	err := r.db.QueryRowContext(ctx, `DELETE FROM workout_attachments WHERE user_id = $1 AND id = $2 RETURNING blob_key`, userID, id).Scan(&key)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("repository: failed to delete workout attachment: %w", err)
+	}
+	return key, nil
+}
+
+// ListPendingScans returns up to limit attachments awaiting a virus scan, oldest first.
+func (r *postgresWorkoutAttachmentRepository) ListPendingScans(ctx context.Context, limit int) ([]models.WorkoutAttachment, error) {
+	query := `SELECT ` + workoutAttachmentColumns + ` FROM workout_attachments
+		WHERE scan_status = $1 ORDER BY created_at LIMIT $2`
+	return r.queryAttachments(ctx, query, models.ScanPending, limit)
+}
+
+// SetScanStatus records the outcome of a virus scan.
+func (r *postgresWorkoutAttachmentRepository) SetScanStatus(ctx context.Context, userID, id uuid.UUID, status string, at time.Time) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE workout_attachments SET scan_status = $3, scanned_at = $4 WHERE user_id = $1 AND id = $2`,
+		userID, id, status, at)
+	if err != nil {
+		return fmt.Errorf("repository: failed to set workout attachment scan status: %w", err)
+	}
+	return nil
+}
+
+// PurgeExpired deletes attachments that expired before the cutoff and returns their blob keys.
+func (r *postgresWorkoutAttachmentRepository) PurgeExpired(ctx context.Context, before time.Time) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, `DELETE FROM workout_attachments WHERE expires_at < $1 RETURNING blob_key`, before)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to purge workout attachments: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []string{}
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, fmt.Errorf("repository: failed to scan purged workout attachment: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: failed to purge workout attachments: %w", err)
+	}
+	return keys, nil
+}
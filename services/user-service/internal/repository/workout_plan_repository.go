@@ -0,0 +1,144 @@
+// services/user-service/internal/repository/workout_plan_repository.go
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+)
+
+// postgresWorkoutPlanRepository is the PostgreSQL implementation of WorkoutPlanRepository.
+type postgresWorkoutPlanRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresWorkoutPlanRepository creates a WorkoutPlanRepository on an open pool and runs its
+// migrations. The users table must already exist.
+func NewPostgresWorkoutPlanRepository(db *sql.DB) (WorkoutPlanRepository, error) {
+	repo := &postgresWorkoutPlanRepository{db: db}
+	if err := repo.Migrate(); err != nil {
+		return nil, fmt.Errorf("failed to run workout plan migrations: %w", err)
+	}
+	return repo, nil
+}
+
+// Migrate applies the pending migrations in migrations/workout_plans.
+func (r *postgresWorkoutPlanRepository) Migrate() error {
+	return MigrateSchema(r.db, "workout_plans")
+}
+
+const workoutPlanColumns = `id, coach_id, name, description, weeks, sessions, author_name, author_username,
+	author_platform, source_id, imported_at, created_at, updated_at`
+
+func scanWorkoutPlan(row rowScanner) (*models.WorkoutPlan, error) {
+	var p models.WorkoutPlan
+	var sessions []byte
+	var sourceID uuid.NullUUID
+	var importedAt sql.NullTime
+	err := row.Scan(&p.ID, &p.CoachID, &p.Name, &p.Description, &p.Weeks, &sessions, &p.Author.Name, &p.Author.Username,
+		&p.Author.Platform, &sourceID, &importedAt, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(sessions, &p.Sessions); err != nil {
+		return nil, fmt.Errorf("decode sessions of workout plan %s: %w", p.ID, err)
+	}
+	if sourceID.Valid {
+		p.SourceID = &sourceID.UUID
+	}
+	if importedAt.Valid {
+		p.ImportedAt = &importedAt.Time
+	}
+	return &p, nil
+}
+
+// SavePlans inserts the coach's plans, replacing those whose ID exists, in one transaction.
+func (r *postgresWorkoutPlanRepository) SavePlans(ctx context.Context, coachID uuid.UUID, plans []models.WorkoutPlan) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("repository: failed to begin workout plan transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO workout_plans (` + workoutPlanColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name, description = EXCLUDED.description, weeks = EXCLUDED.weeks,
+			sessions = EXCLUDED.sessions, author_name = EXCLUDED.author_name, author_username = EXCLUDED.author_username,
+			author_platform = EXCLUDED.author_platform, source_id = EXCLUDED.source_id, imported_at = EXCLUDED.imported_at,
+			updated_at = EXCLUDED.updated_at
+		WHERE workout_plans.coach_id = EXCLUDED.coach_id`
+	for _, p := range plans {
+		if p.CoachID != coachID {
+			return fmt.Errorf("repository: workout plan %s belongs to another coach", p.ID)
+		}
+		sessions, err := json.Marshal(p.Sessions)
+		if err != nil {
+			return fmt.Errorf("repository: failed to encode workout plan: %w", err)
+		}
+		res, err := tx.ExecContext(ctx, query, p.ID, p.CoachID, p.Name, p.Description, p.Weeks, sessions, p.Author.Name,
+			p.Author.Username, p.Author.Platform, p.SourceID, p.ImportedAt, p.CreatedAt, p.UpdatedAt)
+		if err != nil {
+			return fmt.Errorf("repository: failed to save workout plan: %w", err)
+		}
+		if n, err := res.RowsAffected(); err != nil || n != 1 {
+			return fmt.Errorf("repository: failed to save workout plan %s: id taken by another coach", p.ID)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("repository: failed to commit workout plans: %w", err)
+	}
+	return nil
+}
+
+// GetPlan returns one of the coach's plans, or nil if it does not exist.
+func (r *postgresWorkoutPlanRepository) GetPlan(ctx context.Context, coachID, id uuid.UUID) (*models.WorkoutPlan, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+workoutPlanColumns+` FROM workout_plans WHERE coach_id = $1 AND id = $2`, coachID, id)
+	p, err := scanWorkoutPlan(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to get workout plan: %w", err)
+	}
+	return p, nil
+}
+
+// ListPlans returns the coach's plans, oldest first.
+func (r *postgresWorkoutPlanRepository) ListPlans(ctx context.Context, coachID uuid.UUID) ([]models.WorkoutPlan, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+workoutPlanColumns+` FROM workout_plans
+		WHERE coach_id = $1 ORDER BY created_at, id`, coachID)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to list workout plans: %w", err)
+	}
+	defer rows.Close()
+
+	plans := []models.WorkoutPlan{}
+	for rows.Next() {
+		p, err := scanWorkoutPlan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("repository: failed to scan workout plan: %w", err)
+		}
+		plans = append(plans, *p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: failed to list workout plans: %w", err)
+	}
+	return plans, nil
+}
+
+// DeletePlan deletes one of the coach's plans, reporting whether it existed.
+func (r *postgresWorkoutPlanRepository) DeletePlan(ctx context.Context, coachID, id uuid.UUID) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM workout_plans WHERE coach_id = $1 AND id = $2`, coachID, id)
+	if err != nil {
+		return false, fmt.Errorf("repository: failed to delete workout plan: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("repository: failed to delete workout plan: %w", err)
+	}
+	return n == 1, nil
+}
@@ -0,0 +1,150 @@
+// services/user-service/internal/services/account_deletion_service.go
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/blobstore"
+	"health-tracker-project/services/user-service/internal/config"
+	"health-tracker-project/services/user-service/internal/eventbus"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/repository"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// erasureBatchSize caps the accounts erased per pass, so one pass cannot run unbounded.
+const erasureBatchSize = 100
+
+// AccountDeletionServiceImpl implements the AccountDeletionService interface.
+type AccountDeletionServiceImpl struct {
+	userRepo  repository.UserRepository
+	auditRepo repository.AuditRepository
+	appRepo   repository.DeveloperAppRepository
+	blobs     blobstore.Store
+	publisher eventbus.Publisher
+	events    UserEventService
+}
+
+// NewAccountDeletionService creates a new instance of AccountDeletionServiceImpl.
+func NewAccountDeletionService(userRepo repository.UserRepository, auditRepo repository.AuditRepository, appRepo repository.DeveloperAppRepository,
+	blobs blobstore.Store, publisher eventbus.Publisher, events UserEventService) *AccountDeletionServiceImpl {
+	return &AccountDeletionServiceImpl{userRepo: userRepo, auditRepo: auditRepo, appRepo: appRepo, blobs: blobs, publisher: publisher, events: events}
+}
+
+// RequestDeletion schedules the erasure of the caller's own account after the configured grace
+// period. The account is locked and all of its sessions are revoked straight away. Asking again
+// returns the existing schedule rather than restarting the grace period.
+func (s *AccountDeletionServiceImpl) RequestDeletion(ctx context.Context, userID uuid.UUID) (*models.AccountDeletion, error) {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to retrieve user '%s' for deletion: %v", userID, err)
+		return nil, fmt.Errorf("service: failed to retrieve user for deletion: %w", err)
+	}
+	if user == nil {
+		return nil, notFound("service: user not found")
+	}
+	if user.Status == models.StatusPendingDeletion && user.DeletionDueAt != nil {
+		return &models.AccountDeletion{UserID: userID, Status: user.Status, DueAt: *user.DeletionDueAt}, nil
+	}
+
+	now := time.Now().UTC()
+	dueAt := now.AddDate(0, 0, config.Current().AccountDeletionGraceDays)
+	// JWT iat has second precision, so revoke from the start of the current second.
+	revokedAt := now.Truncate(time.Second)
+	previous := user.Status
+	user.Status = models.StatusPendingDeletion
+	user.DeletionDueAt = &dueAt
+	user.SessionsRevokedAt = &revokedAt
+	if err := s.userRepo.UpdateUser(ctx, user); err != nil {
+		logger.FromContext(ctx).Errorf("Failed to schedule deletion of user '%s': %v", userID, err)
+		return nil, fmt.Errorf("service: failed to schedule account deletion: %w", err)
+	}
+	s.events.Record(ctx, userID, models.UserEventStatusChanged, "Account scheduled for deletion",
+		map[string]string{"from": previous, "to": user.Status, "due_at": dueAt.Format(time.RFC3339)})
+	s.events.Notify(ctx, userID, models.UserEventSessionRevoked, "Signed out everywhere: account scheduled for deletion",
+		map[string]string{"reason": "account_" + user.Status})
+	logger.FromContext(ctx).Infof("User %s requested deletion, due at %s", userID, dueAt.Format(time.RFC3339))
+	return &models.AccountDeletion{UserID: userID, Status: user.Status, DueAt: dueAt}, nil
+}
+
+// EraseDue periodically erases accounts whose grace period has ended. It blocks until ctx is done.
+func (s *AccountDeletionServiceImpl) EraseDue(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	for {
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C:
+		}
+		users, err := s.userRepo.ListDueDeletions(ctx, time.Now().UTC(), erasureBatchSize)
+		if err != nil {
+			logger.FromContext(ctx).Errorf("Failed to list accounts due for erasure: %v", err)
+			continue
+		}
+		erased := 0
+		for _, user := range users {
+			if err := s.erase(ctx, user); err != nil {
+				logger.FromContext(ctx).Errorf("Failed to erase user %s, retrying next pass: %v", user.ID, err)
+				continue
+			}
+			erased++
+		}
+		if erased > 0 {
+			logger.FromContext(ctx).Infof("Erased %d accounts", erased)
+		}
+	}
+}
+
+// erase tells other services to purge the user, anonymizes the audit log, and removes the user with
+// their attachments. Each step can be repeated, so a pass failing halfway is finished by the next one.
+// The event goes first: if it cannot be delivered, nothing is removed and the next pass retries it.
+func (s *AccountDeletionServiceImpl) erase(ctx context.Context, user models.User) error {
+	event := eventbus.NewEvent(eventbus.UserDeleted, user.ID, map[string]string{"due_at": user.DeletionDueAt.Format(time.RFC3339)})
+	if err := s.publisher.Publish(ctx, event); err != nil {
+		return fmt.Errorf("service: failed to publish deletion event: %w", err)
+	}
+
+	pseudonym := "erased:" + uuid.NewString()
+	if _, err := s.auditRepo.AnonymizeSubject(ctx, user.ID.String(), user.Email, pseudonym); err != nil {
+		return fmt.Errorf("service: failed to anonymize audit events: %w", err)
+	}
+	// Failed logins by username record the username instead of the email.
+	if user.Username != "" {
+		if _, err := s.auditRepo.AnonymizeSubject(ctx, user.ID.String(), user.Username, pseudonym); err != nil {
+			return fmt.Errorf("service: failed to anonymize audit events: %w", err)
+		}
+	}
+	// Developer apps live in the home database, so they are not removed with the user's rows.
+	if _, err := s.appRepo.DeleteOwnerApps(ctx, user.ID); err != nil {
+		return fmt.Errorf("service: failed to delete developer apps: %w", err)
+	}
+
+	keys, err := s.userRepo.EraseUser(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("service: failed to erase user: %w", err)
+	}
+	for _, key := range keys {
+		if err := s.blobs.Delete(key); err != nil {
+			logger.FromContext(ctx).Warnf("Failed to delete attachment blob %s of erased user %s: %v", key, user.ID, err)
+		}
+	}
+
+	// Proof of the erasure that does not identify whom it was about. The event ID is left out, since
+	// it is derived from the user ID.
+	record := &models.AuditEvent{
+		ID:        uuid.New(),
+		Action:    models.AuditUserErase,
+		Outcome:   models.AuditSuccess,
+		ActorID:   "system",
+		TargetID:  pseudonym,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := s.auditRepo.CreateEvent(ctx, record); err != nil {
+		logger.FromContext(ctx).Errorf("Failed to record audit event for erasure of %s: %v", pseudonym, err)
+	}
+	return nil
+}
@@ -0,0 +1,225 @@
+// services/user-service/internal/services/aggregation_service.go
+package services
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/repository"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// AggregationServiceImpl implements the AggregationService interface.
+type AggregationServiceImpl struct {
+	userRepo repository.UserRepository
+}
+
+// NewAggregationService creates a new instance of AggregationServiceImpl.
+func NewAggregationService(userRepo repository.UserRepository) *AggregationServiceImpl {
+	return &AggregationServiceImpl{userRepo: userRepo}
+}
+
+// ListPeriods returns the user's custom periods, earliest first.
+func (s *AggregationServiceImpl) ListPeriods(ctx context.Context, userID uuid.UUID) ([]models.AggregationPeriod, error) {
+	periods, err := s.userRepo.ListAggregationPeriods(ctx, userID)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to list aggregation periods for user %s: %v", userID, err)
+		return nil, fmt.Errorf("service: failed to list aggregation periods: %w", err)
+	}
+	return periods, nil
+}
+
+// CreatePeriod adds a custom period, up to models.MaxAggregationPeriods per user.
+func (s *AggregationServiceImpl) CreatePeriod(ctx context.Context, userID uuid.UUID, req models.AggregationPeriodRequest) (*models.AggregationPeriod, error) {
+	period, err := newAggregationPeriod(req)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := s.ListPeriods(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= models.MaxAggregationPeriods {
+		return nil, conflict("service: at most %d aggregation periods per user; delete one first", models.MaxAggregationPeriods)
+	}
+
+	now := time.Now().UTC()
+	period.ID = uuid.New()
+	period.UserID = userID
+	period.CreatedAt = now
+	period.UpdatedAt = now
+	if err := s.userRepo.CreateAggregationPeriod(ctx, period); err != nil {
+		logger.FromContext(ctx).Errorf("Failed to create aggregation period for user %s: %v", userID, err)
+		return nil, fmt.Errorf("service: failed to create aggregation period: %w", err)
+	}
+	return period, nil
+}
+
+// UpdatePeriod replaces the name, kind, and dates of one of the user's custom periods.
+func (s *AggregationServiceImpl) UpdatePeriod(ctx context.Context, userID, id uuid.UUID, req models.AggregationPeriodRequest) (*models.AggregationPeriod, error) {
+	period, err := newAggregationPeriod(req)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := s.ListPeriods(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	i := slices.IndexFunc(existing, func(p models.AggregationPeriod) bool { return p.ID == id })
+	if i < 0 {
+		return nil, notFound("service: aggregation period not found")
+	}
+
+	period.ID = id
+	period.UserID = userID
+	period.CreatedAt = existing[i].CreatedAt
+	period.UpdatedAt = time.Now().UTC()
+	found, err := s.userRepo.UpdateAggregationPeriod(ctx, period)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to update aggregation period %s for user %s: %v", id, userID, err)
+		return nil, fmt.Errorf("service: failed to update aggregation period: %w", err)
+	}
+	if !found {
+		return nil, notFound("service: aggregation period not found")
+	}
+	return period, nil
+}
+
+// DeletePeriod deletes one of the user's custom periods.
+func (s *AggregationServiceImpl) DeletePeriod(ctx context.Context, userID, id uuid.UUID) error {
+	found, err := s.userRepo.DeleteAggregationPeriod(ctx, userID, id)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to delete aggregation period %s for user %s: %v", id, userID, err)
+		return fmt.Errorf("service: failed to delete aggregation period: %w", err)
+	}
+	if !found {
+		return notFound("service: aggregation period not found")
+	}
+	return nil
+}
+
+// Windows resolves the aggregation windows of the given kinds (all when empty) that overlap the
+// local dates from through to: every week starting on the user's WeekStart, and every custom period.
+// Windows are returned whole, not clipped to the range, ordered by start.
+func (s *AggregationServiceImpl) Windows(ctx context.Context, userID uuid.UUID, from, to string, kinds []string) ([]models.AggregationWindow, error) {
+	fromDate, err1 := time.Parse(time.DateOnly, from)
+	toDate, err2 := time.Parse(time.DateOnly, to)
+	if err1 != nil || err2 != nil {
+		return nil, invalid("service: invalid aggregation range: from and to must be dates in YYYY-MM-DD format")
+	}
+	if toDate.Before(fromDate) {
+		return nil, invalid("service: invalid aggregation range: to is before from")
+	}
+	if days := int(toDate.Sub(fromDate).Hours()/24) + 1; days > models.MaxAggregationWindowsRange {
+		return nil, invalid("service: invalid aggregation range: at most %d days at a time", models.MaxAggregationWindowsRange)
+	}
+	for _, kind := range kinds {
+		if kind != models.AggregationWindowWeek && kind != models.AggregationWindowPeriod {
+			return nil, invalid("service: invalid aggregation range: unknown window kind %q", kind)
+		}
+	}
+	wants := func(kind string) bool { return len(kinds) == 0 || slices.Contains(kinds, kind) }
+
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to retrieve user by ID: %w", err)
+	}
+	if user == nil {
+		return nil, notFound("service: user not found")
+	}
+	history, err := timezoneHistory(ctx, s.userRepo, user)
+	if err != nil {
+		return nil, err
+	}
+	window := func(w models.AggregationWindow, start, end time.Time) (models.AggregationWindow, error) {
+		w.StartsOn, w.EndsOn = start.Format(time.DateOnly), end.Format(time.DateOnly)
+		if w.Start, err = localMidnight(history, start); err != nil {
+			return w, err
+		}
+		w.End, err = localMidnight(history, end.AddDate(0, 0, 1))
+		return w, err
+	}
+
+	windows := []models.AggregationWindow{}
+	if wants(models.AggregationWindowWeek) {
+		weekStart, ok := models.WeekStarts[user.WeekStart]
+		if !ok {
+			weekStart = models.WeekStarts[models.DefaultWeekStart]
+		}
+		start := fromDate.AddDate(0, 0, -((int(fromDate.Weekday()) - int(weekStart) + 7) % 7))
+		for ; !start.After(toDate); start = start.AddDate(0, 0, 7) {
+			w, err := window(models.AggregationWindow{Kind: models.AggregationWindowWeek}, start, start.AddDate(0, 0, 6))
+			if err != nil {
+				return nil, fmt.Errorf("service: failed to resolve aggregation windows: %w", err)
+			}
+			windows = append(windows, w)
+		}
+	}
+	if wants(models.AggregationWindowPeriod) {
+		periods, err := s.ListPeriods(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range periods {
+			if p.StartsOn > to || p.EndsOn < from { // YYYY-MM-DD compares chronologically
+				continue
+			}
+			start, err1 := time.Parse(time.DateOnly, p.StartsOn)
+			end, err2 := time.Parse(time.DateOnly, p.EndsOn)
+			if err1 != nil || err2 != nil {
+				return nil, fmt.Errorf("service: invalid stored dates for aggregation period %s", p.ID)
+			}
+			id := p.ID
+			w, err := window(models.AggregationWindow{Kind: models.AggregationWindowPeriod, PeriodID: &id, Name: p.Name}, start, end)
+			if err != nil {
+				return nil, fmt.Errorf("service: failed to resolve aggregation windows: %w", err)
+			}
+			windows = append(windows, w)
+		}
+	}
+	sort.SliceStable(windows, func(i, j int) bool { return windows[i].Start.Before(windows[j].Start) })
+	return windows, nil
+}
+
+// localMidnight returns the instant the local date begins in the timezone the user was in that day.
+// The zone is taken at midday UTC, which falls on that date in every zone but the far Pacific ones.
+func localMidnight(history []models.TimezonePeriod, date time.Time) (time.Time, error) {
+	loc, err := models.TimezoneAt(history, date.Add(12*time.Hour))
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, loc).UTC(), nil
+}
+
+// newAggregationPeriod validates a period request. Kind defaults to custom.
+func newAggregationPeriod(req models.AggregationPeriodRequest) (*models.AggregationPeriod, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > models.MaxAggregationPeriodNameLen {
+		return nil, invalid("service: invalid aggregation period: name is required and at most %d characters", models.MaxAggregationPeriodNameLen)
+	}
+	kind := req.Kind
+	if kind == "" {
+		kind = models.AggregationPeriodCustom
+	}
+	if !slices.Contains(models.AggregationPeriodKinds, kind) {
+		return nil, invalid("service: invalid aggregation period: kind must be one of %s", strings.Join(models.AggregationPeriodKinds, ", "))
+	}
+	start, err1 := time.Parse(time.DateOnly, req.StartsOn)
+	end, err2 := time.Parse(time.DateOnly, req.EndsOn)
+	if err1 != nil || err2 != nil {
+		return nil, invalid("service: invalid aggregation period: starts_on and ends_on must be dates in YYYY-MM-DD format")
+	}
+	if end.Before(start) {
+		return nil, invalid("service: invalid aggregation period: ends_on is before starts_on")
+	}
+	if end.Sub(start) >= models.MaxAggregationPeriodDays*24*time.Hour {
+		return nil, invalid("service: invalid aggregation period: at most %d days long", models.MaxAggregationPeriodDays)
+	}
+	return &models.AggregationPeriod{Name: name, Kind: kind, StartsOn: req.StartsOn, EndsOn: req.EndsOn}, nil
+}
@@ -0,0 +1,283 @@
+// services/user-service/internal/services/announcement_service.go
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/config"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/push"
+	"health-tracker-project/services/user-service/internal/repository"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+const (
+	maxAnnouncementTitle  = 100
+	maxAnnouncementBody   = 500
+	maxInactiveDays       = 365
+	announcementSample    = 5
+	announcementPage      = 100             // Recipients sent between two progress records
+	announcementLease     = 5 * time.Minute // Longer than a page takes at 1 send per second
+	defaultAnnouncements  = 50
+	maxAnnouncementsLimit = 200
+)
+
+// AnnouncementServiceImpl implements the AnnouncementService interface. Announcements are fanned out
+// through the push pipeline by SendDue, throttled to announcement_sends_per_second.
+type AnnouncementServiceImpl struct {
+	announcementRepo repository.AnnouncementRepository
+	userRepo         repository.UserRepository
+	notifier         push.Notifier
+}
+
+// NewAnnouncementService creates a new instance of AnnouncementServiceImpl.
+func NewAnnouncementService(announcementRepo repository.AnnouncementRepository, userRepo repository.UserRepository, notifier push.Notifier) *AnnouncementServiceImpl {
+	return &AnnouncementServiceImpl{announcementRepo: announcementRepo, userRepo: userRepo, notifier: notifier}
+}
+
+// validate checks an announcement request and fills in the segment defaults.
+func (s *AnnouncementServiceImpl) validate(req *models.CreateAnnouncementRequest) error {
+	req.Title, req.Body = strings.TrimSpace(req.Title), strings.TrimSpace(req.Body)
+	if req.Title == "" || req.Body == "" {
+		return invalid("service: announcement title and body are required")
+	}
+	if utf8.RuneCountInString(req.Title) > maxAnnouncementTitle {
+		return invalid("service: announcement title must be at most %d characters", maxAnnouncementTitle)
+	}
+	if utf8.RuneCountInString(req.Body) > maxAnnouncementBody {
+		return invalid("service: announcement body must be at most %d characters", maxAnnouncementBody)
+	}
+
+	segment := &req.Segment
+	segment.Org, segment.Plan = strings.TrimSpace(segment.Org), strings.TrimSpace(segment.Plan)
+	switch segment.Type {
+	case models.SegmentAll:
+	case models.SegmentOrg:
+		if segment.Org == "" {
+			return invalid("service: segment org is required for the org segment")
+		}
+	case models.SegmentPlan:
+		if segment.Plan == "" {
+			return invalid("service: segment plan is required for the plan segment")
+		}
+	case models.SegmentInactive:
+		if segment.InactiveDays == 0 {
+			segment.InactiveDays = models.DefaultInactiveDays
+		}
+		if segment.InactiveDays < 1 || segment.InactiveDays > maxInactiveDays {
+			return invalid("service: segment inactive_days must be between 1 and %d", maxInactiveDays)
+		}
+	default:
+		return invalid("service: segment type is required and must be one of all, org, plan, inactive")
+	}
+	// Only the fields of the segment's type are kept, so the stored segment says what was targeted.
+	if segment.Type != models.SegmentOrg {
+		segment.Org = ""
+	}
+	if segment.Type != models.SegmentPlan {
+		segment.Plan = ""
+	}
+	if segment.Type != models.SegmentInactive {
+		segment.InactiveDays = 0
+	}
+	return nil
+}
+
+// Preview returns what an announcement would send and how many users it would reach, without sending it.
+func (s *AnnouncementServiceImpl) Preview(ctx context.Context, req models.CreateAnnouncementRequest) (*models.AnnouncementPreview, error) {
+	if err := s.validate(&req); err != nil {
+		return nil, err
+	}
+	audience, err := s.userRepo.CountAnnouncementRecipients(ctx, req.Segment)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to count announcement recipients: %v", err)
+		return nil, fmt.Errorf("service: failed to preview announcement: %w", err)
+	}
+	sample, err := s.userRepo.ListAnnouncementRecipients(ctx, req.Segment, uuid.Nil, announcementSample)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to sample announcement recipients: %v", err)
+		return nil, fmt.Errorf("service: failed to preview announcement: %w", err)
+	}
+	if sample == nil {
+		sample = []uuid.UUID{}
+	}
+	return &models.AnnouncementPreview{Title: req.Title, Body: req.Body, Audience: *audience, Sample: sample}, nil
+}
+
+// Create schedules an announcement for its send time, or as soon as possible.
+func (s *AnnouncementServiceImpl) Create(ctx context.Context, req models.CreateAnnouncementRequest, actor string) (*models.Announcement, error) {
+	if err := s.validate(&req); err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	sendAt := now
+	if req.SendAt != nil && req.SendAt.After(now) {
+		sendAt = req.SendAt.UTC()
+	}
+	a := &models.Announcement{
+		ID:        uuid.New(),
+		Title:     req.Title,
+		Body:      req.Body,
+		Segment:   req.Segment,
+		Status:    models.AnnouncementScheduled,
+		SendAt:    sendAt,
+		CreatedBy: actor,
+		CreatedAt: now,
+	}
+	if err := s.announcementRepo.CreateAnnouncement(ctx, a); err != nil {
+		logger.FromContext(ctx).Errorf("Failed to create announcement: %v", err)
+		return nil, fmt.Errorf("service: failed to create announcement: %w", err)
+	}
+	logger.FromContext(ctx).Infof("Announcement %s to segment %s scheduled for %s by %s", a.ID, a.Segment.Type, a.SendAt.Format(time.RFC3339), actor)
+	return a, nil
+}
+
+// Get returns an announcement with its delivery stats.
+func (s *AnnouncementServiceImpl) Get(ctx context.Context, id uuid.UUID) (*models.Announcement, error) {
+	a, err := s.announcementRepo.GetAnnouncement(ctx, id)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to get announcement %s: %v", id, err)
+		return nil, fmt.Errorf("service: failed to get announcement: %w", err)
+	}
+	if a == nil {
+		return nil, notFound("service: announcement not found")
+	}
+	return a, nil
+}
+
+// List returns announcements matching the filter, newest first.
+func (s *AnnouncementServiceImpl) List(ctx context.Context, filter models.AnnouncementFilter) ([]models.Announcement, error) {
+	switch filter.Status {
+	case "", models.AnnouncementScheduled, models.AnnouncementSending, models.AnnouncementSent, models.AnnouncementCancelled:
+	default:
+		return nil, invalid("service: status must be one of scheduled, sending, sent, cancelled")
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultAnnouncements
+	}
+	if filter.Limit > maxAnnouncementsLimit {
+		filter.Limit = maxAnnouncementsLimit
+	}
+	announcements, err := s.announcementRepo.ListAnnouncements(ctx, filter)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to list announcements: %v", err)
+		return nil, fmt.Errorf("service: failed to list announcements: %w", err)
+	}
+	return announcements, nil
+}
+
+// Cancel stops an announcement that is scheduled or still sending; what was sent stays sent.
+func (s *AnnouncementServiceImpl) Cancel(ctx context.Context, id uuid.UUID, actor string) (*models.Announcement, error) {
+	cancelled, err := s.announcementRepo.CancelAnnouncement(ctx, id, time.Now().UTC())
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to cancel announcement %s: %v", id, err)
+		return nil, fmt.Errorf("service: failed to cancel announcement: %w", err)
+	}
+	a, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !cancelled {
+		return nil, conflict("service: announcement is already %s", a.Status)
+	}
+	logger.FromContext(ctx).Infof("Announcement %s cancelled by %s after %d sent", id, actor, a.Stats.Sent)
+	return a, nil
+}
+
+// SendDue sends the announcements whose time has come, on every tick of interval. Blocks until ctx is done; run
+// it in a goroutine. Every replica runs it: each announcement is leased to one replica at a time, and
+// taken over by another if its sender stops.
+func (s *AnnouncementServiceImpl) SendDue(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	for {
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C:
+		}
+		for s.sendNext(ctx) {
+		}
+	}
+}
+
+// sendNext claims one due announcement and sends it in full. It returns false when none was due.
+func (s *AnnouncementServiceImpl) sendNext(ctx context.Context) bool {
+	now := time.Now().UTC()
+	a, err := s.announcementRepo.ClaimDueAnnouncement(ctx, now, now.Add(announcementLease))
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to claim a due announcement: %v", err)
+		return false
+	}
+	if a == nil {
+		return false
+	}
+	if a.Cursor == uuid.Nil {
+		audience, err := s.userRepo.CountAnnouncementRecipients(ctx, a.Segment)
+		if err != nil {
+			logger.FromContext(ctx).Warnf("Failed to count recipients of announcement %s: %v", a.ID, err)
+		} else if err := s.announcementRepo.SetAnnouncementRecipients(ctx, a.ID, audience.Recipients); err != nil {
+			logger.FromContext(ctx).Warnf("Failed to record recipients of announcement %s: %v", a.ID, err)
+		}
+	}
+	s.fanOut(ctx, a)
+	return true
+}
+
+// fanOut sends an announcement to its recipients from its cursor on, a page at a time, recording
+// progress after each page. It stops early when the announcement is cancelled or a record fails;
+// the lease then runs out and the send resumes from the last recorded page.
+func (s *AnnouncementServiceImpl) fanOut(ctx context.Context, a *models.Announcement) {
+	cursor := a.Cursor
+	for {
+		recipients, err := s.userRepo.ListAnnouncementRecipients(ctx, a.Segment, cursor, announcementPage)
+		if err != nil {
+			logger.FromContext(ctx).Errorf("Failed to list recipients of announcement %s: %v", a.ID, err)
+			return
+		}
+		if len(recipients) == 0 {
+			break
+		}
+		// Read on every page, so a changed rate applies to a send in progress.
+		pace := time.Second / time.Duration(config.Current().AnnouncementSendsPerSecond)
+		sent, failed := 0, 0
+		for _, userID := range recipients {
+			time.Sleep(pace)
+			err := s.notifier.Notify(ctx, push.Notification{
+				UserID: userID,
+				Title:  a.Title,
+				Body:   a.Body,
+				Data:   map[string]string{"type": "announcement", "announcement_id": a.ID.String()},
+			})
+			if err != nil {
+				failed++
+				logger.FromContext(ctx).Debugf("Failed to send announcement %s to %s: %v", a.ID, userID, err)
+				continue
+			}
+			sent++
+		}
+		cursor = recipients[len(recipients)-1]
+		sending, err := s.announcementRepo.RecordAnnouncementProgress(ctx, a.ID, cursor, sent, failed, time.Now().UTC().Add(announcementLease))
+		if err != nil {
+			logger.FromContext(ctx).Errorf("Failed to record progress of announcement %s: %v", a.ID, err)
+			return
+		}
+		if !sending {
+			logger.FromContext(ctx).Infof("Announcement %s stopped: it was cancelled", a.ID)
+			return
+		}
+		if failed > 0 {
+			logger.FromContext(ctx).Warnf("Announcement %s: %d of %d push notifications failed", a.ID, failed, len(recipients))
+		}
+	}
+	if err := s.announcementRepo.FinishAnnouncement(ctx, a.ID, time.Now().UTC()); err != nil {
+		logger.FromContext(ctx).Errorf("Failed to finish announcement %s: %v", a.ID, err)
+		return
+	}
+	logger.FromContext(ctx).Infof("Announcement %s sent", a.ID)
+}
@@ -0,0 +1,494 @@
+// services/user-service/internal/services/appointment_service.go
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/config"
+	"health-tracker-project/services/user-service/internal/ics"
+	"health-tracker-project/services/user-service/internal/mailer"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/push"
+	"health-tracker-project/services/user-service/internal/repository"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+const (
+	minSlotMinutes          = 5
+	maxSlotMinutes          = 8 * 60
+	maxSlotsPerPublish      = 500
+	maxAvailabilityAhead    = 365 * 24 * time.Hour // Latest a slot may be published for
+	defaultSlotRange        = 30 * 24 * time.Hour
+	maxSlotRange            = 90 * 24 * time.Hour
+	maxAppointmentTextChars = 1000
+)
+
+// AppointmentServiceImpl implements the AppointmentService interface.
+type AppointmentServiceImpl struct {
+	appointmentRepo repository.AppointmentRepository
+	userRepo        repository.UserRepository
+	mail            mailer.Mailer
+	notifier        push.Notifier
+	events          UserEventService // Records bookings on the booking user's timeline
+}
+
+// NewAppointmentService creates a new instance of AppointmentServiceImpl.
+func NewAppointmentService(appointmentRepo repository.AppointmentRepository, userRepo repository.UserRepository, mail mailer.Mailer, notifier push.Notifier, events UserEventService) *AppointmentServiceImpl {
+	return &AppointmentServiceImpl{appointmentRepo: appointmentRepo, userRepo: userRepo, mail: mail, notifier: notifier, events: events}
+}
+
+// isProvider reports whether a user may publish availability and be booked.
+func isProvider(user *models.User) bool {
+	return user != nil && user.Status == models.StatusActive &&
+		slices.Contains(models.ScopesForRole(user.Role), models.ScopeAppointments)
+}
+
+// PublishAvailability cuts the requested window into back-to-back slots and publishes them.
+// A remainder shorter than a slot is left out.
+func (s *AppointmentServiceImpl) PublishAvailability(ctx context.Context, providerID uuid.UUID, req models.PublishAvailabilityRequest) ([]models.AppointmentSlot, error) {
+	now := time.Now().UTC()
+	switch {
+	case req.SlotMinutes < minSlotMinutes || req.SlotMinutes > maxSlotMinutes:
+		return nil, invalid("service: slot_minutes must be between %d and %d", minSlotMinutes, maxSlotMinutes)
+	case req.StartsAt.Before(now):
+		return nil, invalid("service: starts_at must be in the future")
+	case req.EndsAt.After(now.Add(maxAvailabilityAhead)):
+		return nil, invalid("service: ends_at must be within a year")
+	case req.EndsAt.Sub(req.StartsAt) < time.Duration(req.SlotMinutes)*time.Minute:
+		return nil, invalid("service: ends_at must leave room for at least one slot")
+	case utf8.RuneCountInString(req.Location) > 500:
+		return nil, invalid("service: location must be at most 500 characters")
+	}
+
+	length := time.Duration(req.SlotMinutes) * time.Minute
+	slots := []models.AppointmentSlot{}
+	for start := req.StartsAt.UTC(); !start.Add(length).After(req.EndsAt); start = start.Add(length) {
+		if len(slots) == maxSlotsPerPublish {
+			return nil, invalid("service: at most %d slots can be published at once", maxSlotsPerPublish)
+		}
+		slots = append(slots, models.AppointmentSlot{
+			ID:         uuid.New(),
+			ProviderID: providerID,
+			StartsAt:   start,
+			EndsAt:     start.Add(length),
+			Location:   strings.TrimSpace(req.Location),
+			CreatedAt:  now,
+		})
+	}
+
+	if err := s.appointmentRepo.CreateSlots(ctx, providerID, slots); err != nil {
+		if errors.Is(err, ErrConflict) {
+			return nil, conflict("service: slots overlap existing availability")
+		}
+		logger.FromContext(ctx).Errorf("Failed to publish availability for provider %s: %v", providerID, err)
+		return nil, fmt.Errorf("service: failed to publish availability: %w", err)
+	}
+	return slots, nil
+}
+
+// ListSlots returns a provider's slots starting in the filter's range, by default the next 30 days.
+// Past slots are never listed.
+func (s *AppointmentServiceImpl) ListSlots(ctx context.Context, filter models.SlotFilter) ([]models.AppointmentSlot, error) {
+	provider, err := s.userRepo.GetUserByID(ctx, filter.ProviderID)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to retrieve provider '%s': %v", filter.ProviderID, err)
+		return nil, fmt.Errorf("service: failed to retrieve provider: %w", err)
+	}
+	if !isProvider(provider) {
+		return nil, notFound("service: provider not found")
+	}
+
+	now := time.Now().UTC()
+	if filter.From.Before(now) {
+		filter.From = now
+	}
+	if filter.To.IsZero() {
+		filter.To = filter.From.Add(defaultSlotRange)
+	}
+	if filter.To.Sub(filter.From) > maxSlotRange {
+		return nil, invalid("service: range must be at most %d days", int(maxSlotRange.Hours()/24))
+	}
+	slots, err := s.appointmentRepo.ListSlots(ctx, filter)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to list slots of provider %s: %v", filter.ProviderID, err)
+		return nil, fmt.Errorf("service: failed to list slots: %w", err)
+	}
+	return slots, nil
+}
+
+// DeleteSlot withdraws one of the provider's slots. Booked slots must be cancelled first.
+func (s *AppointmentServiceImpl) DeleteSlot(ctx context.Context, providerID, id uuid.UUID) error {
+	slot, err := s.appointmentRepo.GetSlot(ctx, id)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to get slot %s: %v", id, err)
+		return fmt.Errorf("service: failed to get slot: %w", err)
+	}
+	if slot == nil || slot.ProviderID != providerID {
+		return notFound("service: slot not found")
+	}
+	deleted, err := s.appointmentRepo.DeleteSlot(ctx, providerID, id)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to delete slot %s: %v", id, err)
+		return fmt.Errorf("service: failed to delete slot: %w", err)
+	}
+	if !deleted {
+		return conflict("service: slot is booked")
+	}
+	return nil
+}
+
+// openSlot returns a slot that can still be booked: it exists, has not started, and belongs to an active provider.
+func (s *AppointmentServiceImpl) openSlot(ctx context.Context, id uuid.UUID) (*models.AppointmentSlot, error) {
+	slot, err := s.appointmentRepo.GetSlot(ctx, id)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to get slot %s: %v", id, err)
+		return nil, fmt.Errorf("service: failed to get slot: %w", err)
+	}
+	if slot == nil {
+		return nil, notFound("service: slot not found")
+	}
+	provider, err := s.userRepo.GetUserByID(ctx, slot.ProviderID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to retrieve provider: %w", err)
+	}
+	if !isProvider(provider) {
+		return nil, notFound("service: slot not found")
+	}
+	if !slot.StartsAt.After(time.Now()) {
+		return nil, conflict("service: slot has already started")
+	}
+	if slot.Booked {
+		return nil, conflict("service: slot is already booked")
+	}
+	return slot, nil
+}
+
+// Book books an open slot for the user and sends both participants a calendar invite.
+func (s *AppointmentServiceImpl) Book(ctx context.Context, userID uuid.UUID, req models.BookAppointmentRequest) (*models.Appointment, error) {
+	reason := strings.TrimSpace(req.Reason)
+	if utf8.RuneCountInString(reason) > maxAppointmentTextChars {
+		return nil, invalid("service: reason must be at most %d characters", maxAppointmentTextChars)
+	}
+	slot, err := s.openSlot(ctx, req.SlotID)
+	if err != nil {
+		return nil, err
+	}
+	if slot.ProviderID == userID {
+		return nil, invalid("service: cannot book your own slot")
+	}
+
+	appointment := &models.Appointment{
+		ID:         uuid.New(),
+		SlotID:     &slot.ID,
+		ProviderID: slot.ProviderID,
+		UserID:     userID,
+		Reason:     reason,
+		Status:     models.AppointmentBooked,
+		CreatedAt:  time.Now().UTC(),
+	}
+	if err := s.appointmentRepo.BookSlot(ctx, appointment); err != nil {
+		switch {
+		case errors.Is(err, ErrNotFound):
+			return nil, notFound("service: slot not found")
+		case errors.Is(err, ErrConflict):
+			return nil, conflict("service: slot is already booked")
+		}
+		logger.FromContext(ctx).Errorf("Failed to book slot %s for user %s: %v", slot.ID, userID, err)
+		return nil, fmt.Errorf("service: failed to book appointment: %w", err)
+	}
+
+	s.events.Record(ctx, userID, models.UserEventAppointmentBooked, "Booked an appointment for "+appointment.StartsAt.Format(time.RFC1123),
+		map[string]string{"appointment_id": appointment.ID.String(), "provider_id": appointment.ProviderID.String()})
+	go s.notify(context.WithoutCancel(ctx), *appointment, ics.MethodRequest, "Appointment booked", "A new appointment was booked.", userID)
+	return appointment, nil
+}
+
+// ListAppointments returns appointments matching the filter, earliest first. Handlers set the
+// filter's ProviderID or UserID to the caller.
+func (s *AppointmentServiceImpl) ListAppointments(ctx context.Context, filter models.AppointmentFilter) ([]models.Appointment, error) {
+	if filter.Status != "" && filter.Status != models.AppointmentBooked && filter.Status != models.AppointmentCancelled && filter.Status != models.AppointmentRescheduled {
+		return nil, invalid("service: status must be booked, cancelled, or rescheduled")
+	}
+	appointments, err := s.appointmentRepo.ListAppointments(ctx, filter)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to list appointments: %v", err)
+		return nil, fmt.Errorf("service: failed to list appointments: %w", err)
+	}
+	return appointments, nil
+}
+
+// GetAppointment returns an appointment the caller booked or provides.
+func (s *AppointmentServiceImpl) GetAppointment(ctx context.Context, callerID, id uuid.UUID) (*models.Appointment, error) {
+	appointment, err := s.appointmentRepo.GetAppointment(ctx, id)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to get appointment %s: %v", id, err)
+		return nil, fmt.Errorf("service: failed to get appointment: %w", err)
+	}
+	if appointment == nil || (appointment.UserID != callerID && appointment.ProviderID != callerID) {
+		return nil, notFound("service: appointment not found")
+	}
+	return appointment, nil
+}
+
+// bookedAppointment returns a booked appointment the caller takes part in, checking the policy's
+// notice period when the caller is the booking user.
+func (s *AppointmentServiceImpl) bookedAppointment(ctx context.Context, callerID, id uuid.UUID, noticeHours int, action string) (*models.Appointment, error) {
+	appointment, err := s.GetAppointment(ctx, callerID, id)
+	if err != nil {
+		return nil, err
+	}
+	if appointment.Status != models.AppointmentBooked {
+		return nil, conflict("service: appointment is not booked")
+	}
+	if !appointment.StartsAt.After(time.Now()) {
+		return nil, conflict("service: appointment has already started")
+	}
+	if callerID == appointment.UserID && time.Until(appointment.StartsAt) < time.Duration(noticeHours)*time.Hour {
+		return nil, forbidden("service: %s closes %d hours before the appointment", action, noticeHours)
+	}
+	return appointment, nil
+}
+
+// Cancel cancels a booked appointment. Users must give the policy's notice; providers can cancel
+// until the appointment starts. Both participants get a calendar cancellation.
+func (s *AppointmentServiceImpl) Cancel(ctx context.Context, callerID, id uuid.UUID, req models.CancelAppointmentRequest) (*models.Appointment, error) {
+	reason := strings.TrimSpace(req.Reason)
+	if utf8.RuneCountInString(reason) > maxAppointmentTextChars {
+		return nil, invalid("service: reason must be at most %d characters", maxAppointmentTextChars)
+	}
+	appointment, err := s.bookedAppointment(ctx, callerID, id, config.Current().AppointmentPolicy.CancelNoticeHours, "cancellation")
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	appointment.CancelledAt, appointment.CancelledBy, appointment.CancelReason = &now, &callerID, reason
+	cancelled, err := s.appointmentRepo.CancelAppointment(ctx, appointment)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to cancel appointment %s: %v", id, err)
+		return nil, fmt.Errorf("service: failed to cancel appointment: %w", err)
+	}
+	if !cancelled {
+		return nil, conflict("service: appointment is not booked")
+	}
+	appointment.Status = models.AppointmentCancelled
+
+	s.events.Record(ctx, appointment.UserID, models.UserEventAppointmentCancelled, "Appointment on "+appointment.StartsAt.Format(time.RFC1123)+" was cancelled",
+		map[string]string{"appointment_id": appointment.ID.String(), "cancelled_by": callerID.String()})
+	go s.notify(context.WithoutCancel(ctx), *appointment, ics.MethodCancel, "Appointment cancelled", "An appointment was cancelled.", callerID)
+	return appointment, nil
+}
+
+// Reschedule moves the user's booked appointment to another open slot of the same provider,
+// within the policy's notice period and reschedule limit. Providers cancel instead.
+func (s *AppointmentServiceImpl) Reschedule(ctx context.Context, callerID, id uuid.UUID, req models.RescheduleAppointmentRequest) (*models.Appointment, error) {
+	policy := config.Current().AppointmentPolicy
+	old, err := s.bookedAppointment(ctx, callerID, id, policy.RescheduleNoticeHours, "rescheduling")
+	if err != nil {
+		return nil, err
+	}
+	if callerID != old.UserID {
+		return nil, forbidden("service: only the booking user can reschedule")
+	}
+	if policy.MaxReschedules > 0 && old.RescheduleCount >= policy.MaxReschedules {
+		return nil, forbidden("service: appointment was already rescheduled %d times", old.RescheduleCount)
+	}
+	slot, err := s.openSlot(ctx, req.SlotID)
+	if err != nil {
+		return nil, err
+	}
+	if slot.ProviderID != old.ProviderID {
+		return nil, invalid("service: slot must be with the same provider")
+	}
+
+	now := time.Now().UTC()
+	old.CancelledAt, old.CancelledBy = &now, &callerID
+	replacement := &models.Appointment{
+		ID:              uuid.New(),
+		SlotID:          &slot.ID,
+		ProviderID:      old.ProviderID,
+		UserID:          old.UserID,
+		Reason:          old.Reason,
+		Status:          models.AppointmentBooked,
+		RescheduledFrom: &old.ID,
+		RescheduleCount: old.RescheduleCount + 1,
+		CreatedAt:       now,
+	}
+	if err := s.appointmentRepo.RescheduleAppointment(ctx, old, replacement); err != nil {
+		switch {
+		case errors.Is(err, ErrNotFound):
+			return nil, notFound("service: slot not found")
+		case errors.Is(err, ErrConflict): // The slot was booked, or the appointment changed, meanwhile
+			return nil, conflict("service: %s", strings.TrimPrefix(err.Error(), "repository: "))
+		}
+		logger.FromContext(ctx).Errorf("Failed to reschedule appointment %s: %v", id, err)
+		return nil, fmt.Errorf("service: failed to reschedule appointment: %w", err)
+	}
+	old.Status = models.AppointmentRescheduled
+
+	s.events.Record(ctx, old.UserID, models.UserEventAppointmentRescheduled, "Moved an appointment to "+replacement.StartsAt.Format(time.RFC1123),
+		map[string]string{"appointment_id": replacement.ID.String(), "rescheduled_from": old.ID.String()})
+	go func(ctx context.Context) {
+		s.notify(ctx, *old, ics.MethodCancel, "Appointment rescheduled", "An appointment was moved to a new time.", callerID)
+		s.notify(ctx, *replacement, ics.MethodRequest, "Appointment rescheduled", "An appointment was moved to a new time.", callerID)
+	}(context.WithoutCancel(ctx))
+	return replacement, nil
+}
+
+// Invite renders the calendar invite of an appointment the caller takes part in: a request while
+// it is booked, a cancellation otherwise.
+func (s *AppointmentServiceImpl) Invite(ctx context.Context, callerID, id uuid.UUID) ([]byte, string, error) {
+	appointment, err := s.GetAppointment(ctx, callerID, id)
+	if err != nil {
+		return nil, "", err
+	}
+	user, provider, err := s.participants(ctx, *appointment)
+	if err != nil {
+		return nil, "", err
+	}
+	method := ics.MethodRequest
+	if appointment.Status != models.AppointmentBooked {
+		method = ics.MethodCancel
+	}
+	return invite(*appointment, user, provider, method), method, nil
+}
+
+// participants loads the booking user and the provider of an appointment.
+func (s *AppointmentServiceImpl) participants(ctx context.Context, a models.Appointment) (*models.User, *models.User, error) {
+	user, err := s.userRepo.GetUserByID(ctx, a.UserID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("service: failed to retrieve user: %w", err)
+	}
+	provider, err := s.userRepo.GetUserByID(ctx, a.ProviderID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("service: failed to retrieve provider: %w", err)
+	}
+	if user == nil || provider == nil {
+		return nil, nil, fmt.Errorf("service: appointment participant no longer exists")
+	}
+	return user, provider, nil
+}
+
+// invite builds an appointment's calendar object. A cancellation has a higher sequence than the
+// request, so calendars apply it.
+func invite(a models.Appointment, user, provider *models.User, method string) []byte {
+	sequence := 0
+	if method == ics.MethodCancel {
+		sequence = 1
+	}
+	description := "Appointment booked through Pulse."
+	if a.Reason != "" {
+		description += "\nReason: " + a.Reason
+	}
+	return ics.Invite(ics.Event{
+		UID:         a.ID.String() + "@pulse",
+		Sequence:    sequence,
+		Start:       a.StartsAt,
+		End:         a.EndsAt,
+		Summary:     "Appointment with " + provider.Name,
+		Description: description,
+		Location:    a.Location,
+		Organizer:   ics.Attendee{Name: provider.Name, Email: provider.Email},
+		Attendees:   []ics.Attendee{{Name: user.Name, Email: user.Email}},
+		Stamp:       time.Now(),
+	}, method)
+}
+
+// localTime formats an instant in a user's timezone for emails, falling back to UTC.
+func localTime(t time.Time, user *models.User) string {
+	if loc, err := time.LoadLocation(user.Timezone); err == nil {
+		t = t.In(loc)
+	}
+	return t.Format("Monday, 2 January 2006 at 15:04 MST")
+}
+
+// notify emails both participants the appointment's invite and pushes a notification to whoever
+// did not cause the change. Failures are only logged.
+func (s *AppointmentServiceImpl) notify(ctx context.Context, a models.Appointment, method, subject, summary string, actorID uuid.UUID) {
+	user, provider, err := s.participants(ctx, a)
+	if err != nil {
+		logger.FromContext(ctx).Warnf("Failed to notify participants of appointment %s: %v", a.ID, err)
+		return
+	}
+	attachment := mailer.Attachment{Filename: "invite.ics", ContentType: ics.ContentType(method), Content: invite(a, user, provider, method)}
+	for _, to := range []*models.User{user, provider} {
+		body := fmt.Sprintf("%s\n\nWhen: %s\nWith: %s", summary, localTime(a.StartsAt, to), provider.Name)
+		if to == provider {
+			body = fmt.Sprintf("%s\n\nWhen: %s\nWith: %s", summary, localTime(a.StartsAt, to), user.Name)
+		}
+		if a.Location != "" {
+			body += "\nWhere: " + a.Location
+		}
+		if err := sendWithAttachment(s.mail, to.Email, subject, body, attachment); err != nil {
+			logger.FromContext(ctx).Warnf("Failed to email appointment %s to %s: %v", a.ID, to.ID, err)
+		}
+		if to.ID != actorID {
+			s.push(ctx, to.ID, subject, a)
+		}
+	}
+}
+
+// sendWithAttachment sends the invite along when the mailer supports attachments.
+func sendWithAttachment(mail mailer.Mailer, to, subject, body string, attachment mailer.Attachment) error {
+	if m, ok := mail.(mailer.AttachmentMailer); ok {
+		return m.SendWithAttachments(to, subject, body, attachment)
+	}
+	return mail.Send(to, subject, body)
+}
+
+func (s *AppointmentServiceImpl) push(ctx context.Context, userID uuid.UUID, title string, a models.Appointment) {
+	err := s.notifier.Notify(ctx, push.Notification{
+		UserID: userID,
+		Title:  title,
+		Body:   "Open Pulse to see the details.",
+		Data:   map[string]string{"type": "appointment", "appointment_id": a.ID.String()},
+	})
+	if err != nil {
+		logger.FromContext(ctx).Warnf("Failed to send appointment notification to %s: %v", userID, err)
+	}
+}
+
+// SendReminders emails and pushes a reminder to both participants of every booked appointment
+// starting within the policy's reminder_hours, once per appointment, every interval. It returns when ctx is done.
+func (s *AppointmentServiceImpl) SendReminders(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	for {
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C:
+		}
+		s.sendReminders(ctx)
+	}
+}
+
+func (s *AppointmentServiceImpl) sendReminders(ctx context.Context) {
+	hours := config.Current().AppointmentPolicy.ReminderHours
+	if hours == 0 {
+		return
+	}
+	due, err := s.appointmentRepo.ListDueReminders(ctx, time.Now().Add(time.Duration(hours)*time.Hour))
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to list due appointment reminders: %v", err)
+		return
+	}
+	for _, a := range due {
+		// Marked first, so a failing mailer cannot cause a reminder on every tick.
+		if err := s.appointmentRepo.MarkReminderSent(ctx, a.ProviderID, a.ID, time.Now().UTC()); err != nil {
+			logger.FromContext(ctx).Errorf("Failed to mark reminder of appointment %s: %v", a.ID, err)
+			continue
+		}
+		s.notify(ctx, a, ics.MethodRequest, "Appointment reminder", "Reminder of your upcoming appointment.", uuid.Nil)
+	}
+	if len(due) > 0 {
+		logger.FromContext(ctx).Infof("Sent reminders for %d appointments", len(due))
+	}
+}
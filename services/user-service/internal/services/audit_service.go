@@ -0,0 +1,61 @@
+// services/user-service/internal/services/audit_service.go
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/repository"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 500
+)
+
+// AuditServiceImpl implements the AuditService interface.
+type AuditServiceImpl struct {
+	auditRepo repository.AuditRepository
+}
+
+// NewAuditService creates a new instance of AuditServiceImpl.
+func NewAuditService(auditRepo repository.AuditRepository) *AuditServiceImpl {
+	return &AuditServiceImpl{auditRepo: auditRepo}
+}
+
+// Record stores an audit event. Failures are logged at error level rather than returned:
+// the audited action has already happened, but a gap in the audit log must not go unnoticed. The event
+// is stored even if ctx is cancelled, as a client that hung up must not leave a gap either.
+func (s *AuditServiceImpl) Record(ctx context.Context, event models.AuditEvent) {
+	event.ID = uuid.New()
+	event.CreatedAt = time.Now().UTC()
+	if err := s.auditRepo.CreateEvent(context.WithoutCancel(ctx), &event); err != nil {
+		logger.FromContext(ctx).Errorw("Failed to record audit event",
+			"action", event.Action,
+			"outcome", event.Outcome,
+			"actor_id", event.ActorID,
+			"target_id", event.TargetID,
+			"error", err,
+		)
+	}
+}
+
+// ListEvents returns audit events matching the filter, newest first.
+func (s *AuditServiceImpl) ListEvents(ctx context.Context, filter models.AuditEventFilter) ([]models.AuditEvent, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultAuditLimit
+	}
+	if filter.Limit > maxAuditLimit {
+		filter.Limit = maxAuditLimit
+	}
+	events, err := s.auditRepo.ListEvents(ctx, filter)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to retrieve audit events: %v", err)
+		return nil, fmt.Errorf("service: failed to retrieve audit events: %w", err)
+	}
+	return events, nil
+}
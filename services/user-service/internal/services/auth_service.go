@@ -2,96 +2,565 @@
 package services
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/auth/oidc"
+	"health-tracker-project/services/user-service/internal/auth/saml"
+	"health-tracker-project/services/user-service/internal/config"
+	"health-tracker-project/services/user-service/internal/mailer"
+	"health-tracker-project/services/user-service/internal/metrics"
 	"health-tracker-project/services/user-service/internal/models"
 	"health-tracker-project/services/user-service/internal/repository"
 	"health-tracker-project/services/user-service/internal/utils/jwt"
 	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
 )
 
+// authResponseFloor is the minimum time spent on login and registration requests.
+// Padding every outcome to the same floor hides timing differences between
+// unknown emails, wrong passwords, and existing accounts.
+const authResponseFloor = 400 * time.Millisecond
+
+// passwordResetTTL is how long a password reset token remains valid.
+const passwordResetTTL = 30 * time.Minute
+
+const (
+	defaultLoginHistoryLimit = 50
+	maxLoginHistoryLimit     = 200
+)
+
+// padResponseTime sleeps until at least authResponseFloor has elapsed since start.
+func padResponseTime(start time.Time) {
+	if remaining := authResponseFloor - time.Since(start); remaining > 0 {
+		time.Sleep(remaining)
+	}
+}
+
 // AuthServiceImpl implements the AuthService interface.
 type AuthServiceImpl struct {
-	userRepo repository.UserRepository // Depends on the UserRepository interface
+	userRepo    repository.UserRepository         // Depends on the UserRepository interface
+	mailer      mailer.Mailer                     // Delivers account notification emails
+	domains     mailer.DomainChecker              // Checks that new emails' domains accept mail; nil to skip
+	privacyMode bool                              // When true, registration never reveals whether an email is taken
+	events      UserEventService                  // Records account changes on the user's own timeline
+	loginRepo   repository.LoginAttemptRepository // Per-user login history
+	identities  IdentityService                   // Maps SSO identities to users and links them
+	sessionRepo repository.SessionRepository      // Signed-in sessions; a token is valid only while its session exists
 }
 
 // NewAuthService creates a new instance of AuthServiceImpl.
-func NewAuthService(userRepo repository.UserRepository) *AuthServiceImpl {
-	return &AuthServiceImpl{userRepo: userRepo}
+func NewAuthService(userRepo repository.UserRepository, mailer mailer.Mailer, domains mailer.DomainChecker, privacyMode bool, events UserEventService, loginRepo repository.LoginAttemptRepository, identities IdentityService, sessionRepo repository.SessionRepository) *AuthServiceImpl {
+	return &AuthServiceImpl{userRepo: userRepo, mailer: mailer, domains: domains, privacyMode: privacyMode, events: events, loginRepo: loginRepo, identities: identities, sessionRepo: sessionRepo}
 }
 
 // RegisterUser handles the business logic for new user registration.
-func (s *AuthServiceImpl) RegisterUser(req models.RegisterRequest) (*models.UserResponse, error) {
+// In privacy mode it returns (nil, nil) for both new and existing emails; the outcome
+// is communicated to the address owner by email instead of in the response.
+func (s *AuthServiceImpl) RegisterUser(ctx context.Context, req models.RegisterRequest) (*models.UserResponse, error) {
+	defer padResponseTime(time.Now()) // Uniform timing so existing emails can't be detected by latency
+
 	// Business validation: Ensure all required fields are present.
 	if req.Name == "" || req.Email == "" || req.Password == "" {
-		logger.Logger.Debug("Registration request missing required fields.")
-		return nil, fmt.Errorf("service: name, email, and password are required")
+		logger.FromContext(ctx).Debug("Registration request missing required fields.")
+		return nil, invalid("service: name, email, and password are required")
+	}
+	// Add more robust validation here (e.g., password strength).
+	email, err := normalizeEmail(req.Email, s.domains)
+	if err != nil {
+		return nil, err
+	}
+
+	// Checked before the email, so a taken username is reported whether or not the email is registered
+	// and the answer reveals nothing about the email in privacy mode.
+	username, err := checkUsername(ctx, s.userRepo, req.Username, uuid.Nil)
+	if err != nil {
+		return nil, err
 	}
-	// Add more robust validation here (e.g., email format, password strength).
 
-	// Check if user with this email already exists.
-	existingUser, err := s.userRepo.GetUserByEmail(req.Email)
+	// Check if user with this email, or another address of its mailbox, already exists.
+	existingUser, err := s.userRepo.GetUserByEmail(ctx, email)
 	if err != nil {
-		logger.Logger.Errorf("Failed to check for existing user by email '%s': %v", req.Email, err)
+		logger.FromContext(ctx).Errorf("Failed to check for existing user by email '%s': %v", email, err)
 		return nil, fmt.Errorf("service: failed to check for existing user by email: %w", err)
 	}
 	if existingUser != nil {
-		logger.Logger.Warnf("Registration attempt with existing email: %s", req.Email)
-		return nil, fmt.Errorf("service: user with this email already exists")
+		logger.FromContext(ctx).Warnf("Registration attempt with existing email: %s", email)
+		if s.privacyMode {
+			// Tell the real owner instead of the requester, so the response confirms nothing.
+			if err := s.mailer.Send(existingUser.Email, "You already have a Pulse account",
+				"Someone tried to register with this email address. If this was you, log in or reset your password instead."); err != nil {
+				logger.FromContext(ctx).Errorf("Failed to send existing-account notice to '%s': %v", existingUser.Email, err)
+			}
+			return nil, nil
+		}
+		return nil, duplicateEmail("service: user with this email already exists")
 	}
 
 	// Create new user model (password hashing is handled inside models.NewUser).
-	newUser, err := models.NewUser(req.Name, req.Email, req.Password)
+	newUser, err := models.NewUser(req.Name, email, req.Password)
 	if err != nil {
-		logger.Logger.Errorf("Failed to create new user model: %v", err)
+		logger.FromContext(ctx).Errorf("Failed to create new user model: %v", err)
 		return nil, fmt.Errorf("service: failed to create new user model: %w", err)
 	}
+	newUser.Username = username
+	newUser.Region = config.RegionForCountry(req.Country) // Empty when data residency is disabled
 
 	// Persist the user to the database via the repository.
-	if err := s.userRepo.CreateUser(newUser); err != nil {
-		logger.Logger.Errorf("Failed to save new user '%s': %v", newUser.ID, err)
+	if err := s.userRepo.CreateUser(ctx, newUser); err != nil {
+		logger.FromContext(ctx).Errorf("Failed to save new user '%s': %v", newUser.ID, err)
 		return nil, fmt.Errorf("service: failed to save new user: %w", err)
 	}
 
 	userResponse := newUser.ToUserResponse()
-	logger.Logger.Infof("User registered successfully: ID %s, Email %s", newUser.ID, newUser.Email)
+	s.events.Record(ctx, newUser.ID, models.UserEventRegistered, "Account registered", nil)
+	logger.FromContext(ctx).Infof("User registered successfully: ID %s, Email %s", newUser.ID, newUser.Email)
+	if s.privacyMode {
+		if err := s.mailer.Send(newUser.Email, "Welcome to Pulse", "Your account has been created. You can now log in."); err != nil {
+			logger.FromContext(ctx).Errorf("Failed to send welcome email to '%s': %v", newUser.Email, err)
+		}
+		return nil, nil
+	}
 	return &userResponse, nil
 }
 
 // AuthenticateUser handles the business logic for user login.
-func (s *AuthServiceImpl) AuthenticateUser(req models.LoginRequest) (*models.AuthResponse, error) {
+func (s *AuthServiceImpl) AuthenticateUser(ctx context.Context, req models.LoginRequest) (*models.AuthResponse, error) {
+	defer padResponseTime(time.Now()) // Uniform timing across all login outcomes
+
 	// Business validation: Ensure required fields for login are present.
-	if req.Email == "" || req.Password == "" {
-		logger.Logger.Debug("Login request missing email or password.")
-		return nil, fmt.Errorf("service: email and password are required")
+	// Usernames never contain "@", so an email field without one holds a username.
+	login := req.Email
+	if req.Username != "" {
+		login = req.Username
+	}
+	if login == "" || req.Password == "" {
+		logger.FromContext(ctx).Debug("Login request missing email or password.")
+		return nil, invalid("service: email and password are required")
 	}
 
-	// Retrieve user by email from the repository.
-	user, err := s.userRepo.GetUserByEmail(req.Email)
+	// Retrieve user by email or username from the repository.
+	user, err := s.lookupLogin(ctx, login)
 	if err != nil {
-		logger.Logger.Errorf("Failed to retrieve user by email '%s' for authentication: %v", req.Email, err)
+		logger.FromContext(ctx).Errorf("Failed to retrieve user '%s' for authentication: %v", login, err)
 		return nil, fmt.Errorf("service: failed to retrieve user for authentication: %w", err)
 	}
 	// Check if user exists and if password is correct.
+	// Unknown emails still pay for a bcrypt comparison, and both failures share one error.
+	if user == nil {
+		models.SimulatePasswordCheck(req.Password)
+	}
 	if user == nil || !user.CheckPassword(req.Password) {
-		logger.Logger.Warnf("Invalid login attempt for email '%s'.", req.Email)
-		return nil, fmt.Errorf("service: invalid credentials")
+		logger.FromContext(ctx).Warnf("Invalid login attempt for '%s'.", login)
+		if user != nil {
+			s.recordLoginAttempt(ctx, user.ID, models.LoginMethodPassword, req.Client, "invalid_credentials")
+		}
+		return nil, badCredentials("service: invalid credentials")
+	}
+	// Checked after the password so the status of an account is only revealed to its owner.
+	if user.Status != models.StatusActive {
+		logger.FromContext(ctx).Warnf("Login rejected for %s account: ID %s", user.Status, user.ID)
+		s.recordLoginAttempt(ctx, user.ID, models.LoginMethodPassword, req.Client, "account_"+user.Status)
+		return nil, inactive("service: account is %s", user.Status)
+	}
+
+	// Upgrade hashes made with an older algorithm or weaker parameters while the plaintext is at hand.
+	// A failed upgrade must not fail the login; it is retried on the next one.
+	if user.PasswordNeedsRehash() {
+		if err := user.SetPassword(req.Password); err != nil {
+			logger.FromContext(ctx).Warnf("Failed to rehash password for user ID %s: %v", user.ID, err)
+		} else if err := s.userRepo.UpdateUser(ctx, user); err != nil {
+			logger.FromContext(ctx).Warnf("Failed to store rehashed password for user ID %s: %v", user.ID, err)
+		} else {
+			logger.FromContext(ctx).Infof("Password hash upgraded for user ID %s", user.ID)
+		}
+	}
+
+	s.recordLoginAttempt(ctx, user.ID, models.LoginMethodPassword, req.Client, "")
+	logger.FromContext(ctx).Infof("User authenticated successfully: ID %s, Email %s", user.ID, user.Email)
+	return s.issueAuthResponse(ctx, user, req.Client)
+}
+
+// lookupLogin finds the user a login identifier names: an email, or a username when it has no "@".
+// Malformed emails and usernames match nobody.
+func (s *AuthServiceImpl) lookupLogin(ctx context.Context, login string) (*models.User, error) {
+	if strings.Contains(login, "@") {
+		email, err := models.NormalizeEmail(login)
+		if err != nil {
+			return nil, nil
+		}
+		return s.userRepo.GetUserByEmail(ctx, email)
+	}
+	username, err := models.NormalizeUsername(login)
+	if err != nil {
+		return nil, nil
+	}
+	return s.userRepo.GetUserByUsername(ctx, username)
+}
+
+// AuthenticateOIDC signs in a user verified by an external OIDC provider.
+// The provider account is mapped to a Pulse user through the identity service; when its verified email
+// belongs to an account the identity is not linked to, a link challenge is returned instead of a session.
+func (s *AuthServiceImpl) AuthenticateOIDC(ctx context.Context, identity *oidc.Identity, client models.ClientInfo) (*models.AuthResponse, *models.IdentityLinkChallenge, error) {
+	if identity.Email == "" || !identity.EmailVerified {
+		logger.FromContext(ctx).Warnf("OIDC sign-in rejected for subject '%s' from %s: email missing or unverified", identity.Subject, identity.Issuer)
+		return nil, nil, forbidden("service: identity provider did not supply a verified email")
+	}
+	return s.authenticateExternal(ctx, models.ExternalIdentity{
+		Method:  models.LoginMethodOIDC,
+		Issuer:  identity.Issuer,
+		Subject: identity.Subject,
+		Email:   identity.Email,
+		Name:    identity.Name,
+	}, client)
+}
+
+// AuthenticateSAML signs in a user asserted by the configured SAML identity provider.
+// Users are mapped, provisioned, and linked the same way as for OIDC; the IdP is trusted to vouch for the email.
+func (s *AuthServiceImpl) AuthenticateSAML(ctx context.Context, identity *saml.Identity, client models.ClientInfo) (*models.AuthResponse, *models.IdentityLinkChallenge, error) {
+	if identity.Email == "" {
+		logger.FromContext(ctx).Warnf("SAML sign-in rejected for subject '%s' from %s: no email in assertion", identity.Subject, identity.Issuer)
+		return nil, nil, forbidden("service: identity provider did not supply an email")
+	}
+	return s.authenticateExternal(ctx, models.ExternalIdentity{
+		Method:  models.LoginMethodSAML,
+		Issuer:  identity.Issuer,
+		Subject: identity.Subject,
+		Email:   identity.Email,
+		Name:    identity.Name,
+	}, client)
+}
+
+// authenticateExternal signs in the user an SSO identity resolves to, or returns the link challenge for it.
+func (s *AuthServiceImpl) authenticateExternal(ctx context.Context, ext models.ExternalIdentity, client models.ClientInfo) (*models.AuthResponse, *models.IdentityLinkChallenge, error) {
+	email, err := models.NormalizeEmail(ext.Email)
+	if err != nil {
+		logger.FromContext(ctx).Warnf("%s sign-in rejected for subject '%s' from %s: invalid email: %v", ext.Method, ext.Subject, ext.Issuer, err)
+		return nil, nil, forbidden("service: identity provider supplied an invalid email: %w", err)
+	}
+	ext.Email = email
+	user, challenge, err := s.identities.ResolveExternal(ctx, ext)
+	if err != nil {
+		return nil, nil, err
+	}
+	if challenge != nil {
+		return nil, challenge, nil
+	}
+	authResponse, err := s.signInExternal(ctx, user, ext.Method, ext.Issuer, client)
+	return authResponse, nil, err
+}
+
+// CompleteIdentityLink finishes a link challenge and signs the user in through the newly linked identity.
+func (s *AuthServiceImpl) CompleteIdentityLink(ctx context.Context, req models.LinkIdentityRequest) (*models.AuthResponse, error) {
+	user, identity, err := s.identities.CompleteLink(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return s.signInExternal(ctx, user, identity.Method, identity.Issuer, req.Client)
+}
+
+// signInExternal issues a session for a user signing in through SSO, if the account is active.
+func (s *AuthServiceImpl) signInExternal(ctx context.Context, user *models.User, method, issuer string, client models.ClientInfo) (*models.AuthResponse, error) {
+	if user.Status != models.StatusActive {
+		logger.FromContext(ctx).Warnf("%s sign-in rejected for %s account: ID %s", method, user.Status, user.ID)
+		s.recordLoginAttempt(ctx, user.ID, method, client, "account_"+user.Status)
+		return nil, inactive("service: account is %s", user.Status)
+	}
+
+	s.recordLoginAttempt(ctx, user.ID, method, client, "")
+	logger.FromContext(ctx).Infof("User authenticated via %s: ID %s, Issuer %s", method, user.ID, issuer)
+	return s.issueAuthResponse(ctx, user, client)
+}
+
+// recordLoginAttempt adds a sign-in attempt to the user's login history; an empty failureReason means success.
+// A failure to record is logged and does not affect the sign-in.
+func (s *AuthServiceImpl) recordLoginAttempt(ctx context.Context, userID uuid.UUID, method string, client models.ClientInfo, failureReason string) {
+	attempt := &models.LoginAttempt{
+		ID:            uuid.New(),
+		UserID:        userID,
+		Success:       failureReason == "",
+		Method:        method,
+		FailureReason: failureReason,
+		IP:            client.IP,
+		UserAgent:     client.UserAgent,
+		CreatedAt:     time.Now().UTC(),
+	}
+	if err := s.loginRepo.CreateAttempt(ctx, attempt); err != nil {
+		logger.FromContext(ctx).Warnf("Failed to record login attempt for user %s: %v", userID, err)
 	}
+}
 
-	// Generate JWT upon successful authentication.
+// GetLoginHistory returns a user's login attempts matching the filter, newest first.
+func (s *AuthServiceImpl) GetLoginHistory(ctx context.Context, filter models.LoginAttemptFilter) ([]models.LoginAttempt, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultLoginHistoryLimit
+	}
+	if filter.Limit > maxLoginHistoryLimit {
+		filter.Limit = maxLoginHistoryLimit
+	}
+	attempts, err := s.loginRepo.ListAttempts(ctx, filter)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to retrieve login history for user %s: %v", filter.UserID, err)
+		return nil, fmt.Errorf("service: failed to retrieve login history: %w", err)
+	}
+	return attempts, nil
+}
+
+// issueAuthResponse starts a session for an authenticated user and generates its access token.
+// When the user goes over the configured session limit, their oldest sessions are signed out. The
+// user's open connections are told of both.
+func (s *AuthServiceImpl) issueAuthResponse(ctx context.Context, user *models.User, client models.ClientInfo) (*models.AuthResponse, error) {
 	tokenDuration := 15 * time.Minute // Short-lived access token
+	now := time.Now().UTC()
+	session := &models.Session{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		IP:        client.IP,
+		UserAgent: client.UserAgent,
+		CreatedAt: now,
+		ExpiresAt: now.Add(tokenDuration),
+	}
+	evicted, err := s.sessionRepo.CreateSession(ctx, session, config.Current().MaxSessionsPerUser)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to create session for user '%s': %v", user.ID, err)
+		return nil, fmt.Errorf("service: failed to create session: %w", err)
+	}
+	s.events.Notify(ctx, user.ID, models.UserEventSessionStarted, "Signed in",
+		map[string]string{"session_id": session.ID.String(), "ip": client.IP, "user_agent": client.UserAgent})
+	if len(evicted) > 0 {
+		metrics.SessionsEvicted(len(evicted))
+		logger.FromContext(ctx).Infof("Signed out %d oldest session(s) of user %s over the session limit", len(evicted), user.ID)
+		for _, id := range evicted {
+			s.events.Notify(ctx, user.ID, models.UserEventSessionRevoked, "Signed out by the session limit",
+				map[string]string{"session_id": id.String(), "reason": "session_limit"})
+		}
+	}
+
 	// Generate JWT using user's ID and Name for claims.
-	tokenString, err := jwt.GenerateJWT(user.ID.String(), user.Name, tokenDuration)
+	tokenString, err := jwt.GenerateJWT(user.ID.String(), user.Name, user.Role, models.ScopesForRole(user.Role), session.ID.String(), tokenDuration)
 	if err != nil {
-		logger.Logger.Errorf("Failed to generate JWT for user '%s': %v", user.ID, err)
+		logger.FromContext(ctx).Errorf("Failed to generate JWT for user '%s': %v", user.ID, err)
 		return nil, fmt.Errorf("service: failed to generate token: %w", err)
 	}
 
-	logger.Logger.Infof("User authenticated successfully: ID %s, Email %s", user.ID, user.Email)
 	return &models.AuthResponse{
-		Token:        tokenString,
-		User:         user.ToUserResponse(),
-		ExpiresInSec: int64(tokenDuration.Seconds()),
+		Token:         tokenString,
+		User:          user.ToUserResponse(),
+		ExpiresInSec:  int64(tokenDuration.Seconds()),
+		ReverifyEmail: user.EmailSuppressed(),
 	}, nil
-}
\ No newline at end of file
+}
+
+// Logout ends a session of a user so its token is rejected from then on.
+func (s *AuthServiceImpl) Logout(ctx context.Context, userID, sessionID uuid.UUID) error {
+	if err := s.sessionRepo.DeleteSession(ctx, userID, sessionID); err != nil {
+		logger.FromContext(ctx).Errorf("Failed to delete session '%s': %v", sessionID, err)
+		return fmt.Errorf("service: failed to end session: %w", err)
+	}
+	s.events.Notify(ctx, userID, models.UserEventSessionRevoked, "Signed out",
+		map[string]string{"session_id": sessionID.String(), "reason": "logout"})
+	return nil
+}
+
+// ReapSessions deletes expired sessions and refreshes the session gauges every interval. It returns when ctx is done.
+func (s *AuthServiceImpl) ReapSessions(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	for {
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C:
+		}
+		s.reapSessions(ctx)
+	}
+}
+
+func (s *AuthServiceImpl) reapSessions(ctx context.Context) {
+	reaped, err := s.sessionRepo.DeleteExpiredSessions(ctx, time.Now())
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to reap expired sessions: %v", err)
+		return
+	}
+	metrics.SessionsReaped(reaped)
+	if reaped > 0 {
+		logger.FromContext(ctx).Debugf("Reaped %d expired session(s)", reaped)
+	}
+
+	sessions, users, err := s.sessionRepo.CountActiveSessions(ctx)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to count active sessions: %v", err)
+		return
+	}
+	metrics.SetActiveSessions(sessions, users)
+}
+
+// RequestPasswordReset issues a single-use reset token and emails it to the user.
+// It succeeds silently for unknown emails so the endpoint cannot be used to probe accounts.
+func (s *AuthServiceImpl) RequestPasswordReset(ctx context.Context, req models.ForgotPasswordRequest) error {
+	defer padResponseTime(time.Now())
+
+	if req.Email == "" {
+		logger.FromContext(ctx).Debug("Forgot-password request missing email.")
+		return invalid("service: email is required")
+	}
+
+	email, err := models.NormalizeEmail(req.Email)
+	if err != nil {
+		return invalid("service: invalid email: %w", err)
+	}
+	user, err := s.userRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to retrieve user by email '%s' for password reset: %v", req.Email, err)
+		return fmt.Errorf("service: failed to retrieve user for password reset: %w", err)
+	}
+	if user == nil {
+		logger.FromContext(ctx).Debugf("Password reset requested for unknown email '%s'.", req.Email)
+		return nil
+	}
+
+	token, err := generateResetToken()
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to generate password reset token for user '%s': %v", user.ID, err)
+		return fmt.Errorf("service: failed to generate reset token: %w", err)
+	}
+	if err := s.userRepo.CreatePasswordResetToken(ctx, user.ID, hashResetToken(token), time.Now().Add(passwordResetTTL)); err != nil {
+		logger.FromContext(ctx).Errorf("Failed to store password reset token for user '%s': %v", user.ID, err)
+		return fmt.Errorf("service: failed to store reset token: %w", err)
+	}
+
+	body := fmt.Sprintf("Use this code to reset your Pulse password: %s\nIt expires in %d minutes and can only be used once.",
+		token, int(passwordResetTTL.Minutes()))
+	if err := s.mailer.Send(user.Email, "Reset your Pulse password", body); err != nil {
+		logger.FromContext(ctx).Errorf("Failed to send password reset email to user '%s': %v", user.ID, err)
+		return fmt.Errorf("service: failed to send reset email: %w", err)
+	}
+
+	logger.FromContext(ctx).Infof("Password reset token issued for user: %s", user.ID)
+	return nil
+}
+
+// ResetPassword consumes a reset token, sets the new password, and invalidates all existing sessions.
+// It returns the ID of the user whose password was reset.
+func (s *AuthServiceImpl) ResetPassword(ctx context.Context, req models.ResetPasswordRequest) (uuid.UUID, error) {
+	if req.Token == "" || req.NewPassword == "" {
+		logger.FromContext(ctx).Debug("Reset-password request missing token or new password.")
+		return uuid.Nil, invalid("service: token and new password are required")
+	}
+
+	userID, err := s.userRepo.ConsumePasswordResetToken(ctx, hashResetToken(req.Token))
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to consume password reset token: %v", err)
+		return uuid.Nil, fmt.Errorf("service: failed to verify reset token: %w", err)
+	}
+	if userID == uuid.Nil {
+		logger.FromContext(ctx).Warn("Password reset attempted with an invalid or expired token.")
+		return uuid.Nil, invalidToken("service: invalid or expired reset token")
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to retrieve user '%s' for password reset: %v", userID, err)
+		return uuid.Nil, fmt.Errorf("service: failed to retrieve user for password reset: %w", err)
+	}
+	if user == nil {
+		logger.FromContext(ctx).Warnf("User '%s' for password reset token no longer exists.", userID)
+		return uuid.Nil, invalidToken("service: invalid or expired reset token")
+	}
+
+	if err := user.SetPassword(req.NewPassword); err != nil {
+		logger.FromContext(ctx).Errorf("Failed to hash new password for user '%s': %v", userID, err)
+		return uuid.Nil, fmt.Errorf("service: failed to hash new password: %w", err)
+	}
+	// JWT iat has second precision, so revoke from the start of the current second.
+	revokedAt := time.Now().UTC().Truncate(time.Second)
+	user.SessionsRevokedAt = &revokedAt
+
+	if err := s.userRepo.UpdateUser(ctx, user); err != nil {
+		logger.FromContext(ctx).Errorf("Failed to save reset password for user '%s': %v", userID, err)
+		return uuid.Nil, fmt.Errorf("service: failed to save new password: %w", err)
+	}
+	// SessionsRevokedAt already rejects the old tokens; deleting their sessions frees the user's session slots.
+	if err := s.sessionRepo.DeleteUserSessions(ctx, user.ID); err != nil {
+		logger.FromContext(ctx).Warnf("Failed to delete sessions of user '%s' after password reset: %v", userID, err)
+	}
+	// The token was emailed, so using it proves the user receives mail there.
+	if err := s.userRepo.MarkEmailVerified(ctx, user.ID, time.Now().UTC()); err != nil {
+		logger.FromContext(ctx).Warnf("Failed to mark email of user '%s' verified after password reset: %v", userID, err)
+	}
+
+	s.events.Record(ctx, user.ID, models.UserEventPasswordChanged, "Password reset", nil)
+	s.events.Notify(ctx, user.ID, models.UserEventSessionRevoked, "Signed out everywhere by a password reset",
+		map[string]string{"reason": "password_reset"})
+	logger.FromContext(ctx).Infof("Password reset completed for user: %s", userID)
+	return user.ID, nil
+}
+
+// ValidateToken parses a JWT and verifies its session is still open and not revoked
+// and that its user still exists. The returned claims carry the user's current role and scopes.
+func (s *AuthServiceImpl) ValidateToken(ctx context.Context, tokenString string) (*jwt.Claims, error) {
+	claims, err := jwt.ParseJWT(tokenString)
+	if err != nil {
+		return nil, fmt.Errorf("service: invalid token: %w", err)
+	}
+
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("service: invalid token subject")
+	}
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to retrieve user '%s' for token validation: %v", userID, err)
+		return nil, fmt.Errorf("service: failed to validate token: %w", err)
+	}
+	if user == nil {
+		return nil, fmt.Errorf("service: token user no longer exists")
+	}
+	if user.Status != models.StatusActive {
+		logger.FromContext(ctx).Debugf("Rejected token for %s account: %s", user.Status, userID)
+		return nil, inactive("service: account is %s", user.Status)
+	}
+	if user.SessionsRevokedAt != nil && (claims.IssuedAt == nil || claims.IssuedAt.Time.Before(*user.SessionsRevokedAt)) {
+		logger.FromContext(ctx).Debugf("Rejected revoked session token for user: %s", userID)
+		return nil, fmt.Errorf("service: session has been revoked")
+	}
+	// Sessions end on logout, on eviction by the session limit, and on password reset.
+	sessionID, err := uuid.Parse(claims.ID)
+	if err != nil {
+		return nil, fmt.Errorf("service: session has been revoked")
+	}
+	session, err := s.sessionRepo.GetSession(ctx, userID, sessionID)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to retrieve session '%s' for token validation: %v", sessionID, err)
+		return nil, fmt.Errorf("service: failed to validate token: %w", err)
+	}
+	if session == nil {
+		logger.FromContext(ctx).Debugf("Rejected token for ended session of user: %s", userID)
+		return nil, fmt.Errorf("service: session has been revoked")
+	}
+	// The stored role is authoritative over the one baked into the token.
+	claims.Role = user.Role
+	claims.Scopes = models.ScopesForRole(user.Role)
+	return claims, nil
+}
+
+// generateResetToken returns a random URL-safe token.
+func generateResetToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashResetToken returns the hex SHA-256 of a reset token, which is what gets persisted.
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
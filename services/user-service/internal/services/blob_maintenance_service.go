@@ -0,0 +1,94 @@
+// services/user-service/internal/services/blob_maintenance_service.go
+package services
+
+import (
+	"context"
+	"time"
+
+	"health-tracker-project/services/user-service/internal/blobstore"
+	"health-tracker-project/services/user-service/internal/config"
+	"health-tracker-project/services/user-service/internal/metrics"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// BlobMaintenanceServiceImpl implements the BlobMaintenanceService interface.
+type BlobMaintenanceServiceImpl struct {
+	store *blobstore.TieredStore
+}
+
+// NewBlobMaintenanceService creates a new instance of BlobMaintenanceServiceImpl.
+func NewBlobMaintenanceService(store *blobstore.TieredStore) *BlobMaintenanceServiceImpl {
+	return &BlobMaintenanceServiceImpl{store: store}
+}
+
+// ApplyLifecycle runs one pass of the runtime config's blob_storage lifecycle rules.
+func (s *BlobMaintenanceServiceImpl) ApplyLifecycle(ctx context.Context) (blobstore.LifecycleResult, error) {
+	var rules []blobstore.LifecycleRule
+	for _, rule := range config.Current().BlobStorage.Lifecycle {
+		rules = append(rules, blobstore.LifecycleRule{
+			Prefix:      rule.Prefix,
+			ColdAfter:   time.Duration(rule.ColdAfterDays) * 24 * time.Hour,
+			DeleteAfter: time.Duration(rule.DeleteAfterDays) * 24 * time.Hour,
+		})
+	}
+	result, err := s.store.ApplyLifecycle(rules, time.Now())
+	metrics.BlobLifecyclePass(result.Transitioned, result.Expired, result.Failed)
+	return result, err
+}
+
+// VerifySample checksums the runtime config's blob_storage integrity_sample_size files, picked at random.
+func (s *BlobMaintenanceServiceImpl) VerifySample(ctx context.Context) (blobstore.IntegrityResult, error) {
+	n := config.Current().BlobStorage.IntegritySampleSize
+	if n == 0 {
+		return blobstore.IntegrityResult{}, nil
+	}
+	result, err := s.store.VerifySample(n)
+	metrics.BlobIntegrityPass(result.Checked, len(result.Corrupt))
+	return result, err
+}
+
+// RunLifecycle applies the lifecycle rules every interval.
+func (s *BlobMaintenanceServiceImpl) RunLifecycle(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	for {
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C:
+		}
+		result, err := s.ApplyLifecycle(ctx)
+		if err != nil {
+			logger.FromContext(ctx).Errorf("Blob lifecycle pass failed on %d files: %v", result.Failed, err)
+		}
+		if result.Transitioned > 0 || result.Expired > 0 || result.StaleUploads > 0 {
+			logger.FromContext(ctx).Infof("Blob lifecycle moved %d files to cold storage, deleted %d, and removed %d interrupted uploads",
+				result.Transitioned, result.Expired, result.StaleUploads)
+		}
+	}
+}
+
+// RunIntegrityChecks verifies a random sample of stored files every interval. Corrupt files are
+// logged at error level, and so reported; they are left in place for an operator to restore.
+func (s *BlobMaintenanceServiceImpl) RunIntegrityChecks(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	for {
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C:
+		}
+		result, err := s.VerifySample(ctx)
+		if err != nil {
+			logger.FromContext(ctx).Errorf("Blob integrity pass could not read %d files: %v", result.Failed, err)
+		}
+		for _, key := range result.Corrupt {
+			logger.FromContext(ctx).Errorf("Blob %s no longer matches its checksum", key)
+		}
+		if result.Checked > 0 {
+			logger.FromContext(ctx).Infof("Blob integrity pass checked %d files: %d corrupt, %d given a first checksum",
+				result.Checked, len(result.Corrupt), result.Recorded)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+// services/user-service/internal/services/consent_service.go
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/repository"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+const (
+	defaultRevocationLimit = 100
+	maxRevocationLimit     = 500
+)
+
+// ConsentServiceImpl implements the ConsentService interface.
+type ConsentServiceImpl struct {
+	catalog     *models.IntegrationCatalog
+	consentRepo repository.ConsentRepository
+	events      UserEventService // Records grants and revocations on the user's own timeline
+}
+
+// NewConsentService creates a new instance of ConsentServiceImpl.
+func NewConsentService(catalog *models.IntegrationCatalog, consentRepo repository.ConsentRepository, events UserEventService) *ConsentServiceImpl {
+	return &ConsentServiceImpl{catalog: catalog, consentRepo: consentRepo, events: events}
+}
+
+// ListIntegrations returns the catalog of linkable integrations with their current terms.
+func (s *ConsentServiceImpl) ListIntegrations() []models.Integration {
+	return s.catalog.Integrations
+}
+
+// GrantConsent records a user's explicit acceptance of an integration's current terms, which must be
+// in place before the integration is linked. Accepting new terms supersedes the previous consent.
+func (s *ConsentServiceImpl) GrantConsent(ctx context.Context, userID uuid.UUID, provider string, req models.GrantConsentRequest, client models.ClientInfo) (*models.IntegrationConsent, error) {
+	integration := s.catalog.Find(provider)
+	if integration == nil {
+		return nil, notFound("service: unknown integration")
+	}
+	if !req.Accept {
+		return nil, invalid("service: consent must be explicitly accepted")
+	}
+	if req.TermsVersion != integration.TermsVersion {
+		return nil, conflict("service: terms_version does not match the current terms (%s)", integration.TermsVersion)
+	}
+
+	active, err := s.consentRepo.GetActiveConsent(ctx, userID, provider)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to get consent of user %s for %s: %v", userID, provider, err)
+		return nil, fmt.Errorf("service: failed to get consent: %w", err)
+	}
+	if active != nil && active.TermsVersion == integration.TermsVersion {
+		return nil, conflict("service: consent already granted")
+	}
+
+	consent := &models.IntegrationConsent{
+		ID:           uuid.New(),
+		UserID:       userID,
+		Provider:     provider,
+		TermsVersion: integration.TermsVersion,
+		Imports:      integration.Imports,
+		Exports:      integration.Exports,
+		IP:           client.IP,
+		UserAgent:    client.UserAgent,
+		AcceptedAt:   time.Now().UTC(),
+	}
+	if err := s.consentRepo.GrantConsent(ctx, consent); err != nil {
+		logger.FromContext(ctx).Errorf("Failed to record consent of user %s for %s: %v", userID, provider, err)
+		return nil, fmt.Errorf("service: failed to record consent: %w", err)
+	}
+
+	s.events.Record(ctx, userID, models.UserEventConsentGranted, "Allowed "+integration.Name+" to exchange data",
+		map[string]string{"provider": provider, "terms_version": integration.TermsVersion})
+	logger.FromContext(ctx).Infof("User %s consented to %s terms %s", userID, provider, integration.TermsVersion)
+	return consent, nil
+}
+
+// RevokeConsent withdraws a user's consent. The sync-service stops syncing the integration when it
+// sees the revocation, and deletes the data it imported when deleteData is set.
+func (s *ConsentServiceImpl) RevokeConsent(ctx context.Context, userID uuid.UUID, provider string, deleteData bool) (*models.IntegrationConsent, error) {
+	consent, err := s.consentRepo.RevokeConsent(ctx, userID, provider, deleteData, time.Now().UTC())
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to revoke consent of user %s for %s: %v", userID, provider, err)
+		return nil, fmt.Errorf("service: failed to revoke consent: %w", err)
+	}
+	if consent == nil {
+		return nil, notFound("service: no active consent")
+	}
+
+	name := provider
+	if integration := s.catalog.Find(provider); integration != nil {
+		name = integration.Name
+	}
+	summary := "Stopped sharing data with " + name
+	if deleteData {
+		summary += " and asked for imported data to be deleted"
+	}
+	s.events.Record(ctx, userID, models.UserEventConsentRevoked, summary,
+		map[string]string{"provider": provider, "delete_data": fmt.Sprint(deleteData)})
+	logger.FromContext(ctx).Infof("User %s revoked consent for %s (delete data: %t)", userID, provider, deleteData)
+	return consent, nil
+}
+
+// ListConsents returns a user's consent history, newest first.
+func (s *ConsentServiceImpl) ListConsents(ctx context.Context, userID uuid.UUID) ([]models.IntegrationConsent, error) {
+	consents, err := s.consentRepo.ListConsents(ctx, userID)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to list consents of user %s: %v", userID, err)
+		return nil, fmt.Errorf("service: failed to list consents: %w", err)
+	}
+	return consents, nil
+}
+
+// CheckConsent returns the consent that allows data to flow between a user and a provider. A consent
+// to terms that have since changed does not cover the new terms, so syncing must wait for the user
+// to accept them.
+func (s *ConsentServiceImpl) CheckConsent(ctx context.Context, userID uuid.UUID, provider string) (*models.IntegrationConsent, error) {
+	integration := s.catalog.Find(provider)
+	if integration == nil {
+		return nil, notFound("service: unknown integration")
+	}
+	consent, err := s.consentRepo.GetActiveConsent(ctx, userID, provider)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to get consent of user %s for %s: %v", userID, provider, err)
+		return nil, fmt.Errorf("service: failed to get consent: %w", err)
+	}
+	if consent == nil {
+		return nil, notFound("service: no active consent")
+	}
+	if consent.TermsVersion != integration.TermsVersion {
+		return nil, conflict("service: consent is for outdated terms")
+	}
+	return consent, nil
+}
+
+// ListRevocations returns revocations for the sync-service to act on, oldest first.
+func (s *ConsentServiceImpl) ListRevocations(ctx context.Context, filter models.ConsentRevocationFilter) ([]models.IntegrationConsent, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultRevocationLimit
+	}
+	if filter.Limit > maxRevocationLimit {
+		filter.Limit = maxRevocationLimit
+	}
+	revocations, err := s.consentRepo.ListRevocations(ctx, filter)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to list consent revocations: %v", err)
+		return nil, fmt.Errorf("service: failed to list consent revocations: %w", err)
+	}
+	return revocations, nil
+}
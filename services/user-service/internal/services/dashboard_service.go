@@ -0,0 +1,102 @@
+// services/user-service/internal/services/dashboard_service.go
+package services
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/repository"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// DashboardServiceImpl implements the DashboardService interface.
+type DashboardServiceImpl struct {
+	dashboardRepo repository.DashboardRepository
+}
+
+// NewDashboardService creates a new instance of DashboardServiceImpl.
+func NewDashboardService(dashboardRepo repository.DashboardRepository) *DashboardServiceImpl {
+	return &DashboardServiceImpl{dashboardRepo: dashboardRepo}
+}
+
+// GetLayout returns the user's saved layout, or the default layout if none was saved.
+// Widgets added to the catalogue after the layout was saved are appended hidden, and
+// widgets since removed from it are dropped.
+func (s *DashboardServiceImpl) GetLayout(ctx context.Context, userID uuid.UUID) (*models.DashboardLayout, error) {
+	layout, err := s.dashboardRepo.GetLayout(ctx, userID)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to retrieve dashboard layout for user %s: %v", userID, err)
+		return nil, fmt.Errorf("service: failed to retrieve dashboard layout: %w", err)
+	}
+	if layout == nil {
+		return models.DefaultDashboardLayout(), nil
+	}
+
+	widgets := make([]models.DashboardWidget, 0, len(models.DashboardWidgetTypes))
+	for _, w := range layout.Widgets {
+		if slices.Contains(models.DashboardWidgetTypes, w.Type) {
+			widgets = append(widgets, w)
+		}
+	}
+	for _, t := range models.DashboardWidgetTypes {
+		if !slices.ContainsFunc(widgets, func(w models.DashboardWidget) bool { return w.Type == t }) {
+			widgets = append(widgets, models.DashboardWidget{Type: t, Visible: false, DateRange: models.DefaultDashboardDateRange})
+		}
+	}
+	layout.Widgets = widgets
+	layout.SchemaVersion = models.DashboardSchemaVersion
+	return layout, nil
+}
+
+// SaveLayout validates and stores the user's layout, replacing any previous one.
+func (s *DashboardServiceImpl) SaveLayout(ctx context.Context, userID uuid.UUID, layout models.DashboardLayout) (*models.DashboardLayout, error) {
+	if err := validateDashboardLayout(&layout); err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	layout.Default = false
+	layout.UpdatedAt = &now
+	if err := s.dashboardRepo.SaveLayout(ctx, userID, &layout); err != nil {
+		logger.FromContext(ctx).Errorf("Failed to save dashboard layout for user %s: %v", userID, err)
+		return nil, fmt.Errorf("service: failed to save dashboard layout: %w", err)
+	}
+	return s.GetLayout(ctx, userID)
+}
+
+// ResetLayout discards the user's saved layout and returns the default.
+func (s *DashboardServiceImpl) ResetLayout(ctx context.Context, userID uuid.UUID) (*models.DashboardLayout, error) {
+	if err := s.dashboardRepo.DeleteLayout(ctx, userID); err != nil {
+		logger.FromContext(ctx).Errorf("Failed to reset dashboard layout for user %s: %v", userID, err)
+		return nil, fmt.Errorf("service: failed to reset dashboard layout: %w", err)
+	}
+	return models.DefaultDashboardLayout(), nil
+}
+
+// validateDashboardLayout checks a submitted layout against the current schema and fills in default date ranges.
+func validateDashboardLayout(layout *models.DashboardLayout) error {
+	if layout.SchemaVersion != models.DashboardSchemaVersion {
+		return invalid("service: invalid dashboard layout: schema_version must be %d", models.DashboardSchemaVersion)
+	}
+	seen := map[string]bool{}
+	for i := range layout.Widgets {
+		w := &layout.Widgets[i]
+		if !slices.Contains(models.DashboardWidgetTypes, w.Type) {
+			return invalid("service: invalid dashboard layout: unknown widget type %q", w.Type)
+		}
+		if seen[w.Type] {
+			return invalid("service: invalid dashboard layout: widget %q appears more than once", w.Type)
+		}
+		seen[w.Type] = true
+		if w.DateRange == "" {
+			w.DateRange = models.DefaultDashboardDateRange
+		}
+		if !slices.Contains(models.DashboardDateRanges, w.DateRange) {
+			return invalid("service: invalid dashboard layout: widget %q has unsupported date_range %q", w.Type, w.DateRange)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+// services/user-service/internal/services/data_summary_service.go
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/datasummary"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/repository"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// userServiceName is how this service's own categories are attributed in a data summary.
+const userServiceName = "user-service"
+
+// dataSummaryTimeout bounds how long a summary waits for other services; slower ones are reported unavailable.
+const dataSummaryTimeout = 3 * time.Second
+
+// DataSummaryServiceImpl implements the DataSummaryService interface.
+type DataSummaryServiceImpl struct {
+	userRepo repository.UserRepository
+	sources  []datasummary.Source // Other Pulse services, asked in parallel
+}
+
+// NewDataSummaryService creates a new instance of DataSummaryServiceImpl.
+func NewDataSummaryService(userRepo repository.UserRepository, sources []datasummary.Source) *DataSummaryServiceImpl {
+	return &DataSummaryServiceImpl{userRepo: userRepo, sources: sources}
+}
+
+// GetDataSummary assembles the user's data categories: this service's from the database, then those of
+// every source, in the order they are configured. A source that fails or is too slow is listed as
+// unavailable rather than failing the summary, so the settings screen still shows the rest.
+func (s *DataSummaryServiceImpl) GetDataSummary(ctx context.Context, userID uuid.UUID) (*models.DataSummary, error) {
+	local, err := s.userRepo.SummarizeUserData(ctx, userID)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to summarize data of user %s: %v", userID, err)
+		return nil, fmt.Errorf("service: failed to summarize user data: %w", err)
+	}
+	if local == nil {
+		return nil, notFound("service: user not found")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, dataSummaryTimeout)
+	defer cancel()
+	remote := make([][]models.DataCategory, len(s.sources))
+	failed := make([]error, len(s.sources))
+	var wg sync.WaitGroup
+	for i, source := range s.sources {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			remote[i], failed[i] = source.Summarize(ctx, userID)
+		}()
+	}
+	wg.Wait()
+
+	summary := &models.DataSummary{UserID: userID, GeneratedAt: time.Now().UTC(), Categories: []models.DataCategory{}}
+	for _, c := range local {
+		c.Service = userServiceName
+		summary.Categories = append(summary.Categories, c)
+	}
+	for i, source := range s.sources {
+		if failed[i] != nil {
+			logger.FromContext(ctx).Warnf("Data summary of user %s is missing %s: %v", userID, source.Name(), failed[i])
+			summary.Unavailable = append(summary.Unavailable, source.Name())
+			continue
+		}
+		summary.Categories = append(summary.Categories, remote[i]...)
+	}
+	for _, c := range summary.Categories {
+		summary.TotalBytes += c.Bytes
+	}
+	return summary, nil
+}
@@ -0,0 +1,308 @@
+// services/user-service/internal/services/developer_app_service.go
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/config"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/repository"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// maxDeveloperAppUsageDays bounds the usage history returned at once.
+const maxDeveloperAppUsageDays = 90
+
+// DeveloperAppServiceImpl implements the DeveloperAppService interface.
+type DeveloperAppServiceImpl struct {
+	appRepo  repository.DeveloperAppRepository
+	userRepo repository.UserRepository
+	sandbox  bool // Debug recording is only offered outside production
+}
+
+// NewDeveloperAppService creates a new instance of DeveloperAppServiceImpl. sandbox enables debug recording.
+func NewDeveloperAppService(appRepo repository.DeveloperAppRepository, userRepo repository.UserRepository, sandbox bool) *DeveloperAppServiceImpl {
+	return &DeveloperAppServiceImpl{appRepo: appRepo, userRepo: userRepo, sandbox: sandbox}
+}
+
+// CreateApp registers an app for the caller and returns it with its API key, which is not shown again.
+func (s *DeveloperAppServiceImpl) CreateApp(ctx context.Context, ownerID uuid.UUID, req models.CreateDeveloperAppRequest) (*models.DeveloperAppCredentials, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > 100 {
+		return nil, invalid("service: invalid developer app: name is required and at most 100 characters")
+	}
+	if len(req.Description) > 500 {
+		return nil, invalid("service: invalid developer app: description is at most 500 characters")
+	}
+	apps, err := s.ListApps(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	active := 0
+	for _, app := range apps {
+		if app.RevokedAt == nil {
+			active++
+		}
+	}
+	if active >= models.MaxDeveloperApps {
+		return nil, conflict("service: at most %d developer apps per user; revoke one first", models.MaxDeveloperApps)
+	}
+
+	app := &models.DeveloperApp{
+		ID:          uuid.New(),
+		OwnerID:     ownerID,
+		Name:        name,
+		Description: strings.TrimSpace(req.Description),
+		CreatedAt:   time.Now().UTC(),
+	}
+	key, err := issueAPIKey(app)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.appRepo.CreateApp(ctx, app); err != nil {
+		logger.FromContext(ctx).Errorf("Failed to create developer app for user %s: %v", ownerID, err)
+		return nil, fmt.Errorf("service: failed to create developer app: %w", err)
+	}
+	logger.FromContext(ctx).Infof("User %s registered developer app %s", ownerID, app.ID)
+	return &models.DeveloperAppCredentials{App: *app, APIKey: key}, nil
+}
+
+// ListApps returns the caller's apps, oldest first, including revoked ones.
+func (s *DeveloperAppServiceImpl) ListApps(ctx context.Context, ownerID uuid.UUID) ([]models.DeveloperApp, error) {
+	apps, err := s.appRepo.ListApps(ctx, ownerID)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to list developer apps for user %s: %v", ownerID, err)
+		return nil, fmt.Errorf("service: failed to list developer apps: %w", err)
+	}
+	return apps, nil
+}
+
+// RotateKey replaces an app's API key. The old key stops working at once.
+func (s *DeveloperAppServiceImpl) RotateKey(ctx context.Context, ownerID, appID uuid.UUID) (*models.DeveloperAppCredentials, error) {
+	app, err := s.ownedApp(ctx, ownerID, appID)
+	if err != nil {
+		return nil, err
+	}
+	if app.RevokedAt != nil {
+		return nil, conflict("service: developer app is revoked")
+	}
+	key, err := issueAPIKey(app)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	app.KeyRotatedAt = &now
+	if err := s.appRepo.UpdateApp(ctx, app); err != nil {
+		logger.FromContext(ctx).Errorf("Failed to rotate key of developer app %s: %v", appID, err)
+		return nil, fmt.Errorf("service: failed to rotate developer app key: %w", err)
+	}
+	logger.FromContext(ctx).Infof("User %s rotated the key of developer app %s", ownerID, appID)
+	return &models.DeveloperAppCredentials{App: *app, APIKey: key}, nil
+}
+
+// RevokeApp permanently disables an app's key. Its usage history is kept. Revoking twice is a no-op.
+func (s *DeveloperAppServiceImpl) RevokeApp(ctx context.Context, ownerID, appID uuid.UUID) (*models.DeveloperApp, error) {
+	app, err := s.ownedApp(ctx, ownerID, appID)
+	if err != nil {
+		return nil, err
+	}
+	if app.RevokedAt != nil {
+		return app, nil
+	}
+	now := time.Now().UTC()
+	app.RevokedAt = &now
+	app.DebugUntil = nil
+	if err := s.appRepo.UpdateApp(ctx, app); err != nil {
+		logger.FromContext(ctx).Errorf("Failed to revoke developer app %s: %v", appID, err)
+		return nil, fmt.Errorf("service: failed to revoke developer app: %w", err)
+	}
+	if err := s.appRepo.DeleteRecordings(ctx, appID); err != nil {
+		logger.FromContext(ctx).Errorf("Failed to delete recordings of revoked developer app %s: %v", appID, err)
+	}
+	logger.FromContext(ctx).Infof("User %s revoked developer app %s", ownerID, appID)
+	return app, nil
+}
+
+// GetUsage returns an app's public API usage per UTC day and route over the last days days, today included.
+func (s *DeveloperAppServiceImpl) GetUsage(ctx context.Context, ownerID, appID uuid.UUID, days int) ([]models.DeveloperAppUsage, error) {
+	if days < 1 || days > maxDeveloperAppUsageDays {
+		return nil, invalid("service: invalid developer app usage range: days must be between 1 and %d", maxDeveloperAppUsageDays)
+	}
+	if _, err := s.ownedApp(ctx, ownerID, appID); err != nil {
+		return nil, err
+	}
+	until := time.Now().UTC()
+	usage, err := s.appRepo.ListUsage(ctx, appID, until.AddDate(0, 0, 1-days), until)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to list usage of developer app %s: %v", appID, err)
+		return nil, fmt.Errorf("service: failed to list developer app usage: %w", err)
+	}
+	return usage, nil
+}
+
+// Authenticate returns the app an API key belongs to. Keys of revoked apps, and of apps whose owner
+// cannot sign in, are rejected.
+func (s *DeveloperAppServiceImpl) Authenticate(ctx context.Context, apiKey string) (*models.DeveloperApp, error) {
+	if !strings.HasPrefix(apiKey, models.APIKeyPrefix) {
+		return nil, badCredentials("service: invalid API key")
+	}
+	app, err := s.appRepo.GetAppByKeyHash(ctx, hashResetToken(apiKey))
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to look up API key: %w", err)
+	}
+	if app == nil || app.RevokedAt != nil {
+		return nil, badCredentials("service: invalid API key")
+	}
+	owner, err := s.userRepo.GetUserByID(ctx, app.OwnerID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to retrieve developer app owner: %w", err)
+	}
+	if owner == nil || owner.Status != models.StatusActive {
+		return nil, badCredentials("service: invalid API key")
+	}
+	return app, nil
+}
+
+// QuotaExceeded reports whether an app has used up today's public_api_daily_quota.
+func (s *DeveloperAppServiceImpl) QuotaExceeded(ctx context.Context, appID uuid.UUID) (bool, error) {
+	quota := config.Current().PublicAPIDailyQuota
+	if quota <= 0 {
+		return false, nil
+	}
+	n, err := s.appRepo.CountRequests(ctx, appID, time.Now().UTC())
+	if err != nil {
+		return false, fmt.Errorf("service: failed to count developer app requests: %w", err)
+	}
+	return n >= int64(quota), nil
+}
+
+// RecordRequest adds one public API request to the app's usage for today. throttled requests were
+// refused before reaching the handler. Failures are logged, not returned.
+func (s *DeveloperAppServiceImpl) RecordRequest(ctx context.Context, appID uuid.UUID, route string, status int, throttled bool) {
+	usage := models.DeveloperAppUsage{Date: time.Now().UTC().Format(time.DateOnly), Route: route}
+	switch {
+	case throttled:
+		usage.Throttled = 1
+	case status >= http.StatusInternalServerError:
+		usage.Requests, usage.ServerErrors = 1, 1
+	case status >= http.StatusBadRequest:
+		usage.Requests, usage.ClientErrors = 1, 1
+	default:
+		usage.Requests = 1
+	}
+	if err := s.appRepo.AddUsage(context.WithoutCancel(ctx), appID, usage); err != nil {
+		logger.FromContext(ctx).Errorf("Failed to record usage of developer app %s: %v", appID, err)
+	}
+}
+
+// StartDebug turns on debug recording of an app's public API requests for models.DebugRecordingDuration,
+// discarding earlier recordings. Starting it again while it is on extends it. Only offered in the sandbox.
+func (s *DeveloperAppServiceImpl) StartDebug(ctx context.Context, ownerID, appID uuid.UUID) (*models.DeveloperApp, error) {
+	if !s.sandbox {
+		return nil, forbidden("service: debug recording is only available in the sandbox")
+	}
+	app, err := s.ownedApp(ctx, ownerID, appID)
+	if err != nil {
+		return nil, err
+	}
+	if app.RevokedAt != nil {
+		return nil, conflict("service: developer app is revoked")
+	}
+	now := time.Now().UTC()
+	if !app.Debugging(now) {
+		if err := s.appRepo.DeleteRecordings(ctx, appID); err != nil {
+			return nil, fmt.Errorf("service: failed to clear developer app recordings: %w", err)
+		}
+	}
+	until := now.Add(models.DebugRecordingDuration)
+	app.DebugUntil = &until
+	if err := s.appRepo.UpdateApp(ctx, app); err != nil {
+		logger.FromContext(ctx).Errorf("Failed to start debug recording of developer app %s: %v", appID, err)
+		return nil, fmt.Errorf("service: failed to start debug recording: %w", err)
+	}
+	logger.FromContext(ctx).Infof("User %s started debug recording of developer app %s until %s", ownerID, appID, until.Format(time.RFC3339))
+	return app, nil
+}
+
+// StopDebug turns off debug recording of an app and deletes its recordings.
+func (s *DeveloperAppServiceImpl) StopDebug(ctx context.Context, ownerID, appID uuid.UUID) (*models.DeveloperApp, error) {
+	app, err := s.ownedApp(ctx, ownerID, appID)
+	if err != nil {
+		return nil, err
+	}
+	if app.DebugUntil != nil {
+		app.DebugUntil = nil
+		if err := s.appRepo.UpdateApp(ctx, app); err != nil {
+			logger.FromContext(ctx).Errorf("Failed to stop debug recording of developer app %s: %v", appID, err)
+			return nil, fmt.Errorf("service: failed to stop debug recording: %w", err)
+		}
+	}
+	if err := s.appRepo.DeleteRecordings(ctx, appID); err != nil {
+		return nil, fmt.Errorf("service: failed to delete developer app recordings: %w", err)
+	}
+	logger.FromContext(ctx).Infof("User %s stopped debug recording of developer app %s", ownerID, appID)
+	return app, nil
+}
+
+// ExportHAR returns an app's recorded exchanges, oldest first, as a HAR document. Recordings stay
+// available after the recording window ends, until the next StartDebug or StopDebug.
+func (s *DeveloperAppServiceImpl) ExportHAR(ctx context.Context, ownerID, appID uuid.UUID) (*models.HAR, error) {
+	app, err := s.ownedApp(ctx, ownerID, appID)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := s.appRepo.ListRecordings(ctx, appID)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to list recordings of developer app %s: %v", appID, err)
+		return nil, fmt.Errorf("service: failed to list developer app recordings: %w", err)
+	}
+	return &models.HAR{Log: models.HARLog{
+		Version: "1.2",
+		Creator: models.HARCreator{Name: "Pulse user-service", Version: "1"},
+		Entries: entries,
+		Comment: fmt.Sprintf("Public API requests of developer app %q (%s); credentials redacted", app.Name, app.ID),
+	}}, nil
+}
+
+// Recording reports whether an app's public API requests should be recorded now.
+func (s *DeveloperAppServiceImpl) Recording(app *models.DeveloperApp) bool {
+	return s.sandbox && app.Debugging(time.Now())
+}
+
+// RecordExchange stores a recorded exchange of an app, keeping the newest models.MaxDebugRecordings.
+// Failures are logged, not returned.
+func (s *DeveloperAppServiceImpl) RecordExchange(ctx context.Context, appID uuid.UUID, entry models.HAREntry) {
+	if err := s.appRepo.AddRecording(context.WithoutCancel(ctx), appID, entry, models.MaxDebugRecordings); err != nil {
+		logger.FromContext(ctx).Errorf("Failed to record exchange of developer app %s: %v", appID, err)
+	}
+}
+
+// ownedApp returns the caller's app, or a not found error if it is not theirs.
+func (s *DeveloperAppServiceImpl) ownedApp(ctx context.Context, ownerID, appID uuid.UUID) (*models.DeveloperApp, error) {
+	app, err := s.appRepo.GetApp(ctx, appID)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to retrieve developer app %s: %v", appID, err)
+		return nil, fmt.Errorf("service: failed to retrieve developer app: %w", err)
+	}
+	if app == nil || app.OwnerID != ownerID {
+		return nil, notFound("service: developer app not found")
+	}
+	return app, nil
+}
+
+// issueAPIKey sets a new key on app and returns it. Keys are generated and hashed like password reset tokens.
+func issueAPIKey(app *models.DeveloperApp) (string, error) {
+	token, err := generateResetToken()
+	if err != nil {
+		return "", fmt.Errorf("service: failed to generate API key: %w", err)
+	}
+	key := models.APIKeyPrefix + token
+	app.KeyPrefix = key[:len(models.APIKeyPrefix)+8]
+	app.KeyHash = hashResetToken(key)
+	return key, nil
+}
@@ -0,0 +1,161 @@
+// services/user-service/internal/services/email_deliverability_service.go
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/mailer"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/repository"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+const emailVerificationTTL = 24 * time.Hour // How long an emailed verification code can be used
+
+// EmailDeliverabilityServiceImpl implements the EmailDeliverabilityService interface. It is also the
+// mailer.Suppressions of the Mailer every other service sends with.
+type EmailDeliverabilityServiceImpl struct {
+	userRepo repository.UserRepository
+	mailer   mailer.Mailer    // Not suppressing: verification codes must reach suppressed addresses
+	events   UserEventService // Records bounces, complaints, and re-verifications on the user's own timeline
+}
+
+// NewEmailDeliverabilityService creates a new instance of EmailDeliverabilityServiceImpl. mail must be
+// the provider's Mailer itself, not one wrapped in a mailer.SuppressingMailer.
+func NewEmailDeliverabilityService(userRepo repository.UserRepository, mail mailer.Mailer, events UserEventService) *EmailDeliverabilityServiceImpl {
+	return &EmailDeliverabilityServiceImpl{userRepo: userRepo, mailer: mail, events: events}
+}
+
+// HandleEvents applies bounces and complaints reported by a mail provider to the users whose current
+// email they are about, and returns how many it applied. Events about unknown or former addresses are
+// skipped.
+func (s *EmailDeliverabilityServiceImpl) HandleEvents(ctx context.Context, events []models.EmailDeliveryEvent) (int, error) {
+	applied := 0
+	for _, event := range events {
+		email, err := models.NormalizeEmail(event.Email)
+		if err != nil {
+			logger.FromContext(ctx).Debugf("Skipping %s %s about malformed email '%s'", event.Provider, event.Kind, event.Email)
+			continue
+		}
+		user, err := s.userRepo.GetUserByEmail(ctx, email)
+		if err != nil {
+			logger.FromContext(ctx).Errorf("Failed to retrieve user by email '%s' for a %s: %v", email, event.Kind, err)
+			return applied, fmt.Errorf("service: failed to retrieve user for email delivery event: %w", err)
+		}
+		if user == nil || user.Email != email {
+			logger.FromContext(ctx).Debugf("Skipping %s %s about an email no user has: %s", event.Provider, event.Kind, email)
+			continue
+		}
+
+		at := event.OccurredAt
+		if at.IsZero() {
+			at = time.Now().UTC()
+		}
+		status, err := s.userRepo.RecordEmailDeliveryEvent(ctx, user.ID, email, event.Kind, at)
+		if err != nil {
+			logger.FromContext(ctx).Errorf("Failed to record %s for user %s: %v", event.Kind, user.ID, err)
+			return applied, fmt.Errorf("service: failed to record email delivery event: %w", err)
+		}
+		if status == "" {
+			continue // The email changed since the lookup
+		}
+		applied++
+		if status != user.EmailStatus {
+			logger.FromContext(ctx).Infof("Email of user %s is now %s after a %s reported by %s", user.ID, status, event.Kind, event.Provider)
+		}
+		s.events.Record(ctx, user.ID, models.UserEventEmailUndeliverable, "Mail to your email "+emailEventSummaries[event.Kind],
+			map[string]string{"type": event.Kind, "email_status": status, "provider": event.Provider})
+	}
+	return applied, nil
+}
+
+// emailEventSummaries complete the timeline summary of each kind of delivery event.
+var emailEventSummaries = map[string]string{
+	models.EmailHardBounce: "bounced",
+	models.EmailSoftBounce: "was temporarily refused",
+	models.EmailComplaint:  "was reported as spam",
+}
+
+// Suppressed reports whether mail to an address must not be sent: it is a user's current email, and
+// has bounced or been complained about since it was last verified.
+func (s *EmailDeliverabilityServiceImpl) Suppressed(to string) (bool, error) {
+	email, err := models.NormalizeEmail(to)
+	if err != nil {
+		return false, nil
+	}
+	user, err := s.userRepo.GetUserByEmail(context.Background(), email) // mailer.Suppressions passes no context
+	if err != nil {
+		return false, err
+	}
+	return user != nil && user.Email == email && user.EmailSuppressed(), nil
+}
+
+// RequestVerification emails the user a code that verifies their current email, even when mail to it
+// is suppressed.
+func (s *EmailDeliverabilityServiceImpl) RequestVerification(ctx context.Context, userID uuid.UUID) error {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to retrieve user '%s' for email verification: %v", userID, err)
+		return fmt.Errorf("service: failed to retrieve user for email verification: %w", err)
+	}
+	if user == nil {
+		return notFound("service: user not found")
+	}
+
+	token, err := generateResetToken()
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to generate email verification token for user '%s': %v", userID, err)
+		return fmt.Errorf("service: failed to generate verification token: %w", err)
+	}
+	if err := s.userRepo.CreateEmailVerificationToken(ctx, userID, user.Email, hashResetToken(token), time.Now().Add(emailVerificationTTL)); err != nil {
+		logger.FromContext(ctx).Errorf("Failed to store email verification token for user '%s': %v", userID, err)
+		return fmt.Errorf("service: failed to store verification token: %w", err)
+	}
+
+	body := fmt.Sprintf("Use this code to confirm that Pulse can email you here: %s\nIt expires in %d hours and can only be used once.",
+		token, int(emailVerificationTTL.Hours()))
+	if err := s.mailer.Send(user.Email, "Confirm your email for Pulse", body); err != nil {
+		logger.FromContext(ctx).Errorf("Failed to send email verification to user '%s': %v", userID, err)
+		return fmt.Errorf("service: failed to send verification email: %w", err)
+	}
+	logger.FromContext(ctx).Infof("Email verification code issued for user: %s", userID)
+	return nil
+}
+
+// ConfirmVerification consumes a code sent by RequestVerification. The email it was sent to becomes
+// verified and deliverable again, if it is still the user's.
+func (s *EmailDeliverabilityServiceImpl) ConfirmVerification(ctx context.Context, userID uuid.UUID, token string) (*models.UserResponse, error) {
+	if token == "" {
+		return nil, invalid("service: token is required")
+	}
+	email, err := s.userRepo.ConsumeEmailVerificationToken(ctx, userID, hashResetToken(token))
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to consume email verification token of user '%s': %v", userID, err)
+		return nil, fmt.Errorf("service: failed to verify token: %w", err)
+	}
+	if email == "" {
+		logger.FromContext(ctx).Warnf("Email verification attempted by user %s with an invalid or expired token.", userID)
+		return nil, invalidToken("service: invalid or expired verification token")
+	}
+	restored, err := s.userRepo.RestoreEmailDeliverability(ctx, userID, email, time.Now().UTC())
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to restore email deliverability of user '%s': %v", userID, err)
+		return nil, fmt.Errorf("service: failed to verify email: %w", err)
+	}
+	if !restored {
+		return nil, invalidToken("service: invalid or expired verification token") // Sent to an email the user has since changed
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil || user == nil {
+		logger.FromContext(ctx).Errorf("Failed to retrieve user '%s' after email verification: %v", userID, err)
+		return nil, fmt.Errorf("service: failed to retrieve user after email verification: %w", err)
+	}
+	s.events.Record(ctx, userID, models.UserEventEmailReverified, "Email verified", nil)
+	logger.FromContext(ctx).Infof("Email of user %s verified again", userID)
+	resp := user.ToUserResponse()
+	return &resp, nil
+}
@@ -0,0 +1,95 @@
+// services/user-service/internal/services/errors.go
+package services
+
+import (
+	"errors"
+
+	"health-tracker-project/services/user-service/internal/repository"
+)
+
+// The errors the handlers map to statuses with errors.Is: ErrNotFound to 404 Not Found, ErrDuplicateEmail
+// and ErrConflict to 409 Conflict; ErrDuplicateUsername is a kind of ErrConflict with a code of its own.
+// Services return them wrapped in their own "service: ..." message, which the handlers may show; the
+// repository's errors of the same kinds pass through.
+var (
+	ErrNotFound          = repository.ErrNotFound
+	ErrDuplicateEmail    = repository.ErrDuplicateEmail
+	ErrDuplicateUsername = repository.ErrDuplicateUsername
+	ErrConflict          = repository.ErrConflict
+)
+
+// The errors the handlers map to the other client error statuses, also with errors.Is. The message of
+// the service's error says what is wrong, and is shown unless the route words its own.
+var (
+	ErrInvalidInput       = errors.New("invalid input")                            // A field missing or out of range; 400 Bad Request
+	ErrInvalidToken       = errors.New("invalid or expired token")                 // A reset, link, or verification token; the route picks the status
+	ErrInvalidCredentials = errors.New("invalid credentials")                      // A password, code, or API key; 401 Unauthorized
+	ErrForbidden          = errors.New("not allowed")                              // Refused to this caller, whatever they send; 403 Forbidden
+	ErrTooLarge           = errors.New("too large")                                // An upload over its limit; 413 Content Too Large
+	ErrUnsupportedType    = errors.New("unsupported content type")                 // An upload of a type not accepted; 415 Unsupported Media Type
+	ErrAccountInactive    = repository.Errorf(ErrForbidden, "account is inactive") // Suspended or deactivated; a kind of ErrForbidden
+)
+
+// ErrNotApplied is the outcome of a bulk operation that was fine but rolled back because another
+// operation of its request failed; the handlers map it to 424 Failed Dependency.
+var ErrNotApplied = errors.New("service: not applied because another operation failed")
+
+// notFound returns an error with the formatted message that errors.Is matches to ErrNotFound.
+func notFound(format string, args ...any) error {
+	return repository.Errorf(ErrNotFound, format, args...)
+}
+
+// invalid returns an error with the formatted message that errors.Is matches to ErrInvalidInput. A %w
+// verb wraps its operand as well.
+func invalid(format string, args ...any) error {
+	return repository.Errorf(ErrInvalidInput, format, args...)
+}
+
+// forbidden returns an error with the formatted message that errors.Is matches to ErrForbidden.
+func forbidden(format string, args ...any) error {
+	return repository.Errorf(ErrForbidden, format, args...)
+}
+
+// inactive returns the error of an account that is not active, which errors.Is matches to
+// ErrAccountInactive and ErrForbidden.
+func inactive(format string, args ...any) error {
+	return repository.Errorf(ErrAccountInactive, format, args...)
+}
+
+// invalidToken returns an error with the formatted message that errors.Is matches to ErrInvalidToken.
+func invalidToken(format string, args ...any) error {
+	return repository.Errorf(ErrInvalidToken, format, args...)
+}
+
+// badCredentials returns an error with the formatted message that errors.Is matches to
+// ErrInvalidCredentials.
+func badCredentials(format string, args ...any) error {
+	return repository.Errorf(ErrInvalidCredentials, format, args...)
+}
+
+// tooLarge returns an error with the formatted message that errors.Is matches to ErrTooLarge.
+func tooLarge(format string, args ...any) error {
+	return repository.Errorf(ErrTooLarge, format, args...)
+}
+
+// unsupportedType returns an error with the formatted message that errors.Is matches to
+// ErrUnsupportedType.
+func unsupportedType(format string, args ...any) error {
+	return repository.Errorf(ErrUnsupportedType, format, args...)
+}
+
+// conflict returns an error with the formatted message that errors.Is matches to ErrConflict.
+func conflict(format string, args ...any) error {
+	return repository.Errorf(ErrConflict, format, args...)
+}
+
+// duplicateEmail returns an error with the message that errors.Is matches to ErrDuplicateEmail.
+func duplicateEmail(message string) error {
+	return repository.Errorf(ErrDuplicateEmail, "%s", message)
+}
+
+// duplicateUsername returns an error with the formatted message that errors.Is matches to
+// ErrDuplicateUsername and ErrConflict.
+func duplicateUsername(format string, args ...any) error {
+	return repository.Errorf(ErrDuplicateUsername, format, args...)
+}
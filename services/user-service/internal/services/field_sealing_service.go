@@ -0,0 +1,64 @@
+// services/user-service/internal/services/field_sealing_service.go
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"health-tracker-project/services/user-service/internal/metrics"
+	"health-tracker-project/services/user-service/internal/repository"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// resealBatchSize caps the users resealed per repository call, so a pass after a key rotation is
+// spread over many short statements rather than one long one.
+const resealBatchSize = 100
+
+// FieldSealingServiceImpl implements the FieldSealingService interface.
+type FieldSealingServiceImpl struct {
+	userRepo repository.UserRepository
+}
+
+// NewFieldSealingService creates a new instance of FieldSealingServiceImpl.
+func NewFieldSealingService(userRepo repository.UserRepository) *FieldSealingServiceImpl {
+	return &FieldSealingServiceImpl{userRepo: userRepo}
+}
+
+// ResealPending seals, under the current key, the health fields of every user still stored in plaintext
+// or under a previous key, a batch at a time. It does nothing without PII_ENCRYPTION_KEYS. Replicas may
+// run it at once: a user is only updated if their row is still as it was read.
+func (s *FieldSealingServiceImpl) ResealPending(ctx context.Context) (int, error) {
+	resealed := 0
+	for {
+		n, err := s.userRepo.ResealHealthFields(ctx, resealBatchSize)
+		resealed += n
+		metrics.HealthFieldsResealed(n)
+		if err != nil {
+			metrics.ResealFailed()
+			return resealed, fmt.Errorf("service: failed to reseal health fields: %w", err)
+		}
+		if n < resealBatchSize {
+			return resealed, nil
+		}
+	}
+}
+
+// RunResealing reseals pending health fields on every tick of interval. Blocks until ctx is done.
+func (s *FieldSealingServiceImpl) RunResealing(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	for {
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C:
+		}
+		resealed, err := s.ResealPending(ctx)
+		if err != nil {
+			logger.FromContext(ctx).Errorf("Resealing stopped after %d users: %v", resealed, err)
+		} else if resealed > 0 {
+			logger.FromContext(ctx).Infof("Resealed the health fields of %d users", resealed)
+		}
+	}
+}
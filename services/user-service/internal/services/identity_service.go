@@ -0,0 +1,250 @@
+// services/user-service/internal/services/identity_service.go
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/mailer"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/repository"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+const (
+	identityLinkTTL         = 15 * time.Minute // How long a link challenge can be completed
+	maxIdentityLinkAttempts = 5                // Failed proofs before the challenge is dropped
+)
+
+// IdentityServiceImpl implements the IdentityService interface.
+type IdentityServiceImpl struct {
+	userRepo     repository.UserRepository
+	identityRepo repository.IdentityRepository
+	mailer       mailer.Mailer    // Delivers the email code for link challenges
+	events       UserEventService // Records links on the user's own timeline
+}
+
+// NewIdentityService creates a new instance of IdentityServiceImpl.
+func NewIdentityService(userRepo repository.UserRepository, identityRepo repository.IdentityRepository, mailer mailer.Mailer, events UserEventService) *IdentityServiceImpl {
+	return &IdentityServiceImpl{userRepo: userRepo, identityRepo: identityRepo, mailer: mailer, events: events}
+}
+
+// ResolveExternal maps a verified SSO identity to a Pulse user.
+// A linked identity signs in its user. An unknown email provisions a new user with the identity linked.
+// An email that already has an account returns a link challenge instead, so a provider asserting an
+// email is never enough on its own to take over an account created another way.
+func (s *IdentityServiceImpl) ResolveExternal(ctx context.Context, ext models.ExternalIdentity) (*models.User, *models.IdentityLinkChallenge, error) {
+	linked, err := s.identityRepo.GetIdentity(ctx, ext.Issuer, ext.Subject)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to look up %s identity '%s' from %s: %v", ext.Method, ext.Subject, ext.Issuer, err)
+		return nil, nil, fmt.Errorf("service: failed to look up identity: %w", err)
+	}
+	if linked != nil {
+		user, err := s.userRepo.GetUserByID(ctx, linked.UserID)
+		if err != nil {
+			logger.FromContext(ctx).Errorf("Failed to retrieve user '%s' for linked identity: %v", linked.UserID, err)
+			return nil, nil, fmt.Errorf("service: failed to retrieve user for authentication: %w", err)
+		}
+		if user != nil {
+			return user, nil, nil
+		}
+	}
+
+	user, err := s.userRepo.GetUserByEmail(ctx, ext.Email)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to retrieve user by email '%s' for %s sign-in: %v", ext.Email, ext.Method, err)
+		return nil, nil, fmt.Errorf("service: failed to retrieve user for authentication: %w", err)
+	}
+	if user == nil {
+		user, err = s.provision(ctx, ext)
+		return user, nil, err
+	}
+	// Inactive accounts are turned away by the caller; there is nothing to link to.
+	if user.Status != models.StatusActive {
+		return user, nil, nil
+	}
+
+	challenge, err := s.startLink(ctx, user, ext)
+	if err != nil {
+		return nil, nil, err
+	}
+	return nil, challenge, nil
+}
+
+// provision creates a user for a first-time SSO sign-in, with the identity linked and an unusable random password.
+func (s *IdentityServiceImpl) provision(ctx context.Context, ext models.ExternalIdentity) (*models.User, error) {
+	name := ext.Name
+	if name == "" {
+		name = ext.Email
+	}
+	randomPassword, err := generateResetToken() // Never disclosed; the user can set one via forgot-password
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to generate password: %w", err)
+	}
+	user, err := models.NewUser(name, ext.Email, randomPassword)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to create user model for %s sign-in: %v", ext.Method, err)
+		return nil, fmt.Errorf("service: failed to create new user model: %w", err)
+	}
+	verifiedAt := time.Now().UTC() // The provider vouches for the email
+	user.EmailVerifiedAt = &verifiedAt
+	if err := s.userRepo.CreateUser(ctx, user); err != nil {
+		logger.FromContext(ctx).Errorf("Failed to save %s user '%s': %v", ext.Method, user.ID, err)
+		return nil, fmt.Errorf("service: failed to save new user: %w", err)
+	}
+	if _, err := s.link(ctx, user.ID, ext); err != nil {
+		return nil, err
+	}
+	s.events.Record(ctx, user.ID, models.UserEventRegistered, "Account registered via single sign-on",
+		map[string]string{"issuer": ext.Issuer})
+	logger.FromContext(ctx).Infof("User provisioned from %s issuer %s: ID %s", ext.Method, ext.Issuer, user.ID)
+	return user, nil
+}
+
+// startLink stores a link request for an existing account and emails the account a one-time code.
+func (s *IdentityServiceImpl) startLink(ctx context.Context, user *models.User, ext models.ExternalIdentity) (*models.IdentityLinkChallenge, error) {
+	token, err := generateResetToken()
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to generate link token: %w", err)
+	}
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to generate link code: %w", err)
+	}
+	code := fmt.Sprintf("%06d", n.Int64())
+
+	now := time.Now().UTC()
+	req := &models.IdentityLinkRequest{
+		ID:        uuid.New(),
+		TokenHash: hashResetToken(token),
+		CodeHash:  hashLinkCode(token, code),
+		UserID:    user.ID,
+		Identity:  ext,
+		ExpiresAt: now.Add(identityLinkTTL),
+		CreatedAt: now,
+	}
+	if err := s.identityRepo.CreateLinkRequest(ctx, req); err != nil {
+		logger.FromContext(ctx).Errorf("Failed to store identity link request for user '%s': %v", user.ID, err)
+		return nil, fmt.Errorf("service: failed to start identity link: %w", err)
+	}
+
+	body := fmt.Sprintf("Someone signed in to Pulse through %s with your email address. To link that sign-in to your account, enter this code: %s\n"+
+		"It expires in %d minutes. If this wasn't you, ignore this email; nothing is linked without the code or your password.",
+		ext.Issuer, code, int(identityLinkTTL.Minutes()))
+	if err := s.mailer.Send(user.Email, "Link a new sign-in method to your Pulse account", body); err != nil {
+		// The password proof still works, so the challenge is returned anyway.
+		logger.FromContext(ctx).Errorf("Failed to send identity link code to user '%s': %v", user.ID, err)
+	}
+
+	logger.FromContext(ctx).Infof("Identity link started for user %s from %s issuer %s", user.ID, ext.Method, ext.Issuer)
+	return &models.IdentityLinkChallenge{
+		LinkToken: token,
+		Email:     user.Email,
+		Methods:   []string{models.LinkMethodPassword, models.LinkMethodEmailCode},
+		ExpiresAt: req.ExpiresAt,
+	}, nil
+}
+
+// CompleteLink proves ownership of the account behind a link challenge, with its password or the emailed code,
+// and links the SSO identity to it. It returns the user and the newly linked identity.
+func (s *IdentityServiceImpl) CompleteLink(ctx context.Context, req models.LinkIdentityRequest) (*models.User, *models.UserIdentity, error) {
+	if req.LinkToken == "" || (req.Password == "") == (req.Code == "") {
+		return nil, nil, invalid("service: link_token and either password or code are required")
+	}
+
+	lr, err := s.identityRepo.GetLinkRequest(ctx, hashResetToken(req.LinkToken))
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to retrieve identity link request: %v", err)
+		return nil, nil, fmt.Errorf("service: failed to retrieve identity link: %w", err)
+	}
+	if lr == nil {
+		return nil, nil, invalidToken("service: invalid or expired link token")
+	}
+	if lr.Attempts >= maxIdentityLinkAttempts {
+		_, _ = s.identityRepo.DeleteLinkRequest(ctx, lr.ID)
+		return nil, nil, invalidToken("service: invalid or expired link token")
+	}
+	user, err := s.userRepo.GetUserByID(ctx, lr.UserID)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to retrieve user '%s' for identity link: %v", lr.UserID, err)
+		return nil, nil, fmt.Errorf("service: failed to retrieve user: %w", err)
+	}
+	if user == nil {
+		return nil, nil, invalidToken("service: invalid or expired link token")
+	}
+
+	var proven bool
+	if req.Password != "" {
+		proven = user.CheckPassword(req.Password)
+	} else {
+		proven = subtle.ConstantTimeCompare([]byte(hashLinkCode(req.LinkToken, req.Code)), []byte(lr.CodeHash)) == 1
+	}
+	if !proven {
+		if err := s.identityRepo.IncrementLinkAttempts(ctx, lr.ID); err != nil {
+			logger.FromContext(ctx).Errorf("Failed to count failed identity link attempt: %v", err)
+		}
+		logger.FromContext(ctx).Warnf("Identity link proof failed for user %s", user.ID)
+		return nil, nil, badCredentials("service: invalid password or code")
+	}
+
+	// Deleting first makes the request single-use even under concurrent completions.
+	deleted, err := s.identityRepo.DeleteLinkRequest(ctx, lr.ID)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to consume identity link request: %v", err)
+		return nil, nil, fmt.Errorf("service: failed to complete identity link: %w", err)
+	}
+	if !deleted {
+		return nil, nil, invalidToken("service: invalid or expired link token")
+	}
+	identity, err := s.link(ctx, user.ID, lr.Identity)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	method := models.LinkMethodEmailCode
+	if req.Password != "" {
+		method = models.LinkMethodPassword
+	} else if err := s.userRepo.MarkEmailVerified(ctx, user.ID, time.Now().UTC()); err != nil {
+		logger.FromContext(ctx).Warnf("Failed to mark email of user '%s' verified after identity link: %v", user.ID, err)
+	}
+	s.events.Record(ctx, user.ID, models.UserEventIdentityLinked, "Single sign-on linked",
+		map[string]string{"issuer": lr.Identity.Issuer, "proof": method})
+	logger.FromContext(ctx).Infof("Identity from %s issuer %s linked to user %s", lr.Identity.Method, lr.Identity.Issuer, user.ID)
+	return user, identity, nil
+}
+
+// ListIdentities returns the SSO identities linked to a user.
+func (s *IdentityServiceImpl) ListIdentities(ctx context.Context, userID uuid.UUID) ([]models.UserIdentity, error) {
+	identities, err := s.identityRepo.ListIdentities(ctx, userID)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to list identities for user %s: %v", userID, err)
+		return nil, fmt.Errorf("service: failed to list identities: %w", err)
+	}
+	return identities, nil
+}
+
+func (s *IdentityServiceImpl) link(ctx context.Context, userID uuid.UUID, ext models.ExternalIdentity) (*models.UserIdentity, error) {
+	identity := &models.UserIdentity{
+		UserID:   userID,
+		Method:   ext.Method,
+		Issuer:   ext.Issuer,
+		Subject:  ext.Subject,
+		Email:    ext.Email,
+		LinkedAt: time.Now().UTC(),
+	}
+	if err := s.identityRepo.CreateIdentity(ctx, identity); err != nil {
+		logger.FromContext(ctx).Errorf("Failed to link %s identity to user '%s': %v", ext.Method, userID, err)
+		return nil, fmt.Errorf("service: failed to link identity: %w", err)
+	}
+	return identity, nil
+}
+
+// hashLinkCode binds an email code to its link token, so a code is only valid with the token it was sent for.
+func hashLinkCode(token, code string) string {
+	return hashResetToken(token + ":" + code)
+}
@@ -2,23 +2,260 @@
 package services
 
 import (
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+
 	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/auth/oidc"
+	"health-tracker-project/services/user-service/internal/auth/saml"
+	"health-tracker-project/services/user-service/internal/blobstore"
 	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/utils/jwt"
 )
 
 // AuthService defines the interface for authentication-related business logic.
 type AuthService interface {
-	RegisterUser(req models.RegisterRequest) (*models.UserResponse, error)
-	AuthenticateUser(req models.LoginRequest) (*models.AuthResponse, error)
+	RegisterUser(ctx context.Context, req models.RegisterRequest) (*models.UserResponse, error)
+	AuthenticateUser(ctx context.Context, req models.LoginRequest) (*models.AuthResponse, error)
+	AuthenticateOIDC(ctx context.Context, identity *oidc.Identity, client models.ClientInfo) (*models.AuthResponse, *models.IdentityLinkChallenge, error)
+	AuthenticateSAML(ctx context.Context, identity *saml.Identity, client models.ClientInfo) (*models.AuthResponse, *models.IdentityLinkChallenge, error)
+	CompleteIdentityLink(ctx context.Context, req models.LinkIdentityRequest) (*models.AuthResponse, error)
+	RequestPasswordReset(ctx context.Context, req models.ForgotPasswordRequest) error
+	ResetPassword(ctx context.Context, req models.ResetPasswordRequest) (uuid.UUID, error)
+	ValidateToken(ctx context.Context, tokenString string) (*jwt.Claims, error) // Parses a JWT and checks the session is still valid
+	Logout(ctx context.Context, userID, sessionID uuid.UUID) error
+	GetLoginHistory(ctx context.Context, filter models.LoginAttemptFilter) ([]models.LoginAttempt, error)
 	// Add other authentication-related methods if needed, e.g., ResetPassword, VerifyEmail
 }
 
 // UserService defines the interface for general user-related business logic.
 type UserService interface {
-	CreateUser(req models.CreateUserRequest) (*models.UserResponse, error)
-	GetUserByID(id uuid.UUID) (*models.UserResponse, error)
-	GetAllUsers() ([]models.UserResponse, error)
-	GetUserByEmail(email string) (*models.UserResponse, error)
-	UpdateUser(id uuid.UUID, req models.UpdateUserRequest) (*models.UserResponse, error)
-	DeleteUser(id uuid.UUID) error
-}
\ No newline at end of file
+	CreateUser(ctx context.Context, req models.CreateUserRequest) (*models.UserResponse, error)
+	GetUserByID(ctx context.Context, id uuid.UUID) (*models.UserResponse, error)
+	GetAllUsers(ctx context.Context, after *models.PageKey, limit int) ([]models.UserResponse, error)
+	ListUsers(ctx context.Context, filter models.UserFilter) (*models.UserList, error)
+	SearchUsers(ctx context.Context, query string, limit int) ([]models.UserSearchResult, error)
+	GetUserByEmail(ctx context.Context, email string) (*models.UserResponse, error)
+	GetUserByUsername(ctx context.Context, handle string) (*models.UserResponse, error)
+	CheckHandleAvailability(ctx context.Context, name string) (*models.HandleAvailability, error)
+	UpdateUser(ctx context.Context, id uuid.UUID, req models.UpdateUserRequest) (*models.UserResponse, error)
+	DeleteUser(ctx context.Context, id uuid.UUID) error
+	ApplyBulk(ctx context.Context, items []models.BulkUserItem) ([]models.BulkUserOutcome, error) // All or none; outcomes in item order
+	SuspendUser(ctx context.Context, id uuid.UUID, actor string) (*models.UserResponse, error)
+	ReactivateUser(ctx context.Context, id uuid.UUID, actor string) (*models.UserResponse, error)
+	DeactivateUser(ctx context.Context, id uuid.UUID) error // Self-service; the caller's own account
+	GetTimezoneHistory(ctx context.Context, id uuid.UUID) ([]models.TimezonePeriod, error)
+	TimezoneAt(ctx context.Context, id uuid.UUID, at time.Time) (*time.Location, error) // Zone in effect at a past instant, for aggregations
+	MergeUsers(ctx context.Context, req models.MergeUsersRequest, actor string) (*models.UserMerge, error)
+	UndoUserMerge(ctx context.Context, id uuid.UUID, actor string) (*models.UserMerge, error)
+	GetProfilePrompts(ctx context.Context, id uuid.UUID) ([]models.ProfilePrompt, error) // Missing optional fields worth asking for next
+	DismissProfilePrompt(ctx context.Context, id uuid.UUID, field string) error
+	GetMetadata(ctx context.Context, id uuid.UUID) (models.UserMetadata, error)
+	UpdateMetadata(ctx context.Context, id uuid.UUID, patch map[string]json.RawMessage) (models.UserMetadata, error) // Key-level merge; null removes a key
+}
+
+// SystemEventService defines the interface for the admin-visible operational timeline.
+type SystemEventService interface {
+	RecordEvent(ctx context.Context, req models.CreateSystemEventRequest, actor string) (*models.SystemEvent, error)
+	Record(ctx context.Context, eventType, message string) // Fire-and-forget recording for events raised by the service itself
+	GetTimeline(ctx context.Context, filter models.SystemEventFilter) ([]models.SystemEvent, error)
+}
+
+// UserEventService defines the interface for per-user domain event timelines.
+type UserEventService interface {
+	Record(ctx context.Context, userID uuid.UUID, eventType, summary string, details map[string]string) // Fire-and-forget, like SystemEventService.Record; also streamed to the user
+	Notify(ctx context.Context, userID uuid.UUID, eventType, summary string, details map[string]string) // Streamed to the user's open connections only, not kept on the timeline
+	GetTimeline(ctx context.Context, filter models.UserEventFilter) ([]models.UserEvent, error)
+}
+
+// AuditService defines the interface for the security audit log.
+type AuditService interface {
+	Record(ctx context.Context, event models.AuditEvent) // Fire-and-forget; ID and CreatedAt are filled in
+	ListEvents(ctx context.Context, filter models.AuditEventFilter) ([]models.AuditEvent, error)
+}
+
+// DashboardService defines the interface for per-user dashboard layouts.
+type DashboardService interface {
+	GetLayout(ctx context.Context, userID uuid.UUID) (*models.DashboardLayout, error) // The saved layout, or the default
+	SaveLayout(ctx context.Context, userID uuid.UUID, layout models.DashboardLayout) (*models.DashboardLayout, error)
+	ResetLayout(ctx context.Context, userID uuid.UUID) (*models.DashboardLayout, error)
+}
+
+// SettingsService defines the interface for per-user settings such as notification preferences,
+// goal defaults, and privacy toggles.
+type SettingsService interface {
+	GetSettings(ctx context.Context, userID uuid.UUID) (*models.UserSettings, error)                                        // Every known key, defaults applied
+	UpdateSettings(ctx context.Context, userID uuid.UUID, changes map[string]json.RawMessage) (*models.UserSettings, error) // A null value resets the key
+}
+
+// AggregationService defines the interface for the windows analytics aggregate over: weeks on the
+// user's chosen first day, and custom periods such as training blocks and challenges.
+type AggregationService interface {
+	ListPeriods(ctx context.Context, userID uuid.UUID) ([]models.AggregationPeriod, error)
+	CreatePeriod(ctx context.Context, userID uuid.UUID, req models.AggregationPeriodRequest) (*models.AggregationPeriod, error)
+	UpdatePeriod(ctx context.Context, userID, id uuid.UUID, req models.AggregationPeriodRequest) (*models.AggregationPeriod, error)
+	DeletePeriod(ctx context.Context, userID, id uuid.UUID) error
+	Windows(ctx context.Context, userID uuid.UUID, from, to string, kinds []string) ([]models.AggregationWindow, error) // Dates are YYYY-MM-DD; kinds empty means all
+}
+
+// DeveloperAppService defines the interface for public API developer apps, their keys, and usage.
+type DeveloperAppService interface {
+	CreateApp(ctx context.Context, ownerID uuid.UUID, req models.CreateDeveloperAppRequest) (*models.DeveloperAppCredentials, error)
+	ListApps(ctx context.Context, ownerID uuid.UUID) ([]models.DeveloperApp, error)
+	RotateKey(ctx context.Context, ownerID, appID uuid.UUID) (*models.DeveloperAppCredentials, error)
+	RevokeApp(ctx context.Context, ownerID, appID uuid.UUID) (*models.DeveloperApp, error)
+	GetUsage(ctx context.Context, ownerID, appID uuid.UUID, days int) ([]models.DeveloperAppUsage, error)
+	Authenticate(ctx context.Context, apiKey string) (*models.DeveloperApp, error)
+	QuotaExceeded(ctx context.Context, appID uuid.UUID) (bool, error)                             // Whether today's public_api_daily_quota is used up
+	RecordRequest(ctx context.Context, appID uuid.UUID, route string, status int, throttled bool) // Fire-and-forget
+	StartDebug(ctx context.Context, ownerID, appID uuid.UUID) (*models.DeveloperApp, error)
+	StopDebug(ctx context.Context, ownerID, appID uuid.UUID) (*models.DeveloperApp, error)
+	ExportHAR(ctx context.Context, ownerID, appID uuid.UUID) (*models.HAR, error)
+	Recording(app *models.DeveloperApp) bool                                    // Whether the app's requests are being recorded
+	RecordExchange(ctx context.Context, appID uuid.UUID, entry models.HAREntry) // Fire-and-forget
+}
+
+// OnboardingService defines the interface for onboarding recommendations and the first-run flow.
+type OnboardingService interface {
+	Recommend(ctx context.Context, answers models.OnboardingAnswers) (*models.OnboardingRecommendation, error)
+	GetState(ctx context.Context, userID uuid.UUID) (*models.OnboardingState, error)
+	Advance(ctx context.Context, userID uuid.UUID, step string) (*models.OnboardingState, error)
+	Funnel(ctx context.Context, since time.Time) (*models.OnboardingFunnel, error) // All users if since is zero
+}
+
+// DataSummaryService defines the interface for summarizing what Pulse stores about a user.
+type DataSummaryService interface {
+	GetDataSummary(ctx context.Context, userID uuid.UUID) (*models.DataSummary, error)
+}
+
+// RolloutService defines the interface for deciding which soft-launched features a user gets.
+type RolloutService interface {
+	Enabled(ctx context.Context, feature string, audience models.RolloutAudience) (bool, error)
+	Features(ctx context.Context, audience models.RolloutAudience) (map[string]bool, error) // Every feature in a rollout
+}
+
+// AnnouncementService defines the interface for admin announcements pushed to segments of users.
+type AnnouncementService interface {
+	Preview(ctx context.Context, req models.CreateAnnouncementRequest) (*models.AnnouncementPreview, error)
+	Create(ctx context.Context, req models.CreateAnnouncementRequest, actor string) (*models.Announcement, error)
+	Get(ctx context.Context, id uuid.UUID) (*models.Announcement, error)
+	List(ctx context.Context, filter models.AnnouncementFilter) ([]models.Announcement, error)
+	Cancel(ctx context.Context, id uuid.UUID, actor string) (*models.Announcement, error) // Stops a send in progress after its current page
+}
+
+// EmailDeliverabilityService defines the interface for bounces and complaints reported by the mail
+// provider, and for verifying a suppressed email again.
+type EmailDeliverabilityService interface {
+	HandleEvents(ctx context.Context, events []models.EmailDeliveryEvent) (int, error) // How many were about a user's current email
+	RequestVerification(ctx context.Context, userID uuid.UUID) error
+	ConfirmVerification(ctx context.Context, userID uuid.UUID, token string) (*models.UserResponse, error)
+}
+
+// QuickLogService defines the interface for reading quick-log phrases into structured entries.
+type QuickLogService interface {
+	Parse(ctx context.Context, text, locale string) (*models.QuickLogResult, error) // locale is a BCP 47 tag, for decimal separators
+}
+
+// IdentityService defines the interface for SSO identities linked to users.
+type IdentityService interface {
+	ResolveExternal(ctx context.Context, ext models.ExternalIdentity) (*models.User, *models.IdentityLinkChallenge, error) // Exactly one of user and challenge is set on success
+	CompleteLink(ctx context.Context, req models.LinkIdentityRequest) (*models.User, *models.UserIdentity, error)
+	ListIdentities(ctx context.Context, userID uuid.UUID) ([]models.UserIdentity, error)
+}
+
+// ResidencyService defines the interface for admin tooling over data residency regions.
+type ResidencyService interface {
+	MoveUser(ctx context.Context, id uuid.UUID, req models.ChangeRegionRequest) (*models.UserResponse, error)
+	FindViolations(ctx context.Context) ([]models.ResidencyViolation, error)
+}
+
+// MeteringService defines the interface for billing-grade usage metering.
+type MeteringService interface {
+	Record(ctx context.Context, event models.MeteringEvent) // Fire-and-forget; duplicates by idempotency key are dropped
+	Reconcile(ctx context.Context, filter models.MeteringFilter) (*models.MeteringReport, error)
+}
+
+// ConsentService defines the interface for consent to third-party integrations.
+type ConsentService interface {
+	ListIntegrations() []models.Integration
+	GrantConsent(ctx context.Context, userID uuid.UUID, provider string, req models.GrantConsentRequest, client models.ClientInfo) (*models.IntegrationConsent, error)
+	RevokeConsent(ctx context.Context, userID uuid.UUID, provider string, deleteData bool) (*models.IntegrationConsent, error)
+	ListConsents(ctx context.Context, userID uuid.UUID) ([]models.IntegrationConsent, error)
+	CheckConsent(ctx context.Context, userID uuid.UUID, provider string) (*models.IntegrationConsent, error) // For the sync-service, before linking and syncing
+	ListRevocations(ctx context.Context, filter models.ConsentRevocationFilter) ([]models.IntegrationConsent, error)
+}
+
+// MessagingService defines the interface for messaging between users and their authorized coaches.
+type MessagingService interface {
+	AuthorizeCoach(ctx context.Context, userID, coachID uuid.UUID) (*models.CoachAuthorization, error)
+	RevokeCoach(ctx context.Context, userID, coachID uuid.UUID) error
+	ListCoaches(ctx context.Context, userID uuid.UUID) ([]models.CoachAuthorization, error)
+	ListClients(ctx context.Context, coachID uuid.UUID) ([]models.CoachAuthorization, error)
+	CreateThread(ctx context.Context, callerID uuid.UUID, req models.CreateThreadRequest) (*models.MessageThread, error)
+	ListThreads(ctx context.Context, callerID uuid.UUID) ([]models.MessageThread, error)
+	ListMessages(ctx context.Context, callerID uuid.UUID, filter models.MessageFilter) ([]models.Message, error)
+	SendMessage(ctx context.Context, callerID, threadID uuid.UUID, req models.SendMessageRequest) (*models.Message, error)
+	MarkRead(ctx context.Context, callerID, threadID uuid.UUID) (int64, error) // Read receipts for the other participant's messages
+	UploadAttachment(ctx context.Context, callerID, threadID uuid.UUID, filename string, content io.Reader) (*models.MessageAttachment, error)
+	GetAttachment(ctx context.Context, callerID, threadID, id uuid.UUID) (*models.MessageAttachment, io.ReadCloser, error)
+	Export(ctx context.Context, callerID uuid.UUID) (*models.MessagingExport, error)
+}
+
+// AppointmentService defines the interface for provider availability and appointment booking.
+type AppointmentService interface {
+	PublishAvailability(ctx context.Context, providerID uuid.UUID, req models.PublishAvailabilityRequest) ([]models.AppointmentSlot, error)
+	ListSlots(ctx context.Context, filter models.SlotFilter) ([]models.AppointmentSlot, error)
+	DeleteSlot(ctx context.Context, providerID, id uuid.UUID) error
+	Book(ctx context.Context, userID uuid.UUID, req models.BookAppointmentRequest) (*models.Appointment, error)
+	ListAppointments(ctx context.Context, filter models.AppointmentFilter) ([]models.Appointment, error)
+	GetAppointment(ctx context.Context, callerID, id uuid.UUID) (*models.Appointment, error)
+	Cancel(ctx context.Context, callerID, id uuid.UUID, req models.CancelAppointmentRequest) (*models.Appointment, error)
+	Reschedule(ctx context.Context, callerID, id uuid.UUID, req models.RescheduleAppointmentRequest) (*models.Appointment, error)
+	Invite(ctx context.Context, callerID, id uuid.UUID) (invite []byte, method string, err error) // iCalendar object for the appointment
+}
+
+// WorkoutAttachmentService defines the interface for form-check videos and files on workout sets.
+type WorkoutAttachmentService interface {
+	Upload(ctx context.Context, userID uuid.UUID, req models.UploadWorkoutAttachmentRequest, content io.Reader) (*models.WorkoutAttachment, error)
+	List(ctx context.Context, userID uuid.UUID, setRef string) ([]models.WorkoutAttachment, error)
+	Update(ctx context.Context, userID, id uuid.UUID, req models.UpdateWorkoutAttachmentRequest) (*models.WorkoutAttachment, error)
+	Delete(ctx context.Context, userID, id uuid.UUID) error
+	Open(ctx context.Context, userID, id uuid.UUID) (*models.WorkoutAttachment, io.ReadCloser, error)
+	ListForCoach(ctx context.Context, coachID, clientID uuid.UUID, setRef string) ([]models.WorkoutAttachment, error)
+	OpenForCoach(ctx context.Context, coachID, clientID, id uuid.UUID) (*models.WorkoutAttachment, io.ReadCloser, error)
+}
+
+// WorkoutPlanService defines the interface for the training plans coaches write, and share with
+// coaches elsewhere as plan documents.
+type WorkoutPlanService interface {
+	Create(ctx context.Context, coachID uuid.UUID, req models.CreateWorkoutPlanRequest) (*models.WorkoutPlan, error)
+	List(ctx context.Context, coachID uuid.UUID) ([]models.WorkoutPlan, error)
+	Get(ctx context.Context, coachID, id uuid.UUID) (*models.WorkoutPlan, error)
+	Delete(ctx context.Context, coachID, id uuid.UUID) error
+	Export(ctx context.Context, coachID uuid.UUID, ids []uuid.UUID) (*models.WorkoutPlanDocument, error) // All plans when ids is empty
+	Import(ctx context.Context, coachID uuid.UUID, doc models.WorkoutPlanDocument, opts models.WorkoutPlanImportOptions) (*models.WorkoutPlanImport, error)
+}
+
+// BlobMaintenanceService defines the interface for upkeep of the blob store: moving files between
+// storage tiers by lifecycle rules, and checking stored files against their checksums.
+type BlobMaintenanceService interface {
+	ApplyLifecycle(ctx context.Context) (blobstore.LifecycleResult, error)
+	VerifySample(ctx context.Context) (blobstore.IntegrityResult, error)
+}
+
+// AccountDeletionService defines the interface for right-to-be-forgotten requests.
+type AccountDeletionService interface {
+	RequestDeletion(ctx context.Context, userID uuid.UUID) (*models.AccountDeletion, error) // Self-service; the caller's own account
+}
+
+// OutboxService defines the interface for publishing the domain events waiting in the outbox.
+type OutboxService interface {
+	DispatchPending(ctx context.Context) (published int, err error)
+}
+
+// FieldSealingService defines the interface for moving sealed columns to the current encryption key.
+type FieldSealingService interface {
+	ResealPending(ctx context.Context) (resealed int, err error)
+}
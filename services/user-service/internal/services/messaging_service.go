@@ -0,0 +1,432 @@
+// services/user-service/internal/services/messaging_service.go
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/blobstore"
+	"health-tracker-project/services/user-service/internal/config"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/push"
+	"health-tracker-project/services/user-service/internal/repository"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+const (
+	MaxAttachmentBytes     = 10 << 20 // Largest accepted attachment upload
+	maxMessageLength       = 10000    // Characters per message body
+	maxMessageAttachments  = 10
+	maxThreadSubjectLength = 200
+	defaultMessageLimit    = 50
+	maxMessageLimit        = 200
+	unsentAttachmentTTL    = 24 * time.Hour // Uploads not sent in a message by then are deleted
+)
+
+// attachmentTypes are the accepted attachment content types, sniffed from the upload rather than
+// taken from the client, so nothing executable can be served back to the other participant.
+var attachmentTypes = []string{"image/jpeg", "image/png", "image/gif", "image/webp", "application/pdf"}
+
+// MessagingServiceImpl implements the MessagingService interface.
+type MessagingServiceImpl struct {
+	messagingRepo repository.MessagingRepository
+	userRepo      repository.UserRepository
+	blobs         blobstore.Store
+	notifier      push.Notifier
+	events        UserEventService // Records coach authorizations on the user's own timeline
+}
+
+// NewMessagingService creates a new instance of MessagingServiceImpl.
+func NewMessagingService(messagingRepo repository.MessagingRepository, userRepo repository.UserRepository, blobs blobstore.Store, notifier push.Notifier, events UserEventService) *MessagingServiceImpl {
+	return &MessagingServiceImpl{messagingRepo: messagingRepo, userRepo: userRepo, blobs: blobs, notifier: notifier, events: events}
+}
+
+// AuthorizeCoach lets a coach message the user. Only active accounts with the coach role can be authorized.
+func (s *MessagingServiceImpl) AuthorizeCoach(ctx context.Context, userID, coachID uuid.UUID) (*models.CoachAuthorization, error) {
+	if userID == coachID {
+		return nil, invalid("service: cannot authorize yourself as a coach")
+	}
+	coach, err := s.userRepo.GetUserByID(ctx, coachID)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to retrieve coach '%s': %v", coachID, err)
+		return nil, fmt.Errorf("service: failed to retrieve coach: %w", err)
+	}
+	if coach == nil || coach.Role != models.RoleCoach || coach.Status != models.StatusActive {
+		return nil, notFound("service: coach not found")
+	}
+
+	auth := &models.CoachAuthorization{UserID: userID, CoachID: coachID, AuthorizedAt: time.Now().UTC()}
+	if err := s.messagingRepo.AuthorizeCoach(ctx, auth); err != nil {
+		logger.FromContext(ctx).Errorf("Failed to authorize coach %s for user %s: %v", coachID, userID, err)
+		return nil, fmt.Errorf("service: failed to authorize coach: %w", err)
+	}
+	s.events.Record(ctx, userID, models.UserEventCoachAuthorized, "Allowed "+coach.Name+" to message you as your coach",
+		map[string]string{"coach_id": coachID.String()})
+	return auth, nil
+}
+
+// RevokeCoach stops a coach from messaging the user or reading their threads.
+func (s *MessagingServiceImpl) RevokeCoach(ctx context.Context, userID, coachID uuid.UUID) error {
+	revoked, err := s.messagingRepo.RevokeCoach(ctx, userID, coachID, time.Now().UTC())
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to revoke coach %s for user %s: %v", coachID, userID, err)
+		return fmt.Errorf("service: failed to revoke coach: %w", err)
+	}
+	if !revoked {
+		return notFound("service: coach is not authorized")
+	}
+	s.events.Record(ctx, userID, models.UserEventCoachRevoked, "Removed a coach's access",
+		map[string]string{"coach_id": coachID.String()})
+	return nil
+}
+
+// ListCoaches returns the coaches a user has authorized, including revoked ones.
+func (s *MessagingServiceImpl) ListCoaches(ctx context.Context, userID uuid.UUID) ([]models.CoachAuthorization, error) {
+	auths, err := s.messagingRepo.ListCoaches(ctx, userID)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to list coaches of user %s: %v", userID, err)
+		return nil, fmt.Errorf("service: failed to list coaches: %w", err)
+	}
+	return auths, nil
+}
+
+// ListClients returns the users who currently authorize a coach.
+func (s *MessagingServiceImpl) ListClients(ctx context.Context, coachID uuid.UUID) ([]models.CoachAuthorization, error) {
+	auths, err := s.messagingRepo.ListClients(ctx, coachID)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to list clients of coach %s: %v", coachID, err)
+		return nil, fmt.Errorf("service: failed to list clients: %w", err)
+	}
+	return slices.DeleteFunc(auths, func(a models.CoachAuthorization) bool { return a.RevokedAt != nil }), nil
+}
+
+// CreateThread starts a thread between a user and a coach they authorized. Either side can start it.
+func (s *MessagingServiceImpl) CreateThread(ctx context.Context, callerID uuid.UUID, req models.CreateThreadRequest) (*models.MessageThread, error) {
+	subject := strings.TrimSpace(req.Subject)
+	if utf8.RuneCountInString(subject) > maxThreadSubjectLength {
+		return nil, invalid("service: subject must be at most %d characters", maxThreadSubjectLength)
+	}
+	if req.ParticipantID == uuid.Nil || req.ParticipantID == callerID {
+		return nil, invalid("service: participant_id must name your coach or client")
+	}
+
+	now := time.Now().UTC()
+	thread := &models.MessageThread{ID: uuid.New(), Subject: subject, CreatedAt: now, LastMessageAt: now}
+	if ok, err := s.messagingRepo.IsCoachAuthorized(ctx, callerID, req.ParticipantID); err != nil {
+		return nil, fmt.Errorf("service: failed to check coach authorization: %w", err)
+	} else if ok {
+		thread.UserID, thread.CoachID = callerID, req.ParticipantID
+	} else if ok, err := s.messagingRepo.IsCoachAuthorized(ctx, req.ParticipantID, callerID); err != nil {
+		return nil, fmt.Errorf("service: failed to check coach authorization: %w", err)
+	} else if ok {
+		thread.UserID, thread.CoachID = req.ParticipantID, callerID
+	} else {
+		return nil, invalid("service: participant_id must name your coach or client")
+	}
+
+	if err := s.messagingRepo.CreateThread(ctx, thread); err != nil {
+		logger.FromContext(ctx).Errorf("Failed to create thread: %v", err)
+		return nil, fmt.Errorf("service: failed to create thread: %w", err)
+	}
+	return thread, nil
+}
+
+// threadFor returns a thread the caller takes part in. A coach loses access to a user's threads
+// when the user revokes them.
+func (s *MessagingServiceImpl) threadFor(ctx context.Context, callerID, threadID uuid.UUID) (*models.MessageThread, error) {
+	thread, err := s.messagingRepo.GetThread(ctx, threadID)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to get thread %s: %v", threadID, err)
+		return nil, fmt.Errorf("service: failed to get thread: %w", err)
+	}
+	if thread == nil || (thread.UserID != callerID && thread.CoachID != callerID) {
+		return nil, notFound("service: thread not found")
+	}
+	if thread.CoachID == callerID {
+		authorized, err := s.messagingRepo.IsCoachAuthorized(ctx, thread.UserID, callerID)
+		if err != nil {
+			return nil, fmt.Errorf("service: failed to check coach authorization: %w", err)
+		}
+		if !authorized {
+			return nil, forbidden("service: coach is no longer authorized")
+		}
+	}
+	return thread, nil
+}
+
+// ListThreads returns the caller's threads, most recently active first. A coach only sees threads of
+// users who still authorize them.
+func (s *MessagingServiceImpl) ListThreads(ctx context.Context, callerID uuid.UUID) ([]models.MessageThread, error) {
+	threads, err := s.messagingRepo.ListThreads(ctx, callerID)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to list threads of %s: %v", callerID, err)
+		return nil, fmt.Errorf("service: failed to list threads: %w", err)
+	}
+	clients, err := s.ListClients(ctx, callerID)
+	if err != nil {
+		return nil, err
+	}
+	active := map[uuid.UUID]bool{}
+	for _, c := range clients {
+		active[c.UserID] = true
+	}
+	return slices.DeleteFunc(threads, func(t models.MessageThread) bool {
+		return t.CoachID == callerID && !active[t.UserID]
+	}), nil
+}
+
+// ListMessages returns a page of a thread's messages, newest first.
+func (s *MessagingServiceImpl) ListMessages(ctx context.Context, callerID uuid.UUID, filter models.MessageFilter) ([]models.Message, error) {
+	thread, err := s.threadFor(ctx, callerID, filter.ThreadID)
+	if err != nil {
+		return nil, err
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultMessageLimit
+	}
+	if filter.Limit > maxMessageLimit {
+		filter.Limit = maxMessageLimit
+	}
+	messages, err := s.messagingRepo.ListMessages(ctx, thread.UserID, filter)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to list messages of thread %s: %v", thread.ID, err)
+		return nil, fmt.Errorf("service: failed to list messages: %w", err)
+	}
+	return messages, nil
+}
+
+// SendMessage posts a message to a thread and notifies the other participant.
+func (s *MessagingServiceImpl) SendMessage(ctx context.Context, callerID, threadID uuid.UUID, req models.SendMessageRequest) (*models.Message, error) {
+	body := strings.TrimSpace(req.Body)
+	if body == "" && len(req.AttachmentIDs) == 0 {
+		return nil, invalid("service: message needs a body or an attachment")
+	}
+	if utf8.RuneCountInString(body) > maxMessageLength {
+		return nil, invalid("service: message must be at most %d characters", maxMessageLength)
+	}
+	if len(req.AttachmentIDs) > maxMessageAttachments {
+		return nil, invalid("service: at most %d attachments per message", maxMessageAttachments)
+	}
+	thread, err := s.threadFor(ctx, callerID, threadID)
+	if err != nil {
+		return nil, err
+	}
+	for i, id := range req.AttachmentIDs {
+		attachment, err := s.messagingRepo.GetAttachment(ctx, thread.UserID, thread.ID, id)
+		if err != nil {
+			return nil, fmt.Errorf("service: failed to get attachment: %w", err)
+		}
+		if attachment == nil || attachment.UploaderID != callerID || attachment.MessageID != nil || slices.Contains(req.AttachmentIDs[:i], id) {
+			return nil, invalid("service: unknown attachment %s", id)
+		}
+	}
+
+	msg := &models.Message{ID: uuid.New(), ThreadID: thread.ID, SenderID: callerID, Body: body, CreatedAt: time.Now().UTC()}
+	if err := s.messagingRepo.CreateMessage(ctx, thread.UserID, msg, req.AttachmentIDs); err != nil {
+		logger.FromContext(ctx).Errorf("Failed to send message in thread %s: %v", thread.ID, err)
+		return nil, fmt.Errorf("service: failed to send message: %w", err)
+	}
+
+	recipient := thread.CoachID
+	if callerID == thread.CoachID {
+		recipient = thread.UserID
+	}
+	go s.notify(context.WithoutCancel(ctx), recipient, callerID, thread.ID)
+	return msg, nil
+}
+
+// notify tells the recipient about a new message without revealing its contents, since push
+// payloads pass through third parties.
+func (s *MessagingServiceImpl) notify(ctx context.Context, recipientID, senderID, threadID uuid.UUID) {
+	title := "New message"
+	if sender, err := s.userRepo.GetUserByID(ctx, senderID); err == nil && sender != nil {
+		title = "New message from " + sender.Name
+	}
+	err := s.notifier.Notify(ctx, push.Notification{
+		UserID: recipientID,
+		Title:  title,
+		Body:   "Open Pulse to read it.",
+		Data:   map[string]string{"type": "message", "thread_id": threadID.String()},
+	})
+	if err != nil {
+		logger.FromContext(ctx).Warnf("Failed to send message notification to %s: %v", recipientID, err)
+	}
+}
+
+// MarkRead records read receipts for the other participant's unread messages in a thread.
+func (s *MessagingServiceImpl) MarkRead(ctx context.Context, callerID, threadID uuid.UUID) (int64, error) {
+	thread, err := s.threadFor(ctx, callerID, threadID)
+	if err != nil {
+		return 0, err
+	}
+	n, err := s.messagingRepo.MarkRead(ctx, thread.UserID, thread.ID, callerID, time.Now().UTC())
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to mark thread %s read: %v", thread.ID, err)
+		return 0, fmt.Errorf("service: failed to mark messages read: %w", err)
+	}
+	return n, nil
+}
+
+// UploadAttachment stores a file in the blob store for the caller to send in the thread. It is
+// deleted if it is not sent within a day.
+func (s *MessagingServiceImpl) UploadAttachment(ctx context.Context, callerID, threadID uuid.UUID, filename string, content io.Reader) (*models.MessageAttachment, error) {
+	thread, err := s.threadFor(ctx, callerID, threadID)
+	if err != nil {
+		return nil, err
+	}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(content, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, fmt.Errorf("service: failed to read attachment: %w", err)
+	}
+	if n == 0 {
+		return nil, invalid("service: attachment is empty")
+	}
+	head = head[:n]
+	contentType, _, _ := strings.Cut(http.DetectContentType(head), ";")
+	if !slices.Contains(attachmentTypes, contentType) {
+		return nil, unsupportedType("service: unsupported attachment type, expected one of %s", strings.Join(attachmentTypes, ", "))
+	}
+
+	filename = strings.TrimSpace(filepath.Base(filepath.Clean("/" + filename)))
+	if filename == "/" || filename == "" {
+		filename = "attachment"
+	}
+	if len(filename) > 255 {
+		filename = filename[:255]
+	}
+
+	attachment := &models.MessageAttachment{
+		ID:          uuid.New(),
+		ThreadID:    thread.ID,
+		UploaderID:  callerID,
+		Filename:    filename,
+		ContentType: contentType,
+		CreatedAt:   time.Now().UTC(),
+	}
+	attachment.BlobKey = fmt.Sprintf("messages/%s/%s/%s", thread.UserID, thread.ID, attachment.ID)
+	size, err := s.blobs.Put(attachment.BlobKey, io.LimitReader(io.MultiReader(bytes.NewReader(head), content), MaxAttachmentBytes+1))
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to store attachment for thread %s: %v", thread.ID, err)
+		return nil, fmt.Errorf("service: failed to store attachment: %w", err)
+	}
+	if size > MaxAttachmentBytes {
+		s.deleteBlob(attachment.BlobKey)
+		return nil, tooLarge("service: attachment must be at most %d bytes", MaxAttachmentBytes)
+	}
+	attachment.Size = size
+
+	if err := s.messagingRepo.CreateAttachment(ctx, thread.UserID, attachment); err != nil {
+		s.deleteBlob(attachment.BlobKey)
+		logger.FromContext(ctx).Errorf("Failed to record attachment for thread %s: %v", thread.ID, err)
+		return nil, fmt.Errorf("service: failed to record attachment: %w", err)
+	}
+	return attachment, nil
+}
+
+// GetAttachment opens an attachment of a thread the caller takes part in. Unsent uploads are only
+// visible to their uploader. The caller must close the returned reader.
+func (s *MessagingServiceImpl) GetAttachment(ctx context.Context, callerID, threadID, id uuid.UUID) (*models.MessageAttachment, io.ReadCloser, error) {
+	thread, err := s.threadFor(ctx, callerID, threadID)
+	if err != nil {
+		return nil, nil, err
+	}
+	attachment, err := s.messagingRepo.GetAttachment(ctx, thread.UserID, thread.ID, id)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to get attachment %s: %v", id, err)
+		return nil, nil, fmt.Errorf("service: failed to get attachment: %w", err)
+	}
+	if attachment == nil || (attachment.MessageID == nil && attachment.UploaderID != callerID) {
+		return nil, nil, notFound("service: attachment not found")
+	}
+	content, err := s.blobs.Get(attachment.BlobKey)
+	if err == blobstore.ErrNotFound {
+		return nil, nil, notFound("service: attachment not found")
+	}
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to read attachment %s: %v", id, err)
+		return nil, nil, fmt.Errorf("service: failed to read attachment: %w", err)
+	}
+	return attachment, content, nil
+}
+
+// Export returns every thread the caller can see, with all messages oldest first. Attachments are
+// listed with their metadata and downloaded separately.
+func (s *MessagingServiceImpl) Export(ctx context.Context, callerID uuid.UUID) (*models.MessagingExport, error) {
+	threads, err := s.ListThreads(ctx, callerID)
+	if err != nil {
+		return nil, err
+	}
+	export := &models.MessagingExport{UserID: callerID, ExportedAt: time.Now().UTC(), Threads: []models.MessageThreadExport{}}
+	for _, thread := range threads {
+		messages := []models.Message{}
+		filter := models.MessageFilter{ThreadID: thread.ID, Limit: maxMessageLimit}
+		for {
+			page, err := s.messagingRepo.ListMessages(ctx, thread.UserID, filter)
+			if err != nil {
+				logger.FromContext(ctx).Errorf("Failed to export messages of thread %s: %v", thread.ID, err)
+				return nil, fmt.Errorf("service: failed to export messages: %w", err)
+			}
+			messages = append(messages, page...)
+			if len(page) < filter.Limit {
+				break
+			}
+			last := page[len(page)-1]
+			filter.After = &models.PageKey{At: last.CreatedAt, ID: last.ID}
+		}
+		slices.Reverse(messages)
+		export.Threads = append(export.Threads, models.MessageThreadExport{MessageThread: thread, Messages: messages})
+	}
+	return export, nil
+}
+
+// PurgeExpired applies message retention every interval: messages older than message_retention_days
+// in the runtime config are deleted with their attachments, as are uploads never sent. It returns when ctx is done.
+func (s *MessagingServiceImpl) PurgeExpired(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	for {
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C:
+		}
+		s.purgeExpired(ctx)
+	}
+}
+
+func (s *MessagingServiceImpl) purgeExpired(ctx context.Context) {
+	now := time.Now().UTC()
+	var before time.Time // Zero keeps every message
+	if days := config.Current().MessageRetentionDays; days > 0 {
+		before = now.AddDate(0, 0, -days)
+	}
+	keys, n, err := s.messagingRepo.PurgeMessages(ctx, before, now.Add(-unsentAttachmentTTL))
+	for _, key := range keys {
+		s.deleteBlob(key)
+	}
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to apply message retention: %v", err)
+		return
+	}
+	if n > 0 || len(keys) > 0 {
+		logger.FromContext(ctx).Infof("Message retention deleted %d messages and %d attachments", n, len(keys))
+	}
+}
+
+// deleteBlob removes an attachment's content; a failure leaves an orphaned blob, which is only logged.
+func (s *MessagingServiceImpl) deleteBlob(key string) {
+	if err := s.blobs.Delete(key); err != nil {
+		logger.Logger.Warnf("Failed to delete attachment blob %s: %v", key, err)
+	}
+}
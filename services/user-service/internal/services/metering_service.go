@@ -0,0 +1,160 @@
+// services/user-service/internal/services/metering_service.go
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/repository"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+const (
+	meteringQueueSize     = 10000 // Events buffered for the background writer
+	meteringWriteAttempts = 3     // Idempotent writes are retried; only then is an event counted as failed
+	maxMeteringPeriod     = 366 * 24 * time.Hour
+)
+
+// MeteringServiceImpl implements the MeteringService interface. Events are written by a background
+// worker so metering never slows a request down; when the queue is full the caller writes the event
+// itself rather than dropping it.
+type MeteringServiceImpl struct {
+	meteringRepo repository.MeteringRepository
+	userRepo     repository.UserRepository // Measures storage for the daily snapshots
+	queue        chan models.MeteringEvent
+
+	emitted, written, duplicates, failed atomic.Int64
+}
+
+// NewMeteringService creates a new instance of MeteringServiceImpl and starts its writer.
+func NewMeteringService(meteringRepo repository.MeteringRepository, userRepo repository.UserRepository) *MeteringServiceImpl {
+	s := &MeteringServiceImpl{
+		meteringRepo: meteringRepo,
+		userRepo:     userRepo,
+		queue:        make(chan models.MeteringEvent, meteringQueueSize),
+	}
+	go s.writeLoop()
+	return s
+}
+
+// Record queues a usage event. IdempotencyKey must identify the usage, so that recording the same
+// usage twice (e.g. a retried job) bills it once; ID, OccurredAt, and RecordedAt are filled in if unset.
+func (s *MeteringServiceImpl) Record(ctx context.Context, event models.MeteringEvent) {
+	if event.ID == uuid.Nil {
+		event.ID = uuid.New()
+	}
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now().UTC()
+	}
+	s.emitted.Add(1)
+	select {
+	case s.queue <- event:
+	default:
+		logger.FromContext(ctx).Warn("Metering queue is full; writing event synchronously.")
+		s.write(context.WithoutCancel(ctx), event)
+	}
+}
+
+func (s *MeteringServiceImpl) writeLoop() {
+	for event := range s.queue {
+		s.write(context.Background(), event) // Queued events outlive the requests that recorded them
+	}
+}
+
+// write persists one event, retrying with backoff. An event that cannot be written is logged in full
+// so it can be replayed; its idempotency key makes a replay safe.
+func (s *MeteringServiceImpl) write(ctx context.Context, event models.MeteringEvent) {
+	var err error
+	for attempt := 0; attempt < meteringWriteAttempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(attempt*attempt) * 100 * time.Millisecond)
+		}
+		event.RecordedAt = time.Now().UTC()
+		var inserted bool
+		if inserted, err = s.meteringRepo.RecordEvent(ctx, &event); err == nil {
+			if inserted {
+				s.written.Add(1)
+			} else {
+				s.duplicates.Add(1)
+			}
+			return
+		}
+	}
+	s.failed.Add(1)
+	logger.FromContext(ctx).Errorw("Metering event lost", "error", err, "idempotency_key", event.IdempotencyKey,
+		"user_id", event.UserID.String(), "meter", event.Meter, "dimension", event.Dimension,
+		"quantity", event.Quantity, "occurred_at", event.OccurredAt)
+}
+
+// SnapshotStorage records every user's stored bytes once per UTC day, checking every interval.
+// It returns when ctx is done. Snapshots of the same day share an idempotency key, so only the first one counts.
+func (s *MeteringServiceImpl) SnapshotStorage(ctx context.Context, interval time.Duration) {
+	s.snapshotStorage(ctx)
+	t := time.NewTicker(interval)
+	for {
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C:
+		}
+		s.snapshotStorage(ctx)
+	}
+}
+
+func (s *MeteringServiceImpl) snapshotStorage(ctx context.Context) {
+	usage, err := s.userRepo.StorageUsage(ctx)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to measure storage for metering: %v", err)
+		return
+	}
+	now := time.Now().UTC()
+	day := now.Format(time.DateOnly)
+	for userID, bytes := range usage {
+		s.Record(ctx, models.MeteringEvent{
+			IdempotencyKey: fmt.Sprintf("%s:%s:%s", models.MeterStorageBytes, userID, day),
+			UserID:         userID,
+			Meter:          models.MeterStorageBytes,
+			Quantity:       bytes,
+			OccurredAt:     now,
+		})
+	}
+}
+
+// Reconcile totals the stored usage of a period, [since, until), next to this instance's pipeline
+// counters, so missing or failed writes show up before usage is invoiced.
+func (s *MeteringServiceImpl) Reconcile(ctx context.Context, filter models.MeteringFilter) (*models.MeteringReport, error) {
+	if filter.Until.IsZero() {
+		filter.Until = time.Now().UTC()
+	}
+	if filter.Since.IsZero() {
+		filter.Since = filter.Until.AddDate(0, -1, 0)
+	}
+	if !filter.Since.Before(filter.Until) {
+		return nil, invalid("service: since must be before until")
+	}
+	if filter.Until.Sub(filter.Since) > maxMeteringPeriod {
+		return nil, invalid("service: period must not be longer than 366 days")
+	}
+
+	meters, users, err := s.meteringRepo.Summarize(ctx, filter)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to summarize metering events: %v", err)
+		return nil, fmt.Errorf("service: failed to summarize usage: %w", err)
+	}
+
+	stats := models.MeteringPipelineStats{
+		Emitted:    s.emitted.Load(),
+		Written:    s.written.Load(),
+		Duplicates: s.duplicates.Load(),
+		Failed:     s.failed.Load(),
+		Pending:    int64(len(s.queue)),
+	}
+	// Events being written right now are neither pending nor counted, so a busy pipeline can be briefly unbalanced.
+	stats.Balanced = stats.Emitted == stats.Written+stats.Duplicates+stats.Failed+stats.Pending
+	return &models.MeteringReport{Since: filter.Since, Until: filter.Until, Meters: meters, Users: users, Pipeline: stats}, nil
+}
@@ -0,0 +1,184 @@
+// services/user-service/internal/services/onboarding_service.go
+package services
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/repository"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// Bounds on the age accepted for onboarding recommendations.
+const (
+	minOnboardingAge = 13
+	maxOnboardingAge = 120
+)
+
+// OnboardingServiceImpl implements the OnboardingService interface.
+type OnboardingServiceImpl struct {
+	rules    *models.OnboardingRuleset
+	userRepo repository.UserRepository
+	events   UserEventService // Records onboarding moves on the user's own timeline
+}
+
+// NewOnboardingService creates a new instance of OnboardingServiceImpl on a validated ruleset.
+func NewOnboardingService(rules *models.OnboardingRuleset, userRepo repository.UserRepository, events UserEventService) *OnboardingServiceImpl {
+	return &OnboardingServiceImpl{rules: rules, userRepo: userRepo, events: events}
+}
+
+// Recommend returns the goals, reminder defaults, and starter plan of the first rule matching the answers.
+func (s *OnboardingServiceImpl) Recommend(ctx context.Context, answers models.OnboardingAnswers) (*models.OnboardingRecommendation, error) {
+	if answers.Age < minOnboardingAge || answers.Age > maxOnboardingAge {
+		return nil, invalid("service: age must be between %d and %d", minOnboardingAge, maxOnboardingAge)
+	}
+	if !slices.Contains(s.rules.ActivityLevels, answers.ActivityLevel) {
+		return nil, invalid("service: activity_level must be one of %s", strings.Join(s.rules.ActivityLevels, ", "))
+	}
+	if !slices.Contains(s.rules.Objectives, answers.Objective) {
+		return nil, invalid("service: objective must be one of %s", strings.Join(s.rules.Objectives, ", "))
+	}
+
+	for _, rule := range s.rules.Rules {
+		if !matchesOnboardingCondition(rule.When, answers) {
+			continue
+		}
+		return &models.OnboardingRecommendation{
+			Rule:           rule.Name,
+			RulesetVersion: s.rules.Version,
+			Goals:          rule.Goals,
+			Reminders:      rule.Reminders,
+			StarterPlan:    rule.StarterPlan,
+		}, nil
+	}
+	// Unreachable with a validated ruleset, whose last rule matches everything.
+	return nil, fmt.Errorf("service: no onboarding rule matched")
+}
+
+func matchesOnboardingCondition(when models.OnboardingCondition, answers models.OnboardingAnswers) bool {
+	if when.MinAge > 0 && answers.Age < when.MinAge {
+		return false
+	}
+	if when.MaxAge > 0 && answers.Age > when.MaxAge {
+		return false
+	}
+	if len(when.ActivityLevels) > 0 && !slices.Contains(when.ActivityLevels, answers.ActivityLevel) {
+		return false
+	}
+	if len(when.Objectives) > 0 && !slices.Contains(when.Objectives, answers.Objective) {
+		return false
+	}
+	return true
+}
+
+// GetState returns where a user is in the first-run flow and which step comes next.
+func (s *OnboardingServiceImpl) GetState(ctx context.Context, userID uuid.UUID) (*models.OnboardingState, error) {
+	state, err := s.userRepo.GetOnboarding(ctx, userID)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to retrieve onboarding state for user '%s': %v", userID, err)
+		return nil, fmt.Errorf("service: failed to retrieve onboarding state: %w", err)
+	}
+	if state == nil {
+		return nil, notFound("service: user not found")
+	}
+	state.Next = nextOnboardingStep(state.Step)
+	return state, nil
+}
+
+// Advance moves a user to step, which must be the step after their current one, or done to skip the
+// rest of the flow. Posting the current step again changes nothing, so clients can retry safely.
+func (s *OnboardingServiceImpl) Advance(ctx context.Context, userID uuid.UUID, step string) (*models.OnboardingState, error) {
+	if !slices.Contains(models.OnboardingSteps, step) {
+		return nil, invalid("service: step must be one of %s", strings.Join(models.OnboardingSteps, ", "))
+	}
+	state, err := s.GetState(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if state.Step == step {
+		return state, nil
+	}
+	skip := step != state.Next
+	if skip && step != models.OnboardingDone {
+		return nil, conflict("service: cannot move onboarding from %s to %s; next is %s", state.Step, step, state.Next)
+	}
+
+	now := time.Now().UTC()
+	moved, err := s.userRepo.AdvanceOnboarding(ctx, userID, state.Step, step, skip, now)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to advance onboarding for user '%s': %v", userID, err)
+		return nil, fmt.Errorf("service: failed to advance onboarding: %w", err)
+	}
+	if !moved {
+		// Another request moved the user first; it is only an error if it moved them elsewhere.
+		current, err := s.GetState(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		if current.Step != step {
+			return nil, conflict("service: cannot move onboarding from %s to %s; next is %s", current.Step, step, current.Next)
+		}
+		return current, nil
+	}
+
+	summary := "Onboarding step " + step + " reached"
+	if skip {
+		summary = "Onboarding skipped at " + state.Step
+	}
+	s.events.Record(ctx, userID, models.UserEventOnboardingAdvanced, summary,
+		map[string]string{"from": state.Step, "to": step, "skipped": fmt.Sprint(skip)})
+	logger.FromContext(ctx).Infof("Onboarding of user %s moved from %s to %s", userID, state.Step, step)
+	return &models.OnboardingState{Step: step, Next: nextOnboardingStep(step), UpdatedAt: now}, nil
+}
+
+// Funnel counts the users registered since since at each onboarding step, to measure drop-off.
+// Users who skipped count as having reached the step they skipped from, but none after it.
+func (s *OnboardingServiceImpl) Funnel(ctx context.Context, since time.Time) (*models.OnboardingFunnel, error) {
+	counts, err := s.userRepo.CountOnboardingSteps(ctx, since)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to count onboarding steps: %v", err)
+		return nil, fmt.Errorf("service: failed to count onboarding steps: %w", err)
+	}
+
+	funnel := &models.OnboardingFunnel{Steps: make([]models.OnboardingFunnelStep, len(models.OnboardingSteps))}
+	if !since.IsZero() {
+		funnel.Since = &since
+	}
+	for i, step := range models.OnboardingSteps {
+		funnel.Steps[i].Step = step
+	}
+	for _, c := range counts {
+		furthest := c.Step
+		if c.SkippedFrom != "" {
+			furthest = c.SkippedFrom
+		}
+		last := slices.Index(models.OnboardingSteps, furthest)
+		if last < 0 {
+			continue // Not a step this version knows
+		}
+		funnel.Users += c.Users
+		if c.SkippedFrom != "" {
+			funnel.Skipped += c.Users
+		} else {
+			funnel.Steps[last].Current += c.Users
+		}
+		for i := 0; i <= last; i++ {
+			funnel.Steps[i].Reached += c.Users
+		}
+	}
+	return funnel, nil
+}
+
+// nextOnboardingStep returns the step after step, or "" for done.
+func nextOnboardingStep(step string) string {
+	i := slices.Index(models.OnboardingSteps, step)
+	if i < 0 || i == len(models.OnboardingSteps)-1 {
+		return ""
+	}
+	return models.OnboardingSteps[i+1]
+}
@@ -0,0 +1,122 @@
+// services/user-service/internal/services/outbox_service.go
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/eventbus"
+	"health-tracker-project/services/user-service/internal/metrics"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/repository"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+const (
+	outboxBatchSize  = 100                // Events claimed at a time
+	outboxLease      = time.Minute        // How long a claimed event is kept from other dispatchers
+	outboxMaxBackoff = 10 * time.Minute   // Longest wait before publishing a failed event again
+	outboxRetention  = 7 * 24 * time.Hour // How long sent events are kept, for investigating deliveries
+)
+
+// OutboxServiceImpl implements the OutboxService interface. Every replica runs Dispatch: each pending
+// event is leased to one dispatcher at a time, and taken over by another if its dispatcher stops.
+type OutboxServiceImpl struct {
+	outboxRepo repository.OutboxRepository
+	publisher  eventbus.Publisher
+}
+
+// NewOutboxService creates a new instance of OutboxServiceImpl.
+func NewOutboxService(outboxRepo repository.OutboxRepository, publisher eventbus.Publisher) *OutboxServiceImpl {
+	return &OutboxServiceImpl{outboxRepo: outboxRepo, publisher: publisher}
+}
+
+// DispatchPending publishes the pending events, oldest first, and marks each one sent. It stops at the
+// first event that fails to publish: that event is retried after a backoff that grows with its failed
+// attempts, and the rest of its batch waits as long, rather than each timing out against a gateway that
+// is down. An event published but not marked sent is published again once its lease ends, which
+// consumers tolerate, since they must handle redelivery.
+func (s *OutboxServiceImpl) DispatchPending(ctx context.Context) (int, error) {
+	published := 0
+	for {
+		now := time.Now().UTC()
+		events, err := s.outboxRepo.ClaimOutboxEvents(ctx, now, now.Add(outboxLease), outboxBatchSize)
+		if err != nil {
+			return published, fmt.Errorf("service: failed to claim outbox events: %w", err)
+		}
+		for i, e := range events {
+			if err := s.publisher.Publish(ctx, outboxEventbusEvent(e)); err != nil {
+				metrics.OutboxPublishFailed()
+				s.retryLater(ctx, e, events[i+1:], err)
+				return published, fmt.Errorf("service: failed to publish %s event %s: %w", e.Type, e.ID, err)
+			}
+			published++
+			metrics.OutboxPublished(1)
+			if err := s.outboxRepo.MarkOutboxEventSent(ctx, e.ID, time.Now().UTC()); err != nil {
+				return published, fmt.Errorf("service: failed to mark %s event %s sent: %w", e.Type, e.ID, err)
+			}
+		}
+		if len(events) < outboxBatchSize {
+			return published, nil
+		}
+	}
+}
+
+// retryLater records a failed attempt to publish an event and hands the events claimed after it back,
+// all to be retried after the event's backoff.
+func (s *OutboxServiceImpl) retryLater(ctx context.Context, failed models.OutboxEvent, rest []models.OutboxEvent, cause error) {
+	retryAt := time.Now().UTC().Add(outboxBackoff(failed.Attempts))
+	if err := s.outboxRepo.FailOutboxEvent(ctx, failed.ID, retryAt, cause.Error()); err != nil {
+		logger.FromContext(ctx).Warnf("Failed to record failed publish of outbox event %s, retrying once its lease ends: %v", failed.ID, err)
+	}
+	ids := make([]uuid.UUID, len(rest))
+	for i, e := range rest {
+		ids[i] = e.ID
+	}
+	if err := s.outboxRepo.ReleaseOutboxEvents(ctx, ids, retryAt); err != nil {
+		logger.FromContext(ctx).Warnf("Failed to release %d outbox events, retrying once their lease ends: %v", len(ids), err)
+	}
+}
+
+// outboxBackoff returns how long to wait before publishing an event that failed attempts times before
+// this failure: a second, doubling with each earlier failure, up to outboxMaxBackoff.
+func outboxBackoff(attempts int) time.Duration {
+	if attempts >= 10 {
+		return outboxMaxBackoff
+	}
+	return min(time.Second<<attempts, outboxMaxBackoff)
+}
+
+// outboxEventbusEvent returns the event to publish for an outbox event.
+func outboxEventbusEvent(e models.OutboxEvent) eventbus.Event {
+	return eventbus.Event{ID: e.ID, Type: e.Type, UserID: e.UserID, OccurredAt: e.OccurredAt, Data: e.Data}
+}
+
+// Dispatch publishes the pending events on every tick of interval, and deletes the events sent more
+// than outboxRetention ago once an hour. Blocks until ctx is done; run it in a goroutine.
+func (s *OutboxServiceImpl) Dispatch(ctx context.Context, interval time.Duration) {
+	var purgedAt time.Time
+	t := time.NewTicker(interval)
+	for {
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C:
+		}
+		if published, err := s.DispatchPending(ctx); err != nil {
+			logger.FromContext(ctx).Warnf("Outbox dispatch stopped after publishing %d events: %v", published, err)
+		}
+		if time.Since(purgedAt) < time.Hour {
+			continue
+		}
+		purgedAt = time.Now()
+		if n, err := s.outboxRepo.PurgeSentOutboxEvents(ctx, purgedAt.UTC().Add(-outboxRetention)); err != nil {
+			logger.FromContext(ctx).Errorf("Failed to purge sent outbox events: %v", err)
+		} else if n > 0 {
+			logger.FromContext(ctx).Infof("Purged %d sent outbox events", n)
+		}
+	}
+}
@@ -0,0 +1,299 @@
+// services/user-service/internal/services/quicklog_service.go
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/utils/measure"
+)
+
+// Plausible bounds for quick-logged values.
+const (
+	maxQuickLogDistanceM = 1000 * 1000
+	maxQuickLogSteps     = 200000
+	maxQuickLogVolumeML  = 10000
+	minQuickLogWeightKg  = 20
+	maxQuickLogWeightKg  = 400
+	minQuickLogBPM       = 25
+	maxQuickLogBPM       = 250
+	maxQuickLogDuration  = 24 * time.Hour
+)
+
+// quickLogActivities maps the words a workout phrase starts with to its activity.
+var quickLogActivities = map[string]string{
+	"ran": "running", "run": "running", "running": "running", "jog": "running", "jogged": "running", "jogging": "running",
+	"walked": "walking", "walk": "walking", "walking": "walking",
+	"cycled": "cycling", "cycle": "cycling", "cycling": "cycling", "biked": "cycling", "bike": "cycling", "biking": "cycling", "rode": "cycling", "ride": "cycling",
+	"swam": "swimming", "swim": "swimming", "swimming": "swimming",
+	"hiked": "hiking", "hike": "hiking", "hiking": "hiking",
+	"rowed": "rowing", "row": "rowing", "rowing": "rowing",
+	"yoga":   "yoga",
+	"lifted": "strength_training", "lifting": "strength_training", "strength": "strength_training",
+}
+
+// metresPer maps the distance units of workout phrases to metres.
+var metresPer = map[string]float64{
+	"k": 1000, "km": 1000, "kms": 1000, "kilometer": 1000, "kilometers": 1000, "kilometre": 1000, "kilometres": 1000,
+	"mi": measure.MiToKm(1) * 1000, "mile": measure.MiToKm(1) * 1000, "miles": measure.MiToKm(1) * 1000,
+	"m": 1, "meter": 1, "meters": 1, "metre": 1, "metres": 1,
+	"yd": 0.9144, "yard": 0.9144, "yards": 0.9144,
+}
+
+// millilitresPer maps the volume units of hydration phrases to millilitres. Cups are US cups.
+var millilitresPer = map[string]float64{
+	"ml": 1, "cl": 10, "l": 1000, "liter": 1000, "liters": 1000, "litre": 1000, "litres": 1000,
+	"oz": 29.5735, "fl oz": 29.5735, "cup": 240, "cups": 240, "glass": 250, "glasses": 250,
+}
+
+// The quick-log grammar. Phrases are lowercased, with runs of spaces collapsed and final punctuation
+// removed, before they are matched; rules are tried in the order of Parse.
+var (
+	quickLogSeparator = regexp.MustCompile(`\s*(?:[;\n]|[,.!?]\s|\band\b|\bthen\b)\s*`)
+	quickLogNumber    = `(\d[\d.,]*)`
+	quickLogSteps     = regexp.MustCompile(`^(?:walked\s+)?` + quickLogNumber + `\s+steps$|^steps\s+` + quickLogNumber + `$`)
+	quickLogHeartRate = regexp.MustCompile(`^(?:resting\s+)?(?:heart\s*rate|hr|pulse)\s+(?:(?:is|was|of|at)\s+)?` + quickLogNumber + `(?:\s*bpm)?$|^` + quickLogNumber + `\s*bpm$`)
+	quickLogWeight    = regexp.MustCompile(`^(?:my\s+)?(?:weight|weigh|weighed|weighing|wt)\s+(?:(?:is|was|of|at|in at)\s+)?(.+)$`)
+	quickLogSleep     = regexp.MustCompile(`^(?:slept|sleep|sleeping)\s+(?:for\s+)?(.+)$`)
+	quickLogWater     = regexp.MustCompile(`^(?:drank|drink|water|hydration)\s+(.+?)(?:\s+(?:of\s+)?water)?$|^(.+?)\s+(?:of\s+)?water$`)
+	quickLogVolume    = regexp.MustCompile(`^` + quickLogNumber + `\s*(ml|cl|l|liters?|litres?|fl oz|oz|cups?|glass(?:es)?)$`)
+	quickLogDistance  = regexp.MustCompile(`^` + quickLogNumber + `\s*(k|km|kms|kilomet(?:er|re)s?|mi|miles?|m|met(?:er|re)s?|yd|yards?)\b\s*`)
+	quickLogConnector = regexp.MustCompile(`^(?:in|for|took|over)\s+`)
+)
+
+// QuickLogServiceImpl implements the QuickLogService interface.
+type QuickLogServiceImpl struct{}
+
+// NewQuickLogService creates a new instance of QuickLogServiceImpl.
+func NewQuickLogService() *QuickLogServiceImpl {
+	return &QuickLogServiceImpl{}
+}
+
+// Parse splits text into phrases at semicolons, line breaks, commas or sentence ends followed by a space,
+// "and", and "then", and reads each phrase with a fixed grammar: steps, heart rate, weight, sleep, water, and
+// workouts. Numbers follow the locale like other measurement input. Phrases the grammar does not cover,
+// or whose values are implausible, are rejected one by one; it is an error if none is understood.
+func (s *QuickLogServiceImpl) Parse(ctx context.Context, text, locale string) (*models.QuickLogResult, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, invalid("service: text is required")
+	}
+	if len(text) > models.MaxQuickLogLength {
+		return nil, invalid("service: text must be at most %d bytes", models.MaxQuickLogLength)
+	}
+
+	result := &models.QuickLogResult{Entries: []models.QuickLogEntry{}, Rejected: []models.QuickLogRejection{}}
+	for _, phrase := range quickLogSeparator.Split(text, -1) {
+		phrase = strings.TrimSpace(phrase)
+		if phrase == "" {
+			continue
+		}
+		entry, err := parseQuickLogPhrase(phrase, locale)
+		if err != nil {
+			result.Rejected = append(result.Rejected, models.QuickLogRejection{Phrase: phrase, Reason: err.Error()})
+			continue
+		}
+		entry.Phrase = phrase
+		result.Entries = append(result.Entries, *entry)
+	}
+	if len(result.Entries) == 0 {
+		reasons := make([]string, len(result.Rejected))
+		for i, r := range result.Rejected {
+			reasons[i] = fmt.Sprintf("%q: %s", r.Phrase, r.Reason)
+		}
+		return nil, invalid("service: no entry recognized (%s); try phrases like \"ran 5k in 28 minutes\" or \"weight 82.4\"",
+			strings.Join(reasons, "; "))
+	}
+
+	summaries := make([]string, len(result.Entries))
+	for i, entry := range result.Entries {
+		summaries[i] = entry.Summary
+	}
+	result.Confirmation = "Log " + strings.Join(summaries, ", ") + "?"
+	return result, nil
+}
+
+// parseQuickLogPhrase reads one phrase into an entry without its Phrase.
+func parseQuickLogPhrase(phrase, locale string) (*models.QuickLogEntry, error) {
+	p := strings.Join(strings.Fields(strings.ToLower(phrase)), " ")
+	p = strings.TrimRight(p, ".!")
+
+	if m := quickLogSteps.FindStringSubmatch(p); m != nil {
+		steps, err := measure.ParseNumber(m[1]+m[2], locale)
+		if err != nil {
+			return nil, err
+		}
+		if steps != math.Trunc(steps) || steps <= 0 || steps > maxQuickLogSteps {
+			return nil, fmt.Errorf("steps must be a whole number up to %d", maxQuickLogSteps)
+		}
+		return &models.QuickLogEntry{Type: models.QuickLogSteps, Value: steps, Unit: "steps",
+			Summary: fmt.Sprintf("%s steps", formatQuickLogNumber(steps))}, nil
+	}
+	if m := quickLogHeartRate.FindStringSubmatch(p); m != nil {
+		bpm, err := measure.ParseNumber(m[1]+m[2], locale)
+		if err != nil {
+			return nil, err
+		}
+		if bpm != math.Trunc(bpm) || bpm < minQuickLogBPM || bpm > maxQuickLogBPM {
+			return nil, fmt.Errorf("heart rate must be a whole number between %d and %d bpm", minQuickLogBPM, maxQuickLogBPM)
+		}
+		return &models.QuickLogEntry{Type: models.QuickLogHeartRate, Value: bpm, Unit: "bpm",
+			Summary: fmt.Sprintf("heart rate %s bpm", formatQuickLogNumber(bpm))}, nil
+	}
+	if m := quickLogWeight.FindStringSubmatch(p); m != nil {
+		kg, err := measure.ParseWeight(m[1], locale)
+		if err != nil {
+			return nil, err
+		}
+		if kg < minQuickLogWeightKg || kg > maxQuickLogWeightKg {
+			return nil, fmt.Errorf("weight must be between %d and %d kg", minQuickLogWeightKg, maxQuickLogWeightKg)
+		}
+		return &models.QuickLogEntry{Type: models.QuickLogWeight, Value: kg, Unit: "kg",
+			Summary: fmt.Sprintf("weight %s kg", formatQuickLogNumber(kg))}, nil
+	}
+	if m := quickLogSleep.FindStringSubmatch(p); m != nil {
+		d, err := parseQuickLogSleep(m[1], locale)
+		if err != nil {
+			return nil, err
+		}
+		return &models.QuickLogEntry{Type: models.QuickLogSleep, DurationS: int(d / time.Second),
+			Summary: "sleep " + formatQuickLogHours(d)}, nil
+	}
+	if m := quickLogWater.FindStringSubmatch(p); m != nil {
+		ml, err := parseQuickLogVolume(m[1]+m[2], locale)
+		if err != nil {
+			return nil, err
+		}
+		return &models.QuickLogEntry{Type: models.QuickLogHydration, Value: ml, Unit: "ml",
+			Summary: fmt.Sprintf("water %s ml", formatQuickLogNumber(ml))}, nil
+	}
+	if verb, rest, _ := strings.Cut(p, " "); quickLogActivities[verb] != "" {
+		return parseQuickLogWorkout(quickLogActivities[verb], rest, locale)
+	}
+	return nil, fmt.Errorf("not understood")
+}
+
+// parseQuickLogWorkout reads what follows the activity word: a distance, a duration, or a distance and
+// then a duration, optionally joined by "in", "for", "took", or "over".
+func parseQuickLogWorkout(activity, rest, locale string) (*models.QuickLogEntry, error) {
+	entry := &models.QuickLogEntry{Type: models.QuickLogWorkout, Activity: activity}
+	rest = quickLogConnector.ReplaceAllString(rest, "")
+	if m := quickLogDistance.FindStringSubmatch(rest); m != nil {
+		n, err := measure.ParseNumber(m[1], locale)
+		if err != nil {
+			return nil, err
+		}
+		metres := n * metresPer[m[2]]
+		if metres <= 0 || metres > maxQuickLogDistanceM {
+			return nil, fmt.Errorf("distance must be more than 0 and at most %d km", maxQuickLogDistanceM/1000)
+		}
+		entry.DistanceM = math.Round(metres*10) / 10
+		rest = quickLogConnector.ReplaceAllString(rest[len(m[0]):], "")
+	}
+	if rest != "" {
+		d, err := measure.ParseDuration(rest, locale, time.Minute)
+		if err != nil {
+			return nil, err
+		}
+		if d <= 0 || d > maxQuickLogDuration {
+			return nil, fmt.Errorf("duration must be more than 0 and at most 24 hours")
+		}
+		entry.DurationS = int(d / time.Second)
+	}
+	if entry.DistanceM == 0 && entry.DurationS == 0 {
+		return nil, fmt.Errorf("a workout needs a distance or a duration")
+	}
+
+	summary := strings.ReplaceAll(activity, "_", " ")
+	if entry.DistanceM > 0 {
+		summary += " " + formatQuickLogDistance(entry.DistanceM)
+	}
+	if entry.DurationS > 0 {
+		if entry.DistanceM > 0 {
+			summary += " in"
+		} else {
+			summary += " for"
+		}
+		summary += " " + formatQuickLogClock(time.Duration(entry.DurationS)*time.Second)
+	}
+	if entry.DistanceM > 0 && entry.DurationS > 0 {
+		switch activity {
+		case "running", "walking", "hiking":
+			pace := time.Duration(float64(entry.DurationS) / (entry.DistanceM / 1000) * float64(time.Second))
+			summary += fmt.Sprintf(" (%s /km)", formatQuickLogClock(pace))
+		case "cycling":
+			summary += fmt.Sprintf(" (%s km/h)", formatQuickLogNumber(math.Round(entry.DistanceM/float64(entry.DurationS)*36)/10))
+		}
+	}
+	entry.Summary = summary
+	return entry, nil
+}
+
+// parseQuickLogSleep reads a sleep duration. Unlike workouts, a bare number is in hours.
+func parseQuickLogSleep(s, locale string) (time.Duration, error) {
+	var d time.Duration
+	if hours, err := measure.ParseNumber(s, locale); err == nil {
+		d = time.Duration(hours * float64(time.Hour))
+	} else if d, err = measure.ParseDuration(s, locale, time.Minute); err != nil {
+		return 0, err
+	}
+	if d <= 0 || d > maxQuickLogDuration {
+		return 0, fmt.Errorf("sleep must be more than 0 and at most 24 hours")
+	}
+	return d.Round(time.Minute), nil
+}
+
+// parseQuickLogVolume reads a volume with its unit into millilitres.
+func parseQuickLogVolume(s, locale string) (float64, error) {
+	m := quickLogVolume.FindStringSubmatch(s)
+	if m == nil {
+		return 0, fmt.Errorf("invalid volume %q: give a unit such as ml, l, oz, or cups", s)
+	}
+	n, err := measure.ParseNumber(m[1], locale)
+	if err != nil {
+		return 0, err
+	}
+	ml := math.Round(n * millilitresPer[m[2]])
+	if ml <= 0 || ml > maxQuickLogVolumeML {
+		return 0, fmt.Errorf("volume must be more than 0 and at most %d l", maxQuickLogVolumeML/1000)
+	}
+	return ml, nil
+}
+
+// formatQuickLogDistance writes metres as km from 1 km up, to 0.01 km.
+func formatQuickLogDistance(metres float64) string {
+	if metres >= 1000 {
+		return formatQuickLogNumber(math.Round(metres/10)/100) + " km"
+	}
+	return formatQuickLogNumber(metres) + " m"
+}
+
+// formatQuickLogClock writes a duration as m:ss, or h:mm:ss from an hour up.
+func formatQuickLogClock(d time.Duration) string {
+	sec := int(d.Round(time.Second) / time.Second)
+	if sec >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", sec/3600, sec/60%60, sec%60)
+	}
+	return fmt.Sprintf("%d:%02d", sec/60, sec%60)
+}
+
+// formatQuickLogHours writes a duration as hours and minutes, e.g. "7 h 30 min".
+func formatQuickLogHours(d time.Duration) string {
+	h, min := int(d/time.Hour), int(d%time.Hour/time.Minute)
+	switch {
+	case h == 0:
+		return fmt.Sprintf("%d min", min)
+	case min == 0:
+		return fmt.Sprintf("%d h", h)
+	}
+	return fmt.Sprintf("%d h %d min", h, min)
+}
+
+func formatQuickLogNumber(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,71 @@
+// services/user-service/internal/services/residency_service.go
+package services
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/repository"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// ResidencyServiceImpl implements the ResidencyService interface.
+type ResidencyServiceImpl struct {
+	userRepo      repository.UserRepository
+	residencyRepo repository.ResidencyRepository
+	events        UserEventService // Records moves on the user's own timeline
+}
+
+// NewResidencyService creates a new instance of ResidencyServiceImpl.
+func NewResidencyService(userRepo repository.UserRepository, residencyRepo repository.ResidencyRepository, events UserEventService) *ResidencyServiceImpl {
+	return &ResidencyServiceImpl{userRepo: userRepo, residencyRepo: residencyRepo, events: events}
+}
+
+// MoveUser moves all data of a user to another region's database.
+func (s *ResidencyServiceImpl) MoveUser(ctx context.Context, id uuid.UUID, req models.ChangeRegionRequest) (*models.UserResponse, error) {
+	if req.Region == "" {
+		return nil, invalid("service: region is required")
+	}
+	if !slices.Contains(s.residencyRepo.Regions(), req.Region) {
+		return nil, invalid("service: unknown region, expected one of %s", strings.Join(s.residencyRepo.Regions(), ", "))
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, id)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to retrieve user '%s' for region move: %v", id, err)
+		return nil, fmt.Errorf("service: failed to retrieve user: %w", err)
+	}
+	if user == nil {
+		return nil, notFound("service: user not found")
+	}
+	if user.Region == req.Region {
+		return nil, conflict("service: user is already in region %s", req.Region)
+	}
+
+	previous := user.Region
+	if err := s.residencyRepo.MigrateSubject(ctx, id, req.Region); err != nil {
+		logger.FromContext(ctx).Errorf("Failed to move user '%s' to region %s: %v", id, req.Region, err)
+		return nil, fmt.Errorf("service: failed to move user: %w", err)
+	}
+	user.Region = req.Region
+
+	s.events.Record(ctx, id, models.UserEventRegionChanged, "Data moved to region "+req.Region,
+		map[string]string{"from": previous, "to": req.Region})
+	logger.FromContext(ctx).Infof("User %s moved from region %s to %s", id, previous, req.Region)
+	resp := user.ToUserResponse()
+	return &resp, nil
+}
+
+// FindViolations lists users whose data was found outside their assigned region.
+func (s *ResidencyServiceImpl) FindViolations(ctx context.Context) ([]models.ResidencyViolation, error) {
+	violations, err := s.residencyRepo.FindViolations(ctx)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to check data residency: %v", err)
+		return nil, fmt.Errorf("service: failed to check data residency: %w", err)
+	}
+	return violations, nil
+}
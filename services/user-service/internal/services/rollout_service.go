@@ -0,0 +1,124 @@
+// services/user-service/internal/services/rollout_service.go
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"health-tracker-project/services/user-service/internal/config"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/repository"
+	"health-tracker-project/services/user-service/internal/utils/reqctx"
+)
+
+// RolloutServiceImpl implements the RolloutService interface.
+type RolloutServiceImpl struct {
+	userRepo repository.UserRepository // For the user's plan
+}
+
+// NewRolloutService creates a new instance of RolloutServiceImpl.
+func NewRolloutService(userRepo repository.UserRepository) *RolloutServiceImpl {
+	return &RolloutServiceImpl{userRepo: userRepo}
+}
+
+// Enabled reports whether a feature is on for the audience. A per-request override in X-Feature-Flags
+// decides first, where overrides are trusted. A feature without a rollout in the runtime config has
+// launched and is on for everyone; one with a rollout needs its feature flag on and the audience admitted.
+func (s *RolloutServiceImpl) Enabled(ctx context.Context, feature string, audience models.RolloutAudience) (bool, error) {
+	if enabled, ok := reqctx.FeatureOverride(ctx, feature); ok {
+		return enabled, nil
+	}
+	cfg := config.Current()
+	rollout, ok := cfg.Rollouts[feature]
+	if !ok {
+		return true, nil
+	}
+	if !cfg.FeatureFlags[feature] {
+		return false, nil
+	}
+	return s.admits(ctx, feature, rollout, audience, nil)
+}
+
+// Features reports, for every feature in a rollout, whether it is on for the audience, so clients
+// can hide what the server would refuse. The plan is looked up at most once.
+func (s *RolloutServiceImpl) Features(ctx context.Context, audience models.RolloutAudience) (map[string]bool, error) {
+	cfg := config.Current()
+	var plan *string
+	features := make(map[string]bool, len(cfg.Rollouts))
+	for feature, rollout := range cfg.Rollouts {
+		if enabled, ok := reqctx.FeatureOverride(ctx, feature); ok {
+			features[feature] = enabled
+			continue
+		}
+		if !cfg.FeatureFlags[feature] {
+			features[feature] = false
+			continue
+		}
+		if len(rollout.Plans) > 0 && plan == nil {
+			p, err := s.plan(ctx, audience)
+			if err != nil {
+				return nil, err
+			}
+			plan = &p
+		}
+		enabled, err := s.admits(ctx, feature, rollout, audience, plan)
+		if err != nil {
+			return nil, err
+		}
+		features[feature] = enabled
+	}
+	return features, nil
+}
+
+// admits checks the audience against a rollout's lists, then its percentage. plan is looked up when nil.
+func (s *RolloutServiceImpl) admits(ctx context.Context, feature string, rollout config.Rollout, audience models.RolloutAudience, plan *string) (bool, error) {
+	if len(rollout.Countries) > 0 && !slices.Contains(rollout.Countries, strings.ToUpper(audience.Country)) {
+		return false, nil
+	}
+	if len(rollout.Locales) > 0 && !slices.ContainsFunc(rollout.Locales, func(tag string) bool { return localeMatches(tag, audience.Locale) }) {
+		return false, nil
+	}
+	if len(rollout.Plans) > 0 {
+		if plan == nil {
+			p, err := s.plan(ctx, audience)
+			if err != nil {
+				return false, err
+			}
+			plan = &p
+		}
+		if !slices.Contains(rollout.Plans, *plan) {
+			return false, nil
+		}
+	}
+	return rollout.Percent == 0 || rolloutBucket(feature, audience) < rollout.Percent, nil
+}
+
+// plan returns the user's plan from their metadata, or "" if none is recorded.
+func (s *RolloutServiceImpl) plan(ctx context.Context, audience models.RolloutAudience) (string, error) {
+	metadata, err := s.userRepo.GetUserMetadata(ctx, audience.UserID)
+	if err != nil {
+		return "", fmt.Errorf("service: failed to look up the user's plan: %w", err)
+	}
+	var plan string
+	json.Unmarshal(metadata[models.UserPlanMetadataKey], &plan) // Not a string: no plan
+	return plan, nil
+}
+
+// localeMatches reports whether a locale falls under a rollout's tag: the same tag, or a more
+// specific one ("de" admits "de-AT" and "de_AT"), ignoring case.
+func localeMatches(tag, locale string) bool {
+	locale = strings.ReplaceAll(locale, "_", "-")
+	return strings.EqualFold(tag, locale) || len(locale) > len(tag) && strings.EqualFold(tag, locale[:len(tag)]) && locale[len(tag)] == '-'
+}
+
+// rolloutBucket places a user in one of 100 buckets of a feature. A user keeps their bucket while
+// the percentage grows, so raising it only adds users; each feature shuffles users differently.
+func rolloutBucket(feature string, audience models.RolloutAudience) int {
+	sum := sha256.Sum256([]byte(feature + ":" + audience.UserID.String()))
+	return int(binary.BigEndian.Uint64(sum[:8]) % 100)
+}
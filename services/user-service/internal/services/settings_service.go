@@ -0,0 +1,165 @@
+// services/user-service/internal/services/settings_service.go
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/repository"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// SettingsServiceImpl implements the SettingsService interface.
+type SettingsServiceImpl struct {
+	settingsRepo repository.SettingsRepository
+}
+
+// NewSettingsService creates a new instance of SettingsServiceImpl.
+func NewSettingsService(settingsRepo repository.SettingsRepository) *SettingsServiceImpl {
+	return &SettingsServiceImpl{settingsRepo: settingsRepo}
+}
+
+// GetSettings returns every known setting, with the user's value or the default.
+func (s *SettingsServiceImpl) GetSettings(ctx context.Context, userID uuid.UUID) (*models.UserSettings, error) {
+	stored, err := s.settingsRepo.GetSettings(ctx, userID)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to retrieve settings for user %s: %v", userID, err)
+		return nil, fmt.Errorf("service: failed to retrieve settings: %w", err)
+	}
+	return applySettingDefaults(stored), nil
+}
+
+// UpdateSettings sets the given keys and leaves the others as they are. A null value resets a key to
+// its default. Unknown keys and invalid values reject the whole update.
+func (s *SettingsServiceImpl) UpdateSettings(ctx context.Context, userID uuid.UUID, changes map[string]json.RawMessage) (*models.UserSettings, error) {
+	stored, err := s.settingsRepo.GetSettings(ctx, userID)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to retrieve settings for user %s: %v", userID, err)
+		return nil, fmt.Errorf("service: failed to retrieve settings: %w", err)
+	}
+	if len(changes) == 0 {
+		return applySettingDefaults(stored), nil
+	}
+
+	// Start from the stored values still valid, so keys since dropped from the catalogue are cleaned up.
+	values := map[string]interface{}{}
+	if stored != nil {
+		for key, v := range stored.Settings {
+			if def, ok := settingDefinition(key); ok {
+				if value, err := settingValue(def, v); err == nil {
+					values[key] = value
+				}
+			}
+		}
+	}
+	keys := make([]string, 0, len(changes))
+	for key := range changes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys) // Report the first invalid key in a stable order
+	for _, key := range keys {
+		def, ok := settingDefinition(key)
+		if !ok {
+			return nil, invalid("service: invalid settings: unknown setting %q", key)
+		}
+		var v interface{}
+		if err := json.Unmarshal(changes[key], &v); err != nil {
+			return nil, invalid("service: invalid settings: %s is not valid JSON", key)
+		}
+		if v == nil {
+			delete(values, key)
+			continue
+		}
+		value, err := settingValue(def, v)
+		if err != nil {
+			return nil, invalid("service: invalid settings: %s %v", key, err)
+		}
+		values[key] = value
+	}
+
+	now := time.Now().UTC()
+	saved := &models.UserSettings{Settings: values, UpdatedAt: &now}
+	if err := s.settingsRepo.SaveSettings(ctx, userID, saved); err != nil {
+		logger.FromContext(ctx).Errorf("Failed to save settings for user %s: %v", userID, err)
+		return nil, fmt.Errorf("service: failed to save settings: %w", err)
+	}
+	logger.FromContext(ctx).Infof("User %s updated settings %s", userID, strings.Join(keys, ", "))
+	return applySettingDefaults(saved), nil
+}
+
+// applySettingDefaults returns every known setting from stored values, which may be nil. Stored values
+// that no longer pass their definition, for example after a bound was tightened, read as the default.
+func applySettingDefaults(stored *models.UserSettings) *models.UserSettings {
+	settings := &models.UserSettings{Settings: map[string]interface{}{}, Customized: []string{}}
+	var values map[string]interface{}
+	if stored != nil {
+		values = stored.Settings
+		settings.UpdatedAt = stored.UpdatedAt
+	}
+	for _, def := range models.SettingDefinitions {
+		settings.Settings[def.Key] = def.Default
+		v, ok := values[def.Key]
+		if !ok {
+			continue
+		}
+		value, err := settingValue(def, v)
+		if err != nil {
+			continue
+		}
+		settings.Settings[def.Key] = value
+		settings.Customized = append(settings.Customized, def.Key)
+	}
+	sort.Strings(settings.Customized)
+	return settings
+}
+
+// settingDefinition looks up a known setting by key.
+func settingDefinition(key string) (models.SettingDefinition, bool) {
+	i := slices.IndexFunc(models.SettingDefinitions, func(def models.SettingDefinition) bool { return def.Key == key })
+	if i < 0 {
+		return models.SettingDefinition{}, false
+	}
+	return models.SettingDefinitions[i], true
+}
+
+// settingValue checks a decoded JSON value against its definition and returns it in the definition's type.
+func settingValue(def models.SettingDefinition, v interface{}) (interface{}, error) {
+	switch def.Type {
+	case models.SettingBool:
+		if b, ok := v.(bool); ok {
+			return b, nil
+		}
+		return nil, fmt.Errorf("must be true or false")
+	case models.SettingInt:
+		var n int
+		switch v := v.(type) {
+		case float64:
+			if v != math.Trunc(v) || v < math.MinInt32 || v > math.MaxInt32 {
+				return nil, fmt.Errorf("must be a whole number between %d and %d", def.Min, def.Max)
+			}
+			n = int(v)
+		case int:
+			n = v
+		default:
+			return nil, fmt.Errorf("must be a whole number between %d and %d", def.Min, def.Max)
+		}
+		if n < def.Min || n > def.Max {
+			return nil, fmt.Errorf("must be a whole number between %d and %d", def.Min, def.Max)
+		}
+		return n, nil
+	case models.SettingString:
+		if s, ok := v.(string); ok && slices.Contains(def.Values, s) {
+			return s, nil
+		}
+		return nil, fmt.Errorf("must be one of %s", strings.Join(def.Values, ", "))
+	}
+	return nil, fmt.Errorf("has unsupported type %q", def.Type)
+}
@@ -0,0 +1,90 @@
+// services/user-service/internal/services/system_event_service.go
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/repository"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+const (
+	defaultTimelineLimit = 100
+	maxTimelineLimit     = 500
+)
+
+// SystemEventServiceImpl implements the SystemEventService interface.
+type SystemEventServiceImpl struct {
+	eventRepo repository.SystemEventRepository
+}
+
+// NewSystemEventService creates a new instance of SystemEventServiceImpl.
+func NewSystemEventService(eventRepo repository.SystemEventRepository) *SystemEventServiceImpl {
+	return &SystemEventServiceImpl{eventRepo: eventRepo}
+}
+
+// RecordEvent validates and stores an event submitted through the admin API.
+func (s *SystemEventServiceImpl) RecordEvent(ctx context.Context, req models.CreateSystemEventRequest, actor string) (*models.SystemEvent, error) {
+	switch req.Type {
+	case models.SystemEventDeploy, models.SystemEventMigration, models.SystemEventConfigChange, models.SystemEventMaintenance:
+	default:
+		return nil, invalid("service: event type is required and must be one of deploy, migration, config_change, maintenance")
+	}
+	if req.Message == "" {
+		return nil, invalid("service: event message is required")
+	}
+
+	now := time.Now().UTC()
+	startsAt := now
+	if req.StartsAt != nil {
+		startsAt = req.StartsAt.UTC()
+	}
+	if req.EndsAt != nil && req.EndsAt.Before(startsAt) {
+		return nil, invalid("service: event ends_at must not be before starts_at")
+	}
+
+	event := &models.SystemEvent{
+		ID:        uuid.New(),
+		Type:      req.Type,
+		Message:   req.Message,
+		Actor:     actor,
+		StartsAt:  startsAt,
+		EndsAt:    req.EndsAt,
+		CreatedAt: now,
+	}
+	if err := s.eventRepo.CreateEvent(ctx, event); err != nil {
+		logger.FromContext(ctx).Errorf("Failed to record system event: %v", err)
+		return nil, fmt.Errorf("service: failed to record system event: %w", err)
+	}
+	logger.FromContext(ctx).Infof("System event recorded: %s by %s", event.Type, actor)
+	return event, nil
+}
+
+// Record stores an event raised by the service itself (e.g. migrations at startup).
+// Failures are logged rather than returned, since the timeline must never block the caller.
+func (s *SystemEventServiceImpl) Record(ctx context.Context, eventType, message string) {
+	if _, err := s.RecordEvent(context.WithoutCancel(ctx), models.CreateSystemEventRequest{Type: eventType, Message: message}, "system"); err != nil {
+		logger.FromContext(ctx).Warnf("Failed to record %s system event: %v", eventType, err)
+	}
+}
+
+// GetTimeline returns events matching the filter, newest first.
+func (s *SystemEventServiceImpl) GetTimeline(ctx context.Context, filter models.SystemEventFilter) ([]models.SystemEvent, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultTimelineLimit
+	}
+	if filter.Limit > maxTimelineLimit {
+		filter.Limit = maxTimelineLimit
+	}
+
+	events, err := s.eventRepo.ListEvents(ctx, filter)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to retrieve system timeline: %v", err)
+		return nil, fmt.Errorf("service: failed to retrieve timeline: %w", err)
+	}
+	return events, nil
+}
@@ -0,0 +1,77 @@
+// services/user-service/internal/services/user_event_service.go
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/realtime"
+	"health-tracker-project/services/user-service/internal/repository"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+const (
+	defaultUserTimelineLimit = 50
+	maxUserTimelineLimit     = 200
+)
+
+// UserEventServiceImpl implements the UserEventService interface.
+type UserEventServiceImpl struct {
+	eventRepo repository.UserEventRepository
+	stream    realtime.Publisher // Delivers events to the user's open connections; nil to skip
+}
+
+// NewUserEventService creates a new instance of UserEventServiceImpl. stream may be nil.
+func NewUserEventService(eventRepo repository.UserEventRepository, stream realtime.Publisher) *UserEventServiceImpl {
+	return &UserEventServiceImpl{eventRepo: eventRepo, stream: stream}
+}
+
+// Record stores a domain event on a user's timeline and streams it to the user's open connections.
+// Failures are logged rather than returned, since the timeline must never block the action it describes.
+func (s *UserEventServiceImpl) Record(ctx context.Context, userID uuid.UUID, eventType, summary string, details map[string]string) {
+	event := newUserEvent(userID, eventType, summary, details)
+	if err := s.eventRepo.CreateEvent(context.WithoutCancel(ctx), &event); err != nil {
+		logger.FromContext(ctx).Warnf("Failed to record %s event for user %s: %v", eventType, userID, err)
+	}
+	if s.stream != nil {
+		s.stream.Publish(userID, event)
+	}
+}
+
+// Notify streams an event to the user's open connections without recording it, for changes such as
+// sign-ins that the timeline does not show.
+func (s *UserEventServiceImpl) Notify(ctx context.Context, userID uuid.UUID, eventType, summary string, details map[string]string) {
+	if s.stream != nil {
+		s.stream.Publish(userID, newUserEvent(userID, eventType, summary, details))
+	}
+}
+
+func newUserEvent(userID uuid.UUID, eventType, summary string, details map[string]string) models.UserEvent {
+	return models.UserEvent{
+		ID:         uuid.New(),
+		UserID:     userID,
+		Type:       eventType,
+		Summary:    summary,
+		Details:    details,
+		OccurredAt: time.Now().UTC(),
+	}
+}
+
+// GetTimeline returns a user's events matching the filter, newest first, or oldest first with Since.
+func (s *UserEventServiceImpl) GetTimeline(ctx context.Context, filter models.UserEventFilter) ([]models.UserEvent, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultUserTimelineLimit
+	}
+	if filter.Limit > maxUserTimelineLimit {
+		filter.Limit = maxUserTimelineLimit
+	}
+	events, err := s.eventRepo.ListEvents(ctx, filter)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to retrieve timeline for user %s: %v", filter.UserID, err)
+		return nil, fmt.Errorf("service: failed to retrieve timeline: %w", err)
+	}
+	return events, nil
+}
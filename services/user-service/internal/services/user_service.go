@@ -2,82 +2,141 @@
 package services
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/eventbus"
+	"health-tracker-project/services/user-service/internal/mailer"
 	"health-tracker-project/services/user-service/internal/models"
 	"health-tracker-project/services/user-service/internal/repository"
 	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+	"health-tracker-project/services/user-service/internal/utils/measure"
+)
+
+// Plausible bounds for the optional profile fields.
+const (
+	minHeightCM = 50
+	maxHeightCM = 272
+	maxAgeYears = 130
+)
+
+// Page sizes of the user listings.
+const (
+	defaultUserListLimit = 50
+	maxUserListLimit     = 200
+)
+
+// Page size and query length of the user search.
+const (
+	defaultUserSearchLimit = 20
+	maxUserSearchLimit     = 100
+	maxUserSearchQuery     = 100 // Characters
 )
 
 // UserServiceImpl implements the UserService interface.
 type UserServiceImpl struct {
-	userRepo repository.UserRepository // Depends on the UserRepository interface
+	userRepo  repository.UserRepository // Depends on the UserRepository interface
+	events    UserEventService          // Records account changes on the user's own timeline
+	domains   mailer.DomainChecker      // Checks that new emails' domains accept mail; nil to skip
+	publisher eventbus.Publisher        // Tells other services about merges, so they re-point the donor's data
 }
 
 // NewUserService creates a new instance of UserServiceImpl.
-func NewUserService(userRepo repository.UserRepository) *UserServiceImpl {
-	return &UserServiceImpl{userRepo: userRepo}
+func NewUserService(userRepo repository.UserRepository, events UserEventService, domains mailer.DomainChecker, publisher eventbus.Publisher) *UserServiceImpl {
+	return &UserServiceImpl{userRepo: userRepo, events: events, domains: domains, publisher: publisher}
 }
 
 // CreateUser handles the business logic for creating a new user (e.g., by an admin).
-func (s *UserServiceImpl) CreateUser(req models.CreateUserRequest) (*models.UserResponse, error) {
+func (s *UserServiceImpl) CreateUser(ctx context.Context, req models.CreateUserRequest) (*models.UserResponse, error) {
+	newUser, err := s.newUser(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Persist user to database
+	if err := s.userRepo.CreateUser(ctx, newUser); err != nil {
+		logger.FromContext(ctx).Errorf("Failed to save new user '%s': %v", newUser.ID, err)
+		return nil, fmt.Errorf("service: failed to save new user: %w", err)
+	}
+
+	userResponse := newUser.ToUserResponse()
+	s.events.Record(ctx, newUser.ID, models.UserEventRegistered, "Account created by an administrator", nil)
+	logger.FromContext(ctx).Infof("User created via admin/service: ID %s, Email %s", newUser.ID, newUser.Email)
+	return &userResponse, nil
+}
+
+// newUser checks a request to create a user and returns the user it creates, not yet stored.
+func (s *UserServiceImpl) newUser(ctx context.Context, req models.CreateUserRequest) (*models.User, error) {
 	// Business validation
 	if req.Name == "" || req.Email == "" || req.Password == "" {
-		logger.Logger.Debug("CreateUser request missing required fields.")
-		return nil, fmt.Errorf("service: name, email, and password are required")
+		logger.FromContext(ctx).Debug("CreateUser request missing required fields.")
+		return nil, invalid("service: name, email, and password are required")
 	}
 
-	// Check if user with this email already exists
-	existingUser, err := s.userRepo.GetUserByEmail(req.Email)
+	email, err := normalizeEmail(req.Email, s.domains)
+	if err != nil {
+		return nil, err
+	}
+	username, err := checkUsername(ctx, s.userRepo, req.Username, uuid.Nil)
 	if err != nil {
-		logger.Logger.Errorf("Failed to check for existing user by email '%s': %v", req.Email, err)
+		return nil, err
+	}
+
+	// Check if user with this email, or another address of its mailbox, already exists
+	existingUser, err := s.userRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to check for existing user by email '%s': %v", email, err)
 		return nil, fmt.Errorf("service: failed to check for existing user by email: %w", err)
 	}
 	if existingUser != nil {
-		logger.Logger.Warnf("CreateUser attempt with existing email: %s", req.Email)
-		return nil, fmt.Errorf("service: user with this email already exists")
+		logger.FromContext(ctx).Warnf("CreateUser attempt with existing email: %s", email)
+		return nil, duplicateEmail("service: user with this email already exists")
 	}
 
 	// Create new user model (password hashing handled inside NewUser)
-	newUser, err := models.NewUser(req.Name, req.Email, req.Password)
+	newUser, err := models.NewUser(req.Name, email, req.Password)
 	if err != nil {
-		logger.Logger.Errorf("Failed to create new user model: %v", err)
+		logger.FromContext(ctx).Errorf("Failed to create new user model: %v", err)
 		return nil, fmt.Errorf("service: failed to create new user model: %w", err)
 	}
-
-	// Persist user to database
-	if err := s.userRepo.CreateUser(newUser); err != nil {
-		logger.Logger.Errorf("Failed to save new user '%s': %v", newUser.ID, err)
-		return nil, fmt.Errorf("service: failed to save new user: %w", err)
-	}
-
-	userResponse := newUser.ToUserResponse()
-	logger.Logger.Infof("User created via admin/service: ID %s, Email %s", newUser.ID, newUser.Email)
-	return &userResponse, nil
+	newUser.Username = username
+	return newUser, nil
 }
 
 // GetUserByID retrieves a user by their ID.
-func (s *UserServiceImpl) GetUserByID(id uuid.UUID) (*models.UserResponse, error) {
-	user, err := s.userRepo.GetUserByID(id)
+func (s *UserServiceImpl) GetUserByID(ctx context.Context, id uuid.UUID) (*models.UserResponse, error) {
+	user, err := s.userRepo.GetUserByID(ctx, id)
 	if err != nil {
-		logger.Logger.Errorf("Failed to retrieve user by ID '%s': %v", id, err)
+		logger.FromContext(ctx).Errorf("Failed to retrieve user by ID '%s': %v", id, err)
 		return nil, fmt.Errorf("service: failed to retrieve user by ID: %w", err)
 	}
 	if user == nil {
-		logger.Logger.Debugf("User with ID '%s' not found.", id)
-		return nil, fmt.Errorf("service: user not found")
+		logger.FromContext(ctx).Debugf("User with ID '%s' not found.", id)
+		return nil, notFound("service: user not found")
 	}
 	userResponse := user.ToUserResponse()
-	logger.Logger.Debugf("Retrieved user by ID: %s", id)
+	logger.FromContext(ctx).Debugf("Retrieved user by ID: %s", id)
 	return &userResponse, nil
 }
 
-// GetAllUsers retrieves all users.
-func (s *UserServiceImpl) GetAllUsers() ([]models.UserResponse, error) {
-	users, err := s.userRepo.GetAllUsers()
+// GetAllUsers retrieves a page of all users, oldest first, resuming after the page key. limit defaults
+// and is capped like the admin user listing's.
+func (s *UserServiceImpl) GetAllUsers(ctx context.Context, after *models.PageKey, limit int) ([]models.UserResponse, error) {
+	if limit <= 0 {
+		limit = defaultUserListLimit
+	}
+	users, err := s.userRepo.GetAllUsers(ctx, after, min(limit, maxUserListLimit))
 	if err != nil {
-		logger.Logger.Errorf("Failed to retrieve all users: %v", err)
+		logger.FromContext(ctx).Errorf("Failed to retrieve all users: %v", err)
 		return nil, fmt.Errorf("service: failed to retrieve all users: %w", err)
 	}
 
@@ -85,103 +144,883 @@ func (s *UserServiceImpl) GetAllUsers() ([]models.UserResponse, error) {
 	for i, user := range users {
 		userResponses[i] = user.ToUserResponse()
 	}
-	logger.Logger.Debugf("Retrieved %d users.", len(userResponses))
+	logger.FromContext(ctx).Debugf("Retrieved %d users.", len(userResponses))
 	return userResponses, nil
 }
 
-// GetUserByEmail retrieves a user by their email address.
-func (s *UserServiceImpl) GetUserByEmail(email string) (*models.UserResponse, error) {
-	if email == "" {
-		logger.Logger.Debug("GetUserByEmail request missing email.")
-		return nil, fmt.Errorf("service: email is required")
+// SearchUsers returns up to limit users whose name, username, or email resembles query, best match first.
+// limit defaults to 20 and is capped at 100.
+func (s *UserServiceImpl) SearchUsers(ctx context.Context, query string, limit int) ([]models.UserSearchResult, error) {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return nil, invalid("service: q is required")
+	}
+	if utf8.RuneCountInString(query) > maxUserSearchQuery {
+		return nil, invalid("service: q must be at most %d characters", maxUserSearchQuery)
+	}
+	if limit <= 0 {
+		limit = defaultUserSearchLimit
+	}
+
+	matches, err := s.userRepo.SearchUsers(ctx, query, min(limit, maxUserSearchLimit))
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to search users: %v", err)
+		return nil, fmt.Errorf("service: failed to search users: %w", err)
+	}
+	results := make([]models.UserSearchResult, len(matches))
+	for i, m := range matches {
+		results[i] = models.UserSearchResult{User: m.User.ToUserResponse(), Score: m.Score}
+	}
+	return results, nil
+}
+
+// ListUsers returns a page of the users matching filter, newest first, with counts of all matching users.
+func (s *UserServiceImpl) ListUsers(ctx context.Context, filter models.UserFilter) (*models.UserList, error) {
+	roles := []string{models.RoleUser, models.RoleAdmin, models.RoleCoach, models.RoleClinician}
+	if filter.Role != "" && !slices.Contains(roles, filter.Role) {
+		return nil, invalid("service: role must be one of %s", strings.Join(roles, ", "))
+	}
+	statuses := []string{models.StatusActive, models.StatusSuspended, models.StatusDeactivated, models.StatusPendingDeletion}
+	if filter.Status != "" && !slices.Contains(statuses, filter.Status) {
+		return nil, invalid("service: status must be one of %s", strings.Join(statuses, ", "))
+	}
+	if !filter.CreatedSince.IsZero() && !filter.CreatedUntil.IsZero() && !filter.CreatedSince.Before(filter.CreatedUntil) {
+		return nil, invalid("service: created_since must be before created_until")
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultUserListLimit
 	}
+	filter.Limit = min(filter.Limit, maxUserListLimit)
 
-	user, err := s.userRepo.GetUserByEmail(email)
+	users, err := s.userRepo.ListUsers(ctx, filter)
 	if err != nil {
-		logger.Logger.Errorf("Failed to retrieve user by email '%s': %v", email, err)
+		logger.FromContext(ctx).Errorf("Failed to list users: %v", err)
+		return nil, fmt.Errorf("service: failed to list users: %w", err)
+	}
+	counts, err := s.userRepo.CountUsers(ctx, filter, time.Now().Add(-models.ActiveUserWindow))
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to count users: %v", err)
+		return nil, fmt.Errorf("service: failed to count users: %w", err)
+	}
+
+	list := &models.UserList{Users: make([]models.UserResponse, len(users)), Counts: *counts}
+	for i, user := range users {
+		list.Users[i] = user.ToUserResponse()
+	}
+	return list, nil
+}
+
+// GetUserByEmail retrieves a user by their email address, ignoring case and +tags.
+func (s *UserServiceImpl) GetUserByEmail(ctx context.Context, addr string) (*models.UserResponse, error) {
+	if addr == "" {
+		logger.FromContext(ctx).Debug("GetUserByEmail request missing email.")
+		return nil, invalid("service: email is required")
+	}
+	email, err := models.NormalizeEmail(addr)
+	if err != nil {
+		return nil, notFound("service: user not found") // No user can have a malformed email
+	}
+
+	user, err := s.userRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to retrieve user by email '%s': %v", email, err)
 		return nil, fmt.Errorf("service: failed to retrieve user by email: %w", err)
 	}
 	if user == nil {
-		logger.Logger.Debugf("User with email '%s' not found.", email)
-		return nil, fmt.Errorf("service: user not found")
+		logger.FromContext(ctx).Debugf("User with email '%s' not found.", email)
+		return nil, notFound("service: user not found")
+	}
+	userResponse := user.ToUserResponse()
+	logger.FromContext(ctx).Debugf("Retrieved user by email: %s", email)
+	return &userResponse, nil
+}
+
+// GetUserByUsername retrieves a user by their username, ignoring case.
+func (s *UserServiceImpl) GetUserByUsername(ctx context.Context, handle string) (*models.UserResponse, error) {
+	username, err := models.NormalizeUsername(handle)
+	if err != nil {
+		return nil, notFound("service: user not found") // No user can have a malformed or reserved username
+	}
+	user, err := s.userRepo.GetUserByUsername(ctx, username)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to retrieve user by username '%s': %v", username, err)
+		return nil, fmt.Errorf("service: failed to retrieve user by username: %w", err)
+	}
+	if user == nil {
+		return nil, notFound("service: user not found")
 	}
 	userResponse := user.ToUserResponse()
-	logger.Logger.Debugf("Retrieved user by email: %s", email)
 	return &userResponse, nil
 }
 
 // UpdateUser updates an existing user's details.
-func (s *UserServiceImpl) UpdateUser(id uuid.UUID, req models.UpdateUserRequest) (*models.UserResponse, error) {
+func (s *UserServiceImpl) UpdateUser(ctx context.Context, id uuid.UUID, req models.UpdateUserRequest) (*models.UserResponse, error) {
+	existingUser, change, err := s.updatedUser(ctx, id, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Persist updated user
+	if err := s.userRepo.UpdateUser(ctx, existingUser); err != nil {
+		logger.FromContext(ctx).Errorf("Failed to update user '%s': %v", id, err)
+		return nil, fmt.Errorf("service: failed to update user: %w", err)
+	}
+	if change.timezoneChanged {
+		// Past samples keep the zone that was in effect when they were recorded.
+		if err := s.userRepo.RecordTimezoneChange(ctx, id, existingUser.Timezone, existingUser.UpdatedAt); err != nil {
+			logger.FromContext(ctx).Errorf("Failed to record timezone change for user '%s': %v", id, err)
+			return nil, fmt.Errorf("service: failed to record timezone change: %w", err)
+		}
+	}
+	s.recordProfileChange(ctx, existingUser, change)
+
+	userResponse := existingUser.ToUserResponse()
+	logger.FromContext(ctx).Infof("User updated: %s", userResponse.ID)
+	return &userResponse, nil
+}
+
+// profileChange is what an update request changed about a user, for the events recorded once it is saved.
+type profileChange struct {
+	fields           []string // Changed profile fields, for the profile_updated event
+	previousTimezone string
+	timezoneChanged  bool
+	passwordChanged  bool
+}
+
+// updatedUser checks a request to update a user and returns the user as it updates them, not yet
+// stored, along with what it changed.
+func (s *UserServiceImpl) updatedUser(ctx context.Context, id uuid.UUID, req models.UpdateUserRequest) (*models.User, profileChange, error) {
+	var change profileChange
 	// Retrieve existing user
-	existingUser, err := s.userRepo.GetUserByID(id)
+	existingUser, err := s.userRepo.GetUserByID(ctx, id)
 	if err != nil {
-		logger.Logger.Errorf("Failed to retrieve user '%s' for update: %v", id, err)
-		return nil, fmt.Errorf("service: failed to retrieve user for update: %w", err)
+		logger.FromContext(ctx).Errorf("Failed to retrieve user '%s' for update: %v", id, err)
+		return nil, change, fmt.Errorf("service: failed to retrieve user for update: %w", err)
 	}
 	if existingUser == nil {
-		logger.Logger.Warnf("User '%s' not found for update.", id)
-		return nil, fmt.Errorf("service: user not found for update")
+		logger.FromContext(ctx).Warnf("User '%s' not found for update.", id)
+		return nil, change, notFound("service: user not found for update")
 	}
 
 	// Apply updates based on provided fields in the request
-	if req.Name != "" {
+	if req.Name != "" && req.Name != existingUser.Name {
 		existingUser.Name = req.Name
+		change.fields = append(change.fields, "name")
 	}
 	if req.Email != "" {
 		// If email is changed, check for uniqueness among other users
 		if req.Email != existingUser.Email {
-			userWithNewEmail, err := s.userRepo.GetUserByEmail(req.Email)
+			email, err := normalizeEmail(req.Email, s.domains)
 			if err != nil {
-				logger.Logger.Errorf("Failed to check for email uniqueness for user '%s' with new email '%s': %v", id, req.Email, err)
-				return nil, fmt.Errorf("service: failed to check for email uniqueness: %w", err)
+				return nil, change, err
 			}
-			if userWithNewEmail != nil && userWithNewEmail.ID != existingUser.ID {
-				logger.Logger.Warnf("Update for user '%s' failed, new email '%s' already in use.", id, req.Email)
-				return nil, fmt.Errorf("service: new email already in use by another user")
+			if email != existingUser.Email {
+				userWithNewEmail, err := s.userRepo.GetUserByEmail(ctx, email)
+				if err != nil {
+					logger.FromContext(ctx).Errorf("Failed to check for email uniqueness for user '%s' with new email '%s': %v", id, email, err)
+					return nil, change, fmt.Errorf("service: failed to check for email uniqueness: %w", err)
+				}
+				if userWithNewEmail != nil && userWithNewEmail.ID != existingUser.ID {
+					logger.FromContext(ctx).Warnf("Update for user '%s' failed, new email '%s' already in use.", id, email)
+					return nil, change, duplicateEmail("service: new email already in use by another user")
+				}
+				change.fields = append(change.fields, "email")
+				existingUser.Email = email
+				existingUser.EmailVerifiedAt = nil        // Cleared by the update; the new address is not verified
+				existingUser.EmailStatus = models.EmailOK // Bounces of the old address do not apply to the new one
 			}
 		}
-		existingUser.Email = req.Email
+	}
+	if req.Username != nil {
+		username := ""
+		if *req.Username != "" {
+			if username, err = checkUsername(ctx, s.userRepo, *req.Username, id); err != nil {
+				return nil, change, err
+			}
+		}
+		if username != existingUser.Username {
+			existingUser.Username = username
+			change.fields = append(change.fields, "username")
+		}
 	}
 	if req.Password != nil && *req.Password != "" { // Check if password is provided and not empty
 		// Use models.NewUser to hash the new password.
 		// We create a temporary user just for its password hashing capability.
 		tempUserWithHashedPwd, err := models.NewUser("", "", *req.Password)
 		if err != nil {
-			logger.Logger.Errorf("Failed to hash new password for user '%s': %v", id, err)
-			return nil, fmt.Errorf("service: failed to hash new password: %w", err)
+			logger.FromContext(ctx).Errorf("Failed to hash new password for user '%s': %v", id, err)
+			return nil, change, fmt.Errorf("service: failed to hash new password: %w", err)
 		}
 		existingUser.PasswordHash = tempUserWithHashedPwd.PasswordHash
+		change.passwordChanged = true
 	}
 
-	// Persist updated user
-	if err := s.userRepo.UpdateUser(existingUser); err != nil {
-		logger.Logger.Errorf("Failed to update user '%s': %v", id, err)
-		return nil, fmt.Errorf("service: failed to update user: %w", err)
+	if req.Timezone != nil && *req.Timezone != existingUser.Timezone {
+		if _, err := time.LoadLocation(*req.Timezone); err != nil || *req.Timezone == "" || *req.Timezone == "Local" {
+			logger.FromContext(ctx).Warnf("Update for user '%s' failed, invalid timezone '%s'.", id, *req.Timezone)
+			return nil, change, invalid("service: timezone must be a valid IANA name, e.g. Europe/Berlin")
+		}
+		change.previousTimezone = existingUser.Timezone
+		existingUser.Timezone = *req.Timezone
+		change.timezoneChanged = true
+	}
+	if req.WeekStart != nil && *req.WeekStart != existingUser.WeekStart {
+		if _, ok := models.WeekStarts[*req.WeekStart]; !ok {
+			return nil, change, invalid("service: week_start must be one of mon, tue, wed, thu, fri, sat, sun")
+		}
+		existingUser.WeekStart = *req.WeekStart
+		change.fields = append(change.fields, "week_start")
+	}
+	if req.Units != nil && *req.Units != existingUser.Units {
+		if !measure.ValidSystem(*req.Units) {
+			return nil, change, invalid("service: units must be %s or %s", measure.Metric, measure.Imperial)
+		}
+		existingUser.Units = *req.Units
+		change.fields = append(change.fields, "units")
+	}
+	if req.HeightCM != nil {
+		if *req.HeightCM < minHeightCM || *req.HeightCM > maxHeightCM {
+			return nil, change, invalid("service: height_cm must be between %d and %d", minHeightCM, maxHeightCM)
+		}
+		if existingUser.HeightCM == nil || *existingUser.HeightCM != *req.HeightCM {
+			change.fields = append(change.fields, "height_cm")
+		}
+		existingUser.HeightCM = req.HeightCM
+	}
+	if req.DateOfBirth != nil {
+		dob, err := time.Parse(time.DateOnly, *req.DateOfBirth)
+		if err != nil {
+			return nil, change, invalid("service: date_of_birth must be a date in YYYY-MM-DD format")
+		}
+		if now := time.Now().UTC(); !dob.Before(now) || dob.Before(now.AddDate(-maxAgeYears, 0, 0)) {
+			return nil, change, invalid("service: date_of_birth must be in the past and at most %d years ago", maxAgeYears)
+		}
+		if existingUser.DateOfBirth == nil || !existingUser.DateOfBirth.Equal(dob) {
+			change.fields = append(change.fields, "date_of_birth")
+		}
+		existingUser.DateOfBirth = &dob
 	}
 
-	userResponse := existingUser.ToUserResponse()
-	logger.Logger.Infof("User updated: %s", userResponse.ID)
-	return &userResponse, nil
+	return existingUser, change, nil
+}
+
+// recordProfileChange records the events of a saved update on the user's timeline.
+func (s *UserServiceImpl) recordProfileChange(ctx context.Context, user *models.User, change profileChange) {
+	if change.timezoneChanged {
+		s.events.Record(ctx, user.ID, models.UserEventTimezoneChanged, "Timezone changed to "+user.Timezone,
+			map[string]string{"from": change.previousTimezone, "to": user.Timezone})
+	}
+	if len(change.fields) > 0 {
+		s.events.Record(ctx, user.ID, models.UserEventProfileUpdated, "Profile updated",
+			map[string]string{"fields": strings.Join(change.fields, ",")})
+	}
+	if change.passwordChanged {
+		s.events.Record(ctx, user.ID, models.UserEventPasswordChanged, "Password changed", nil)
+	}
 }
 
 // DeleteUser deletes a user by their ID.
-func (s *UserServiceImpl) DeleteUser(id uuid.UUID) error {
+func (s *UserServiceImpl) DeleteUser(ctx context.Context, id uuid.UUID) error {
 	// Optional: Check if user exists before attempting delete to return a more specific "not found" error.
 	// This adds a DB lookup but provides clearer API responses.
-	user, err := s.userRepo.GetUserByID(id)
+	user, err := s.userRepo.GetUserByID(ctx, id)
 	if err != nil {
-		logger.Logger.Errorf("Failed to check user existence before deleting user '%s': %v", id, err)
+		logger.FromContext(ctx).Errorf("Failed to check user existence before deleting user '%s': %v", id, err)
 		return fmt.Errorf("service: failed to check user existence before delete: %w", err)
 	}
 	if user == nil {
-		logger.Logger.Warnf("Deletion failed, user '%s' not found.", id)
-		return fmt.Errorf("service: user not found for deletion")
+		logger.FromContext(ctx).Warnf("Deletion failed, user '%s' not found.", id)
+		return notFound("service: user not found for deletion")
 	}
 
-	if err := s.userRepo.DeleteUser(id); err != nil {
-		logger.Logger.Errorf("Failed to delete user '%s': %v", id, err)
+	if err := s.userRepo.DeleteUser(ctx, id); err != nil {
+		logger.FromContext(ctx).Errorf("Failed to delete user '%s': %v", id, err)
 		return fmt.Errorf("service: failed to delete user: %w", err)
 	}
-	logger.Logger.Infof("User deleted: %s", id)
+	s.notifyDeleted(ctx, id)
+	logger.FromContext(ctx).Infof("User deleted: %s", id)
+	return nil
+}
+
+// notifyDeleted ends the sessions of a deleted user.
+func (s *UserServiceImpl) notifyDeleted(ctx context.Context, id uuid.UUID) {
+	s.events.Notify(ctx, id, models.UserEventSessionRevoked, "Signed out everywhere: account deleted",
+		map[string]string{"reason": "account_deleted"})
+}
+
+// ApplyBulk applies create, update, and delete operations together: all of them, or none if any fails.
+// Every operation is checked as CreateUser, UpdateUser, or DeleteUser would check it on its own, against
+// the users as they were before the request, so operations cannot build on each other; no two may
+// change the same user or claim the same email or username either. The outcome of an operation that
+// passed is ErrNotApplied if another failed. The error is for failures of the whole request, such as
+// the database being unreachable.
+func (s *UserServiceImpl) ApplyBulk(ctx context.Context, items []models.BulkUserItem) ([]models.BulkUserOutcome, error) {
+	outcomes := make([]models.BulkUserOutcome, len(items))
+	changes := make([]models.UserChange, len(items))
+	profileChanges := make([]profileChange, len(items))
+	claimed := bulkClaims{users: map[uuid.UUID]int{}, emails: map[string]int{}, usernames: map[string]int{}}
+	failed := false
+	for i, item := range items {
+		var user *models.User
+		var err error
+		switch item.Op {
+		case models.BulkCreate:
+			user, err = s.newUser(ctx, item.Create)
+		case models.BulkUpdate:
+			user, profileChanges[i], err = s.updatedUser(ctx, item.ID, item.Update)
+		case models.BulkDelete:
+			if user, err = s.userRepo.GetUserByID(ctx, item.ID); err != nil {
+				err = fmt.Errorf("service: failed to check user existence before delete: %w", err)
+			} else if user == nil {
+				err = notFound("service: user not found for deletion")
+			}
+		default:
+			err = invalid("service: op must be one of %s, %s, %s", models.BulkCreate, models.BulkUpdate, models.BulkDelete)
+		}
+		if err == nil {
+			err = claimed.claim(i, item.Op, user, profileChanges[i].fields)
+		}
+		if err != nil {
+			outcomes[i].Err = err
+			failed = true
+			continue
+		}
+		changes[i] = models.UserChange{Op: item.Op, User: user, TimezoneChanged: profileChanges[i].timezoneChanged}
+	}
+	if failed {
+		logger.FromContext(ctx).Warnf("Bulk request of %d operations not applied: some failed their checks", len(items))
+		return notApplied(outcomes), nil
+	}
+
+	if err := s.userRepo.ApplyUserChanges(ctx, changes); err != nil {
+		var changeErr *repository.ChangeError
+		if errors.As(err, &changeErr) && (errors.Is(err, ErrNotFound) || errors.Is(err, ErrDuplicateEmail) || errors.Is(err, ErrConflict)) {
+			// Another request got there first since the checks
+			logger.FromContext(ctx).Warnf("Bulk request of %d operations not applied: %v", len(items), err)
+			outcomes[changeErr.Index].Err = fmt.Errorf("service: failed to apply operation: %w", changeErr.Err)
+			return notApplied(outcomes), nil
+		}
+		logger.FromContext(ctx).Errorf("Failed to apply bulk request of %d operations: %v", len(items), err)
+		return nil, fmt.Errorf("service: failed to apply bulk operations: %w", err)
+	}
+
+	for i, change := range changes {
+		switch change.Op {
+		case models.BulkCreate:
+			s.events.Record(ctx, change.User.ID, models.UserEventRegistered, "Account created by an administrator", nil)
+		case models.BulkUpdate:
+			s.recordProfileChange(ctx, change.User, profileChanges[i])
+		case models.BulkDelete:
+			s.notifyDeleted(ctx, change.User.ID)
+		}
+		if change.Op != models.BulkDelete {
+			userResponse := change.User.ToUserResponse()
+			outcomes[i].User = &userResponse
+		}
+	}
+	logger.FromContext(ctx).Infof("Bulk request applied: %d operations", len(items))
+	return outcomes, nil
+}
+
+// notApplied marks the operations of a bulk request that did not fail as not applied.
+func notApplied(outcomes []models.BulkUserOutcome) []models.BulkUserOutcome {
+	for i := range outcomes {
+		if outcomes[i].Err == nil {
+			outcomes[i].Err = ErrNotApplied
+		}
+	}
+	return outcomes
+}
+
+// bulkClaims are the users, email keys, and usernames the operations of a bulk request change, by the
+// index of the operation that changes them.
+type bulkClaims struct {
+	users     map[uuid.UUID]int
+	emails    map[string]int
+	usernames map[string]int
+}
+
+// claim records what operation i changes, unless an earlier operation changes it already. A delete
+// claims its user; a create or update, its user and the email and username it sets.
+func (c bulkClaims) claim(i int, op string, user *models.User, fields []string) error {
+	if j, ok := c.users[user.ID]; ok {
+		return conflict("service: user is already changed by operation %d", j)
+	}
+	key, username := "", ""
+	if op == models.BulkCreate || slices.Contains(fields, "email") {
+		key = models.EmailKey(user.Email)
+		if j, ok := c.emails[key]; ok {
+			return duplicateEmail(fmt.Sprintf("service: email is already used by operation %d", j))
+		}
+	}
+	if op == models.BulkCreate || slices.Contains(fields, "username") {
+		username = user.Username
+		if j, ok := c.usernames[username]; ok && username != "" {
+			return duplicateUsername("service: username is already taken by operation %d", j)
+		}
+	}
+	c.users[user.ID] = i
+	if key != "" {
+		c.emails[key] = i
+	}
+	if username != "" {
+		c.usernames[username] = i
+	}
+	return nil
+}
+
+// SuspendUser blocks an account: it can no longer log in and its existing tokens stop working.
+func (s *UserServiceImpl) SuspendUser(ctx context.Context, id uuid.UUID, actor string) (*models.UserResponse, error) {
+	if id.String() == actor {
+		return nil, invalid("service: admins cannot suspend their own account")
+	}
+	return s.setStatus(ctx, id, models.StatusSuspended, actor)
+}
+
+// ReactivateUser returns a suspended, deactivated, or pending_deletion account to active, cancelling
+// a scheduled deletion. Sessions revoked on the way out stay revoked; the user has to log in again.
+func (s *UserServiceImpl) ReactivateUser(ctx context.Context, id uuid.UUID, actor string) (*models.UserResponse, error) {
+	return s.setStatus(ctx, id, models.StatusActive, actor)
+}
+
+// DeactivateUser closes the caller's own account. Its data is kept so an admin can reactivate it.
+func (s *UserServiceImpl) DeactivateUser(ctx context.Context, id uuid.UUID) error {
+	_, err := s.setStatus(ctx, id, models.StatusDeactivated, id.String())
+	return err
+}
+
+// setStatus changes an account's status, revoking all sessions unless the new status is active.
+func (s *UserServiceImpl) setStatus(ctx context.Context, id uuid.UUID, status, actor string) (*models.UserResponse, error) {
+	user, err := s.userRepo.GetUserByID(ctx, id)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to retrieve user '%s' for status change: %v", id, err)
+		return nil, fmt.Errorf("service: failed to retrieve user for status change: %w", err)
+	}
+	if user == nil {
+		return nil, notFound("service: user not found")
+	}
+	if user.Status == status {
+		resp := user.ToUserResponse()
+		return &resp, nil // Already in the requested state
+	}
+	if user.Status == models.StatusPendingDeletion && status != models.StatusActive {
+		return nil, conflict("service: account is scheduled for deletion; reactivate it to cancel the deletion")
+	}
+
+	previous := user.Status
+	user.Status = status
+	user.UpdatedAt = time.Now().UTC()
+	user.DeletionDueAt = nil // Reactivation cancels a scheduled deletion
+	if status != models.StatusActive {
+		// JWT iat has second precision, so revoke from the start of the current second.
+		revokedAt := user.UpdatedAt.Truncate(time.Second)
+		user.SessionsRevokedAt = &revokedAt
+	}
+	if err := s.userRepo.UpdateUser(ctx, user); err != nil {
+		logger.FromContext(ctx).Errorf("Failed to set status of user '%s' to %s: %v", id, status, err)
+		return nil, fmt.Errorf("service: failed to update user status: %w", err)
+	}
+	s.events.Record(ctx, id, models.UserEventStatusChanged, "Account "+statusVerb(status),
+		map[string]string{"from": previous, "to": status})
+	if status != models.StatusActive {
+		s.events.Notify(ctx, id, models.UserEventSessionRevoked, "Signed out everywhere: account "+status,
+			map[string]string{"reason": "account_" + status})
+	}
+	logger.FromContext(ctx).Infof("User %s is now %s (by %s)", id, status, actor)
+	resp := user.ToUserResponse()
+	return &resp, nil
+}
+
+// statusVerb describes the transition into status for timeline summaries.
+func statusVerb(status string) string {
+	if status == models.StatusActive {
+		return "reactivated"
+	}
+	return status
+}
+
+// MergeUsers folds a duplicate (donor) account into a primary account. The donor's external identities
+// move to the primary account and its email becomes an alias of it, so signing in either way reaches the
+// primary account. The donor is removed, which invalidates all of its sessions, and a snapshot is kept so
+// the merge can be undone. Other services are told to re-point the donor's data to the primary account.
+func (s *UserServiceImpl) MergeUsers(ctx context.Context, req models.MergeUsersRequest, actor string) (*models.UserMerge, error) {
+	if req.PrimaryUserID == uuid.Nil || req.DonorUserID == uuid.Nil {
+		return nil, invalid("service: primary_user_id and donor_user_id are required")
+	}
+	if req.PrimaryUserID == req.DonorUserID {
+		return nil, invalid("service: cannot merge a user into itself")
+	}
+
+	primary, err := s.userRepo.GetUserByID(ctx, req.PrimaryUserID)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to retrieve primary user '%s' for merge: %v", req.PrimaryUserID, err)
+		return nil, fmt.Errorf("service: failed to retrieve primary user: %w", err)
+	}
+	donor, err := s.userRepo.GetUserByID(ctx, req.DonorUserID)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to retrieve donor user '%s' for merge: %v", req.DonorUserID, err)
+		return nil, fmt.Errorf("service: failed to retrieve donor user: %w", err)
+	}
+	if primary == nil || donor == nil {
+		return nil, notFound("service: user not found for merge")
+	}
+
+	merge := &models.UserMerge{
+		ID:            uuid.New(),
+		PrimaryUserID: primary.ID,
+		DonorUserID:   donor.ID,
+		DonorEmail:    donor.Email,
+		DonorSnapshot: *donor,
+		MergedBy:      actor,
+		CreatedAt:     time.Now().UTC(),
+	}
+	if err := s.userRepo.MergeUsers(ctx, merge); err != nil {
+		logger.FromContext(ctx).Errorf("Failed to merge user '%s' into '%s': %v", donor.ID, primary.ID, err)
+		return nil, fmt.Errorf("service: failed to merge users: %w", err)
+	}
+	s.events.Record(ctx, primary.ID, models.UserEventAccountMerged, "Another account was merged into this one",
+		map[string]string{"action": "merged", "merge_id": merge.ID.String(), "donor_user_id": donor.ID.String()})
+	s.events.Notify(ctx, donor.ID, models.UserEventSessionRevoked, "Signed out everywhere: account merged into another",
+		map[string]string{"reason": "account_merged"})
+	s.publishMerge(ctx, eventbus.UserMerged, merge)
+	logger.FromContext(ctx).Infof("User %s merged into %s by %s", donor.ID, primary.ID, actor)
+	return merge, nil
+}
+
+// UndoUserMerge restores the donor account of a previous merge.
+func (s *UserServiceImpl) UndoUserMerge(ctx context.Context, id uuid.UUID, actor string) (*models.UserMerge, error) {
+	merge, err := s.userRepo.GetUserMerge(ctx, id)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to retrieve merge '%s': %v", id, err)
+		return nil, fmt.Errorf("service: failed to retrieve merge: %w", err)
+	}
+	if merge == nil {
+		return nil, notFound("service: merge not found")
+	}
+	if merge.UndoneAt != nil {
+		return nil, conflict("service: merge already undone")
+	}
+
+	existing, err := s.userRepo.GetUserByEmail(ctx, merge.DonorEmail)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to check for existing user by email: %w", err)
+	}
+	// The merge's own alias finds the primary user, whose email is another one.
+	ownAlias := existing != nil && existing.ID == merge.PrimaryUserID && models.EmailKey(existing.Email) != models.EmailKey(merge.DonorEmail)
+	if existing != nil && !ownAlias {
+		return nil, duplicateEmail("service: donor email already in use by another user")
+	}
+
+	if err := s.userRepo.UndoUserMerge(ctx, merge, actor); err != nil {
+		logger.FromContext(ctx).Errorf("Failed to undo merge '%s': %v", id, err)
+		return nil, fmt.Errorf("service: failed to undo merge: %w", err)
+	}
+	s.events.Record(ctx, merge.PrimaryUserID, models.UserEventAccountMerged, "A previous account merge was undone",
+		map[string]string{"action": "undone", "merge_id": merge.ID.String(), "donor_user_id": merge.DonorUserID.String()})
+	s.publishMerge(ctx, eventbus.UserMergeUndone, merge)
+	logger.FromContext(ctx).Infof("Merge %s undone by %s", id, actor)
+	return merge, nil
+}
+
+// publishMerge tells other services about a merge or its undo. The merge is committed either way, so a
+// failure is only logged. The event ID is derived from the merge, so consumers can drop redeliveries.
+func (s *UserServiceImpl) publishMerge(ctx context.Context, eventType string, merge *models.UserMerge) {
+	event := eventbus.NewEvent(eventType, merge.PrimaryUserID, map[string]string{
+		"merge_id":      merge.ID.String(),
+		"donor_user_id": merge.DonorUserID.String(),
+	})
+	event.ID = uuid.NewSHA1(merge.ID, []byte(eventType)) // A user can be merged into more than once
+	if err := s.publisher.Publish(ctx, event); err != nil {
+		logger.FromContext(ctx).Errorf("Failed to publish %s event %s for merge %s: %v", eventType, event.ID, merge.ID, err)
+	}
+}
+
+// GetTimezoneHistory returns the user's timezone history, oldest first.
+func (s *UserServiceImpl) GetTimezoneHistory(ctx context.Context, id uuid.UUID) ([]models.TimezonePeriod, error) {
+	user, err := s.userRepo.GetUserByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to retrieve user by ID: %w", err)
+	}
+	if user == nil {
+		return nil, notFound("service: user not found")
+	}
+	return timezoneHistory(ctx, s.userRepo, user)
+}
+
+// timezoneHistory returns the user's timezone history, oldest first.
+func timezoneHistory(ctx context.Context, userRepo repository.UserRepository, user *models.User) ([]models.TimezonePeriod, error) {
+	history, err := userRepo.GetTimezoneHistory(ctx, user.ID)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to retrieve timezone history for user '%s': %v", user.ID, err)
+		return nil, fmt.Errorf("service: failed to retrieve timezone history: %w", err)
+	}
+	if len(history) == 0 {
+		// Users created before history tracking only have their current zone.
+		history = append(history, models.TimezonePeriod{Timezone: user.Timezone, EffectiveFrom: user.CreatedAt})
+	}
+	return history, nil
+}
+
+// TimezoneAt returns the timezone that was in effect for the user at the given instant.
+// Aggregations should use it to bucket each sample instead of the user's current zone.
+func (s *UserServiceImpl) TimezoneAt(ctx context.Context, id uuid.UUID, at time.Time) (*time.Location, error) {
+	history, err := s.GetTimezoneHistory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return models.TimezoneAt(history, at)
+}
+
+// GetProfilePrompts returns the optional profile fields the user has not filled in, most valuable first.
+// A dismissed field is left out for models.ProfilePromptCooldown, and for good after models.MaxProfilePromptDismissals dismissals.
+func (s *UserServiceImpl) GetProfilePrompts(ctx context.Context, id uuid.UUID) ([]models.ProfilePrompt, error) {
+	user, err := s.userRepo.GetUserByID(ctx, id)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to retrieve user '%s' for profile prompts: %v", id, err)
+		return nil, fmt.Errorf("service: failed to retrieve user: %w", err)
+	}
+	if user == nil {
+		return nil, notFound("service: user not found")
+	}
+	dismissals, err := s.userRepo.GetProfilePromptDismissals(ctx, id)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to retrieve profile prompt dismissals for user '%s': %v", id, err)
+		return nil, fmt.Errorf("service: failed to retrieve profile prompt dismissals: %w", err)
+	}
+
+	missing := map[string]bool{
+		"timezone":      user.Timezone == "" || user.Timezone == models.DefaultTimezone,
+		"date_of_birth": user.DateOfBirth == nil,
+		"height_cm":     user.HeightCM == nil,
+	}
+	now := time.Now().UTC()
+	prompts := []models.ProfilePrompt{}
+	for _, prompt := range models.ProfilePromptFields {
+		if !missing[prompt.Field] {
+			continue
+		}
+		if d, ok := dismissals[prompt.Field]; ok {
+			if d.Count >= models.MaxProfilePromptDismissals || now.Sub(d.LastDismissedAt) < models.ProfilePromptCooldown {
+				continue
+			}
+		}
+		prompts = append(prompts, prompt)
+	}
+	return prompts, nil
+}
+
+// DismissProfilePrompt records that the user declined to provide a field for now.
+func (s *UserServiceImpl) DismissProfilePrompt(ctx context.Context, id uuid.UUID, field string) error {
+	if !isProfilePromptField(field) {
+		return invalid("service: unknown profile prompt field")
+	}
+	user, err := s.userRepo.GetUserByID(ctx, id)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to retrieve user '%s' to dismiss profile prompt: %v", id, err)
+		return fmt.Errorf("service: failed to retrieve user: %w", err)
+	}
+	if user == nil {
+		return notFound("service: user not found")
+	}
+	if err := s.userRepo.DismissProfilePrompt(ctx, id, field, time.Now().UTC()); err != nil {
+		logger.FromContext(ctx).Errorf("Failed to dismiss profile prompt '%s' for user '%s': %v", field, id, err)
+		return fmt.Errorf("service: failed to dismiss profile prompt: %w", err)
+	}
 	return nil
-}
\ No newline at end of file
+}
+
+func isProfilePromptField(field string) bool {
+	for _, prompt := range models.ProfilePromptFields {
+		if prompt.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// GetMetadata returns the custom attributes integrators attached to a user.
+func (s *UserServiceImpl) GetMetadata(ctx context.Context, id uuid.UUID) (models.UserMetadata, error) {
+	metadata, err := s.userRepo.GetUserMetadata(ctx, id)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to retrieve metadata for user '%s': %v", id, err)
+		return nil, fmt.Errorf("service: failed to retrieve user metadata: %w", err)
+	}
+	if metadata == nil {
+		return nil, notFound("service: user not found")
+	}
+	return metadata, nil
+}
+
+// UpdateMetadata merges patch into a user's metadata key by key: keys with a value are set, keys set to
+// null are removed, and keys not mentioned are kept. The merged metadata must stay within
+// models.MaxUserMetadataBytes of JSON text.
+func (s *UserServiceImpl) UpdateMetadata(ctx context.Context, id uuid.UUID, patch map[string]json.RawMessage) (models.UserMetadata, error) {
+	set := models.UserMetadata{}
+	var remove []string
+	for key, value := range patch {
+		if key == "" || len(key) > models.MaxUserMetadataKeyLength || strings.ContainsFunc(key, func(c rune) bool {
+			return !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '.' || c == '-')
+		}) {
+			return nil, invalid("service: invalid metadata: key %q must be 1 to %d letters, digits, underscores, dots, or hyphens", key, models.MaxUserMetadataKeyLength)
+		}
+		if string(value) == "null" {
+			remove = append(remove, key)
+		} else {
+			set[key] = value
+		}
+	}
+
+	metadata, err := s.userRepo.MergeUserMetadata(ctx, id, set, remove, models.MaxUserMetadataBytes)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to update metadata for user '%s': %v", id, err)
+		return nil, fmt.Errorf("service: failed to update user metadata: %w", err)
+	}
+	if metadata == nil {
+		// Nothing was written: either there is no such user or the result was too large.
+		if _, err := s.GetMetadata(ctx, id); err != nil {
+			return nil, err
+		}
+		return nil, invalid("service: invalid metadata: at most %d bytes of JSON per user", models.MaxUserMetadataBytes)
+	}
+	logger.FromContext(ctx).Infof("Metadata updated for user %s: %d key(s) set, %d removed", id, len(set), len(remove))
+	return metadata, nil
+}
+
+// normalizeEmail validates a submitted email and returns it normalized (see models.NormalizeEmail).
+// With a DomainChecker, the domain must also accept mail; synthetic journeys' reserved domain is exempt.
+func normalizeEmail(addr string, domains mailer.DomainChecker) (string, error) {
+	email, err := models.NormalizeEmail(addr)
+	if err != nil {
+		return "", invalid("service: invalid email: %w", err)
+	}
+	if domain := email[strings.LastIndex(email, "@")+1:]; domains != nil && domain != models.SyntheticEmailDomain {
+		if err := domains.CheckDomain(domain); err != nil {
+			return "", invalid("service: invalid email: %w", err)
+		}
+	}
+	return email, nil
+}
+
+// checkUsername validates a requested username and returns it lowercased, or "" if none was requested.
+// It fails if a user other than userID already has it.
+func checkUsername(ctx context.Context, userRepo repository.UserRepository, handle string, userID uuid.UUID) (string, error) {
+	if handle == "" {
+		return "", nil
+	}
+	username, err := models.NormalizeUsername(handle)
+	if err != nil {
+		return "", invalid("service: invalid username: %w", err)
+	}
+	existing, err := userRepo.GetUserByUsername(ctx, username)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to check for existing user by username '%s': %v", username, err)
+		return "", fmt.Errorf("service: failed to check for existing user by username: %w", err)
+	}
+	if existing != nil && existing.ID != userID {
+		return "", duplicateUsername("service: username is already taken")
+	}
+	if existing == nil && models.LooksReserved(username) {
+		return "", invalid("service: invalid username: %q is reserved", username)
+	}
+	return username, nil
+}
+
+// Suggestions offered for a handle that is not available.
+const (
+	handleSuggestions = 3
+	handleCandidates  = 8 // Lookups per request at most, so a check costs little however the name is chosen
+)
+
+// CheckHandleAvailability tells whether a handle can be taken, with available handles close to it when it
+// cannot. Taken and reserved handles get the same answer, so the check does not single out registered users.
+func (s *UserServiceImpl) CheckHandleAvailability(ctx context.Context, name string) (*models.HandleAvailability, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, invalid("service: name is required")
+	}
+	result := &models.HandleAvailability{Name: name, Suggestions: []string{}}
+	username, err := models.NormalizeUsername(name)
+	switch {
+	case errors.Is(err, models.ErrReservedUsername):
+		result.Name, result.Reason = strings.ToLower(name), models.HandleUnavailable
+	case err != nil:
+		result.Reason, result.Message = models.HandleInvalid, err.Error()
+	default:
+		result.Name = username
+		free, err := s.handleFree(ctx, username)
+		if err != nil {
+			return nil, err
+		}
+		if free {
+			result.Available = true
+			return result, nil
+		}
+		result.Reason = models.HandleUnavailable
+	}
+	if result.Reason == models.HandleUnavailable {
+		result.Message = "is not available"
+	}
+
+	base := handleBase(name)
+	candidates := []string{base}
+	for i := range handleCandidates {
+		candidates = append(candidates, base+[]string{"", "_", "."}[i%3]+strconv.Itoa(10+rand.IntN(990)))
+	}
+	lookups := 0
+	for _, candidate := range candidates {
+		if len(result.Suggestions) == handleSuggestions || lookups == handleCandidates {
+			break
+		}
+		if candidate == result.Name || slices.Contains(result.Suggestions, candidate) {
+			continue
+		}
+		if _, err := models.NormalizeUsername(candidate); err != nil || models.LooksReserved(candidate) {
+			continue
+		}
+		lookups++
+		free, err := s.handleFree(ctx, candidate)
+		if err != nil {
+			return nil, err
+		}
+		if free {
+			result.Suggestions = append(result.Suggestions, candidate)
+		}
+	}
+	return result, nil
+}
+
+// handleFree reports whether a normalized handle is neither reserved nor taken.
+func (s *UserServiceImpl) handleFree(ctx context.Context, username string) (bool, error) {
+	if models.LooksReserved(username) {
+		return false, nil
+	}
+	existing, err := s.userRepo.GetUserByUsername(ctx, username)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to check availability of username '%s': %v", username, err)
+		return false, fmt.Errorf("service: failed to check username availability: %w", err)
+	}
+	return existing == nil, nil
+}
+
+// handleBase turns a name into the start of a valid handle, keeping its letters, digits, underscores, and
+// single dots, starting at its first letter, and leaving room for a three-digit suffix.
+func handleBase(name string) string {
+	var b strings.Builder
+	for _, c := range strings.ToLower(name) {
+		switch {
+		case c >= 'a' && c <= 'z':
+			b.WriteRune(c)
+		case b.Len() == 0:
+			// Handles start with a letter
+		case c >= '0' && c <= '9' || c == '_':
+			b.WriteRune(c)
+		case c == '.' && !strings.HasSuffix(b.String(), "."):
+			b.WriteRune(c)
+		}
+	}
+	base := b.String()
+	if len(base) > models.MaxUsernameLength-4 {
+		base = base[:models.MaxUsernameLength-4]
+	}
+	return strings.TrimRight(base, ".")
+}
@@ -0,0 +1,367 @@
+// services/user-service/internal/services/workout_attachment_service.go
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/blobstore"
+	"health-tracker-project/services/user-service/internal/config"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/repository"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+	"health-tracker-project/services/user-service/internal/virusscan"
+)
+
+const scanBatchSize = 20 // Attachments scanned per tick
+
+// setRefPattern matches workout set IDs from the workout service, which are opaque to this service.
+var setRefPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// workoutVideoTypes are the accepted form-check video types; other workout attachments take the
+// messaging attachment types and the smaller file limit. Types are sniffed, not taken from the client.
+var workoutVideoTypes = []string{"video/mp4", "video/webm"}
+
+// WorkoutAttachmentServiceImpl implements the WorkoutAttachmentService interface.
+type WorkoutAttachmentServiceImpl struct {
+	attachmentRepo repository.WorkoutAttachmentRepository
+	messagingRepo  repository.MessagingRepository // Coach authorizations decide who sees shared attachments
+	blobs          blobstore.Store
+	scanner        virusscan.Scanner // Nil when no scanner is configured
+}
+
+// NewWorkoutAttachmentService creates a new instance of WorkoutAttachmentServiceImpl. With a nil scanner,
+// uploads are marked not_scanned and can be downloaded straight away.
+func NewWorkoutAttachmentService(attachmentRepo repository.WorkoutAttachmentRepository, messagingRepo repository.MessagingRepository, blobs blobstore.Store, scanner virusscan.Scanner) *WorkoutAttachmentServiceImpl {
+	return &WorkoutAttachmentServiceImpl{attachmentRepo: attachmentRepo, messagingRepo: messagingRepo, blobs: blobs, scanner: scanner}
+}
+
+// WorkoutAttachmentUploadLimit is the largest upload of any accepted type, in bytes, under the current runtime config.
+func WorkoutAttachmentUploadLimit() int64 {
+	limits := config.Current().WorkoutAttachments
+	return int64(max(limits.MaxVideoMB, limits.MaxFileMB)) << 20
+}
+
+// expiry resolves an expiry in days from now against the runtime limits. Nil days takes the default;
+// a nil result keeps the attachment.
+func expiry(days *int, now time.Time) (*time.Time, error) {
+	limits := config.Current().WorkoutAttachments
+	d := limits.DefaultExpiryDays
+	if days != nil {
+		d = *days
+	}
+	if d < 0 {
+		return nil, invalid("service: expires_in_days must not be negative")
+	}
+	if limits.MaxExpiryDays > 0 && (d == 0 || d > limits.MaxExpiryDays) {
+		return nil, invalid("service: attachments must expire within %d days", limits.MaxExpiryDays)
+	}
+	if d == 0 {
+		return nil, nil
+	}
+	at := now.AddDate(0, 0, d)
+	return &at, nil
+}
+
+// Upload stores a form-check video or file for one of the user's workout sets. It can only be
+// downloaded once the virus scan passes.
+func (s *WorkoutAttachmentServiceImpl) Upload(ctx context.Context, userID uuid.UUID, req models.UploadWorkoutAttachmentRequest, content io.Reader) (*models.WorkoutAttachment, error) {
+	if !setRefPattern.MatchString(req.SetRef) {
+		return nil, invalid("service: invalid workout set ID")
+	}
+	now := time.Now().UTC()
+	expiresAt, err := expiry(req.ExpiresInDays, now)
+	if err != nil {
+		return nil, err
+	}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(content, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, fmt.Errorf("service: failed to read attachment: %w", err)
+	}
+	if n == 0 {
+		return nil, invalid("service: attachment is empty")
+	}
+	head = head[:n]
+	contentType, _, _ := strings.Cut(http.DetectContentType(head), ";")
+	limits := config.Current().WorkoutAttachments
+	limitMB := limits.MaxFileMB
+	if slices.Contains(workoutVideoTypes, contentType) {
+		limitMB = limits.MaxVideoMB
+	} else if !slices.Contains(attachmentTypes, contentType) {
+		return nil, unsupportedType("service: unsupported attachment type, expected one of %s",
+			strings.Join(append(slices.Clone(workoutVideoTypes), attachmentTypes...), ", "))
+	}
+
+	filename := strings.TrimSpace(filepath.Base(filepath.Clean("/" + req.Filename)))
+	if filename == "/" || filename == "" {
+		filename = "attachment"
+	}
+	if len(filename) > 255 {
+		filename = filename[:255]
+	}
+
+	attachment := &models.WorkoutAttachment{
+		ID:                uuid.New(),
+		UserID:            userID,
+		SetRef:            req.SetRef,
+		Filename:          filename,
+		ContentType:       contentType,
+		ScanStatus:        models.ScanPending,
+		SharedWithCoaches: req.SharedWithCoaches,
+		ExpiresAt:         expiresAt,
+		CreatedAt:         now,
+	}
+	if s.scanner == nil {
+		attachment.ScanStatus = models.ScanNotScanned
+	}
+	attachment.BlobKey = fmt.Sprintf("workouts/%s/%s", userID, attachment.ID)
+	limit := int64(limitMB) << 20
+	size, err := s.blobs.Put(attachment.BlobKey, io.LimitReader(io.MultiReader(bytes.NewReader(head), content), limit+1))
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to store workout attachment for user %s: %v", userID, err)
+		return nil, fmt.Errorf("service: failed to store attachment: %w", err)
+	}
+	if size > limit {
+		s.deleteBlob(attachment.BlobKey)
+		return nil, tooLarge("service: %s attachments must be at most %d MB", contentType, limitMB)
+	}
+	attachment.Size = size
+
+	if err := s.attachmentRepo.CreateAttachment(ctx, attachment); err != nil {
+		s.deleteBlob(attachment.BlobKey)
+		logger.FromContext(ctx).Errorf("Failed to record workout attachment for user %s: %v", userID, err)
+		return nil, fmt.Errorf("service: failed to record attachment: %w", err)
+	}
+	return attachment, nil
+}
+
+// List returns the user's workout attachments, newest first, optionally for one set.
+func (s *WorkoutAttachmentServiceImpl) List(ctx context.Context, userID uuid.UUID, setRef string) ([]models.WorkoutAttachment, error) {
+	attachments, err := s.attachmentRepo.ListAttachments(ctx, userID, setRef, false)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to list workout attachments for user %s: %v", userID, err)
+		return nil, fmt.Errorf("service: failed to list attachments: %w", err)
+	}
+	return live(attachments), nil
+}
+
+// get returns one of the user's attachments, treating an expired one as gone before it is purged.
+func (s *WorkoutAttachmentServiceImpl) get(ctx context.Context, userID, id uuid.UUID) (*models.WorkoutAttachment, error) {
+	attachment, err := s.attachmentRepo.GetAttachment(ctx, userID, id)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to get workout attachment %s: %v", id, err)
+		return nil, fmt.Errorf("service: failed to get attachment: %w", err)
+	}
+	if attachment == nil || (attachment.ExpiresAt != nil && !attachment.ExpiresAt.After(time.Now())) {
+		return nil, notFound("service: attachment not found")
+	}
+	return attachment, nil
+}
+
+// Update changes whether coaches see an attachment, or when it expires.
+func (s *WorkoutAttachmentServiceImpl) Update(ctx context.Context, userID, id uuid.UUID, req models.UpdateWorkoutAttachmentRequest) (*models.WorkoutAttachment, error) {
+	attachment, err := s.get(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if req.SharedWithCoaches != nil {
+		attachment.SharedWithCoaches = *req.SharedWithCoaches
+	}
+	if req.ExpiresInDays != nil {
+		if attachment.ExpiresAt, err = expiry(req.ExpiresInDays, time.Now().UTC()); err != nil {
+			return nil, err
+		}
+	}
+	updated, err := s.attachmentRepo.UpdateAttachment(ctx, attachment)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to update workout attachment %s: %v", id, err)
+		return nil, fmt.Errorf("service: failed to update attachment: %w", err)
+	}
+	if !updated {
+		return nil, notFound("service: attachment not found")
+	}
+	return attachment, nil
+}
+
+// Delete removes one of the user's attachments and its content.
+func (s *WorkoutAttachmentServiceImpl) Delete(ctx context.Context, userID, id uuid.UUID) error {
+	key, err := s.attachmentRepo.DeleteAttachment(ctx, userID, id)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to delete workout attachment %s: %v", id, err)
+		return fmt.Errorf("service: failed to delete attachment: %w", err)
+	}
+	if key == "" {
+		return notFound("service: attachment not found")
+	}
+	s.deleteBlob(key)
+	return nil
+}
+
+// Open opens one of the user's attachments. The caller must close the returned reader.
+func (s *WorkoutAttachmentServiceImpl) Open(ctx context.Context, userID, id uuid.UUID) (*models.WorkoutAttachment, io.ReadCloser, error) {
+	attachment, err := s.get(ctx, userID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.open(attachment)
+}
+
+// clientFor checks that the coach is currently authorized by the client.
+func (s *WorkoutAttachmentServiceImpl) clientFor(ctx context.Context, coachID, clientID uuid.UUID) error {
+	authorized, err := s.messagingRepo.IsCoachAuthorized(ctx, clientID, coachID)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to check coach %s for user %s: %v", coachID, clientID, err)
+		return fmt.Errorf("service: failed to check coach authorization: %w", err)
+	}
+	if !authorized {
+		return notFound("service: client not found")
+	}
+	return nil
+}
+
+// ListForCoach returns the attachments a client shares with their coaches, optionally for one set.
+func (s *WorkoutAttachmentServiceImpl) ListForCoach(ctx context.Context, coachID, clientID uuid.UUID, setRef string) ([]models.WorkoutAttachment, error) {
+	if err := s.clientFor(ctx, coachID, clientID); err != nil {
+		return nil, err
+	}
+	attachments, err := s.attachmentRepo.ListAttachments(ctx, clientID, setRef, true)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to list shared workout attachments for user %s: %v", clientID, err)
+		return nil, fmt.Errorf("service: failed to list attachments: %w", err)
+	}
+	return live(attachments), nil
+}
+
+// OpenForCoach opens an attachment a client shares with their coaches. The caller must close the returned reader.
+func (s *WorkoutAttachmentServiceImpl) OpenForCoach(ctx context.Context, coachID, clientID, id uuid.UUID) (*models.WorkoutAttachment, io.ReadCloser, error) {
+	if err := s.clientFor(ctx, coachID, clientID); err != nil {
+		return nil, nil, err
+	}
+	attachment, err := s.get(ctx, clientID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !attachment.SharedWithCoaches {
+		return nil, nil, notFound("service: attachment not found")
+	}
+	return s.open(attachment)
+}
+
+// open gates the download on the virus scan and opens the content.
+func (s *WorkoutAttachmentServiceImpl) open(attachment *models.WorkoutAttachment) (*models.WorkoutAttachment, io.ReadCloser, error) {
+	switch attachment.ScanStatus {
+	case models.ScanClean, models.ScanNotScanned:
+	case models.ScanInfected:
+		return nil, nil, conflict("service: attachment failed the virus scan")
+	default:
+		return nil, nil, conflict("service: attachment is awaiting a virus scan")
+	}
+	content, err := s.blobs.Get(attachment.BlobKey)
+	if err == blobstore.ErrNotFound {
+		return nil, nil, notFound("service: attachment not found")
+	}
+	if err != nil {
+		logger.Logger.Errorf("Failed to open workout attachment %s: %v", attachment.ID, err)
+		return nil, nil, fmt.Errorf("service: failed to open attachment: %w", err)
+	}
+	return attachment, content, nil
+}
+
+// live drops attachments that have expired but not been purged yet.
+func live(attachments []models.WorkoutAttachment) []models.WorkoutAttachment {
+	now := time.Now()
+	return slices.DeleteFunc(attachments, func(a models.WorkoutAttachment) bool {
+		return a.ExpiresAt != nil && !a.ExpiresAt.After(now)
+	})
+}
+
+// ScanPending scans pending uploads every interval. Infected content is deleted, keeping the record so
+// the user can see why; a scan that fails is retried on the next tick. It returns when ctx is done,
+// and at once without a scanner.
+func (s *WorkoutAttachmentServiceImpl) ScanPending(ctx context.Context, interval time.Duration) {
+	if s.scanner == nil {
+		return
+	}
+	t := time.NewTicker(interval)
+	for {
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C:
+		}
+		s.scanPending(ctx)
+	}
+}
+
+func (s *WorkoutAttachmentServiceImpl) scanPending(ctx context.Context) {
+	attachments, err := s.attachmentRepo.ListPendingScans(ctx, scanBatchSize)
+	if err != nil {
+		logger.FromContext(ctx).Errorf("Failed to list workout attachments to scan: %v", err)
+		return
+	}
+	for _, attachment := range attachments {
+		content, err := s.blobs.Get(attachment.BlobKey)
+		if err != nil {
+			logger.FromContext(ctx).Errorf("Failed to open workout attachment %s for scanning: %v", attachment.ID, err)
+			continue
+		}
+		signature, err := s.scanner.Scan(content)
+		content.Close()
+		if err != nil {
+			logger.FromContext(ctx).Warnf("Failed to scan workout attachment %s: %v", attachment.ID, err)
+			continue
+		}
+		status := models.ScanClean
+		if signature != "" {
+			status = models.ScanInfected
+			logger.FromContext(ctx).Warnf("Workout attachment %s of user %s is infected (%s); deleting its content", attachment.ID, attachment.UserID, signature)
+			s.deleteBlob(attachment.BlobKey)
+		}
+		if err := s.attachmentRepo.SetScanStatus(ctx, attachment.UserID, attachment.ID, status, time.Now().UTC()); err != nil {
+			logger.FromContext(ctx).Errorf("Failed to record scan of workout attachment %s: %v", attachment.ID, err)
+		}
+	}
+}
+
+// PurgeExpired deletes expired attachments and their content every interval. It returns when ctx is done.
+func (s *WorkoutAttachmentServiceImpl) PurgeExpired(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	for {
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C:
+		}
+		keys, err := s.attachmentRepo.PurgeExpired(ctx, time.Now().UTC())
+		for _, key := range keys {
+			s.deleteBlob(key)
+		}
+		if err != nil {
+			logger.FromContext(ctx).Errorf("Failed to purge expired workout attachments: %v", err)
+			continue
+		}
+		if len(keys) > 0 {
+			logger.FromContext(ctx).Infof("Deleted %d expired workout attachments", len(keys))
+		}
+	}
+}
+
+// deleteBlob removes an attachment's content; a failure leaves an orphaned blob, which is only logged.
+func (s *WorkoutAttachmentServiceImpl) deleteBlob(key string) {
+	if err := s.blobs.Delete(key); err != nil {
+		logger.Logger.Warnf("Failed to delete workout attachment blob %s: %v", key, err)
+	}
+}
@@ -0,0 +1,350 @@
+// services/user-service/internal/services/workout_plan_service.go
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/models"
+	"health-tracker-project/services/user-service/internal/repository"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+const (
+	maxPlanWeeks           = 52
+	maxPlansPerDocument    = 50
+	maxExercisesPerSession = 30
+	maxPlanDescription     = 2000
+)
+
+// Conflict policies of an import.
+const (
+	onConflictSkip    = "skip"
+	onConflictReplace = "replace"
+)
+
+// WorkoutPlanServiceImpl implements the WorkoutPlanService interface.
+type WorkoutPlanServiceImpl struct {
+	planRepo repository.WorkoutPlanRepository
+	userRepo repository.UserRepository // Credits the coach as the author of the plans they write
+}
+
+// NewWorkoutPlanService creates a new instance of WorkoutPlanServiceImpl.
+func NewWorkoutPlanService(planRepo repository.WorkoutPlanRepository, userRepo repository.UserRepository) *WorkoutPlanServiceImpl {
+	return &WorkoutPlanServiceImpl{planRepo: planRepo, userRepo: userRepo}
+}
+
+// planKey is what makes a plan's name taken: names differing only in case or surrounding space are the same.
+func planKey(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// checkPlan checks a plan beyond what the validate tags of the request did: the name is set, every
+// session falls within the plan's weeks, no day has two sessions, and each exercise is counted in
+// either reps or time. at is the plan's path in the request, such as plans[2], or "" for the request itself.
+func checkPlan(at, name, description string, weeks int, sessions []models.WorkoutPlanSession) error {
+	switch {
+	case strings.TrimSpace(name) == "" || utf8.RuneCountInString(name) > 100:
+		return invalid("service: %sname must be 1 to 100 characters", in(at))
+	case utf8.RuneCountInString(description) > maxPlanDescription:
+		return invalid("service: %sdescription must be at most %d characters", in(at), maxPlanDescription)
+	case weeks < 1 || weeks > maxPlanWeeks:
+		return invalid("service: %sweeks must be between 1 and %d", in(at), maxPlanWeeks)
+	case len(sessions) == 0:
+		return invalid("service: %ssessions must list at least one session", in(at))
+	}
+	days := map[[2]int]bool{}
+	for i, session := range sessions {
+		path := fmt.Sprintf("sessions[%d]", i)
+		if at != "" {
+			path = at + "." + path
+		}
+		switch {
+		case session.Week < 1 || session.Week > weeks:
+			return invalid("service: %sweek must be between 1 and the plan's %d weeks", in(path), weeks)
+		case session.Day < 1 || session.Day > 7:
+			return invalid("service: %sday must be between 1 and 7", in(path))
+		case days[[2]int{session.Week, session.Day}]:
+			return invalid("service: %sweek %d, day %d already has a session", in(path), session.Week, session.Day)
+		case len(session.Exercises) == 0 || len(session.Exercises) > maxExercisesPerSession:
+			return invalid("service: %sexercises must list 1 to %d exercises", in(path), maxExercisesPerSession)
+		}
+		days[[2]int{session.Week, session.Day}] = true
+		for j, e := range session.Exercises {
+			exercise := fmt.Sprintf("%s.exercises[%d]", path, j)
+			switch {
+			case strings.TrimSpace(e.Name) == "":
+				return invalid("service: %sname is required", in(exercise))
+			case e.Sets < 1:
+				return invalid("service: %ssets must be at least 1", in(exercise))
+			case (e.Reps > 0) == (e.DurationSeconds > 0):
+				return invalid("service: %sset either reps or duration_seconds", in(exercise))
+			}
+		}
+	}
+	return nil
+}
+
+// in introduces a message about a field of the request at path, or of the request itself when path is "".
+func in(path string) string {
+	if path == "" {
+		return ""
+	}
+	return "in " + path + ", "
+}
+
+// Create writes a new plan, credited to the coach. Names are unique among the coach's plans.
+func (s *WorkoutPlanServiceImpl) Create(ctx context.Context, coachID uuid.UUID, req models.CreateWorkoutPlanRequest) (*models.WorkoutPlan, error) {
+	if err := checkPlan("", req.Name, req.Description, req.Weeks, req.Sessions); err != nil {
+		return nil, err
+	}
+	coach, err := s.userRepo.GetUserByID(ctx, coachID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to get coach: %w", err)
+	}
+	if coach == nil {
+		return nil, notFound("service: coach not found")
+	}
+	plans, err := s.planRepo.ListPlans(ctx, coachID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to list workout plans: %w", err)
+	}
+	for _, p := range plans {
+		if planKey(p.Name) == planKey(req.Name) {
+			return nil, conflict("service: you already have a plan named %q", p.Name)
+		}
+	}
+
+	now := time.Now().UTC()
+	plan := models.WorkoutPlan{
+		ID:          uuid.New(),
+		CoachID:     coachID,
+		Name:        strings.TrimSpace(req.Name),
+		Description: req.Description,
+		Weeks:       req.Weeks,
+		Sessions:    req.Sessions,
+		Author:      models.PlanAuthor{Name: coach.Name, Username: coach.Username, Platform: models.PlanPlatformPulse},
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := s.planRepo.SavePlans(ctx, coachID, []models.WorkoutPlan{plan}); err != nil {
+		return nil, fmt.Errorf("service: failed to create workout plan: %w", err)
+	}
+	logger.FromContext(ctx).Infof("Coach %s created workout plan %s", coachID, plan.ID)
+	return &plan, nil
+}
+
+// List returns the coach's plans, oldest first.
+func (s *WorkoutPlanServiceImpl) List(ctx context.Context, coachID uuid.UUID) ([]models.WorkoutPlan, error) {
+	plans, err := s.planRepo.ListPlans(ctx, coachID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to list workout plans: %w", err)
+	}
+	return plans, nil
+}
+
+// Get returns one of the coach's plans.
+func (s *WorkoutPlanServiceImpl) Get(ctx context.Context, coachID, id uuid.UUID) (*models.WorkoutPlan, error) {
+	plan, err := s.planRepo.GetPlan(ctx, coachID, id)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to get workout plan: %w", err)
+	}
+	if plan == nil {
+		return nil, notFound("service: workout plan not found")
+	}
+	return plan, nil
+}
+
+// Delete deletes one of the coach's plans. Copies other coaches imported are theirs, and stay.
+func (s *WorkoutPlanServiceImpl) Delete(ctx context.Context, coachID, id uuid.UUID) error {
+	deleted, err := s.planRepo.DeletePlan(ctx, coachID, id)
+	if err != nil {
+		return fmt.Errorf("service: failed to delete workout plan: %w", err)
+	}
+	if !deleted {
+		return notFound("service: workout plan not found")
+	}
+	return nil
+}
+
+// Export writes the coach's plans with the given IDs, or all of them, as a plan document. Plans keep
+// their original author and are listed under their origin ID, so importing them again, anywhere,
+// recognizes them.
+func (s *WorkoutPlanServiceImpl) Export(ctx context.Context, coachID uuid.UUID, ids []uuid.UUID) (*models.WorkoutPlanDocument, error) {
+	plans, err := s.planRepo.ListPlans(ctx, coachID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to list workout plans: %w", err)
+	}
+	if len(ids) > 0 {
+		byID := make(map[uuid.UUID]models.WorkoutPlan, len(plans))
+		for _, p := range plans {
+			byID[p.ID] = p
+		}
+		plans = make([]models.WorkoutPlan, 0, len(ids))
+		for _, id := range ids {
+			p, ok := byID[id]
+			if !ok {
+				return nil, notFound("service: workout plan %s not found", id)
+			}
+			delete(byID, id) // An ID asked for twice is exported once
+			plans = append(plans, p)
+		}
+	}
+	if len(plans) > maxPlansPerDocument {
+		return nil, invalid("service: at most %d plans can be exported at once; choose them with the id parameter", maxPlansPerDocument)
+	}
+
+	now := time.Now().UTC()
+	doc := &models.WorkoutPlanDocument{
+		Format:     models.WorkoutPlanFormat,
+		Version:    models.WorkoutPlanVersion,
+		ExportedAt: &now,
+		Plans:      make([]models.ExportedWorkoutPlan, 0, len(plans)),
+	}
+	for _, p := range plans {
+		doc.Plans = append(doc.Plans, models.ExportedWorkoutPlan{
+			ID:          p.OriginID(),
+			Name:        p.Name,
+			Description: p.Description,
+			Weeks:       p.Weeks,
+			Sessions:    p.Sessions,
+			Author:      p.Author,
+		})
+	}
+	return doc, nil
+}
+
+// Import adds the plans of a document to the coach's, keeping their authors. A plan conflicts with
+// the coach's plan of the same origin ID, or failing that, with their plan of the same name; under
+// replace the conflicting plan is overwritten and keeps its ID, under skip it is left alone. A plan
+// would be skipped under either if its name is taken by a plan other than the one it replaces. The
+// document is checked as a whole first, and the plans are saved all or none. A dry run reports the
+// same outcomes and saves nothing.
+func (s *WorkoutPlanServiceImpl) Import(ctx context.Context, coachID uuid.UUID, doc models.WorkoutPlanDocument, opts models.WorkoutPlanImportOptions) (*models.WorkoutPlanImport, error) {
+	switch {
+	case doc.Format != models.WorkoutPlanFormat:
+		return nil, invalid("service: format must be %s", models.WorkoutPlanFormat)
+	case doc.Version < 1 || doc.Version > models.WorkoutPlanVersion:
+		return nil, invalid("service: unsupported plan document version %d, expected at most %d", doc.Version, models.WorkoutPlanVersion)
+	case len(doc.Plans) == 0 || len(doc.Plans) > maxPlansPerDocument:
+		return nil, invalid("service: plans must list 1 to %d plans", maxPlansPerDocument)
+	}
+	onConflict := opts.OnConflict
+	if onConflict == "" {
+		onConflict = onConflictSkip
+	}
+	if onConflict != onConflictSkip && onConflict != onConflictReplace {
+		return nil, invalid("service: on_conflict must be skip or replace")
+	}
+	ids, names := map[uuid.UUID]bool{}, map[string]bool{}
+	for i, p := range doc.Plans {
+		at := fmt.Sprintf("plans[%d]", i)
+		switch {
+		case p.ID == uuid.Nil:
+			return nil, invalid("service: %sid is required", in(at))
+		case ids[p.ID]:
+			return nil, invalid("service: %sid %s is that of an earlier plan", in(at), p.ID)
+		case names[planKey(p.Name)]:
+			return nil, invalid("service: %sname %q is that of an earlier plan", in(at), p.Name)
+		case strings.TrimSpace(p.Author.Name) == "":
+			return nil, invalid("service: %sauthor.name is required", in(at))
+		}
+		if err := checkPlan(at, p.Name, p.Description, p.Weeks, p.Sessions); err != nil {
+			return nil, err
+		}
+		ids[p.ID], names[planKey(p.Name)] = true, true
+	}
+
+	existing, err := s.planRepo.ListPlans(ctx, coachID)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to list workout plans: %w", err)
+	}
+	// The maps follow the plans as the import changes them, so a plan is compared with those the
+	// plans before it in the document created or replaced.
+	byOrigin := make(map[uuid.UUID]*models.WorkoutPlan, len(existing))
+	byName := make(map[string]*models.WorkoutPlan, len(existing))
+	for i := range existing {
+		byOrigin[existing[i].OriginID()] = &existing[i]
+		byName[planKey(existing[i].Name)] = &existing[i]
+	}
+
+	now := time.Now().UTC()
+	result := &models.WorkoutPlanImport{DryRun: opts.DryRun, Plans: make([]models.PlanImportOutcome, 0, len(doc.Plans))}
+	saved := make([]models.WorkoutPlan, 0, len(doc.Plans)) // Not grown, so the maps can point into it
+	for _, p := range doc.Plans {
+		sourceID := p.ID
+		outcome := models.PlanImportOutcome{SourceID: sourceID, Name: p.Name, Action: models.PlanImportCreate}
+		target := byOrigin[sourceID]
+		named := byName[planKey(p.Name)]
+		blocked := false // Replacing the target would leave two plans of one name
+		switch {
+		case target != nil && named != nil && named.ID != target.ID:
+			outcome.Conflict, target, blocked = models.PlanConflictNameTaken, named, true
+		case target != nil:
+			outcome.Conflict = models.PlanConflictSameSource
+		case named != nil:
+			outcome.Conflict, target = models.PlanConflictNameTaken, named
+		}
+		if target != nil {
+			existingID := target.ID
+			outcome.ExistingID = &existingID
+			outcome.Action = models.PlanImportReplace
+			if blocked || onConflict == onConflictSkip {
+				outcome.Action = models.PlanImportSkip
+			}
+		}
+
+		plan := models.WorkoutPlan{
+			ID:          uuid.New(),
+			CoachID:     coachID,
+			Name:        strings.TrimSpace(p.Name),
+			Description: p.Description,
+			Weeks:       p.Weeks,
+			Sessions:    p.Sessions,
+			Author:      p.Author,
+			SourceID:    &sourceID,
+			ImportedAt:  &now,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+		switch outcome.Action {
+		case models.PlanImportSkip:
+			result.Skipped++
+			result.Plans = append(result.Plans, outcome)
+			continue
+		case models.PlanImportReplace:
+			plan.ID, plan.CreatedAt = target.ID, target.CreatedAt
+			if sourceID == target.ID { // The coach's own plan coming back
+				plan.SourceID, plan.ImportedAt = nil, nil
+			}
+			delete(byOrigin, target.OriginID())
+			delete(byName, planKey(target.Name))
+			result.Replaced++
+		default:
+			result.Created++
+		}
+		saved = append(saved, plan)
+		stored := &saved[len(saved)-1]
+		byOrigin[stored.OriginID()] = stored
+		byName[planKey(stored.Name)] = stored
+		if !opts.DryRun {
+			planID := plan.ID
+			outcome.PlanID = &planID
+		}
+		result.Plans = append(result.Plans, outcome)
+	}
+
+	if opts.DryRun || len(saved) == 0 {
+		return result, nil
+	}
+	if err := s.planRepo.SavePlans(ctx, coachID, saved); err != nil {
+		return nil, fmt.Errorf("service: failed to import workout plans: %w", err)
+	}
+	logger.FromContext(ctx).Infof("Coach %s imported workout plans: %d created, %d replaced, %d skipped",
+		coachID, result.Created, result.Replaced, result.Skipped)
+	return result, nil
+}
@@ -0,0 +1,88 @@
+// services/user-service/internal/utils/certs/certs.go
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// expiryWarning is how long before its expiry a loaded certificate is logged as about to expire.
+const expiryWarning = 14 * 24 * time.Hour
+
+// Reloader serves a certificate and key from PEM files and picks up new ones when the files change,
+// so renewed certificates take effect without a restart or dropped connections.
+type Reloader struct {
+	certFile, keyFile string
+	cert              atomic.Pointer[tls.Certificate]
+	stamp             string // Sizes and modification times of the files last loaded or tried
+}
+
+// NewReloader loads the certificate of certFile and keyFile. Watch must run for changes to be picked up.
+func NewReloader(certFile, keyFile string) (*Reloader, error) {
+	r := &Reloader{certFile: certFile, keyFile: keyFile}
+	r.stamp, _ = r.fileStamp()
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetCertificate returns the current certificate, for tls.Config.GetCertificate.
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.cert.Load(), nil
+}
+
+// Watch checks the files every interval and reloads the certificate when either changed. A pair that
+// fails to load, such as a certificate written before its key, is logged and the previous one kept
+// until the files change again.
+func (r *Reloader) Watch(interval time.Duration) {
+	for range time.Tick(interval) {
+		stamp, err := r.fileStamp()
+		if err != nil || stamp == r.stamp {
+			continue // Files briefly missing while being replaced are checked again next time
+		}
+		r.stamp = stamp
+		if err := r.load(); err != nil {
+			logger.Logger.Errorf("Failed to reload TLS certificate, keeping the previous one: %v", err)
+		}
+	}
+}
+
+func (r *Reloader) load() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("load TLS certificate %s: %w", r.certFile, err)
+	}
+	if cert.Leaf == nil {
+		if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+			return fmt.Errorf("parse TLS certificate %s: %w", r.certFile, err)
+		}
+	}
+	r.cert.Store(&cert)
+	leaf := cert.Leaf
+	logger.Logger.Infof("Loaded TLS certificate for %v from %s, expires %s", leaf.DNSNames, r.certFile, leaf.NotAfter.Format(time.RFC3339))
+	if until := time.Until(leaf.NotAfter); until < expiryWarning {
+		logger.Logger.Warnf("TLS certificate %s expires in %s", r.certFile, until.Round(time.Hour))
+	}
+	return nil
+}
+
+// fileStamp identifies the current contents of the files. Stat follows symlinks, so the certificates
+// Kubernetes mounts from secrets, which are swapped by relinking, are seen changing too.
+func (r *Reloader) fileStamp() (string, error) {
+	var stamp string
+	for _, name := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(name)
+		if err != nil {
+			return "", err
+		}
+		stamp += fmt.Sprintf("%d:%d;", info.Size(), info.ModTime().UnixNano())
+	}
+	return stamp, nil
+}
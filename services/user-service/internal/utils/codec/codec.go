@@ -0,0 +1,157 @@
+// services/user-service/internal/utils/codec/codec.go
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"mime"
+	"strconv"
+	"strings"
+)
+
+// maxDepth bounds the nesting of decoded documents, so a crafted body cannot exhaust the stack.
+const maxDepth = 64
+
+// Codec is a wire format for the service's JSON documents. Handlers only speak JSON; codecs
+// convert a document to and from their format at the edge, so every route accepts and answers
+// them without knowing. Documents are exchanged as JSON decodes them with UseNumber: nil, bool,
+// json.Number, string, []any, and map[string]any.
+type Codec interface {
+	// ContentType is sent on responses in the format, parameters included.
+	ContentType() string
+	// Encode writes a document in the format.
+	Encode(doc any) ([]byte, error)
+	// Decode reads a document in the format.
+	Decode(data []byte) (any, error)
+}
+
+// JSON is the default format, used when a caller asks for nothing else.
+var JSON Codec = jsonCodec{}
+
+// registered holds the codecs by media type, aliases included, and order the order they were
+// registered in, which breaks ties between equally preferred formats.
+var (
+	registered = map[string]Codec{}
+	order      []Codec
+)
+
+func init() {
+	Register(JSON, "application/json")
+	Register(MessagePack, "application/msgpack", "application/x-msgpack", "application/vnd.msgpack")
+	Register(Protobuf, "application/x-protobuf", "application/protobuf", "application/vnd.google.protobuf")
+}
+
+// Register makes c available under each of the media types, which are matched without their
+// parameters. It is meant to be called from init functions.
+func Register(c Codec, mediaTypes ...string) {
+	for _, mt := range mediaTypes {
+		registered[strings.ToLower(mt)] = c
+	}
+	order = append(order, c)
+}
+
+// Lookup returns the codec for a request's Content-Type, if one is registered.
+func Lookup(contentType string) (Codec, bool) {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+	c, ok := registered[mt]
+	return c, ok
+}
+
+// Negotiate picks the codec an Accept header prefers, by quality and then by how specific the
+// matching range is. JSON wins ties and is also the answer when nothing registered is acceptable,
+// since answering in JSON is more useful than 406 Not Acceptable.
+func Negotiate(accept string) Codec {
+	if strings.TrimSpace(accept) == "" {
+		return JSON
+	}
+	type rank struct {
+		q           float64
+		specificity int
+	}
+	ranks := map[Codec]rank{}
+	for _, part := range strings.Split(accept, ",") {
+		mt, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if s, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(s, 64); err != nil || q < 0 || q > 1 {
+				continue
+			}
+		}
+		for name, c := range registered {
+			specificity := 0
+			switch {
+			case mt == name:
+				specificity = 2
+			case strings.HasSuffix(mt, "/*") && strings.HasPrefix(name, strings.TrimSuffix(mt, "*")):
+				specificity = 1
+			case mt != "*/*":
+				continue
+			}
+			if r, ok := ranks[c]; !ok || specificity > r.specificity {
+				ranks[c] = rank{q, specificity}
+			}
+		}
+	}
+	best, bestQ := JSON, 0.0
+	for _, c := range order {
+		if r, ok := ranks[c]; ok && r.q > bestQ {
+			best, bestQ = c, r.q
+		}
+	}
+	return best
+}
+
+// FromJSON converts a JSON document to c's format.
+func FromJSON(c Codec, data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	return c.Encode(doc)
+}
+
+// ToJSON converts a document in c's format to JSON.
+func ToJSON(c Codec, data []byte) ([]byte, error) {
+	doc, err := c.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+}
+
+// floatNumber writes a decoded float as a JSON number. Whole numbers are written without an
+// exponent, so they still decode into integer fields.
+func floatNumber(f float64) (json.Number, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return "", errors.New("NaN and infinite numbers are not supported")
+	}
+	if f == math.Trunc(f) && math.Abs(f) < 1e21 {
+		return json.Number(strconv.FormatFloat(f, 'f', -1, 64)), nil
+	}
+	return json.Number(strconv.FormatFloat(f, 'g', -1, 64)), nil
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) ContentType() string { return "application/json" }
+
+func (jsonCodec) Encode(doc any) ([]byte, error) { return json.Marshal(doc) }
+
+func (jsonCodec) Decode(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc any
+	err := dec.Decode(&doc)
+	return doc, err
+}
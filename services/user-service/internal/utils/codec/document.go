@@ -0,0 +1,387 @@
+// services/user-service/internal/utils/codec/document.go
+package codec
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Marshal encodes v, a response DTO, in c's format. v is converted to a document as encoding/json
+// would write it, honouring json tags, omitempty and omitzero, embedded structs, and Marshaler
+// and TextMarshaler implementations, but without writing and parsing JSON text in between.
+func Marshal(c Codec, v any) ([]byte, error) {
+	doc, err := Document(v)
+	if err != nil {
+		return nil, err
+	}
+	return c.Encode(doc)
+}
+
+// Document converts v to the document encoding/json would write for it. Integers are kept as
+// exact json.Numbers, so no codec needs to route them through a float.
+func Document(v any) (any, error) {
+	return document(reflect.ValueOf(v), 0)
+}
+
+var (
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+	timeType          = reflect.TypeFor[time.Time]()
+	numberType        = reflect.TypeFor[json.Number]()
+)
+
+func document(v reflect.Value, depth int) (any, error) {
+	if depth > maxDepth {
+		return nil, fmt.Errorf("nested deeper than %d", maxDepth)
+	}
+	if !v.IsValid() {
+		return nil, nil
+	}
+	t := v.Type()
+	switch {
+	case t == timeType:
+		ts := v.Interface().(time.Time)
+		if y := ts.Year(); y < 0 || y > 9999 {
+			return nil, fmt.Errorf("time %v has a year outside [0,9999]", ts)
+		}
+		return ts.Format(time.RFC3339Nano), nil
+	case t == numberType:
+		return v.Interface().(json.Number), nil
+	case t.Kind() != reflect.Pointer && v.CanAddr() && reflect.PointerTo(t).Implements(jsonMarshalerType):
+		return marshaledDocument(v.Addr().Interface().(json.Marshaler))
+	case t.Implements(jsonMarshalerType):
+		if isNilValue(v) {
+			return nil, nil
+		}
+		return marshaledDocument(v.Interface().(json.Marshaler))
+	case t.Kind() != reflect.Pointer && v.CanAddr() && reflect.PointerTo(t).Implements(textMarshalerType):
+		return marshaledText(v.Addr().Interface().(encoding.TextMarshaler))
+	case t.Implements(textMarshalerType):
+		if isNilValue(v) {
+			return nil, nil
+		}
+		return marshaledText(v.Interface().(encoding.TextMarshaler))
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		return v.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return json.Number(strconv.FormatInt(v.Int(), 10)), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return json.Number(strconv.FormatUint(v.Uint(), 10)), nil
+	case reflect.Float32, reflect.Float64:
+		return jsonFloatNumber(v.Float(), t.Bits())
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return document(v.Elem(), depth+1)
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil, nil
+		}
+		if t.Elem().Kind() == reflect.Uint8 && !reflect.PointerTo(t.Elem()).Implements(jsonMarshalerType) && !reflect.PointerTo(t.Elem()).Implements(textMarshalerType) {
+			return base64.StdEncoding.EncodeToString(v.Bytes()), nil
+		}
+		fallthrough
+	case reflect.Array:
+		list := make([]any, v.Len())
+		for i := range list {
+			item, err := document(v.Index(i), depth+1)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = item
+		}
+		return list, nil
+	case reflect.Map:
+		if v.IsNil() {
+			return nil, nil
+		}
+		obj := make(map[string]any, v.Len())
+		for iter := v.MapRange(); iter.Next(); {
+			key, err := mapKey(iter.Key())
+			if err != nil {
+				return nil, err
+			}
+			if obj[key], err = document(iter.Value(), depth+1); err != nil {
+				return nil, err
+			}
+		}
+		return obj, nil
+	case reflect.Struct:
+		obj := map[string]any{}
+		for _, f := range jsonFields(t) {
+			fv, ok := fieldByIndex(v, f.index)
+			if !ok || (f.omitEmpty && isEmptyValue(fv)) || (f.omitZero && isZeroValue(fv)) {
+				continue
+			}
+			value, err := document(fv, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			if f.quoted {
+				value = quotedValue(value)
+			}
+			obj[f.name] = value
+		}
+		return obj, nil
+	}
+	return nil, fmt.Errorf("cannot encode %s", t)
+}
+
+func isNilValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface, reflect.Map, reflect.Slice:
+		return v.IsNil()
+	}
+	return false
+}
+
+// marshaledDocument reads what a json.Marshaler writes back into a document.
+func marshaledDocument(m json.Marshaler) (any, error) {
+	data, err := m.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid JSON from %T.MarshalJSON: %w", m, err)
+	}
+	return doc, nil
+}
+
+func marshaledText(m encoding.TextMarshaler) (any, error) {
+	text, err := m.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return string(text), nil
+}
+
+// jsonFloatNumber writes a float as encoding/json does: without an exponent unless it is very
+// large or very small.
+func jsonFloatNumber(f float64, bits int) (json.Number, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return "", fmt.Errorf("unsupported value %v", f)
+	}
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 {
+		if bits == 64 && (abs < 1e-6 || abs >= 1e21) || bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			format = 'e'
+		}
+	}
+	s := strconv.FormatFloat(f, format, -1, bits)
+	if format == 'e' {
+		// Clean up e-09 to e-9, as encoding/json does
+		if n := len(s); n >= 4 && s[n-4] == 'e' && s[n-3] == '-' && s[n-2] == '0' {
+			s = s[:n-2] + s[n-1:]
+		}
+	}
+	return json.Number(s), nil
+}
+
+// mapKey writes a map key as encoding/json does: strings as they are, TextMarshalers as their text,
+// and integers in decimal.
+func mapKey(k reflect.Value) (string, error) {
+	if k.Kind() == reflect.String {
+		return k.String(), nil
+	}
+	if tm, ok := k.Interface().(encoding.TextMarshaler); ok {
+		if k.Kind() == reflect.Pointer && k.IsNil() {
+			return "", nil
+		}
+		text, err := tm.MarshalText()
+		return string(text), err
+	}
+	switch k.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(k.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(k.Uint(), 10), nil
+	}
+	return "", fmt.Errorf("unsupported map key type %s", k.Type())
+}
+
+// quotedValue applies the ",string" tag option, which writes a scalar as a JSON string.
+func quotedValue(v any) any {
+	switch v := v.(type) {
+	case bool:
+		return strconv.FormatBool(v)
+	case json.Number:
+		return string(v)
+	case string:
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+	return v
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Interface, reflect.Pointer:
+		return v.IsZero()
+	}
+	return false
+}
+
+func isZeroValue(v reflect.Value) bool {
+	if z, ok := v.Interface().(interface{ IsZero() bool }); ok {
+		if v.Kind() == reflect.Pointer && v.IsNil() {
+			return true
+		}
+		return z.IsZero()
+	}
+	return v.IsZero()
+}
+
+// jsonField is a struct field as encoding/json writes it: its name, where it is (through embedded
+// structs), and its tag options.
+type jsonField struct {
+	name                string
+	index               []int
+	omitEmpty, omitZero bool
+	quoted              bool
+	tagged              bool
+}
+
+var fieldCache sync.Map // reflect.Type to []jsonField
+
+// jsonFields returns the fields encoding/json writes for a struct type, in order. Fields of
+// embedded structs are promoted unless a shallower field, or a tagged one at the same depth, has
+// their name; ambiguous names are left out, as encoding/json leaves them out.
+func jsonFields(t reflect.Type) []jsonField {
+	if cached, ok := fieldCache.Load(t); ok {
+		return cached.([]jsonField)
+	}
+	var all []jsonField
+	collectFields(t, nil, map[reflect.Type]bool{}, &all)
+
+	byName := map[string][]jsonField{}
+	var names []string
+	for _, f := range all {
+		if _, ok := byName[f.name]; !ok {
+			names = append(names, f.name)
+		}
+		byName[f.name] = append(byName[f.name], f)
+	}
+	var fields []jsonField
+	for _, name := range names {
+		if f, ok := dominantField(byName[name]); ok {
+			fields = append(fields, f)
+		}
+	}
+	slices.SortStableFunc(fields, func(a, b jsonField) int { return slices.Compare(a.index, b.index) })
+	fieldCache.Store(t, fields)
+	return fields
+}
+
+func collectFields(t reflect.Type, index []int, visited map[reflect.Type]bool, out *[]jsonField) {
+	if visited[t] {
+		return
+	}
+	visited[t] = true
+	defer delete(visited, t)
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		ft := sf.Type
+		if sf.Anonymous {
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if !sf.IsExported() && ft.Kind() != reflect.Struct {
+				continue
+			}
+			if name == "" && ft.Kind() == reflect.Struct {
+				collectFields(ft, append(slices.Clip(index), i), visited, out)
+				continue
+			}
+		} else if !sf.IsExported() {
+			continue
+		}
+		f := jsonField{name: name, index: append(slices.Clip(index), i), tagged: name != ""}
+		if name == "" {
+			f.name = sf.Name
+		}
+		for opt := range strings.SplitSeq(opts, ",") {
+			switch opt {
+			case "omitempty":
+				f.omitEmpty = true
+			case "omitzero":
+				f.omitZero = true
+			case "string":
+				switch ft.Kind() {
+				case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+					reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+					reflect.Float32, reflect.Float64, reflect.String:
+					f.quoted = true
+				}
+			}
+		}
+		*out = append(*out, f)
+	}
+}
+
+// dominantField picks the field a name refers to: the shallowest one, preferring a tagged one among
+// those; there is none if that still leaves more than one.
+func dominantField(fields []jsonField) (jsonField, bool) {
+	slices.SortStableFunc(fields, func(a, b jsonField) int { return len(a.index) - len(b.index) })
+	depth := len(fields[0].index)
+	var candidates []jsonField
+	for _, f := range fields {
+		if len(f.index) == depth {
+			candidates = append(candidates, f)
+		}
+	}
+	if len(candidates) == 1 {
+		return candidates[0], true
+	}
+	var tagged []jsonField
+	for _, f := range candidates {
+		if f.tagged {
+			tagged = append(tagged, f)
+		}
+	}
+	if len(tagged) == 1 {
+		return tagged[0], true
+	}
+	return jsonField{}, false
+}
+
+// fieldByIndex returns the field at index, or false if it is reached through a nil embedded pointer.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
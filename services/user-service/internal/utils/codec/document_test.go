@@ -0,0 +1,235 @@
+// services/user-service/internal/utils/codec/document_test.go
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"health-tracker-project/services/user-service/internal/models"
+)
+
+type docBase struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Shadowed  string    `json:"name"`
+}
+
+type docExtra struct {
+	Note string `json:"note,omitempty"`
+}
+
+type docRaw struct{ v string }
+
+func (r docRaw) MarshalJSON() ([]byte, error) { return json.Marshal(map[string]string{"raw": r.v}) }
+
+type docPtrMarshaler struct{ n int }
+
+func (p *docPtrMarshaler) MarshalJSON() ([]byte, error) { return json.Marshal(p.n * 2) }
+
+type docLevel int
+
+func (l docLevel) MarshalText() ([]byte, error) { return []byte([]string{"low", "high"}[l]), nil }
+
+type docValue struct {
+	docBase
+	*docExtra
+	Name       string            `json:"name"`
+	Count      int64             `json:"count"`
+	Big        uint64            `json:"big"`
+	Ratio      float64           `json:"ratio"`
+	Small      float32           `json:"small"`
+	Tiny       float64           `json:"tiny"`
+	Flag       bool              `json:"flag,omitempty"`
+	Optional   *float64          `json:"optional,omitempty"`
+	Missing    *string           `json:"missing"`
+	Updated    time.Time         `json:"updated,omitzero"`
+	Tags       []string          `json:"tags"`
+	NoTags     []string          `json:"no_tags"`
+	Empty      []string          `json:"empty,omitempty"`
+	Blob       []byte            `json:"blob"`
+	Scores     map[int]float64   `json:"scores"`
+	Levels     map[docLevel]bool `json:"levels"`
+	Level      docLevel          `json:"level"`
+	Raw        docRaw            `json:"raw"`
+	Doubled    docPtrMarshaler   `json:"doubled"`
+	Message    json.RawMessage   `json:"message"`
+	Number     json.Number       `json:"number"`
+	Quoted     int               `json:"quoted,string"`
+	Any        any               `json:"any"`
+	Nested     []map[string]any  `json:"nested"`
+	Array      [2]int8           `json:"array"`
+	Skipped    string            `json:"-"`
+	Untagged   string
+	unexported string
+	Pointers   map[string]*docRaw `json:"pointers"`
+}
+
+func TestDocumentMatchesEncodingJSON(t *testing.T) {
+	height := 180.5
+	created := time.Date(2024, 3, 1, 9, 30, 0, 123000000, time.FixedZone("CET", 3600))
+	full := &docValue{
+		docBase:  docBase{ID: uuid.MustParse("0b5e6c1a-2f4d-4e8b-9a7c-3d1f5e7b9a2c"), CreatedAt: created, Shadowed: "hidden"},
+		docExtra: &docExtra{Note: "hello"},
+		Name:     "Ada",
+		Count:    math.MaxInt64,
+		Big:      math.MaxUint64,
+		Ratio:    66.9,
+		Small:    0.1,
+		Tiny:     1e-9,
+		Flag:     true,
+		Optional: &height,
+		Updated:  created.Add(time.Hour),
+		Tags:     []string{"a", "b"},
+		Blob:     []byte("binary\x00data"),
+		Scores:   map[int]float64{1: 1.5, -2: 1e21},
+		Levels:   map[docLevel]bool{0: true, 1: false},
+		Level:    1,
+		Raw:      docRaw{"x"},
+		Doubled:  docPtrMarshaler{21},
+		Message:  json.RawMessage(`{"b":[1,2.5,"c"],"n":12345678901234567890}`),
+		Number:   "42.0",
+		Quoted:   7,
+		Any:      []any{nil, true, "s", 3},
+		Nested:   []map[string]any{{"k": map[string]any{"deep": []int{1}}}},
+		Array:    [2]int8{-1, 1},
+		Skipped:  "never",
+		Untagged: "kept",
+		Pointers: map[string]*docRaw{"nil": nil, "set": {"y"}},
+	}
+
+	tests := []struct {
+		name string
+		v    any
+	}{
+		{"full", full},
+		{"zero", docValue{}},
+		{"value with pointer-receiver marshaler not addressable", *full},
+		{"user response", models.UserResponse{ID: uuid.New(), Name: "Ada", Email: "ada@example.com", CreatedAt: created}},
+		{"error envelope", models.ErrorResponse{Error: models.APIError{Code: "NOT_FOUND", Message: "User not found", Details: []models.ErrorDetail{{Field: "id", Message: "unknown"}}}}},
+		{"map", map[string]string{"message": "ok"}},
+		{"nil", nil},
+		{"slice of structs", []models.ErrorDetail{{Message: "a"}, {Field: "f", Message: "b"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want, err := json.Marshal(tt.v)
+			if err != nil {
+				t.Fatalf("json.Marshal: %v", err)
+			}
+			doc, err := Document(tt.v)
+			if err != nil {
+				t.Fatalf("Document: %v", err)
+			}
+			got, err := json.Marshal(doc)
+			if err != nil {
+				t.Fatalf("json.Marshal(Document): %v", err)
+			}
+			if !reflect.DeepEqual(decodeNumbers(t, got), decodeNumbers(t, want)) {
+				t.Errorf("Document(%T) =\n%s\nencoding/json writes\n%s", tt.v, got, want)
+			}
+		})
+	}
+}
+
+func decodeNumbers(t *testing.T, data []byte) any {
+	t.Helper()
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		t.Fatalf("decoding %s: %v", data, err)
+	}
+	return doc
+}
+
+func TestDocumentErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		v    any
+	}{
+		{"NaN", math.NaN()},
+		{"infinity", map[string]float64{"x": math.Inf(1)}},
+		{"channel", make(chan int)},
+		{"year outside 0-9999", time.Date(10000, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"struct map key", map[struct{ A int }]int{{1}: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Document(tt.v); err == nil {
+				t.Errorf("Document(%v) succeeded, want an error", tt.v)
+			}
+		})
+	}
+}
+
+func TestMarshalKeepsIntegers(t *testing.T) {
+	type counters struct {
+		Max      int64  `json:"max"`
+		Min      int64  `json:"min"`
+		Unsigned uint64 `json:"unsigned"`
+		Exact    int64  `json:"exact"`
+		Ratio    float64
+	}
+	v := counters{Max: math.MaxInt64, Min: math.MinInt64, Unsigned: math.MaxUint64, Exact: 1 << 53, Ratio: 0.25}
+
+	tests := []struct {
+		codec Codec
+		want  map[string]any
+	}{
+		{MessagePack, map[string]any{
+			"max": json.Number("9223372036854775807"), "min": json.Number("-9223372036854775808"),
+			"unsigned": json.Number("18446744073709551615"), "exact": json.Number("9007199254740992"), "Ratio": json.Number("0.25"),
+		}},
+		{Protobuf, map[string]any{
+			"max": "9223372036854775807", "min": "-9223372036854775808",
+			"unsigned": "18446744073709551615", "exact": json.Number("9007199254740992"), "Ratio": json.Number("0.25"),
+		}},
+		{JSON, map[string]any{
+			"max": json.Number("9223372036854775807"), "min": json.Number("-9223372036854775808"),
+			"unsigned": json.Number("18446744073709551615"), "exact": json.Number("9007199254740992"), "Ratio": json.Number("0.25"),
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.codec.ContentType(), func(t *testing.T) {
+			data, err := Marshal(tt.codec, v)
+			if err != nil {
+				t.Fatalf("Marshal: %v", err)
+			}
+			got, err := tt.codec.Decode(data)
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			if !reflect.DeepEqual(got, any(tt.want)) {
+				t.Errorf("round trip = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func BenchmarkMarshal(b *testing.B) {
+	users := make([]models.UserResponse, 100)
+	for i := range users {
+		users[i] = models.UserResponse{ID: uuid.New(), Name: "Ada Lovelace", Email: "ada@example.com", Timezone: "UTC", CreatedAt: time.Now()}
+	}
+	b.Run("direct", func(b *testing.B) {
+		for b.Loop() {
+			if _, err := Marshal(MessagePack, users); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("through JSON", func(b *testing.B) {
+		for b.Loop() {
+			data, _ := json.Marshal(users)
+			if _, err := FromJSON(MessagePack, data); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
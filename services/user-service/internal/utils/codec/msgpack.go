@@ -0,0 +1,323 @@
+// services/user-service/internal/utils/codec/msgpack.go
+package codec
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// MessagePack encodes documents in MessagePack (https://msgpack.org). Integers use the smallest
+// form that holds them and other numbers are float64; object keys are sorted. Decoding also
+// takes float32, binary data (as a base64 string, as JSON carries bytes), and timestamps (as
+// RFC 3339 strings), but no other extension types.
+var MessagePack Codec = msgpackCodec{}
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) ContentType() string { return "application/msgpack" }
+
+func (msgpackCodec) Encode(doc any) ([]byte, error) {
+	return appendMsgpack(nil, doc)
+}
+
+func appendMsgpack(b []byte, v any) ([]byte, error) {
+	switch v := v.(type) {
+	case nil:
+		return append(b, 0xc0), nil
+	case bool:
+		if v {
+			return append(b, 0xc3), nil
+		}
+		return append(b, 0xc2), nil
+	case json.Number:
+		if i, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+			return appendMsgpackInt(b, i), nil
+		}
+		if u, err := strconv.ParseUint(string(v), 10, 64); err == nil {
+			return binary.BigEndian.AppendUint64(append(b, 0xcf), u), nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %s", v)
+		}
+		return binary.BigEndian.AppendUint64(append(b, 0xcb), math.Float64bits(f)), nil
+	case string:
+		switch n := len(v); {
+		case n < 32:
+			b = append(b, 0xa0|byte(n))
+		case n <= math.MaxUint8:
+			b = append(b, 0xd9, byte(n))
+		case n <= math.MaxUint16:
+			b = binary.BigEndian.AppendUint16(append(b, 0xda), uint16(n))
+		default:
+			b = binary.BigEndian.AppendUint32(append(b, 0xdb), uint32(n))
+		}
+		return append(b, v...), nil
+	case []any:
+		b = appendMsgpackLen(b, len(v), 0x90, 0xdc)
+		for _, item := range v {
+			var err error
+			if b, err = appendMsgpack(b, item); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case map[string]any:
+		b = appendMsgpackLen(b, len(v), 0x80, 0xde)
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			b, _ = appendMsgpack(b, k)
+			var err error
+			if b, err = appendMsgpack(b, v[k]); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("cannot encode %T", v)
+}
+
+func appendMsgpackInt(b []byte, i int64) []byte {
+	switch {
+	case i >= 0 && i < 128:
+		return append(b, byte(i))
+	case i >= -32 && i < 0:
+		return append(b, byte(i))
+	case i >= 0 && i <= math.MaxUint8:
+		return append(b, 0xcc, byte(i))
+	case i >= 0 && i <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xcd), uint16(i))
+	case i >= 0 && i <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, 0xce), uint32(i))
+	case i >= 0:
+		return binary.BigEndian.AppendUint64(append(b, 0xcf), uint64(i))
+	case i >= math.MinInt8:
+		return append(b, 0xd0, byte(i))
+	case i >= math.MinInt16:
+		return binary.BigEndian.AppendUint16(append(b, 0xd1), uint16(i))
+	case i >= math.MinInt32:
+		return binary.BigEndian.AppendUint32(append(b, 0xd2), uint32(i))
+	}
+	return binary.BigEndian.AppendUint64(append(b, 0xd3), uint64(i))
+}
+
+// appendMsgpackLen writes the header of an array or map: fix is the format of up to 15 entries,
+// and long that of up to 65535, followed by the 32-bit one.
+func appendMsgpackLen(b []byte, n int, fix, long byte) []byte {
+	switch {
+	case n < 16:
+		return append(b, fix|byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, long), uint16(n))
+	}
+	return binary.BigEndian.AppendUint32(append(b, long+1), uint32(n))
+}
+
+func (msgpackCodec) Decode(data []byte) (any, error) {
+	d := &msgpackDecoder{data: data}
+	doc, err := d.value(0)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MessagePack: %w", err)
+	}
+	if d.pos != len(d.data) {
+		return nil, fmt.Errorf("invalid MessagePack: %d bytes after the document", len(d.data)-d.pos)
+	}
+	return doc, nil
+}
+
+var errMsgpackShort = errors.New("unexpected end of data")
+
+type msgpackDecoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *msgpackDecoder) next(n int) ([]byte, error) {
+	if n < 0 || n > len(d.data)-d.pos {
+		return nil, errMsgpackShort
+	}
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+// uint reads a big-endian unsigned integer of size bytes.
+func (d *msgpackDecoder) uint(size int) (uint64, error) {
+	b, err := d.next(size)
+	if err != nil {
+		return 0, err
+	}
+	var u uint64
+	for _, c := range b {
+		u = u<<8 | uint64(c)
+	}
+	return u, nil
+}
+
+func (d *msgpackDecoder) value(depth int) (any, error) {
+	if depth > maxDepth {
+		return nil, fmt.Errorf("nested deeper than %d", maxDepth)
+	}
+	head, err := d.next(1)
+	if err != nil {
+		return nil, err
+	}
+	t := head[0]
+	switch {
+	case t < 0x80:
+		return json.Number(strconv.Itoa(int(t))), nil
+	case t >= 0xe0:
+		return json.Number(strconv.Itoa(int(int8(t)))), nil
+	case t&0xf0 == 0x80:
+		return d.object(int(t&0x0f), depth)
+	case t&0xf0 == 0x90:
+		return d.array(int(t&0x0f), depth)
+	case t&0xe0 == 0xa0:
+		return d.str(int(t & 0x1f))
+	}
+	switch t {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6: // bin 8, 16, 32
+		n, err := d.uint(1 << (t - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		b, err := d.next(int(n))
+		if err != nil {
+			return nil, err
+		}
+		return base64.StdEncoding.EncodeToString(b), nil
+	case 0xc7, 0xc8, 0xc9: // ext 8, 16, 32
+		n, err := d.uint(1 << (t - 0xc7))
+		if err != nil {
+			return nil, err
+		}
+		return d.ext(int(n))
+	case 0xca:
+		u, err := d.uint(4)
+		return jsonFloat(float64(math.Float32frombits(uint32(u))), err)
+	case 0xcb:
+		u, err := d.uint(8)
+		return jsonFloat(math.Float64frombits(u), err)
+	case 0xcc, 0xcd, 0xce, 0xcf: // uint 8 to 64
+		u, err := d.uint(1 << (t - 0xcc))
+		return json.Number(strconv.FormatUint(u, 10)), err
+	case 0xd0, 0xd1, 0xd2, 0xd3: // int 8 to 64
+		size := 1 << (t - 0xd0)
+		u, err := d.uint(size)
+		shift := 64 - 8*size
+		return json.Number(strconv.FormatInt(int64(u<<shift)>>shift, 10)), err
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8: // fixext 1 to 16
+		return d.ext(1 << (t - 0xd4))
+	case 0xd9, 0xda, 0xdb: // str 8, 16, 32
+		n, err := d.uint(1 << (t - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return d.str(int(n))
+	case 0xdc, 0xdd: // array 16, 32
+		n, err := d.uint(2 << (t - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return d.array(int(n), depth)
+	case 0xde, 0xdf: // map 16, 32
+		n, err := d.uint(2 << (t - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return d.object(int(n), depth)
+	}
+	return nil, fmt.Errorf("unknown type byte 0x%02x", t)
+}
+
+// jsonFloat converts a decoded float to a JSON number.
+func jsonFloat(f float64, err error) (any, error) {
+	if err != nil {
+		return nil, err
+	}
+	return floatNumber(f)
+}
+
+func (d *msgpackDecoder) str(n int) (any, error) {
+	b, err := d.next(n)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (d *msgpackDecoder) array(n, depth int) (any, error) {
+	if n > len(d.data)-d.pos { // Every item takes at least a byte
+		return nil, errMsgpackShort
+	}
+	items := make([]any, n)
+	for i := range items {
+		var err error
+		if items[i], err = d.value(depth + 1); err != nil {
+			return nil, err
+		}
+	}
+	return items, nil
+}
+
+func (d *msgpackDecoder) object(n, depth int) (any, error) {
+	if 2*n > len(d.data)-d.pos {
+		return nil, errMsgpackShort
+	}
+	fields := make(map[string]any, n)
+	for range n {
+		key, err := d.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		k, ok := key.(string)
+		if !ok {
+			return nil, errors.New("map keys must be strings")
+		}
+		if fields[k], err = d.value(depth + 1); err != nil {
+			return nil, err
+		}
+	}
+	return fields, nil
+}
+
+// ext reads an extension value of n bytes. Only timestamps (type -1) are understood.
+func (d *msgpackDecoder) ext(n int) (any, error) {
+	b, err := d.next(n + 1)
+	if err != nil {
+		return nil, err
+	}
+	if int8(b[0]) != -1 {
+		return nil, fmt.Errorf("unsupported extension type %d", int8(b[0]))
+	}
+	var t time.Time
+	switch b = b[1:]; len(b) {
+	case 4:
+		t = time.Unix(int64(binary.BigEndian.Uint32(b)), 0)
+	case 8:
+		u := binary.BigEndian.Uint64(b)
+		t = time.Unix(int64(u&(1<<34-1)), int64(u>>34))
+	case 12:
+		t = time.Unix(int64(binary.BigEndian.Uint64(b[4:])), int64(binary.BigEndian.Uint32(b)))
+	default:
+		return nil, fmt.Errorf("invalid timestamp of %d bytes", len(b))
+	}
+	return t.UTC().Format(time.RFC3339Nano), nil
+}
@@ -0,0 +1,276 @@
+// services/user-service/internal/utils/codec/protobuf.go
+package codec
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"unicode/utf8"
+)
+
+// Protobuf encodes documents as a google.protobuf.Value message (google/protobuf/struct.proto),
+// the well-known type that holds any JSON value, so callers decode responses and encode requests
+// with their protobuf library's generated struct.proto types and need no schema from this
+// service. Objects are Struct messages and arrays ListValue messages. Numbers are doubles, except
+// integers beyond 2^53, which a double cannot hold exactly: like int64 fields in proto3's JSON
+// mapping, they are written as strings. Map entries are written in key order.
+var Protobuf Codec = protobufCodec{}
+
+type protobufCodec struct{}
+
+func (protobufCodec) ContentType() string {
+	return "application/x-protobuf; proto=google.protobuf.Value"
+}
+
+// Field numbers and wire types of struct.proto.
+const (
+	valueNull   = 1 // null_value, enum NullValue
+	valueNumber = 2 // number_value, double
+	valueString = 3 // string_value
+	valueBool   = 4 // bool_value
+	valueStruct = 5 // struct_value, Struct
+	valueList   = 6 // list_value, ListValue
+
+	structFields = 1 // Struct.fields, map<string, Value>, as entries with key 1 and value 2
+	listValues   = 1 // ListValue.values, repeated Value
+
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+func (protobufCodec) Encode(doc any) ([]byte, error) {
+	return appendProtoValue(nil, doc)
+}
+
+func appendTag(b []byte, field, wireType int) []byte {
+	return binary.AppendUvarint(b, uint64(field<<3|wireType))
+}
+
+func appendProtoBytes(b []byte, field int, data []byte) []byte {
+	b = appendTag(b, field, wireBytes)
+	b = binary.AppendUvarint(b, uint64(len(data)))
+	return append(b, data...)
+}
+
+// appendProtoValue writes the fields of a Value message holding v.
+func appendProtoValue(b []byte, v any) ([]byte, error) {
+	switch v := v.(type) {
+	case nil:
+		return append(appendTag(b, valueNull, wireVarint), 0), nil
+	case bool:
+		x := uint64(0)
+		if v {
+			x = 1
+		}
+		return binary.AppendUvarint(appendTag(b, valueBool, wireVarint), x), nil
+	case json.Number:
+		if !exactDouble(v) {
+			return appendProtoBytes(b, valueString, []byte(v)), nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %s", v)
+		}
+		return binary.LittleEndian.AppendUint64(appendTag(b, valueNumber, wireFixed64), math.Float64bits(f)), nil
+	case string:
+		return appendProtoBytes(b, valueString, []byte(v)), nil
+	case []any:
+		var list []byte
+		for _, item := range v {
+			value, err := appendProtoValue(nil, item)
+			if err != nil {
+				return nil, err
+			}
+			list = appendProtoBytes(list, listValues, value)
+		}
+		return appendProtoBytes(b, valueList, list), nil
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		var fields []byte
+		for _, k := range keys {
+			value, err := appendProtoValue(nil, v[k])
+			if err != nil {
+				return nil, err
+			}
+			entry := appendProtoBytes(nil, 1, []byte(k))
+			entry = appendProtoBytes(entry, 2, value)
+			fields = appendProtoBytes(fields, structFields, entry)
+		}
+		return appendProtoBytes(b, valueStruct, fields), nil
+	}
+	return nil, fmt.Errorf("cannot encode %T", v)
+}
+
+// maxExactInteger is the largest integer from which every smaller one is exactly a double.
+const maxExactInteger = 1 << 53
+
+// exactDouble reports whether n, if it is an integer, is held exactly by a double.
+func exactDouble(n json.Number) bool {
+	if i, err := strconv.ParseInt(string(n), 10, 64); err == nil {
+		return i >= -maxExactInteger && i <= maxExactInteger
+	}
+	if u, err := strconv.ParseUint(string(n), 10, 64); err == nil {
+		return u <= maxExactInteger
+	}
+	return true
+}
+
+func (protobufCodec) Decode(data []byte) (any, error) {
+	doc, err := decodeProtoValue(data, 0)
+	if err != nil {
+		return nil, fmt.Errorf("invalid google.protobuf.Value: %w", err)
+	}
+	return doc, nil
+}
+
+// protoField is one field of a message: its number and wire type, and its value, held in x for
+// varint and fixed-size fields and in data for length-delimited ones.
+type protoField struct {
+	num, wireType int
+	x             uint64
+	data          []byte
+}
+
+// protoFields calls fn with each field of a message in order. Groups are not supported.
+func protoFields(msg []byte, fn func(protoField) error) error {
+	for len(msg) > 0 {
+		tag, n := binary.Uvarint(msg)
+		if n <= 0 || tag>>3 == 0 || tag>>3 > math.MaxInt32 {
+			return errors.New("invalid field tag")
+		}
+		msg = msg[n:]
+		f := protoField{num: int(tag >> 3), wireType: int(tag & 7)}
+		switch f.wireType {
+		case wireVarint:
+			if f.x, n = binary.Uvarint(msg); n <= 0 {
+				return errors.New("invalid varint")
+			}
+		case wireFixed64:
+			if n = 8; len(msg) < n {
+				return errors.New("unexpected end of data")
+			}
+			f.x = binary.LittleEndian.Uint64(msg)
+		case wireFixed32:
+			if n = 4; len(msg) < n {
+				return errors.New("unexpected end of data")
+			}
+			f.x = uint64(binary.LittleEndian.Uint32(msg))
+		case wireBytes:
+			size, m := binary.Uvarint(msg)
+			if m <= 0 || size > uint64(len(msg)-m) {
+				return errors.New("invalid length")
+			}
+			f.data, n = msg[m:m+int(size)], m+int(size)
+		default:
+			return fmt.Errorf("unsupported wire type %d", f.wireType)
+		}
+		msg = msg[n:]
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeProtoValue reads a Value message. Of the kinds it holds, the last one wins, as for any
+// oneof; a Value without one is null. Unknown fields are skipped.
+func decodeProtoValue(msg []byte, depth int) (any, error) {
+	if depth > maxDepth {
+		return nil, fmt.Errorf("nested deeper than %d", maxDepth)
+	}
+	var v any
+	err := protoFields(msg, func(f protoField) error {
+		want := wireBytes
+		switch f.num {
+		case valueNull, valueBool:
+			want = wireVarint
+		case valueNumber:
+			want = wireFixed64
+		}
+		if f.num >= valueNull && f.num <= valueList && f.wireType != want {
+			return fmt.Errorf("field %d has wire type %d, expected %d", f.num, f.wireType, want)
+		}
+		var err error
+		switch f.num {
+		case valueNull:
+			v = nil
+		case valueNumber:
+			n, err := floatNumber(math.Float64frombits(f.x))
+			if err != nil {
+				return err
+			}
+			v = n
+		case valueString:
+			if !utf8.Valid(f.data) {
+				return errors.New("string_value is not valid UTF-8")
+			}
+			v = string(f.data)
+		case valueBool:
+			v = f.x != 0
+		case valueStruct:
+			v, err = decodeProtoStruct(f.data, depth)
+		case valueList:
+			v, err = decodeProtoList(f.data, depth)
+		}
+		return err
+	})
+	return v, err
+}
+
+func decodeProtoStruct(msg []byte, depth int) (map[string]any, error) {
+	fields := map[string]any{}
+	err := protoFields(msg, func(f protoField) error {
+		if f.num != structFields {
+			return nil
+		}
+		if f.wireType != wireBytes {
+			return errors.New("Struct.fields entry is not length-delimited")
+		}
+		var key string
+		var value any
+		err := protoFields(f.data, func(e protoField) error {
+			if e.wireType != wireBytes || (e.num != 1 && e.num != 2) {
+				return nil
+			}
+			if e.num == 1 {
+				if !utf8.Valid(e.data) {
+					return errors.New("Struct key is not valid UTF-8")
+				}
+				key = string(e.data)
+				return nil
+			}
+			var err error
+			value, err = decodeProtoValue(e.data, depth+1)
+			return err
+		})
+		fields[key] = value
+		return err
+	})
+	return fields, err
+}
+
+func decodeProtoList(msg []byte, depth int) ([]any, error) {
+	values := []any{}
+	err := protoFields(msg, func(f protoField) error {
+		if f.num != listValues {
+			return nil
+		}
+		if f.wireType != wireBytes {
+			return errors.New("ListValue.values item is not length-delimited")
+		}
+		value, err := decodeProtoValue(f.data, depth+1)
+		values = append(values, value)
+		return err
+	})
+	return values, err
+}
@@ -0,0 +1,139 @@
+// services/user-service/internal/utils/jwt/jwks.go
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// JWK is a single JSON Web Key as defined by RFC 7517.
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	// RSA public key parameters
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// EC public key parameters
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKSet is the document served at /.well-known/jwks.json.
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public signing keys in JWKS format.
+// With HS256 the set is empty, since a shared secret must never be published.
+func JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	if keyID == "" {
+		return set
+	}
+
+	key := JWK{Use: "sig", Alg: signingMethod.Alg(), Kid: keyID}
+	switch pub := verifyKey.(type) {
+	case *rsa.PublicKey:
+		key.Kty = "RSA"
+		key.N = b64(pub.N.Bytes())
+		key.E = b64(big.NewInt(int64(pub.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		key.Kty = "EC"
+		key.Crv = pub.Curve.Params().Name
+		key.X = b64(pub.X.FillBytes(make([]byte, size)))
+		key.Y = b64(pub.Y.FillBytes(make([]byte, size)))
+	default:
+		return set
+	}
+	set.Keys = append(set.Keys, key)
+	return set
+}
+
+// loadOrGeneratePrivateKey reads a PEM-encoded private key matching alg, or
+// generates a fresh one when no path is configured.
+func loadOrGeneratePrivateKey(alg, path string) (crypto.Signer, error) {
+	if path == "" {
+		logger.Logger.Warnf("No JWT private key configured for %s, generating an ephemeral key (tokens will not survive restarts)", alg)
+		if alg == "RS256" {
+			return rsa.GenerateKey(rand.Reader, 2048)
+		}
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWT private key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("failed to decode JWT private key: no PEM block found")
+	}
+
+	var key interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JWT private key: %w", err)
+	}
+
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		if alg != "RS256" {
+			return nil, fmt.Errorf("JWT private key is RSA but algorithm is %s", alg)
+		}
+		return k, nil
+	case *ecdsa.PrivateKey:
+		if alg != "ES256" || k.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("JWT private key is not a P-256 EC key required by %s", alg)
+		}
+		return k, nil
+	default:
+		return nil, fmt.Errorf("unsupported JWT private key type %T", key)
+	}
+}
+
+// publicKeyOf extracts the public half of a signer.
+func publicKeyOf(signer crypto.Signer) (interface{}, error) {
+	switch pub := signer.Public().(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey:
+		return pub, nil
+	default:
+		return nil, fmt.Errorf("unsupported JWT public key type %T", pub)
+	}
+}
+
+// thumbprint derives a stable key ID from the SHA-256 of the public key DER.
+func thumbprint(publicKey interface{}) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal JWT public key: %w", err)
+	}
+	sum := sha256.Sum256(der)
+	return b64(sum[:16]), nil
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -14,28 +14,88 @@ import (
 // var jwtSecret = []byte(os.Getenv("JWT_SECRET")) // Use this in production
 var jwtSecret = []byte("your-super-secret-jwt-key") // For development convenience, CHANGE THIS!
 
+// Signing state configured by InitJWT. Defaults to HS256 with jwtSecret so the
+// package keeps working if InitJWT is never called.
+var (
+	signingMethod jwt.SigningMethod = jwt.SigningMethodHS256
+	signingKey    interface{}       = jwtSecret
+	verifyKey     interface{}       = jwtSecret
+	keyID         string            // Set only for asymmetric algorithms, published in the JWKS
+)
+
 // Claims struct holds custom claims along with standard JWT claims.
 type Claims struct {
-	UserID   string `json:"user_id"`
-	Username string `json:"username"` // Keeping 'Username' in claims for display/identification
+	UserID   string   `json:"user_id"`
+	Username string   `json:"username"` // Keeping 'Username' in claims for display/identification
+	Role     string   `json:"role"`
+	Scopes   []string `json:"scopes"` // Permission scopes, so other services can authorize without a lookup
 	jwt.RegisteredClaims
 }
 
-// GenerateJWT generates a new JWT token for a given user.
-func GenerateJWT(userID, username string, expiration time.Duration) (string, error) {
+// InitJWT configures the signing algorithm and keys used for all tokens.
+// Supported algorithms are HS256 (shared secret), RS256 and ES256 (asymmetric).
+// For RS256/ES256 the private key is read from privateKeyPath (PEM); if the path
+// is empty an ephemeral key is generated, which is only suitable for development.
+func InitJWT(alg, secret, privateKeyPath string) error {
+	if alg == "" {
+		alg = "HS256"
+	}
+
+	switch alg {
+	case "HS256":
+		if secret != "" {
+			jwtSecret = []byte(secret)
+		}
+		signingMethod = jwt.SigningMethodHS256
+		signingKey = jwtSecret
+		verifyKey = jwtSecret
+		keyID = ""
+	case "RS256", "ES256":
+		privateKey, err := loadOrGeneratePrivateKey(alg, privateKeyPath)
+		if err != nil {
+			return err
+		}
+		publicKey, err := publicKeyOf(privateKey)
+		if err != nil {
+			return err
+		}
+		kid, err := thumbprint(publicKey)
+		if err != nil {
+			return err
+		}
+		signingMethod = jwt.GetSigningMethod(alg)
+		signingKey = privateKey
+		verifyKey = publicKey
+		keyID = kid
+	default:
+		return fmt.Errorf("unsupported JWT signing algorithm: %s", alg)
+	}
+
+	logger.Logger.Infof("JWT signing configured with algorithm %s", alg)
+	return nil
+}
+
+// GenerateJWT generates a new JWT token for a given user. The session ID is carried in the "jti" claim.
+func GenerateJWT(userID, username, role string, scopes []string, sessionID string, expiration time.Duration) (string, error) {
 	expirationTime := time.Now().Add(expiration)
 	claims := &Claims{
 		UserID:   userID,
 		Username: username,
+		Role:     role,
+		Scopes:   scopes,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        sessionID,
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(jwtSecret)
+	token := jwt.NewWithClaims(signingMethod, claims)
+	if keyID != "" {
+		token.Header["kid"] = keyID // Lets verifiers pick the matching key from the JWKS
+	}
+	tokenString, err := token.SignedString(signingKey)
 	if err != nil {
 		logger.Logger.Errorf("Failed to sign JWT token for user ID '%s': %v", userID, err)
 		return "", fmt.Errorf("failed to sign token: %w", err)
@@ -47,10 +107,10 @@ func GenerateJWT(userID, username string, expiration time.Duration) (string, err
 // ParseJWT parses and validates a JWT token string.
 func ParseJWT(tokenString string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		if token.Method.Alg() != signingMethod.Alg() {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return jwtSecret, nil
+		return verifyKey, nil
 	})
 
 	if err != nil {
@@ -66,4 +126,4 @@ func ParseJWT(tokenString string) (*Claims, error) {
 
 	logger.Logger.Debugf("JWT token parsed successfully for user ID: %s", claims.UserID)
 	return claims, nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,63 @@
+// services/user-service/internal/utils/logger/hook.go
+package logger
+
+import (
+	"sync/atomic"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// ErrorHook receives every entry logged at error level or above, after redaction.
+// Fields holds the entry's structured fields, including those added with With.
+type ErrorHook func(ent zapcore.Entry, fields map[string]interface{})
+
+var errorHook atomic.Pointer[ErrorHook]
+
+// SetErrorHook installs h as the error hook, e.g. to forward errors to an error tracker. Nil removes it.
+// The hook runs synchronously on the logging goroutine, so it must not block.
+func SetErrorHook(h ErrorHook) {
+	if h == nil {
+		errorHook.Store(nil)
+		return
+	}
+	errorHook.Store(&h)
+}
+
+// hookCore passes error entries to the installed ErrorHook.
+type hookCore struct {
+	fields []zapcore.Field
+}
+
+func (c *hookCore) Enabled(lvl zapcore.Level) bool {
+	return lvl >= zapcore.ErrorLevel && errorHook.Load() != nil
+}
+
+func (c *hookCore) With(fields []zapcore.Field) zapcore.Core {
+	return &hookCore{fields: append(c.fields[:len(c.fields):len(c.fields)], fields...)}
+}
+
+func (c *hookCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write is also called by the tee for entries below error level, so the level is checked again.
+func (c *hookCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	hook := errorHook.Load()
+	if hook == nil || ent.Level < zapcore.ErrorLevel {
+		return nil
+	}
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range c.fields {
+		f.AddTo(enc)
+	}
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	(*hook)(ent, enc.Fields)
+	return nil
+}
+
+func (c *hookCore) Sync() error { return nil }
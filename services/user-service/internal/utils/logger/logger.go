@@ -12,6 +12,9 @@ import (
 // Logger is a global SugaredLogger instance for convenient logging throughout the application.
 var Logger *zap.SugaredLogger
 
+// level is the logger's adjustable level, kept so it can be changed at runtime.
+var level zap.AtomicLevel
+
 // InitLogger initializes the global Zap logger based on the application environment.
 func InitLogger(env string) {
 	var config zap.Config
@@ -20,20 +23,35 @@ func InitLogger(env string) {
 		config = zap.NewProductionConfig()
 		config.Encoding = "json"
 		config.Level.SetLevel(zap.InfoLevel)
+		// Keep 1% of debug entries if debug logging is switched on at runtime
+		defaultSampling = &SamplingConfig{Levels: map[string]float64{"debug": 0.01}}
 	} else {
 		// Development configuration: console format, Debug level by default, with colors
 		config = zap.NewDevelopmentConfig()
 		config.Encoding = "console"
 		config.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder // Add colors for dev
-		config.Level.SetLevel(zap.DebugLevel) // More verbose logging in dev
+		config.Level.SetLevel(zap.DebugLevel)                               // More verbose logging in dev
 	}
 
 	// Direct output to standard streams
 	config.OutputPaths = []string{"stdout"}
 	config.ErrorOutputPaths = []string{"stderr"}
 
-	// Build the logger instance
-	l, err := config.Build()
+	level = config.Level
+	sampling.Store(defaultSampling)
+
+	// Sensitive data is masked unless LOG_REDACTION=off, which is ignored in production
+	redact := env == "production" || os.Getenv("LOG_REDACTION") != "off"
+
+	// Build the logger instance, with sampling and redaction applied in front of the output
+	// core and the error hook (so error trackers only ever see redacted data)
+	l, err := config.Build(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		core = zapcore.NewTee(core, &hookCore{})
+		if redact {
+			core = &redactingCore{core}
+		}
+		return &samplingCore{core}
+	}))
 	if err != nil {
 		panic(fmt.Sprintf("failed to build zap logger: %v", err))
 	}
@@ -51,4 +69,20 @@ func InitLogger(env string) {
 			fmt.Fprintf(os.Stderr, "failed to sync logger: %v\n", err)
 		}
 	}()
-}
\ No newline at end of file
+}
+
+// SetLevel changes the minimum log level of the running logger (e.g. "debug", "info", "warn").
+func SetLevel(lvl string) error {
+	parsed, err := zapcore.ParseLevel(lvl)
+	if err != nil {
+		return fmt.Errorf("invalid log level %q: %w", lvl, err)
+	}
+	level.SetLevel(parsed)
+	return nil
+}
+
+// ValidLevel reports whether lvl is a log level understood by SetLevel.
+func ValidLevel(lvl string) bool {
+	_, err := zapcore.ParseLevel(lvl)
+	return err == nil
+}
@@ -0,0 +1,154 @@
+// services/user-service/internal/utils/logger/redact.go
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// redacted replaces the value of a sensitive structured field.
+const redacted = "[REDACTED]"
+
+// sensitiveKeys are structured field names whose values are always replaced, matched
+// case-insensitively as a substring so "new_password" and "jwt_token" are covered too.
+var sensitiveKeys = []string{
+	"password", "token", "secret", "authorization", "cookie", "email",
+	"weight", "height", "heart_rate", "blood_pressure", "glucose", "bmi", "calories", "steps",
+}
+
+// redactPatterns mask sensitive values inside free text (formatted messages and string fields).
+var redactPatterns = []struct {
+	re   *regexp.Regexp
+	repl string
+}{
+	// JWTs: three base64url segments, the first always starting with eyJ ('{"')
+	{regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`), "[token]"},
+	{regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9._~+/=-]+`), "Bearer [token]"},
+	// Reset tokens, hashes, and other long opaque strings (UUIDs are shorter and stay readable)
+	{regexp.MustCompile(`\b[A-Za-z0-9_-]{40,}\b`), "[token]"},
+	// Emails keep their domain, which is useful for debugging delivery problems
+	{regexp.MustCompile(`[A-Za-z0-9._%+-]+@([A-Za-z0-9.-]+\.[A-Za-z]{2,})`), "***@$1"},
+	// Health measurements written as "name: value" or "name=value"
+	{regexp.MustCompile(`(?i)\b(weight|height|heart[_ ]?rate|blood[_ ]?pressure|glucose|bmi|calories|steps)(\s*[:=]\s*)[0-9][0-9./]*`), "$1$2[REDACTED]"},
+}
+
+// Redact masks emails, tokens, and health values in s.
+func Redact(s string) string {
+	for _, p := range redactPatterns {
+		s = p.re.ReplaceAllString(s, p.repl)
+	}
+	return s
+}
+
+func sensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, k := range sensitiveKeys {
+		if strings.Contains(key, k) {
+			return true
+		}
+	}
+	return false
+}
+
+func redactFields(fields []zapcore.Field) []zapcore.Field {
+	out := make([]zapcore.Field, len(fields))
+	for i, f := range fields {
+		out[i] = redactField(f)
+	}
+	return out
+}
+
+// redactField masks the value of f. Values of structured types (zap.Any of a struct, map, or slice,
+// zap.Reflect, zap.Object, zap.Array) are encoded to plain maps and lists first, so their keys are
+// checked and their strings masked as if they had been logged field by field.
+func redactField(f zapcore.Field) zapcore.Field {
+	if sensitiveKey(f.Key) {
+		return zapcore.Field{Key: f.Key, Type: zapcore.StringType, String: redacted}
+	}
+	switch f.Type {
+	case zapcore.StringType:
+		f.String = Redact(f.String)
+	case zapcore.ByteStringType:
+		if b, ok := f.Interface.([]byte); ok {
+			f.Interface = []byte(Redact(string(b)))
+		}
+	case zapcore.StringerType, zapcore.ErrorType:
+		// Encoded as zap does, which reports a panicking String or Error method under key+"Error"
+		enc := zapcore.NewMapObjectEncoder()
+		f.AddTo(enc)
+		if s, ok := enc.Fields[f.Key].(string); ok {
+			return zapcore.Field{Key: f.Key, Type: zapcore.StringType, String: Redact(s)}
+		}
+		s, _ := enc.Fields[f.Key+"Error"].(string)
+		return zapcore.Field{Key: f.Key + "Error", Type: zapcore.StringType, String: Redact(s)}
+	case zapcore.ReflectType:
+		doc, err := json.Marshal(f.Interface)
+		var v any
+		if err == nil {
+			dec := json.NewDecoder(bytes.NewReader(doc))
+			dec.UseNumber()
+			err = dec.Decode(&v)
+		}
+		if err != nil {
+			return zapcore.Field{Key: f.Key, Type: zapcore.StringType, String: redacted}
+		}
+		return zapcore.Field{Key: f.Key, Type: zapcore.ReflectType, Interface: redactValue(v)}
+	case zapcore.ArrayMarshalerType, zapcore.ObjectMarshalerType:
+		enc := zapcore.NewMapObjectEncoder()
+		f.AddTo(enc)
+		return zapcore.Field{Key: f.Key, Type: zapcore.ReflectType, Interface: redactValue(enc.Fields[f.Key])}
+	}
+	return f
+}
+
+// redactValue masks a decoded structured value: the values of sensitive keys at any depth, and
+// emails, tokens, and health values in every string.
+func redactValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for key, value := range v {
+			if sensitiveKey(key) {
+				out[key] = redacted
+			} else {
+				out[key] = redactValue(value)
+			}
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, value := range v {
+			out[i] = redactValue(value)
+		}
+		return out
+	case string:
+		return Redact(v)
+	}
+	return v
+}
+
+// redactingCore masks sensitive data in messages and fields before they are encoded.
+type redactingCore struct {
+	zapcore.Core
+}
+
+func (c *redactingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactingCore{c.Core.With(redactFields(fields))}
+}
+
+// Check registers this core rather than the wrapped one so every write goes through Write below.
+func (c *redactingCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *redactingCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	ent.Message = Redact(ent.Message)
+	return c.Core.Write(ent, redactFields(fields))
+}
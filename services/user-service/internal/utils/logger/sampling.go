@@ -0,0 +1,111 @@
+// services/user-service/internal/utils/logger/sampling.go
+package logger
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// SamplingConfig controls which fraction of low-severity log entries is written.
+// Rates are between 0 (drop all) and 1 (keep all). Warnings and errors are never sampled.
+type SamplingConfig struct {
+	Levels  map[string]float64 `json:"levels"`  // Level name ("debug", "info") -> rate; missing levels are always logged
+	Classes map[string]float64 `json:"classes"` // Message prefix -> rate; the longest matching prefix overrides the level rate
+}
+
+// Validate checks that every level name is sampleable and every rate is within [0, 1].
+func (c *SamplingConfig) Validate() error {
+	for lvl, rate := range c.Levels {
+		if lvl != "debug" && lvl != "info" {
+			return fmt.Errorf("sampling is only supported for debug and info, not %q", lvl)
+		}
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("sampling rate for %s must be between 0 and 1", lvl)
+		}
+	}
+	for class, rate := range c.Classes {
+		if class == "" {
+			return fmt.Errorf("sampling class must not be empty")
+		}
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("sampling rate for class %q must be between 0 and 1", class)
+		}
+	}
+	return nil
+}
+
+var (
+	sampling        atomic.Pointer[SamplingConfig]
+	defaultSampling *SamplingConfig // Set by InitLogger from the environment
+	sampleCounters  sync.Map        // Class key -> *atomic.Uint64
+)
+
+// SetSampling replaces the active sampling config of the running logger.
+func SetSampling(cfg SamplingConfig) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	sampling.Store(&cfg)
+	return nil
+}
+
+// ResetSampling restores the sampling config chosen at startup for the environment.
+func ResetSampling() {
+	sampling.Store(defaultSampling)
+}
+
+// sampled decides whether an entry is written. Sampling is deterministic per class:
+// a rate of 0.01 keeps the first entry and then every hundredth.
+func sampled(ent zapcore.Entry) bool {
+	if ent.Level >= zapcore.WarnLevel {
+		return true
+	}
+	cfg := sampling.Load()
+	if cfg == nil {
+		return true
+	}
+
+	key := "level:" + ent.Level.String()
+	rate, ok := cfg.Levels[ent.Level.String()]
+	if !ok {
+		rate = 1
+	}
+	longest := -1
+	for class, r := range cfg.Classes {
+		if len(class) > longest && strings.HasPrefix(ent.Message, class) {
+			key, rate, longest = "class:"+class, r, len(class)
+		}
+	}
+
+	if rate >= 1 {
+		return true
+	}
+	if rate <= 0 {
+		return false
+	}
+	counter, _ := sampleCounters.LoadOrStore(key, new(atomic.Uint64))
+	n := counter.(*atomic.Uint64).Add(1)
+	return (n-1)%uint64(math.Round(1/rate)) == 0
+}
+
+// samplingCore drops entries that are not selected by the active sampling config.
+type samplingCore struct {
+	zapcore.Core
+}
+
+func (c *samplingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &samplingCore{c.Core.With(fields)}
+}
+
+func (c *samplingCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	// Check the level first so disabled entries don't advance the sample counters.
+	if !c.Enabled(ent.Level) || !sampled(ent) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
@@ -0,0 +1,320 @@
+// services/user-service/internal/utils/measure/measure.go
+
+// Package measure parses human-style measurement input, such as 5'11", 72,5 kg, or 1:45:30, into
+// canonical units: centimetres, kilograms, and time.Duration. Decimal separators follow the
+// request's locale when the input alone is ambiguous. It also converts canonical values into the
+// unit system a user prefers, for display; values are always stored in metric units.
+package measure
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	cmPerInch = 2.54
+	kgPerLb   = 0.45359237
+	kmPerMile = 1.609344
+)
+
+// Unit systems a user can prefer.
+const (
+	Metric   = "metric"
+	Imperial = "imperial"
+)
+
+// ValidSystem reports whether system is a unit system users can prefer.
+func ValidSystem(system string) bool {
+	return system == Metric || system == Imperial
+}
+
+// Quantity is a value in a display unit, such as 70.9 in.
+type Quantity struct {
+	Value float64 `json:"value"`
+	Unit  string  `json:"unit"`
+}
+
+// KgToLb converts kilograms to pounds.
+func KgToLb(kg float64) float64 { return kg / kgPerLb }
+
+// LbToKg converts pounds to kilograms.
+func LbToKg(lb float64) float64 { return lb * kgPerLb }
+
+// CmToIn converts centimetres to inches.
+func CmToIn(cm float64) float64 { return cm / cmPerInch }
+
+// InToCm converts inches to centimetres.
+func InToCm(in float64) float64 { return in * cmPerInch }
+
+// KmToMi converts kilometres to miles.
+func KmToMi(km float64) float64 { return km / kmPerMile }
+
+// MiToKm converts miles to kilometres.
+func MiToKm(mi float64) float64 { return mi * kmPerMile }
+
+// Height expresses a height in centimetres in the unit system: centimetres or inches, to 0.1.
+// Unknown systems get metric.
+func Height(cm float64, system string) Quantity {
+	if system == Imperial {
+		return Quantity{Value: round(CmToIn(cm), 1), Unit: "in"}
+	}
+	return Quantity{Value: round(cm, 1), Unit: "cm"}
+}
+
+// Weight expresses a weight in kilograms in the unit system: kilograms or pounds, to 0.1.
+func Weight(kg float64, system string) Quantity {
+	if system == Imperial {
+		return Quantity{Value: round(KgToLb(kg), 1), Unit: "lb"}
+	}
+	return Quantity{Value: round(kg, 1), Unit: "kg"}
+}
+
+// Distance expresses a distance in kilometres in the unit system: kilometres or miles, to 0.01.
+func Distance(km float64, system string) Quantity {
+	if system == Imperial {
+		return Quantity{Value: round(KmToMi(km), 2), Unit: "mi"}
+	}
+	return Quantity{Value: round(km, 2), Unit: "km"}
+}
+
+// commaDecimalLanguages are the languages writing decimals with a comma, e.g. "72,5".
+var commaDecimalLanguages = map[string]bool{
+	"bg": true, "ca": true, "cs": true, "da": true, "de": true, "el": true, "es": true, "et": true,
+	"fi": true, "fr": true, "hr": true, "hu": true, "id": true, "it": true, "lt": true, "lv": true,
+	"nb": true, "nl": true, "nn": true, "no": true, "pl": true, "pt": true, "ro": true, "ru": true,
+	"sk": true, "sl": true, "sr": true, "sv": true, "tr": true, "uk": true, "vi": true,
+}
+
+// commaDecimal reports whether the locale (a BCP 47 tag such as "de-AT") writes decimals with a comma.
+func commaDecimal(locale string) bool {
+	lang, _, _ := strings.Cut(strings.ToLower(strings.ReplaceAll(locale, "_", "-")), "-")
+	return commaDecimalLanguages[lang]
+}
+
+// ParseNumber parses a decimal written with either separator. With both, the last one is the decimal
+// separator; a separator used more than once groups thousands. A single separator followed by exactly
+// three digits ("1,500" or "1.500") is read the way the locale writes numbers.
+func ParseNumber(s, locale string) (float64, error) {
+	s = strings.TrimSpace(s)
+	commas, dots := strings.Count(s, ","), strings.Count(s, ".")
+	lastComma, lastDot := strings.LastIndex(s, ","), strings.LastIndex(s, ".")
+	decimal := "" // The decimal separator, if any
+	switch {
+	case commas > 0 && dots > 0:
+		decimal = ","
+		if lastDot > lastComma {
+			decimal = "."
+		}
+	case commas == 1:
+		decimal = ","
+		if len(s)-lastComma-1 == 3 && !commaDecimal(locale) {
+			decimal = ""
+		}
+	case dots == 1:
+		decimal = "."
+		if len(s)-lastDot-1 == 3 && commaDecimal(locale) {
+			decimal = ""
+		}
+	}
+	var b strings.Builder
+	for i, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case string(r) == decimal && (i == lastComma || i == lastDot):
+			b.WriteByte('.')
+		case r == ',' || r == '.':
+			// Grouping separator
+		default:
+			return 0, fmt.Errorf("invalid number %q", s)
+		}
+	}
+	n, err := strconv.ParseFloat(b.String(), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", s)
+	}
+	return n, nil
+}
+
+// quantity is one number and the unit written after it, e.g. "5" and "ft" in "5 ft 11 in".
+type quantity struct {
+	value float64
+	unit  string // Lower case; "" when none was given
+}
+
+// quantities splits input such as "5'11\"" or "11 st 4 lb" into numbers with their units.
+// Typographic quotes are folded into ' and ".
+func quantities(s, locale string) ([]quantity, error) {
+	s = strings.NewReplacer("′", "'", "’", "'", "″", `"`, "”", `"`, "''", `"`).Replace(strings.TrimSpace(s))
+	var out []quantity
+	runes := []rune(s)
+	for i := 0; i < len(runes); {
+		if unicode.IsSpace(runes[i]) {
+			i++
+			continue
+		}
+		start := i
+		for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.' || runes[i] == ',') {
+			i++
+		}
+		if i == start {
+			return nil, fmt.Errorf("expected a number at %q", string(runes[start:]))
+		}
+		value, err := ParseNumber(string(runes[start:i]), locale)
+		if err != nil {
+			return nil, err
+		}
+		for i < len(runes) && unicode.IsSpace(runes[i]) {
+			i++
+		}
+		start = i
+		for i < len(runes) && (unicode.IsLetter(runes[i]) || runes[i] == '\'' || runes[i] == '"') {
+			i++
+		}
+		out = append(out, quantity{value: value, unit: strings.ToLower(string(runes[start:i]))})
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("no value given")
+	}
+	return out, nil
+}
+
+// ParseHeight parses a height such as 5'11", 5 ft 11 in, 71 in, 180 cm, or 1,80 m into centimetres.
+// A number without a unit is in centimetres.
+func ParseHeight(s, locale string) (float64, error) {
+	qs, err := quantities(s, locale)
+	if err != nil {
+		return 0, fmt.Errorf("invalid height %q: %w", s, err)
+	}
+	var cm float64
+	for i, q := range qs {
+		switch q.unit {
+		case "ft", "feet", "foot", "'":
+			if i != 0 {
+				return 0, fmt.Errorf("invalid height %q: feet must come first", s)
+			}
+			cm += InToCm(q.value * 12)
+		case "in", "inch", "inches", `"`:
+			cm += InToCm(q.value)
+		case "cm":
+			cm += q.value
+		case "m":
+			cm += q.value * 100
+		case "mm":
+			cm += q.value / 10
+		case "":
+			if i > 0 && isFeet(qs[i-1].unit) {
+				cm += InToCm(q.value) // 5'11 means 5 ft 11 in
+			} else if len(qs) == 1 {
+				cm += q.value
+			} else {
+				return 0, fmt.Errorf("invalid height %q: missing unit", s)
+			}
+		default:
+			return 0, fmt.Errorf("invalid height %q: unknown unit %q", s, q.unit)
+		}
+	}
+	return round(cm, 1), nil
+}
+
+func isFeet(unit string) bool {
+	return unit == "ft" || unit == "feet" || unit == "foot" || unit == "'"
+}
+
+// ParseWeight parses a weight such as 72,5 kg, 160 lb, or 11 st 4 lb into kilograms.
+// A number without a unit is in kilograms.
+func ParseWeight(s, locale string) (float64, error) {
+	qs, err := quantities(s, locale)
+	if err != nil {
+		return 0, fmt.Errorf("invalid weight %q: %w", s, err)
+	}
+	var kg float64
+	for i, q := range qs {
+		switch q.unit {
+		case "kg", "kgs", "kilo", "kilos", "kilogram", "kilograms":
+			kg += q.value
+		case "g", "gram", "grams":
+			kg += q.value / 1000
+		case "lb", "lbs", "pound", "pounds":
+			kg += LbToKg(q.value)
+		case "st", "stone", "stones":
+			kg += LbToKg(q.value * 14)
+		case "":
+			if i > 0 && (qs[i-1].unit == "st" || qs[i-1].unit == "stone" || qs[i-1].unit == "stones") {
+				kg += LbToKg(q.value) // 11 st 4 means 11 st 4 lb
+			} else if len(qs) == 1 {
+				kg += q.value
+			} else {
+				return 0, fmt.Errorf("invalid weight %q: missing unit", s)
+			}
+		default:
+			return 0, fmt.Errorf("invalid weight %q: unknown unit %q", s, q.unit)
+		}
+	}
+	return round(kg, 2), nil
+}
+
+// ParseDuration parses a duration such as 1:45:30, 45:30, 1h45m, 1 h 30 min, or 90 min. A number
+// without a unit is in bareUnit. Clock notation with three parts is h:mm:ss; with two parts the
+// last one is in bareUnit, so 1:30 is 1 h 30 min when bareUnit is a minute and 1 min 30 s when it is
+// a second.
+func ParseDuration(s, locale string, bareUnit time.Duration) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if strings.Contains(s, ":") {
+		return parseClock(s, bareUnit)
+	}
+	qs, err := quantities(s, locale)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q: %w", s, err)
+	}
+	var d float64
+	for _, q := range qs {
+		switch q.unit {
+		case "h", "hr", "hrs", "hour", "hours", "std":
+			d += q.value * float64(time.Hour)
+		case "m", "min", "mins", "minute", "minutes", "'":
+			d += q.value * float64(time.Minute)
+		case "s", "sec", "secs", "second", "seconds", `"`:
+			d += q.value * float64(time.Second)
+		case "":
+			if len(qs) != 1 {
+				return 0, fmt.Errorf("invalid duration %q: missing unit", s)
+			}
+			d += q.value * float64(bareUnit)
+		default:
+			return 0, fmt.Errorf("invalid duration %q: unknown unit %q", s, q.unit)
+		}
+	}
+	return time.Duration(math.Round(d)), nil
+}
+
+// parseClock parses h:mm:ss, or two parts whose last one is in bareUnit.
+func parseClock(s string, bareUnit time.Duration) (time.Duration, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) > 3 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	units := []time.Duration{time.Hour, time.Minute, time.Second}[3-len(parts):]
+	if len(parts) == 2 {
+		units = []time.Duration{bareUnit * 60, bareUnit}
+	}
+	var d time.Duration
+	for i, part := range parts {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || n < 0 || (i > 0 && (n > 59 || len(strings.TrimSpace(part)) != 2)) {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		d += time.Duration(n) * units[i]
+	}
+	return d, nil
+}
+
+// round rounds to the given number of decimal places.
+func round(v float64, places int) float64 {
+	p := math.Pow(10, float64(places))
+	return math.Round(v*p) / p
+}
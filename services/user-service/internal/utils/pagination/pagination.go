@@ -0,0 +1,87 @@
+// services/user-service/internal/utils/pagination/pagination.go
+
+// Package pagination issues and reads the cursor tokens that list endpoints page with. A cursor holds
+// the position of the last item of a page (its timestamp, and its ID to order items with the same
+// timestamp) and is signed with HMAC-SHA256 over the list it was issued for, so clients can neither
+// forge a position nor reuse a cursor with other filters. Because positions are keys rather than
+// offsets, pages stay stable while items are inserted.
+package pagination
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// ErrInvalidCursor is returned for a cursor this service did not issue for the same list and filters.
+var ErrInvalidCursor = errors.New("pagination: invalid cursor")
+
+// minSecretLength is the shortest PAGINATION_SECRET accepted, in bytes.
+const minSecretLength = 32
+
+// key signs cursors; Init sets it.
+var key []byte
+
+// Init sets the secret cursors are signed with. Without one a random key is generated, so cursors
+// stop working when the service restarts and are rejected by other replicas.
+func Init(secret string) error {
+	if secret == "" {
+		key = make([]byte, 32)
+		rand.Read(key)
+		logger.Logger.Warn("PAGINATION_SECRET is not set; cursors are signed with a random key and break on restart or across replicas")
+		return nil
+	}
+	if len(secret) < minSecretLength {
+		return errors.New("pagination: the secret must be at least 32 bytes")
+	}
+	key = []byte(secret)
+	return nil
+}
+
+// Position is where a page ended: the sort timestamp and ID of its last item.
+type Position struct {
+	At time.Time `json:"t"`
+	ID uuid.UUID `json:"i"`
+}
+
+// Encode returns the cursor resuming a list after pos. scope names the list and its filters, such as
+// the request path and query without the cursor; the cursor is only accepted with the same scope.
+func Encode(scope string, pos Position) string {
+	payload, _ := json.Marshal(pos)
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(sign(scope, encoded))
+}
+
+// Decode returns the position of a cursor issued by Encode with the same scope, or ErrInvalidCursor.
+func Decode(scope, cursor string) (Position, error) {
+	var pos Position
+	encoded, signature, ok := strings.Cut(cursor, ".")
+	if !ok {
+		return pos, ErrInvalidCursor
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, sign(scope, encoded)) {
+		return pos, ErrInvalidCursor
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || json.Unmarshal(payload, &pos) != nil || pos.At.IsZero() {
+		return pos, ErrInvalidCursor
+	}
+	return pos, nil
+}
+
+func sign(scope, encoded string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(scope))
+	mac.Write([]byte{0})
+	mac.Write([]byte(encoded))
+	return mac.Sum(nil)
+}
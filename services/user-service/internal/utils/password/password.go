@@ -0,0 +1,196 @@
+// services/user-service/internal/utils/password/password.go
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"health-tracker-project/services/user-service/internal/utils/logger" // Import the logger
+)
+
+// Supported hashing algorithms.
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+)
+
+// Hasher hashes passwords with one algorithm and parameter set.
+type Hasher interface {
+	// Hash returns an encoded hash that includes the algorithm and its parameters.
+	Hash(password string) (string, error)
+	// Verify reports whether password matches an encoded hash produced by this algorithm.
+	Verify(encoded, password string) (bool, error)
+	// Handles reports whether an encoded hash was produced by this algorithm.
+	Handles(encoded string) bool
+	// NeedsRehash reports whether an encoded hash of this algorithm uses weaker parameters than the hasher.
+	NeedsRehash(encoded string) bool
+}
+
+// Config selects the hasher for new passwords.
+type Config struct {
+	Algorithm  string // bcrypt (default) or argon2id
+	BcryptCost int    // 0 means bcrypt.DefaultCost
+	Argon2     Argon2idHasher
+}
+
+// current hashes new passwords; all hashers are kept so existing hashes of any algorithm still verify.
+var (
+	current Hasher = &BcryptHasher{Cost: bcrypt.DefaultCost}
+	hashers        = []Hasher{&BcryptHasher{Cost: bcrypt.DefaultCost}, DefaultArgon2idHasher()}
+)
+
+// Init configures the hasher used for new passwords. It must be called before serving requests.
+func Init(cfg Config) error {
+	if cfg.BcryptCost == 0 {
+		cfg.BcryptCost = bcrypt.DefaultCost
+	}
+	if cfg.BcryptCost < bcrypt.MinCost || cfg.BcryptCost > bcrypt.MaxCost {
+		return fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+	bcryptHasher := &BcryptHasher{Cost: cfg.BcryptCost}
+	argon := cfg.Argon2
+	if argon.Memory == 0 || argon.Iterations == 0 || argon.Parallelism == 0 {
+		argon = *DefaultArgon2idHasher()
+	}
+	argonHasher := &argon
+
+	switch cfg.Algorithm {
+	case "", AlgorithmBcrypt:
+		current = bcryptHasher
+		logger.Logger.Infof("Password hashing configured with bcrypt (cost %d)", bcryptHasher.Cost)
+	case AlgorithmArgon2id:
+		current = argonHasher
+		logger.Logger.Infof("Password hashing configured with argon2id (m=%d, t=%d, p=%d)", argon.Memory, argon.Iterations, argon.Parallelism)
+	default:
+		return fmt.Errorf("unsupported password hashing algorithm: %s", cfg.Algorithm)
+	}
+	hashers = []Hasher{bcryptHasher, argonHasher}
+	return nil
+}
+
+// Hash hashes a new password with the configured hasher.
+func Hash(password string) (string, error) {
+	return current.Hash(password)
+}
+
+// Verify reports whether password matches encoded, whichever supported algorithm produced it.
+func Verify(encoded, password string) bool {
+	for _, h := range hashers {
+		if h.Handles(encoded) {
+			ok, err := h.Verify(encoded, password)
+			if err != nil {
+				logger.Logger.Warnf("Password hash could not be verified: %v", err)
+			}
+			return ok
+		}
+	}
+	return false
+}
+
+// NeedsRehash reports whether encoded should be replaced by a hash from the configured
+// hasher, because it uses another algorithm or weaker parameters.
+func NeedsRehash(encoded string) bool {
+	return !current.Handles(encoded) || current.NeedsRehash(encoded)
+}
+
+// BcryptHasher hashes passwords with bcrypt.
+type BcryptHasher struct {
+	Cost int
+}
+
+func (h *BcryptHasher) Hash(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), h.Cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
+func (h *BcryptHasher) Verify(encoded, password string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if err == bcrypt.ErrMismatchedHashAndPassword {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (h *BcryptHasher) Handles(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
+
+func (h *BcryptHasher) NeedsRehash(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost < h.Cost
+}
+
+// Argon2idHasher hashes passwords with argon2id, encoded in the PHC string format
+// $argon2id$v=19$m=<memory>,t=<iterations>,p=<parallelism>$<salt>$<key>.
+type Argon2idHasher struct {
+	Memory      uint32 // KiB
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2idHasher returns the OWASP-recommended baseline parameters.
+func DefaultArgon2idHasher() *Argon2idHasher {
+	return &Argon2idHasher{Memory: 64 * 1024, Iterations: 3, Parallelism: 2, SaltLength: 16, KeyLength: 32}
+}
+
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, max(h.SaltLength, 16))
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, h.Iterations, h.Memory, h.Parallelism, max(h.KeyLength, 32))
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, h.Memory, h.Iterations, h.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (h *Argon2idHasher) Verify(encoded, password string) (bool, error) {
+	params, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return false, err
+	}
+	computed := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+	return subtle.ConstantTimeCompare(computed, key) == 1, nil
+}
+
+func (h *Argon2idHasher) Handles(encoded string) bool {
+	return strings.HasPrefix(encoded, "$argon2id$")
+}
+
+func (h *Argon2idHasher) NeedsRehash(encoded string) bool {
+	params, _, _, err := decodeArgon2id(encoded)
+	return err != nil || params.Memory < h.Memory || params.Iterations < h.Iterations || params.Parallelism < h.Parallelism
+}
+
+func decodeArgon2id(encoded string) (*Argon2idHasher, []byte, []byte, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return nil, nil, nil, fmt.Errorf("invalid argon2id hash format")
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, nil, nil, fmt.Errorf("unsupported argon2id version")
+	}
+	params := &Argon2idHasher{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid argon2id parameters: %w", err)
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid argon2id salt: %w", err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return nil, nil, nil, fmt.Errorf("invalid argon2id key")
+	}
+	return params, salt, key, nil
+}
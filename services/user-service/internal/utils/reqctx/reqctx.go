@@ -0,0 +1,179 @@
+// services/user-service/internal/utils/reqctx/reqctx.go
+package reqctx
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Standard headers propagated between Pulse services.
+const (
+	HeaderRequestID    = "X-Request-ID"
+	HeaderUserID       = "X-User-ID"
+	HeaderOrgID        = "X-Org-ID"
+	HeaderFeatureFlags = "X-Feature-Flags" // Comma-separated overrides, e.g. "new-onboarding=on,beta-export=off"
+	HeaderLocale       = "X-Locale"
+)
+
+// Values is the per-request context shared across service boundaries.
+// UserID and OrgID are informational for logging and downstream calls, and are never read from an
+// incoming request: UserID is set with WithUserID once the JWT has been verified. Authorization must
+// always be based on the verified JWT.
+type Values struct {
+	RequestID    string
+	UserID       string
+	OrgID        string
+	FeatureFlags map[string]bool
+	Locale       string
+}
+
+type contextKey struct{}
+
+// WithValues returns a copy of ctx carrying v.
+func WithValues(ctx context.Context, v Values) context.Context {
+	return context.WithValue(ctx, contextKey{}, v)
+}
+
+// FromContext returns the values stored in ctx, or zero Values if none are set.
+func FromContext(ctx context.Context) Values {
+	v, _ := ctx.Value(contextKey{}).(Values)
+	return v
+}
+
+// WithUserID returns a copy of ctx with the user ID replaced, e.g. once the JWT has been verified.
+func WithUserID(ctx context.Context, userID string) context.Context {
+	v := FromContext(ctx)
+	v.UserID = userID
+	return WithValues(ctx, v)
+}
+
+// RequestID returns the request ID stored in ctx.
+func RequestID(ctx context.Context) string {
+	return FromContext(ctx).RequestID
+}
+
+// FeatureOverride reports whether the request overrides a feature flag, and to what.
+func FeatureOverride(ctx context.Context, name string) (enabled, ok bool) {
+	enabled, ok = FromContext(ctx).FeatureFlags[name]
+	return enabled, ok
+}
+
+// Extract reads the standard headers from an incoming request. A request ID is generated if absent
+// or unusable. X-User-ID and X-Org-ID are not read: any client can send them, so they are stripped
+// at the edge (see Strip) and the user ID comes from the verified JWT.
+// Feature-flag overrides are only honoured when trustOverrides is true.
+func Extract(r *http.Request, trustOverrides bool) Values {
+	v := Values{
+		RequestID: r.Header.Get(HeaderRequestID),
+		Locale:    r.Header.Get(HeaderLocale),
+	}
+	if !validRequestID(v.RequestID) {
+		v.RequestID = uuid.NewString()
+	}
+	if v.Locale == "" {
+		// Fall back to the first Accept-Language tag, e.g. "en-GB,en;q=0.9" -> "en-GB".
+		lang, _, _ := strings.Cut(r.Header.Get("Accept-Language"), ",")
+		lang, _, _ = strings.Cut(lang, ";")
+		v.Locale = strings.TrimSpace(lang)
+	}
+	if trustOverrides {
+		v.FeatureFlags = ParseFeatureFlags(r.Header.Get(HeaderFeatureFlags))
+	}
+	return v
+}
+
+// Strip returns r without the identity headers, so nothing behind the edge mistakes a client's claim
+// for a verified identity. r itself is left as it is.
+func Strip(r *http.Request) *http.Request {
+	if r.Header.Get(HeaderUserID) == "" && r.Header.Get(HeaderOrgID) == "" {
+		return r
+	}
+	stripped := *r
+	stripped.Header = r.Header.Clone()
+	stripped.Header.Del(HeaderUserID)
+	stripped.Header.Del(HeaderOrgID)
+	return &stripped
+}
+
+// validRequestID reports whether a request ID from a caller can be kept: up to 128 printable ASCII
+// characters without spaces, so it is safe to echo in headers and to write to logs.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseFeatureFlags parses "a=on,b=off,c" into a map; a bare name means on.
+func ParseFeatureFlags(header string) map[string]bool {
+	flags := map[string]bool{}
+	for _, part := range strings.Split(header, ",") {
+		name, value, hasValue := strings.Cut(strings.TrimSpace(part), "=")
+		if name == "" {
+			continue
+		}
+		switch strings.ToLower(value) {
+		case "off", "false", "0":
+			flags[name] = false
+		default:
+			flags[name] = !hasValue || value != ""
+		}
+	}
+	return flags
+}
+
+// Inject writes the values from ctx onto an outgoing request.
+func Inject(ctx context.Context, r *http.Request) {
+	v := FromContext(ctx)
+	set := func(key, value string) {
+		if value != "" && r.Header.Get(key) == "" {
+			r.Header.Set(key, value)
+		}
+	}
+	set(HeaderRequestID, v.RequestID)
+	set(HeaderUserID, v.UserID)
+	set(HeaderOrgID, v.OrgID)
+	set(HeaderLocale, v.Locale)
+	if len(v.FeatureFlags) > 0 {
+		parts := make([]string, 0, len(v.FeatureFlags))
+		for name, on := range v.FeatureFlags {
+			state := "off"
+			if on {
+				state = "on"
+			}
+			parts = append(parts, name+"="+state)
+		}
+		set(HeaderFeatureFlags, strings.Join(parts, ","))
+	}
+}
+
+// Transport is an http.RoundTripper that propagates the standard headers from the request context.
+// Use it only for calls to other Pulse services, never to third parties.
+type Transport struct {
+	Base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	r = r.Clone(r.Context()) // RoundTrippers must not modify the caller's request
+	Inject(r.Context(), r)
+	return base.RoundTrip(r)
+}
+
+// NewClient returns an HTTP client for internal service-to-service calls.
+func NewClient(timeout time.Duration) *http.Client {
+	return &http.Client{Timeout: timeout, Transport: &Transport{}}
+}
@@ -0,0 +1,222 @@
+// services/user-service/internal/utils/schema/schema.go
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Schema is the subset of OpenAPI 3 schema objects used by this service's spec.
+type Schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Format               string             `json:"format"`
+	Nullable             bool               `json:"nullable"`
+	Enum                 []string           `json:"enum"`
+	Required             []string           `json:"required"`
+	Properties           map[string]*Schema `json:"properties"`
+	Items                *Schema            `json:"items"`
+	AdditionalProperties json.RawMessage    `json:"additionalProperties"` // false, or a schema for map values
+}
+
+type mediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type response struct {
+	Content map[string]mediaType `json:"content"`
+}
+
+type operation struct {
+	Responses map[string]response `json:"responses"`
+}
+
+// Document is a parsed OpenAPI document.
+type Document struct {
+	Paths      map[string]map[string]operation `json:"paths"`
+	Components struct {
+		Schemas map[string]*Schema `json:"schemas"`
+	} `json:"components"`
+}
+
+// Parse decodes an OpenAPI JSON document.
+func Parse(data []byte) (*Document, error) {
+	var doc Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI document: %w", err)
+	}
+	return &doc, nil
+}
+
+// ValidateResponse checks a JSON response body against the schema documented for
+// method, path, and status. Error statuses an operation does not document, including those of
+// undocumented paths, are checked against the shared ErrorResponse schema, if the document has one.
+// It returns one message per mismatch; nil means the response conforms.
+func (d *Document) ValidateResponse(method, path string, status int, body []byte) []string {
+	op, ok := d.findOperation(method, path)
+	resp, documented := op.Responses[strconv.Itoa(status)]
+	if !documented && status >= 400 && d.Components.Schemas["ErrorResponse"] != nil {
+		resp = response{Content: map[string]mediaType{"application/json": {Schema: &Schema{Ref: "#/components/schemas/ErrorResponse"}}}}
+	} else if !ok {
+		return []string{fmt.Sprintf("%s %s is not documented", method, path)}
+	} else if !documented {
+		return []string{fmt.Sprintf("status %d is not documented for %s %s", status, method, path)}
+	}
+	media, ok := resp.Content["application/json"]
+	if !ok || media.Schema == nil {
+		if len(bytes.TrimSpace(body)) > 0 {
+			return []string{fmt.Sprintf("%s %s %d returned a JSON body but none is documented", method, path, status)}
+		}
+		return nil
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return []string{fmt.Sprintf("response body is not valid JSON: %v", err)}
+	}
+	var problems []string
+	d.validate(media.Schema, value, "$", &problems)
+	return problems
+}
+
+// findOperation matches a request path against the documented path templates.
+// Literal paths win over templated ones (e.g. /users/by-email over /users/{id}).
+func (d *Document) findOperation(method, path string) (operation, bool) {
+	method = strings.ToLower(method)
+	if ops, ok := d.Paths[path]; ok {
+		op, ok := ops[method]
+		return op, ok
+	}
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for template, ops := range d.Paths {
+		if !strings.Contains(template, "{") {
+			continue
+		}
+		parts := strings.Split(strings.Trim(template, "/"), "/")
+		if len(parts) != len(segments) {
+			continue
+		}
+		match := true
+		for i, part := range parts {
+			if !strings.HasPrefix(part, "{") && part != segments[i] {
+				match = false
+				break
+			}
+		}
+		if match {
+			op, ok := ops[method]
+			return op, ok
+		}
+	}
+	return operation{}, false
+}
+
+func (d *Document) resolve(s *Schema) *Schema {
+	for s != nil && s.Ref != "" {
+		s = d.Components.Schemas[strings.TrimPrefix(s.Ref, "#/components/schemas/")]
+	}
+	return s
+}
+
+func (d *Document) validate(s *Schema, value interface{}, at string, problems *[]string) {
+	s = d.resolve(s)
+	if s == nil {
+		*problems = append(*problems, fmt.Sprintf("%s: unresolvable schema reference", at))
+		return
+	}
+	if value == nil {
+		if !s.Nullable {
+			*problems = append(*problems, fmt.Sprintf("%s: null is not allowed", at))
+		}
+		return
+	}
+	fail := func(format string, args ...interface{}) {
+		*problems = append(*problems, at+": "+fmt.Sprintf(format, args...))
+	}
+
+	switch s.Type {
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			fail("expected object, got %T", value)
+			return
+		}
+		for _, name := range s.Required {
+			if _, present := obj[name]; !present {
+				fail("missing required field %q", name)
+			}
+		}
+		var extra *Schema
+		closed := string(s.AdditionalProperties) == "false"
+		if len(s.AdditionalProperties) > 0 && !closed && string(s.AdditionalProperties) != "true" {
+			extra = &Schema{}
+			if err := json.Unmarshal(s.AdditionalProperties, extra); err != nil {
+				extra = nil
+			}
+		}
+		for name, v := range obj {
+			if prop, ok := s.Properties[name]; ok {
+				d.validate(prop, v, at+"."+name, problems)
+			} else if extra != nil {
+				d.validate(extra, v, at+"."+name, problems)
+			} else if closed {
+				fail("undocumented field %q", name)
+			}
+		}
+	case "array":
+		arr, ok := value.([]interface{})
+		if !ok {
+			fail("expected array, got %T", value)
+			return
+		}
+		if s.Items != nil {
+			for i, v := range arr {
+				d.validate(s.Items, v, fmt.Sprintf("%s[%d]", at, i), problems)
+			}
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			fail("expected string, got %T", value)
+			return
+		}
+		if len(s.Enum) > 0 && !slices.Contains(s.Enum, str) {
+			fail("value %q is not one of %v", str, s.Enum)
+		}
+		switch s.Format {
+		case "uuid":
+			if _, err := uuid.Parse(str); err != nil {
+				fail("value %q is not a uuid", str)
+			}
+		case "date-time":
+			if _, err := time.Parse(time.RFC3339Nano, str); err != nil {
+				fail("value %q is not an RFC 3339 date-time", str)
+			}
+		case "date":
+			if _, err := time.Parse(time.DateOnly, str); err != nil {
+				fail("value %q is not a YYYY-MM-DD date", str)
+			}
+		}
+	case "integer":
+		n, ok := value.(float64)
+		if !ok || n != math.Trunc(n) {
+			fail("expected integer, got %v", value)
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			fail("expected number, got %T", value)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			fail("expected boolean, got %T", value)
+		}
+	}
+}
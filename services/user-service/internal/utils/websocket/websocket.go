@@ -0,0 +1,296 @@
+// services/user-service/internal/utils/websocket/websocket.go
+package websocket
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Message types, the opcodes of RFC 6455.
+const (
+	TextMessage   = 1
+	BinaryMessage = 2
+	CloseMessage  = 8
+	PingMessage   = 9
+	PongMessage   = 10
+)
+
+// Close codes sent in close frames (RFC 6455, section 7.4).
+const (
+	CloseNormalClosure   = 1000
+	CloseGoingAway       = 1001
+	CloseProtocolError   = 1002
+	CloseUnsupportedData = 1003
+	CloseNoStatus        = 1005 // Received without a code; never sent
+	CloseInvalidPayload  = 1007
+	ClosePolicyViolation = 1008
+	CloseMessageTooBig   = 1009
+	CloseTryAgainLater   = 1013
+)
+
+// acceptGUID is appended to the client's key to compute Sec-WebSocket-Accept.
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// writeTimeout bounds each frame written, so a peer that stops reading cannot hold a writer forever.
+const writeTimeout = 10 * time.Second
+
+// HandshakeError is a request that cannot be upgraded, with the status to answer it with.
+type HandshakeError struct {
+	Status  int
+	Message string
+}
+
+func (e *HandshakeError) Error() string { return "websocket: " + e.Message }
+
+// CloseError is returned by ReadMessage once the connection is closed by either side with a close frame.
+type CloseError struct {
+	Code   int
+	Reason string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("websocket: closed with code %d %s", e.Code, e.Reason)
+}
+
+// IsUpgrade reports whether a request asks to switch to the WebSocket protocol.
+func IsUpgrade(r *http.Request) bool {
+	return headerHasToken(r.Header, "Connection", "upgrade") && headerHasToken(r.Header, "Upgrade", "websocket")
+}
+
+// headerHasToken reports whether a comma-separated header holds token, ignoring case.
+func headerHasToken(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Upgrade completes the opening handshake of a GET request and takes over its connection. The
+// headers already set on w, such as X-Request-ID, are sent with the 101 Switching Protocols
+// response. A request that is not a valid handshake returns a *HandshakeError and w is untouched,
+// so the caller answers it; after any other error the connection is gone.
+func Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	if r.Method != http.MethodGet {
+		return nil, &HandshakeError{http.StatusMethodNotAllowed, "the handshake must be a GET request"}
+	}
+	if !IsUpgrade(r) {
+		return nil, &HandshakeError{http.StatusUpgradeRequired, "expected Connection: Upgrade and Upgrade: websocket"}
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		return nil, &HandshakeError{http.StatusUpgradeRequired, "only version 13 of the protocol is supported"}
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if raw, err := base64.StdEncoding.DecodeString(key); err != nil || len(raw) != 16 {
+		return nil, &HandshakeError{http.StatusBadRequest, "Sec-WebSocket-Key must be 16 bytes in base64"}
+	}
+
+	netConn, brw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return nil, fmt.Errorf("websocket: failed to take over the connection: %w", err)
+	}
+	sum := sha1.Sum([]byte(key + acceptGUID))
+	h := w.Header().Clone()
+	h.Set("Upgrade", "websocket")
+	h.Set("Connection", "Upgrade")
+	h.Set("Sec-WebSocket-Accept", base64.StdEncoding.EncodeToString(sum[:]))
+	netConn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	brw.WriteString("HTTP/1.1 101 Switching Protocols\r\n")
+	h.Write(brw)
+	brw.WriteString("\r\n")
+	if err := brw.Flush(); err != nil {
+		netConn.Close()
+		return nil, fmt.Errorf("websocket: failed to complete the handshake: %w", err)
+	}
+	netConn.SetDeadline(time.Time{})
+	return &Conn{conn: netConn, br: brw.Reader, readLimit: 1 << 20}, nil
+}
+
+// Conn is the server side of a WebSocket connection. One goroutine may read while others write;
+// writes are serialized.
+type Conn struct {
+	conn      net.Conn
+	br        *bufio.Reader // Holds what the client sent after the handshake, too
+	readLimit int64
+
+	mu        sync.Mutex // Guards writes and closeSent
+	closeSent bool
+}
+
+// SetReadLimit caps the size of a message from the client. A larger one closes the connection with
+// CloseMessageTooBig. The default is 1 MiB.
+func (c *Conn) SetReadLimit(n int64) { c.readLimit = n }
+
+// SetReadDeadline sets when a pending or future ReadMessage fails, as net.Conn does.
+func (c *Conn) SetReadDeadline(t time.Time) error { return c.conn.SetReadDeadline(t) }
+
+// ReadMessage returns the next data message, reassembled from its fragments, or the next pong. Pings
+// are answered and close frames echoed as they arrive; after a close frame, from either side, it
+// returns a *CloseError. Frames that break the protocol close the connection with the matching code.
+func (c *Conn) ReadMessage() (messageType int, data []byte, err error) {
+	var msgType int
+	var msg []byte
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			var ce *CloseError
+			if errors.As(err, &ce) {
+				c.WriteClose(ce.Code, ce.Reason)
+			}
+			return 0, nil, err
+		}
+		switch opcode {
+		case PingMessage:
+			if err := c.WriteMessage(PongMessage, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case PongMessage:
+			return PongMessage, payload, nil
+		case CloseMessage:
+			code, reason := CloseNoStatus, ""
+			if len(payload) >= 2 {
+				code, reason = int(binary.BigEndian.Uint16(payload)), string(payload[2:])
+			}
+			echo := code
+			if echo == CloseNoStatus {
+				echo = CloseNormalClosure
+			}
+			c.WriteClose(echo, "")
+			return 0, nil, &CloseError{Code: code, Reason: reason}
+		case 0: // Continuation
+			if msgType == 0 {
+				return 0, nil, c.fail(CloseProtocolError, "continuation without a message")
+			}
+		case TextMessage, BinaryMessage:
+			if msgType != 0 {
+				return 0, nil, c.fail(CloseProtocolError, "new message before the last one ended")
+			}
+			msgType = opcode
+		default:
+			return 0, nil, c.fail(CloseProtocolError, "unknown opcode")
+		}
+		if int64(len(msg))+int64(len(payload)) > c.readLimit {
+			return 0, nil, c.fail(CloseMessageTooBig, "message too big")
+		}
+		msg = append(msg, payload...)
+		if fin {
+			if msgType == TextMessage && !utf8.Valid(msg) {
+				return 0, nil, c.fail(CloseInvalidPayload, "text is not valid UTF-8")
+			}
+			return msgType, msg, nil
+		}
+	}
+}
+
+// readFrame reads one frame and unmasks its payload. Protocol errors are returned as a *CloseError
+// with the code to close with.
+func (c *Conn) readFrame() (fin bool, opcode int, payload []byte, err error) {
+	var head [2]byte
+	if _, err := io.ReadFull(c.br, head[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin, opcode = head[0]&0x80 != 0, int(head[0]&0x0f)
+	if head[0]&0x70 != 0 {
+		return false, 0, nil, &CloseError{CloseProtocolError, "reserved bits set"}
+	}
+	if head[1]&0x80 == 0 {
+		return false, 0, nil, &CloseError{CloseProtocolError, "client frames must be masked"}
+	}
+	size := uint64(head[1] & 0x7f)
+	if opcode >= CloseMessage && (!fin || size > 125) {
+		return false, 0, nil, &CloseError{CloseProtocolError, "invalid control frame"}
+	}
+	switch size {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		size = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		size = binary.BigEndian.Uint64(ext[:])
+	}
+	if size > uint64(c.readLimit) {
+		return false, 0, nil, &CloseError{CloseMessageTooBig, "message too big"}
+	}
+	var mask [4]byte
+	if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+		return false, 0, nil, err
+	}
+	payload = make([]byte, size)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return fin, opcode, payload, nil
+}
+
+// fail closes the connection for a protocol error and returns the error.
+func (c *Conn) fail(code int, reason string) error {
+	c.WriteClose(code, reason)
+	return &CloseError{Code: code, Reason: reason}
+}
+
+// WriteMessage sends a message in a single frame. Nothing is sent after the close frame.
+func (c *Conn) WriteMessage(messageType int, data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.writeFrame(messageType, data)
+}
+
+// WriteClose sends a close frame with code and reason, once; later calls do nothing.
+func (c *Conn) WriteClose(code int, reason string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(reason) > 123 { // Control frames carry at most 125 bytes
+		reason = reason[:123]
+	}
+	err := c.writeFrame(CloseMessage, append(binary.BigEndian.AppendUint16(nil, uint16(code)), reason...))
+	c.closeSent = true
+	return err
+}
+
+func (c *Conn) writeFrame(opcode int, data []byte) error {
+	if c.closeSent {
+		return errors.New("websocket: close already sent")
+	}
+	frame := []byte{0x80 | byte(opcode)}
+	switch n := len(data); {
+	case n <= 125:
+		frame = append(frame, byte(n))
+	case n <= 0xffff:
+		frame = binary.BigEndian.AppendUint16(append(frame, 126), uint16(n))
+	default:
+		frame = binary.BigEndian.AppendUint64(append(frame, 127), uint64(n))
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	_, err := c.conn.Write(append(frame, data...))
+	return err
+}
+
+// Close closes the underlying connection without a close frame; send one with WriteClose first.
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,350 @@
+// services/user-service/internal/utils/websocket/websocket_test.go
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// frame is a frame as the tests write and read it, before masking.
+type frame struct {
+	fin     bool
+	opcode  int
+	payload []byte
+}
+
+// String shows a frame in failures, with the start of its payload.
+func (f frame) String() string {
+	return fmt.Sprintf("{fin:%t opcode:%d payload:%d bytes %.40q}", f.fin, f.opcode, len(f.payload), f.payload)
+}
+
+// clientFrame encodes f as a client sends it: masked, with the shortest length encoding.
+func clientFrame(f frame) []byte {
+	head := byte(f.opcode)
+	if f.fin {
+		head |= 0x80
+	}
+	b := []byte{head}
+	switch n := len(f.payload); {
+	case n <= 125:
+		b = append(b, 0x80|byte(n))
+	case n <= 0xffff:
+		b = binary.BigEndian.AppendUint16(append(b, 0x80|126), uint16(n))
+	default:
+		b = binary.BigEndian.AppendUint64(append(b, 0x80|127), uint64(n))
+	}
+	mask := []byte{0x12, 0x34, 0x56, 0x78}
+	b = append(b, mask...)
+	for i, c := range f.payload {
+		b = append(b, c^mask[i%4])
+	}
+	return b
+}
+
+// serverFrames decodes the frames the server wrote, failing on any that a client would reject.
+func serverFrames(t *testing.T, b []byte) []frame {
+	t.Helper()
+	var frames []frame
+	for len(b) > 0 {
+		if len(b) < 2 || b[0]&0x70 != 0 || b[1]&0x80 != 0 {
+			t.Fatalf("malformed server frame % x", b)
+		}
+		f := frame{fin: b[0]&0x80 != 0, opcode: int(b[0] & 0x0f)}
+		size, rest := uint64(b[1]&0x7f), b[2:]
+		switch size {
+		case 126:
+			size, rest = uint64(binary.BigEndian.Uint16(rest)), rest[2:]
+			if size <= 125 {
+				t.Fatalf("server frame of %d bytes uses a 16-bit length", size)
+			}
+		case 127:
+			size, rest = binary.BigEndian.Uint64(rest), rest[8:]
+			if size <= 0xffff {
+				t.Fatalf("server frame of %d bytes uses a 64-bit length", size)
+			}
+		}
+		if uint64(len(rest)) < size {
+			t.Fatalf("server frame of %d bytes holds %d", size, len(rest))
+		}
+		f.payload, b = rest[:size], rest[size:]
+		frames = append(frames, f)
+	}
+	return frames
+}
+
+// closePayload is the payload of a close frame.
+func closePayload(code int, reason string) []byte {
+	return append(binary.BigEndian.AppendUint16(nil, uint16(code)), reason...)
+}
+
+// testReadLimit is the read limit of the connections under test.
+const testReadLimit = 100000
+
+// message is what one ReadMessage returned.
+type message struct {
+	typ  int
+	data string
+}
+
+// exchange feeds input to the server side of a connection, reads messages until ReadMessage fails,
+// and returns them, the error, and the frames the server wrote. With hangUp the client drops the
+// connection once input is sent.
+func exchange(t *testing.T, input []byte, hangUp bool) ([]message, error, []frame) {
+	t.Helper()
+	server, client := net.Pipe()
+	c := &Conn{conn: server, br: bufio.NewReader(server), readLimit: testReadLimit}
+
+	go func() {
+		client.Write(input)
+		if hangUp {
+			client.Close()
+		}
+	}()
+	written := make(chan []byte)
+	go func() {
+		b, _ := io.ReadAll(client)
+		written <- b
+	}()
+
+	var messages []message
+	var err error
+	for {
+		var typ int
+		var data []byte
+		if typ, data, err = c.ReadMessage(); err != nil {
+			break
+		}
+		messages = append(messages, message{typ, string(data)})
+	}
+	c.Close()
+	return messages, err, serverFrames(t, <-written)
+}
+
+func TestReadMessage(t *testing.T) {
+	data := func(opcode int, payload string) frame { return frame{true, opcode, []byte(payload)} }
+	closeFrame := func(code int, reason string) frame { return frame{true, CloseMessage, closePayload(code, reason)} }
+	big := strings.Repeat("x", 70000) // Needs a 64-bit length, and fits testReadLimit
+
+	tests := []struct {
+		name     string
+		input    []frame
+		raw      []byte // Sent after input, for frames clientFrame cannot build
+		want     []message
+		wantCode int // Of the *CloseError ReadMessage ends with; 0 if the client hangs up after its input
+		written  []frame
+	}{
+		{"text then close", []frame{data(TextMessage, "hello"), closeFrame(CloseNormalClosure, "bye")}, nil,
+			[]message{{TextMessage, "hello"}}, CloseNormalClosure, []frame{closeFrame(CloseNormalClosure, "")}},
+		{"binary", []frame{data(BinaryMessage, "\x00\xff"), closeFrame(CloseGoingAway, "")}, nil,
+			[]message{{BinaryMessage, "\x00\xff"}}, CloseGoingAway, []frame{closeFrame(CloseGoingAway, "")}},
+		{"empty message", []frame{data(TextMessage, ""), closeFrame(CloseNormalClosure, "")}, nil,
+			[]message{{TextMessage, ""}}, CloseNormalClosure, []frame{closeFrame(CloseNormalClosure, "")}},
+		{"16-bit length", []frame{data(TextMessage, big[:300]), closeFrame(CloseNormalClosure, "")}, nil,
+			[]message{{TextMessage, big[:300]}}, CloseNormalClosure, []frame{closeFrame(CloseNormalClosure, "")}},
+		{"64-bit length", []frame{data(BinaryMessage, big), closeFrame(CloseNormalClosure, "")}, nil,
+			[]message{{BinaryMessage, big}}, CloseNormalClosure, []frame{closeFrame(CloseNormalClosure, "")}},
+		{"fragments reassembled", []frame{{false, TextMessage, []byte("hel")}, {false, 0, []byte("l")}, {true, 0, []byte("o")}, closeFrame(CloseNormalClosure, "")}, nil,
+			[]message{{TextMessage, "hello"}}, CloseNormalClosure, []frame{closeFrame(CloseNormalClosure, "")}},
+		{"ping between fragments answered", []frame{{false, TextMessage, []byte("a")}, data(PingMessage, "p1"), {true, 0, []byte("b")}, closeFrame(CloseNormalClosure, "")}, nil,
+			[]message{{TextMessage, "ab"}}, CloseNormalClosure, []frame{data(PongMessage, "p1"), closeFrame(CloseNormalClosure, "")}},
+		{"pong returned", []frame{data(PongMessage, "p"), closeFrame(CloseNormalClosure, "")}, nil,
+			[]message{{PongMessage, "p"}}, CloseNormalClosure, []frame{closeFrame(CloseNormalClosure, "")}},
+		{"UTF-8 split across fragments", []frame{{false, TextMessage, []byte("Zo\xc3")}, {true, 0, []byte("\xab")}, closeFrame(CloseNormalClosure, "")}, nil,
+			[]message{{TextMessage, "Zoë"}}, CloseNormalClosure, []frame{closeFrame(CloseNormalClosure, "")}},
+		{"close without a code", []frame{{true, CloseMessage, nil}}, nil,
+			nil, CloseNoStatus, []frame{closeFrame(CloseNormalClosure, "")}},
+
+		{"invalid UTF-8", []frame{data(TextMessage, "\xff")}, nil,
+			nil, CloseInvalidPayload, []frame{closeFrame(CloseInvalidPayload, "text is not valid UTF-8")}},
+		{"invalid UTF-8 in binary allowed", []frame{data(BinaryMessage, "\xff"), closeFrame(CloseNormalClosure, "")}, nil,
+			[]message{{BinaryMessage, "\xff"}}, CloseNormalClosure, []frame{closeFrame(CloseNormalClosure, "")}},
+		{"continuation without a message", []frame{data(0, "x")}, nil,
+			nil, CloseProtocolError, []frame{closeFrame(CloseProtocolError, "continuation without a message")}},
+		{"message inside a message", []frame{{false, TextMessage, []byte("a")}, data(TextMessage, "b")}, nil,
+			nil, CloseProtocolError, []frame{closeFrame(CloseProtocolError, "new message before the last one ended")}},
+		{"unknown opcode", []frame{data(3, "x")}, nil,
+			nil, CloseProtocolError, []frame{closeFrame(CloseProtocolError, "unknown opcode")}},
+		{"fragmented control frame", []frame{{false, PingMessage, []byte("p")}}, nil,
+			nil, CloseProtocolError, []frame{closeFrame(CloseProtocolError, "invalid control frame")}},
+		{"long control frame", []frame{data(PingMessage, big[:126])}, nil,
+			nil, CloseProtocolError, []frame{closeFrame(CloseProtocolError, "invalid control frame")}},
+		{"reserved bits", nil, []byte{0x80 | 0x40 | TextMessage, 0x80},
+			nil, CloseProtocolError, []frame{closeFrame(CloseProtocolError, "reserved bits set")}},
+		{"unmasked", nil, []byte{0x80 | TextMessage, 1, 'x'},
+			nil, CloseProtocolError, []frame{closeFrame(CloseProtocolError, "client frames must be masked")}},
+		{"frame too big", []frame{data(BinaryMessage, strings.Repeat("x", testReadLimit+1))}, nil,
+			nil, CloseMessageTooBig, []frame{closeFrame(CloseMessageTooBig, "message too big")}},
+		{"fragments too big together", []frame{{false, BinaryMessage, []byte(big)}, {true, 0, []byte(big)}}, nil,
+			nil, CloseMessageTooBig, []frame{closeFrame(CloseMessageTooBig, "message too big")}},
+		{"connection dropped mid-frame", nil, clientFrame(data(TextMessage, "hello"))[:5],
+			nil, 0, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var input []byte
+			for _, f := range tt.input {
+				input = append(input, clientFrame(f)...)
+			}
+			got, err, written := exchange(t, append(input, tt.raw...), tt.wantCode == 0)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("messages = %v, want %v", got, tt.want)
+			}
+			var ce *CloseError
+			switch {
+			case tt.wantCode == 0 && errors.As(err, &ce):
+				t.Errorf("ReadMessage() error = %v, want one other than a close", err)
+			case tt.wantCode != 0 && (!errors.As(err, &ce) || ce.Code != tt.wantCode):
+				t.Errorf("ReadMessage() error = %v, want a close with code %d", err, tt.wantCode)
+			}
+			if len(written) != len(tt.written) {
+				t.Fatalf("server wrote %d frames, want %d: %v", len(written), len(tt.written), written)
+			}
+			for i := range written {
+				if !written[i].fin || written[i].opcode != tt.written[i].opcode || !bytes.Equal(written[i].payload, tt.written[i].payload) {
+					t.Errorf("server frame %d = %v, want %v", i, written[i], tt.written[i])
+				}
+			}
+		})
+	}
+}
+
+func TestWriteMessage(t *testing.T) {
+	long := strings.Repeat("r", 200)
+	tests := []struct {
+		name  string
+		write func(c *Conn) error
+		want  []frame
+	}{
+		{"small", func(c *Conn) error { return c.WriteMessage(TextMessage, []byte("hi")) },
+			[]frame{{true, TextMessage, []byte("hi")}}},
+		{"16-bit length", func(c *Conn) error { return c.WriteMessage(BinaryMessage, bytes.Repeat([]byte{1}, 126)) },
+			[]frame{{true, BinaryMessage, bytes.Repeat([]byte{1}, 126)}}},
+		{"64-bit length", func(c *Conn) error { return c.WriteMessage(BinaryMessage, bytes.Repeat([]byte{2}, 0x10000)) },
+			[]frame{{true, BinaryMessage, bytes.Repeat([]byte{2}, 0x10000)}}},
+		{"close reason cut to fit a control frame", func(c *Conn) error { return c.WriteClose(CloseGoingAway, long) },
+			[]frame{{true, CloseMessage, closePayload(CloseGoingAway, long[:123])}}},
+		{"nothing after close", func(c *Conn) error {
+			c.WriteClose(CloseNormalClosure, "")
+			c.WriteClose(CloseGoingAway, "")
+			if err := c.WriteMessage(TextMessage, []byte("late")); err == nil {
+				return errors.New("WriteMessage() after WriteClose() returned no error")
+			}
+			return nil
+		}, []frame{{true, CloseMessage, closePayload(CloseNormalClosure, "")}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, client := net.Pipe()
+			c := &Conn{conn: server, br: bufio.NewReader(server), readLimit: testReadLimit}
+			written := make(chan []byte)
+			go func() {
+				b, _ := io.ReadAll(client)
+				written <- b
+			}()
+			if err := tt.write(c); err != nil {
+				t.Fatal(err)
+			}
+			c.Close()
+			if got := serverFrames(t, <-written); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("server wrote %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpgradeRejects(t *testing.T) {
+	handshake := func(edit func(r *http.Request)) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/ws", nil)
+		r.Header.Set("Connection", "keep-alive, Upgrade")
+		r.Header.Set("Upgrade", "websocket")
+		r.Header.Set("Sec-WebSocket-Version", "13")
+		r.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+		edit(r)
+		return r
+	}
+	tests := []struct {
+		name   string
+		req    *http.Request
+		status int
+	}{
+		{"POST", handshake(func(r *http.Request) { r.Method = http.MethodPost }), http.StatusMethodNotAllowed},
+		{"no Upgrade header", handshake(func(r *http.Request) { r.Header.Del("Upgrade") }), http.StatusUpgradeRequired},
+		{"no Connection upgrade", handshake(func(r *http.Request) { r.Header.Set("Connection", "keep-alive") }), http.StatusUpgradeRequired},
+		{"other version", handshake(func(r *http.Request) { r.Header.Set("Sec-WebSocket-Version", "8") }), http.StatusUpgradeRequired},
+		{"no key", handshake(func(r *http.Request) { r.Header.Del("Sec-WebSocket-Key") }), http.StatusBadRequest},
+		{"short key", handshake(func(r *http.Request) { r.Header.Set("Sec-WebSocket-Key", "c2hvcnQ=") }), http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			_, err := Upgrade(w, tt.req)
+			var he *HandshakeError
+			if !errors.As(err, &he) || he.Status != tt.status {
+				t.Fatalf("Upgrade() error = %v, want a HandshakeError with status %d", err, tt.status)
+			}
+			if w.Code != http.StatusOK || w.Body.Len() != 0 {
+				t.Error("Upgrade() answered a request it rejected")
+			}
+		})
+	}
+}
+
+func TestUpgrade(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-ID", "req-1")
+		c, err := Upgrade(w, r)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer c.Close()
+		if typ, data, err := c.ReadMessage(); err == nil {
+			c.WriteMessage(typ, data)
+		}
+	}))
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	// The handshake of RFC 6455, section 1.3, with a frame sent right behind it.
+	req := "GET /ws HTTP/1.1\r\nHost: example.com\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n" +
+		"Sec-WebSocket-Version: 13\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n\r\n"
+	if _, err := conn.Write(append([]byte(req), clientFrame(frame{true, TextMessage, []byte("echo")})...)); err != nil {
+		t.Fatal(err)
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("status = %d, want 101", resp.StatusCode)
+	}
+	for name, want := range map[string]string{
+		"Sec-WebSocket-Accept": "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=",
+		"Upgrade":              "websocket",
+		"Connection":           "Upgrade",
+		"X-Request-ID":         "req-1",
+	} {
+		if got := resp.Header.Get(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+	echoed, _ := io.ReadAll(br)
+	if got := serverFrames(t, echoed); !reflect.DeepEqual(got, []frame{{true, TextMessage, []byte("echo")}}) {
+		t.Errorf("server wrote %v, want the message echoed", got)
+	}
+}
@@ -0,0 +1,81 @@
+// services/user-service/internal/virusscan/virusscan.go
+package virusscan
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// Scanner checks content for malware.
+type Scanner interface {
+	// Scan reads r to the end. It returns the name of the signature found, or "" when the content is clean.
+	Scan(r io.Reader) (signature string, err error)
+}
+
+// chunkSize is the largest chunk sent to clamd; its StreamMaxLength limit applies to the total.
+const chunkSize = 64 << 10
+
+// ClamdScanner scans content with a ClamAV daemon over TCP, using the INSTREAM command.
+type ClamdScanner struct {
+	addr    string
+	timeout time.Duration
+}
+
+// NewClamdScanner creates a ClamdScanner for the daemon at addr (host:port).
+func NewClamdScanner(addr string) *ClamdScanner {
+	return &ClamdScanner{addr: addr, timeout: 5 * time.Minute}
+}
+
+// Scan streams r to clamd and parses its verdict.
+func (s *ClamdScanner) Scan(r io.Reader) (string, error) {
+	conn, err := net.DialTimeout("tcp", s.addr, 10*time.Second)
+	if err != nil {
+		return "", fmt.Errorf("failed to connect to clamd: %w", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(s.timeout))
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return "", fmt.Errorf("failed to start clamd scan: %w", err)
+	}
+	buf := make([]byte, chunkSize)
+	header := make([]byte, 4)
+	for {
+		n, readErr := io.ReadFull(r, buf)
+		if n > 0 {
+			binary.BigEndian.PutUint32(header, uint32(n))
+			if _, err := conn.Write(append(header, buf[:n]...)); err != nil {
+				return "", fmt.Errorf("failed to send content to clamd: %w", err)
+			}
+		}
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		}
+		if readErr != nil {
+			return "", fmt.Errorf("failed to read content: %w", readErr)
+		}
+	}
+	if _, err := conn.Write([]byte{0, 0, 0, 0}); err != nil {
+		return "", fmt.Errorf("failed to finish clamd scan: %w", err)
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && reply == "" {
+		return "", fmt.Errorf("failed to read clamd reply: %w", err)
+	}
+	// Replies look like "stream: OK", "stream: Eicar-Signature FOUND", or "... ERROR".
+	reply = strings.TrimSuffix(strings.TrimPrefix(strings.TrimRight(reply, "\x00"), "stream: "), "\n")
+	switch {
+	case reply == "OK":
+		return "", nil
+	case strings.HasSuffix(reply, " FOUND"):
+		return strings.TrimSuffix(reply, " FOUND"), nil
+	default:
+		return "", fmt.Errorf("clamd scan failed: %s", reply)
+	}
+}